@@ -3,11 +3,14 @@ package main
 import (
 	"github.com/cortezaproject/corteza-server/compose"
 	"github.com/cortezaproject/corteza-server/pkg/cli"
+	"github.com/crusttech/crust-server/pkg/extensions"
 )
 
 func main() {
 	cfg := compose.Configure()
 	cfg.RootCommandName = "crust-server-compose"
+	extensions.Compose(cfg)
+
 	cmd := cfg.MakeCLI(cli.Context())
 	cli.HandleError(cmd.Execute())
 }
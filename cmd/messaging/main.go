@@ -3,11 +3,14 @@ package main
 import (
 	"github.com/cortezaproject/corteza-server/messaging"
 	"github.com/cortezaproject/corteza-server/pkg/cli"
+	"github.com/crusttech/crust-server/pkg/extensions"
 )
 
 func main() {
 	cfg := messaging.Configure()
 	cfg.RootCommandName = "crust-server-messaging"
+	extensions.Messaging(cfg)
+
 	cmd := cfg.MakeCLI(cli.Context())
 	cli.HandleError(cmd.Execute())
 }
@@ -9,6 +9,7 @@ import (
 	"github.com/cortezaproject/corteza-server/pkg/cli"
 	"github.com/cortezaproject/corteza-server/pkg/logger"
 	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/crusttech/crust-server/pkg/extensions"
 	"github.com/crusttech/crust-server/pkg/subscription"
 )
 
@@ -29,6 +30,8 @@ func main() {
 		},
 	)
 
+	extensions.Monolith(cfg)
+
 	cmd := cfg.MakeCLI(cli.Context())
 	cli.HandleError(cmd.Execute())
 }
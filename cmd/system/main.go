@@ -9,6 +9,7 @@ import (
 	"github.com/cortezaproject/corteza-server/pkg/logger"
 	"github.com/cortezaproject/corteza-server/system"
 	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/crusttech/crust-server/pkg/extensions"
 	"github.com/crusttech/crust-server/pkg/subscription"
 )
 
@@ -30,6 +31,8 @@ func main() {
 		},
 	)
 
+	extensions.System(cfg)
+
 	cmd := cfg.MakeCLI(cli.Context())
 	cli.HandleError(cmd.Execute())
 }
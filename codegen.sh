@@ -152,4 +152,14 @@ function specs {
 
 specs
 
+# Repositories and REST handlers of extensions with rest.json (see codegen/),
+# endpoints of the others are still described with spec.json
+function generate {
+	yellow "> go generate"
+	go generate ./pkg/...
+	green "OK"
+}
+
+generate
+
 gofmt
@@ -0,0 +1,555 @@
+// Command repository generates repository of a type
+//
+// It is run with go:generate from the package of the type, for example:
+//
+//	//go:generate go run ../../codegen/repository -type App -table crust_compose_external_app -database compose -sort name
+//
+// and writes repository.gen.go with the repository, its filter and basic
+// CRUD methods. Columns are read from db tags of type's fields; dal tags
+// describe how fields are used:
+//
+//	scope   field is required to find or delete by ID (e.g. namespace)
+//	filter  field can be used in the filter, zero values do not filter
+//	sort    field can be used to sort by
+//
+// Scope fields are filters as well. Types with deleted_at column are
+// soft-deleted. The package must define <Type>Set and Err<Type>NotFound
+// (with withStack method that returns *fault.Error).
+//
+// Only repositories of extapp and templates are generated so far; other
+// extensions keep hand-written ones until their types change.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+type (
+	field struct {
+		Name   string
+		Type   string
+		Column string
+		JSON   string
+
+		Scope  bool
+		Filter bool
+		Sort   bool
+	}
+
+	spec struct {
+		Package  string
+		Type     string
+		Table    string
+		Database string
+		Sort     string
+
+		// Receiver and ID argument names
+		Var string
+		ID  string
+
+		Fields []*field
+
+		SoftDelete bool
+		CreatedAt  bool
+		UpdatedAt  bool
+	}
+)
+
+func main() {
+	var (
+		s   = &spec{}
+		out string
+	)
+
+	flag.StringVar(&s.Type, "type", "", "type of the stored entity")
+	flag.StringVar(&s.Table, "table", "", "database table")
+	flag.StringVar(&s.Database, "database", "compose", "database (compose, messaging or system)")
+	flag.StringVar(&s.Sort, "sort", "", "default sort, fields as named in JSON")
+	flag.StringVar(&out, "output", "repository.gen.go", "output file")
+	flag.Parse()
+
+	if s.Type == "" || s.Table == "" {
+		log.Fatal("type and table are required")
+	}
+
+	if err := s.load("."); err != nil {
+		log.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tpl.Execute(buf, s); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("could not format generated code: %v\n%s", err, buf.String())
+	}
+
+	if err = ioutil.WriteFile(out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// load reads fields of the type from package in the directory
+func (s *spec) load(dir string) error {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), dir, nil, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	for name, pkg := range pkgs {
+		if strings.HasSuffix(name, "_test") {
+			continue
+		}
+
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+
+				for _, sp := range gd.Specs {
+					ts := sp.(*ast.TypeSpec)
+					if ts.Name.Name != s.Type {
+						continue
+					}
+
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						return fmt.Errorf("%s is not a struct", s.Type)
+					}
+
+					s.Package = name
+					return s.fields(st)
+				}
+			}
+		}
+	}
+
+	return fmt.Errorf("type %s not found", s.Type)
+}
+
+func (s *spec) fields(st *ast.StructType) error {
+	s.Var = strings.ToLower(s.Type[:1])
+	s.ID = lcFirst(s.Type) + "ID"
+
+	for _, f := range st.Fields.List {
+		if f.Tag == nil || len(f.Names) != 1 {
+			continue
+		}
+
+		tag := reflect.StructTag(strings.Trim(f.Tag.Value, "`"))
+		column := tag.Get("db")
+		if column == "" || column == "-" {
+			continue
+		}
+
+		fld := &field{
+			Name:   f.Names[0].Name,
+			Type:   typeString(f.Type),
+			Column: column,
+			JSON:   strings.Split(tag.Get("json"), ",")[0],
+		}
+
+		for _, opt := range strings.Split(tag.Get("dal"), ",") {
+			switch opt {
+			case "scope":
+				fld.Scope, fld.Filter = true, true
+			case "filter":
+				fld.Filter = true
+			case "sort":
+				fld.Sort = true
+			}
+		}
+
+		switch {
+		case column == "id":
+			fld.Sort = true
+		case column == "deleted_at":
+			s.SoftDelete = true
+		case column == "created_at" && fld.Type == "time.Time":
+			s.CreatedAt = true
+		case column == "updated_at" && fld.Type == "*time.Time":
+			s.UpdatedAt = true
+		}
+
+		if fld.Sort && (fld.JSON == "" || fld.JSON == "-") {
+			return fmt.Errorf("sortable field %s has no JSON name", fld.Name)
+		}
+
+		s.Fields = append(s.Fields, fld)
+	}
+
+	if s.Field("id") == nil {
+		return fmt.Errorf("%s has no id column", s.Type)
+	}
+
+	if s.Sort == "" {
+		s.Sort = s.Field("id").JSON
+	}
+
+	return nil
+}
+
+// Field returns field of the column
+func (s spec) Field(column string) *field {
+	for _, f := range s.Fields {
+		if f.Column == column {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (s spec) Scope() (ff []*field) {
+	for _, f := range s.Fields {
+		if f.Scope {
+			ff = append(ff, f)
+		}
+	}
+
+	return
+}
+
+func (s spec) Filters() (ff []*field) {
+	for _, f := range s.Fields {
+		if f.Filter {
+			ff = append(ff, f)
+		}
+	}
+
+	return
+}
+
+func (s spec) Sortable() (ff []*field) {
+	for _, f := range s.Fields {
+		if f.Sort {
+			ff = append(ff, f)
+		}
+	}
+
+	return
+}
+
+// Args returns arguments of methods that find or delete by ID, scope first
+func (s spec) Args() string {
+	var aa []string
+	for _, f := range s.Scope() {
+		aa = append(aa, lcFirst(f.Name))
+	}
+
+	return strings.Join(append(aa, s.ID), ", ")
+}
+
+// Params returns parameters of methods that find or delete by ID
+func (s spec) Params() string {
+	return s.Args() + " uint64"
+}
+
+// WithIDs returns calls that add scope and entity IDs to the not found error
+func (s spec) WithIDs() string {
+	var ww []string
+	for _, f := range s.Scope() {
+		if f.Type == "uint64" {
+			ww = append(ww, fmt.Sprintf(".WithID(%q, %s)", lcFirst(f.Name), lcFirst(f.Name)))
+		}
+	}
+
+	return strings.Join(append(ww, fmt.Sprintf(".WithID(%q, %s)", s.ID, s.ID)), "")
+}
+
+// Eq returns map of columns and arguments that select the entity by ID
+func (s spec) Eq() string {
+	var ee = []string{fmt.Sprintf("%q: %s", "id", s.ID)}
+	for _, f := range s.Scope() {
+		ee = append(ee, fmt.Sprintf("%q: %s", f.Column, lcFirst(f.Name)))
+	}
+
+	return "squirrel.Eq{" + strings.Join(ee, ", ") + "}"
+}
+
+// FilterType is type of the field in the filter
+//
+// Booleans are pointers, so that false can be filtered by.
+func (f field) FilterType() string {
+	if f.Type == "bool" {
+		return "*bool"
+	}
+
+	return f.Type
+}
+
+// FilterJSON is JSON tag of the field in the filter
+func (f field) FilterJSON() string {
+	name := f.JSON
+	if name == "" || name == "-" {
+		name = lcFirst(f.Name)
+	}
+
+	if isInt(f.Type) && strings.HasSuffix(f.Name, "ID") {
+		return name + ",string"
+	}
+
+	return name
+}
+
+// Set checks if the filter field is set
+func (f field) Set() string {
+	switch {
+	case f.Type == "bool":
+		return "f." + f.Name + " != nil"
+	case isInt(f.Type):
+		return "f." + f.Name + " != 0"
+	default:
+		return "f." + f.Name + ` != ""`
+	}
+}
+
+// Value returns value of the filter field
+func (f field) Value() string {
+	if f.Type == "bool" {
+		return "*f." + f.Name
+	}
+
+	return "f." + f.Name
+}
+
+func isInt(t string) bool {
+	return strings.HasPrefix(t, "int") || strings.HasPrefix(t, "uint")
+}
+
+func typeString(e ast.Expr) string {
+	switch t := e.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return "*" + typeString(t.X)
+	case *ast.SelectorExpr:
+		return typeString(t.X) + "." + t.Sel.Name
+	case *ast.ArrayType:
+		return "[]" + typeString(t.Elt)
+	case *ast.MapType:
+		return "map[" + typeString(t.Key) + "]" + typeString(t.Value)
+	}
+
+	return fmt.Sprintf("%T", e)
+}
+
+func lcFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	// Leading acronyms are lowered as a whole: "URL" => "url", "IDs" => "ids"
+	rr := []rune(s)
+	for i := 0; i < len(rr) && unicode.IsUpper(rr[i]); i++ {
+		if i > 0 && i+1 < len(rr) && unicode.IsLower(rr[i+1]) {
+			break
+		}
+
+		rr[i] = unicode.ToLower(rr[i])
+	}
+
+	return string(rr)
+}
+
+var tpl = template.Must(template.New("repository").Parse(`// Code generated by codegen/repository. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"context"
+{{- if .SoftDelete }}
+	"time"
+{{- end }}
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/dal"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+
+	// {{ .Type }}Filter selects {{ .Type }} entries, fields with zero values do not filter
+	{{ .Type }}Filter struct {
+{{- range .Filters }}
+		{{ .Name }} {{ .FilterType }} ` + "`" + `json:"{{ .FilterJSON }}"` + "`" + `
+{{- end }}
+{{ if .SoftDelete }}
+		// Deleted entries are excluded by default
+		Deleted rh.FilterState ` + "`" + `json:"deleted"` + "`" + `
+{{ end }}
+		// Comma separated fields with optional direction, "{{ .Sort }}" by default
+		Sort string ` + "`" + `json:"sort"` + "`" + `
+
+		// All entries are returned when PerPage is zero
+		Page    uint ` + "`" + `json:"page"` + "`" + `
+		PerPage uint ` + "`" + `json:"perPage"` + "`" + `
+	}
+)
+
+var (
+	// Columns that entries can be sorted by, by fields' JSON names
+	sortColumns = map[string]string{
+{{- range .Sortable }}
+		"{{ .JSON }}": "{{ .Column }}",
+{{- end }}
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("{{ .Database }}").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "{{ .Table }}"
+}
+
+func (r repository) columns() []string {
+	return []string{
+{{- range .Fields }}
+		"{{ .Column }}",
+{{- end }}
+	}
+}
+
+{{ if .SoftDelete -}}
+// query selects entries that are not deleted
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(r.columns()...).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+{{- else -}}
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(r.columns()...).
+		From(r.table())
+}
+{{- end }}
+
+func (r repository) FindByID({{ .Params }}) (*{{ .Type }}, error) {
+	var (
+		{{ .Var }} = &{{ .Type }}{}
+		q = r.query().Where({{ .Eq }})
+	)
+
+	if err := rh.FetchOne(r.db(), q, {{ .Var }}); err != nil {
+		return nil, err
+	} else if {{ .Var }}.ID == 0 {
+		return nil, Err{{ .Type }}NotFound.withStack(){{ .WithIDs }}
+	}
+
+	return {{ .Var }}, nil
+}
+
+// Find returns a page of entries that match the filter
+func (r repository) Find(f {{ .Type }}Filter) (set {{ .Type }}Set, err error) {
+	sort := f.Sort
+	if sort == "" {
+		sort = "{{ .Sort }}"
+	}
+
+	order, err := dal.Order(sort, sortColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	// ID keeps the order stable between pages
+	q := r.filter(f).OrderBy(append(order, "id")...)
+
+	return set, rh.FetchPaged(r.db(), q, f.Page, f.PerPage, &set)
+}
+
+// Count returns number of entries that match the filter
+func (r repository) Count(f {{ .Type }}Filter) (uint, error) {
+	return rh.Count(r.db(), r.filter(f))
+}
+
+func (r repository) filter(f {{ .Type }}Filter) squirrel.SelectBuilder {
+{{- if .SoftDelete }}
+	q := rh.FilterNullByState(squirrel.Select(r.columns()...).From(r.table()), "deleted_at", f.Deleted)
+{{- else }}
+	q := r.query()
+{{- end }}
+{{ range .Filters }}
+	if {{ .Set }} {
+		q = q.Where(squirrel.Eq{"{{ .Column }}": {{ .Value }}})
+	}
+{{ end }}
+	return q
+}
+
+func (r repository) Create({{ .Var }} *{{ .Type }}) (*{{ .Type }}, error) {
+	{{ .Var }}.ID = factory.Sonyflake.NextID()
+{{- if .CreatedAt }}
+	rh.SetCurrentTimeRounded(&{{ .Var }}.CreatedAt)
+{{- end }}
+
+	return {{ .Var }}, errors.WithStack(r.db().Insert(r.table(), {{ .Var }}))
+}
+
+func (r repository) Update({{ .Var }} *{{ .Type }}) (*{{ .Type }}, error) {
+{{- if .UpdatedAt }}
+	rh.SetCurrentTimeRounded(&{{ .Var }}.UpdatedAt)
+{{ end }}
+	return {{ .Var }}, errors.WithStack(r.db().Replace(r.table(), {{ .Var }}))
+}
+{{ if .SoftDelete }}
+func (r repository) DeleteByID({{ .Params }}) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"deleted_at": time.Now()},
+		{{ .Eq }},
+	)
+}
+
+func (r repository) UndeleteByID({{ .Params }}) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"deleted_at": nil},
+		{{ .Eq }},
+	)
+}
+{{ else }}
+func (r repository) DeleteByID({{ .Params }}) error {
+	return rh.Delete(r.db(), r.table(), {{ .Eq }})
+}
+{{ end -}}
+`))
@@ -0,0 +1,508 @@
+// Command rest generates REST handlers of a package
+//
+// It is run with go:generate from the package, for example:
+//
+//	//go:generate go run ../../codegen/rest
+//
+// Endpoints are defined in rest.json of the package (see definition
+// type). Command writes rest.gen.go with request structs, the API
+// interface and MountRoutes, and openapi.json with the OpenAPI (3.0)
+// description of the endpoints.
+//
+// Requests are checked against their validate tags (see validate pkg)
+// before they are passed to the controller.
+//
+// The package must define controller type that implements the API.
+//
+// Only extapp and templates are generated so far; other extensions mount
+// hand-written handlers (see rest.Handler) and are moved over as their
+// endpoints change.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+type (
+	definition struct {
+		// Prefix of request structs and handler names
+		Resource string `json:"resource"`
+
+		// Description of the endpoints, "external app" => "mounts external app endpoints"
+		Description string `json:"description"`
+
+		// App (compose, messaging, system) and path of the extension
+		App  string `json:"app"`
+		Path string `json:"path"`
+
+		// Endpoints are available only to authenticated users, except public ones
+		Authenticated bool `json:"authenticated"`
+
+		// Packages of param types, e.g. "github.com/cortezaproject/corteza-server/compose/types"
+		Imports []string `json:"imports"`
+
+		// Parameters of all endpoints, params of the path
+		Params []*param `json:"params"`
+
+		Endpoints []*endpoint `json:"endpoints"`
+
+		Package string `json:"-"`
+	}
+
+	endpoint struct {
+		Name   string   `json:"name"`
+		Title  string   `json:"title"`
+		Method string   `json:"method"`
+		Path   string   `json:"path"`
+		Params []*param `json:"params"`
+
+		// Endpoint is available to everyone, even when definition is authenticated
+		Public bool `json:"public"`
+
+		Resource string   `json:"-"`
+		All      []*param `json:"-"`
+
+		// Endpoint is available only to authenticated users
+		Authenticated bool `json:"-"`
+	}
+
+	param struct {
+		Name string `json:"name"`
+
+		// Go field name, defaults to the name with the first letter in upper case
+		Field string `json:"field"`
+
+		// Go type; path and query params are limited to uint64, uint, string and bool,
+		// header params to string
+		Type string `json:"type"`
+
+		// Where the param is read from: path, query, header or body
+		In string `json:"in"`
+
+		Title    string `json:"title"`
+		Required bool   `json:"required"`
+
+		// Additional validation rules, added to the validate tag
+		Validate string `json:"validate"`
+	}
+)
+
+func main() {
+	var (
+		in  string
+		out string
+		oa  string
+	)
+
+	flag.StringVar(&in, "definition", "rest.json", "definition of the endpoints")
+	flag.StringVar(&out, "output", "rest.gen.go", "output file")
+	flag.StringVar(&oa, "openapi", "openapi.json", "OpenAPI output file")
+	flag.Parse()
+
+	d, err := load(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = tpl.Execute(buf, d); err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("could not format generated code: %v\n%s", err, buf.String())
+	}
+
+	if err = ioutil.WriteFile(out, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	spec, err := json.MarshalIndent(d.openAPI(), "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err = ioutil.WriteFile(oa, append(spec, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func load(path string) (*definition, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &definition{}
+	if err = json.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	if d.Package = os.Getenv("GOPACKAGE"); d.Package == "" {
+		return nil, fmt.Errorf("GOPACKAGE is not set, run with go generate")
+	}
+
+	if d.Resource == "" {
+		return nil, fmt.Errorf("resource is required")
+	}
+
+	for _, p := range d.Params {
+		if err = p.check(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range d.Endpoints {
+		e.Resource = d.Resource
+		e.All = append(append([]*param{}, d.Params...), e.Params...)
+		e.Authenticated = d.Authenticated && !e.Public
+
+		if e.Name == "" || e.Method == "" || e.Path == "" {
+			return nil, fmt.Errorf("endpoint requires name, method and path")
+		}
+
+		e.Method = strings.ToUpper(e.Method)
+
+		for _, p := range e.Params {
+			if err = p.check(); err != nil {
+				return nil, fmt.Errorf("%s: %v", e.Name, err)
+			}
+		}
+	}
+
+	return d, nil
+}
+
+func (p *param) check() error {
+	if p.Name == "" || p.Type == "" {
+		return fmt.Errorf("param requires name and type")
+	}
+
+	if p.Field == "" {
+		p.Field = strings.ToUpper(p.Name[:1]) + p.Name[1:]
+	}
+
+	switch p.In {
+	case "path", "query":
+		switch p.Type {
+		case "uint64", "uint", "string", "bool":
+		default:
+			return fmt.Errorf("%s param %s can not be %s", p.In, p.Name, p.Type)
+		}
+	case "header":
+		if p.Type != "string" {
+			return fmt.Errorf("header param %s can not be %s", p.Name, p.Type)
+		}
+	case "body":
+	default:
+		return fmt.Errorf("param %s is in unknown place %q", p.Name, p.In)
+	}
+
+	return nil
+}
+
+// HasBody checks if any of endpoint's params are read from the body
+func (e endpoint) HasBody() bool {
+	for _, p := range e.All {
+		if p.In == "body" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e endpoint) Request() string {
+	return e.Resource + e.Name + "Request"
+}
+
+// Tags returns struct tags of the param
+//
+// Only body params are decoded from JSON; IDs of the path are always required.
+func (p param) Tags() string {
+	var (
+		name = "-"
+		rr   []string
+	)
+
+	if p.In == "body" {
+		name = p.Name
+		if p.Type == "uint64" {
+			name += ",string"
+		}
+	}
+
+	if p.Required || (p.In == "path" && p.Type == "uint64") {
+		rr = append(rr, "required")
+	}
+
+	if p.Validate != "" {
+		rr = append(rr, p.Validate)
+	}
+
+	tags := fmt.Sprintf("json:%q", name)
+	if len(rr) > 0 {
+		tags += fmt.Sprintf(" validate:%q", strings.Join(rr, ","))
+	}
+
+	return "`" + tags + "`"
+}
+
+// Read returns expression that reads path, query or header param
+func (p param) Read() string {
+	if p.In == "header" {
+		return fmt.Sprintf("r.Header.Get(%q)", p.Name)
+	}
+
+	if p.In == "path" {
+		if p.Type == "uint64" {
+			return fmt.Sprintf("rest.ParamUint64(r, %q)", p.Name)
+		}
+
+		return fmt.Sprintf("chi.URLParam(r, %q)", p.Name)
+	}
+
+	switch p.Type {
+	case "uint64":
+		return fmt.Sprintf("rest.QueryUint64(r, %q)", p.Name)
+	case "uint":
+		return fmt.Sprintf("rest.QueryUint(r, %q)", p.Name)
+	case "bool":
+		return fmt.Sprintf("rest.QueryBool(r, %q)", p.Name)
+	}
+
+	return fmt.Sprintf("r.URL.Query().Get(%q)", p.Name)
+}
+
+// RouterMethod returns chi router's method for the HTTP method
+func (e endpoint) RouterMethod() string {
+	return strings.ToUpper(e.Method[:1]) + strings.ToLower(e.Method[1:])
+}
+
+// openAPI returns OpenAPI document of the endpoints
+func (d definition) openAPI() interface{} {
+	type (
+		obj = map[string]interface{}
+	)
+
+	var (
+		paths = obj{}
+
+		// Responses are negotiated (see rest.Negotiate), protobuf messages are google.protobuf.Value
+		content = obj{
+			"application/json":       obj{},
+			"application/msgpack":    obj{},
+			"application/x-protobuf": obj{},
+		}
+	)
+
+	for _, e := range d.Endpoints {
+		var (
+			path   = strings.TrimSuffix(d.Path+e.Path, "/")
+			params []obj
+			props  = obj{}
+			req    []string
+		)
+
+		if path == "" {
+			path = "/"
+		}
+
+		for _, p := range e.All {
+			if p.In == "body" {
+				props[p.Name] = schema(p)
+				if p.Required {
+					req = append(req, p.Name)
+				}
+
+				continue
+			}
+
+			params = append(params, obj{
+				"name":        p.Name,
+				"in":          p.In,
+				"description": p.Title,
+				"required":    p.In == "path" || p.Required,
+				"schema":      schema(p),
+			})
+		}
+
+		op := obj{
+			"operationId": d.Resource + "." + e.Name,
+			"summary":     e.Title,
+			"tags":        []string{d.Resource},
+			"responses": obj{
+				"200": obj{"description": "OK", "content": content},
+			},
+		}
+
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if len(props) > 0 {
+			body := obj{"type": "object", "properties": props}
+			if len(req) > 0 {
+				sort.Strings(req)
+				body["required"] = req
+			}
+
+			op["requestBody"] = obj{
+				"content": obj{"application/json": obj{"schema": body}},
+			}
+		}
+
+		if paths[path] == nil {
+			paths[path] = obj{}
+		}
+
+		paths[path].(obj)[strings.ToLower(e.Method)] = op
+	}
+
+	return obj{
+		"openapi": "3.0.0",
+		"info": obj{
+			"title":       d.Resource,
+			"description": d.Description,
+			"version":     "1.0",
+		},
+		"x-crust-app": d.App,
+		"paths":       paths,
+	}
+}
+
+// schema returns OpenAPI schema of param's type
+//
+// IDs are encoded as strings, they would lose precision as JSON numbers.
+func schema(p *param) map[string]interface{} {
+	var s = map[string]interface{}{}
+
+	switch t := strings.TrimPrefix(p.Type, "*"); {
+	case t == "uint64":
+		s["type"], s["format"] = "string", "uint64"
+	case t == "string":
+		s["type"] = "string"
+	case t == "bool":
+		s["type"] = "boolean"
+	case strings.HasPrefix(t, "int") || strings.HasPrefix(t, "uint"):
+		s["type"] = "integer"
+	case strings.HasPrefix(t, "[]"):
+		s["type"], s["items"] = "array", map[string]interface{}{}
+	default:
+		s["type"] = "object"
+	}
+
+	if p.Title != "" && p.In == "body" {
+		s["description"] = p.Title
+	}
+
+	// Limits of validation rules
+	for _, rule := range strings.Split(p.Validate, ",") {
+		kv := strings.SplitN(strings.TrimSpace(rule), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		n, _ := strconv.Atoi(kv[1])
+
+		switch {
+		case kv[0] == "oneof":
+			s["enum"] = strings.Fields(kv[1])
+		case s["type"] == "string":
+			s[kv[0]+"Length"] = n
+		case s["type"] == "array":
+			s[kv[0]+"Items"] = n
+		case s["type"] == "integer" && kv[0] == "max":
+			s["maximum"] = n
+		case s["type"] == "integer" && kv[0] == "min":
+			s["minimum"] = n
+		}
+	}
+
+	return s
+}
+
+var tpl = template.Must(template.New("rest").Parse(`// Code generated by codegen/rest from rest.json. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+{{ if .Authenticated }}
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+{{- end }}
+{{- range .Imports }}
+	"{{ . }}"
+{{- end }}
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+type (
+	// {{ .Resource }}API is implemented by the controller
+	{{ .Resource }}API interface {
+{{- range .Endpoints }}
+		{{ .Name }}(ctx context.Context, req *{{ .Request }}) (interface{}, error)
+{{- end }}
+	}
+{{ range .Endpoints }}
+	// {{ .Request }} holds params of {{ .Resource }}.{{ .Name }}
+	{{ .Request }} struct {
+{{- range .All }}
+{{- if .Title }}
+		// {{ .Title }}
+{{- end }}
+		{{ .Field }} {{ .Type }} {{ .Tags }}
+{{- end }}
+	}
+{{ end -}}
+)
+
+{{ range .Endpoints -}}
+// Fill reads params from the request and checks them
+func (req *{{ .Request }}) Fill(r *http.Request) error {
+{{- range .All }}
+{{- if ne .In "body" }}
+	req.{{ .Field }} = {{ .Read }}
+{{- end }}
+{{- end }}
+{{ if .HasBody }}
+	return rest.Decode(r, req)
+{{- else }}
+	return rest.Validate(req)
+{{- end }}
+}
+
+{{ end -}}
+
+// MountRoutes mounts {{ .Description }} endpoints
+//
+// Expects to be mounted under {{ .Path }}
+func MountRoutes(r chi.Router) {
+	var api {{ .Resource }}API = &controller{}
+{{ range .Endpoints }}
+	r{{ if .Authenticated }}.With(auth.MiddlewareValidOnly){{ end }}.{{ .RouterMethod }}("{{ .Path }}", rest.Handler("{{ .Resource }}.{{ .Name }}", func(r *http.Request) (interface{}, error) {
+		req := &{{ .Request }}{}
+		if err := req.Fill(r); err != nil {
+			return nil, err
+		}
+
+		return api.{{ .Name }}(r.Context(), req)
+	}))
+{{ end -}}
+}
+`))
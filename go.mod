@@ -3,14 +3,31 @@ module github.com/crusttech/crust-server
 go 1.12
 
 require (
+	github.com/360EntSecGroup-Skylar/excelize/v2 v2.0.2
+	github.com/Masterminds/squirrel v1.1.1-0.20191017225151-12f2162c8d8d
 	github.com/cortezaproject/corteza-server v0.0.0-20200110160908-6f0a7efb96b4
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/disintegration/imaging v1.6.0
+	github.com/edwvee/exiffix v0.0.0-20180602190213-b57537c92a6b
+	github.com/go-chi/chi v3.3.4+incompatible
+	github.com/golang/protobuf v1.3.2
+	github.com/gomodule/redigo v2.0.0+incompatible
+	github.com/gorilla/websocket v1.4.0
+	github.com/jmoiron/sqlx v1.2.0
 	github.com/joho/godotenv v1.3.0
 	github.com/kr/pretty v0.1.0 // indirect
-	github.com/prometheus/client_golang v0.9.3 // indirect
+	github.com/minio/minio-go/v6 v6.0.39
+	github.com/pkg/errors v0.8.1
+	github.com/prometheus/client_golang v0.9.3
+	github.com/prometheus/client_model v0.0.0-20190129233127-fd36f4220a90
+	github.com/prometheus/common v0.4.0
+	github.com/sony/sonyflake v0.0.0-20181109022403-6d5bd6181009
 	github.com/spf13/cobra v0.0.3
+	github.com/titpetric/factory v0.0.0-20190806200833-ae4b02b9e034
 	go.uber.org/zap v1.10.0
+	google.golang.org/grpc v1.22.1
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
+	gopkg.in/mail.v2 v2.3.1
 )
 
 replace gopkg.in/Masterminds/squirrel.v1 => github.com/Masterminds/squirrel v1.1.0
@@ -0,0 +1,134 @@
+package access
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+)
+
+type (
+	rulesFinder interface {
+		FindRulesByRoleID(roleID uint64) permissions.RuleSet
+	}
+
+	// batch holds caller's rules for a single operation
+	//
+	// Rules are indexed once, so checking many resources does not scan
+	// (and lock) all rules of the permission service for each of them,
+	// as Can() of corteza's access control does.
+	batch struct {
+		superuser bool
+		roles     []uint64
+
+		// access by resource and role
+		rules map[permissions.Resource]map[uint64]permissions.Access
+	}
+)
+
+// newBatch loads rules of caller's roles (and everyone role) for the operation
+func newBatch(ctx context.Context, rf rulesFinder, op permissions.Operation) *batch {
+	var (
+		i = auth.GetIdentityFromContext(ctx)
+		b = &batch{
+			superuser: auth.IsSuperUser(i),
+			roles:     i.Roles(),
+			rules:     map[permissions.Resource]map[uint64]permissions.Access{},
+		}
+	)
+
+	if b.superuser || rf == nil {
+		return b
+	}
+
+	b.load(rf, op)
+	return b
+}
+
+// load indexes rules of batch's roles (and everyone role) for the operation
+func (b *batch) load(rf rulesFinder, op permissions.Operation) {
+	for _, roleID := range append([]uint64{permissions.EveryoneRoleID}, b.roles...) {
+		for _, r := range rf.FindRulesByRoleID(roleID) {
+			if r.Operation != op || r.Access == permissions.Inherit {
+				continue
+			}
+
+			if b.rules[r.Resource] == nil {
+				b.rules[r.Resource] = map[uint64]permissions.Access{}
+			}
+
+			b.rules[r.Resource][r.RoleID] = r.Access
+		}
+	}
+}
+
+// can mirrors corteza's permission service Can()
+//
+// Rules are checked for caller's roles and then for everyone role, on the
+// resource and then on its wildcard; fallbacks are called when no rule matches.
+func (b batch) can(res permissions.Resource, ff ...permissions.CheckAccessFunc) bool {
+	if b.superuser {
+		return true
+	}
+
+	if v := b.check(res); v != permissions.Inherit {
+		return v == permissions.Allow
+	}
+
+	for _, f := range ff {
+		if v := f(); v != permissions.Inherit {
+			return v == permissions.Allow
+		}
+	}
+
+	return false
+}
+
+func (b batch) check(res permissions.Resource) permissions.Access {
+	if !res.IsValid() {
+		return permissions.Deny
+	}
+
+	if len(b.roles) > 0 {
+		if v := b.checkResource(res, b.roles...); v != permissions.Inherit {
+			return v
+		}
+	}
+
+	return b.checkResource(res, permissions.EveryoneRoleID)
+}
+
+func (b batch) checkResource(res permissions.Resource, roles ...uint64) permissions.Access {
+	if v := b.checkRoles(res, roles...); v != permissions.Inherit {
+		return v
+	}
+
+	if res.IsAppendable() {
+		return b.checkRoles(res.AppendWildcard(), roles...)
+	}
+
+	return permissions.Inherit
+}
+
+// checkRoles returns Deny when any of the roles is denied, Allow when
+// any of them is allowed and Inherit when there are no rules
+func (b batch) checkRoles(res permissions.Resource, roles ...uint64) permissions.Access {
+	var (
+		v     = permissions.Access(permissions.Inherit)
+		rules = b.rules[res]
+	)
+
+	for _, roleID := range roles {
+		// Deny is the zero value, roles without rules must be skipped
+		a, ok := rules[roleID]
+		switch {
+		case !ok:
+		case a == permissions.Deny:
+			return permissions.Deny
+		case a == permissions.Allow:
+			v = permissions.Allow
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,70 @@
+package access
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+)
+
+// CanReadRecords checks if the caller can read records, by record ID
+//
+// Same as compose's CanReadRecord (on record's module), with a single
+// rules pass for all records.
+func CanReadRecords(ctx context.Context, rr types.RecordSet) map[uint64]bool {
+	var (
+		b   = newBatch(ctx, service.DefaultPermissions, "record.read")
+		out = make(map[uint64]bool, len(rr))
+	)
+
+	for _, r := range rr {
+		out[r.ID] = b.can(r.PermissionResource())
+	}
+
+	return out
+}
+
+// CanReadModuleRecords checks if the caller can read records of modules, by module ID
+//
+// Same as compose's CanReadRecord, with a single rules pass for all modules.
+func CanReadModuleRecords(ctx context.Context, mm types.ModuleSet) map[uint64]bool {
+	var (
+		b   = newBatch(ctx, service.DefaultPermissions, "record.read")
+		out = make(map[uint64]bool, len(mm))
+	)
+
+	for _, m := range mm {
+		out[m.ID] = b.can(m.PermissionResource())
+	}
+
+	return out
+}
+
+// CanReadRecordValues checks if the caller can read values of the fields, by field ID
+//
+// Same as compose's CanReadRecordValue, with a single rules pass for all fields.
+func CanReadRecordValues(ctx context.Context, ff types.ModuleFieldSet) map[uint64]bool {
+	var (
+		b   = newBatch(ctx, service.DefaultPermissions, "record.value.read")
+		out = make(map[uint64]bool, len(ff))
+	)
+
+	for _, f := range ff {
+		out[f.ID] = b.can(f.PermissionResource(), permissions.Allowed)
+	}
+
+	return out
+}
+
+// ReadableFields returns fields with values that the caller can read
+func ReadableFields(ctx context.Context, ff types.ModuleFieldSet) (out types.ModuleFieldSet) {
+	readable := CanReadRecordValues(ctx, ff)
+	for _, f := range ff {
+		if readable[f.ID] {
+			out = append(out, f)
+		}
+	}
+
+	return
+}
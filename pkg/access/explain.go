@@ -0,0 +1,106 @@
+package access
+
+import (
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+)
+
+type (
+	// Trace of rules evaluation, how roles got the access to the operation on the resource
+	Trace struct {
+		Resource  permissions.Resource  `json:"resource"`
+		Operation permissions.Operation `json:"operation"`
+
+		// Evaluation steps, in order; evaluation stops at the first step
+		// with allow or deny
+		Steps []*Step `json:"steps"`
+
+		// Inherit when no rule matched, operation's default decides then
+		Access permissions.Access `json:"access"`
+
+		// Invalid resources are always denied
+		Invalid bool `json:"invalid,omitempty"`
+	}
+
+	// Step checks rules of the roles on the resource (or its wildcard)
+	Step struct {
+		Resource permissions.Resource `json:"resource"`
+		Everyone bool                 `json:"everyone"`
+
+		// Rules that matched, deny of any role wins over allow
+		Rules permissions.RuleSet `json:"rules"`
+
+		Access permissions.Access `json:"access"`
+	}
+)
+
+// Explain evaluates rules the same way as corteza's permission service
+// Check() does, recording every step
+//
+// Superuser and fallbacks of the operation are not part of the trace;
+// they are not rules.
+func Explain(rf rulesFinder, res permissions.Resource, op permissions.Operation, roles ...uint64) *Trace {
+	var (
+		t = &Trace{
+			Resource:  res,
+			Operation: op,
+			Steps:     []*Step{},
+			Access:    permissions.Inherit,
+		}
+
+		b = &batch{
+			roles: roles,
+			rules: map[permissions.Resource]map[uint64]permissions.Access{},
+		}
+	)
+
+	if !res.IsValid() {
+		t.Invalid = true
+		t.Access = permissions.Deny
+		return t
+	}
+
+	if rf != nil {
+		b.load(rf, op)
+	}
+
+	if len(roles) > 0 {
+		if t.Access = b.explainResource(t, res, false, roles...); t.Access != permissions.Inherit {
+			return t
+		}
+	}
+
+	t.Access = b.explainResource(t, res, true, permissions.EveryoneRoleID)
+	return t
+}
+
+// explainResource mirrors checkResource, adds steps to the trace
+func (b batch) explainResource(t *Trace, res permissions.Resource, everyone bool, roles ...uint64) permissions.Access {
+	if v := b.explainRoles(t, res, everyone, roles...); v != permissions.Inherit || !res.IsAppendable() {
+		return v
+	}
+
+	return b.explainRoles(t, res.AppendWildcard(), everyone, roles...)
+}
+
+func (b batch) explainRoles(t *Trace, res permissions.Resource, everyone bool, roles ...uint64) permissions.Access {
+	s := &Step{
+		Resource: res,
+		Everyone: everyone,
+		Rules:    permissions.RuleSet{},
+		Access:   b.checkRoles(res, roles...),
+	}
+
+	for _, roleID := range roles {
+		if a, ok := b.rules[res][roleID]; ok {
+			s.Rules = append(s.Rules, &permissions.Rule{
+				RoleID:    roleID,
+				Resource:  res,
+				Operation: t.Operation,
+				Access:    a,
+			})
+		}
+	}
+
+	t.Steps = append(t.Steps, s)
+	return s.Access
+}
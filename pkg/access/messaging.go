@@ -0,0 +1,40 @@
+package access
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+)
+
+// CanReadChannels checks if the caller can read channels, by channel ID
+//
+// Same as messaging's CanReadChannel, with a single rules pass for all
+// channels. Membership (fallback for private channels) must be preloaded.
+func CanReadChannels(ctx context.Context, cc types.ChannelSet) map[uint64]bool {
+	var (
+		b   *batch
+		out = make(map[uint64]bool, len(cc))
+	)
+
+	if service.DefaultPermissions != nil {
+		b = newBatch(ctx, service.DefaultPermissions, "read")
+	} else {
+		// Messaging is not running; nothing but super user can read channels
+		b = newBatch(ctx, nil, "read")
+	}
+
+	for _, ch := range cc {
+		ch := ch
+		out[ch.ID] = b.can(ch.PermissionResource(), func() permissions.Access {
+			if ch.IsValid() && (ch.Type == types.ChannelTypePublic || ch.Member != nil) {
+				return permissions.Allow
+			}
+
+			return permissions.Deny
+		})
+	}
+
+	return out
+}
@@ -0,0 +1,25 @@
+package access
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+// CanReadRoles checks if the caller can read roles, by role ID
+//
+// Same as system's CanReadRole, with a single rules pass for all roles.
+func CanReadRoles(ctx context.Context, rr types.RoleSet) map[uint64]bool {
+	var (
+		b   = newBatch(ctx, service.DefaultPermissions, "read")
+		out = make(map[uint64]bool, len(rr))
+	)
+
+	for _, r := range rr {
+		out[r.ID] = b.can(r.PermissionResource(), permissions.Allowed)
+	}
+
+	return out
+}
@@ -0,0 +1,131 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+	"github.com/crusttech/crust-server/pkg/egress"
+)
+
+var (
+	webhookClient = egress.Client(10 * time.Second)
+)
+
+// perform performs the action with the notification
+//
+// Context is the context of rule's owner.
+func (a Action) perform(ctx context.Context, n *Notification) error {
+	switch a.Kind {
+	case ActionEmail:
+		return a.email(ctx, n)
+	case ActionWebhook:
+		return a.webhook(ctx, n)
+	case ActionRecord:
+		return a.record(ctx, n)
+	}
+
+	return ErrInvalidAction.withStack()
+}
+
+func (a Action) email(ctx context.Context, n *Notification) error {
+	var (
+		m    = mail.New()
+		rcpt = append([]string{}, a.Recipients...)
+		body = fmt.Sprintf(
+			"Alert %q is %s.\n\nValue: %s\nCondition: %s %s\nAt: %s\n",
+			n.Rule, n.State, formatFloat(n.Value), n.Operator, formatFloat(n.Threshold), n.At.Format(time.RFC3339),
+		)
+	)
+
+	err := service.DefaultNotification.AttachEmailRecipients(auth.SetSuperUserContext(ctx), m, "To", rcpt...)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(string(n.State)), n.Rule)
+	if n.Repeated {
+		subject += " (still firing)"
+	}
+
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/plain", body)
+
+	return mail.Send(m)
+}
+
+// webhook posts the notification, signed with HMAC-SHA256 of the body
+func (a Action) webhook(ctx context.Context, n *Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if a.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(a.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Alert-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	rsp, err := webhookClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return errors.Errorf("webhook responded with unexpected status: %s", rsp.Status)
+	}
+
+	return nil
+}
+
+// record creates a record with notification's properties
+func (a Action) record(ctx context.Context, n *Notification) error {
+	var (
+		properties = map[string]string{
+			"rule":      n.Rule,
+			"state":     string(n.State),
+			"value":     formatFloat(n.Value),
+			"threshold": formatFloat(n.Threshold),
+			"at":        n.At.Format(time.RFC3339),
+		}
+
+		r = &types.Record{NamespaceID: n.NamespaceID, ModuleID: a.ModuleID}
+	)
+
+	for property, field := range a.Fields {
+		if v, ok := properties[property]; ok && field != "" {
+			r.Values = append(r.Values, &types.RecordValue{Name: field, Value: v})
+		}
+	}
+
+	_, err := service.DefaultRecord.With(ctx).Create(r)
+	return err
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
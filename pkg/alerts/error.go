@@ -0,0 +1,44 @@
+package alerts
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	alertsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNameRequired     = alertsError{"NameRequired", fault.Invalid}
+	ErrInvalidSource    = alertsError{"InvalidSource", fault.Invalid}
+	ErrInvalidOperator  = alertsError{"InvalidOperator", fault.Invalid}
+	ErrInvalidAggregate = alertsError{"InvalidAggregate", fault.Invalid}
+	ErrMetricRequired   = alertsError{"MetricRequired", fault.Invalid}
+	ErrInvalidSelector  = alertsError{"InvalidSelector", fault.Invalid}
+	ErrInvalidInterval  = alertsError{"InvalidInterval", fault.Invalid}
+	ErrNoTrigger        = alertsError{"NoTrigger", fault.Invalid}
+	ErrInvalidAction    = alertsError{"InvalidAction", fault.Invalid}
+	ErrInvalidRecipient = alertsError{"InvalidRecipient", fault.Invalid}
+	ErrNotFiring        = alertsError{"NotFiring", fault.Conflict}
+	ErrNoPermissions    = alertsError{"NoPermissions", fault.Forbidden}
+	ErrRuleNotFound     = alertsError{"RuleNotFound", fault.NotFound}
+)
+
+func (e alertsError) Error() string {
+	return e.String()
+}
+
+func (e alertsError) String() string {
+	return "crust.alerts." + e.name
+}
+
+func (e alertsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e alertsError) withStack() *fault.Error {
+	return fault.New(e)
+}
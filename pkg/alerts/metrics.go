@@ -0,0 +1,154 @@
+package alerts
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type (
+	// baselines keeps counter values from the previous evaluation of metric rules
+	baselines struct {
+		sync.Mutex
+		values map[baselineKey]float64
+	}
+
+	baselineKey struct {
+		ruleID   uint64
+		selector string
+	}
+)
+
+var (
+	gatherer prometheus.Gatherer = prometheus.DefaultGatherer
+
+	counters = &baselines{values: map[baselineKey]float64{}}
+)
+
+// metricValue returns value of the metric rule
+//
+// Returns false when there is no value yet: on the first evaluation
+// of counters, after counters were reset, or when dividing by zero.
+func metricValue(rule *Rule) (float64, bool, error) {
+	mff, err := gatherer.Gather()
+	if err != nil {
+		return 0, false, err
+	}
+
+	value, ok, err := selectorValue(mff, rule.ID, "metric", rule.Metric)
+	if err != nil || rule.Per == nil {
+		return value, ok, err
+	}
+
+	// Baseline of the divisor is updated even when there is no value yet
+	per, perOk, err := selectorValue(mff, rule.ID, "per", rule.Per)
+	if err != nil || !ok || !perOk || per == 0 {
+		return 0, false, err
+	}
+
+	return value / per, true, nil
+}
+
+func selectorValue(mff []*dto.MetricFamily, ruleID uint64, key string, s *Selector) (float64, bool, error) {
+	matchers, err := s.matchers()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var (
+		sum     float64
+		counter bool
+	)
+
+	for _, mf := range mff {
+		if mf.GetName() != s.Name {
+			continue
+		}
+
+		counter = mf.GetType() != dto.MetricType_GAUGE && mf.GetType() != dto.MetricType_UNTYPED
+
+		for _, m := range mf.GetMetric() {
+			if matches(m, matchers) {
+				sum += sample(mf.GetType(), m)
+			}
+		}
+	}
+
+	if !counter {
+		return sum, true, nil
+	}
+
+	return counters.increase(ruleID, key, sum)
+}
+
+// increase returns the increase of the counter since the previous call
+func (b *baselines) increase(ruleID uint64, key string, value float64) (float64, bool, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	k := baselineKey{ruleID, key}
+	prev, ok := b.values[k]
+	b.values[k] = value
+
+	if !ok || value < prev {
+		return 0, false, nil
+	}
+
+	return value - prev, true, nil
+}
+
+// forget removes baselines of the rule, selectors could have changed
+func (b *baselines) forget(ruleID uint64) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, key := range []string{"metric", "per"} {
+		delete(b.values, baselineKey{ruleID, key})
+	}
+}
+
+func (s Selector) matchers() (map[string]*regexp.Regexp, error) {
+	mm := map[string]*regexp.Regexp{}
+	for name, expr := range s.Labels {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, ErrInvalidSelector.withStack()
+		}
+
+		mm[name] = re
+	}
+
+	return mm, nil
+}
+
+func matches(m *dto.Metric, matchers map[string]*regexp.Regexp) bool {
+	values := map[string]string{}
+	for _, l := range m.GetLabel() {
+		values[l.GetName()] = l.GetValue()
+	}
+
+	for name, re := range matchers {
+		if !re.MatchString(values[name]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sample(t dto.MetricType, m *dto.Metric) float64 {
+	switch t {
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue()
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue()
+	case dto.MetricType_SUMMARY:
+		return float64(m.GetSummary().GetSampleCount())
+	case dto.MetricType_HISTOGRAM:
+		return float64(m.GetHistogram().GetSampleCount())
+	default:
+		return m.GetUntyped().GetValue()
+	}
+}
@@ -0,0 +1,66 @@
+package alerts
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200205000000.alerts",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_alert_rule (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  source             VARCHAR(16)     NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  aggregate          VARCHAR(255)    NOT NULL DEFAULT '',
+  filter             TEXT            NOT NULL,
+  metric             TEXT                NULL,
+  per                TEXT                NULL,
+  operator           VARCHAR(2)      NOT NULL,
+  threshold          DOUBLE          NOT NULL,
+  interval_sec       INT UNSIGNED    NOT NULL DEFAULT 0,
+  on_events          BOOLEAN         NOT NULL DEFAULT FALSE,
+  actions            TEXT            NOT NULL,
+  repeat_every       INT UNSIGNED    NOT NULL DEFAULT 0,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  state              VARCHAR(16)     NOT NULL DEFAULT 'ok',
+  value              DOUBLE              NULL DEFAULT NULL,
+  evaluated_at       DATETIME            NULL DEFAULT NULL,
+  next_evaluation_at DATETIME            NULL DEFAULT NULL,
+  fired_at           DATETIME            NULL DEFAULT NULL,
+  notified_at        DATETIME            NULL DEFAULT NULL,
+  acked_by           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  acked_at           DATETIME            NULL DEFAULT NULL,
+  silenced_until     DATETIME            NULL DEFAULT NULL,
+  last_error         TEXT            NOT NULL,
+
+  owned_by           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (rel_module),
+  INDEX (next_evaluation_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_alert_event (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_rule           BIGINT UNSIGNED NOT NULL,
+  state              VARCHAR(16)     NOT NULL,
+  value              DOUBLE              NULL DEFAULT NULL,
+  rel_user           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_rule, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,75 @@
+package alerts
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// record wraps record service and marks modules with changed records
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service; rules that are evaluated on record changes
+// are evaluated by the watcher shortly after module's records change
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	r, err := svc.RecordService.Create(r)
+	if err == nil {
+		changed.add(r.ModuleID)
+	}
+
+	return r, err
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	r, err := svc.RecordService.Update(r)
+	if err == nil {
+		changed.add(r.ModuleID)
+	}
+
+	return r, err
+}
+
+func (svc record) Organize(namespaceID, moduleID, recordID uint64, sortingField, sortingValue, sortingFilter, valueField, value string) error {
+	err := svc.RecordService.Organize(namespaceID, moduleID, recordID, sortingField, sortingValue, sortingFilter, valueField, value)
+	if err == nil {
+		changed.add(moduleID)
+	}
+
+	return err
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	if !changed.any() {
+		return svc.RecordService.DeleteByID(namespaceID, recordID)
+	}
+
+	// Record is loaded only to find its module
+	r, err := svc.RecordService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(namespaceID, recordID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.RecordService.DeleteByID(namespaceID, recordID); err == nil {
+		changed.add(r.ModuleID)
+	}
+
+	return err
+}
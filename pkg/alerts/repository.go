@@ -0,0 +1,220 @@
+package alerts
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_alert_rule"
+}
+
+func (r repository) tableEvent() string {
+	return "crust_compose_alert_event"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"name",
+			"source",
+			"rel_module",
+			"aggregate",
+			"filter",
+			"metric",
+			"per",
+			"operator",
+			"threshold",
+			"interval_sec",
+			"on_events",
+			"actions",
+			"repeat_every",
+			"enabled",
+			"state",
+			"value",
+			"evaluated_at",
+			"next_evaluation_at",
+			"fired_at",
+			"notified_at",
+			"acked_by",
+			"acked_at",
+			"silenced_until",
+			"last_error",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(namespaceID, ruleID uint64) (*Rule, error) {
+	var (
+		rule = &Rule{}
+		q    = r.query().Where(squirrel.Eq{"id": ruleID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, rule); err != nil {
+		return nil, err
+	} else if rule.ID == 0 {
+		return nil, ErrRuleNotFound.withStack().WithID("namespaceID", namespaceID).WithID("ruleID", ruleID)
+	}
+
+	return rule, nil
+}
+
+func (r repository) Find(namespaceID uint64) (set RuleSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindDue returns enabled rules that are scheduled to be evaluated
+func (r repository) FindDue(now time.Time) (set RuleSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"enabled": true}).
+		Where(squirrel.LtOrEq{"next_evaluation_at": now}).
+		OrderBy("next_evaluation_at")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindTriggered returns enabled rules that are evaluated when records of the modules change
+func (r repository) FindTriggered(moduleIDs ...uint64) (set RuleSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"enabled": true, "on_events": true, "source": SourceRecords, "rel_module": moduleIDs})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// TriggeredModules returns IDs of modules with rules that are evaluated on record changes
+func (r repository) TriggeredModules() (IDs []uint64, err error) {
+	q := squirrel.
+		Select("DISTINCT rel_module").
+		From(r.table()).
+		Where(squirrel.Eq{"enabled": true, "on_events": true, "source": SourceRecords, "deleted_at": nil})
+
+	return IDs, rh.FetchAll(r.db(), q, &IDs)
+}
+
+func (r repository) Create(rule *Rule) (*Rule, error) {
+	rule.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&rule.CreatedAt)
+
+	return rule, errors.WithStack(r.db().Insert(r.table(), rule))
+}
+
+func (r repository) Update(rule *Rule) (*Rule, error) {
+	rh.SetCurrentTimeRounded(&rule.UpdatedAt)
+
+	return rule, errors.WithStack(r.db().Replace(r.table(), rule))
+}
+
+// UpdateState stores outcome of the evaluation without touching rule's configuration
+//
+// Acknowledgement and silencing are changed by users at any time and are stored separately.
+func (r repository) UpdateState(rule *Rule) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{
+			"state":              rule.State,
+			"value":              rule.Value,
+			"evaluated_at":       rule.EvaluatedAt,
+			"next_evaluation_at": rule.NextEvalAt,
+			"fired_at":           rule.FiredAt,
+			"notified_at":        rule.NotifiedAt,
+			"last_error":         rule.LastError,
+		},
+		squirrel.Eq{"id": rule.ID},
+	)
+}
+
+// UpdateAck acknowledges the rule, zero user ID clears the acknowledgement
+func (r repository) UpdateAck(ruleID, userID uint64, at *time.Time) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"acked_by": userID, "acked_at": at},
+		squirrel.Eq{"id": ruleID},
+	)
+}
+
+// UpdateLastError stores error of the action, performed after the evaluation
+func (r repository) UpdateLastError(ruleID uint64, msg string) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"last_error": msg},
+		squirrel.Eq{"id": ruleID},
+	)
+}
+
+func (r repository) UpdateSilence(ruleID uint64, until *time.Time) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"silenced_until": until},
+		squirrel.Eq{"id": ruleID},
+	)
+}
+
+func (r repository) DeleteByID(namespaceID, ruleID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": ruleID, "rel_namespace": namespaceID},
+	)
+}
+
+// FindEvents returns the latest events of the rule, newest first
+func (r repository) FindEvents(ruleID uint64) (set EventSet, err error) {
+	q := squirrel.
+		Select("id", "rel_rule", "state", "value", "rel_user", "created_at").
+		From(r.tableEvent()).
+		Where(squirrel.Eq{"rel_rule": ruleID}).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(maxEvents)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateEvent(e *Event) (*Event, error) {
+	e.ID = factory.Sonyflake.NextID()
+
+	return e, errors.WithStack(r.db().Insert(r.tableEvent(), e))
+}
@@ -0,0 +1,96 @@
+package alerts
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts alert rule endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("AlertRule.List", func(r *http.Request) (interface{}, error) {
+		return DefaultAlerts.With(r.Context()).Find(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Post("/", rest.Handler("AlertRule.Create", func(r *http.Request) (interface{}, error) {
+		rule := &Rule{}
+		if err := rest.Decode(r, rule); err != nil {
+			return nil, err
+		}
+
+		rule.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultAlerts.With(r.Context()).Create(rule)
+	}))
+
+	r.Get("/{ruleID}", rest.Handler("AlertRule.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultAlerts.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "ruleID"),
+		)
+	}))
+
+	r.Put("/{ruleID}", rest.Handler("AlertRule.Update", func(r *http.Request) (interface{}, error) {
+		rule := &Rule{}
+		if err := rest.Decode(r, rule); err != nil {
+			return nil, err
+		}
+
+		rule.ID = rest.ParamUint64(r, "ruleID")
+		rule.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultAlerts.With(r.Context()).Update(rule)
+	}))
+
+	r.Delete("/{ruleID}", rest.Handler("AlertRule.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultAlerts.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "ruleID"),
+		)
+	}))
+
+	r.Post("/{ruleID}/ack", rest.Handler("AlertRule.Ack", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultAlerts.With(r.Context()).Ack(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "ruleID"),
+		)
+	}))
+
+	r.Put("/{ruleID}/silence", rest.Handler("AlertRule.Silence", func(r *http.Request) (interface{}, error) {
+		var payload struct {
+			Until time.Time `json:"until"`
+		}
+
+		if err := rest.Decode(r, &payload); err != nil {
+			return nil, err
+		}
+
+		return resputil.OK(), DefaultAlerts.With(r.Context()).Silence(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "ruleID"),
+			&payload.Until,
+		)
+	}))
+
+	r.Delete("/{ruleID}/silence", rest.Handler("AlertRule.Unsilence", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultAlerts.With(r.Context()).Silence(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "ruleID"),
+			nil,
+		)
+	}))
+
+	r.Get("/{ruleID}/events", rest.Handler("AlertRule.Events", func(r *http.Request) (interface{}, error) {
+		return DefaultAlerts.With(r.Context()).FindEvents(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "ruleID"),
+		)
+	}))
+}
@@ -0,0 +1,760 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/pkg/ql"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/outbox"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	alertsService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	AlertsService interface {
+		With(ctx context.Context) AlertsService
+
+		Find(namespaceID uint64) (RuleSet, error)
+		FindByID(namespaceID, ruleID uint64) (*Rule, error)
+		Create(*Rule) (*Rule, error)
+		Update(*Rule) (*Rule, error)
+		DeleteByID(namespaceID, ruleID uint64) error
+
+		Ack(namespaceID, ruleID uint64) error
+		Silence(namespaceID, ruleID uint64, until *time.Time) error
+		FindEvents(namespaceID, ruleID uint64) (EventSet, error)
+	}
+
+	// changes collects modules with changed records, rules
+	// of these modules are evaluated by the watcher
+	changes struct {
+		sync.Mutex
+
+		// Modules with rules that are evaluated on record changes
+		triggered map[uint64]bool
+
+		pending map[uint64]bool
+	}
+)
+
+var (
+	DefaultAlerts AlertsService
+
+	// now is used for scheduling and can be overridden
+	now = time.Now
+
+	changed = &changes{triggered: map[uint64]bool{}, pending: map[uint64]bool{}}
+)
+
+// Init initializes alerting service and starts evaluating rules in the background
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &alertsService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+	}
+
+	DefaultAlerts = svc.With(ctx)
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	outbox.Handle(topicNotification, deliver)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc alertsService) With(ctx context.Context) AlertsService {
+	return svc.with(ctx)
+}
+
+func (svc alertsService) with(ctx context.Context) *alertsService {
+	return &alertsService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc alertsService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc alertsService) Find(namespaceID uint64) (RuleSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.Find(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set {
+		set[i] = set[i].withoutSecrets()
+	}
+
+	return set, nil
+}
+
+func (svc alertsService) FindByID(namespaceID, ruleID uint64) (*Rule, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	rule, err := svc.repository.FindByID(namespaceID, ruleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule.withoutSecrets(), nil
+}
+
+// Create stores new rule, scheduled rules are evaluated with the next check
+func (svc alertsService) Create(in *Rule) (*Rule, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	rule := &Rule{
+		NamespaceID: in.NamespaceID,
+		Name:        in.Name,
+		Source:      in.Source,
+		ModuleID:    in.ModuleID,
+		Aggregate:   in.Aggregate,
+		Filter:      in.Filter,
+		Metric:      in.Metric,
+		Per:         in.Per,
+		Operator:    in.Operator,
+		Threshold:   in.Threshold,
+		Interval:    in.Interval,
+		OnEvents:    in.OnEvents,
+		Actions:     in.Actions,
+		RepeatEvery: in.RepeatEvery,
+		Enabled:     in.Enabled,
+		State:       StateOK,
+		OwnedBy:     auth.GetIdentityFromContext(svc.ctx).Identity(),
+	}
+
+	rule.NextEvalAt = rule.next(now())
+
+	rule, err := svc.repository.Create(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule.withoutSecrets(), nil
+}
+
+// Update modifies the rule
+//
+// State of the rule is kept. Secrets of webhooks are changed only
+// when a new one is given for the same URL.
+func (svc alertsService) Update(upd *Rule) (*Rule, error) {
+	rule, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	for i, a := range upd.Actions {
+		if a.Kind == ActionWebhook && a.Secret == "" && i < len(rule.Actions) && rule.Actions[i].URL == a.URL {
+			a.Secret = rule.Actions[i].Secret
+		}
+	}
+
+	rule.Name = upd.Name
+	rule.Source = upd.Source
+	rule.ModuleID = upd.ModuleID
+	rule.Aggregate = upd.Aggregate
+	rule.Filter = upd.Filter
+	rule.Metric = upd.Metric
+	rule.Per = upd.Per
+	rule.Operator = upd.Operator
+	rule.Threshold = upd.Threshold
+	rule.Interval = upd.Interval
+	rule.OnEvents = upd.OnEvents
+	rule.Actions = upd.Actions
+	rule.RepeatEvery = upd.RepeatEvery
+	rule.Enabled = upd.Enabled
+	rule.NextEvalAt = rule.next(now())
+
+	// Selectors could have changed, counting starts over
+	counters.forget(rule.ID)
+
+	if rule, err = svc.repository.Update(rule); err != nil {
+		return nil, err
+	}
+
+	return rule.withoutSecrets(), nil
+}
+
+func (svc alertsService) DeleteByID(namespaceID, ruleID uint64) error {
+	if _, err := svc.FindByID(namespaceID, ruleID); err != nil {
+		return err
+	}
+
+	counters.forget(ruleID)
+
+	return svc.repository.DeleteByID(namespaceID, ruleID)
+}
+
+// Ack acknowledges breached rule, it stops repeated notifications until the rule recovers
+func (svc alertsService) Ack(namespaceID, ruleID uint64) error {
+	rule, err := svc.FindByID(namespaceID, ruleID)
+	if err != nil {
+		return err
+	}
+
+	if rule.State != StateFiring {
+		return ErrNotFiring.withStack()
+	}
+
+	var (
+		userID = auth.GetIdentityFromContext(svc.ctx).Identity()
+		at     = now().Truncate(time.Second)
+	)
+
+	if err = svc.repository.UpdateAck(rule.ID, userID, &at); err != nil {
+		return err
+	}
+
+	_, err = svc.repository.CreateEvent(&Event{RuleID: rule.ID, State: StateAcked, Value: rule.Value, UserID: userID, CreatedAt: at})
+	return err
+}
+
+// Silence suppresses rule's notifications until the given time, nil removes the silence
+//
+// Rule is still evaluated and its state changes are recorded.
+func (svc alertsService) Silence(namespaceID, ruleID uint64, until *time.Time) error {
+	rule, err := svc.FindByID(namespaceID, ruleID)
+	if err != nil {
+		return err
+	}
+
+	at := now().Truncate(time.Second)
+	if until != nil && !until.After(at) {
+		until = nil
+	}
+
+	if err = svc.repository.UpdateSilence(rule.ID, until); err != nil || until == nil {
+		return err
+	}
+
+	_, err = svc.repository.CreateEvent(&Event{
+		RuleID:    rule.ID,
+		State:     StateSilenced,
+		Value:     rule.Value,
+		UserID:    auth.GetIdentityFromContext(svc.ctx).Identity(),
+		CreatedAt: at,
+	})
+
+	return err
+}
+
+// FindEvents returns history of rule's state changes
+func (svc alertsService) FindEvents(namespaceID, ruleID uint64) (EventSet, error) {
+	if _, err := svc.FindByID(namespaceID, ruleID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindEvents(ruleID)
+}
+
+// evaluate compares rule's value with the threshold, records state changes and notifies
+//
+// Rules without a value (see metricValue) keep their state.
+func (svc alertsService) evaluate(rule *Rule) error {
+	var (
+		at = now().Truncate(time.Second)
+
+		e        *Event
+		n        *Notification
+		clearAck bool
+	)
+
+	rule.EvaluatedAt, rule.NextEvalAt = &at, rule.next(at)
+	rule.LastError = ""
+
+	value, ok, err := svc.value(rule)
+	if err != nil {
+		rule.LastError = err.Error()
+	} else if ok {
+		rule.Value = &value
+		breached := rule.Operator.breached(value, rule.Threshold)
+
+		switch {
+		case breached && rule.State != StateFiring:
+			rule.State, rule.FiredAt = StateFiring, &at
+			e, n = transition(rule, at), svc.notification(rule, at, false)
+
+		case !breached && rule.State == StateFiring:
+			rule.State = StateOK
+			e, n = transition(rule, at), svc.notification(rule, at, false)
+			clearAck = rule.AckedBy > 0
+
+		case breached && rule.RepeatEvery > 0 && rule.AckedBy == 0 && rule.NotifiedAt != nil:
+			if at.Sub(*rule.NotifiedAt) >= time.Duration(rule.RepeatEvery)*time.Second {
+				n = svc.notification(rule, at, true)
+			}
+		}
+	}
+
+	if serr := svc.store(rule, e, clearAck, n); serr != nil {
+		return errors.Wrap(serr, "could not store alert rule state")
+	}
+
+	return err
+}
+
+// store saves outcome of the evaluation in a single transaction
+//
+// Rule's state, the state change event and notifications are stored
+// together; notifications are sent by the outbox dispatcher once the
+// transaction is committed, so a failed evaluation sends nothing and
+// a stored state change is always notified.
+func (svc alertsService) store(rule *Rule, e *Event, clearAck bool, n *Notification) error {
+	db := svc.repository.db()
+
+	return db.Transaction(func() error {
+		r := Repository(svc.ctx, db)
+
+		if err := r.UpdateState(rule); err != nil {
+			return err
+		}
+
+		if e != nil {
+			if _, err := r.CreateEvent(e); err != nil {
+				return err
+			}
+		}
+
+		if clearAck {
+			if err := r.UpdateAck(rule.ID, 0, nil); err != nil {
+				return err
+			}
+		}
+
+		if n == nil {
+			return nil
+		}
+
+		// Every action is delivered (and retried) on its own
+		for i := range rule.Actions {
+			if err := outbox.Write(db, topicNotification, &delivery{Notification: n, Action: i}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// transition returns event of rule's state change
+func transition(rule *Rule, at time.Time) *Event {
+	state := rule.State
+	if state == StateOK {
+		state = StateResolved
+	}
+
+	return &Event{RuleID: rule.ID, State: state, Value: rule.Value, CreatedAt: at}
+}
+
+// notification returns notification of rule's state, nil when the rule is silenced
+func (svc alertsService) notification(rule *Rule, at time.Time, repeated bool) *Notification {
+	if rule.silenced(at) {
+		return nil
+	}
+
+	n := &Notification{
+		RuleID:      rule.ID,
+		NamespaceID: rule.NamespaceID,
+		Rule:        rule.Name,
+		State:       rule.State,
+		Value:       *rule.Value,
+		Operator:    rule.Operator,
+		Threshold:   rule.Threshold,
+		Repeated:    repeated,
+		At:          at,
+	}
+
+	if n.State == StateOK {
+		n.State = StateResolved
+	}
+
+	rule.NotifiedAt = &at
+
+	return n
+}
+
+// deliver performs notification's action as the owner of the rule
+//
+// Notifications of deleted rules and removed actions are dropped;
+// failures are stored as rule's last error and retried by the outbox.
+func deliver(ctx context.Context, payload json.RawMessage) error {
+	d := &delivery{}
+	if err := json.Unmarshal(payload, d); err != nil {
+		return errors.WithStack(err)
+	}
+
+	repo := Repository(ctx, nil)
+
+	rule, err := repo.FindByID(d.NamespaceID, d.RuleID)
+	if fault.Is(err, ErrRuleNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if d.Action >= len(rule.Actions) {
+		return nil
+	}
+
+	ctx, err = runas.Compose(ctx, rule.OwnedBy)
+	if err == nil {
+		a := rule.Actions[d.Action]
+		err = errors.Wrapf(a.perform(ctx, d.Notification), "%s action failed", a.Kind)
+	}
+
+	if err != nil {
+		if serr := repo.UpdateLastError(rule.ID, err.Error()); serr != nil {
+			return serr
+		}
+	}
+
+	return err
+}
+
+// value returns current value of the rule, as seen by rule's owner
+func (svc alertsService) value(rule *Rule) (float64, bool, error) {
+	if rule.Source == SourceMetric {
+		return metricValue(rule)
+	}
+
+	ctx, err := runas.Compose(svc.ctx, rule.OwnedBy)
+	if err != nil {
+		return 0, false, err
+	}
+
+	metrics, column := "", "count"
+	if rule.Aggregate != "" {
+		metrics, column = rule.Aggregate+" AS value", "value"
+	}
+
+	out, err := service.DefaultRecord.With(ctx).Report(rule.NamespaceID, rule.ModuleID, metrics, "", rule.Filter)
+	if err != nil {
+		return 0, false, err
+	}
+
+	rows, ok := out.([]map[string]interface{})
+	if !ok {
+		return 0, false, errors.Errorf("unexpected report result %T", out)
+	} else if len(rows) == 0 {
+		return 0, true, nil
+	}
+
+	switch v := rows[0][column].(type) {
+	case nil:
+		// Aggregate of no records
+		return 0, true, nil
+	case float64:
+		return v, true, nil
+	case int64:
+		return float64(v), true, nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil, errors.WithStack(err)
+	default:
+		return 0, false, errors.Errorf("unexpected value %T", v)
+	}
+}
+
+// next returns time of the next scheduled evaluation, nil for rules evaluated only on record changes
+func (r Rule) next(after time.Time) *time.Time {
+	if r.Interval == 0 {
+		return nil
+	}
+
+	t := after.Add(time.Duration(r.Interval) * time.Second)
+	return &t
+}
+
+func (svc alertsService) validate(rule *Rule) error {
+	if err := svc.canManage(rule.NamespaceID); err != nil {
+		return err
+	}
+
+	if rule.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	switch rule.Source {
+	case SourceRecords:
+		if _, err := svc.module.FindByID(rule.NamespaceID, rule.ModuleID); err != nil {
+			return err
+		}
+
+		if rule.Aggregate != "" {
+			cc, err := ql.NewParser().ParseColumns(rule.Aggregate)
+			if err != nil || len(cc) != 1 || cc[0].Alias != "" {
+				return ErrInvalidAggregate.withStack()
+			}
+		}
+
+		rule.Metric, rule.Per = nil, nil
+
+	case SourceMetric:
+		// Metrics are not limited to namespace's data
+		if !isAdmin(svc.ctx) {
+			return ErrNoPermissions.withStack()
+		}
+
+		if rule.Metric == nil || rule.Metric.Name == "" {
+			return ErrMetricRequired.withStack()
+		}
+
+		for _, s := range []*Selector{rule.Metric, rule.Per} {
+			if s == nil {
+				continue
+			} else if s.Name == "" {
+				return ErrMetricRequired.withStack()
+			} else if _, err := s.matchers(); err != nil {
+				return err
+			}
+		}
+
+		rule.ModuleID, rule.Aggregate, rule.Filter, rule.OnEvents = 0, "", "", false
+
+	default:
+		return ErrInvalidSource.withStack()
+	}
+
+	if !rule.Operator.IsValid() {
+		return ErrInvalidOperator.withStack()
+	}
+
+	if rule.Interval > 0 && rule.Interval < minInterval {
+		return ErrInvalidInterval.withStack()
+	} else if rule.Interval == 0 && !rule.OnEvents {
+		return ErrNoTrigger.withStack()
+	}
+
+	for _, a := range rule.Actions {
+		if err := svc.validateAction(rule.NamespaceID, a); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc alertsService) validateAction(namespaceID uint64, a *Action) error {
+	switch a.Kind {
+	case ActionEmail:
+		if len(a.Recipients) == 0 {
+			return ErrInvalidAction.withStack()
+		}
+
+		for _, rcpt := range a.Recipients {
+			if !validRecipient(rcpt) {
+				return ErrInvalidRecipient.withStack()
+			}
+		}
+
+	case ActionWebhook:
+		u, err := url.Parse(a.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrInvalidAction.withStack()
+		}
+
+	case ActionRecord:
+		if _, err := svc.module.FindByID(namespaceID, a.ModuleID); err != nil {
+			return err
+		}
+
+	default:
+		return ErrInvalidAction.withStack()
+	}
+
+	return nil
+}
+
+func (svc alertsService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// watch evaluates scheduled rules and rules of modules with changed records
+func (svc alertsService) watch(ctx context.Context) {
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			ctx := auth.SetSuperUserContext(ctx)
+			repo := Repository(ctx, nil)
+
+			if IDs, err := repo.TriggeredModules(); err != nil {
+				svc.logger.Error("could not load modules with triggered alert rules", zap.Error(err))
+			} else {
+				changed.setTriggered(IDs)
+			}
+
+			var set RuleSet
+
+			if IDs := changed.flush(); len(IDs) > 0 {
+				triggered, err := repo.FindTriggered(IDs...)
+				if err != nil {
+					svc.logger.Error("could not load triggered alert rules", zap.Error(err))
+				}
+
+				set = append(set, triggered...)
+			}
+
+			due, err := repo.FindDue(now())
+			if err != nil {
+				svc.logger.Error("could not load due alert rules", zap.Error(err))
+			}
+
+			seen := map[uint64]bool{}
+			for _, rule := range append(set, due...) {
+				if seen[rule.ID] {
+					continue
+				}
+
+				seen[rule.ID] = true
+				if err = svc.with(ctx).evaluate(rule); err != nil {
+					svc.logger.Error("could not evaluate alert rule", zap.Uint64("ruleID", rule.ID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func (c *changes) setTriggered(IDs []uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.triggered = map[uint64]bool{}
+	for _, ID := range IDs {
+		c.triggered[ID] = true
+	}
+}
+
+// any checks if there are any modules with triggered rules
+func (c *changes) any() bool {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.triggered) > 0
+}
+
+// add marks module's records as changed, if module has triggered rules
+func (c *changes) add(moduleID uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.triggered[moduleID] {
+		c.pending[moduleID] = true
+	}
+}
+
+// flush returns modules with changed records since the previous flush
+func (c *changes) flush() (IDs []uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	for ID := range c.pending {
+		IDs = append(IDs, ID)
+	}
+
+	c.pending = map[uint64]bool{}
+	return
+}
+
+// isAdmin checks if the user is a super user or a member of the administrators role
+func isAdmin(ctx context.Context) bool {
+	i := auth.GetIdentityFromContext(ctx)
+	if auth.IsSuperUser(i) {
+		return true
+	}
+
+	for _, roleID := range i.Roles() {
+		if roleID == permissions.AdminsRoleID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validRecipient checks if recipient is a user ID or an email address
+func validRecipient(rcpt string) bool {
+	rcpt = strings.TrimSpace(rcpt)
+	if ID, err := strconv.ParseUint(rcpt, 10, 64); err == nil {
+		return ID > 0
+	}
+
+	if i := strings.Index(rcpt, " "); i > -1 {
+		// "<email> <name>"
+		rcpt = rcpt[:i]
+	}
+
+	return mail.IsValidAddress(rcpt)
+}
@@ -0,0 +1,244 @@
+package alerts
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Rule checks a condition and notifies when it is breached and when it recovers
+	//
+	// Records rules compare an aggregate of module's records (count by default)
+	// with the threshold; metric rules do the same with a system metric.
+	Rule struct {
+		ID          uint64 `json:"ruleID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Name        string `json:"name" db:"name"`
+
+		Source Source `json:"source" db:"source"`
+
+		// Records source: aggregate ("SUM(amount)", defaults to count)
+		// of module's records, matching the filter
+		ModuleID  uint64 `json:"moduleID,string,omitempty" db:"rel_module"`
+		Aggregate string `json:"aggregate,omitempty" db:"aggregate"`
+		Filter    string `json:"filter,omitempty" db:"filter"`
+
+		// Metric source: sum of matching metric's samples, optionally divided by
+		// the sum of another metric's samples ("error rate")
+		Metric *Selector `json:"metric,omitempty" db:"metric"`
+		Per    *Selector `json:"per,omitempty" db:"per"`
+
+		Operator  Operator `json:"operator" db:"operator"`
+		Threshold float64  `json:"threshold" db:"threshold"`
+
+		// Rule is evaluated every interval (seconds) and/or when module's records change
+		Interval uint `json:"interval" db:"interval_sec"`
+		OnEvents bool `json:"onEvents" db:"on_events"`
+
+		Actions Actions `json:"actions" db:"actions"`
+
+		// Breached rule notifies again after this many seconds unless acknowledged
+		RepeatEvery uint `json:"repeatEvery" db:"repeat_every"`
+
+		Enabled bool `json:"enabled" db:"enabled"`
+
+		State         State      `json:"state" db:"state"`
+		Value         *float64   `json:"value,omitempty" db:"value"`
+		EvaluatedAt   *time.Time `json:"evaluatedAt,omitempty" db:"evaluated_at"`
+		NextEvalAt    *time.Time `json:"nextEvaluationAt,omitempty" db:"next_evaluation_at"`
+		FiredAt       *time.Time `json:"firedAt,omitempty" db:"fired_at"`
+		NotifiedAt    *time.Time `json:"notifiedAt,omitempty" db:"notified_at"`
+		AckedBy       uint64     `json:"ackedBy,string,omitempty" db:"acked_by"`
+		AckedAt       *time.Time `json:"ackedAt,omitempty" db:"acked_at"`
+		SilencedUntil *time.Time `json:"silencedUntil,omitempty" db:"silenced_until"`
+		LastError     string     `json:"lastError,omitempty" db:"last_error"`
+
+		// Records are aggregated and created in the name of the owner
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	RuleSet []*Rule
+
+	// Selector selects samples of a metric
+	//
+	// Label values are regular expressions and must match the whole value.
+	// Counters (and histogram & summary counts) are evaluated as the increase
+	// since the previous evaluation; metrics are of this server instance only.
+	Selector struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels,omitempty"`
+	}
+
+	// Action is performed when the rule is breached or recovers
+	Action struct {
+		Kind ActionKind `json:"kind"`
+
+		// Email: user IDs or email addresses
+		Recipients []string `json:"recipients,omitempty"`
+
+		// Webhook: notification is posted as JSON, signed with the secret
+		URL    string `json:"url,omitempty"`
+		Secret string `json:"secret,omitempty"`
+
+		// Record: record is created in the module (and runs module's automation scripts);
+		// fields maps notification properties (rule, state, value, threshold, at) to field names
+		ModuleID uint64            `json:"moduleID,string,omitempty"`
+		Fields   map[string]string `json:"fields,omitempty"`
+	}
+
+	Actions []*Action
+
+	// Event is a change of rule's state
+	Event struct {
+		ID     uint64   `json:"eventID,string" db:"id"`
+		RuleID uint64   `json:"ruleID,string" db:"rel_rule"`
+		State  State    `json:"state" db:"state"`
+		Value  *float64 `json:"value,omitempty" db:"value"`
+
+		// Who acknowledged or silenced the rule
+		UserID uint64 `json:"userID,string,omitempty" db:"rel_user"`
+
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	EventSet []*Event
+
+	// Notification is sent by actions
+	Notification struct {
+		RuleID      uint64    `json:"ruleID,string"`
+		NamespaceID uint64    `json:"namespaceID,string"`
+		Rule        string    `json:"rule"`
+		State       State     `json:"state"`
+		Value       float64   `json:"value"`
+		Operator    Operator  `json:"operator"`
+		Threshold   float64   `json:"threshold"`
+		Repeated    bool      `json:"repeated"`
+		At          time.Time `json:"at"`
+	}
+
+	// delivery is outbox's payload, notification for one of rule's actions
+	delivery struct {
+		*Notification
+		Action int `json:"action"`
+	}
+
+	Source     string
+	Operator   string
+	ActionKind string
+	State      string
+)
+
+const (
+	SourceRecords Source = "records"
+	SourceMetric  Source = "metric"
+
+	ActionEmail   ActionKind = "email"
+	ActionWebhook ActionKind = "webhook"
+	ActionRecord  ActionKind = "record"
+
+	StateOK       State = "ok"
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+
+	// Only recorded in the event history
+	StateAcked    State = "acknowledged"
+	StateSilenced State = "silenced"
+
+	topicNotification = "alerts.notification"
+
+	// How often scheduled rules are checked and record changes are processed
+	watchInterval = 5 * time.Second
+
+	minInterval = 60
+
+	maxEvents = 100
+)
+
+var (
+	operators = map[Operator]func(a, b float64) bool{
+		">":  func(a, b float64) bool { return a > b },
+		">=": func(a, b float64) bool { return a >= b },
+		"<":  func(a, b float64) bool { return a < b },
+		"<=": func(a, b float64) bool { return a <= b },
+		"==": func(a, b float64) bool { return a == b },
+		"!=": func(a, b float64) bool { return a != b },
+	}
+)
+
+func (s Source) IsValid() bool {
+	return s == SourceRecords || s == SourceMetric
+}
+
+func (o Operator) IsValid() bool {
+	return operators[o] != nil
+}
+
+func (o Operator) breached(value, threshold float64) bool {
+	return operators[o](value, threshold)
+}
+
+func (k ActionKind) IsValid() bool {
+	return k == ActionEmail || k == ActionWebhook || k == ActionRecord
+}
+
+// silenced checks if rule's notifications are silenced at the given time
+func (r Rule) silenced(at time.Time) bool {
+	return r.SilencedUntil != nil && r.SilencedUntil.After(at)
+}
+
+func (r Rule) withoutSecrets() *Rule {
+	aa := make(Actions, len(r.Actions))
+	for i, a := range r.Actions {
+		c := *a
+		c.Secret = ""
+		aa[i] = &c
+	}
+
+	r.Actions = aa
+	return &r
+}
+
+func (s *Selector) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(s)
+}
+
+func (s *Selector) Scan(value interface{}) error {
+	if b, ok := value.([]byte); ok {
+		if err := json.Unmarshal(b, s); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Selector", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (aa Actions) Value() (driver.Value, error) {
+	if aa == nil {
+		aa = Actions{}
+	}
+
+	return json.Marshal(aa)
+}
+
+func (aa *Actions) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*aa = Actions{}
+	case []byte:
+		if err := json.Unmarshal(b, aa); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Actions", string(b))
+		}
+	}
+
+	return nil
+}
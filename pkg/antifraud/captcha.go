@@ -0,0 +1,87 @@
+package antifraud
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Provider verifies CAPTCHA response tokens
+	Provider interface {
+		Verify(ctx context.Context, token, remoteIP string) (*Verification, error)
+	}
+
+	Verification struct {
+		Success bool `json:"success"`
+
+		// Providers with invisible challenges (reCAPTCHA v3) score how likely
+		// the client is human, from 0 (bot) to 1
+		Score *float64 `json:"score,omitempty"`
+
+		Errors []string `json:"error-codes,omitempty"`
+	}
+
+	// siteverify verifies tokens with the siteverify API,
+	// shared by hCaptcha and reCAPTCHA
+	siteverify struct {
+		url    string
+		secret string
+	}
+)
+
+var (
+	providers = map[string]string{
+		"hcaptcha":  "https://hcaptcha.com/siteverify",
+		"recaptcha": "https://www.google.com/recaptcha/api/siteverify",
+	}
+
+	captchaClient = &http.Client{Timeout: 10 * time.Second}
+)
+
+// NewProvider returns CAPTCHA provider by its name
+func NewProvider(name, secret string) (Provider, error) {
+	if u, ok := providers[strings.ToLower(name)]; ok && secret != "" {
+		return &siteverify{url: u, secret: secret}, nil
+	}
+
+	return nil, ErrUnknownProvider.withStack()
+}
+
+func (p siteverify) Verify(ctx context.Context, token, remoteIP string) (*Verification, error) {
+	form := url.Values{
+		"secret":   {p.secret},
+		"response": {token},
+		"remoteip": {remoteIP},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rsp, err := captchaClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected CAPTCHA verification status: %s", rsp.Status)
+	}
+
+	v := &Verification{}
+	if err = json.NewDecoder(rsp.Body).Decode(v); err != nil {
+		return nil, errors.Wrap(err, "could not decode CAPTCHA verification")
+	}
+
+	return v, nil
+}
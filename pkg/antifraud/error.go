@@ -0,0 +1,36 @@
+package antifraud
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	antifraudError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrCaptchaRequired    = antifraudError{"CaptchaRequired", fault.Forbidden}
+	ErrCaptchaFailed      = antifraudError{"CaptchaFailed", fault.Forbidden}
+	ErrCaptchaUnavailable = antifraudError{"CaptchaUnavailable", fault.Unavailable}
+	ErrUnknownProvider    = antifraudError{"UnknownProvider", fault.Invalid}
+	ErrBlocked            = antifraudError{"Blocked", fault.Forbidden}
+)
+
+func (e antifraudError) Error() string {
+	return e.String()
+}
+
+func (e antifraudError) String() string {
+	return "crust.antifraud." + e.name
+}
+
+func (e antifraudError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e antifraudError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,84 @@
+package antifraud
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/crusttech/crust-server/pkg/seclog"
+)
+
+type (
+	// Scorer estimates risk of the attempt, from 0 (safe) to 1
+	Scorer func(ctx context.Context, a *Attempt) (float64, error)
+)
+
+var (
+	scorers = map[string]Scorer{
+		"velocity":   velocity,
+		"user-agent": userAgent,
+		"captcha":    captchaScore,
+	}
+)
+
+// RegisterScorer adds (or replaces) a risk scoring hook
+//
+// Attempt's risk is the highest score of all hooks.
+// Must be called before the server starts.
+func RegisterScorer(name string, s Scorer) {
+	scorers[name] = s
+}
+
+// score runs all hooks and returns the highest score with scores of all hooks
+//
+// Hooks that fail do not contribute to the risk
+func score(ctx context.Context, a *Attempt) (risk float64, scores map[string]float64, errs map[string]error) {
+	scores = map[string]float64{}
+	errs = map[string]error{}
+
+	for name, s := range scorers {
+		r, err := s(ctx, a)
+		if err != nil {
+			errs[name] = err
+			continue
+		}
+
+		r = math.Max(0, math.Min(1, r))
+		scores[name] = r
+		risk = math.Max(risk, r)
+	}
+
+	return
+}
+
+// velocity scores how many attempts came from the same address recently
+func velocity(ctx context.Context, a *Attempt) (float64, error) {
+	since := time.Now().Add(-velocityWindow)
+
+	count, err := seclog.DefaultSecLog.With(ctx).Count(seclog.EventFilter{
+		Kind:     eventKind,
+		Action:   string(a.Endpoint),
+		RemoteIP: a.RemoteIP,
+		Since:    &since,
+	})
+
+	return float64(count) / velocityLimit, err
+}
+
+// userAgent scores requests without user agent, usually sent by scripts
+func userAgent(ctx context.Context, a *Attempt) (float64, error) {
+	if a.UserAgent == "" {
+		return 0.5, nil
+	}
+
+	return 0, nil
+}
+
+// captchaScore converts provider's score (likelihood of a human) into risk
+func captchaScore(ctx context.Context, a *Attempt) (float64, error) {
+	if a.Captcha == nil || a.Captcha.Score == nil {
+		return 0, nil
+	}
+
+	return 1 - *a.Captcha.Score, nil
+}
@@ -0,0 +1,183 @@
+package antifraud
+
+import (
+	"context"
+	"net/http"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/crusttech/crust-server/pkg/rest"
+	"github.com/crusttech/crust-server/pkg/seclog"
+)
+
+type (
+	antifraudService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		settings settingsGetter
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	AntifraudService interface {
+		With(ctx context.Context) AntifraudService
+
+		Check(Endpoint, *http.Request) error
+	}
+)
+
+var (
+	DefaultAntifraud AntifraudService
+)
+
+// Init initializes antifraud service
+//
+// Must be called after security event log is initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &antifraudService{
+		logger:   log,
+		settings: service.DefaultSettings,
+	}
+
+	DefaultAntifraud = svc.With(ctx)
+
+	return nil
+}
+
+func (svc antifraudService) With(ctx context.Context) AntifraudService {
+	return &antifraudService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		settings: svc.settings,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc antifraudService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Middleware checks requests to protected endpoints before they are handled
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e := match(r.URL.Path); e != "" && r.Method != http.MethodOptions && DefaultAntifraud != nil {
+			if err := DefaultAntifraud.With(r.Context()).Check(e, r); err != nil {
+				rest.Error(w, r, err)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Check verifies CAPTCHA (when required) and scores the request to the endpoint
+//
+// Every checked request is recorded on the security event log, with its score.
+func (svc antifraudService) Check(endpoint Endpoint, r *http.Request) (err error) {
+	cfg := svc.config()
+
+	ec := cfg.Endpoints[endpoint]
+	if ec == nil {
+		return nil
+	}
+
+	var (
+		e = seclog.FromRequest(r, eventKind, string(endpoint))
+		a = &Attempt{
+			Endpoint:  endpoint,
+			RemoteIP:  e.RemoteIP,
+			UserAgent: e.UserAgent,
+			Request:   r,
+		}
+	)
+
+	e.Meta = seclog.Meta{}
+
+	if ec.Captcha {
+		if a.Captcha, err = svc.captcha(cfg, a); err != nil {
+			e.Meta["captcha"] = err.Error()
+		} else {
+			e.Meta["captcha"] = a.Captcha
+		}
+	}
+
+	if err == nil {
+		risk, scores, errs := score(svc.ctx, a)
+		for name, err := range errs {
+			svc.log().Error("could not score request", zap.String("scorer", name), zap.Error(err))
+		}
+
+		e.Risk, e.Meta["scores"] = risk, scores
+
+		if ec.MaxRisk > 0 && risk > ec.MaxRisk {
+			err = ErrBlocked.withStack()
+		}
+	}
+
+	if e.Outcome = outcomeAllowed; err != nil {
+		e.Outcome = outcomeBlocked
+	}
+
+	if rErr := seclog.DefaultSecLog.With(svc.ctx).Record(e); rErr != nil {
+		svc.log().Error("could not record security event", zap.Error(rErr))
+	}
+
+	return err
+}
+
+// captcha verifies response token from the request
+//
+// When provider is not configured or not available, requests are rejected
+func (svc antifraudService) captcha(cfg *Config, a *Attempt) (*Verification, error) {
+	token := a.Request.Header.Get(captchaHeader)
+	if token == "" {
+		token = a.Request.URL.Query().Get(captchaParam)
+	}
+
+	if token == "" {
+		return nil, ErrCaptchaRequired.withStack()
+	}
+
+	p, err := NewProvider(cfg.Provider, cfg.Secret)
+	if err != nil {
+		svc.log().Error("CAPTCHA provider not configured", zap.String("provider", cfg.Provider))
+		return nil, ErrCaptchaUnavailable.withStack()
+	}
+
+	v, err := p.Verify(svc.ctx, token, a.RemoteIP)
+	if err != nil {
+		svc.log().Error("could not verify CAPTCHA", zap.Error(err))
+		return nil, ErrCaptchaUnavailable.withStack()
+	}
+
+	if !v.Success {
+		return v, ErrCaptchaFailed.withStack()
+	}
+
+	return v, nil
+}
+
+// config loads antifraud configuration from system settings
+func (svc antifraudService) config() *Config {
+	cfg := &Config{}
+
+	v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingConfig, 0)
+	if err != nil {
+		svc.log().Error("could not load antifraud settings", zap.Error(err))
+	} else if v != nil {
+		if err = v.Value.Unmarshal(cfg); err != nil {
+			svc.log().Error("could not decode antifraud settings", zap.Error(err))
+		}
+	}
+
+	return cfg
+}
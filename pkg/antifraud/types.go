@@ -0,0 +1,100 @@
+package antifraud
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// Config is stored in system settings, under settingConfig
+	//
+	// Example:
+	//   {
+	//     "provider": "hcaptcha",
+	//     "secret": "0x...",
+	//     "endpoints": {
+	//       "signup": { "captcha": true, "maxRisk": 0.8 },
+	//       "sink": { "maxRisk": 0.9 }
+	//     }
+	//   }
+	Config struct {
+		// CAPTCHA provider (hcaptcha, recaptcha) and its secret key
+		Provider string `json:"provider"`
+		Secret   string `json:"secret"`
+
+		// Only configured endpoints are checked
+		Endpoints map[Endpoint]*EndpointConfig `json:"endpoints"`
+	}
+
+	EndpointConfig struct {
+		// Require solved CAPTCHA
+		Captcha bool `json:"captcha"`
+
+		// Requests with a higher risk score are blocked; 0 disables blocking
+		MaxRisk float64 `json:"maxRisk"`
+	}
+
+	// Endpoint is a public endpoint that can be protected
+	Endpoint string
+
+	// Attempt is a request to a protected endpoint
+	Attempt struct {
+		Endpoint  Endpoint
+		RemoteIP  string
+		UserAgent string
+
+		// Result of CAPTCHA verification, when CAPTCHA is required
+		Captcha *Verification
+
+		Request *http.Request
+	}
+)
+
+const (
+	EndpointSignup        Endpoint = "signup"
+	EndpointPasswordReset Endpoint = "password-reset"
+	EndpointSink          Endpoint = "sink"
+
+	settingConfig = "crust.antifraud"
+
+	// CAPTCHA response token is sent in a header; sinks (often plain
+	// HTML forms) can send it as a query string parameter
+	captchaHeader = "X-Captcha-Token"
+	captchaParam  = "captcha-token"
+
+	// Kind of security log events
+	eventKind = "antifraud"
+
+	outcomeAllowed = "allowed"
+	outcomeBlocked = "blocked"
+
+	// Velocity scorer: attempts from the same address within the window
+	// that make the risk score reach 1
+	velocityWindow = 10 * time.Minute
+	velocityLimit  = 20
+)
+
+var (
+	// Paths (relative to system app's root) of protected endpoints
+	endpoints = map[Endpoint]string{
+		EndpointSignup:        "/auth/internal/signup",
+		EndpointPasswordReset: "/auth/internal/request-password-reset",
+		EndpointSink:          "/sink",
+	}
+)
+
+// match returns protected endpoint the path points to
+//
+// System routes are prefixed when running as a monolith, so only path suffix is compared
+func match(path string) Endpoint {
+	path = strings.TrimSuffix(path, "/")
+
+	for e, p := range endpoints {
+		if strings.HasSuffix(path, p) {
+			return e
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,63 @@
+package antivirus
+
+import (
+	"context"
+	"io"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// attachment wraps attachment service and scans uploaded files
+	attachment struct {
+		messagingService.AttachmentService
+		ctx context.Context
+	}
+)
+
+// Attachment decorates attachment service with scanning of uploads and
+// blocking downloads of quarantined files
+func Attachment(as messagingService.AttachmentService) messagingService.AttachmentService {
+	return &attachment{AttachmentService: as, ctx: context.Background()}
+}
+
+func (svc attachment) With(ctx context.Context) messagingService.AttachmentService {
+	return &attachment{
+		AttachmentService: svc.AttachmentService.With(ctx),
+		ctx:               ctx,
+	}
+}
+
+func (svc attachment) Create(name string, size int64, fh io.ReadSeeker, channelID, replyTo uint64) (*messagingTypes.Attachment, error) {
+	av := defaultAntivirus.with(svc.ctx)
+
+	v, err := av.scan(name, fh)
+	if err != nil {
+		return nil, err
+	}
+
+	att, err := svc.AttachmentService.Create(name, size, fh, channelID, replyTo)
+	if err != nil {
+		return nil, err
+	}
+
+	av.record(att, v)
+	return att, nil
+}
+
+func (svc attachment) OpenOriginal(att *messagingTypes.Attachment) (io.ReadSeeker, error) {
+	if err := defaultAntivirus.with(svc.ctx).canOpen(att); err != nil {
+		return nil, err
+	}
+
+	return svc.AttachmentService.OpenOriginal(att)
+}
+
+func (svc attachment) OpenPreview(att *messagingTypes.Attachment) (io.ReadSeeker, error) {
+	if err := defaultAntivirus.with(svc.ctx).canOpen(att); err != nil {
+		return nil, err
+	}
+
+	return svc.AttachmentService.OpenPreview(att)
+}
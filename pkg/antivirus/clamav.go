@@ -0,0 +1,105 @@
+package antivirus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// clamav scans files with clamd's INSTREAM command
+	clamav struct {
+		addr    string
+		timeout time.Duration
+	}
+)
+
+const (
+	// Size of chunks the file is streamed in
+	clamavChunk = 64 * 1024
+)
+
+// ClamAV returns scanner that streams files to clamd on the TCP address
+//
+// Files larger than clamd's StreamMaxLength can not be scanned.
+func ClamAV(addr string, timeout time.Duration) Scanner {
+	return &clamav{addr: addr, timeout: timeout}
+}
+
+func (s clamav) Scan(ctx context.Context, name string, r io.Reader) (*Verdict, error) {
+	var d net.Dialer
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not connect to clamd")
+	}
+
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err = s.stream(conn, r); err != nil {
+		return nil, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "can not read clamd reply")
+	}
+
+	return parseClamAV(reply)
+}
+
+// stream sends the file in length prefixed chunks, terminated by an empty one
+func (clamav) stream(w io.Writer, r io.Reader) error {
+	if _, err := w.Write([]byte("zINSTREAM\x00")); err != nil {
+		return errors.Wrap(err, "can not start clamd scan")
+	}
+
+	buf := make([]byte, 4+clamavChunk)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return errors.Wrap(werr, "can not stream file to clamd")
+			}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		} else if err != nil {
+			return errors.Wrap(err, "can not read scanned file")
+		}
+	}
+
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return errors.Wrap(err, "can not finish clamd scan")
+}
+
+// parseClamAV reads verdict from the reply, "stream: OK" or
+// "stream: <threat> FOUND"; everything else is an error
+func parseClamAV(reply string) (*Verdict, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &Verdict{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Verdict{Infected: true, Threat: strings.TrimSuffix(reply, " FOUND")}, nil
+	default:
+		return nil, errors.Errorf("clamd could not scan the file: %s", reply)
+	}
+}
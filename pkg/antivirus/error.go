@@ -0,0 +1,40 @@
+package antivirus
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	antivirusError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrUnknownScanner        = antivirusError{"UnknownScanner", fault.Invalid}
+	ErrUnknownMode           = antivirusError{"UnknownMode", fault.Invalid}
+	ErrScannerUnavailable    = antivirusError{"ScannerUnavailable", fault.Unavailable}
+	ErrScannerNotConfigured  = antivirusError{"ScannerNotConfigured", fault.Unavailable}
+	ErrMalwareDetected       = antivirusError{"MalwareDetected", fault.Invalid}
+	ErrAttachmentQuarantined = antivirusError{"AttachmentQuarantined", fault.Forbidden}
+	ErrScanNotFound          = antivirusError{"ScanNotFound", fault.NotFound}
+	ErrNotQuarantined        = antivirusError{"NotQuarantined", fault.Conflict}
+	ErrNoPermissions         = antivirusError{"NoPermissions", fault.Forbidden}
+)
+
+func (e antivirusError) Error() string {
+	return e.String()
+}
+
+func (e antivirusError) String() string {
+	return "crust.antivirus." + e.name
+}
+
+func (e antivirusError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e antivirusError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,167 @@
+package antivirus
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// icap scans files with RESPMOD requests to an ICAP service
+	icap struct {
+		url     *url.URL
+		timeout time.Duration
+	}
+)
+
+const (
+	icapDefaultPort = "1344"
+)
+
+var (
+	// Headers that ICAP antivirus services report threats with
+	icapThreatHeaders = []string{"X-Infection-Found", "X-Violations-Found", "X-Virus-ID"}
+
+	// Threat name in X-Infection-Found, "Type=0; Resolution=2; Threat=Eicar-Test-Signature;"
+	icapThreat = regexp.MustCompile(`Threat=([^;]+)`)
+)
+
+// ICAP returns scanner that sends files to the ICAP service,
+// e.g. icap://localhost:1344/avscan
+//
+// Files are sent as HTTP responses to be modified; unmodified (204) are
+// clean, replaced ones (blocked by the service) are infected.
+func ICAP(rawURL string, timeout time.Duration) (Scanner, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "icap" || u.Host == "" {
+		return nil, ErrScannerNotConfigured.withStack().WithMessage("invalid ICAP service URL " + rawURL)
+	}
+
+	return &icap{url: u, timeout: timeout}, nil
+}
+
+func (s icap) Scan(ctx context.Context, name string, r io.Reader) (*Verdict, error) {
+	var (
+		d    net.Dialer
+		host = s.url.Host
+	)
+
+	if s.url.Port() == "" {
+		host = net.JoinHostPort(s.url.Hostname(), icapDefaultPort)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not connect to ICAP service")
+	}
+
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	w := bufio.NewWriter(conn)
+	if err = s.request(w, name, r); err != nil {
+		return nil, err
+	}
+
+	return parseICAP(bufio.NewReader(conn))
+}
+
+// request writes RESPMOD with encapsulated request and response headers
+// and the file as chunked response body
+func (s icap) request(w *bufio.Writer, name string, r io.Reader) error {
+	var (
+		reqHdr = "GET /" + url.PathEscape(name) + " HTTP/1.1\r\nHost: crust\r\n\r\n"
+		resHdr = "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nTransfer-Encoding: chunked\r\n\r\n"
+	)
+
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", s.url.String())
+	fmt.Fprintf(w, "Host: %s\r\n", s.url.Host)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: req-hdr=0, res-hdr=%d, res-body=%d\r\n\r\n", len(reqHdr), len(reqHdr)+len(resHdr))
+	w.WriteString(reqHdr)
+	w.WriteString(resHdr)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			w.WriteString("\r\n")
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrap(err, "can not read scanned file")
+		}
+	}
+
+	w.WriteString("0\r\n\r\n")
+	return errors.Wrap(w.Flush(), "can not send file to ICAP service")
+}
+
+// parseICAP reads verdict from status and headers of the ICAP response
+func parseICAP(r *bufio.Reader) (*Verdict, error) {
+	tp := textproto.NewReader(r)
+
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, errors.Wrap(err, "can not read ICAP response")
+	}
+
+	// ICAP/1.0 204 No Content
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		return nil, errors.Errorf("invalid ICAP response: %s", line)
+	}
+
+	status, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, errors.Errorf("invalid ICAP response: %s", line)
+	}
+
+	hdr, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "can not read ICAP response headers")
+	}
+
+	switch {
+	case status == 204:
+		return &Verdict{}, nil
+	case status != 200:
+		return nil, errors.Errorf("ICAP service could not scan the file: %s", line)
+	}
+
+	for _, h := range icapThreatHeaders {
+		v := hdr.Get(h)
+		if v == "" {
+			continue
+		}
+
+		if m := icapThreat.FindStringSubmatch(v); m != nil {
+			v = m[1]
+		}
+
+		return &Verdict{Infected: true, Threat: strings.TrimSpace(v)}, nil
+	}
+
+	// Response was modified without saying why, e.g. replaced with a block page
+	return &Verdict{Infected: true, Threat: "unknown"}, nil
+}
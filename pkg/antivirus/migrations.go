@@ -0,0 +1,29 @@
+package antivirus
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200228000000.antivirus",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_attachment_scan (
+  rel_attachment   BIGINT UNSIGNED NOT NULL,
+  name             TEXT            CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL,
+  status           VARCHAR(16)     NOT NULL,
+  threat           VARCHAR(255)    NOT NULL DEFAULT '',
+  scanner          VARCHAR(16)     NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  scanned_at       DATETIME        NOT NULL,
+  released_by      BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  released_at      DATETIME            NULL,
+
+  PRIMARY KEY (rel_attachment),
+  INDEX lookup_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
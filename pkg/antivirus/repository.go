@@ -0,0 +1,105 @@
+package antivirus
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_attachment_scan"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"rel_attachment",
+			"name",
+			"status",
+			"threat",
+			"scanner",
+			"rel_user",
+			"scanned_at",
+			"released_by",
+			"released_at",
+		).
+		From(r.table())
+}
+
+// FindByAttachmentID returns scan of the attachment, nil when it was not scanned
+func (r repository) FindByAttachmentID(attachmentID uint64) (*Scan, error) {
+	var (
+		s = &Scan{}
+		q = r.query().Where(squirrel.Eq{"rel_attachment": attachmentID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.AttachmentID == 0 {
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+// Find returns scans that match the filter, latest first
+func (r repository) Find(f Filter) (set ScanSet, err error) {
+	q := r.query().
+		OrderBy("scanned_at DESC").
+		Limit(maxListed)
+
+	if f.Status != "" {
+		q = q.Where(squirrel.Eq{"status": f.Status})
+	}
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(s *Scan) error {
+	return errors.WithStack(r.db().Insert(r.table(), s))
+}
+
+// Release marks quarantined file as harmless; returns false when it is not quarantined
+func (r repository) Release(s *Scan) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.table()+" SET status = ?, released_by = ?, released_at = ? WHERE rel_attachment = ? AND status = ?",
+		StatusReleased, s.ReleasedBy, s.ReleasedAt, s.AttachmentID, StatusQuarantined,
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
@@ -0,0 +1,51 @@
+package antivirus
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts endpoints for reviewing scanned attachments
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Latest scans, quarantined by default: ?status=clean|quarantined|released|unscanned
+	r.Get("/", rest.Handler("Antivirus.List", func(r *http.Request) (interface{}, error) {
+		svc, err := antivirusService(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return svc.Find(Filter{Status: Status(r.URL.Query().Get("status"))})
+	}))
+
+	r.Get("/{attachmentID}", rest.Handler("Antivirus.Read", func(r *http.Request) (interface{}, error) {
+		svc, err := antivirusService(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return svc.FindByID(rest.ParamUint64(r, "attachmentID"))
+	}))
+
+	r.Post("/{attachmentID}/release", rest.Handler("Antivirus.Release", func(r *http.Request) (interface{}, error) {
+		svc, err := antivirusService(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return svc.Release(rest.ParamUint64(r, "attachmentID"))
+	}))
+}
+
+func antivirusService(r *http.Request) (AntivirusService, error) {
+	if !Enabled() {
+		return nil, ErrScannerNotConfigured.withStack()
+	}
+
+	return DefaultAntivirus.With(r.Context()), nil
+}
@@ -0,0 +1,280 @@
+package antivirus
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	accessController interface {
+		CanManageSettings(context.Context) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac      accessController
+		scanner Scanner
+
+		// Name of the scanner, recorded with scans
+		kind string
+
+		mode Mode
+
+		// Accept uploads when the scanner is not reachable
+		failOpen bool
+
+		repository *repository
+	}
+
+	AntivirusService interface {
+		With(ctx context.Context) AntivirusService
+
+		Find(Filter) (ScanSet, error)
+		FindByID(attachmentID uint64) (*Scan, error)
+		Release(attachmentID uint64) (*Scan, error)
+	}
+)
+
+var (
+	// DefaultAntivirus is nil when uploads are not scanned
+	DefaultAntivirus AntivirusService
+
+	// used by service decorators
+	defaultAntivirus *service
+
+	// now is used for scan times and can be overridden
+	now = time.Now
+)
+
+// Init initializes scanning of uploaded attachments and decorates
+// attachment service with it
+//
+// Scanner is selected with ANTIVIRUS_SCANNER: "clamav" (clamd on
+// ANTIVIRUS_CLAMAV_ADDR) or "icap" (service on ANTIVIRUS_ICAP_URL).
+// Uploads are not scanned when it is empty.
+//
+// ANTIVIRUS_MODE decides what happens with infected uploads, "reject"
+// refuses them and "flag" keeps them in quarantine. Uploads are refused
+// when the scanner can not be reached, unless ANTIVIRUS_FAIL_OPEN is true.
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	var (
+		scanner Scanner
+		kind    = options.EnvString("", "ANTIVIRUS_SCANNER", "")
+		mode    = Mode(options.EnvString("", "ANTIVIRUS_MODE", string(ModeReject)))
+		timeout = options.EnvDuration("", "ANTIVIRUS_TIMEOUT", 30*time.Second)
+		err     error
+	)
+
+	switch kind {
+	case "":
+		log.Debug("attachments are not scanned")
+		return nil
+	case ScannerClamAV:
+		scanner = ClamAV(options.EnvString("", "ANTIVIRUS_CLAMAV_ADDR", "localhost:3310"), timeout)
+	case ScannerICAP:
+		if scanner, err = ICAP(options.EnvString("", "ANTIVIRUS_ICAP_URL", ""), timeout); err != nil {
+			return err
+		}
+	default:
+		return ErrUnknownScanner.withStack().WithMessage("unknown antivirus scanner " + kind)
+	}
+
+	if !mode.IsValid() {
+		return ErrUnknownMode.withStack().WithMessage("unknown antivirus mode " + string(mode))
+	}
+
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		scanner:  scanner,
+		kind:     kind,
+		mode:     mode,
+		failOpen: options.EnvBool("", "ANTIVIRUS_FAIL_OPEN", false),
+	}
+
+	DefaultAntivirus = svc.With(ctx)
+	defaultAntivirus = svc.with(ctx)
+
+	messagingService.DefaultAttachment = Attachment(messagingService.DefaultAttachment)
+
+	log.Info("attachments are scanned", zap.String("scanner", kind), zap.String("mode", string(mode)))
+	return nil
+}
+
+// Enabled reports if uploads are scanned
+func Enabled() bool {
+	return DefaultAntivirus != nil
+}
+
+func (svc service) With(ctx context.Context) AntivirusService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		scanner:  svc.scanner,
+		kind:     svc.kind,
+		mode:     svc.mode,
+		failOpen: svc.failOpen,
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns scans of uploaded attachments, quarantined by default
+func (svc service) Find(f Filter) (ScanSet, error) {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if f.Status == "" {
+		f.Status = StatusQuarantined
+	}
+
+	ss, err := svc.repository.Find(f)
+	if err != nil {
+		return nil, err
+	} else if ss == nil {
+		return ScanSet{}, nil
+	}
+
+	return ss, nil
+}
+
+// FindByID returns scan of the attachment to its uploader and to administrators
+func (svc service) FindByID(attachmentID uint64) (*Scan, error) {
+	s, err := svc.repository.FindByAttachmentID(attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil || (s.UserID != auth.GetIdentityFromContext(svc.ctx).Identity() && !svc.ac.CanManageSettings(svc.ctx)) {
+		return nil, ErrScanNotFound.withStack().WithID("attachmentID", attachmentID)
+	}
+
+	return s, nil
+}
+
+// Release allows download of the quarantined attachment that was
+// flagged by mistake
+func (svc service) Release(attachmentID uint64) (*Scan, error) {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	s, err := svc.repository.FindByAttachmentID(attachmentID)
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		return nil, ErrScanNotFound.withStack().WithID("attachmentID", attachmentID)
+	}
+
+	t := now().Truncate(time.Second)
+	s.ReleasedBy, s.ReleasedAt = auth.GetIdentityFromContext(svc.ctx).Identity(), &t
+
+	if ok, err := svc.repository.Release(s); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrNotQuarantined.withStack().WithID("attachmentID", attachmentID)
+	}
+
+	s.Status = StatusReleased
+	svc.log(zap.Uint64("attachmentID", s.AttachmentID), zap.String("threat", s.Threat)).Info("quarantined attachment released")
+	return s, nil
+}
+
+// scan checks the upload and rewinds it; verdict is nil when the
+// scanner could not be reached and uploads are accepted anyway
+func (svc service) scan(name string, fh io.ReadSeeker) (*Verdict, error) {
+	v, err := svc.scanner.Scan(svc.ctx, name, fh)
+
+	if _, serr := fh.Seek(0, io.SeekStart); serr != nil {
+		return nil, serr
+	}
+
+	if err != nil {
+		log := svc.log(zap.String("name", name), zap.Error(err))
+
+		if svc.failOpen {
+			log.Warn("could not scan attachment, accepting it unscanned")
+			return nil, nil
+		}
+
+		log.Error("could not scan attachment")
+		return nil, ErrScannerUnavailable.withStack()
+	}
+
+	if v.Infected {
+		svc.log(zap.String("name", name), zap.String("threat", v.Threat), zap.String("mode", string(svc.mode))).
+			Warn("malware detected in uploaded attachment")
+
+		if svc.mode == ModeReject {
+			return nil, ErrMalwareDetected.withStack()
+		}
+	}
+
+	return v, nil
+}
+
+// record stores outcome of the scan of the created attachment
+func (svc service) record(att *messagingTypes.Attachment, v *Verdict) {
+	s := &Scan{
+		AttachmentID: att.ID,
+		Name:         att.Name,
+		Status:       StatusUnscanned,
+		Scanner:      svc.kind,
+		UserID:       auth.GetIdentityFromContext(svc.ctx).Identity(),
+		ScannedAt:    now().Truncate(time.Second),
+	}
+
+	switch {
+	case v == nil:
+	case v.Infected:
+		s.Status, s.Threat = StatusQuarantined, v.Threat
+	default:
+		s.Status = StatusClean
+	}
+
+	if err := svc.repository.Create(s); err != nil {
+		svc.log(zap.Uint64("attachmentID", att.ID), zap.Error(err)).Error("could not store attachment scan")
+	}
+}
+
+// canOpen checks that the attachment is not quarantined; administrators
+// can open quarantined attachments to review them
+func (svc service) canOpen(att *messagingTypes.Attachment) error {
+	s, err := svc.repository.FindByAttachmentID(att.ID)
+	if err != nil {
+		return err
+	}
+
+	if s != nil && s.Status == StatusQuarantined && !svc.ac.CanManageSettings(svc.ctx) {
+		return ErrAttachmentQuarantined.withStack().WithID("attachmentID", att.ID)
+	}
+
+	return nil
+}
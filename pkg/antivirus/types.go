@@ -0,0 +1,76 @@
+package antivirus
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type (
+	// Scanner checks content of uploaded files
+	//
+	// Scanners return an error only when the file could not be scanned,
+	// infected files are reported with the verdict.
+	Scanner interface {
+		Scan(ctx context.Context, name string, r io.Reader) (*Verdict, error)
+	}
+
+	// Verdict of the scanner, threat is the name of the detected malware
+	Verdict struct {
+		Infected bool
+		Threat   string
+	}
+
+	// Scan is the outcome of scanning the attachment
+	Scan struct {
+		AttachmentID uint64 `json:"attachmentID,string" db:"rel_attachment"`
+		Name         string `json:"name" db:"name"`
+		Status       Status `json:"status" db:"status"`
+		Threat       string `json:"threat,omitempty" db:"threat"`
+		Scanner      string `json:"scanner" db:"scanner"`
+
+		UserID    uint64    `json:"userID,string" db:"rel_user"`
+		ScannedAt time.Time `json:"scannedAt" db:"scanned_at"`
+
+		// Quarantined file that was found to be harmless
+		ReleasedBy uint64     `json:"releasedBy,string,omitempty" db:"released_by"`
+		ReleasedAt *time.Time `json:"releasedAt,omitempty" db:"released_at"`
+	}
+
+	ScanSet []*Scan
+
+	Filter struct {
+		Status Status `json:"status"`
+	}
+
+	Status string
+
+	// Mode is what happens with infected uploads
+	Mode string
+)
+
+const (
+	StatusClean       Status = "clean"
+	StatusQuarantined Status = "quarantined"
+	StatusReleased    Status = "released"
+
+	// Scanner was not reachable and uploads are accepted without scanning
+	StatusUnscanned Status = "unscanned"
+
+	// Infected uploads are refused
+	ModeReject Mode = "reject"
+
+	// Infected uploads are stored, but can not be downloaded until
+	// they are released
+	ModeFlag Mode = "flag"
+
+	ScannerClamAV = "clamav"
+	ScannerICAP   = "icap"
+
+	// Limit of quarantined files that are listed
+	maxListed = 500
+)
+
+func (m Mode) IsValid() bool {
+	return m == ModeReject || m == ModeFlag
+}
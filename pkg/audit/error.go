@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	auditError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrActionRequired = auditError{"ActionRequired", fault.Invalid}
+	ErrNoPermissions  = auditError{"NoPermissions", fault.Forbidden}
+)
+
+func (e auditError) Error() string {
+	return e.String()
+}
+
+func (e auditError) String() string {
+	return "crust.audit." + e.name
+}
+
+func (e auditError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e auditError) withStack() *fault.Error {
+	return fault.New(e)
+}
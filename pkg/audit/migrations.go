@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200219000000.audit",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_audit_log (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_actor        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  action           VARCHAR(64)     NOT NULL,
+  resource         VARCHAR(128)    NOT NULL,
+  request_id       VARCHAR(64)     NOT NULL DEFAULT '',
+  meta             JSON            NOT NULL,
+
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  INDEX (rel_actor, created_at),
+  INDEX (resource, created_at),
+  INDEX (action, created_at),
+  INDEX (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_audit_log"
+}
+
+func (r repository) query(f EntryFilter) squirrel.SelectBuilder {
+	q := squirrel.
+		Select(
+			"id",
+			"rel_actor",
+			"action",
+			"resource",
+			"request_id",
+			"meta",
+			"created_at",
+		).
+		From(r.table())
+
+	if f.ActorID > 0 {
+		q = q.Where(squirrel.Eq{"rel_actor": f.ActorID})
+	}
+
+	if f.Action != "" {
+		q = q.Where(squirrel.Eq{"action": f.Action})
+	}
+
+	if strings.HasSuffix(f.Resource, "*") {
+		q = q.Where(squirrel.Like{"resource": strings.TrimSuffix(f.Resource, "*") + "%"})
+	} else if f.Resource != "" {
+		q = q.Where(squirrel.Eq{"resource": f.Resource})
+	}
+
+	if f.From != nil {
+		q = q.Where(squirrel.GtOrEq{"created_at": f.From})
+	}
+
+	if f.To != nil {
+		q = q.Where(squirrel.Lt{"created_at": f.To})
+	}
+
+	return q
+}
+
+// Find returns a page of entries that match the filter, newest first
+func (r repository) Find(f EntryFilter) (set EntrySet, _ EntryFilter, err error) {
+	q := r.query(f)
+
+	if f.Count, err = rh.Count(r.db(), q); err != nil || f.Count == 0 {
+		return nil, f, err
+	}
+
+	q = q.OrderBy("created_at DESC", "id DESC")
+
+	return set, f, rh.FetchPaged(r.db(), q, f.Page, f.PerPage, &set)
+}
+
+func (r repository) Create(e *Entry) (*Entry, error) {
+	e.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	return e, errors.WithStack(r.db().Insert(r.table(), e))
+}
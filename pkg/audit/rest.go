@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts audit log endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?actorID=&action=role.update&resource=system:role:*&from=2020-01-01T00:00:00Z&to=&page=1&perPage=50
+	r.Get("/", rest.Handler("Audit.List", func(r *http.Request) (interface{}, error) {
+		return DefaultAudit.With(r.Context()).Find(EntryFilter{
+			ActorID:  rest.QueryUint64(r, "actorID"),
+			Action:   r.URL.Query().Get("action"),
+			Resource: r.URL.Query().Get("resource"),
+			From:     rest.QueryTime(r, "from"),
+			To:       rest.QueryTime(r, "to"),
+
+			PageFilter: rh.Paging(rest.QueryUint(r, "page"), rest.QueryUint(r, "perPage")),
+		})
+	}))
+}
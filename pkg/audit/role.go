@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// roleService wraps role service and records its changes
+	roleService struct {
+		service.RoleService
+		ctx context.Context
+	}
+)
+
+// Role decorates role service with audit log
+//
+// Only successful changes are recorded.
+func Role(rs service.RoleService) service.RoleService {
+	return &roleService{RoleService: rs, ctx: context.Background()}
+}
+
+func (svc roleService) With(ctx context.Context) service.RoleService {
+	return &roleService{
+		RoleService: svc.RoleService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc roleService) Create(role *types.Role) (*types.Role, error) {
+	r, err := svc.RoleService.Create(role)
+	if err == nil {
+		svc.record(ActionRoleCreate, r.ID, Meta{"name": r.Name, "handle": r.Handle})
+	}
+
+	return r, err
+}
+
+func (svc roleService) Update(role *types.Role) (*types.Role, error) {
+	old, _ := svc.RoleService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(role.ID)
+
+	r, err := svc.RoleService.Update(role)
+	if err == nil {
+		meta := Meta{}
+		if old != nil {
+			meta["changes"] = changes(
+				"name", old.Name, r.Name,
+				"handle", old.Handle, r.Handle,
+			)
+		}
+
+		svc.record(ActionRoleUpdate, r.ID, meta)
+	}
+
+	return r, err
+}
+
+func (svc roleService) Merge(roleID, targetRoleID uint64) error {
+	err := svc.RoleService.Merge(roleID, targetRoleID)
+	return svc.recordErr(ActionRoleMerge, roleID, Meta{"targetRoleID": id(targetRoleID)}, err)
+}
+
+func (svc roleService) Move(roleID, organisationID uint64) error {
+	err := svc.RoleService.Move(roleID, organisationID)
+	return svc.recordErr(ActionRoleMove, roleID, Meta{"organisationID": id(organisationID)}, err)
+}
+
+func (svc roleService) Archive(roleID uint64) error {
+	return svc.recordErr(ActionRoleArchive, roleID, nil, svc.RoleService.Archive(roleID))
+}
+
+func (svc roleService) Unarchive(roleID uint64) error {
+	return svc.recordErr(ActionRoleUnarchive, roleID, nil, svc.RoleService.Unarchive(roleID))
+}
+
+func (svc roleService) Delete(roleID uint64) error {
+	return svc.recordErr(ActionRoleDelete, roleID, nil, svc.RoleService.Delete(roleID))
+}
+
+func (svc roleService) Undelete(roleID uint64) error {
+	return svc.recordErr(ActionRoleUndelete, roleID, nil, svc.RoleService.Undelete(roleID))
+}
+
+func (svc roleService) MemberAdd(roleID, userID uint64) error {
+	err := svc.RoleService.MemberAdd(roleID, userID)
+	return svc.recordErr(ActionRoleMemberAdd, roleID, Meta{"userID": id(userID)}, err)
+}
+
+func (svc roleService) MemberRemove(roleID, userID uint64) error {
+	err := svc.RoleService.MemberRemove(roleID, userID)
+	return svc.recordErr(ActionRoleMemberRemove, roleID, Meta{"userID": id(userID)}, err)
+}
+
+func (svc roleService) record(action string, roleID uint64, meta Meta) {
+	defaultAudit.with(svc.ctx).record(action, roleResource(roleID), meta)
+}
+
+// recordErr records the change when there was no error
+func (svc roleService) recordErr(action string, roleID uint64, meta Meta, err error) error {
+	if err == nil {
+		svc.record(action, roleID, meta)
+	}
+
+	return err
+}
+
+func roleResource(roleID uint64) string {
+	return types.RolePermissionResource.AppendID(roleID).String()
+}
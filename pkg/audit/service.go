@@ -0,0 +1,132 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	auditService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageSettings(context.Context) bool
+	}
+
+	AuditService interface {
+		With(ctx context.Context) AuditService
+
+		Record(*Entry) error
+		Find(EntryFilter) (*Payload, error)
+	}
+)
+
+var (
+	DefaultAudit AuditService
+
+	// used by service decorators
+	defaultAudit *auditService
+)
+
+// Init initializes audit log and records changes of roles and users
+//
+// Must be called after system services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := (&auditService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+	}).with(ctx)
+
+	DefaultAudit = svc
+	defaultAudit = svc
+
+	service.DefaultRole = Role(service.DefaultRole)
+	service.DefaultUser = User(service.DefaultUser)
+
+	return nil
+}
+
+func (svc auditService) With(ctx context.Context) AuditService {
+	return svc.with(ctx)
+}
+
+func (svc auditService) with(ctx context.Context) *auditService {
+	return &auditService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc auditService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Record stores the entry
+//
+// Actor and request are taken from the context; changes made with
+// superuser context (by the system) are recorded without the actor.
+func (svc auditService) Record(e *Entry) error {
+	if e.Action == "" {
+		return ErrActionRequired.withStack()
+	}
+
+	if i := auth.GetIdentityFromContext(svc.ctx); !auth.IsSuperUser(i) {
+		e.ActorID = i.Identity()
+	}
+
+	e.RequestID = middleware.GetReqID(svc.ctx)
+
+	_, err := svc.repository.Create(e)
+	return err
+}
+
+// record stores the entry of the change that was already made,
+// errors are logged only
+func (svc auditService) record(action, resource string, meta Meta) {
+	err := svc.Record(&Entry{Action: action, Resource: resource, Meta: meta})
+	if err != nil {
+		svc.log(zap.String("action", action), zap.String("resource", resource), zap.Error(err)).
+			Error("could not record audit log entry")
+	}
+}
+
+// Find returns a page of entries that match the filter
+//
+// Audit log is available to those that can manage settings
+func (svc auditService) Find(f EntryFilter) (*Payload, error) {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	switch {
+	case f.PerPage == 0:
+		f.PerPage = defaultPerPage
+	case f.PerPage > maxPerPage:
+		f.PerPage = maxPerPage
+	}
+
+	set, f, err := svc.repository.Find(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Payload{Filter: f, Set: set}, nil
+}
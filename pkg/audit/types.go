@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	// Entry records a change made by the actor
+	Entry struct {
+		ID uint64 `json:"entryID,string" db:"id"`
+
+		// User that made the change, zero for changes made by the system
+		ActorID uint64 `json:"actorID,string" db:"rel_actor"`
+
+		// What was done and to what, for example role.update of "system:role:123"
+		Action   string `json:"action" db:"action"`
+		Resource string `json:"resource" db:"resource"`
+
+		// Request the change was made in, when made through the API
+		RequestID string `json:"requestID,omitempty" db:"request_id"`
+
+		Meta Meta `json:"meta,omitempty" db:"meta"`
+
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	EntrySet []*Entry
+
+	EntryFilter struct {
+		ActorID uint64 `json:"actorID,string"`
+		Action  string `json:"action"`
+
+		// Resource or resources of the type when ending with "*" (system:role:*)
+		Resource string `json:"resource"`
+
+		From *time.Time `json:"from"`
+		To   *time.Time `json:"to"`
+
+		rh.PageFilter
+	}
+
+	// Payload is a page of entries with the filter and total count
+	Payload struct {
+		Filter EntryFilter `json:"filter"`
+		Set    EntrySet    `json:"set"`
+	}
+
+	// Meta holds additional, action specific, details
+	Meta map[string]interface{}
+)
+
+const (
+	ActionRoleCreate       = "role.create"
+	ActionRoleUpdate       = "role.update"
+	ActionRoleMerge        = "role.merge"
+	ActionRoleMove         = "role.move"
+	ActionRoleArchive      = "role.archive"
+	ActionRoleUnarchive    = "role.unarchive"
+	ActionRoleDelete       = "role.delete"
+	ActionRoleUndelete     = "role.undelete"
+	ActionRoleMemberAdd    = "role.memberAdd"
+	ActionRoleMemberRemove = "role.memberRemove"
+
+	ActionUserCreate      = "user.create"
+	ActionUserUpdate      = "user.update"
+	ActionUserDelete      = "user.delete"
+	ActionUserUndelete    = "user.undelete"
+	ActionUserSuspend     = "user.suspend"
+	ActionUserUnsuspend   = "user.unsuspend"
+	ActionUserSetPassword = "user.setPassword"
+
+	defaultPerPage = 50
+	maxPerPage     = 500
+)
+
+func (m Meta) Value() (driver.Value, error) {
+	if m == nil {
+		m = Meta{}
+	}
+
+	return json.Marshal(m)
+}
+
+func (m *Meta) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*m = Meta{}
+	case []byte:
+		if err := json.Unmarshal(b, m); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Meta", string(b))
+		}
+	}
+
+	return nil
+}
+
+// id formats ID the same way as IDs are sent to clients
+func id(ID uint64) string {
+	return strconv.FormatUint(ID, 10)
+}
+
+// changes returns names of changed attributes, from name, old & new value triples
+func changes(vv ...string) []string {
+	out := []string{}
+	for i := 0; i+2 < len(vv); i += 3 {
+		if vv[i+1] != vv[i+2] {
+			out = append(out, vv[i])
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"context"
+	"io"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// userService wraps user service and records its changes
+	userService struct {
+		service.UserService
+		ctx context.Context
+	}
+)
+
+// User decorates user service with audit log
+//
+// Only successful changes are recorded; updates are recorded with names
+// of changed attributes, not their values.
+func User(us service.UserService) service.UserService {
+	return &userService{UserService: us, ctx: context.Background()}
+}
+
+func (svc userService) With(ctx context.Context) service.UserService {
+	return &userService{
+		UserService: svc.UserService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc userService) Create(input *types.User) (*types.User, error) {
+	return svc.created(svc.UserService.Create(input))
+}
+
+func (svc userService) CreateWithAvatar(input *types.User, avatar io.Reader) (*types.User, error) {
+	return svc.created(svc.UserService.CreateWithAvatar(input, avatar))
+}
+
+func (svc userService) Update(mod *types.User) (*types.User, error) {
+	old := svc.old(mod.ID)
+	u, err := svc.UserService.Update(mod)
+	return svc.updated(old, u, err)
+}
+
+func (svc userService) UpdateWithAvatar(mod *types.User, avatar io.Reader) (*types.User, error) {
+	old := svc.old(mod.ID)
+	u, err := svc.UserService.UpdateWithAvatar(mod, avatar)
+	return svc.updated(old, u, err)
+}
+
+func (svc userService) Delete(userID uint64) error {
+	return svc.recordErr(ActionUserDelete, userID, svc.UserService.Delete(userID))
+}
+
+func (svc userService) Undelete(userID uint64) error {
+	return svc.recordErr(ActionUserUndelete, userID, svc.UserService.Undelete(userID))
+}
+
+func (svc userService) Suspend(userID uint64) error {
+	return svc.recordErr(ActionUserSuspend, userID, svc.UserService.Suspend(userID))
+}
+
+func (svc userService) Unsuspend(userID uint64) error {
+	return svc.recordErr(ActionUserUnsuspend, userID, svc.UserService.Unsuspend(userID))
+}
+
+func (svc userService) SetPassword(userID uint64, password string) error {
+	return svc.recordErr(ActionUserSetPassword, userID, svc.UserService.SetPassword(userID, password))
+}
+
+// old returns the user as it is before the update
+func (svc userService) old(userID uint64) *types.User {
+	u, _ := svc.UserService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(userID)
+	return u
+}
+
+func (svc userService) created(u *types.User, err error) (*types.User, error) {
+	if err == nil {
+		svc.record(ActionUserCreate, u.ID, nil)
+	}
+
+	return u, err
+}
+
+func (svc userService) updated(old *types.User, u *types.User, err error) (*types.User, error) {
+	if err == nil {
+		meta := Meta{}
+		if old != nil {
+			meta["changes"] = changes(
+				"email", old.Email, u.Email,
+				"username", old.Username, u.Username,
+				"handle", old.Handle, u.Handle,
+				"name", old.Name, u.Name,
+				"kind", string(old.Kind), string(u.Kind),
+			)
+		}
+
+		svc.record(ActionUserUpdate, u.ID, meta)
+	}
+
+	return u, err
+}
+
+func (svc userService) record(action string, userID uint64, meta Meta) {
+	defaultAudit.with(svc.ctx).record(action, userResource(userID), meta)
+}
+
+// recordErr records the change when there was no error
+func (svc userService) recordErr(action string, userID uint64, err error) error {
+	if err == nil {
+		svc.record(action, userID, nil)
+	}
+
+	return err
+}
+
+func userResource(userID uint64) string {
+	return types.UserPermissionResource.AppendID(userID).String()
+}
@@ -0,0 +1,42 @@
+package autoroles
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// authService wraps auth service and evaluates role rules on login
+	authService struct {
+		service.AuthService
+		ctx context.Context
+	}
+)
+
+// Auth decorates auth service with role rules
+//
+// Memberships are synced before they are loaded into the token;
+// login does not fail when rules can not be applied.
+func Auth(as service.AuthService) service.AuthService {
+	return &authService{AuthService: as, ctx: context.Background()}
+}
+
+func (svc authService) With(ctx context.Context) service.AuthService {
+	return &authService{
+		AuthService: svc.AuthService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc authService) LoadRoleMemberships(u *types.User) error {
+	ar := defaultAutoRoles.with(svc.ctx)
+	if err := ar.sync(u); err != nil {
+		ar.log(zap.Uint64("userID", u.ID), zap.Error(err)).Error("could not apply role rules")
+	}
+
+	return svc.AuthService.LoadRoleMemberships(u)
+}
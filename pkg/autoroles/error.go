@@ -0,0 +1,34 @@
+package autoroles
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	autorolesError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrRuleNotFound      = autorolesError{"RuleNotFound", fault.NotFound}
+	ErrInvalidExpression = autorolesError{"InvalidExpression", fault.Invalid}
+	ErrNoPermissions     = autorolesError{"NoPermissions", fault.Forbidden}
+)
+
+func (e autorolesError) Error() string {
+	return e.String()
+}
+
+func (e autorolesError) String() string {
+	return "crust.autoroles." + e.name
+}
+
+func (e autorolesError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e autorolesError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,35 @@
+package autoroles
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200218000000.autoroles",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_system_role_rule (
+  rel_role         BIGINT UNSIGNED NOT NULL,
+  expression       TEXT            NOT NULL,
+  enabled          BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  updated_by       BIGINT UNSIGNED NOT NULL,
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       DATETIME            NULL,
+
+  PRIMARY KEY (rel_role)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_system_role_rule_member (
+  rel_role         BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  assigned_at      DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_role, rel_user),
+  INDEX (rel_user)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
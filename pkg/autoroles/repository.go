@@ -0,0 +1,127 @@
+package autoroles
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_system_role_rule"
+}
+
+func (r repository) tableMember() string {
+	return "crust_system_role_rule_member"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"rel_role",
+			"expression",
+			"enabled",
+			"updated_by",
+			"created_at",
+			"updated_at",
+		).
+		From(r.table())
+}
+
+func (r repository) FindByRoleID(roleID uint64) (*Rule, error) {
+	var (
+		rule = &Rule{}
+		q    = r.query().Where(squirrel.Eq{"rel_role": roleID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, rule); err != nil {
+		return nil, err
+	} else if rule.RoleID == 0 {
+		return nil, nil
+	}
+
+	return rule, nil
+}
+
+// Enabled returns enabled rules of roles that are not archived or deleted
+func (r repository) Enabled() (set RuleSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"enabled": true}).
+		Where("rel_role IN (SELECT id FROM sys_role WHERE archived_at IS NULL AND deleted_at IS NULL)")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Save(rule *Rule) error {
+	return r.db().Replace(r.table(), rule)
+}
+
+// Delete removes the rule and forgets its assignments
+func (r repository) Delete(roleID uint64) error {
+	return r.db().Transaction(func() error {
+		if err := rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_role": roleID}); err != nil {
+			return err
+		}
+
+		return rh.Delete(r.db(), r.tableMember(), squirrel.Eq{"rel_role": roleID})
+	})
+}
+
+// Assigned returns IDs of users the rule assigned the role to
+func (r repository) Assigned(roleID uint64) (ids []uint64, err error) {
+	q := squirrel.Select("rel_user").From(r.tableMember()).Where(squirrel.Eq{"rel_role": roleID})
+	return ids, rh.FetchAll(r.db(), q, &ids)
+}
+
+// AssignedTo returns IDs of roles that rules assigned to the user
+func (r repository) AssignedTo(userID uint64) (ids []uint64, err error) {
+	q := squirrel.Select("rel_role").From(r.tableMember()).Where(squirrel.Eq{"rel_user": userID})
+	return ids, rh.FetchAll(r.db(), q, &ids)
+}
+
+// Memberships returns IDs of user's roles
+func (r repository) Memberships(userID uint64) (ids []uint64, err error) {
+	q := squirrel.Select("rel_role").From("sys_role_member").Where(squirrel.Eq{"rel_user": userID})
+	return ids, rh.FetchAll(r.db(), q, &ids)
+}
+
+// Assign records that the rule assigned the role to the user
+func (r repository) Assign(roleID, userID uint64) error {
+	_, err := r.db().Exec(
+		"INSERT IGNORE INTO "+r.tableMember()+" (rel_role, rel_user, assigned_at) VALUES (?, ?, ?)",
+		roleID, userID, time.Now(),
+	)
+
+	return errors.WithStack(err)
+}
+
+// Unassign forgets the assignment
+func (r repository) Unassign(roleID, userID uint64) error {
+	return rh.Delete(r.db(), r.tableMember(), squirrel.Eq{"rel_role": roleID, "rel_user": userID})
+}
@@ -0,0 +1,56 @@
+package autoroles
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts role rule endpoints
+//
+// Expects to be mounted under a path with {roleID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("RoleRule.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultAutoRoles.With(r.Context()).Read(
+			rest.ParamUint64(r, "roleID"),
+		)
+	}))
+
+	// Sets the rule, users are evaluated when they log in or are updated
+	r.Put("/", rest.Handler("RoleRule.Save", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Expression string `json:"expression"`
+			Enabled    bool   `json:"enabled"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultAutoRoles.With(r.Context()).Save(
+			rest.ParamUint64(r, "roleID"),
+			body.Expression,
+			body.Enabled,
+		)
+	}))
+
+	// Removes the rule and revokes memberships it assigned
+	r.Delete("/", rest.Handler("RoleRule.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultAutoRoles.With(r.Context()).Delete(
+			rest.ParamUint64(r, "roleID"),
+		)
+	}))
+
+	// Evaluates the rule for all users right away
+	r.Post("/apply", rest.Handler("RoleRule.Apply", func(r *http.Request) (interface{}, error) {
+		return DefaultAutoRoles.With(r.Context()).Apply(
+			rest.ParamUint64(r, "roleID"),
+		)
+	}))
+}
@@ -0,0 +1,362 @@
+package autoroles
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+type (
+	autorolesService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		role service.RoleService
+		user service.UserService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanUpdateRole(context.Context, *types.Role) bool
+		CanManageRoleMembers(context.Context, *types.Role) bool
+	}
+
+	AutoRolesService interface {
+		With(ctx context.Context) AutoRolesService
+
+		Read(roleID uint64) (*Rule, error)
+		Save(roleID uint64, expression string, enabled bool) (*Rule, error)
+		Delete(roleID uint64) error
+
+		Apply(roleID uint64) (*Result, error)
+	}
+)
+
+var (
+	DefaultAutoRoles AutoRolesService
+
+	// used by service decorators
+	defaultAutoRoles *autorolesService
+)
+
+// Init initializes role rules and evaluates them when users log in
+// or are created or updated
+//
+// Must be called after system services are initialized and before other
+// extensions decorate auth service, so that their changes of memberships
+// see assigned roles.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := (&autorolesService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		role:   service.DefaultRole,
+		user:   service.DefaultUser,
+	}).with(ctx)
+
+	DefaultAutoRoles = svc
+	defaultAutoRoles = svc
+
+	service.DefaultAuth = Auth(service.DefaultAuth)
+	service.DefaultUser = User(service.DefaultUser)
+
+	return nil
+}
+
+func (svc autorolesService) With(ctx context.Context) AutoRolesService {
+	return svc.with(ctx)
+}
+
+func (svc autorolesService) with(ctx context.Context) *autorolesService {
+	return &autorolesService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		role: svc.role.With(ctx),
+		user: svc.user.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc autorolesService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Read returns rule of the role
+func (svc autorolesService) Read(roleID uint64) (*Rule, error) {
+	if _, err := svc.role.FindByID(roleID); err != nil {
+		return nil, err
+	}
+
+	return svc.rule(roleID)
+}
+
+// Save sets rule of the role
+//
+// Disabled rules are not evaluated, memberships they assigned stay.
+func (svc autorolesService) Save(roleID uint64, expression string, enabled bool) (*Rule, error) {
+	if _, err := svc.manageable(roleID); err != nil {
+		return nil, err
+	}
+
+	if _, err := expr.Parse(expression); err != nil {
+		return nil, ErrInvalidExpression.withStack().WithMessage(err.Error())
+	}
+
+	rule, err := svc.repository.FindByRoleID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule == nil {
+		rule = &Rule{RoleID: roleID, CreatedAt: time.Now()}
+	} else {
+		rule.UpdatedAt = now()
+	}
+
+	rule.Expression = expression
+	rule.Enabled = enabled
+	rule.UpdatedBy = auth.GetIdentityFromContext(svc.ctx).Identity()
+
+	if err = svc.repository.Save(rule); err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// Delete removes rule of the role and revokes memberships it assigned
+func (svc autorolesService) Delete(roleID uint64) error {
+	if _, err := svc.manageable(roleID); err != nil {
+		return err
+	}
+
+	if _, err := svc.rule(roleID); err != nil {
+		return err
+	}
+
+	ids, err := svc.repository.Assigned(roleID)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range ids {
+		if err = svc.members().MemberRemove(roleID, userID); err != nil {
+			return err
+		}
+	}
+
+	return svc.repository.Delete(roleID)
+}
+
+// Apply evaluates the rule for all active users
+//
+// Users are otherwise (re)evaluated when they log in or are updated.
+func (svc autorolesService) Apply(roleID uint64) (*Result, error) {
+	if _, err := svc.manageable(roleID); err != nil {
+		return nil, err
+	}
+
+	rule, err := svc.rule(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	uu, _, err := svc.user.With(auth.SetSuperUserContext(svc.ctx)).Find(types.UserFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := svc.repository.Assigned(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out      = &Result{}
+		assigned = set(ids)
+	)
+
+	for _, u := range uu {
+		member, err := svc.member(roleID, u.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch svc.evaluate(rule, u, member, assigned[u.ID]) {
+		case actionAssign:
+			err = svc.assign(roleID, u.ID)
+			out.Assigned++
+		case actionRevoke:
+			err = svc.revoke(roleID, u.ID)
+			out.Revoked++
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	svc.log(zap.Uint64("roleID", roleID), zap.Int("assigned", out.Assigned), zap.Int("revoked", out.Revoked)).
+		Info("role rule applied")
+
+	return out, nil
+}
+
+// sync assigns and revokes user's memberships by enabled rules
+func (svc autorolesService) sync(u *types.User) error {
+	if u == nil || u.ID == 0 {
+		return nil
+	}
+
+	rr, err := svc.repository.Enabled()
+	if err != nil || len(rr) == 0 {
+		return err
+	}
+
+	ids, err := svc.repository.Memberships(u.ID)
+	if err != nil {
+		return err
+	}
+
+	members := set(ids)
+
+	if ids, err = svc.repository.AssignedTo(u.ID); err != nil {
+		return err
+	}
+
+	assigned := set(ids)
+
+	for _, rule := range rr {
+		switch svc.evaluate(rule, u, members[rule.RoleID], assigned[rule.RoleID]) {
+		case actionAssign:
+			err = svc.assign(rule.RoleID, u.ID)
+		case actionRevoke:
+			err = svc.revoke(rule.RoleID, u.ID)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+const (
+	actionNone = iota
+	actionAssign
+	actionRevoke
+)
+
+// evaluate decides what to do with user's membership
+//
+// Memberships are not changed when the expression can not be evaluated.
+func (svc autorolesService) evaluate(rule *Rule, u *types.User, member, assigned bool) int {
+	e, err := expr.Parse(rule.Expression)
+	if err == nil {
+		var match bool
+		if match, err = e.Test(scope(u)); err == nil {
+			switch {
+			case match && !member:
+				return actionAssign
+			case !match && assigned:
+				return actionRevoke
+			}
+
+			return actionNone
+		}
+	}
+
+	svc.log(zap.Uint64("roleID", rule.RoleID), zap.Uint64("userID", u.ID), zap.Error(err)).
+		Warn("could not evaluate role rule")
+
+	return actionNone
+}
+
+func (svc autorolesService) assign(roleID, userID uint64) error {
+	if err := svc.members().MemberAdd(roleID, userID); err != nil {
+		return err
+	}
+
+	svc.log(zap.Uint64("roleID", roleID), zap.Uint64("userID", userID)).Info("role assigned by rule")
+	return svc.repository.Assign(roleID, userID)
+}
+
+func (svc autorolesService) revoke(roleID, userID uint64) error {
+	if err := svc.members().MemberRemove(roleID, userID); err != nil {
+		return err
+	}
+
+	svc.log(zap.Uint64("roleID", roleID), zap.Uint64("userID", userID)).Info("role revoked by rule")
+	return svc.repository.Unassign(roleID, userID)
+}
+
+// members returns role service for changing memberships
+//
+// Memberships are changed through the current (decorated) role service,
+// so that other extensions see the changes.
+func (svc autorolesService) members() service.RoleService {
+	return service.DefaultRole.With(auth.SetSuperUserContext(svc.ctx))
+}
+
+func (svc autorolesService) member(roleID, userID uint64) (bool, error) {
+	ids, err := svc.repository.Memberships(userID)
+	if err != nil {
+		return false, err
+	}
+
+	return set(ids)[roleID], nil
+}
+
+func (svc autorolesService) rule(roleID uint64) (*Rule, error) {
+	rule, err := svc.repository.FindByRoleID(roleID)
+	if err != nil {
+		return nil, err
+	} else if rule == nil {
+		return nil, ErrRuleNotFound.withStack().WithID("roleID", roleID)
+	}
+
+	return rule, nil
+}
+
+func (svc autorolesService) manageable(roleID uint64) (*types.Role, error) {
+	r, err := svc.role.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanUpdateRole(svc.ctx, r) || !svc.ac.CanManageRoleMembers(svc.ctx, r) {
+		return nil, ErrNoPermissions.withStack().WithID("roleID", roleID)
+	}
+
+	return r, nil
+}
+
+func now() *time.Time {
+	n := time.Now()
+	return &n
+}
+
+func set(ids []uint64) map[uint64]bool {
+	out := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		out[id] = true
+	}
+
+	return out
+}
@@ -0,0 +1,59 @@
+package autoroles
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cortezaproject/corteza-server/system/types"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+type (
+	// Rule assigns the role to users that match the expression
+	//
+	// Memberships are assigned and revoked when users log in and when
+	// they are created or updated. Only memberships assigned by the rule
+	// are revoked, members added manually stay.
+	Rule struct {
+		RoleID     uint64 `json:"roleID,string" db:"rel_role"`
+		Expression string `json:"expression" db:"expression"`
+		Enabled    bool   `json:"enabled" db:"enabled"`
+
+		UpdatedBy uint64     `json:"updatedBy,string" db:"updated_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	RuleSet []*Rule
+
+	// Result of applying the rule to all users
+	Result struct {
+		Assigned int `json:"assigned"`
+		Revoked  int `json:"revoked"`
+	}
+)
+
+// scope prepares user's attributes for expression evaluation
+//
+//	user.domain == "example.com" and not (user.handle in ["bot", "ci"])
+func scope(u *types.User) expr.Scope {
+	var domain string
+	if at := strings.LastIndex(u.Email, "@"); at > -1 {
+		domain = strings.ToLower(u.Email[at+1:])
+	}
+
+	return expr.Scope{
+		"user": expr.Scope{
+			"id":             u.ID,
+			"email":          u.Email,
+			"domain":         domain,
+			"username":       u.Username,
+			"handle":         u.Handle,
+			"name":           u.Name,
+			"kind":           string(u.Kind),
+			"organisationID": u.OrganisationID,
+			"emailConfirmed": u.EmailConfirmed,
+			"createdAt":      u.CreatedAt,
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package autoroles
+
+import (
+	"context"
+	"io"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// userService wraps user service and evaluates role rules on changes
+	userService struct {
+		service.UserService
+		ctx context.Context
+	}
+)
+
+// User decorates user service with role rules
+//
+// Rules are applied to created and updated users, errors are logged
+// and do not fail the change.
+func User(us service.UserService) service.UserService {
+	return &userService{UserService: us, ctx: context.Background()}
+}
+
+func (svc userService) With(ctx context.Context) service.UserService {
+	return &userService{
+		UserService: svc.UserService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc userService) Create(input *types.User) (*types.User, error) {
+	return svc.sync(svc.UserService.Create(input))
+}
+
+func (svc userService) Update(mod *types.User) (*types.User, error) {
+	return svc.sync(svc.UserService.Update(mod))
+}
+
+func (svc userService) CreateWithAvatar(input *types.User, avatar io.Reader) (*types.User, error) {
+	return svc.sync(svc.UserService.CreateWithAvatar(input, avatar))
+}
+
+func (svc userService) UpdateWithAvatar(mod *types.User, avatar io.Reader) (*types.User, error) {
+	return svc.sync(svc.UserService.UpdateWithAvatar(mod, avatar))
+}
+
+func (svc userService) sync(u *types.User, err error) (*types.User, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	ar := defaultAutoRoles.with(svc.ctx)
+	if err = ar.sync(u); err != nil {
+		ar.log(zap.Uint64("userID", u.ID), zap.Error(err)).Error("could not apply role rules")
+	}
+
+	return u, nil
+}
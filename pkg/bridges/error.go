@@ -0,0 +1,42 @@
+package bridges
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	bridgesError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNameRequired         = bridgesError{"NameRequired", fault.Invalid}
+	ErrChannelRequired      = bridgesError{"ChannelRequired", fault.Invalid}
+	ErrTemplateRequired     = bridgesError{"TemplateRequired", fault.Invalid}
+	ErrInvalidTemplate      = bridgesError{"InvalidTemplate", fault.Invalid}
+	ErrInvalidFilter        = bridgesError{"InvalidFilter", fault.Invalid}
+	ErrEventsRequired       = bridgesError{"EventsRequired", fault.Invalid}
+	ErrInvalidEvent         = bridgesError{"InvalidEvent", fault.Invalid}
+	ErrInvalidRecord        = bridgesError{"InvalidRecord", fault.Invalid}
+	ErrMessagingUnavailable = bridgesError{"MessagingUnavailable", fault.Unavailable}
+	ErrBridgeNotFound       = bridgesError{"BridgeNotFound", fault.NotFound}
+	ErrNoPermissions        = bridgesError{"NoPermissions", fault.Forbidden}
+)
+
+func (e bridgesError) Error() string {
+	return e.String()
+}
+
+func (e bridgesError) String() string {
+	return "crust.bridges." + e.name
+}
+
+func (e bridgesError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e bridgesError) withStack() *fault.Error {
+	return fault.New(e)
+}
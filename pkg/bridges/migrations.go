@@ -0,0 +1,38 @@
+package bridges
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200210000000.bridges",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_bridge (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  rel_channel        BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  events             TEXT            NOT NULL,
+  filter             TEXT            NOT NULL,
+  template           TEXT            NOT NULL,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  last_error         TEXT            NOT NULL,
+  last_posted_at     DATETIME            NULL DEFAULT NULL,
+
+  owned_by           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (rel_module)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,94 @@
+package bridges
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// record wraps record service and posts messages of bridges after
+	// records are changed
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with bridges
+//
+// Messages are posted in the background after the change is stored;
+// failures do not affect the change. Values before the update are loaded
+// (as superuser) only for modules with enabled bridges.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	r, err := svc.RecordService.Create(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if set := svc.bridges(r.ModuleID, EventCreate); len(set) > 0 {
+		svc.post(set, EventCreate, r, nil)
+	}
+
+	return r, nil
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	var (
+		set = svc.bridges(r.ModuleID, EventUpdate)
+		old *types.Record
+		err error
+	)
+
+	if len(set) > 0 {
+		old, err = svc.RecordService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(r.NamespaceID, r.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r, err = svc.RecordService.Update(r); err != nil {
+		return nil, err
+	}
+
+	svc.post(set, EventUpdate, r, old)
+	return r, nil
+}
+
+// bridges returns enabled bridges of the module for the event
+func (svc record) bridges(moduleID uint64, event string) (out BridgeSet) {
+	set, err := Repository(svc.ctx, nil).FindEnabled(moduleID)
+	if err != nil {
+		defaultBridges.log(zap.Uint64("moduleID", moduleID), zap.Error(err)).Error("could not load bridges")
+		return nil
+	}
+
+	for _, b := range set {
+		if b.Events.has(event) {
+			out = append(out, b)
+		}
+	}
+
+	return out
+}
+
+func (svc record) post(set BridgeSet, event string, r, old *types.Record) {
+	for _, b := range set {
+		go defaultBridges.post(b, event, r.ID, old)
+	}
+}
@@ -0,0 +1,127 @@
+package bridges
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_bridge"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_module",
+			"rel_channel",
+			"name",
+			"events",
+			"filter",
+			"template",
+			"enabled",
+			"last_error",
+			"last_posted_at",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(namespaceID, bridgeID uint64) (*Bridge, error) {
+	var (
+		b = &Bridge{}
+		q = r.query().Where(squirrel.Eq{"id": bridgeID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, b); err != nil {
+		return nil, err
+	} else if b.ID == 0 {
+		return nil, ErrBridgeNotFound.withStack().WithID("namespaceID", namespaceID).WithID("bridgeID", bridgeID)
+	}
+
+	return b, nil
+}
+
+func (r repository) Find(namespaceID uint64) (set BridgeSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindEnabled returns enabled bridges of the module
+func (r repository) FindEnabled(moduleID uint64) (set BridgeSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_module": moduleID, "enabled": true})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(b *Bridge) (*Bridge, error) {
+	b.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&b.CreatedAt)
+
+	return b, errors.WithStack(r.db().Insert(r.table(), b))
+}
+
+func (r repository) Update(b *Bridge) (*Bridge, error) {
+	rh.SetCurrentTimeRounded(&b.UpdatedAt)
+
+	return b, errors.WithStack(r.db().Replace(r.table(), b))
+}
+
+// UpdateState stores outcome of the last post without touching the rest of the bridge
+func (r repository) UpdateState(b *Bridge) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{
+			"last_error":     b.LastError,
+			"last_posted_at": b.LastPostedAt,
+		},
+		squirrel.Eq{"id": b.ID},
+	)
+}
+
+func (r repository) DeleteByID(namespaceID, bridgeID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": bridgeID, "rel_namespace": namespaceID},
+	)
+}
@@ -0,0 +1,66 @@
+package bridges
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts bridge management endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("Bridge.List", func(r *http.Request) (interface{}, error) {
+		return DefaultBridges.With(r.Context()).Find(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Post("/", rest.Handler("Bridge.Create", func(r *http.Request) (interface{}, error) {
+		b := &Bridge{}
+		if err := rest.Decode(r, b); err != nil {
+			return nil, err
+		}
+
+		b.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultBridges.With(r.Context()).Create(b)
+	}))
+
+	r.Get("/{bridgeID}", rest.Handler("Bridge.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultBridges.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "bridgeID"),
+		)
+	}))
+
+	r.Put("/{bridgeID}", rest.Handler("Bridge.Update", func(r *http.Request) (interface{}, error) {
+		b := &Bridge{}
+		if err := rest.Decode(r, b); err != nil {
+			return nil, err
+		}
+
+		b.ID = rest.ParamUint64(r, "bridgeID")
+		b.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultBridges.With(r.Context()).Update(b)
+	}))
+
+	r.Delete("/{bridgeID}", rest.Handler("Bridge.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultBridges.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "bridgeID"),
+		)
+	}))
+
+	// ?recordID=; renders the message without posting it
+	r.Get("/{bridgeID}/preview", rest.Handler("Bridge.Preview", func(r *http.Request) (interface{}, error) {
+		return DefaultBridges.With(r.Context()).Preview(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "bridgeID"),
+			rest.QueryUint64(r, "recordID"),
+		)
+	}))
+}
@@ -0,0 +1,67 @@
+package bridges
+
+import (
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+// scope prepares variables for filter evaluation and template rendering
+//
+// Old record is nil when record is created.
+func scope(event string, ns *types.Namespace, m *types.Module, r, old *types.Record, link string) expr.Scope {
+	return expr.Scope{
+		"event":  event,
+		"record": recordScope(m, r),
+		"old":    recordScope(m, old),
+		"module": expr.Scope{
+			"moduleID": payload.Uint64toa(m.ID),
+			"name":     m.Name,
+			"handle":   m.Handle,
+		},
+		"namespace": expr.Scope{
+			"namespaceID": payload.Uint64toa(ns.ID),
+			"name":        ns.Name,
+			"slug":        ns.Slug,
+		},
+		"link": link,
+	}
+}
+
+func recordScope(m *types.Module, r *types.Record) expr.Scope {
+	var (
+		values = expr.Scope{}
+		rs     = expr.Scope{"values": values}
+	)
+
+	if r == nil {
+		return rs
+	}
+
+	rs["recordID"] = payload.Uint64toa(r.ID)
+	rs["ownedBy"] = payload.Uint64toa(r.OwnedBy)
+	rs["createdBy"] = payload.Uint64toa(r.CreatedBy)
+	rs["updatedBy"] = payload.Uint64toa(r.UpdatedBy)
+	rs["createdAt"] = r.CreatedAt
+
+	if r.UpdatedAt != nil {
+		rs["updatedAt"] = *r.UpdatedAt
+	}
+
+	for _, f := range m.Fields {
+		vv := r.Values.FilterByName(f.Name)
+
+		if f.Multi {
+			ss := make([]string, 0, len(vv))
+			for _, v := range vv {
+				ss = append(ss, v.Value)
+			}
+
+			values[f.Name] = ss
+		} else if len(vv) > 0 {
+			values[f.Name] = vv[0].Value
+		}
+	}
+
+	return rs
+}
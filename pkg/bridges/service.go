@@ -0,0 +1,377 @@
+package bridges
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	"github.com/crusttech/crust-server/pkg/expr"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	bridgesService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		settings settingsGetter
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+		page      service.PageService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	BridgesService interface {
+		With(ctx context.Context) BridgesService
+
+		Find(namespaceID uint64) (BridgeSet, error)
+		FindByID(namespaceID, bridgeID uint64) (*Bridge, error)
+		Create(*Bridge) (*Bridge, error)
+		Update(*Bridge) (*Bridge, error)
+		DeleteByID(namespaceID, bridgeID uint64) error
+
+		Preview(namespaceID, bridgeID, recordID uint64) (*Preview, error)
+	}
+)
+
+var (
+	DefaultBridges BridgesService
+
+	// defaultBridges is used by the record decorator
+	defaultBridges *bridgesService
+
+	// now is used for post times and can be overridden
+	now = time.Now
+)
+
+// Init initializes bridges service
+//
+// Must be called after compose services are initialized; messages are
+// posted only when running as a monolith
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &bridgesService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		settings:  service.DefaultSettings,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+		page:      service.DefaultPage,
+	}
+
+	DefaultBridges = svc.With(ctx)
+	defaultBridges = svc
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	return nil
+}
+
+func (svc bridgesService) With(ctx context.Context) BridgesService {
+	return svc.with(ctx)
+}
+
+func (svc bridgesService) with(ctx context.Context) *bridgesService {
+	return &bridgesService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		ac:       svc.ac,
+		settings: svc.settings,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+		page:      svc.page.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc bridgesService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc bridgesService) Find(namespaceID uint64) (BridgeSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Find(namespaceID)
+}
+
+func (svc bridgesService) FindByID(namespaceID, bridgeID uint64) (*Bridge, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindByID(namespaceID, bridgeID)
+}
+
+// Create stores new bridge
+//
+// Bridge is owned by the user that created it, unless set otherwise;
+// owner must be able to post to the channel.
+func (svc bridgesService) Create(in *Bridge) (*Bridge, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	b := &Bridge{NamespaceID: in.NamespaceID, OwnedBy: in.OwnedBy}
+	if b.OwnedBy == 0 {
+		b.OwnedBy = auth.GetIdentityFromContext(svc.ctx).Identity()
+	}
+
+	configure(b, in)
+	return svc.repository.Create(b)
+}
+
+func (svc bridgesService) Update(upd *Bridge) (*Bridge, error) {
+	if err := svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	b, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if upd.OwnedBy > 0 {
+		b.OwnedBy = upd.OwnedBy
+	}
+
+	configure(b, upd)
+	return svc.repository.Update(b)
+}
+
+func (svc bridgesService) DeleteByID(namespaceID, bridgeID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindByID(namespaceID, bridgeID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, bridgeID)
+}
+
+// Preview renders bridge's message for the record, as if it was created
+func (svc bridgesService) Preview(namespaceID, bridgeID, recordID uint64) (*Preview, error) {
+	b, err := svc.FindByID(namespaceID, bridgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Preview{}
+	p.Matches, p.Message, err = svc.render(b, EventCreate, recordID, nil)
+	return p, err
+}
+
+// post renders and posts bridge's message in the name of bridge's owner
+func (svc bridgesService) post(b *Bridge, event string, recordID uint64, old *types.Record) {
+	var (
+		log = svc.logger.With(zap.Uint64("bridgeID", b.ID), zap.Uint64("recordID", recordID))
+		err error
+	)
+
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		log.Warn("could not post message", zap.Error(err))
+
+		b.LastError = err.Error()
+		if serr := Repository(context.Background(), nil).UpdateState(b); serr != nil {
+			log.Error("could not store bridge state", zap.Error(serr))
+		}
+	}()
+
+	if messagingService.DefaultMessage == nil {
+		err = ErrMessagingUnavailable.withStack()
+		return
+	}
+
+	ctx, err := runas.Compose(context.Background(), b.OwnedBy)
+	if err != nil {
+		return
+	}
+
+	matches, msg, err := svc.with(ctx).render(b, event, recordID, old)
+	if err != nil || !matches {
+		return
+	}
+
+	_, err = messagingService.DefaultMessage.With(ctx).Create(&messagingTypes.Message{
+		ChannelID: b.ChannelID,
+		Message:   msg,
+	})
+
+	if err != nil {
+		return
+	}
+
+	posted := now()
+	b.LastError, b.LastPostedAt = "", &posted
+	if err = svc.with(ctx).repository.UpdateState(b); err != nil {
+		log.Error("could not store bridge state", zap.Error(err))
+		err = nil
+	}
+}
+
+// render checks bridge's filter and renders the message
+//
+// Record is loaded with permissions of the current user.
+func (svc bridgesService) render(b *Bridge, event string, recordID uint64, old *types.Record) (bool, string, error) {
+	ns, err := svc.namespace.FindByID(b.NamespaceID)
+	if err != nil {
+		return false, "", err
+	}
+
+	m, err := svc.module.FindByID(b.NamespaceID, b.ModuleID)
+	if err != nil {
+		return false, "", err
+	}
+
+	r, err := service.DefaultRecord.With(svc.ctx).FindByID(b.NamespaceID, recordID)
+	if err != nil {
+		return false, "", err
+	} else if r.ModuleID != b.ModuleID {
+		return false, "", ErrInvalidRecord.withStack()
+	}
+
+	s := scope(event, ns, m, r, old, svc.link(ns, m, r))
+
+	if b.Filter != "" {
+		f, err := expr.Parse(b.Filter)
+		if err != nil {
+			return false, "", ErrInvalidFilter.withStack()
+		}
+
+		if ok, err := f.Test(s); err != nil || !ok {
+			return false, "", err
+		}
+	}
+
+	t, err := expr.ParseTemplate(b.Template)
+	if err != nil {
+		return false, "", ErrInvalidTemplate.withStack()
+	}
+
+	msg, err := t.Render(s)
+	return err == nil, msg, err
+}
+
+// link returns URL of the record page; relative when base URL of
+// the web application is not configured
+func (svc bridgesService) link(ns *types.Namespace, m *types.Module, r *types.Record) string {
+	p, err := svc.page.FindByModuleID(ns.ID, m.ID)
+	if err != nil || p == nil {
+		return ""
+	}
+
+	var base string
+	if v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingFrontendURL, 0); err == nil && v != nil {
+		base = strings.TrimSuffix(v.String(), "/")
+	}
+
+	return fmt.Sprintf("%s/compose/ns/%s/pages/%d/record/%d", base, ns.Slug, p.ID, r.ID)
+}
+
+func (svc bridgesService) validate(b *Bridge) error {
+	if err := svc.canManage(b.NamespaceID); err != nil {
+		return err
+	}
+
+	if b.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	if _, err := svc.module.FindByID(b.NamespaceID, b.ModuleID); err != nil {
+		return err
+	}
+
+	if b.ChannelID == 0 {
+		return ErrChannelRequired.withStack()
+	}
+
+	if messagingService.DefaultChannel == nil {
+		return ErrMessagingUnavailable.withStack()
+	}
+
+	if _, err := messagingService.DefaultChannel.With(svc.ctx).FindByID(b.ChannelID); err != nil {
+		return err
+	}
+
+	if len(b.Events) == 0 {
+		return ErrEventsRequired.withStack()
+	}
+
+	for _, e := range b.Events {
+		if e != EventCreate && e != EventUpdate {
+			return ErrInvalidEvent.withStack()
+		}
+	}
+
+	if b.Filter != "" {
+		if _, err := expr.Parse(b.Filter); err != nil {
+			return ErrInvalidFilter.withStack()
+		}
+	}
+
+	if b.Template == "" {
+		return ErrTemplateRequired.withStack()
+	}
+
+	if _, err := expr.ParseTemplate(b.Template); err != nil {
+		return ErrInvalidTemplate.withStack()
+	}
+
+	return nil
+}
+
+func (svc bridgesService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// configure copies settings of the bridge
+func configure(b, in *Bridge) {
+	b.ModuleID = in.ModuleID
+	b.ChannelID = in.ChannelID
+	b.Name = in.Name
+	b.Events = in.Events
+	b.Filter = in.Filter
+	b.Template = in.Template
+	b.Enabled = in.Enabled
+}
@@ -0,0 +1,92 @@
+package bridges
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Bridge posts a message to a channel when records of the module are
+	// created or updated
+	//
+	// Template is a text with {{ expression }} placeholders, filter is an
+	// expression; both can access:
+	//   - record.values.<field>, record.recordID, record.ownedBy, ...
+	//   - old.values.<field> (values before the update, empty on create)
+	//   - module.name, module.handle, namespace.name, namespace.slug
+	//   - event ("create" or "update")
+	//   - link (URL of the record page)
+	//
+	// Messages are posted in the name of the bridge's owner.
+	Bridge struct {
+		ID          uint64 `json:"bridgeID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+		ChannelID   uint64 `json:"channelID,string" db:"rel_channel"`
+		Name        string `json:"name" db:"name"`
+		Events      Events `json:"events" db:"events"`
+		Filter      string `json:"filter" db:"filter"`
+		Template    string `json:"template" db:"template"`
+		Enabled     bool   `json:"enabled" db:"enabled"`
+
+		LastError    string     `json:"lastError,omitempty" db:"last_error"`
+		LastPostedAt *time.Time `json:"lastPostedAt,omitempty" db:"last_posted_at"`
+
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	BridgeSet []*Bridge
+
+	Events []string
+
+	// Preview of the bridge's message for the record
+	Preview struct {
+		Matches bool   `json:"matches"`
+		Message string `json:"message"`
+	}
+)
+
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+
+	// Base URL of the web application, prepended to record links
+	settingFrontendURL = "crust.frontend-url"
+)
+
+func (ee Events) has(event string) bool {
+	for _, e := range ee {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ee Events) Value() (driver.Value, error) {
+	if ee == nil {
+		ee = Events{}
+	}
+
+	return json.Marshal(ee)
+}
+
+func (ee *Events) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ee = Events{}
+	case []byte:
+		if err := json.Unmarshal(b, ee); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Events", string(b))
+		}
+	}
+
+	return nil
+}
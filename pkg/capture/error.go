@@ -0,0 +1,40 @@
+package capture
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	captureError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNameRequired         = captureError{"NameRequired", fault.Invalid}
+	ErrMappingRequired      = captureError{"MappingRequired", fault.Invalid}
+	ErrInvalidMapping       = captureError{"InvalidMapping", fault.Invalid}
+	ErrChannelNotAllowed    = captureError{"ChannelNotAllowed", fault.Forbidden}
+	ErrMessageNotFound      = captureError{"MessageNotFound", fault.NotFound}
+	ErrAlreadyCaptured      = captureError{"AlreadyCaptured", fault.Conflict}
+	ErrMessagingUnavailable = captureError{"MessagingUnavailable", fault.Unavailable}
+	ErrActionNotFound       = captureError{"ActionNotFound", fault.NotFound}
+	ErrNoPermissions        = captureError{"NoPermissions", fault.Forbidden}
+)
+
+func (e captureError) Error() string {
+	return e.String()
+}
+
+func (e captureError) String() string {
+	return "crust.capture." + e.name
+}
+
+func (e captureError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e captureError) withStack() *fault.Error {
+	return fault.New(e)
+}
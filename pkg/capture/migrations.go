@@ -0,0 +1,45 @@
+package capture
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200211000000.capture",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_capture_action (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  channels           TEXT            NOT NULL,
+  mapping            TEXT            NOT NULL,
+
+  created_by         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_capture (
+  rel_record         BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  rel_action         BIGINT UNSIGNED NOT NULL,
+  rel_channel        BIGINT UNSIGNED NOT NULL,
+  rel_message        BIGINT UNSIGNED NOT NULL,
+  captured_by        BIGINT UNSIGNED NOT NULL,
+  captured_at        DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_record),
+  INDEX (rel_message)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,131 @@
+package capture
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableAction() string {
+	return "crust_compose_capture_action"
+}
+
+func (r repository) tableCapture() string {
+	return "crust_compose_capture"
+}
+
+func (r repository) queryActions() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_module",
+			"name",
+			"channels",
+			"mapping",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableAction()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindActionByID(namespaceID, actionID uint64) (*Action, error) {
+	var (
+		a = &Action{}
+		q = r.queryActions().Where(squirrel.Eq{"id": actionID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, a); err != nil {
+		return nil, err
+	} else if a.ID == 0 {
+		return nil, ErrActionNotFound.withStack().WithID("namespaceID", namespaceID).WithID("actionID", actionID)
+	}
+
+	return a, nil
+}
+
+func (r repository) FindActions(namespaceID uint64) (set ActionSet, err error) {
+	q := r.queryActions().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateAction(a *Action) (*Action, error) {
+	a.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&a.CreatedAt)
+
+	return a, errors.WithStack(r.db().Insert(r.tableAction(), a))
+}
+
+func (r repository) UpdateAction(a *Action) (*Action, error) {
+	rh.SetCurrentTimeRounded(&a.UpdatedAt)
+
+	return a, errors.WithStack(r.db().Replace(r.tableAction(), a))
+}
+
+func (r repository) DeleteActionByID(namespaceID, actionID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableAction(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": actionID, "rel_namespace": namespaceID},
+	)
+}
+
+// FindCaptures returns records created from the message, in the namespace
+func (r repository) FindCaptures(namespaceID, messageID uint64) (set CaptureSet, err error) {
+	q := squirrel.
+		Select(
+			"rel_record",
+			"rel_namespace",
+			"rel_module",
+			"rel_action",
+			"rel_channel",
+			"rel_message",
+			"captured_by",
+			"captured_at",
+		).
+		From(r.tableCapture()).
+		Where(squirrel.Eq{"rel_namespace": namespaceID, "rel_message": messageID}).
+		OrderBy("captured_at")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateCapture(c *Capture) (*Capture, error) {
+	rh.SetCurrentTimeRounded(&c.CapturedAt)
+
+	return c, errors.WithStack(r.db().Insert(r.tableCapture(), c))
+}
@@ -0,0 +1,84 @@
+package capture
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts capture action endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("CaptureAction.List", func(r *http.Request) (interface{}, error) {
+		return DefaultCapture.With(r.Context()).FindActions(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Post("/", rest.Handler("CaptureAction.Create", func(r *http.Request) (interface{}, error) {
+		a := &Action{}
+		if err := rest.Decode(r, a); err != nil {
+			return nil, err
+		}
+
+		a.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultCapture.With(r.Context()).CreateAction(a)
+	}))
+
+	// ?messageID=; records created from the message
+	r.Get("/captures", rest.Handler("CaptureAction.Captures", func(r *http.Request) (interface{}, error) {
+		return DefaultCapture.With(r.Context()).FindCaptures(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.QueryUint64(r, "messageID"),
+		)
+	}))
+
+	r.Get("/{actionID}", rest.Handler("CaptureAction.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultCapture.With(r.Context()).FindActionByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "actionID"),
+		)
+	}))
+
+	r.Put("/{actionID}", rest.Handler("CaptureAction.Update", func(r *http.Request) (interface{}, error) {
+		a := &Action{}
+		if err := rest.Decode(r, a); err != nil {
+			return nil, err
+		}
+
+		a.ID = rest.ParamUint64(r, "actionID")
+		a.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultCapture.With(r.Context()).UpdateAction(a)
+	}))
+
+	r.Delete("/{actionID}", rest.Handler("CaptureAction.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultCapture.With(r.Context()).DeleteAction(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "actionID"),
+		)
+	}))
+
+	// {"channelID": "...", "messageID": "..."}; returns the created record
+	r.Post("/{actionID}/capture", rest.Handler("CaptureAction.Capture", func(r *http.Request) (interface{}, error) {
+		c := struct {
+			ChannelID uint64 `json:"channelID,string"`
+			MessageID uint64 `json:"messageID,string"`
+		}{}
+
+		if err := rest.Decode(r, &c); err != nil {
+			return nil, err
+		}
+
+		return DefaultCapture.With(r.Context()).Capture(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "actionID"),
+			c.ChannelID,
+			c.MessageID,
+		)
+	}))
+}
@@ -0,0 +1,440 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	captureService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		settings settingsGetter
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	CaptureService interface {
+		With(ctx context.Context) CaptureService
+
+		FindActions(namespaceID uint64) (ActionSet, error)
+		FindActionByID(namespaceID, actionID uint64) (*Action, error)
+		CreateAction(*Action) (*Action, error)
+		UpdateAction(*Action) (*Action, error)
+		DeleteAction(namespaceID, actionID uint64) error
+
+		Capture(namespaceID, actionID, channelID, messageID uint64) (*types.Record, error)
+		FindCaptures(namespaceID, messageID uint64) (CaptureSet, error)
+	}
+)
+
+var (
+	DefaultCapture CaptureService
+)
+
+// Init initializes message capture service
+//
+// Must be called after compose services are initialized; messages can be
+// captured only when running as a monolith
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultCapture = (&captureService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		settings:  service.DefaultSettings,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc captureService) With(ctx context.Context) CaptureService {
+	return &captureService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		ac:       svc.ac,
+		settings: svc.settings,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc captureService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// FindActions returns capture actions of the namespace
+//
+// Actions are listed to everyone that can read the namespace, so that
+// they can be offered on messages.
+func (svc captureService) FindActions(namespaceID uint64) (ActionSet, error) {
+	if _, err := svc.namespace.FindByID(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindActions(namespaceID)
+}
+
+func (svc captureService) FindActionByID(namespaceID, actionID uint64) (*Action, error) {
+	if _, err := svc.namespace.FindByID(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindActionByID(namespaceID, actionID)
+}
+
+func (svc captureService) CreateAction(in *Action) (*Action, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.CreateAction(&Action{
+		NamespaceID: in.NamespaceID,
+		ModuleID:    in.ModuleID,
+		Name:        in.Name,
+		Channels:    in.Channels,
+		Mapping:     in.Mapping,
+		CreatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+}
+
+func (svc captureService) UpdateAction(upd *Action) (*Action, error) {
+	if err := svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	a, err := svc.repository.FindActionByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.ModuleID = upd.ModuleID
+	a.Name = upd.Name
+	a.Channels = upd.Channels
+	a.Mapping = upd.Mapping
+
+	return svc.repository.UpdateAction(a)
+}
+
+func (svc captureService) DeleteAction(namespaceID, actionID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindActionByID(namespaceID, actionID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteActionByID(namespaceID, actionID)
+}
+
+// Capture creates record from the message, its thread and attachments
+//
+// Current user must be able to read the message and create records of
+// the module. Message can be captured once per action.
+func (svc captureService) Capture(namespaceID, actionID, channelID, messageID uint64) (*types.Record, error) {
+	if messagingService.DefaultMessage == nil {
+		return nil, ErrMessagingUnavailable.withStack()
+	}
+
+	a, err := svc.FindActionByID(namespaceID, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.Channels.allows(channelID) {
+		return nil, ErrChannelNotAllowed.withStack()
+	}
+
+	cc, err := svc.repository.FindCaptures(namespaceID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cc {
+		if c.ActionID == a.ID {
+			return nil, ErrAlreadyCaptured.withStack()
+		}
+	}
+
+	m, err := svc.module.FindByID(namespaceID, a.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, thread, err := svc.message(channelID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := messagingService.DefaultChannel.With(svc.ctx).FindByID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := service.DefaultRecord.With(svc.ctx).Create(&types.Record{
+		NamespaceID: namespaceID,
+		ModuleID:    m.ID,
+		Values:      svc.values(a.Mapping, m, ch, msg, thread),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = svc.repository.CreateCapture(&Capture{
+		RecordID:    r.ID,
+		NamespaceID: namespaceID,
+		ModuleID:    m.ID,
+		ActionID:    a.ID,
+		ChannelID:   channelID,
+		MessageID:   messageID,
+		CapturedBy:  auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		// Record is there, only the provenance is missing
+		svc.log(zap.Uint64("recordID", r.ID), zap.Uint64("messageID", messageID), zap.Error(err)).
+			Error("could not store capture")
+	}
+
+	return r, nil
+}
+
+// FindCaptures returns records of the namespace created from the message
+func (svc captureService) FindCaptures(namespaceID, messageID uint64) (CaptureSet, error) {
+	if _, err := svc.namespace.FindByID(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindCaptures(namespaceID, messageID)
+}
+
+// message loads the message and its replies, as current user
+func (svc captureService) message(channelID, messageID uint64) (*messagingTypes.Message, messagingTypes.MessageSet, error) {
+	var (
+		ms = messagingService.DefaultMessage.With(svc.ctx)
+		f  = messagingTypes.MessageFilter{
+			CurrentUserID: auth.GetIdentityFromContext(svc.ctx).Identity(),
+			ChannelID:     []uint64{channelID},
+			FromID:        messageID,
+			ToID:          messageID,
+		}
+	)
+
+	mm, _, err := ms.Find(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var msg *messagingTypes.Message
+	for _, m := range mm {
+		if m.ID == messageID {
+			msg = m
+		}
+	}
+
+	if msg == nil {
+		return nil, nil, ErrMessageNotFound.withStack()
+	}
+
+	if msg.Replies == 0 {
+		return msg, nil, nil
+	}
+
+	f.FromID, f.ToID = 0, 0
+	f.ThreadID = []uint64{messageID}
+	thread, _, err := ms.Find(f)
+	return msg, thread, err
+}
+
+// values maps message data into record values
+func (svc captureService) values(mp Mapping, m *types.Module, ch *messagingTypes.Channel, msg *messagingTypes.Message, thread messagingTypes.MessageSet) types.RecordValueSet {
+	var (
+		vv  = types.RecordValueSet{}
+		set = func(name string, values ...string) {
+			f := m.Fields.FindByName(name)
+			if f == nil {
+				return
+			}
+
+			if !f.Multi && len(values) > 1 {
+				values = []string{strings.Join(values, "\n")}
+			}
+
+			for i, v := range values {
+				vv = append(vv, &types.RecordValue{Name: name, Value: v, Place: uint(i)})
+			}
+		}
+	)
+
+	set(mp.Title, title(msg.Message))
+	set(mp.Body, msg.Message)
+	set(mp.Reporter, payload.Uint64toa(msg.UserID))
+	set(mp.Channel, ch.Name)
+	set(mp.Link, svc.link(msg))
+
+	if len(thread) > 0 {
+		var (
+			names = svc.names(thread)
+			pp    = make([]string, 0, len(thread))
+		)
+
+		for _, r := range thread {
+			pp = append(pp, names[r.UserID]+": "+r.Message)
+		}
+
+		set(mp.Thread, strings.Join(pp, "\n\n"))
+	}
+
+	var uu []string
+	for _, m := range append(messagingTypes.MessageSet{msg}, thread...) {
+		if m.Attachment != nil {
+			uu = append(uu, m.Attachment.Url)
+		}
+	}
+
+	if len(uu) > 0 {
+		set(mp.Attachments, uu...)
+	}
+
+	return vv
+}
+
+// link returns URL of the message (its thread); relative when base URL of
+// the web application is not configured
+func (svc captureService) link(msg *messagingTypes.Message) string {
+	var base string
+	if v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingFrontendURL, 0); err == nil && v != nil {
+		base = strings.TrimSuffix(v.String(), "/")
+	}
+
+	return fmt.Sprintf("%s/messaging/ch/%d/thread/%d", base, msg.ChannelID, msg.ID)
+}
+
+// names returns names of authors of the messages; IDs when names can not be loaded
+func (svc captureService) names(mm messagingTypes.MessageSet) map[uint64]string {
+	var (
+		out = map[uint64]string{}
+		ids []uint64
+	)
+
+	for _, m := range mm {
+		if _, ok := out[m.UserID]; !ok {
+			out[m.UserID] = payload.Uint64toa(m.UserID)
+			ids = append(ids, m.UserID)
+		}
+	}
+
+	if systemService.DefaultUser == nil {
+		return out
+	}
+
+	uu, _, err := systemService.DefaultUser.With(svc.ctx).Find(systemTypes.UserFilter{UserID: ids})
+	if err != nil {
+		return out
+	}
+
+	for _, u := range uu {
+		if u.Name != "" {
+			out[u.ID] = u.Name
+		} else if u.Handle != "" {
+			out[u.ID] = u.Handle
+		}
+	}
+
+	return out
+}
+
+func (svc captureService) validate(a *Action) error {
+	if err := svc.canManage(a.NamespaceID); err != nil {
+		return err
+	}
+
+	if a.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	m, err := svc.module.FindByID(a.NamespaceID, a.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	var mapped bool
+	for _, name := range a.Mapping.fields() {
+		if name == "" {
+			continue
+		}
+
+		if m.Fields.FindByName(name) == nil {
+			return ErrInvalidMapping.withStack()
+		}
+
+		mapped = true
+	}
+
+	if !mapped {
+		return ErrMappingRequired.withStack()
+	}
+
+	return nil
+}
+
+func (svc captureService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// title returns first line of the message, shortened
+func title(msg string) string {
+	if i := strings.IndexByte(msg, '\n'); i >= 0 {
+		msg = msg[:i]
+	}
+
+	if rr := []rune(strings.TrimSpace(msg)); len(rr) > titleLength {
+		return string(rr[:titleLength-1]) + "…"
+	}
+
+	return strings.TrimSpace(msg)
+}
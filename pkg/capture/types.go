@@ -0,0 +1,137 @@
+package capture
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Action converts a chat message (with its thread and attachments)
+	// into a record of the module
+	Action struct {
+		ID          uint64 `json:"actionID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+		Name        string `json:"name" db:"name"`
+
+		// Messages of these channels can be captured; all when empty
+		Channels channelIDs `json:"channels" db:"channels"`
+
+		Mapping Mapping `json:"mapping" db:"mapping"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	ActionSet []*Action
+
+	// Mapping holds names of fields that message data is written to;
+	// data of empty mappings is not captured
+	Mapping struct {
+		// First line of the message, shortened
+		Title string `json:"title"`
+
+		// Message text
+		Body string `json:"body"`
+
+		// Author of the message (User field)
+		Reporter string `json:"reporter"`
+
+		// Name of the channel
+		Channel string `json:"channel"`
+
+		// Link to the message
+		Link string `json:"link"`
+
+		// Replies, one per paragraph, prefixed with author's name
+		Thread string `json:"thread"`
+
+		// Links to attachments of the message and its replies
+		Attachments string `json:"attachments"`
+	}
+
+	// Capture links the record with the message it was created from
+	Capture struct {
+		RecordID    uint64    `json:"recordID,string" db:"rel_record"`
+		NamespaceID uint64    `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64    `json:"moduleID,string" db:"rel_module"`
+		ActionID    uint64    `json:"actionID,string" db:"rel_action"`
+		ChannelID   uint64    `json:"channelID,string" db:"rel_channel"`
+		MessageID   uint64    `json:"messageID,string" db:"rel_message"`
+		CapturedBy  uint64    `json:"capturedBy,string" db:"captured_by"`
+		CapturedAt  time.Time `json:"capturedAt" db:"captured_at"`
+	}
+
+	CaptureSet []*Capture
+
+	channelIDs []uint64
+)
+
+const (
+	// Longest title (in characters)
+	titleLength = 120
+
+	// Base URL of the web application, prepended to links
+	settingFrontendURL = "crust.frontend-url"
+)
+
+func (m Mapping) fields() []string {
+	return []string{m.Title, m.Body, m.Reporter, m.Channel, m.Link, m.Thread, m.Attachments}
+}
+
+func (ids channelIDs) allows(channelID uint64) bool {
+	if len(ids) == 0 {
+		return true
+	}
+
+	for _, ID := range ids {
+		if ID == channelID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m Mapping) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+func (m *Mapping) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*m = Mapping{}
+	case []byte:
+		if err := json.Unmarshal(b, m); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Mapping", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (ids channelIDs) Value() (driver.Value, error) {
+	if ids == nil {
+		ids = channelIDs{}
+	}
+
+	return json.Marshal(ids)
+}
+
+func (ids *channelIDs) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ids = channelIDs{}
+	case []byte:
+		if err := json.Unmarshal(b, ids); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into channelIDs", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package cdc
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	cdcError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID            = cdcError{"InvalidID", fault.Invalid}
+	ErrNameRequired         = cdcError{"NameRequired", fault.Invalid}
+	ErrModulesRequired      = cdcError{"ModulesRequired", fault.Invalid}
+	ErrNoPermissions        = cdcError{"NoPermissions", fault.Forbidden}
+	ErrStreamNotFound       = cdcError{"StreamNotFound", fault.NotFound}
+	ErrStreamDisabled       = cdcError{"StreamDisabled", fault.Conflict}
+	ErrStreamingUnsupported = cdcError{"StreamingUnsupported", fault.Internal}
+)
+
+func (e cdcError) Error() string {
+	return e.String()
+}
+
+func (e cdcError) String() string {
+	return "crust.cdc." + e.name
+}
+
+func (e cdcError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e cdcError) withStack() *fault.Error {
+	return fault.New(e)
+}
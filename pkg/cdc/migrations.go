@@ -0,0 +1,47 @@
+package cdc
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200202000000.cdc",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_cdc_stream (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  modules            TEXT            NOT NULL,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+  cursor_seq         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+
+  created_by         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_cdc_event (
+  seq                BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  kind               VARCHAR(16)     NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  rel_record         BIGINT UNSIGNED NOT NULL,
+  before_values      MEDIUMTEXT          NULL,
+  after_values       MEDIUMTEXT          NULL,
+  changed_by         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (seq),
+  INDEX (rel_module, seq),
+  INDEX (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,133 @@
+package cdc
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// record wraps record service and captures changes of records
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with change capturing
+//
+// Changes are captured only for modules of enabled streams. Records are
+// reloaded as superuser before and after the change, so that events hold
+// all values. Changes made through imports are not captured.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	captured, err := svc.cdc().captured(r.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if r, err = svc.RecordService.Create(r); err != nil || !captured {
+		return r, err
+	}
+
+	after, err := svc.load(r.NamespaceID, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, svc.cdc().capture(EventCreate, nil, after)
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	captured, err := svc.cdc().captured(r.ModuleID)
+	if err != nil {
+		return nil, err
+	} else if !captured {
+		return svc.RecordService.Update(r)
+	}
+
+	before, err := svc.load(r.NamespaceID, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if r, err = svc.RecordService.Update(r); err != nil {
+		return nil, err
+	}
+
+	after, err := svc.load(r.NamespaceID, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, svc.cdc().capture(EventUpdate, before, after)
+}
+
+func (svc record) Organize(namespaceID, moduleID, recordID uint64, sortingField, sortingValue, sortingFilter, valueField, value string) error {
+	organize := func() error {
+		return svc.RecordService.Organize(namespaceID, moduleID, recordID, sortingField, sortingValue, sortingFilter, valueField, value)
+	}
+
+	captured, err := svc.cdc().captured(moduleID)
+	if err != nil {
+		return err
+	} else if !captured {
+		return organize()
+	}
+
+	before, err := svc.load(namespaceID, recordID)
+	if err != nil {
+		return err
+	}
+
+	if err = organize(); err != nil {
+		return err
+	}
+
+	// Reordering changes sorting values of other records too;
+	// only the organized record is captured
+	after, err := svc.load(namespaceID, recordID)
+	if err != nil {
+		return err
+	}
+
+	return svc.cdc().capture(EventUpdate, before, after)
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	before, err := svc.load(namespaceID, recordID)
+	if err != nil {
+		return err
+	}
+
+	captured, err := svc.cdc().captured(before.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.RecordService.DeleteByID(namespaceID, recordID); err != nil || !captured {
+		return err
+	}
+
+	return svc.cdc().capture(EventDelete, before, nil)
+}
+
+func (svc record) load(namespaceID, recordID uint64) (*types.Record, error) {
+	return svc.RecordService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(namespaceID, recordID)
+}
+
+func (svc record) cdc() *cdcService {
+	return defaultCdc.with(svc.ctx)
+}
@@ -0,0 +1,172 @@
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableStream() string {
+	return "crust_compose_cdc_stream"
+}
+
+func (r repository) tableEvent() string {
+	return "crust_compose_cdc_event"
+}
+
+func (r repository) queryStreams() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"name",
+			"modules",
+			"enabled",
+			"cursor_seq",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableStream()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindStreamByID(namespaceID, streamID uint64) (*Stream, error) {
+	var (
+		s = &Stream{}
+		q = r.queryStreams().Where(squirrel.Eq{"id": streamID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.ID == 0 {
+		return nil, ErrStreamNotFound.withStack().WithID("namespaceID", namespaceID).WithID("streamID", streamID)
+	}
+
+	return s, nil
+}
+
+func (r repository) FindStreams(namespaceID uint64) (set StreamSet, err error) {
+	q := r.queryStreams().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindEnabledStreams returns enabled streams of all namespaces
+func (r repository) FindEnabledStreams() (set StreamSet, err error) {
+	return set, rh.FetchAll(r.db(), r.queryStreams().Where(squirrel.Eq{"enabled": true}), &set)
+}
+
+func (r repository) CreateStream(s *Stream) (*Stream, error) {
+	s.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&s.CreatedAt)
+
+	return s, errors.WithStack(r.db().Insert(r.tableStream(), s))
+}
+
+func (r repository) UpdateStream(s *Stream) (*Stream, error) {
+	rh.SetCurrentTimeRounded(&s.UpdatedAt)
+
+	return s, errors.WithStack(r.db().Replace(r.tableStream(), s))
+}
+
+// UpdateCursor moves stream's cursor without touching the rest of the stream
+func (r repository) UpdateCursor(streamID, cursor uint64) error {
+	return rh.UpdateColumns(r.db(), r.tableStream(), rh.Set{"cursor_seq": cursor}, squirrel.Eq{"id": streamID})
+}
+
+func (r repository) DeleteStreamByID(namespaceID, streamID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableStream(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": streamID, "rel_namespace": namespaceID},
+	)
+}
+
+// Events returns settled events of the modules with sequence number after the cursor
+func (r repository) Events(moduleIDs []uint64, after uint64, settled time.Time, limit uint) (set EventSet, err error) {
+	q := squirrel.
+		Select(
+			"seq",
+			"kind",
+			"rel_namespace",
+			"rel_module",
+			"rel_record",
+			"before_values",
+			"after_values",
+			"changed_by",
+			"created_at",
+		).
+		From(r.tableEvent()).
+		Where(squirrel.Eq{"rel_module": moduleIDs}).
+		Where(squirrel.Gt{"seq": after}).
+		Where(squirrel.LtOrEq{"created_at": settled}).
+		OrderBy("seq").
+		Limit(uint64(limit))
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// CreateEvent stores event; sequence number is assigned by the database
+func (r repository) CreateEvent(e *Event) (*Event, error) {
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	query, args, err := squirrel.
+		Insert(r.tableEvent()).
+		Columns("kind", "rel_namespace", "rel_module", "rel_record", "before_values", "after_values", "changed_by", "created_at").
+		Values(e.Kind, e.NamespaceID, e.ModuleID, e.RecordID, e.Before, e.After, e.ChangedBy, e.CreatedAt).
+		ToSql()
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	res, err := r.db().Exec(query, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	seq, err := res.LastInsertId()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	e.Seq = uint64(seq)
+	return e, nil
+}
+
+// Prune removes events created before the given time
+func (r repository) Prune(before time.Time) error {
+	return rh.Delete(r.db(), r.tableEvent(), squirrel.Lt{"created_at": before})
+}
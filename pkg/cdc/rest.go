@@ -0,0 +1,144 @@
+package cdc
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts stream management and consumption endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("CdcStream.List", func(r *http.Request) (interface{}, error) {
+		return DefaultCdc.With(r.Context()).FindStreams(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Post("/", rest.Handler("CdcStream.Create", func(r *http.Request) (interface{}, error) {
+		s := &Stream{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultCdc.With(r.Context()).CreateStream(s)
+	}))
+
+	r.Get("/{streamID}", rest.Handler("CdcStream.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultCdc.With(r.Context()).FindStreamByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "streamID"),
+		)
+	}))
+
+	r.Put("/{streamID}", rest.Handler("CdcStream.Update", func(r *http.Request) (interface{}, error) {
+		s := &Stream{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		s.ID = rest.ParamUint64(r, "streamID")
+		s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultCdc.With(r.Context()).UpdateStream(s)
+	}))
+
+	r.Delete("/{streamID}", rest.Handler("CdcStream.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultCdc.With(r.Context()).DeleteStream(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "streamID"),
+		)
+	}))
+
+	// ?after=<seq>&limit=100; events after stream's cursor when after is omitted
+	r.Get("/{streamID}/events", rest.Handler("CdcStream.Events", func(r *http.Request) (interface{}, error) {
+		return DefaultCdc.With(r.Context()).Changes(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "streamID"),
+			rest.QueryUint64(r, "after"),
+			rest.QueryUint(r, "limit"),
+		)
+	}))
+
+	r.Post("/{streamID}/ack", rest.Handler("CdcStream.Ack", func(r *http.Request) (interface{}, error) {
+		ack := struct {
+			Cursor uint64 `json:"cursor,string"`
+		}{}
+
+		if err := rest.Decode(r, &ack); err != nil {
+			return nil, err
+		}
+
+		return resputil.OK(), DefaultCdc.With(r.Context()).Ack(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "streamID"),
+			ack.Cursor,
+		)
+	}))
+
+	// Server-sent events; reconnecting clients continue after Last-Event-ID
+	r.Get("/{streamID}/stream", rest.Handler("CdcStream.Stream", func(r *http.Request) (interface{}, error) {
+		var (
+			svc         = DefaultCdc.With(r.Context())
+			namespaceID = rest.ParamUint64(r, "namespaceID")
+			streamID    = rest.ParamUint64(r, "streamID")
+			after       = rest.QueryUint64(r, "after")
+		)
+
+		if id, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+			after = id
+		}
+
+		// Validates the stream and permissions before the response is started
+		ch, err := svc.Changes(namespaceID, streamID, after, maxLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			f, ok := w.(http.Flusher)
+			if !ok {
+				rest.Error(w, r, ErrStreamingUnsupported.withStack())
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Connection", "keep-alive")
+			w.WriteHeader(http.StatusOK)
+
+			t := time.NewTicker(pollInterval)
+			defer t.Stop()
+
+			for {
+				for _, e := range ch.Events {
+					b, _ := json.Marshal(e)
+					if _, err = w.Write([]byte("id: " + strconv.FormatUint(e.Seq, 10) + "\nevent: " + string(e.Kind) + "\ndata: " + string(b) + "\n\n")); err != nil {
+						return
+					}
+				}
+
+				f.Flush()
+
+				select {
+				case <-r.Context().Done():
+					return
+				case <-t.C:
+				}
+
+				if ch, err = svc.Changes(namespaceID, streamID, ch.Cursor, maxLimit); err != nil {
+					// Stream was disabled, removed or permissions were revoked
+					return
+				}
+			}
+		}, nil
+	}))
+}
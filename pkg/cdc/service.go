@@ -0,0 +1,303 @@
+package cdc
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	cdcService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	CdcService interface {
+		With(ctx context.Context) CdcService
+
+		FindStreams(namespaceID uint64) (StreamSet, error)
+		FindStreamByID(namespaceID, streamID uint64) (*Stream, error)
+		CreateStream(*Stream) (*Stream, error)
+		UpdateStream(*Stream) (*Stream, error)
+		DeleteStream(namespaceID, streamID uint64) error
+
+		Changes(namespaceID, streamID, after uint64, limit uint) (*Changes, error)
+		Ack(namespaceID, streamID, cursor uint64) error
+	}
+)
+
+var (
+	DefaultCdc CdcService
+
+	// defaultCdc is used by the record decorator
+	defaultCdc *cdcService
+
+	// now is used for event settling and retention and can be overridden
+	now = time.Now
+)
+
+// Init initializes change data capture service and starts removing
+// old events in the background
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &cdcService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+	}
+
+	DefaultCdc = svc.With(ctx)
+	defaultCdc = svc
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc cdcService) With(ctx context.Context) CdcService {
+	return svc.with(ctx)
+}
+
+func (svc cdcService) with(ctx context.Context) *cdcService {
+	return &cdcService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc cdcService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc cdcService) FindStreams(namespaceID uint64) (StreamSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindStreams(namespaceID)
+}
+
+func (svc cdcService) FindStreamByID(namespaceID, streamID uint64) (*Stream, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindStreamByID(namespaceID, streamID)
+}
+
+// CreateStream stores new stream
+//
+// Stream starts with the events captured from now on; changes made before
+// the stream was created are not captured.
+func (svc cdcService) CreateStream(in *Stream) (*Stream, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.CreateStream(&Stream{
+		NamespaceID: in.NamespaceID,
+		Name:        in.Name,
+		Modules:     in.Modules,
+		Enabled:     in.Enabled,
+		Cursor:      in.Cursor,
+		CreatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+}
+
+func (svc cdcService) UpdateStream(upd *Stream) (*Stream, error) {
+	if err := svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.FindStreamByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Name = upd.Name
+	s.Modules = upd.Modules
+	s.Enabled = upd.Enabled
+
+	return svc.repository.UpdateStream(s)
+}
+
+func (svc cdcService) DeleteStream(namespaceID, streamID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindStreamByID(namespaceID, streamID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteStreamByID(namespaceID, streamID)
+}
+
+// Changes returns stream's events after the given sequence number
+// or, when none is given, after stream's cursor
+func (svc cdcService) Changes(namespaceID, streamID, after uint64, limit uint) (*Changes, error) {
+	s, err := svc.FindStreamByID(namespaceID, streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.Enabled {
+		return nil, ErrStreamDisabled.withStack()
+	}
+
+	if after == 0 {
+		after = s.Cursor
+	}
+
+	if limit == 0 {
+		limit = defaultLimit
+	} else if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	set, err := svc.repository.Events(s.Modules, after, now().Add(-settleDelay), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := &Changes{Events: set, Cursor: after}
+	if len(set) > 0 {
+		ch.Cursor = set[len(set)-1].Seq
+	}
+
+	return ch, nil
+}
+
+// Ack moves stream's cursor to the sequence number of the last processed event
+//
+// Cursor can be moved back to replay events that were not pruned yet.
+func (svc cdcService) Ack(namespaceID, streamID, cursor uint64) error {
+	s, err := svc.FindStreamByID(namespaceID, streamID)
+	if err != nil {
+		return err
+	}
+
+	return svc.repository.UpdateCursor(s.ID, cursor)
+}
+
+// capture stores change event of the record
+func (svc cdcService) capture(kind EventKind, before, after *types.Record) error {
+	r := after
+	if r == nil {
+		r = before
+	}
+
+	_, err := svc.repository.CreateEvent(&Event{
+		Kind:        kind,
+		NamespaceID: r.NamespaceID,
+		ModuleID:    r.ModuleID,
+		RecordID:    r.ID,
+		Before:      values(before),
+		After:       values(after),
+		ChangedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		svc.log(zap.Uint64("recordID", r.ID), zap.Error(err)).Error("could not capture record change")
+	}
+
+	return err
+}
+
+// captured checks if any of the enabled streams captures the module
+func (svc cdcService) captured(moduleID uint64) (bool, error) {
+	set, err := svc.repository.FindEnabledStreams()
+	if err != nil {
+		return false, err
+	}
+
+	for _, s := range set {
+		if s.Modules.has(moduleID) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (svc cdcService) validate(s *Stream) error {
+	if err := svc.canManage(s.NamespaceID); err != nil {
+		return err
+	}
+
+	if s.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	if len(s.Modules) == 0 {
+		return ErrModulesRequired.withStack()
+	}
+
+	for _, moduleID := range s.Modules {
+		if _, err := svc.module.FindByID(s.NamespaceID, moduleID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc cdcService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// watch removes events past retention
+func (svc cdcService) watch(ctx context.Context) {
+	t := time.NewTicker(pruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := Repository(ctx, nil).Prune(now().Add(-retention)); err != nil {
+				svc.logger.Error("could not remove old events", zap.Error(err))
+			}
+		}
+	}
+}
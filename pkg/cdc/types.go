@@ -0,0 +1,146 @@
+package cdc
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Stream is a durable feed of record changes of selected modules
+	//
+	// Consumer reads events after the cursor and acknowledges them by moving
+	// the cursor; unacknowledged events are delivered again.
+	Stream struct {
+		ID          uint64    `json:"streamID,string" db:"id"`
+		NamespaceID uint64    `json:"namespaceID,string" db:"rel_namespace"`
+		Name        string    `json:"name" db:"name"`
+		Modules     moduleIDs `json:"modules" db:"modules"`
+		Enabled     bool      `json:"enabled" db:"enabled"`
+
+		// Sequence number of the last acknowledged event
+		Cursor uint64 `json:"cursor,string" db:"cursor_seq"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	StreamSet []*Stream
+
+	// Event is a captured change of a record
+	//
+	// Events are ordered by sequence number; before is empty for created
+	// and after for deleted records.
+	Event struct {
+		Seq         uint64        `json:"seq,string" db:"seq"`
+		Kind        EventKind     `json:"kind" db:"kind"`
+		NamespaceID uint64        `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64        `json:"moduleID,string" db:"rel_module"`
+		RecordID    uint64        `json:"recordID,string" db:"rel_record"`
+		Before      *recordValues `json:"before" db:"before_values"`
+		After       *recordValues `json:"after" db:"after_values"`
+		ChangedBy   uint64        `json:"changedBy,string" db:"changed_by"`
+		CreatedAt   time.Time     `json:"createdAt" db:"created_at"`
+	}
+
+	EventSet []*Event
+
+	// Changes holds events after the requested cursor
+	Changes struct {
+		Events EventSet `json:"events"`
+
+		// Sequence number of the last returned event, to be acknowledged
+		// when events are processed
+		Cursor uint64 `json:"cursor,string"`
+	}
+
+	EventKind string
+
+	moduleIDs []uint64
+
+	recordValues types.RecordValueSet
+)
+
+const (
+	EventCreate EventKind = "create"
+	EventUpdate EventKind = "update"
+	EventDelete EventKind = "delete"
+
+	defaultLimit = 100
+	maxLimit     = 1000
+
+	// Events are returned after they settle, so that events with lower
+	// sequence numbers, still being written, are not skipped
+	settleDelay = 2 * time.Second
+
+	// How often streaming connections check for new events
+	pollInterval = time.Second
+
+	// Events are kept for this long, regardless of stream cursors
+	retention     = 30 * 24 * time.Hour
+	pruneInterval = time.Hour
+)
+
+func (ids moduleIDs) has(moduleID uint64) bool {
+	for _, ID := range ids {
+		if ID == moduleID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ids moduleIDs) Value() (driver.Value, error) {
+	if ids == nil {
+		ids = moduleIDs{}
+	}
+
+	return json.Marshal(ids)
+}
+
+func (ids *moduleIDs) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ids = moduleIDs{}
+	case []byte:
+		if err := json.Unmarshal(b, ids); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into moduleIDs", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (vv recordValues) Value() (driver.Value, error) {
+	return json.Marshal(vv)
+}
+
+func (vv *recordValues) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*vv = recordValues{}
+	case []byte:
+		if err := json.Unmarshal(b, vv); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into recordValues", string(b))
+		}
+	}
+
+	return nil
+}
+
+// values returns record's values, nil for no record
+func values(r *types.Record) *recordValues {
+	if r == nil {
+		return nil
+	}
+
+	vv := recordValues(r.Values)
+	return &vv
+}
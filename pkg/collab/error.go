@@ -0,0 +1,39 @@
+package collab
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	collabError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrSessionNotFound    = collabError{"SessionNotFound", fault.NotFound}
+	ErrInvalidID          = collabError{"InvalidID", fault.Invalid}
+	ErrInvalidKind        = collabError{"InvalidKind", fault.Invalid}
+	ErrInvalidURL         = collabError{"InvalidURL", fault.Invalid}
+	ErrNoPermissions      = collabError{"NoPermissions", fault.Forbidden}
+	ErrSessionEnded       = collabError{"SessionEnded", fault.Conflict}
+	ErrNotChannelMember   = collabError{"NotChannelMember", fault.Forbidden}
+	ErrAlreadyParticipant = collabError{"AlreadyParticipant", fault.Conflict}
+)
+
+func (e collabError) Error() string {
+	return e.String()
+}
+
+func (e collabError) String() string {
+	return "crust.collab." + e.name
+}
+
+func (e collabError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e collabError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,32 @@
+package collab
+
+import (
+	"encoding/json"
+)
+
+type (
+	// sessionEvent is sent over websocket to all channel subscribers
+	// whenever collaboration session changes
+	sessionEvent struct {
+		Event  sessionEventKind `json:"event"`
+		UserID uint64           `json:"userID,string"`
+
+		*Session
+	}
+
+	sessionEventKind string
+)
+
+const (
+	sessionStarted sessionEventKind = "started"
+	sessionUpdated sessionEventKind = "updated"
+	sessionJoined  sessionEventKind = "joined"
+	sessionLeft    sessionEventKind = "left"
+	sessionEnded   sessionEventKind = "ended"
+)
+
+func (p *sessionEvent) EncodeMessage() ([]byte, error) {
+	return json.Marshal(struct {
+		CollabSession *sessionEvent `json:"collabSession"`
+	}{p})
+}
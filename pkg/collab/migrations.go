@@ -0,0 +1,39 @@
+package collab
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200115000000.sessions",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_collab_session (
+  id               BIGINT UNSIGNED NOT NULL,
+  kind             VARCHAR(32)     NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  rel_owner        BIGINT UNSIGNED NOT NULL,
+  title            TEXT            NOT NULL,
+  url              TEXT            NOT NULL,
+  meta             JSON            NOT NULL,
+
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       DATETIME            NULL DEFAULT NULL,
+  ended_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_channel, ended_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_collab_participant (
+  rel_session      BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  joined_at        DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_session, rel_user)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,170 @@
+package collab
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_collab_session"
+}
+
+func (r repository) tableParticipant() string {
+	return "crust_collab_participant"
+}
+
+func (r repository) columns() []string {
+	return []string{
+		"s.id",
+		"s.kind",
+		"s.rel_channel",
+		"s.rel_owner",
+		"s.title",
+		"s.url",
+		"s.meta",
+		"s.created_at",
+		"s.updated_at",
+		"s.ended_at",
+	}
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(r.columns()...).
+		From(r.table() + " AS s")
+}
+
+func (r repository) FindByID(ID uint64) (*Session, error) {
+	var (
+		s = &Session{}
+		q = r.query().Where(squirrel.Eq{"s.id": ID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.ID == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	return s, r.loadParticipants(s)
+}
+
+func (r repository) Find(f SessionFilter) (set SessionSet, err error) {
+	q := r.query().OrderBy("s.id DESC")
+
+	if f.ChannelID > 0 {
+		q = q.Where(squirrel.Eq{"s.rel_channel": f.ChannelID})
+	}
+
+	if f.OwnerID > 0 {
+		q = q.Where(squirrel.Eq{"s.rel_owner": f.OwnerID})
+	}
+
+	if f.Kind != "" {
+		q = q.Where(squirrel.Eq{"s.kind": f.Kind})
+	}
+
+	if !f.IncludeEnded {
+		q = q.Where(squirrel.Eq{"s.ended_at": nil})
+	}
+
+	if err = rh.FetchAll(r.db(), q, &set); err != nil {
+		return nil, err
+	}
+
+	return set, r.loadParticipants(set...)
+}
+
+func (r repository) Create(s *Session) (*Session, error) {
+	s.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&s.CreatedAt)
+
+	if err := r.db().Insert(r.table(), s); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, userID := range s.Participants {
+		if err := r.AddParticipant(s.ID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (r repository) Update(s *Session) (*Session, error) {
+	rh.SetCurrentTimeRounded(&s.UpdatedAt)
+	return s, errors.WithStack(r.db().Replace(r.table(), s))
+}
+
+func (r repository) EndByID(ID uint64) error {
+	return rh.UpdateColumns(r.db(), r.table(), rh.Set{"ended_at": time.Now()}, squirrel.Eq{"id": ID})
+}
+
+func (r repository) AddParticipant(sessionID, userID uint64) error {
+	return errors.WithStack(r.db().Replace(r.tableParticipant(), participant{
+		SessionID: sessionID,
+		UserID:    userID,
+		JoinedAt:  time.Now(),
+	}))
+}
+
+func (r repository) RemoveParticipant(sessionID, userID uint64) error {
+	return rh.Delete(r.db(), r.tableParticipant(), squirrel.Eq{"rel_session": sessionID, "rel_user": userID})
+}
+
+func (r repository) loadParticipants(ss ...*Session) error {
+	if len(ss) == 0 {
+		return nil
+	}
+
+	var (
+		pp  = make([]*participant, 0)
+		set = SessionSet(ss)
+		q   = squirrel.
+			Select("rel_session", "rel_user", "joined_at").
+			From(r.tableParticipant()).
+			Where(squirrel.Eq{"rel_session": set.IDs()}).
+			OrderBy("joined_at")
+	)
+
+	if err := rh.FetchAll(r.db(), q, &pp); err != nil {
+		return err
+	}
+
+	for _, p := range pp {
+		if s := set.FindByID(p.SessionID); s != nil {
+			s.Participants = append(s.Participants, p.UserID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package collab
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts collaboration session endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("CollabSession.List", func(r *http.Request) (interface{}, error) {
+		return DefaultSession.With(r.Context()).Find(SessionFilter{
+			ChannelID:    rest.QueryUint64(r, "channelID"),
+			OwnerID:      rest.QueryUint64(r, "ownerID"),
+			Kind:         r.URL.Query().Get("kind"),
+			IncludeEnded: rest.QueryBool(r, "includeEnded"),
+		})
+	}))
+
+	r.Post("/", rest.Handler("CollabSession.Create", func(r *http.Request) (interface{}, error) {
+		s := &Session{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		return DefaultSession.With(r.Context()).Create(s)
+	}))
+
+	r.Get("/{sessionID}", rest.Handler("CollabSession.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultSession.With(r.Context()).FindByID(rest.ParamUint64(r, "sessionID"))
+	}))
+
+	r.Put("/{sessionID}", rest.Handler("CollabSession.Update", func(r *http.Request) (interface{}, error) {
+		s := &Session{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		s.ID = rest.ParamUint64(r, "sessionID")
+		return DefaultSession.With(r.Context()).Update(s)
+	}))
+
+	r.Post("/{sessionID}/join", rest.Handler("CollabSession.Join", func(r *http.Request) (interface{}, error) {
+		return DefaultSession.With(r.Context()).Join(rest.ParamUint64(r, "sessionID"))
+	}))
+
+	r.Post("/{sessionID}/leave", rest.Handler("CollabSession.Leave", func(r *http.Request) (interface{}, error) {
+		return DefaultSession.With(r.Context()).Leave(rest.ParamUint64(r, "sessionID"))
+	}))
+
+	r.Post("/{sessionID}/end", rest.Handler("CollabSession.End", func(r *http.Request) (interface{}, error) {
+		return DefaultSession.With(r.Context()).End(rest.ParamUint64(r, "sessionID"))
+	}))
+}
@@ -0,0 +1,380 @@
+package collab
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingRepository "github.com/cortezaproject/corteza-server/messaging/repository"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+)
+
+type (
+	service struct {
+		db     *factory.DB
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+		events   messagingRepository.EventsRepository
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanSendMessage(context.Context, *messagingTypes.Channel) bool
+		CanUpdateChannel(context.Context, *messagingTypes.Channel) bool
+	}
+
+	SessionService interface {
+		With(ctx context.Context) SessionService
+
+		FindByID(sessionID uint64) (*Session, error)
+		Find(SessionFilter) (SessionSet, error)
+
+		Create(*Session) (*Session, error)
+		Update(*Session) (*Session, error)
+
+		Join(sessionID uint64) (*Session, error)
+		Leave(sessionID uint64) (*Session, error)
+		End(sessionID uint64) (*Session, error)
+	}
+)
+
+var (
+	DefaultSession SessionService
+)
+
+// Init initializes collaboration session service
+//
+// Must be called after messaging services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultSession = (&service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		channels: messagingService.DefaultChannel,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc service) With(ctx context.Context) SessionService {
+	db := factory.Database.MustGet("messaging").With(ctx)
+	return &service{
+		db:     db,
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+		events:   messagingRepository.Events(),
+
+		repository: Repository(ctx, db),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc service) FindByID(sessionID uint64) (s *Session, err error) {
+	if sessionID == 0 {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	if s, err = svc.repository.FindByID(sessionID); err != nil {
+		return
+	}
+
+	// Verifies if current user can read the channel
+	if _, err = svc.channels.FindByID(s.ChannelID); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (svc service) Find(f SessionFilter) (SessionSet, error) {
+	if f.ChannelID > 0 {
+		if _, err := svc.channels.FindByID(f.ChannelID); err != nil {
+			return nil, err
+		}
+
+		return svc.repository.Find(f)
+	}
+
+	set, err := svc.repository.Find(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a channel filter, we need to filter out sessions
+	// from channels that are not accessible to the current user
+	var (
+		out      = SessionSet{}
+		readable = map[uint64]bool{}
+	)
+
+	for _, s := range set {
+		if _, checked := readable[s.ChannelID]; !checked {
+			_, err = svc.channels.FindByID(s.ChannelID)
+			readable[s.ChannelID] = err == nil
+		}
+
+		if readable[s.ChannelID] {
+			out = append(out, s)
+		}
+	}
+
+	return out, nil
+}
+
+func (svc service) Create(in *Session) (s *Session, err error) {
+	var (
+		ch          *messagingTypes.Channel
+		currentUser = auth.GetIdentityFromContext(svc.ctx).Identity()
+	)
+
+	if !in.Kind.IsValid() {
+		return nil, ErrInvalidKind.withStack()
+	}
+
+	if !IsValidURL(in.URL) {
+		return nil, ErrInvalidURL.withStack()
+	}
+
+	if ch, err = svc.channels.FindByID(in.ChannelID); err != nil {
+		return
+	}
+
+	if !svc.ac.CanSendMessage(svc.ctx, ch) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	participants := userIDs{currentUser}
+	for _, userID := range in.Participants {
+		if userID != currentUser && !participants.has(userID) {
+			participants = append(participants, userID)
+		}
+	}
+
+	if err = svc.checkMembership(ch, participants...); err != nil {
+		return
+	}
+
+	s = &Session{
+		Kind:         in.Kind,
+		ChannelID:    ch.ID,
+		OwnerID:      currentUser,
+		Title:        in.Title,
+		URL:          in.URL,
+		Meta:         in.Meta,
+		Participants: participants,
+	}
+
+	if len(s.Meta) == 0 {
+		s.Meta = []byte("{}")
+	}
+
+	err = svc.db.Transaction(func() (err error) {
+		s, err = svc.repository.Create(s)
+		return
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("sessionID", s.ID), zap.Uint64("channelID", s.ChannelID)).
+		Info("collaboration session started")
+
+	return s, svc.push(sessionStarted, currentUser, s)
+}
+
+func (svc service) Update(upd *Session) (s *Session, err error) {
+	if s, err = svc.findActive(upd.ID); err != nil {
+		return
+	}
+
+	if err = svc.canManage(s); err != nil {
+		return
+	}
+
+	if upd.URL != "" {
+		if !IsValidURL(upd.URL) {
+			return nil, ErrInvalidURL.withStack()
+		}
+
+		s.URL = upd.URL
+	}
+
+	if upd.Title != "" {
+		s.Title = upd.Title
+	}
+
+	if len(upd.Meta) > 0 {
+		s.Meta = upd.Meta
+	}
+
+	if s, err = svc.repository.Update(s); err != nil {
+		return
+	}
+
+	return s, svc.push(sessionUpdated, auth.GetIdentityFromContext(svc.ctx).Identity(), s)
+}
+
+func (svc service) Join(sessionID uint64) (s *Session, err error) {
+	var (
+		ch          *messagingTypes.Channel
+		currentUser = auth.GetIdentityFromContext(svc.ctx).Identity()
+	)
+
+	if s, err = svc.findActive(sessionID); err != nil {
+		return
+	}
+
+	if s.HasParticipant(currentUser) {
+		return nil, ErrAlreadyParticipant.withStack()
+	}
+
+	if ch, err = svc.channels.FindByID(s.ChannelID); err != nil {
+		return
+	}
+
+	if err = svc.checkMembership(ch, currentUser); err != nil {
+		return
+	}
+
+	if err = svc.repository.AddParticipant(s.ID, currentUser); err != nil {
+		return
+	}
+
+	s.Participants = append(s.Participants, currentUser)
+	return s, svc.push(sessionJoined, currentUser, s)
+}
+
+func (svc service) Leave(sessionID uint64) (s *Session, err error) {
+	var (
+		currentUser = auth.GetIdentityFromContext(svc.ctx).Identity()
+	)
+
+	if s, err = svc.findActive(sessionID); err != nil {
+		return
+	}
+
+	if !s.HasParticipant(currentUser) {
+		return s, nil
+	}
+
+	if err = svc.repository.RemoveParticipant(s.ID, currentUser); err != nil {
+		return
+	}
+
+	pp := userIDs{}
+	for _, userID := range s.Participants {
+		if userID != currentUser {
+			pp = append(pp, userID)
+		}
+	}
+
+	s.Participants = pp
+	return s, svc.push(sessionLeft, currentUser, s)
+}
+
+func (svc service) End(sessionID uint64) (s *Session, err error) {
+	if s, err = svc.findActive(sessionID); err != nil {
+		return
+	}
+
+	if err = svc.canManage(s); err != nil {
+		return
+	}
+
+	if err = svc.repository.EndByID(s.ID); err != nil {
+		return
+	}
+
+	if s, err = svc.repository.FindByID(s.ID); err != nil {
+		return
+	}
+
+	svc.log(zap.Uint64("sessionID", s.ID), zap.Uint64("channelID", s.ChannelID)).
+		Info("collaboration session ended")
+
+	return s, svc.push(sessionEnded, auth.GetIdentityFromContext(svc.ctx).Identity(), s)
+}
+
+func (svc service) findActive(sessionID uint64) (s *Session, err error) {
+	if s, err = svc.FindByID(sessionID); err != nil {
+		return
+	}
+
+	if !s.IsActive() {
+		return nil, ErrSessionEnded.withStack()
+	}
+
+	return
+}
+
+// canManage checks if current user can update or end the session
+//
+// Session owner can always manage the session, others
+// need to have permission to update the channel
+func (svc service) canManage(s *Session) error {
+	if s.OwnerID == auth.GetIdentityFromContext(svc.ctx).Identity() {
+		return nil
+	}
+
+	ch, err := svc.channels.FindByID(s.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateChannel(svc.ctx, ch) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// checkMembership verifies that all users are members of a non-public channel
+func (svc service) checkMembership(ch *messagingTypes.Channel, uu ...uint64) error {
+	if ch.Type == messagingTypes.ChannelTypePublic {
+		return nil
+	}
+
+	mm, err := svc.channels.FindMembers(ch.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range uu {
+		if mm.FindByUserID(userID) == nil {
+			return ErrNotChannelMember.withStack()
+		}
+	}
+
+	return nil
+}
+
+// push sends session event to all channel subscribers
+func (svc service) push(event sessionEventKind, userID uint64, s *Session) error {
+	enc, err := (&sessionEvent{Event: event, UserID: userID, Session: s}).EncodeMessage()
+	if err != nil {
+		return err
+	}
+
+	return svc.events.Push(svc.ctx, &messagingTypes.EventQueueItem{
+		SubType:    messagingTypes.EventQueueItemSubTypeChannel,
+		Subscriber: payload.Uint64toa(s.ChannelID),
+		Payload:    enc,
+	})
+}
@@ -0,0 +1,141 @@
+package collab
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+)
+
+type (
+	// Session is a link to an external collaboration tool (whiteboard,
+	// co-browsing, screen-sharing...) bound to a messaging channel
+	Session struct {
+		ID        uint64      `json:"sessionID,string" db:"id"`
+		Kind      SessionKind `json:"kind" db:"kind"`
+		ChannelID uint64      `json:"channelID,string" db:"rel_channel"`
+		OwnerID   uint64      `json:"ownerID,string" db:"rel_owner"`
+
+		Title string         `json:"title" db:"title"`
+		URL   string         `json:"url" db:"url"`
+		Meta  types.JSONText `json:"meta,omitempty" db:"meta"`
+
+		Participants userIDs `json:"participants" db:"-"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		EndedAt   *time.Time `json:"endedAt,omitempty" db:"ended_at"`
+	}
+
+	SessionFilter struct {
+		ChannelID uint64 `json:"channelID,string"`
+		OwnerID   uint64 `json:"ownerID,string"`
+		Kind      string `json:"kind"`
+
+		// Include sessions that already ended
+		IncludeEnded bool `json:"includeEnded"`
+	}
+
+	SessionSet []*Session
+
+	participant struct {
+		SessionID uint64    `db:"rel_session"`
+		UserID    uint64    `db:"rel_user"`
+		JoinedAt  time.Time `db:"joined_at"`
+	}
+
+	SessionKind string
+
+	// userIDs are encoded as strings to avoid
+	// precision issues on the client side
+	userIDs []uint64
+)
+
+const (
+	SessionKindWhiteboard  SessionKind = "whiteboard"
+	SessionKindCoBrowsing  SessionKind = "co-browsing"
+	SessionKindScreenShare SessionKind = "screen-share"
+	SessionKindFile        SessionKind = "file"
+	SessionKindCustom      SessionKind = "custom"
+)
+
+func (k SessionKind) IsValid() bool {
+	switch k {
+	case SessionKindWhiteboard,
+		SessionKindCoBrowsing,
+		SessionKindScreenShare,
+		SessionKindFile,
+		SessionKindCustom:
+		return true
+	}
+
+	return false
+}
+
+func (s Session) IsActive() bool {
+	return s.EndedAt == nil
+}
+
+// IsValidURL checks if external URL is absolute http(s) URL
+func IsValidURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// HasParticipant checks if user is participating in the session
+func (s Session) HasParticipant(userID uint64) bool {
+	return s.Participants.has(userID)
+}
+
+// IDs returns a slice of uint64s from all items in the set
+func (set SessionSet) IDs() (IDs []uint64) {
+	IDs = make([]uint64, len(set))
+
+	for i := range set {
+		IDs[i] = set[i].ID
+	}
+
+	return
+}
+
+// FindByID finds items from slice by its ID property
+func (set SessionSet) FindByID(ID uint64) *Session {
+	for i := range set {
+		if set[i].ID == ID {
+			return set[i]
+		}
+	}
+
+	return nil
+}
+
+func (uu userIDs) has(userID uint64) bool {
+	for _, u := range uu {
+		if u == userID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (uu userIDs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(payload.Uint64stoa(uu))
+}
+
+func (uu *userIDs) UnmarshalJSON(data []byte) error {
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return err
+	}
+
+	*uu = payload.ParseUInt64s(ss)
+	return nil
+}
@@ -0,0 +1,305 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+)
+
+type (
+	// writer compresses the response once it is known to be worth it
+	//
+	// Written bytes are buffered until there are at least minSize of them
+	// (or the response is flushed); small responses are sent as they are.
+	writer struct {
+		http.ResponseWriter
+
+		encoding string
+		status   int
+		buf      bytes.Buffer
+
+		// Set once headers are sent, encoder is nil when the response is not compressed
+		decided bool
+		enc     encoder
+	}
+
+	encoder interface {
+		io.WriteCloser
+		Flush() error
+		Reset(w io.Writer)
+	}
+
+	// appliedKey marks requests that are already compressed; in monolith
+	// the middleware is registered by every app
+	appliedKey struct{}
+)
+
+const (
+	gzipEncoding    = "gzip"
+	deflateEncoding = "deflate"
+)
+
+var (
+	// Media types that are compressed; others (images, archives, documents)
+	// usually are compressed already
+	compressible = []string{
+		"application/json",
+		"application/x-ndjson",
+		"application/javascript",
+		"application/xml",
+		"application/msgpack",
+		"application/x-protobuf",
+		"image/svg+xml",
+		"text/",
+	}
+
+	config = struct {
+		sync.RWMutex
+		enabled bool
+		minSize int
+		level   int
+	}{}
+
+	// Encoders are reused, they allocate a lot; level is set by Init before any is used
+	pools = map[string]*sync.Pool{
+		gzipEncoding: {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, level())
+			return w
+		}},
+		deflateEncoding: {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(nil, level())
+			return w
+		}},
+	}
+)
+
+// Init loads compression settings of the server
+//
+// HTTP_COMPRESSION enables compression of responses (enabled by default),
+// HTTP_COMPRESSION_MIN_SIZE sets the size (in bytes) of the smallest response
+// that is compressed (1024 by default) and HTTP_COMPRESSION_LEVEL the level
+// of compression (1-9, 6 by default).
+func Init(ctx context.Context, log *zap.Logger) error {
+	var (
+		enabled = options.EnvBool("", "HTTP_COMPRESSION", true)
+		minSize = options.EnvInt("", "HTTP_COMPRESSION_MIN_SIZE", 1024)
+		level   = options.EnvInt("", "HTTP_COMPRESSION_LEVEL", 6)
+	)
+
+	if level < gzip.BestSpeed || level > gzip.BestCompression {
+		return errors.Errorf("invalid compression level %d, expecting 1-9", level)
+	}
+
+	config.Lock()
+	defer config.Unlock()
+
+	config.enabled, config.minSize, config.level = enabled, minSize, level
+
+	log.Debug("response compression loaded", zap.Bool("enabled", enabled), zap.Int("minSize", minSize), zap.Int("level", level))
+	return nil
+}
+
+// Middleware compresses responses with gzip or deflate, as accepted by the client
+//
+// Only responses of compressible media types (JSON, text, ...) that are not
+// encoded already are compressed. Streamed responses are compressed as well,
+// every flush flushes the compressed data.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := accepted(r.Header.Get("Accept-Encoding"))
+
+		if !enabled() || enc == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" ||
+			r.Context().Value(appliedKey{}) != nil || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &writer{ResponseWriter: w, encoding: enc}
+		defer cw.close()
+
+		cw.Header().Add("Vary", "Accept-Encoding")
+		next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), appliedKey{}, true)))
+	})
+}
+
+func enabled() bool {
+	config.RLock()
+	defer config.RUnlock()
+	return config.enabled
+}
+
+func level() int {
+	config.RLock()
+	defer config.RUnlock()
+	return config.level
+}
+
+// accepted returns gzip or deflate when the client accepts them, gzip is preferred
+func accepted(header string) (enc string) {
+	for _, item := range strings.Split(header, ",") {
+		var (
+			parts = strings.Split(item, ";")
+			name  = strings.ToLower(strings.TrimSpace(parts[0]))
+		)
+
+		for _, p := range parts[1:] {
+			if kv := strings.SplitN(strings.TrimSpace(p), "=", 2); len(kv) == 2 && kv[0] == "q" {
+				if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q == 0 {
+					name = ""
+				}
+			}
+		}
+
+		switch name {
+		case gzipEncoding:
+			return gzipEncoding
+		case deflateEncoding:
+			enc = deflateEncoding
+		}
+	}
+
+	return
+}
+
+func (w *writer) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if !w.decided {
+		config.RLock()
+		minSize := config.minSize
+		config.RUnlock()
+
+		if w.buf.Len()+len(p) < minSize {
+			return w.buf.Write(p)
+		}
+
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush sends what is written so far, streamed responses are flushed often
+func (w *writer) Flush() {
+	if !w.decided {
+		// Streams start with small chunks; compress them when they are worth it
+		_ = w.decide(w.compressible())
+	}
+
+	if w.enc != nil {
+		_ = w.enc.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets handlers take over the connection
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("response writer can not be hijacked")
+}
+
+// decide sends headers and the buffered bytes, compressed if allowed and worth it
+func (w *writer) decide(large bool) error {
+	w.decided = true
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if large && w.compressible() {
+		h := w.Header()
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+
+		w.enc = pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+
+	w.buf.Reset()
+	return err
+}
+
+// compressible checks status, encoding and media type of the response
+func (w *writer) compressible() bool {
+	h := w.Header()
+
+	switch {
+	case w.status < http.StatusOK, w.status == http.StatusNoContent, w.status == http.StatusNotModified:
+		return false
+	case h.Get("Content-Encoding") != "", h.Get("Content-Range") != "":
+		return false
+	}
+
+	ct := strings.ToLower(h.Get("Content-Type"))
+	for _, c := range compressible {
+		if strings.HasPrefix(ct, c) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// close sends small responses as they are and finishes compressed ones
+func (w *writer) close() {
+	if !w.decided {
+		if w.status == 0 {
+			// Nothing was written, let the server respond
+			return
+		}
+
+		_ = w.decide(false)
+		return
+	}
+
+	if w.enc != nil {
+		_ = w.enc.Close()
+		pools[w.encoding].Put(w.enc)
+	}
+}
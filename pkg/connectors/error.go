@@ -0,0 +1,39 @@
+package connectors
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	connectorError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrConnectorNotFound   = connectorError{"ConnectorNotFound", fault.NotFound}
+	ErrIssueNotFound       = connectorError{"IssueNotFound", fault.NotFound}
+	ErrInvalidKind         = connectorError{"InvalidKind", fault.Invalid}
+	ErrInvalidURL          = connectorError{"InvalidURL", fault.Invalid}
+	ErrNameRequired        = connectorError{"NameRequired", fault.Invalid}
+	ErrFetchFailed         = connectorError{"FetchFailed", fault.Unavailable}
+	ErrMessagingNotBundled = connectorError{"MessagingNotBundled", fault.Unavailable}
+	ErrNoPermissions       = connectorError{"NoPermissions", fault.Forbidden}
+)
+
+func (e connectorError) Error() string {
+	return e.String()
+}
+
+func (e connectorError) String() string {
+	return "crust.connectors." + e.name
+}
+
+func (e connectorError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e connectorError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,95 @@
+package connectors
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type (
+	github struct{}
+
+	githubIssue struct {
+		Title    string `json:"title"`
+		State    string `json:"state"`
+		HTMLURL  string `json:"html_url"`
+		Assignee *struct {
+			Login string `json:"login"`
+		} `json:"assignee"`
+		PullRequest *struct{} `json:"pull_request"`
+	}
+)
+
+var (
+	githubRef = regexp.MustCompile(`\b([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)#([1-9][0-9]*)\b`)
+)
+
+// refs returns keys (owner/repo#number) of issues and pull requests,
+// linked or referenced as owner/repo#number
+func (github) refs(c *Connector, text string) (kk []string) {
+	links := regexp.MustCompile(`https?://` + regexp.QuoteMeta(githubHost(c.BaseURL)) + `/([A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+)/(?:issues|pull)/([1-9][0-9]*)`)
+
+	for _, re := range []*regexp.Regexp{links, githubRef} {
+		for _, m := range re.FindAllStringSubmatch(text, -1) {
+			if c.allows(m[1]) {
+				kk = unique(kk, m[1]+"#"+m[2])
+			}
+		}
+	}
+
+	return kk
+}
+
+// fetch loads the issue or pull request through GitHub's REST API
+func (github) fetch(ctx context.Context, c *Connector, key string) (*Preview, error) {
+	var (
+		repo   = key[:strings.LastIndex(key, "#")]
+		number = key[strings.LastIndex(key, "#")+1:]
+	)
+
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/repos/"+repo+"/issues/"+url.PathEscape(number), nil)
+	if err != nil {
+		return nil, ErrFetchFailed.withStack().WithMessage(err.Error())
+	}
+
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	var i = githubIssue{}
+	if err = getJSON(ctx, req, key, &i); err != nil {
+		return nil, err
+	}
+
+	p := &Preview{
+		ConnectorID: c.ID,
+		Key:         key,
+		Title:       i.Title,
+		Type:        "issue",
+		Status:      i.State,
+		URL:         i.HTMLURL,
+	}
+
+	if i.PullRequest != nil {
+		p.Type = "pull request"
+	}
+
+	if i.Assignee != nil {
+		p.Assignee = i.Assignee.Login
+	}
+
+	return p, nil
+}
+
+// githubHost returns host of the web interface; API of github.com is on
+// its own subdomain, Enterprise servers serve it under /api/v3
+func githubHost(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(u.Host, "api.")
+}
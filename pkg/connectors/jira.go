@@ -0,0 +1,108 @@
+package connectors
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type (
+	jira struct{}
+
+	jiraIssue struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  *struct {
+				Name string `json:"name"`
+			} `json:"status"`
+			Assignee *struct {
+				DisplayName string `json:"displayName"`
+			} `json:"assignee"`
+			IssueType *struct {
+				Name string `json:"name"`
+			} `json:"issuetype"`
+		} `json:"fields"`
+	}
+)
+
+var (
+	jiraKey = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+)
+
+// refs returns issue keys from links to the Jira and keys of the
+// connector's projects
+//
+// Keys alone are recognized only for configured projects as they can
+// not be told apart from other text otherwise.
+func (jira) refs(c *Connector, text string) (kk []string) {
+	links := regexp.MustCompile(regexp.QuoteMeta(c.BaseURL+"/browse/") + `([A-Z][A-Z0-9_]+-[1-9][0-9]*)`)
+	for _, m := range links.FindAllStringSubmatch(text, -1) {
+		if c.allows(jiraProject(m[1])) {
+			kk = unique(kk, m[1])
+		}
+	}
+
+	if len(c.Projects) == 0 {
+		return kk
+	}
+
+	for _, k := range jiraKey.FindAllString(text, -1) {
+		if c.allows(jiraProject(k)) {
+			kk = unique(kk, k)
+		}
+	}
+
+	return kk
+}
+
+// fetch loads the issue through Jira's REST API
+//
+// Basic authentication is used when username is set (Jira Cloud API
+// tokens), personal access token otherwise.
+func (jira) fetch(ctx context.Context, c *Connector, key string) (*Preview, error) {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/rest/api/2/issue/"+url.PathEscape(key)+"?fields=summary,status,assignee,issuetype", nil)
+	if err != nil {
+		return nil, ErrFetchFailed.withStack().WithMessage(err.Error())
+	}
+
+	switch {
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Token)
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	var i = jiraIssue{}
+	if err = getJSON(ctx, req, key, &i); err != nil {
+		return nil, err
+	}
+
+	p := &Preview{
+		ConnectorID: c.ID,
+		Key:         key,
+		Title:       i.Fields.Summary,
+		URL:         c.BaseURL + "/browse/" + i.Key,
+	}
+
+	if i.Fields.Status != nil {
+		p.Status = i.Fields.Status.Name
+	}
+
+	if i.Fields.Assignee != nil {
+		p.Assignee = i.Fields.Assignee.DisplayName
+	}
+
+	if i.Fields.IssueType != nil {
+		p.Type = i.Fields.IssueType.Name
+	}
+
+	return p, nil
+}
+
+// jiraProject returns project key of the issue key
+func jiraProject(key string) string {
+	return key[:strings.LastIndex(key, "-")]
+}
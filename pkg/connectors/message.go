@@ -0,0 +1,93 @@
+package connectors
+
+import (
+	"context"
+	"io"
+
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+type (
+	// message wraps message service and previews issues referenced in messages
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with previews of referenced issues
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Create(m)
+	if err != nil {
+		return nil, err
+	}
+
+	go defaultConnector.unfurl(m, false)
+	return m, nil
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.CreateWithAvatar(m, avatar)
+	if err != nil {
+		return nil, err
+	}
+
+	go defaultConnector.unfurl(m, false)
+	return m, nil
+}
+
+func (svc message) Update(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Update(m)
+	if err != nil {
+		return nil, err
+	}
+
+	go defaultConnector.unfurl(m, true)
+	return m, nil
+}
+
+// unfurl links issues referenced in the message and publishes their
+// previews to the channel; edited messages are published also when
+// they do not reference any issues anymore
+func (svc service) unfurl(m *messagingTypes.Message, updated bool) {
+	log := svc.logger.With(zap.Uint64("messageID", m.ID))
+
+	r, err := svc.repository()
+	if err != nil {
+		log.Error("could not preview issues", zap.Error(err))
+		return
+	}
+
+	set, err := svc.resolve(r, m.Message)
+	if err != nil {
+		log.Error("could not preview issues", zap.Error(err))
+		return
+	}
+
+	if len(set) == 0 && !updated {
+		return
+	}
+
+	if err = r.ReplaceLinks(m.ID, m.ChannelID, set); err != nil {
+		log.Error("could not link issues", zap.Error(err))
+		return
+	}
+
+	e := &UnfurledEvent{MessageID: m.ID, ChannelID: m.ChannelID, Previews: set}
+	live.Publish(&live.Event{Scope: live.ChannelScope(m.ChannelID), Type: live.EventMessageUnfurled, Payload: e})
+}
@@ -0,0 +1,66 @@
+package connectors
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200307000000.connectors",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_connector (
+  id                 BIGINT UNSIGNED NOT NULL,
+  kind               VARCHAR(16)     NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  base_url           VARCHAR(512)    NOT NULL,
+  username           VARCHAR(255)    NOT NULL DEFAULT '',
+  token              TEXT            NOT NULL,
+  projects           TEXT            NOT NULL,
+  sync_status        BOOLEAN         NOT NULL DEFAULT FALSE,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  created_by         BIGINT UNSIGNED NOT NULL,
+  roles              TEXT            NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_messaging_issue (
+  rel_connector      BIGINT UNSIGNED NOT NULL,
+  issue_key          VARCHAR(255)    NOT NULL,
+  title              TEXT            NOT NULL,
+  type               VARCHAR(64)     NOT NULL DEFAULT '',
+  status             VARCHAR(64)     NOT NULL DEFAULT '',
+  assignee           VARCHAR(255)    NOT NULL DEFAULT '',
+  url                VARCHAR(512)    NOT NULL DEFAULT '',
+  synced_status      VARCHAR(64)     NOT NULL DEFAULT '',
+  fetched_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_connector, issue_key)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_messaging_issue_link (
+  rel_message        BIGINT UNSIGNED NOT NULL,
+  rel_channel        BIGINT UNSIGNED NOT NULL,
+  rel_connector      BIGINT UNSIGNED NOT NULL,
+  issue_key          VARCHAR(255)    NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_message, rel_connector, issue_key),
+  INDEX (rel_connector, issue_key, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+		{
+			Name: "20200313000000.connectors-roles",
+			Up: `
+ALTER TABLE crust_messaging_connector
+  DROP COLUMN roles;
+`,
+		},
+	}
+)
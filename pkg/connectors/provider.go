@@ -0,0 +1,74 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/crusttech/crust-server/pkg/egress"
+)
+
+type (
+	// provider talks to an issue tracker of the kind
+	provider interface {
+		// refs returns keys of issues referenced in the text
+		refs(c *Connector, text string) []string
+
+		// fetch loads the issue with connector's credentials
+		fetch(ctx context.Context, c *Connector, key string) (*Preview, error)
+	}
+)
+
+var (
+	providers = map[string]provider{
+		KindJira:   jira{},
+		KindGitHub: github{},
+	}
+
+	client = egress.Client(fetchTimeout)
+)
+
+// getJSON sends the request and decodes the response into dst
+func getJSON(ctx context.Context, req *http.Request, key string, dst interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	rsp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return ErrFetchFailed.withStack().WithMessage(err.Error())
+	}
+
+	defer rsp.Body.Close()
+
+	switch {
+	case rsp.StatusCode == http.StatusNotFound:
+		return ErrIssueNotFound.withStack().WithMessage("issue " + key + " not found")
+	case rsp.StatusCode < 200 || rsp.StatusCode > 299:
+		return ErrFetchFailed.withStack().WithMessage(fmt.Sprintf("unexpected status %d for issue %s", rsp.StatusCode, key))
+	}
+
+	if err = json.NewDecoder(rsp.Body).Decode(dst); err != nil {
+		return ErrFetchFailed.withStack().WithMessage(err.Error())
+	}
+
+	return nil
+}
+
+// unique appends keys that are not in the list yet
+func unique(kk []string, add ...string) []string {
+	for _, a := range add {
+		found := false
+		for _, k := range kk {
+			if k == a {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			kk = append(kk, a)
+		}
+	}
+
+	return kk
+}
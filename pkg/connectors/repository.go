@@ -0,0 +1,245 @@
+package connectors
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+
+	// link is a reference to the issue in a message
+	link struct {
+		MessageID   uint64    `db:"rel_message"`
+		ChannelID   uint64    `db:"rel_channel"`
+		ConnectorID uint64    `db:"rel_connector"`
+		Key         string    `db:"issue_key"`
+		CreatedAt   time.Time `db:"created_at"`
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_connector"
+}
+
+func (r repository) tableIssue() string {
+	return "crust_messaging_issue"
+}
+
+func (r repository) tableLink() string {
+	return "crust_messaging_issue_link"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"kind",
+			"name",
+			"base_url",
+			"username",
+			"token",
+			"projects",
+			"sync_status",
+			"enabled",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) queryIssue() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"i.rel_connector",
+			"i.issue_key",
+			"i.title",
+			"i.type",
+			"i.status",
+			"i.assignee",
+			"i.url",
+			"i.fetched_at",
+			"i.synced_status",
+		).
+		From(r.tableIssue() + " AS i")
+}
+
+func (r repository) FindByID(connectorID uint64) (*Connector, error) {
+	var c = &Connector{}
+
+	if err := rh.FetchOne(r.db(), r.query().Where(squirrel.Eq{"id": connectorID}), c); err != nil {
+		return nil, err
+	} else if c.ID == 0 {
+		return nil, ErrConnectorNotFound.withStack()
+	}
+
+	return c, nil
+}
+
+func (r repository) Find() (set ConnectorSet, err error) {
+	return set, rh.FetchAll(r.db(), r.query().OrderBy("id"), &set)
+}
+
+func (r repository) FindEnabled() (set ConnectorSet, err error) {
+	return set, rh.FetchAll(r.db(), r.query().Where(squirrel.Eq{"enabled": true}).OrderBy("id"), &set)
+}
+
+// FindSynced returns enabled connectors that post status changes
+func (r repository) FindSynced() (set ConnectorSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"enabled": true, "sync_status": true}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(c *Connector) (*Connector, error) {
+	c.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&c.CreatedAt)
+
+	return c, errors.WithStack(r.db().Insert(r.table(), c))
+}
+
+func (r repository) Update(c *Connector) (*Connector, error) {
+	rh.SetCurrentTimeRounded(&c.UpdatedAt)
+
+	return c, errors.WithStack(r.db().Update(r.table(), c, "id"))
+}
+
+// Delete removes the connector with its issues and links to them
+func (r repository) Delete(connectorID uint64) error {
+	return r.db().Transaction(func() error {
+		err := rh.UpdateColumns(r.db(), r.table(), rh.Set{"deleted_at": time.Now()}, squirrel.Eq{"id": connectorID})
+		if err != nil {
+			return err
+		}
+
+		if err = rh.Delete(r.db(), r.tableLink(), squirrel.Eq{"rel_connector": connectorID}); err != nil {
+			return err
+		}
+
+		return rh.Delete(r.db(), r.tableIssue(), squirrel.Eq{"rel_connector": connectorID})
+	})
+}
+
+// FindPreview returns the issue as it was last fetched, nil when it was not
+func (r repository) FindPreview(connectorID uint64, key string) (*Preview, error) {
+	var p = &Preview{}
+
+	q := r.queryIssue().Where(squirrel.Eq{"i.rel_connector": connectorID, "i.issue_key": key})
+	if err := rh.FetchOne(r.db(), q, p); err != nil {
+		return nil, err
+	} else if p.Key == "" {
+		return nil, nil
+	}
+
+	return p, nil
+}
+
+// StorePreview stores the fetched issue; status that channels were told
+// about is set only for new ones
+func (r repository) StorePreview(p *Preview) error {
+	_, err := r.db().Exec(
+		"INSERT INTO "+r.tableIssue()+" (rel_connector, issue_key, title, type, status, assignee, url, fetched_at, synced_status)"+
+			" VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"+
+			" ON DUPLICATE KEY UPDATE title = VALUES(title), type = VALUES(type), status = VALUES(status),"+
+			" assignee = VALUES(assignee), url = VALUES(url), fetched_at = VALUES(fetched_at)",
+		p.ConnectorID, p.Key, p.Title, p.Type, p.Status, p.Assignee, p.URL, p.FetchedAt, p.Status,
+	)
+
+	return errors.WithStack(err)
+}
+
+// Synced marks the status change as posted; false is returned when
+// another instance did that already
+func (r repository) Synced(connectorID uint64, key, from, to string) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.tableIssue()+" SET synced_status = ? WHERE rel_connector = ? AND issue_key = ? AND synced_status = ?",
+		to, connectorID, key, from,
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, errors.WithStack(err)
+}
+
+// FindByMessageID returns previews of issues linked in the message
+func (r repository) FindByMessageID(messageID uint64) (set PreviewSet, err error) {
+	q := r.queryIssue().
+		Join(r.tableLink()+" AS l ON (l.rel_connector = i.rel_connector AND l.issue_key = i.issue_key)").
+		Where(squirrel.Eq{"l.rel_message": messageID}).
+		OrderBy("i.rel_connector", "i.issue_key")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// ReplaceLinks replaces issues linked in the message
+func (r repository) ReplaceLinks(messageID, channelID uint64, set PreviewSet) error {
+	return r.db().Transaction(func() error {
+		if err := rh.Delete(r.db(), r.tableLink(), squirrel.Eq{"rel_message": messageID}); err != nil {
+			return err
+		}
+
+		for _, p := range set {
+			l := &link{MessageID: messageID, ChannelID: channelID, ConnectorID: p.ConnectorID, Key: p.Key}
+			rh.SetCurrentTimeRounded(&l.CreatedAt)
+
+			if err := r.db().Insert(r.tableLink(), l); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// FindLinkedSince returns connector's issues linked since the given time,
+// the ones fetched the longest ago first
+func (r repository) FindLinkedSince(connectorID uint64, since time.Time, limit uint64) (set PreviewSet, err error) {
+	q := r.queryIssue().
+		Where(squirrel.Eq{"i.rel_connector": connectorID}).
+		Where("EXISTS (SELECT 1 FROM "+r.tableLink()+" AS l WHERE l.rel_connector = i.rel_connector AND l.issue_key = i.issue_key AND l.created_at >= ?)", since).
+		OrderBy("i.fetched_at").
+		Limit(limit)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindChannelsSince returns channels the issue was linked in since the given time
+func (r repository) FindChannelsSince(connectorID uint64, key string, since time.Time) (ids []uint64, err error) {
+	return ids, r.db().Select(
+		&ids,
+		"SELECT DISTINCT rel_channel FROM "+r.tableLink()+" WHERE rel_connector = ? AND issue_key = ? AND created_at >= ? ORDER BY rel_channel",
+		connectorID, key, since,
+	)
+}
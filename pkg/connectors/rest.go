@@ -0,0 +1,78 @@
+package connectors
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts endpoints of connectors and previews of messages
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("Connector.List", func(r *http.Request) (interface{}, error) {
+		return DefaultConnector.With(r.Context()).Find()
+	}))
+
+	r.Post("/", rest.Handler("Connector.Create", func(r *http.Request) (interface{}, error) {
+		in := ConnectorInput{}
+		if err := rest.Decode(r, &in); err != nil {
+			return nil, err
+		}
+
+		return DefaultConnector.With(r.Context()).Create(in)
+	}))
+
+	r.Put("/{connectorID}", rest.Handler("Connector.Update", func(r *http.Request) (interface{}, error) {
+		in := ConnectorInput{}
+		if err := rest.Decode(r, &in); err != nil {
+			return nil, err
+		}
+
+		return DefaultConnector.With(r.Context()).Update(rest.ParamUint64(r, "connectorID"), in)
+	}))
+
+	r.Delete("/{connectorID}", rest.Handler("Connector.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultConnector.With(r.Context()).DeleteByID(rest.ParamUint64(r, "connectorID"))
+	}))
+
+	mountResolve(r)
+
+	r.Get("/messages/{messageID}/previews", rest.Handler("Connector.MessagePreviews", func(r *http.Request) (interface{}, error) {
+		return DefaultConnector.With(r.Context()).MessagePreviews(rest.ParamUint64(r, "messageID"))
+	}))
+}
+
+// MountComposeRoutes mounts endpoints of previews of issues in records
+func MountComposeRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	mountResolve(r)
+
+	r.Get("/record/{recordID}", rest.Handler("Connector.RecordPreviews", func(r *http.Request) (interface{}, error) {
+		return DefaultConnector.With(r.Context()).RecordPreviews(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recordID"),
+		)
+	}))
+}
+
+// mountResolve mounts endpoint of previews of issues referenced in the
+// text, for messages and values that are being edited
+func mountResolve(r chi.Router) {
+	r.Post("/resolve", rest.Handler("Connector.Resolve", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Text string `json:"text"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultConnector.With(r.Context()).Resolve(body.Text)
+	}))
+}
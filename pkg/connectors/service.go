@@ -0,0 +1,324 @@
+package connectors
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	messagingRepository "github.com/cortezaproject/corteza-server/messaging/repository"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	ConnectorService interface {
+		With(ctx context.Context) ConnectorService
+
+		Find() (ConnectorSet, error)
+		Create(in ConnectorInput) (*Connector, error)
+		Update(connectorID uint64, in ConnectorInput) (*Connector, error)
+		DeleteByID(connectorID uint64) error
+
+		Resolve(text string) (PreviewSet, error)
+		MessagePreviews(messageID uint64) (PreviewSet, error)
+		RecordPreviews(namespaceID, recordID uint64) (PreviewSet, error)
+	}
+)
+
+var (
+	DefaultConnector ConnectorService
+
+	defaultConnector *service
+
+	// now is used for cache and sync times and can be overridden
+	now = time.Now
+)
+
+// Init initializes connectors of messaging
+//
+// Issues referenced in messages are previewed in the background; status
+// changes of issues linked in the last week are checked every
+// CONNECTORS_SYNC_INTERVAL (0 disables it) and posted into the channels.
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{logger: log}
+
+	DefaultConnector = svc.With(ctx)
+	defaultConnector = svc.with(ctx)
+
+	go defaultConnector.watch(ctx, options.EnvDuration("", "CONNECTORS_SYNC_INTERVAL", 5*time.Minute))
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+// InitCompose initializes previews of issues referenced in record fields
+//
+// Connectors are stored with messaging, which has to be bundled.
+func InitCompose(ctx context.Context, log *zap.Logger) error {
+	DefaultConnector = (&service{logger: log}).With(ctx)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) ConnectorService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// repository returns repository on messaging's database
+func (svc service) repository() (*repository, error) {
+	db, err := factory.Database.Get("messaging")
+	if err != nil {
+		return nil, ErrMessagingNotBundled.withStack()
+	}
+
+	return Repository(svc.ctx, db.With(svc.ctx)), nil
+}
+
+// Find returns all connectors; only admins can see them
+func (svc service) Find() (ConnectorSet, error) {
+	r, err := svc.manage()
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := r.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range set {
+		c.HasToken = c.Token != ""
+	}
+
+	return set, nil
+}
+
+func (svc service) Create(in ConnectorInput) (*Connector, error) {
+	r, err := svc.manage()
+	if err != nil {
+		return nil, err
+	}
+
+	i := auth.GetIdentityFromContext(svc.ctx)
+	c := &Connector{Enabled: true, CreatedBy: i.Identity()}
+	if err = c.apply(in); err != nil {
+		return nil, err
+	}
+
+	if c, err = r.Create(c); err != nil {
+		return nil, err
+	}
+
+	c.HasToken = c.Token != ""
+
+	svc.log(zap.Uint64("connectorID", c.ID), zap.String("kind", c.Kind)).Info("connector created")
+	return c, nil
+}
+
+// Update changes connector's settings
+//
+// Status changes are posted in the name of the user that updated it
+// from then on.
+func (svc service) Update(connectorID uint64, in ConnectorInput) (*Connector, error) {
+	r, err := svc.manage()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := r.FindByID(connectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.apply(in); err != nil {
+		return nil, err
+	}
+
+	i := auth.GetIdentityFromContext(svc.ctx)
+	c.CreatedBy = i.Identity()
+
+	if c, err = r.Update(c); err != nil {
+		return nil, err
+	}
+
+	c.HasToken = c.Token != ""
+
+	svc.log(zap.Uint64("connectorID", c.ID)).Info("connector updated")
+	return c, nil
+}
+
+// DeleteByID removes the connector with previews of its issues
+func (svc service) DeleteByID(connectorID uint64) error {
+	r, err := svc.manage()
+	if err != nil {
+		return err
+	}
+
+	if _, err = r.FindByID(connectorID); err != nil {
+		return err
+	}
+
+	if err = r.Delete(connectorID); err != nil {
+		return err
+	}
+
+	svc.log(zap.Uint64("connectorID", connectorID)).Info("connector deleted")
+	return nil
+}
+
+// Resolve returns previews of issues referenced in the text
+func (svc service) Resolve(text string) (PreviewSet, error) {
+	r, err := svc.repository()
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.resolve(r, text)
+}
+
+// MessagePreviews returns previews of issues linked in the message
+func (svc service) MessagePreviews(messageID uint64) (PreviewSet, error) {
+	r, err := svc.repository()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := messagingRepository.Message(svc.ctx, messagingRepository.DB(svc.ctx)).FindByID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fails when the channel can not be read
+	if _, err = messagingService.DefaultChannel.With(svc.ctx).FindByID(m.ChannelID); err != nil {
+		return nil, err
+	}
+
+	return r.FindByMessageID(messageID)
+}
+
+// RecordPreviews returns previews of issues referenced in values of the
+// record that the current user can read
+func (svc service) RecordPreviews(namespaceID, recordID uint64) (PreviewSet, error) {
+	r, err := svc.repository()
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := composeService.DefaultRecord.With(svc.ctx).FindByID(namespaceID, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	vv := make([]string, 0, len(rec.Values))
+	for _, v := range rec.Values {
+		vv = append(vv, v.Value)
+	}
+
+	return svc.resolve(r, strings.Join(vv, "\n"))
+}
+
+// resolve returns previews of issues referenced in the text, fetching the
+// ones that are not cached; issues that can not be fetched are left out
+func (svc service) resolve(r *repository, text string) (PreviewSet, error) {
+	var set = PreviewSet{}
+
+	if strings.TrimSpace(text) == "" {
+		return set, nil
+	}
+
+	cc, err := r.FindEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cc {
+		for _, key := range providers[c.Kind].refs(c, text) {
+			if len(set) == maxRefs {
+				return set, nil
+			}
+
+			p, err := svc.preview(r, c, key)
+			if err != nil {
+				svc.log(zap.Uint64("connectorID", c.ID), zap.String("key", key)).Warn("could not fetch issue", zap.Error(err))
+				continue
+			}
+
+			set = append(set, p)
+		}
+	}
+
+	return set, nil
+}
+
+// preview returns the cached issue or fetches it when it is too old;
+// cached one is returned when fetching fails
+func (svc service) preview(r *repository, c *Connector, key string) (*Preview, error) {
+	cached, err := r.FindPreview(c.ID, key)
+	if err != nil {
+		return nil, err
+	} else if cached != nil && now().Sub(cached.FetchedAt) < cacheTTL {
+		return cached, nil
+	}
+
+	p, err := providers[c.Kind].fetch(svc.ctx, c, key)
+	if err != nil {
+		if cached != nil {
+			return cached, nil
+		}
+
+		return nil, err
+	}
+
+	p.FetchedAt = now().Truncate(time.Second)
+	return p, r.StorePreview(p)
+}
+
+// manage checks if the current user can manage connectors
+func (svc service) manage() (*repository, error) {
+	if !isAdmin(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return svc.repository()
+}
+
+func isAdmin(ctx context.Context) bool {
+	i := auth.GetIdentityFromContext(ctx)
+	if auth.IsSuperUser(i) {
+		return true
+	}
+
+	for _, roleID := range i.Roles() {
+		if roleID == permissions.AdminsRoleID {
+			return true
+		}
+	}
+
+	return false
+}
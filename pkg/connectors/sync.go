@@ -0,0 +1,125 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+// watch checks linked issues for status changes in intervals
+func (svc service) watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.sync(ctx)
+		}
+	}
+}
+
+// sync fetches recently linked issues of connectors with sync enabled and
+// posts status changes into channels they were linked in
+//
+// Connector's creator is resolved before statuses are stored; connectors
+// are skipped while system service (in another process) is unreachable,
+// so that changes are posted later and not lost.
+func (svc service) sync(ctx context.Context) {
+	var (
+		r     = Repository(ctx, nil)
+		since = now().Add(-syncWindow)
+	)
+
+	cc, err := r.FindSynced()
+	if err != nil {
+		svc.logger.Error("could not load connectors", zap.Error(err))
+		return
+	}
+
+	for _, c := range cc {
+		as, err := runas.Context(ctx, c.CreatedBy)
+		if fault.Is(err, runas.ErrUsersUnavailable) {
+			svc.logger.Warn("could not resolve connector's creator, will retry", zap.Uint64("connectorID", c.ID), zap.Error(err))
+			continue
+		} else if err != nil {
+			svc.logger.Error("could not resolve identity of connector's creator", zap.Uint64("connectorID", c.ID), zap.Error(err))
+			as = nil
+		}
+
+		set, err := r.FindLinkedSince(c.ID, since, syncBatch)
+		if err != nil {
+			svc.logger.Error("could not load linked issues", zap.Uint64("connectorID", c.ID), zap.Error(err))
+			continue
+		}
+
+		for _, cached := range set {
+			log := svc.logger.With(zap.Uint64("connectorID", c.ID), zap.String("key", cached.Key))
+
+			p, err := providers[c.Kind].fetch(ctx, c, cached.Key)
+			if err != nil {
+				log.Warn("could not fetch issue", zap.Error(err))
+				continue
+			}
+
+			p.FetchedAt = now().Truncate(time.Second)
+			if err = r.StorePreview(p); err != nil {
+				log.Error("could not store issue", zap.Error(err))
+				continue
+			}
+
+			if cached.SyncedStatus == p.Status {
+				continue
+			}
+
+			if ok, err := r.Synced(c.ID, p.Key, cached.SyncedStatus, p.Status); err != nil {
+				log.Error("could not store synced status", zap.Error(err))
+			} else if ok && cached.SyncedStatus != "" && as != nil {
+				svc.notify(as, r, c, cached.SyncedStatus, p, since)
+			}
+		}
+	}
+}
+
+// notify posts the status change into channels the issue was linked in,
+// in the name of the connector; context holds identity of its creator
+func (svc service) notify(ctx context.Context, r *repository, c *Connector, from string, p *Preview, since time.Time) {
+	log := svc.logger.With(zap.Uint64("connectorID", c.ID), zap.String("key", p.Key))
+
+	ids, err := r.FindChannelsSince(c.ID, p.Key, since)
+	if err != nil {
+		log.Error("could not load channels of issue", zap.Error(err))
+		return
+	}
+
+	var (
+		text = fmt.Sprintf("**[%s: %s](%s)** changed status from *%s* to *%s*", p.Key, p.Title, p.URL, from, p.Status)
+		ms   = messagingService.DefaultMessage.With(ctx)
+	)
+
+	for _, channelID := range ids {
+		_, err = ms.Create(&messagingTypes.Message{
+			ChannelID: channelID,
+			UserID:    c.CreatedBy,
+			Message:   text,
+			Meta:      &messagingTypes.MessageMeta{Username: c.Name},
+		})
+
+		if err != nil {
+			log.Error("could not post status change", zap.Uint64("channelID", channelID), zap.Error(err))
+		}
+	}
+}
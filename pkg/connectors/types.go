@@ -0,0 +1,182 @@
+package connectors
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Connector links messages and records to issues of an issue tracker
+	//
+	// Issues are fetched with connector's credentials, so everything they
+	// can read can be previewed; projects (Jira project keys or GitHub
+	// repositories) limit that. Token is never sent back.
+	Connector struct {
+		ID         uint64    `json:"connectorID,string" db:"id"`
+		Kind       string    `json:"kind" db:"kind"`
+		Name       string    `json:"name" db:"name"`
+		BaseURL    string    `json:"baseURL" db:"base_url"`
+		Username   string    `json:"username" db:"username"`
+		Token      string    `json:"-" db:"token"`
+		Projects   stringSet `json:"projects" db:"projects"`
+		SyncStatus bool      `json:"syncStatus" db:"sync_status"`
+		Enabled    bool      `json:"enabled" db:"enabled"`
+
+		HasToken bool `json:"hasToken" db:"-"`
+
+		// Status changes are posted in the name of the user that created
+		// the connector and with roles they have at the time
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	ConnectorSet []*Connector
+
+	// ConnectorInput holds settings of the connector; token is left as
+	// it is when not given
+	ConnectorInput struct {
+		Kind       string   `json:"kind"`
+		Name       string   `json:"name"`
+		BaseURL    string   `json:"baseURL"`
+		Username   string   `json:"username"`
+		Token      *string  `json:"token"`
+		Projects   []string `json:"projects"`
+		SyncStatus *bool    `json:"syncStatus"`
+		Enabled    *bool    `json:"enabled"`
+	}
+
+	// Preview is the issue as it was last fetched
+	Preview struct {
+		ConnectorID uint64    `json:"connectorID,string" db:"rel_connector"`
+		Key         string    `json:"key" db:"issue_key"`
+		Title       string    `json:"title" db:"title"`
+		Type        string    `json:"type" db:"type"`
+		Status      string    `json:"status" db:"status"`
+		Assignee    string    `json:"assignee" db:"assignee"`
+		URL         string    `json:"url" db:"url"`
+		FetchedAt   time.Time `json:"fetchedAt" db:"fetched_at"`
+
+		// Status that channels were last told about
+		SyncedStatus string `json:"-" db:"synced_status"`
+	}
+
+	PreviewSet []*Preview
+
+	// UnfurledEvent is published to the channel when previews of
+	// the message's issues are ready
+	UnfurledEvent struct {
+		MessageID uint64     `json:"messageID,string"`
+		ChannelID uint64     `json:"channelID,string"`
+		Previews  PreviewSet `json:"previews"`
+	}
+
+	stringSet []string
+)
+
+const (
+	KindJira   = "jira"
+	KindGitHub = "github"
+
+	maxNameLength = 64
+
+	// Only the first references in the text are previewed
+	maxRefs = 10
+
+	// Previews are fetched again when older than this
+	cacheTTL = 10 * time.Minute
+
+	fetchTimeout = 10 * time.Second
+
+	// Issues linked within the window are checked for status changes,
+	// the ones fetched the longest ago first
+	syncWindow = 7 * 24 * time.Hour
+	syncBatch  = 100
+)
+
+// apply validates and sets connector's settings
+func (c *Connector) apply(in ConnectorInput) error {
+	if _, ok := providers[in.Kind]; !ok {
+		return ErrInvalidKind.withStack().WithMessage("unknown connector kind " + in.Kind)
+	}
+
+	if in.BaseURL == "" && in.Kind == KindGitHub {
+		in.BaseURL = "https://api.github.com"
+	}
+
+	if u, err := url.Parse(in.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL.withStack()
+	}
+
+	if in.Name = strings.TrimSpace(in.Name); in.Name == "" || len([]rune(in.Name)) > maxNameLength {
+		return ErrNameRequired.withStack()
+	}
+
+	c.Kind = in.Kind
+	c.Name = in.Name
+	c.BaseURL = strings.TrimSuffix(in.BaseURL, "/")
+	c.Username = strings.TrimSpace(in.Username)
+	c.Projects = stringSet{}
+
+	for _, p := range in.Projects {
+		if p = strings.TrimSpace(p); p != "" {
+			c.Projects = append(c.Projects, p)
+		}
+	}
+
+	if in.Token != nil {
+		c.Token = *in.Token
+	}
+
+	if in.SyncStatus != nil {
+		c.SyncStatus = *in.SyncStatus
+	}
+
+	if in.Enabled != nil {
+		c.Enabled = *in.Enabled
+	}
+
+	return nil
+}
+
+// allows checks if issues of the project can be previewed
+func (c Connector) allows(project string) bool {
+	if len(c.Projects) == 0 {
+		return true
+	}
+
+	for _, p := range c.Projects {
+		if strings.EqualFold(p, project) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ss stringSet) Value() (driver.Value, error) {
+	if ss == nil {
+		ss = stringSet{}
+	}
+
+	return json.Marshal(ss)
+}
+
+func (ss *stringSet) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ss = stringSet{}
+	case []byte:
+		if err := json.Unmarshal(b, ss); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into stringSet", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package consistency
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli"
+)
+
+// Command checks (and repairs) databases of all apps the server connects to
+//
+// Reports are written to stdout as JSON, one per app. Command fails when
+// any inconsistency is left, so it can be used in scheduled jobs:
+//
+//	crust-server consistency --repair --class=channel-counter,unread-total
+func Command(ctx context.Context, c *cli.Config) *cobra.Command {
+	var (
+		repair  bool
+		classes []string
+	)
+
+	cmd := &cobra.Command{
+		Use:          "consistency",
+		Short:        "Check consistency of the database",
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				enc        = json.NewEncoder(cmd.OutOrStdout())
+				consistent = true
+			)
+
+			enc.SetIndent("", "  ")
+
+			for _, app := range appNames {
+				// Standalone servers connect to their own database only
+				if _, err := factory.Database.Get(app); err != nil {
+					continue
+				}
+
+				// Classes are of one of the apps
+				var cc []string
+				for _, class := range classes {
+					if valid(app, class) {
+						cc = append(cc, class)
+					}
+				}
+
+				// None of the classes are of this app, nothing to repair
+				rep, err := run(ctx, app, repair && (len(classes) == 0 || len(cc) > 0), cc...)
+				if err != nil {
+					return err
+				}
+
+				if err = enc.Encode(rep); err != nil {
+					return err
+				}
+
+				consistent = consistent && rep.Consistent
+			}
+
+			if !consistent {
+				return errors.New("database is not consistent")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&repair, "repair", false, "Repair inconsistencies that can be safely repaired")
+	cmd.Flags().StringSliceVar(&classes, "class", nil, "Repair only inconsistencies of the classes")
+
+	return cmd
+}
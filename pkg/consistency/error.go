@@ -0,0 +1,33 @@
+package consistency
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	consistencyError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions = consistencyError{"NoPermissions", fault.Forbidden}
+	ErrUnknownClass  = consistencyError{"UnknownClass", fault.Invalid}
+)
+
+func (e consistencyError) Error() string {
+	return e.String()
+}
+
+func (e consistencyError) String() string {
+	return "crust.consistency." + e.name
+}
+
+func (e consistencyError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e consistencyError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,118 @@
+package consistency
+
+var (
+	systemInvariants = []*invariant{
+		{
+			class:       "membership-user",
+			description: "role memberships of users that do not exist",
+			find: `
+SELECT CONCAT_WS(':', m.rel_role, m.rel_user)
+  FROM sys_role_member AS m
+ WHERE NOT EXISTS (SELECT 1 FROM sys_user AS u WHERE u.id = m.rel_user)`,
+			repair: deletePairs("sys_role_member", "rel_role", "rel_user"),
+		},
+		{
+			class:       "membership-role",
+			description: "role memberships of roles that do not exist",
+			find: `
+SELECT CONCAT_WS(':', m.rel_role, m.rel_user)
+  FROM sys_role_member AS m
+ WHERE NOT EXISTS (SELECT 1 FROM sys_role AS r WHERE r.id = m.rel_role)`,
+			repair: deletePairs("sys_role_member", "rel_role", "rel_user"),
+		},
+		{
+			class:       "expiry-membership",
+			description: "expirations of role memberships that do not exist",
+			find: `
+SELECT CONCAT_WS(':', e.rel_role, e.rel_user)
+  FROM crust_system_role_member_expiry AS e
+ WHERE NOT EXISTS (SELECT 1 FROM sys_role_member AS m WHERE m.rel_role = e.rel_role AND m.rel_user = e.rel_user)`,
+			repair: deletePairs("crust_system_role_member_expiry", "rel_role", "rel_user"),
+		},
+	}
+
+	composeInvariants = []*invariant{
+		{
+			class:       "module-namespace",
+			description: "modules of namespaces that do not exist",
+			find: `
+SELECT CAST(m.id AS CHAR)
+  FROM compose_module AS m
+ WHERE NOT EXISTS (SELECT 1 FROM compose_namespace AS ns WHERE ns.id = m.rel_namespace)`,
+		},
+		{
+			class:       "field-module",
+			description: "fields of modules that do not exist",
+			find: `
+SELECT CAST(f.id AS CHAR)
+  FROM compose_module_field AS f
+ WHERE NOT EXISTS (SELECT 1 FROM compose_module AS m WHERE m.id = f.rel_module)`,
+			repair: deleteByIDs("compose_module_field"),
+		},
+		{
+			class:       "record-module",
+			description: "undeleted records of modules that do not exist",
+			find: `
+SELECT CAST(r.id AS CHAR)
+  FROM compose_record AS r
+ WHERE r.deleted_at IS NULL
+   AND NOT EXISTS (SELECT 1 FROM compose_module AS m WHERE m.id = r.module_id)`,
+
+			// Records are deleted the way corteza deletes them,
+			// they can still be restored
+			repair: softDeleteRecords,
+		},
+		{
+			class:       "value-record",
+			description: "values of records that do not exist",
+			find: `
+SELECT DISTINCT CAST(v.record_id AS CHAR)
+  FROM compose_record_value AS v
+ WHERE NOT EXISTS (SELECT 1 FROM compose_record AS r WHERE r.id = v.record_id)`,
+			repair: deleteValues,
+		},
+	}
+
+	messagingInvariants = []*invariant{
+		{
+			class:       "member-channel",
+			description: "channel memberships of channels that do not exist",
+			find: `
+SELECT CONCAT_WS(':', cm.rel_channel, cm.rel_user)
+  FROM messaging_channel_member AS cm
+ WHERE NOT EXISTS (SELECT 1 FROM messaging_channel AS ch WHERE ch.id = cm.rel_channel)`,
+			repair: deletePairs("messaging_channel_member", "rel_channel", "rel_user"),
+		},
+		{
+			class:       "channel-counter",
+			description: "channel counters that differ from counted members and messages",
+			find: `
+SELECT CAST(c.rel_channel AS CHAR)
+  FROM crust_messaging_channel_counter AS c
+ WHERE c.members <> (SELECT COUNT(*) FROM messaging_channel_member AS cm WHERE cm.rel_channel = c.rel_channel AND cm.type <> 'invitee')
+    OR c.messages <> (SELECT COUNT(*) FROM messaging_message AS m WHERE m.rel_channel = c.rel_channel AND m.deleted_at IS NULL)`,
+			repair: recountChannels,
+		},
+		{
+			class:       "unread-total",
+			description: "unread totals that differ from unread counts of channels",
+			find: `
+SELECT CAST(t.rel_user AS CHAR)
+  FROM crust_messaging_unread_total AS t
+       LEFT JOIN (
+           SELECT u.rel_user,
+                  COALESCE(SUM(CASE WHEN u.rel_reply_to = 0 THEN u.count END), 0) AS messages,
+                  COUNT(CASE WHEN u.rel_reply_to = 0 AND u.count > 0 THEN 1 END) AS channels,
+                  COUNT(CASE WHEN u.rel_reply_to > 0 AND u.count > 0 THEN 1 END) AS threads
+             FROM messaging_unread AS u
+                  INNER JOIN messaging_channel_member AS cm ON (cm.rel_channel = u.rel_channel AND cm.rel_user = u.rel_user)
+                  INNER JOIN messaging_channel AS ch ON (ch.id = u.rel_channel AND ch.deleted_at IS NULL)
+            GROUP BY u.rel_user
+       ) AS a ON (a.rel_user = t.rel_user)
+ WHERE t.messages <> COALESCE(a.messages, 0)
+    OR t.channels <> COALESCE(a.channels, 0)
+    OR t.threads <> COALESCE(a.threads, 0)`,
+			repair: recountUnread,
+		},
+	}
+)
@@ -0,0 +1,101 @@
+package consistency
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/counters"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	return r.dbh
+}
+
+// Keys returns keys selected by the SQL
+func (r repository) Keys(sql string) (keys []string, err error) {
+	return keys, errors.WithStack(r.db().Select(&keys, sql))
+}
+
+// deletePairs removes rows by "<a>:<b>" keys
+func deletePairs(table, a, b string) func(r *repository, keys []string) error {
+	return func(r *repository, keys []string) error {
+		or := squirrel.Or{}
+		for _, k := range keys {
+			if pair := ids(strings.Split(k, ":")); len(pair) == 2 {
+				or = append(or, squirrel.Eq{a: pair[0], b: pair[1]})
+			}
+		}
+
+		if len(or) == 0 {
+			return nil
+		}
+
+		return rh.Delete(r.db(), table, or)
+	}
+}
+
+func deleteByIDs(table string) func(r *repository, keys []string) error {
+	return func(r *repository, keys []string) error {
+		return rh.Delete(r.db(), table, squirrel.Eq{"id": ids(keys)})
+	}
+}
+
+func softDeleteRecords(r *repository, keys []string) error {
+	q := squirrel.
+		Update("compose_record").
+		Set("deleted_at", time.Now()).
+		Where(squirrel.Eq{"id": ids(keys)})
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db().Exec(query, args...)
+	return errors.WithStack(err)
+}
+
+func deleteValues(r *repository, keys []string) error {
+	return rh.Delete(r.db(), "compose_record_value", squirrel.Eq{"record_id": ids(keys)})
+}
+
+func recountChannels(r *repository, keys []string) error {
+	return counters.Repository(r.ctx, r.db()).RecountChannels(ids(keys)...)
+}
+
+func recountUnread(r *repository, keys []string) error {
+	return counters.Repository(r.ctx, r.db()).RecountUnread(ids(keys)...)
+}
+
+// ids parses IDs, invalid are skipped
+func ids(ss []string) []uint64 {
+	out := make([]uint64, 0, len(ss))
+	for _, s := range ss {
+		if id, err := strconv.ParseUint(s, 10, 64); err == nil {
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
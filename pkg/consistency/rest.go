@@ -0,0 +1,44 @@
+package consistency
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountComposeRoutes mounts consistency endpoints of compose
+func MountComposeRoutes(r chi.Router) {
+	mount(r, "compose")
+}
+
+// MountSystemRoutes mounts consistency endpoints of system
+func MountSystemRoutes(r chi.Router) {
+	mount(r, "system")
+}
+
+// MountMessagingRoutes mounts consistency endpoints of messaging
+func MountMessagingRoutes(r chi.Router) {
+	mount(r, "messaging")
+}
+
+func mount(r chi.Router, app string) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("Consistency.Check", func(r *http.Request) (interface{}, error) {
+		return DefaultConsistency.With(r.Context()).Check(app)
+	}))
+
+	// ?class=membership-user,record-module (all repairable when not set)
+	r.Post("/repair", rest.Handler("Consistency.Repair", func(r *http.Request) (interface{}, error) {
+		var classes []string
+		if c := r.URL.Query().Get("class"); c != "" {
+			classes = strings.Split(c, ",")
+		}
+
+		return DefaultConsistency.With(r.Context()).Repair(app, classes...)
+	}))
+}
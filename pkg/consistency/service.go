@@ -0,0 +1,188 @@
+package consistency
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	consistencyService struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	accessController interface {
+		CanManageSettings(context.Context) bool
+	}
+
+	ConsistencyService interface {
+		With(ctx context.Context) ConsistencyService
+
+		Check(app string) (*Report, error)
+		Repair(app string, classes ...string) (*Report, error)
+	}
+)
+
+var (
+	DefaultConsistency ConsistencyService
+
+	// Access control of apps with initialized services
+	controllers = map[string]accessController{}
+)
+
+// InitCompose enables consistency checks of compose database
+//
+// Must be called after compose services are initialized
+func InitCompose(ctx context.Context, log *zap.Logger) error {
+	return initApp(ctx, log, "compose", composeService.DefaultAccessControl)
+}
+
+// InitSystem enables consistency checks of system database
+//
+// Must be called after system services are initialized
+func InitSystem(ctx context.Context, log *zap.Logger) error {
+	return initApp(ctx, log, "system", systemService.DefaultAccessControl)
+}
+
+// InitMessaging enables consistency checks of messaging database
+//
+// Must be called after messaging services are initialized
+func InitMessaging(ctx context.Context, log *zap.Logger) error {
+	return initApp(ctx, log, "messaging", messagingService.DefaultAccessControl)
+}
+
+func initApp(ctx context.Context, log *zap.Logger, app string, ac accessController) error {
+	controllers[app] = ac
+	DefaultConsistency = (&consistencyService{logger: log}).With(ctx)
+	return nil
+}
+
+func (svc consistencyService) With(ctx context.Context) ConsistencyService {
+	return &consistencyService{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc consistencyService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Check reports broken invariants of the app
+//
+// Checks are available to those that can manage app's settings
+func (svc consistencyService) Check(app string) (*Report, error) {
+	if err := svc.can(app); err != nil {
+		return nil, err
+	}
+
+	return run(svc.ctx, app, false)
+}
+
+// Repair repairs broken invariants of the classes (all when none are given)
+//
+// Issues without safe repair are reported only.
+func (svc consistencyService) Repair(app string, classes ...string) (*Report, error) {
+	if err := svc.can(app); err != nil {
+		return nil, err
+	}
+
+	rep, err := run(svc.ctx, app, true, classes...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, i := range rep.Issues {
+		if i.Repaired > 0 {
+			svc.log(zap.String("app", app), zap.String("class", i.Class), zap.Int("repaired", i.Repaired)).
+				Info("inconsistency repaired")
+		}
+	}
+
+	return rep, nil
+}
+
+func (svc consistencyService) can(app string) error {
+	if ac := controllers[app]; ac == nil || !ac.CanManageSettings(svc.ctx) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// run checks invariants of the app and repairs those of the classes
+//
+// Used by the service and by the command, which runs without services.
+func run(ctx context.Context, app string, repair bool, classes ...string) (*Report, error) {
+	var (
+		r   = Repository(ctx, factory.Database.MustGet(app).With(ctx))
+		out = &Report{App: app, Consistent: true, Issues: []*Issue{}}
+		sel = map[string]bool{}
+	)
+
+	for _, c := range classes {
+		if !valid(app, c) {
+			return nil, ErrUnknownClass.withStack().WithMessage(c)
+		}
+
+		sel[c] = true
+	}
+
+	for _, inv := range apps[app] {
+		keys, err := r.Keys(inv.find)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(keys) == 0 {
+			continue
+		}
+
+		i := &Issue{
+			Class:       inv.class,
+			Description: inv.description,
+			Count:       len(keys),
+			Samples:     keys,
+			Repairable:  inv.repair != nil,
+		}
+
+		if len(i.Samples) > maxSamples {
+			i.Samples = i.Samples[:maxSamples]
+		}
+
+		if repair && i.Repairable && (len(sel) == 0 || sel[inv.class]) {
+			if err = inv.repair(r, keys); err != nil {
+				return nil, err
+			}
+
+			i.Repaired = len(keys)
+		}
+
+		if i.Repaired < i.Count {
+			out.Consistent = false
+		}
+
+		out.Issues = append(out.Issues, i)
+	}
+
+	return out, nil
+}
+
+func valid(app, class string) bool {
+	for _, inv := range apps[app] {
+		if inv.class == class {
+			return true
+		}
+	}
+
+	return false
+}
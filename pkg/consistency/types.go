@@ -0,0 +1,54 @@
+package consistency
+
+type (
+	// Report of app's invariants, only broken ones are listed
+	Report struct {
+		App        string   `json:"app"`
+		Consistent bool     `json:"consistent"`
+		Issues     []*Issue `json:"issues"`
+	}
+
+	// Issue is a broken invariant and rows that break it
+	Issue struct {
+		Class       string `json:"class"`
+		Description string `json:"description"`
+
+		// Number of rows and keys of the first few
+		Count   int      `json:"count"`
+		Samples []string `json:"samples"`
+
+		// Issues without safe repair must be resolved manually
+		Repairable bool `json:"repairable"`
+		Repaired   int  `json:"repaired,omitempty"`
+	}
+
+	// invariant that must hold across tables
+	invariant struct {
+		class       string
+		description string
+
+		// SQL that selects keys of rows that break the invariant
+		find string
+
+		// repair fixes rows of the keys; invariants without safe
+		// repair do not have it
+		repair func(r *repository, keys []string) error
+	}
+)
+
+const (
+	// Keys of rows reported with the issue
+	maxSamples = 20
+)
+
+var (
+	// Invariants by app
+	apps = map[string][]*invariant{
+		"system":    systemInvariants,
+		"compose":   composeInvariants,
+		"messaging": messagingInvariants,
+	}
+
+	// Order apps are checked in by the command
+	appNames = []string{"system", "compose", "messaging"}
+)
@@ -0,0 +1,77 @@
+package counters
+
+import (
+	"context"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// channel wraps channel service and updates counters after membership changes
+	channel struct {
+		messagingService.ChannelService
+		ctx context.Context
+	}
+)
+
+// Channel decorates channel service with counter updates
+func Channel(cs messagingService.ChannelService) messagingService.ChannelService {
+	return &channel{ChannelService: cs, ctx: context.Background()}
+}
+
+func (svc channel) With(ctx context.Context) messagingService.ChannelService {
+	return &channel{
+		ChannelService: svc.ChannelService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc channel) Create(ch *messagingTypes.Channel) (*messagingTypes.Channel, error) {
+	ch, err := svc.ChannelService.Create(ch)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCounters.membersChanged(ch.ID)
+	return ch, nil
+}
+
+func (svc channel) AddMember(channelID uint64, memberIDs ...uint64) (messagingTypes.ChannelMemberSet, error) {
+	mm, err := svc.ChannelService.AddMember(channelID, memberIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCounters.membersChanged(channelID, memberIDs...)
+	return mm, nil
+}
+
+func (svc channel) DeleteMember(channelID uint64, memberIDs ...uint64) error {
+	if err := svc.ChannelService.DeleteMember(channelID, memberIDs...); err != nil {
+		return err
+	}
+
+	defaultCounters.membersChanged(channelID, memberIDs...)
+	return nil
+}
+
+func (svc channel) Delete(channelID uint64) (*messagingTypes.Channel, error) {
+	ch, err := svc.ChannelService.Delete(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCounters.channelChanged(channelID)
+	return ch, nil
+}
+
+func (svc channel) Undelete(channelID uint64) (*messagingTypes.Channel, error) {
+	ch, err := svc.ChannelService.Undelete(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCounters.channelChanged(channelID)
+	return ch, nil
+}
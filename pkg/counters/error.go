@@ -0,0 +1,33 @@
+package counters
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	countersError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrTooManyReads = countersError{"TooManyReads", fault.TooMany}
+	ErrInvalidRead  = countersError{"InvalidRead", fault.Invalid}
+)
+
+func (e countersError) Error() string {
+	return e.String()
+}
+
+func (e countersError) String() string {
+	return "crust.counters." + e.name
+}
+
+func (e countersError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e countersError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,91 @@
+package counters
+
+import (
+	"context"
+	"io"
+
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// message wraps message service and updates counters after messages
+	// are created, deleted or read
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with counter updates
+//
+// Message counts are updated right after the change, unread totals of
+// channel members are recounted and pushed in the background.
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Create(m)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCounters.messagesChanged(m.ChannelID, 1)
+	go defaultCounters.pushDeltas(m)
+	return m, nil
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.CreateWithAvatar(m, avatar)
+	if err != nil {
+		return nil, err
+	}
+
+	defaultCounters.messagesChanged(m.ChannelID, 1)
+	go defaultCounters.pushDeltas(m)
+	return m, nil
+}
+
+func (svc message) Delete(messageID uint64) error {
+	channelID, err := defaultCounters.repository.MessageChannel(messageID)
+	if err != nil {
+		defaultCounters.log(zap.Uint64("messageID", messageID), zap.Error(err)).Error("could not load channel of the message")
+	}
+
+	if err = svc.MessageService.Delete(messageID); err != nil {
+		return err
+	}
+
+	if channelID > 0 {
+		defaultCounters.messagesChanged(channelID, -1)
+	}
+
+	return nil
+}
+
+func (svc message) MarkAsRead(channelID, threadID, lastReadMessageID uint64) (uint64, uint32, uint32, error) {
+	lastID, count, threads, err := svc.MessageService.MarkAsRead(channelID, threadID, lastReadMessageID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	defaultCounters.marked(&ReadMarker{
+		ChannelID:     channelID,
+		ThreadID:      threadID,
+		UserID:        auth.GetIdentityFromContext(svc.ctx).Identity(),
+		LastMessageID: lastID,
+	})
+
+	return lastID, count, threads, nil
+}
@@ -0,0 +1,49 @@
+package counters
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200212000000.counters",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_channel_counter (
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  members          INT UNSIGNED    NOT NULL DEFAULT 0,
+  messages         INT UNSIGNED    NOT NULL DEFAULT 0,
+  updated_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_channel)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_messaging_unread_total (
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  messages         INT UNSIGNED    NOT NULL DEFAULT 0,
+  channels         INT UNSIGNED    NOT NULL DEFAULT 0,
+  threads          INT UNSIGNED    NOT NULL DEFAULT 0,
+  updated_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_user)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+		{
+			Name: "20200311000000.read-markers",
+			Up: `
+ALTER TABLE crust_messaging_unread_total ADD COLUMN mentions INT UNSIGNED NOT NULL DEFAULT 0 AFTER threads;
+
+CREATE TABLE IF NOT EXISTS crust_messaging_read_marker (
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  rel_reply_to     BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  rel_last_message BIGINT UNSIGNED NOT NULL,
+  read_at          DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_channel, rel_reply_to, rel_user)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,114 @@
+package counters
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+var (
+	// Snapshots are pushed to all subscribed users periodically, fixing
+	// counters of clients that missed deltas
+	snapshotInterval = 5 * time.Minute
+)
+
+// pushDeltas tells users with unread counters of message's channel (or thread)
+// about the new message
+func (svc service) pushDeltas(m *messagingTypes.Message) {
+	defer sentry.Recover()
+
+	userIDs, err := svc.repository.Unreaders(m.ChannelID, m.ReplyTo, m.UserID)
+	if err != nil {
+		svc.log(zap.Uint64("messageID", m.ID), zap.Error(err)).Error("could not push counter deltas")
+		return
+	}
+
+	if userIDs = subscribed(userIDs); len(userIDs) == 0 {
+		return
+	}
+
+	mentioned, err := svc.repository.Mentioned(m.ID)
+	if err != nil {
+		svc.log(zap.Uint64("messageID", m.ID), zap.Error(err)).Error("could not push counter deltas")
+		return
+	}
+
+	isMentioned := map[uint64]bool{}
+	for _, userID := range mentioned {
+		isMentioned[userID] = true
+	}
+
+	for _, userID := range userIDs {
+		d := &UnreadDelta{ChannelID: m.ChannelID, ThreadID: m.ReplyTo, MessageID: m.ID, Unread: 1}
+		if isMentioned[userID] {
+			d.Mentions = 1
+		}
+
+		live.Publish(&live.Event{Scope: live.UserScope(userID), Type: live.EventCountersDelta, Payload: d})
+	}
+}
+
+// pushSnapshots sends current unread counters to the users
+func (svc service) pushSnapshots(userIDs ...uint64) {
+	for _, userID := range subscribed(userIDs) {
+		s, err := svc.snapshot(userID)
+		if err != nil {
+			svc.log(zap.Uint64("userID", userID), zap.Error(err)).Error("could not push counter snapshot")
+			continue
+		}
+
+		live.Publish(&live.Event{Scope: live.UserScope(userID), Type: live.EventCountersSnapshot, Payload: s})
+	}
+}
+
+// snapshot returns user's unread counters
+func (svc service) snapshot(userID uint64) (*UnreadSnapshot, error) {
+	cc, err := svc.repository.UnreadCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := svc.repository.FindUnreadTotal(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cc == nil {
+		cc = []*UnreadCount{}
+	}
+
+	return &UnreadSnapshot{Channels: cc, Unread: t}, nil
+}
+
+func (svc service) watchSnapshots(ctx context.Context) {
+	defer sentry.Recover()
+
+	t := time.NewTicker(snapshotInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.with(ctx).pushSnapshots(live.SubscribedUsers()...)
+		}
+	}
+}
+
+// subscribed returns users subscribed to their user scopes
+func subscribed(userIDs []uint64) []uint64 {
+	out := make([]uint64, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if live.Subscribed(live.UserScope(userID)) {
+			out = append(out, userID)
+		}
+	}
+
+	return out
+}
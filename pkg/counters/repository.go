@@ -0,0 +1,364 @@
+package counters
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+
+	// channelRow is a channel with current user's membership type
+	channelRow struct {
+		ID         uint64                     `db:"id"`
+		Type       messagingTypes.ChannelType `db:"type"`
+		ArchivedAt *time.Time                 `db:"archived_at"`
+		MemberType sql.NullString             `db:"member_type"`
+	}
+)
+
+const (
+	sqlRecountChannels = `
+INSERT INTO crust_messaging_channel_counter (rel_channel, members, messages, updated_at)
+SELECT ch.id,
+       (SELECT COUNT(*) FROM messaging_channel_member AS cm WHERE cm.rel_channel = ch.id AND cm.type <> 'invitee'),
+       (SELECT COUNT(*) FROM messaging_message AS m WHERE m.rel_channel = ch.id AND m.deleted_at IS NULL),
+       NOW()
+  FROM messaging_channel AS ch
+ WHERE %s
+    ON DUPLICATE KEY UPDATE members = VALUES(members), messages = VALUES(messages), updated_at = VALUES(updated_at)`
+
+	sqlRecountMembers = `
+INSERT INTO crust_messaging_channel_counter (rel_channel, members, updated_at)
+SELECT ?, COUNT(*), NOW()
+  FROM messaging_channel_member
+ WHERE rel_channel = ? AND type <> 'invitee'
+    ON DUPLICATE KEY UPDATE members = VALUES(members), updated_at = VALUES(updated_at)`
+
+	sqlAddMessages = `
+UPDATE crust_messaging_channel_counter
+   SET messages = GREATEST(CAST(messages AS SIGNED) + ?, 0), updated_at = NOW()
+ WHERE rel_channel = ?`
+
+	sqlRecountUnread = `
+INSERT INTO crust_messaging_unread_total (rel_user, messages, channels, threads, mentions, updated_at)
+SELECT u.rel_user,
+       COALESCE(SUM(CASE WHEN u.rel_reply_to = 0 THEN u.count END), 0),
+       COUNT(CASE WHEN u.rel_reply_to = 0 AND u.count > 0 THEN 1 END),
+       COUNT(CASE WHEN u.rel_reply_to > 0 AND u.count > 0 THEN 1 END),
+       COALESCE(SUM(CASE WHEN u.rel_reply_to = 0 AND u.count > 0 THEN ` + sqlUnreadMentions + ` END), 0),
+       NOW()
+  FROM messaging_unread AS u
+       INNER JOIN messaging_channel_member AS cm ON (cm.rel_channel = u.rel_channel AND cm.rel_user = u.rel_user)
+       INNER JOIN messaging_channel AS ch ON (ch.id = u.rel_channel AND ch.deleted_at IS NULL)
+ WHERE %s
+ GROUP BY u.rel_user
+    ON DUPLICATE KEY UPDATE messages = VALUES(messages), channels = VALUES(channels), threads = VALUES(threads), mentions = VALUES(mentions), updated_at = VALUES(updated_at)`
+
+	// Mentions of the unread counter's user in messages after the last
+	// read one, replies are not counted
+	sqlUnreadMentions = `(SELECT COUNT(*)
+          FROM messaging_mention AS mn
+               INNER JOIN messaging_message AS m ON (m.id = mn.rel_message AND m.reply_to = 0 AND m.deleted_at IS NULL)
+         WHERE mn.rel_channel = u.rel_channel AND mn.rel_user = u.rel_user AND mn.rel_message > u.rel_last_message)`
+
+	sqlUnreadCounts = `
+SELECT u.rel_channel,
+       u.count,
+       ` + sqlUnreadMentions + ` AS mentions
+  FROM messaging_unread AS u
+       INNER JOIN messaging_channel_member AS cm ON (cm.rel_channel = u.rel_channel AND cm.rel_user = u.rel_user)
+       INNER JOIN messaging_channel AS ch ON (ch.id = u.rel_channel AND ch.deleted_at IS NULL)
+ WHERE u.rel_user = ? AND u.rel_reply_to = 0 AND u.count > 0
+ ORDER BY u.rel_channel`
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_channel_counter"
+}
+
+func (r repository) tableUnread() string {
+	return "crust_messaging_unread_total"
+}
+
+func (r repository) tableMarker() string {
+	return "crust_messaging_read_marker"
+}
+
+// Find returns counters of the channels
+func (r repository) Find(channelIDs ...uint64) (set ChannelCounterSet, err error) {
+	if len(channelIDs) == 0 {
+		return ChannelCounterSet{}, nil
+	}
+
+	q := squirrel.
+		Select("rel_channel", "members", "messages", "updated_at").
+		From(r.table()).
+		Where(squirrel.Eq{"rel_channel": channelIDs})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindUnreadTotal returns unread totals of the user, zero when not counted yet
+func (r repository) FindUnreadTotal(userID uint64) (*UnreadTotal, error) {
+	var (
+		t = &UnreadTotal{}
+		q = squirrel.
+			Select("rel_user", "messages", "channels", "threads", "mentions", "updated_at").
+			From(r.tableUnread()).
+			Where(squirrel.Eq{"rel_user": userID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, t); err != nil {
+		return nil, err
+	}
+
+	t.UserID = userID
+	return t, nil
+}
+
+// ChannelUnread returns user's unread counts of the channels, by channel ID
+//
+// Read from corteza's unread table, by its primary key.
+func (r repository) ChannelUnread(userID uint64, channelIDs ...uint64) (map[uint64]uint, error) {
+	var (
+		out = map[uint64]uint{}
+		uu  []*channelUnread
+	)
+
+	if len(channelIDs) == 0 {
+		return out, nil
+	}
+
+	q := squirrel.
+		Select("rel_channel", "count").
+		From("messaging_unread").
+		Where(squirrel.Eq{"rel_channel": channelIDs, "rel_reply_to": 0, "rel_user": userID})
+
+	if err := rh.FetchAll(r.db(), q, &uu); err != nil {
+		return nil, err
+	}
+
+	for _, u := range uu {
+		out[u.ChannelID] = u.Count
+	}
+
+	return out, nil
+}
+
+// Channels returns undeleted channels that are public or have the user as a member
+func (r repository) Channels(userID uint64) (messagingTypes.ChannelSet, error) {
+	var (
+		rows []*channelRow
+		q    = squirrel.
+			Select("ch.id", "ch.type", "ch.archived_at", "cm.type AS member_type").
+			From("messaging_channel AS ch").
+			LeftJoin("messaging_channel_member AS cm ON (cm.rel_channel = ch.id AND cm.rel_user = ?)", userID).
+			Where("ch.deleted_at IS NULL").
+			Where(squirrel.Or{
+				squirrel.Eq{"ch.type": messagingTypes.ChannelTypePublic},
+				squirrel.NotEq{"cm.rel_user": nil},
+			}).
+			OrderBy("ch.id")
+	)
+
+	if err := rh.FetchAll(r.db(), q, &rows); err != nil {
+		return nil, err
+	}
+
+	set := make(messagingTypes.ChannelSet, len(rows))
+	for i, row := range rows {
+		set[i] = &messagingTypes.Channel{ID: row.ID, Type: row.Type, ArchivedAt: row.ArchivedAt}
+
+		if row.MemberType.Valid {
+			set[i].Member = &messagingTypes.ChannelMember{
+				ChannelID: row.ID,
+				UserID:    userID,
+				Type:      messagingTypes.ChannelMembershipType(row.MemberType.String),
+			}
+		}
+	}
+
+	return set, nil
+}
+
+// RecountChannels recounts members and messages of the channels (all when none are given)
+func (r repository) RecountChannels(channelIDs ...uint64) error {
+	var cnd squirrel.Sqlizer = squirrel.Expr("TRUE")
+	if len(channelIDs) > 0 {
+		cnd = squirrel.Eq{"ch.id": channelIDs}
+	}
+
+	return r.exec(r.db(), sqlRecountChannels, cnd)
+}
+
+// RecountMembers recounts members of the channel
+func (r repository) RecountMembers(channelID uint64) error {
+	_, err := r.db().Exec(sqlRecountMembers, channelID, channelID)
+	return errors.Wrap(err, "can not recount channel members")
+}
+
+// AddMessages changes message count of the channel by delta
+//
+// Channels without counter are recounted.
+func (r repository) AddMessages(channelID uint64, delta int) error {
+	res, err := r.db().Exec(sqlAddMessages, delta, channelID)
+	if err != nil {
+		return errors.Wrap(err, "can not update channel message count")
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return r.RecountChannels(channelID)
+	}
+
+	return nil
+}
+
+// RecountUnread recounts unread totals of the users (all when none are given)
+//
+// Totals are reset first; users without unread counters (no channels left)
+// would keep their old totals otherwise.
+func (r repository) RecountUnread(userIDs ...uint64) error {
+	var (
+		cnd   squirrel.Sqlizer = squirrel.Expr("TRUE")
+		reset                  = squirrel.Update(r.tableUnread()).
+			Set("messages", 0).
+			Set("channels", 0).
+			Set("threads", 0).
+			Set("mentions", 0)
+	)
+
+	if len(userIDs) > 0 {
+		cnd = squirrel.Eq{"u.rel_user": userIDs}
+		reset = reset.Where(squirrel.Eq{"rel_user": userIDs})
+	}
+
+	db := r.db()
+	return db.Transaction(func() error {
+		if query, args, err := reset.ToSql(); err != nil {
+			return err
+		} else if _, err = db.Exec(query, args...); err != nil {
+			return errors.Wrap(err, "can not reset unread totals")
+		}
+
+		return r.exec(db, sqlRecountUnread, cnd)
+	})
+}
+
+// RecountChannelUnread recounts unread totals of all channel members
+func (r repository) RecountChannelUnread(channelID uint64) error {
+	return r.exec(r.db(), sqlRecountUnread, squirrel.Expr(
+		"u.rel_user IN (SELECT rel_user FROM messaging_channel_member WHERE rel_channel = ?)",
+		channelID,
+	))
+}
+
+// exec runs query with the condition in place of %s
+func (r repository) exec(db *factory.DB, query string, cnd squirrel.Sqlizer) error {
+	where, args, err := cnd.ToSql()
+	if err != nil {
+		return err
+	}
+
+	if _, err = db.Exec(fmt.Sprintf(query, where), args...); err != nil {
+		return errors.Wrap(err, "can not recount counters")
+	}
+
+	return nil
+}
+
+// Members returns IDs of channel's members
+func (r repository) Members(channelID uint64) (userIDs []uint64, err error) {
+	return userIDs, r.db().Select(&userIDs, "SELECT rel_user FROM messaging_channel_member WHERE rel_channel = ?", channelID)
+}
+
+// UnreadCounts returns user's unread messages and mentions of channels with unread messages
+func (r repository) UnreadCounts(userID uint64) (cc []*UnreadCount, err error) {
+	return cc, errors.Wrap(r.db().Select(&cc, sqlUnreadCounts, userID), "can not load unread counts")
+}
+
+// UnreadChannels returns IDs of channels with unread messages or replies of the user
+func (r repository) UnreadChannels(userID uint64) (channelIDs []uint64, err error) {
+	err = r.db().Select(&channelIDs,
+		"SELECT DISTINCT rel_channel FROM messaging_unread WHERE rel_user = ? AND count > 0 ORDER BY rel_channel",
+		userID,
+	)
+
+	return channelIDs, errors.Wrap(err, "can not load unread channels")
+}
+
+// FindMarkers returns read markers of channel (or thread) members
+//
+// Markers are corteza's last read messages, with times they were read
+// at when recorded.
+func (r repository) FindMarkers(channelID, threadID uint64) (set ReadMarkerSet, err error) {
+	q := squirrel.
+		Select("u.rel_channel", "u.rel_reply_to", "u.rel_user", "u.rel_last_message", "rm.read_at").
+		From("messaging_unread AS u").
+		Join("messaging_channel_member AS cm ON (cm.rel_channel = u.rel_channel AND cm.rel_user = u.rel_user)").
+		LeftJoin(r.tableMarker()+" AS rm ON (rm.rel_channel = u.rel_channel AND rm.rel_reply_to = u.rel_reply_to AND rm.rel_user = u.rel_user AND rm.rel_last_message = u.rel_last_message)").
+		Where(squirrel.Eq{"u.rel_channel": channelID, "u.rel_reply_to": threadID}).
+		Where("u.rel_last_message > 0").
+		OrderBy("u.rel_last_message DESC", "u.rel_user")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// SaveMarker records when the user read the channel (or thread)
+func (r repository) SaveMarker(m *ReadMarker) error {
+	return errors.Wrap(r.db().Replace(r.tableMarker(), m), "can not save read marker")
+}
+
+// Unreaders returns IDs of users with unread counters of the channel or thread,
+// except the given one; same users that corteza increments counters of
+func (r repository) Unreaders(channelID, threadID, exceptUserID uint64) (userIDs []uint64, err error) {
+	err = r.db().Select(&userIDs,
+		"SELECT rel_user FROM messaging_unread WHERE rel_channel = ? AND rel_reply_to = ? AND rel_user <> ?",
+		channelID, threadID, exceptUserID,
+	)
+
+	return userIDs, errors.Wrap(err, "can not load unread counters")
+}
+
+// Mentioned returns IDs of users mentioned in the message
+func (r repository) Mentioned(messageID uint64) (userIDs []uint64, err error) {
+	err = r.db().Select(&userIDs, "SELECT rel_user FROM messaging_mention WHERE rel_message = ?", messageID)
+	return userIDs, errors.Wrap(err, "can not load mentions")
+}
+
+// MessageChannel returns ID of message's channel
+func (r repository) MessageChannel(messageID uint64) (channelID uint64, err error) {
+	err = r.db().Get(&channelID, "SELECT rel_channel FROM messaging_message WHERE id = ?", messageID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return channelID, err
+}
@@ -0,0 +1,46 @@
+package counters
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts channel counter endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Member, message, unread and mention counts of current user's channels,
+	// of all or of the given ones (?channelID=1&channelID=2)
+	r.Get("/", rest.Handler("ChannelCounters.List", func(r *http.Request) (interface{}, error) {
+		return DefaultCounters.With(r.Context()).Find(rest.QueryUint64s(r, "channelID")...)
+	}))
+
+	// Marks channels and threads as read, {"reads": [{"channelID": "1", "messageID": "2"}]}
+	r.Post("/read", rest.Handler("ChannelCounters.MarkAsRead", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Reads []*Read `json:"reads"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultCounters.With(r.Context()).MarkAsRead(body.Reads)
+	}))
+
+	r.Post("/read-all", rest.Handler("ChannelCounters.MarkAllAsRead", func(r *http.Request) (interface{}, error) {
+		return DefaultCounters.With(r.Context()).MarkAllAsRead()
+	}))
+
+	// Read receipts, markers of channel members (?threadID= for thread's)
+	r.Get("/{channelID}/read-markers", rest.Handler("ChannelCounters.ReadMarkers", func(r *http.Request) (interface{}, error) {
+		return DefaultCounters.With(r.Context()).FindMarkers(
+			rest.ParamUint64(r, "channelID"),
+			rest.QueryUint64(r, "threadID"),
+		)
+	}))
+}
@@ -0,0 +1,343 @@
+package counters
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/access"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		repository *repository
+	}
+
+	CounterService interface {
+		With(ctx context.Context) CounterService
+
+		Find(channelIDs ...uint64) (*Counters, error)
+		Reconcile() error
+
+		MarkAsRead(rr []*Read) (ReadMarkerSet, error)
+		MarkAllAsRead() (ReadMarkerSet, error)
+		FindMarkers(channelID, threadID uint64) (ReadMarkerSet, error)
+	}
+)
+
+const (
+	// Counters are recounted from scratch periodically, fixing drift
+	// caused by changes made around messaging services (imports, failed updates)
+	reconcileInterval = time.Hour
+)
+
+var (
+	DefaultCounters CounterService
+
+	// used by service decorators
+	defaultCounters *service
+)
+
+// Init initializes counters and decorates channel & message services
+//
+// Counters are updated after members join or leave, messages are created
+// or deleted and channels are read; users subscribed to their user scope
+// of live get deltas and snapshots of their unread counters, subscribers
+// of the channel get read markers of its members. Must be called after
+// messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger: log,
+	}
+
+	DefaultCounters = svc.With(ctx)
+	defaultCounters = svc.with(ctx)
+
+	messagingService.DefaultChannel = Channel(messagingService.DefaultChannel)
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+
+	snapshotInterval = options.EnvDuration("", "COUNTERS_SNAPSHOT_INTERVAL", snapshotInterval)
+
+	go svc.watch(ctx)
+	go svc.watchSnapshots(ctx)
+
+	return nil
+}
+
+func (svc service) With(ctx context.Context) CounterService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns counters of channels that current user can read and user's unread totals
+//
+// Counters of all channels are returned when none are given.
+func (svc service) Find(channelIDs ...uint64) (*Counters, error) {
+	userID := auth.GetIdentityFromContext(svc.ctx).Identity()
+
+	cc, err := svc.repository.Channels(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		readable  = access.CanReadChannels(svc.ctx, cc)
+		ids       = make([]uint64, 0, len(cc))
+		requested = map[uint64]bool{}
+	)
+
+	for _, channelID := range channelIDs {
+		requested[channelID] = true
+	}
+
+	for _, ch := range cc {
+		if readable[ch.ID] && (len(channelIDs) == 0 || requested[ch.ID]) {
+			ids = append(ids, ch.ID)
+		}
+	}
+
+	set, err := svc.repository.Find(ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := svc.repository.ChannelUnread(userID, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mentions are counted only in channels with unread messages
+	uc, err := svc.repository.UnreadCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	mentions := map[uint64]uint{}
+	for _, c := range uc {
+		mentions[c.ChannelID] = c.Mentions
+	}
+
+	out := &Counters{Channels: make(ChannelCounterSet, len(ids))}
+	for i, channelID := range ids {
+		if out.Channels[i] = set.FindByChannelID(channelID); out.Channels[i] == nil {
+			// Not counted yet
+			out.Channels[i] = &ChannelCounter{ChannelID: channelID}
+		}
+
+		out.Channels[i].Unread = unread[channelID]
+		out.Channels[i].Mentions = mentions[channelID]
+	}
+
+	if out.Unread, err = svc.repository.FindUnreadTotal(userID); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// MarkAsRead marks channels and threads as read, returns current user's read markers
+//
+// Unread counters of the user are recounted and pushed after every mark.
+func (svc service) MarkAsRead(rr []*Read) (ReadMarkerSet, error) {
+	if len(rr) > maxReads {
+		return nil, ErrTooManyReads.withStack()
+	}
+
+	for _, r := range rr {
+		if r.ChannelID == 0 {
+			return nil, ErrInvalidRead.withStack()
+		}
+	}
+
+	return svc.markAsRead(rr)
+}
+
+// MarkAllAsRead marks all channels with unread messages or replies of the
+// current user as read
+func (svc service) MarkAllAsRead() (ReadMarkerSet, error) {
+	ids, err := svc.repository.UnreadChannels(auth.GetIdentityFromContext(svc.ctx).Identity())
+	if err != nil {
+		return nil, err
+	}
+
+	rr := make([]*Read, len(ids))
+	for i, channelID := range ids {
+		rr[i] = &Read{ChannelID: channelID}
+	}
+
+	return svc.markAsRead(rr)
+}
+
+func (svc service) markAsRead(rr []*Read) (ReadMarkerSet, error) {
+	var (
+		ms     = messagingService.DefaultMessage.With(svc.ctx)
+		userID = auth.GetIdentityFromContext(svc.ctx).Identity()
+		out    = make(ReadMarkerSet, 0, len(rr))
+	)
+
+	for _, r := range rr {
+		lastID, _, _, err := ms.MarkAsRead(r.ChannelID, r.ThreadID, r.MessageID)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, &ReadMarker{
+			ChannelID:     r.ChannelID,
+			ThreadID:      r.ThreadID,
+			UserID:        userID,
+			LastMessageID: lastID,
+		})
+	}
+
+	return out, nil
+}
+
+// FindMarkers returns read markers of members of the channel (or thread),
+// the latest first
+func (svc service) FindMarkers(channelID, threadID uint64) (ReadMarkerSet, error) {
+	if _, err := messagingService.DefaultChannel.With(svc.ctx).FindByID(channelID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindMarkers(channelID, threadID)
+}
+
+// Reconcile recounts all counters
+func (svc service) Reconcile() error {
+	if err := svc.repository.RecountChannels(); err != nil {
+		return err
+	}
+
+	return svc.repository.RecountUnread()
+}
+
+// membersChanged recounts channel's members and unread totals of
+// users that joined or left
+func (svc service) membersChanged(channelID uint64, userIDs ...uint64) {
+	if err := svc.repository.RecountMembers(channelID); err != nil {
+		svc.log(zap.Uint64("channelID", channelID), zap.Error(err)).Error("could not recount members")
+	}
+
+	if len(userIDs) > 0 {
+		go svc.recount(zap.Uint64("channelID", channelID), func() error {
+			if err := svc.repository.RecountUnread(userIDs...); err != nil {
+				return err
+			}
+
+			svc.pushSnapshots(userIDs...)
+			return nil
+		})
+	}
+}
+
+// messagesChanged updates channel's message count and unread totals of its members
+func (svc service) messagesChanged(channelID uint64, delta int) {
+	if err := svc.repository.AddMessages(channelID, delta); err != nil {
+		svc.log(zap.Uint64("channelID", channelID), zap.Error(err)).Error("could not update message count")
+	}
+
+	go svc.recount(zap.Uint64("channelID", channelID), func() error {
+		if err := svc.repository.RecountChannelUnread(channelID); err != nil || delta > 0 {
+			// Members are told about new messages with deltas
+			return err
+		}
+
+		userIDs, err := svc.repository.Members(channelID)
+		if err != nil {
+			return err
+		}
+
+		svc.pushSnapshots(userIDs...)
+		return nil
+	})
+}
+
+// channelChanged recounts unread totals of all members after channel is deleted or restored
+func (svc service) channelChanged(channelID uint64) {
+	go svc.recount(zap.Uint64("channelID", channelID), func() error {
+		userIDs, err := svc.repository.Members(channelID)
+		if err != nil || len(userIDs) == 0 {
+			return err
+		}
+
+		if err = svc.repository.RecountUnread(userIDs...); err != nil {
+			return err
+		}
+
+		svc.pushSnapshots(userIDs...)
+		return nil
+	})
+}
+
+// marked records user's read marker and publishes it to the channel
+func (svc service) marked(m *ReadMarker) {
+	now := time.Now().Truncate(time.Second)
+	m.ReadAt = &now
+
+	if err := svc.repository.SaveMarker(m); err != nil {
+		svc.log(zap.Uint64("channelID", m.ChannelID), zap.Error(err)).Error("could not record read marker")
+	}
+
+	live.Publish(&live.Event{Scope: live.ChannelScope(m.ChannelID), Type: live.EventReadMarker, Payload: m})
+
+	svc.read(m.UserID)
+}
+
+// read recounts unread totals of the user
+func (svc service) read(userID uint64) {
+	go svc.recount(zap.Uint64("userID", userID), func() error {
+		if err := svc.repository.RecountUnread(userID); err != nil {
+			return err
+		}
+
+		svc.pushSnapshots(userID)
+		return nil
+	})
+}
+
+func (svc service) recount(field zapcore.Field, fn func() error) {
+	if err := fn(); err != nil {
+		svc.log(field, zap.Error(err)).Error("could not recount unread totals")
+	}
+}
+
+func (svc service) watch(ctx context.Context) {
+	t := time.NewTicker(reconcileInterval)
+	defer t.Stop()
+
+	for {
+		if err := svc.with(ctx).Reconcile(); err != nil {
+			svc.logger.Error("could not reconcile counters", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
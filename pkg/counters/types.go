@@ -0,0 +1,124 @@
+package counters
+
+import (
+	"time"
+)
+
+type (
+	// ChannelCounter holds precomputed counts of a channel
+	ChannelCounter struct {
+		ChannelID uint64 `json:"channelID,string" db:"rel_channel"`
+
+		// Members and owners, invitees are not counted
+		Members uint `json:"members" db:"members"`
+
+		// Messages and replies, deleted are not counted
+		Messages uint `json:"messages" db:"messages"`
+
+		// Unread messages and mentions of the current user (not stored with the counter)
+		Unread   uint `json:"unread" db:"-"`
+		Mentions uint `json:"mentions" db:"-"`
+
+		UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	}
+
+	ChannelCounterSet []*ChannelCounter
+
+	// UnreadTotal holds precomputed unread counts of a user, over all channels
+	UnreadTotal struct {
+		UserID uint64 `json:"userID,string" db:"rel_user"`
+
+		// Unread messages, replies in threads are not included
+		Messages uint `json:"messages" db:"messages"`
+
+		// Channels with unread messages
+		Channels uint `json:"channels" db:"channels"`
+
+		// Threads with unread replies
+		Threads uint `json:"threads" db:"threads"`
+
+		// Mentions in unread messages, replies in threads are not included
+		Mentions uint `json:"mentions" db:"mentions"`
+
+		UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	}
+
+	// Counters of the current user's channels
+	Counters struct {
+		Channels ChannelCounterSet `json:"channels"`
+		Unread   *UnreadTotal      `json:"unread"`
+	}
+
+	// UnreadDelta is pushed to users when a message is created in a channel
+	// or thread they have unread counters of
+	UnreadDelta struct {
+		ChannelID uint64 `json:"channelID,string"`
+		ThreadID  uint64 `json:"threadID,string,omitempty"`
+		MessageID uint64 `json:"messageID,string"`
+
+		// Added to unread messages and mentions of the channel (thread)
+		Unread   int `json:"unread"`
+		Mentions int `json:"mentions"`
+	}
+
+	// UnreadSnapshot replaces user's counters, pushed after channels are
+	// read and periodically, so that missed or misapplied deltas are fixed
+	UnreadSnapshot struct {
+		// Channels with unread messages, all others have none
+		Channels []*UnreadCount `json:"channels"`
+		Unread   *UnreadTotal   `json:"unread"`
+	}
+
+	// UnreadCount holds user's unread messages and mentions of a channel
+	UnreadCount struct {
+		ChannelID uint64 `json:"channelID,string" db:"rel_channel"`
+		Unread    uint   `json:"unread" db:"count"`
+		Mentions  uint   `json:"mentions" db:"mentions"`
+	}
+
+	// ReadMarker is the last message of the channel (or thread) the user read
+	//
+	// Markers of channel members are read receipts; they are published
+	// to the channel when users read it.
+	ReadMarker struct {
+		ChannelID     uint64 `json:"channelID,string" db:"rel_channel"`
+		ThreadID      uint64 `json:"threadID,string,omitempty" db:"rel_reply_to"`
+		UserID        uint64 `json:"userID,string" db:"rel_user"`
+		LastMessageID uint64 `json:"lastMessageID,string" db:"rel_last_message"`
+
+		// Not known for markers that were set before they were recorded
+		ReadAt *time.Time `json:"readAt,omitempty" db:"read_at"`
+	}
+
+	ReadMarkerSet []*ReadMarker
+
+	// Read marks the channel (or thread) as read up to the message,
+	// to the last message when not given
+	Read struct {
+		ChannelID uint64 `json:"channelID,string"`
+		ThreadID  uint64 `json:"threadID,string"`
+		MessageID uint64 `json:"messageID,string"`
+	}
+
+	// channelUnread is user's unread count of a channel
+	channelUnread struct {
+		ChannelID uint64 `db:"rel_channel"`
+		Count     uint   `db:"count"`
+	}
+)
+
+const (
+	// Channels and threads that can be marked as read at once
+	maxReads = 100
+)
+
+// FindByChannelID finds counter of the channel
+func (set ChannelCounterSet) FindByChannelID(channelID uint64) *ChannelCounter {
+	for _, c := range set {
+		if c.ChannelID == channelID {
+			return c
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package crmsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// adapter talks to the API of a connection of the kind
+	adapter interface {
+		// changes returns objects changed after the given time, the
+		// oldest first, up to batchSize
+		changes(ctx context.Context, c *Connection, object string, fields []string, since *time.Time) ([]*remote, error)
+
+		fetch(ctx context.Context, c *Connection, object, ID string, fields []string) (*remote, error)
+
+		// create and update return version of the object after the change
+		create(ctx context.Context, c *Connection, object string, values map[string]string) (string, time.Time, error)
+		update(ctx context.Context, c *Connection, object, ID string, values map[string]string) (time.Time, error)
+	}
+)
+
+var (
+	adapters = map[string]adapter{
+		KindSalesforce: &salesforce{tokens: map[string]*salesforceToken{}},
+		KindServiceNow: servicenow{},
+	}
+
+	client = &http.Client{Timeout: requestTimeout}
+)
+
+// do sends the request and decodes JSON response into dst, when given
+func do(ctx context.Context, req *http.Request, dst interface{}) (int, error) {
+	req.Header.Set("Accept", "application/json")
+
+	rsp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, ErrRemoteFailed.withStack().WithMessage(err.Error())
+	}
+
+	defer rsp.Body.Close()
+
+	switch {
+	case rsp.StatusCode == http.StatusNotFound:
+		return rsp.StatusCode, ErrRemoteNotFound.withStack()
+	case rsp.StatusCode < 200 || rsp.StatusCode > 299:
+		b, _ := ioutil.ReadAll(io.LimitReader(rsp.Body, maxResponseError))
+		return rsp.StatusCode, ErrRemoteFailed.withStack().WithMessage(fmt.Sprintf("unexpected status %d: %s", rsp.StatusCode, strings.TrimSpace(string(b))))
+	case dst == nil || rsp.StatusCode == http.StatusNoContent:
+		return rsp.StatusCode, nil
+	}
+
+	if err = json.NewDecoder(rsp.Body).Decode(dst); err != nil {
+		return rsp.StatusCode, ErrRemoteFailed.withStack().WithMessage(err.Error())
+	}
+
+	return rsp.StatusCode, nil
+}
+
+// text returns JSON value as text; objects and lists are left as JSON
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// request returns request with values encoded as JSON body, when given;
+// empty values clear fields
+func request(method, url string, values map[string]string) (*http.Request, error) {
+	var body io.Reader
+
+	if values != nil {
+		out := map[string]interface{}{}
+		for k, v := range values {
+			if v == "" {
+				out[k] = nil
+			} else {
+				out[k] = v
+			}
+		}
+
+		b, err := json.Marshal(out)
+		if err != nil {
+			return nil, ErrRemoteFailed.withStack().WithMessage(err.Error())
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, ErrRemoteFailed.withStack().WithMessage(err.Error())
+	}
+
+	if values != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
@@ -0,0 +1,44 @@
+package crmsync
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	crmsyncError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrConnectionNotFound = crmsyncError{"ConnectionNotFound", fault.NotFound}
+	ErrSyncNotFound       = crmsyncError{"SyncNotFound", fault.NotFound}
+	ErrQueuedNotFound     = crmsyncError{"QueuedNotFound", fault.NotFound}
+	ErrRemoteNotFound     = crmsyncError{"RemoteNotFound", fault.NotFound}
+	ErrConnectionInUse    = crmsyncError{"ConnectionInUse", fault.Conflict}
+	ErrInvalidKind        = crmsyncError{"InvalidKind", fault.Invalid}
+	ErrInvalidURL         = crmsyncError{"InvalidURL", fault.Invalid}
+	ErrInvalidObject      = crmsyncError{"InvalidObject", fault.Invalid}
+	ErrInvalidMapping     = crmsyncError{"InvalidMapping", fault.Invalid}
+	ErrInvalidDirection   = crmsyncError{"InvalidDirection", fault.Invalid}
+	ErrInvalidConflict    = crmsyncError{"InvalidConflict", fault.Invalid}
+	ErrRemoteFailed       = crmsyncError{"RemoteFailed", fault.Unavailable}
+	ErrNoPermissions      = crmsyncError{"NoPermissions", fault.Forbidden}
+)
+
+func (e crmsyncError) Error() string {
+	return e.String()
+}
+
+func (e crmsyncError) String() string {
+	return "crust.crmsync." + e.name
+}
+
+func (e crmsyncError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e crmsyncError) withStack() *fault.Error {
+	return fault.New(e)
+}
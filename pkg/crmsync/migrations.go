@@ -0,0 +1,90 @@
+package crmsync
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200310000000.crmsync",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_crm_connection (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  kind               VARCHAR(16)     NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  instance_url       VARCHAR(512)    NOT NULL,
+  login_url          VARCHAR(512)    NOT NULL DEFAULT '',
+  client_id          VARCHAR(255)    NOT NULL DEFAULT '',
+  client_secret      TEXT            NOT NULL,
+  username           VARCHAR(255)    NOT NULL DEFAULT '',
+  password           TEXT            NOT NULL,
+
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_crm_sync (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_connection     BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  object             VARCHAR(128)    NOT NULL,
+  mapping            TEXT            NOT NULL,
+  direction          VARCHAR(8)      NOT NULL,
+  conflict           VARCHAR(16)     NOT NULL,
+  interval_minutes   INT UNSIGNED    NOT NULL,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  pull_cursor        DATETIME            NULL DEFAULT NULL,
+  pushed_at          DATETIME            NULL DEFAULT NULL,
+  synced_at          DATETIME            NULL DEFAULT NULL,
+  next_sync_at       DATETIME            NULL DEFAULT NULL,
+  last_error         TEXT            NOT NULL,
+
+  owned_by           BIGINT UNSIGNED NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (enabled, next_sync_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_crm_link (
+  rel_sync           BIGINT UNSIGNED NOT NULL,
+  rel_record         BIGINT UNSIGNED NOT NULL,
+  remote_id          VARCHAR(64)     NOT NULL,
+  remote_version     DATETIME        NOT NULL,
+  local_version      DATETIME        NOT NULL,
+  synced_at          DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_sync, rel_record),
+  UNIQUE KEY uid_remote (rel_sync, remote_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_crm_queue (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_sync           BIGINT UNSIGNED NOT NULL,
+  direction          VARCHAR(8)      NOT NULL,
+  item               VARCHAR(64)     NOT NULL,
+  error              TEXT            NOT NULL,
+  attempts           INT UNSIGNED    NOT NULL DEFAULT 1,
+
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  resolved_at        DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY uid_item (rel_sync, direction, item)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,353 @@
+package crmsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableConnection() string {
+	return "crust_compose_crm_connection"
+}
+
+func (r repository) tableSync() string {
+	return "crust_compose_crm_sync"
+}
+
+func (r repository) tableLink() string {
+	return "crust_compose_crm_link"
+}
+
+func (r repository) tableQueue() string {
+	return "crust_compose_crm_queue"
+}
+
+func (r repository) queryConnection() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"kind",
+			"name",
+			"instance_url",
+			"login_url",
+			"client_id",
+			"client_secret",
+			"username",
+			"password",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableConnection()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindConnectionByID(namespaceID, connectionID uint64) (*Connection, error) {
+	var (
+		c = &Connection{}
+		q = r.queryConnection().Where(squirrel.Eq{"id": connectionID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, c); err != nil {
+		return nil, err
+	} else if c.ID == 0 {
+		return nil, ErrConnectionNotFound.withStack()
+	}
+
+	return c, nil
+}
+
+func (r repository) FindConnections(namespaceID uint64) (set ConnectionSet, err error) {
+	q := r.queryConnection().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateConnection(c *Connection) (*Connection, error) {
+	c.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&c.CreatedAt)
+
+	return c, errors.WithStack(r.db().Insert(r.tableConnection(), c))
+}
+
+func (r repository) UpdateConnection(c *Connection) (*Connection, error) {
+	rh.SetCurrentTimeRounded(&c.UpdatedAt)
+
+	return c, errors.WithStack(r.db().Update(r.tableConnection(), c, "id"))
+}
+
+func (r repository) DeleteConnectionByID(namespaceID, connectionID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableConnection(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": connectionID, "rel_namespace": namespaceID},
+	)
+}
+
+func (r repository) querySync() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_connection",
+			"rel_module",
+			"object",
+			"mapping",
+			"direction",
+			"conflict",
+			"interval_minutes",
+			"enabled",
+			"pull_cursor",
+			"pushed_at",
+			"synced_at",
+			"next_sync_at",
+			"last_error",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableSync()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindSyncByID(namespaceID, syncID uint64) (*Sync, error) {
+	var (
+		s = &Sync{}
+		q = r.querySync().Where(squirrel.Eq{"id": syncID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.ID == 0 {
+		return nil, ErrSyncNotFound.withStack()
+	}
+
+	return s, nil
+}
+
+func (r repository) FindSyncs(namespaceID uint64) (set SyncSet, err error) {
+	q := r.querySync().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindDueSyncs returns enabled syncs that were never synced or are due
+func (r repository) FindDueSyncs(now time.Time) (set SyncSet, err error) {
+	q := r.querySync().
+		Where(squirrel.Eq{"enabled": true}).
+		Where(squirrel.Or{squirrel.Eq{"next_sync_at": nil}, squirrel.LtOrEq{"next_sync_at": now}}).
+		OrderBy("next_sync_at")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// CountSyncs returns number of syncs of the connection
+func (r repository) CountSyncs(connectionID uint64) (n uint, err error) {
+	var (
+		out = struct {
+			Count uint `db:"count"`
+		}{}
+
+		q = squirrel.
+			Select("COUNT(*) AS count").
+			From(r.tableSync()).
+			Where(squirrel.Eq{"rel_connection": connectionID, "deleted_at": nil})
+	)
+
+	return out.Count, rh.FetchOne(r.db(), q, &out)
+}
+
+func (r repository) CreateSync(s *Sync) (*Sync, error) {
+	s.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&s.CreatedAt)
+
+	return s, errors.WithStack(r.db().Insert(r.tableSync(), s))
+}
+
+func (r repository) UpdateSync(s *Sync) (*Sync, error) {
+	rh.SetCurrentTimeRounded(&s.UpdatedAt)
+
+	return s, errors.WithStack(r.db().Update(r.tableSync(), s, "id"))
+}
+
+func (r repository) UpdateSyncState(s *Sync) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableSync(),
+		rh.Set{
+			"pull_cursor":  s.Cursor,
+			"pushed_at":    s.PushedAt,
+			"synced_at":    s.SyncedAt,
+			"next_sync_at": s.NextSyncAt,
+			"last_error":   s.LastError,
+		},
+		squirrel.Eq{"id": s.ID},
+	)
+}
+
+// DeleteSyncByID removes the sync with its links and queue
+func (r repository) DeleteSyncByID(namespaceID, syncID uint64) error {
+	return r.db().Transaction(func() error {
+		err := rh.UpdateColumns(
+			r.db(),
+			r.tableSync(),
+			rh.Set{"deleted_at": time.Now()},
+			squirrel.Eq{"id": syncID, "rel_namespace": namespaceID},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if err = rh.Delete(r.db(), r.tableLink(), squirrel.Eq{"rel_sync": syncID}); err != nil {
+			return err
+		}
+
+		return rh.Delete(r.db(), r.tableQueue(), squirrel.Eq{"rel_sync": syncID})
+	})
+}
+
+func (r repository) queryLink() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"rel_sync",
+			"rel_record",
+			"remote_id",
+			"remote_version",
+			"local_version",
+			"synced_at",
+		).
+		From(r.tableLink())
+}
+
+// FindLinkByRemote returns link of the remote object, nil when there is none
+func (r repository) FindLinkByRemote(syncID uint64, remoteID string) (*Link, error) {
+	return r.findLink(squirrel.Eq{"rel_sync": syncID, "remote_id": remoteID})
+}
+
+// FindLinkByRecord returns link of the record, nil when there is none
+func (r repository) FindLinkByRecord(syncID, recordID uint64) (*Link, error) {
+	return r.findLink(squirrel.Eq{"rel_sync": syncID, "rel_record": recordID})
+}
+
+func (r repository) findLink(cnd squirrel.Sqlizer) (*Link, error) {
+	var l = &Link{}
+
+	if err := rh.FetchOne(r.db(), r.queryLink().Where(cnd), l); err != nil {
+		return nil, err
+	} else if l.RecordID == 0 {
+		return nil, nil
+	}
+
+	return l, nil
+}
+
+func (r repository) SaveLink(l *Link) (*Link, error) {
+	rh.SetCurrentTimeRounded(&l.SyncedAt)
+
+	return l, errors.WithStack(r.db().Replace(r.tableLink(), l))
+}
+
+func (r repository) queryQueue() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_sync",
+			"direction",
+			"item",
+			"error",
+			"attempts",
+			"created_at",
+			"updated_at",
+			"resolved_at",
+		).
+		From(r.tableQueue())
+}
+
+func (r repository) FindQueuedByID(syncID, queuedID uint64) (*Queued, error) {
+	var (
+		q = &Queued{}
+		s = r.queryQueue().Where(squirrel.Eq{"id": queuedID, "rel_sync": syncID})
+	)
+
+	if err := rh.FetchOne(r.db(), s, q); err != nil {
+		return nil, err
+	} else if q.ID == 0 {
+		return nil, ErrQueuedNotFound.withStack()
+	}
+
+	return q, nil
+}
+
+// FindQueued returns items of the sync that were not resolved, the latest first
+func (r repository) FindQueued(syncID uint64) (set QueuedSet, err error) {
+	q := r.queryQueue().
+		Where(squirrel.Eq{"rel_sync": syncID, "resolved_at": nil}).
+		OrderBy("id DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Enqueue stores the item that failed; items that are in the queue
+// already get the new error and another attempt
+func (r repository) Enqueue(syncID uint64, d Direction, item, reason string) error {
+	at := time.Now().Truncate(time.Second)
+
+	_, err := r.db().Exec(
+		"INSERT INTO "+r.tableQueue()+" (id, rel_sync, direction, item, error, attempts, created_at)"+
+			" VALUES (?, ?, ?, ?, ?, 1, ?)"+
+			" ON DUPLICATE KEY UPDATE error = VALUES(error), attempts = attempts + 1, updated_at = VALUES(created_at), resolved_at = NULL",
+		factory.Sonyflake.NextID(), syncID, d, item, reason, at,
+	)
+
+	return errors.WithStack(err)
+}
+
+// Resolve marks queued item as resolved; it is resolved by the next
+// successful sync of the item
+func (r repository) Resolve(syncID uint64, d Direction, item string) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableQueue(),
+		rh.Set{"resolved_at": time.Now()},
+		squirrel.Eq{"rel_sync": syncID, "direction": d, "item": item, "resolved_at": nil},
+	)
+}
+
+func (r repository) DeleteQueued(queuedID uint64) error {
+	return rh.Delete(r.db(), r.tableQueue(), squirrel.Eq{"id": queuedID})
+}
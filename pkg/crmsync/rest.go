@@ -0,0 +1,116 @@
+package crmsync
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts CRM sync management endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Route("/connections", func(r chi.Router) {
+		r.Get("/", rest.Handler("CRMConnection.List", func(r *http.Request) (interface{}, error) {
+			return DefaultCRMSync.With(r.Context()).FindConnections(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Post("/", rest.Handler("CRMConnection.Create", func(r *http.Request) (interface{}, error) {
+			c := &Connection{}
+			if err := rest.Decode(r, c); err != nil {
+				return nil, err
+			}
+
+			c.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultCRMSync.With(r.Context()).CreateConnection(c)
+		}))
+
+		r.Put("/{connectionID}", rest.Handler("CRMConnection.Update", func(r *http.Request) (interface{}, error) {
+			c := &Connection{}
+			if err := rest.Decode(r, c); err != nil {
+				return nil, err
+			}
+
+			c.ID = rest.ParamUint64(r, "connectionID")
+			c.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultCRMSync.With(r.Context()).UpdateConnection(c)
+		}))
+
+		r.Delete("/{connectionID}", rest.Handler("CRMConnection.Delete", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultCRMSync.With(r.Context()).DeleteConnection(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "connectionID"),
+			)
+		}))
+	})
+
+	r.Route("/syncs", func(r chi.Router) {
+		r.Get("/", rest.Handler("CRMSync.List", func(r *http.Request) (interface{}, error) {
+			return DefaultCRMSync.With(r.Context()).FindSyncs(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Post("/", rest.Handler("CRMSync.Create", func(r *http.Request) (interface{}, error) {
+			s := &Sync{}
+			if err := rest.Decode(r, s); err != nil {
+				return nil, err
+			}
+
+			s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultCRMSync.With(r.Context()).CreateSync(s)
+		}))
+
+		r.Put("/{syncID}", rest.Handler("CRMSync.Update", func(r *http.Request) (interface{}, error) {
+			s := &Sync{}
+			if err := rest.Decode(r, s); err != nil {
+				return nil, err
+			}
+
+			s.ID = rest.ParamUint64(r, "syncID")
+			s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultCRMSync.With(r.Context()).UpdateSync(s)
+		}))
+
+		r.Delete("/{syncID}", rest.Handler("CRMSync.Delete", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultCRMSync.With(r.Context()).DeleteSync(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "syncID"),
+			)
+		}))
+
+		r.Post("/{syncID}/sync", rest.Handler("CRMSync.Sync", func(r *http.Request) (interface{}, error) {
+			return DefaultCRMSync.With(r.Context()).Sync(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "syncID"),
+			)
+		}))
+
+		r.Get("/{syncID}/queue", rest.Handler("CRMSync.Queue", func(r *http.Request) (interface{}, error) {
+			return DefaultCRMSync.With(r.Context()).FindQueued(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "syncID"),
+			)
+		}))
+
+		r.Post("/{syncID}/queue/{queuedID}/retry", rest.Handler("CRMSync.Retry", func(r *http.Request) (interface{}, error) {
+			return DefaultCRMSync.With(r.Context()).Retry(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "syncID"),
+				rest.ParamUint64(r, "queuedID"),
+			)
+		}))
+
+		r.Delete("/{syncID}/queue/{queuedID}", rest.Handler("CRMSync.Discard", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultCRMSync.With(r.Context()).Discard(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "syncID"),
+				rest.ParamUint64(r, "queuedID"),
+			)
+		}))
+	})
+}
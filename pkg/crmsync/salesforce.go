@@ -0,0 +1,196 @@
+package crmsync
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// salesforce talks to Salesforce's REST API
+	//
+	// Access tokens are kept until they are rejected or the connection
+	// is changed.
+	salesforce struct {
+		mux    sync.Mutex
+		tokens map[string]*salesforceToken
+	}
+
+	salesforceToken struct {
+		AccessToken string `json:"access_token"`
+		InstanceURL string `json:"instance_url"`
+	}
+)
+
+const (
+	salesforceAPI = "/services/data/v47.0"
+
+	// Layouts of times in responses and in queries
+	salesforceTime  = "2006-01-02T15:04:05.000-0700"
+	salesforceQuery = "2006-01-02T15:04:05Z"
+)
+
+func (sf *salesforce) changes(ctx context.Context, c *Connection, object string, fields []string, since *time.Time) ([]*remote, error) {
+	q := "SELECT " + strings.Join(append([]string{"Id", "LastModifiedDate"}, fields...), ", ") + " FROM " + object
+	if since != nil {
+		q += " WHERE LastModifiedDate > " + since.UTC().Format(salesforceQuery)
+	}
+
+	q += fmt.Sprintf(" ORDER BY LastModifiedDate ASC LIMIT %d", batchSize)
+
+	var out struct {
+		Records []map[string]interface{} `json:"records"`
+	}
+
+	if err := sf.call(ctx, c, http.MethodGet, "/query?q="+url.QueryEscape(q), nil, &out); err != nil {
+		return nil, err
+	}
+
+	rr := make([]*remote, 0, len(out.Records))
+	for _, o := range out.Records {
+		r, err := sf.remote(o, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		rr = append(rr, r)
+	}
+
+	return rr, nil
+}
+
+func (sf *salesforce) fetch(ctx context.Context, c *Connection, object, ID string, fields []string) (*remote, error) {
+	var (
+		o    = map[string]interface{}{}
+		path = "/sobjects/" + object + "/" + url.PathEscape(ID) + "?fields=" + url.QueryEscape(strings.Join(append([]string{"Id", "LastModifiedDate"}, fields...), ","))
+	)
+
+	if err := sf.call(ctx, c, http.MethodGet, path, nil, &o); err != nil {
+		return nil, err
+	}
+
+	return sf.remote(o, fields)
+}
+
+func (sf *salesforce) create(ctx context.Context, c *Connection, object string, values map[string]string) (string, time.Time, error) {
+	var out struct {
+		ID string `json:"id"`
+	}
+
+	if err := sf.call(ctx, c, http.MethodPost, "/sobjects/"+object, values, &out); err != nil {
+		return "", time.Time{}, err
+	}
+
+	r, err := sf.fetch(ctx, c, object, out.ID, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return out.ID, r.ModifiedAt, nil
+}
+
+func (sf *salesforce) update(ctx context.Context, c *Connection, object, ID string, values map[string]string) (time.Time, error) {
+	if err := sf.call(ctx, c, http.MethodPatch, "/sobjects/"+object+"/"+url.PathEscape(ID), values, nil); err != nil {
+		return time.Time{}, err
+	}
+
+	r, err := sf.fetch(ctx, c, object, ID, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return r.ModifiedAt, nil
+}
+
+// call sends the request to the API; token is requested again once,
+// when the one that was kept is rejected
+func (sf *salesforce) call(ctx context.Context, c *Connection, method, path string, values map[string]string, dst interface{}) error {
+	for retry := true; ; retry = false {
+		t, err := sf.token(ctx, c)
+		if err != nil {
+			return err
+		}
+
+		req, err := request(method, t.InstanceURL+salesforceAPI+path, values)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+t.AccessToken)
+
+		status, err := do(ctx, req, dst)
+		if status == http.StatusUnauthorized && retry {
+			sf.forget(c)
+			continue
+		}
+
+		return err
+	}
+}
+
+// token returns kept access token or signs in with the username-password flow
+func (sf *salesforce) token(ctx context.Context, c *Connection) (*salesforceToken, error) {
+	sf.mux.Lock()
+	defer sf.mux.Unlock()
+
+	if t, ok := sf.tokens[sf.key(c)]; ok {
+		return t, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"password"},
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"username":      {c.Username},
+		"password":      {c.Password},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.LoginURL+"/services/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, ErrRemoteFailed.withStack().WithMessage(err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	t := &salesforceToken{}
+	if _, err = do(ctx, req, t); err != nil {
+		return nil, err
+	}
+
+	if c.InstanceURL != "" {
+		t.InstanceURL = c.InstanceURL
+	}
+
+	sf.tokens[sf.key(c)] = t
+	return t, nil
+}
+
+func (sf *salesforce) forget(c *Connection) {
+	sf.mux.Lock()
+	defer sf.mux.Unlock()
+
+	delete(sf.tokens, sf.key(c))
+}
+
+// key identifies the version of connection's settings
+func (sf *salesforce) key(c *Connection) string {
+	return fmt.Sprintf("%d:%v", c.ID, c.UpdatedAt)
+}
+
+func (sf *salesforce) remote(o map[string]interface{}, fields []string) (*remote, error) {
+	at, err := time.Parse(salesforceTime, text(o["LastModifiedDate"]))
+	if err != nil {
+		return nil, ErrRemoteFailed.withStack().WithMessage("invalid LastModifiedDate")
+	}
+
+	r := &remote{ID: text(o["Id"]), Values: map[string]string{}, ModifiedAt: at.Truncate(time.Second)}
+	for _, f := range fields {
+		r.Values[f] = text(o[f])
+	}
+
+	return r, nil
+}
@@ -0,0 +1,436 @@
+package crmsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	crmsyncService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	CRMSyncService interface {
+		With(ctx context.Context) CRMSyncService
+
+		FindConnections(namespaceID uint64) (ConnectionSet, error)
+		CreateConnection(*Connection) (*Connection, error)
+		UpdateConnection(*Connection) (*Connection, error)
+		DeleteConnection(namespaceID, connectionID uint64) error
+
+		FindSyncs(namespaceID uint64) (SyncSet, error)
+		CreateSync(*Sync) (*Sync, error)
+		UpdateSync(*Sync) (*Sync, error)
+		DeleteSync(namespaceID, syncID uint64) error
+		Sync(namespaceID, syncID uint64) (*SyncResult, error)
+
+		FindQueued(namespaceID, syncID uint64) (QueuedSet, error)
+		Retry(namespaceID, syncID, queuedID uint64) (*SyncResult, error)
+		Discard(namespaceID, syncID, queuedID uint64) error
+	}
+)
+
+var (
+	DefaultCRMSync CRMSyncService
+
+	// now is used for sync cursors and schedules and can be overridden
+	now = time.Now
+)
+
+// Init initializes CRM sync service and starts running due syncs
+// in the background
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &crmsyncService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+	}
+
+	DefaultCRMSync = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc crmsyncService) With(ctx context.Context) CRMSyncService {
+	return svc.with(ctx)
+}
+
+func (svc crmsyncService) with(ctx context.Context) *crmsyncService {
+	return &crmsyncService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc crmsyncService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc crmsyncService) FindConnections(namespaceID uint64) (ConnectionSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.FindConnections(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set {
+		set[i] = set[i].withoutSecrets()
+	}
+
+	return set, nil
+}
+
+func (svc crmsyncService) CreateConnection(in *Connection) (*Connection, error) {
+	if err := svc.canManage(in.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+
+	c, err := svc.repository.CreateConnection(&Connection{
+		NamespaceID:  in.NamespaceID,
+		Kind:         in.Kind,
+		Name:         in.Name,
+		InstanceURL:  in.InstanceURL,
+		LoginURL:     in.LoginURL,
+		ClientID:     in.ClientID,
+		ClientSecret: in.ClientSecret,
+		Username:     in.Username,
+		Password:     in.Password,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return c.withoutSecrets(), nil
+}
+
+// UpdateConnection modifies connection
+//
+// Kind can not be changed; secrets are changed only when new ones are given
+func (svc crmsyncService) UpdateConnection(upd *Connection) (*Connection, error) {
+	if err := svc.canManage(upd.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	c, err := svc.repository.FindConnectionByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	upd.Kind = c.Kind
+	if err = upd.validate(); err != nil {
+		return nil, err
+	}
+
+	c.Name = upd.Name
+	c.InstanceURL = upd.InstanceURL
+	c.LoginURL = upd.LoginURL
+	c.ClientID = upd.ClientID
+	c.Username = upd.Username
+
+	if upd.ClientSecret != "" {
+		c.ClientSecret = upd.ClientSecret
+	}
+
+	if upd.Password != "" {
+		c.Password = upd.Password
+	}
+
+	if c, err = svc.repository.UpdateConnection(c); err != nil {
+		return nil, err
+	}
+
+	return c.withoutSecrets(), nil
+}
+
+// DeleteConnection removes connection that is not used by any sync
+func (svc crmsyncService) DeleteConnection(namespaceID, connectionID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindConnectionByID(namespaceID, connectionID); err != nil {
+		return err
+	}
+
+	if n, err := svc.repository.CountSyncs(connectionID); err != nil {
+		return err
+	} else if n > 0 {
+		return ErrConnectionInUse.withStack()
+	}
+
+	return svc.repository.DeleteConnectionByID(namespaceID, connectionID)
+}
+
+func (svc crmsyncService) FindSyncs(namespaceID uint64) (SyncSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindSyncs(namespaceID)
+}
+
+func (svc crmsyncService) CreateSync(in *Sync) (*Sync, error) {
+	if err := svc.validateSync(in); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.CreateSync(&Sync{
+		NamespaceID:  in.NamespaceID,
+		ConnectionID: in.ConnectionID,
+		ModuleID:     in.ModuleID,
+		Object:       in.Object,
+		Mapping:      in.Mapping,
+		Direction:    in.Direction,
+		Conflict:     in.Conflict,
+		Interval:     in.Interval,
+		Enabled:      in.Enabled,
+		OwnedBy:      in.OwnedBy,
+	})
+}
+
+// UpdateSync modifies sync
+//
+// Connection, module and object can not be changed, links would point
+// to objects of another kind
+func (svc crmsyncService) UpdateSync(upd *Sync) (*Sync, error) {
+	s, err := svc.repository.FindSyncByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	upd.ConnectionID, upd.ModuleID, upd.Object = s.ConnectionID, s.ModuleID, s.Object
+
+	if err = svc.validateSync(upd); err != nil {
+		return nil, err
+	}
+
+	if s.Interval != upd.Interval {
+		// Reschedule with the new interval
+		s.NextSyncAt = nil
+	}
+
+	s.Mapping = upd.Mapping
+	s.Direction = upd.Direction
+	s.Conflict = upd.Conflict
+	s.Interval = upd.Interval
+	s.Enabled = upd.Enabled
+	s.OwnedBy = upd.OwnedBy
+
+	return svc.repository.UpdateSync(s)
+}
+
+func (svc crmsyncService) DeleteSync(namespaceID, syncID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteSyncByID(namespaceID, syncID)
+}
+
+// Sync synchronises the module immediately
+func (svc crmsyncService) Sync(namespaceID, syncID uint64) (*SyncResult, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.FindSyncByID(namespaceID, syncID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.sync(s)
+}
+
+// FindQueued returns items of the sync that could not be synced
+func (svc crmsyncService) FindQueued(namespaceID, syncID uint64) (QueuedSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	if _, err := svc.repository.FindSyncByID(namespaceID, syncID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindQueued(syncID)
+}
+
+// Retry syncs queued item immediately
+func (svc crmsyncService) Retry(namespaceID, syncID, queuedID uint64) (*SyncResult, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.FindSyncByID(namespaceID, syncID)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := svc.repository.FindQueuedByID(syncID, queuedID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.retry(s, q)
+}
+
+// Discard removes item from the queue without syncing it
+func (svc crmsyncService) Discard(namespaceID, syncID, queuedID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindSyncByID(namespaceID, syncID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindQueuedByID(syncID, queuedID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteQueued(queuedID)
+}
+
+func (svc crmsyncService) validateSync(s *Sync) error {
+	if err := svc.canManage(s.NamespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindConnectionByID(s.NamespaceID, s.ConnectionID); err != nil {
+		return err
+	}
+
+	if !validName.MatchString(s.Object) {
+		return ErrInvalidObject.withStack()
+	}
+
+	m, err := svc.module.FindByID(s.NamespaceID, s.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if len(s.Mapping) == 0 {
+		return ErrInvalidMapping.withStack().WithMessage("no fields are mapped")
+	}
+
+	mapped := map[string]bool{}
+	for rf, lf := range s.Mapping {
+		if !validName.MatchString(rf) {
+			return ErrInvalidMapping.withStack().WithMessage("invalid remote field " + rf)
+		}
+
+		if !m.Fields.HasName(lf) {
+			return ErrInvalidMapping.withStack().WithMessage("unknown module field " + lf)
+		}
+
+		if mapped[lf] {
+			return ErrInvalidMapping.withStack().WithMessage("module field " + lf + " is mapped more than once")
+		}
+
+		mapped[lf] = true
+	}
+
+	if s.Direction == "" {
+		s.Direction = DirectionBoth
+	} else if !s.Direction.IsValid() {
+		return ErrInvalidDirection.withStack()
+	}
+
+	if s.Conflict == "" {
+		s.Conflict = ConflictNewestWins
+	} else if !s.Conflict.IsValid() {
+		return ErrInvalidConflict.withStack()
+	}
+
+	if s.Interval == 0 {
+		s.Interval = defaultInterval
+	} else if s.Interval < minInterval {
+		s.Interval = minInterval
+	}
+
+	if s.OwnedBy == 0 {
+		s.OwnedBy = auth.GetIdentityFromContext(svc.ctx).Identity()
+	}
+
+	return nil
+}
+
+func (svc crmsyncService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// watch periodically runs syncs that are due
+func (svc crmsyncService) watch(ctx context.Context) {
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			ctx := auth.SetSuperUserContext(ctx)
+
+			set, err := Repository(ctx, nil).FindDueSyncs(now())
+			if err != nil {
+				svc.logger.Error("could not load due syncs", zap.Error(err))
+				continue
+			}
+
+			for _, s := range set {
+				if _, err = svc.with(ctx).sync(s); err != nil {
+					svc.logger.Error("could not sync module", zap.Uint64("syncID", s.ID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package crmsync
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// servicenow talks to ServiceNow's Table API
+	servicenow struct{}
+)
+
+const (
+	// Layout of times in responses, in UTC
+	servicenowTime = "2006-01-02 15:04:05"
+)
+
+func (sn servicenow) changes(ctx context.Context, c *Connection, object string, fields []string, since *time.Time) ([]*remote, error) {
+	q := "ORDERBYsys_updated_on"
+	if since != nil {
+		at := since.UTC()
+		q = "sys_updated_on>javascript:gs.dateGenerate('" + at.Format("2006-01-02") + "','" + at.Format("15:04:05") + "')^" + q
+	}
+
+	params := sn.params(fields)
+	params.Set("sysparm_query", q)
+	params.Set("sysparm_limit", strconv.Itoa(batchSize))
+
+	var out struct {
+		Result []map[string]interface{} `json:"result"`
+	}
+
+	if err := sn.call(ctx, c, http.MethodGet, "/"+object+"?"+params.Encode(), nil, &out); err != nil {
+		return nil, err
+	}
+
+	rr := make([]*remote, 0, len(out.Result))
+	for _, o := range out.Result {
+		r, err := sn.remote(o, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		rr = append(rr, r)
+	}
+
+	return rr, nil
+}
+
+func (sn servicenow) fetch(ctx context.Context, c *Connection, object, ID string, fields []string) (*remote, error) {
+	var out struct {
+		Result map[string]interface{} `json:"result"`
+	}
+
+	if err := sn.call(ctx, c, http.MethodGet, "/"+object+"/"+url.PathEscape(ID)+"?"+sn.params(fields).Encode(), nil, &out); err != nil {
+		return nil, err
+	}
+
+	return sn.remote(out.Result, fields)
+}
+
+func (sn servicenow) create(ctx context.Context, c *Connection, object string, values map[string]string) (string, time.Time, error) {
+	var out struct {
+		Result map[string]interface{} `json:"result"`
+	}
+
+	if err := sn.call(ctx, c, http.MethodPost, "/"+object+"?"+sn.params(nil).Encode(), values, &out); err != nil {
+		return "", time.Time{}, err
+	}
+
+	r, err := sn.remote(out.Result, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return r.ID, r.ModifiedAt, nil
+}
+
+func (sn servicenow) update(ctx context.Context, c *Connection, object, ID string, values map[string]string) (time.Time, error) {
+	var out struct {
+		Result map[string]interface{} `json:"result"`
+	}
+
+	if err := sn.call(ctx, c, http.MethodPatch, "/"+object+"/"+url.PathEscape(ID)+"?"+sn.params(nil).Encode(), values, &out); err != nil {
+		return time.Time{}, err
+	}
+
+	r, err := sn.remote(out.Result, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return r.ModifiedAt, nil
+}
+
+func (sn servicenow) call(ctx context.Context, c *Connection, method, path string, values map[string]string, dst interface{}) error {
+	req, err := request(method, c.InstanceURL+"/api/now/table"+path, values)
+	if err != nil {
+		return err
+	}
+
+	req.SetBasicAuth(c.Username, c.Password)
+
+	_, err = do(ctx, req, dst)
+	return err
+}
+
+// params returns parameters that select the fields with raw values
+func (sn servicenow) params(fields []string) url.Values {
+	return url.Values{
+		"sysparm_fields":                 {strings.Join(append([]string{"sys_id", "sys_updated_on"}, fields...), ",")},
+		"sysparm_display_value":          {"false"},
+		"sysparm_exclude_reference_link": {"true"},
+	}
+}
+
+func (sn servicenow) remote(o map[string]interface{}, fields []string) (*remote, error) {
+	at, err := time.ParseInLocation(servicenowTime, text(o["sys_updated_on"]), time.UTC)
+	if err != nil {
+		return nil, ErrRemoteFailed.withStack().WithMessage("invalid sys_updated_on")
+	}
+
+	r := &remote{ID: text(o["sys_id"]), Values: map[string]string{}, ModifiedAt: at}
+	for _, f := range fields {
+		r.Values[f] = text(o[f])
+	}
+
+	return r, nil
+}
@@ -0,0 +1,404 @@
+package crmsync
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	composeRepository "github.com/cortezaproject/corteza-server/compose/repository"
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	// run holds everything one synchronisation of the module needs
+	run struct {
+		ctx        context.Context
+		log        *zap.Logger
+		repository *repository
+
+		sync    *Sync
+		conn    *Connection
+		adapter adapter
+		module  *types.Module
+		records service.RecordService
+
+		res *SyncResult
+	}
+)
+
+// sync retries queued items, pulls remote changes and pushes local
+// changes, as the direction allows
+//
+// Pulling first resolves conflicts before anything is pushed; records
+// written by the pull are not pushed back.
+func (svc crmsyncService) sync(s *Sync) (res *SyncResult, err error) {
+	var (
+		log     = svc.log(zap.Uint64("syncID", s.ID))
+		started = now().Truncate(time.Second)
+	)
+
+	res = &SyncResult{}
+
+	defer func() {
+		next := s.next(started)
+		s.NextSyncAt = &next
+
+		s.LastError = ""
+		if err != nil {
+			s.LastError = err.Error()
+		} else {
+			s.SyncedAt = &started
+		}
+
+		if serr := svc.repository.UpdateSyncState(s); serr != nil {
+			log.Error("could not store sync state", zap.Error(serr))
+		}
+	}()
+
+	r, err := svc.start(s, res)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.retryQueued(); err != nil {
+		return nil, err
+	}
+
+	if s.Direction.pulls() {
+		if err = r.pull(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.Direction.pushes() {
+		if err = r.push(); err != nil {
+			return nil, err
+		}
+
+		s.PushedAt = &started
+	}
+
+	log.Info("module synced",
+		zap.Uint("created", res.Created),
+		zap.Uint("updated", res.Updated),
+		zap.Uint("pushed", res.Pushed),
+		zap.Uint("conflicts", res.Conflicts),
+		zap.Uint("failed", res.Failed),
+	)
+
+	return res, nil
+}
+
+// retry syncs one queued item
+func (svc crmsyncService) retry(s *Sync, q *Queued) (*SyncResult, error) {
+	res := &SyncResult{}
+
+	r, err := svc.start(s, res)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.item(q.Direction, q.Item, r.retryItem(q)); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// start prepares the run; records are read and written in the name of the owner
+func (svc crmsyncService) start(s *Sync, res *SyncResult) (*run, error) {
+	c, err := svc.repository.FindConnectionByID(s.NamespaceID, s.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	a, ok := adapters[c.Kind]
+	if !ok {
+		return nil, ErrInvalidKind.withStack()
+	}
+
+	ctx, err := runas.Compose(svc.ctx, s.OwnedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := service.DefaultModule.With(ctx).FindByID(s.NamespaceID, s.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &run{
+		ctx:        ctx,
+		log:        svc.log(zap.Uint64("syncID", s.ID)),
+		repository: svc.repository,
+
+		sync:    s,
+		conn:    c,
+		adapter: a,
+		module:  m,
+		records: service.DefaultRecord.With(ctx),
+
+		res: res,
+	}, nil
+}
+
+// retryQueued retries items that failed before and were not retried too many times
+func (r *run) retryQueued() error {
+	qq, err := r.repository.FindQueued(r.sync.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, q := range qq {
+		if q.Attempts >= maxAttempts || !r.allows(q.Direction) {
+			continue
+		}
+
+		if err = r.item(q.Direction, q.Item, r.retryItem(q)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *run) retryItem(q *Queued) error {
+	switch q.Direction {
+	case DirectionPull:
+		o, err := r.adapter.fetch(r.ctx, r.conn, r.sync.Object, q.Item, r.sync.Mapping.fields())
+		if fault.Is(err, ErrRemoteNotFound) {
+			// Deleted since, nothing to sync
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		return r.pullRemote(o)
+
+	case DirectionPush:
+		ID, _ := strconv.ParseUint(q.Item, 10, 64)
+
+		rec, err := r.records.FindByID(r.sync.NamespaceID, ID)
+		if fault.Is(err, composeRepository.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		return r.pushRecord(rec)
+	}
+
+	return ErrInvalidDirection.withStack()
+}
+
+// pull applies objects changed since the cursor
+func (r *run) pull() error {
+	fields := r.sync.Mapping.fields()
+
+	for {
+		oo, err := r.adapter.changes(r.ctx, r.conn, r.sync.Object, fields, r.sync.Cursor)
+		if err != nil {
+			return err
+		}
+
+		advanced := false
+		for _, o := range oo {
+			if err = r.item(DirectionPull, o.ID, r.pullRemote(o)); err != nil {
+				return err
+			}
+
+			if r.sync.Cursor == nil || o.ModifiedAt.After(*r.sync.Cursor) {
+				at := o.ModifiedAt
+				r.sync.Cursor, advanced = &at, true
+			}
+		}
+
+		// Full page of objects changed at the same time would be fetched forever
+		if len(oo) < batchSize || !advanced {
+			return nil
+		}
+	}
+}
+
+// pullRemote creates or updates the record linked with the remote object
+func (r *run) pullRemote(o *remote) error {
+	l, err := r.repository.FindLinkByRemote(r.sync.ID, o.ID)
+	if err != nil {
+		return err
+	} else if l != nil && l.RemoteVersion.Equal(o.ModifiedAt) {
+		// Already have this version (we pushed it or pulled it before)
+		return nil
+	}
+
+	var rec *types.Record
+
+	if l != nil {
+		if rec, err = r.records.FindByID(r.sync.NamespaceID, l.RecordID); err != nil && !fault.Is(err, composeRepository.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	if rec == nil {
+		// New object or local record was deleted
+		rec, err = r.records.Create(&types.Record{
+			NamespaceID: r.sync.NamespaceID,
+			ModuleID:    r.module.ID,
+			Values:      r.values(nil, o),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		r.res.Created++
+	} else {
+		if local := version(rec.CreatedAt, rec.UpdatedAt); local.After(l.LocalVersion) {
+			r.res.Conflicts++
+
+			if !r.remoteWins(local, o.ModifiedAt) {
+				// Local change is kept and pushed, when pushing
+				return nil
+			}
+		}
+
+		rec.Values = r.values(rec.Values, o)
+		if rec, err = r.records.Update(rec); err != nil {
+			return err
+		}
+
+		r.res.Updated++
+	}
+
+	_, err = r.repository.SaveLink(&Link{
+		SyncID:        r.sync.ID,
+		RecordID:      rec.ID,
+		RemoteID:      o.ID,
+		RemoteVersion: o.ModifiedAt,
+		LocalVersion:  version(rec.CreatedAt, rec.UpdatedAt),
+	})
+
+	return err
+}
+
+// push sends records that changed locally since the last push
+func (r *run) push() error {
+	f := types.RecordFilter{NamespaceID: r.sync.NamespaceID, ModuleID: r.module.ID}
+	if r.sync.PushedAt != nil {
+		p := r.sync.PushedAt.Local().Format("2006-01-02 15:04:05")
+		f.Filter = "createdAt >= '" + p + "' OR updatedAt >= '" + p + "'"
+	}
+
+	rr, _, err := r.records.Find(f)
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range rr {
+		if err = r.item(DirectionPush, strconv.FormatUint(rec.ID, 10), r.pushRecord(rec)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pushRecord creates or updates the remote object linked with the record
+func (r *run) pushRecord(rec *types.Record) error {
+	l, err := r.repository.FindLinkByRecord(r.sync.ID, rec.ID)
+	if err != nil {
+		return err
+	}
+
+	local := version(rec.CreatedAt, rec.UpdatedAt)
+	if l != nil && !local.After(l.LocalVersion) {
+		// Not changed since it was synced (or written by the pull)
+		return nil
+	}
+
+	values := map[string]string{}
+	for rf, lf := range r.sync.Mapping {
+		values[rf] = ""
+		if vv := rec.Values.FilterByName(lf); len(vv) > 0 {
+			values[rf] = vv[0].Value
+		}
+	}
+
+	if l == nil {
+		l = &Link{SyncID: r.sync.ID, RecordID: rec.ID}
+		l.RemoteID, l.RemoteVersion, err = r.adapter.create(r.ctx, r.conn, r.sync.Object, values)
+	} else {
+		l.RemoteVersion, err = r.adapter.update(r.ctx, r.conn, r.sync.Object, l.RemoteID, values)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	l.LocalVersion = local
+	if _, err = r.repository.SaveLink(l); err != nil {
+		return err
+	}
+
+	r.res.Pushed++
+	return nil
+}
+
+// item resolves the queued item when it was synced and queues it when it failed
+//
+// Only errors of the queue itself are returned; the run continues with
+// the next item.
+func (r *run) item(d Direction, item string, err error) error {
+	if err == nil {
+		return r.repository.Resolve(r.sync.ID, d, item)
+	}
+
+	r.res.Failed++
+	r.log.Warn("could not sync item", zap.String("direction", string(d)), zap.String("item", item), zap.Error(err))
+
+	return r.repository.Enqueue(r.sync.ID, d, item, err.Error())
+}
+
+func (r *run) allows(d Direction) bool {
+	return (d == DirectionPull && r.sync.Direction.pulls()) || (d == DirectionPush && r.sync.Direction.pushes())
+}
+
+// remoteWins decides conflict of the local change with the remote one
+func (r *run) remoteWins(local, remote time.Time) bool {
+	switch r.sync.Conflict {
+	case ConflictRemoteWins:
+		return true
+	case ConflictLocalWins:
+		return false
+	}
+
+	return !local.After(remote)
+}
+
+// values returns record values with mapped fields set to values of the
+// remote object; fields that are not mapped are kept
+func (r *run) values(vv types.RecordValueSet, o *remote) types.RecordValueSet {
+	var (
+		out    = types.RecordValueSet{}
+		mapped = r.sync.Mapping.local(o)
+	)
+
+	for _, v := range vv {
+		if _, ok := mapped[v.Name]; !ok {
+			out = append(out, v)
+		}
+	}
+
+	for _, rf := range r.sync.Mapping.fields() {
+		lf := r.sync.Mapping[rf]
+		if v := mapped[lf]; v != "" && r.module.Fields.HasName(lf) {
+			out = append(out, &types.RecordValue{Name: lf, Value: v})
+		}
+	}
+
+	return out
+}
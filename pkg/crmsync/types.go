@@ -0,0 +1,285 @@
+package crmsync
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Connection holds credentials of a Salesforce org or ServiceNow instance
+	//
+	// Salesforce is authenticated with OAuth's username-password flow,
+	// ServiceNow with basic authentication. Secrets are never sent back and
+	// are left as they are when not given.
+	Connection struct {
+		ID          uint64 `json:"connectionID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Kind        string `json:"kind" db:"kind"`
+		Name        string `json:"name" db:"name"`
+
+		// Required for ServiceNow; Salesforce's is known after signing in
+		InstanceURL string `json:"instanceURL" db:"instance_url"`
+
+		// Salesforce only, login.salesforce.com by default
+		LoginURL     string `json:"loginURL,omitempty" db:"login_url"`
+		ClientID     string `json:"clientID,omitempty" db:"client_id"`
+		ClientSecret string `json:"clientSecret,omitempty" db:"client_secret"`
+
+		Username string `json:"username" db:"username"`
+		Password string `json:"password,omitempty" db:"password"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	ConnectionSet []*Connection
+
+	// Sync keeps records of the module in sync with objects of the connection
+	//
+	// Remote changes are pulled first, conflicts with local changes are
+	// resolved with the policy; local changes that are left are pushed.
+	// Records are written in the name of the owner.
+	Sync struct {
+		ID           uint64    `json:"syncID,string" db:"id"`
+		NamespaceID  uint64    `json:"namespaceID,string" db:"rel_namespace"`
+		ConnectionID uint64    `json:"connectionID,string" db:"rel_connection"`
+		ModuleID     uint64    `json:"moduleID,string" db:"rel_module"`
+		Object       string    `json:"object" db:"object"`
+		Mapping      Mapping   `json:"mapping" db:"mapping"`
+		Direction    Direction `json:"direction" db:"direction"`
+		Conflict     Conflict  `json:"conflict" db:"conflict"`
+		Interval     uint      `json:"interval" db:"interval_minutes"`
+		Enabled      bool      `json:"enabled" db:"enabled"`
+
+		// Remote time of the last pulled change, changes after it are pulled next time
+		Cursor *time.Time `json:"cursor,omitempty" db:"pull_cursor"`
+
+		// Our time of the last successful push
+		PushedAt *time.Time `json:"pushedAt,omitempty" db:"pushed_at"`
+
+		SyncedAt   *time.Time `json:"syncedAt,omitempty" db:"synced_at"`
+		NextSyncAt *time.Time `json:"nextSyncAt,omitempty" db:"next_sync_at"`
+		LastError  string     `json:"lastError,omitempty" db:"last_error"`
+
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	SyncSet []*Sync
+
+	// Link connects local record with the remote object it is synced with
+	Link struct {
+		SyncID   uint64 `json:"syncID,string" db:"rel_sync"`
+		RecordID uint64 `json:"recordID,string" db:"rel_record"`
+		RemoteID string `json:"remoteID" db:"remote_id"`
+
+		// Versions (time of the last change) of both after the last sync
+		RemoteVersion time.Time `json:"remoteVersion" db:"remote_version"`
+		LocalVersion  time.Time `json:"localVersion" db:"local_version"`
+
+		SyncedAt time.Time `json:"syncedAt" db:"synced_at"`
+	}
+
+	// Queued is a record or remote object that could not be synced
+	//
+	// Items stay in the queue until they are retried successfully or
+	// discarded; failing again only counts the attempt.
+	Queued struct {
+		ID        uint64    `json:"queuedID,string" db:"id"`
+		SyncID    uint64    `json:"syncID,string" db:"rel_sync"`
+		Direction Direction `json:"direction" db:"direction"`
+
+		// Remote ID when pulling, record ID when pushing
+		Item     string `json:"item" db:"item"`
+		Error    string `json:"error" db:"error"`
+		Attempts uint   `json:"attempts" db:"attempts"`
+
+		CreatedAt  time.Time  `json:"createdAt" db:"created_at"`
+		UpdatedAt  *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		ResolvedAt *time.Time `json:"resolvedAt,omitempty" db:"resolved_at"`
+	}
+
+	QueuedSet []*Queued
+
+	// SyncResult sums up one synchronisation
+	SyncResult struct {
+		Created   uint `json:"created"`
+		Updated   uint `json:"updated"`
+		Pushed    uint `json:"pushed"`
+		Conflicts uint `json:"conflicts"`
+		Failed    uint `json:"failed"`
+	}
+
+	// remote is an object of the connection with mapped fields
+	remote struct {
+		ID         string
+		Values     map[string]string
+		ModifiedAt time.Time
+	}
+
+	// Mapping maps fields of remote object to module fields
+	Mapping map[string]string
+
+	Direction string
+	Conflict  string
+)
+
+const (
+	KindSalesforce = "salesforce"
+	KindServiceNow = "servicenow"
+
+	DirectionPull Direction = "pull"
+	DirectionPush Direction = "push"
+	DirectionBoth Direction = "both"
+
+	// Remote changes win over local ones
+	ConflictRemoteWins Conflict = "remote-wins"
+
+	// Local changes win over remote ones
+	ConflictLocalWins Conflict = "local-wins"
+
+	// The later change wins
+	ConflictNewestWins Conflict = "newest-wins"
+
+	defaultInterval = 15
+	minInterval     = 5
+
+	// Sync is due when checked every minute
+	watchInterval = time.Minute
+
+	// Objects per page when pulling
+	batchSize = 200
+
+	// Queued items are retried with every sync up to the number of
+	// attempts, after that only when retried manually
+	maxAttempts = 5
+
+	requestTimeout = 30 * time.Second
+
+	// Response body is read up to the size, for the error
+	maxResponseError = 512
+)
+
+var (
+	// Names of objects and fields end up in queries
+	validName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+)
+
+func (d Direction) IsValid() bool {
+	return d == DirectionPull || d == DirectionPush || d == DirectionBoth
+}
+
+func (d Direction) pulls() bool {
+	return d == DirectionPull || d == DirectionBoth
+}
+
+func (d Direction) pushes() bool {
+	return d == DirectionPush || d == DirectionBoth
+}
+
+func (c Conflict) IsValid() bool {
+	return c == ConflictRemoteWins || c == ConflictLocalWins || c == ConflictNewestWins
+}
+
+// validate checks connection's settings and fills in the defaults
+func (c *Connection) validate() error {
+	if _, ok := adapters[c.Kind]; !ok {
+		return ErrInvalidKind.withStack().WithMessage("unknown connection kind " + c.Kind)
+	}
+
+	var urls = []string{c.InstanceURL}
+	if c.Kind == KindSalesforce {
+		if c.LoginURL == "" {
+			c.LoginURL = "https://login.salesforce.com"
+		}
+
+		// Instance is known after signing in
+		urls = []string{c.LoginURL}
+		if c.InstanceURL != "" {
+			urls = append(urls, c.InstanceURL)
+		}
+	}
+
+	for _, s := range urls {
+		if u, err := url.Parse(s); err != nil || u.Scheme != "https" || u.Host == "" {
+			return ErrInvalidURL.withStack()
+		}
+	}
+
+	c.InstanceURL = strings.TrimSuffix(c.InstanceURL, "/")
+	c.LoginURL = strings.TrimSuffix(c.LoginURL, "/")
+	c.Name = strings.TrimSpace(c.Name)
+	return nil
+}
+
+func (c Connection) withoutSecrets() *Connection {
+	c.ClientSecret = ""
+	c.Password = ""
+	return &c
+}
+
+// next returns time of the next sync
+func (s Sync) next(now time.Time) time.Time {
+	return now.Add(time.Duration(s.Interval) * time.Minute).Truncate(time.Second)
+}
+
+// local returns values of the module fields, mapped from remote object
+func (m Mapping) local(r *remote) map[string]string {
+	out := map[string]string{}
+	for rf, lf := range m {
+		out[lf] = r.Values[rf]
+	}
+
+	return out
+}
+
+// fields returns names of remote fields, sorted
+func (m Mapping) fields() []string {
+	ff := make([]string, 0, len(m))
+	for rf := range m {
+		ff = append(ff, rf)
+	}
+
+	sort.Strings(ff)
+	return ff
+}
+
+func (m Mapping) Value() (driver.Value, error) {
+	if m == nil {
+		m = Mapping{}
+	}
+
+	return json.Marshal(m)
+}
+
+func (m *Mapping) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*m = Mapping{}
+	case []byte:
+		if err := json.Unmarshal(b, m); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Mapping", string(b))
+		}
+	}
+
+	return nil
+}
+
+// version returns time of the last change of the record
+func version(createdAt time.Time, updatedAt *time.Time) time.Time {
+	if updatedAt != nil {
+		return updatedAt.Truncate(time.Second)
+	}
+
+	return createdAt.Truncate(time.Second)
+}
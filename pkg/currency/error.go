@@ -0,0 +1,35 @@
+package currency
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	currencyError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidAmount   = currencyError{"InvalidAmount", fault.Invalid}
+	ErrInvalidCurrency = currencyError{"InvalidCurrency", fault.Invalid}
+	ErrUnknownRate     = currencyError{"UnknownRate", fault.Invalid}
+	ErrNoRates         = currencyError{"NoRates", fault.Unavailable}
+)
+
+func (e currencyError) Error() string {
+	return e.String()
+}
+
+func (e currencyError) String() string {
+	return "crust.currency." + e.name
+}
+
+func (e currencyError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e currencyError) withStack() *fault.Error {
+	return fault.New(e)
+}
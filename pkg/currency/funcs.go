@@ -0,0 +1,86 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+func init() {
+	// convert("12.50 EUR", "USD") or convert(12.5, "EUR", "USD")
+	expr.RegisterFunc("convert", func(args ...interface{}) (interface{}, error) {
+		var (
+			a   Amount
+			to  string
+			err error
+		)
+
+		switch len(args) {
+		case 2:
+			if a, err = ParseAmount(fmt.Sprint(args[0]), DefaultCurrency); err != nil {
+				return nil, err
+			}
+
+			to = fmt.Sprint(args[1])
+		case 3:
+			if a, err = ParseAmount(fmt.Sprint(args[0]), fmt.Sprint(args[1])); err != nil {
+				return nil, err
+			}
+
+			to = fmt.Sprint(args[2])
+		default:
+			return nil, fmt.Errorf("expecting 2 or 3 arguments, got %d", len(args))
+		}
+
+		if DefaultRate == nil {
+			return nil, ErrNoRates.withStack()
+		}
+
+		return DefaultRate.With(context.Background()).Convert(a.Value, a.Currency, to, time.Now())
+	})
+
+	// amount("12.50 EUR") returns 12.5
+	expr.RegisterFunc("amount", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 || args[0] == nil {
+			return nil, nil
+		}
+
+		a, err := ParseAmount(fmt.Sprint(args[0]), DefaultCurrency)
+		if err != nil {
+			return nil, nil
+		}
+
+		return a.Value, nil
+	})
+
+	// currency("12.50 EUR") returns "EUR"
+	expr.RegisterFunc("currency", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 || args[0] == nil {
+			return nil, nil
+		}
+
+		a, err := ParseAmount(fmt.Sprint(args[0]), DefaultCurrency)
+		if err != nil {
+			return nil, nil
+		}
+
+		return a.Currency, nil
+	})
+
+	// roundCurrency(1.005, "EUR") returns 1.01
+	expr.RegisterFunc("roundCurrency", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("expecting 2 arguments, got %d", len(args))
+		}
+
+		v, err := strconv.ParseFloat(fmt.Sprint(args[0]), 64)
+		if err != nil {
+			return nil, nil
+		}
+
+		return Round(v, fmt.Sprint(args[1])), nil
+	})
+}
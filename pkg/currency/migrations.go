@@ -0,0 +1,23 @@
+package currency
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200118000000.rates",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_currency_rate (
+  day              DATE            NOT NULL,
+  base             CHAR(3)         NOT NULL,
+  currency         CHAR(3)         NOT NULL,
+  rate             DECIMAL(20,10)  NOT NULL,
+
+  PRIMARY KEY (day, currency)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
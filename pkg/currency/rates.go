@@ -0,0 +1,143 @@
+package currency
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Rates are exchange rates of all currencies relative to the base currency
+	Rates struct {
+		Base  string             `json:"base"`
+		Day   time.Time          `json:"day"`
+		Rates map[string]float64 `json:"rates"`
+	}
+
+	// Provider fetches the latest exchange rates
+	Provider interface {
+		Latest(ctx context.Context) (*Rates, error)
+	}
+
+	ecb struct {
+		url    string
+		client *http.Client
+	}
+
+	static Rates
+)
+
+const (
+	ecbDailyURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+)
+
+var (
+	// DefaultProvider is used by the rate service; can be replaced
+	// before extensions are initialized
+	DefaultProvider Provider = ECB("")
+)
+
+// ECB provides daily reference rates published by the European Central Bank
+func ECB(url string) Provider {
+	if url == "" {
+		url = ecbDailyURL
+	}
+
+	return &ecb{url: url, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (p ecb) Latest(ctx context.Context) (*Rates, error) {
+	req, err := http.NewRequest(http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch exchange rates")
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("could not fetch exchange rates, unexpected status %d", rsp.StatusCode)
+	}
+
+	var doc struct {
+		Cube struct {
+			Cube []struct {
+				Time string `xml:"time,attr"`
+				Cube []struct {
+					Currency string  `xml:"currency,attr"`
+					Rate     float64 `xml:"rate,attr"`
+				} `xml:"Cube"`
+			} `xml:"Cube"`
+		} `xml:"Cube"`
+	}
+
+	if err = xml.NewDecoder(rsp.Body).Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "could not decode exchange rates")
+	}
+
+	if len(doc.Cube.Cube) == 0 {
+		return nil, ErrNoRates.withStack()
+	}
+
+	day, err := time.Parse("2006-01-02", doc.Cube.Cube[0].Time)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode exchange rates")
+	}
+
+	r := &Rates{Base: "EUR", Day: day, Rates: map[string]float64{"EUR": 1}}
+	for _, c := range doc.Cube.Cube[0].Cube {
+		r.Rates[c.Currency] = c.Rate
+	}
+
+	return r, nil
+}
+
+// Static provides fixed exchange rates (useful for offline setups)
+func Static(base string, rates map[string]float64) Provider {
+	rr := map[string]float64{base: 1}
+	for c, r := range rates {
+		rr[c] = r
+	}
+
+	return &static{Base: base, Rates: rr}
+}
+
+func (p static) Latest(context.Context) (*Rates, error) {
+	return &Rates{Base: p.Base, Day: today(), Rates: p.Rates}, nil
+}
+
+// Convert converts value from one currency to another
+//
+// Result is not rounded
+func (r Rates) Convert(v float64, from, to string) (float64, error) {
+	if from == to {
+		return v, nil
+	}
+
+	fr, ok := r.Rates[from]
+	if !ok || fr == 0 {
+		return 0, ErrUnknownRate.withStack()
+	}
+
+	tr, ok := r.Rates[to]
+	if !ok {
+		return 0, ErrUnknownRate.withStack()
+	}
+
+	return v / fr * tr, nil
+}
+
+func today() time.Time {
+	return day(time.Now())
+}
+
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
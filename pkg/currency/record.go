@@ -0,0 +1,70 @@
+package currency
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// record wraps record service and normalizes currency field values
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with currency field handling
+//
+// Submitted currency values are validated, rounded to currency's minor
+// units and stored in canonical form ("12.50 EUR").
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	if err := svc.normalize(r); err != nil {
+		return nil, err
+	}
+
+	return svc.RecordService.Create(r)
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	if err := svc.normalize(r); err != nil {
+		return nil, err
+	}
+
+	return svc.RecordService.Update(r)
+}
+
+func (svc record) normalize(r *types.Record) error {
+	m, err := service.DefaultModule.With(svc.ctx).FindByID(r.NamespaceID, r.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range r.Values {
+		f := m.Fields.FindByName(v.Name)
+		if !IsCurrency(f) || v.Value == "" {
+			continue
+		}
+
+		a, err := ParseAmount(v.Value, FieldCurrency(f))
+		if err != nil {
+			return err
+		}
+
+		v.Value = a.String()
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package currency
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+
+	rate struct {
+		Day      time.Time `db:"day"`
+		Base     string    `db:"base"`
+		Currency string    `db:"currency"`
+		Rate     float64   `db:"rate"`
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_currency_rate"
+}
+
+// FindByDay returns the most recent rates, stored on or before the given day
+func (r repository) FindByDay(day time.Time) (*Rates, error) {
+	last, args, err := squirrel.
+		Select("MAX(day)").
+		From(r.table()).
+		Where(squirrel.LtOrEq{"day": day}).
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		q = squirrel.
+			Select("day", "base", "currency", "rate").
+			From(r.table()).
+			Where("day = ("+last+")", args...)
+
+		rr = []*rate{}
+	)
+
+	if err := rh.FetchAll(r.db(), q, &rr); err != nil {
+		return nil, err
+	}
+
+	if len(rr) == 0 {
+		return nil, nil
+	}
+
+	out := &Rates{Base: rr[0].Base, Day: rr[0].Day, Rates: map[string]float64{}}
+	for _, rt := range rr {
+		out.Rates[rt.Currency] = rt.Rate
+	}
+
+	return out, nil
+}
+
+func (r repository) Store(rr *Rates) error {
+	return r.db().Transaction(func() error {
+		for c, v := range rr.Rates {
+			err := r.db().Replace(r.table(), rate{
+				Day:      rr.Day,
+				Base:     rr.Base,
+				Currency: c,
+				Rate:     v,
+			})
+
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		return nil
+	})
+}
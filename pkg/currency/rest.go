@@ -0,0 +1,64 @@
+package currency
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts exchange rate endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?date=2020-01-15 (defaults to today)
+	r.Get("/rates", rest.Handler("Currency.Rates", func(r *http.Request) (interface{}, error) {
+		d, err := queryDay(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return DefaultRate.With(r.Context()).Rates(d)
+	}))
+
+	// ?amount=12.5&from=EUR&to=USD&date=2020-01-15
+	r.Get("/convert", rest.Handler("Currency.Convert", func(r *http.Request) (interface{}, error) {
+		var (
+			q  = r.URL.Query()
+			to = strings.ToUpper(q.Get("to"))
+		)
+
+		d, err := queryDay(r)
+		if err != nil {
+			return nil, err
+		}
+
+		a, err := ParseAmount(q.Get("amount"), strings.ToUpper(q.Get("from")))
+		if err != nil {
+			return nil, err
+		}
+
+		if !IsValidCode(to) {
+			return nil, ErrInvalidCurrency.withStack()
+		}
+
+		v, err := DefaultRate.With(r.Context()).Convert(a.Value, a.Currency, to, d)
+		if err != nil {
+			return nil, err
+		}
+
+		return Amount{Value: v, Currency: to}, nil
+	}))
+}
+
+func queryDay(r *http.Request) (time.Time, error) {
+	if d := r.URL.Query().Get("date"); d != "" {
+		return time.Parse("2006-01-02", d)
+	}
+
+	return time.Now(), nil
+}
@@ -0,0 +1,149 @@
+package currency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	rateService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		provider Provider
+		cache    *cache
+
+		repository *repository
+	}
+
+	// cache holds rates by day
+	cache struct {
+		sync.RWMutex
+		rates map[time.Time]*Rates
+	}
+
+	RateService interface {
+		With(ctx context.Context) RateService
+
+		Rates(day time.Time) (*Rates, error)
+		Convert(v float64, from, to string, day time.Time) (float64, error)
+	}
+)
+
+var (
+	DefaultRate RateService
+)
+
+// Init initializes exchange rate service and hooks
+// currency field handling into record service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultRate = (&rateService{
+		logger:   log,
+		provider: DefaultProvider,
+		cache:    &cache{rates: map[time.Time]*Rates{}},
+	}).With(ctx)
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	return nil
+}
+
+func (svc rateService) With(ctx context.Context) RateService {
+	return &rateService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		provider: svc.provider,
+		cache:    svc.cache,
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc rateService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Rates returns exchange rates for the given day
+//
+// Rates are fetched from the provider once per day and stored. For past
+// days without stored rates, the most recent rates before that day are used.
+func (svc rateService) Rates(d time.Time) (rr *Rates, err error) {
+	d = day(d)
+
+	if rr = svc.cache.get(d); rr != nil {
+		return
+	}
+
+	if rr, err = svc.repository.FindByDay(d); err != nil {
+		return
+	}
+
+	if rr == nil || (rr.Day.Before(d) && !d.Before(today())) {
+		// Nothing stored or rates for today are not yet fetched
+		var latest *Rates
+		if latest, err = svc.provider.Latest(svc.ctx); err != nil {
+			if rr != nil {
+				// Provider is not available; use what we have
+				svc.log(zap.Error(err)).Warn("could not fetch exchange rates, using stored ones")
+				return rr, nil
+			}
+
+			return nil, err
+		}
+
+		if err = svc.repository.Store(latest); err != nil {
+			return nil, err
+		}
+
+		svc.log(zap.Time("day", latest.Day), zap.Int("count", len(latest.Rates))).
+			Info("exchange rates fetched")
+
+		rr = latest
+	}
+
+	svc.cache.set(d, rr)
+	return rr, nil
+}
+
+// Convert converts value between currencies, using rates for the given day
+//
+// Result is rounded to target currency's minor units
+func (svc rateService) Convert(v float64, from, to string, d time.Time) (float64, error) {
+	if from == to {
+		return Round(v, to), nil
+	}
+
+	rr, err := svc.Rates(d)
+	if err != nil {
+		return 0, err
+	}
+
+	if v, err = rr.Convert(v, from, to); err != nil {
+		return 0, err
+	}
+
+	return Round(v, to), nil
+}
+
+func (c *cache) get(d time.Time) *Rates {
+	c.RLock()
+	defer c.RUnlock()
+	return c.rates[d]
+}
+
+func (c *cache) set(d time.Time, rr *Rates) {
+	c.Lock()
+	defer c.Unlock()
+	c.rates[d] = rr
+}
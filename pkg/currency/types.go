@@ -0,0 +1,115 @@
+package currency
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Amount is a monetary value in a specific currency
+	//
+	// Stored in record value as "<amount> <currency>", for example "12.50 EUR".
+	// Amount comes first so that values can still be sorted and cast to numbers.
+	Amount struct {
+		Value    float64 `json:"value"`
+		Currency string  `json:"currency"`
+	}
+)
+
+const (
+	// FieldKind is kind of the module field that holds amount with currency
+	//
+	// Default currency can be set with "currency" field option
+	FieldKind = "Currency"
+)
+
+var (
+	// DefaultCurrency is used when neither value nor field specify a currency
+	DefaultCurrency = "EUR"
+
+	codeMatcher = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	// Currencies with number of minor units other than 2 (ISO 4217)
+	minorUnits = map[string]int{
+		"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+		"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+		"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	}
+)
+
+// IsCurrency returns true if field holds amount with currency
+func IsCurrency(f *types.ModuleField) bool {
+	return f != nil && f.Kind == FieldKind
+}
+
+// FieldCurrency returns default currency of the field
+func FieldCurrency(f *types.ModuleField) string {
+	if f != nil && f.Options != nil {
+		if c, ok := f.Options["currency"].(string); ok && IsValidCode(strings.ToUpper(c)) {
+			return strings.ToUpper(c)
+		}
+	}
+
+	return DefaultCurrency
+}
+
+// IsValidCode checks if currency code is in ISO 4217 format (3 uppercase letters)
+func IsValidCode(code string) bool {
+	return codeMatcher.MatchString(code)
+}
+
+// MinorUnits returns number of decimal places used by the currency
+func MinorUnits(code string) int {
+	if u, ok := minorUnits[code]; ok {
+		return u
+	}
+
+	return 2
+}
+
+// Round rounds value to currency's minor units
+//
+// Halves are rounded away from zero (1.005 EUR => 1.01 EUR); all
+// conversions and stored values use the same rule
+func Round(v float64, code string) float64 {
+	p := math.Pow(10, float64(MinorUnits(code)))
+
+	// Small correction compensates for binary representation
+	// of decimal values (1.005 is stored as 1.00499999...)
+	return math.Round(v*p+math.Copysign(1e-9, v)) / p
+}
+
+// ParseAmount parses amount with optional currency
+//
+// Accepted formats: "12.5", "12.5 EUR", "EUR 12.5" and "12.5EUR"
+func ParseAmount(s, defaultCurrency string) (a Amount, err error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	a.Currency = defaultCurrency
+
+	switch {
+	case len(s) > 3 && IsValidCode(s[:3]):
+		a.Currency, s = s[:3], s[3:]
+	case len(s) > 3 && IsValidCode(s[len(s)-3:]):
+		a.Currency, s = s[len(s)-3:], s[:len(s)-3]
+	}
+
+	if a.Value, err = strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil {
+		return a, ErrInvalidAmount.withStack()
+	}
+
+	if !IsValidCode(a.Currency) {
+		return a, ErrInvalidCurrency.withStack()
+	}
+
+	a.Value = Round(a.Value, a.Currency)
+	return a, nil
+}
+
+// String formats amount the way it is stored
+func (a Amount) String() string {
+	return strconv.FormatFloat(Round(a.Value, a.Currency), 'f', MinorUnits(a.Currency), 64) + " " + a.Currency
+}
@@ -0,0 +1,32 @@
+package cursor
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	cursorError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidCursor = cursorError{"InvalidCursor", fault.Invalid}
+)
+
+func (e cursorError) Error() string {
+	return e.String()
+}
+
+func (e cursorError) String() string {
+	return "crust.cursor." + e.name
+}
+
+func (e cursorError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e cursorError) withStack() *fault.Error {
+	return fault.New(e)
+}
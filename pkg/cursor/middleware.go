@@ -0,0 +1,54 @@
+package cursor
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+var (
+	// corteza's list endpoints that can be paged with cursors, with and without app's prefix
+	systemLists    = regexp.MustCompile(`^(/system)?/(roles|users)/?$`)
+	messagingLists = regexp.MustCompile(`^(/messaging)?/(channels|search/messages)/?$`)
+
+	lists = map[string]http.Handler{
+		"roles":           auth.MiddlewareValidOnly(rest.Handler("Cursor.Roles", listRoles)),
+		"users":           auth.MiddlewareValidOnly(rest.Handler("Cursor.Users", listUsers)),
+		"channels":        auth.MiddlewareValidOnly(rest.Handler("Cursor.Channels", listChannels)),
+		"search/messages": auth.MiddlewareValidOnly(rest.Handler("Cursor.Messages", listMessages)),
+	}
+)
+
+// MiddlewareSystem pages lists of roles and users with cursors
+//
+// Requests with cursor param (empty for the first page) get a page of the
+// list that follows the cursor, ordered by ID; the rest are left to
+// corteza, paged with page & perPage params.
+func MiddlewareSystem(next http.Handler) http.Handler {
+	return middleware(systemLists, next)
+}
+
+// MiddlewareMessaging pages lists of channels and messages (search) with cursors
+//
+// See MiddlewareSystem; size of the page is set with limit param.
+func MiddlewareMessaging(next http.Handler) http.Handler {
+	return middleware(messagingLists, next)
+}
+
+func middleware(paths *regexp.Regexp, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			m     = paths.FindStringSubmatch(r.URL.Path)
+			_, ok = r.URL.Query()["cursor"]
+		)
+
+		if DefaultCursor == nil || r.Method != http.MethodGet || m == nil || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		lists[m[2]].ServeHTTP(w, r)
+	})
+}
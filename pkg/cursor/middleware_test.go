@@ -0,0 +1,44 @@
+package cursor
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestListPaths(t *testing.T) {
+	tests := []struct {
+		paths *regexp.Regexp
+		path  string
+		want  string
+	}{
+		{systemLists, "/roles/", "roles"},
+		{systemLists, "/system/roles", "roles"},
+		{systemLists, "/system/users/", "users"},
+		{systemLists, "/system/users/1", ""},
+		{systemLists, "/compose/roles/", ""},
+		{systemLists, "/system/roles/1/members", ""},
+		{messagingLists, "/channels/", "channels"},
+		{messagingLists, "/messaging/channels/", "channels"},
+		{messagingLists, "/messaging/search/messages", "search/messages"},
+		{messagingLists, "/messaging/search/threads", ""},
+		{messagingLists, "/messaging/channels/1/messages/", ""},
+		{messagingLists, "/system/channels/", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var got string
+			if m := tt.paths.FindStringSubmatch(tt.path); m != nil {
+				got = m[2]
+			}
+
+			if got != tt.want {
+				t.Errorf("expected list %q, got %q", tt.want, got)
+			}
+
+			if _, ok := lists[got]; got != "" && !ok {
+				t.Errorf("expected handler of list %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package cursor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+		app string
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB, app string) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+		app: app,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet(r.app).With(r.ctx)
+}
+
+// Roles returns IDs of roles on the page, ordered by ID
+//
+// Mirrors filtering of corteza's role repository, so that pages are full;
+// roles are loaded (and filtered again) by corteza's service.
+func (r repository) Roles(f systemTypes.RoleFilter, p Page, readable squirrel.Sqlizer) ([]uint64, cursors, error) {
+	q := squirrel.
+		Select("r.id").
+		From("sys_role AS r")
+
+	q = rh.FilterNullByState(q, "r.deleted_at", f.Deleted)
+	q = rh.FilterNullByState(q, "r.archived_at", f.Archived)
+
+	if len(f.RoleID) > 0 {
+		q = q.Where(squirrel.Eq{"r.id": f.RoleID})
+	}
+
+	if f.MemberID > 0 {
+		q = q.Where(squirrel.Expr("r.id IN (SELECT rel_role FROM sys_role_member AS m WHERE m.rel_user = ?)", f.MemberID))
+	}
+
+	if f.Query != "" {
+		qs := f.Query + "%"
+		q = q.Where(squirrel.Or{
+			squirrel.Like{"r.name": qs},
+			squirrel.Like{"r.handle": qs},
+		})
+	}
+
+	if f.Name != "" {
+		q = q.Where(squirrel.Eq{"r.name": f.Name})
+	}
+
+	if f.Handle != "" {
+		q = q.Where(squirrel.Eq{"r.handle": f.Handle})
+	}
+
+	if readable != nil {
+		q = q.Where(readable)
+	}
+
+	return r.keyset(q, "r.id", false, p)
+}
+
+// Users returns IDs of users on the page, ordered by ID
+//
+// Mirrors filtering of corteza's user repository; masked emails and names
+// are left to corteza's service, which filters the loaded users again.
+func (r repository) Users(f systemTypes.UserFilter, p Page, readable squirrel.Sqlizer) ([]uint64, cursors, error) {
+	q := squirrel.
+		Select("u.id").
+		From("sys_user AS u")
+
+	q = rh.FilterNullByState(q, "u.deleted_at", f.Deleted)
+	q = rh.FilterNullByState(q, "u.suspended_at", f.Suspended)
+
+	if len(f.UserID) > 0 {
+		q = q.Where(squirrel.Eq{"u.id": f.UserID})
+	}
+
+	if len(f.RoleID) > 0 {
+		or := squirrel.Or{}
+		for _, roleID := range f.RoleID {
+			or = append(or, squirrel.Expr("u.id IN (SELECT rel_user FROM sys_role_member WHERE rel_role = ?)", roleID))
+		}
+
+		q = q.Where(or)
+	}
+
+	if f.Query != "" {
+		qs := f.Query + "%"
+		q = q.Where(squirrel.Or{
+			squirrel.Like{"u.username": qs},
+			squirrel.Like{"u.handle": qs},
+			squirrel.Like{"u.email": qs},
+			squirrel.Like{"u.name": qs},
+		})
+	}
+
+	if f.Email != "" {
+		q = q.Where(squirrel.Eq{"u.email": f.Email})
+	}
+
+	if f.Username != "" {
+		q = q.Where(squirrel.Eq{"u.username": f.Username})
+	}
+
+	if f.Handle != "" {
+		q = q.Where(squirrel.Eq{"u.handle": f.Handle})
+	}
+
+	if f.Kind != "" {
+		q = q.Where(squirrel.Eq{"u.kind": f.Kind})
+	}
+
+	if readable != nil {
+		q = q.Where(readable)
+	}
+
+	return r.keyset(q, "u.id", false, p)
+}
+
+// Channels returns IDs of channels on the page that are public or the user is member of, ordered by ID
+//
+// Mirrors filtering of corteza's channel repository.
+func (r repository) Channels(f messagingTypes.ChannelFilter, p Page, userID uint64) ([]uint64, cursors, error) {
+	q := squirrel.
+		Select("c.id").
+		From("messaging_channel AS c").
+		Where(squirrel.Eq{"c.archived_at": nil, "c.deleted_at": nil}).
+		Where(visibleChannels("c.id", userID))
+
+	if len(f.ChannelID) > 0 {
+		q = q.Where(squirrel.Eq{"c.id": f.ChannelID})
+	}
+
+	if f.Query != "" {
+		q = q.Where(squirrel.Like{"LOWER(c.name)": "%" + strings.ToLower(f.Query) + "%"})
+	}
+
+	return r.keyset(q, "c.id", false, p)
+}
+
+// Messages returns IDs of messages (or thread's replies) on the page, newest first
+//
+// Mirrors filtering of corteza's message repository, except for pinned and
+// bookmarked messages; those are left to corteza's service, which filters
+// the loaded messages again.
+func (r repository) Messages(f messagingTypes.MessageFilter, p Page, userID uint64) ([]uint64, cursors, error) {
+	q := squirrel.
+		Select("m.id").
+		From("messaging_message AS m").
+		Where(squirrel.Eq{"m.deleted_at": nil}).
+		Where(visibleChannels("m.rel_channel", userID))
+
+	if len(f.ChannelID) > 0 {
+		q = q.Where(squirrel.Eq{"m.rel_channel": f.ChannelID})
+	}
+
+	if len(f.UserID) > 0 {
+		q = q.Where(squirrel.Eq{"m.rel_user": f.UserID})
+	}
+
+	if len(f.ThreadID) > 0 {
+		q = q.Where(squirrel.Eq{"m.reply_to": f.ThreadID})
+	} else {
+		q = q.Where(squirrel.Eq{"m.reply_to": 0})
+	}
+
+	if len(f.Type) > 0 {
+		q = q.Where(squirrel.Eq{"m.type": f.Type})
+	}
+
+	if f.Query != "" {
+		q = q.Where(squirrel.Like{"LOWER(m.message)": "%" + strings.ToLower(f.Query) + "%"})
+	}
+
+	return r.keyset(q, "m.id", true, p)
+}
+
+// visibleChannels limits the column to public channels and channels of the user
+func visibleChannels(col string, userID uint64) squirrel.Sqlizer {
+	return squirrel.Or{
+		squirrel.Expr(col+" IN (SELECT id FROM messaging_channel WHERE type = ?)", messagingTypes.ChannelTypePublic),
+		squirrel.Expr(col+" IN (SELECT rel_channel FROM messaging_channel_member WHERE rel_user = ?)", userID),
+	}
+}
+
+// keyset loads IDs of the page that follows (or precedes) the cursor
+//
+// One more ID than the limit is loaded to tell if there is a page beyond.
+// Pages before the cursor are loaded in reverse and flipped back.
+func (r repository) keyset(q squirrel.SelectBuilder, col string, desc bool, p Page) (ids []uint64, c cursors, err error) {
+	var (
+		back = p.cursor != nil && p.cursor.Before
+
+		// order of the query, list order unless going back
+		reverse = desc != back
+	)
+
+	if p.cursor != nil {
+		if reverse {
+			q = q.Where(squirrel.Lt{col: p.cursor.ID})
+		} else {
+			q = q.Where(squirrel.Gt{col: p.cursor.ID})
+		}
+	}
+
+	if reverse {
+		q = q.OrderBy(col + " DESC")
+	} else {
+		q = q.OrderBy(col)
+	}
+
+	if err = rh.FetchAll(r.db(), q.Limit(uint64(p.Limit)+1), &ids); err != nil {
+		return
+	}
+
+	more := uint(len(ids)) > p.Limit
+	if more {
+		ids = ids[:p.Limit]
+	}
+
+	if back {
+		for i, j := 0, len(ids)-1; i < j; i, j = i+1, j-1 {
+			ids[i], ids[j] = ids[j], ids[i]
+		}
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	var (
+		next = &Cursor{ID: ids[len(ids)-1]}
+		prev = &Cursor{ID: ids[0], Before: true}
+	)
+
+	// There is always something on the side we came from
+	if back {
+		c.next = next
+		if more {
+			c.prev = prev
+		}
+	} else {
+		if more {
+			c.next = next
+		}
+		if p.cursor != nil {
+			c.prev = prev
+		}
+	}
+
+	return
+}
@@ -0,0 +1,93 @@
+package cursor
+
+import (
+	"net/http"
+
+	messagingRequest "github.com/cortezaproject/corteza-server/messaging/rest/request"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	systemRequest "github.com/cortezaproject/corteza-server/system/rest/request"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// Lists are read with corteza's request params (see rest/request pkgs),
+// so that the filters are the same as without the cursor
+
+func listRoles(r *http.Request) (interface{}, error) {
+	req := systemRequest.NewRoleList()
+	if err := req.Fill(r); err != nil {
+		return nil, err
+	}
+
+	f := RoleFilter{Page: page(r, req.PerPage)}
+	f.Query = req.Query
+	f.Deleted = rh.FilterState(req.Deleted)
+	f.Archived = rh.FilterState(req.Archived)
+
+	return DefaultCursor.With(r.Context()).Roles(f)
+}
+
+func listUsers(r *http.Request) (interface{}, error) {
+	req := systemRequest.NewUserList()
+	if err := req.Fill(r); err != nil {
+		return nil, err
+	}
+
+	f := UserFilter{Page: page(r, req.PerPage)}
+	f.UserID = payload.ParseUInt64s(req.UserID)
+	f.RoleID = payload.ParseUInt64s(req.RoleID)
+	f.Query = req.Query
+	f.Email = req.Email
+	f.Username = req.Username
+	f.Handle = req.Handle
+	f.Kind = req.Kind
+	f.Deleted = rh.FilterState(req.Deleted)
+	f.Suspended = rh.FilterState(req.Suspended)
+
+	if req.IncDeleted && f.Deleted == 0 {
+		f.Deleted = rh.FilterStateInclusive
+	}
+
+	if req.IncSuspended && f.Suspended == 0 {
+		f.Suspended = rh.FilterStateInclusive
+	}
+
+	return DefaultCursor.With(r.Context()).Users(f)
+}
+
+func listChannels(r *http.Request) (interface{}, error) {
+	req := messagingRequest.NewChannelList()
+	if err := req.Fill(r); err != nil {
+		return nil, err
+	}
+
+	f := ChannelFilter{Page: page(r, rest.QueryUint(r, "limit"))}
+	f.Query = req.Query
+
+	return DefaultCursor.With(r.Context()).Channels(f)
+}
+
+func listMessages(r *http.Request) (interface{}, error) {
+	req := messagingRequest.NewSearchMessages()
+	if err := req.Fill(r); err != nil {
+		return nil, err
+	}
+
+	f := MessageFilter{Page: page(r, req.Limit)}
+	f.ChannelID = payload.ParseUInt64s(req.ChannelID)
+	f.ThreadID = payload.ParseUInt64s(req.ThreadID)
+	f.UserID = payload.ParseUInt64s(req.UserID)
+	f.Type = req.Type
+	f.PinnedOnly = req.PinnedOnly
+	f.BookmarkedOnly = req.BookmarkedOnly
+	f.Query = req.Query
+
+	return DefaultCursor.With(r.Context()).Messages(f)
+}
+
+func page(r *http.Request, limit uint) Page {
+	return Page{
+		Cursor: r.URL.Query().Get("cursor"),
+		Limit:  limit,
+	}
+}
@@ -0,0 +1,240 @@
+package cursor
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	cursorService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		// system services, when initialized
+		ac   readableFilterer
+		role systemService.RoleService
+		user systemService.UserService
+
+		// messaging services, when initialized
+		channel messagingService.ChannelService
+		message messagingService.MessageService
+	}
+
+	readableFilterer interface {
+		FilterReadableRoles(context.Context) *permissions.ResourceFilter
+		FilterReadableUsers(context.Context) *permissions.ResourceFilter
+	}
+
+	CursorService interface {
+		With(ctx context.Context) CursorService
+
+		Roles(RoleFilter) (*Payload, error)
+		Users(UserFilter) (*Payload, error)
+		Channels(ChannelFilter) (*Payload, error)
+		Messages(MessageFilter) (*Payload, error)
+	}
+)
+
+var (
+	DefaultCursor CursorService
+
+	// shared by apps of the monolith
+	defaultCursor *cursorService
+)
+
+// InitSystem initializes cursor pagination of roles and users
+//
+// Must be called after system services are initialized
+func InitSystem(ctx context.Context, log *zap.Logger) error {
+	svc := start(ctx, log)
+	svc.ac = systemService.DefaultAccessControl
+	svc.role = systemService.DefaultRole
+	svc.user = systemService.DefaultUser
+
+	return nil
+}
+
+// InitMessaging initializes cursor pagination of channels and messages
+//
+// Must be called after messaging services are initialized
+func InitMessaging(ctx context.Context, log *zap.Logger) error {
+	svc := start(ctx, log)
+	svc.channel = messagingService.DefaultChannel
+	svc.message = messagingService.DefaultMessage
+
+	return nil
+}
+
+func start(ctx context.Context, log *zap.Logger) *cursorService {
+	if defaultCursor == nil {
+		defaultCursor = (&cursorService{logger: log}).with(ctx)
+		DefaultCursor = defaultCursor
+	}
+
+	return defaultCursor
+}
+
+func (svc cursorService) With(ctx context.Context) CursorService {
+	return svc.with(ctx)
+}
+
+func (svc cursorService) with(ctx context.Context) *cursorService {
+	return &cursorService{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:   svc.ac,
+		role: svc.role,
+		user: svc.user,
+
+		channel: svc.channel,
+		message: svc.message,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc cursorService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc cursorService) repository(app string) *repository {
+	return Repository(svc.ctx, factory.Database.MustGet(app).With(svc.ctx), app)
+}
+
+// Roles returns a page of readable roles, ordered by ID
+//
+// Roles on the page are loaded by corteza's role service, with the filter.
+func (svc cursorService) Roles(f RoleFilter) (*Payload, error) {
+	if err := f.parse(maxLimit); err != nil {
+		return nil, err
+	}
+
+	ids, c, err := svc.repository("system").Roles(f.RoleFilter, f.Page, svc.ac.FilterReadableRoles(svc.ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	set := systemTypes.RoleSet{}
+	if len(ids) > 0 {
+		rf := f.RoleFilter
+		rf.RoleID = ids
+		rf.Sort = ""
+		rf.PageFilter = rh.Paging(1, uint(len(ids)))
+
+		if set, _, err = svc.role.With(svc.ctx).Find(rf); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.payload(f, orderedRoles(set, ids)), nil
+}
+
+// Users returns a page of readable users, ordered by ID
+//
+// Users on the page are loaded by corteza's user service, with the filter.
+func (svc cursorService) Users(f UserFilter) (*Payload, error) {
+	if err := f.parse(maxLimit); err != nil {
+		return nil, err
+	}
+
+	ids, c, err := svc.repository("system").Users(f.UserFilter, f.Page, svc.ac.FilterReadableUsers(svc.ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	set := systemTypes.UserSet{}
+	if len(ids) > 0 {
+		uf := f.UserFilter
+		uf.UserID = ids
+		uf.Sort = ""
+		uf.PageFilter = rh.Paging(1, uint(len(ids)))
+
+		if set, _, err = svc.user.With(svc.ctx).Find(uf); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.payload(f, orderedUsers(set, ids)), nil
+}
+
+// Channels returns a page of public channels and channels of the current user, ordered by ID
+//
+// Channels on the page are loaded by corteza's channel service, which
+// leaves out the ones the user can not read; such a page holds fewer
+// channels than the limit but the cursors stay valid.
+func (svc cursorService) Channels(f ChannelFilter) (*Payload, error) {
+	if err := f.parse(maxLimit); err != nil {
+		return nil, err
+	}
+
+	userID := auth.GetIdentityFromContext(svc.ctx).Identity()
+
+	ids, c, err := svc.repository("messaging").Channels(f.ChannelFilter, f.Page, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := messagingTypes.ChannelSet{}
+	if len(ids) > 0 {
+		cf := f.ChannelFilter
+		cf.ChannelID = ids
+
+		if set, _, err = svc.channel.With(svc.ctx).Find(cf); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.payload(f, payload.Channels(orderedChannels(set, ids))), nil
+}
+
+// Messages returns a page of messages or thread's replies, newest first
+//
+// Next cursor points to older messages, previous to newer ones. Messages
+// on the page are loaded by corteza's message service, with the filter;
+// pages of pinned or bookmarked messages hold fewer messages than the limit.
+func (svc cursorService) Messages(f MessageFilter) (*Payload, error) {
+	if err := f.parse(maxMessageLimit); err != nil {
+		return nil, err
+	}
+
+	userID := auth.GetIdentityFromContext(svc.ctx).Identity()
+
+	ids, c, err := svc.repository("messaging").Messages(f.MessageFilter, f.Page, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := messagingTypes.MessageSet{}
+	if len(ids) > 0 {
+		mf := f.MessageFilter
+
+		// IDs are ordered, newest first
+		mf.AfterID, mf.BeforeID = 0, 0
+		mf.FromID, mf.ToID = ids[len(ids)-1], ids[0]
+		mf.Limit = uint(len(ids))
+
+		if set, _, err = svc.message.With(svc.ctx).Find(mf); err != nil {
+			return nil, err
+		}
+	}
+
+	svc.log(
+		zap.Uint64s("channelID", f.ChannelID),
+		zap.Int("count", len(set)),
+	).Debug("messages loaded")
+
+	return c.payload(f, payload.Messages(svc.ctx, set)), nil
+}
@@ -0,0 +1,165 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// Cursor points to the boundary of a page
+	//
+	// Without Before, it points to what follows the item with the ID,
+	// with Before, to what precedes it.
+	Cursor struct {
+		ID     uint64 `json:"id,string"`
+		Before bool   `json:"before,omitempty"`
+	}
+
+	// Page of the set, one cursor or none (first page) and size of the page
+	Page struct {
+		Cursor string `json:"cursor,omitempty"`
+		Limit  uint   `json:"limit"`
+
+		cursor *Cursor
+	}
+
+	// RoleFilter is corteza's filter of roles with a page
+	RoleFilter struct {
+		systemTypes.RoleFilter
+		Page
+	}
+
+	// UserFilter is corteza's filter of users with a page
+	UserFilter struct {
+		systemTypes.UserFilter
+		Page
+	}
+
+	// ChannelFilter is corteza's filter of channels with a page
+	ChannelFilter struct {
+		messagingTypes.ChannelFilter
+		Page
+	}
+
+	// MessageFilter is corteza's filter of messages with a page
+	//
+	// Message ID ranges (AfterID, FromID...) are replaced by the cursor.
+	MessageFilter struct {
+		messagingTypes.MessageFilter
+		Page
+	}
+
+	// Payload is a page of the set with cursors of the pages around it
+	//
+	// Cursors are omitted when there is nothing (more) to load.
+	Payload struct {
+		Filter     interface{} `json:"filter"`
+		Set        interface{} `json:"set"`
+		NextCursor string      `json:"nextCursor,omitempty"`
+		PrevCursor string      `json:"prevCursor,omitempty"`
+	}
+
+	// Cursors returned by keyset query
+	cursors struct {
+		next, prev *Cursor
+	}
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+
+	// corteza does not load more messages at once
+	maxMessageLimit = 100
+)
+
+// Encode returns cursor as an opaque, URL safe token
+func (c *Cursor) Encode() string {
+	if c == nil {
+		return ""
+	}
+
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Decode parses cursor from the token, empty token is no cursor
+func Decode(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	var (
+		c        = &Cursor{}
+		raw, err = base64.RawURLEncoding.DecodeString(token)
+	)
+
+	if err != nil || json.Unmarshal(raw, c) != nil || c.ID == 0 {
+		return nil, ErrInvalidCursor.withStack()
+	}
+
+	return c, nil
+}
+
+// parse decodes the cursor and normalizes limit
+func (p *Page) parse(max uint) (err error) {
+	if p.cursor, err = Decode(p.Cursor); err != nil {
+		return
+	}
+
+	if p.Limit == 0 {
+		p.Limit = defaultLimit
+	}
+
+	if p.Limit > max {
+		p.Limit = max
+	}
+
+	return
+}
+
+func (c cursors) payload(f, set interface{}) *Payload {
+	return &Payload{
+		Filter:     f,
+		Set:        set,
+		NextCursor: c.next.Encode(),
+		PrevCursor: c.prev.Encode(),
+	}
+}
+
+// orderedRoles returns roles in order of IDs, skipping the missing ones
+func orderedRoles(set systemTypes.RoleSet, ids []uint64) systemTypes.RoleSet {
+	out := systemTypes.RoleSet{}
+	for _, id := range ids {
+		if r := set.FindByID(id); r != nil {
+			out = append(out, r)
+		}
+	}
+
+	return out
+}
+
+func orderedUsers(set systemTypes.UserSet, ids []uint64) systemTypes.UserSet {
+	out := systemTypes.UserSet{}
+	for _, id := range ids {
+		if u := set.FindByID(id); u != nil {
+			out = append(out, u)
+		}
+	}
+
+	return out
+}
+
+func orderedChannels(set messagingTypes.ChannelSet, ids []uint64) messagingTypes.ChannelSet {
+	out := messagingTypes.ChannelSet{}
+	for _, id := range ids {
+		if c := set.FindByID(id); c != nil {
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,74 @@
+// Package dal holds helpers of generated repositories
+//
+// Repositories are generated from type definitions with codegen/repository;
+// see its documentation for the supported struct tags.
+package dal
+
+import (
+	"strings"
+
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	dalError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidSort = dalError{"InvalidSort", fault.Invalid}
+)
+
+func (e dalError) Error() string {
+	return e.String()
+}
+
+func (e dalError) String() string {
+	return "crust.dal." + e.name
+}
+
+func (e dalError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e dalError) withStack() *fault.Error {
+	return fault.New(e)
+}
+
+// Order returns ORDER BY expressions of the sort
+//
+// Sort holds comma separated fields (as named in JSON) with an optional
+// direction, for example "name, createdAt DESC". Fields are translated to
+// columns; sorting by fields that are not in columns is not allowed.
+func Order(sort string, columns map[string]string) ([]string, error) {
+	var out []string
+
+	for _, expr := range strings.Split(sort, ",") {
+		parts := strings.Fields(expr)
+		if len(parts) == 0 {
+			continue
+		} else if len(parts) > 2 {
+			return nil, ErrInvalidSort.withStack()
+		}
+
+		column, ok := columns[parts[0]]
+		if !ok {
+			return nil, ErrInvalidSort.withStack()
+		}
+
+		if len(parts) == 2 {
+			switch dir := strings.ToUpper(parts[1]); dir {
+			case "ASC", "DESC":
+				column += " " + dir
+			default:
+				return nil, ErrInvalidSort.withStack()
+			}
+		}
+
+		out = append(out, column)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,170 @@
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+)
+
+type (
+	// rule sets timeout of requests with matching method & path
+	rule struct {
+		method  string
+		pattern string
+		timeout time.Duration
+	}
+
+	rules []*rule
+
+	// appliedKey marks requests that already have a deadline; in monolith
+	// the middleware is registered by every app
+	appliedKey struct{}
+)
+
+var (
+	// Long-lived responses are not limited unless configured otherwise
+	builtin = rules{
+		{pattern: "/cdc/*/stream"},
+		{pattern: "/records/stream"},
+		{pattern: "/message-stream"},
+		{pattern: "/diagnostics/bundle"},
+		{pattern: "/diagnostics/pprof/profile"},
+		{pattern: "/diagnostics/pprof/trace"},
+	}
+
+	config = struct {
+		sync.RWMutex
+		timeout time.Duration
+		rules   rules
+	}{}
+)
+
+// Init loads request timeouts
+//
+// HTTP_REQUEST_TIMEOUT sets timeout of all API requests (disabled by default).
+// HTTP_ROUTE_TIMEOUTS sets timeouts of individual routes as comma separated
+// "[METHOD ]pattern=duration" rules, first matching rule applies:
+//
+//	POST /module/*/record/import=10m,GET /namespace/*/exports/*=5m,/search=0
+//
+// Patterns are matched against the end of the request path (segment-wise,
+// "*" matches one segment), so rules work the same in standalone and
+// monolith servers. Zero duration disables the timeout.
+func Init(ctx context.Context, log *zap.Logger) error {
+	rr, err := parseRules(options.EnvString("", "HTTP_ROUTE_TIMEOUTS", ""))
+	if err != nil {
+		return err
+	}
+
+	config.Lock()
+	defer config.Unlock()
+
+	config.timeout = options.EnvDuration("", "HTTP_REQUEST_TIMEOUT", 0)
+	config.rules = append(rr, builtin...)
+
+	log.Debug("request timeouts loaded", zap.Duration("timeout", config.timeout), zap.Int("rules", len(rr)))
+	return nil
+}
+
+// Middleware sets deadline of the request's context
+//
+// Context of the request is also canceled when client disconnects;
+// services & repositories pass it down to database queries and outgoing
+// requests, so abandoned requests stop as soon as possible.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Context().Value(appliedKey{}) != nil || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), appliedKey{}, true)
+
+		if d := timeout(r.Method, r.URL.Path); d > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// timeout returns timeout of the first matching rule or the default timeout
+func timeout(method, p string) time.Duration {
+	config.RLock()
+	defer config.RUnlock()
+
+	for _, r := range config.rules {
+		if r.matches(method, p) {
+			return r.timeout
+		}
+	}
+
+	return config.timeout
+}
+
+func (r rule) matches(method, p string) bool {
+	if r.method != "" && r.method != method {
+		return false
+	}
+
+	var (
+		segments = strings.Count(r.pattern, "/")
+		cut      = len(p)
+	)
+
+	// Take as many trailing segments of the path as there are in the pattern
+	for ; segments > 0 && cut > 0; segments-- {
+		cut = strings.LastIndexByte(p[:cut], '/')
+	}
+
+	if segments > 0 || cut < 0 {
+		return false
+	}
+
+	ok, _ := path.Match(r.pattern, p[cut:])
+	return ok
+}
+
+func parseRules(s string) (rr rules, err error) {
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+
+		eq := strings.LastIndexByte(item, '=')
+		if eq < 0 {
+			return nil, errors.Errorf("invalid route timeout %q, expecting [METHOD ]pattern=duration", item)
+		}
+
+		r := &rule{pattern: strings.TrimSpace(item[:eq])}
+		if r.timeout, err = time.ParseDuration(strings.TrimSpace(item[eq+1:])); err != nil {
+			return nil, errors.Wrapf(err, "invalid duration of route timeout %q", item)
+		}
+
+		if sp := strings.IndexByte(r.pattern, ' '); sp > 0 {
+			r.method, r.pattern = strings.ToUpper(r.pattern[:sp]), strings.TrimSpace(r.pattern[sp+1:])
+		}
+
+		if !strings.HasPrefix(r.pattern, "/") {
+			return nil, errors.Errorf("invalid route timeout %q, pattern must start with /", item)
+		}
+
+		if _, err = path.Match(r.pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern of route timeout %q", item)
+		}
+
+		rr = append(rr, r)
+	}
+
+	return rr, nil
+}
@@ -0,0 +1,35 @@
+package dependencies
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+)
+
+type (
+	// chart wraps chart service and checks dependents of deleted charts
+	chart struct {
+		service.ChartService
+		ctx context.Context
+	}
+)
+
+// Chart decorates chart service with dependency checks
+func Chart(cs service.ChartService) service.ChartService {
+	return &chart{ChartService: cs, ctx: context.Background()}
+}
+
+func (svc chart) With(ctx context.Context) service.ChartService {
+	return &chart{
+		ChartService: svc.ChartService.With(ctx),
+		ctx:          ctx,
+	}
+}
+
+func (svc chart) DeleteByID(namespaceID, chartID uint64) error {
+	if err := defaultDependencies.with(svc.ctx).check(namespaceID, nodeID(KindChart, chartID)); err != nil {
+		return err
+	}
+
+	return svc.ChartService.DeleteByID(namespaceID, chartID)
+}
@@ -0,0 +1,35 @@
+package dependencies
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	dependenciesError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidKind   = dependenciesError{"InvalidKind", fault.Invalid}
+	ErrNodeNotFound  = dependenciesError{"NodeNotFound", fault.NotFound}
+	ErrHasDependents = dependenciesError{"HasDependents", fault.Conflict}
+	ErrNoPermissions = dependenciesError{"NoPermissions", fault.Forbidden}
+)
+
+func (e dependenciesError) Error() string {
+	return e.String()
+}
+
+func (e dependenciesError) String() string {
+	return "crust.dependencies." + e.name
+}
+
+func (e dependenciesError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e dependenciesError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,282 @@
+package dependencies
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+)
+
+var (
+	// Field values used in script sources: values.name, values['name']
+	sourceValue = regexp.MustCompile(`values(?:\.([A-Za-z_][A-Za-z0-9_]*)|\[['"]([^'"]+)['"]\])`)
+)
+
+func newGraph() *Graph {
+	return &Graph{
+		Nodes: []*Node{},
+		Edges: []*Edge{},
+		nodes: map[string]*Node{},
+		in:    map[string][]*Edge{},
+	}
+}
+
+// build makes graph of namespace's resources
+//
+// Script dependencies are guessed from triggers and sources: scripts
+// depend on modules they are triggered by or whose handles they
+// mention, and on fields of those modules they read from values.
+func build(mm types.ModuleSet, pp types.PageSet, cc types.ChartSet, ss automation.ScriptSet, tt automation.TriggerSet) *Graph {
+	g := newGraph()
+
+	for _, m := range mm {
+		g.node(KindModule, m.ID, label(m.Handle, m.Name), 0)
+		for _, f := range m.Fields {
+			g.node(KindField, f.ID, label(m.Handle, m.Name)+"."+f.Name, m.ID)
+		}
+	}
+
+	for _, p := range pp {
+		g.node(KindPage, p.ID, label(p.Handle, p.Title), 0)
+	}
+
+	for _, c := range cc {
+		g.node(KindChart, c.ID, label(c.Handle, c.Name), 0)
+	}
+
+	for _, s := range ss {
+		g.node(KindScript, s.ID, s.Name, 0)
+	}
+
+	for _, m := range mm {
+		for _, f := range m.Fields {
+			g.edge(nodeID(KindField, f.ID), nodeID(KindModule, m.ID), EdgeField, "")
+
+			if refID := idOption(f.Options, "moduleID"); refID > 0 {
+				g.edge(nodeID(KindField, f.ID), nodeID(KindModule, refID), EdgeReference, f.Kind)
+			}
+		}
+	}
+
+	for _, p := range pp {
+		from := nodeID(KindPage, p.ID)
+
+		if p.SelfID > 0 {
+			g.edge(from, nodeID(KindPage, p.SelfID), EdgeParent, "")
+		}
+
+		if p.ModuleID > 0 {
+			g.edge(from, nodeID(KindModule, p.ModuleID), EdgeRecordPage, "")
+		}
+
+		for i, b := range p.Blocks {
+			detail := fmt.Sprintf("%s #%d", b.Kind, i+1)
+
+			moduleID := idOption(b.Options, "moduleID")
+			if moduleID > 0 {
+				g.edge(from, nodeID(KindModule, moduleID), EdgeBlock, detail)
+			} else {
+				// Record blocks show fields of page's module
+				moduleID = p.ModuleID
+			}
+
+			if chartID := idOption(b.Options, "chartID"); chartID > 0 {
+				g.edge(from, nodeID(KindChart, chartID), EdgeBlock, detail)
+			}
+
+			if m := mm.FindByID(moduleID); m != nil {
+				for _, name := range blockFields(b.Options) {
+					if f := m.Fields.FindByName(name); f != nil {
+						g.edge(from, nodeID(KindField, f.ID), EdgeBlock, detail)
+					}
+				}
+			}
+		}
+	}
+
+	for _, c := range cc {
+		for _, r := range c.Config.Reports {
+			if r.ModuleID > 0 {
+				g.edge(nodeID(KindChart, c.ID), nodeID(KindModule, r.ModuleID), EdgeReport, "")
+			}
+		}
+	}
+
+	for _, s := range ss {
+		var (
+			from    = nodeID(KindScript, s.ID)
+			modules = types.ModuleSet{}
+		)
+
+		for _, t := range tt {
+			if t.ScriptID != s.ID || t.DeletedAt != nil {
+				continue
+			}
+
+			moduleID, _ := strconv.ParseUint(t.Condition, 10, 64)
+			if m := mm.FindByID(moduleID); m != nil {
+				g.edge(from, nodeID(KindModule, m.ID), EdgeTrigger, t.Event)
+				modules = append(modules, m)
+			}
+		}
+
+		for _, m := range mm {
+			if mentions(s.Source, m.Handle) {
+				g.edge(from, nodeID(KindModule, m.ID), EdgeSource, "")
+				modules = append(modules, m)
+			}
+		}
+
+		for _, match := range sourceValue.FindAllStringSubmatch(s.Source, -1) {
+			name := match[1] + match[2]
+			for _, m := range modules {
+				if f := m.Fields.FindByName(name); f != nil {
+					g.edge(from, nodeID(KindField, f.ID), EdgeSource, "")
+				}
+			}
+		}
+	}
+
+	return g
+}
+
+func (g *Graph) node(kind string, id uint64, label string, moduleID uint64) {
+	n := &Node{ID: nodeID(kind, id), Kind: kind, ResourceID: id, Label: label, ModuleID: moduleID}
+	g.nodes[n.ID] = n
+	g.Nodes = append(g.Nodes, n)
+}
+
+// edge adds the edge when both nodes exist and it is not there yet
+func (g *Graph) edge(from, to, kind, detail string) {
+	if g.nodes[from] == nil || g.nodes[to] == nil {
+		return
+	}
+
+	for _, e := range g.in[to] {
+		if e.From == from && e.Kind == kind && e.Detail == detail {
+			return
+		}
+	}
+
+	e := &Edge{From: from, To: to, Kind: kind, Detail: detail}
+	g.in[to] = append(g.in[to], e)
+	g.Edges = append(g.Edges, e)
+}
+
+// impact returns dependents and dependencies of the node
+//
+// Dependents are found breadth first, each with the edge it was first
+// reached by, nearest first.
+func (g *Graph) impact(id string) *Impact {
+	n := g.nodes[id]
+	if n == nil {
+		return nil
+	}
+
+	out := &Impact{Node: n, Dependents: []*Dependent{}, Dependencies: []*Edge{}}
+
+	for _, e := range g.Edges {
+		if e.From == id {
+			out.Dependencies = append(out.Dependencies, e)
+		}
+	}
+
+	var (
+		seen  = map[string]bool{id: true}
+		queue = []string{id}
+	)
+
+	for depth := 1; len(queue) > 0; depth++ {
+		var next []string
+		for _, to := range queue {
+			for _, e := range g.in[to] {
+				if seen[e.From] {
+					continue
+				}
+
+				seen[e.From] = true
+				next = append(next, e.From)
+				out.Dependents = append(out.Dependents, &Dependent{Node: g.nodes[e.From], Depth: depth, Via: e})
+			}
+		}
+
+		queue = next
+	}
+
+	sort.SliceStable(out.Dependents, func(i, j int) bool {
+		return out.Dependents[i].Depth < out.Dependents[j].Depth
+	})
+
+	return out
+}
+
+// external returns dependents that are not removed with the node, its own fields
+func (i *Impact) external() []*Dependent {
+	out := make([]*Dependent, 0, len(i.Dependents))
+	for _, d := range i.Dependents {
+		if d.Via.Kind != EdgeField {
+			out = append(out, d)
+		}
+	}
+
+	return out
+}
+
+// idOption returns ID from field or block options
+func idOption(oo map[string]interface{}, name string) uint64 {
+	switch v := oo[name].(type) {
+	case string:
+		id, _ := strconv.ParseUint(v, 10, 64)
+		return id
+	case float64:
+		return uint64(v)
+	}
+
+	return 0
+}
+
+// blockFields returns names of fields shown by the block
+//
+// Fields are listed by names or as objects with names.
+func blockFields(oo map[string]interface{}) (out []string) {
+	ff, _ := oo["fields"].([]interface{})
+	for _, f := range ff {
+		switch v := f.(type) {
+		case string:
+			out = append(out, v)
+		case map[string]interface{}:
+			if name, ok := v["name"].(string); ok {
+				out = append(out, name)
+			}
+		}
+	}
+
+	return out
+}
+
+// mentions checks if the source has the handle in quotes
+func mentions(source, handle string) bool {
+	if handle == "" {
+		return false
+	}
+
+	for _, q := range []string{`'`, `"`, "`"} {
+		if strings.Contains(source, q+handle+q) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func label(handle, name string) string {
+	if handle != "" {
+		return handle
+	}
+
+	return name
+}
@@ -0,0 +1,69 @@
+package dependencies
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// module wraps module service and checks dependents of deleted modules and fields
+	module struct {
+		service.ModuleService
+		ctx context.Context
+	}
+)
+
+// Module decorates module service with dependency checks
+//
+// Fields removed by update are checked too.
+func Module(ms service.ModuleService) service.ModuleService {
+	return &module{ModuleService: ms, ctx: context.Background()}
+}
+
+func (svc module) With(ctx context.Context) service.ModuleService {
+	return &module{
+		ModuleService: svc.ModuleService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc module) Update(m *types.Module) (*types.Module, error) {
+	old, err := svc.ModuleService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(m.NamespaceID, m.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		kept    = map[uint64]bool{}
+		removed []string
+	)
+
+	for _, f := range m.Fields {
+		kept[f.ID] = true
+	}
+
+	for _, f := range old.Fields {
+		if !kept[f.ID] {
+			removed = append(removed, nodeID(KindField, f.ID))
+		}
+	}
+
+	if len(removed) > 0 {
+		if err = defaultDependencies.with(svc.ctx).check(m.NamespaceID, removed...); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.ModuleService.Update(m)
+}
+
+func (svc module) DeleteByID(namespaceID, moduleID uint64) error {
+	if err := defaultDependencies.with(svc.ctx).check(namespaceID, nodeID(KindModule, moduleID)); err != nil {
+		return err
+	}
+
+	return svc.ModuleService.DeleteByID(namespaceID, moduleID)
+}
@@ -0,0 +1,35 @@
+package dependencies
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+)
+
+type (
+	// page wraps page service and checks dependents of deleted pages
+	page struct {
+		service.PageService
+		ctx context.Context
+	}
+)
+
+// Page decorates page service with dependency checks
+func Page(ps service.PageService) service.PageService {
+	return &page{PageService: ps, ctx: context.Background()}
+}
+
+func (svc page) With(ctx context.Context) service.PageService {
+	return &page{
+		PageService: svc.PageService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc page) DeleteByID(namespaceID, pageID uint64) error {
+	if err := defaultDependencies.with(svc.ctx).check(namespaceID, nodeID(KindPage, pageID)); err != nil {
+		return err
+	}
+
+	return svc.PageService.DeleteByID(namespaceID, pageID)
+}
@@ -0,0 +1,32 @@
+package dependencies
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts dependency graph endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("Dependencies.Graph", func(r *http.Request) (interface{}, error) {
+		return DefaultDependencies.With(r.Context()).Graph(
+			rest.ParamUint64(r, "namespaceID"),
+		)
+	}))
+
+	// What depends on the resource (module, field, page, chart, script)
+	r.Get("/{kind}/{resourceID}", rest.Handler("Dependencies.Impact", func(r *http.Request) (interface{}, error) {
+		return DefaultDependencies.With(r.Context()).Impact(
+			rest.ParamUint64(r, "namespaceID"),
+			chi.URLParam(r, "kind"),
+			rest.ParamUint64(r, "resourceID"),
+		)
+	}))
+}
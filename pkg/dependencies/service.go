@@ -0,0 +1,255 @@
+package dependencies
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+)
+
+type (
+	dependenciesService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		settings settingsGetter
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+		page      service.PageService
+		chart     service.ChartService
+		scripts   scriptFinder
+	}
+
+	accessController interface {
+		CanReadNamespace(context.Context, *types.Namespace) bool
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	scriptFinder interface {
+		FindScripts(context.Context, automation.ScriptFilter) (automation.ScriptSet, automation.ScriptFilter, error)
+		FindTriggers(context.Context, automation.TriggerFilter) (automation.TriggerSet, automation.TriggerFilter, error)
+	}
+
+	DependenciesService interface {
+		With(ctx context.Context) DependenciesService
+
+		Graph(namespaceID uint64) (*Graph, error)
+		Impact(namespaceID uint64, kind string, resourceID uint64) (*Impact, error)
+	}
+)
+
+var (
+	DefaultDependencies DependenciesService
+
+	// used by service decorators
+	defaultDependencies *dependenciesService
+)
+
+// Init initializes dependencies service and checks dependents of deleted
+// modules, fields, pages and charts
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := (&dependenciesService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		settings:  service.DefaultSettings,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+		page:      service.DefaultPage,
+		chart:     service.DefaultChart,
+		scripts:   service.DefaultInternalAutomationManager,
+	}).with(ctx)
+
+	DefaultDependencies = svc
+	defaultDependencies = svc
+
+	service.DefaultModule = Module(service.DefaultModule)
+	service.DefaultPage = Page(service.DefaultPage)
+	service.DefaultChart = Chart(service.DefaultChart)
+
+	return nil
+}
+
+func (svc dependenciesService) With(ctx context.Context) DependenciesService {
+	return svc.with(ctx)
+}
+
+func (svc dependenciesService) with(ctx context.Context) *dependenciesService {
+	return &dependenciesService{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		settings: svc.settings,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module,
+		page:      svc.page,
+		chart:     svc.chart,
+		scripts:   svc.scripts,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc dependenciesService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Graph returns dependency graph of the namespace
+//
+// Graph holds all resources of the namespace, also ones the user can
+// not read, so that the impact of changes is not underestimated.
+func (svc dependenciesService) Graph(namespaceID uint64) (*Graph, error) {
+	if err := svc.readable(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.graph(namespaceID)
+}
+
+// Impact returns resources that depend on the resource, and ones it depends on
+func (svc dependenciesService) Impact(namespaceID uint64, kind string, resourceID uint64) (*Impact, error) {
+	if !kinds[kind] {
+		return nil, ErrInvalidKind.withStack()
+	}
+
+	g, err := svc.Graph(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	i := g.impact(nodeID(kind, resourceID))
+	if i == nil {
+		return nil, ErrNodeNotFound.withStack().WithID("resourceID", resourceID)
+	}
+
+	return i, nil
+}
+
+// check applies the policy to resources about to be deleted
+//
+// Errors of loading the graph are logged, deleting is not blocked by them.
+func (svc dependenciesService) check(namespaceID uint64, ids ...string) error {
+	g, err := svc.graph(namespaceID)
+	if err != nil {
+		svc.log(zap.Error(err)).Error("could not load dependency graph")
+		return nil
+	}
+
+	var (
+		deleted    = map[string]bool{}
+		dependents []*Dependent
+	)
+
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	for _, id := range ids {
+		if i := g.impact(id); i != nil {
+			for _, d := range i.external() {
+				// Resources deleted together do not count
+				if !deleted[d.Node.ID] {
+					dependents = append(dependents, d)
+				}
+			}
+		}
+	}
+
+	if len(dependents) == 0 {
+		return nil
+	}
+
+	labels := make([]string, 0, maxListed)
+	for i, d := range dependents {
+		if i == maxListed {
+			labels = append(labels, "...")
+			break
+		}
+
+		labels = append(labels, d.Node.Kind+" "+d.Node.Label)
+	}
+
+	if svc.policy() == PolicyEnforce {
+		return ErrHasDependents.withStack().
+			WithMessage("resource has dependents: " + strings.Join(labels, ", "))
+	}
+
+	svc.log(zap.Strings("resources", ids), zap.Strings("dependents", labels)).
+		Warn("deleting resources with dependents")
+
+	return nil
+}
+
+func (svc dependenciesService) graph(namespaceID uint64) (*Graph, error) {
+	ctx := auth.SetSuperUserContext(svc.ctx)
+
+	mm, _, err := svc.module.With(ctx).Find(types.ModuleFilter{NamespaceID: namespaceID})
+	if err != nil {
+		return nil, err
+	}
+
+	pp, _, err := svc.page.With(ctx).Find(types.PageFilter{NamespaceID: namespaceID})
+	if err != nil {
+		return nil, err
+	}
+
+	cc, _, err := svc.chart.With(ctx).Find(types.ChartFilter{NamespaceID: namespaceID})
+	if err != nil {
+		return nil, err
+	}
+
+	ss, _, err := svc.scripts.FindScripts(ctx, automation.ScriptFilter{NamespaceID: namespaceID})
+	if err != nil {
+		return nil, err
+	}
+
+	tt, _, err := svc.scripts.FindTriggers(ctx, automation.TriggerFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	return build(mm, pp, cc, ss, tt), nil
+}
+
+// policy returns policy for deleting resources with dependents
+func (svc dependenciesService) policy() string {
+	v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingPolicy, 0)
+	if err != nil || v == nil {
+		return PolicyWarn
+	}
+
+	var p string
+	if err = v.Value.Unmarshal(&p); err != nil || p != PolicyEnforce {
+		return PolicyWarn
+	}
+
+	return p
+}
+
+func (svc dependenciesService) readable(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanReadNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack().WithID("namespaceID", namespaceID)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package dependencies
+
+import (
+	"strconv"
+)
+
+type (
+	// Graph of namespace's resources, edges point from dependents to
+	// resources they depend on
+	Graph struct {
+		Nodes []*Node `json:"nodes"`
+		Edges []*Edge `json:"edges"`
+
+		nodes map[string]*Node
+
+		// Edges by the node they point to
+		in map[string][]*Edge
+	}
+
+	// Node is a module, field, page, chart or automation script
+	Node struct {
+		ID         string `json:"id"`
+		Kind       string `json:"kind"`
+		ResourceID uint64 `json:"resourceID,string"`
+
+		// Handle, name or title
+		Label string `json:"label"`
+
+		// Module of the field
+		ModuleID uint64 `json:"moduleID,string,omitempty"`
+	}
+
+	Edge struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Kind string `json:"kind"`
+
+		// Where the reference is, for example block kind or trigger event
+		Detail string `json:"detail,omitempty"`
+	}
+
+	// Impact of changing or deleting the resource
+	Impact struct {
+		Node *Node `json:"node"`
+
+		// Resources that depend on the node, directly (depth 1) or
+		// through other resources
+		Dependents []*Dependent `json:"dependents"`
+
+		// Resources the node depends on
+		Dependencies []*Edge `json:"dependencies"`
+	}
+
+	Dependent struct {
+		Node  *Node `json:"node"`
+		Depth int   `json:"depth"`
+
+		// Edge the dependent was reached by
+		Via *Edge `json:"via"`
+	}
+)
+
+const (
+	KindModule = "module"
+	KindField  = "field"
+	KindPage   = "page"
+	KindChart  = "chart"
+	KindScript = "script"
+
+	// Field belongs to the module, it is removed with it
+	EdgeField = "field"
+
+	// Record field references records of the module
+	EdgeReference = "reference"
+
+	// Page shows records of the module
+	EdgeRecordPage = "record-page"
+
+	// Page is child of the page
+	EdgeParent = "parent"
+
+	// Page block shows the module, its fields or the chart
+	EdgeBlock = "block"
+
+	// Chart reports on the module
+	EdgeReport = "report"
+
+	// Script is triggered by records of the module
+	EdgeTrigger = "trigger"
+
+	// Script source mentions the module or field
+	EdgeSource = "source"
+
+	// Deletion of resources with dependents is logged
+	PolicyWarn = "warn"
+
+	// Resources with dependents can not be deleted
+	PolicyEnforce = "enforce"
+
+	// Policy for deleting resources with dependents, warn by default
+	settingPolicy = "crust.dependencies.on-delete"
+
+	// Dependents listed in errors
+	maxListed = 10
+)
+
+var (
+	kinds = map[string]bool{
+		KindModule: true,
+		KindField:  true,
+		KindPage:   true,
+		KindChart:  true,
+		KindScript: true,
+	}
+)
+
+func nodeID(kind string, id uint64) string {
+	return kind + ":" + strconv.FormatUint(id, 10)
+}
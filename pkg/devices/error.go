@@ -0,0 +1,35 @@
+package devices
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	devicesError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrDeviceNotFound   = devicesError{"DeviceNotFound", fault.NotFound}
+	ErrDeviceRevoked    = devicesError{"DeviceRevoked", fault.Forbidden}
+	ErrInvalidChallenge = devicesError{"InvalidChallenge", fault.Invalid}
+	ErrInvalidCode      = devicesError{"InvalidCode", fault.Invalid}
+)
+
+func (e devicesError) Error() string {
+	return e.String()
+}
+
+func (e devicesError) String() string {
+	return "crust.devices." + e.name
+}
+
+func (e devicesError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e devicesError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,91 @@
+package devices
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+type (
+	// recorder buffers response, so that it can be replaced before it is sent
+	recorder struct {
+		header http.Header
+		status int
+		body   bytes.Buffer
+	}
+)
+
+// Middleware rejects tokens of revoked devices and records devices of internal logins
+//
+// When login from an untrusted device needs to be verified, login
+// response (with the token) is replaced with the challenge.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if DefaultDevices == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		svc := DefaultDevices.With(r.Context())
+
+		if token := rest.Token(r); token != "" && svc.Revoked(token) {
+			rest.Error(w, r, ErrDeviceRevoked.withStack())
+			return
+		}
+
+		if r.Method != http.MethodPost || !strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), loginPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		var rsp struct {
+			Response *LoginResponse `json:"response"`
+		}
+
+		if json.Unmarshal(rec.body.Bytes(), &rsp) != nil || rsp.Response == nil || rsp.Response.JWT == "" {
+			// Failed login
+			rec.flush(w)
+			return
+		}
+
+		c, err := svc.Login(r, rsp.Response.JWT)
+		if err != nil {
+			rest.Error(w, r, err)
+			return
+		} else if c != nil {
+			resputil.JSON(w, c)
+			return
+		}
+
+		rec.flush(w)
+	})
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (rec *recorder) flush(w http.ResponseWriter) {
+	for k, vv := range rec.header {
+		w.Header()[k] = vv
+	}
+
+	w.WriteHeader(rec.status)
+	_, _ = w.Write(rec.body.Bytes())
+}
@@ -0,0 +1,55 @@
+package devices
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200129000000.devices",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_device (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  fingerprint      CHAR(64)        NOT NULL,
+  user_agent       VARCHAR(255)    NOT NULL DEFAULT '',
+  last_ip          VARCHAR(45)     NOT NULL DEFAULT '',
+  trusted_until    DATETIME            NULL DEFAULT NULL,
+
+  first_seen_at    DATETIME        NOT NULL,
+  last_seen_at     DATETIME        NOT NULL,
+  revoked_at       DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  UNIQUE INDEX (rel_user, fingerprint)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_device_session (
+  token_hash       CHAR(64)        NOT NULL,
+  rel_device       BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  expires_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (token_hash),
+  INDEX (rel_device),
+  INDEX (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_device_challenge (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_device       BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  code_hash        CHAR(64)        NOT NULL,
+  attempts         INT UNSIGNED    NOT NULL DEFAULT 0,
+
+  expires_at       DATETIME        NOT NULL,
+  verified_at      DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,193 @@
+package devices
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) tableDevice() string {
+	return "crust_device"
+}
+
+func (r repository) tableSession() string {
+	return "crust_device_session"
+}
+
+func (r repository) tableChallenge() string {
+	return "crust_device_challenge"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_user",
+			"fingerprint",
+			"user_agent",
+			"last_ip",
+			"trusted_until",
+			"first_seen_at",
+			"last_seen_at",
+			"revoked_at",
+		).
+		From(r.tableDevice())
+}
+
+func (r repository) FindByID(deviceID uint64) (*Device, error) {
+	return r.findOne(squirrel.Eq{"id": deviceID})
+}
+
+// FindByFingerprint returns user's device, revoked ones included
+func (r repository) FindByFingerprint(userID uint64, fingerprint string) (*Device, error) {
+	return r.findOne(squirrel.Eq{"rel_user": userID, "fingerprint": fingerprint})
+}
+
+func (r repository) findOne(cnd squirrel.Sqlizer) (*Device, error) {
+	var (
+		d = &Device{}
+		q = r.query().Where(cnd)
+	)
+
+	if err := rh.FetchOne(r.db(), q, d); err != nil {
+		return nil, err
+	} else if d.ID == 0 {
+		return nil, ErrDeviceNotFound.withStack()
+	}
+
+	return d, nil
+}
+
+// Find returns user's active devices, most recently used first
+func (r repository) Find(userID uint64) (set DeviceSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_user": userID, "revoked_at": nil}).
+		OrderBy("last_seen_at DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Save(d *Device) (*Device, error) {
+	if d.ID == 0 {
+		d.ID = factory.Sonyflake.NextID()
+	}
+
+	return d, errors.WithStack(r.db().Replace(r.tableDevice(), d))
+}
+
+// Revoke marks device as revoked and removes its trust
+func (r repository) Revoke(deviceID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableDevice(),
+		rh.Set{"revoked_at": time.Now(), "trusted_until": nil},
+		squirrel.Eq{"id": deviceID},
+	)
+}
+
+func (r repository) CreateSession(s *session) error {
+	return errors.WithStack(r.db().Replace(r.tableSession(), s))
+}
+
+// FindSession returns session of the token, if it is known
+func (r repository) FindSession(tokenHash string) (*session, error) {
+	var (
+		s = &session{}
+		q = squirrel.
+			Select("token_hash", "rel_device", "rel_user", "expires_at").
+			From(r.tableSession()).
+			Where(squirrel.Eq{"token_hash": tokenHash})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil || s.TokenHash == "" {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// RevokedSessions returns hashes of unexpired tokens issued to revoked devices
+func (r repository) RevokedSessions(now time.Time) (hh map[string]time.Time, err error) {
+	var (
+		ss = []*session{}
+		q  = squirrel.
+			Select("s.token_hash", "s.rel_device", "s.rel_user", "s.expires_at").
+			From(r.tableSession() + " AS s").
+			Join(r.tableDevice() + " AS d ON (d.id = s.rel_device)").
+			Where(squirrel.Gt{"s.expires_at": now}).
+			Where(squirrel.NotEq{"d.revoked_at": nil})
+	)
+
+	if err = rh.FetchAll(r.db(), q, &ss); err != nil {
+		return
+	}
+
+	hh = map[string]time.Time{}
+	for _, s := range ss {
+		hh[s.TokenHash] = s.ExpiresAt
+	}
+
+	return
+}
+
+func (r repository) FindChallenge(challengeID uint64) (*Challenge, error) {
+	var (
+		c = &Challenge{}
+		q = squirrel.
+			Select("id", "rel_device", "rel_user", "code_hash", "attempts", "expires_at", "verified_at").
+			From(r.tableChallenge()).
+			Where(squirrel.Eq{"id": challengeID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, c); err != nil {
+		return nil, err
+	} else if c.ID == 0 {
+		return nil, ErrInvalidChallenge.withStack()
+	}
+
+	return c, nil
+}
+
+func (r repository) CreateChallenge(c *Challenge) (*Challenge, error) {
+	return c, errors.WithStack(r.db().Insert(r.tableChallenge(), c))
+}
+
+func (r repository) UpdateChallenge(c *Challenge) (*Challenge, error) {
+	return c, errors.WithStack(r.db().Replace(r.tableChallenge(), c))
+}
+
+// Prune removes expired sessions and challenges
+func (r repository) Prune(now time.Time) (err error) {
+	if err = rh.Delete(r.db(), r.tableSession(), squirrel.Lt{"expires_at": now}); err != nil {
+		return
+	}
+
+	return rh.Delete(r.db(), r.tableChallenge(), squirrel.Lt{"expires_at": now})
+}
@@ -0,0 +1,41 @@
+package devices
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts current user's device endpoints and login verification
+func MountRoutes(r chi.Router) {
+	// Login is verified before user has a token
+	r.Post("/verify", rest.Handler("Device.Verify", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			ChallengeID uint64 `json:"challengeID,string" validate:"required"`
+			Code        string `json:"code" validate:"required"`
+			Trust       bool   `json:"trust"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultDevices.With(r.Context()).Verify(r, body.ChallengeID, body.Code, body.Trust)
+	}))
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.MiddlewareValidOnly)
+
+		r.Get("/", rest.Handler("Device.List", func(r *http.Request) (interface{}, error) {
+			return DefaultDevices.With(r.Context()).Find(r)
+		}))
+
+		r.Delete("/{deviceID}", rest.Handler("Device.Revoke", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultDevices.With(r.Context()).Revoke(rest.ParamUint64(r, "deviceID"))
+		}))
+	})
+}
@@ -0,0 +1,440 @@
+package devices
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/rest"
+	"github.com/crusttech/crust-server/pkg/seclog"
+)
+
+type (
+	devicesService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		settings settingsGetter
+
+		repository *repository
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	DevicesService interface {
+		With(ctx context.Context) DevicesService
+
+		Find(r *http.Request) (DeviceSet, error)
+		Revoke(deviceID uint64) error
+
+		Login(r *http.Request, token string) (*Challenge, error)
+		Verify(r *http.Request, challengeID uint64, code string, trust bool) (*LoginResponse, error)
+
+		Revoked(token string) bool
+	}
+
+	// revokedTokens holds hashes of tokens issued to revoked devices, until they expire
+	revokedTokens struct {
+		sync.RWMutex
+		hashes map[string]time.Time
+	}
+)
+
+var (
+	DefaultDevices DevicesService
+
+	revoked = &revokedTokens{hashes: map[string]time.Time{}}
+
+	// now is used for device activity & trust and can be overridden
+	now = time.Now
+)
+
+// Init initializes device service and starts reloading revoked tokens in the background
+//
+// Must be called after security event log is initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &devicesService{
+		logger:   log,
+		settings: service.DefaultSettings,
+	}
+
+	DefaultDevices = svc.With(ctx)
+
+	svc.refresh(ctx)
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc devicesService) With(ctx context.Context) DevicesService {
+	return &devicesService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		settings: svc.settings,
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc devicesService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns current user's devices; device of the request is marked as current
+func (svc devicesService) Find(r *http.Request) (DeviceSet, error) {
+	set, err := svc.repository.Find(auth.GetIdentityFromContext(svc.ctx).Identity())
+	if err != nil {
+		return nil, err
+	}
+
+	if s, _ := svc.repository.FindSession(hash(rest.Token(r))); s != nil {
+		for _, d := range set {
+			d.Current = d.ID == s.DeviceID
+		}
+	}
+
+	return set, nil
+}
+
+// Revoke signs out the device and removes its trust
+//
+// Tokens issued to the device are rejected from then on
+func (svc devicesService) Revoke(deviceID uint64) error {
+	d, err := svc.repository.FindByID(deviceID)
+	if err != nil {
+		return err
+	}
+
+	if d.UserID != auth.GetIdentityFromContext(svc.ctx).Identity() {
+		return ErrDeviceNotFound.withStack()
+	}
+
+	if err = svc.repository.Revoke(d.ID); err != nil {
+		return err
+	}
+
+	svc.refresh(svc.ctx)
+
+	svc.record(&seclog.Event{Kind: eventKind, Action: "revoke", UserID: d.UserID, Meta: seclog.Meta{"deviceID": strconv.FormatUint(d.ID, 10)}})
+	return nil
+}
+
+// Login records device of a successful login
+//
+// When policy requires it and device is not trusted, challenge is returned
+// and the token must not be given to the client; login is finished with Verify.
+func (svc devicesService) Login(r *http.Request, token string) (*Challenge, error) {
+	identity, err := auth.DefaultJwtHandler.Decode(token)
+	if err != nil {
+		return nil, err
+	}
+
+	d, isNew, err := svc.seen(r, identity.Identity())
+	if err != nil {
+		return nil, err
+	}
+
+	e := seclog.FromRequest(r, eventKind, "login")
+	e.UserID, e.Meta = d.UserID, seclog.Meta{"deviceID": strconv.FormatUint(d.ID, 10), "new": isNew}
+
+	if svc.policy().requires(identity.Roles()) && !d.trusted(now()) {
+		c, err := svc.challenge(d)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Outcome = "challenged"
+		svc.record(e)
+		return c, nil
+	}
+
+	if err = svc.repository.CreateSession(newSession(d, token)); err != nil {
+		return nil, err
+	}
+
+	e.Outcome = "allowed"
+	svc.record(e)
+	return nil, nil
+}
+
+// Verify confirms login challenge with the code and issues the token
+//
+// Device is trusted for the configured number of days when requested
+func (svc devicesService) Verify(r *http.Request, challengeID uint64, code string, trust bool) (*LoginResponse, error) {
+	c, err := svc.repository.FindChallenge(challengeID)
+	if err != nil {
+		return nil, err
+	}
+
+	e := seclog.FromRequest(r, eventKind, "verify")
+	e.UserID, e.Meta = c.UserID, seclog.Meta{"deviceID": strconv.FormatUint(c.DeviceID, 10)}
+
+	n := now()
+	if err = c.verify(code, n); fault.Is(err, ErrInvalidCode) {
+		if _, uerr := svc.repository.UpdateChallenge(c); uerr != nil {
+			return nil, uerr
+		}
+
+		e.Outcome = "failed"
+		svc.record(e)
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	if _, err = svc.repository.UpdateChallenge(c); err != nil {
+		return nil, err
+	}
+
+	d, err := svc.repository.FindByID(c.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if days := svc.policy().TrustDays; trust && days > 0 {
+		until := n.AddDate(0, 0, int(days))
+		d.TrustedUntil = &until
+
+		if d, err = svc.repository.Save(d); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := auth.SetSuperUserContext(svc.ctx)
+
+	u, err := service.DefaultUser.With(ctx).FindByID(c.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = service.DefaultAuth.With(ctx).LoadRoleMemberships(u); err != nil {
+		return nil, err
+	}
+
+	rsp := &LoginResponse{
+		JWT:  auth.DefaultJwtHandler.Encode(u),
+		User: payload.User(u),
+	}
+
+	if err = svc.repository.CreateSession(newSession(d, rsp.JWT)); err != nil {
+		return nil, err
+	}
+
+	e.Outcome = "verified"
+	svc.record(e)
+	return rsp, nil
+}
+
+// Revoked checks if the token was issued to a revoked device
+func (svc devicesService) Revoked(token string) bool {
+	revoked.RLock()
+	defer revoked.RUnlock()
+
+	_, ok := revoked.hashes[hash(token)]
+	return ok
+}
+
+// seen updates (or creates) device of the request
+func (svc devicesService) seen(r *http.Request, userID uint64) (d *Device, isNew bool, err error) {
+	var (
+		fp = fingerprint(r, userID)
+		n  = now().Truncate(time.Second)
+	)
+
+	d, err = svc.repository.FindByFingerprint(userID, fp)
+	if err != nil && !fault.Is(err, ErrDeviceNotFound) {
+		return
+	}
+
+	if d == nil || d.RevokedAt != nil {
+		// Revoked devices start over, as new ones
+		isNew = true
+		d = &Device{ID: idOf(d), UserID: userID, Fingerprint: fp, FirstSeenAt: n}
+	}
+
+	d.UserAgent = r.UserAgent()
+	if len(d.UserAgent) > maxUserAgentSize {
+		d.UserAgent = d.UserAgent[:maxUserAgentSize]
+	}
+
+	d.LastIP = seclog.RemoteIP(r)
+	d.LastSeenAt = n
+
+	d, err = svc.repository.Save(d)
+	return
+}
+
+// challenge creates login challenge and sends the code to the user
+func (svc devicesService) challenge(d *Device) (*Challenge, error) {
+	code, err := generateCode()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Challenge{
+		ID:                   factory.Sonyflake.NextID(),
+		DeviceID:             d.ID,
+		UserID:               d.UserID,
+		CodeHash:             hash(code),
+		VerificationRequired: true,
+		ExpiresAt:            now().Add(challengeTTL).Truncate(time.Second),
+	}
+
+	if c, err = svc.repository.CreateChallenge(c); err != nil {
+		return nil, err
+	}
+
+	u, err := service.DefaultUser.With(auth.SetSuperUserContext(svc.ctx)).FindByID(d.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	m := mail.New()
+	m.SetAddressHeader("To", u.Email, u.Name)
+	m.SetHeader("Subject", "Login verification code")
+	m.SetBody("text/plain", fmt.Sprintf(
+		"Your verification code is %s\n\nIt expires in %d minutes. Login was attempted from:\n%s (%s)\n\n"+
+			"If this was not you, change your password.\n",
+		code, int(challengeTTL.Minutes()), d.UserAgent, d.LastIP,
+	))
+
+	if err = mail.Send(m); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// policy loads device policy from system settings
+func (svc devicesService) policy() (p Policy) {
+	v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingPolicy, 0)
+	if err != nil {
+		svc.log().Error("could not load device policy", zap.Error(err))
+	} else if v != nil {
+		if err = v.Value.Unmarshal(&p); err != nil {
+			svc.log().Error("could not decode device policy", zap.Error(err))
+		}
+	}
+
+	return
+}
+
+func (svc devicesService) record(e *seclog.Event) {
+	if err := seclog.DefaultSecLog.With(svc.ctx).Record(e); err != nil {
+		svc.log().Error("could not record security event", zap.Error(err))
+	}
+}
+
+// refresh reloads hashes of revoked tokens
+//
+// Reloaded periodically, so that revocations on other instances are picked up
+func (svc devicesService) refresh(ctx context.Context) {
+	hh, err := Repository(ctx, factory.Database.MustGet("system").With(ctx)).RevokedSessions(now())
+	if err != nil {
+		svc.logger.Error("could not load revoked sessions", zap.Error(err))
+		return
+	}
+
+	revoked.Lock()
+	revoked.hashes = hh
+	revoked.Unlock()
+}
+
+func (svc devicesService) watch(ctx context.Context) {
+	t := time.NewTicker(refreshInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.refresh(ctx)
+
+			if err := Repository(ctx, factory.Database.MustGet("system").With(ctx)).Prune(now()); err != nil {
+				svc.logger.Error("could not remove expired sessions", zap.Error(err))
+			}
+		}
+	}
+}
+
+func newSession(d *Device, token string) *session {
+	return &session{
+		TokenHash: hash(token),
+		DeviceID:  d.ID,
+		UserID:    d.UserID,
+		ExpiresAt: expiry(token),
+	}
+}
+
+// fingerprint identifies user's device by client's device ID or, when not
+// given, by user agent & language
+func fingerprint(r *http.Request, userID uint64) string {
+	var src = r.Header.Get(deviceHeader)
+	if src == "" {
+		src = r.UserAgent() + "\n" + r.Header.Get("Accept-Language")
+	}
+
+	return hash(strconv.FormatUint(userID, 10) + "\n" + src)
+}
+
+// expiry returns expiration time of the token
+func expiry(token string) time.Time {
+	c := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, c); err == nil {
+		if exp, ok := c["exp"].(float64); ok {
+			return time.Unix(int64(exp), 0)
+		}
+	}
+
+	return now().Add(24 * time.Hour)
+}
+
+func generateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", codeLength, n), nil
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func idOf(d *Device) uint64 {
+	if d == nil {
+		return 0
+	}
+
+	return d.ID
+}
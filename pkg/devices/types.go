@@ -0,0 +1,139 @@
+package devices
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"time"
+
+	"github.com/cortezaproject/corteza-server/pkg/payload/outgoing"
+)
+
+type (
+	// Device is a client (browser, app) user logged in from
+	Device struct {
+		ID     uint64 `json:"deviceID,string" db:"id"`
+		UserID uint64 `json:"userID,string" db:"rel_user" redact:"owner"`
+
+		// Hash of the client's device ID (X-Device-ID header) or user agent & language
+		Fingerprint string `json:"-" db:"fingerprint"`
+
+		UserAgent string `json:"userAgent" db:"user_agent" redact:"self|admin"`
+		LastIP    string `json:"lastIP" db:"last_ip" redact:"self|admin,mask"`
+
+		// Logins from trusted devices are not verified
+		TrustedUntil *time.Time `json:"trustedUntil,omitempty" db:"trusted_until"`
+
+		// Device current request is made from
+		Current bool `json:"current" db:"-"`
+
+		FirstSeenAt time.Time  `json:"firstSeenAt" db:"first_seen_at"`
+		LastSeenAt  time.Time  `json:"lastSeenAt" db:"last_seen_at"`
+		RevokedAt   *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+	}
+
+	DeviceSet []*Device
+
+	// Challenge is a pending verification of a login from a new or untrusted device
+	//
+	// Code is sent to user's email and must be confirmed to finish the login
+	Challenge struct {
+		ID       uint64 `json:"challengeID,string" db:"id"`
+		DeviceID uint64 `json:"-" db:"rel_device"`
+		UserID   uint64 `json:"-" db:"rel_user"`
+		CodeHash string `json:"-" db:"code_hash"`
+		Attempts uint   `json:"-" db:"attempts"`
+
+		VerificationRequired bool `json:"verificationRequired" db:"-"`
+
+		ExpiresAt  time.Time  `json:"expiresAt" db:"expires_at"`
+		VerifiedAt *time.Time `json:"-" db:"verified_at"`
+	}
+
+	// session links issued token with the device, so that the token can be revoked
+	session struct {
+		TokenHash string    `db:"token_hash"`
+		DeviceID  uint64    `db:"rel_device"`
+		UserID    uint64    `db:"rel_user"`
+		ExpiresAt time.Time `db:"expires_at"`
+	}
+
+	// Policy is stored in system settings, under settingPolicy
+	//
+	// Example:
+	//   { "trustDays": 30, "verifyRoles": ["1234567890"] }
+	Policy struct {
+		// How long verified device can be trusted; 0 disables trusting
+		TrustDays uint `json:"trustDays"`
+
+		// Members of these roles (or everyone) must verify logins from new or untrusted devices
+		VerifyRoles []string `json:"verifyRoles"`
+		VerifyAll   bool     `json:"verifyAll"`
+	}
+
+	// LoginResponse is the same as response of corteza's internal login
+	LoginResponse struct {
+		JWT  string         `json:"jwt"`
+		User *outgoing.User `json:"user"`
+	}
+)
+
+const (
+	settingPolicy = "crust.devices"
+
+	// Clients can send persistent device ID, otherwise devices are recognized by user agent
+	deviceHeader = "X-Device-ID"
+
+	// Internal login, relative to system app's root
+	loginPath = "/auth/internal/login"
+
+	// Kind of security log events
+	eventKind = "devices"
+
+	codeLength       = 6
+	challengeTTL     = 10 * time.Minute
+	maxCodeAttempts  = 5
+	maxUserAgentSize = 255
+
+	// How often revoked tokens are reloaded and expired sessions removed
+	refreshInterval = time.Minute
+)
+
+func (d Device) trusted(now time.Time) bool {
+	return d.TrustedUntil != nil && d.TrustedUntil.After(now)
+}
+
+// requires checks if member of the roles must verify logins from untrusted devices
+func (p Policy) requires(roles []uint64) bool {
+	if p.VerifyAll {
+		return true
+	}
+
+	for _, s := range p.VerifyRoles {
+		ID, _ := strconv.ParseUint(s, 10, 64)
+		for _, r := range roles {
+			if ID > 0 && ID == r {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// verify compares the code with the one that was sent
+//
+// Challenge can be verified once, before it expires and while there are
+// attempts left.
+func (c *Challenge) verify(code string, now time.Time) error {
+	if c.VerifiedAt != nil || c.ExpiresAt.Before(now) || c.Attempts >= maxCodeAttempts {
+		return ErrInvalidChallenge.withStack()
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.CodeHash), []byte(hash(code))) != 1 {
+		c.Attempts++
+		return ErrInvalidCode.withStack()
+	}
+
+	c.VerifiedAt = &now
+	return nil
+}
@@ -0,0 +1,90 @@
+package devices
+
+import (
+	"testing"
+	"time"
+
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+func TestChallengeRequired(t *testing.T) {
+	var (
+		at      = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+		later   = at.Add(time.Hour)
+		earlier = at.Add(-time.Hour)
+	)
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		roles   []uint64
+		trusted *time.Time
+		want    bool
+	}{
+		{"no policy", Policy{}, []uint64{1}, nil, false},
+		{"everyone", Policy{VerifyAll: true}, nil, nil, true},
+		{"member of verified role", Policy{VerifyRoles: []string{"2", "3"}}, []uint64{1, 3}, nil, true},
+		{"not member of verified role", Policy{VerifyRoles: []string{"2"}}, []uint64{1, 3}, nil, false},
+		{"invalid role ID", Policy{VerifyRoles: []string{"x"}}, []uint64{0}, nil, false},
+		{"trusted device", Policy{VerifyAll: true}, nil, &later, false},
+		{"trust expired", Policy{VerifyAll: true}, nil, &earlier, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Device{TrustedUntil: tt.trusted}
+			if got := tt.policy.requires(tt.roles) && !d.trusted(at); got != tt.want {
+				t.Errorf("expected challenge required to be %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChallengeVerify(t *testing.T) {
+	var (
+		created = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+		expires = created.Add(challengeTTL)
+	)
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		attempts uint
+		verified bool
+		err      error
+		left     uint
+	}{
+		{"valid code", "123456", created.Add(time.Minute), 0, false, nil, 0},
+		{"valid code after failed attempts", "123456", created.Add(time.Minute), maxCodeAttempts - 1, false, nil, maxCodeAttempts - 1},
+		{"invalid code", "654321", created.Add(time.Minute), 0, false, ErrInvalidCode, 1},
+		{"no attempts left", "123456", created.Add(time.Minute), maxCodeAttempts, false, ErrInvalidChallenge, maxCodeAttempts},
+		{"at expiration", "123456", expires, 0, false, nil, 0},
+		{"expired", "123456", expires.Add(time.Second), 0, false, ErrInvalidChallenge, 0},
+		{"already verified", "123456", created.Add(time.Minute), 0, true, ErrInvalidChallenge, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Challenge{CodeHash: hash("123456"), Attempts: tt.attempts, ExpiresAt: expires}
+			if tt.verified {
+				c.VerifiedAt = &created
+			}
+
+			err := c.verify(tt.code, tt.at)
+			if tt.err != nil {
+				if !fault.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+			} else if err != nil {
+				t.Fatalf("expected code to be valid, got %v", err)
+			} else if c.VerifiedAt == nil || !c.VerifiedAt.Equal(tt.at) {
+				t.Errorf("expected challenge to be verified at %v, got %v", tt.at, c.VerifiedAt)
+			}
+
+			if c.Attempts != tt.left {
+				t.Errorf("expected %d failed attempts, got %d", tt.left, c.Attempts)
+			}
+		})
+	}
+}
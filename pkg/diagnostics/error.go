@@ -0,0 +1,33 @@
+package diagnostics
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	diagnosticsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions  = diagnosticsError{"NoPermissions", fault.Forbidden}
+	ErrUnknownProfile = diagnosticsError{"UnknownProfile", fault.Invalid}
+)
+
+func (e diagnosticsError) Error() string {
+	return e.String()
+}
+
+func (e diagnosticsError) String() string {
+	return "crust.diagnostics." + e.name
+}
+
+func (e diagnosticsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e diagnosticsError) withStack() *fault.Error {
+	return fault.New(e)
+}
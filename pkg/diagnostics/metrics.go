@@ -0,0 +1,74 @@
+package diagnostics
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// Go collector of the default registry exports summary of GC pauses,
+	// histogram can be aggregated over instances
+	metricGCPauses = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "crust_runtime_gc_pause_seconds",
+		Help:    "Stop-the-world pauses of garbage collection.",
+		Buckets: []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+	})
+
+	metricGoroutinesPeak = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "crust_runtime_goroutines_peak",
+		Help: "Highest number of goroutines seen since the start.",
+	})
+
+	metricHeapObjects = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "crust_runtime_heap_objects",
+		Help: "Number of allocated heap objects.",
+	})
+)
+
+const (
+	// How often runtime stats are sampled
+	sampleInterval = 10 * time.Second
+)
+
+func registerMetrics() {
+	prometheus.MustRegister(
+		metricGCPauses,
+		metricGoroutinesPeak,
+		metricHeapObjects,
+	)
+}
+
+// sampler observes GC pauses since the last sample
+//
+// Runtime keeps the last 256 pauses; pauses between samples of a very
+// busy collector are lost.
+type sampler struct {
+	numGC uint32
+	peak  int
+}
+
+func (s *sampler) sample() {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	n := ms.NumGC - s.numGC
+	if n > uint32(len(ms.PauseNs)) {
+		n = uint32(len(ms.PauseNs))
+	}
+
+	for i := uint32(0); i < n; i++ {
+		p := ms.PauseNs[(ms.NumGC-i+255)%uint32(len(ms.PauseNs))]
+		metricGCPauses.Observe(time.Duration(p).Seconds())
+	}
+
+	s.numGC = ms.NumGC
+
+	if g := runtime.NumGoroutine(); g > s.peak {
+		s.peak = g
+		metricGoroutinesPeak.Set(float64(g))
+	}
+
+	metricHeapObjects.Set(float64(ms.HeapObjects))
+}
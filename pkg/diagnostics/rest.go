@@ -0,0 +1,97 @@
+package diagnostics
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountComposeRoutes mounts diagnostics of compose, see DiagnosticsService.Allowed
+func MountComposeRoutes(r chi.Router) {
+	mount(r, "compose")
+}
+
+// MountSystemRoutes mounts diagnostics of system, see DiagnosticsService.Allowed
+func MountSystemRoutes(r chi.Router) {
+	mount(r, "system")
+}
+
+// MountMessagingRoutes mounts diagnostics of messaging, see DiagnosticsService.Allowed
+func MountMessagingRoutes(r chi.Router) {
+	mount(r, "messaging")
+}
+
+func mount(r chi.Router, app string) {
+	r.Use(auth.MiddlewareValidOnly)
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := DefaultDiagnostics.With(r.Context()).Allowed(); err != nil {
+				rest.Error(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	})
+
+	r.Get("/runtime", rest.Handler("Diagnostics.Runtime", func(r *http.Request) (interface{}, error) {
+		return DefaultDiagnostics.With(r.Context()).Runtime(), nil
+	}))
+
+	// Same as net/http/pprof under /debug/pprof/
+	r.Get("/pprof/", pprof.Index)
+	r.Get("/pprof/cmdline", pprof.Cmdline)
+	r.Get("/pprof/profile", pprof.Profile)
+	r.Get("/pprof/symbol", pprof.Symbol)
+	r.Post("/pprof/symbol", pprof.Symbol)
+	r.Get("/pprof/trace", pprof.Trace)
+	r.Get("/pprof/{profile}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(chi.URLParam(r, "profile")).ServeHTTP(w, r)
+	})
+
+	// Stacks of all goroutines, as text
+	r.Get("/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_ = profile("goroutine", 2)(w)
+	})
+
+	// ?gc=true collects garbage first, profile has only live objects
+	r.Get("/heap", func(w http.ResponseWriter, r *http.Request) {
+		if rest.QueryBool(r, "gc") {
+			runtime.GC()
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="heap.pprof"`)
+		_ = profile("heap", 0)(w)
+	})
+
+	// ?seconds=10 sets length of CPU profile, 0 skips it
+	r.Get("/bundle", func(w http.ResponseWriter, r *http.Request) {
+		seconds := defaultProfileSeconds
+		if s := r.URL.Query().Get("seconds"); s != "" {
+			seconds, _ = strconv.Atoi(s)
+		}
+
+		if seconds < 0 {
+			seconds = 0
+		} else if seconds > maxProfileSeconds {
+			seconds = maxProfileSeconds
+		}
+
+		name := "diagnostics-" + app + "-" + time.Now().UTC().Format("20060102T150405Z") + ".zip"
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+
+		// Headers are sent, errors can only be logged
+		_ = DefaultDiagnostics.With(r.Context()).Bundle(w, time.Duration(seconds)*time.Second)
+	})
+}
@@ -0,0 +1,286 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/version"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	diagnosticsService struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	DiagnosticsService interface {
+		With(ctx context.Context) DiagnosticsService
+
+		Allowed() error
+		Runtime() *Runtime
+		Bundle(w io.Writer, cpu time.Duration) error
+	}
+)
+
+var (
+	DefaultDiagnostics DiagnosticsService
+
+	startedAt = time.Now()
+
+	once sync.Once
+)
+
+// InitCompose initializes diagnostics of compose
+//
+// Must be called after compose services are initialized
+func InitCompose(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log)
+}
+
+// InitSystem initializes diagnostics of system
+//
+// Must be called after system services are initialized
+func InitSystem(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log)
+}
+
+// InitMessaging initializes diagnostics of messaging
+//
+// Must be called after messaging services are initialized
+func InitMessaging(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log)
+}
+
+func start(ctx context.Context, log *zap.Logger) error {
+	// Apps of the monolith share the process
+	once.Do(func() {
+		DefaultDiagnostics = (&diagnosticsService{logger: log}).with(ctx)
+
+		registerMetrics()
+		go sample(ctx)
+	})
+
+	return nil
+}
+
+func sample(ctx context.Context) {
+	var (
+		s = &sampler{}
+		t = time.NewTicker(sampleInterval)
+	)
+
+	defer t.Stop()
+
+	for {
+		s.sample()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (svc diagnosticsService) With(ctx context.Context) DiagnosticsService {
+	return svc.with(ctx)
+}
+
+func (svc diagnosticsService) with(ctx context.Context) *diagnosticsService {
+	return &diagnosticsService{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc diagnosticsService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Allowed checks if the current user can get diagnostics
+//
+// Profiles and dumps expose internals of the whole process, secrets and
+// credentials included, so diagnostics of every app are available only to
+// those that can grant system permissions. Servers that run without system
+// services (standalone compose and messaging) do not serve them.
+func (svc diagnosticsService) Allowed() error {
+	if ac := systemService.DefaultAccessControl; ac == nil || !ac.CanGrant(svc.ctx) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// Runtime returns current runtime stats
+func (svc diagnosticsService) Runtime() *Runtime {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	r := &Runtime{
+		Version:    version.Version,
+		GoVersion:  runtime.Version(),
+		StartedAt:  startedAt,
+		Uptime:     time.Since(startedAt).Round(time.Second).String(),
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Goroutines: runtime.NumGoroutine(),
+		CgoCalls:   runtime.NumCgoCall(),
+		Memory: Memory{
+			Alloc:       ms.Alloc,
+			TotalAlloc:  ms.TotalAlloc,
+			Sys:         ms.Sys,
+			HeapAlloc:   ms.HeapAlloc,
+			HeapInuse:   ms.HeapInuse,
+			HeapIdle:    ms.HeapIdle,
+			HeapObjects: ms.HeapObjects,
+			StackInuse:  ms.StackInuse,
+		},
+		GC: GC{
+			NumGC:        ms.NumGC,
+			NextGC:       ms.NextGC,
+			PauseTotal:   time.Duration(ms.PauseTotalNs).String(),
+			CPUFraction:  ms.GCCPUFraction,
+			RecentPauses: []string{},
+		},
+	}
+
+	if ms.LastGC > 0 {
+		t := time.Unix(0, int64(ms.LastGC))
+		r.GC.LastGC = &t
+	}
+
+	for i := uint32(0); i < ms.NumGC && i < recentPauses; i++ {
+		p := ms.PauseNs[(ms.NumGC-i+255)%uint32(len(ms.PauseNs))]
+		r.GC.RecentPauses = append(r.GC.RecentPauses, time.Duration(p).String())
+	}
+
+	return r
+}
+
+// Bundle writes zip with runtime stats, profiles, goroutine dump and metrics
+//
+// CPU is profiled for the given duration (none when zero), other profiles
+// are snapshots. Heap profile is taken after garbage collection.
+func (svc diagnosticsService) Bundle(w io.Writer, cpu time.Duration) error {
+	var (
+		z = zip.NewWriter(w)
+
+		files = []struct {
+			name  string
+			write func(io.Writer) error
+		}{
+			{"runtime.json", func(w io.Writer) error {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(svc.Runtime())
+			}},
+			{"goroutines.txt", profile("goroutine", 2)},
+			{"heap.pprof", func(w io.Writer) error {
+				runtime.GC()
+				return profile("heap", 0)(w)
+			}},
+			{"allocs.pprof", profile("allocs", 0)},
+			{"block.pprof", profile("block", 0)},
+			{"mutex.pprof", profile("mutex", 0)},
+			{"threadcreate.pprof", profile("threadcreate", 0)},
+			{"metrics.txt", metrics},
+		}
+	)
+
+	if cpu > 0 {
+		// CPU profile first, other files do not skew it
+		if err := svc.write(z, "cpu.pprof", func(w io.Writer) error { return cpuProfile(svc.ctx, w, cpu) }); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range files {
+		if err := svc.write(z, f.name, f.write); err != nil {
+			return err
+		}
+	}
+
+	svc.log(zap.Duration("cpu", cpu)).Info("diagnostics bundle captured")
+
+	return z.Close()
+}
+
+// write adds the file to the zip
+//
+// Files that fail are replaced with the error, the rest of the bundle is still useful.
+func (svc diagnosticsService) write(z *zip.Writer, name string, write func(io.Writer) error) error {
+	buf := &bytes.Buffer{}
+	if err := write(buf); err != nil {
+		svc.log(zap.String("file", name), zap.Error(err)).Warn("could not capture diagnostics")
+
+		buf.Reset()
+		buf.WriteString(err.Error() + "\n")
+		name += ".error"
+	}
+
+	f, err := z.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = buf.WriteTo(f)
+	return err
+}
+
+func profile(name string, debug int) func(io.Writer) error {
+	return func(w io.Writer) error {
+		p := pprof.Lookup(name)
+		if p == nil {
+			return ErrUnknownProfile.withStack()
+		}
+
+		return p.WriteTo(w, debug)
+	}
+}
+
+// cpuProfile profiles CPU for the duration, less when context is done
+func cpuProfile(ctx context.Context, w io.Writer, d time.Duration) error {
+	if err := pprof.StartCPUProfile(w); err != nil {
+		return err
+	}
+
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+
+	pprof.StopCPUProfile()
+	return nil
+}
+
+// metrics writes all registered metrics in Prometheus text format
+func metrics(w io.Writer) error {
+	mf, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	enc := expfmt.NewEncoder(w, expfmt.FmtText)
+	for _, m := range mf {
+		if err = enc.Encode(m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package diagnostics
+
+import (
+	"time"
+)
+
+type (
+	// Runtime stats of the process
+	Runtime struct {
+		Version    string    `json:"version"`
+		GoVersion  string    `json:"goVersion"`
+		StartedAt  time.Time `json:"startedAt"`
+		Uptime     string    `json:"uptime"`
+		NumCPU     int       `json:"numCPU"`
+		GOMAXPROCS int       `json:"gomaxprocs"`
+		Goroutines int       `json:"goroutines"`
+		CgoCalls   int64     `json:"cgoCalls"`
+		Memory     Memory    `json:"memory"`
+		GC         GC        `json:"gc"`
+	}
+
+	Memory struct {
+		Alloc       uint64 `json:"alloc"`
+		TotalAlloc  uint64 `json:"totalAlloc"`
+		Sys         uint64 `json:"sys"`
+		HeapAlloc   uint64 `json:"heapAlloc"`
+		HeapInuse   uint64 `json:"heapInuse"`
+		HeapIdle    uint64 `json:"heapIdle"`
+		HeapObjects uint64 `json:"heapObjects"`
+		StackInuse  uint64 `json:"stackInuse"`
+	}
+
+	GC struct {
+		NumGC       uint32     `json:"numGC"`
+		LastGC      *time.Time `json:"lastGC,omitempty"`
+		NextGC      uint64     `json:"nextGC"`
+		PauseTotal  string     `json:"pauseTotal"`
+		CPUFraction float64    `json:"cpuFraction"`
+
+		// Recent pauses, newest first
+		RecentPauses []string `json:"recentPauses"`
+	}
+)
+
+const (
+	// CPU profile length of bundles, when not given
+	defaultProfileSeconds = 10
+	maxProfileSeconds     = 60
+
+	recentPauses = 10
+)
@@ -0,0 +1,242 @@
+package drift
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+)
+
+type (
+	// handles of namespace's resources by their IDs
+	handles struct {
+		modules map[uint64]string
+		fields  map[uint64]string
+		pages   map[uint64]string
+		scripts map[uint64]string
+		roles   map[uint64]string
+	}
+)
+
+// bundle makes bundle of the namespace
+//
+// Resources are read as they are, callers check permissions.
+func (svc driftService) bundle(ctx context.Context, ns *types.Namespace) (*Bundle, error) {
+	var (
+		b = &Bundle{
+			Namespace:   ns.Slug,
+			Modules:     map[string]*Module{},
+			Pages:       map[string]*Page{},
+			Scripts:     map[string]*Script{},
+			Permissions: map[string]string{},
+		}
+
+		h = &handles{
+			modules: map[uint64]string{},
+			fields:  map[uint64]string{},
+			pages:   map[uint64]string{},
+			scripts: map[uint64]string{},
+			roles:   svc.roles(svc.ctx),
+		}
+	)
+
+	mm, _, err := svc.module.With(ctx).Find(types.ModuleFilter{NamespaceID: ns.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	pp, _, err := svc.page.With(ctx).Find(types.PageFilter{NamespaceID: ns.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	ss, _, err := svc.scripts.FindScripts(ctx, automation.ScriptFilter{NamespaceID: ns.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	tt, _, err := svc.scripts.FindTriggers(ctx, automation.TriggerFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	rr, err := svc.repository.Rules()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range mm {
+		h.modules[m.ID] = key("module", m.ID, m.Handle)
+		for _, f := range m.Fields {
+			h.fields[f.ID] = h.modules[m.ID] + "." + f.Name
+		}
+	}
+
+	for _, p := range pp {
+		h.pages[p.ID] = key("page", p.ID, p.Handle)
+	}
+
+	for _, s := range ss {
+		h.scripts[s.ID] = key("script", s.ID, s.Name)
+	}
+
+	for _, m := range mm {
+		bm := &Module{Name: m.Name, Fields: map[string]*Field{}}
+		if len(m.Meta) > 0 && m.Meta.String() != "{}" {
+			bm.Meta = []byte(m.Meta)
+		}
+
+		for _, f := range m.Fields {
+			bm.Fields[f.Name] = &Field{
+				Kind:         f.Kind,
+				Label:        f.Label,
+				Place:        f.Place,
+				Options:      h.options(f.Options),
+				Private:      f.Private,
+				Required:     f.Required,
+				Visible:      f.Visible,
+				Multi:        f.Multi,
+				DefaultValue: f.DefaultValue,
+			}
+		}
+
+		b.Modules[h.modules[m.ID]] = bm
+	}
+
+	for _, p := range pp {
+		bp := &Page{
+			Title:       p.Title,
+			Description: p.Description,
+			Parent:      h.pages[p.SelfID],
+			Module:      h.modules[p.ModuleID],
+			Visible:     p.Visible,
+			Blocks:      make(types.PageBlocks, len(p.Blocks)),
+		}
+
+		for i, block := range p.Blocks {
+			block.Options = h.options(block.Options)
+			bp.Blocks[i] = block
+		}
+
+		b.Pages[h.pages[p.ID]] = bp
+	}
+
+	for _, s := range ss {
+		bs := &Script{
+			Source:   s.Source,
+			Async:    s.Async,
+			RunInUA:  s.RunInUA,
+			Critical: s.Critical,
+			Enabled:  s.Enabled,
+			Timeout:  s.Timeout,
+		}
+
+		for _, t := range tt {
+			if t.ScriptID != s.ID || t.DeletedAt != nil {
+				continue
+			}
+
+			bt := &Trigger{Event: t.Event, Resource: t.Resource, Condition: t.Condition, Enabled: t.Enabled}
+			if moduleID, _ := strconv.ParseUint(t.Condition, 10, 64); h.modules[moduleID] != "" {
+				bt.Condition = h.modules[moduleID]
+			}
+
+			bs.Triggers = append(bs.Triggers, bt)
+		}
+
+		b.Scripts[h.scripts[s.ID]] = bs
+	}
+
+	for _, r := range rr {
+		if r.Access == permissions.Inherit {
+			continue
+		}
+
+		res, ok := h.resource(ns, r.Resource)
+		if !ok {
+			// Resource of another namespace
+			continue
+		}
+
+		role, ok := h.roles[r.RoleID]
+		if !ok {
+			role = strconv.FormatUint(r.RoleID, 10)
+		}
+
+		b.Permissions[role+" "+string(r.Operation)+" "+res] = r.Access.String()
+	}
+
+	return b, nil
+}
+
+// resource returns the permission resource with handles instead of IDs
+//
+// Resources of other namespaces (and charts) are not in the bundle.
+func (h handles) resource(ns *types.Namespace, r permissions.Resource) (string, bool) {
+	if !r.IsAppendable() || r.HasWildcard() {
+		return r.String(), true
+	}
+
+	id, _ := strconv.ParseUint(strings.TrimPrefix(r.String(), r.TrimID().String()), 10, 64)
+
+	var hh map[uint64]string
+	switch r.TrimID() {
+	case types.NamespacePermissionResource:
+		return r.TrimID().String() + ns.Slug, id == ns.ID
+	case types.ModulePermissionResource:
+		hh = h.modules
+	case types.ModuleFieldPermissionResource:
+		hh = h.fields
+	case types.PagePermissionResource:
+		hh = h.pages
+	case types.AutomationScriptPermissionResource:
+		hh = h.scripts
+	}
+
+	handle, ok := hh[id]
+	return r.TrimID().String() + handle, ok
+}
+
+// options returns copy of field or block options with references replaced by handles
+//
+// moduleID and pageID options are replaced with module and page options.
+func (h handles) options(in map[string]interface{}) map[string]interface{} {
+	if len(in) == 0 {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+
+	ref := func(name string, hh map[uint64]string) {
+		s, ok := out[name+"ID"].(string)
+		if !ok {
+			return
+		}
+
+		id, _ := strconv.ParseUint(s, 10, 64)
+		if handle, ok := hh[id]; ok {
+			delete(out, name+"ID")
+			out[name] = handle
+		}
+	}
+
+	ref("module", h.modules)
+	ref("page", h.pages)
+
+	return out
+}
+
+// key returns handle of the resource, kind and ID when it has none
+func key(kind string, id uint64, handle string) string {
+	if handle != "" {
+		return handle
+	}
+
+	return kind + "-" + strconv.FormatUint(id, 10)
+}
@@ -0,0 +1,62 @@
+package drift
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// envelope is corteza's response format
+	envelope struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Response json.RawMessage `json:"response"`
+	}
+)
+
+var (
+	httpClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// fetch reads bundle of the remote namespace, with remote user's token
+func (r Remote) fetch(ctx context.Context) (*Bundle, error) {
+	u, err := url.Parse(r.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || r.NamespaceID == 0 {
+		return nil, ErrInvalidRemote.withStack()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/namespace/%d/drift/bundle", strings.TrimSuffix(r.BaseURL, "/"), r.NamespaceID), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+r.Token)
+
+	rsp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, ErrRemoteUnavailable.withStack().WithMessage(fmt.Sprintf("could not reach %s: %v", u.Host, err))
+	}
+
+	defer rsp.Body.Close()
+
+	// Errors are sent in the envelope, with status of the error
+	var e envelope
+	if err = json.NewDecoder(rsp.Body).Decode(&e); err != nil || (rsp.StatusCode != http.StatusOK && e.Error == nil) {
+		return nil, ErrRemoteUnavailable.withStack().WithMessage(fmt.Sprintf("%s responded with unexpected status: %s", u.Host, rsp.Status))
+	}
+
+	if e.Error != nil {
+		return nil, ErrRemoteUnavailable.withStack().WithMessage(fmt.Sprintf("%s responded with error: %s", u.Host, e.Error.Message))
+	}
+
+	b := &Bundle{}
+	return b, errors.WithStack(json.Unmarshal(e.Response, b))
+}
@@ -0,0 +1,154 @@
+package drift
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type (
+	entry struct {
+		kind  string
+		key   string
+		value interface{}
+	}
+)
+
+var (
+	// Order of kinds in the report
+	kinds = map[string]int{
+		KindModule:     0,
+		KindField:      1,
+		KindPage:       2,
+		KindScript:     3,
+		KindPermission: 4,
+	}
+)
+
+// compare returns drift of local bundle from the reference
+//
+// Fields of modules that are missing or extra are not reported separately.
+func compare(local, reference *Bundle) ([]*Drift, error) {
+	var (
+		le = local.entries()
+		re = reference.entries()
+
+		out = make([]*Drift, 0)
+	)
+
+	for k, r := range re {
+		if l, ok := le[k]; ok {
+			d, err := changed(l, r)
+			if err != nil {
+				return nil, err
+			} else if d != nil {
+				out = append(out, d)
+			}
+		} else if r.kind != KindField || local.Modules[module(r.key)] != nil {
+			out = append(out, &Drift{Kind: r.kind, Key: r.key, Status: StatusMissing, Reference: r.value})
+		}
+	}
+
+	for k, l := range le {
+		if _, ok := re[k]; !ok && (l.kind != KindField || reference.Modules[module(l.key)] != nil) {
+			out = append(out, &Drift{Kind: l.kind, Key: l.key, Status: StatusExtra, Local: l.value})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return kinds[out[i].Kind] < kinds[out[j].Kind]
+		}
+
+		return out[i].Key < out[j].Key
+	})
+
+	return out, nil
+}
+
+// changed compares JSON representations of the entries
+//
+// Objects are compared by attributes, changed ones are listed.
+func changed(local, reference *entry) (*Drift, error) {
+	l, err := normalize(local.value)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := normalize(reference.value)
+	if err != nil {
+		return nil, err
+	}
+
+	if reflect.DeepEqual(l, r) {
+		return nil, nil
+	}
+
+	d := &Drift{Kind: local.kind, Key: local.key, Status: StatusChanged, Local: l, Reference: r}
+
+	lm, lok := l.(map[string]interface{})
+	rm, rok := r.(map[string]interface{})
+	if lok && rok {
+		for a := range lm {
+			if !reflect.DeepEqual(lm[a], rm[a]) {
+				d.Attributes = append(d.Attributes, a)
+			}
+		}
+
+		for a := range rm {
+			if _, ok := lm[a]; !ok {
+				d.Attributes = append(d.Attributes, a)
+			}
+		}
+
+		sort.Strings(d.Attributes)
+	}
+
+	return d, nil
+}
+
+// entries returns resources of the bundle by kind and key
+//
+// Module fields are separate entries, keyed by "<module>.<field>".
+func (b *Bundle) entries() map[string]*entry {
+	out := map[string]*entry{}
+	add := func(kind, key string, value interface{}) {
+		out[kind+":"+key] = &entry{kind: kind, key: key, value: value}
+	}
+
+	for h, m := range b.Modules {
+		add(KindModule, h, &Module{Name: m.Name, Meta: m.Meta})
+		for name, f := range m.Fields {
+			add(KindField, h+"."+name, f)
+		}
+	}
+
+	for h, p := range b.Pages {
+		add(KindPage, h, p)
+	}
+
+	for name, s := range b.Scripts {
+		add(KindScript, name, s)
+	}
+
+	for rule, access := range b.Permissions {
+		add(KindPermission, rule, access)
+	}
+
+	return out
+}
+
+func normalize(v interface{}) (out interface{}, err error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, json.Unmarshal(raw, &out)
+}
+
+// module returns module handle of the field key
+func module(field string) string {
+	return field[:strings.Index(field, ".")]
+}
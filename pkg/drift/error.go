@@ -0,0 +1,35 @@
+package drift
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	driftError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions     = driftError{"NoPermissions", fault.Forbidden}
+	ErrNothingToCompare  = driftError{"NothingToCompare", fault.Invalid}
+	ErrInvalidRemote     = driftError{"InvalidRemote", fault.Invalid}
+	ErrRemoteUnavailable = driftError{"RemoteUnavailable", fault.Unavailable}
+)
+
+func (e driftError) Error() string {
+	return e.String()
+}
+
+func (e driftError) String() string {
+	return "crust.drift." + e.name
+}
+
+func (e driftError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e driftError) withStack() *fault.Error {
+	return fault.New(e)
+}
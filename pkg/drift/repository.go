@@ -0,0 +1,46 @@
+package drift
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "compose_permission_rules"
+}
+
+// Rules returns all compose permission rules
+func (r repository) Rules() (rr []*permissions.Rule, err error) {
+	q := squirrel.
+		Select("rel_role", "resource", "operation", "access").
+		From(r.table())
+
+	return rr, rh.FetchAll(r.db(), q, &rr)
+}
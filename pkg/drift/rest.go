@@ -0,0 +1,40 @@
+package drift
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts drift endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Read by other instances when they compare with this one
+	r.Get("/bundle", rest.Handler("Drift.Bundle", func(r *http.Request) (interface{}, error) {
+		return DefaultDrift.With(r.Context()).Bundle(
+			rest.ParamUint64(r, "namespaceID"),
+		)
+	}))
+
+	// Compares with the bundle or remote instance in the body:
+	//
+	//	{"bundle": {...}}
+	//	{"remote": {"baseURL": "https://stage.example.com/api/compose", "token": "...", "namespaceID": "..."}}
+	r.Post("/", rest.Handler("Drift.Compare", func(r *http.Request) (interface{}, error) {
+		ref := &Reference{}
+		if err := rest.Decode(r, ref); err != nil {
+			return nil, err
+		}
+
+		return DefaultDrift.With(r.Context()).Compare(
+			rest.ParamUint64(r, "namespaceID"),
+			ref,
+		)
+	}))
+}
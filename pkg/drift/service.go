@@ -0,0 +1,174 @@
+package drift
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+)
+
+type (
+	driftService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+		page      service.PageService
+		scripts   scriptFinder
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	scriptFinder interface {
+		FindScripts(context.Context, automation.ScriptFilter) (automation.ScriptSet, automation.ScriptFilter, error)
+		FindTriggers(context.Context, automation.TriggerFilter) (automation.TriggerSet, automation.TriggerFilter, error)
+	}
+
+	DriftService interface {
+		With(ctx context.Context) DriftService
+
+		Bundle(namespaceID uint64) (*Bundle, error)
+		Compare(namespaceID uint64, ref *Reference) (*Report, error)
+	}
+)
+
+var (
+	DefaultDrift DriftService
+)
+
+// Init initializes drift service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultDrift = (&driftService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+		page:      service.DefaultPage,
+		scripts:   service.DefaultInternalAutomationManager,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc driftService) With(ctx context.Context) DriftService {
+	return &driftService{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:        svc.ac,
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module,
+		page:      svc.page,
+		scripts:   svc.scripts,
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc driftService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Bundle returns configuration of the namespace, for comparing on other instances
+//
+// Bundle holds automation sources and permissions, it is available only
+// to users that can manage the namespace.
+func (svc driftService) Bundle(namespaceID uint64) (*Bundle, error) {
+	ns, err := svc.loadNamespace(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.bundle(auth.SetSuperUserContext(svc.ctx), ns)
+}
+
+// Compare reports drift of the namespace from the bundle or remote instance
+func (svc driftService) Compare(namespaceID uint64, ref *Reference) (*Report, error) {
+	ns, err := svc.loadNamespace(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reference = ref.Bundle
+	switch {
+	case ref.Remote != nil:
+		if reference, err = ref.Remote.fetch(svc.ctx); err != nil {
+			return nil, err
+		}
+	case reference == nil:
+		return nil, ErrNothingToCompare.withStack()
+	}
+
+	local, err := svc.bundle(auth.SetSuperUserContext(svc.ctx), ns)
+	if err != nil {
+		return nil, err
+	}
+
+	dd, err := compare(local, reference)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("namespaceID", ns.ID), zap.Int("drift", len(dd))).Debug("namespace compared")
+
+	return &Report{Namespace: ns.Slug, InSync: len(dd) == 0, Drift: dd}, nil
+}
+
+func (svc driftService) loadNamespace(namespaceID uint64) (*types.Namespace, error) {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return nil, ErrNoPermissions.withStack().WithID("namespaceID", namespaceID)
+	}
+
+	return ns, nil
+}
+
+// roles returns handles of roles, built-in ones are known without system service
+//
+// Roles are read with user's token, rules of roles without handles are keyed by IDs.
+func (svc driftService) roles(ctx context.Context) map[uint64]string {
+	out := map[uint64]string{
+		permissions.EveryoneRoleID: "everyone",
+		permissions.AdminsRoleID:   "admins",
+	}
+
+	if service.DefaultSystemRole == nil {
+		return out
+	}
+
+	rr, err := service.DefaultSystemRole.Find(ctx)
+	if err != nil {
+		svc.log(zap.Error(err)).Warn("could not load roles")
+		return out
+	}
+
+	for _, r := range rr {
+		if r.Handle != "" {
+			out[r.ID] = r.Handle
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,133 @@
+package drift
+
+import (
+	"encoding/json"
+
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Bundle is configuration of the namespace
+	//
+	// Resources are keyed and referenced by handles (names of fields and
+	// scripts) instead of IDs, so that bundles of different instances
+	// can be compared. Resources without handles are keyed by IDs and
+	// show up as drift on other instances.
+	Bundle struct {
+		Namespace string `json:"namespace"`
+
+		Modules map[string]*Module `json:"modules"`
+		Pages   map[string]*Page   `json:"pages"`
+		Scripts map[string]*Script `json:"scripts"`
+
+		// Access ("allow", "deny") by rule: "<role> <operation> <resource>";
+		// roles by handles, resources of the namespace by handles of their kind
+		Permissions map[string]string `json:"permissions"`
+	}
+
+	Module struct {
+		Name   string            `json:"name"`
+		Meta   json.RawMessage   `json:"meta,omitempty"`
+		Fields map[string]*Field `json:"fields,omitempty"`
+	}
+
+	Field struct {
+		Kind  string `json:"kind"`
+		Label string `json:"label"`
+		Place int    `json:"place"`
+
+		// Module references are replaced with module handles
+		Options composeTypes.ModuleFieldOptions `json:"options,omitempty"`
+
+		Private  bool `json:"isPrivate"`
+		Required bool `json:"isRequired"`
+		Visible  bool `json:"isVisible"`
+		Multi    bool `json:"isMulti"`
+
+		DefaultValue composeTypes.RecordValueSet `json:"defaultValue,omitempty"`
+	}
+
+	Page struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+
+		// Handles of the parent page and page's module
+		Parent string `json:"parent,omitempty"`
+		Module string `json:"module,omitempty"`
+
+		Visible bool `json:"visible"`
+
+		// Module and page references in block options are replaced with handles
+		Blocks composeTypes.PageBlocks `json:"blocks"`
+	}
+
+	Script struct {
+		Source   string `json:"source"`
+		Async    bool   `json:"async"`
+		RunInUA  bool   `json:"runInUA"`
+		Critical bool   `json:"critical"`
+		Enabled  bool   `json:"enabled"`
+		Timeout  uint   `json:"timeout"`
+
+		Triggers []*Trigger `json:"triggers,omitempty"`
+	}
+
+	Trigger struct {
+		Event    string `json:"event"`
+		Resource string `json:"resource"`
+
+		// Module handle for record triggers
+		Condition string `json:"condition,omitempty"`
+		Enabled   bool   `json:"enabled"`
+	}
+
+	// Remote instance to compare with, its bundle is read with the token
+	//
+	// Token is used only for the request and never stored.
+	Remote struct {
+		// Root of remote compose API, for example https://crm.example.com/api/compose
+		BaseURL     string `json:"baseURL"`
+		Token       string `json:"token"`
+		NamespaceID uint64 `json:"namespaceID,string"`
+	}
+
+	// Reference that local namespace is compared with, bundle or remote instance
+	Reference struct {
+		Bundle *Bundle `json:"bundle,omitempty"`
+		Remote *Remote `json:"remote,omitempty"`
+	}
+
+	// Report lists differences of local namespace from the reference
+	Report struct {
+		Namespace string   `json:"namespace"`
+		InSync    bool     `json:"inSync"`
+		Drift     []*Drift `json:"drift"`
+	}
+
+	// Drift of a resource, what should be changed locally to match the reference
+	//
+	// Missing resources exist only in the reference, extra ones only locally.
+	Drift struct {
+		Kind   string `json:"kind"`
+		Key    string `json:"key"`
+		Status string `json:"status"`
+
+		// Changed attributes
+		Attributes []string `json:"attributes,omitempty"`
+
+		Local     interface{} `json:"local,omitempty"`
+		Reference interface{} `json:"reference,omitempty"`
+	}
+)
+
+const (
+	KindModule     = "module"
+	KindField      = "field"
+	KindPage       = "page"
+	KindScript     = "script"
+	KindPermission = "permission"
+
+	StatusMissing = "missing"
+	StatusExtra   = "extra"
+	StatusChanged = "changed"
+)
@@ -0,0 +1,33 @@
+package edits
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	editError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrMessageNotFound = editError{"MessageNotFound", fault.NotFound}
+	ErrNoPermissions   = editError{"NoPermissions", fault.Forbidden}
+)
+
+func (e editError) Error() string {
+	return e.String()
+}
+
+func (e editError) String() string {
+	return "crust.edits." + e.name
+}
+
+func (e editError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e editError) withStack() *fault.Error {
+	return fault.New(e)
+}
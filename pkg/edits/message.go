@@ -0,0 +1,51 @@
+package edits
+
+import (
+	"context"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// message wraps message service and stores revisions of edited messages
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with storing revisions of edited messages
+//
+// Corteza overwrites the text of the edited message.
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Update(in *messagingTypes.Message) (*messagingTypes.Message, error) {
+	if in == nil || in.ID == 0 {
+		return svc.MessageService.Update(in)
+	}
+
+	edits := defaultEdit.with(svc.ctx)
+
+	prev, err := edits.original(in.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := svc.MessageService.Update(in)
+	if err != nil || prev == nil {
+		return m, err
+	}
+
+	edits.store(prev, m)
+	return m, nil
+}
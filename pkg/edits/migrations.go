@@ -0,0 +1,28 @@
+package edits
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	// Revision holds the text a message had before it was edited
+	Migrations = migrations.Set{
+		{
+			Name: "20200226000000.edits",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_message_revision (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_message      BIGINT UNSIGNED NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  version          INT UNSIGNED    NOT NULL,
+  message          TEXT            CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL,
+  edited_by        BIGINT UNSIGNED NOT NULL,
+  edited_at        DATETIME        NOT NULL,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY uid_message_version (rel_message, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,112 @@
+package edits
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_message_revision"
+}
+
+// Messages returns messages with their text, deleted are omitted
+func (r repository) Messages(messageIDs ...uint64) (rr messageRowSet, err error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := squirrel.
+		Select("id", "rel_channel", "rel_user", "message").
+		From("messaging_message").
+		Where(squirrel.Eq{"id": messageIDs, "deleted_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.db().Select(&rr, query, args...); err != nil {
+		return nil, errors.Wrap(err, "can not load messages")
+	}
+
+	return rr, nil
+}
+
+// FindByMessageID returns revisions of the message, oldest first
+func (r repository) FindByMessageID(messageID uint64) (rr RevisionSet, err error) {
+	q := squirrel.
+		Select("id", "rel_message", "rel_channel", "version", "message", "edited_by", "edited_at").
+		From(r.table()).
+		Where(squirrel.Eq{"rel_message": messageID}).
+		OrderBy("version")
+
+	return rr, rh.FetchAll(r.db(), q, &rr)
+}
+
+// Create stores the revision as the next version of the message
+func (r repository) Create(rev *Revision) error {
+	rev.ID = factory.Sonyflake.NextID()
+
+	err := r.db().Get(&rev.Version,
+		"SELECT COALESCE(MAX(version), 0) + 1 FROM "+r.table()+" WHERE rel_message = ?",
+		rev.MessageID,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "can not number revision")
+	}
+
+	return errors.WithStack(r.db().Insert(r.table(), rev))
+}
+
+// Counts returns number of revisions of the messages, messages without
+// revisions are omitted
+func (r repository) Counts(messageIDs ...uint64) (rr []*countRow, err error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := squirrel.
+		Select("rel_message", "COUNT(*) AS count").
+		From(r.table()).
+		Where(squirrel.Eq{"rel_message": messageIDs}).
+		GroupBy("rel_message").
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.db().Select(&rr, query, args...); err != nil {
+		return nil, errors.Wrap(err, "can not count revisions")
+	}
+
+	return rr, nil
+}
@@ -0,0 +1,27 @@
+package edits
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts message history endpoints
+//
+// Messages are edited with corteza's message endpoints.
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Edit markers of the messages: ?messageID=<ID>&messageID=<ID>
+	r.Get("/", rest.Handler("Edits.List", func(r *http.Request) (interface{}, error) {
+		return DefaultEdit.With(r.Context()).Find(rest.QueryUint64s(r, "messageID")...)
+	}))
+
+	// Previous texts of the message
+	r.Get("/{messageID}/history", rest.Handler("Edits.History", func(r *http.Request) (interface{}, error) {
+		return DefaultEdit.With(r.Context()).History(rest.ParamUint64(r, "messageID"))
+	}))
+}
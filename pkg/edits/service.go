@@ -0,0 +1,215 @@
+package edits
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	accessController interface {
+		CanUpdateMessages(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+
+		repository *repository
+	}
+
+	EditService interface {
+		With(ctx context.Context) EditService
+
+		History(messageID uint64) (*History, error)
+		Find(messageIDs ...uint64) (SummarySet, error)
+	}
+)
+
+var (
+	DefaultEdit EditService
+
+	// used by service decorators
+	defaultEdit *service
+
+	// now is used for edit times and can be overridden
+	now = time.Now
+)
+
+// Init initializes message history and decorates message service with
+// storing revisions of edited messages
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		channels: messagingService.DefaultChannel,
+	}
+
+	DefaultEdit = svc.With(ctx)
+	defaultEdit = svc.with(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) EditService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// History returns revisions of the message
+//
+// Previous text is visible to the author and to those that can update
+// messages of others in the channel, not to everyone that can read it.
+func (svc service) History(messageID uint64) (*History, error) {
+	mm, err := svc.repository.Messages(messageID)
+	if err != nil {
+		return nil, err
+	} else if len(mm) == 0 {
+		return nil, ErrMessageNotFound.withStack().WithID("messageID", messageID)
+	}
+
+	m := mm[0]
+	ch, err := svc.channels.FindByID(m.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.UserID != auth.GetIdentityFromContext(svc.ctx).Identity() && !svc.ac.CanUpdateMessages(svc.ctx, ch) {
+		return nil, ErrNoPermissions.withStack().WithID("messageID", messageID)
+	}
+
+	rr, err := svc.repository.FindByMessageID(messageID)
+	if err != nil {
+		return nil, err
+	} else if rr == nil {
+		rr = RevisionSet{}
+	}
+
+	return &History{MessageID: m.ID, ChannelID: m.ChannelID, Message: m.Message, Revisions: rr}, nil
+}
+
+// Find returns edit markers of messages in channels that the current
+// user can read
+func (svc service) Find(messageIDs ...uint64) (SummarySet, error) {
+	mm, err := svc.readable(messageIDs...)
+	if err != nil || len(mm) == 0 {
+		return SummarySet{}, err
+	}
+
+	cc, err := counts(svc.repository, mm.IDs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(SummarySet, len(mm))
+	for i, m := range mm {
+		out[i] = &Summary{MessageID: m.ID, Edited: cc[m.ID] > 0, Revisions: cc[m.ID]}
+	}
+
+	return out, nil
+}
+
+// readable returns messages in channels that the current user can read
+func (svc service) readable(messageIDs ...uint64) (messageRowSet, error) {
+	mm, err := svc.repository.Messages(messageIDs...)
+	if err != nil || len(mm) == 0 {
+		return nil, err
+	}
+
+	cc, _, err := svc.channels.Find(messagingTypes.ChannelFilter{ChannelID: mm.ChannelIDs()})
+	if err != nil {
+		return nil, err
+	}
+
+	out := messageRowSet{}
+	for _, m := range mm {
+		if cc.FindByID(m.ChannelID) != nil {
+			out = append(out, m)
+		}
+	}
+
+	return out, nil
+}
+
+// counts returns number of revisions by message ID
+func counts(r *repository, messageIDs ...uint64) (map[uint64]uint, error) {
+	rr, err := r.Counts(messageIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[uint64]uint, len(rr))
+	for _, r := range rr {
+		out[r.MessageID] = r.Count
+	}
+
+	return out, nil
+}
+
+// original returns the message as it is before it is edited, nil when
+// it does not exist
+func (svc service) original(messageID uint64) (*messageRow, error) {
+	mm, err := svc.repository.Messages(messageID)
+	if err != nil || len(mm) == 0 {
+		return nil, err
+	}
+
+	return mm[0], nil
+}
+
+// store keeps the text the message had before it was edited
+func (svc service) store(prev *messageRow, edited *messagingTypes.Message) {
+	if prev.Message == edited.Message {
+		return
+	}
+
+	rev := &Revision{
+		MessageID: prev.ID,
+		ChannelID: prev.ChannelID,
+		Message:   prev.Message,
+		EditedBy:  auth.GetIdentityFromContext(svc.ctx).Identity(),
+		EditedAt:  now(),
+	}
+
+	if err := svc.repository.Create(rev); err != nil {
+		svc.log(zap.Error(err), zap.Uint64("messageID", prev.ID)).Error("could not store message revision")
+	}
+}
+
+// Counts returns number of revisions by message ID, without checking if
+// the messages can be read
+//
+// Used to mark edited messages in responses of other services.
+func Counts(ctx context.Context, messageIDs ...uint64) (map[uint64]uint, error) {
+	return counts(Repository(ctx, nil), messageIDs...)
+}
@@ -0,0 +1,83 @@
+package edits
+
+import (
+	"time"
+)
+
+type (
+	// History of the message, oldest revision first
+	History struct {
+		MessageID uint64 `json:"messageID,string"`
+		ChannelID uint64 `json:"channelID,string"`
+
+		// Current text of the message
+		Message string `json:"message"`
+
+		Revisions RevisionSet `json:"revisions"`
+	}
+
+	// Revision is the text the message had until it was edited
+	Revision struct {
+		ID        uint64    `json:"-" db:"id"`
+		MessageID uint64    `json:"-" db:"rel_message"`
+		ChannelID uint64    `json:"-" db:"rel_channel"`
+		Version   uint      `json:"version" db:"version"`
+		Message   string    `json:"message" db:"message"`
+		EditedBy  uint64    `json:"editedBy,string" db:"edited_by"`
+		EditedAt  time.Time `json:"editedAt" db:"edited_at"`
+	}
+
+	RevisionSet []*Revision
+
+	// Summary tells if and how many times the message was edited
+	Summary struct {
+		MessageID uint64 `json:"messageID,string"`
+		Edited    bool   `json:"edited"`
+		Revisions uint   `json:"revisions"`
+	}
+
+	SummarySet []*Summary
+
+	// countRow is the number of revisions of a message
+	countRow struct {
+		MessageID uint64 `db:"rel_message"`
+		Count     uint   `db:"count"`
+	}
+
+	// messageRow is a message with its channel, author and text
+	messageRow struct {
+		ID        uint64 `db:"id"`
+		ChannelID uint64 `db:"rel_channel"`
+		UserID    uint64 `db:"rel_user"`
+		Message   string `db:"message"`
+	}
+
+	messageRowSet []*messageRow
+)
+
+// IDs returns IDs of the messages
+func (set messageRowSet) IDs() []uint64 {
+	out := make([]uint64, len(set))
+	for i, m := range set {
+		out[i] = m.ID
+	}
+
+	return out
+}
+
+// ChannelIDs returns IDs of channels of the messages
+func (set messageRowSet) ChannelIDs() []uint64 {
+	var (
+		out  = []uint64{}
+		seen = map[uint64]bool{}
+	)
+
+	for _, m := range set {
+		if !seen[m.ChannelID] {
+			seen[m.ChannelID] = true
+			out = append(out, m.ChannelID)
+		}
+	}
+
+	return out
+}
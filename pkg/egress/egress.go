@@ -0,0 +1,88 @@
+// Package egress provides HTTP clients for URLs that users configure
+//
+// Webhooks, actions, external commands and such are sent to URLs that
+// users give; these clients can not connect to loopback, private,
+// link-local and unspecified addresses. Addresses are checked after host
+// names are resolved, so neither names nor redirects can point requests
+// to internal services. Proxies are dialed the same way, internal ones
+// work only when internal targets are allowed (EGRESS_ALLOW_INTERNAL).
+package egress
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+)
+
+const (
+	dialTimeout = 30 * time.Second
+)
+
+var (
+	allowInternal struct {
+		sync.Once
+		allowed bool
+	}
+)
+
+// Client returns HTTP client with the timeout, see Transport
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: Transport(nil),
+	}
+}
+
+// Transport returns HTTP transport that rejects connections to internal addresses
+func Transport(tc *tls.Config) *http.Transport {
+	d := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: dialTimeout,
+		Control:   Control,
+	}
+
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         d.DialContext,
+		TLSClientConfig:     tc,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// Control is net.Dialer's hook that stops connections to internal addresses
+//
+// Setting is read when the first connection is made, after the
+// environment (and .env file) is loaded.
+func Control(network, address string, _ syscall.RawConn) error {
+	allowInternal.Do(func() {
+		allowInternal.allowed = options.EnvBool("", "EGRESS_ALLOW_INTERNAL", false)
+	})
+
+	if allowInternal.allowed {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || internal(ip) {
+		return ErrInternalTarget.withStack()
+	}
+
+	return nil
+}
+
+// internal checks if the address is loopback, private, link-local or unspecified
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+}
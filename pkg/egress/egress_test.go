@@ -0,0 +1,39 @@
+package egress
+
+import (
+	"testing"
+
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+func TestControl(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{"93.184.216.34:443", true},
+		{"[2606:2800:220:1:248:1893:25c8:1946]:443", true},
+		{"127.0.0.1:80", false},
+		{"[::1]:80", false},
+		{"10.0.0.1:80", false},
+		{"172.16.0.1:80", false},
+		{"192.168.1.1:80", false},
+		{"[fd00::1]:80", false},
+		{"169.254.169.254:80", false},
+		{"[fe80::1]:80", false},
+		{"0.0.0.0:80", false},
+		{"[::]:80", false},
+		{"localhost:80", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := Control("tcp", tt.address, nil)
+			if tt.allowed && err != nil {
+				t.Errorf("expected connection to be allowed, got %v", err)
+			} else if !tt.allowed && !fault.Is(err, ErrInternalTarget) {
+				t.Errorf("expected %v, got %v", ErrInternalTarget, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package egress
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	egressError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInternalTarget = egressError{"InternalTarget", fault.Forbidden}
+)
+
+func (e egressError) Error() string {
+	return e.String()
+}
+
+func (e egressError) String() string {
+	return "crust.egress." + e.name
+}
+
+func (e egressError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e egressError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,41 @@
+package etl
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	etlError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID           = etlError{"InvalidID", fault.Invalid}
+	ErrNameRequired        = etlError{"NameRequired", fault.Invalid}
+	ErrInvalidName         = etlError{"InvalidName", fault.Invalid}
+	ErrInvalidSource       = etlError{"InvalidSource", fault.Invalid}
+	ErrInvalidFormat       = etlError{"InvalidFormat", fault.Invalid}
+	ErrInvalidFrequency    = etlError{"InvalidFrequency", fault.Invalid}
+	ErrDestinationRequired = etlError{"DestinationRequired", fault.Invalid}
+	ErrNoPermissions       = etlError{"NoPermissions", fault.Forbidden}
+	ErrExportNotFound      = etlError{"ExportNotFound", fault.NotFound}
+	ErrMessagingNotBundled = etlError{"MessagingNotBundled", fault.Unavailable}
+)
+
+func (e etlError) Error() string {
+	return e.String()
+}
+
+func (e etlError) String() string {
+	return "crust.etl." + e.name
+}
+
+func (e etlError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e etlError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,48 @@
+package etl
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200203000000.etl",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_etl_export (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  source             VARCHAR(32)     NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  format             VARCHAR(16)     NOT NULL,
+  frequency          VARCHAR(16)     NOT NULL,
+  incremental        BOOLEAN         NOT NULL DEFAULT FALSE,
+
+  endpoint           VARCHAR(255)    NOT NULL DEFAULT '',
+  secure             BOOLEAN         NOT NULL DEFAULT TRUE,
+  bucket             VARCHAR(255)    NOT NULL DEFAULT '',
+  access_key_id      VARCHAR(255)    NOT NULL DEFAULT '',
+  secret_access_key  VARCHAR(255)    NOT NULL DEFAULT '',
+  path               VARCHAR(512)    NOT NULL DEFAULT '',
+  prefix             VARCHAR(255)    NOT NULL DEFAULT '',
+
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+  export_cursor      DATETIME            NULL DEFAULT NULL,
+  last_run_at        DATETIME            NULL DEFAULT NULL,
+  last_error         TEXT            NOT NULL,
+  next_run_at        DATETIME            NULL DEFAULT NULL,
+
+  owned_by           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (next_run_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
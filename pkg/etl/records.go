@@ -0,0 +1,228 @@
+package etl
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/access"
+)
+
+type (
+	exportedRecord struct {
+		ID        uint64     `db:"id"`
+		OwnedBy   uint64     `db:"owned_by"`
+		CreatedAt time.Time  `db:"created_at"`
+		UpdatedAt *time.Time `db:"updated_at"`
+		DeletedAt *time.Time `db:"deleted_at"`
+		ChangedAt time.Time  `db:"changed_at"`
+	}
+
+	exportedValue struct {
+		RecordID uint64 `db:"record_id"`
+		Name     string `db:"name"`
+		Value    string `db:"value"`
+	}
+)
+
+const (
+	// Time of the last change of a record, deletion included
+	changedAt = "COALESCE(deleted_at, updated_at, created_at)"
+
+	timestampLayout = time.RFC3339
+)
+
+// exportRecords writes records of export's module, as seen by the owner
+//
+// Records are read in batches, ordered by the time of the last change, so
+// that incremental exports write partitions one after another. Values of
+// fields that the owner can not read are not exported.
+func (svc etlService) exportRecords(ctx context.Context, e *Export, w *partitionWriter, since *time.Time, until time.Time) error {
+	m, err := service.DefaultModule.With(ctx).FindByID(e.NamespaceID, e.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if !service.DefaultAccessControl.CanReadRecord(ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	var (
+		db = factory.Database.MustGet("compose").With(ctx)
+
+		fields types.ModuleFieldSet
+		names  []string
+
+		lastChanged time.Time
+		lastID      uint64
+	)
+
+	w.columns = []*Column{
+		{Name: "id", Type: "STRING", Mode: "REQUIRED"},
+		{Name: "owned_by", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "created_at", Type: "TIMESTAMP", Mode: "REQUIRED"},
+		{Name: "updated_at", Type: "TIMESTAMP", Mode: "NULLABLE"},
+		{Name: "deleted_at", Type: "TIMESTAMP", Mode: "NULLABLE"},
+	}
+
+	for _, f := range access.ReadableFields(ctx, m.Fields) {
+		fields = append(fields, f)
+		names = append(names, f.Name)
+		w.columns = append(w.columns, fieldColumn(f))
+	}
+
+	for {
+		var (
+			rr []*exportedRecord
+			vv []*exportedValue
+
+			q = squirrel.
+				Select("id", "owned_by", "created_at", "updated_at", "deleted_at", changedAt+" AS changed_at").
+				From("compose_record").
+				Where(squirrel.Eq{"module_id": m.ID}).
+				Where(squirrel.Lt{changedAt: until}).
+				OrderBy("changed_at", "id").
+				Limit(batchSize)
+		)
+
+		if !e.Incremental {
+			q = q.Where(squirrel.Eq{"deleted_at": nil})
+		} else if since != nil {
+			q = q.Where(squirrel.GtOrEq{changedAt: *since})
+		}
+
+		if lastID > 0 {
+			q = q.Where("("+changedAt+" > ? OR ("+changedAt+" = ? AND id > ?))", lastChanged, lastChanged, lastID)
+		}
+
+		if err = rh.FetchAll(db, q, &rr); err != nil || len(rr) == 0 {
+			return err
+		}
+
+		ids := make([]uint64, len(rr))
+		for i, r := range rr {
+			ids[i] = r.ID
+		}
+
+		q = squirrel.
+			Select("record_id", "name", "value").
+			From("compose_record_value").
+			Where(squirrel.Eq{"record_id": ids, "name": names, "deleted_at": nil}).
+			OrderBy("record_id", "place")
+
+		if err = rh.FetchAll(db, q, &vv); err != nil {
+			return err
+		}
+
+		values := map[uint64]map[string][]string{}
+		for _, v := range vv {
+			if values[v.RecordID] == nil {
+				values[v.RecordID] = map[string][]string{}
+			}
+
+			values[v.RecordID][v.Name] = append(values[v.RecordID][v.Name], v.Value)
+		}
+
+		for _, r := range rr {
+			day := until
+			if e.Incremental {
+				day = r.ChangedAt
+			}
+
+			cells := []interface{}{id(r.ID), id(r.OwnedBy), timestamp(&r.CreatedAt), timestamp(r.UpdatedAt), timestamp(r.DeletedAt)}
+			for _, f := range fields {
+				cells = append(cells, fieldCell(f, values[r.ID][f.Name]))
+			}
+
+			if err = w.write(day.Format(dateLayout), cells); err != nil {
+				return err
+			}
+		}
+
+		last := rr[len(rr)-1]
+		lastChanged, lastID = last.ChangedAt, last.ID
+
+		if len(rr) < batchSize {
+			return nil
+		}
+	}
+}
+
+// fieldColumn describes column of the module field
+//
+// References (records, users, files) are exported as IDs; multi-value
+// fields are repeated columns (JSON arrays in CSV files).
+func fieldColumn(f *types.ModuleField) *Column {
+	c := &Column{Name: f.Name, Type: "STRING", Mode: "NULLABLE"}
+
+	switch f.Kind {
+	case "Number":
+		c.Type = "NUMERIC"
+	case "Bool":
+		c.Type = "BOOLEAN"
+	case "DateTime":
+		c.Type = "TIMESTAMP"
+		if onlyDate, _ := f.Options["onlyDate"].(bool); onlyDate {
+			c.Type = "DATE"
+		}
+	}
+
+	if f.Multi {
+		c.Mode = "REPEATED"
+	}
+
+	return c
+}
+
+func fieldCell(f *types.ModuleField, vv []string) interface{} {
+	if f.Kind == "Bool" {
+		bb := make([]interface{}, len(vv))
+		for i, v := range vv {
+			bb[i] = v == "1" || v == "true"
+		}
+
+		return cell(f, bb)
+	}
+
+	ss := make([]interface{}, len(vv))
+	for i, v := range vv {
+		ss[i] = v
+	}
+
+	return cell(f, ss)
+}
+
+func cell(f *types.ModuleField, vv []interface{}) interface{} {
+	if f.Multi {
+		return vv
+	}
+
+	if len(vv) == 0 {
+		return nil
+	}
+
+	return vv[0]
+}
+
+// id returns ID as string, numbers in JSON files would lose precision
+func id(ID uint64) interface{} {
+	if ID == 0 {
+		return nil
+	}
+
+	return strconv.FormatUint(ID, 10)
+}
+
+func timestamp(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return t.Format(timestampLayout)
+}
@@ -0,0 +1,140 @@
+package etl
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_etl_export"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"name",
+			"source",
+			"rel_module",
+			"format",
+			"frequency",
+			"incremental",
+			"endpoint",
+			"secure",
+			"bucket",
+			"access_key_id",
+			"secret_access_key",
+			"path",
+			"prefix",
+			"enabled",
+			"export_cursor",
+			"last_run_at",
+			"last_error",
+			"next_run_at",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(namespaceID, exportID uint64) (*Export, error) {
+	var (
+		e = &Export{}
+		q = r.query().Where(squirrel.Eq{"id": exportID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, e); err != nil {
+		return nil, err
+	} else if e.ID == 0 {
+		return nil, ErrExportNotFound.withStack().WithID("namespaceID", namespaceID).WithID("exportID", exportID)
+	}
+
+	return e, nil
+}
+
+func (r repository) Find(namespaceID uint64) (set ExportSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindDue returns enabled exports that are scheduled to run
+func (r repository) FindDue(now time.Time) (set ExportSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"enabled": true}).
+		Where(squirrel.LtOrEq{"next_run_at": now}).
+		OrderBy("next_run_at")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(e *Export) (*Export, error) {
+	e.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	return e, errors.WithStack(r.db().Insert(r.table(), e))
+}
+
+func (r repository) Update(e *Export) (*Export, error) {
+	rh.SetCurrentTimeRounded(&e.UpdatedAt)
+
+	return e, errors.WithStack(r.db().Replace(r.table(), e))
+}
+
+// UpdateState stores outcome of the run without touching export's configuration
+func (r repository) UpdateState(e *Export) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{
+			"export_cursor": e.Cursor,
+			"last_run_at":   e.LastRunAt,
+			"last_error":    e.LastError,
+			"next_run_at":   e.NextRunAt,
+		},
+		squirrel.Eq{"id": e.ID},
+	)
+}
+
+func (r repository) DeleteByID(namespaceID, exportID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": exportID, "rel_namespace": namespaceID},
+	)
+}
@@ -0,0 +1,64 @@
+package etl
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts export management endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("EtlExport.List", func(r *http.Request) (interface{}, error) {
+		return DefaultEtl.With(r.Context()).Find(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Post("/", rest.Handler("EtlExport.Create", func(r *http.Request) (interface{}, error) {
+		e := &Export{}
+		if err := rest.Decode(r, e); err != nil {
+			return nil, err
+		}
+
+		e.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultEtl.With(r.Context()).Create(e)
+	}))
+
+	r.Get("/{exportID}", rest.Handler("EtlExport.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultEtl.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "exportID"),
+		)
+	}))
+
+	r.Put("/{exportID}", rest.Handler("EtlExport.Update", func(r *http.Request) (interface{}, error) {
+		e := &Export{}
+		if err := rest.Decode(r, e); err != nil {
+			return nil, err
+		}
+
+		e.ID = rest.ParamUint64(r, "exportID")
+		e.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultEtl.With(r.Context()).Update(e)
+	}))
+
+	r.Delete("/{exportID}", rest.Handler("EtlExport.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultEtl.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "exportID"),
+		)
+	}))
+
+	r.Post("/{exportID}/run", rest.Handler("EtlExport.Run", func(r *http.Request) (interface{}, error) {
+		return DefaultEtl.With(r.Context()).Run(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "exportID"),
+		)
+	}))
+}
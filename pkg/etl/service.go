@@ -0,0 +1,445 @@
+package etl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/pkg/store"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	etlService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	EtlService interface {
+		With(ctx context.Context) EtlService
+
+		Find(namespaceID uint64) (ExportSet, error)
+		FindByID(namespaceID, exportID uint64) (*Export, error)
+		Create(*Export) (*Export, error)
+		Update(*Export) (*Export, error)
+		DeleteByID(namespaceID, exportID uint64) error
+
+		Run(namespaceID, exportID uint64) (*Run, error)
+	}
+)
+
+var (
+	DefaultEtl EtlService
+
+	// now is used for scheduling and can be overridden
+	now = time.Now
+
+	// Export name is used as a directory name
+	validName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Init initializes export service and starts running scheduled exports in the background
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &etlService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+	}
+
+	DefaultEtl = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc etlService) With(ctx context.Context) EtlService {
+	return svc.with(ctx)
+}
+
+func (svc etlService) with(ctx context.Context) *etlService {
+	return &etlService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc etlService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc etlService) Find(namespaceID uint64) (ExportSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.Find(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set {
+		set[i] = set[i].withoutSecret()
+	}
+
+	return set, nil
+}
+
+func (svc etlService) FindByID(namespaceID, exportID uint64) (*Export, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	e, err := svc.repository.FindByID(namespaceID, exportID)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.withoutSecret(), nil
+}
+
+// Create stores new export, it runs with the next check of scheduled exports
+func (svc etlService) Create(in *Export) (*Export, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	next := now().Truncate(time.Second)
+
+	e, err := svc.repository.Create(&Export{
+		NamespaceID:     in.NamespaceID,
+		Name:            in.Name,
+		Source:          in.Source,
+		ModuleID:        in.ModuleID,
+		Format:          in.Format,
+		Frequency:       in.Frequency,
+		Incremental:     in.Incremental,
+		Endpoint:        in.Endpoint,
+		Secure:          in.Secure,
+		Bucket:          in.Bucket,
+		AccessKeyID:     in.AccessKeyID,
+		SecretAccessKey: in.SecretAccessKey,
+		Path:            in.Path,
+		Prefix:          in.Prefix,
+		Enabled:         in.Enabled,
+		NextRunAt:       &next,
+		OwnedBy:         auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.withoutSecret(), nil
+}
+
+// Update modifies the export
+//
+// Source and module can not be changed, exported files would not match the schema.
+// Secret access key is changed only when a new one is given.
+func (svc etlService) Update(upd *Export) (*Export, error) {
+	e, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	upd.Source, upd.ModuleID = e.Source, e.ModuleID
+
+	if err = svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	if upd.Incremental != e.Incremental {
+		// Switching to incremental mode starts with all data
+		e.Cursor = nil
+	}
+
+	e.Name = upd.Name
+	e.Format = upd.Format
+	e.Frequency = upd.Frequency
+	e.Incremental = upd.Incremental
+	e.Endpoint = upd.Endpoint
+	e.Secure = upd.Secure
+	e.Bucket = upd.Bucket
+	e.AccessKeyID = upd.AccessKeyID
+	e.Path = upd.Path
+	e.Prefix = upd.Prefix
+	e.Enabled = upd.Enabled
+
+	if upd.SecretAccessKey != "" {
+		e.SecretAccessKey = upd.SecretAccessKey
+	}
+
+	if e, err = svc.repository.Update(e); err != nil {
+		return nil, err
+	}
+
+	return e.withoutSecret(), nil
+}
+
+func (svc etlService) DeleteByID(namespaceID, exportID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindByID(namespaceID, exportID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, exportID)
+}
+
+// Run runs the export immediately
+func (svc etlService) Run(namespaceID, exportID uint64) (*Run, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	e, err := svc.repository.FindByID(namespaceID, exportID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.run(e)
+}
+
+// run writes export's data and schema and schedules the next run
+//
+// Failed runs do not move the cursor; the next incremental run
+// exports the same changes again.
+func (svc etlService) run(e *Export) (run *Run, err error) {
+	var (
+		started = now().Truncate(time.Second)
+		until   = started
+		since   *time.Time
+		st      store.Store
+	)
+
+	defer func() {
+		next := started.Add(e.Frequency.period())
+		e.LastRunAt, e.NextRunAt, e.LastError = &started, &next, ""
+
+		if err != nil {
+			e.LastError = err.Error()
+		}
+
+		if serr := svc.repository.UpdateState(e); serr != nil {
+			svc.log(zap.Uint64("exportID", e.ID), zap.Error(serr)).Error("could not store export state")
+		}
+	}()
+
+	ctx, err := runas.Compose(svc.ctx, e.OwnedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if st, err = connect(e); err != nil {
+		return nil, err
+	}
+
+	if e.Incremental {
+		since = e.Cursor
+	}
+
+	w := &partitionWriter{
+		store:  st,
+		export: e,
+		file:   "data." + e.Format.extension(),
+		run:    &Run{Files: []string{}},
+	}
+
+	if e.Incremental {
+		// Each run adds a file to partitions, full exports replace them
+		w.file = "part-" + strconv.FormatInt(started.Unix(), 10) + "." + e.Format.extension()
+	}
+
+	switch e.Source {
+	case SourceRecords:
+		err = svc.exportRecords(ctx, e, w, since, until)
+	case SourceMessagingStats:
+		until = startOfDay(started)
+		err = svc.exportStats(ctx, e, w, since, until)
+	}
+
+	if err == nil {
+		err = w.close()
+	}
+
+	if err != nil {
+		w.abort(err)
+		return nil, err
+	}
+
+	if err = writeSchema(st, e, w.columns, started); err != nil {
+		return nil, err
+	}
+
+	e.Cursor = &until
+
+	svc.log(zap.Uint64("exportID", e.ID), zap.Uint("rows", w.run.Rows)).Info("export finished")
+
+	return w.run, nil
+}
+
+func writeSchema(st store.Store, e *Export, columns []*Column, updatedAt time.Time) error {
+	b, err := json.MarshalIndent(&Schema{
+		Name:         e.Name,
+		Source:       e.Source,
+		ModuleID:     e.ModuleID,
+		Format:       e.Format,
+		Incremental:  e.Incremental,
+		Partitioning: partitionColumn,
+		Columns:      columns,
+		UpdatedAt:    updatedAt,
+	}, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return st.Save(objectName(e, "_schema.json"), bytes.NewReader(b))
+}
+
+func (svc etlService) validate(e *Export) error {
+	if err := svc.canManage(e.NamespaceID); err != nil {
+		return err
+	}
+
+	if e.Name == "" {
+		return ErrNameRequired.withStack()
+	} else if !validName.MatchString(e.Name) {
+		return ErrInvalidName.withStack()
+	}
+
+	if e.Format == "" {
+		e.Format = FormatCSV
+	} else if !e.Format.IsValid() {
+		return ErrInvalidFormat.withStack()
+	}
+
+	if e.Frequency == "" {
+		e.Frequency = FrequencyDaily
+	} else if !e.Frequency.IsValid() {
+		return ErrInvalidFrequency.withStack()
+	}
+
+	switch e.Source {
+	case SourceRecords:
+		if _, err := svc.module.FindByID(e.NamespaceID, e.ModuleID); err != nil {
+			return err
+		}
+	case SourceMessagingStats:
+		if _, err := factory.Database.Get("messaging"); err != nil {
+			return ErrMessagingNotBundled.withStack()
+		}
+
+		// Statistics are not limited to namespace's data
+		if !isAdmin(svc.ctx) {
+			return ErrNoPermissions.withStack()
+		}
+
+		e.ModuleID = 0
+	default:
+		return ErrInvalidSource.withStack()
+	}
+
+	if e.Path != "" {
+		// Local directories are on the server's filesystem
+		if !isAdmin(svc.ctx) {
+			return ErrNoPermissions.withStack()
+		}
+	} else if e.Bucket == "" || e.Endpoint == "" {
+		return ErrDestinationRequired.withStack()
+	}
+
+	return nil
+}
+
+func (svc etlService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// watch runs scheduled exports
+func (svc etlService) watch(ctx context.Context) {
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			ctx := auth.SetSuperUserContext(ctx)
+
+			set, err := Repository(ctx, nil).FindDue(now())
+			if err != nil {
+				svc.logger.Error("could not load due exports", zap.Error(err))
+				continue
+			}
+
+			for _, e := range set {
+				if _, err = svc.with(ctx).run(e); err != nil {
+					svc.logger.Error("could not run export", zap.Uint64("exportID", e.ID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+// isAdmin checks if the user is a super user or a member of the administrators role
+func isAdmin(ctx context.Context) bool {
+	i := auth.GetIdentityFromContext(ctx)
+	if auth.IsSuperUser(i) {
+		return true
+	}
+
+	for _, roleID := range i.Roles() {
+		if roleID == permissions.AdminsRoleID {
+			return true
+		}
+	}
+
+	return false
+}
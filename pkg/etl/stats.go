@@ -0,0 +1,88 @@
+package etl
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	channelStats struct {
+		Day       time.Time `db:"day"`
+		ChannelID uint64    `db:"rel_channel"`
+		Channel   string    `db:"channel"`
+		Messages  uint      `db:"messages"`
+		Replies   uint      `db:"replies"`
+		Users     uint      `db:"users"`
+	}
+)
+
+// exportStats writes daily messaging statistics per channel
+//
+// Only complete days (before until) are exported, each into its own partition.
+// Days are in server's timezone.
+func (svc etlService) exportStats(ctx context.Context, e *Export, w *partitionWriter, since *time.Time, until time.Time) error {
+	db, err := factory.Database.Get("messaging")
+	if err != nil {
+		return ErrMessagingNotBundled.withStack()
+	}
+
+	if !isAdmin(ctx) {
+		return ErrNoPermissions.withStack()
+	}
+
+	w.columns = []*Column{
+		{Name: "day", Type: "DATE", Mode: "REQUIRED"},
+		{Name: "channel_id", Type: "STRING", Mode: "REQUIRED"},
+		{Name: "channel", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "messages", Type: "INTEGER", Mode: "REQUIRED"},
+		{Name: "replies", Type: "INTEGER", Mode: "REQUIRED"},
+		{Name: "users", Type: "INTEGER", Mode: "REQUIRED"},
+	}
+
+	var (
+		set []*channelStats
+		q   = squirrel.
+			Select(
+				"DATE(m.created_at) AS day",
+				"m.rel_channel",
+				"COALESCE(c.name, '') AS channel",
+				"COUNT(*) AS messages",
+				"SUM(m.reply_to > 0) AS replies",
+				"COUNT(DISTINCT m.rel_user) AS users",
+			).
+			From("messaging_message AS m").
+			LeftJoin("messaging_channel AS c ON (c.id = m.rel_channel)").
+			Where(squirrel.Eq{"m.deleted_at": nil}).
+			Where(squirrel.Lt{"m.created_at": until}).
+			GroupBy("DATE(m.created_at)", "m.rel_channel").
+			OrderBy("day", "m.rel_channel")
+	)
+
+	if since != nil {
+		q = q.Where(squirrel.GtOrEq{"m.created_at": *since})
+	}
+
+	if err = rh.FetchAll(db.With(ctx), q, &set); err != nil {
+		return err
+	}
+
+	for _, s := range set {
+		cells := []interface{}{s.Day.Format(dateLayout), id(s.ChannelID), s.Channel, s.Messages, s.Replies, s.Users}
+		if err = w.write(s.Day.Format(dateLayout), cells); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startOfDay returns midnight of the day, in server's timezone
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Local().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
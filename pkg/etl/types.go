@@ -0,0 +1,144 @@
+package etl
+
+import (
+	"time"
+)
+
+type (
+	// Export periodically writes module's records or messaging statistics
+	// to object storage, in a layout that data warehouses load directly
+	//
+	// Files are partitioned by date (Hive style, dt=YYYY-MM-DD):
+	//
+	//   <prefix>/<name>/_schema.json
+	//   <prefix>/<name>/dt=2020-02-01/part-<run>.csv
+	//
+	// Full exports write a snapshot of all records into the partition of the
+	// run date. Incremental exports write records changed (or deleted) since
+	// the previous run, into partitions of the date of the change.
+	Export struct {
+		ID          uint64 `json:"exportID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Name        string `json:"name" db:"name"`
+
+		Source   Source `json:"source" db:"source"`
+		ModuleID uint64 `json:"moduleID,string,omitempty" db:"rel_module"`
+
+		Format      Format    `json:"format" db:"format"`
+		Frequency   Frequency `json:"frequency" db:"frequency"`
+		Incremental bool      `json:"incremental" db:"incremental"`
+
+		// S3 compatible bucket or a local directory (path)
+		Endpoint        string `json:"endpoint,omitempty" db:"endpoint"`
+		Secure          bool   `json:"secure" db:"secure"`
+		Bucket          string `json:"bucket,omitempty" db:"bucket"`
+		AccessKeyID     string `json:"accessKeyID,omitempty" db:"access_key_id"`
+		SecretAccessKey string `json:"secretAccessKey,omitempty" db:"secret_access_key"`
+		Path            string `json:"path,omitempty" db:"path"`
+		Prefix          string `json:"prefix" db:"prefix"`
+
+		Enabled bool `json:"enabled" db:"enabled"`
+
+		// Data is exported up to this time, incremental exports continue from here
+		Cursor *time.Time `json:"cursor,omitempty" db:"export_cursor"`
+
+		LastRunAt *time.Time `json:"lastRunAt,omitempty" db:"last_run_at"`
+		LastError string     `json:"lastError,omitempty" db:"last_error"`
+		NextRunAt *time.Time `json:"nextRunAt,omitempty" db:"next_run_at"`
+
+		// Data is read in the name of the owner
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	ExportSet []*Export
+
+	// Run describes files written by one export run
+	Run struct {
+		Rows  uint     `json:"rows"`
+		Files []string `json:"files"`
+	}
+
+	// Schema is written next to the exported files
+	//
+	// Column types and modes follow BigQuery's naming, other
+	// warehouses map them to their own types.
+	Schema struct {
+		Name         string    `json:"name"`
+		Source       Source    `json:"source"`
+		ModuleID     uint64    `json:"moduleID,string,omitempty"`
+		Format       Format    `json:"format"`
+		Incremental  bool      `json:"incremental"`
+		Partitioning string    `json:"partitioning"`
+		Columns      []*Column `json:"columns"`
+		UpdatedAt    time.Time `json:"updatedAt"`
+	}
+
+	Column struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		Mode string `json:"mode"`
+	}
+
+	Source    string
+	Format    string
+	Frequency string
+)
+
+const (
+	SourceRecords        Source = "records"
+	SourceMessagingStats Source = "messaging-stats"
+
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+
+	FrequencyHourly Frequency = "hourly"
+	FrequencyDaily  Frequency = "daily"
+	FrequencyWeekly Frequency = "weekly"
+
+	partitionColumn = "dt"
+	dateLayout      = "2006-01-02"
+
+	// Records are read in batches of this size
+	batchSize = 500
+
+	watchInterval = time.Minute
+)
+
+func (s Source) IsValid() bool {
+	return s == SourceRecords || s == SourceMessagingStats
+}
+
+func (f Format) IsValid() bool {
+	return f == FormatCSV || f == FormatNDJSON
+}
+
+func (f Format) extension() string {
+	if f == FormatNDJSON {
+		return "json"
+	}
+
+	return "csv"
+}
+
+func (f Frequency) IsValid() bool {
+	return f == FrequencyHourly || f == FrequencyDaily || f == FrequencyWeekly
+}
+
+func (f Frequency) period() time.Duration {
+	switch f {
+	case FrequencyHourly:
+		return time.Hour
+	case FrequencyWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+func (e Export) withoutSecret() *Export {
+	e.SecretAccessKey = ""
+	return &e
+}
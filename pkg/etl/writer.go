@@ -0,0 +1,180 @@
+package etl
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"path"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/pkg/store"
+	"github.com/cortezaproject/corteza-server/pkg/store/minio"
+	"github.com/cortezaproject/corteza-server/pkg/store/plain"
+)
+
+type (
+	// partitionWriter writes rows into one file per partition
+	//
+	// Rows must be ordered by partition; files are streamed into the
+	// store while rows are written, so they are never kept in memory.
+	partitionWriter struct {
+		store   store.Store
+		export  *Export
+		columns []*Column
+
+		// File name, the same in all partitions of the run
+		file string
+
+		day  string
+		pw   *io.PipeWriter
+		csv  *csv.Writer
+		json *json.Encoder
+		done chan error
+
+		run *Run
+	}
+)
+
+// connect returns store of export's destination
+//
+// Buckets must exist; they are never created automatically
+// since that would put them in the default region.
+func connect(e *Export) (store.Store, error) {
+	if e.Path != "" {
+		return plain.New(e.Path)
+	}
+
+	return minio.New(e.Bucket, minio.Options{
+		Endpoint:        e.Endpoint,
+		Secure:          e.Secure,
+		Strict:          true,
+		AccessKeyID:     e.AccessKeyID,
+		SecretAccessKey: e.SecretAccessKey,
+	})
+}
+
+// objectName returns name of the object in export's directory
+//
+// Local directory stores expect names that include the directory.
+func objectName(e *Export, elem ...string) string {
+	return path.Join(append([]string{e.Path, e.Prefix, e.Name}, elem...)...)
+}
+
+func (w *partitionWriter) write(day string, cells []interface{}) (err error) {
+	if day != w.day || w.pw == nil {
+		if err = w.close(); err != nil {
+			return err
+		}
+
+		if err = w.open(day); err != nil {
+			return err
+		}
+	}
+
+	w.run.Rows++
+
+	if w.csv != nil {
+		rec := make([]string, len(cells))
+		for i, c := range cells {
+			rec[i] = csvCell(c)
+		}
+
+		return w.csv.Write(rec)
+	}
+
+	row := make(map[string]interface{}, len(cells))
+	for i, c := range cells {
+		row[w.columns[i].Name] = c
+	}
+
+	return w.json.Encode(row)
+}
+
+func (w *partitionWriter) open(day string) error {
+	var (
+		name   = objectName(w.export, partitionColumn+"="+day, w.file)
+		pr, pw = io.Pipe()
+	)
+
+	w.day, w.pw, w.done = day, pw, make(chan error, 1)
+	w.csv, w.json = nil, nil
+	w.run.Files = append(w.run.Files, name)
+
+	go func() {
+		err := w.store.Save(name, pr)
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+
+	if w.export.Format == FormatNDJSON {
+		w.json = json.NewEncoder(pw)
+		return nil
+	}
+
+	w.csv = csv.NewWriter(pw)
+
+	header := make([]string, len(w.columns))
+	for i, c := range w.columns {
+		header[i] = c.Name
+	}
+
+	return w.csv.Write(header)
+}
+
+// close finishes the file of the current partition
+func (w *partitionWriter) close() error {
+	if w.pw == nil {
+		return nil
+	}
+
+	var err error
+	if w.csv != nil {
+		w.csv.Flush()
+		err = w.csv.Error()
+	}
+
+	if err != nil {
+		w.pw.CloseWithError(err)
+	} else {
+		w.pw.Close()
+	}
+
+	w.pw = nil
+
+	if serr := <-w.done; serr != nil {
+		return errors.Wrapf(serr, "could not store %s", w.run.Files[len(w.run.Files)-1])
+	}
+
+	return err
+}
+
+// abort stops writing the current file and removes what was stored of it
+func (w *partitionWriter) abort(err error) {
+	if w.pw == nil {
+		return
+	}
+
+	w.pw.CloseWithError(err)
+	<-w.done
+	w.pw = nil
+
+	_ = w.store.Remove(w.run.Files[len(w.run.Files)-1])
+}
+
+func csvCell(c interface{}) string {
+	switch v := c.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
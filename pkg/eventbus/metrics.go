@@ -0,0 +1,30 @@
+package eventbus
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricPublished = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_eventbus_published_total",
+		Help: "Number of messaging events published to other nodes.",
+	})
+
+	metricReceived = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_eventbus_received_total",
+		Help: "Number of messaging events received from other nodes.",
+	})
+
+	metricDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_eventbus_dropped_total",
+		Help: "Number of messaging events not published because the backend was slow or unavailable.",
+	})
+)
+
+func registerMetrics() {
+	prometheus.MustRegister(
+		metricPublished,
+		metricReceived,
+		metricDropped,
+	)
+}
@@ -0,0 +1,16 @@
+package eventbus
+
+import (
+	_ "unsafe"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+// Queue of messaging events, pushed by services and websocket sessions,
+// pulled by the websocket feed and delivered to sessions of this node
+//
+// Corteza has no way to replace it; pipe is swapped by the bridge, so
+// that events are published to other nodes before they are delivered.
+//
+//go:linkname pipe github.com/cortezaproject/corteza-server/messaging/repository.eventsPipe
+var pipe chan *messagingTypes.EventQueueItem
@@ -0,0 +1,112 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+)
+
+type (
+	// redisBackend uses Redis pub/sub
+	//
+	// Messages are published over pooled connections, subscription
+	// holds its own connection that is pinged to detect failures.
+	redisBackend struct {
+		addr string
+		pool *redis.Pool
+
+		timeout    time.Duration
+		pingPeriod time.Duration
+		readLimit  time.Duration
+	}
+)
+
+func newRedisBackend(opt *options.PubSubOpt) *redisBackend {
+	b := &redisBackend{
+		addr:       opt.RedisAddr,
+		timeout:    opt.RedisTimeout,
+		pingPeriod: opt.RedisPingPeriod,
+		readLimit:  opt.RedisPingTimeout + opt.RedisTimeout,
+	}
+
+	b.pool = &redis.Pool{
+		MaxIdle:     4,
+		IdleTimeout: 5 * time.Minute,
+		Dial: func() (redis.Conn, error) {
+			return b.dial(b.timeout)
+		},
+	}
+
+	return b
+}
+
+func (b *redisBackend) dial(readTimeout time.Duration) (redis.Conn, error) {
+	return redis.Dial(
+		"tcp",
+		b.addr,
+		redis.DialConnectTimeout(b.timeout),
+		redis.DialReadTimeout(readTimeout),
+		redis.DialWriteTimeout(b.timeout),
+	)
+}
+
+func (b *redisBackend) Publish(ctx context.Context, channel string, message []byte) error {
+	c := b.pool.Get()
+	defer c.Close()
+
+	_, err := c.Do("PUBLISH", channel, message)
+	return err
+}
+
+func (b *redisBackend) Subscribe(ctx context.Context, channel string, onMessage func(message []byte)) error {
+	c, err := b.dial(b.readLimit)
+	if err != nil {
+		return err
+	}
+
+	psc := redis.PubSubConn{Conn: c}
+	defer psc.Close()
+
+	if err = psc.Subscribe(channel); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		for {
+			switch n := psc.Receive().(type) {
+			case error:
+				done <- n
+				return
+			case redis.Message:
+				onMessage(n.Data)
+			case redis.Subscription:
+				if n.Count == 0 {
+					done <- nil
+					return
+				}
+			}
+		}
+	}()
+
+	ping := time.NewTicker(b.pingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ping.C:
+			if err = psc.Ping(""); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			// Receiving stops when the connection is closed
+			_ = psc.Unsubscribe()
+			return ctx.Err()
+		case err = <-done:
+			return err
+		}
+	}
+}
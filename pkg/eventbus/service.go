@@ -0,0 +1,179 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/messaging/repository"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+)
+
+type (
+	// bridge forwards events between the node's queue and the backend
+	bridge struct {
+		logger  *zap.Logger
+		backend Backend
+		channel string
+
+		// Identifies events published by this node
+		origin uint64
+
+		// Pushed to by corteza, after the pipe is swapped
+		in chan *messagingTypes.EventQueueItem
+
+		// Pulled from by the websocket feed of this node
+		local chan *messagingTypes.EventQueueItem
+
+		// Events waiting to be published
+		out chan []byte
+	}
+)
+
+var (
+	// Backends by pub/sub mode, other modes keep events on the node
+	Backends = map[string]func(*options.PubSubOpt) Backend{
+		ModeRedis: func(opt *options.PubSubOpt) Backend { return newRedisBackend(opt) },
+	}
+
+	// How long to wait before the backend is subscribed to again
+	resubscribeDelay = 5 * time.Second
+)
+
+// Init fans out messaging events to websocket sessions of all nodes
+//
+// Must be called after messaging services and websocket are initialized.
+// Configured with corteza's pub/sub options, PUBSUB_MODE=redis and
+// PUBSUB_REDIS_ADDR; events stay on the node with other modes.
+func Init(ctx context.Context, log *zap.Logger) error {
+	opt := options.PubSub("")
+
+	mk, ok := Backends[opt.Mode]
+	if !ok {
+		log.Debug("events are delivered to websocket sessions of this node only", zap.String("mode", opt.Mode))
+		return nil
+	}
+
+	// Makes sure the queue of the websocket feed exists
+	repository.Events()
+
+	b := &bridge{
+		logger:  log,
+		backend: mk(opt),
+		channel: options.EnvString("", "PUBSUB_EVENTS_CHANNEL", "crust.messaging.events"),
+		origin:  factory.Sonyflake.NextID(),
+		in:      make(chan *messagingTypes.EventQueueItem, eventsBacklog),
+		local:   pipe,
+		out:     make(chan []byte, publishBacklog),
+	}
+
+	registerMetrics()
+
+	// Events pushed from now on are published to other nodes
+	pipe = b.in
+
+	go b.forward(ctx)
+	go b.publish(ctx)
+	go b.subscribe(ctx)
+
+	log.Info("events are delivered to websocket sessions of all nodes",
+		zap.String("mode", opt.Mode),
+		zap.String("channel", b.channel),
+		zap.Uint64("origin", b.origin),
+	)
+
+	return nil
+}
+
+// forward delivers node's events locally and queues them for publishing
+func (b *bridge) forward(ctx context.Context) {
+	defer sentry.Recover()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-b.in:
+			b.deliver(ctx, item)
+
+			msg, err := json.Marshal(&envelope{Origin: b.origin, Item: item})
+			if err != nil {
+				b.logger.Error("could not encode event", zap.Error(err))
+				continue
+			}
+
+			select {
+			case b.out <- msg:
+			default:
+				metricDropped.Inc()
+			}
+		}
+	}
+}
+
+// publish sends queued events to the backend, one at a time
+func (b *bridge) publish(ctx context.Context) {
+	defer sentry.Recover()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-b.out:
+			if err := b.backend.Publish(ctx, b.channel, msg); err != nil {
+				metricDropped.Inc()
+				b.logger.Warn("could not publish event", zap.Error(err))
+				continue
+			}
+
+			metricPublished.Inc()
+		}
+	}
+}
+
+// subscribe delivers events of other nodes locally, until the context is canceled
+func (b *bridge) subscribe(ctx context.Context) {
+	defer sentry.Recover()
+
+	for {
+		err := b.backend.Subscribe(ctx, b.channel, func(msg []byte) {
+			e := &envelope{}
+			if err := json.Unmarshal(msg, e); err != nil || e.Item == nil {
+				b.logger.Warn("could not decode event", zap.Error(err))
+				return
+			}
+
+			if e.Origin == b.origin {
+				return
+			}
+
+			metricReceived.Inc()
+			b.deliver(ctx, e.Item)
+		})
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		b.logger.Error("subscription to events failed, events of other nodes are not delivered", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
+
+// deliver puts the event into the queue of the websocket feed
+func (b *bridge) deliver(ctx context.Context, item *messagingTypes.EventQueueItem) {
+	select {
+	case b.local <- item:
+	case <-ctx.Done():
+	}
+}
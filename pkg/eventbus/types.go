@@ -0,0 +1,36 @@
+package eventbus
+
+import (
+	"context"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// Backend delivers messages published on the channel to all nodes
+	//
+	// Subscribe blocks until the context is canceled or the connection
+	// is lost; nodes get messages they published too.
+	Backend interface {
+		Publish(ctx context.Context, channel string, message []byte) error
+		Subscribe(ctx context.Context, channel string, onMessage func(message []byte)) error
+	}
+
+	// envelope of the event, published to other nodes
+	envelope struct {
+		Origin uint64                         `json:"origin,string"`
+		Item   *messagingTypes.EventQueueItem `json:"item"`
+	}
+)
+
+const (
+	// Events are forwarded to other nodes by the bus
+	ModeRedis = "redis"
+
+	// Published events that wait to be sent to the backend, the
+	// rest are dropped while the backend is slow or unavailable
+	publishBacklog = 1024
+
+	// Same as corteza's queue of events
+	eventsBacklog = 512
+)
@@ -0,0 +1,40 @@
+package expiry
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// authService wraps auth service and leaves expired memberships out of tokens
+	authService struct {
+		service.AuthService
+		ctx context.Context
+	}
+)
+
+// Auth decorates auth service with expiring memberships
+//
+// Tokens are issued without roles of expired memberships, even
+// before the sweeper removes them.
+func Auth(as service.AuthService) service.AuthService {
+	return &authService{AuthService: as, ctx: context.Background()}
+}
+
+func (svc authService) With(ctx context.Context) service.AuthService {
+	return &authService{
+		AuthService: svc.AuthService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc authService) LoadRoleMemberships(u *types.User) error {
+	if err := svc.AuthService.LoadRoleMemberships(u); err != nil {
+		return err
+	}
+
+	u.SetRoles(defaultExpiry.filter(u.ID, u.Roles()))
+	return nil
+}
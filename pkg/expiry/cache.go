@@ -0,0 +1,128 @@
+package expiry
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// cache holds expirations of memberships, so that they are not
+	// loaded on every request; reloaded by the sweeper
+	cache struct {
+		sync.RWMutex
+
+		// Expiration times by user and role
+		users map[uint64]map[uint64]time.Time
+
+		// Current roles of users whose tokens have expired memberships
+		roles map[uint64]*resolved
+	}
+
+	// resolved roles are valid until the next membership of the user expires
+	resolved struct {
+		roleIDs []uint64
+		until   time.Time
+	}
+)
+
+func newCache() *cache {
+	return &cache{
+		users: map[uint64]map[uint64]time.Time{},
+		roles: map[uint64]*resolved{},
+	}
+}
+
+// load replaces all expirations and forgets resolved roles
+func (c *cache) load(set MembershipSet) {
+	users := map[uint64]map[uint64]time.Time{}
+	for _, m := range set {
+		if users[m.UserID] == nil {
+			users[m.UserID] = map[uint64]time.Time{}
+		}
+
+		users[m.UserID][m.RoleID] = m.ExpiresAt
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.users = users
+	c.roles = map[uint64]*resolved{}
+}
+
+// set stores (or removes, when zero) expiration of the membership
+func (c *cache) set(roleID, userID uint64, expiresAt time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if expiresAt.IsZero() {
+		delete(c.users[userID], roleID)
+	} else {
+		if c.users[userID] == nil {
+			c.users[userID] = map[uint64]time.Time{}
+		}
+
+		c.users[userID][roleID] = expiresAt
+	}
+
+	delete(c.roles, userID)
+}
+
+// filter returns roles without ones whose membership expired
+func (c *cache) filter(userID uint64, roleIDs []uint64, at time.Time) []uint64 {
+	c.RLock()
+	defer c.RUnlock()
+
+	out := make([]uint64, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		if exp, ok := c.users[userID][roleID]; !ok || exp.After(at) {
+			out = append(out, roleID)
+		}
+	}
+
+	return out
+}
+
+// expired checks if any of the roles has expired membership
+func (c *cache) expired(userID uint64, roleIDs []uint64, at time.Time) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.users[userID]) == 0 {
+		return false
+	}
+
+	for _, roleID := range roleIDs {
+		if exp, ok := c.users[userID][roleID]; ok && !exp.After(at) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolved returns current roles of the user, when they are still valid
+func (c *cache) resolved(userID uint64, at time.Time) ([]uint64, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if r, ok := c.roles[userID]; ok && (r.until.IsZero() || at.Before(r.until)) {
+		return r.roleIDs, true
+	}
+
+	return nil, false
+}
+
+// resolve stores current roles of the user, resolved at the given time
+func (c *cache) resolve(userID uint64, roleIDs []uint64, at time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	r := &resolved{roleIDs: roleIDs}
+	for _, exp := range c.users[userID] {
+		if exp.After(at) && (r.until.IsZero() || exp.Before(r.until)) {
+			r.until = exp
+		}
+	}
+
+	c.roles[userID] = r
+}
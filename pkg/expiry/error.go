@@ -0,0 +1,36 @@
+package expiry
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	expiryError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID          = expiryError{"InvalidID", fault.Invalid}
+	ErrInvalidExpiry      = expiryError{"InvalidExpiry", fault.Invalid}
+	ErrUserNotFound       = expiryError{"UserNotFound", fault.NotFound}
+	ErrMembershipNotFound = expiryError{"MembershipNotFound", fault.NotFound}
+	ErrNoPermissions      = expiryError{"NoPermissions", fault.Forbidden}
+)
+
+func (e expiryError) Error() string {
+	return e.String()
+}
+
+func (e expiryError) String() string {
+	return "crust.expiry." + e.name
+}
+
+func (e expiryError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e expiryError) withStack() *fault.Error {
+	return fault.New(e)
+}
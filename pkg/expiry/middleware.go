@@ -0,0 +1,29 @@
+package expiry
+
+import (
+	"net/http"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// Middleware removes roles of expired memberships from the identity
+//
+// Tokens hold roles that were resolved when they were issued, this
+// applies expirations to tokens issued before.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if defaultExpiry == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		i, err := defaultExpiry.with(r.Context()).identity(auth.GetIdentityFromContext(r.Context()))
+		if err != nil {
+			rest.Error(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.SetIdentityToContext(r.Context(), i)))
+	})
+}
@@ -0,0 +1,27 @@
+package expiry
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200217000000.expiry",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_system_role_member_expiry (
+  rel_role         BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+
+  expires_at       DATETIME        NOT NULL,
+
+  created_by       BIGINT UNSIGNED NOT NULL,
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_role, rel_user),
+  INDEX (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
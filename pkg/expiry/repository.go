@@ -0,0 +1,123 @@
+package expiry
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_system_role_member_expiry"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"rel_role",
+			"rel_user",
+			"expires_at",
+			"created_by",
+			"created_at",
+		).
+		From(r.table())
+}
+
+// Find returns expiring memberships of the role, all of them when roleID is 0
+func (r repository) Find(roleID uint64) (set MembershipSet, err error) {
+	q := r.query().OrderBy("expires_at")
+	if roleID > 0 {
+		q = q.Where(squirrel.Eq{"rel_role": roleID})
+	}
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) FindByID(roleID, userID uint64) (*Membership, error) {
+	var (
+		m = &Membership{}
+		q = r.query().Where(squirrel.Eq{"rel_role": roleID, "rel_user": userID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, m); err != nil {
+		return nil, err
+	} else if m.RoleID == 0 {
+		return nil, nil
+	}
+
+	return m, nil
+}
+
+// UserExists checks if the user exists and is not deleted
+func (r repository) UserExists(userID uint64) (bool, error) {
+	var (
+		ids []uint64
+		q   = squirrel.Select("id").From("sys_user").Where(squirrel.Eq{"id": userID, "deleted_at": nil})
+	)
+
+	return len(ids) > 0, rh.FetchAll(r.db(), q, &ids)
+}
+
+// Save adds the user to the role (when not a member already) and sets expiration
+func (r repository) Save(m *Membership) error {
+	return r.db().Transaction(func() error {
+		if _, err := r.db().Exec("INSERT IGNORE INTO sys_role_member (rel_role, rel_user) VALUES (?, ?)", m.RoleID, m.UserID); err != nil {
+			return errors.WithStack(err)
+		}
+
+		return r.db().Replace(r.table(), m)
+	})
+}
+
+// Delete removes expiration, membership is kept
+func (r repository) Delete(roleID, userID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_role": roleID, "rel_user": userID})
+}
+
+// RemoveExpired removes memberships that expired before the given time
+//
+// Expirations are kept, see Prune.
+func (r repository) RemoveExpired(at time.Time) (int64, error) {
+	res, err := r.db().Exec(
+		"DELETE m FROM sys_role_member AS m INNER JOIN "+r.table()+" AS e "+
+			"ON (e.rel_role = m.rel_role AND e.rel_user = m.rel_user) WHERE e.expires_at <= ?",
+		at,
+	)
+
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return res.RowsAffected()
+}
+
+// Prune removes expirations that are older than the given time
+func (r repository) Prune(before time.Time) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Lt{"expires_at": before})
+}
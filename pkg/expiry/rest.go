@@ -0,0 +1,50 @@
+package expiry
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts expiring role membership endpoints
+//
+// Expects to be mounted under a path with {roleID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("RoleMemberExpiry.List", func(r *http.Request) (interface{}, error) {
+		return DefaultExpiry.With(r.Context()).Find(
+			rest.ParamUint64(r, "roleID"),
+		)
+	}))
+
+	// Adds the user to the role until expiresAt, or changes the expiration
+	r.Put("/{userID}", rest.Handler("RoleMemberExpiry.Set", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			ExpiresAt time.Time `json:"expiresAt"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultExpiry.With(r.Context()).Set(
+			rest.ParamUint64(r, "roleID"),
+			rest.ParamUint64(r, "userID"),
+			body.ExpiresAt,
+		)
+	}))
+
+	// Removes the expiration, membership becomes permanent
+	r.Delete("/{userID}", rest.Handler("RoleMemberExpiry.Unset", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultExpiry.With(r.Context()).Unset(
+			rest.ParamUint64(r, "roleID"),
+			rest.ParamUint64(r, "userID"),
+		)
+	}))
+}
@@ -0,0 +1,77 @@
+package expiry
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// roleService wraps role service and hides expired memberships
+	roleService struct {
+		service.RoleService
+		ctx context.Context
+	}
+)
+
+// Role decorates role service with expiring memberships
+//
+// Expired memberships are not listed before the sweeper removes them.
+// Adding a member makes the membership permanent, removing a member
+// removes its expiration too.
+func Role(rs service.RoleService) service.RoleService {
+	return &roleService{RoleService: rs, ctx: context.Background()}
+}
+
+func (svc roleService) With(ctx context.Context) service.RoleService {
+	return &roleService{
+		RoleService: svc.RoleService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc roleService) Membership(userID uint64) ([]*types.RoleMember, error) {
+	mm, err := svc.RoleService.Membership(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.filter(mm), nil
+}
+
+func (svc roleService) MemberList(roleID uint64) ([]*types.RoleMember, error) {
+	mm, err := svc.RoleService.MemberList(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.filter(mm), nil
+}
+
+func (svc roleService) MemberAdd(roleID, userID uint64) error {
+	if err := svc.RoleService.MemberAdd(roleID, userID); err != nil {
+		return err
+	}
+
+	return defaultExpiry.with(svc.ctx).unset(roleID, userID)
+}
+
+func (svc roleService) MemberRemove(roleID, userID uint64) error {
+	if err := svc.RoleService.MemberRemove(roleID, userID); err != nil {
+		return err
+	}
+
+	return defaultExpiry.with(svc.ctx).unset(roleID, userID)
+}
+
+func (svc roleService) filter(mm []*types.RoleMember) []*types.RoleMember {
+	out := make([]*types.RoleMember, 0, len(mm))
+	for _, m := range mm {
+		if rr := defaultExpiry.filter(m.UserID, []uint64{m.RoleID}); len(rr) > 0 {
+			out = append(out, m)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,274 @@
+package expiry
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+	"github.com/crusttech/crust-server/pkg/rolecache"
+)
+
+type (
+	expiryService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		role service.RoleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageRoleMembers(context.Context, *types.Role) bool
+	}
+
+	ExpiryService interface {
+		With(ctx context.Context) ExpiryService
+
+		Find(roleID uint64) (MembershipSet, error)
+		Set(roleID, userID uint64, expiresAt time.Time) (*Membership, error)
+		Unset(roleID, userID uint64) error
+	}
+)
+
+var (
+	DefaultExpiry ExpiryService
+
+	// used by service decorators and middleware
+	defaultExpiry *expiryService
+
+	current = newCache()
+
+	// Expired memberships are kept as long as tokens issued before they expired are valid
+	retention = 30 * 24 * time.Hour
+
+	// now is used for expirations and can be overridden
+	now = time.Now
+)
+
+// Init initializes expiring memberships and starts the sweeper
+//
+// Must be called after system services are initialized and before
+// role tree, so that descendants of expired roles are not added.
+func Init(ctx context.Context, log *zap.Logger) error {
+	retention = options.JWT("").Expiry
+
+	svc := (&expiryService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		role:   service.DefaultRole,
+	}).with(ctx)
+
+	DefaultExpiry = svc
+	defaultExpiry = svc
+
+	service.DefaultRole = Role(service.DefaultRole)
+	service.DefaultAuth = Auth(service.DefaultAuth)
+
+	svc.sweep(ctx)
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc expiryService) With(ctx context.Context) ExpiryService {
+	return svc.with(ctx)
+}
+
+func (svc expiryService) with(ctx context.Context) *expiryService {
+	return &expiryService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		role: svc.role.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc expiryService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns memberships of the role that did not expire yet
+func (svc expiryService) Find(roleID uint64) (MembershipSet, error) {
+	if _, err := svc.role.FindByID(roleID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.Find(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(MembershipSet, 0, len(set))
+	for _, m := range set {
+		if !m.Expired(now()) {
+			out = append(out, m)
+		}
+	}
+
+	return out, nil
+}
+
+// Set adds the user to the role until the given time
+//
+// Members of the role get the expiration too; it can be extended
+// or shortened by setting it again.
+func (svc expiryService) Set(roleID, userID uint64, expiresAt time.Time) (*Membership, error) {
+	if _, err := svc.manageable(roleID); err != nil {
+		return nil, err
+	}
+
+	if userID == 0 {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	if !expiresAt.After(now()) {
+		return nil, ErrInvalidExpiry.withStack().WithMessage("expiration must be in the future")
+	}
+
+	if ok, err := svc.repository.UserExists(userID); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrUserNotFound.withStack().WithID("userID", userID)
+	}
+
+	m := &Membership{
+		RoleID:    roleID,
+		UserID:    userID,
+		ExpiresAt: expiresAt.UTC().Truncate(time.Second),
+		CreatedBy: auth.GetIdentityFromContext(svc.ctx).Identity(),
+		CreatedAt: now().UTC().Truncate(time.Second),
+	}
+
+	if err := svc.repository.Save(m); err != nil {
+		return nil, err
+	}
+
+	current.set(roleID, userID, m.ExpiresAt)
+
+	svc.log(zap.Uint64("roleID", roleID), zap.Uint64("userID", userID), zap.Time("expiresAt", m.ExpiresAt)).
+		Info("expiring role membership set")
+
+	return m, nil
+}
+
+// Unset removes expiration, the user stays member of the role
+func (svc expiryService) Unset(roleID, userID uint64) error {
+	if _, err := svc.manageable(roleID); err != nil {
+		return err
+	}
+
+	m, err := svc.repository.FindByID(roleID, userID)
+	if err != nil {
+		return err
+	} else if m == nil || m.Expired(now()) {
+		return ErrMembershipNotFound.withStack().WithID("roleID", roleID).WithID("userID", userID)
+	}
+
+	return svc.unset(roleID, userID)
+}
+
+func (svc expiryService) unset(roleID, userID uint64) error {
+	if err := svc.repository.Delete(roleID, userID); err != nil {
+		return err
+	}
+
+	current.set(roleID, userID, time.Time{})
+	return nil
+}
+
+// filter removes roles of expired memberships
+func (svc expiryService) filter(userID uint64, roleIDs []uint64) []uint64 {
+	return current.filter(userID, roleIDs, now())
+}
+
+// identity returns identity without roles of expired memberships
+//
+// Roles of the token were resolved when it was issued; when any of
+// them expired since, memberships are resolved again (with nested
+// roles, when role tree is enabled).
+func (svc expiryService) identity(i auth.Identifiable) (auth.Identifiable, error) {
+	if !i.Valid() || auth.IsSuperUser(i) || !current.expired(i.Identity(), i.Roles(), now()) {
+		return i, nil
+	}
+
+	if rr, ok := current.resolved(i.Identity(), now()); ok {
+		return auth.NewIdentity(i.Identity(), rr...), nil
+	}
+
+	// Cached roles were resolved before the membership expired
+	rolecache.Invalidate(i.Identity())
+
+	u := &types.User{ID: i.Identity()}
+	if err := service.DefaultAuth.With(auth.SetSuperUserContext(svc.ctx)).LoadRoleMemberships(u); err != nil {
+		return nil, err
+	}
+
+	current.resolve(u.ID, u.Roles(), now())
+	return auth.NewIdentity(u.ID, u.Roles()...), nil
+}
+
+func (svc expiryService) manageable(roleID uint64) (*types.Role, error) {
+	r, err := svc.role.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanManageRoleMembers(svc.ctx, r) {
+		return nil, ErrNoPermissions.withStack().WithID("roleID", roleID)
+	}
+
+	return r, nil
+}
+
+// sweep removes expired memberships and reloads expirations
+func (svc expiryService) sweep(ctx context.Context) {
+	r := Repository(ctx, factory.Database.MustGet("system").With(ctx))
+
+	if n, err := r.RemoveExpired(now()); err != nil {
+		svc.logger.Error("could not remove expired role memberships", zap.Error(err))
+	} else if n > 0 {
+		svc.logger.Info("expired role memberships removed", zap.Int64("count", n))
+		rolecache.Flush()
+	}
+
+	if err := r.Prune(now().Add(-retention)); err != nil {
+		svc.logger.Error("could not prune expired role memberships", zap.Error(err))
+	}
+
+	set, err := r.Find(0)
+	if err != nil {
+		svc.logger.Error("could not load role membership expirations", zap.Error(err))
+		return
+	}
+
+	current.load(set)
+}
+
+func (svc expiryService) watch(ctx context.Context) {
+	t := time.NewTicker(sweepInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.sweep(ctx)
+		}
+	}
+}
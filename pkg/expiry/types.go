@@ -0,0 +1,34 @@
+package expiry
+
+import (
+	"time"
+)
+
+type (
+	// Membership of the user in the role that ends at ExpiresAt
+	//
+	// Membership itself is kept by corteza (sys_role_member), it is
+	// removed by the sweeper after it expires. Expired memberships are
+	// kept for the lifetime of tokens, so that roles of tokens issued
+	// before the expiration are ignored too.
+	Membership struct {
+		RoleID    uint64    `json:"roleID,string" db:"rel_role"`
+		UserID    uint64    `json:"userID,string" db:"rel_user"`
+		ExpiresAt time.Time `json:"expiresAt" db:"expires_at"`
+
+		CreatedBy uint64    `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	MembershipSet []*Membership
+)
+
+const (
+	// How often expired memberships are removed and cache is reloaded
+	sweepInterval = time.Minute
+)
+
+// Expired checks if the membership expired at the given time
+func (m Membership) Expired(at time.Time) bool {
+	return !m.ExpiresAt.After(at)
+}
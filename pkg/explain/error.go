@@ -0,0 +1,35 @@
+package explain
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	explainError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions     = explainError{"NoPermissions", fault.Forbidden}
+	ErrOperationRequired = explainError{"OperationRequired", fault.Invalid}
+	ErrAppNotAvailable   = explainError{"AppNotAvailable", fault.Unavailable}
+	ErrUserNotFound      = explainError{"UserNotFound", fault.NotFound}
+)
+
+func (e explainError) Error() string {
+	return e.String()
+}
+
+func (e explainError) String() string {
+	return "crust.explain." + e.name
+}
+
+func (e explainError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e explainError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,25 @@
+package explain
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts explanation of effective permissions
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?userID=123&resource=compose:module:456&operation=record.read
+	r.Get("/", rest.Handler("Permissions.Explain", func(r *http.Request) (interface{}, error) {
+		return DefaultExplain.With(r.Context()).Explain(
+			rest.QueryUint64(r, "userID"),
+			permissions.Resource(r.URL.Query().Get("resource")),
+			permissions.Operation(r.URL.Query().Get("operation")),
+		)
+	}))
+}
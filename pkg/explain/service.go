@@ -0,0 +1,156 @@
+package explain
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/crusttech/crust-server/pkg/access"
+)
+
+type (
+	explainService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+	}
+
+	accessController interface {
+		CanGrant(context.Context) bool
+	}
+
+	rulesFinder interface {
+		FindRulesByRoleID(roleID uint64) permissions.RuleSet
+	}
+
+	ExplainService interface {
+		With(ctx context.Context) ExplainService
+
+		Explain(userID uint64, res permissions.Resource, op permissions.Operation) (*Explanation, error)
+	}
+)
+
+var (
+	DefaultExplain ExplainService
+)
+
+// Init initializes explanation of effective permissions
+//
+// Must be called after system services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultExplain = (&explainService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+	}).with(ctx)
+
+	return nil
+}
+
+func (svc explainService) With(ctx context.Context) ExplainService {
+	return svc.with(ctx)
+}
+
+func (svc explainService) with(ctx context.Context) *explainService {
+	return &explainService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc explainService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Explain tells if the user can perform the operation on the resource and why
+//
+// Roles are user's current memberships, not the ones in user's token.
+// Compose and messaging resources can be explained only when the app runs
+// in the same process (monolith).
+//
+// Explanations are available to those that can grant system permissions
+func (svc explainService) Explain(userID uint64, res permissions.Resource, op permissions.Operation) (*Explanation, error) {
+	if !svc.ac.CanGrant(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if op == "" {
+		return nil, ErrOperationRequired.withStack()
+	}
+
+	rf := rulesOf(res)
+	if rf == nil {
+		return nil, ErrAppNotAvailable.withStack()
+	}
+
+	ctx := auth.SetSuperUserContext(svc.ctx)
+
+	if _, err := service.DefaultUser.With(ctx).FindByID(userID); err != nil {
+		svc.log(zap.Uint64("userID", userID), zap.Error(err)).Debug("could not find user")
+		return nil, ErrUserNotFound.withStack()
+	}
+
+	mm, err := service.DefaultRole.With(ctx).Membership(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out = &Explanation{UserID: userID}
+		ids = make([]uint64, 0, len(mm))
+	)
+
+	for _, m := range mm {
+		r := &Role{RoleID: m.RoleID}
+		if role, err := service.DefaultRole.With(ctx).FindByID(m.RoleID); err == nil {
+			r.Name, r.Handle = role.Name, role.Handle
+		}
+
+		out.Roles = append(out.Roles, r)
+		ids = append(ids, m.RoleID)
+	}
+
+	out.Roles = append(out.Roles, &Role{RoleID: permissions.EveryoneRoleID, Everyone: true})
+
+	out.Trace = access.Explain(rf, res, op, ids...)
+
+	switch out.Access {
+	case permissions.Allow:
+		out.Verdict = VerdictAllow
+	case permissions.Deny:
+		out.Verdict = VerdictDeny
+	default:
+		out.Verdict = VerdictDefault
+	}
+
+	return out, nil
+}
+
+// rulesOf returns permission service of resource's app, nil when the app is not running
+func rulesOf(res permissions.Resource) rulesFinder {
+	switch res.GetService() {
+	case "system":
+		if service.DefaultPermissions != nil {
+			return service.DefaultPermissions
+		}
+	case "compose":
+		if composeService.DefaultPermissions != nil {
+			return composeService.DefaultPermissions
+		}
+	case "messaging":
+		if messagingService.DefaultPermissions != nil {
+			return messagingService.DefaultPermissions
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package explain
+
+import (
+	"github.com/crusttech/crust-server/pkg/access"
+)
+
+type (
+	// Explanation of user's access to the operation on the resource
+	Explanation struct {
+		UserID uint64 `json:"userID,string"`
+
+		// Roles of the user and everyone role
+		Roles []*Role `json:"roles"`
+
+		*access.Trace
+
+		// Allow or deny when a rule matched, default when operation's
+		// default decides (most operations are denied by default)
+		Verdict string `json:"verdict"`
+	}
+
+	Role struct {
+		RoleID   uint64 `json:"roleID,string"`
+		Name     string `json:"name,omitempty"`
+		Handle   string `json:"handle,omitempty"`
+		Everyone bool   `json:"everyone,omitempty"`
+	}
+)
+
+const (
+	VerdictAllow   = "allow"
+	VerdictDeny    = "deny"
+	VerdictDefault = "default"
+)
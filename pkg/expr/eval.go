@@ -0,0 +1,555 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Scope holds variables and functions available to the expression
+	//
+	// Values of type Func (or func(...interface{}) (interface{}, error))
+	// can be called from the expression
+	Scope map[string]interface{}
+
+	// Func is a function that can be called from the expression
+	Func func(args ...interface{}) (interface{}, error)
+
+	node interface {
+		eval(Scope) (interface{}, error)
+	}
+
+	literal struct {
+		value interface{}
+	}
+
+	ident struct {
+		name string
+	}
+
+	member struct {
+		object node
+		key    node
+	}
+
+	call struct {
+		name string
+		args []node
+	}
+
+	list struct {
+		items []node
+	}
+
+	unary struct {
+		op      string
+		operand node
+	}
+
+	binary struct {
+		op          string
+		left, right node
+	}
+)
+
+// Eval evaluates expression against the given scope
+func (e Expr) Eval(scope Scope) (interface{}, error) {
+	if scope == nil {
+		scope = Scope{}
+	}
+
+	v, err := e.root.eval(scope)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", e.src, err)
+	}
+
+	return v, nil
+}
+
+// Test evaluates expression and converts result into boolean
+func (e Expr) Test(scope Scope) (bool, error) {
+	v, err := e.Eval(scope)
+	if err != nil {
+		return false, err
+	}
+
+	return Truthy(v), nil
+}
+
+func (n *literal) eval(Scope) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *ident) eval(s Scope) (interface{}, error) {
+	if v, ok := s[n.name]; ok {
+		return v, nil
+	}
+
+	// Unknown identifiers resolve into nil; this makes expressions over
+	// sparse data (records with missing values) much simpler to write
+	return nil, nil
+}
+
+func (n *member) eval(s Scope) (interface{}, error) {
+	obj, err := n.object.eval(s)
+	if err != nil || obj == nil {
+		return nil, err
+	}
+
+	key, err := n.key.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o := obj.(type) {
+	case Scope:
+		return o[toString(key)], nil
+	case map[string]interface{}:
+		return o[toString(key)], nil
+	case map[string]string:
+		return o[toString(key)], nil
+	case map[string][]string:
+		return o[toString(key)], nil
+	}
+
+	rv := reflect.ValueOf(obj)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		i, ok := toNumber(key)
+		if !ok || i < 0 || int(i) >= rv.Len() {
+			return nil, nil
+		}
+
+		return rv.Index(int(i)).Interface(), nil
+	case reflect.Map:
+		if mv := rv.MapIndex(reflect.ValueOf(toString(key))); mv.IsValid() {
+			return mv.Interface(), nil
+		}
+
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("can not access %v on %T", key, obj)
+}
+
+func (n *call) eval(s Scope) (interface{}, error) {
+	var fn Func
+
+	switch f := s[n.name].(type) {
+	case Func:
+		fn = f
+	case func(...interface{}) (interface{}, error):
+		fn = f
+	case nil:
+		if fn = builtins[strings.ToLower(n.name)]; fn == nil {
+			return nil, fmt.Errorf("unknown function %s()", n.name)
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a function", n.name)
+	}
+
+	args := make([]interface{}, len(n.args))
+	for i := range n.args {
+		v, err := n.args[i].eval(s)
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = v
+	}
+
+	return fn(args...)
+}
+
+func (n *list) eval(s Scope) (interface{}, error) {
+	out := make([]interface{}, len(n.items))
+	for i := range n.items {
+		v, err := n.items[i].eval(s)
+		if err != nil {
+			return nil, err
+		}
+
+		out[i] = v
+	}
+
+	return out, nil
+}
+
+func (n *unary) eval(s Scope) (interface{}, error) {
+	v, err := n.operand.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		return !Truthy(v), nil
+	case "-":
+		if d, ok := v.(time.Duration); ok {
+			return -d, nil
+		}
+
+		if f, ok := toNumber(v); ok {
+			return -f, nil
+		}
+
+		return nil, fmt.Errorf("can not negate %T", v)
+	}
+
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func (n *binary) eval(s Scope) (interface{}, error) {
+	left, err := n.left.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Short-circuit logical operators
+	switch n.op {
+	case "&&":
+		if !Truthy(left) {
+			return false, nil
+		}
+
+		right, err := n.right.eval(s)
+		return Truthy(right), err
+
+	case "||":
+		if Truthy(left) {
+			return true, nil
+		}
+
+		right, err := n.right.eval(s)
+		return Truthy(right), err
+	}
+
+	right, err := n.right.eval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return Equal(left, right), nil
+	case "!=":
+		return !Equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		c, ok := Compare(left, right)
+		if !ok {
+			// Incomparable values (one of them is nil for example)
+			return false, nil
+		}
+
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "in":
+		return contains(right, left), nil
+	}
+
+	return arithmetic(n.op, left, right)
+}
+
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	// Time & duration arithmetic
+	switch l := left.(type) {
+	case time.Time:
+		switch r := right.(type) {
+		case time.Duration:
+			switch op {
+			case "+":
+				return l.Add(r), nil
+			case "-":
+				return l.Add(-r), nil
+			}
+		case time.Time:
+			if op == "-" {
+				return l.Sub(r), nil
+			}
+		}
+
+		return nil, fmt.Errorf("unsupported operation %s on time", op)
+
+	case time.Duration:
+		if r, ok := right.(time.Duration); ok {
+			switch op {
+			case "+":
+				return l + r, nil
+			case "-":
+				return l - r, nil
+			}
+		}
+
+		if r, ok := toNumber(right); ok {
+			switch op {
+			case "*":
+				return time.Duration(float64(l) * r), nil
+			case "/":
+				if r == 0 {
+					return nil, fmt.Errorf("division by zero")
+				}
+				return time.Duration(float64(l) / r), nil
+			}
+		}
+
+		return nil, fmt.Errorf("unsupported operation %s on duration", op)
+	}
+
+	if op == "+" {
+		// String concatenation when any of the operands is a
+		// non-numeric string
+		_, lNum := toNumber(left)
+		_, rNum := toNumber(right)
+
+		_, lStr := left.(string)
+		_, rStr := right.(string)
+
+		if (lStr && !lNum) || (rStr && !rNum) {
+			return toString(left) + toString(right), nil
+		}
+	}
+
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		if left == nil || right == nil {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("unsupported operation %T %s %T", left, op, right)
+	}
+
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(l, r), nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// Truthy converts any value into boolean
+//
+// nil, false, 0, empty strings, lists & maps and zero time are false
+func Truthy(v interface{}) bool {
+	switch c := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return c
+	case string:
+		return c != "" && c != "0" && strings.ToLower(c) != "false"
+	case time.Time:
+		return !c.IsZero()
+	case time.Duration:
+		return c != 0
+	}
+
+	if f, ok := toNumber(v); ok {
+		return f != 0
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len() > 0
+	case reflect.Ptr:
+		return !rv.IsNil()
+	}
+
+	return true
+}
+
+// Equal compares two values with type coercion
+//
+// Numbers and numeric strings are compared as numbers
+func Equal(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if c, ok := Compare(a, b); ok {
+		return c == 0
+	}
+
+	if ab, ok := a.(bool); ok {
+		return ab == Truthy(b)
+	}
+
+	if bb, ok := b.(bool); ok {
+		return bb == Truthy(a)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// Compare compares two values; returns false when values are not comparable
+func Compare(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+
+	if at, ok := toTime(a); ok {
+		if bt, ok := toTime(b); ok {
+			switch {
+			case at.Before(bt):
+				return -1, true
+			case at.After(bt):
+				return 1, true
+			}
+
+			return 0, true
+		}
+	}
+
+	if ad, ok := a.(time.Duration); ok {
+		if bd, ok := b.(time.Duration); ok {
+			return compareFloats(float64(ad), float64(bd)), true
+		}
+	}
+
+	af, aok := toNumber(a)
+	bf, bok := toNumber(b)
+	if aok && bok {
+		return compareFloats(af, bf), true
+	}
+
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		return strings.Compare(as, bs), true
+	}
+
+	return 0, false
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// contains checks if needle is in the haystack (list, string or map keys)
+func contains(haystack, needle interface{}) bool {
+	if haystack == nil {
+		return false
+	}
+
+	if s, ok := haystack.(string); ok {
+		return strings.Contains(s, toString(needle))
+	}
+
+	rv := reflect.ValueOf(haystack)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if Equal(rv.Index(i).Interface(), needle) {
+				return true
+			}
+		}
+	case reflect.Map:
+		return rv.MapIndex(reflect.ValueOf(toString(needle))).IsValid()
+	}
+
+	return false
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch c := v.(type) {
+	case float64:
+		return c, true
+	case float32:
+		return float64(c), true
+	case int:
+		return float64(c), true
+	case int64:
+		return float64(c), true
+	case int32:
+		return float64(c), true
+	case uint:
+		return float64(c), true
+	case uint64:
+		return float64(c), true
+	case uint32:
+		return float64(c), true
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(c), 64); err == nil {
+			return f, true
+		}
+	}
+
+	return 0, false
+}
+
+func toTime(v interface{}) (time.Time, bool) {
+	switch c := v.(type) {
+	case time.Time:
+		return c, true
+	case *time.Time:
+		if c != nil {
+			return *c, true
+		}
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, c); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// String converts any value into string
+//
+// Numbers are formatted without trailing zeros and time in RFC3339
+func String(v interface{}) string {
+	return toString(v)
+}
+
+func toString(v interface{}) string {
+	switch c := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return c
+	case float64:
+		return strconv.FormatFloat(c, 'f', -1, 64)
+	case time.Time:
+		return c.Format(time.RFC3339)
+	case fmt.Stringer:
+		return c.String()
+	}
+
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,167 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	// now is used by now() and today() and can be overridden
+	now = time.Now
+
+	builtins = map[string]Func{
+		"now": func(args ...interface{}) (interface{}, error) {
+			return now(), nil
+		},
+
+		"today": func(args ...interface{}) (interface{}, error) {
+			n := now()
+			return time.Date(n.Year(), n.Month(), n.Day(), 0, 0, 0, 0, n.Location()), nil
+		},
+
+		"date": func(args ...interface{}) (interface{}, error) {
+			if err := argCount(args, 1); err != nil {
+				return nil, err
+			}
+
+			if t, ok := toTime(args[0]); ok {
+				return t, nil
+			}
+
+			return nil, nil
+		},
+
+		"len": func(args ...interface{}) (interface{}, error) {
+			if err := argCount(args, 1); err != nil {
+				return nil, err
+			}
+
+			switch c := args[0].(type) {
+			case nil:
+				return float64(0), nil
+			case string:
+				return float64(len([]rune(c))), nil
+			}
+
+			rv := reflect.ValueOf(args[0])
+			switch rv.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				return float64(rv.Len()), nil
+			}
+
+			return nil, fmt.Errorf("len() does not support %T", args[0])
+		},
+
+		"empty": func(args ...interface{}) (interface{}, error) {
+			if err := argCount(args, 1); err != nil {
+				return nil, err
+			}
+
+			switch c := args[0].(type) {
+			case nil:
+				return true, nil
+			case string:
+				return strings.TrimSpace(c) == "", nil
+			}
+
+			rv := reflect.ValueOf(args[0])
+			switch rv.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				return rv.Len() == 0, nil
+			}
+
+			return false, nil
+		},
+
+		"lower": func(args ...interface{}) (interface{}, error) {
+			if err := argCount(args, 1); err != nil {
+				return nil, err
+			}
+
+			return strings.ToLower(toString(args[0])), nil
+		},
+
+		"upper": func(args ...interface{}) (interface{}, error) {
+			if err := argCount(args, 1); err != nil {
+				return nil, err
+			}
+
+			return strings.ToUpper(toString(args[0])), nil
+		},
+
+		"contains": func(args ...interface{}) (interface{}, error) {
+			if err := argCount(args, 2); err != nil {
+				return nil, err
+			}
+
+			return contains(args[0], args[1]), nil
+		},
+
+		"startswith": func(args ...interface{}) (interface{}, error) {
+			if err := argCount(args, 2); err != nil {
+				return nil, err
+			}
+
+			return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+		},
+
+		"coalesce": func(args ...interface{}) (interface{}, error) {
+			for _, a := range args {
+				if a != nil && a != "" {
+					return a, nil
+				}
+			}
+
+			return nil, nil
+		},
+
+		"number": func(args ...interface{}) (interface{}, error) {
+			if err := argCount(args, 1); err != nil {
+				return nil, err
+			}
+
+			if f, ok := toNumber(args[0]); ok {
+				return f, nil
+			}
+
+			return nil, nil
+		},
+
+		"round": func(args ...interface{}) (interface{}, error) {
+			if len(args) < 1 || len(args) > 2 {
+				return nil, fmt.Errorf("expecting 1 or 2 arguments, got %d", len(args))
+			}
+
+			f, ok := toNumber(args[0])
+			if !ok {
+				return nil, nil
+			}
+
+			var places float64
+			if len(args) == 2 {
+				places, _ = toNumber(args[1])
+			}
+
+			p := math.Pow(10, places)
+			return math.Round(f*p) / p, nil
+		},
+	}
+)
+
+// RegisterFunc adds function to the list of functions available to all expressions
+//
+// Not safe for concurrent use; should be called from init()
+func RegisterFunc(name string, fn Func) {
+	builtins[strings.ToLower(name)] = fn
+}
+
+func argCount(args []interface{}, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("expecting %d argument(s), got %d", n, len(args))
+	}
+
+	return nil
+}
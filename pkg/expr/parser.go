@@ -0,0 +1,532 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type (
+	// Expr is a parsed expression, ready to be evaluated
+	Expr struct {
+		src  string
+		root node
+	}
+
+	parser struct {
+		src    []rune
+		pos    int
+		tokens []token
+		cur    int
+	}
+
+	token struct {
+		kind tokenKind
+		val  string
+		pos  int
+	}
+
+	tokenKind int
+)
+
+const (
+	tEOF tokenKind = iota
+	tNumber
+	tDuration
+	tString
+	tIdent
+	tOperator
+	tLParen
+	tRParen
+	tLBracket
+	tRBracket
+	tComma
+	tDot
+)
+
+var (
+	// Multi-char operators must come before their single-char prefixes
+	operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "=", "!", "+", "-", "*", "/", "%"}
+
+	// Keywords that are used as operators
+	keywordOperators = map[string]string{
+		"and": "&&",
+		"or":  "||",
+		"not": "!",
+		"in":  "in",
+	}
+
+	durationUnits = map[string]time.Duration{
+		"s": time.Second,
+		"m": time.Minute,
+		"h": time.Hour,
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+)
+
+// Parse parses expression
+//
+// Supported syntax:
+//   - literals: numbers (42, 3.14), strings ("foo", 'bar'), true, false, null,
+//     durations (30s, 15m, 2h, 7d, 1w) and lists ([1, 2, 3])
+//   - identifiers with member and index access (record.values.name, list[0])
+//   - function calls (lower(user.email))
+//   - operators (by precedence): or/||, and/&&, not/!, comparison (==, =, !=, <, <=, >, >=, in),
+//     +, -, *, /, % and unary minus
+func Parse(src string) (*Expr, error) {
+	p := &parser{src: []rune(src)}
+
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+
+	if len(p.tokens) == 1 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.val, t.pos)
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+// MustParse parses expression and panics on error
+func MustParse(src string) *Expr {
+	e, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return e
+}
+
+func (e Expr) String() string {
+	return e.src
+}
+
+func (p *parser) tokenize() error {
+	for {
+		for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+			p.pos++
+		}
+
+		if p.pos >= len(p.src) {
+			p.tokens = append(p.tokens, token{kind: tEOF, pos: p.pos})
+			return nil
+		}
+
+		var (
+			start = p.pos
+			r     = p.src[p.pos]
+		)
+
+		switch {
+		case unicode.IsDigit(r):
+			for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+				p.pos++
+			}
+
+			num := string(p.src[start:p.pos])
+			unitStart := p.pos
+			for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) {
+				p.pos++
+			}
+
+			if unit := string(p.src[unitStart:p.pos]); unit != "" {
+				if _, ok := durationUnits[unit]; !ok {
+					return fmt.Errorf("unknown duration unit %q at position %d", unit, unitStart)
+				}
+
+				p.tokens = append(p.tokens, token{kind: tDuration, val: num + unit, pos: start})
+			} else {
+				p.tokens = append(p.tokens, token{kind: tNumber, val: num, pos: start})
+			}
+
+		case r == '"' || r == '\'':
+			var (
+				sb  strings.Builder
+				end = false
+			)
+
+			for p.pos++; p.pos < len(p.src); p.pos++ {
+				c := p.src[p.pos]
+				if c == '\\' && p.pos+1 < len(p.src) {
+					p.pos++
+					sb.WriteRune(p.src[p.pos])
+					continue
+				}
+
+				if c == r {
+					p.pos++
+					end = true
+					break
+				}
+
+				sb.WriteRune(c)
+			}
+
+			if !end {
+				return fmt.Errorf("unterminated string at position %d", start)
+			}
+
+			p.tokens = append(p.tokens, token{kind: tString, val: sb.String(), pos: start})
+
+		case unicode.IsLetter(r) || r == '_':
+			for p.pos < len(p.src) && (unicode.IsLetter(p.src[p.pos]) || unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '_') {
+				p.pos++
+			}
+
+			ident := string(p.src[start:p.pos])
+			if op, ok := keywordOperators[strings.ToLower(ident)]; ok {
+				p.tokens = append(p.tokens, token{kind: tOperator, val: op, pos: start})
+			} else {
+				p.tokens = append(p.tokens, token{kind: tIdent, val: ident, pos: start})
+			}
+
+		default:
+			var single = map[rune]tokenKind{
+				'(': tLParen,
+				')': tRParen,
+				'[': tLBracket,
+				']': tRBracket,
+				',': tComma,
+				'.': tDot,
+			}
+
+			if k, ok := single[r]; ok {
+				p.pos++
+				p.tokens = append(p.tokens, token{kind: k, val: string(r), pos: start})
+				continue
+			}
+
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(p.src[p.pos:]), op) {
+					p.pos += len([]rune(op))
+					p.tokens = append(p.tokens, token{kind: tOperator, val: op, pos: start})
+					matched = true
+					break
+				}
+			}
+
+			if !matched {
+				return fmt.Errorf("unexpected character %q at position %d", r, start)
+			}
+		}
+	}
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.cur]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.cur]
+	if t.kind != tEOF {
+		p.cur++
+	}
+
+	return t
+}
+
+func (p *parser) isOperator(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tOperator {
+		return "", false
+	}
+
+	for _, op := range ops {
+		if t.val == op {
+			return op, true
+		}
+	}
+
+	return "", false
+}
+
+func (p *parser) expect(k tokenKind, what string) error {
+	if t := p.next(); t.kind != k {
+		return fmt.Errorf("expecting %s at position %d", what, t.pos)
+	}
+
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.isOperator("||"); !ok {
+			return left, nil
+		}
+
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binary{op: "||", left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, ok := p.isOperator("&&"); !ok {
+			return left, nil
+		}
+
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binary{op: "&&", left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.isOperator("!"); ok {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unary{op: "!", operand: operand}, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.isOperator("==", "=", "!=", "<", "<=", ">", ">=", "in")
+	if !ok {
+		return left, nil
+	}
+
+	p.next()
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	if op == "=" {
+		op = "=="
+	}
+
+	return &binary{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.isOperator("+", "-")
+		if !ok {
+			return left, nil
+		}
+
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op, ok := p.isOperator("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binary{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.isOperator("-"); ok {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &unary{op: "-", operand: operand}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (n node, err error) {
+	if n, err = p.parsePrimary(); err != nil {
+		return
+	}
+
+	for {
+		switch p.peek().kind {
+		case tDot:
+			p.next()
+			t := p.next()
+			if t.kind != tIdent {
+				return nil, fmt.Errorf("expecting identifier at position %d", t.pos)
+			}
+
+			n = &member{object: n, key: &literal{value: t.val}}
+
+		case tLBracket:
+			p.next()
+			key, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			if err = p.expect(tRBracket, "]"); err != nil {
+				return nil, err
+			}
+
+			n = &member{object: n, key: key}
+
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tNumber:
+		f, err := strconv.ParseFloat(t.val, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", t.val, t.pos)
+		}
+
+		return &literal{value: f}, nil
+
+	case tDuration:
+		var (
+			l    = len(t.val) - 1
+			unit = durationUnits[t.val[l:]]
+		)
+
+		f, err := strconv.ParseFloat(t.val[:l], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q at position %d", t.val, t.pos)
+		}
+
+		return &literal{value: time.Duration(f * float64(unit))}, nil
+
+	case tString:
+		return &literal{value: t.val}, nil
+
+	case tIdent:
+		switch strings.ToLower(t.val) {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null", "nil":
+			return &literal{value: nil}, nil
+		}
+
+		if p.peek().kind != tLParen {
+			return &ident{name: t.val}, nil
+		}
+
+		p.next()
+		args, err := p.parseList(tRParen, ")")
+		if err != nil {
+			return nil, err
+		}
+
+		return &call{name: t.val, args: args}, nil
+
+	case tLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		return n, p.expect(tRParen, ")")
+
+	case tLBracket:
+		items, err := p.parseList(tRBracket, "]")
+		if err != nil {
+			return nil, err
+		}
+
+		return &list{items: items}, nil
+
+	case tEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q at position %d", t.val, t.pos)
+}
+
+// parseList parses comma separated expressions until the closing token
+func (p *parser) parseList(closing tokenKind, what string) (nn []node, err error) {
+	if p.peek().kind == closing {
+		p.next()
+		return
+	}
+
+	for {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		nn = append(nn, n)
+
+		if p.peek().kind == tComma {
+			p.next()
+			continue
+		}
+
+		return nn, p.expect(closing, what)
+	}
+}
@@ -0,0 +1,67 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+type (
+	// Template is a text with {{ expression }} placeholders
+	Template struct {
+		// Text around the placeholders; always one more than expressions
+		texts []string
+		exprs []*Expr
+	}
+)
+
+// ParseTemplate splits text into static parts and placeholder expressions
+func ParseTemplate(src string) (*Template, error) {
+	t := &Template{}
+
+	for {
+		start := strings.Index(src, "{{")
+		if start < 0 {
+			t.texts = append(t.texts, src)
+			return t, nil
+		}
+
+		end := strings.Index(src[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder at %d", start)
+		}
+
+		e, err := Parse(src[start+2 : start+end])
+		if err != nil {
+			return nil, err
+		}
+
+		t.texts = append(t.texts, src[:start])
+		t.exprs = append(t.exprs, e)
+		src = src[start+end+2:]
+	}
+}
+
+// Render evaluates placeholders and joins their values with the static parts
+//
+// Lists of strings (multi-value fields) are joined with comma.
+func (t Template) Render(s Scope) (string, error) {
+	var out = strings.Builder{}
+
+	for i, e := range t.exprs {
+		out.WriteString(t.texts[i])
+
+		v, err := e.Eval(s)
+		if err != nil {
+			return "", err
+		}
+
+		if ss, ok := v.([]string); ok {
+			out.WriteString(strings.Join(ss, ","))
+		} else {
+			out.WriteString(toString(v))
+		}
+	}
+
+	out.WriteString(t.texts[len(t.texts)-1])
+	return out.String(), nil
+}
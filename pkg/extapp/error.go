@@ -0,0 +1,38 @@
+package extapp
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	extappError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID     = extappError{"InvalidID", fault.Invalid}
+	ErrInvalidURL    = extappError{"InvalidURL", fault.Invalid}
+	ErrInvalidBlock  = extappError{"InvalidBlock", fault.Invalid}
+	ErrInvalidRecord = extappError{"InvalidRecord", fault.Invalid}
+	ErrInvalidToken  = extappError{"InvalidToken", fault.Forbidden}
+	ErrNoPermissions = extappError{"NoPermissions", fault.Forbidden}
+	ErrAppNotFound   = extappError{"AppNotFound", fault.NotFound}
+)
+
+func (e extappError) Error() string {
+	return e.String()
+}
+
+func (e extappError) String() string {
+	return "crust.extapp." + e.name
+}
+
+func (e extappError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e extappError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,30 @@
+package extapp
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200117000000.apps",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_external_app (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  name             VARCHAR(64)     NOT NULL,
+  url              TEXT            NOT NULL,
+  secret           VARCHAR(128)    NOT NULL,
+  token_ttl        INT             NOT NULL DEFAULT 0,
+
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       DATETIME            NULL DEFAULT NULL,
+  deleted_at       DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
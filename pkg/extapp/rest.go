@@ -0,0 +1,64 @@
+package extapp
+
+import (
+	"context"
+
+	"github.com/titpetric/factory/resputil"
+)
+
+//go:generate go run ../../codegen/rest
+
+type (
+	// controller implements endpoints defined in rest.json
+	controller struct{}
+)
+
+func (controller) List(ctx context.Context, req *ExternalAppListRequest) (interface{}, error) {
+	return DefaultApp.With(ctx).Find(AppFilter{
+		NamespaceID: req.NamespaceID,
+		Sort:        req.Sort,
+		Page:        req.Page,
+		PerPage:     req.PerPage,
+	})
+}
+
+func (controller) Create(ctx context.Context, req *ExternalAppCreateRequest) (interface{}, error) {
+	return DefaultApp.With(ctx).Create(&App{
+		NamespaceID: req.NamespaceID,
+		Name:        req.Name,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		TokenTTL:    req.TokenTTL,
+	})
+}
+
+func (controller) Read(ctx context.Context, req *ExternalAppReadRequest) (interface{}, error) {
+	return DefaultApp.With(ctx).FindByID(req.NamespaceID, req.AppID)
+}
+
+func (controller) Update(ctx context.Context, req *ExternalAppUpdateRequest) (interface{}, error) {
+	return DefaultApp.With(ctx).Update(&App{
+		ID:          req.AppID,
+		NamespaceID: req.NamespaceID,
+		Name:        req.Name,
+		URL:         req.URL,
+		Secret:      req.Secret,
+		TokenTTL:    req.TokenTTL,
+	})
+}
+
+func (controller) Delete(ctx context.Context, req *ExternalAppDeleteRequest) (interface{}, error) {
+	return resputil.OK(), DefaultApp.With(ctx).DeleteByID(req.NamespaceID, req.AppID)
+}
+
+func (controller) Token(ctx context.Context, req *ExternalAppTokenRequest) (interface{}, error) {
+	return DefaultApp.With(ctx).Token(req.NamespaceID, req.AppID, TokenRequest{
+		PageID:   req.PageID,
+		Block:    req.Block,
+		RecordID: req.RecordID,
+	})
+}
+
+func (controller) Verify(ctx context.Context, req *ExternalAppVerifyRequest) (interface{}, error) {
+	return DefaultApp.With(ctx).Verify(req.NamespaceID, req.AppID, req.Token)
+}
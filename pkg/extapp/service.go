@@ -0,0 +1,396 @@
+package extapp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	appService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		page      service.PageService
+		record    service.RecordService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	AppService interface {
+		With(ctx context.Context) AppService
+
+		FindByID(namespaceID, appID uint64) (*App, error)
+		Find(AppFilter) (AppSet, error)
+		Create(*App) (*App, error)
+		Update(*App) (*App, error)
+		DeleteByID(namespaceID, appID uint64) error
+
+		Token(namespaceID, appID uint64, req TokenRequest) (*Token, error)
+		Verify(namespaceID, appID uint64, token string) (*Claims, error)
+	}
+
+	// Claims are encoded into the token that is passed to the external app
+	//
+	// Subject holds ID of the user, audience holds ID of the app
+	Claims struct {
+		jwt.StandardClaims
+
+		NamespaceID string   `json:"namespaceID"`
+		PageID      string   `json:"pageID"`
+		ModuleID    string   `json:"moduleID,omitempty"`
+		RecordID    string   `json:"recordID,omitempty"`
+		Roles       []string `json:"roles"`
+	}
+)
+
+const (
+	tokenIssuer = "crust"
+)
+
+var (
+	DefaultApp AppService
+)
+
+// Init initializes external app service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultApp = (&appService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		page:      service.DefaultPage,
+		record:    service.DefaultRecord,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc appService) With(ctx context.Context) AppService {
+	return &appService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		page:      svc.page.With(ctx),
+		record:    svc.record.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc appService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc appService) FindByID(namespaceID, appID uint64) (*App, error) {
+	if _, err := svc.namespace.FindByID(namespaceID); err != nil {
+		return nil, err
+	}
+
+	a, err := svc.repository.FindByID(namespaceID, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.withoutSecret(), nil
+}
+
+func (svc appService) Find(f AppFilter) (AppSet, error) {
+	if _, err := svc.namespace.FindByID(f.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.Find(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set {
+		set[i] = set[i].withoutSecret()
+	}
+
+	return set, nil
+}
+
+// Create stores new external app
+//
+// Secret is generated when not given and returned only once, with the created app
+func (svc appService) Create(in *App) (*App, error) {
+	if err := svc.canManage(in.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	if err := validate(in); err != nil {
+		return nil, err
+	}
+
+	a := &App{
+		NamespaceID: in.NamespaceID,
+		Name:        in.Name,
+		URL:         in.URL,
+		Secret:      in.Secret,
+		TokenTTL:    in.TokenTTL,
+	}
+
+	if a.Secret == "" {
+		a.Secret = generateSecret()
+	}
+
+	return svc.repository.Create(a)
+}
+
+// Update modifies external app
+//
+// Secret is changed only when a new one is given
+func (svc appService) Update(upd *App) (*App, error) {
+	if err := svc.canManage(upd.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	if err := validate(upd); err != nil {
+		return nil, err
+	}
+
+	a, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Name = upd.Name
+	a.URL = upd.URL
+	a.TokenTTL = upd.TokenTTL
+
+	if upd.Secret != "" {
+		a.Secret = upd.Secret
+	}
+
+	if a, err = svc.repository.Update(a); err != nil {
+		return nil, err
+	}
+
+	return a.withoutSecret(), nil
+}
+
+func (svc appService) DeleteByID(namespaceID, appID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindByID(namespaceID, appID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, appID)
+}
+
+// Token issues short-lived signed token for the app embedded into the page block
+//
+// Token carries current user, user's roles and page & record context. Page
+// (and record when given) must be readable by the current user and the
+// referenced block must embed this app.
+func (svc appService) Token(namespaceID, appID uint64, req TokenRequest) (*Token, error) {
+	var (
+		p *types.Page
+		r *types.Record
+
+		identity = auth.GetIdentityFromContext(svc.ctx)
+	)
+
+	a, err := svc.repository.FindByID(namespaceID, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	if p, err = svc.page.FindByID(namespaceID, req.PageID); err != nil {
+		return nil, err
+	}
+
+	if req.Block < 0 || req.Block >= len(p.Blocks) || BlockAppID(p.Blocks[req.Block]) != a.ID {
+		return nil, ErrInvalidBlock.withStack()
+	}
+
+	if req.RecordID > 0 {
+		if p.ModuleID == 0 {
+			return nil, ErrInvalidRecord.withStack()
+		}
+
+		if r, err = svc.record.FindByID(namespaceID, req.RecordID); err != nil {
+			return nil, err
+		}
+
+		if r.ModuleID != p.ModuleID {
+			return nil, ErrInvalidRecord.withStack()
+		}
+	}
+
+	var (
+		c = Claims{
+			StandardClaims: jwt.StandardClaims{
+				Id:      strconv.FormatUint(factory.Sonyflake.NextID(), 10),
+				Subject: strconv.FormatUint(identity.Identity(), 10),
+			},
+			NamespaceID: strconv.FormatUint(namespaceID, 10),
+			PageID:      strconv.FormatUint(p.ID, 10),
+			Roles:       []string{},
+		}
+	)
+
+	if p.ModuleID > 0 {
+		c.ModuleID = strconv.FormatUint(p.ModuleID, 10)
+	}
+
+	if r != nil {
+		c.RecordID = strconv.FormatUint(r.ID, 10)
+	}
+
+	for _, roleID := range identity.Roles() {
+		c.Roles = append(c.Roles, strconv.FormatUint(roleID, 10))
+	}
+
+	signed, exp, err := sign(*a, c, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("appID", a.ID), zap.Uint64("pageID", p.ID), zap.Uint64("recordID", req.RecordID)).
+		Debug("external app token issued")
+
+	return &Token{
+		Token:     signed,
+		URL:       withToken(a.URL, signed),
+		ExpiresAt: exp,
+	}, nil
+}
+
+// Verify checks the token that app received and returns its claims
+//
+// Apps that can not verify tokens themselves send them back, in the
+// X-Crust-App-Token header; token must be signed with app's secret,
+// issued for this app and not expired.
+func (svc appService) Verify(namespaceID, appID uint64, token string) (*Claims, error) {
+	a, err := svc.repository.FindByID(namespaceID, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	return verify(*a, token, time.Now())
+}
+
+func (svc appService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+func validate(a *App) error {
+	if !IsValidURL(a.URL) {
+		return ErrInvalidURL.withStack()
+	}
+
+	return nil
+}
+
+func (a App) withoutSecret() *App {
+	a.Secret = ""
+	return &a
+}
+
+// sign issues token with the claims for the app, signed with app's secret
+//
+// Returns the token and time when it expires.
+func sign(a App, c Claims, now time.Time) (string, time.Time, error) {
+	exp := now.Add(a.ttl())
+
+	c.Issuer = tokenIssuer
+	c.Audience = strconv.FormatUint(a.ID, 10)
+	c.IssuedAt = now.Unix()
+	c.ExpiresAt = exp.Unix()
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(a.Secret))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signed, exp, nil
+}
+
+// verify checks that token was signed (HS256) with app's secret,
+// issued for the app and that it did not expire yet
+func verify(a App, token string, now time.Time) (*Claims, error) {
+	var (
+		c = &Claims{}
+		p = &jwt.Parser{
+			ValidMethods:         []string{jwt.SigningMethodHS256.Alg()},
+			SkipClaimsValidation: true,
+		}
+	)
+
+	_, err := p.ParseWithClaims(token, c, func(*jwt.Token) (interface{}, error) {
+		return []byte(a.Secret), nil
+	})
+
+	switch {
+	case err != nil,
+		!c.VerifyIssuer(tokenIssuer, true),
+		!c.VerifyAudience(strconv.FormatUint(a.ID, 10), true),
+		!c.VerifyExpiresAt(now.Unix(), true):
+		return nil, ErrInvalidToken.withStack()
+	}
+
+	return c, nil
+}
+
+// withToken appends token to app's URL as a fragment
+//
+// Fragment is not sent with the request that loads the app into the iframe,
+// so the token does not end up in access logs of the app or proxies.
+func withToken(appURL, token string) string {
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return appURL
+	}
+
+	u.Fragment = "token=" + token
+
+	return u.String()
+}
+
+func generateSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
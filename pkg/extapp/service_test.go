@@ -0,0 +1,156 @@
+package extapp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+func TestSignTTL(t *testing.T) {
+	var (
+		now = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	tests := []struct {
+		name string
+		ttl  int
+		want time.Duration
+	}{
+		{"default", 0, 5 * time.Minute},
+		{"negative", -60, 5 * time.Minute},
+		{"within bounds", 600, 10 * time.Minute},
+		{"at maximum", 3600, time.Hour},
+		{"over maximum", 86400, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := App{ID: 1, Secret: "secret", TokenTTL: tt.ttl}
+
+			token, exp, err := sign(a, Claims{}, now)
+			if err != nil {
+				t.Fatalf("could not sign token: %v", err)
+			}
+
+			if !exp.Equal(now.Add(tt.want)) {
+				t.Errorf("expected token to expire at %v, got %v", now.Add(tt.want), exp)
+			}
+
+			c, err := verify(a, token, now)
+			if err != nil {
+				t.Fatalf("expected token to be valid, got %v", err)
+			}
+
+			if c.IssuedAt != now.Unix() || c.ExpiresAt != now.Add(tt.want).Unix() {
+				t.Errorf("expected token to be valid from %v for %v, got %d-%d", now, tt.want, c.IssuedAt, c.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	var (
+		now = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		app   = App{ID: 1, Secret: "secret"}
+		other = App{ID: 2, Secret: "other secret"}
+
+		claims = Claims{
+			StandardClaims: jwt.StandardClaims{Subject: "100"},
+			NamespaceID:    "10",
+			PageID:         "20",
+			RecordID:       "30",
+			Roles:          []string{"1", "2"},
+		}
+
+		signed = func(a App, at time.Time) func(*testing.T) string {
+			return func(t *testing.T) string {
+				token, _, err := sign(a, claims, at)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return token
+			}
+		}
+
+		// token of the app, signed with other method or other claims
+		with = func(m jwt.SigningMethod, key interface{}, modify func(*Claims)) func(*testing.T) string {
+			return func(t *testing.T) string {
+				c := claims
+				c.Issuer = tokenIssuer
+				c.Audience = "1"
+				c.ExpiresAt = now.Add(time.Minute).Unix()
+				modify(&c)
+
+				token, err := jwt.NewWithClaims(m, c).SignedString(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return token
+			}
+		}
+
+		keep = func(*Claims) {}
+	)
+
+	tests := []struct {
+		name  string
+		token func(*testing.T) string
+		at    time.Time
+		err   error
+	}{
+		{"token of the app", signed(app, now), now.Add(time.Minute), nil},
+		{"at expiration", signed(app, now), now.Add(defaultTokenTTL * time.Second), nil},
+		{"expired", signed(app, now), now.Add(defaultTokenTTL*time.Second + time.Second), ErrInvalidToken},
+		{"token of other app", signed(other, now), now, ErrInvalidToken},
+		{"other app with the same secret", signed(App{ID: 2, Secret: app.Secret}, now), now, ErrInvalidToken},
+		{"signed with HS256", with(jwt.SigningMethodHS256, []byte(app.Secret), keep), now, nil},
+		{"signed with HS512", with(jwt.SigningMethodHS512, []byte(app.Secret), keep), now, ErrInvalidToken},
+		{"unsigned", with(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, keep), now, ErrInvalidToken},
+		{"other issuer", with(jwt.SigningMethodHS256, []byte(app.Secret), func(c *Claims) { c.Issuer = "corteza" }), now, ErrInvalidToken},
+		{"without expiration", with(jwt.SigningMethodHS256, []byte(app.Secret), func(c *Claims) { c.ExpiresAt = 0 }), now, ErrInvalidToken},
+		{"malformed", func(*testing.T) string { return "token" }, now, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := verify(app, tt.token(t), tt.at)
+			if tt.err != nil {
+				if !fault.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("expected token to be valid, got %v", err)
+			}
+
+			if c.Subject != "100" || c.RecordID != "30" || len(c.Roles) != 2 {
+				t.Errorf("expected claims of the token, got %+v", c)
+			}
+		})
+	}
+}
+
+func TestWithToken(t *testing.T) {
+	got := withToken("https://app.example.com/embed?lang=en", "a.b.c")
+
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Query().Get("token") != "" || u.Query().Get("lang") != "en" {
+		t.Errorf("expected token not to be in the query, got %s", got)
+	}
+
+	if u.Fragment != "token=a.b.c" {
+		t.Errorf("expected token in the fragment, got %s", got)
+	}
+}
@@ -0,0 +1,98 @@
+package extapp
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+//go:generate go run ../../codegen/repository -type App -table crust_compose_external_app -database compose -sort name
+
+type (
+	// App is an external application that can be embedded into
+	// compose pages through an external app page block
+	//
+	// Secret is used to sign tokens that are passed to the app;
+	// it is never returned after the app is created.
+	App struct {
+		ID          uint64 `json:"appID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace" dal:"scope"`
+
+		Name   string `json:"name" db:"name" dal:"sort"`
+		URL    string `json:"url" db:"url"`
+		Secret string `json:"secret,omitempty" db:"secret"`
+
+		// Token lifetime in seconds
+		TokenTTL int `json:"tokenTTL" db:"token_ttl"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at" dal:"sort"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	AppSet []*App
+
+	// TokenRequest describes where the app is embedded
+	TokenRequest struct {
+		PageID   uint64 `json:"pageID,string"`
+		Block    int    `json:"block"`
+		RecordID uint64 `json:"recordID,string,omitempty"`
+	}
+
+	// Token is a short-lived signed token with URL of the app
+	// that should be loaded into the iframe
+	//
+	// URL carries the token in its fragment (#token=...); apps pass it on
+	// in the X-Crust-App-Token header, never in the query.
+	Token struct {
+		Token     string    `json:"token"`
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+)
+
+const (
+	// BlockKind is kind of the page block that embeds an external app
+	//
+	// Block options must contain appID
+	BlockKind = "ExternalApp"
+
+	defaultTokenTTL = 5 * 60
+	maxTokenTTL     = 60 * 60
+)
+
+func IsValidURL(u string) bool {
+	p, err := url.Parse(u)
+	return err == nil && (p.Scheme == "https" || p.Scheme == "http") && p.Host != ""
+}
+
+// ttl returns token lifetime, within allowed bounds
+func (a App) ttl() time.Duration {
+	switch {
+	case a.TokenTTL <= 0:
+		return defaultTokenTTL * time.Second
+	case a.TokenTTL > maxTokenTTL:
+		return maxTokenTTL * time.Second
+	}
+
+	return time.Duration(a.TokenTTL) * time.Second
+}
+
+// BlockAppID returns ID of the app referenced by the page block
+func BlockAppID(b types.PageBlock) uint64 {
+	if b.Kind != BlockKind || b.Options == nil {
+		return 0
+	}
+
+	var id uint64
+	switch v := b.Options["appID"].(type) {
+	case string:
+		fmt.Sscan(v, &id)
+	case float64:
+		id = uint64(v)
+	}
+
+	return id
+}
@@ -0,0 +1,50 @@
+package extensions
+
+import (
+	"github.com/crusttech/crust-server/pkg/functions"
+	"github.com/crusttech/crust-server/pkg/offline"
+	"github.com/crusttech/crust-server/pkg/search"
+	"github.com/crusttech/crust-server/pkg/suggest"
+	"github.com/crusttech/crust-server/pkg/webdav"
+)
+
+var (
+	// bundle holds extensions that depend on services of all apps
+	// and are available only when running as a monolith
+	bundle = app{
+		name: "bundle",
+
+		extensions: []extension{
+			{
+				name:   "search",
+				init:   search.Init,
+				path:   "/search",
+				routes: search.MountRoutes,
+			},
+			{
+				name:   "offline",
+				init:   offline.Init,
+				path:   "/offline",
+				routes: offline.MountRoutes,
+			},
+			{
+				name:   "suggest",
+				path:   "/suggest",
+				routes: suggest.MountSuggestRoutes,
+			},
+			{
+				name:   "webdav",
+				init:   webdav.Init,
+				path:   "/webdav",
+				routes: webdav.MountRoutes,
+			},
+			{
+				// Called by automation scripts
+				name:   "functions",
+				init:   functions.Init,
+				path:   "/functions",
+				routes: functions.MountRoutes,
+			},
+		},
+	}
+)
@@ -0,0 +1,380 @@
+package extensions
+
+import (
+	"github.com/crusttech/crust-server/pkg/alerts"
+	"github.com/crusttech/crust-server/pkg/bridges"
+	"github.com/crusttech/crust-server/pkg/capture"
+	"github.com/crusttech/crust-server/pkg/cdc"
+	"github.com/crusttech/crust-server/pkg/compress"
+	"github.com/crusttech/crust-server/pkg/connectors"
+	"github.com/crusttech/crust-server/pkg/consistency"
+	"github.com/crusttech/crust-server/pkg/crmsync"
+	"github.com/crusttech/crust-server/pkg/currency"
+	"github.com/crusttech/crust-server/pkg/deadline"
+	"github.com/crusttech/crust-server/pkg/dependencies"
+	"github.com/crusttech/crust-server/pkg/diagnostics"
+	"github.com/crusttech/crust-server/pkg/drift"
+	"github.com/crusttech/crust-server/pkg/etl"
+	"github.com/crusttech/crust-server/pkg/extapp"
+	"github.com/crusttech/crust-server/pkg/federation"
+	"github.com/crusttech/crust-server/pkg/feeds"
+	"github.com/crusttech/crust-server/pkg/gc"
+	"github.com/crusttech/crust-server/pkg/hierarchy"
+	"github.com/crusttech/crust-server/pkg/httpaction"
+	"github.com/crusttech/crust-server/pkg/ingest"
+	"github.com/crusttech/crust-server/pkg/live"
+	"github.com/crusttech/crust-server/pkg/localized"
+	"github.com/crusttech/crust-server/pkg/locks"
+	"github.com/crusttech/crust-server/pkg/outbox"
+	"github.com/crusttech/crust-server/pkg/outmail"
+	"github.com/crusttech/crust-server/pkg/permhistory"
+	"github.com/crusttech/crust-server/pkg/records"
+	"github.com/crusttech/crust-server/pkg/recurrence"
+	"github.com/crusttech/crust-server/pkg/relations"
+	"github.com/crusttech/crust-server/pkg/reports"
+	"github.com/crusttech/crust-server/pkg/residency"
+	"github.com/crusttech/crust-server/pkg/retry"
+	"github.com/crusttech/crust-server/pkg/revisions"
+	"github.com/crusttech/crust-server/pkg/s3events"
+	"github.com/crusttech/crust-server/pkg/sandbox"
+	"github.com/crusttech/crust-server/pkg/schemacache"
+	"github.com/crusttech/crust-server/pkg/seed"
+	"github.com/crusttech/crust-server/pkg/storage"
+	"github.com/crusttech/crust-server/pkg/suggest"
+	"github.com/crusttech/crust-server/pkg/templates"
+	"github.com/crusttech/crust-server/pkg/triggers"
+	"github.com/crusttech/crust-server/pkg/versions"
+	"github.com/crusttech/crust-server/pkg/visibility"
+)
+
+var (
+	compose = app{
+		name:   "compose",
+		prefix: "/compose",
+
+		extensions: []extension{
+			{
+				// Must stay first, it recreates record service
+				// that other extensions decorate
+				name:       "triggers",
+				migrations: triggers.Migrations,
+				init:       triggers.Init,
+				path:       "/namespace/{namespaceID}/trigger-filters",
+				routes:     triggers.MountRoutes,
+			},
+			{
+				// Must come before extensions that use store
+				// or decorate attachment service
+				name:    "storage",
+				init:    storage.InitCompose,
+				command: storage.Command,
+			},
+			{
+				// Right after triggers, so that all record
+				// services load modules from the cache
+				name:   "schemacache",
+				init:   schemacache.Init,
+				path:   "/schema-cache",
+				routes: schemacache.MountRoutes,
+			},
+			{
+				name:       "sandbox",
+				migrations: sandbox.Migrations,
+				init:       sandbox.Init,
+				path:       "/automation-sandbox",
+				routes:     sandbox.MountRoutes,
+			},
+			{
+				name:       "retry",
+				migrations: retry.Migrations,
+				init:       retry.Init,
+				path:       "/automation-jobs",
+				routes:     retry.MountRoutes,
+			},
+			{
+				name:       "visibility",
+				migrations: visibility.Migrations,
+				init:       visibility.Init,
+				path:       "/namespace/{namespaceID}/visibility",
+				routes:     visibility.MountRoutes,
+			},
+			{
+				name:       "extapp",
+				migrations: extapp.Migrations,
+				init:       extapp.Init,
+				path:       "/namespace/{namespaceID}/external-apps",
+				routes:     extapp.MountRoutes,
+			},
+			{
+				name:       "localized",
+				init:       localized.Init,
+				middleware: localized.Middleware,
+			},
+			{
+				name:       "currency",
+				migrations: currency.Migrations,
+				init:       currency.Init,
+				path:       "/currency",
+				routes:     currency.MountRoutes,
+			},
+			{
+				name:       "hierarchy",
+				migrations: hierarchy.Migrations,
+				init:       hierarchy.Init,
+				path:       "/namespace/{namespaceID}/module/{moduleID}/tree",
+				routes:     hierarchy.MountRoutes,
+			},
+			{
+				name:       "relations",
+				migrations: relations.Migrations,
+				init:       relations.Init,
+				path:       "/namespace/{namespaceID}/record/{recordID}/relations",
+				routes:     relations.MountRoutes,
+			},
+			{
+				name:       "suggest",
+				migrations: suggest.Migrations,
+				init:       suggest.Init,
+				path:       "/namespace/{namespaceID}/module/{moduleID}/suggest",
+				routes:     suggest.MountRoutes,
+			},
+			{
+				name:   "records",
+				init:   records.Init,
+				path:   "/namespace/{namespaceID}/module/{moduleID}/records",
+				routes: records.MountRoutes,
+			},
+			{
+				name:       "templates",
+				migrations: templates.Migrations,
+				init:       templates.Init,
+				path:       "/namespace/{namespaceID}/module/{moduleID}/record-templates",
+				routes:     templates.MountRoutes,
+			},
+			{
+				name:       "recurrence",
+				migrations: recurrence.Migrations,
+				init:       recurrence.Init,
+				path:       "/namespace/{namespaceID}/module/{moduleID}/record-recurrences",
+				routes:     recurrence.MountRoutes,
+			},
+			{
+				name:       "ingest",
+				migrations: ingest.Migrations,
+				init:       ingest.Init,
+				path:       "/namespace/{namespaceID}/module/{moduleID}/ingest-profiles",
+				routes:     ingest.MountRoutes,
+			},
+			{
+				name:       "s3events",
+				migrations: s3events.Migrations,
+				init:       s3events.Init,
+				path:       "/namespace/{namespaceID}/module/{moduleID}/s3-sources",
+				routes:     s3events.MountRoutes,
+			},
+			{
+				// SNS subscriptions, without authentication
+				name:   "s3events-notify",
+				path:   "/s3-events",
+				routes: s3events.MountNotificationRoutes,
+			},
+			{
+				name:       "residency",
+				migrations: residency.Migrations,
+				init:       residency.Init,
+				path:       "/namespace/{namespaceID}/residency",
+				routes:     residency.MountRoutes,
+			},
+			{
+				name:       "cdc",
+				migrations: cdc.Migrations,
+				init:       cdc.Init,
+				path:       "/namespace/{namespaceID}/cdc",
+				routes:     cdc.MountRoutes,
+			},
+			{
+				// Messaging statistics are available only when running as a monolith
+				name:       "etl",
+				migrations: etl.Migrations,
+				init:       etl.Init,
+				path:       "/namespace/{namespaceID}/exports",
+				routes:     etl.MountRoutes,
+			},
+			{
+				name:       "federation",
+				migrations: federation.Migrations,
+				init:       federation.Init,
+				path:       "/namespace/{namespaceID}/federation",
+				routes:     federation.MountRoutes,
+			},
+			{
+				// Called by federated nodes, authenticated with node tokens
+				name:   "federation-peer",
+				path:   "/federation",
+				routes: federation.MountPeerRoutes,
+			},
+			{
+				// Salesforce and ServiceNow objects, synced with modules
+				name:       "crmsync",
+				migrations: crmsync.Migrations,
+				init:       crmsync.Init,
+				path:       "/namespace/{namespaceID}/crm-sync",
+				routes:     crmsync.MountRoutes,
+			},
+			{
+				name:       "reports",
+				migrations: reports.Migrations,
+				init:       reports.Init,
+				path:       "/namespace/{namespaceID}/report-subscriptions",
+				routes:     reports.MountRoutes,
+			},
+			{
+				name:       "outbox",
+				migrations: outbox.Migrations,
+				init:       outbox.Init,
+			},
+			{
+				name:       "alerts",
+				migrations: alerts.Migrations,
+				init:       alerts.Init,
+				path:       "/namespace/{namespaceID}/alert-rules",
+				routes:     alerts.MountRoutes,
+			},
+			{
+				name:       "httpaction",
+				migrations: httpaction.Migrations,
+				init:       httpaction.Init,
+				path:       "/namespace/{namespaceID}/http-actions",
+				routes:     httpaction.MountRoutes,
+			},
+			{
+				// Messages are posted only when running as a monolith
+				name:       "bridges",
+				migrations: bridges.Migrations,
+				init:       bridges.Init,
+				path:       "/namespace/{namespaceID}/bridges",
+				routes:     bridges.MountRoutes,
+			},
+			{
+				// Messages can be captured only when running as a monolith
+				name:       "capture",
+				migrations: capture.Migrations,
+				init:       capture.Init,
+				path:       "/namespace/{namespaceID}/capture-actions",
+				routes:     capture.MountRoutes,
+			},
+			{
+				name:       "locks",
+				migrations: locks.Migrations,
+				init:       locks.Init,
+				path:       "/namespace/{namespaceID}/module/{moduleID}/record-locks",
+				routes:     locks.MountRoutes,
+			},
+			{
+				// Comments of changes are read from X-Crust-Change-Comment header
+				name:       "revisions",
+				migrations: revisions.Migrations,
+				init:       revisions.Init,
+				path:       "/namespace/{namespaceID}/module/{moduleID}/revisions",
+				routes:     revisions.MountModuleRoutes,
+				middleware: revisions.Middleware,
+			},
+			{
+				name:   "revisions-page",
+				path:   "/namespace/{namespaceID}/page/{pageID}/revisions",
+				routes: revisions.MountPageRoutes,
+			},
+			{
+				// Deletion of resources with dependents is logged or refused,
+				// see crust.dependencies.on-delete setting
+				name:   "dependencies",
+				init:   dependencies.Init,
+				path:   "/namespace/{namespaceID}/dependencies",
+				routes: dependencies.MountRoutes,
+			},
+			{
+				// Bundles are compared across instances (dev, stage, prod)
+				name:   "drift",
+				init:   drift.Init,
+				path:   "/namespace/{namespaceID}/drift",
+				routes: drift.MountRoutes,
+			},
+			{
+				// Message events are published too when running as a monolith
+				name:   "live",
+				init:   live.InitRecords,
+				path:   "/live",
+				routes: live.MountRoutes,
+			},
+			{
+				name:       "permhistory",
+				migrations: permhistory.ComposeMigrations,
+				init:       permhistory.InitCompose,
+				path:       "/permissions/history",
+				routes:     permhistory.MountComposeRoutes,
+				middleware: permhistory.MiddlewareCompose,
+			},
+			{
+				name:   "diagnostics",
+				init:   diagnostics.InitCompose,
+				path:   "/diagnostics",
+				routes: diagnostics.MountComposeRoutes,
+			},
+			{
+				name:    "consistency",
+				init:    consistency.InitCompose,
+				path:    "/consistency",
+				routes:  consistency.MountComposeRoutes,
+				command: consistency.Command,
+			},
+			{
+				name:    "seed",
+				command: seed.Command,
+			},
+			{
+				name:       "gc",
+				migrations: gc.ComposeMigrations,
+				init:       gc.InitCompose,
+				path:       "/orphans",
+				routes:     gc.MountComposeRoutes,
+			},
+			{
+				name:   "versions",
+				init:   versions.Init,
+				path:   "/namespace/{namespaceID}/versions",
+				routes: versions.MountComposeRoutes,
+			},
+			{
+				name:       "feeds",
+				migrations: feeds.ComposeMigrations,
+				init:       feeds.InitCompose,
+				path:       "/feeds",
+				routes:     feeds.MountComposeRoutes,
+			},
+			{
+				name:   "connectors",
+				init:   connectors.InitCompose,
+				path:   "/namespace/{namespaceID}/issues",
+				routes: connectors.MountComposeRoutes,
+			},
+			{
+				// Tracking is served from OUTMAIL_BASE_URL when it is set
+				name:       "outmail",
+				migrations: outmail.Migrations,
+				init:       outmail.Init,
+				path:       "/namespace/{namespaceID}/outbound-mail",
+				routes:     outmail.MountRoutes,
+			},
+			{
+				name:       "deadline",
+				init:       deadline.Init,
+				middleware: deadline.Middleware,
+			},
+			{
+				name:       "compress",
+				init:       compress.Init,
+				middleware: compress.Middleware,
+			},
+		},
+	}
+)
@@ -0,0 +1,167 @@
+package extensions
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/spf13/cobra"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli"
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+type (
+	// extension hooks crust-specific functionality into one of corteza's apps
+	extension struct {
+		// Extension name, used for logging & migrations
+		name string
+
+		// Database migrations, executed on app's database
+		migrations migrations.Set
+
+		// Initializes extension
+		//
+		// Called after app services are initialized
+		init func(ctx context.Context, log *zap.Logger) error
+
+		// REST API endpoints (optional)
+		//
+		// Routes are mounted under the path, relative to app's root
+		path   string
+		routes func(r chi.Router)
+
+		// HTTP middleware (optional)
+		//
+		// Applied to all API routes, including corteza's
+		middleware func(http.Handler) http.Handler
+
+		// CLI command (optional)
+		//
+		// Extensions of more than one app add it once
+		command cli.CommandMaker
+	}
+
+	app struct {
+		// App name, also name of the database connection
+		name string
+
+		// Where app's routes are mounted when running as a monolith
+		prefix string
+
+		extensions []extension
+	}
+)
+
+// Messaging extends standalone messaging server
+func Messaging(cfg *cli.Config) {
+	extend(cfg, messaging, false)
+}
+
+// Compose extends standalone compose server
+func Compose(cfg *cli.Config) {
+	extend(cfg, compose, false)
+}
+
+// System extends standalone system server
+func System(cfg *cli.Config) {
+	extend(cfg, system, false)
+}
+
+// Monolith extends all three apps bundled together
+func Monolith(cfg *cli.Config) {
+	extend(cfg, system, true)
+	extend(cfg, compose, true)
+	extend(cfg, messaging, true)
+	extend(cfg, bundle, true)
+}
+
+var (
+	// Names of extensions with commands already added
+	commands = map[string]bool{}
+)
+
+func extend(cfg *cli.Config, a app, monolith bool) {
+	var (
+		migrate = func(ctx context.Context, cmd *cobra.Command, c *cli.Config) (err error) {
+			var db *factory.DB
+
+			for _, e := range a.extensions {
+				if len(e.migrations) == 0 {
+					continue
+				}
+
+				// Apps without migrations (bundle) do not have their own database
+				if db == nil {
+					if db, err = factory.Database.Get(a.name); err != nil {
+						return err
+					}
+
+					db = db.With(ctx).Quiet()
+				}
+
+				if err = migrations.Migrate(db, c.Log, e.name, e.migrations); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		prefix string
+	)
+
+	if monolith {
+		prefix = a.prefix
+	}
+
+	cfg.ProvisionMigrateDatabase = append(cfg.ProvisionMigrateDatabase, migrate)
+
+	for _, e := range a.extensions {
+		if e.command != nil && !commands[e.name] {
+			commands[e.name] = true
+			cfg.AdtSubCommands = append(cfg.AdtSubCommands, e.command)
+		}
+	}
+
+	cfg.ApiServerPreRun = append(cfg.ApiServerPreRun, func(ctx context.Context, cmd *cobra.Command, c *cli.Config) error {
+		if monolith && c.ProvisionOpt.MigrateDatabase {
+			// Monolith does not run its own ProvisionMigrateDatabase runners
+			// before server starts, only ones from the bundled apps.
+			cli.HandleError(migrate(ctx, cmd, c))
+		}
+
+		for _, e := range a.extensions {
+			if e.init == nil {
+				continue
+			}
+
+			if err := e.init(ctx, c.Log.Named(e.name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	// Middlewares must be registered before any route is mounted
+	cfg.ApiServerRoutes = append(cli.Mounters{func(r chi.Router) {
+		for _, e := range a.extensions {
+			if e.middleware != nil {
+				r.Use(e.middleware)
+			}
+		}
+	}}, cfg.ApiServerRoutes...)
+
+	cfg.ApiServerRoutes = append(cfg.ApiServerRoutes, func(r chi.Router) {
+		for _, e := range a.extensions {
+			if e.routes == nil {
+				continue
+			}
+
+			r.Route(prefix+e.path, e.routes)
+		}
+	})
+}
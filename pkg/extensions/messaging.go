@@ -0,0 +1,272 @@
+package extensions
+
+import (
+	"github.com/crusttech/crust-server/pkg/antivirus"
+	"github.com/crusttech/crust-server/pkg/collab"
+	"github.com/crusttech/crust-server/pkg/compress"
+	"github.com/crusttech/crust-server/pkg/connectors"
+	"github.com/crusttech/crust-server/pkg/consistency"
+	"github.com/crusttech/crust-server/pkg/counters"
+	"github.com/crusttech/crust-server/pkg/cursor"
+	"github.com/crusttech/crust-server/pkg/deadline"
+	"github.com/crusttech/crust-server/pkg/diagnostics"
+	"github.com/crusttech/crust-server/pkg/edits"
+	"github.com/crusttech/crust-server/pkg/eventbus"
+	"github.com/crusttech/crust-server/pkg/feeds"
+	"github.com/crusttech/crust-server/pkg/flood"
+	"github.com/crusttech/crust-server/pkg/fulltext"
+	"github.com/crusttech/crust-server/pkg/gc"
+	"github.com/crusttech/crust-server/pkg/groups"
+	"github.com/crusttech/crust-server/pkg/images"
+	"github.com/crusttech/crust-server/pkg/incoming"
+	"github.com/crusttech/crust-server/pkg/live"
+	"github.com/crusttech/crust-server/pkg/messages"
+	"github.com/crusttech/crust-server/pkg/moderation"
+	"github.com/crusttech/crust-server/pkg/notifications"
+	"github.com/crusttech/crust-server/pkg/permhistory"
+	"github.com/crusttech/crust-server/pkg/privacy"
+	"github.com/crusttech/crust-server/pkg/public"
+	"github.com/crusttech/crust-server/pkg/reactions"
+	"github.com/crusttech/crust-server/pkg/scheduled"
+	"github.com/crusttech/crust-server/pkg/seed"
+	"github.com/crusttech/crust-server/pkg/slashcommands"
+	"github.com/crusttech/crust-server/pkg/slowmode"
+	"github.com/crusttech/crust-server/pkg/storage"
+	"github.com/crusttech/crust-server/pkg/threads"
+	"github.com/crusttech/crust-server/pkg/versions"
+	"github.com/crusttech/crust-server/pkg/webhooks"
+)
+
+var (
+	messaging = app{
+		name:   "messaging",
+		prefix: "/messaging",
+
+		extensions: []extension{
+			{
+				// Must stay first, it replaces store and attachment
+				// service that other extensions use and decorate
+				name:    "storage",
+				init:    storage.InitMessaging,
+				command: storage.Command,
+			},
+			{
+				// Must come before extensions that decorate attachment
+				// service, it creates image attachments by itself
+				name:       "images",
+				migrations: images.Migrations,
+				init:       images.Init,
+				path:       "/attachment-thumbnails",
+				routes:     images.MountRoutes,
+			},
+			{
+				name:       "collab",
+				migrations: collab.Migrations,
+				init:       collab.Init,
+				path:       "/collab-sessions",
+				routes:     collab.MountRoutes,
+			},
+			{
+				name:       "deadline",
+				init:       deadline.Init,
+				middleware: deadline.Middleware,
+			},
+			{
+				name:       "compress",
+				init:       compress.Init,
+				middleware: compress.Middleware,
+			},
+			{
+				name:   "messages",
+				init:   messages.Init,
+				path:   "/channels/{channelID}/message-stream",
+				routes: messages.MountRoutes,
+			},
+			{
+				// Events of corteza's websocket, not of live
+				name: "eventbus",
+				init: eventbus.Init,
+			},
+			{
+				// Must decorate channel service before other extensions do,
+				// see GROUPS_MAX_MEMBERS setting
+				name: "groups",
+				init: groups.Init,
+			},
+			{
+				name:       "counters",
+				migrations: counters.Migrations,
+				init:       counters.Init,
+				path:       "/channel-counters",
+				routes:     counters.MountRoutes,
+			},
+			{
+				name:       "cursor",
+				init:       cursor.InitMessaging,
+				middleware: cursor.MiddlewareMessaging,
+			},
+			{
+				name:       "permhistory",
+				migrations: permhistory.MessagingMigrations,
+				init:       permhistory.InitMessaging,
+				path:       "/permissions/history",
+				routes:     permhistory.MountMessagingRoutes,
+				middleware: permhistory.MiddlewareMessaging,
+			},
+			{
+				name:   "diagnostics",
+				init:   diagnostics.InitMessaging,
+				path:   "/diagnostics",
+				routes: diagnostics.MountMessagingRoutes,
+			},
+			{
+				name:    "consistency",
+				init:    consistency.InitMessaging,
+				path:    "/consistency",
+				routes:  consistency.MountMessagingRoutes,
+				command: consistency.Command,
+			},
+			{
+				name:    "seed",
+				command: seed.Command,
+			},
+			{
+				name:       "gc",
+				migrations: gc.MessagingMigrations,
+				init:       gc.InitMessaging,
+				path:       "/orphans",
+				routes:     gc.MountMessagingRoutes,
+			},
+			{
+				name:   "versions",
+				init:   versions.Init,
+				path:   "/versions",
+				routes: versions.MountMessagingRoutes,
+			},
+			{
+				name:   "live",
+				init:   live.Init,
+				path:   "/live",
+				routes: live.MountRoutes,
+			},
+			{
+				name:       "reactions",
+				migrations: reactions.Migrations,
+				init:       reactions.Init,
+				path:       "/message-reactions",
+				routes:     reactions.MountRoutes,
+			},
+			{
+				name:   "threads",
+				init:   threads.Init,
+				path:   "/threads",
+				routes: threads.MountRoutes,
+			},
+			{
+				name:       "fulltext",
+				migrations: fulltext.Migrations,
+				init:       fulltext.Init,
+				path:       "/message-search",
+				routes:     fulltext.MountRoutes,
+			},
+			{
+				name:   "flood",
+				init:   flood.Init,
+				path:   "/flood-restrictions",
+				routes: flood.MountRoutes,
+			},
+			{
+				// After flood, messages rejected in slow mode are not counted as posts
+				name:       "slowmode",
+				migrations: slowmode.Migrations,
+				init:       slowmode.Init,
+				path:       "/channels/{channelID}/slow-mode",
+				routes:     slowmode.MountRoutes,
+			},
+			{
+				name:       "scheduled",
+				migrations: scheduled.Migrations,
+				init:       scheduled.Init,
+				path:       "/channels/{channelID}/scheduled-messages",
+				routes:     scheduled.MountRoutes,
+			},
+			{
+				name:       "edits",
+				migrations: edits.Migrations,
+				init:       edits.Init,
+				path:       "/message-edits",
+				routes:     edits.MountRoutes,
+			},
+			{
+				name:       "moderation",
+				migrations: moderation.Migrations,
+				init:       moderation.Init,
+				path:       "/channels/{channelID}/moderation",
+				routes:     moderation.MountRoutes,
+			},
+			{
+				name:       "antivirus",
+				migrations: antivirus.Migrations,
+				init:       antivirus.Init,
+				path:       "/attachment-scans",
+				routes:     antivirus.MountRoutes,
+			},
+			{
+				name:       "privacy",
+				migrations: privacy.Migrations,
+				init:       privacy.Init,
+				path:       "/privacy",
+				routes:     privacy.MountRoutes,
+			},
+			{
+				name:       "notifications",
+				migrations: notifications.Migrations,
+				init:       notifications.Init,
+				path:       "/notifications",
+				routes:     notifications.MountRoutes,
+			},
+			{
+				name:       "public",
+				migrations: public.Migrations,
+				init:       public.Init,
+				path:       "/public-channels",
+				routes:     public.MountRoutes,
+			},
+			{
+				name:       "webhooks",
+				migrations: webhooks.Migrations,
+				init:       webhooks.Init,
+				path:       "/channel-webhooks",
+				routes:     webhooks.MountRoutes,
+			},
+			{
+				name:       "incoming",
+				migrations: incoming.Migrations,
+				init:       incoming.Init,
+				path:       "/incoming-webhooks",
+				routes:     incoming.MountRoutes,
+			},
+			{
+				name:       "feeds",
+				migrations: feeds.MessagingMigrations,
+				init:       feeds.InitMessaging,
+				path:       "/feeds",
+				routes:     feeds.MountMessagingRoutes,
+			},
+			{
+				name:       "connectors",
+				migrations: connectors.Migrations,
+				init:       connectors.Init,
+				path:       "/connectors",
+				routes:     connectors.MountRoutes,
+			},
+			{
+				name:       "slashcommands",
+				migrations: slashcommands.Migrations,
+				init:       slashcommands.Init,
+				path:       "/slash-commands",
+				routes:     slashcommands.MountRoutes,
+			},
+		},
+	}
+)
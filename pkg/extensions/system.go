@@ -0,0 +1,207 @@
+package extensions
+
+import (
+	"github.com/crusttech/crust-server/pkg/antifraud"
+	"github.com/crusttech/crust-server/pkg/audit"
+	"github.com/crusttech/crust-server/pkg/autoroles"
+	"github.com/crusttech/crust-server/pkg/compress"
+	"github.com/crusttech/crust-server/pkg/consistency"
+	"github.com/crusttech/crust-server/pkg/cursor"
+	"github.com/crusttech/crust-server/pkg/deadline"
+	"github.com/crusttech/crust-server/pkg/devices"
+	"github.com/crusttech/crust-server/pkg/diagnostics"
+	"github.com/crusttech/crust-server/pkg/expiry"
+	"github.com/crusttech/crust-server/pkg/explain"
+	"github.com/crusttech/crust-server/pkg/gc"
+	"github.com/crusttech/crust-server/pkg/live"
+	"github.com/crusttech/crust-server/pkg/members"
+	"github.com/crusttech/crust-server/pkg/permhistory"
+	"github.com/crusttech/crust-server/pkg/recent"
+	"github.com/crusttech/crust-server/pkg/redact"
+	"github.com/crusttech/crust-server/pkg/rolecache"
+	"github.com/crusttech/crust-server/pkg/rolemerge"
+	"github.com/crusttech/crust-server/pkg/roletree"
+	"github.com/crusttech/crust-server/pkg/seclog"
+	"github.com/crusttech/crust-server/pkg/seed"
+	"github.com/crusttech/crust-server/pkg/stepup"
+	"github.com/crusttech/crust-server/pkg/suggest"
+	"github.com/crusttech/crust-server/pkg/versions"
+)
+
+var (
+	system = app{
+		name:   "system",
+		prefix: "/system",
+
+		extensions: []extension{
+			{
+				name:       "suggest",
+				migrations: suggest.SystemMigrations,
+				init:       suggest.InitUsers,
+			},
+			{
+				name:       "recent",
+				migrations: recent.Migrations,
+				init:       recent.Init,
+				path:       "/recent",
+				routes:     recent.MountRoutes,
+			},
+			{
+				name:       "seclog",
+				migrations: seclog.Migrations,
+				init:       seclog.Init,
+				path:       "/security-events",
+				routes:     seclog.MountRoutes,
+			},
+			{
+				// Before audit, so that merges are recorded
+				name:   "rolemerge",
+				init:   rolemerge.Init,
+				path:   "/roles/{roleID}/merge-dry-run",
+				routes: rolemerge.MountRoutes,
+			},
+			{
+				// Before other extensions that decorate role & user services,
+				// so that changes they make are recorded too
+				name:       "audit",
+				migrations: audit.Migrations,
+				init:       audit.Init,
+				path:       "/audit",
+				routes:     audit.MountRoutes,
+			},
+			{
+				name:       "antifraud",
+				init:       antifraud.Init,
+				middleware: antifraud.Middleware,
+			},
+			{
+				name:       "devices",
+				migrations: devices.Migrations,
+				init:       devices.Init,
+				path:       "/devices",
+				routes:     devices.MountRoutes,
+				middleware: devices.Middleware,
+			},
+			{
+				name:       "stepup",
+				migrations: stepup.Migrations,
+				init:       stepup.Init,
+				path:       "/stepup",
+				routes:     stepup.MountRoutes,
+				middleware: stepup.Middleware,
+			},
+			{
+				name:   "members",
+				init:   members.Init,
+				path:   "/roles/{roleID}/members/bulk",
+				routes: members.MountRoutes,
+			},
+			{
+				// Before expiry and roletree, memberships are synced
+				// before they are filtered and expanded
+				name:       "autoroles",
+				migrations: autoroles.Migrations,
+				init:       autoroles.Init,
+				path:       "/roles/{roleID}/rule",
+				routes:     autoroles.MountRoutes,
+			},
+			{
+				// Before roletree, descendants of expired roles are not added;
+				// expirations apply to all apps only when running as a monolith
+				name:       "expiry",
+				migrations: expiry.Migrations,
+				init:       expiry.Init,
+				path:       "/roles/{roleID}/expiring-members",
+				routes:     expiry.MountRoutes,
+				middleware: expiry.Middleware,
+			},
+			{
+				name:       "roletree",
+				migrations: roletree.Migrations,
+				init:       roletree.Init,
+				path:       "/roles/{roleID}/children",
+				routes:     roletree.MountRoutes,
+			},
+			{
+				// After role rules, expiry and roletree, effective roles are cached
+				// as they resolve them; applies to all apps when running as a monolith
+				name:       "rolecache",
+				init:       rolecache.Init,
+				middleware: rolecache.Middleware,
+			},
+			{
+				name:       "permhistory",
+				migrations: permhistory.SystemMigrations,
+				init:       permhistory.InitSystem,
+				path:       "/permissions/history",
+				routes:     permhistory.MountSystemRoutes,
+				middleware: permhistory.MiddlewareSystem,
+			},
+			{
+				name:   "explain",
+				init:   explain.Init,
+				path:   "/permissions/explain",
+				routes: explain.MountRoutes,
+			},
+			{
+				name:       "cursor",
+				init:       cursor.InitSystem,
+				middleware: cursor.MiddlewareSystem,
+			},
+			{
+				name:   "diagnostics",
+				init:   diagnostics.InitSystem,
+				path:   "/diagnostics",
+				routes: diagnostics.MountSystemRoutes,
+			},
+			{
+				name:    "consistency",
+				init:    consistency.InitSystem,
+				path:    "/consistency",
+				routes:  consistency.MountSystemRoutes,
+				command: consistency.Command,
+			},
+			{
+				name:    "seed",
+				command: seed.Command,
+			},
+			{
+				name:       "gc",
+				migrations: gc.SystemMigrations,
+				init:       gc.InitSystem,
+				path:       "/orphans",
+				routes:     gc.MountSystemRoutes,
+			},
+			{
+				name:   "versions",
+				init:   versions.Init,
+				path:   "/versions",
+				routes: versions.MountSystemRoutes,
+			},
+			{
+				name:       "deadline",
+				init:       deadline.Init,
+				middleware: deadline.Middleware,
+			},
+			{
+				name:       "compress",
+				init:       compress.Init,
+				middleware: compress.Middleware,
+			},
+			{
+				// After compress, user payloads are changed before they
+				// are compressed; presence is tracked by the live endpoints
+				// of messaging and compose
+				name:       "presence",
+				init:       live.InitPresence,
+				middleware: live.PresenceMiddleware,
+			},
+			{
+				// After presence, users are redacted as corteza sends
+				// them, before presence is added
+				name:       "redact",
+				middleware: redact.Middleware,
+			},
+		},
+	}
+)
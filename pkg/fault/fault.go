@@ -0,0 +1,348 @@
+// Package fault provides errors returned by crust services
+//
+// Packages declare their errors with name and kind (see
+// alerts.ErrRuleNotFound) and return them with New, which records the
+// operation (the calling function) and the stack. IDs of the resources
+// involved and a message that is safe to show to the user can be added
+// to the error:
+//
+//	return nil, ErrRuleNotFound.withStack().WithID("ruleID", ruleID)
+//
+// Errors are still compared with the definitions, with Is (or errors.Cause).
+// REST handlers translate errors into HTTP responses with Status.
+package fault
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type (
+	// Kind of the error, determines HTTP status of the response
+	Kind int
+
+	// Error of the service
+	Error struct {
+		Kind Kind
+
+		// Function that returned the error, e.g. "alerts.repository.FindByID"
+		Op string
+
+		// IDs of the resources involved, in the order they were added
+		IDs []ID
+
+		// Message that can be shown to the user
+		Message string
+
+		// When the request can be repeated, for errors of limits
+		RetryAfter time.Duration
+
+		// Wrapped error, defined by the package
+		Err error
+
+		stack []uintptr
+	}
+
+	// ID of the resource the error is about
+	ID struct {
+		Name  string
+		Value uint64
+	}
+
+	causer interface {
+		Cause() error
+	}
+
+	wrapper interface {
+		Unwrap() error
+	}
+
+	kinder interface {
+		Kind() Kind
+	}
+)
+
+const (
+	// Invalid request
+	Invalid Kind = iota
+	NotFound
+	Forbidden
+	Conflict
+	TooMany
+	Unavailable
+
+	// Internal errors are not expected, e.g. failed queries
+	Internal
+)
+
+const (
+	maxDepth = 32
+)
+
+// New returns error that wraps the error of the package
+//
+// Kind is taken from the error (its Kind method), errors without one are
+// internal. Packages call New from their withStack method; it is skipped
+// when looking for the operation.
+func New(err error) *Error {
+	var (
+		pcs = make([]uintptr, maxDepth)
+		n   = runtime.Callers(2, pcs)
+	)
+
+	pcs = pcs[:n]
+
+	// Skip withStack helpers of packages
+	for len(pcs) > 1 && strings.HasSuffix(funcName(pcs[0]), ".withStack") {
+		pcs = pcs[1:]
+	}
+
+	e := &Error{
+		Kind:  Internal,
+		Err:   err,
+		stack: pcs,
+	}
+
+	if k, ok := err.(kinder); ok {
+		e.Kind = k.Kind()
+	}
+
+	if len(pcs) > 0 {
+		e.Op = op(funcName(pcs[0]))
+	}
+
+	return e
+}
+
+// WithID adds ID of the resource to the error
+func (e *Error) WithID(name string, id uint64) *Error {
+	e.IDs = append(e.IDs, ID{Name: name, Value: id})
+	return e
+}
+
+// WithMessage sets message that can be shown to the user
+func (e *Error) WithMessage(msg string) *Error {
+	e.Message = msg
+	return e
+}
+
+// WithRetryAfter sets how long the client should wait before repeating the request
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	e.RetryAfter = d
+	return e
+}
+
+// WithKind overrides kind of the error
+func (e *Error) WithKind(k Kind) *Error {
+	e.Kind = k
+	return e
+}
+
+// IDMap returns IDs of the error as strings, numbers in JSON would lose precision
+func (e *Error) IDMap() map[string]string {
+	if len(e.IDs) == 0 {
+		return nil
+	}
+
+	m := make(map[string]string, len(e.IDs))
+	for _, id := range e.IDs {
+		m[id.Name] = strconv.FormatUint(id.Value, 10)
+	}
+
+	return m
+}
+
+// Error returns code of the wrapped error, e.g. "crust.alerts.RuleNotFound"
+//
+// Clients rely on codes, other details are available through the fields.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error (errors.Is and errors.As of go 1.13)
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the wrapped error (errors.Cause of pkg/errors)
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// StackTrace returns stack of the function that created the error
+func (e *Error) StackTrace() errors.StackTrace {
+	st := make(errors.StackTrace, len(e.stack))
+	for i, pc := range e.stack {
+		st[i] = errors.Frame(pc)
+	}
+
+	return st
+}
+
+// Format prints code of the error; %+v adds the operation, IDs, message and stack
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		_, _ = io.WriteString(s, e.Error())
+		if e.Op != "" {
+			_, _ = fmt.Fprintf(s, " in %s", e.Op)
+		}
+
+		for _, id := range e.IDs {
+			_, _ = fmt.Fprintf(s, " %s=%d", id.Name, id.Value)
+		}
+
+		if e.Message != "" {
+			_, _ = fmt.Fprintf(s, ": %s", e.Message)
+		}
+
+		_, _ = fmt.Fprintf(s, "%+v", e.StackTrace())
+	case verb == 'v', verb == 's':
+		_, _ = io.WriteString(s, e.Error())
+	case verb == 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// Is checks if err is or wraps the target
+//
+// Both wrappers of pkg/errors (Cause) and of go 1.13 (Unwrap) are followed.
+func Is(err, target error) bool {
+	if target == nil || !reflect.TypeOf(target).Comparable() {
+		return err == target
+	}
+
+	for ; err != nil; err = next(err) {
+		if err == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAny checks if err is or wraps any of the targets
+func IsAny(err error, targets ...error) bool {
+	for _, t := range targets {
+		if Is(err, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As returns the first *Error in the chain of err
+func As(err error) (*Error, bool) {
+	for ; err != nil; err = next(err) {
+		if e, ok := err.(*Error); ok {
+			return e, true
+		}
+	}
+
+	return nil, false
+}
+
+// Status returns HTTP status of the error
+//
+// Errors that are not (wrapped) *Error are internal.
+func Status(err error) int {
+	e, ok := As(err)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+
+	switch e.Kind {
+	case Invalid:
+		return http.StatusBadRequest
+	case NotFound:
+		return http.StatusNotFound
+	case Forbidden:
+		return http.StatusForbidden
+	case Conflict:
+		return http.StatusConflict
+	case TooMany:
+		return http.StatusTooManyRequests
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+// Fields returns log fields of the error: code, kind, operation and IDs
+func Fields(err error) []zap.Field {
+	e, ok := As(err)
+	if !ok {
+		return nil
+	}
+
+	ff := []zap.Field{
+		zap.String("code", e.Error()),
+		zap.String("kind", e.Kind.String()),
+		zap.String("op", e.Op),
+	}
+
+	for _, id := range e.IDs {
+		ff = append(ff, zap.Uint64(id.Name, id.Value))
+	}
+
+	return ff
+}
+
+func (k Kind) String() string {
+	switch k {
+	case Invalid:
+		return "invalid"
+	case NotFound:
+		return "notFound"
+	case Forbidden:
+		return "forbidden"
+	case Conflict:
+		return "conflict"
+	case TooMany:
+		return "tooMany"
+	case Unavailable:
+		return "unavailable"
+	}
+
+	return "internal"
+}
+
+func next(err error) error {
+	switch e := err.(type) {
+	case wrapper:
+		return e.Unwrap()
+	case causer:
+		return e.Cause()
+	}
+
+	return nil
+}
+
+func funcName(pc uintptr) string {
+	if fn := runtime.FuncForPC(pc - 1); fn != nil {
+		return fn.Name()
+	}
+
+	return ""
+}
+
+// op returns short name of the function,
+// "github.com/crusttech/crust-server/pkg/alerts.(*service).evaluate" => "alerts.service.evaluate"
+func op(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return strings.NewReplacer("(*", "", "(", "", ")", "").Replace(name)
+}
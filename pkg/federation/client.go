@@ -0,0 +1,106 @@
+package federation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// client calls peer endpoints of the node
+	client struct {
+		ctx  context.Context
+		node *Node
+	}
+
+	// envelope is corteza's response format
+	envelope struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Response json.RawMessage `json:"response"`
+	}
+)
+
+var (
+	httpClient = &http.Client{Timeout: 30 * time.Second}
+)
+
+// Modules returns modules that node exposes to us
+func (c client) Modules() (mm []*ModuleInfo, err error) {
+	return mm, c.do(http.MethodGet, "/modules", nil, &mm)
+}
+
+// Changes returns records of the exposed module that changed since the given time
+func (c client) Changes(exposedID uint64, since *time.Time, page uint) (*Changes, error) {
+	q := url.Values{"page": {fmt.Sprint(page)}}
+	if since != nil {
+		q.Set("since", since.UTC().Format(time.RFC3339))
+	}
+
+	out := &Changes{}
+	return out, c.do(http.MethodGet, fmt.Sprintf("/modules/%d/records?%s", exposedID, q.Encode()), nil, out)
+}
+
+// Push sends local changes of replicated records to the node
+func (c client) Push(exposedID uint64, rr []*PushedRecord) (out []*PushResult, err error) {
+	return out, c.do(http.MethodPost, fmt.Sprintf("/modules/%d/records", exposedID), rr, &out)
+}
+
+func (c client) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.node.BaseURL, "/")+"/federation"+path, body)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	req.Header.Set(tokenHeader, c.node.OutboundToken)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rsp, err := httpClient.Do(req.WithContext(c.ctx))
+	if err != nil {
+		return errors.Wrapf(err, "could not reach node %q", c.node.Name)
+	}
+
+	defer rsp.Body.Close()
+
+	// Errors are sent in the envelope, with status of the error
+	var e envelope
+	if err = json.NewDecoder(rsp.Body).Decode(&e); err != nil {
+		if rsp.StatusCode != http.StatusOK {
+			return errors.Errorf("node %q responded with unexpected status: %s", c.node.Name, rsp.Status)
+		}
+
+		return errors.Wrapf(err, "could not decode response of node %q", c.node.Name)
+	}
+
+	if e.Error != nil {
+		return errors.Errorf("node %q responded with error: %s", c.node.Name, e.Error.Message)
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		return errors.Errorf("node %q responded with unexpected status: %s", c.node.Name, rsp.Status)
+	}
+
+	return errors.WithStack(json.Unmarshal(e.Response, out))
+}
@@ -0,0 +1,44 @@
+package federation
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	federationError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID         = federationError{"InvalidID", fault.Invalid}
+	ErrInvalidURL        = federationError{"InvalidURL", fault.Invalid}
+	ErrInvalidConflict   = federationError{"InvalidConflict", fault.Invalid}
+	ErrInvalidField      = federationError{"InvalidField", fault.Invalid}
+	ErrNameRequired      = federationError{"NameRequired", fault.Invalid}
+	ErrOwnerRequired     = federationError{"OwnerRequired", fault.Invalid}
+	ErrNoPermissions     = federationError{"NoPermissions", fault.Forbidden}
+	ErrNodeNotFound      = federationError{"NodeNotFound", fault.NotFound}
+	ErrExposedNotFound   = federationError{"ExposedNotFound", fault.NotFound}
+	ErrSharedNotFound    = federationError{"SharedNotFound", fault.NotFound}
+	ErrInvalidToken      = federationError{"InvalidToken", fault.Forbidden}
+	ErrPushNotAllowed    = federationError{"PushNotAllowed", fault.Forbidden}
+	ErrNamespaceMismatch = federationError{"NamespaceMismatch", fault.Invalid}
+)
+
+func (e federationError) Error() string {
+	return e.String()
+}
+
+func (e federationError) String() string {
+	return "crust.federation." + e.name
+}
+
+func (e federationError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e federationError) withStack() *fault.Error {
+	return fault.New(e)
+}
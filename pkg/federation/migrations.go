@@ -0,0 +1,98 @@
+package federation
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200201000000.federation",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_federation_node (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  base_url           VARCHAR(512)    NOT NULL,
+  outbound_token     VARCHAR(256)    NOT NULL DEFAULT '',
+  inbound_token_hash CHAR(64)        NOT NULL,
+
+  created_by         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (inbound_token_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_federation_exposed (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_node           BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  fields             TEXT            NOT NULL,
+  run_as             BIGINT UNSIGNED NOT NULL,
+  allow_push         BOOLEAN         NOT NULL DEFAULT FALSE,
+  conflict           VARCHAR(32)     NOT NULL,
+
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (rel_node),
+  INDEX (rel_module)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_federation_shared (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_node           BIGINT UNSIGNED NOT NULL,
+  remote_exposed_id  BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  push               BOOLEAN         NOT NULL DEFAULT FALSE,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+  pull_cursor        DATETIME            NULL DEFAULT NULL,
+  pushed_at          DATETIME            NULL DEFAULT NULL,
+  synced_at          DATETIME            NULL DEFAULT NULL,
+  last_error         TEXT            NOT NULL,
+
+  owned_by           BIGINT UNSIGNED NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (rel_node)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_federation_link (
+  rel_node           BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  rel_record         BIGINT UNSIGNED NOT NULL,
+  remote_record_id   BIGINT UNSIGNED NOT NULL,
+  origin             VARCHAR(16)     NOT NULL,
+  remote_version     DATETIME        NOT NULL,
+  local_version      DATETIME        NOT NULL,
+  synced_at          DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_node, rel_module, remote_record_id),
+  INDEX (rel_record)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_federation_tombstone (
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  rel_record         BIGINT UNSIGNED NOT NULL,
+  deleted_at         DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_module, rel_record),
+  INDEX (rel_module, deleted_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
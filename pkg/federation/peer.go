@@ -0,0 +1,290 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+// Authenticate returns node that the token was issued to
+func (svc federationService) Authenticate(token string) (*Node, error) {
+	if token == "" {
+		return nil, ErrInvalidToken.withStack()
+	}
+
+	n, err := svc.repository.FindNodeByToken(hash(token))
+	if fault.Is(err, ErrNodeNotFound) {
+		return nil, ErrInvalidToken.withStack()
+	}
+
+	return n, err
+}
+
+// Modules returns structure of modules exposed to the node
+func (svc federationService) Modules(node *Node) ([]*ModuleInfo, error) {
+	set, err := svc.repository.FindExposed(node.NamespaceID, node.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ctx = auth.SetSuperUserContext(svc.ctx)
+		out = make([]*ModuleInfo, 0, len(set))
+	)
+
+	for _, e := range set {
+		m, err := service.DefaultModule.With(ctx).FindByID(e.NamespaceID, e.ModuleID)
+		if err != nil {
+			svc.log(zap.Uint64("exposedID", e.ID)).Error("could not load exposed module", zap.Error(err))
+			continue
+		}
+
+		mi := &ModuleInfo{
+			ExposedID: e.ID,
+			Name:      m.Name,
+			Handle:    m.Handle,
+			AllowPush: e.AllowPush,
+		}
+
+		for _, f := range sharedFields(m, e) {
+			mi.Fields = append(mi.Fields, &FieldInfo{
+				Name:     f.Name,
+				Label:    f.Label,
+				Kind:     f.Kind,
+				Options:  f.Options,
+				Multi:    f.Multi,
+				Required: f.Required,
+			})
+		}
+
+		out = append(out, mi)
+	}
+
+	return out, nil
+}
+
+// Changes returns records of the exposed module that changed since the given time
+//
+// Records are read with permissions of exposed module's user. Deleted
+// records are returned (by ID) only with the first page.
+func (svc federationService) Changes(node *Node, exposedID uint64, since *time.Time, page uint) (*Changes, error) {
+	e, ctx, err := svc.exposed(node, exposedID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := service.DefaultModule.With(ctx).FindByID(e.NamespaceID, e.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out = &Changes{
+			Records: []*RecordInfo{},
+			Deleted: []string{},
+			Until:   now().Truncate(time.Second),
+		}
+
+		f = types.RecordFilter{
+			NamespaceID: e.NamespaceID,
+			ModuleID:    e.ModuleID,
+			Sort:        "id",
+			PageFilter:  rh.PageFilter{Page: page, PerPage: batchSize},
+		}
+
+		fields = sharedFields(m, e)
+	)
+
+	if since != nil {
+		s := since.Local().Format("2006-01-02 15:04:05")
+		f.Filter = fmt.Sprintf("createdAt >= '%s' OR updatedAt >= '%s'", s, s)
+	}
+
+	rr, _, err := service.DefaultRecord.With(ctx).Find(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rr {
+		out.Records = append(out.Records, &RecordInfo{
+			ID:        r.ID,
+			Values:    filterValues(r.Values, fields),
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+		})
+	}
+
+	if since != nil && page <= 1 {
+		tt, err := svc.repository.Tombstones(e.ModuleID, *since)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range tt {
+			out.Deleted = append(out.Deleted, strconv.FormatUint(t.RecordID, 10))
+		}
+	}
+
+	return out, nil
+}
+
+// Push applies changes made on the node
+//
+// Changes of records that changed here since the change's base are
+// conflicts, resolved by exposed module's conflict policy.
+func (svc federationService) Push(node *Node, exposedID uint64, rr []*PushedRecord) ([]*PushResult, error) {
+	e, ctx, err := svc.exposed(node, exposedID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.AllowPush {
+		return nil, ErrPushNotAllowed.withStack()
+	}
+
+	m, err := service.DefaultModule.With(ctx).FindByID(e.NamespaceID, e.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		out    = make([]*PushResult, 0, len(rr))
+		fields = sharedFields(m, e)
+	)
+
+	for _, pr := range rr {
+		res, err := svc.apply(ctx, node, e, fields, pr)
+		if err != nil {
+			res = &PushResult{SourceID: pr.SourceID, RecordID: pr.ID, Status: PushFailed, Error: err.Error()}
+		}
+
+		out = append(out, res)
+	}
+
+	return out, nil
+}
+
+func (svc federationService) apply(ctx context.Context, node *Node, e *Exposed, fields types.ModuleFieldSet, pr *PushedRecord) (*PushResult, error) {
+	var (
+		rs  = service.DefaultRecord.With(ctx)
+		res = &PushResult{SourceID: pr.SourceID, RecordID: pr.ID}
+	)
+
+	if pr.ID == 0 {
+		// Created on the node; it might have been stored already when the node
+		// did not get the response
+		l, err := svc.repository.FindLink(node.ID, e.ModuleID, pr.SourceID)
+		if err != nil {
+			return nil, err
+		} else if l == nil {
+			r, err := rs.Create(&types.Record{
+				NamespaceID: e.NamespaceID,
+				ModuleID:    e.ModuleID,
+				Values:      filterValues(pr.Values, fields),
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			res.RecordID, res.Status, res.Version = r.ID, PushCreated, version(r.CreatedAt, r.UpdatedAt)
+
+			_, err = svc.repository.SaveLink(&Link{
+				NodeID:         node.ID,
+				ModuleID:       e.ModuleID,
+				RecordID:       r.ID,
+				RemoteRecordID: pr.SourceID,
+				Origin:         OriginRemote,
+				RemoteVersion:  pr.UpdatedAt,
+				LocalVersion:   res.Version,
+			})
+
+			return res, err
+		}
+
+		res.RecordID = l.RecordID
+		pr.ID = l.RecordID
+	}
+
+	r, err := rs.FindByID(e.NamespaceID, pr.ID)
+	if err != nil {
+		return nil, err
+	} else if r.ModuleID != e.ModuleID {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	current := version(r.CreatedAt, r.UpdatedAt)
+	if pr.Base != nil && !current.Equal(pr.Base.Truncate(time.Second)) {
+		if e.Conflict == ConflictOriginWins || !pr.UpdatedAt.After(current) {
+			res.Status, res.Version = PushConflict, current
+			return res, nil
+		}
+	}
+
+	// Values of fields that are not shared are kept
+	values := filterValues(pr.Values, fields)
+	for _, v := range r.Values {
+		if fields.FindByName(v.Name) == nil {
+			values = append(values, v)
+		}
+	}
+
+	r.Values = values
+	if r, err = rs.Update(r); err != nil {
+		return nil, err
+	}
+
+	res.Status, res.Version = PushUpdated, version(r.CreatedAt, r.UpdatedAt)
+	return res, nil
+}
+
+// exposed returns module exposed to the node and context of the user it is exposed as
+func (svc federationService) exposed(node *Node, exposedID uint64) (*Exposed, context.Context, error) {
+	e, err := svc.repository.FindExposedByID(node.NamespaceID, exposedID)
+	if err != nil {
+		return nil, nil, err
+	} else if e.NodeID != node.ID {
+		return nil, nil, ErrExposedNotFound.withStack()
+	}
+
+	ctx, err := runas.Compose(svc.ctx, e.RunAs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e, ctx, nil
+}
+
+// sharedFields returns fields of the module that are exposed
+//
+// Private fields are never shared
+func sharedFields(m *types.Module, e *Exposed) (out types.ModuleFieldSet) {
+	for _, f := range m.Fields {
+		if !f.Private && e.Fields.has(f.Name) {
+			out = append(out, f)
+		}
+	}
+
+	return out
+}
+
+func filterValues(vv types.RecordValueSet, fields types.ModuleFieldSet) (out types.RecordValueSet) {
+	out = types.RecordValueSet{}
+	for _, v := range vv {
+		if fields.FindByName(v.Name) != nil {
+			out = append(out, &types.RecordValue{Name: v.Name, Value: v.Value})
+		}
+	}
+
+	return out
+}
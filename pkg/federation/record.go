@@ -0,0 +1,46 @@
+package federation
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+)
+
+type (
+	// record wraps record service and keeps deletions of exposed
+	// modules' records, so that they are replicated to nodes
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with tombstones for deleted records
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	r, err := svc.RecordService.FindByID(namespaceID, recordID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.RecordService.DeleteByID(namespaceID, recordID); err != nil {
+		return err
+	}
+
+	repo := Repository(svc.ctx, nil)
+	if exposed, err := repo.IsExposed(r.ModuleID); err != nil || !exposed {
+		return err
+	}
+
+	return repo.CreateTombstone(&tombstone{ModuleID: r.ModuleID, RecordID: recordID})
+}
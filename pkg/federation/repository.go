@@ -0,0 +1,381 @@
+package federation
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableNode() string {
+	return "crust_federation_node"
+}
+
+func (r repository) tableExposed() string {
+	return "crust_federation_exposed"
+}
+
+func (r repository) tableShared() string {
+	return "crust_federation_shared"
+}
+
+func (r repository) tableLink() string {
+	return "crust_federation_link"
+}
+
+func (r repository) tableTombstone() string {
+	return "crust_federation_tombstone"
+}
+
+func (r repository) queryNode() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"name",
+			"base_url",
+			"outbound_token",
+			"inbound_token_hash",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableNode()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindNodeByID(namespaceID, nodeID uint64) (*Node, error) {
+	return r.findNode(squirrel.Eq{"id": nodeID, "rel_namespace": namespaceID})
+}
+
+// FindNodeByToken returns node that authenticates with the token (hash)
+func (r repository) FindNodeByToken(hash string) (*Node, error) {
+	return r.findNode(squirrel.Eq{"inbound_token_hash": hash})
+}
+
+func (r repository) findNode(cnd squirrel.Sqlizer) (*Node, error) {
+	var (
+		n = &Node{}
+		q = r.queryNode().Where(cnd)
+	)
+
+	if err := rh.FetchOne(r.db(), q, n); err != nil {
+		return nil, err
+	} else if n.ID == 0 {
+		return nil, ErrNodeNotFound.withStack()
+	}
+
+	return n, nil
+}
+
+func (r repository) FindNodes(namespaceID uint64) (set NodeSet, err error) {
+	q := r.queryNode().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateNode(n *Node) (*Node, error) {
+	n.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&n.CreatedAt)
+
+	return n, errors.WithStack(r.db().Insert(r.tableNode(), n))
+}
+
+func (r repository) UpdateNode(n *Node) (*Node, error) {
+	rh.SetCurrentTimeRounded(&n.UpdatedAt)
+
+	return n, errors.WithStack(r.db().Replace(r.tableNode(), n))
+}
+
+func (r repository) DeleteNodeByID(namespaceID, nodeID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableNode(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": nodeID, "rel_namespace": namespaceID},
+	)
+}
+
+func (r repository) queryExposed() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_node",
+			"rel_module",
+			"fields",
+			"run_as",
+			"allow_push",
+			"conflict",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableExposed()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindExposedByID(namespaceID, exposedID uint64) (*Exposed, error) {
+	var (
+		e = &Exposed{}
+		q = r.queryExposed().Where(squirrel.Eq{"id": exposedID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, e); err != nil {
+		return nil, err
+	} else if e.ID == 0 {
+		return nil, ErrExposedNotFound.withStack().WithID("namespaceID", namespaceID).WithID("exposedID", exposedID)
+	}
+
+	return e, nil
+}
+
+// FindExposed returns modules exposed in the namespace (to the node, when given)
+func (r repository) FindExposed(namespaceID, nodeID uint64) (set ExposedSet, err error) {
+	q := r.queryExposed().Where(squirrel.Eq{"rel_namespace": namespaceID})
+
+	if nodeID > 0 {
+		q = q.Where(squirrel.Eq{"rel_node": nodeID})
+	}
+
+	return set, rh.FetchAll(r.db(), q.OrderBy("id"), &set)
+}
+
+// IsExposed checks if module is exposed to any node
+func (r repository) IsExposed(moduleID uint64) (bool, error) {
+	var (
+		count uint
+		q     = squirrel.
+			Select("COUNT(*)").
+			From(r.tableExposed()).
+			Where(squirrel.Eq{"rel_module": moduleID, "deleted_at": nil})
+	)
+
+	if err := rh.FetchOne(r.db(), q, &count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r repository) CreateExposed(e *Exposed) (*Exposed, error) {
+	e.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	return e, errors.WithStack(r.db().Insert(r.tableExposed(), e))
+}
+
+func (r repository) UpdateExposed(e *Exposed) (*Exposed, error) {
+	rh.SetCurrentTimeRounded(&e.UpdatedAt)
+
+	return e, errors.WithStack(r.db().Replace(r.tableExposed(), e))
+}
+
+func (r repository) DeleteExposedByID(namespaceID, exposedID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableExposed(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": exposedID, "rel_namespace": namespaceID},
+	)
+}
+
+func (r repository) queryShared() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_node",
+			"remote_exposed_id",
+			"rel_module",
+			"push",
+			"enabled",
+			"pull_cursor",
+			"pushed_at",
+			"synced_at",
+			"last_error",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableShared()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindSharedByID(namespaceID, sharedID uint64) (*Shared, error) {
+	var (
+		s = &Shared{}
+		q = r.queryShared().Where(squirrel.Eq{"id": sharedID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.ID == 0 {
+		return nil, ErrSharedNotFound.withStack().WithID("namespaceID", namespaceID).WithID("sharedID", sharedID)
+	}
+
+	return s, nil
+}
+
+func (r repository) FindShared(namespaceID uint64) (set SharedSet, err error) {
+	q := r.queryShared().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindEnabledShared returns shared modules (of all namespaces) that are synced in the background
+func (r repository) FindEnabledShared() (set SharedSet, err error) {
+	q := r.queryShared().
+		Where(squirrel.Eq{"enabled": true}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateShared(s *Shared) (*Shared, error) {
+	s.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&s.CreatedAt)
+
+	return s, errors.WithStack(r.db().Insert(r.tableShared(), s))
+}
+
+func (r repository) UpdateShared(s *Shared) (*Shared, error) {
+	rh.SetCurrentTimeRounded(&s.UpdatedAt)
+
+	return s, errors.WithStack(r.db().Replace(r.tableShared(), s))
+}
+
+// UpdateSharedState stores result of the sync, without touching the configuration
+func (r repository) UpdateSharedState(s *Shared) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableShared(),
+		rh.Set{
+			"rel_module":  s.ModuleID,
+			"pull_cursor": s.Cursor,
+			"pushed_at":   s.PushedAt,
+			"synced_at":   s.SyncedAt,
+			"last_error":  s.LastError,
+		},
+		squirrel.Eq{"id": s.ID},
+	)
+}
+
+func (r repository) DeleteSharedByID(namespaceID, sharedID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableShared(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": sharedID, "rel_namespace": namespaceID},
+	)
+}
+
+func (r repository) queryLink() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"rel_node",
+			"rel_module",
+			"rel_record",
+			"remote_record_id",
+			"origin",
+			"remote_version",
+			"local_version",
+			"synced_at",
+		).
+		From(r.tableLink())
+}
+
+// FindLink returns link of the remote record, nil when record was not replicated yet
+func (r repository) FindLink(nodeID, moduleID, remoteRecordID uint64) (*Link, error) {
+	var (
+		l = &Link{}
+		q = r.queryLink().Where(squirrel.Eq{"rel_node": nodeID, "rel_module": moduleID, "remote_record_id": remoteRecordID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, l); err != nil {
+		return nil, err
+	} else if l.RecordID == 0 {
+		return nil, nil
+	}
+
+	return l, nil
+}
+
+// FindLinks returns links of local records of the module
+func (r repository) FindLinks(nodeID, moduleID uint64, recordIDs ...uint64) (set LinkSet, err error) {
+	q := r.queryLink().Where(squirrel.Eq{"rel_node": nodeID, "rel_module": moduleID, "rel_record": recordIDs})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindLinksByRecord returns provenance of the local record
+func (r repository) FindLinksByRecord(recordID uint64) (set LinkSet, err error) {
+	q := r.queryLink().
+		Where(squirrel.Eq{"rel_record": recordID}).
+		OrderBy("synced_at DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) SaveLink(l *Link) (*Link, error) {
+	rh.SetCurrentTimeRounded(&l.SyncedAt)
+
+	return l, errors.WithStack(r.db().Replace(r.tableLink(), l))
+}
+
+func (r repository) DeleteLink(l *Link) error {
+	return rh.Delete(r.db(), r.tableLink(), squirrel.Eq{
+		"rel_node":         l.NodeID,
+		"rel_module":       l.ModuleID,
+		"remote_record_id": l.RemoteRecordID,
+	})
+}
+
+// Tombstones returns IDs of module's records deleted since the given time
+func (r repository) Tombstones(moduleID uint64, since time.Time) (set []*tombstone, err error) {
+	q := squirrel.
+		Select("rel_module", "rel_record", "deleted_at").
+		From(r.tableTombstone()).
+		Where(squirrel.Eq{"rel_module": moduleID}).
+		Where(squirrel.GtOrEq{"deleted_at": since})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateTombstone(t *tombstone) error {
+	rh.SetCurrentTimeRounded(&t.DeletedAt)
+
+	return errors.WithStack(r.db().Replace(r.tableTombstone(), t))
+}
@@ -0,0 +1,191 @@
+package federation
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+type (
+	contextKey struct{}
+)
+
+// MountRoutes mounts federation management endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Route("/nodes", func(r chi.Router) {
+		r.Get("/", rest.Handler("FederationNode.List", func(r *http.Request) (interface{}, error) {
+			return DefaultFederation.With(r.Context()).FindNodes(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Post("/", rest.Handler("FederationNode.Create", func(r *http.Request) (interface{}, error) {
+			n := &Node{}
+			if err := rest.Decode(r, n); err != nil {
+				return nil, err
+			}
+
+			n.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultFederation.With(r.Context()).CreateNode(n)
+		}))
+
+		r.Put("/{nodeID}", rest.Handler("FederationNode.Update", func(r *http.Request) (interface{}, error) {
+			n := &Node{}
+			if err := rest.Decode(r, n); err != nil {
+				return nil, err
+			}
+
+			n.ID = rest.ParamUint64(r, "nodeID")
+			n.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultFederation.With(r.Context()).UpdateNode(n)
+		}))
+
+		r.Delete("/{nodeID}", rest.Handler("FederationNode.Delete", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultFederation.With(r.Context()).DeleteNode(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "nodeID"),
+			)
+		}))
+	})
+
+	r.Route("/exposed", func(r chi.Router) {
+		r.Get("/", rest.Handler("FederationExposed.List", func(r *http.Request) (interface{}, error) {
+			return DefaultFederation.With(r.Context()).FindExposed(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Post("/", rest.Handler("FederationExposed.Create", func(r *http.Request) (interface{}, error) {
+			e := &Exposed{}
+			if err := rest.Decode(r, e); err != nil {
+				return nil, err
+			}
+
+			e.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultFederation.With(r.Context()).CreateExposed(e)
+		}))
+
+		r.Put("/{exposedID}", rest.Handler("FederationExposed.Update", func(r *http.Request) (interface{}, error) {
+			e := &Exposed{}
+			if err := rest.Decode(r, e); err != nil {
+				return nil, err
+			}
+
+			e.ID = rest.ParamUint64(r, "exposedID")
+			e.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultFederation.With(r.Context()).UpdateExposed(e)
+		}))
+
+		r.Delete("/{exposedID}", rest.Handler("FederationExposed.Delete", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultFederation.With(r.Context()).DeleteExposed(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "exposedID"),
+			)
+		}))
+	})
+
+	r.Route("/shared", func(r chi.Router) {
+		r.Get("/", rest.Handler("FederationShared.List", func(r *http.Request) (interface{}, error) {
+			return DefaultFederation.With(r.Context()).FindShared(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Post("/", rest.Handler("FederationShared.Create", func(r *http.Request) (interface{}, error) {
+			s := &Shared{}
+			if err := rest.Decode(r, s); err != nil {
+				return nil, err
+			}
+
+			s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultFederation.With(r.Context()).CreateShared(s)
+		}))
+
+		r.Put("/{sharedID}", rest.Handler("FederationShared.Update", func(r *http.Request) (interface{}, error) {
+			s := &Shared{}
+			if err := rest.Decode(r, s); err != nil {
+				return nil, err
+			}
+
+			s.ID = rest.ParamUint64(r, "sharedID")
+			s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultFederation.With(r.Context()).UpdateShared(s)
+		}))
+
+		r.Delete("/{sharedID}", rest.Handler("FederationShared.Delete", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultFederation.With(r.Context()).DeleteShared(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "sharedID"),
+			)
+		}))
+
+		r.Post("/{sharedID}/sync", rest.Handler("FederationShared.Sync", func(r *http.Request) (interface{}, error) {
+			return DefaultFederation.With(r.Context()).Sync(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "sharedID"),
+			)
+		}))
+	})
+
+	r.Get("/provenance/{recordID}", rest.Handler("Federation.Provenance", func(r *http.Request) (interface{}, error) {
+		return DefaultFederation.With(r.Context()).Provenance(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recordID"),
+		)
+	}))
+}
+
+// MountPeerRoutes mounts endpoints that nodes call
+//
+// Nodes authenticate with the token in X-Federation-Token header, not as users.
+func MountPeerRoutes(r chi.Router) {
+	r.Use(authenticate)
+
+	r.Get("/modules", rest.Handler("FederationPeer.Modules", func(r *http.Request) (interface{}, error) {
+		return DefaultFederation.With(r.Context()).Modules(node(r))
+	}))
+
+	r.Get("/modules/{exposedID}/records", rest.Handler("FederationPeer.Changes", func(r *http.Request) (interface{}, error) {
+		var since *time.Time
+		if t, err := time.Parse(time.RFC3339, r.URL.Query().Get("since")); err == nil {
+			since = &t
+		}
+
+		return DefaultFederation.With(r.Context()).Changes(
+			node(r),
+			rest.ParamUint64(r, "exposedID"),
+			since,
+			rest.QueryUint(r, "page"),
+		)
+	}))
+
+	r.Post("/modules/{exposedID}/records", rest.Handler("FederationPeer.Push", func(r *http.Request) (interface{}, error) {
+		var rr []*PushedRecord
+		if err := rest.Decode(r, &rr); err != nil {
+			return nil, err
+		}
+
+		return DefaultFederation.With(r.Context()).Push(node(r), rest.ParamUint64(r, "exposedID"), rr)
+	}))
+}
+
+// authenticate resolves node from the token and stores it in the request context
+func authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := DefaultFederation.With(r.Context()).Authenticate(r.Header.Get(tokenHeader))
+		if err != nil {
+			rest.Error(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, n)))
+	})
+}
+
+func node(r *http.Request) *Node {
+	return r.Context().Value(contextKey{}).(*Node)
+}
@@ -0,0 +1,449 @@
+package federation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	federationService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	FederationService interface {
+		With(ctx context.Context) FederationService
+
+		FindNodes(namespaceID uint64) (NodeSet, error)
+		CreateNode(*Node) (*Node, error)
+		UpdateNode(*Node) (*Node, error)
+		DeleteNode(namespaceID, nodeID uint64) error
+
+		FindExposed(namespaceID uint64) (ExposedSet, error)
+		CreateExposed(*Exposed) (*Exposed, error)
+		UpdateExposed(*Exposed) (*Exposed, error)
+		DeleteExposed(namespaceID, exposedID uint64) error
+
+		FindShared(namespaceID uint64) (SharedSet, error)
+		CreateShared(*Shared) (*Shared, error)
+		UpdateShared(*Shared) (*Shared, error)
+		DeleteShared(namespaceID, sharedID uint64) error
+		Sync(namespaceID, sharedID uint64) (*SyncResult, error)
+
+		Provenance(namespaceID, recordID uint64) (LinkSet, error)
+
+		// Peer endpoints, called by nodes
+		Authenticate(token string) (*Node, error)
+		Modules(node *Node) ([]*ModuleInfo, error)
+		Changes(node *Node, exposedID uint64, since *time.Time, page uint) (*Changes, error)
+		Push(node *Node, exposedID uint64, rr []*PushedRecord) ([]*PushResult, error)
+	}
+)
+
+var (
+	DefaultFederation FederationService
+
+	// now is used for sync cursors and can be overridden
+	now = time.Now
+)
+
+// Init initializes federation service and starts syncing shared modules
+// in the background
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &federationService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+	}
+
+	DefaultFederation = svc.With(ctx)
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc federationService) With(ctx context.Context) FederationService {
+	return svc.with(ctx)
+}
+
+func (svc federationService) with(ctx context.Context) *federationService {
+	return &federationService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc federationService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc federationService) FindNodes(namespaceID uint64) (NodeSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.FindNodes(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set {
+		set[i] = set[i].withoutTokens()
+	}
+
+	return set, nil
+}
+
+// CreateNode stores new node
+//
+// Inbound token is generated and returned only once, with the created node;
+// it needs to be configured as outbound token of the node on the peer.
+func (svc federationService) CreateNode(in *Node) (*Node, error) {
+	if err := svc.canManage(in.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	if err := validateNode(in); err != nil {
+		return nil, err
+	}
+
+	token := generateToken()
+
+	n, err := svc.repository.CreateNode(&Node{
+		NamespaceID:      in.NamespaceID,
+		Name:             in.Name,
+		BaseURL:          in.BaseURL,
+		OutboundToken:    in.OutboundToken,
+		InboundTokenHash: hash(token),
+		CreatedBy:        auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	n = n.withoutTokens()
+	n.InboundToken = token
+	return n, nil
+}
+
+// UpdateNode modifies node
+//
+// Outbound token is changed only when a new one is given
+func (svc federationService) UpdateNode(upd *Node) (*Node, error) {
+	if err := svc.canManage(upd.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	if err := validateNode(upd); err != nil {
+		return nil, err
+	}
+
+	n, err := svc.repository.FindNodeByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	n.Name = upd.Name
+	n.BaseURL = upd.BaseURL
+
+	if upd.OutboundToken != "" {
+		n.OutboundToken = upd.OutboundToken
+	}
+
+	if n, err = svc.repository.UpdateNode(n); err != nil {
+		return nil, err
+	}
+
+	return n.withoutTokens(), nil
+}
+
+func (svc federationService) DeleteNode(namespaceID, nodeID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteNodeByID(namespaceID, nodeID)
+}
+
+func (svc federationService) FindExposed(namespaceID uint64) (ExposedSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindExposed(namespaceID, 0)
+}
+
+func (svc federationService) CreateExposed(in *Exposed) (*Exposed, error) {
+	if err := svc.validateExposed(in); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.CreateExposed(&Exposed{
+		NamespaceID: in.NamespaceID,
+		NodeID:      in.NodeID,
+		ModuleID:    in.ModuleID,
+		Fields:      in.Fields,
+		RunAs:       in.RunAs,
+		AllowPush:   in.AllowPush,
+		Conflict:    in.Conflict,
+	})
+}
+
+func (svc federationService) UpdateExposed(upd *Exposed) (*Exposed, error) {
+	e, err := svc.repository.FindExposedByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Module and node can not be changed, nodes would end up with a mix of records
+	upd.NodeID, upd.ModuleID = e.NodeID, e.ModuleID
+
+	if err = svc.validateExposed(upd); err != nil {
+		return nil, err
+	}
+
+	e.Fields = upd.Fields
+	e.RunAs = upd.RunAs
+	e.AllowPush = upd.AllowPush
+	e.Conflict = upd.Conflict
+
+	return svc.repository.UpdateExposed(e)
+}
+
+func (svc federationService) DeleteExposed(namespaceID, exposedID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteExposedByID(namespaceID, exposedID)
+}
+
+func (svc federationService) FindShared(namespaceID uint64) (SharedSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindShared(namespaceID)
+}
+
+func (svc federationService) CreateShared(in *Shared) (*Shared, error) {
+	if err := svc.validateShared(in); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.CreateShared(&Shared{
+		NamespaceID: in.NamespaceID,
+		NodeID:      in.NodeID,
+		ExposedID:   in.ExposedID,
+		Push:        in.Push,
+		Enabled:     in.Enabled,
+		OwnedBy:     in.OwnedBy,
+	})
+}
+
+func (svc federationService) UpdateShared(upd *Shared) (*Shared, error) {
+	s, err := svc.repository.FindSharedByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	upd.NodeID, upd.ExposedID = s.NodeID, s.ExposedID
+
+	if err = svc.validateShared(upd); err != nil {
+		return nil, err
+	}
+
+	s.Push = upd.Push
+	s.Enabled = upd.Enabled
+	s.OwnedBy = upd.OwnedBy
+
+	return svc.repository.UpdateShared(s)
+}
+
+func (svc federationService) DeleteShared(namespaceID, sharedID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteSharedByID(namespaceID, sharedID)
+}
+
+// Sync synchronises shared module immediately
+func (svc federationService) Sync(namespaceID, sharedID uint64) (*SyncResult, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.FindSharedByID(namespaceID, sharedID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.sync(s)
+}
+
+// Provenance returns links of the record to records on other nodes
+func (svc federationService) Provenance(namespaceID, recordID uint64) (LinkSet, error) {
+	if _, err := service.DefaultRecord.With(svc.ctx).FindByID(namespaceID, recordID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindLinksByRecord(recordID)
+}
+
+func (svc federationService) validateExposed(e *Exposed) error {
+	if err := svc.canManage(e.NamespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindNodeByID(e.NamespaceID, e.NodeID); err != nil {
+		return err
+	}
+
+	if e.RunAs == 0 {
+		return ErrOwnerRequired.withStack()
+	}
+
+	if e.Conflict == "" {
+		e.Conflict = ConflictOriginWins
+	} else if !e.Conflict.IsValid() {
+		return ErrInvalidConflict.withStack()
+	}
+
+	m, err := svc.module.FindByID(e.NamespaceID, e.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range e.Fields {
+		if f := m.Fields.FindByName(name); f == nil || f.Private {
+			return ErrInvalidField.withStack()
+		}
+	}
+
+	return nil
+}
+
+func (svc federationService) validateShared(s *Shared) error {
+	if err := svc.canManage(s.NamespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindNodeByID(s.NamespaceID, s.NodeID); err != nil {
+		return err
+	}
+
+	if s.ExposedID == 0 {
+		return ErrInvalidID.withStack()
+	}
+
+	if s.OwnedBy == 0 {
+		s.OwnedBy = auth.GetIdentityFromContext(svc.ctx).Identity()
+	}
+
+	return nil
+}
+
+func (svc federationService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// watch periodically syncs enabled shared modules
+func (svc federationService) watch(ctx context.Context) {
+	t := time.NewTicker(syncInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			ctx := auth.SetSuperUserContext(ctx)
+
+			set, err := Repository(ctx, nil).FindEnabledShared()
+			if err != nil {
+				svc.logger.Error("could not load shared modules", zap.Error(err))
+				continue
+			}
+
+			for _, s := range set {
+				if _, err = svc.with(ctx).sync(s); err != nil {
+					svc.logger.Error("could not sync shared module", zap.Uint64("sharedID", s.ID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func validateNode(n *Node) error {
+	if n.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	if u, err := url.Parse(n.BaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return ErrInvalidURL.withStack()
+	}
+
+	return nil
+}
+
+func generateToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,409 @@
+package federation
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	composeRepository "github.com/cortezaproject/corteza-server/compose/repository"
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+var (
+	// Fields of these kinds hold IDs that mean nothing on another node;
+	// they are mirrored as plain strings
+	referenceKinds = map[string]bool{
+		"Record": true,
+		"User":   true,
+		"File":   true,
+	}
+)
+
+// sync pushes local changes (when enabled) and pulls node's changes of the shared module
+//
+// Pushing first lets the node resolve conflicts; whatever it decides
+// is pulled back in the same run.
+func (svc federationService) sync(s *Shared) (res *SyncResult, err error) {
+	var (
+		log     = svc.log(zap.Uint64("sharedID", s.ID))
+		started = now().Truncate(time.Second)
+	)
+
+	res = &SyncResult{}
+
+	defer func() {
+		s.LastError = ""
+		if err != nil {
+			s.LastError = err.Error()
+		} else {
+			s.SyncedAt = &started
+		}
+
+		if serr := svc.repository.UpdateSharedState(s); serr != nil {
+			log.Error("could not store sync state", zap.Error(serr))
+		}
+	}()
+
+	node, err := svc.repository.FindNodeByID(s.NamespaceID, s.NodeID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := client{ctx: svc.ctx, node: node}
+
+	mm, err := c.Modules()
+	if err != nil {
+		return nil, err
+	}
+
+	var info *ModuleInfo
+	for _, mi := range mm {
+		if mi.ExposedID == s.ExposedID {
+			info = mi
+		}
+	}
+
+	if info == nil {
+		return nil, ErrExposedNotFound.withStack()
+	}
+
+	ctx, err := runas.Compose(svc.ctx, s.OwnedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := svc.syncStructure(ctx, s, info)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Push && info.AllowPush {
+		if err = svc.push(ctx, c, s, m, res); err != nil {
+			return nil, err
+		}
+
+		s.PushedAt = &started
+	}
+
+	if err = svc.pull(ctx, c, s, m, res); err != nil {
+		return nil, err
+	}
+
+	log.Info("shared module synced",
+		zap.Uint("pushed", res.Pushed),
+		zap.Uint("conflicts", res.Conflicts),
+		zap.Uint("created", res.Created),
+		zap.Uint("updated", res.Updated),
+		zap.Uint("deleted", res.Deleted),
+	)
+
+	return res, nil
+}
+
+// syncStructure creates local module on the first sync and keeps its fields
+// the same as fields of the exposed module
+func (svc federationService) syncStructure(ctx context.Context, s *Shared, info *ModuleInfo) (*types.Module, error) {
+	ms := service.DefaultModule.With(ctx)
+
+	if s.ModuleID == 0 {
+		m, err := ms.Create(&types.Module{
+			NamespaceID: s.NamespaceID,
+			Name:        info.Name,
+			Meta:        []byte("{}"),
+			Fields:      mirrorFields(nil, info.Fields),
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		s.ModuleID = m.ID
+		return m, nil
+	}
+
+	m, err := ms.FindByID(s.NamespaceID, s.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ff := mirrorFields(m.Fields, info.Fields)
+	if sameFields(m.Fields, ff) {
+		return m, nil
+	}
+
+	m.Fields = ff
+	return ms.Update(m)
+}
+
+// push sends records that changed locally since the last push to the node
+func (svc federationService) push(ctx context.Context, c client, s *Shared, m *types.Module, res *SyncResult) error {
+	f := types.RecordFilter{NamespaceID: s.NamespaceID, ModuleID: m.ID}
+	if s.PushedAt != nil {
+		p := s.PushedAt.Local().Format("2006-01-02 15:04:05")
+		f.Filter = "createdAt >= '" + p + "' OR updatedAt >= '" + p + "'"
+	}
+
+	rr, _, err := service.DefaultRecord.With(ctx).Find(f)
+	if err != nil || len(rr) == 0 {
+		return err
+	}
+
+	links, err := svc.repository.FindLinks(c.node.ID, m.ID, rr.IDs()...)
+	if err != nil {
+		return err
+	}
+
+	var (
+		linked = map[uint64]*Link{}
+		local  = map[uint64]*types.Record{}
+		batch  []*PushedRecord
+	)
+
+	for _, l := range links {
+		linked[l.RecordID] = l
+	}
+
+	for _, r := range rr {
+		var (
+			l  = linked[r.ID]
+			pr = &PushedRecord{SourceID: r.ID, Values: r.Values, UpdatedAt: version(r.CreatedAt, r.UpdatedAt)}
+		)
+
+		if l != nil {
+			if !pr.UpdatedAt.After(l.LocalVersion) {
+				// Not changed since it was synced (or written by the pull)
+				continue
+			}
+
+			pr.ID, pr.Base = l.RemoteRecordID, &l.RemoteVersion
+		}
+
+		local[r.ID] = r
+		batch = append(batch, pr)
+	}
+
+	for len(batch) > 0 {
+		n := batchSize
+		if n > len(batch) {
+			n = len(batch)
+		}
+
+		results, err := c.Push(s.ExposedID, batch[:n])
+		if err != nil {
+			return err
+		}
+
+		batch = batch[n:]
+
+		for _, pr := range results {
+			r := local[pr.SourceID]
+			if r == nil {
+				continue
+			}
+
+			switch pr.Status {
+			case PushCreated, PushUpdated:
+				res.Pushed++
+
+				_, err = svc.repository.SaveLink(&Link{
+					NodeID:         c.node.ID,
+					ModuleID:       m.ID,
+					RecordID:       r.ID,
+					RemoteRecordID: pr.RecordID,
+					Origin:         linkOrigin(linked[r.ID]),
+					RemoteVersion:  pr.Version,
+					LocalVersion:   version(r.CreatedAt, r.UpdatedAt),
+				})
+
+				if err != nil {
+					return err
+				}
+
+			case PushConflict:
+				// Node's version is pulled, local change is lost
+				res.Conflicts++
+
+			default:
+				svc.log(zap.Uint64("recordID", r.ID)).Warn("node could not apply pushed record", zap.String("error", pr.Error))
+			}
+		}
+	}
+
+	return nil
+}
+
+// pull applies node's changes since the last pull
+func (svc federationService) pull(ctx context.Context, c client, s *Shared, m *types.Module, res *SyncResult) error {
+	var until *time.Time
+
+	for page := uint(1); ; page++ {
+		ch, err := c.Changes(s.ExposedID, s.Cursor, page)
+		if err != nil {
+			return err
+		}
+
+		if until == nil {
+			until = &ch.Until
+		}
+
+		for _, ri := range ch.Records {
+			if err = svc.pullRecord(ctx, c.node, s, m, ri, res); err != nil {
+				return err
+			}
+		}
+
+		for _, ID := range recordIDs(ch.Deleted) {
+			if err = svc.pullDeleted(ctx, c.node, s, m, ID, res); err != nil {
+				return err
+			}
+		}
+
+		if len(ch.Records) < batchSize {
+			break
+		}
+	}
+
+	s.Cursor = until
+	return nil
+}
+
+func (svc federationService) pullRecord(ctx context.Context, node *Node, s *Shared, m *types.Module, ri *RecordInfo, res *SyncResult) error {
+	var (
+		rs     = service.DefaultRecord.With(ctx)
+		remote = version(ri.CreatedAt, ri.UpdatedAt)
+		values = types.RecordValueSet{}
+	)
+
+	l, err := svc.repository.FindLink(node.ID, m.ID, ri.ID)
+	if err != nil {
+		return err
+	} else if l != nil && l.RemoteVersion.Equal(remote) {
+		// Already have this version (we pushed it or pulled it before)
+		return nil
+	}
+
+	for _, v := range ri.Values {
+		if m.Fields.HasName(v.Name) {
+			values = append(values, &types.RecordValue{Name: v.Name, Value: v.Value})
+		}
+	}
+
+	var r *types.Record
+
+	if l != nil {
+		if r, err = rs.FindByID(s.NamespaceID, l.RecordID); err != nil && !fault.Is(err, composeRepository.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	if r == nil {
+		// New record or local copy was deleted
+		if r, err = rs.Create(&types.Record{NamespaceID: s.NamespaceID, ModuleID: m.ID, Values: values}); err != nil {
+			return err
+		}
+
+		res.Created++
+	} else {
+		r.Values = values
+		if r, err = rs.Update(r); err != nil {
+			return err
+		}
+
+		res.Updated++
+	}
+
+	_, err = svc.repository.SaveLink(&Link{
+		NodeID:         node.ID,
+		ModuleID:       m.ID,
+		RecordID:       r.ID,
+		RemoteRecordID: ri.ID,
+		Origin:         pulledOrigin(l),
+		RemoteVersion:  remote,
+		LocalVersion:   version(r.CreatedAt, r.UpdatedAt),
+	})
+
+	return err
+}
+
+func (svc federationService) pullDeleted(ctx context.Context, node *Node, s *Shared, m *types.Module, remoteID uint64, res *SyncResult) error {
+	l, err := svc.repository.FindLink(node.ID, m.ID, remoteID)
+	if err != nil || l == nil {
+		return err
+	}
+
+	err = service.DefaultRecord.With(ctx).DeleteByID(s.NamespaceID, l.RecordID)
+	if err != nil && !fault.Is(err, composeRepository.ErrRecordNotFound) {
+		return err
+	}
+
+	res.Deleted++
+	return svc.repository.DeleteLink(l)
+}
+
+// mirrorFields returns fields of the local module, updated with fields of the exposed module
+//
+// Existing fields keep their IDs, fields that are no longer exposed are removed.
+func mirrorFields(existing types.ModuleFieldSet, ff []*FieldInfo) types.ModuleFieldSet {
+	out := types.ModuleFieldSet{}
+
+	for i, fi := range ff {
+		f := &types.ModuleField{}
+		if e := existing.FindByName(fi.Name); e != nil {
+			c := *e
+			f = &c
+		}
+
+		f.Name = fi.Name
+		f.Label = fi.Label
+		f.Kind = fi.Kind
+		f.Options = fi.Options
+		f.Multi = fi.Multi
+		f.Visible = true
+		f.Place = i
+
+		if referenceKinds[f.Kind] {
+			f.Kind = "String"
+			f.Options = types.ModuleFieldOptions{}
+		}
+
+		out = append(out, f)
+	}
+
+	return out
+}
+
+func sameFields(a, b types.ModuleFieldSet) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Label != b[i].Label || a[i].Kind != b[i].Kind || a[i].Multi != b[i].Multi {
+			return false
+		}
+	}
+
+	return true
+}
+
+// linkOrigin keeps origin of the existing link; pushed records without one were created locally
+func linkOrigin(l *Link) Origin {
+	if l == nil {
+		return OriginLocal
+	}
+
+	return l.Origin
+}
+
+// pulledOrigin keeps origin of the existing link; pulled records without one were created on the node
+func pulledOrigin(l *Link) Origin {
+	if l == nil {
+		return OriginRemote
+	}
+
+	return l.Origin
+}
@@ -0,0 +1,283 @@
+package federation
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+)
+
+type (
+	// Node is a peer crust-server instance that namespace federates with
+	//
+	// Peer authenticates to us with the inbound token (issued when node is
+	// created and returned only once), we authenticate to the peer with
+	// the outbound token that the peer issued to us.
+	Node struct {
+		ID          uint64 `json:"nodeID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+
+		Name string `json:"name" db:"name"`
+
+		// Root of peer's compose API, for example https://crm.example.com/api/compose
+		BaseURL string `json:"baseURL" db:"base_url"`
+
+		OutboundToken    string `json:"outboundToken,omitempty" db:"outbound_token"`
+		InboundToken     string `json:"inboundToken,omitempty" db:"-"`
+		InboundTokenHash string `json:"-" db:"inbound_token_hash"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	NodeSet []*Node
+
+	// Exposed is a local module that is shared with a node
+	//
+	// Records are read and written with permissions of the RunAs user,
+	// only listed fields are shared (all non-private fields when empty).
+	Exposed struct {
+		ID          uint64 `json:"exposedID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		NodeID      uint64 `json:"nodeID,string" db:"rel_node"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+
+		Fields fieldNames `json:"fields" db:"fields"`
+		RunAs  uint64     `json:"runAs,string" db:"run_as"`
+
+		// Can the node push changes back
+		AllowPush bool     `json:"allowPush" db:"allow_push"`
+		Conflict  Conflict `json:"conflict" db:"conflict"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	ExposedSet []*Exposed
+
+	// Shared is a module that a node exposes to us, mirrored into a local module
+	//
+	// Local module is created (and its fields kept in sync) on the first sync.
+	Shared struct {
+		ID          uint64 `json:"sharedID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		NodeID      uint64 `json:"nodeID,string" db:"rel_node"`
+		ExposedID   uint64 `json:"exposedID,string" db:"remote_exposed_id"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+
+		// Push local changes back to the node
+		Push    bool `json:"push" db:"push"`
+		Enabled bool `json:"enabled" db:"enabled"`
+
+		// Node's time of the last successful pull, changes after it are pulled next time
+		Cursor *time.Time `json:"cursor,omitempty" db:"pull_cursor"`
+
+		// Our time of the last successful push
+		PushedAt *time.Time `json:"pushedAt,omitempty" db:"pushed_at"`
+
+		SyncedAt  *time.Time `json:"syncedAt,omitempty" db:"synced_at"`
+		LastError string     `json:"lastError,omitempty" db:"last_error"`
+
+		// Mirrored records are created in the name of the owner
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	SharedSet []*Shared
+
+	// Link is provenance of a record: it connects local record with the
+	// record on the node it was replicated from (or to)
+	Link struct {
+		NodeID         uint64 `json:"nodeID,string" db:"rel_node"`
+		ModuleID       uint64 `json:"moduleID,string" db:"rel_module"`
+		RecordID       uint64 `json:"recordID,string" db:"rel_record"`
+		RemoteRecordID uint64 `json:"remoteRecordID,string" db:"remote_record_id"`
+
+		// Pulled records originate on the node, pushed ones were created there and pushed to us
+		Origin Origin `json:"origin" db:"origin"`
+
+		// Versions (time of the last change) of both records after the last sync
+		RemoteVersion time.Time `json:"remoteVersion" db:"remote_version"`
+		LocalVersion  time.Time `json:"localVersion" db:"local_version"`
+
+		SyncedAt time.Time `json:"syncedAt" db:"synced_at"`
+	}
+
+	LinkSet []*Link
+
+	// tombstone keeps deletion of exposed module's record, so that it can be replicated
+	tombstone struct {
+		ModuleID  uint64    `db:"rel_module"`
+		RecordID  uint64    `db:"rel_record"`
+		DeletedAt time.Time `db:"deleted_at"`
+	}
+
+	// ModuleInfo is structure of an exposed module, as seen by the node
+	ModuleInfo struct {
+		ExposedID uint64       `json:"exposedID,string"`
+		Name      string       `json:"name"`
+		Handle    string       `json:"handle"`
+		Fields    []*FieldInfo `json:"fields"`
+		AllowPush bool         `json:"allowPush"`
+	}
+
+	FieldInfo struct {
+		Name     string                          `json:"name"`
+		Label    string                          `json:"label"`
+		Kind     string                          `json:"kind"`
+		Options  composeTypes.ModuleFieldOptions `json:"options"`
+		Multi    bool                            `json:"isMulti"`
+		Required bool                            `json:"isRequired"`
+	}
+
+	// RecordInfo is a replicated record
+	RecordInfo struct {
+		ID        uint64                      `json:"recordID,string"`
+		Values    composeTypes.RecordValueSet `json:"values"`
+		CreatedAt time.Time                   `json:"createdAt"`
+		UpdatedAt *time.Time                  `json:"updatedAt,omitempty"`
+	}
+
+	// Changes are records of an exposed module that changed since the given time
+	Changes struct {
+		Records []*RecordInfo `json:"records"`
+		Deleted []string      `json:"deleted"`
+
+		// Node's time when changes were collected; cursor for the next pull
+		Until time.Time `json:"until"`
+	}
+
+	// PushedRecord is a change made on the node
+	//
+	// Base is version of the record that the change is based on
+	// (zero for records created on the node).
+	PushedRecord struct {
+		ID        uint64                      `json:"recordID,string"`
+		SourceID  uint64                      `json:"sourceID,string"`
+		Base      *time.Time                  `json:"base,omitempty"`
+		Values    composeTypes.RecordValueSet `json:"values"`
+		UpdatedAt time.Time                   `json:"updatedAt"`
+	}
+
+	PushResult struct {
+		SourceID uint64     `json:"sourceID,string"`
+		RecordID uint64     `json:"recordID,string"`
+		Status   PushStatus `json:"status"`
+		Version  time.Time  `json:"version"`
+		Error    string     `json:"error,omitempty"`
+	}
+
+	// SyncResult sums up one synchronisation of a shared module
+	SyncResult struct {
+		Pushed    uint `json:"pushed"`
+		Conflicts uint `json:"conflicts"`
+		Created   uint `json:"created"`
+		Updated   uint `json:"updated"`
+		Deleted   uint `json:"deleted"`
+	}
+
+	Conflict   string
+	Origin     string
+	PushStatus string
+
+	fieldNames []string
+)
+
+const (
+	// Pushed change is rejected when record changed on the origin since
+	ConflictOriginWins Conflict = "origin-wins"
+
+	// The later change wins
+	ConflictNewestWins Conflict = "newest-wins"
+
+	OriginRemote Origin = "remote"
+	OriginLocal  Origin = "local"
+
+	PushCreated  PushStatus = "created"
+	PushUpdated  PushStatus = "updated"
+	PushConflict PushStatus = "conflict"
+	PushFailed   PushStatus = "failed"
+
+	// Header with node's token on peer endpoints
+	tokenHeader = "X-Federation-Token"
+
+	// How often shared modules are synced
+	syncInterval = 5 * time.Minute
+
+	// Records per page when pulling and per batch when pushing
+	batchSize = 100
+)
+
+func (c Conflict) IsValid() bool {
+	return c == ConflictOriginWins || c == ConflictNewestWins
+}
+
+func (n Node) withoutTokens() *Node {
+	n.OutboundToken = ""
+	n.InboundToken = ""
+	return &n
+}
+
+// version returns time of the last change of the record
+func version(createdAt time.Time, updatedAt *time.Time) time.Time {
+	if updatedAt != nil {
+		return updatedAt.Truncate(time.Second)
+	}
+
+	return createdAt.Truncate(time.Second)
+}
+
+func (ff fieldNames) has(name string) bool {
+	if len(ff) == 0 {
+		return true
+	}
+
+	for _, f := range ff {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ff fieldNames) Value() (driver.Value, error) {
+	if ff == nil {
+		ff = fieldNames{}
+	}
+
+	return json.Marshal(ff)
+}
+
+func (ff *fieldNames) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ff = fieldNames{}
+	case []byte:
+		if err := json.Unmarshal(b, ff); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into fieldNames", string(b))
+		}
+	}
+
+	return nil
+}
+
+func recordIDs(ss []string) []uint64 {
+	out := make([]uint64, 0, len(ss))
+	for _, s := range ss {
+		if ID := payload.ParseUInt64(s); ID > 0 {
+			out = append(out, ID)
+		}
+	}
+
+	return out
+}
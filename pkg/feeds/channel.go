@@ -0,0 +1,171 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	// channelSource renders messages of the channel; replies are not included
+	channelSource struct {
+		settings settingsGetter
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+)
+
+var (
+	// Types of messages that are rendered into entries
+	messageTypes = []string{
+		messagingTypes.MessageTypeSimpleMessage.String(),
+		messagingTypes.MessageTypeAttachment.String(),
+		messagingTypes.MessageTypeInlineImage.String(),
+	}
+)
+
+// InitMessaging initializes feeds of channel messages
+//
+// Must be called after messaging services are initialized
+func InitMessaging(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log, "messaging", &channelSource{settings: messagingService.DefaultSettings})
+}
+
+func (channelSource) validate(ctx context.Context, f *Feed) error {
+	if f.ChannelID == 0 {
+		return ErrChannelRequired.withStack()
+	}
+
+	f.NamespaceID, f.ModuleID, f.Filter, f.Sort, f.Mapping = 0, 0, "", "", Mapping{}
+
+	_, err := messagingService.DefaultChannel.With(ctx).FindByID(f.ChannelID)
+	return err
+}
+
+// runAs loads the owner with roles they currently have; feeds of
+// suspended or deleted owners fail
+//
+// Standalone messaging server gets the owner from system service, see
+// runas.Identity; feeds respond with 503 while it can not be reached.
+func (channelSource) runAs(ctx context.Context, f *Feed) (context.Context, error) {
+	return runas.Context(ctx, f.OwnedBy)
+}
+
+func (src channelSource) document(ctx context.Context, f *Feed, limit uint) (*Document, error) {
+	ch, err := messagingService.DefaultChannel.With(ctx).FindByID(f.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	mm, _, err := messagingService.DefaultMessage.With(ctx).Find(messagingTypes.MessageFilter{
+		ChannelID: []uint64{ch.ID},
+		Type:      messageTypes,
+		Limit:     limit,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		base  = frontendURL(ctx, src.settings)
+		names = names(ctx, mm)
+
+		d = &Document{
+			ID:      fmt.Sprintf("urn:crust:messaging:feed:%d", f.ID),
+			Title:   f.Title,
+			Link:    fmt.Sprintf("%s/messaging/ch/%d", base, ch.ID),
+			Updated: ch.CreatedAt,
+			Entries: make([]*Entry, 0, len(mm)),
+		}
+	)
+
+	if d.Title == "" {
+		d.Title = "#" + ch.Name
+	}
+
+	for _, m := range mm {
+		e := &Entry{
+			ID:        fmt.Sprintf("urn:crust:messaging:message:%d", m.ID),
+			Title:     title(m.Message),
+			Content:   m.Message,
+			Link:      fmt.Sprintf("%s/messaging/ch/%d/thread/%d", base, ch.ID, m.ID),
+			Author:    names[m.UserID],
+			Published: m.CreatedAt,
+			Updated:   m.CreatedAt,
+		}
+
+		if m.UpdatedAt != nil {
+			e.Updated = *m.UpdatedAt
+		}
+
+		if m.Attachment != nil && e.Title == "" {
+			e.Title = m.Attachment.Name
+		}
+
+		if e.Updated.After(d.Updated) {
+			d.Updated = e.Updated
+		}
+
+		d.Entries = append(d.Entries, e)
+	}
+
+	return d, nil
+}
+
+// names returns names of authors of the messages; IDs when names can not be loaded
+func names(ctx context.Context, mm messagingTypes.MessageSet) map[uint64]string {
+	var (
+		out = map[uint64]string{}
+		ids []uint64
+	)
+
+	for _, m := range mm {
+		if _, ok := out[m.UserID]; !ok {
+			out[m.UserID] = payload.Uint64toa(m.UserID)
+			ids = append(ids, m.UserID)
+		}
+	}
+
+	if systemService.DefaultUser == nil {
+		return out
+	}
+
+	uu, _, err := systemService.DefaultUser.With(ctx).Find(systemTypes.UserFilter{UserID: ids})
+	if err != nil {
+		return out
+	}
+
+	for _, u := range uu {
+		if u.Name != "" {
+			out[u.ID] = u.Name
+		} else if u.Handle != "" {
+			out[u.ID] = u.Handle
+		}
+	}
+
+	return out
+}
+
+// frontendURL returns base URL of the web application; links are
+// relative when it is not configured
+func frontendURL(ctx context.Context, s settingsGetter) string {
+	if v, err := s.Get(auth.SetSuperUserContext(ctx), settingFrontendURL, 0); err == nil && v != nil {
+		return strings.TrimSuffix(v.String(), "/")
+	}
+
+	return ""
+}
@@ -0,0 +1,140 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type (
+	rss struct {
+		XMLName xml.Name   `xml:"rss"`
+		Version string     `xml:"version,attr"`
+		DC      string     `xml:"xmlns:dc,attr"`
+		Channel rssChannel `xml:"channel"`
+	}
+
+	rssChannel struct {
+		Title         string     `xml:"title"`
+		Link          string     `xml:"link"`
+		Description   string     `xml:"description"`
+		LastBuildDate string     `xml:"lastBuildDate"`
+		Items         []*rssItem `xml:"item"`
+	}
+
+	rssItem struct {
+		Title       string  `xml:"title"`
+		Link        string  `xml:"link,omitempty"`
+		Description string  `xml:"description"`
+		Creator     string  `xml:"dc:creator,omitempty"`
+		GUID        rssGUID `xml:"guid"`
+		PubDate     string  `xml:"pubDate"`
+	}
+
+	rssGUID struct {
+		IsPermaLink bool   `xml:"isPermaLink,attr"`
+		Value       string `xml:",chardata"`
+	}
+
+	atom struct {
+		XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string       `xml:"id"`
+		Title   string       `xml:"title"`
+		Updated string       `xml:"updated"`
+		Link    *atomLink    `xml:"link,omitempty"`
+		Entries []*atomEntry `xml:"entry"`
+	}
+
+	atomEntry struct {
+		ID        string      `xml:"id"`
+		Title     string      `xml:"title"`
+		Published string      `xml:"published"`
+		Updated   string      `xml:"updated"`
+		Author    *atomAuthor `xml:"author,omitempty"`
+		Link      *atomLink   `xml:"link,omitempty"`
+		Content   atomContent `xml:"content"`
+	}
+
+	atomAuthor struct {
+		Name string `xml:"name"`
+	}
+
+	atomLink struct {
+		Href string `xml:"href,attr"`
+	}
+
+	atomContent struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	}
+)
+
+// RSS writes the document as RSS 2.0
+func (d Document) RSS(w io.Writer) error {
+	out := rss{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         d.Title,
+			Link:          d.Link,
+			Description:   d.Title,
+			LastBuildDate: d.Updated.UTC().Format(time.RFC1123Z),
+			Items:         make([]*rssItem, len(d.Entries)),
+		},
+	}
+
+	for i, e := range d.Entries {
+		out.Channel.Items[i] = &rssItem{
+			Title:       e.Title,
+			Link:        e.Link,
+			Description: e.Content,
+			Creator:     e.Author,
+			GUID:        rssGUID{Value: e.ID},
+			PubDate:     e.Published.UTC().Format(time.RFC1123Z),
+		}
+	}
+
+	return encode(w, out)
+}
+
+// Atom writes the document as Atom 1.0
+func (d Document) Atom(w io.Writer) error {
+	out := atom{
+		ID:      d.ID,
+		Title:   d.Title,
+		Updated: d.Updated.UTC().Format(time.RFC3339),
+		Entries: make([]*atomEntry, len(d.Entries)),
+	}
+
+	if d.Link != "" {
+		out.Link = &atomLink{Href: d.Link}
+	}
+
+	for i, e := range d.Entries {
+		out.Entries[i] = &atomEntry{
+			ID:        e.ID,
+			Title:     e.Title,
+			Published: e.Published.UTC().Format(time.RFC3339),
+			Updated:   e.Updated.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "text", Value: e.Content},
+		}
+
+		if e.Author != "" {
+			out.Entries[i].Author = &atomAuthor{Name: e.Author}
+		}
+
+		if e.Link != "" {
+			out.Entries[i].Link = &atomLink{Href: e.Link}
+		}
+	}
+
+	return encode(w, out)
+}
+
+func encode(w io.Writer, v interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(v)
+}
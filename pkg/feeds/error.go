@@ -0,0 +1,38 @@
+package feeds
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	feedError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrFeedNotFound     = feedError{"FeedNotFound", fault.NotFound}
+	ErrUnknownApp       = feedError{"UnknownApp", fault.Invalid}
+	ErrChannelRequired  = feedError{"ChannelRequired", fault.Invalid}
+	ErrModuleRequired   = feedError{"ModuleRequired", fault.Invalid}
+	ErrInvalidMapping   = feedError{"InvalidMapping", fault.Invalid}
+	ErrInvalidDateField = feedError{"InvalidDateField", fault.Invalid}
+	ErrNoPermissions    = feedError{"NoPermissions", fault.Forbidden}
+)
+
+func (e feedError) Error() string {
+	return e.String()
+}
+
+func (e feedError) String() string {
+	return "crust.feeds." + e.name
+}
+
+func (e feedError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e feedError) withStack() *fault.Error {
+	return fault.New(e)
+}
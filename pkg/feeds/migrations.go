@@ -0,0 +1,53 @@
+package feeds
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	ComposeMigrations   = migrationsOf("compose")
+	MessagingMigrations = migrationsOf("messaging")
+)
+
+// migrationsOf returns migrations of app's feed table
+//
+// Names differ by app, apps can share the database.
+func migrationsOf(app string) migrations.Set {
+	return migrations.Set{
+		{
+			Name: "20200305000000.feeds-" + app,
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_` + app + `_feed (
+  id                 BIGINT UNSIGNED NOT NULL,
+  token_hash         CHAR(64)        NOT NULL,
+  title              VARCHAR(255)    NOT NULL DEFAULT '',
+
+  rel_channel        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+
+  rel_namespace      BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  rel_module         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  filter             TEXT            NOT NULL,
+  sort               VARCHAR(255)    NOT NULL DEFAULT '',
+  mapping            TEXT            NOT NULL,
+
+  owned_by           BIGINT UNSIGNED NOT NULL,
+  roles              TEXT            NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY uid_token (token_hash),
+  INDEX (owned_by)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+		{
+			Name: "20200313000000.feeds-" + app + "-roles",
+			Up: `
+ALTER TABLE crust_` + app + `_feed
+  DROP COLUMN roles;
+`,
+		},
+	}
+}
@@ -0,0 +1,288 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/expr"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	// recordSource renders records of the module, mapped into entries
+	recordSource struct {
+		settings settingsGetter
+	}
+
+	// templates of the mapping; nil when not set
+	templates struct {
+		title, content, author, link *expr.Template
+	}
+)
+
+const (
+	// Newest records first, unless feed is sorted otherwise
+	defaultSort = "createdAt DESC"
+)
+
+// InitCompose initializes feeds of records
+//
+// Must be called after compose services are initialized
+func InitCompose(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log, "compose", &recordSource{settings: service.DefaultSettings})
+}
+
+func (recordSource) validate(ctx context.Context, f *Feed) error {
+	if f.NamespaceID == 0 || f.ModuleID == 0 {
+		return ErrModuleRequired.withStack()
+	}
+
+	f.ChannelID = 0
+
+	if _, err := parseMapping(f.Mapping); err != nil {
+		return err
+	}
+
+	m, err := service.DefaultModule.With(ctx).FindByID(f.NamespaceID, f.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if f.Mapping.Date != "" {
+		if mf := m.Fields.FindByName(f.Mapping.Date); mf == nil || mf.Kind != "DateTime" || mf.Multi {
+			return ErrInvalidDateField.withStack().WithMessage("date must be a single-value date & time field")
+		}
+	}
+
+	// Checks filter, sort and permissions to read records
+	_, _, err = service.DefaultRecord.With(ctx).Find(f.recordFilter(1))
+	return err
+}
+
+// runAs issues token of the owner, with roles they currently have
+func (recordSource) runAs(ctx context.Context, f *Feed) (context.Context, error) {
+	return runas.Compose(ctx, f.OwnedBy)
+}
+
+func (src recordSource) document(ctx context.Context, f *Feed, limit uint) (*Document, error) {
+	tt, err := parseMapping(f.Mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := service.DefaultNamespace.With(ctx).FindByID(f.NamespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := service.DefaultModule.With(ctx).FindByID(f.NamespaceID, f.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	rr, _, err := service.DefaultRecord.With(ctx).Find(f.recordFilter(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		base = frontendURL(ctx, src.settings)
+
+		// Records are linked to the module's record page, when there is one
+		pageID uint64
+
+		d = &Document{
+			ID:      fmt.Sprintf("urn:crust:compose:feed:%d", f.ID),
+			Title:   f.Title,
+			Link:    fmt.Sprintf("%s/compose/ns/%s", base, ns.Slug),
+			Updated: m.CreatedAt,
+			Entries: make([]*Entry, 0, len(rr)),
+		}
+	)
+
+	if d.Title == "" {
+		d.Title = m.Name
+	}
+
+	if p, err := service.DefaultPage.With(ctx).FindByModuleID(ns.ID, m.ID); err == nil && p != nil {
+		pageID = p.ID
+	}
+
+	for _, r := range rr {
+		var (
+			link string
+			s    = scope(ns, m, r)
+			e    = &Entry{
+				ID:        fmt.Sprintf("urn:crust:compose:record:%d", r.ID),
+				Published: r.CreatedAt,
+				Updated:   r.CreatedAt,
+			}
+		)
+
+		if pageID > 0 {
+			link = fmt.Sprintf("%s/compose/ns/%s/pages/%d/record/%d", base, ns.Slug, pageID, r.ID)
+		}
+
+		s["link"] = link
+
+		if r.UpdatedAt != nil {
+			e.Updated = *r.UpdatedAt
+		}
+
+		if f.Mapping.Date != "" {
+			if t := dateValue(r, f.Mapping.Date); t != nil {
+				e.Published, e.Updated = *t, *t
+			}
+		}
+
+		if e.Title, err = render(tt.title, s); err != nil {
+			return nil, err
+		} else if e.Content, err = render(tt.content, s); err != nil {
+			return nil, err
+		} else if e.Author, err = render(tt.author, s); err != nil {
+			return nil, err
+		} else if e.Link, err = render(tt.link, s); err != nil {
+			return nil, err
+		}
+
+		if tt.link == nil {
+			e.Link = link
+		}
+
+		if e.Updated.After(d.Updated) {
+			d.Updated = e.Updated
+		}
+
+		d.Entries = append(d.Entries, e)
+	}
+
+	return d, nil
+}
+
+func (f Feed) recordFilter(limit uint) types.RecordFilter {
+	rf := types.RecordFilter{
+		NamespaceID: f.NamespaceID,
+		ModuleID:    f.ModuleID,
+		Filter:      f.Filter,
+		Sort:        f.Sort,
+		PageFilter:  rh.PageFilter{Page: 1, PerPage: limit},
+	}
+
+	if rf.Sort == "" {
+		rf.Sort = defaultSort
+	}
+
+	return rf
+}
+
+// parseMapping parses templates of the mapping; title is required
+func parseMapping(m Mapping) (*templates, error) {
+	var (
+		tt  = &templates{}
+		err error
+	)
+
+	if m.Title == "" {
+		return nil, ErrInvalidMapping.withStack().WithMessage("title is required")
+	}
+
+	for _, t := range []struct {
+		name string
+		src  string
+		dst  **expr.Template
+	}{
+		{"title", m.Title, &tt.title},
+		{"content", m.Content, &tt.content},
+		{"author", m.Author, &tt.author},
+		{"link", m.Link, &tt.link},
+	} {
+		if t.src == "" {
+			continue
+		}
+
+		if *t.dst, err = expr.ParseTemplate(t.src); err != nil {
+			return nil, ErrInvalidMapping.withStack().WithMessage(t.name + ": " + err.Error())
+		}
+	}
+
+	return tt, nil
+}
+
+func render(t *expr.Template, s expr.Scope) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+
+	return t.Render(s)
+}
+
+// dateValue returns value of the date & time field; nil when not set
+func dateValue(r *types.Record, field string) *time.Time {
+	v := r.Values.FilterByName(field)
+	if len(v) == 0 {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, v[0].Value); err == nil {
+			return &t
+		}
+	}
+
+	return nil
+}
+
+// scope prepares variables for template rendering
+func scope(ns *types.Namespace, m *types.Module, r *types.Record) expr.Scope {
+	var (
+		values = expr.Scope{}
+		rs     = expr.Scope{
+			"values":    values,
+			"recordID":  payload.Uint64toa(r.ID),
+			"ownedBy":   payload.Uint64toa(r.OwnedBy),
+			"createdBy": payload.Uint64toa(r.CreatedBy),
+			"updatedBy": payload.Uint64toa(r.UpdatedBy),
+			"createdAt": r.CreatedAt,
+		}
+	)
+
+	if r.UpdatedAt != nil {
+		rs["updatedAt"] = *r.UpdatedAt
+	}
+
+	for _, f := range m.Fields {
+		vv := r.Values.FilterByName(f.Name)
+
+		if f.Multi {
+			ss := make([]string, 0, len(vv))
+			for _, v := range vv {
+				ss = append(ss, v.Value)
+			}
+
+			values[f.Name] = ss
+		} else if len(vv) > 0 {
+			values[f.Name] = vv[0].Value
+		}
+	}
+
+	return expr.Scope{
+		"record": rs,
+		"module": expr.Scope{
+			"moduleID": payload.Uint64toa(m.ID),
+			"name":     m.Name,
+			"handle":   m.Handle,
+		},
+		"namespace": expr.Scope{
+			"namespaceID": payload.Uint64toa(ns.ID),
+			"name":        ns.Name,
+			"slug":        ns.Slug,
+		},
+	}
+}
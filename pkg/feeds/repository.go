@@ -0,0 +1,112 @@
+package feeds
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+		app string
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB, app string) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+		app: app,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet(r.app).With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_" + r.app + "_feed"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"token_hash",
+			"title",
+			"rel_channel",
+			"rel_namespace",
+			"rel_module",
+			"filter",
+			"sort",
+			"mapping",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(feedID uint64) (*Feed, error) {
+	return r.findOneBy(squirrel.Eq{"id": feedID})
+}
+
+func (r repository) FindByTokenHash(hash string) (*Feed, error) {
+	return r.findOneBy(squirrel.Eq{"token_hash": hash})
+}
+
+func (r repository) findOneBy(cnd squirrel.Sqlizer) (*Feed, error) {
+	var f = &Feed{}
+
+	if err := rh.FetchOne(r.db(), r.query().Where(cnd), f); err != nil {
+		return nil, err
+	} else if f.ID == 0 {
+		return nil, ErrFeedNotFound.withStack()
+	}
+
+	return f, nil
+}
+
+// FindByOwner returns feeds of the user
+func (r repository) FindByOwner(userID uint64) (set FeedSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"owned_by": userID}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(f *Feed) (*Feed, error) {
+	f.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&f.CreatedAt)
+
+	return f, errors.WithStack(r.db().Insert(r.table(), f))
+}
+
+func (r repository) Update(f *Feed) (*Feed, error) {
+	rh.SetCurrentTimeRounded(&f.UpdatedAt)
+
+	return f, errors.WithStack(r.db().Update(r.table(), f, "id"))
+}
+
+func (r repository) DeleteByID(feedID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": feedID},
+	)
+}
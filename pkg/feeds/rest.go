@@ -0,0 +1,84 @@
+package feeds
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountComposeRoutes mounts feeds of records
+func MountComposeRoutes(r chi.Router) {
+	mount(r, "compose")
+}
+
+// MountMessagingRoutes mounts feeds of channel messages
+func MountMessagingRoutes(r chi.Router) {
+	mount(r, "messaging")
+}
+
+// mount mounts feed management and feeds themselves
+//
+// Feed readers can not sign in, feeds are read with the token in the path.
+func mount(r chi.Router, app string) {
+	// ?limit=50
+	r.Get("/{token}/rss", rest.Handler("Feed.RSS", func(r *http.Request) (interface{}, error) {
+		return document(r, app, "application/rss+xml", Document.RSS)
+	}))
+
+	r.Get("/{token}/atom", rest.Handler("Feed.Atom", func(r *http.Request) (interface{}, error) {
+		return document(r, app, "application/atom+xml", Document.Atom)
+	}))
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.MiddlewareValidOnly)
+
+		r.Get("/", rest.Handler("Feed.List", func(r *http.Request) (interface{}, error) {
+			return DefaultFeeds.With(r.Context()).Find(app)
+		}))
+
+		r.Post("/", rest.Handler("Feed.Create", func(r *http.Request) (interface{}, error) {
+			f := &Feed{}
+			if err := rest.Decode(r, f); err != nil {
+				return nil, err
+			}
+
+			return DefaultFeeds.With(r.Context()).Create(app, f)
+		}))
+
+		r.Put("/{feedID}", rest.Handler("Feed.Update", func(r *http.Request) (interface{}, error) {
+			f := &Feed{}
+			if err := rest.Decode(r, f); err != nil {
+				return nil, err
+			}
+
+			f.ID = rest.ParamUint64(r, "feedID")
+			return DefaultFeeds.With(r.Context()).Update(app, f)
+		}))
+
+		r.Delete("/{feedID}", rest.Handler("Feed.Delete", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultFeeds.With(r.Context()).DeleteByID(app, rest.ParamUint64(r, "feedID"))
+		}))
+
+		// Issues a new token, the old one stops working
+		r.Post("/{feedID}/token", rest.Handler("Feed.RegenerateToken", func(r *http.Request) (interface{}, error) {
+			return DefaultFeeds.With(r.Context()).RegenerateToken(app, rest.ParamUint64(r, "feedID"))
+		}))
+	})
+}
+
+func document(r *http.Request, app, contentType string, encode func(Document, io.Writer) error) (interface{}, error) {
+	d, err := DefaultFeeds.With(r.Context()).Render(app, chi.URLParam(r, "token"), rest.QueryUint(r, "limit"))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+		_ = encode(*d, w)
+	}, nil
+}
@@ -0,0 +1,234 @@
+package feeds
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	feedsService struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	// source renders feeds of an app
+	source interface {
+		// validate checks if the current user can read what the feed follows
+		validate(ctx context.Context, f *Feed) error
+
+		// runAs returns context with identity of feed's owner
+		runAs(ctx context.Context, f *Feed) (context.Context, error)
+
+		// document renders the latest entries of the feed
+		document(ctx context.Context, f *Feed, limit uint) (*Document, error)
+	}
+
+	FeedsService interface {
+		With(ctx context.Context) FeedsService
+
+		Find(app string) (FeedSet, error)
+		Create(app string, f *Feed) (*Feed, error)
+		Update(app string, f *Feed) (*Feed, error)
+		DeleteByID(app string, feedID uint64) error
+		RegenerateToken(app string, feedID uint64) (*Feed, error)
+
+		Render(app, token string, limit uint) (*Document, error)
+	}
+)
+
+var (
+	DefaultFeeds FeedsService
+
+	// Sources of initialized apps
+	sources = map[string]source{}
+)
+
+func start(ctx context.Context, log *zap.Logger, app string, src source) error {
+	sources[app] = src
+	DefaultFeeds = (&feedsService{logger: log}).With(ctx)
+	return nil
+}
+
+func (svc feedsService) With(ctx context.Context) FeedsService {
+	return &feedsService{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc feedsService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc feedsService) repository(app string) *repository {
+	return Repository(svc.ctx, factory.Database.MustGet(app).With(svc.ctx), app)
+}
+
+// Find returns feeds of the current user
+func (svc feedsService) Find(app string) (FeedSet, error) {
+	if sources[app] == nil {
+		return nil, ErrUnknownApp.withStack()
+	}
+
+	return svc.repository(app).FindByOwner(auth.GetIdentityFromContext(svc.ctx).Identity())
+}
+
+// Create saves the feed with a new token
+func (svc feedsService) Create(app string, f *Feed) (*Feed, error) {
+	src := sources[app]
+	if src == nil {
+		return nil, ErrUnknownApp.withStack()
+	}
+
+	if err := src.validate(svc.ctx, f); err != nil {
+		return nil, err
+	}
+
+	i := auth.GetIdentityFromContext(svc.ctx)
+	f.OwnedBy = i.Identity()
+	f.Token = generateToken()
+	f.TokenHash = hash(f.Token)
+
+	f, err := svc.repository(app).Create(f)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.String("app", app), zap.Uint64("feedID", f.ID)).Info("feed created")
+	return f, nil
+}
+
+// Update changes what the feed follows; token is kept
+func (svc feedsService) Update(app string, upd *Feed) (*Feed, error) {
+	f, err := svc.findOwned(app, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Title = upd.Title
+	f.ChannelID = upd.ChannelID
+	f.NamespaceID = upd.NamespaceID
+	f.ModuleID = upd.ModuleID
+	f.Filter = upd.Filter
+	f.Sort = upd.Sort
+	f.Mapping = upd.Mapping
+
+	if err = sources[app].validate(svc.ctx, f); err != nil {
+		return nil, err
+	}
+
+	return svc.repository(app).Update(f)
+}
+
+func (svc feedsService) DeleteByID(app string, feedID uint64) error {
+	f, err := svc.findOwned(app, feedID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.repository(app).DeleteByID(f.ID); err != nil {
+		return err
+	}
+
+	svc.log(zap.String("app", app), zap.Uint64("feedID", f.ID)).Info("feed deleted")
+	return nil
+}
+
+// RegenerateToken issues a new token; readers with the old one lose access
+func (svc feedsService) RegenerateToken(app string, feedID uint64) (*Feed, error) {
+	f, err := svc.findOwned(app, feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Token = generateToken()
+	f.TokenHash = hash(f.Token)
+
+	if f, err = svc.repository(app).Update(f); err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.String("app", app), zap.Uint64("feedID", f.ID)).Info("feed token regenerated")
+	return f, nil
+}
+
+// Render returns the latest entries of the feed with the token
+//
+// Entries are read with permissions of feed's owner.
+func (svc feedsService) Render(app, token string, limit uint) (*Document, error) {
+	src := sources[app]
+	if src == nil {
+		return nil, ErrUnknownApp.withStack()
+	}
+
+	f, err := svc.repository(app).FindByTokenHash(hash(token))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := src.runAs(svc.ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = defaultLimit
+	} else if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	return src.document(ctx, f, limit)
+}
+
+func (svc feedsService) findOwned(app string, feedID uint64) (*Feed, error) {
+	if sources[app] == nil {
+		return nil, ErrUnknownApp.withStack()
+	}
+
+	f, err := svc.repository(app).FindByID(feedID)
+	if err != nil {
+		return nil, err
+	} else if f.OwnedBy != auth.GetIdentityFromContext(svc.ctx).Identity() {
+		return nil, ErrFeedNotFound.withStack()
+	}
+
+	return f, nil
+}
+
+// title returns the first line of the text, shortened
+func title(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[:i]
+	}
+
+	if rr := []rune(strings.TrimSpace(text)); len(rr) > titleLength {
+		return string(rr[:titleLength-1]) + "…"
+	}
+
+	return strings.TrimSpace(text)
+}
+
+func generateToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
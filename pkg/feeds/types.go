@@ -0,0 +1,110 @@
+package feeds
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Feed lets feed readers follow channel messages (messaging) or
+	// records of a module (compose)
+	//
+	// Feed is read with its token, without signing in, and with
+	// permissions of its owner; token is sent back only when feed is
+	// created or its token is regenerated, only its hash is stored.
+	Feed struct {
+		ID    uint64 `json:"feedID,string" db:"id"`
+		Title string `json:"title" db:"title"`
+
+		// Channel feeds
+		ChannelID uint64 `json:"channelID,string,omitempty" db:"rel_channel"`
+
+		// Record feeds; filter and sort are the same as when records are listed
+		NamespaceID uint64  `json:"namespaceID,string,omitempty" db:"rel_namespace"`
+		ModuleID    uint64  `json:"moduleID,string,omitempty" db:"rel_module"`
+		Filter      string  `json:"filter,omitempty" db:"filter"`
+		Sort        string  `json:"sort,omitempty" db:"sort"`
+		Mapping     Mapping `json:"mapping" db:"mapping"`
+
+		Token     string `json:"token,omitempty" db:"-"`
+		TokenHash string `json:"-" db:"token_hash"`
+
+		OwnedBy uint64 `json:"ownedBy,string" db:"owned_by"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	FeedSet []*Feed
+
+	// Mapping holds templates that records are rendered into entries with
+	//
+	// Templates are texts with {{ expression }} placeholders that can access:
+	//   - record.values.<field>, record.recordID, record.ownedBy, ...
+	//   - module.name, module.handle, namespace.name, namespace.slug
+	//   - link (URL of the record page)
+	Mapping struct {
+		// Required
+		Title string `json:"title,omitempty"`
+
+		Content string `json:"content,omitempty"`
+		Author  string `json:"author,omitempty"`
+
+		// URL of the record page by default
+		Link string `json:"link,omitempty"`
+
+		// Name of the date or datetime field with the entry's date;
+		// record's update (or creation) time by default
+		Date string `json:"date,omitempty"`
+	}
+
+	// Document is the rendered feed
+	Document struct {
+		ID      string
+		Title   string
+		Link    string
+		Updated time.Time
+		Entries []*Entry
+	}
+
+	Entry struct {
+		ID        string
+		Title     string
+		Content   string
+		Link      string
+		Author    string
+		Published time.Time
+		Updated   time.Time
+	}
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+
+	titleLength = 80
+
+	// Base URL of the web application, prepended to links
+	settingFrontendURL = "crust.frontend-url"
+)
+
+func (m Mapping) Value() (driver.Value, error) {
+	return json.Marshal(m)
+}
+
+func (m *Mapping) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*m = Mapping{}
+	case []byte:
+		if err := json.Unmarshal(b, m); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Mapping", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package flood
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	floodError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrMessageLimitReached  = floodError{"MessageLimitReached", fault.TooMany}
+	ErrRepeatLimitReached   = floodError{"RepeatLimitReached", fault.TooMany}
+	ErrThrottleLimitReached = floodError{"ThrottleLimitReached", fault.TooMany}
+	ErrPostingRestricted    = floodError{"PostingRestricted", fault.Forbidden}
+	ErrNoPermissions        = floodError{"NoPermissions", fault.Forbidden}
+	ErrRestrictionNotFound  = floodError{"RestrictionNotFound", fault.NotFound}
+)
+
+func (e floodError) Error() string {
+	return e.String()
+}
+
+func (e floodError) String() string {
+	return "crust.flood." + e.name
+}
+
+func (e floodError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e floodError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,28 @@
+package flood
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts endpoints for moderators
+//
+// Restrictions are kept by each instance; endpoints see those of the
+// instance that handles the request.
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Users that are throttled or muted
+	r.Get("/", rest.Handler("Flood.Restrictions", func(r *http.Request) (interface{}, error) {
+		return DefaultFlood.With(r.Context()).Restrictions()
+	}))
+
+	r.Delete("/{userID}", rest.Handler("Flood.Lift", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultFlood.With(r.Context()).Lift(rest.ParamUint64(r, "userID"))
+	}))
+}
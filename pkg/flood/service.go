@@ -0,0 +1,246 @@
+package flood
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	"github.com/crusttech/crust-server/pkg/live"
+	"github.com/crusttech/crust-server/pkg/seclog"
+)
+
+type (
+	accessController interface {
+		CanManageSettings(context.Context) bool
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		settings settingsGetter
+		channels messagingService.ChannelService
+	}
+
+	FloodService interface {
+		With(ctx context.Context) FloodService
+
+		Restrictions() (RestrictionSet, error)
+		Lift(userID uint64) error
+	}
+)
+
+var (
+	DefaultFlood FloodService
+
+	// used by service decorators
+	defaultFlood *service
+
+	// now is used for counting posts and can be overridden
+	now = time.Now
+
+	// Configuration, reloaded from settings periodically
+	current    = defaultConfig
+	currentMux sync.RWMutex
+)
+
+// Init initializes flood control and decorates message service with
+// limits of posting
+//
+// Limits are configured in messaging settings (see Config); posts are
+// not limited until they are. Must be called after messaging services
+// and live are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		settings: messagingService.DefaultSettings,
+		channels: messagingService.DefaultChannel,
+	}
+
+	DefaultFlood = svc.With(ctx)
+	defaultFlood = svc.with(ctx)
+
+	defaultFlood.load()
+	go defaultFlood.watch(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) FloodService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		settings: svc.settings,
+		channels: svc.channels.With(ctx),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Restrictions returns users that are throttled or muted, on this instance
+func (svc service) Restrictions() (RestrictionSet, error) {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return posts.restrictions(now()), nil
+}
+
+// Lift allows the user to post again and forgets the user's violations
+func (svc service) Lift(userID uint64) error {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return ErrNoPermissions.withStack()
+	}
+
+	if !posts.lift(userID, now()) {
+		return ErrRestrictionNotFound.withStack().WithID("userID", userID)
+	}
+
+	svc.log(zap.Uint64("userID", userID)).Info("posting restrictions lifted")
+	return nil
+}
+
+// check counts the message against limits of current user
+//
+// Those that can manage messaging settings are not limited.
+func (svc service) check(m *messagingTypes.Message) error {
+	i := auth.GetIdentityFromContext(svc.ctx)
+	if auth.IsSuperUser(i) || svc.ac.CanManageSettings(svc.ctx) {
+		return nil
+	}
+
+	var (
+		cfg         = config()
+		channelType string
+	)
+
+	if len(cfg.Limits) > 0 && m.ChannelID > 0 {
+		// Missing channel is reported by the message service
+		if ch, err := svc.channels.FindByID(m.ChannelID); err == nil {
+			channelType = string(ch.Type)
+		}
+	}
+
+	r, err := posts.check(cfg, i.Identity(), m.ChannelID, channelType, m.Message, now())
+	if r != nil {
+		go svc.restricted(cfg, r)
+	}
+
+	return err
+}
+
+// restricted records the restriction and notifies moderators when the user is muted
+func (svc service) restricted(cfg Config, r *Restriction) {
+	defer sentry.Recover()
+
+	var (
+		log     = svc.log(zap.Uint64("userID", r.UserID), zap.Uint64("channelID", r.ChannelID), zap.Int("violations", r.Violations))
+		outcome = "throttled"
+	)
+
+	if r.MutedUntil != nil {
+		outcome = "muted"
+	}
+
+	log.Info("posting restricted", zap.String("outcome", outcome))
+
+	if seclog.DefaultSecLog != nil {
+		err := seclog.DefaultSecLog.With(svc.ctx).Record(&seclog.Event{
+			Kind:    eventKind,
+			Action:  "post",
+			UserID:  r.UserID,
+			Outcome: outcome,
+			Meta: seclog.Meta{
+				"channelID":  strconv.FormatUint(r.ChannelID, 10),
+				"violations": r.Violations,
+			},
+		})
+
+		if err != nil {
+			log.Error("could not record posting restriction", zap.Error(err))
+		}
+	}
+
+	if r.MutedUntil == nil {
+		return
+	}
+
+	for _, m := range cfg.Moderators {
+		ID, _ := strconv.ParseUint(m, 10, 64)
+		if s := live.UserScope(ID); ID > 0 && live.Subscribed(s) {
+			live.Publish(&live.Event{Scope: s, Type: live.EventFloodMuted, Payload: r})
+		}
+	}
+}
+
+// load reads configuration from settings, defaults are used for what is not set
+func (svc service) load() {
+	cfg := defaultConfig
+
+	v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingConfig, 0)
+	if err != nil {
+		svc.logger.Error("could not load flood control settings", zap.Error(err))
+		return
+	} else if v != nil {
+		if err = v.Value.Unmarshal(&cfg); err != nil {
+			svc.logger.Error("could not decode flood control settings", zap.Error(err))
+			return
+		}
+	}
+
+	currentMux.Lock()
+	defer currentMux.Unlock()
+
+	current = cfg
+}
+
+// watch reloads configuration and forgets old posts
+func (svc service) watch(ctx context.Context) {
+	defer sentry.Recover()
+
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.load()
+			posts.prune(now())
+		}
+	}
+}
+
+func config() Config {
+	currentMux.RLock()
+	defer currentMux.RUnlock()
+
+	return current
+}
@@ -0,0 +1,225 @@
+package flood
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// post is a message the user posted recently
+	post struct {
+		at          time.Time
+		channelType string
+		text        uint64
+	}
+
+	user struct {
+		posts      []post
+		violations []time.Time
+		lastPost   time.Time
+
+		channelID      uint64
+		throttledUntil time.Time
+		mutedUntil     time.Time
+	}
+
+	// tracker counts recent posts of users and keeps their restrictions
+	//
+	// Posts are counted on the instance they were made on.
+	tracker struct {
+		sync.Mutex
+		users map[uint64]*user
+	}
+)
+
+var (
+	posts = &tracker{users: map[uint64]*user{}}
+)
+
+// check records the post or returns why the user can not post it
+//
+// Restriction is returned when the user was throttled or muted by this post.
+func (t *tracker) check(cfg Config, userID, channelID uint64, channelType, text string, now time.Time) (*Restriction, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	u := t.users[userID]
+	if u == nil {
+		u = &user{}
+		t.users[userID] = u
+	}
+
+	if now.Before(u.mutedUntil) {
+		return nil, ErrPostingRestricted.withStack().
+			WithMessage("you can not post messages until " + u.mutedUntil.UTC().Format(time.RFC3339)).
+			WithRetryAfter(u.mutedUntil.Sub(now))
+	}
+
+	if now.Before(u.throttledUntil) && now.Sub(u.lastPost) < time.Duration(cfg.ThrottleInterval) {
+		err := ErrThrottleLimitReached.withStack().
+			WithMessage("you are posting too fast, wait " + time.Duration(cfg.ThrottleInterval).String() + " between messages").
+			WithRetryAfter(time.Duration(cfg.ThrottleInterval) - now.Sub(u.lastPost))
+
+		// Posting while throttled is a violation too; restriction is
+		// returned only when it makes the user muted
+		if r := u.violate(cfg, userID, channelID, now); r.MutedUntil != nil {
+			return r, err
+		}
+
+		return nil, err
+	}
+
+	p := post{at: now, channelType: channelType, text: hash(text)}
+
+	if l := cfg.Limits[channelType]; l != nil {
+		var (
+			since   = now.Add(-time.Duration(l.Window))
+			count   uint
+			repeats uint
+		)
+
+		for _, r := range u.posts {
+			if r.channelType != channelType || r.at.Before(since) {
+				continue
+			}
+
+			count++
+			if r.text == p.text {
+				repeats++
+			}
+		}
+
+		var err error
+		switch {
+		case l.Messages > 0 && count >= l.Messages:
+			err = ErrMessageLimitReached.withStack()
+		case l.Repeats > 0 && repeats >= l.Repeats:
+			err = ErrRepeatLimitReached.withStack()
+		}
+
+		if err != nil {
+			return u.violate(cfg, userID, channelID, now), err
+		}
+	}
+
+	u.posts = append(u.posts, p)
+	u.lastPost = now
+
+	return nil, nil
+}
+
+// violate throttles the user, or mutes when limits were exceeded too many times
+func (u *user) violate(cfg Config, userID, channelID uint64, now time.Time) *Restriction {
+	u.violations = append(u.violations, now)
+	u.forget(now)
+
+	u.channelID = channelID
+	u.throttledUntil = now.Add(time.Duration(cfg.ThrottleFor))
+
+	if cfg.MuteAfter > 0 && uint(len(u.violations)) >= cfg.MuteAfter {
+		u.mutedUntil = now.Add(time.Duration(cfg.MuteFor))
+	}
+
+	return u.restriction(userID, now)
+}
+
+// forget removes posts and violations that are too old to matter
+func (u *user) forget(now time.Time) {
+	var (
+		posts      = u.posts[:0]
+		violations = u.violations[:0]
+	)
+
+	// Windows of limits are not known here; posts are kept as long as
+	// violations, windows are expected to be shorter
+	for _, p := range u.posts {
+		if now.Sub(p.at) < escalationWindow {
+			posts = append(posts, p)
+		}
+	}
+
+	for _, v := range u.violations {
+		if now.Sub(v) < escalationWindow {
+			violations = append(violations, v)
+		}
+	}
+
+	u.posts, u.violations = posts, violations
+}
+
+func (u *user) restriction(userID uint64, now time.Time) *Restriction {
+	if !now.Before(u.throttledUntil) && !now.Before(u.mutedUntil) {
+		return nil
+	}
+
+	r := &Restriction{
+		UserID:     userID,
+		ChannelID:  u.channelID,
+		Violations: len(u.violations),
+	}
+
+	if now.Before(u.throttledUntil) {
+		t := u.throttledUntil
+		r.ThrottledUntil = &t
+	}
+
+	if now.Before(u.mutedUntil) {
+		t := u.mutedUntil
+		r.MutedUntil = &t
+	}
+
+	return r
+}
+
+// restrictions returns users that are throttled or muted now
+func (t *tracker) restrictions(now time.Time) RestrictionSet {
+	t.Lock()
+	defer t.Unlock()
+
+	out := RestrictionSet{}
+	for userID, u := range t.users {
+		if r := u.restriction(userID, now); r != nil {
+			out = append(out, r)
+		}
+	}
+
+	return out
+}
+
+// lift removes restrictions and violations of the user
+func (t *tracker) lift(userID uint64, now time.Time) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	u := t.users[userID]
+	if u == nil || u.restriction(userID, now) == nil {
+		return false
+	}
+
+	u.violations = nil
+	u.throttledUntil, u.mutedUntil = time.Time{}, time.Time{}
+	return true
+}
+
+// prune forgets old posts and users without recent activity
+func (t *tracker) prune(now time.Time) {
+	t.Lock()
+	defer t.Unlock()
+
+	for userID, u := range t.users {
+		u.forget(now)
+
+		if len(u.posts) == 0 && len(u.violations) == 0 && u.restriction(userID, now) == nil {
+			delete(t.users, userID)
+		}
+	}
+}
+
+// hash of the text, ignoring case and whitespace
+func hash(text string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToLower(strings.Join(strings.Fields(text), " "))))
+	return h.Sum64()
+}
@@ -0,0 +1,109 @@
+package flood
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	// Config is stored in messaging settings, under settingConfig
+	//
+	// Example:
+	//   {
+	//     "limits": {
+	//       "public": { "messages": 10, "window": "10s", "repeats": 3 },
+	//       "group": { "messages": 30, "window": "10s" }
+	//     },
+	//     "throttleInterval": "10s",
+	//     "throttleFor": "5m",
+	//     "muteAfter": 3,
+	//     "muteFor": "15m",
+	//     "moderators": ["<userID>"]
+	//   }
+	Config struct {
+		// Limits by channel type (public, private, group);
+		// posting to channels of other types is not limited
+		Limits map[string]*Limit `json:"limits"`
+
+		// User that exceeds a limit can post one message per interval, for a while
+		ThrottleInterval Duration `json:"throttleInterval"`
+		ThrottleFor      Duration `json:"throttleFor"`
+
+		// User that exceeds limits this many times within escalationWindow
+		// can not post at all, for a while
+		MuteAfter uint     `json:"muteAfter"`
+		MuteFor   Duration `json:"muteFor"`
+
+		// Users that are notified when someone is muted
+		Moderators []string `json:"moderators"`
+	}
+
+	// Limit of messages posted to channels of the type, within the window
+	Limit struct {
+		Messages uint     `json:"messages"`
+		Window   Duration `json:"window"`
+
+		// How many times the same text can be posted within the window, 0 is unlimited
+		Repeats uint `json:"repeats"`
+	}
+
+	// Restriction of the user who exceeded limits
+	Restriction struct {
+		UserID uint64 `json:"userID,string"`
+
+		// Channel the user was posting to when restricted
+		ChannelID uint64 `json:"channelID,string,omitempty"`
+
+		// Times limits were exceeded within escalationWindow
+		Violations int `json:"violations"`
+
+		ThrottledUntil *time.Time `json:"throttledUntil,omitempty"`
+		MutedUntil     *time.Time `json:"mutedUntil,omitempty"`
+	}
+
+	RestrictionSet []*Restriction
+
+	// Duration in JSON as a string, e.g. "10s"
+	Duration time.Duration
+)
+
+const (
+	settingConfig = "crust.flood"
+
+	// Violations older than this do not count towards a mute
+	escalationWindow = time.Hour
+
+	// How often configuration is reloaded and old posts are forgotten
+	watchInterval = time.Minute
+
+	// Kind of security log events
+	eventKind = "flood"
+)
+
+var (
+	defaultConfig = Config{
+		ThrottleInterval: Duration(10 * time.Second),
+		ThrottleFor:      Duration(5 * time.Minute),
+		MuteAfter:        3,
+		MuteFor:          Duration(15 * time.Minute),
+	}
+)
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+	return nil
+}
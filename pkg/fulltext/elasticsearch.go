@@ -0,0 +1,495 @@
+package fulltext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	elasticsearch struct {
+		url    string
+		index  string
+		client *http.Client
+	}
+
+	// esBuild fills a new index that replaces the aliased one
+	esBuild struct {
+		elasticsearch
+		alias string
+	}
+
+	esHit struct {
+		Score     float64  `json:"_score"`
+		Source    Document `json:"_source"`
+		Highlight struct {
+			Body []string `json:"body"`
+		} `json:"highlight"`
+	}
+)
+
+const (
+	esDefaultIndex = "crust-messages"
+
+	// Mapping of the index; ID fields are keywords, only filtered by.
+	// Changes need a new schemaVersion
+	esMapping = `{
+  "_meta": {"version": %d},
+  "properties": {
+    "messageID": {"type": "keyword"},
+    "channelID": {"type": "keyword"},
+    "threadID":  {"type": "keyword"},
+    "userID":    {"type": "keyword"},
+    "body":      {"type": "text"},
+    "createdAt": {"type": "date"}
+  }
+}`
+)
+
+// Elasticsearch indexes messages into an Elasticsearch (7.x) index
+//
+// Index name is an alias of the index that is searched; indexes are
+// rebuilt next to it and the alias is moved when they are filled.
+func Elasticsearch(url, index string) Index {
+	if index == "" {
+		index = esDefaultIndex
+	}
+
+	return &elasticsearch{
+		url:    strings.TrimSuffix(url, "/"),
+		index:  index,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ensure creates an empty index with the alias when neither exists
+//
+// Version of the created index is unknown, it is rebuilt with messages on start.
+func (i elasticsearch) ensure(ctx context.Context) error {
+	rsp, err := i.do(ctx, http.MethodHead, "/"+i.index, "", nil)
+	if err != nil {
+		return err
+	}
+
+	rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	return i.create(ctx, i.newName(), 0, i.index)
+}
+
+// create creates the index with the mapping of the version, and alias when given
+func (i elasticsearch) create(ctx context.Context, name string, version int, alias string) error {
+	body := map[string]interface{}{
+		"mappings": json.RawMessage(fmt.Sprintf(esMapping, version)),
+	}
+
+	if alias != "" {
+		body["aliases"] = map[string]interface{}{alias: map[string]interface{}{}}
+	}
+
+	rsp, err := i.json(ctx, http.MethodPut, "/"+name, body)
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+	return i.check(rsp, "could not create index")
+}
+
+// aliases returns indexes that match the name, with their aliases
+//
+// Index that is not an alias (created before indexes were rebuilt)
+// is returned under its own name.
+func (i elasticsearch) aliases(ctx context.Context, name string) (map[string][]string, error) {
+	rsp, err := i.do(ctx, http.MethodGet, "/"+name+"/_alias", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	if err = i.check(rsp, "could not read aliases"); err != nil {
+		return nil, err
+	}
+
+	var res map[string]struct {
+		Aliases map[string]interface{} `json:"aliases"`
+	}
+
+	if err = json.NewDecoder(rsp.Body).Decode(&res); err != nil {
+		return nil, errors.Wrap(err, "could not decode aliases")
+	}
+
+	out := make(map[string][]string, len(res))
+	for index, r := range res {
+		out[index] = []string{}
+		for alias := range r.Aliases {
+			out[index] = append(out[index], alias)
+		}
+	}
+
+	return out, nil
+}
+
+func (i elasticsearch) Put(ctx context.Context, dd ...*Document) error {
+	if len(dd) == 0 {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for _, d := range dd {
+		enc.Encode(map[string]interface{}{"index": map[string]string{"_id": fmt.Sprint(d.MessageID)}})
+		if err := enc.Encode(d); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return i.bulk(ctx, buf, false)
+}
+
+func (i elasticsearch) Remove(ctx context.Context, messageIDs ...uint64) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for _, ID := range messageIDs {
+		enc.Encode(map[string]interface{}{"delete": map[string]string{"_id": fmt.Sprint(ID)}})
+	}
+
+	return i.bulk(ctx, buf, false)
+}
+
+// Search matches all terms, the last one as a prefix
+func (i elasticsearch) Search(ctx context.Context, q Query) (HitSet, error) {
+	if len(q.ChannelID) == 0 {
+		return HitSet{}, nil
+	}
+
+	filter := []interface{}{
+		map[string]interface{}{"terms": map[string]interface{}{"channelID": ids(q.ChannelID...)}},
+	}
+
+	if q.ThreadID > 0 {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"threadID": fmt.Sprint(q.ThreadID)}})
+	}
+
+	if q.UserID > 0 {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{"userID": fmt.Sprint(q.UserID)}})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"size": q.Limit,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"match_bool_prefix": map[string]interface{}{
+						"body": map[string]interface{}{"query": q.Terms, "operator": "and"},
+					},
+				},
+				"filter": filter,
+			},
+		},
+		"sort": []interface{}{"_score", map[string]string{"createdAt": "desc"}},
+		"highlight": map[string]interface{}{
+			"fields": map[string]interface{}{
+				"body": map[string]interface{}{"fragment_size": snippetLength, "number_of_fragments": 1},
+			},
+			"pre_tags":  []string{""},
+			"post_tags": []string{""},
+		},
+	})
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rsp, err := i.do(ctx, http.MethodPost, "/"+i.index+"/_search", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rsp.Body.Close()
+
+	if err = i.check(rsp, "could not search messages"); err != nil {
+		return nil, err
+	}
+
+	var res struct {
+		Hits struct {
+			MaxScore float64  `json:"max_score"`
+			Hits     []*esHit `json:"hits"`
+		} `json:"hits"`
+	}
+
+	if err = json.NewDecoder(rsp.Body).Decode(&res); err != nil {
+		return nil, errors.Wrap(err, "could not decode search results")
+	}
+
+	out := make(HitSet, len(res.Hits.Hits))
+	for n, h := range res.Hits.Hits {
+		out[n] = &Hit{
+			MessageID: h.Source.MessageID,
+			ChannelID: h.Source.ChannelID,
+			ThreadID:  h.Source.ThreadID,
+			UserID:    h.Source.UserID,
+			Snippet:   snippet(h.Source.Body, q.Terms),
+			CreatedAt: h.Source.CreatedAt,
+		}
+
+		if len(h.Highlight.Body) > 0 {
+			out[n].Snippet = h.Highlight.Body[0]
+		}
+
+		if res.Hits.MaxScore > 0 {
+			out[n].Score = h.Score / res.Hits.MaxScore
+		}
+	}
+
+	return out, nil
+}
+
+// bulk runs the actions; documents that already exist are not errors when conflicts are ignored
+func (i elasticsearch) bulk(ctx context.Context, body io.Reader, ignoreConflicts bool) error {
+	rsp, err := i.do(ctx, http.MethodPost, "/"+i.index+"/_bulk", "application/x-ndjson", body)
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+
+	if err = i.check(rsp, "could not index messages"); err != nil {
+		return err
+	}
+
+	var res struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+		} `json:"items"`
+	}
+
+	if err = json.NewDecoder(rsp.Body).Decode(&res); err != nil {
+		return errors.Wrap(err, "could not decode bulk response")
+	}
+
+	if !res.Errors {
+		return nil
+	}
+
+	for _, item := range res.Items {
+		for _, r := range item {
+			if r.Status >= 300 && !(ignoreConflicts && r.Status == http.StatusConflict) {
+				return errors.New("could not index some of the messages")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (i elasticsearch) json(ctx context.Context, method, path string, in interface{}) (*http.Response, error) {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return i.do(ctx, method, path, "application/json", bytes.NewReader(body))
+}
+
+func (i elasticsearch) do(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, i.url+path, body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	rsp, err := i.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, ErrIndexUnavailable.withStack().WithMessage(err.Error())
+	}
+
+	return rsp, nil
+}
+
+func (elasticsearch) check(rsp *http.Response, msg string) error {
+	if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+		return nil
+	}
+
+	return errors.Errorf("%s, unexpected status %d", msg, rsp.StatusCode)
+}
+
+// Version is read from metadata of the mapping
+func (i elasticsearch) Version(ctx context.Context) (int, error) {
+	rsp, err := i.do(ctx, http.MethodGet, "/"+i.index+"/_mapping", "", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+
+	if err = i.check(rsp, "could not read mapping"); err != nil {
+		return 0, err
+	}
+
+	var res map[string]struct {
+		Mappings struct {
+			Meta struct {
+				Version int `json:"version"`
+			} `json:"_meta"`
+		} `json:"mappings"`
+	}
+
+	if err = json.NewDecoder(rsp.Body).Decode(&res); err != nil {
+		return 0, errors.Wrap(err, "could not decode mapping")
+	}
+
+	for _, r := range res {
+		return r.Mappings.Meta.Version, nil
+	}
+
+	return 0, nil
+}
+
+// Rebuild creates a new index, unfinished builds (indexes without alias) are removed
+func (i elasticsearch) Rebuild(ctx context.Context) (Build, error) {
+	aa, err := i.aliases(ctx, i.index+"-*")
+	if err != nil {
+		return nil, err
+	}
+
+	for index, aliases := range aa {
+		if len(aliases) == 0 {
+			if err = (esBuild{elasticsearch: i.with(index)}).Discard(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	b := &esBuild{
+		elasticsearch: i.with(i.newName()),
+		alias:         i.index,
+	}
+
+	return b, i.create(ctx, b.index, schemaVersion, "")
+}
+
+// newName returns name for a new index behind the alias
+func (i elasticsearch) newName() string {
+	return fmt.Sprintf("%s-%d", i.index, time.Now().UnixNano())
+}
+
+func (i elasticsearch) with(index string) elasticsearch {
+	return elasticsearch{url: i.url, index: index, client: i.client}
+}
+
+func (b esBuild) Fill(ctx context.Context, dd ...*Document) error {
+	if len(dd) == 0 {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	for _, d := range dd {
+		enc.Encode(map[string]interface{}{"create": map[string]string{"_id": fmt.Sprint(d.MessageID)}})
+		if err := enc.Encode(d); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return b.bulk(ctx, buf, true)
+}
+
+// Swap moves the alias to the new index in one request and removes the old indexes
+func (b esBuild) Swap(ctx context.Context) error {
+	aa, err := b.aliases(ctx, b.alias)
+	if err != nil {
+		return err
+	}
+
+	var (
+		actions = []interface{}{
+			map[string]interface{}{"add": map[string]string{"index": b.index, "alias": b.alias}},
+		}
+
+		old []string
+	)
+
+	for index := range aa {
+		if index == b.alias {
+			// Index that was not aliased is removed with the same request
+			actions = append(actions, map[string]interface{}{"remove_index": map[string]string{"index": index}})
+			continue
+		}
+
+		actions = append(actions, map[string]interface{}{"remove": map[string]string{"index": index, "alias": b.alias}})
+		old = append(old, index)
+	}
+
+	rsp, err := b.json(ctx, http.MethodPost, "/_aliases", map[string]interface{}{"actions": actions})
+	if err != nil {
+		return err
+	}
+
+	rsp.Body.Close()
+
+	if err = b.check(rsp, "could not swap indexes"); err != nil {
+		return err
+	}
+
+	for _, index := range old {
+		if err = (esBuild{elasticsearch: b.with(index)}).Discard(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b esBuild) Discard(ctx context.Context) error {
+	rsp, err := b.do(ctx, http.MethodDelete, "/"+b.index, "", nil)
+	if err != nil {
+		return err
+	}
+
+	rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+
+	return b.check(rsp, "could not remove index")
+}
+
+func ids(IDs ...uint64) []string {
+	out := make([]string, len(IDs))
+	for i, ID := range IDs {
+		out[i] = fmt.Sprint(ID)
+	}
+
+	return out
+}
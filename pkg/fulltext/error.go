@@ -0,0 +1,40 @@
+package fulltext
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	fulltextError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrQueryTooShort         = fulltextError{"QueryTooShort", fault.Invalid}
+	ErrIndexDisabled         = fulltextError{"IndexDisabled", fault.Unavailable}
+	ErrUnknownIndex          = fulltextError{"UnknownIndex", fault.Invalid}
+	ErrIndexUnavailable      = fulltextError{"IndexUnavailable", fault.Unavailable}
+	ErrReindexUnavailable    = fulltextError{"ReindexUnavailable", fault.Unavailable}
+	ErrReindexNotFound       = fulltextError{"ReindexNotFound", fault.NotFound}
+	ErrReindexAlreadyRunning = fulltextError{"ReindexAlreadyRunning", fault.Conflict}
+	ErrReindexNotRunning     = fulltextError{"ReindexNotRunning", fault.Conflict}
+	ErrNoPermissions         = fulltextError{"NoPermissions", fault.Forbidden}
+)
+
+func (e fulltextError) Error() string {
+	return e.String()
+}
+
+func (e fulltextError) String() string {
+	return "crust.fulltext." + e.name
+}
+
+func (e fulltextError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e fulltextError) withStack() *fault.Error {
+	return fault.New(e)
+}
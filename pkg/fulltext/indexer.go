@@ -0,0 +1,172 @@
+package fulltext
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+)
+
+type (
+	// indexer writes changes of messages to the index in batches
+	indexer struct {
+		logger *zap.Logger
+		index  Index
+		jobs   chan job
+
+		// Index being rebuilt, gets changes too; writes are
+		// serialized with attaching and detaching of builds
+		mux   sync.Mutex
+		build Build
+	}
+
+	// job puts the document or, when there is none, removes the message
+	job struct {
+		doc       *Document
+		messageID uint64
+	}
+)
+
+const (
+	// Changes waiting to be indexed; more are dropped (and reindexed later)
+	jobsBacklog = 4096
+
+	batchSize = 100
+)
+
+var (
+	// How long a change can wait for the batch to fill up
+	batchDelay = time.Second
+)
+
+func newIndexer(log *zap.Logger, index Index) *indexer {
+	return &indexer{
+		logger: log,
+		index:  index,
+		jobs:   make(chan job, jobsBacklog),
+	}
+}
+
+// put queues the document to be indexed
+func (i *indexer) put(d *Document) {
+	i.enqueue(job{doc: d, messageID: d.MessageID})
+}
+
+// remove queues the message to be removed from the index
+func (i *indexer) remove(messageID uint64) {
+	i.enqueue(job{messageID: messageID})
+}
+
+func (i *indexer) enqueue(j job) {
+	select {
+	case i.jobs <- j:
+	default:
+		metricDropped.Inc()
+	}
+}
+
+// run indexes queued changes until the context is canceled
+func (i *indexer) run(ctx context.Context) {
+	defer sentry.Recover()
+
+	var (
+		batch = make([]job, 0, batchSize)
+		tick  = time.NewTicker(batchDelay)
+	)
+
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-i.jobs:
+			if batch = append(batch, j); len(batch) < batchSize {
+				continue
+			}
+		case <-tick.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+
+		i.flush(ctx, batch)
+		batch = batch[:0]
+	}
+}
+
+// attach starts writing changes to the build, besides the index
+func (i *indexer) attach(b Build) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	i.build = b
+}
+
+// detach stops writing changes to the build and runs fn (swap or discard
+// of the build) before any other change is written
+func (i *indexer) detach(fn func() error) error {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	i.build = nil
+	return fn()
+}
+
+// flush writes the batch to the index; only the last change of each message counts
+func (i *indexer) flush(ctx context.Context, batch []job) {
+	i.mux.Lock()
+	defer i.mux.Unlock()
+
+	var (
+		last   = make(map[uint64]*Document, len(batch))
+		order  = make([]uint64, 0, len(batch))
+		put    []*Document
+		remove []uint64
+	)
+
+	for _, j := range batch {
+		if _, ok := last[j.messageID]; !ok {
+			order = append(order, j.messageID)
+		}
+
+		last[j.messageID] = j.doc
+	}
+
+	for _, ID := range order {
+		if d := last[ID]; d != nil {
+			put = append(put, d)
+		} else {
+			remove = append(remove, ID)
+		}
+	}
+
+	if err := i.index.Put(ctx, put...); err != nil {
+		metricFailed.Add(float64(len(put)))
+		i.logger.Error("could not index messages", zap.Int("count", len(put)), zap.Error(err))
+	} else {
+		metricIndexed.Add(float64(len(put)))
+	}
+
+	if err := i.index.Remove(ctx, remove...); err != nil {
+		metricFailed.Add(float64(len(remove)))
+		i.logger.Error("could not remove messages from index", zap.Int("count", len(remove)), zap.Error(err))
+	} else {
+		metricRemoved.Add(float64(len(remove)))
+	}
+
+	if i.build == nil {
+		return
+	}
+
+	if err := i.build.Put(ctx, put...); err != nil {
+		i.logger.Error("could not index messages into rebuilt index", zap.Int("count", len(put)), zap.Error(err))
+	}
+
+	if err := i.build.Remove(ctx, remove...); err != nil {
+		i.logger.Error("could not remove messages from rebuilt index", zap.Int("count", len(remove)), zap.Error(err))
+	}
+}
@@ -0,0 +1,86 @@
+package fulltext
+
+import (
+	"context"
+	"io"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// message wraps message service and indexes messages
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with indexing of messages
+//
+// Changes are queued and written to the index in the background.
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Create(m)
+	if err != nil {
+		return nil, err
+	}
+
+	put(m)
+	return m, nil
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.CreateWithAvatar(m, avatar)
+	if err != nil {
+		return nil, err
+	}
+
+	put(m)
+	return m, nil
+}
+
+func (svc message) Update(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Update(m)
+	if err != nil {
+		return nil, err
+	}
+
+	put(m)
+	return m, nil
+}
+
+func (svc message) Delete(messageID uint64) error {
+	if err := svc.MessageService.Delete(messageID); err != nil {
+		return err
+	}
+
+	defaultIndexer.remove(messageID)
+	return nil
+}
+
+// put queues the message for indexing; messages without text (attachments) are skipped
+func put(m *messagingTypes.Message) {
+	if m == nil || m.Message == "" {
+		return
+	}
+
+	defaultIndexer.put(&Document{
+		MessageID: m.ID,
+		ChannelID: m.ChannelID,
+		ThreadID:  m.ReplyTo,
+		UserID:    m.UserID,
+		Body:      m.Message,
+		CreatedAt: m.CreatedAt,
+	})
+}
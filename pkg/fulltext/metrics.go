@@ -0,0 +1,42 @@
+package fulltext
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricIndexed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_fulltext_indexed_total",
+		Help: "Number of messages written to the search index.",
+	})
+
+	metricRemoved = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_fulltext_removed_total",
+		Help: "Number of messages removed from the search index.",
+	})
+
+	metricFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_fulltext_failed_total",
+		Help: "Number of message changes that could not be written to the search index.",
+	})
+
+	metricReindexed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_fulltext_reindexed_total",
+		Help: "Number of messages written to rebuilt search indexes.",
+	})
+
+	metricDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_fulltext_dropped_total",
+		Help: "Number of message changes not indexed because the queue was full.",
+	})
+)
+
+func registerMetrics() {
+	prometheus.MustRegister(
+		metricIndexed,
+		metricRemoved,
+		metricFailed,
+		metricReindexed,
+		metricDropped,
+	)
+}
@@ -0,0 +1,28 @@
+package fulltext
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	// Table of the MySQL index, created even when another index is used
+	Migrations = migrations.Set{
+		{
+			Name: "20200223000000.fulltext",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_search (
+  rel_message      BIGINT UNSIGNED NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  reply_to         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  body             TEXT            NOT NULL,
+  created_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_message),
+  INDEX crust_search_channel (rel_channel),
+  FULLTEXT INDEX crust_search_body (body)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+`,
+		},
+	}
+)
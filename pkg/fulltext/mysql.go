@@ -0,0 +1,226 @@
+package fulltext
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+)
+
+type (
+	mysqlIndex struct {
+		table string
+	}
+
+	// mysqlBuild fills a new table that replaces the searched one
+	mysqlBuild struct {
+		mysqlIndex
+		live string
+	}
+
+	mysqlHit struct {
+		MessageID uint64    `db:"rel_message"`
+		ChannelID uint64    `db:"rel_channel"`
+		ThreadID  uint64    `db:"reply_to"`
+		UserID    uint64    `db:"rel_user"`
+		Body      string    `db:"body"`
+		Score     float64   `db:"score"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+)
+
+const (
+	mysqlTable = "crust_messaging_search"
+
+	// Table of the index schema; changes also need a migration of the
+	// searched table and a new schemaVersion
+	mysqlSchema = `
+CREATE TABLE %s (
+  rel_message      BIGINT UNSIGNED NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  reply_to         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  body             TEXT            NOT NULL,
+  created_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_message),
+  INDEX crust_search_channel (rel_channel),
+  FULLTEXT INDEX crust_search_body (body)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='crust:v%d'
+`
+)
+
+var (
+	// Operators of boolean full-text search, removed from terms
+	booleanOperators = strings.NewReplacer(
+		"+", " ", "-", " ", "<", " ", ">", " ", "(", " ", ")", " ",
+		"~", " ", "*", " ", `"`, " ", "@", " ",
+	)
+)
+
+// MySQL indexes messages into a table with FULLTEXT index, in messaging database
+func MySQL() Index {
+	return &mysqlIndex{table: mysqlTable}
+}
+
+func (mysqlIndex) db(ctx context.Context) *factory.DB {
+	return factory.Database.MustGet("messaging").With(ctx)
+}
+
+func (i mysqlIndex) Put(ctx context.Context, dd ...*Document) error {
+	return i.insert(ctx, squirrel.
+		Insert(i.table).
+		Suffix("ON DUPLICATE KEY UPDATE rel_channel = VALUES(rel_channel), reply_to = VALUES(reply_to), body = VALUES(body)"), dd)
+}
+
+func (i mysqlIndex) insert(ctx context.Context, q squirrel.InsertBuilder, dd []*Document) error {
+	if len(dd) == 0 {
+		return nil
+	}
+
+	q = q.Columns("rel_message", "rel_channel", "reply_to", "rel_user", "body", "created_at")
+	for _, d := range dd {
+		q = q.Values(d.MessageID, d.ChannelID, d.ThreadID, d.UserID, d.Body, d.CreatedAt)
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = i.db(ctx).Exec(query, args...)
+	return errors.Wrap(err, "can not index messages")
+}
+
+func (i mysqlIndex) Remove(ctx context.Context, messageIDs ...uint64) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	query, args, err := squirrel.
+		Delete(i.table).
+		Where(squirrel.Eq{"rel_message": messageIDs}).
+		ToSql()
+
+	if err != nil {
+		return err
+	}
+
+	_, err = i.db(ctx).Exec(query, args...)
+	return errors.Wrap(err, "can not remove messages from index")
+}
+
+// Search matches all terms, the last one as a prefix, in boolean mode
+func (i mysqlIndex) Search(ctx context.Context, q Query) (HitSet, error) {
+	terms := strings.Fields(booleanOperators.Replace(q.Terms))
+	if len(terms) == 0 || len(q.ChannelID) == 0 {
+		return HitSet{}, nil
+	}
+
+	for t := range terms {
+		terms[t] = "+" + terms[t]
+	}
+
+	terms[len(terms)-1] += "*"
+	against := strings.Join(terms, " ")
+
+	sq := squirrel.
+		Select("rel_message", "rel_channel", "reply_to", "rel_user", "body", "created_at").
+		Column("MATCH (body) AGAINST (? IN BOOLEAN MODE) AS score", against).
+		From(i.table).
+		Where("MATCH (body) AGAINST (? IN BOOLEAN MODE)", against).
+		Where(squirrel.Eq{"rel_channel": q.ChannelID}).
+		OrderBy("score DESC", "rel_message DESC").
+		Limit(uint64(q.Limit))
+
+	if q.ThreadID > 0 {
+		sq = sq.Where(squirrel.Eq{"reply_to": q.ThreadID})
+	}
+
+	if q.UserID > 0 {
+		sq = sq.Where(squirrel.Eq{"rel_user": q.UserID})
+	}
+
+	query, args, err := sq.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var rr []*mysqlHit
+	if err = i.db(ctx).Select(&rr, query, args...); err != nil {
+		return nil, errors.Wrap(err, "can not search messages")
+	}
+
+	out := make(HitSet, len(rr))
+	for n, r := range rr {
+		out[n] = &Hit{
+			MessageID: r.MessageID,
+			ChannelID: r.ChannelID,
+			ThreadID:  r.ThreadID,
+			UserID:    r.UserID,
+			Snippet:   snippet(r.Body, terms[0][1:]),
+			Score:     r.Score / (1 + r.Score),
+			CreatedAt: r.CreatedAt,
+		}
+	}
+
+	return out, nil
+}
+
+// Version is read from the comment of the table
+func (i mysqlIndex) Version(ctx context.Context) (v int, err error) {
+	var comment string
+
+	err = i.db(ctx).Get(&comment,
+		"SELECT table_comment FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+		i.table,
+	)
+
+	if err != nil {
+		return 0, errors.Wrap(err, "can not read version of the index")
+	}
+
+	fmt.Sscanf(comment, "crust:v%d", &v)
+	return v, nil
+}
+
+// Rebuild creates a new table, an unfinished build is discarded
+func (i mysqlIndex) Rebuild(ctx context.Context) (Build, error) {
+	b := &mysqlBuild{mysqlIndex: mysqlIndex{table: i.table + "_build"}, live: i.table}
+
+	if err := b.Discard(ctx); err != nil {
+		return nil, err
+	}
+
+	if _, err := i.db(ctx).Exec(fmt.Sprintf(mysqlSchema, b.table, schemaVersion)); err != nil {
+		return nil, errors.Wrap(err, "can not create index table")
+	}
+
+	return b, nil
+}
+
+func (b mysqlBuild) Fill(ctx context.Context, dd ...*Document) error {
+	return b.insert(ctx, squirrel.Insert(b.table).Options("IGNORE"), dd)
+}
+
+// Swap renames both tables in one statement, searches never miss the table
+func (b mysqlBuild) Swap(ctx context.Context) error {
+	old := b.live + "_old"
+
+	_, err := b.db(ctx).Exec(fmt.Sprintf("RENAME TABLE %s TO %s, %s TO %s", b.live, old, b.table, b.live))
+	if err != nil {
+		return errors.Wrap(err, "can not swap index tables")
+	}
+
+	_, err = b.db(ctx).Exec("DROP TABLE IF EXISTS " + old)
+	return errors.Wrap(err, "can not remove old index table")
+}
+
+func (b mysqlBuild) Discard(ctx context.Context) error {
+	_, err := b.db(ctx).Exec("DROP TABLE IF EXISTS " + b.table)
+	return errors.Wrap(err, "can not remove index table")
+}
@@ -0,0 +1,199 @@
+package fulltext
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+)
+
+type (
+	// reindexer rebuilds the index in the background, one rebuild at a time
+	//
+	// Messages are copied to a new index while the old one is searched;
+	// changes of messages are written to both. Message that is removed
+	// while its copy is being written can end up in the new index.
+	reindexer struct {
+		ctx     context.Context
+		logger  *zap.Logger
+		index   Rebuilder
+		indexer *indexer
+
+		mux    sync.Mutex
+		last   *Reindex
+		cancel context.CancelFunc
+	}
+)
+
+var (
+	// Messages copied to the new index at once
+	reindexBatch uint = 500
+)
+
+// start starts a rebuild, unless one is running
+func (r *reindexer) start(reason string) (*Reindex, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.last != nil && r.last.Status == ReindexRunning {
+		return nil, ErrReindexAlreadyRunning.withStack()
+	}
+
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(r.ctx)
+
+	r.last = &Reindex{
+		Status:    ReindexRunning,
+		Reason:    reason,
+		StartedAt: time.Now(),
+	}
+
+	go r.run(ctx, r.last)
+
+	return r.snapshot(), nil
+}
+
+// stop cancels the running rebuild, the new index is discarded
+func (r *reindexer) stop() (*Reindex, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.last == nil || r.last.Status != ReindexRunning {
+		return nil, ErrReindexNotRunning.withStack()
+	}
+
+	r.cancel()
+	return r.snapshot(), nil
+}
+
+// status returns progress of the running or the last rebuild
+func (r *reindexer) status() (*Reindex, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.last == nil {
+		return nil, ErrReindexNotFound.withStack()
+	}
+
+	return r.snapshot(), nil
+}
+
+// snapshot returns copy of the last rebuild, with its progress
+func (r *reindexer) snapshot() *Reindex {
+	out := *r.last
+
+	switch {
+	case out.Status == ReindexCompleted:
+		out.Progress = 1
+	case out.Total > 0 && out.Indexed < out.Total:
+		out.Progress = float64(out.Indexed) / float64(out.Total)
+	case out.Total > 0:
+		// Messages created during the rebuild
+		out.Progress = 0.99
+	}
+
+	return &out
+}
+
+// update changes state of the rebuild
+func (r *reindexer) update(fn func()) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	fn()
+}
+
+func (r *reindexer) run(ctx context.Context, state *Reindex) {
+	defer sentry.Recover()
+
+	var (
+		log = r.logger.With(zap.String("reason", state.Reason))
+		err = r.rebuild(ctx, state)
+		now = time.Now()
+	)
+
+	r.update(func() {
+		state.FinishedAt = &now
+
+		switch {
+		case ctx.Err() != nil:
+			state.Status = ReindexCanceled
+		case err != nil:
+			state.Status = ReindexFailed
+			state.Error = err.Error()
+		default:
+			state.Status = ReindexCompleted
+		}
+	})
+
+	switch state.Status {
+	case ReindexCompleted:
+		log.Info("search index rebuilt", zap.Uint("indexed", state.Indexed), zap.Duration("took", now.Sub(state.StartedAt)))
+	case ReindexCanceled:
+		log.Info("search index rebuild canceled", zap.Uint("indexed", state.Indexed))
+	default:
+		log.Error("could not rebuild search index", zap.Error(err))
+	}
+}
+
+// rebuild copies all messages to a new index and swaps it with the searched one
+func (r *reindexer) rebuild(ctx context.Context, state *Reindex) error {
+	var (
+		repo    = Repository(ctx, nil)
+		afterID uint64
+	)
+
+	total, err := repo.Count()
+	if err != nil {
+		return err
+	}
+
+	r.update(func() { state.Total = total })
+
+	b, err := r.index.Rebuild(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Changes from now on are written to the new index too
+	r.indexer.attach(b)
+
+	abort := func(err error) error {
+		r.indexer.detach(func() error {
+			return b.Discard(context.Background())
+		})
+
+		return err
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return abort(ctx.Err())
+		}
+
+		dd, err := repo.Documents(afterID, reindexBatch)
+		if err != nil {
+			return abort(err)
+		}
+
+		if len(dd) == 0 {
+			break
+		}
+
+		if err = b.Fill(ctx, dd...); err != nil {
+			return abort(err)
+		}
+
+		metricReindexed.Add(float64(len(dd)))
+
+		afterID = dd[len(dd)-1].MessageID
+		r.update(func() { state.Indexed += uint(len(dd)) })
+	}
+
+	// New index is not discarded when swap fails, it might be searched
+	// already; unfinished builds are removed by the next rebuild
+	return r.indexer.detach(func() error { return b.Swap(ctx) })
+}
@@ -0,0 +1,66 @@
+package fulltext
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+// indexable are messages with text that are not deleted
+func (r repository) indexable() squirrel.SelectBuilder {
+	return squirrel.
+		Select().
+		From("messaging_message").
+		Where(squirrel.Eq{"deleted_at": nil}).
+		Where(squirrel.NotEq{"message": ""})
+}
+
+// Count returns number of messages to index
+func (r repository) Count() (n uint, err error) {
+	query, args, err := r.indexable().Column("COUNT(*)").ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, errors.Wrap(r.db().Get(&n, query, args...), "can not count messages")
+}
+
+// Documents returns messages to index with IDs greater than afterID, in order of IDs
+func (r repository) Documents(afterID uint64, limit uint) (dd []*Document, err error) {
+	query, args, err := r.indexable().
+		Columns("id AS rel_message", "rel_channel", "reply_to", "rel_user", "message AS body", "created_at").
+		Where(squirrel.Gt{"id": afterID}).
+		OrderBy("id").
+		Limit(uint64(limit)).
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dd, errors.Wrap(r.db().Select(&dd, query, args...), "can not load messages")
+}
@@ -0,0 +1,68 @@
+package fulltext
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts message search and reindex endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Messages that contain all terms:
+	//   ?query=<terms>&channelID=<ID>&threadID=<ID>&userID=<ID>&limit=<n>
+	r.Get("/", rest.Handler("Fulltext.Search", func(r *http.Request) (interface{}, error) {
+		svc, err := fulltextService(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return svc.Search(Query{
+			Terms:     r.URL.Query().Get("query"),
+			ChannelID: rest.QueryUint64s(r, "channelID"),
+			ThreadID:  rest.QueryUint64(r, "threadID"),
+			UserID:    rest.QueryUint64(r, "userID"),
+			Limit:     rest.QueryUint(r, "limit"),
+		})
+	}))
+
+	// Progress of the running or the last rebuild of the index
+	r.Get("/reindex", rest.Handler("Fulltext.ReindexStatus", func(r *http.Request) (interface{}, error) {
+		svc, err := fulltextService(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return svc.ReindexStatus()
+	}))
+
+	r.Post("/reindex", rest.Handler("Fulltext.Reindex", func(r *http.Request) (interface{}, error) {
+		svc, err := fulltextService(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return svc.Reindex()
+	}))
+
+	r.Delete("/reindex", rest.Handler("Fulltext.CancelReindex", func(r *http.Request) (interface{}, error) {
+		svc, err := fulltextService(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return svc.CancelReindex()
+	}))
+}
+
+func fulltextService(r *http.Request) (FulltextService, error) {
+	if !Enabled() {
+		return nil, ErrIndexDisabled.withStack()
+	}
+
+	return DefaultFulltext.With(r.Context()), nil
+}
@@ -0,0 +1,285 @@
+package fulltext
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/edits"
+)
+
+type (
+	accessController interface {
+		CanManageSettings(context.Context) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		index    Index
+		channels messagingService.ChannelService
+	}
+
+	FulltextService interface {
+		With(ctx context.Context) FulltextService
+
+		Search(Query) (HitSet, error)
+
+		Reindex() (*Reindex, error)
+		ReindexStatus() (*Reindex, error)
+		CancelReindex() (*Reindex, error)
+	}
+)
+
+var (
+	// DefaultFulltext is nil when messages are not indexed
+	DefaultFulltext FulltextService
+
+	// used by service decorators
+	defaultIndexer *indexer
+
+	// nil when the index can not be rebuilt
+	defaultReindexer *reindexer
+)
+
+// Init initializes full-text index of messages and decorates message
+// service with indexing of created, edited and removed messages
+//
+// Index is selected with SEARCH_INDEX: "mysql" (table with FULLTEXT index
+// in the messaging database) or "elasticsearch" (SEARCH_ELASTICSEARCH_URL,
+// SEARCH_ELASTICSEARCH_INDEX). Messages are not indexed when it is empty
+// and search falls back to corteza's LIKE matching.
+//
+// Index that was built with an older schema (or not at all, e.g. table
+// created by migrations) is rebuilt in the background, unless
+// SEARCH_REINDEX_ON_CHANGE is false.
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	var (
+		index Index
+		kind  = options.EnvString("", "SEARCH_INDEX", "")
+	)
+
+	switch kind {
+	case "":
+		log.Debug("messages are not indexed, search matches with LIKE")
+		return nil
+	case IndexMySQL:
+		index = MySQL()
+	case IndexElasticsearch:
+		es := Elasticsearch(
+			options.EnvString("", "SEARCH_ELASTICSEARCH_URL", "http://localhost:9200"),
+			options.EnvString("", "SEARCH_ELASTICSEARCH_INDEX", esDefaultIndex),
+		).(*elasticsearch)
+
+		if err := es.ensure(ctx); err != nil {
+			return err
+		}
+
+		index = es
+	default:
+		return ErrUnknownIndex.withStack().WithMessage("unknown search index " + kind)
+	}
+
+	registerMetrics()
+
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		index:    index,
+		channels: messagingService.DefaultChannel,
+	}
+
+	DefaultFulltext = svc.With(ctx)
+
+	defaultIndexer = newIndexer(log, index)
+	go defaultIndexer.run(ctx)
+
+	if rb, ok := index.(Rebuilder); ok {
+		defaultReindexer = &reindexer{ctx: ctx, logger: log, index: rb, indexer: defaultIndexer}
+
+		if options.EnvBool("", "SEARCH_REINDEX_ON_CHANGE", true) {
+			reindexOutdated(ctx, log, rb)
+		}
+	}
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+
+	log.Info("messages are indexed for search", zap.String("index", kind))
+	return nil
+}
+
+// reindexOutdated starts rebuild of the index that was built with an older schema
+func reindexOutdated(ctx context.Context, log *zap.Logger, rb Rebuilder) {
+	v, err := rb.Version(ctx)
+	if err != nil {
+		log.Warn("could not check version of search index", zap.Error(err))
+		return
+	}
+
+	if v >= schemaVersion {
+		return
+	}
+
+	if _, err = defaultReindexer.start(ReindexSchema); err != nil {
+		log.Warn("could not rebuild search index", zap.Error(err))
+		return
+	}
+
+	log.Info("rebuilding search index", zap.Int("version", v), zap.Int("schemaVersion", schemaVersion))
+}
+
+// Enabled reports if messages are indexed
+func Enabled() bool {
+	return DefaultFulltext != nil
+}
+
+func (svc service) With(ctx context.Context) FulltextService {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		index:    svc.index,
+		channels: svc.channels.With(ctx),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Search finds messages in channels that current user can read
+//
+// When channels are given, only those that can be read are searched.
+func (svc service) Search(q Query) (HitSet, error) {
+	if q.Terms = strings.TrimSpace(q.Terms); utf8.RuneCountInString(q.Terms) < minQueryLength {
+		return nil, ErrQueryTooShort.withStack()
+	}
+
+	if q.Limit == 0 {
+		q.Limit = defaultLimit
+	} else if q.Limit > maxLimit {
+		q.Limit = maxLimit
+	}
+
+	cc, _, err := svc.channels.Find(messagingTypes.ChannelFilter{
+		CurrentUserID: auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	q.ChannelID = readable(cc, q.ChannelID)
+	if len(q.ChannelID) == 0 {
+		return HitSet{}, nil
+	}
+
+	hh, err := svc.index.Search(svc.ctx, q)
+	if err != nil {
+		svc.log(zap.Error(err)).Error("could not search messages")
+		return nil, err
+	}
+
+	if err = markEdited(svc.ctx, hh); err != nil {
+		return nil, err
+	}
+
+	return hh, nil
+}
+
+// markEdited marks hits of edited messages with their number of revisions
+func markEdited(ctx context.Context, hh HitSet) error {
+	if len(hh) == 0 {
+		return nil
+	}
+
+	ID := make([]uint64, len(hh))
+	for i, h := range hh {
+		ID[i] = h.MessageID
+	}
+
+	cc, err := edits.Counts(ctx, ID...)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range hh {
+		h.Revisions = cc[h.MessageID]
+		h.Edited = h.Revisions > 0
+	}
+
+	return nil
+}
+
+// readable returns IDs of channels that were requested and can be read,
+// all readable when none were requested
+func readable(cc messagingTypes.ChannelSet, requested []uint64) []uint64 {
+	if len(requested) == 0 {
+		return cc.IDs()
+	}
+
+	out := make([]uint64, 0, len(requested))
+	for _, ID := range requested {
+		if cc.FindByID(ID) != nil {
+			out = append(out, ID)
+		}
+	}
+
+	return out
+}
+
+// Reindex starts rebuilding of the index in the background
+//
+// Index is searched as it is until the new one is built.
+func (svc service) Reindex() (*Reindex, error) {
+	if err := svc.canReindex(); err != nil {
+		return nil, err
+	}
+
+	svc.log().Info("search index rebuild requested")
+	return defaultReindexer.start(ReindexManual)
+}
+
+// ReindexStatus returns progress of the running or the last rebuild
+func (svc service) ReindexStatus() (*Reindex, error) {
+	if err := svc.canReindex(); err != nil {
+		return nil, err
+	}
+
+	return defaultReindexer.status()
+}
+
+// CancelReindex stops the running rebuild, the index stays as it was
+func (svc service) CancelReindex() (*Reindex, error) {
+	if err := svc.canReindex(); err != nil {
+		return nil, err
+	}
+
+	return defaultReindexer.stop()
+}
+
+func (svc service) canReindex() error {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return ErrNoPermissions.withStack()
+	}
+
+	if defaultReindexer == nil {
+		return ErrReindexUnavailable.withStack()
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// snippet returns part of the body around the first occurrence of the term
+//
+// Whole body is returned when it is short enough; cut parts are marked with "…".
+func snippet(body, term string) string {
+	body = strings.Join(strings.Fields(body), " ")
+	if utf8.RuneCountInString(body) <= snippetLength {
+		return body
+	}
+
+	var (
+		rr    = []rune(body)
+		at    = 0
+		start int
+		end   int
+	)
+
+	if f := strings.Fields(term); len(f) > 0 {
+		lower := strings.ToLower(body)
+		if i := strings.Index(lower, strings.ToLower(f[0])); i > 0 {
+			at = utf8.RuneCountInString(lower[:i])
+		}
+	}
+
+	// Keep a third of the snippet before the term
+	if start = at - snippetLength/3; start < 0 {
+		start = 0
+	}
+
+	if end = start + snippetLength; end > len(rr) {
+		end = len(rr)
+		start = end - snippetLength
+	}
+
+	out := strings.TrimSpace(string(rr[start:end]))
+	if start > 0 {
+		out = "…" + out
+	}
+
+	if end < len(rr) {
+		out += "…"
+	}
+
+	return out
+}
@@ -0,0 +1,137 @@
+package fulltext
+
+import (
+	"context"
+	"time"
+)
+
+type (
+	// Index keeps searchable text of messages
+	//
+	// Implementations must accept documents that are already indexed
+	// (replacing them) and removal of documents that are not.
+	Index interface {
+		Put(ctx context.Context, dd ...*Document) error
+		Remove(ctx context.Context, messageIDs ...uint64) error
+		Search(ctx context.Context, q Query) (HitSet, error)
+	}
+
+	// Rebuilder is an index that can be rebuilt while it is searched
+	Rebuilder interface {
+		// Version of the schema the index was built with, 0 when unknown
+		Version(ctx context.Context) (int, error)
+
+		// Rebuild starts building a new, empty index
+		Rebuild(ctx context.Context) (Build, error)
+	}
+
+	// Build is a new index that replaces the searched one when it is filled
+	Build interface {
+		// Fill adds documents that are not in the index yet
+		Fill(ctx context.Context, dd ...*Document) error
+
+		// Put and Remove apply changes made while the index is built
+		Put(ctx context.Context, dd ...*Document) error
+		Remove(ctx context.Context, messageIDs ...uint64) error
+
+		// Swap atomically replaces the searched index and removes it
+		Swap(ctx context.Context) error
+
+		// Discard removes the unfinished index
+		Discard(ctx context.Context) error
+	}
+
+	// Reindex is the progress of rebuilding the index
+	Reindex struct {
+		Status string `json:"status"`
+
+		// What started the reindex, "manual" or "schema"
+		Reason string `json:"reason"`
+
+		// Messages to index (counted at the start) and indexed so far
+		Total   uint `json:"total"`
+		Indexed uint `json:"indexed"`
+
+		// Share of indexed messages, from 0 to 1
+		Progress float64 `json:"progress"`
+
+		StartedAt  time.Time  `json:"startedAt"`
+		FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+		Error string `json:"error,omitempty"`
+	}
+
+	// Document is the indexed message
+	Document struct {
+		MessageID uint64    `json:"messageID,string" db:"rel_message"`
+		ChannelID uint64    `json:"channelID,string" db:"rel_channel"`
+		ThreadID  uint64    `json:"threadID,string" db:"reply_to"`
+		UserID    uint64    `json:"userID,string" db:"rel_user"`
+		Body      string    `json:"body" db:"body"`
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	// Query selects documents that contain all terms, in the given channels
+	Query struct {
+		Terms string
+
+		// Required, channels the user can read
+		ChannelID []uint64
+
+		// Replies of the thread only
+		ThreadID uint64
+
+		// Messages of the user only
+		UserID uint64
+
+		Limit uint
+	}
+
+	// Hit is a matching message
+	Hit struct {
+		MessageID uint64 `json:"messageID,string"`
+		ChannelID uint64 `json:"channelID,string"`
+		ThreadID  uint64 `json:"threadID,string,omitempty"`
+		UserID    uint64 `json:"userID,string"`
+
+		// Part of the message around the match
+		Snippet string `json:"snippet"`
+
+		// Relevance, from 0 to 1
+		Score float64 `json:"score"`
+
+		CreatedAt time.Time `json:"createdAt"`
+
+		// Message was edited, number of its previous revisions
+		Edited    bool `json:"edited"`
+		Revisions uint `json:"revisions"`
+	}
+
+	HitSet []*Hit
+)
+
+const (
+	ReindexRunning   = "running"
+	ReindexCompleted = "completed"
+	ReindexFailed    = "failed"
+	ReindexCanceled  = "canceled"
+
+	ReindexManual = "manual"
+	ReindexSchema = "schema"
+)
+
+const (
+	// Version of the index schema; indexes built with an older
+	// version are rebuilt on start
+	schemaVersion = 1
+
+	IndexMySQL         = "mysql"
+	IndexElasticsearch = "elasticsearch"
+
+	minQueryLength = 2
+
+	defaultLimit = 50
+	maxLimit     = 500
+
+	snippetLength = 160
+)
@@ -0,0 +1,35 @@
+package functions
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	functionsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrFunctionNotFound = functionsError{"FunctionNotFound", fault.NotFound}
+	ErrInvalidArguments = functionsError{"InvalidArguments", fault.Invalid}
+	ErrMissingArgument  = functionsError{"MissingArgument", fault.Invalid}
+	ErrInvalidTemplate  = functionsError{"InvalidTemplate", fault.Invalid}
+)
+
+func (e functionsError) Error() string {
+	return e.String()
+}
+
+func (e functionsError) String() string {
+	return "crust.functions." + e.name
+}
+
+func (e functionsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e functionsError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,282 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	htmlTemplate "html/template"
+	"strings"
+	textTemplate "text/template"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+	"github.com/crusttech/crust-server/pkg/reports"
+)
+
+var (
+	// library holds all available functions, sorted by name & version
+	library = FunctionSet{
+		{
+			Name:        "compose.createRecord",
+			Version:     1,
+			Description: "Creates record in any namespace the caller can write to",
+			Params: []*Param{
+				{Name: "namespaceID", Type: typeID, Required: true, Description: "Namespace of the module"},
+				{Name: "moduleID", Type: typeID, Required: true, Description: "Module of the new record"},
+				{Name: "values", Type: typeObject, Required: true, Description: "Field values by field name; lists for multi-value fields"},
+			},
+			Returns: "Created record",
+			call:    createRecord,
+		},
+		{
+			Name:        "email.send",
+			Version:     1,
+			Description: "Sends templated email; subject, text & html are Go templates rendered with data",
+			Params: []*Param{
+				{Name: "to", Type: typeStrings, Required: true, Description: "User IDs or email addresses (optionally followed by name)"},
+				{Name: "cc", Type: typeStrings, Description: "User IDs or email addresses"},
+				{Name: "subject", Type: typeString, Required: true, Description: "Subject template"},
+				{Name: "text", Type: typeString, Description: "Plain text body template"},
+				{Name: "html", Type: typeString, Description: "HTML body template; data is escaped"},
+				{Name: "data", Type: typeObject, Description: "Template data"},
+				{Name: "attachments", Type: typeFiles, Description: "Files to attach (as returned by pdf.render)"},
+			},
+			Returns: "Number of recipients",
+			call:    sendEmail,
+		},
+		{
+			Name:        "messaging.sendMessage",
+			Version:     1,
+			Description: "Sends message to a channel (or a thread) the caller is a member of",
+			Params: []*Param{
+				{Name: "channelID", Type: typeID, Required: true, Description: "Channel to send message to"},
+				{Name: "message", Type: typeString, Required: true, Description: "Message text"},
+				{Name: "replyTo", Type: typeID, Description: "Message to reply to"},
+			},
+			Returns: "Sent message",
+			call:    sendMessage,
+		},
+		{
+			Name:        "pdf.render",
+			Version:     1,
+			Description: "Renders plain text as a PDF document; long lines are wrapped",
+			Params: []*Param{
+				{Name: "name", Type: typeString, Required: true, Description: "File name, without extension"},
+				{Name: "title", Type: typeString, Description: "Title on the first page"},
+				{Name: "text", Type: typeString, Required: true, Description: "Document text"},
+			},
+			Returns: "File with base64 encoded content",
+			call:    renderPDF,
+		},
+	}
+)
+
+func createRecord(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		NamespaceID uint64                 `json:"namespaceID,string"`
+		ModuleID    uint64                 `json:"moduleID,string"`
+		Values      map[string]interface{} `json:"values"`
+	}
+
+	if err := decode(raw, &args); err != nil {
+		return nil, err
+	}
+
+	r := &types.Record{NamespaceID: args.NamespaceID, ModuleID: args.ModuleID}
+
+	for name, v := range args.Values {
+		vv, ok := v.([]interface{})
+		if !ok {
+			vv = []interface{}{v}
+		}
+
+		for i, item := range vv {
+			if item == nil {
+				continue
+			}
+
+			r.Values = append(r.Values, &types.RecordValue{Name: name, Value: fmt.Sprint(item), Place: uint(i)})
+		}
+	}
+
+	return service.DefaultRecord.With(ctx).Create(r)
+}
+
+func sendEmail(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		To          []string               `json:"to"`
+		Cc          []string               `json:"cc"`
+		Subject     string                 `json:"subject"`
+		Text        string                 `json:"text"`
+		HTML        string                 `json:"html"`
+		Data        map[string]interface{} `json:"data"`
+		Attachments []*File                `json:"attachments"`
+	}
+
+	if err := decode(raw, &args); err != nil {
+		return nil, err
+	}
+
+	if len(args.To) == 0 || (args.Text == "" && args.HTML == "") {
+		return nil, ErrMissingArgument.withStack()
+	}
+
+	subject, err := renderText(args.Subject, args.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	m := mail.New()
+	m.SetHeader("Subject", strings.TrimSpace(subject))
+
+	if err = service.DefaultNotification.AttachEmailRecipients(ctx, m, "To", args.To...); err != nil {
+		return nil, err
+	}
+
+	if err = service.DefaultNotification.AttachEmailRecipients(ctx, m, "Cc", args.Cc...); err != nil {
+		return nil, err
+	}
+
+	if args.Text != "" {
+		text, err := renderText(args.Text, args.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		m.SetBody("text/plain", text)
+	}
+
+	if args.HTML != "" {
+		html, err := renderHTML(args.HTML, args.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		if args.Text != "" {
+			m.AddAlternative("text/html", html)
+		} else {
+			m.SetBody("text/html", html)
+		}
+	}
+
+	for _, f := range args.Attachments {
+		m.AttachReader(f.Name, bytes.NewReader(f.Content))
+	}
+
+	if err = mail.Send(m); err != nil {
+		return nil, err
+	}
+
+	return struct {
+		Recipients int `json:"recipients"`
+	}{len(args.To) + len(args.Cc)}, nil
+}
+
+// sendMessage is available only when running as a monolith
+func sendMessage(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var args struct {
+		ChannelID uint64 `json:"channelID,string"`
+		Message   string `json:"message"`
+		ReplyTo   uint64 `json:"replyTo,string"`
+	}
+
+	if err := decode(raw, &args); err != nil {
+		return nil, err
+	}
+
+	return messagingService.DefaultMessage.With(ctx).Create(&messagingTypes.Message{
+		ChannelID: args.ChannelID,
+		Message:   args.Message,
+		ReplyTo:   args.ReplyTo,
+	})
+}
+
+func renderPDF(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	var (
+		args struct {
+			Name  string `json:"name"`
+			Title string `json:"title"`
+			Text  string `json:"text"`
+		}
+
+		lines []string
+		buf   bytes.Buffer
+	)
+
+	if err := decode(raw, &args); err != nil {
+		return nil, err
+	}
+
+	if args.Title != "" {
+		lines = append(lines, args.Title, "")
+	}
+
+	for _, line := range strings.Split(args.Text, "\n") {
+		lines = append(lines, wrap(strings.TrimRight(line, "\r"), reports.PDFLineWidth)...)
+	}
+
+	if err := reports.WritePDF(&buf, lines); err != nil {
+		return nil, err
+	}
+
+	return &File{
+		Name:        args.Name + ".pdf",
+		ContentType: "application/pdf",
+		Content:     buf.Bytes(),
+	}, nil
+}
+
+func renderText(src string, data interface{}) (string, error) {
+	t, err := textTemplate.New("").Option("missingkey=zero").Parse(src)
+	if err != nil {
+		return "", ErrInvalidTemplate.withStack()
+	}
+
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func renderHTML(src string, data interface{}) (string, error) {
+	t, err := htmlTemplate.New("").Option("missingkey=zero").Parse(src)
+	if err != nil {
+		return "", ErrInvalidTemplate.withStack()
+	}
+
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// wrap splits line into lines of at most n characters, on spaces when possible
+func wrap(line string, n int) []string {
+	var (
+		rr  = []rune(line)
+		out []string
+	)
+
+	for len(rr) > n {
+		cut := n
+		for i := n; i > n/2; i-- {
+			if rr[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+
+		out = append(out, string(rr[:cut]))
+		rr = []rune(strings.TrimLeft(string(rr[cut:]), " "))
+	}
+
+	return append(out, string(rr))
+}
@@ -0,0 +1,34 @@
+package functions
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts function catalog and call endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("Function.Catalog", func(r *http.Request) (interface{}, error) {
+		return DefaultFunctions.With(r.Context()).Catalog(), nil
+	}))
+
+	// ?version=1; body holds arguments as JSON object
+	r.Post("/{name}", rest.Handler("Function.Call", func(r *http.Request) (interface{}, error) {
+		var args json.RawMessage
+		if err := rest.Decode(r, &args); err != nil {
+			return nil, err
+		}
+
+		return DefaultFunctions.With(r.Context()).Call(
+			chi.URLParam(r, "name"),
+			rest.QueryUint(r, "version"),
+			args,
+		)
+	}))
+}
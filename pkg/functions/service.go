@@ -0,0 +1,119 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	functionsService struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	FunctionsService interface {
+		With(ctx context.Context) FunctionsService
+
+		Catalog() *Catalog
+		Call(name string, version uint, args json.RawMessage) (interface{}, error)
+	}
+)
+
+var (
+	DefaultFunctions FunctionsService
+)
+
+// Init initializes function library service
+//
+// Must be called after services of all apps are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultFunctions = (&functionsService{
+		logger: log,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc functionsService) With(ctx context.Context) FunctionsService {
+	return &functionsService{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc functionsService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Catalog returns all functions with their versions and params
+func (svc functionsService) Catalog() *Catalog {
+	return &Catalog{Functions: library}
+}
+
+// Call runs the function with arguments (JSON object)
+//
+// Latest version of the function is called when version is not given.
+func (svc functionsService) Call(name string, version uint, args json.RawMessage) (interface{}, error) {
+	f := library.find(name, version)
+	if f == nil {
+		return nil, ErrFunctionNotFound.withStack()
+	}
+
+	if err := f.check(args); err != nil {
+		return nil, err
+	}
+
+	var (
+		started = time.Now()
+		log     = svc.log(zap.String("function", f.Name), zap.Uint("version", f.Version))
+	)
+
+	out, err := f.call(svc.ctx, args)
+	if err != nil {
+		log.Debug("function failed", zap.Error(err))
+		return nil, err
+	}
+
+	log.Debug("function called", zap.Duration("duration", time.Since(started)))
+	return out, nil
+}
+
+// check verifies that all required arguments are given
+func (f Function) check(args json.RawMessage) error {
+	aa := map[string]json.RawMessage{}
+	if err := decode(args, &aa); err != nil {
+		return err
+	}
+
+	for _, p := range f.Params {
+		if !p.Required {
+			continue
+		}
+
+		switch v := string(aa[p.Name]); {
+		case v == "", v == "null", v == `""`, v == "[]", p.Type == typeID && v == `"0"`:
+			return ErrMissingArgument.withStack()
+		}
+	}
+
+	return nil
+}
+
+func decode(args json.RawMessage, dst interface{}) error {
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+
+	if err := json.Unmarshal(args, dst); err != nil {
+		return ErrInvalidArguments.withStack()
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package functions
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type (
+	// Function is a server-side function that automation scripts can call
+	//
+	// Functions run with permissions of the caller (script's run-as user).
+	// Incompatible changes are made in a new version of the function;
+	// old versions are kept (and deprecated) so existing scripts keep working.
+	Function struct {
+		Name        string   `json:"name"`
+		Version     uint     `json:"version"`
+		Description string   `json:"description"`
+		Params      []*Param `json:"params"`
+		Returns     string   `json:"returns"`
+		Deprecated  bool     `json:"deprecated,omitempty"`
+
+		call handler
+	}
+
+	FunctionSet []*Function
+
+	Param struct {
+		Name        string `json:"name"`
+		Type        string `json:"type"`
+		Required    bool   `json:"required,omitempty"`
+		Description string `json:"description"`
+	}
+
+	// Catalog lists all functions with their versions
+	Catalog struct {
+		Functions FunctionSet `json:"functions"`
+	}
+
+	// File is produced by functions that render documents and
+	// accepted as an email attachment
+	File struct {
+		Name        string `json:"name"`
+		ContentType string `json:"contentType"`
+
+		// Encoded as base64 string
+		Content []byte `json:"content"`
+	}
+
+	handler func(ctx context.Context, args json.RawMessage) (interface{}, error)
+)
+
+// Param types
+const (
+	typeString  = "string"
+	typeID      = "id"
+	typeStrings = "string[]"
+	typeObject  = "object"
+	typeFiles   = "file[]"
+)
+
+// find returns function with the given name & version;
+// latest version when version is not given
+func (ff FunctionSet) find(name string, version uint) *Function {
+	var found *Function
+
+	for _, f := range ff {
+		if f.Name != name {
+			continue
+		}
+
+		if version > 0 && f.Version == version {
+			return f
+		}
+
+		if version == 0 && (found == nil || f.Version > found.Version) {
+			found = f
+		}
+	}
+
+	return found
+}
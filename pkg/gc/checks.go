@@ -0,0 +1,228 @@
+package gc
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+var (
+	composeChecks = []*check{
+		{
+			kind: KindAttachment,
+
+			// Record attachments that no value of a record references
+			// and page attachments that no page block mentions
+			find: query(`
+SELECT CAST(a.id AS CHAR)
+  FROM compose_attachment AS a
+ WHERE a.deleted_at IS NULL
+   AND ((a.kind = 'record' AND NOT EXISTS (
+         SELECT 1
+           FROM compose_record_value AS v
+                INNER JOIN compose_record AS r ON (r.id = v.record_id AND r.deleted_at IS NULL)
+          WHERE v.ref = a.id AND v.deleted_at IS NULL))
+     OR (a.kind = 'page' AND NOT EXISTS (
+         SELECT 1
+           FROM compose_page AS p
+          WHERE p.rel_namespace = a.rel_namespace AND p.deleted_at IS NULL
+            AND p.blocks LIKE CONCAT('%', a.id, '%'))))`),
+
+			remove: removeAttachments("compose_attachment"),
+		},
+		{
+			kind: KindReference,
+
+			// Values of record fields that reference missing or deleted records
+			find: query(`
+SELECT CONCAT_WS(':', v.record_id, v.name, v.place)
+  FROM compose_record_value AS v
+       INNER JOIN compose_record AS r ON (r.id = v.record_id AND r.deleted_at IS NULL)
+       INNER JOIN compose_module_field AS f ON (f.rel_module = r.module_id AND f.name = v.name AND f.kind = 'Record' AND f.deleted_at IS NULL)
+       LEFT JOIN compose_record AS t ON (t.id = v.ref AND t.deleted_at IS NULL)
+ WHERE v.ref > 0 AND v.deleted_at IS NULL AND t.id IS NULL`),
+
+			remove: removeReferences,
+		},
+		{
+			kind: KindPermissionRule,
+			find: rules("compose_permission_rules",
+				resource{composeTypes.NamespacePermissionResource, "compose_namespace"},
+				resource{composeTypes.ModulePermissionResource, "compose_module"},
+				resource{composeTypes.ModuleFieldPermissionResource, "compose_module_field"},
+				resource{composeTypes.PagePermissionResource, "compose_page"},
+				resource{composeTypes.ChartPermissionResource, "compose_chart"},
+				resource{composeTypes.AutomationScriptPermissionResource, "compose_automation_script"},
+				resource{composeTypes.AutomationTriggerPermissionResource, "compose_automation_trigger"},
+			),
+			remove: removeRules,
+		},
+	}
+
+	systemChecks = []*check{
+		{
+			kind: KindPermissionRule,
+			find: union(
+				rules("sys_permission_rules",
+					resource{systemTypes.RolePermissionResource, "sys_role"},
+					resource{systemTypes.UserPermissionResource, "sys_user"},
+					resource{systemTypes.ApplicationPermissionResource, "sys_application"},
+					resource{systemTypes.OrganisationPermissionResource, "sys_organisation"},
+					resource{systemTypes.AutomationScriptPermissionResource, "sys_automation_script"},
+				),
+
+				// Rules of deleted roles
+				query(`
+SELECT CONCAT_WS(' ', rel_role, resource, operation)
+  FROM sys_permission_rules
+ WHERE rel_role <> ?
+   AND rel_role NOT IN (SELECT id FROM sys_role WHERE deleted_at IS NULL)`, permissions.EveryoneRoleID),
+			),
+			remove: removeRules,
+		},
+	}
+
+	messagingChecks = []*check{
+		{
+			kind: KindAttachment,
+
+			// Attachments of missing or deleted messages
+			find: query(`
+SELECT CAST(a.id AS CHAR)
+  FROM messaging_attachment AS a
+ WHERE a.deleted_at IS NULL
+   AND NOT EXISTS (
+       SELECT 1
+         FROM messaging_message_attachment AS ma
+              INNER JOIN messaging_message AS m ON (m.id = ma.rel_message AND m.deleted_at IS NULL)
+        WHERE ma.rel_attachment = a.id)`),
+
+			remove: removeAttachments("messaging_attachment"),
+		},
+		{
+			kind: KindPermissionRule,
+			find: rules("messaging_permission_rules",
+				resource{messagingTypes.ChannelPermissionResource, "messaging_channel"},
+				resource{messagingTypes.WebhookPermissionResource, "messaging_webhook"},
+			),
+			remove: removeRules,
+		},
+	}
+)
+
+// query returns refs selected by the SQL
+func query(sql string, args ...interface{}) func(r *repository) ([]string, error) {
+	return func(r *repository) ([]string, error) {
+		return r.Refs(sql, args...)
+	}
+}
+
+// union returns refs of all finders
+func union(ff ...func(r *repository) ([]string, error)) func(r *repository) ([]string, error) {
+	return func(r *repository) (out []string, err error) {
+		seen := map[string]bool{}
+		for _, find := range ff {
+			refs, err := find(r)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, ref := range refs {
+				if !seen[ref] {
+					seen[ref] = true
+					out = append(out, ref)
+				}
+			}
+		}
+
+		return out, nil
+	}
+}
+
+// rules returns refs of rules on resources that are missing or deleted
+//
+// Rules on all resources of the type (wildcard) are kept.
+func rules(table string, rr ...resource) func(r *repository) ([]string, error) {
+	ff := make([]func(r *repository) ([]string, error), len(rr))
+	for i, res := range rr {
+		prefix := res.prefix.String()
+		ff[i] = query(`
+SELECT CONCAT_WS(' ', rel_role, resource, operation)
+  FROM `+table+`
+ WHERE resource LIKE ? AND resource <> ?
+   AND CAST(SUBSTRING(resource, ?) AS UNSIGNED) NOT IN (SELECT id FROM `+res.table+` WHERE deleted_at IS NULL)`,
+			prefix+"%", res.prefix.AppendWildcard().String(), len(prefix)+1)
+	}
+
+	return union(ff...)
+}
+
+// removeAttachments removes files and records of attachments
+func removeAttachments(table string) func(svc *gcService, a *app, refs []string) error {
+	return func(svc *gcService, a *app, refs []string) error {
+		r := svc.repository(a)
+
+		files, err := r.Files(table, refs)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range files {
+			if err = a.store.Remove(f); err != nil {
+				// File may be gone already, record is removed anyway
+				svc.log(zap.String("app", a.name), zap.String("file", f), zap.Error(err)).
+					Warn("could not remove attachment file")
+			}
+		}
+
+		return r.DeleteByIDs(table, refs)
+	}
+}
+
+// removeReferences removes record values with dangling references
+func removeReferences(svc *gcService, a *app, refs []string) error {
+	r := svc.repository(a)
+
+	for _, ref := range refs {
+		parts := strings.SplitN(ref, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		recordID, _ := strconv.ParseUint(parts[0], 10, 64)
+		place, _ := strconv.ParseUint(parts[2], 10, 64)
+
+		if err := r.DeleteValue(recordID, parts[1], uint(place)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeRules removes rules by setting them to inherit
+//
+// Rules are changed through the permission service,
+// so that its cache is updated too.
+func removeRules(svc *gcService, a *app, refs []string) error {
+	rr := make([]*permissions.Rule, 0, len(refs))
+	for _, ref := range refs {
+		parts := strings.SplitN(ref, " ", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		roleID, _ := strconv.ParseUint(parts[0], 10, 64)
+		rr = append(rr, permissions.InheritRule(roleID, permissions.Resource(parts[1]), permissions.Operation(parts[2])))
+	}
+
+	return errors.WithStack(a.ac.Grant(auth.SetSuperUserContext(svc.ctx), rr...))
+}
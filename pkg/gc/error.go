@@ -0,0 +1,32 @@
+package gc
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	gcError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions = gcError{"NoPermissions", fault.Forbidden}
+)
+
+func (e gcError) Error() string {
+	return e.String()
+}
+
+func (e gcError) String() string {
+	return "crust.gc." + e.name
+}
+
+func (e gcError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e gcError) withStack() *fault.Error {
+	return fault.New(e)
+}
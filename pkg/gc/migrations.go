@@ -0,0 +1,32 @@
+package gc
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	ComposeMigrations   = migrationsOf("compose")
+	SystemMigrations    = migrationsOf("system")
+	MessagingMigrations = migrationsOf("messaging")
+)
+
+// migrationsOf returns migrations of app's orphan table
+//
+// Names differ by app, apps can share the database.
+func migrationsOf(app string) migrations.Set {
+	return migrations.Set{
+		{
+			Name: "20200220000000.gc-" + app,
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_` + app + `_orphan (
+  kind             VARCHAR(32)     NOT NULL,
+  ref              VARCHAR(255)    NOT NULL,
+
+  first_seen_at    DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (kind, ref)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+}
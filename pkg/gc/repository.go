@@ -0,0 +1,125 @@
+package gc
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+		app string
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB, app string) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+		app: app,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet(r.app).With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_" + r.app + "_orphan"
+}
+
+// Refs returns refs selected by the SQL
+func (r repository) Refs(sql string, args ...interface{}) (refs []string, err error) {
+	return refs, errors.WithStack(r.db().Select(&refs, sql, args...))
+}
+
+// Tracked returns orphans of the kind that were seen before
+func (r repository) Tracked(kind string) (set OrphanSet, err error) {
+	q := squirrel.
+		Select("kind", "ref", "first_seen_at").
+		From(r.table()).
+		Where(squirrel.Eq{"kind": kind})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Track records when the orphans were first seen
+func (r repository) Track(kind string, refs []string, seen time.Time) error {
+	return r.db().Transaction(func() error {
+		for _, ref := range refs {
+			_, err := r.db().Exec(
+				"INSERT IGNORE INTO "+r.table()+" (kind, ref, first_seen_at) VALUES (?, ?, ?)",
+				kind, ref, seen,
+			)
+
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Forget removes orphans from tracking, they were removed or are not orphans anymore
+func (r repository) Forget(kind string, refs []string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"kind": kind, "ref": refs})
+}
+
+// Files returns URLs of original and preview files of attachments
+func (r repository) Files(table string, ids []string) (urls []string, err error) {
+	var (
+		aa []struct {
+			Url        string `db:"url"`
+			PreviewUrl string `db:"preview_url"`
+		}
+
+		q = squirrel.
+			Select("COALESCE(url, '') AS url", "COALESCE(preview_url, '') AS preview_url").
+			From(table).
+			Where(squirrel.Eq{"id": ids})
+	)
+
+	if err = rh.FetchAll(r.db(), q, &aa); err != nil {
+		return nil, err
+	}
+
+	for _, a := range aa {
+		for _, u := range []string{a.Url, a.PreviewUrl} {
+			if u != "" {
+				urls = append(urls, u)
+			}
+		}
+	}
+
+	return urls, nil
+}
+
+func (r repository) DeleteByIDs(table string, ids []string) error {
+	return rh.Delete(r.db(), table, squirrel.Eq{"id": ids})
+}
+
+// DeleteValue removes the record value, the same way as values of deleted records are
+func (r repository) DeleteValue(recordID uint64, name string, place uint) error {
+	_, err := r.db().Exec(
+		"UPDATE compose_record_value SET deleted_at = ? WHERE record_id = ? AND name = ? AND place = ?",
+		time.Now(), recordID, name, place,
+	)
+
+	return errors.WithStack(err)
+}
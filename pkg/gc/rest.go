@@ -0,0 +1,34 @@
+package gc
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountComposeRoutes mounts orphan report of compose
+func MountComposeRoutes(r chi.Router) {
+	mount(r, "compose")
+}
+
+// MountSystemRoutes mounts orphan report of system
+func MountSystemRoutes(r chi.Router) {
+	mount(r, "system")
+}
+
+// MountMessagingRoutes mounts orphan report of messaging
+func MountMessagingRoutes(r chi.Router) {
+	mount(r, "messaging")
+}
+
+func mount(r chi.Router, app string) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Dry-run, lists orphans and when they will be removed
+	r.Get("/", rest.Handler("Orphans.Report", func(r *http.Request) (interface{}, error) {
+		return DefaultGC.With(r.Context()).Report(app)
+	}))
+}
@@ -0,0 +1,271 @@
+package gc
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	gcService struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	accessController interface {
+		CanManageSettings(context.Context) bool
+		Grant(context.Context, ...*permissions.Rule) error
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	GCService interface {
+		With(ctx context.Context) GCService
+
+		Report(app string) (*Report, error)
+	}
+)
+
+var (
+	DefaultGC GCService
+
+	// Apps with initialized collectors
+	apps = map[string]*app{}
+)
+
+// InitCompose starts collecting orphaned attachments, record references and permission rules of compose
+//
+// Must be called after compose services are initialized
+func InitCompose(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log, &app{
+		name:     "compose",
+		checks:   composeChecks,
+		ac:       composeService.DefaultAccessControl,
+		settings: composeService.DefaultSettings,
+		store:    composeService.DefaultStore,
+	})
+}
+
+// InitSystem starts collecting orphaned permission rules of system
+//
+// Must be called after system services are initialized
+func InitSystem(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log, &app{
+		name:     "system",
+		checks:   systemChecks,
+		ac:       systemService.DefaultAccessControl,
+		settings: systemService.DefaultSettings,
+	})
+}
+
+// InitMessaging starts collecting orphaned attachments and permission rules of messaging
+//
+// Must be called after messaging services are initialized
+func InitMessaging(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log, &app{
+		name:     "messaging",
+		checks:   messagingChecks,
+		ac:       messagingService.DefaultAccessControl,
+		settings: messagingService.DefaultSettings,
+		store:    messagingService.DefaultStore,
+	})
+}
+
+func start(ctx context.Context, log *zap.Logger, a *app) error {
+	svc := (&gcService{logger: log}).with(ctx)
+
+	apps[a.name] = a
+	DefaultGC = svc
+
+	go svc.watch(ctx, a)
+
+	return nil
+}
+
+func (svc gcService) With(ctx context.Context) GCService {
+	return svc.with(ctx)
+}
+
+func (svc gcService) with(ctx context.Context) *gcService {
+	return &gcService{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc gcService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Report returns orphans of the app and when they will be removed, without removing them
+//
+// Report is available to those that can manage app's settings
+func (svc gcService) Report(name string) (*Report, error) {
+	a := apps[name]
+	if a == nil || !a.ac.CanManageSettings(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return svc.collect(a, true)
+}
+
+// collect finds orphans of the app and removes those that outlived the grace period
+//
+// Orphans are tracked from the first time they are seen; those that
+// are not orphans anymore (record was undeleted, attachment was used)
+// are not tracked anymore. Dry-run does not change anything.
+func (svc gcService) collect(a *app, dryRun bool) (*Report, error) {
+	var (
+		r     = svc.repository(a)
+		now   = time.Now()
+		grace = svc.gracePeriod(a)
+
+		out = &Report{
+			App:         a.name,
+			DryRun:      dryRun,
+			GracePeriod: grace.String(),
+			Orphans:     OrphanSet{},
+		}
+	)
+
+	for _, c := range a.checks {
+		refs, err := c.find(r)
+		if err != nil {
+			return nil, err
+		}
+
+		tracked, err := r.Tracked(c.kind)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			seen    = map[string]*time.Time{}
+			current = map[string]bool{}
+			gone    []string
+			expired []string
+		)
+
+		for _, o := range tracked {
+			seen[o.Ref] = o.FirstSeenAt
+		}
+
+		for _, ref := range refs {
+			current[ref] = true
+
+			o := &Orphan{Kind: c.kind, Ref: ref, FirstSeenAt: seen[ref]}
+			if o.FirstSeenAt == nil && !dryRun {
+				o.FirstSeenAt = &now
+			}
+
+			if o.FirstSeenAt != nil {
+				removeAt := o.FirstSeenAt.Add(grace)
+				o.RemoveAt = &removeAt
+
+				if !removeAt.After(now) {
+					expired = append(expired, ref)
+				}
+			}
+
+			out.Orphans = append(out.Orphans, o)
+		}
+
+		if dryRun {
+			continue
+		}
+
+		for _, o := range tracked {
+			if !current[o.Ref] {
+				gone = append(gone, o.Ref)
+			}
+		}
+
+		if err = r.Forget(c.kind, gone); err != nil {
+			return nil, err
+		}
+
+		if err = r.Track(c.kind, refs, now); err != nil {
+			return nil, err
+		}
+
+		if len(expired) == 0 {
+			continue
+		}
+
+		if err = c.remove(&svc, a, expired); err != nil {
+			return nil, err
+		}
+
+		if err = r.Forget(c.kind, expired); err != nil {
+			return nil, err
+		}
+
+		out.Removed += len(expired)
+	}
+
+	return out, nil
+}
+
+// gracePeriod returns how long orphans are kept
+func (svc gcService) gracePeriod(a *app) time.Duration {
+	v, err := a.settings.Get(auth.SetSuperUserContext(svc.ctx), settingGracePeriod, 0)
+	if err != nil || v == nil {
+		return defaultGracePeriod
+	}
+
+	var s string
+	if err = v.Value.Unmarshal(&s); err != nil {
+		return defaultGracePeriod
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < minGracePeriod {
+		return defaultGracePeriod
+	}
+
+	return d
+}
+
+func (svc gcService) repository(a *app) *repository {
+	return Repository(svc.ctx, factory.Database.MustGet(a.name).With(svc.ctx), a.name)
+}
+
+func (svc gcService) watch(ctx context.Context, a *app) {
+	t := time.NewTicker(collectInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			rep, err := svc.with(ctx).collect(a, false)
+			if err != nil {
+				svc.logger.Error("could not collect orphans", zap.String("app", a.name), zap.Error(err))
+				continue
+			}
+
+			if len(rep.Orphans) > 0 {
+				svc.logger.Info("orphans collected",
+					zap.String("app", a.name),
+					zap.Int("orphans", len(rep.Orphans)),
+					zap.Int("removed", rep.Removed),
+				)
+			}
+		}
+	}
+}
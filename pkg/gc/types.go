@@ -0,0 +1,80 @@
+package gc
+
+import (
+	"time"
+
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/pkg/store"
+)
+
+type (
+	// Orphan is data nothing references anymore, or that references
+	// something that does not exist anymore
+	//
+	// Ref identifies the orphan, by kind:
+	//  attachment: "<attachmentID>"
+	//  reference: "<recordID>:<field>:<place>" (record value)
+	//  permission-rule: "<roleID> <resource> <operation>"
+	Orphan struct {
+		Kind string `json:"kind" db:"kind"`
+		Ref  string `json:"ref" db:"ref"`
+
+		// When the orphan was first seen, not set for new orphans
+		// in dry-run reports
+		FirstSeenAt *time.Time `json:"firstSeenAt,omitempty" db:"first_seen_at"`
+
+		// Orphans are removed after the grace period
+		RemoveAt *time.Time `json:"removeAt,omitempty" db:"-"`
+	}
+
+	OrphanSet []*Orphan
+
+	// Report of the collection, orphans are not removed in dry-runs
+	Report struct {
+		App         string    `json:"app"`
+		DryRun      bool      `json:"dryRun"`
+		GracePeriod string    `json:"gracePeriod"`
+		Orphans     OrphanSet `json:"orphans"`
+		Removed     int       `json:"removed"`
+	}
+
+	// app with its database, orphan checks and services
+	app struct {
+		name     string
+		checks   []*check
+		ac       accessController
+		settings settingsGetter
+
+		// Store of attachment files
+		store store.Store
+	}
+
+	// check finds and removes orphans of the kind
+	check struct {
+		kind   string
+		find   func(r *repository) ([]string, error)
+		remove func(svc *gcService, a *app, refs []string) error
+	}
+
+	// resource type of permission rules and its table
+	resource struct {
+		prefix permissions.Resource
+		table  string
+	}
+)
+
+const (
+	KindAttachment     = "attachment"
+	KindReference      = "reference"
+	KindPermissionRule = "permission-rule"
+
+	// Orphans are removed when they are orphans for this long,
+	// unless set with crust.gc.grace-period (app's setting, "72h")
+	defaultGracePeriod = 7 * 24 * time.Hour
+	minGracePeriod     = time.Hour
+
+	settingGracePeriod = "crust.gc.grace-period"
+
+	// How often orphans are collected
+	collectInterval = 6 * time.Hour
+)
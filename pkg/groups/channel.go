@@ -0,0 +1,119 @@
+package groups
+
+import (
+	"context"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// channel wraps channel service and manages groups on its own
+	channel struct {
+		messagingService.ChannelService
+		ctx context.Context
+	}
+)
+
+// Channel decorates channel service with managing groups
+//
+// Groups are deduplicated by their exact members, members can be added
+// and removed; other channels are left to the decorated service.
+func Channel(cs messagingService.ChannelService) messagingService.ChannelService {
+	return &channel{ChannelService: cs, ctx: context.Background()}
+}
+
+func (svc channel) With(ctx context.Context) messagingService.ChannelService {
+	return &channel{
+		ChannelService: svc.ChannelService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc channel) Create(ch *messagingTypes.Channel) (*messagingTypes.Channel, error) {
+	if ch == nil || ch.Type != messagingTypes.ChannelTypeGroup {
+		return svc.ChannelService.Create(ch)
+	}
+
+	ID, err := defaultGroups.with(svc.ctx).create(ch)
+	if err != nil {
+		return nil, err
+	}
+
+	if ch, err = svc.ChannelService.FindByID(ID); err != nil {
+		return nil, err
+	}
+
+	return ch, messagingService.Event(svc.ctx).Channel(ch)
+}
+
+// Update refuses to change channels into groups and back
+func (svc channel) Update(upd *messagingTypes.Channel) (*messagingTypes.Channel, error) {
+	if upd != nil && upd.Type.IsValid() {
+		ch, err := svc.ChannelService.FindByID(upd.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if ch.Type != upd.Type && (ch.Type == messagingTypes.ChannelTypeGroup || upd.Type == messagingTypes.ChannelTypeGroup) {
+			return nil, ErrInvalidType.withStack()
+		}
+	}
+
+	return svc.ChannelService.Update(upd)
+}
+
+func (svc channel) InviteUser(channelID uint64, memberIDs ...uint64) (messagingTypes.ChannelMemberSet, error) {
+	if ch, err := svc.group(channelID); err != nil {
+		return nil, err
+	} else if ch != nil {
+		// Members are added to groups directly
+		return nil, ErrInviteUnsupported.withStack()
+	}
+
+	return svc.ChannelService.InviteUser(channelID, memberIDs...)
+}
+
+func (svc channel) AddMember(channelID uint64, memberIDs ...uint64) (messagingTypes.ChannelMemberSet, error) {
+	ch, err := svc.group(channelID)
+	if err != nil {
+		return nil, err
+	} else if ch == nil {
+		return svc.ChannelService.AddMember(channelID, memberIDs...)
+	}
+
+	mm, err := defaultGroups.with(svc.ctx).add(ch, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Members are sent to all of them, new ones included
+	if ch, err = svc.ChannelService.FindByID(channelID); err != nil {
+		return nil, err
+	}
+
+	return mm, messagingService.Event(svc.ctx).Channel(ch)
+}
+
+func (svc channel) DeleteMember(channelID uint64, memberIDs ...uint64) error {
+	ch, err := svc.group(channelID)
+	if err != nil {
+		return err
+	} else if ch == nil {
+		return svc.ChannelService.DeleteMember(channelID, memberIDs...)
+	}
+
+	return defaultGroups.with(svc.ctx).remove(ch, memberIDs)
+}
+
+// group returns the channel when it is a group, nil otherwise
+func (svc channel) group(channelID uint64) (*messagingTypes.Channel, error) {
+	ch, err := svc.ChannelService.FindByID(channelID)
+	if err != nil {
+		return nil, err
+	} else if ch.Type != messagingTypes.ChannelTypeGroup {
+		return nil, nil
+	}
+
+	return ch, nil
+}
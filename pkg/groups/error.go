@@ -0,0 +1,38 @@
+package groups
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	groupsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidMember     = groupsError{"InvalidMember", fault.Invalid}
+	ErrInvalidType       = groupsError{"InvalidType", fault.Invalid}
+	ErrTooFewMembers     = groupsError{"TooFewMembers", fault.Invalid}
+	ErrTooManyMembers    = groupsError{"TooManyMembers", fault.TooMany}
+	ErrGroupExists       = groupsError{"GroupExists", fault.Conflict}
+	ErrInviteUnsupported = groupsError{"InviteUnsupported", fault.Invalid}
+	ErrNoPermissions     = groupsError{"NoPermissions", fault.Forbidden}
+)
+
+func (e groupsError) Error() string {
+	return e.String()
+}
+
+func (e groupsError) String() string {
+	return "crust.groups." + e.name
+}
+
+func (e groupsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e groupsError) withStack() *fault.Error {
+	return fault.New(e)
+}
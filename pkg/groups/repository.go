@@ -0,0 +1,69 @@
+package groups
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+// FindByMembers returns ID of the group with exactly these members, 0 when there is none
+//
+// Unlike corteza's lookup, groups with more members than given do not match.
+// Deleted groups and the excluded one are skipped; the oldest group is
+// returned when there is more than one.
+func (r repository) FindByMembers(userIDs []uint64, exclude uint64) (uint64, error) {
+	var (
+		out = struct {
+			ID uint64 `db:"id"`
+		}{}
+
+		args = make([]interface{}, 0, len(userIDs)+1)
+	)
+
+	for _, ID := range userIDs {
+		args = append(args, ID)
+	}
+
+	args = append(args, len(userIDs))
+
+	q := squirrel.
+		Select("c.id").
+		From("messaging_channel AS c").
+		Join("messaging_channel_member AS cm ON (cm.rel_channel = c.id)").
+		Where(squirrel.Eq{"c.type": messagingTypes.ChannelTypeGroup, "c.deleted_at": nil}).
+		Where(squirrel.NotEq{"c.id": exclude}).
+		GroupBy("c.id").
+		Having("COUNT(*) = ?", len(userIDs)).
+		Having("SUM(cm.rel_user IN ("+strings.TrimSuffix(strings.Repeat("?,", len(userIDs)), ",")+")) = ?", args...).
+		OrderBy("c.id").
+		Limit(1)
+
+	return out.ID, rh.FetchOne(r.db(), q, &out)
+}
@@ -0,0 +1,472 @@
+package groups
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingRepository "github.com/cortezaproject/corteza-server/messaging/repository"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/organization"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanCreateGroupChannel(context.Context) bool
+		CanLeaveChannel(context.Context, *messagingTypes.Channel) bool
+	}
+)
+
+var (
+	// used by service decorators
+	defaultGroups *service
+
+	// Members of a group, including its creator
+	maxMembers = 9
+)
+
+// Init initializes groups (direct messages) of more than two members
+// and decorates channel service with managing them
+//
+// Must be called after messaging services are initialized and before
+// other extensions decorate channel service.
+func Init(ctx context.Context, log *zap.Logger) error {
+	maxMembers = options.EnvInt("", "GROUPS_MAX_MEMBERS", maxMembers)
+
+	defaultGroups = (&service{
+		logger: log,
+		ac:     messagingService.DefaultAccessControl,
+	}).with(ctx)
+
+	messagingService.DefaultChannel = Channel(messagingService.DefaultChannel)
+	return nil
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// create creates a group of the current user with the members,
+// or returns ID of the existing one with the same members
+func (svc service) create(in *messagingTypes.Channel) (uint64, error) {
+	var (
+		userID  = auth.GetIdentityFromContext(svc.ctx).Identity()
+		members = unique(append([]uint64{userID}, in.Members...))
+	)
+
+	if err := validMembers(in.Members); err != nil {
+		return 0, err
+	}
+
+	if err := sized(len(members)); err != nil {
+		return 0, err
+	}
+
+	if !svc.ac.CanCreateGroupChannel(svc.ctx) {
+		return 0, ErrNoPermissions.withStack()
+	}
+
+	if ID, err := svc.repository.FindByMembers(members, 0); err != nil || ID > 0 {
+		return ID, err
+	}
+
+	var (
+		ch = &messagingTypes.Channel{
+			Name:             in.Name,
+			Topic:            in.Topic,
+			Type:             messagingTypes.ChannelTypeGroup,
+			MembershipPolicy: messagingTypes.ChannelMembershipPolicyDefault,
+			OrganisationID:   organization.Corteza().ID,
+			CreatorID:        userID,
+		}
+
+		msgs messagingTypes.MessageSet
+		db   = svc.repository.db()
+	)
+
+	err := db.Transaction(func() (err error) {
+		if ch, err = messagingRepository.Channel(svc.ctx, db).Create(ch); err != nil {
+			return err
+		}
+
+		for _, ID := range members {
+			typ := messagingTypes.ChannelMembershipTypeMember
+			if ID == userID {
+				typ = messagingTypes.ChannelMembershipTypeOwner
+			}
+
+			if err = svc.addMember(db, ch.ID, ID, typ); err != nil {
+				return err
+			}
+		}
+
+		msg, err := svc.systemMessage(db, ch.ID, "<@%d> started a group with %s", userID, mentions(members[1:]))
+		msgs = append(msgs, msg)
+		return err
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	ev := messagingService.Event(svc.ctx)
+	for _, ID := range members {
+		_ = ev.Join(ID, ch.ID)
+	}
+
+	svc.sent(msgs)
+
+	svc.log(zap.Uint64("channelID", ch.ID), zap.Int("members", len(members))).Debug("group created")
+	return ch.ID, nil
+}
+
+// add adds users to the group
+//
+// Any member of the group can add users; the group can not grow over
+// the limit or end up with the same members as another group.
+func (svc service) add(ch *messagingTypes.Channel, userIDs []uint64) (messagingTypes.ChannelMemberSet, error) {
+	if err := validMembers(userIDs); err != nil {
+		return nil, err
+	}
+
+	var (
+		userID = auth.GetIdentityFromContext(svc.ctx).Identity()
+		db     = svc.repository.db()
+		cmr    = messagingRepository.ChannelMember(svc.ctx, db)
+	)
+
+	existing, err := cmr.Find(messagingTypes.ChannelMemberFilterChannels(ch.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if !isMember(existing, userID) || !svc.ac.CanCreateGroupChannel(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	var (
+		out   = messagingTypes.ChannelMemberSet{}
+		added []uint64
+		msgs  messagingTypes.MessageSet
+	)
+
+	for _, ID := range unique(userIDs) {
+		if m := existing.FindByUserID(ID); m != nil {
+			out = append(out, m)
+		} else {
+			added = append(added, ID)
+		}
+	}
+
+	if len(added) == 0 {
+		return out, nil
+	}
+
+	members := append(existing.AllMemberIDs(), added...)
+	if err = svc.distinct(ch.ID, members); err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func() (err error) {
+		for _, ID := range added {
+			if err = svc.addMember(db, ch.ID, ID, messagingTypes.ChannelMembershipTypeMember); err != nil {
+				return err
+			}
+
+			out = append(out, &messagingTypes.ChannelMember{
+				ChannelID: ch.ID,
+				UserID:    ID,
+				Type:      messagingTypes.ChannelMembershipTypeMember,
+			})
+		}
+
+		msg, err := svc.systemMessage(db, ch.ID, "<@%d> added %s to the group", userID, mentions(added))
+		msgs = append(msgs, msg)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	ev := messagingService.Event(svc.ctx)
+	for _, ID := range added {
+		_ = ev.Join(ID, ch.ID)
+	}
+
+	svc.sent(msgs)
+	return out, nil
+}
+
+// remove removes users from the group
+//
+// Members can leave the group, only its owner can remove others. Groups
+// keep at least two members; when the owner leaves, the longest standing
+// member takes over.
+func (svc service) remove(ch *messagingTypes.Channel, userIDs []uint64) error {
+	var (
+		userID = auth.GetIdentityFromContext(svc.ctx).Identity()
+		db     = svc.repository.db()
+		cmr    = messagingRepository.ChannelMember(svc.ctx, db)
+	)
+
+	existing, err := cmr.Find(messagingTypes.ChannelMemberFilterChannels(ch.ID))
+	if err != nil {
+		return err
+	}
+
+	var (
+		removed []uint64
+		others  bool
+		left    = messagingTypes.ChannelMemberSet{}
+		msgs    messagingTypes.MessageSet
+		remove  = map[uint64]bool{}
+	)
+
+	for _, ID := range userIDs {
+		remove[ID] = true
+	}
+
+	for _, m := range existing {
+		if !remove[m.UserID] {
+			left = append(left, m)
+			continue
+		}
+
+		removed = append(removed, m.UserID)
+		if m.UserID == userID {
+			if !svc.ac.CanLeaveChannel(svc.ctx, ch) {
+				return ErrNoPermissions.withStack()
+			}
+		} else {
+			others = true
+		}
+	}
+
+	if len(removed) == 0 {
+		return nil
+	}
+
+	if me := existing.FindByUserID(userID); others && (me == nil || me.Type != messagingTypes.ChannelMembershipTypeOwner) {
+		return ErrNoPermissions.withStack()
+	}
+
+	if len(left) < 2 {
+		return ErrTooFewMembers.withStack()
+	}
+
+	if others {
+		if err = svc.distinct(ch.ID, left.AllMemberIDs()); err != nil {
+			return err
+		}
+	}
+
+	err = db.Transaction(func() (err error) {
+		cmr := messagingRepository.ChannelMember(svc.ctx, db)
+
+		for _, ID := range removed {
+			if err = cmr.Delete(ch.ID, ID); err != nil {
+				return err
+			}
+		}
+
+		if owner(left) == nil {
+			heir := oldest(left)
+			heir.Type = messagingTypes.ChannelMembershipTypeOwner
+			if _, err = cmr.Update(heir); err != nil {
+				return err
+			}
+		}
+
+		for _, ID := range removed {
+			var msg *messagingTypes.Message
+			if ID == userID {
+				msg, err = svc.systemMessage(db, ch.ID, "<@%d> left the group", ID)
+			} else {
+				msg, err = svc.systemMessage(db, ch.ID, "<@%d> removed <@%d> from the group", userID, ID)
+			}
+
+			if err != nil {
+				return err
+			}
+
+			msgs = append(msgs, msg)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	ev := messagingService.Event(svc.ctx)
+	for _, ID := range removed {
+		_ = ev.Part(ID, ch.ID)
+	}
+
+	svc.sent(msgs)
+	return nil
+}
+
+// distinct checks that no other group has the same members
+func (svc service) distinct(channelID uint64, members []uint64) error {
+	if err := sized(len(members)); err != nil {
+		return err
+	}
+
+	ID, err := svc.repository.FindByMembers(members, channelID)
+	if err != nil {
+		return err
+	} else if ID > 0 {
+		return ErrGroupExists.withStack().WithID("channelID", ID)
+	}
+
+	return nil
+}
+
+// addMember creates membership with zero unread messages
+func (svc service) addMember(db *factory.DB, channelID, userID uint64, typ messagingTypes.ChannelMembershipType) error {
+	_, err := messagingRepository.ChannelMember(svc.ctx, db).Create(&messagingTypes.ChannelMember{
+		ChannelID: channelID,
+		UserID:    userID,
+		Type:      typ,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return messagingRepository.Unread(svc.ctx, db).Preset(channelID, 0, userID)
+}
+
+// systemMessage stores a channel event message, the same as corteza's
+// messages about channel changes
+func (svc service) systemMessage(db *factory.DB, channelID uint64, format string, a ...interface{}) (*messagingTypes.Message, error) {
+	return messagingRepository.Message(svc.ctx, db).Create(&messagingTypes.Message{
+		ChannelID: channelID,
+		Message:   fmt.Sprintf(format, a...),
+		Type:      messagingTypes.MessageTypeChannelEvent,
+	})
+}
+
+// sent pushes stored messages to channel's subscribers
+func (svc service) sent(msgs messagingTypes.MessageSet) {
+	ev := messagingService.Event(svc.ctx)
+	for _, msg := range msgs {
+		if err := ev.Message(msg); err != nil {
+			svc.log(zap.Uint64("messageID", msg.ID), zap.Error(err)).Warn("could not send group message event")
+		}
+	}
+}
+
+func sized(n int) error {
+	if n < 2 {
+		return ErrTooFewMembers.withStack()
+	}
+
+	if n > maxMembers {
+		return ErrTooManyMembers.withStack().WithMessage(fmt.Sprintf("groups can have at most %d members", maxMembers))
+	}
+
+	return nil
+}
+
+func validMembers(userIDs []uint64) error {
+	for _, ID := range userIDs {
+		if ID == 0 {
+			return ErrInvalidMember.withStack()
+		}
+	}
+
+	return nil
+}
+
+func isMember(mm messagingTypes.ChannelMemberSet, userID uint64) bool {
+	m := mm.FindByUserID(userID)
+	return m != nil && m.Type != messagingTypes.ChannelMembershipTypeInvitee
+}
+
+func owner(mm messagingTypes.ChannelMemberSet) *messagingTypes.ChannelMember {
+	for _, m := range mm {
+		if m.Type == messagingTypes.ChannelMembershipTypeOwner {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// oldest returns member that joined first
+func oldest(mm messagingTypes.ChannelMemberSet) *messagingTypes.ChannelMember {
+	o := mm[0]
+	for _, m := range mm[1:] {
+		if m.CreatedAt.Before(o.CreatedAt) {
+			o = m
+		}
+	}
+
+	return o
+}
+
+// mentions formats users as a list of mentions
+func mentions(userIDs []uint64) string {
+	out := ""
+	for i, ID := range userIDs {
+		switch {
+		case i == 0:
+		case i == len(userIDs)-1:
+			out += " and "
+		default:
+			out += ", "
+		}
+
+		out += fmt.Sprintf("<@%d>", ID)
+	}
+
+	return out
+}
+
+// unique returns IDs without duplicates, in the same order
+func unique(IDs []uint64) []uint64 {
+	var (
+		out  = make([]uint64, 0, len(IDs))
+		seen = map[uint64]bool{}
+	)
+
+	for _, ID := range IDs {
+		if !seen[ID] {
+			seen[ID] = true
+			out = append(out, ID)
+		}
+	}
+
+	return out
+}
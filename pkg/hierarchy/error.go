@@ -0,0 +1,40 @@
+package hierarchy
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	hierarchyError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNodeNotFound    = hierarchyError{"NodeNotFound", fault.NotFound}
+	ErrInvalidID       = hierarchyError{"InvalidID", fault.Invalid}
+	ErrNotTree         = hierarchyError{"NotTree", fault.Invalid}
+	ErrCycle           = hierarchyError{"Cycle", fault.Invalid}
+	ErrTooDeep         = hierarchyError{"TooDeep", fault.Invalid}
+	ErrInvalidParent   = hierarchyError{"InvalidParent", fault.Invalid}
+	ErrParentNotInTree = hierarchyError{"ParentNotInTree", fault.Invalid}
+	ErrHasChildren     = hierarchyError{"HasChildren", fault.Conflict}
+	ErrNoPermissions   = hierarchyError{"NoPermissions", fault.Forbidden}
+)
+
+func (e hierarchyError) Error() string {
+	return e.String()
+}
+
+func (e hierarchyError) String() string {
+	return "crust.hierarchy." + e.name
+}
+
+func (e hierarchyError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e hierarchyError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,28 @@
+package hierarchy
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200121000000.tree",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_record_tree (
+  rel_record       BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  rel_module       BIGINT UNSIGNED NOT NULL,
+  rel_parent       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  path             VARCHAR(1400)   NOT NULL,
+  depth            INT UNSIGNED    NOT NULL DEFAULT 0,
+  position         INT UNSIGNED    NOT NULL DEFAULT 0,
+
+  PRIMARY KEY (rel_record),
+  INDEX (rel_module, path(255)),
+  INDEX (rel_parent, position)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,188 @@
+package hierarchy
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	// record wraps record service and keeps record tree in sync
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with record tree maintenance
+//
+// Parent is read from module's tree field (see ParentField). Parents must
+// be records of the same module; moves that would create a cycle and
+// deletion of records with children are rejected.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	f, err := svc.parentField(r)
+	if err != nil {
+		return nil, err
+	} else if f == nil {
+		return svc.RecordService.Create(r)
+	}
+
+	parent, err := svc.parent(r, ParentID(f, r))
+	if err != nil {
+		return nil, err
+	}
+
+	if parent != nil && parent.Depth+1 >= maxDepth {
+		return nil, ErrTooDeep.withStack()
+	}
+
+	if r, err = svc.RecordService.Create(r); err != nil {
+		return nil, err
+	}
+
+	path, depth := childOf(parent, r.ID)
+	_, err = svc.repository().Create(&Node{
+		RecordID:    r.ID,
+		NamespaceID: r.NamespaceID,
+		ModuleID:    r.ModuleID,
+		ParentID:    ParentID(f, r),
+		Path:        path,
+		Depth:       depth,
+	})
+
+	return r, err
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	f, err := svc.parentField(r)
+	if err != nil {
+		return nil, err
+	} else if f == nil {
+		return svc.RecordService.Update(r)
+	}
+
+	var (
+		repo     = svc.repository()
+		parentID = ParentID(f, r)
+		height   uint
+	)
+
+	if parentID == r.ID {
+		return nil, ErrCycle.withStack()
+	}
+
+	parent, err := svc.parent(r, parentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parent != nil && parent.HasAncestor(r.ID) {
+		return nil, ErrCycle.withStack()
+	}
+
+	node, err := repo.FindByID(r.ID)
+	if fault.Is(err, ErrNodeNotFound) {
+		node = nil
+	} else if err != nil {
+		return nil, err
+	} else if height, err = repo.Height(node); err != nil {
+		return nil, err
+	}
+
+	if parent != nil && parent.Depth+1+height >= maxDepth {
+		return nil, ErrTooDeep.withStack()
+	}
+
+	if r, err = svc.RecordService.Update(r); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case node == nil:
+		path, depth := childOf(parent, r.ID)
+		_, err = repo.Create(&Node{
+			RecordID:    r.ID,
+			NamespaceID: r.NamespaceID,
+			ModuleID:    r.ModuleID,
+			ParentID:    parentID,
+			Path:        path,
+			Depth:       depth,
+		})
+	case node.ParentID != parentID:
+		err = repo.Move(node, parent)
+	}
+
+	return r, err
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	repo := svc.repository()
+
+	node, err := repo.FindByID(recordID)
+	if fault.Is(err, ErrNodeNotFound) {
+		return svc.RecordService.DeleteByID(namespaceID, recordID)
+	} else if err != nil {
+		return err
+	}
+
+	if node.Children > 0 {
+		return ErrHasChildren.withStack()
+	}
+
+	if err = svc.RecordService.DeleteByID(namespaceID, recordID); err != nil {
+		return err
+	}
+
+	return repo.DeleteByID(recordID)
+}
+
+func (svc record) repository() *repository {
+	return Repository(svc.ctx, nil)
+}
+
+func (svc record) parentField(r *types.Record) (*types.ModuleField, error) {
+	m, err := service.DefaultModule.With(svc.ctx).FindByID(r.NamespaceID, r.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParentField(m), nil
+}
+
+// parent returns node of the parent record or nil for root records
+func (svc record) parent(r *types.Record, parentID uint64) (*Node, error) {
+	if parentID == 0 {
+		return nil, nil
+	}
+
+	n, err := svc.repository().FindByID(parentID)
+	if fault.Is(err, ErrNodeNotFound) {
+		// Parent exists but was created before the tree was (re)built
+		if p, err := svc.RecordService.FindByID(r.NamespaceID, parentID); err == nil && p.ModuleID == r.ModuleID {
+			return nil, ErrParentNotInTree.withStack()
+		}
+
+		return nil, ErrInvalidParent.withStack()
+	} else if err != nil {
+		return nil, err
+	}
+
+	if n.ModuleID != r.ModuleID {
+		return nil, ErrInvalidParent.withStack()
+	}
+
+	return n, nil
+}
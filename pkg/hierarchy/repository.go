@@ -0,0 +1,286 @@
+package hierarchy
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+
+	// link is a record and its parent, as stored in record values
+	link struct {
+		RecordID uint64 `db:"id"`
+		ParentID uint64 `db:"parent"`
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_record_tree"
+}
+
+func (r repository) columns() []string {
+	return []string{
+		"rel_record",
+		"rel_namespace",
+		"rel_module",
+		"rel_parent",
+		"path",
+		"depth",
+		"position",
+	}
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(r.columns()...).
+		From(r.table())
+}
+
+func (r repository) FindByID(recordID uint64) (*Node, error) {
+	var (
+		n = &Node{}
+		q = r.query().Where(squirrel.Eq{"rel_record": recordID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, n); err != nil {
+		return nil, err
+	} else if n.RecordID == 0 {
+		return nil, ErrNodeNotFound
+	}
+
+	return n, r.loadChildren(n)
+}
+
+func (r repository) FindByIDs(recordIDs ...uint64) (set NodeSet, err error) {
+	if len(recordIDs) == 0 {
+		return NodeSet{}, nil
+	}
+
+	q := r.query().Where(squirrel.Eq{"rel_record": recordIDs})
+	if err = rh.FetchAll(r.db(), q, &set); err != nil {
+		return nil, err
+	}
+
+	return set, r.loadChildren(set...)
+}
+
+// Subtree returns all nodes under the root (including the root) or all nodes
+// of the module when root is nil
+//
+// Depth limits number of levels under the root (0 for no limit)
+func (r repository) Subtree(moduleID uint64, root *Node, depth uint) (set NodeSet, err error) {
+	q := r.query().Where(squirrel.Eq{"rel_module": moduleID})
+
+	if root != nil {
+		q = q.Where(squirrel.Like{"path": root.Path + "%"})
+		if depth > 0 {
+			q = q.Where(squirrel.LtOrEq{"depth": root.Depth + depth})
+		}
+	} else if depth > 0 {
+		q = q.Where(squirrel.Lt{"depth": depth})
+	}
+
+	if err = rh.FetchAll(r.db(), q, &set); err != nil {
+		return nil, err
+	}
+
+	return set, r.loadChildren(set...)
+}
+
+// Height returns number of levels under the node
+func (r repository) Height(n *Node) (uint, error) {
+	var (
+		out = struct {
+			Depth uint `db:"depth"`
+		}{}
+
+		q = squirrel.
+			Select("COALESCE(MAX(depth), 0) AS depth").
+			From(r.table()).
+			Where(squirrel.Like{"path": n.Path + "%"})
+	)
+
+	if err := rh.FetchOne(r.db(), q, &out); err != nil {
+		return 0, err
+	}
+
+	return out.Depth - n.Depth, nil
+}
+
+// Create adds node as the last child of its parent
+func (r repository) Create(n *Node) (*Node, error) {
+	var err error
+	if n.Position, err = r.nextPosition(n.ModuleID, n.ParentID); err != nil {
+		return nil, err
+	}
+
+	if err = r.db().Insert(r.table(), n); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return n, nil
+}
+
+// Move moves node with its subtree under a new parent (nil for root)
+//
+// Node is placed as the last child of the new parent
+func (r repository) Move(n *Node, parent *Node) (err error) {
+	var (
+		path, depth = childOf(parent, n.RecordID)
+		position    uint
+	)
+
+	if parent != nil {
+		n.ParentID = parent.RecordID
+	} else {
+		n.ParentID = 0
+	}
+
+	if position, err = r.nextPosition(n.ModuleID, n.ParentID); err != nil {
+		return
+	}
+
+	return r.db().Transaction(func() (err error) {
+		// Rewrite path prefix and depth of the whole subtree (MySQL's SUBSTRING is 1-based)
+		_, err = r.db().Exec(
+			"UPDATE "+r.table()+" SET path = CONCAT(?, SUBSTRING(path, ?)), depth = depth + ? - ? WHERE rel_module = ? AND path LIKE ?",
+			path, len(n.Path)+1, depth, n.Depth, n.ModuleID, n.Path+"%",
+		)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		n.Path, n.Depth, n.Position = path, depth, position
+
+		return rh.UpdateColumns(r.db(), r.table(), rh.Set{
+			"rel_parent": n.ParentID,
+			"position":   n.Position,
+		}, squirrel.Eq{"rel_record": n.RecordID})
+	})
+}
+
+// Reorder places node at the given position among its siblings
+func (r repository) Reorder(n *Node, position uint) error {
+	return r.db().Transaction(func() (err error) {
+		_, err = r.db().Exec(
+			"UPDATE "+r.table()+" SET position = position + 1 WHERE rel_module = ? AND rel_parent = ? AND position >= ? AND rel_record <> ?",
+			n.ModuleID, n.ParentID, position, n.RecordID,
+		)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		n.Position = position
+		return rh.UpdateColumns(r.db(), r.table(), rh.Set{"position": position}, squirrel.Eq{"rel_record": n.RecordID})
+	})
+}
+
+func (r repository) DeleteByID(recordID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_record": recordID})
+}
+
+// Links returns all records of the module with IDs of their parents
+func (r repository) Links(moduleID uint64, field string) (ll []*link, err error) {
+	q := squirrel.
+		Select("r.id", "COALESCE(v.ref, 0) AS parent").
+		From("compose_record AS r").
+		LeftJoin("compose_record_value AS v ON (v.record_id = r.id AND v.name = ? AND v.deleted_at IS NULL)", field).
+		Where(squirrel.Eq{"r.module_id": moduleID, "r.deleted_at": nil}).
+		OrderBy("r.id")
+
+	return ll, rh.FetchAll(r.db(), q, &ll)
+}
+
+// Replace replaces all nodes of the module
+func (r repository) Replace(moduleID uint64, set NodeSet) error {
+	return r.db().Transaction(func() error {
+		if err := rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_module": moduleID}); err != nil {
+			return err
+		}
+
+		for _, n := range set {
+			if err := r.db().Insert(r.table(), n); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r repository) nextPosition(moduleID, parentID uint64) (uint, error) {
+	var (
+		out = struct {
+			Position uint `db:"position"`
+		}{}
+
+		q = squirrel.
+			Select("COALESCE(MAX(position), 0) AS position").
+			From(r.table()).
+			Where(squirrel.Eq{"rel_module": moduleID, "rel_parent": parentID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, &out); err != nil {
+		return 0, err
+	}
+
+	return out.Position + 1, nil
+}
+
+func (r repository) loadChildren(nn ...*Node) error {
+	if len(nn) == 0 {
+		return nil
+	}
+
+	var (
+		set = NodeSet(nn)
+		cc  = make([]*struct {
+			ParentID uint64 `db:"rel_parent"`
+			Count    uint   `db:"children"`
+		}, 0)
+
+		q = squirrel.
+			Select("rel_parent", "COUNT(*) AS children").
+			From(r.table()).
+			Where(squirrel.Eq{"rel_parent": set.IDs()}).
+			GroupBy("rel_parent")
+	)
+
+	if err := rh.FetchAll(r.db(), q, &cc); err != nil {
+		return err
+	}
+
+	for _, c := range cc {
+		if n := set.FindByID(c.ParentID); n != nil {
+			n.Children = c.Count
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package hierarchy
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+type (
+	movePayload struct {
+		ParentID uint64 `json:"parentID,string"`
+		Position uint   `json:"position"`
+	}
+)
+
+// MountRoutes mounts record tree endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {moduleID} params
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?rootID=123&depth=2
+	r.Get("/", rest.Handler("RecordTree.Subtree", func(r *http.Request) (interface{}, error) {
+		return DefaultTree.With(r.Context()).Subtree(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+			rest.QueryUint64(r, "rootID"),
+			rest.QueryUint(r, "depth"),
+		)
+	}))
+
+	r.Post("/rebuild", rest.Handler("RecordTree.Rebuild", func(r *http.Request) (interface{}, error) {
+		return DefaultTree.With(r.Context()).Rebuild(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+		)
+	}))
+
+	r.Get("/{recordID}/ancestors", rest.Handler("RecordTree.Ancestors", func(r *http.Request) (interface{}, error) {
+		return DefaultTree.With(r.Context()).Ancestors(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recordID"),
+		)
+	}))
+
+	r.Post("/{recordID}/move", rest.Handler("RecordTree.Move", func(r *http.Request) (interface{}, error) {
+		p := &movePayload{}
+		if err := rest.Decode(r, p); err != nil {
+			return nil, err
+		}
+
+		return DefaultTree.With(r.Context()).Move(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recordID"),
+			p.ParentID,
+			p.Position,
+		)
+	}))
+}
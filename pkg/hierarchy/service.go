@@ -0,0 +1,296 @@
+package hierarchy
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	treeService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		module service.ModuleService
+		record service.RecordService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanReadRecord(context.Context, *types.Module) bool
+		CanUpdateModule(context.Context, *types.Module) bool
+	}
+
+	TreeService interface {
+		With(ctx context.Context) TreeService
+
+		Nodes(recordIDs ...uint64) (NodeSet, error)
+		Subtree(namespaceID, moduleID, rootID uint64, depth uint) (NodeSet, error)
+		Ancestors(namespaceID, recordID uint64) (NodeSet, error)
+
+		Move(namespaceID, recordID, parentID uint64, position uint) (*Node, error)
+		Rebuild(namespaceID, moduleID uint64) (NodeSet, error)
+	}
+)
+
+var (
+	DefaultTree TreeService
+)
+
+// Init initializes tree service and decorates compose's record service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	DefaultTree = (&treeService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		module: service.DefaultModule,
+		record: service.DefaultRecord,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc treeService) With(ctx context.Context) TreeService {
+	return &treeService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		module: svc.module.With(ctx),
+		record: svc.record.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc treeService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Nodes returns tree nodes of the given records
+//
+// Access is not checked; caller is expected to return only
+// nodes of records that were already loaded
+func (svc treeService) Nodes(recordIDs ...uint64) (NodeSet, error) {
+	return svc.repository.FindByIDs(recordIDs...)
+}
+
+// Subtree returns nodes under the root record (or the whole tree when rootID is 0)
+// in depth-first order
+func (svc treeService) Subtree(namespaceID, moduleID, rootID uint64, depth uint) (NodeSet, error) {
+	m, err := svc.treeModule(namespaceID, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanReadRecord(svc.ctx, m) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	var root *Node
+	if rootID > 0 {
+		if root, err = svc.repository.FindByID(rootID); err != nil {
+			return nil, err
+		} else if root.ModuleID != m.ID {
+			return nil, ErrNodeNotFound.withStack()
+		}
+	}
+
+	set, err := svc.repository.Subtree(m.ID, root, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return set.Ordered(), nil
+}
+
+// Ancestors returns nodes of all ancestors of the record, from the root down
+func (svc treeService) Ancestors(namespaceID, recordID uint64) (NodeSet, error) {
+	if recordID == 0 {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	// Verifies if current user can read the record
+	if _, err := svc.record.FindByID(namespaceID, recordID); err != nil {
+		return nil, err
+	}
+
+	n, err := svc.repository.FindByID(recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.FindByIDs(n.AncestorIDs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	return set.Ordered(), nil
+}
+
+// Move moves record with its subtree under a new parent (0 for root)
+//
+// Parent is changed through the record service, so all record permissions
+// and validations apply. Record is placed at the given position among its
+// new siblings (0 places it after the last one).
+func (svc treeService) Move(namespaceID, recordID, parentID uint64, position uint) (*Node, error) {
+	r, err := svc.record.FindByID(namespaceID, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := svc.treeModule(namespaceID, r.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	f := ParentField(m)
+	if ParentID(f, r) != parentID {
+		vv := types.RecordValueSet{}
+		for _, v := range r.Values {
+			if v.Name != f.Name {
+				vv = append(vv, v)
+			}
+		}
+
+		if parentID > 0 {
+			vv = append(vv, &types.RecordValue{Name: f.Name, Value: strconv.FormatUint(parentID, 10)})
+		}
+
+		r.Values = vv
+		if _, err = svc.record.Update(r); err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := svc.repository.FindByID(recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	if position > 0 && position != n.Position {
+		if err = svc.repository.Reorder(n, position); err != nil {
+			return nil, err
+		}
+	}
+
+	svc.log(zap.Uint64("recordID", recordID), zap.Uint64("parentID", parentID)).
+		Info("record moved")
+
+	return n, nil
+}
+
+// Rebuild recreates module's tree from parent field values
+//
+// Used when tree field is added to a module with existing records. Records
+// with missing parents or parents that form a cycle become roots.
+func (svc treeService) Rebuild(namespaceID, moduleID uint64) (NodeSet, error) {
+	m, err := svc.treeModule(namespaceID, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	ll, err := svc.repository.Links(m.ID, ParentField(m).Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		parents   = map[uint64]uint64{}
+		nodes     = map[uint64]*Node{}
+		positions = map[uint64]uint{}
+		visiting  = map[uint64]bool{}
+
+		place func(uint64) *Node
+	)
+
+	for _, l := range ll {
+		parents[l.RecordID] = l.ParentID
+	}
+
+	place = func(recordID uint64) *Node {
+		if n, ok := nodes[recordID]; ok {
+			return n
+		}
+
+		var parent *Node
+		if parentID, exists := parents[recordID]; parentID > 0 && exists && !visiting[parentID] {
+			if _, exists = parents[parentID]; exists {
+				visiting[recordID] = true
+				parent = place(parentID)
+				delete(visiting, recordID)
+			}
+		}
+
+		if parent != nil && parent.Depth+1 >= maxDepth {
+			parent = nil
+		}
+
+		n := &Node{RecordID: recordID, NamespaceID: namespaceID, ModuleID: m.ID}
+		if parent != nil {
+			n.ParentID = parent.RecordID
+		}
+
+		n.Path, n.Depth = childOf(parent, recordID)
+		nodes[recordID] = n
+		return n
+	}
+
+	set := make(NodeSet, 0, len(ll))
+	for _, l := range ll {
+		n := place(l.RecordID)
+		set = append(set, n)
+	}
+
+	// Positions follow record creation order (links are ordered by ID)
+	for _, n := range set {
+		positions[n.ParentID]++
+		n.Position = positions[n.ParentID]
+	}
+
+	for _, n := range set {
+		if n.ParentID > 0 {
+			nodes[n.ParentID].Children++
+		}
+	}
+
+	if err = svc.repository.Replace(m.ID, set); err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("moduleID", m.ID), zap.Int("nodes", len(set))).
+		Info("record tree rebuilt")
+
+	return set.Ordered(), nil
+}
+
+func (svc treeService) treeModule(namespaceID, moduleID uint64) (*types.Module, error) {
+	m, err := svc.module.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ParentField(m) == nil {
+		return nil, ErrNotTree.withStack()
+	}
+
+	return m, nil
+}
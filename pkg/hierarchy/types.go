@@ -0,0 +1,169 @@
+package hierarchy
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Node is position of the record in the module's tree
+	//
+	// Path lists IDs of all ancestors and of the record itself ("/12/34/56/"),
+	// so the whole subtree can be fetched with a single prefix query and
+	// cycles can be detected without walking the tree.
+	Node struct {
+		RecordID    uint64 `json:"recordID,string" db:"rel_record"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+		ParentID    uint64 `json:"parentID,string" db:"rel_parent"`
+		Path        string `json:"path" db:"path"`
+		Depth       uint   `json:"depth" db:"depth"`
+		Position    uint   `json:"position" db:"position"`
+
+		// Number of direct children
+		Children uint `json:"children" db:"-"`
+	}
+
+	NodeSet []*Node
+)
+
+const (
+	// FieldOption marks self-referencing record field that holds parent record
+	//
+	// Field must be of kind Record with moduleID option set to the ID
+	// of its own module and "tree" option set to true
+	FieldOption = "tree"
+
+	// Max depth of the tree; keeps paths within the column size
+	maxDepth = 64
+)
+
+// ParentField returns field that holds parent record or nil if module is not a tree
+func ParentField(m *types.Module) *types.ModuleField {
+	if m == nil {
+		return nil
+	}
+
+	for _, f := range m.Fields {
+		if f.Kind != "Record" || f.Multi || f.Options == nil || f.Options[FieldOption] != true {
+			continue
+		}
+
+		var moduleID uint64
+		switch v := f.Options["moduleID"].(type) {
+		case string:
+			fmt.Sscan(v, &moduleID)
+		case float64:
+			moduleID = uint64(v)
+		}
+
+		if moduleID == m.ID {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// ParentID returns ID of the parent record from record values
+func ParentID(f *types.ModuleField, r *types.Record) uint64 {
+	if f == nil || r == nil {
+		return 0
+	}
+
+	for _, v := range r.Values.FilterByName(f.Name) {
+		if id, err := strconv.ParseUint(v.Value, 10, 64); err == nil {
+			return id
+		}
+	}
+
+	return 0
+}
+
+// childOf returns path and depth of the record placed under the parent (nil for roots)
+func childOf(parent *Node, recordID uint64) (string, uint) {
+	if parent == nil {
+		return fmt.Sprintf("/%d/", recordID), 0
+	}
+
+	return fmt.Sprintf("%s%d/", parent.Path, recordID), parent.Depth + 1
+}
+
+// HasAncestor returns true if record is the node itself or one of its ancestors
+func (n Node) HasAncestor(recordID uint64) bool {
+	return strings.Contains(n.Path, fmt.Sprintf("/%d/", recordID))
+}
+
+// AncestorIDs returns IDs of all ancestors, from the root down
+func (n Node) AncestorIDs() (ids []uint64) {
+	pp := strings.Split(strings.Trim(n.Path, "/"), "/")
+	for _, p := range pp[:len(pp)-1] {
+		if id, err := strconv.ParseUint(p, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return
+}
+
+func (set NodeSet) FindByID(recordID uint64) *Node {
+	for i := range set {
+		if set[i].RecordID == recordID {
+			return set[i]
+		}
+	}
+
+	return nil
+}
+
+func (set NodeSet) IDs() (ids []uint64) {
+	ids = make([]uint64, len(set))
+	for i := range set {
+		ids[i] = set[i].RecordID
+	}
+
+	return
+}
+
+// Ordered returns nodes in depth-first order with siblings ordered by position
+//
+// Nodes whose parents are not in the set are treated as roots
+func (set NodeSet) Ordered() NodeSet {
+	var (
+		out      = make(NodeSet, 0, len(set))
+		children = map[uint64]NodeSet{}
+		roots    = NodeSet{}
+
+		walk func(NodeSet)
+	)
+
+	for _, n := range set {
+		if n.ParentID > 0 && set.FindByID(n.ParentID) != nil {
+			children[n.ParentID] = append(children[n.ParentID], n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+
+	walk = func(nn NodeSet) {
+		sort.SliceStable(nn, func(i, j int) bool {
+			if nn[i].Position == nn[j].Position {
+				return nn[i].RecordID < nn[j].RecordID
+			}
+
+			return nn[i].Position < nn[j].Position
+		})
+
+		for _, n := range nn {
+			out = append(out, n)
+			walk(children[n.RecordID])
+		}
+	}
+
+	walk(roots)
+	return out
+}
@@ -0,0 +1,50 @@
+package httpaction
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	httpactionError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNameRequired      = httpactionError{"NameRequired", fault.Invalid}
+	ErrInvalidMethod     = httpactionError{"InvalidMethod", fault.Invalid}
+	ErrInvalidURL        = httpactionError{"InvalidURL", fault.Invalid}
+	ErrInvalidTemplate   = httpactionError{"InvalidTemplate", fault.Invalid}
+	ErrInvalidHeader     = httpactionError{"InvalidHeader", fault.Invalid}
+	ErrInvalidMapping    = httpactionError{"InvalidMapping", fault.Invalid}
+	ErrInvalidEvent      = httpactionError{"InvalidEvent", fault.Invalid}
+	ErrInvalidTimeout    = httpactionError{"InvalidTimeout", fault.Invalid}
+	ErrInvalidCACert     = httpactionError{"InvalidCACert", fault.Invalid}
+	ErrInvalidSecretName = httpactionError{"InvalidSecretName", fault.Invalid}
+	ErrSecretNameTaken   = httpactionError{"SecretNameTaken", fault.Conflict}
+	ErrSecretRequired    = httpactionError{"SecretValueRequired", fault.Invalid}
+	ErrUnexpectedStatus  = httpactionError{"UnexpectedStatus", fault.Unavailable}
+	ErrInvalidResponse   = httpactionError{"InvalidResponse", fault.Unavailable}
+	ErrActionDisabled    = httpactionError{"ActionDisabled", fault.Conflict}
+	ErrInvalidRecord     = httpactionError{"InvalidRecord", fault.Invalid}
+	ErrActionNotFound    = httpactionError{"ActionNotFound", fault.NotFound}
+	ErrSecretNotFound    = httpactionError{"SecretNotFound", fault.NotFound}
+	ErrNoPermissions     = httpactionError{"NoPermissions", fault.Forbidden}
+)
+
+func (e httpactionError) Error() string {
+	return e.String()
+}
+
+func (e httpactionError) String() string {
+	return "crust.httpaction." + e.name
+}
+
+func (e httpactionError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e httpactionError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,60 @@
+package httpaction
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200209000000.httpaction",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_http_action (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  method             VARCHAR(8)      NOT NULL,
+  url                TEXT            NOT NULL,
+  headers            TEXT            NOT NULL,
+  body               MEDIUMTEXT      NOT NULL,
+  mapping            TEXT            NOT NULL,
+  events             TEXT            NOT NULL,
+  timeout_sec        INT UNSIGNED    NOT NULL DEFAULT 0,
+  tls_skip_verify    BOOLEAN         NOT NULL DEFAULT FALSE,
+  tls_ca_cert        TEXT            NOT NULL,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  last_status        INT             NOT NULL DEFAULT 0,
+  last_error         TEXT            NOT NULL,
+  last_run_at        DATETIME            NULL DEFAULT NULL,
+
+  owned_by           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (rel_module)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_http_secret (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  description        TEXT            NOT NULL,
+  value              TEXT            NOT NULL,
+
+  created_by         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
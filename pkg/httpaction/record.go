@@ -0,0 +1,70 @@
+package httpaction
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// record wraps record service and runs actions after records are changed
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with actions that run on record events
+//
+// Actions run in the background after the change is stored; failures do not
+// affect the change. Changes made by actions (response mapping) do not run
+// actions again.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	r, err := svc.RecordService.Create(r)
+	if err == nil {
+		svc.fire(EventCreate, r)
+	}
+
+	return r, err
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	r, err := svc.RecordService.Update(r)
+	if err == nil {
+		svc.fire(EventUpdate, r)
+	}
+
+	return r, err
+}
+
+func (svc record) fire(event string, r *types.Record) {
+	if svc.ctx.Value(runKey{}) != nil {
+		return
+	}
+
+	set, err := Repository(svc.ctx, nil).FindEnabledActions(r.ModuleID)
+	if err != nil {
+		defaultHttpAction.log(zap.Uint64("moduleID", r.ModuleID), zap.Error(err)).Error("could not load actions")
+		return
+	}
+
+	for _, a := range set {
+		if a.Events.has(event) {
+			go defaultHttpAction.fire(a, r.ID)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package httpaction
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableAction() string {
+	return "crust_compose_http_action"
+}
+
+func (r repository) tableSecret() string {
+	return "crust_compose_http_secret"
+}
+
+func (r repository) queryActions() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_module",
+			"name",
+			"method",
+			"url",
+			"headers",
+			"body",
+			"mapping",
+			"events",
+			"timeout_sec",
+			"tls_skip_verify",
+			"tls_ca_cert",
+			"enabled",
+			"last_status",
+			"last_error",
+			"last_run_at",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableAction()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) querySecrets() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"name",
+			"description",
+			"value",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableSecret()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindActionByID(namespaceID, actionID uint64) (*Action, error) {
+	var (
+		a = &Action{}
+		q = r.queryActions().Where(squirrel.Eq{"id": actionID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, a); err != nil {
+		return nil, err
+	} else if a.ID == 0 {
+		return nil, ErrActionNotFound.withStack().WithID("namespaceID", namespaceID).WithID("actionID", actionID)
+	}
+
+	return a, nil
+}
+
+func (r repository) FindActions(namespaceID uint64) (set ActionSet, err error) {
+	q := r.queryActions().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindEnabledActions returns enabled actions of the module
+func (r repository) FindEnabledActions(moduleID uint64) (set ActionSet, err error) {
+	q := r.queryActions().
+		Where(squirrel.Eq{"rel_module": moduleID, "enabled": true})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateAction(a *Action) (*Action, error) {
+	a.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&a.CreatedAt)
+
+	return a, errors.WithStack(r.db().Insert(r.tableAction(), a))
+}
+
+func (r repository) UpdateAction(a *Action) (*Action, error) {
+	rh.SetCurrentTimeRounded(&a.UpdatedAt)
+
+	return a, errors.WithStack(r.db().Replace(r.tableAction(), a))
+}
+
+// UpdateActionState stores outcome of the last run without touching the rest of the action
+func (r repository) UpdateActionState(a *Action) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableAction(),
+		rh.Set{
+			"last_status": a.LastStatus,
+			"last_error":  a.LastError,
+			"last_run_at": a.LastRunAt,
+		},
+		squirrel.Eq{"id": a.ID},
+	)
+}
+
+func (r repository) DeleteActionByID(namespaceID, actionID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableAction(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": actionID, "rel_namespace": namespaceID},
+	)
+}
+
+func (r repository) FindSecretByID(namespaceID, secretID uint64) (*Secret, error) {
+	var (
+		s = &Secret{}
+		q = r.querySecrets().Where(squirrel.Eq{"id": secretID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.ID == 0 {
+		return nil, ErrSecretNotFound.withStack().WithID("namespaceID", namespaceID).WithID("secretID", secretID)
+	}
+
+	return s, nil
+}
+
+// FindSecrets returns secrets of the namespace, values included
+func (r repository) FindSecrets(namespaceID uint64) (set SecretSet, err error) {
+	q := r.querySecrets().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateSecret(s *Secret) (*Secret, error) {
+	s.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&s.CreatedAt)
+
+	return s, errors.WithStack(r.db().Insert(r.tableSecret(), s))
+}
+
+func (r repository) UpdateSecret(s *Secret) (*Secret, error) {
+	rh.SetCurrentTimeRounded(&s.UpdatedAt)
+
+	return s, errors.WithStack(r.db().Replace(r.tableSecret(), s))
+}
+
+func (r repository) DeleteSecretByID(namespaceID, secretID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableSecret(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": secretID, "rel_namespace": namespaceID},
+	)
+}
@@ -0,0 +1,109 @@
+package httpaction
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts action and secret management endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("HttpAction.List", func(r *http.Request) (interface{}, error) {
+		return DefaultHttpAction.With(r.Context()).FindActions(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Post("/", rest.Handler("HttpAction.Create", func(r *http.Request) (interface{}, error) {
+		a := &Action{}
+		if err := rest.Decode(r, a); err != nil {
+			return nil, err
+		}
+
+		a.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultHttpAction.With(r.Context()).CreateAction(a)
+	}))
+
+	r.Route("/secrets", func(r chi.Router) {
+		r.Get("/", rest.Handler("HttpSecret.List", func(r *http.Request) (interface{}, error) {
+			return DefaultHttpAction.With(r.Context()).FindSecrets(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Post("/", rest.Handler("HttpSecret.Create", func(r *http.Request) (interface{}, error) {
+			s := &Secret{}
+			if err := rest.Decode(r, s); err != nil {
+				return nil, err
+			}
+
+			s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultHttpAction.With(r.Context()).CreateSecret(s)
+		}))
+
+		// Value is kept when omitted
+		r.Put("/{secretID}", rest.Handler("HttpSecret.Update", func(r *http.Request) (interface{}, error) {
+			s := &Secret{}
+			if err := rest.Decode(r, s); err != nil {
+				return nil, err
+			}
+
+			s.ID = rest.ParamUint64(r, "secretID")
+			s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultHttpAction.With(r.Context()).UpdateSecret(s)
+		}))
+
+		r.Delete("/{secretID}", rest.Handler("HttpSecret.Delete", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultHttpAction.With(r.Context()).DeleteSecret(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "secretID"),
+			)
+		}))
+	})
+
+	r.Get("/{actionID}", rest.Handler("HttpAction.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultHttpAction.With(r.Context()).FindActionByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "actionID"),
+		)
+	}))
+
+	r.Put("/{actionID}", rest.Handler("HttpAction.Update", func(r *http.Request) (interface{}, error) {
+		a := &Action{}
+		if err := rest.Decode(r, a); err != nil {
+			return nil, err
+		}
+
+		a.ID = rest.ParamUint64(r, "actionID")
+		a.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultHttpAction.With(r.Context()).UpdateAction(a)
+	}))
+
+	r.Delete("/{actionID}", rest.Handler("HttpAction.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultHttpAction.With(r.Context()).DeleteAction(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "actionID"),
+		)
+	}))
+
+	// {"recordID": "..."}; runs with permissions of the current user
+	r.Post("/{actionID}/run", rest.Handler("HttpAction.Run", func(r *http.Request) (interface{}, error) {
+		run := struct {
+			RecordID uint64 `json:"recordID,string"`
+		}{}
+
+		if err := rest.Decode(r, &run); err != nil {
+			return nil, err
+		}
+
+		return DefaultHttpAction.With(r.Context()).Run(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "actionID"),
+			run.RecordID,
+		)
+	}))
+}
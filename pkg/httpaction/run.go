@@ -0,0 +1,324 @@
+package httpaction
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/crusttech/crust-server/pkg/egress"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+type (
+	// runKey marks context of record updates made by actions
+	// so that they do not run actions again
+	runKey struct{}
+)
+
+// prepared holds parsed templates of the action
+type prepared struct {
+	url     *expr.Template
+	headers map[string]*expr.Template
+	body    *expr.Template
+}
+
+func prepare(a *Action) (*prepared, error) {
+	var (
+		p   = &prepared{headers: map[string]*expr.Template{}}
+		err error
+	)
+
+	if p.url, err = parseTemplate(a.URL); err != nil {
+		return nil, err
+	}
+
+	for name, value := range a.Headers {
+		if p.headers[name], err = parseTemplate(value); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.body, err = parseTemplate(a.Body); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// request renders templates into HTTP request
+func (p prepared) request(ctx context.Context, method string, s expr.Scope) (*http.Request, error) {
+	rawURL, err := p.url.Render(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, ErrInvalidURL.withStack()
+	}
+
+	body, err := p.body.Render(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var rb io.Reader
+	if body != "" {
+		rb = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, rawURL, rb)
+	if err != nil {
+		return nil, ErrInvalidURL.withStack()
+	}
+
+	for name, t := range p.headers {
+		value, err := t.Render(s)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set(name, value)
+	}
+
+	return req.WithContext(ctx), nil
+}
+
+// httpClient returns client with action's timeout and TLS settings
+//
+// Addresses are checked after they are resolved, so that host names
+// (and redirects) can not point requests to internal services. Proxies
+// are dialed the same way, internal ones need internal targets allowed.
+func httpClient(a *Action) (*http.Client, error) {
+	var (
+		timeout = a.Timeout
+		tc      = &tls.Config{InsecureSkipVerify: a.SkipVerify}
+	)
+
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	if a.CACert != "" {
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM([]byte(a.CACert)) {
+			return nil, ErrInvalidCACert.withStack()
+		}
+	}
+
+	return &http.Client{
+		Timeout:   time.Duration(timeout) * time.Second,
+		Transport: egress.Transport(tc),
+	}, nil
+}
+
+// scope prepares variables & functions for templates
+//
+// Secrets are available only here; rendered values are never
+// returned to the caller or stored.
+func scope(m *types.Module, r *types.Record, ss SecretSet) expr.Scope {
+	var (
+		s       = expr.Scope{"record": recordScope(m, r)}
+		secrets = expr.Scope{}
+	)
+
+	for _, sec := range ss {
+		secrets[sec.Name] = sec.Value
+	}
+
+	s["secrets"] = secrets
+
+	for name, fn := range templateFuncs {
+		s[name] = fn
+	}
+
+	return s
+}
+
+func recordScope(m *types.Module, r *types.Record) expr.Scope {
+	var (
+		values = expr.Scope{}
+		rs     = expr.Scope{
+			"values":    values,
+			"recordID":  payload.Uint64toa(r.ID),
+			"moduleID":  payload.Uint64toa(r.ModuleID),
+			"ownedBy":   payload.Uint64toa(r.OwnedBy),
+			"createdBy": payload.Uint64toa(r.CreatedBy),
+			"createdAt": r.CreatedAt,
+		}
+	)
+
+	for _, f := range m.Fields {
+		vv := r.Values.FilterByName(f.Name)
+
+		if f.Multi {
+			ss := make([]string, 0, len(vv))
+			for _, v := range vv {
+				ss = append(ss, v.Value)
+			}
+
+			values[f.Name] = ss
+		} else if len(vv) > 0 {
+			values[f.Name] = vv[0].Value
+		}
+	}
+
+	return rs
+}
+
+// send runs the request and maps JSON response into record values
+//
+// Transport errors are reduced to their cause; the URL (that might
+// hold secrets) is not part of the error.
+func send(c *http.Client, req *http.Request, m *types.Module, mm Mapping) (int, types.RecordValueSet, error) {
+	rsp, err := c.Do(req)
+	if err != nil {
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+
+		return 0, nil, errors.Wrap(err, "request failed")
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return rsp.StatusCode, nil, ErrUnexpectedStatus.withStack()
+	}
+
+	if len(mm) == 0 {
+		return rsp.StatusCode, nil, nil
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(rsp.Body, maxResponseSize))
+	if err != nil {
+		return rsp.StatusCode, nil, errors.Wrap(err, "could not read response")
+	}
+
+	var doc interface{}
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return rsp.StatusCode, nil, ErrInvalidResponse.withStack()
+	}
+
+	return rsp.StatusCode, mapValues(m, mm, doc), nil
+}
+
+// mapValues picks values from the decoded response
+//
+// Arrays are stored into multi-value fields (only the first item into
+// single-value fields), objects are stored as JSON. Fields with missing
+// or null values are cleared.
+func mapValues(m *types.Module, mm Mapping, doc interface{}) types.RecordValueSet {
+	var out = types.RecordValueSet{}
+
+	for _, fm := range mm {
+		f := m.Fields.FindByName(fm.Field)
+		if f == nil {
+			continue
+		}
+
+		v, _ := lookup(doc, fm.Path)
+
+		items, ok := v.([]interface{})
+		if !ok {
+			items = []interface{}{v}
+		}
+
+		var place uint
+		for _, item := range items {
+			if item == nil {
+				continue
+			}
+
+			out = append(out, &types.RecordValue{Name: f.Name, Value: valueString(item), Place: place})
+			place++
+
+			if !f.Multi {
+				break
+			}
+		}
+	}
+
+	return out
+}
+
+// lookup returns value at the dot separated path
+func lookup(doc interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return doc, true
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		switch c := doc.(type) {
+		case map[string]interface{}:
+			v, ok := c[key]
+			if !ok {
+				return nil, false
+			}
+
+			doc = v
+
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+
+			doc = c[i]
+
+		default:
+			return nil, false
+		}
+	}
+
+	return doc, true
+}
+
+func valueString(v interface{}) string {
+	switch c := v.(type) {
+	case string:
+		return c
+	case bool:
+		if c {
+			return "1"
+		}
+
+		return "0"
+	case float64:
+		return strconv.FormatFloat(c, 'f', -1, 64)
+	}
+
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// merge replaces values of mapped fields
+func merge(vv types.RecordValueSet, mm Mapping, mapped types.RecordValueSet) types.RecordValueSet {
+	var (
+		out    = types.RecordValueSet{}
+		fields = map[string]bool{}
+	)
+
+	for _, fm := range mm {
+		fields[fm.Field] = true
+	}
+
+	for _, v := range vv {
+		if !fields[v.Name] {
+			out = append(out, v)
+		}
+	}
+
+	return append(out, mapped...)
+}
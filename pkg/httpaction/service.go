@@ -0,0 +1,465 @@
+package httpaction
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	httpActionService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	HttpActionService interface {
+		With(ctx context.Context) HttpActionService
+
+		FindActions(namespaceID uint64) (ActionSet, error)
+		FindActionByID(namespaceID, actionID uint64) (*Action, error)
+		CreateAction(*Action) (*Action, error)
+		UpdateAction(*Action) (*Action, error)
+		DeleteAction(namespaceID, actionID uint64) error
+
+		Run(namespaceID, actionID, recordID uint64) (*Result, error)
+
+		FindSecrets(namespaceID uint64) (SecretSet, error)
+		CreateSecret(*Secret) (*Secret, error)
+		UpdateSecret(*Secret) (*Secret, error)
+		DeleteSecret(namespaceID, secretID uint64) error
+	}
+)
+
+var (
+	DefaultHttpAction HttpActionService
+
+	// defaultHttpAction is used by the record decorator
+	defaultHttpAction *httpActionService
+
+	// now is used for run times and can be overridden
+	now = time.Now
+)
+
+// Init initializes HTTP action service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &httpActionService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+	}
+
+	DefaultHttpAction = svc.With(ctx)
+	defaultHttpAction = svc
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	return nil
+}
+
+func (svc httpActionService) With(ctx context.Context) HttpActionService {
+	return svc.with(ctx)
+}
+
+func (svc httpActionService) with(ctx context.Context) *httpActionService {
+	return &httpActionService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc httpActionService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc httpActionService) FindActions(namespaceID uint64) (ActionSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindActions(namespaceID)
+}
+
+func (svc httpActionService) FindActionByID(namespaceID, actionID uint64) (*Action, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindActionByID(namespaceID, actionID)
+}
+
+// CreateAction stores new action
+//
+// Action is owned by the user that created it, unless set otherwise;
+// actions run by record events run in the name of the owner.
+func (svc httpActionService) CreateAction(in *Action) (*Action, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	a := &Action{NamespaceID: in.NamespaceID, OwnedBy: in.OwnedBy}
+	if a.OwnedBy == 0 {
+		a.OwnedBy = auth.GetIdentityFromContext(svc.ctx).Identity()
+	}
+
+	configure(a, in)
+	return svc.repository.CreateAction(a)
+}
+
+func (svc httpActionService) UpdateAction(upd *Action) (*Action, error) {
+	if err := svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	a, err := svc.repository.FindActionByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if upd.OwnedBy > 0 {
+		a.OwnedBy = upd.OwnedBy
+	}
+
+	configure(a, upd)
+	return svc.repository.UpdateAction(a)
+}
+
+func (svc httpActionService) DeleteAction(namespaceID, actionID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindActionByID(namespaceID, actionID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteActionByID(namespaceID, actionID)
+}
+
+// Run sends action's request for the record
+//
+// Action runs with permissions of the current user; the user must be able
+// to read the record and, when response is mapped, to update it. Response
+// itself is not returned, only the status and mapped values.
+func (svc httpActionService) Run(namespaceID, actionID, recordID uint64) (*Result, error) {
+	a, err := svc.repository.FindActionByID(namespaceID, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.Enabled {
+		return nil, ErrActionDisabled.withStack()
+	}
+
+	return svc.run(a, recordID)
+}
+
+// FindSecrets returns secrets of the namespace, without their values
+func (svc httpActionService) FindSecrets(namespaceID uint64) (SecretSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.FindSecrets(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set {
+		set[i] = set[i].withoutValue()
+	}
+
+	return set, nil
+}
+
+func (svc httpActionService) CreateSecret(in *Secret) (*Secret, error) {
+	if err := svc.validateSecret(in); err != nil {
+		return nil, err
+	}
+
+	if in.Value == "" {
+		return nil, ErrSecretRequired.withStack()
+	}
+
+	s, err := svc.repository.CreateSecret(&Secret{
+		NamespaceID: in.NamespaceID,
+		Name:        in.Name,
+		Description: in.Description,
+		Value:       in.Value,
+		CreatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withoutValue(), nil
+}
+
+// UpdateSecret changes secret's name and description; value is
+// changed only when given
+func (svc httpActionService) UpdateSecret(upd *Secret) (*Secret, error) {
+	if err := svc.validateSecret(upd); err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.FindSecretByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Name = upd.Name
+	s.Description = upd.Description
+	if upd.Value != "" {
+		s.Value = upd.Value
+	}
+
+	if s, err = svc.repository.UpdateSecret(s); err != nil {
+		return nil, err
+	}
+
+	return s.withoutValue(), nil
+}
+
+func (svc httpActionService) DeleteSecret(namespaceID, secretID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindSecretByID(namespaceID, secretID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteSecretByID(namespaceID, secretID)
+}
+
+// run renders and sends the request and writes mapped values into the record
+//
+// Outcome is stored on the action; errors never hold rendered values.
+func (svc httpActionService) run(a *Action, recordID uint64) (res *Result, err error) {
+	var (
+		started = now()
+		rs      = service.DefaultRecord.With(svc.ctx)
+	)
+
+	res = &Result{}
+
+	defer func() {
+		a.LastRunAt = &started
+		a.LastStatus = res.Status
+		a.LastError = ""
+		if err != nil {
+			a.LastError = err.Error()
+		}
+
+		if serr := svc.repository.UpdateActionState(a); serr != nil {
+			svc.log(zap.Uint64("actionID", a.ID), zap.Error(serr)).Error("could not store action state")
+		}
+	}()
+
+	m, err := svc.module.FindByID(a.NamespaceID, a.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := rs.FindByID(a.NamespaceID, recordID)
+	if err != nil {
+		return nil, err
+	} else if r.ModuleID != a.ModuleID {
+		return nil, ErrInvalidRecord.withStack()
+	}
+
+	ss, err := svc.repository.FindSecrets(a.NamespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := prepare(a)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := p.request(svc.ctx, a.Method, scope(m, r, ss))
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := httpClient(a)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Status, res.Values, err = send(c, req, m, a.Mapping)
+	res.Duration = uint(now().Sub(started) / time.Millisecond)
+	if err != nil {
+		return res, err
+	}
+
+	if len(a.Mapping) > 0 {
+		r.Values = merge(r.Values, a.Mapping, res.Values)
+		if _, err = service.DefaultRecord.With(context.WithValue(svc.ctx, runKey{}, true)).Update(r); err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// fire runs action in the background, in the name of action's owner
+func (svc httpActionService) fire(a *Action, recordID uint64) {
+	log := svc.logger.With(zap.Uint64("actionID", a.ID), zap.Uint64("recordID", recordID))
+
+	ctx, err := runas.Compose(context.Background(), a.OwnedBy)
+	if err != nil {
+		log.Error("could not run action", zap.Error(err))
+		return
+	}
+
+	if _, err = svc.with(ctx).run(a, recordID); err != nil {
+		log.Warn("action failed", zap.Error(err))
+	}
+}
+
+func (svc httpActionService) validate(a *Action) error {
+	if err := svc.canManage(a.NamespaceID); err != nil {
+		return err
+	}
+
+	if a.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	a.Method = strings.ToUpper(a.Method)
+	if a.Method == "" {
+		a.Method = http.MethodPost
+	}
+
+	if !methods[a.Method] {
+		return ErrInvalidMethod.withStack()
+	}
+
+	if a.URL == "" {
+		return ErrInvalidURL.withStack()
+	}
+
+	for name, value := range a.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") || strings.ContainsAny(value, "\r\n") {
+			return ErrInvalidHeader.withStack()
+		}
+	}
+
+	if _, err := prepare(a); err != nil {
+		return err
+	}
+
+	m, err := svc.module.FindByID(a.NamespaceID, a.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	for _, fm := range a.Mapping {
+		if fm == nil || m.Fields.FindByName(fm.Field) == nil {
+			return ErrInvalidMapping.withStack()
+		}
+	}
+
+	for _, e := range a.Events {
+		if e != EventCreate && e != EventUpdate {
+			return ErrInvalidEvent.withStack()
+		}
+	}
+
+	if a.Timeout > maxTimeout {
+		return ErrInvalidTimeout.withStack()
+	}
+
+	if a.CACert != "" && !x509.NewCertPool().AppendCertsFromPEM([]byte(a.CACert)) {
+		return ErrInvalidCACert.withStack()
+	}
+
+	return nil
+}
+
+func (svc httpActionService) validateSecret(s *Secret) error {
+	if err := svc.canManage(s.NamespaceID); err != nil {
+		return err
+	}
+
+	if !secretName.MatchString(s.Name) {
+		return ErrInvalidSecretName.withStack()
+	}
+
+	set, err := svc.repository.FindSecrets(s.NamespaceID)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range set {
+		if e.Name == s.Name && e.ID != s.ID {
+			return ErrSecretNameTaken.withStack()
+		}
+	}
+
+	return nil
+}
+
+func (svc httpActionService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// configure copies request settings of the action
+func configure(a, in *Action) {
+	a.ModuleID = in.ModuleID
+	a.Name = in.Name
+	a.Method = in.Method
+	a.URL = in.URL
+	a.Headers = in.Headers
+	a.Body = in.Body
+	a.Mapping = in.Mapping
+	a.Events = in.Events
+	a.Timeout = in.Timeout
+	a.SkipVerify = in.SkipVerify
+	a.CACert = in.CACert
+	a.Enabled = in.Enabled
+}
@@ -0,0 +1,43 @@
+package httpaction
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+// templateFuncs are available in templates for escaping values
+var templateFuncs = expr.Scope{
+	// json("a\"b") => "\"a\\\"b\"", encodes lists as arrays
+	"json": expr.Func(func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, ErrInvalidTemplate.withStack()
+		}
+
+		b, err := json.Marshal(args[0])
+		return string(b), err
+	}),
+
+	"urlquery": expr.Func(func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, ErrInvalidTemplate.withStack()
+		}
+
+		if ss, ok := args[0].([]string); ok {
+			return url.QueryEscape(strings.Join(ss, ",")), nil
+		}
+
+		return url.QueryEscape(expr.String(args[0])), nil
+	}),
+}
+
+func parseTemplate(src string) (*expr.Template, error) {
+	t, err := expr.ParseTemplate(src)
+	if err != nil {
+		return nil, ErrInvalidTemplate.withStack()
+	}
+
+	return t, nil
+}
@@ -0,0 +1,201 @@
+package httpaction
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Action sends HTTP request with record's data and maps the response
+	// back into record's fields
+	//
+	// URL, header values and body are templates with {{ expression }}
+	// placeholders; expressions can access the record (record.values.<field>,
+	// record.recordID, ...), namespace's secrets (secrets.<name>) and
+	// json(value) & urlquery(value) functions for escaping.
+	//
+	// Action runs when requested (by users or scripts, through the API) or
+	// after records of the module are created or updated.
+	Action struct {
+		ID          uint64 `json:"actionID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+		Name        string `json:"name" db:"name"`
+
+		Method  string  `json:"method" db:"method"`
+		URL     string  `json:"url" db:"url"`
+		Headers Headers `json:"headers" db:"headers"`
+		Body    string  `json:"body" db:"body"`
+
+		// Values from JSON response are written to record's fields
+		Mapping Mapping `json:"mapping" db:"mapping"`
+
+		// Record events ("create", "update") that run the action
+		Events Events `json:"events" db:"events"`
+
+		// Request timeout in seconds
+		Timeout    uint   `json:"timeout" db:"timeout_sec"`
+		SkipVerify bool   `json:"tlsSkipVerify" db:"tls_skip_verify"`
+		CACert     string `json:"tlsCACert,omitempty" db:"tls_ca_cert"`
+
+		Enabled bool `json:"enabled" db:"enabled"`
+
+		LastStatus int        `json:"lastStatus,omitempty" db:"last_status"`
+		LastError  string     `json:"lastError,omitempty" db:"last_error"`
+		LastRunAt  *time.Time `json:"lastRunAt,omitempty" db:"last_run_at"`
+
+		// Actions run by record events run in the name of the owner
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	ActionSet []*Action
+
+	// Secret is a credential that actions can use in requests
+	//
+	// Secret's value is never returned.
+	Secret struct {
+		ID          uint64 `json:"secretID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Name        string `json:"name" db:"name"`
+		Description string `json:"description" db:"description"`
+		Value       string `json:"value,omitempty" db:"value"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	SecretSet []*Secret
+
+	// FieldMapping maps value at the path (dot separated keys and array
+	// indexes, "data.items.0.id") of the JSON response to record's field
+	FieldMapping struct {
+		Field string `json:"field"`
+		Path  string `json:"path"`
+	}
+
+	Mapping []*FieldMapping
+
+	Headers map[string]string
+
+	Events []string
+
+	// Result of the action's run
+	Result struct {
+		Status   int                  `json:"status"`
+		Duration uint                 `json:"durationMs"`
+		Values   types.RecordValueSet `json:"values"`
+	}
+)
+
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+
+	defaultTimeout = 10
+	maxTimeout     = 60
+
+	// Only this much of the response is read
+	maxResponseSize = 1 << 20
+)
+
+var (
+	methods = map[string]bool{
+		"GET":    true,
+		"POST":   true,
+		"PUT":    true,
+		"PATCH":  true,
+		"DELETE": true,
+	}
+
+	// Secret names must be usable in expressions
+	secretName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+)
+
+func (ee Events) has(event string) bool {
+	for _, e := range ee {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s Secret) withoutValue() *Secret {
+	s.Value = ""
+	return &s
+}
+
+func (hh Headers) Value() (driver.Value, error) {
+	if hh == nil {
+		hh = Headers{}
+	}
+
+	return json.Marshal(hh)
+}
+
+func (hh *Headers) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*hh = Headers{}
+	case []byte:
+		if err := json.Unmarshal(b, hh); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Headers", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (mm Mapping) Value() (driver.Value, error) {
+	if mm == nil {
+		mm = Mapping{}
+	}
+
+	return json.Marshal(mm)
+}
+
+func (mm *Mapping) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*mm = Mapping{}
+	case []byte:
+		if err := json.Unmarshal(b, mm); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Mapping", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (ee Events) Value() (driver.Value, error) {
+	if ee == nil {
+		ee = Events{}
+	}
+
+	return json.Marshal(ee)
+}
+
+func (ee *Events) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ee = Events{}
+	case []byte:
+		if err := json.Unmarshal(b, ee); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Events", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package images
+
+import (
+	"context"
+	"io"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// attachment wraps attachment service and processes uploaded images
+	attachment struct {
+		messagingService.AttachmentService
+		ctx context.Context
+	}
+)
+
+// Attachment decorates attachment service with processing of uploaded
+// images in the background; other files are created as they were
+func Attachment(as messagingService.AttachmentService) messagingService.AttachmentService {
+	return &attachment{AttachmentService: as, ctx: context.Background()}
+}
+
+func (svc attachment) With(ctx context.Context) messagingService.AttachmentService {
+	return &attachment{
+		AttachmentService: svc.AttachmentService.With(ctx),
+		ctx:               ctx,
+	}
+}
+
+func (svc attachment) Create(name string, size int64, fh io.ReadSeeker, channelID, replyTo uint64) (*messagingTypes.Attachment, error) {
+	if mimetype, ok := detect(fh); ok {
+		return defaultImage.with(svc.ctx).create(name, mimetype, fh, channelID, replyTo)
+	}
+
+	return svc.AttachmentService.Create(name, size, fh, channelID, replyTo)
+}
@@ -0,0 +1,35 @@
+package images
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	imagesError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidImage      = imagesError{"InvalidImage", fault.Invalid}
+	ErrInvalidSizes      = imagesError{"InvalidSizes", fault.Invalid}
+	ErrThumbnailNotFound = imagesError{"ThumbnailNotFound", fault.NotFound}
+	ErrNoPermissions     = imagesError{"NoPermissions", fault.Forbidden}
+)
+
+func (e imagesError) Error() string {
+	return e.String()
+}
+
+func (e imagesError) String() string {
+	return "crust.images." + e.name
+}
+
+func (e imagesError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e imagesError) withStack() *fault.Error {
+	return fault.New(e)
+}
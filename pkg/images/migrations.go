@@ -0,0 +1,28 @@
+package images
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200302000000.images",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_attachment_thumbnail (
+  rel_attachment   BIGINT UNSIGNED NOT NULL,
+  size             INT UNSIGNED    NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  url              VARCHAR(512)    NOT NULL,
+  mimetype         VARCHAR(255)    NOT NULL,
+  width            INT UNSIGNED    NOT NULL,
+  height           INT UNSIGNED    NOT NULL,
+  bytes            BIGINT UNSIGNED NOT NULL,
+  created_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_attachment, size)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,235 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"io/ioutil"
+
+	"github.com/disintegration/imaging"
+	"github.com/edwvee/exiffix"
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+var (
+	f2m = map[imaging.Format]string{
+		imaging.JPEG: "image/jpeg",
+		imaging.PNG:  "image/png",
+		imaging.GIF:  "image/gif",
+		imaging.BMP:  "image/bmp",
+	}
+
+	f2e = map[imaging.Format]string{
+		imaging.JPEG: "jpg",
+		imaging.PNG:  "png",
+		imaging.GIF:  "gif",
+		imaging.BMP:  "bmp",
+	}
+)
+
+// process makes preview and thumbnails of the stored image and
+// tells channel members about them
+//
+// Attachment and message are sent again to corteza's websocket, now with
+// the preview, and attachment.processed is published to the channel.
+func (svc service) process(att *messagingTypes.Attachment, msg *messagingTypes.Message) {
+	defer sentry.Recover()
+
+	svc.workers <- struct{}{}
+	defer func() { <-svc.workers }()
+
+	log := svc.log(zap.Uint64("attachmentID", att.ID), zap.Uint64("channelID", msg.ChannelID))
+
+	e, tt, err := svc.render(att)
+	if err != nil {
+		log.Warn("could not process image", zap.Error(err))
+		return
+	}
+
+	for _, t := range tt {
+		t.ChannelID = msg.ChannelID
+	}
+
+	t := now()
+	att.UpdatedAt = &t
+
+	if err = svc.repository.Processed(att, tt); err != nil {
+		log.Error("could not store processed image", zap.Error(err))
+		return
+	}
+
+	if err = messagingService.Event(svc.ctx).Message(msg); err != nil {
+		log.Warn("could not send message event", zap.Error(err))
+	}
+
+	e.MessageID, e.ChannelID = msg.ID, msg.ChannelID
+	live.Publish(&live.Event{Scope: live.ChannelScope(msg.ChannelID), Type: live.EventAttachmentProcessed, Payload: e})
+
+	log.Debug("image processed", zap.Int("thumbnails", len(tt)), zap.Bool("downscaled", e.Downscaled))
+}
+
+// render decodes the image, downscales the original when it is too large
+// and stores preview and thumbnails
+func (svc service) render(att *messagingTypes.Attachment) (*ProcessedEvent, ThumbnailSet, error) {
+	format, ok := formats[att.Meta.Original.Mimetype]
+	if !ok {
+		return nil, nil, ErrInvalidImage.withStack().WithMessage("unsupported image format " + att.Meta.Original.Mimetype)
+	}
+
+	raw, err := svc.read(att.Url)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Dimensions are checked before the image is decoded, to avoid
+	// decompression bombs
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, nil, ErrInvalidImage.withStack().WithMessage(err.Error())
+	} else if cfg.Width*cfg.Height > svc.maxPixels {
+		return nil, nil, ErrInvalidImage.withStack().WithMessage("image has too many pixels to be processed")
+	}
+
+	img, animated, err := decode(raw, format)
+	if err != nil {
+		return nil, nil, ErrInvalidImage.withStack().WithMessage(err.Error())
+	}
+
+	e := &ProcessedEvent{AttachmentID: att.ID, Animated: animated}
+
+	// Animations would lose all but the first frame
+	if svc.maxDimension > 0 && !animated && !fits(img, svc.maxDimension, svc.maxDimension) {
+		img = imaging.Fit(img, svc.maxDimension, svc.maxDimension, imaging.Lanczos)
+
+		buf, err := svc.encode(img, format)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		att.Meta.Original.Size = int64(buf.Len())
+		if err = svc.store.Save(att.Url, buf); err != nil {
+			return nil, nil, err
+		}
+
+		e.Downscaled = true
+	}
+
+	e.Width, e.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	att.SetOriginalImageMeta(e.Width, e.Height, animated)
+
+	// Thumbnails keep transparency of GIF and PNG images
+	tf := imaging.JPEG
+	if format == imaging.GIF || format == imaging.PNG {
+		tf = format
+	}
+
+	// Preview is made the same way as corteza makes it
+	preview := img
+	if e.Width > previewMaxWidth && e.Width > e.Height {
+		preview = imaging.Resize(preview, previewMaxWidth, 0, imaging.Lanczos)
+	} else if e.Height > previewMaxHeight {
+		preview = imaging.Resize(preview, 0, previewMaxHeight, imaging.Lanczos)
+	}
+
+	buf, err := svc.encode(preview, tf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta := att.SetPreviewImageMeta(preview.Bounds().Dx(), preview.Bounds().Dy(), false)
+	meta.Size = int64(buf.Len())
+	meta.Mimetype = f2m[tf]
+	meta.Extension = f2e[tf]
+
+	att.PreviewUrl = svc.store.Preview(att.ID, meta.Extension)
+	if err = svc.store.Save(att.PreviewUrl, buf); err != nil {
+		return nil, nil, err
+	}
+
+	e.Preview = true
+	e.Thumbnails = ThumbnailSet{}
+
+	for _, size := range svc.sizes {
+		// Images are not enlarged, clients use the original instead
+		if fits(img, int(size), int(size)) {
+			break
+		}
+
+		thumb := imaging.Fit(img, int(size), int(size), imaging.Lanczos)
+
+		if buf, err = svc.encode(thumb, tf); err != nil {
+			return nil, nil, err
+		}
+
+		t := &Thumbnail{
+			AttachmentID: att.ID,
+			Size:         size,
+			URL:          svc.store.Preview(att.ID, fmt.Sprintf("%d.%s", size, f2e[tf])),
+			Mimetype:     f2m[tf],
+			Width:        thumb.Bounds().Dx(),
+			Height:       thumb.Bounds().Dy(),
+			Bytes:        int64(buf.Len()),
+			CreatedAt:    now(),
+		}
+
+		if err = svc.store.Save(t.URL, buf); err != nil {
+			return nil, nil, err
+		}
+
+		e.Thumbnails = append(e.Thumbnails, t)
+	}
+
+	return e, e.Thumbnails, nil
+}
+
+// read returns contents of the stored file
+func (svc service) read(name string) ([]byte, error) {
+	fh, err := svc.store.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if c, ok := fh.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	return ioutil.ReadAll(fh)
+}
+
+func (svc service) encode(img image.Image, format imaging.Format) (*bytes.Buffer, error) {
+	var buf = &bytes.Buffer{}
+	return buf, imaging.Encode(buf, img, format, imaging.JPEGQuality(svc.jpegQuality))
+}
+
+// decode returns the image rotated by its orientation and if it is animated
+//
+// Animated GIFs are represented by their first frame.
+func decode(raw []byte, format imaging.Format) (image.Image, bool, error) {
+	switch format {
+	case imaging.JPEG:
+		img, _, err := exiffix.Decode(bytes.NewReader(raw))
+		return img, false, err
+	case imaging.GIF:
+		g, err := gif.DecodeAll(bytes.NewReader(raw))
+		if err != nil {
+			return nil, false, err
+		}
+
+		return g.Image[0], len(g.Image) > 1, nil
+	default:
+		img, err := imaging.Decode(bytes.NewReader(raw))
+		return img, false, err
+	}
+}
+
+// fits reports if the image fits into the box
+func fits(img image.Image, width, height int) bool {
+	return img.Bounds().Dx() <= width && img.Bounds().Dy() <= height
+}
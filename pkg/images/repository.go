@@ -0,0 +1,85 @@
+package images
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_attachment_thumbnail"
+}
+
+// FindByAttachmentID returns thumbnails of the attachment, smallest first
+func (r repository) FindByAttachmentID(attachmentID uint64) (set ThumbnailSet, err error) {
+	q := squirrel.
+		Select(
+			"rel_attachment",
+			"size",
+			"rel_channel",
+			"url",
+			"mimetype",
+			"width",
+			"height",
+			"bytes",
+			"created_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"rel_attachment": attachmentID}).
+		OrderBy("size")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Processed stores processed attachment and replaces its thumbnails
+func (r repository) Processed(att *messagingTypes.Attachment, set ThumbnailSet) error {
+	return r.db().Transaction(func() error {
+		_, err := r.db().Exec(
+			"UPDATE messaging_attachment SET url = ?, preview_url = ?, meta = ?, updated_at = ? WHERE id = ?",
+			att.Url, att.PreviewUrl, att.Meta, att.UpdatedAt, att.ID,
+		)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if _, err = r.db().Exec("DELETE FROM "+r.table()+" WHERE rel_attachment = ?", att.ID); err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, t := range set {
+			if err = r.db().Insert(r.table(), t); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,47 @@
+package images
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts thumbnail endpoints
+//
+// Originals and previews are served by corteza's attachment endpoints.
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Thumbnails of the image, empty until it is processed
+	r.Get("/{attachmentID}", rest.Handler("Images.Thumbnails", func(r *http.Request) (interface{}, error) {
+		return DefaultImage.With(r.Context()).Thumbnails(rest.ParamUint64(r, "attachmentID"))
+	}))
+
+	// Thumbnail that fits into square of the size
+	r.Get("/{attachmentID}/{size}", rest.Handler("Images.Thumbnail", func(r *http.Request) (interface{}, error) {
+		size, _ := strconv.ParseUint(chi.URLParam(r, "size"), 10, 32)
+
+		t, fh, err := DefaultImage.With(r.Context()).OpenThumbnail(rest.ParamUint64(r, "attachmentID"), uint(size))
+		if err != nil {
+			return nil, err
+		}
+
+		return func(w http.ResponseWriter, req *http.Request) {
+			if c, ok := fh.(io.Closer); ok {
+				defer c.Close()
+			}
+
+			name := fmt.Sprintf("%d_%d", t.AttachmentID, t.Size)
+
+			w.Header().Set("Content-Type", t.Mimetype)
+			w.Header().Set("Content-Disposition", "inline; filename="+name)
+			http.ServeContent(w, req, name, t.CreatedAt, fh)
+		}, nil
+	}))
+}
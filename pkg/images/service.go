@@ -0,0 +1,370 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingRepository "github.com/cortezaproject/corteza-server/messaging/repository"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/store"
+)
+
+type (
+	accessController interface {
+		CanAttachMessage(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+		store    store.Store
+
+		// Thumbnail sizes, smallest first
+		sizes []uint
+
+		// Originals larger than that are downscaled, 0 keeps them as they are
+		maxDimension int
+
+		// Images with more pixels are not processed
+		maxPixels int
+
+		jpegQuality   int
+		stripMetadata bool
+
+		// Limits number of images processed at once
+		workers chan struct{}
+
+		repository *repository
+	}
+
+	ImageService interface {
+		With(ctx context.Context) ImageService
+
+		Thumbnails(attachmentID uint64) (ThumbnailSet, error)
+		OpenThumbnail(attachmentID uint64, size uint) (*Thumbnail, io.ReadSeeker, error)
+	}
+)
+
+var (
+	DefaultImage ImageService
+
+	// used by service decorators
+	defaultImage *service
+
+	// now is used for processing times and can be overridden
+	now = time.Now
+
+	// Formats of images that are processed, by sniffed mimetype
+	formats = map[string]imaging.Format{
+		"image/jpeg": imaging.JPEG,
+		"image/png":  imaging.PNG,
+		"image/gif":  imaging.GIF,
+		"image/bmp":  imaging.BMP,
+	}
+)
+
+// Init initializes processing of uploaded images and decorates
+// attachment service with it
+//
+// Metadata of images is stripped before they are stored (unless
+// IMAGES_STRIP_METADATA is false). Preview, thumbnails of
+// IMAGES_THUMBNAIL_SIZES and downscaling of originals larger than
+// IMAGES_MAX_DIMENSION are done in the background, by IMAGES_WORKERS
+// at once; attachment.processed is published to the channel when done.
+//
+// Must be called after messaging services are initialized and before
+// extensions that decorate attachment service.
+func Init(ctx context.Context, log *zap.Logger) error {
+	sizes, err := parseSizes(options.EnvString("", "IMAGES_THUMBNAIL_SIZES", "160,480,1024"))
+	if err != nil {
+		return err
+	}
+
+	workers := options.EnvInt("", "IMAGES_WORKERS", 2)
+	if workers < 1 {
+		workers = 1
+	}
+
+	svc := &service{
+		logger:        log,
+		ac:            messagingService.DefaultAccessControl,
+		channels:      messagingService.DefaultChannel,
+		store:         messagingService.DefaultStore,
+		sizes:         sizes,
+		maxDimension:  options.EnvInt("", "IMAGES_MAX_DIMENSION", 0),
+		maxPixels:     options.EnvInt("", "IMAGES_MAX_PIXELS", 50000000),
+		jpegQuality:   options.EnvInt("", "IMAGES_JPEG_QUALITY", 85),
+		stripMetadata: options.EnvBool("", "IMAGES_STRIP_METADATA", true),
+		workers:       make(chan struct{}, workers),
+	}
+
+	DefaultImage = svc.With(ctx)
+	defaultImage = svc.with(ctx)
+
+	messagingService.DefaultAttachment = Attachment(messagingService.DefaultAttachment)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) ImageService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+		store:    svc.store,
+
+		sizes:         svc.sizes,
+		maxDimension:  svc.maxDimension,
+		maxPixels:     svc.maxPixels,
+		jpegQuality:   svc.jpegQuality,
+		stripMetadata: svc.stripMetadata,
+		workers:       svc.workers,
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Thumbnails returns thumbnails of the image attachment
+//
+// List is empty until the image is processed.
+func (svc service) Thumbnails(attachmentID uint64) (ThumbnailSet, error) {
+	tt, err := svc.repository.FindByAttachmentID(attachmentID)
+	if err != nil {
+		return nil, err
+	} else if len(tt) == 0 {
+		return ThumbnailSet{}, nil
+	}
+
+	if err = svc.canOpen(attachmentID, tt[0].ChannelID); err != nil {
+		return nil, err
+	}
+
+	return tt, nil
+}
+
+// OpenThumbnail returns thumbnail of the size and its contents
+func (svc service) OpenThumbnail(attachmentID uint64, size uint) (*Thumbnail, io.ReadSeeker, error) {
+	tt, err := svc.repository.FindByAttachmentID(attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	t := tt.FindBySize(size)
+	if t == nil {
+		return nil, nil, ErrThumbnailNotFound.withStack().WithID("attachmentID", attachmentID)
+	}
+
+	if err = svc.canOpen(attachmentID, t.ChannelID); err != nil {
+		return nil, nil, err
+	}
+
+	fh, err := svc.store.Open(t.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return t, fh, nil
+}
+
+// canOpen checks if thumbnails of the attachment can be opened
+//
+// Thumbnails are available to those that can read the channel and open
+// the preview; decorators of the attachment service (quarantine) can
+// refuse the latter.
+func (svc service) canOpen(attachmentID, channelID uint64) error {
+	if _, err := svc.channels.FindByID(channelID); err != nil {
+		return err
+	}
+
+	as := messagingService.DefaultAttachment.With(svc.ctx)
+
+	att, err := as.FindByID(attachmentID)
+	if err != nil {
+		return err
+	}
+
+	fh, err := as.OpenPreview(att)
+	if err != nil {
+		return err
+	}
+
+	if c, ok := fh.(io.Closer); ok {
+		_ = c.Close()
+	}
+
+	return nil
+}
+
+// create stores the uploaded image and posts it to the channel
+//
+// It does the same as corteza's attachment service but strips metadata
+// and leaves preview to the background processing.
+func (svc service) create(name, mimetype string, fh io.ReadSeeker, channelID, replyTo uint64) (*messagingTypes.Attachment, error) {
+	ch, err := svc.channels.FindByID(channelID)
+	if err != nil {
+		return nil, err
+	} else if !svc.ac.CanAttachMessage(svc.ctx, ch) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	var (
+		userID = auth.GetIdentityFromContext(svc.ctx).Identity()
+		buf    = &bytes.Buffer{}
+		att    = &messagingTypes.Attachment{
+			ID:     factory.Sonyflake.NextID(),
+			UserID: userID,
+			Name:   strings.TrimSpace(name),
+		}
+	)
+
+	if _, err = fh.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if svc.stripMetadata {
+		err = strip(buf, fh, mimetype)
+	} else {
+		_, err = io.Copy(buf, fh)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	att.Meta.Original.Extension = extension(name)
+	att.Meta.Original.Mimetype = mimetype
+	att.Meta.Original.Size = int64(buf.Len())
+
+	att.Url = svc.store.Original(att.ID, att.Meta.Original.Extension)
+	if err = svc.store.Save(att.Url, buf); err != nil {
+		svc.log(zap.String("name", att.Name), zap.Error(err)).Error("could not store file")
+		return nil, err
+	}
+
+	msg := &messagingTypes.Message{
+		Attachment: att,
+		Message:    name,
+		Type:       messagingTypes.MessageTypeInlineImage,
+		ChannelID:  channelID,
+		ReplyTo:    replyTo,
+		UserID:     userID,
+	}
+
+	db := messagingRepository.DB(svc.ctx)
+	err = db.Transaction(func() (err error) {
+		ar := messagingRepository.Attachment(svc.ctx, db)
+
+		if att, err = ar.CreateAttachment(att); err != nil {
+			return
+		}
+
+		if msg, err = messagingRepository.Message(svc.ctx, db).Create(msg); err != nil {
+			return
+		}
+
+		if err = ar.BindAttachment(att.ID, msg.ID); err != nil {
+			return
+		}
+
+		return messagingService.Event(svc.ctx).Message(msg)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Processing works on its own copies, the attachment is returned
+	// to the uploader while it runs
+	var (
+		a = *att
+		m = *msg
+	)
+
+	m.Attachment = &a
+
+	go defaultImage.with(auth.SetIdentityToContext(defaultImage.ctx, auth.NewIdentity(userID))).process(&a, &m)
+	return att, nil
+}
+
+// detect returns mimetype of the upload when it is an image that is processed
+func detect(fh io.ReadSeeker) (string, bool) {
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return "", false
+	}
+
+	defer fh.Seek(0, io.SeekStart)
+
+	// See http.DetectContentType about 512 bytes
+	var buf = make([]byte, 512)
+	n, err := io.ReadFull(fh, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", false
+	}
+
+	mimetype := http.DetectContentType(buf[:n])
+	_, ok := formats[mimetype]
+	return mimetype, ok
+}
+
+// extension returns extension of the file name, the same way corteza does it
+func extension(name string) string {
+	return strings.Trim(path.Ext(strings.Trim(name, ".")), ".")
+}
+
+// parseSizes parses comma separated list of thumbnail sizes
+func parseSizes(s string) ([]uint, error) {
+	var sizes []uint
+
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+
+		size, err := strconv.ParseUint(p, 10, 16)
+		if err != nil || size == 0 {
+			return nil, ErrInvalidSizes.withStack().WithMessage("invalid thumbnail size " + p)
+		}
+
+		sizes = append(sizes, uint(size))
+	}
+
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+
+	out := sizes[:0]
+	for i, size := range sizes {
+		if i == 0 || size != sizes[i-1] {
+			out = append(out, size)
+		}
+	}
+
+	return out, nil
+}
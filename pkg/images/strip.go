@@ -0,0 +1,245 @@
+package images
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+)
+
+var (
+	pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+	// PNG chunks with text or EXIF metadata
+	pngMetaChunks = map[string]bool{
+		"eXIf": true,
+		"tEXt": true,
+		"zTXt": true,
+		"iTXt": true,
+		"tIME": true,
+	}
+)
+
+const (
+	jpegSOI = 0xd8
+	jpegSOS = 0xda
+	jpegEOI = 0xd9
+
+	jpegAPP0  = 0xe0
+	jpegAPP1  = 0xe1 // EXIF, XMP
+	jpegAPP13 = 0xed // IPTC, Photoshop
+	jpegCOM   = 0xfe
+)
+
+// strip copies the image without its metadata (camera, location, author, ...)
+//
+// Nothing is decoded, pixels are copied as they are. Orientation of JPEG
+// images is the only metadata kept, so that they are not displayed rotated.
+func strip(dst io.Writer, src io.Reader, mimetype string) error {
+	switch mimetype {
+	case "image/jpeg":
+		return stripJPEG(dst, bufio.NewReader(src))
+	case "image/png":
+		return stripPNG(dst, bufio.NewReader(src))
+	default:
+		_, err := io.Copy(dst, src)
+		return err
+	}
+}
+
+func stripJPEG(dst io.Writer, src *bufio.Reader) error {
+	var (
+		marker      [2]byte
+		length      uint16
+		segment     []byte
+		orientation byte
+		kept        = &bytes.Buffer{}
+	)
+
+	if _, err := io.ReadFull(src, marker[:]); err != nil || marker[0] != 0xff || marker[1] != jpegSOI {
+		return ErrInvalidImage.withStack().WithMessage("not a JPEG image")
+	}
+
+	for {
+		if _, err := io.ReadFull(src, marker[:]); err != nil || marker[0] != 0xff {
+			return ErrInvalidImage.withStack().WithMessage("malformed JPEG segment")
+		}
+
+		// Markers can be padded with any number of 0xff
+		for marker[1] == 0xff {
+			if marker[1], _ = src.ReadByte(); marker[1] == 0 {
+				return ErrInvalidImage.withStack().WithMessage("malformed JPEG segment")
+			}
+		}
+
+		if marker[1] == jpegEOI {
+			return ErrInvalidImage.withStack().WithMessage("JPEG image without scan")
+		}
+
+		if err := binary.Read(src, binary.BigEndian, &length); err != nil || length < 2 {
+			return ErrInvalidImage.withStack().WithMessage("malformed JPEG segment")
+		}
+
+		segment = make([]byte, length-2)
+		if _, err := io.ReadFull(src, segment); err != nil {
+			return ErrInvalidImage.withStack().WithMessage("truncated JPEG segment")
+		}
+
+		switch marker[1] {
+		case jpegAPP1:
+			if o := exifOrientation(segment); o > 1 {
+				orientation = o
+			}
+			continue
+		case jpegAPP13, jpegCOM:
+			continue
+		}
+
+		kept.Write(marker[:])
+		_ = binary.Write(kept, binary.BigEndian, length)
+		kept.Write(segment)
+
+		if marker[1] == jpegSOS {
+			break
+		}
+	}
+
+	if _, err := dst.Write([]byte{0xff, jpegSOI}); err != nil {
+		return err
+	}
+
+	// JFIF header must stay the first segment
+	if b := kept.Bytes(); len(b) > 1 && b[1] == jpegAPP0 {
+		n := 4 + int(binary.BigEndian.Uint16(b[2:4]))
+		if _, err := dst.Write(b[:n]); err != nil {
+			return err
+		}
+
+		kept.Next(n)
+	}
+
+	if orientation > 1 {
+		if _, err := dst.Write(orientationSegment(orientation)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := kept.WriteTo(dst); err != nil {
+		return err
+	}
+
+	// Entropy coded data and everything after it is copied as it is
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+func stripPNG(dst io.Writer, src *bufio.Reader) error {
+	var (
+		signature [8]byte
+		header    [8]byte
+		length    uint32
+	)
+
+	if _, err := io.ReadFull(src, signature[:]); err != nil || !bytes.Equal(signature[:], pngSignature) {
+		return ErrInvalidImage.withStack().WithMessage("not a PNG image")
+	}
+
+	if _, err := dst.Write(signature[:]); err != nil {
+		return err
+	}
+
+	for {
+		if _, err := io.ReadFull(src, header[:]); err != nil {
+			return ErrInvalidImage.withStack().WithMessage("truncated PNG chunk")
+		}
+
+		length = binary.BigEndian.Uint32(header[:4])
+		kind := string(header[4:])
+
+		// Data and CRC
+		chunk := io.LimitReader(src, int64(length)+4)
+
+		if pngMetaChunks[kind] {
+			if n, _ := io.Copy(ioutil.Discard, chunk); n != int64(length)+4 {
+				return ErrInvalidImage.withStack().WithMessage("truncated PNG chunk")
+			}
+
+			continue
+		}
+
+		if _, err := dst.Write(header[:]); err != nil {
+			return err
+		}
+
+		if n, err := io.Copy(dst, chunk); err != nil {
+			return err
+		} else if n != int64(length)+4 {
+			return ErrInvalidImage.withStack().WithMessage("truncated PNG chunk")
+		}
+
+		if kind == "IEND" {
+			return nil
+		}
+	}
+}
+
+// exifOrientation returns orientation (1-8) from APP1 segment, 0 when there is none
+func exifOrientation(segment []byte) byte {
+	if len(segment) < 14 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+		return 0
+	}
+
+	var (
+		tiff = segment[6:]
+		bo   binary.ByteOrder
+	)
+
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 0
+	}
+
+	ifd := int(bo.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+
+	entries := int(bo.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		e := ifd + 2 + i*12
+		if e+12 > len(tiff) {
+			return 0
+		}
+
+		// Orientation tag, SHORT
+		if bo.Uint16(tiff[e:]) == 0x0112 && bo.Uint16(tiff[e+2:]) == 3 {
+			if o := bo.Uint16(tiff[e+8:]); o >= 1 && o <= 8 {
+				return byte(o)
+			}
+
+			return 0
+		}
+	}
+
+	return 0
+}
+
+// orientationSegment returns APP1 segment with EXIF that has orientation only
+func orientationSegment(o byte) []byte {
+	return []byte{
+		0xff, jpegAPP1, 0x00, 0x22,
+		'E', 'x', 'i', 'f', 0x00, 0x00,
+		// TIFF header, big endian, IFD at offset 8
+		'M', 'M', 0x00, 0x2a, 0x00, 0x00, 0x00, 0x08,
+		// One entry: orientation, SHORT, count 1, value
+		0x00, 0x01,
+		0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01, 0x00, o, 0x00, 0x00,
+		// No next IFD
+		0x00, 0x00, 0x00, 0x00,
+	}
+}
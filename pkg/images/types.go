@@ -0,0 +1,62 @@
+package images
+
+import (
+	"time"
+)
+
+type (
+	// Thumbnail of the image attachment, fits into square of its size
+	Thumbnail struct {
+		AttachmentID uint64 `json:"attachmentID,string" db:"rel_attachment"`
+		Size         uint   `json:"size" db:"size"`
+		ChannelID    uint64 `json:"channelID,string" db:"rel_channel"`
+
+		// Location in the store, thumbnails are served by size
+		URL string `json:"-" db:"url"`
+
+		Mimetype string `json:"mimetype" db:"mimetype"`
+		Width    int    `json:"width" db:"width"`
+		Height   int    `json:"height" db:"height"`
+		Bytes    int64  `json:"bytes" db:"bytes"`
+
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	ThumbnailSet []*Thumbnail
+
+	// ProcessedEvent is the payload of attachment.processed events
+	//
+	// Preview is available when it is set, thumbnails are listed by size.
+	ProcessedEvent struct {
+		AttachmentID uint64 `json:"attachmentID,string"`
+		MessageID    uint64 `json:"messageID,string"`
+		ChannelID    uint64 `json:"channelID,string"`
+
+		Width    int  `json:"width"`
+		Height   int  `json:"height"`
+		Animated bool `json:"animated"`
+
+		// Original was downscaled to fit the max. dimension
+		Downscaled bool `json:"downscaled"`
+
+		Preview    bool         `json:"preview"`
+		Thumbnails ThumbnailSet `json:"thumbnails"`
+	}
+)
+
+const (
+	// Corteza's preview size; clients expect previews to fit into it
+	previewMaxWidth  = 320
+	previewMaxHeight = 180
+)
+
+// FindBySize returns thumbnail of the size, nil when there is none
+func (set ThumbnailSet) FindBySize(size uint) *Thumbnail {
+	for _, t := range set {
+		if t.Size == size {
+			return t
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package incoming
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	incomingError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrWebhookNotFound    = incomingError{"WebhookNotFound", fault.NotFound}
+	ErrNameRequired       = incomingError{"NameRequired", fault.Invalid}
+	ErrInvalidEvent       = incomingError{"InvalidEvent", fault.Invalid}
+	ErrSourceNotFound     = incomingError{"SourceNotFound", fault.NotFound}
+	ErrInvalidPayload     = incomingError{"InvalidPayload", fault.Invalid}
+	ErrInvalidChannelType = incomingError{"InvalidChannelType", fault.Invalid}
+	ErrEmptyMessage       = incomingError{"EmptyMessage", fault.Invalid}
+	ErrRateLimitReached   = incomingError{"RateLimitReached", fault.TooMany}
+	ErrNoPermissions      = incomingError{"NoPermissions", fault.Forbidden}
+)
+
+func (e incomingError) Error() string {
+	return e.String()
+}
+
+func (e incomingError) String() string {
+	return "crust.incoming." + e.name
+}
+
+func (e incomingError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e incomingError) withStack() *fault.Error {
+	return fault.New(e)
+}
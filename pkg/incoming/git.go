@@ -0,0 +1,386 @@
+package incoming
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type (
+	// gitSource formats events that git services post to webhooks
+	gitSource struct {
+		// header with the type of the event
+		header string
+
+		// format returns kind of the event and its message; message is
+		// empty for events that are not posted
+		format func(event string, body []byte) (kind, text string, err error)
+	}
+
+	commit struct {
+		ID      string
+		Message string
+		URL     string
+		Author  string
+	}
+
+	push struct {
+		User       string
+		Repo       string
+		RepoURL    string
+		Ref        string
+		CompareURL string
+		Commits    []commit
+		Total      int
+		Created    bool
+		Deleted    bool
+	}
+
+	githubRepo struct {
+		FullName string `json:"full_name"`
+		HTMLURL  string `json:"html_url"`
+	}
+
+	githubUser struct {
+		Login string `json:"login"`
+	}
+
+	githubCommit struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+		Author  struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	}
+
+	gitlabProject struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
+	}
+
+	gitlabUser struct {
+		Name string `json:"name"`
+	}
+)
+
+const (
+	SourceGitHub = "github"
+	SourceGitLab = "gitlab"
+
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+	EventPipeline    = "pipeline"
+
+	// Pushes list only the first commits
+	maxCommits = 5
+
+	// Commits are listed by abbreviated IDs
+	shortID = 7
+
+	// GitLab sends zeros as commit of deleted and new branches
+	nullCommit = "0000000000000000000000000000000000000000"
+)
+
+var (
+	gitSources = map[string]gitSource{
+		SourceGitHub: {header: "X-GitHub-Event", format: formatGitHub},
+		SourceGitLab: {header: "X-Gitlab-Event", format: formatGitLab},
+	}
+)
+
+// formatGitHub formats push, pull request and workflow run events
+func formatGitHub(event string, body []byte) (string, string, error) {
+	switch event {
+	case "push":
+		var e struct {
+			Ref        string         `json:"ref"`
+			Compare    string         `json:"compare"`
+			Created    bool           `json:"created"`
+			Deleted    bool           `json:"deleted"`
+			Commits    []githubCommit `json:"commits"`
+			Repository githubRepo     `json:"repository"`
+			Sender     githubUser     `json:"sender"`
+		}
+
+		if err := decode(body, &e); err != nil {
+			return "", "", err
+		}
+
+		p := push{
+			User:       e.Sender.Login,
+			Repo:       e.Repository.FullName,
+			RepoURL:    e.Repository.HTMLURL,
+			Ref:        e.Ref,
+			CompareURL: e.Compare,
+			Total:      len(e.Commits),
+			Created:    e.Created,
+			Deleted:    e.Deleted,
+		}
+
+		for _, c := range e.Commits {
+			p.Commits = append(p.Commits, commit{ID: c.ID, Message: c.Message, URL: c.URL, Author: c.Author.Name})
+		}
+
+		return EventPush, p.text(), nil
+
+	case "pull_request":
+		var e struct {
+			Action      string `json:"action"`
+			Number      int    `json:"number"`
+			PullRequest struct {
+				Title   string `json:"title"`
+				HTMLURL string `json:"html_url"`
+				Merged  bool   `json:"merged"`
+				Draft   bool   `json:"draft"`
+			} `json:"pull_request"`
+			Repository githubRepo `json:"repository"`
+			Sender     githubUser `json:"sender"`
+		}
+
+		if err := decode(body, &e); err != nil {
+			return "", "", err
+		}
+
+		var what = "pull request"
+		if e.PullRequest.Draft {
+			what = "draft pull request"
+		}
+
+		action := map[string]string{
+			"opened":           "opened " + what,
+			"reopened":         "reopened " + what,
+			"closed":           "closed " + what,
+			"ready_for_review": "marked ready for review pull request",
+		}[e.Action]
+
+		if e.Action == "closed" && e.PullRequest.Merged {
+			action = "merged " + what
+		}
+
+		if action == "" {
+			return EventPullRequest, "", nil
+		}
+
+		return EventPullRequest, fmt.Sprintf(
+			"**%s** %s [#%d %s](%s) in [%s](%s)",
+			e.Sender.Login, action, e.Number, e.PullRequest.Title, e.PullRequest.HTMLURL,
+			e.Repository.FullName, e.Repository.HTMLURL,
+		), nil
+
+	case "workflow_run":
+		var e struct {
+			Action      string `json:"action"`
+			WorkflowRun struct {
+				Name       string `json:"name"`
+				RunNumber  int    `json:"run_number"`
+				HeadBranch string `json:"head_branch"`
+				Conclusion string `json:"conclusion"`
+				HTMLURL    string `json:"html_url"`
+			} `json:"workflow_run"`
+			Repository githubRepo `json:"repository"`
+		}
+
+		if err := decode(body, &e); err != nil {
+			return "", "", err
+		}
+
+		result := map[string]string{
+			"success":   "succeeded",
+			"failure":   "failed",
+			"cancelled": "was cancelled",
+			"timed_out": "timed out",
+		}[e.WorkflowRun.Conclusion]
+
+		if e.Action != "completed" || result == "" {
+			return EventPipeline, "", nil
+		}
+
+		return EventPipeline, fmt.Sprintf(
+			"Workflow **%s** [#%d](%s) %s on `%s` of [%s](%s)",
+			e.WorkflowRun.Name, e.WorkflowRun.RunNumber, e.WorkflowRun.HTMLURL, result,
+			e.WorkflowRun.HeadBranch, e.Repository.FullName, e.Repository.HTMLURL,
+		), nil
+	}
+
+	// Ping and events that are not supported
+	return "", "", nil
+}
+
+// formatGitLab formats push, merge request and pipeline events
+func formatGitLab(event string, body []byte) (string, string, error) {
+	switch event {
+	case "Push Hook", "Tag Push Hook":
+		var e struct {
+			Ref               string        `json:"ref"`
+			Before            string        `json:"before"`
+			After             string        `json:"after"`
+			UserName          string        `json:"user_name"`
+			TotalCommitsCount int           `json:"total_commits_count"`
+			Project           gitlabProject `json:"project"`
+			Commits           []struct {
+				ID      string `json:"id"`
+				Message string `json:"message"`
+				URL     string `json:"url"`
+				Author  struct {
+					Name string `json:"name"`
+				} `json:"author"`
+			} `json:"commits"`
+		}
+
+		if err := decode(body, &e); err != nil {
+			return "", "", err
+		}
+
+		p := push{
+			User:    e.UserName,
+			Repo:    e.Project.PathWithNamespace,
+			RepoURL: e.Project.WebURL,
+			Ref:     e.Ref,
+			Total:   e.TotalCommitsCount,
+			Created: e.Before == nullCommit,
+			Deleted: e.After == nullCommit,
+		}
+
+		if !p.Created && !p.Deleted {
+			p.CompareURL = e.Project.WebURL + "/-/compare/" + e.Before + "..." + e.After
+		}
+
+		for _, c := range e.Commits {
+			p.Commits = append(p.Commits, commit{ID: c.ID, Message: c.Message, URL: c.URL, Author: c.Author.Name})
+		}
+
+		return EventPush, p.text(), nil
+
+	case "Merge Request Hook":
+		var e struct {
+			User             gitlabUser    `json:"user"`
+			Project          gitlabProject `json:"project"`
+			ObjectAttributes struct {
+				IID    int    `json:"iid"`
+				Title  string `json:"title"`
+				URL    string `json:"url"`
+				Action string `json:"action"`
+			} `json:"object_attributes"`
+		}
+
+		if err := decode(body, &e); err != nil {
+			return "", "", err
+		}
+
+		action := map[string]string{
+			"open":   "opened",
+			"reopen": "reopened",
+			"close":  "closed",
+			"merge":  "merged",
+		}[e.ObjectAttributes.Action]
+
+		if action == "" {
+			return EventPullRequest, "", nil
+		}
+
+		return EventPullRequest, fmt.Sprintf(
+			"**%s** %s merge request [!%d %s](%s) in [%s](%s)",
+			e.User.Name, action, e.ObjectAttributes.IID, e.ObjectAttributes.Title, e.ObjectAttributes.URL,
+			e.Project.PathWithNamespace, e.Project.WebURL,
+		), nil
+
+	case "Pipeline Hook":
+		var e struct {
+			User             gitlabUser    `json:"user"`
+			Project          gitlabProject `json:"project"`
+			ObjectAttributes struct {
+				ID     int    `json:"id"`
+				Ref    string `json:"ref"`
+				Status string `json:"status"`
+			} `json:"object_attributes"`
+		}
+
+		if err := decode(body, &e); err != nil {
+			return "", "", err
+		}
+
+		result := map[string]string{
+			"success":  "succeeded",
+			"failed":   "failed",
+			"canceled": "was cancelled",
+		}[e.ObjectAttributes.Status]
+
+		if result == "" {
+			return EventPipeline, "", nil
+		}
+
+		return EventPipeline, fmt.Sprintf(
+			"Pipeline [#%d](%s/-/pipelines/%d) %s on `%s` of [%s](%s), triggered by **%s**",
+			e.ObjectAttributes.ID, e.Project.WebURL, e.ObjectAttributes.ID, result,
+			e.ObjectAttributes.Ref, e.Project.PathWithNamespace, e.Project.WebURL, e.User.Name,
+		), nil
+	}
+
+	return "", "", nil
+}
+
+// text returns message of the push; pushes without commits that do not
+// create or remove anything are not posted
+func (p push) text() string {
+	var (
+		repo = fmt.Sprintf("[%s](%s)", p.Repo, p.RepoURL)
+		ref  = strings.TrimPrefix(strings.TrimPrefix(p.Ref, "refs/heads/"), "refs/tags/")
+		what = "branch"
+	)
+
+	if strings.HasPrefix(p.Ref, "refs/tags/") {
+		what = "tag"
+	}
+
+	switch {
+	case p.Deleted:
+		return fmt.Sprintf("**%s** deleted %s `%s` of %s", p.User, what, ref, repo)
+	case what == "tag":
+		return fmt.Sprintf("**%s** pushed tag `%s` to %s", p.User, ref, repo)
+	case len(p.Commits) == 0 && p.Created:
+		return fmt.Sprintf("**%s** created branch `%s` in %s", p.User, ref, repo)
+	case len(p.Commits) == 0:
+		return ""
+	}
+
+	var (
+		head = fmt.Sprintf("**%s** pushed %d commits to `%s` of %s", p.User, p.Total, ref, repo)
+		ll   []string
+	)
+
+	if p.Total == 1 {
+		head = fmt.Sprintf("**%s** pushed 1 commit to `%s` of %s", p.User, ref, repo)
+	}
+
+	if p.CompareURL != "" {
+		head += fmt.Sprintf(" ([compare](%s))", p.CompareURL)
+	}
+
+	for i, c := range p.Commits {
+		if i == maxCommits {
+			break
+		}
+
+		id := c.ID
+		if len(id) > shortID {
+			id = id[:shortID]
+		}
+
+		ll = append(ll, fmt.Sprintf("[`%s`](%s) %s (%s)", id, c.URL, strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0], c.Author))
+	}
+
+	if more := p.Total - len(ll); more > 0 {
+		ll = append(ll, fmt.Sprintf("and %d more", more))
+	}
+
+	return head + "\n\n> " + strings.Join(ll, "\n> ")
+}
+
+func decode(body []byte, dst interface{}) error {
+	if err := json.Unmarshal(body, dst); err != nil {
+		return ErrInvalidPayload.withStack().WithMessage(err.Error())
+	}
+
+	return nil
+}
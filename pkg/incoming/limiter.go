@@ -0,0 +1,63 @@
+package incoming
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// limiter counts messages posted to webhooks (by token hash) in fixed windows
+	//
+	// Messages are counted on the instance they were posted to.
+	limiter struct {
+		sync.Mutex
+
+		// Messages allowed in the window, 0 disables the limit
+		limit  int
+		window time.Duration
+
+		windows map[string]*window
+		swept   time.Time
+	}
+
+	window struct {
+		start time.Time
+		count int
+	}
+)
+
+// allow counts the message or returns error when too many were posted with the token
+func (l *limiter) allow(key string, now time.Time) error {
+	if l.limit <= 0 {
+		return nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	// Windows of tokens that were not used for a while are forgotten
+	if now.Sub(l.swept) > l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) > l.window {
+				delete(l.windows, k)
+			}
+		}
+
+		l.swept = now
+	}
+
+	w := l.windows[key]
+	if w == nil || now.Sub(w.start) > l.window {
+		w = &window{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return ErrRateLimitReached.withStack().
+			WithMessage("too many messages, try again later").
+			WithRetryAfter(w.start.Add(l.window).Sub(now))
+	}
+
+	w.count++
+	return nil
+}
@@ -0,0 +1,46 @@
+package incoming
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200306000000.incoming",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_incoming_webhook (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_channel        BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  token_hash         CHAR(64)        NOT NULL,
+
+  created_by         BIGINT UNSIGNED NOT NULL,
+  roles              TEXT            NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  last_used_at       DATETIME            NULL DEFAULT NULL,
+  revoked_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  UNIQUE KEY uid_token (token_hash),
+  INDEX (rel_channel)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+		{
+			Name: "20200308000000.incoming-events",
+			Up: `
+ALTER TABLE crust_messaging_incoming_webhook
+  ADD COLUMN events TEXT NULL AFTER token_hash;
+`,
+		},
+		{
+			Name: "20200313000000.incoming-roles",
+			Up: `
+ALTER TABLE crust_messaging_incoming_webhook
+  DROP COLUMN roles;
+`,
+		},
+	}
+)
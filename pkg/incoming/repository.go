@@ -0,0 +1,102 @@
+package incoming
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_incoming_webhook"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_channel",
+			"name",
+			"token_hash",
+			"events",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"last_used_at",
+			"revoked_at",
+		).
+		From(r.table())
+}
+
+func (r repository) FindByID(webhookID uint64) (*Webhook, error) {
+	return r.findOneBy(squirrel.Eq{"id": webhookID})
+}
+
+// FindByTokenHash returns webhook with the token, unless it was revoked
+func (r repository) FindByTokenHash(hash string) (*Webhook, error) {
+	return r.findOneBy(squirrel.Eq{"token_hash": hash, "revoked_at": nil})
+}
+
+func (r repository) findOneBy(cnd squirrel.Sqlizer) (*Webhook, error) {
+	var w = &Webhook{}
+
+	if err := rh.FetchOne(r.db(), r.query().Where(cnd), w); err != nil {
+		return nil, err
+	} else if w.ID == 0 {
+		return nil, ErrWebhookNotFound.withStack()
+	}
+
+	return w, nil
+}
+
+// FindByChannelID returns webhooks of the channel, revoked ones included
+func (r repository) FindByChannelID(channelID uint64) (set WebhookSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_channel": channelID}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(w *Webhook) (*Webhook, error) {
+	w.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&w.CreatedAt)
+
+	return w, errors.WithStack(r.db().Insert(r.table(), w))
+}
+
+func (r repository) Update(w *Webhook) (*Webhook, error) {
+	rh.SetCurrentTimeRounded(&w.UpdatedAt)
+
+	return w, errors.WithStack(r.db().Update(r.table(), w, "id"))
+}
+
+func (r repository) Used(webhookID uint64, at time.Time) error {
+	return rh.UpdateColumns(r.db(), r.table(), rh.Set{"last_used_at": at}, squirrel.Eq{"id": webhookID})
+}
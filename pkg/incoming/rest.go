@@ -0,0 +1,112 @@
+package incoming
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts endpoints of incoming webhooks
+//
+// Messages are posted without signing in, with the token in the path.
+func MountRoutes(r chi.Router) {
+	r.Post("/{token}", rest.Handler("IncomingWebhook.Post", func(r *http.Request) (interface{}, error) {
+		p := &Payload{}
+
+		// Truncated payloads are not valid JSON
+		r.Body = ioutil.NopCloser(io.LimitReader(r.Body, maxPayloadSize))
+		if err := rest.Decode(r, p); err != nil {
+			return nil, err
+		}
+
+		msg, err := DefaultIncoming.With(r.Context()).Post(chi.URLParam(r, "token"), p)
+		if err != nil {
+			return nil, err
+		}
+
+		return struct {
+			MessageID uint64 `json:"messageID,string"`
+		}{msg.ID}, nil
+	}))
+
+	// Events of git services (github, gitlab), formatted into messages
+	r.Post("/{token}/{source}", rest.Handler("IncomingWebhook.PostEvent", func(r *http.Request) (interface{}, error) {
+		var source = chi.URLParam(r, "source")
+
+		// Truncated payloads are not valid JSON
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxEventSize))
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := DefaultIncoming.With(r.Context()).PostEvent(
+			chi.URLParam(r, "token"),
+			source,
+			r.Header.Get(gitSources[source].header),
+			body,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var out struct {
+			MessageID uint64 `json:"messageID,string,omitempty"`
+			Skipped   bool   `json:"skipped,omitempty"`
+		}
+
+		if msg == nil {
+			out.Skipped = true
+		} else {
+			out.MessageID = msg.ID
+		}
+
+		return out, nil
+	}))
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.MiddlewareValidOnly)
+
+		// ?channelID=<ID>
+		r.Get("/", rest.Handler("IncomingWebhook.List", func(r *http.Request) (interface{}, error) {
+			return DefaultIncoming.With(r.Context()).Find(rest.QueryUint64(r, "channelID"))
+		}))
+
+		r.Post("/", rest.Handler("IncomingWebhook.Create", func(r *http.Request) (interface{}, error) {
+			var body struct {
+				WebhookInput
+				ChannelID uint64 `json:"channelID,string"`
+			}
+
+			if err := rest.Decode(r, &body); err != nil {
+				return nil, err
+			}
+
+			return DefaultIncoming.With(r.Context()).Create(body.ChannelID, body.WebhookInput)
+		}))
+
+		r.Put("/{webhookID}", rest.Handler("IncomingWebhook.Update", func(r *http.Request) (interface{}, error) {
+			in := WebhookInput{}
+			if err := rest.Decode(r, &in); err != nil {
+				return nil, err
+			}
+
+			return DefaultIncoming.With(r.Context()).Update(rest.ParamUint64(r, "webhookID"), in)
+		}))
+
+		// Issues a new token, the old one stops working
+		r.Post("/{webhookID}/token", rest.Handler("IncomingWebhook.RegenerateToken", func(r *http.Request) (interface{}, error) {
+			return DefaultIncoming.With(r.Context()).RegenerateToken(rest.ParamUint64(r, "webhookID"))
+		}))
+
+		r.Delete("/{webhookID}", rest.Handler("IncomingWebhook.Revoke", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultIncoming.With(r.Context()).Revoke(rest.ParamUint64(r, "webhookID"))
+		}))
+	})
+}
@@ -0,0 +1,330 @@
+package incoming
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	accessController interface {
+		CanUpdateChannel(context.Context, *messagingTypes.Channel) bool
+		CanSendMessage(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+
+		repository *repository
+	}
+
+	IncomingService interface {
+		With(ctx context.Context) IncomingService
+
+		Find(channelID uint64) (WebhookSet, error)
+		Create(channelID uint64, in WebhookInput) (*Webhook, error)
+		Update(webhookID uint64, in WebhookInput) (*Webhook, error)
+		RegenerateToken(webhookID uint64) (*Webhook, error)
+		Revoke(webhookID uint64) error
+
+		Post(token string, p *Payload) (*messagingTypes.Message, error)
+		PostEvent(token, source, event string, body []byte) (*messagingTypes.Message, error)
+	}
+)
+
+var (
+	DefaultIncoming IncomingService
+
+	// now is used for rate limits and usage times and can be overridden
+	now = time.Now
+
+	// Messages posted with tokens
+	posted *limiter
+)
+
+// Init initializes incoming webhooks
+//
+// Every webhook can post INCOMING_WEBHOOKS_RATE_LIMIT messages per
+// INCOMING_WEBHOOKS_RATE_WINDOW (0 disables the limit).
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		channels: messagingService.DefaultChannel,
+	}
+
+	posted = &limiter{
+		limit:   options.EnvInt("", "INCOMING_WEBHOOKS_RATE_LIMIT", 60),
+		window:  options.EnvDuration("", "INCOMING_WEBHOOKS_RATE_WINDOW", time.Minute),
+		windows: map[string]*window{},
+	}
+
+	DefaultIncoming = svc.With(ctx)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) IncomingService {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns webhooks of the channel, revoked ones included
+func (svc service) Find(channelID uint64) (WebhookSet, error) {
+	if err := svc.canManage(channelID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindByChannelID(channelID)
+}
+
+// Create creates webhook with a new token
+func (svc service) Create(channelID uint64, in WebhookInput) (*Webhook, error) {
+	if err := svc.canManage(channelID); err != nil {
+		return nil, err
+	}
+
+	i := auth.GetIdentityFromContext(svc.ctx)
+	w := &Webhook{
+		ChannelID: channelID,
+		Token:     generateToken(),
+		CreatedBy: i.Identity(),
+	}
+
+	if err := w.apply(in); err != nil {
+		return nil, err
+	}
+
+	w.TokenHash = hash(w.Token)
+
+	w, err := svc.repository.Create(w)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("webhookID", w.ID), zap.Uint64("channelID", channelID)).Info("incoming webhook created")
+	return w, nil
+}
+
+// Update changes name and events of the webhook
+func (svc service) Update(webhookID uint64, in WebhookInput) (*Webhook, error) {
+	w, err := svc.repository.FindByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = svc.canManage(w.ChannelID); err != nil {
+		return nil, err
+	}
+
+	if err = w.apply(in); err != nil {
+		return nil, err
+	}
+
+	if w, err = svc.repository.Update(w); err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("webhookID", w.ID), zap.Uint64("channelID", w.ChannelID)).Info("incoming webhook updated")
+	return w, nil
+}
+
+// RegenerateToken issues a new token; the old one stops working
+//
+// Messages are posted in the name of the user that regenerated the token
+// from then on; revoked webhooks are restored.
+func (svc service) RegenerateToken(webhookID uint64) (*Webhook, error) {
+	w, err := svc.repository.FindByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = svc.canManage(w.ChannelID); err != nil {
+		return nil, err
+	}
+
+	i := auth.GetIdentityFromContext(svc.ctx)
+	w.Token = generateToken()
+	w.TokenHash = hash(w.Token)
+	w.CreatedBy = i.Identity()
+	w.RevokedAt = nil
+
+	if w, err = svc.repository.Update(w); err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("webhookID", w.ID), zap.Uint64("channelID", w.ChannelID)).Info("incoming webhook token regenerated")
+	return w, nil
+}
+
+// Revoke stops accepting messages with webhook's token
+func (svc service) Revoke(webhookID uint64) error {
+	w, err := svc.repository.FindByID(webhookID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.canManage(w.ChannelID); err != nil {
+		return err
+	}
+
+	if w.RevokedAt != nil {
+		return nil
+	}
+
+	at := now().Truncate(time.Second)
+	w.RevokedAt = &at
+
+	if _, err = svc.repository.Update(w); err != nil {
+		return err
+	}
+
+	svc.log(zap.Uint64("webhookID", w.ID), zap.Uint64("channelID", w.ChannelID)).Info("incoming webhook revoked")
+	return nil
+}
+
+// Post posts the payload into webhook's channel
+//
+// Message is posted with the username of the payload (name of the webhook
+// by default) and goes through the same checks as any other message.
+func (svc service) Post(token string, p *Payload) (*messagingTypes.Message, error) {
+	w, err := svc.authorize(token)
+	if err != nil {
+		return nil, err
+	}
+
+	text := p.render()
+	if text == "" {
+		return nil, ErrEmptyMessage.withStack()
+	}
+
+	username := truncate(p.Username, maxUsernameLength)
+	if username == "" {
+		username = w.Name
+	}
+
+	return svc.post(w, text, username)
+}
+
+// PostEvent formats the event of a git service and posts it into
+// webhook's channel
+//
+// Events that webhook does not post and the ones that are not worth
+// a message (pings, labels, running pipelines) are skipped; nil is
+// returned for them.
+func (svc service) PostEvent(token, source, event string, body []byte) (*messagingTypes.Message, error) {
+	s, ok := gitSources[source]
+	if !ok {
+		return nil, ErrSourceNotFound.withStack()
+	}
+
+	w, err := svc.authorize(token)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, text, err := s.format(event, body)
+	if err != nil {
+		return nil, err
+	} else if text == "" || !w.Subscribed(kind) {
+		return nil, nil
+	}
+
+	return svc.post(w, text, w.Name)
+}
+
+// authorize returns webhook with the token when its rate limit allows
+func (svc service) authorize(token string) (*Webhook, error) {
+	h := hash(token)
+	if err := posted.allow(h, now()); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindByTokenHash(h)
+}
+
+// post creates the message in the name of webhook's creator
+func (svc service) post(w *Webhook, text, username string) (*messagingTypes.Message, error) {
+	ctx, err := runas.Context(svc.ctx, w.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := messagingService.DefaultMessage.With(ctx).Create(&messagingTypes.Message{
+		ChannelID: w.ChannelID,
+		UserID:    w.CreatedBy,
+		Message:   text,
+		Meta:      &messagingTypes.MessageMeta{Username: username},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = svc.repository.Used(w.ID, now().Truncate(time.Second)); err != nil {
+		svc.log(zap.Uint64("webhookID", w.ID)).Error("could not store webhook usage", zap.Error(err))
+	}
+
+	return msg, nil
+}
+
+// canManage checks if the current user can manage webhooks of the channel
+//
+// Webhooks are managed by users that can update the channel and post
+// messages into it; direct messages can not have them.
+func (svc service) canManage(channelID uint64) error {
+	ch, err := svc.channels.FindByID(channelID)
+	if err != nil {
+		return err
+	} else if !svc.ac.CanUpdateChannel(svc.ctx, ch) || !svc.ac.CanSendMessage(svc.ctx, ch) {
+		return ErrNoPermissions.withStack().WithID("channelID", channelID)
+	} else if ch.Type == messagingTypes.ChannelTypeGroup {
+		return ErrInvalidChannelType.withStack().WithMessage("direct messages can not have webhooks")
+	}
+
+	return nil
+}
+
+func generateToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
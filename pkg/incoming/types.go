@@ -0,0 +1,203 @@
+package incoming
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Webhook lets external systems post messages into the channel
+	//
+	// Messages are posted with the token in the URL, in the name of the
+	// user that created the webhook and with roles they have at the time;
+	// token is sent back only when webhook is created or its token is
+	// regenerated, only its hash is stored.
+	Webhook struct {
+		ID        uint64 `json:"webhookID,string" db:"id"`
+		ChannelID uint64 `json:"channelID,string" db:"rel_channel"`
+		Name      string `json:"name" db:"name"`
+
+		Token     string `json:"token,omitempty" db:"-"`
+		TokenHash string `json:"-" db:"token_hash"`
+
+		// Events of git services that are posted, all when empty
+		Events eventSet `json:"events" db:"events"`
+
+		CreatedBy  uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt  time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt  *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		LastUsedAt *time.Time `json:"lastUsedAt,omitempty" db:"last_used_at"`
+		RevokedAt  *time.Time `json:"revokedAt,omitempty" db:"revoked_at"`
+	}
+
+	WebhookSet []*Webhook
+
+	WebhookInput struct {
+		Name   string   `json:"name"`
+		Events []string `json:"events"`
+	}
+
+	// Payload is posted to the webhook; it is a subset of the format
+	// of Slack's incoming webhooks, so that existing integrations work
+	//
+	// Attachments are rendered into the message as quotes.
+	Payload struct {
+		Text        string        `json:"text"`
+		Username    string        `json:"username"`
+		Attachments []*Attachment `json:"attachments"`
+	}
+
+	Attachment struct {
+		Fallback  string   `json:"fallback"`
+		Pretext   string   `json:"pretext"`
+		Title     string   `json:"title"`
+		TitleLink string   `json:"title_link"`
+		Text      string   `json:"text"`
+		Fields    []*Field `json:"fields"`
+	}
+
+	Field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+	}
+
+	eventSet []string
+)
+
+const (
+	maxNameLength     = 64
+	maxUsernameLength = 64
+
+	// Payloads larger than this are rejected
+	maxPayloadSize = 64 << 10
+
+	// Events of git services carry lists of commits and whole repositories
+	maxEventSize = 1 << 20
+)
+
+var (
+	events = map[string]bool{
+		EventPush:        true,
+		EventPullRequest: true,
+		EventPipeline:    true,
+	}
+)
+
+// Subscribed checks if webhook posts events of the kind
+func (w Webhook) Subscribed(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply validates and sets webhook's settings
+func (w *Webhook) apply(in WebhookInput) error {
+	if w.Name = truncate(in.Name, maxNameLength); w.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	for _, e := range in.Events {
+		if !events[e] {
+			return ErrInvalidEvent.withStack().WithMessage("unknown event " + e)
+		}
+	}
+
+	w.Events = in.Events
+	return nil
+}
+
+// render returns text of the message with attachments
+func (p Payload) render() string {
+	var out = []string{}
+
+	if t := strings.TrimSpace(p.Text); t != "" {
+		out = append(out, t)
+	}
+
+	for _, a := range p.Attachments {
+		if a == nil {
+			continue
+		}
+
+		if t := strings.TrimSpace(a.Pretext); t != "" {
+			out = append(out, t)
+		}
+
+		if ll := a.lines(); len(ll) > 0 {
+			out = append(out, "> "+strings.Join(ll, "\n> "))
+		}
+	}
+
+	return strings.Join(out, "\n\n")
+}
+
+// lines returns lines of the attachment; fallback is used when
+// attachment has nothing else
+func (a Attachment) lines() (ll []string) {
+	switch title := strings.TrimSpace(a.Title); {
+	case title != "" && a.TitleLink != "":
+		ll = append(ll, "**["+title+"]("+a.TitleLink+")**")
+	case title != "":
+		ll = append(ll, "**"+title+"**")
+	}
+
+	if t := strings.TrimSpace(a.Text); t != "" {
+		ll = append(ll, strings.Split(t, "\n")...)
+	}
+
+	for _, f := range a.Fields {
+		if f != nil && (f.Title != "" || f.Value != "") {
+			ll = append(ll, "**"+strings.TrimSpace(f.Title)+"**: "+strings.TrimSpace(f.Value))
+		}
+	}
+
+	if len(ll) == 0 {
+		if t := strings.TrimSpace(a.Fallback); t != "" {
+			ll = append(ll, strings.Split(t, "\n")...)
+		}
+	}
+
+	return ll
+}
+
+// truncate shortens the text to the number of characters
+func truncate(s string, n int) string {
+	if rr := []rune(strings.TrimSpace(s)); len(rr) > n {
+		return string(rr[:n])
+	}
+
+	return strings.TrimSpace(s)
+}
+
+func (ee eventSet) Value() (driver.Value, error) {
+	if ee == nil {
+		ee = eventSet{}
+	}
+
+	return json.Marshal(ee)
+}
+
+func (ee *eventSet) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ee = eventSet{}
+	case []byte:
+		if err := json.Unmarshal(b, ee); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into eventSet", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package ingest
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	ingestError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID         = ingestError{"InvalidID", fault.Invalid}
+	ErrNameRequired      = ingestError{"NameRequired", fault.Invalid}
+	ErrInvalidDirectory  = ingestError{"InvalidDirectory", fault.Invalid}
+	ErrInvalidPattern    = ingestError{"InvalidPattern", fault.Invalid}
+	ErrInvalidFormat     = ingestError{"InvalidFormat", fault.Invalid}
+	ErrInvalidOnError    = ingestError{"InvalidOnError", fault.Invalid}
+	ErrMappingRequired   = ingestError{"MappingRequired", fault.Invalid}
+	ErrInvalidField      = ingestError{"InvalidField", fault.Invalid}
+	ErrNotConfigured     = ingestError{"NotConfigured", fault.Unavailable}
+	ErrUnsupportedFormat = ingestError{"UnsupportedFormat", fault.Invalid}
+	ErrNoPermissions     = ingestError{"NoPermissions", fault.Forbidden}
+	ErrProfileNotFound   = ingestError{"ProfileNotFound", fault.NotFound}
+)
+
+func (e ingestError) Error() string {
+	return e.String()
+}
+
+func (e ingestError) String() string {
+	return "crust.ingest." + e.name
+}
+
+func (e ingestError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e ingestError) withStack() *fault.Error {
+	return fault.New(e)
+}
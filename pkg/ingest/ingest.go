@@ -0,0 +1,227 @@
+package ingest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/compose/decoder"
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	recordDecoder interface {
+		Records(fields map[string]string, Create decoder.RecordCreator) error
+	}
+)
+
+// scan processes all settled files in profile's directory
+func (svc ingestService) scan(ctx context.Context, repo *repository, p *Profile) (set RunSet, err error) {
+	root := svc.root(ctx)
+	if root == "" {
+		return nil, ErrNotConfigured.withStack()
+	}
+
+	var (
+		dir     = filepath.Join(root, p.Directory)
+		settled = time.Now().Add(-settlePeriod)
+	)
+
+	ff, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	set = RunSet{}
+	for _, f := range ff {
+		if f.IsDir() || !p.matches(f.Name()) || f.ModTime().After(settled) {
+			continue
+		}
+
+		run, err := svc.process(ctx, repo, p, dir, f.Name())
+		if err != nil {
+			svc.logger.Error("could not ingest file",
+				zap.Uint64("profileID", p.ID),
+				zap.String("file", f.Name()),
+				zap.Error(err),
+			)
+			continue
+		} else if run != nil {
+			set = append(set, run)
+		}
+	}
+
+	return
+}
+
+// process imports records from one file and archives it
+//
+// File is claimed by moving it to the processing directory first, so
+// it is never picked up twice, even when several instances share the directory.
+func (svc ingestService) process(ctx context.Context, repo *repository, p *Profile, dir, name string) (*Run, error) {
+	var (
+		processing = filepath.Join(dir, processingDir, name)
+	)
+
+	if err := os.MkdirAll(filepath.Dir(processing), 0750); err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(filepath.Join(dir, name), processing); err != nil {
+		if os.IsNotExist(err) {
+			// Claimed by someone else
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	run, err := repo.CreateRun(&Run{
+		ProfileID: p.ID,
+		File:      name,
+		Status:    RunStatusRunning,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	run.Status, run.ArchivedAs = RunStatusOK, archiveDir
+	if err = svc.load(ctx, p, run, processing); err != nil {
+		run.Status, run.ArchivedAs = RunStatusFailed, failedDir
+		run.FailReason = err.Error()
+	}
+
+	// Timestamp prevents overwriting archived files with the same name
+	run.ArchivedAs = filepath.Join(run.ArchivedAs, run.StartedAt.Format("20060102T150405")+"_"+name)
+	if err = os.MkdirAll(filepath.Join(dir, filepath.Dir(run.ArchivedAs)), 0750); err == nil {
+		err = os.Rename(processing, filepath.Join(dir, run.ArchivedAs))
+	}
+
+	if err != nil {
+		svc.logger.Error("could not archive ingested file", zap.String("file", processing), zap.Error(err))
+		run.ArchivedAs = filepath.Join(processingDir, name)
+	}
+
+	if run, err = repo.UpdateRun(run); err != nil {
+		return nil, err
+	}
+
+	svc.logger.Info("file ingested",
+		zap.Uint64("profileID", p.ID),
+		zap.String("file", name),
+		zap.String("status", string(run.Status)),
+		zap.Uint("completed", run.Completed),
+		zap.Uint("failed", run.Failed),
+	)
+
+	if err = svc.notify(ctx, p, run); err != nil {
+		svc.logger.Error("could not send ingest notification", zap.Uint64("runID", run.ID), zap.Error(err))
+	}
+
+	return run, nil
+}
+
+// load creates records from the file in the name of profile's owner
+//
+// Records go through the record service, so all validation, permissions
+// and automation apply. When profile fails on error, records created
+// before the failing entry are kept.
+func (svc ingestService) load(ctx context.Context, p *Profile, run *Run, path string) error {
+	ctx, err := runas.Compose(ctx, p.OwnedBy)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	var dec recordDecoder
+	switch p.Format.detect(run.File) {
+	case FormatCSV:
+		dec = decoder.NewFlatReader(csv.NewReader(f), f)
+	case FormatJSON:
+		dec = decoder.NewStructuredDecoder(json.NewDecoder(f), f)
+	default:
+		return ErrUnsupportedFormat.withStack()
+	}
+
+	records := service.DefaultRecord.With(ctx)
+
+	return dec.Records(p.Mapping, func(r *types.Record) error {
+		if err := ctx.Err(); err != nil {
+			// Server is shutting down; remaining entries would all fail
+			return err
+		}
+
+		r.ID = 0
+		r.NamespaceID = p.NamespaceID
+		r.ModuleID = p.ModuleID
+
+		if _, err := records.Create(r); err != nil {
+			run.Failed++
+
+			if p.OnError == service.IMPORT_ON_ERROR_FAIL {
+				return err
+			}
+
+			if run.FailReason == "" {
+				// Keep the first error to help with fixing the file
+				run.FailReason = err.Error()
+			}
+
+			return nil
+		}
+
+		run.Completed++
+		return nil
+	})
+}
+
+// notify sends run results to profile's recipients
+func (svc ingestService) notify(ctx context.Context, p *Profile, run *Run) error {
+	if len(p.Notify) == 0 {
+		return nil
+	}
+
+	var (
+		m = mail.New()
+
+		// Recipients are resolved into addresses in place
+		rcpt = append([]string{}, p.Notify...)
+
+		body = fmt.Sprintf(
+			"File %s was processed by ingest profile %q.\n\nStatus: %s\nRecords imported: %d\nRecords failed: %d\nArchived as: %s\n",
+			run.File, p.Name, run.Status, run.Completed, run.Failed, run.ArchivedAs,
+		)
+	)
+
+	if run.FailReason != "" {
+		body += "Error: " + run.FailReason + "\n"
+	}
+
+	err := service.DefaultNotification.AttachEmailRecipients(auth.SetSuperUserContext(ctx), m, "To", rcpt...)
+	if err != nil {
+		return err
+	}
+
+	m.SetHeader("Subject", fmt.Sprintf("Ingest %s: %s", run.Status, run.File))
+	m.SetBody("text/plain", body)
+
+	return mail.Send(m)
+}
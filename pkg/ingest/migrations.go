@@ -0,0 +1,53 @@
+package ingest
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200126000000.ingest",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_ingest_profile (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  rel_module       BIGINT UNSIGNED NOT NULL,
+  name             VARCHAR(255)    NOT NULL,
+  directory        VARCHAR(255)    NOT NULL,
+  pattern          VARCHAR(255)    NOT NULL DEFAULT '',
+  format           VARCHAR(16)     NOT NULL DEFAULT '',
+  mapping          JSON            NOT NULL,
+  on_error         VARCHAR(16)     NOT NULL,
+  notify           JSON            NOT NULL,
+  enabled          BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  owned_by         BIGINT UNSIGNED NOT NULL,
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       DATETIME            NULL DEFAULT NULL,
+  deleted_at       DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace, rel_module)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_ingest_run (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_profile      BIGINT UNSIGNED NOT NULL,
+  file             VARCHAR(255)    NOT NULL,
+  status           VARCHAR(16)     NOT NULL,
+  archived_as      VARCHAR(255)    NOT NULL DEFAULT '',
+  completed        INT UNSIGNED    NOT NULL DEFAULT 0,
+  failed           INT UNSIGNED    NOT NULL DEFAULT 0,
+  fail_reason      TEXT            NOT NULL,
+
+  started_at       DATETIME        NOT NULL,
+  finished_at      DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_profile, started_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,142 @@
+package ingest
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableProfile() string {
+	return "crust_compose_ingest_profile"
+}
+
+func (r repository) tableRun() string {
+	return "crust_compose_ingest_run"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_module",
+			"name",
+			"directory",
+			"pattern",
+			"format",
+			"mapping",
+			"on_error",
+			"notify",
+			"enabled",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableProfile()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(namespaceID, profileID uint64) (*Profile, error) {
+	var (
+		p = &Profile{}
+		q = r.query().Where(squirrel.Eq{"id": profileID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, p); err != nil {
+		return nil, err
+	} else if p.ID == 0 {
+		return nil, ErrProfileNotFound.withStack().WithID("namespaceID", namespaceID).WithID("profileID", profileID)
+	}
+
+	return p, nil
+}
+
+func (r repository) Find(f ProfileFilter) (set ProfileSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": f.NamespaceID, "rel_module": f.ModuleID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindEnabled returns enabled profiles of all namespaces
+func (r repository) FindEnabled() (set ProfileSet, err error) {
+	q := r.query().Where(squirrel.Eq{"enabled": true})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(p *Profile) (*Profile, error) {
+	p.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&p.CreatedAt)
+
+	return p, errors.WithStack(r.db().Insert(r.tableProfile(), p))
+}
+
+func (r repository) Update(p *Profile) (*Profile, error) {
+	rh.SetCurrentTimeRounded(&p.UpdatedAt)
+
+	return p, errors.WithStack(r.db().Replace(r.tableProfile(), p))
+}
+
+func (r repository) DeleteByID(namespaceID, profileID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableProfile(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": profileID, "rel_namespace": namespaceID},
+	)
+}
+
+// FindRuns returns the latest runs of the profile, newest first
+func (r repository) FindRuns(profileID uint64) (set RunSet, err error) {
+	q := squirrel.
+		Select("id", "rel_profile", "file", "status", "archived_as", "completed", "failed", "fail_reason", "started_at", "finished_at").
+		From(r.tableRun()).
+		Where(squirrel.Eq{"rel_profile": profileID}).
+		OrderBy("started_at DESC").
+		Limit(maxRuns)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateRun(run *Run) (*Run, error) {
+	run.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&run.StartedAt)
+
+	return run, errors.WithStack(r.db().Insert(r.tableRun(), run))
+}
+
+func (r repository) UpdateRun(run *Run) (*Run, error) {
+	rh.SetCurrentTimeRounded(&run.FinishedAt)
+
+	return run, errors.WithStack(r.db().Replace(r.tableRun(), run))
+}
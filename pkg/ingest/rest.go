@@ -0,0 +1,75 @@
+package ingest
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts ingest profile endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {moduleID} params
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("IngestProfile.List", func(r *http.Request) (interface{}, error) {
+		return DefaultIngest.With(r.Context()).Find(ProfileFilter{
+			NamespaceID: rest.ParamUint64(r, "namespaceID"),
+			ModuleID:    rest.ParamUint64(r, "moduleID"),
+		})
+	}))
+
+	r.Post("/", rest.Handler("IngestProfile.Create", func(r *http.Request) (interface{}, error) {
+		p := &Profile{}
+		if err := rest.Decode(r, p); err != nil {
+			return nil, err
+		}
+
+		p.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		p.ModuleID = rest.ParamUint64(r, "moduleID")
+		return DefaultIngest.With(r.Context()).Create(p)
+	}))
+
+	r.Get("/{profileID}", rest.Handler("IngestProfile.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultIngest.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "profileID"),
+		)
+	}))
+
+	r.Put("/{profileID}", rest.Handler("IngestProfile.Update", func(r *http.Request) (interface{}, error) {
+		p := &Profile{}
+		if err := rest.Decode(r, p); err != nil {
+			return nil, err
+		}
+
+		p.ID = rest.ParamUint64(r, "profileID")
+		p.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultIngest.With(r.Context()).Update(p)
+	}))
+
+	r.Delete("/{profileID}", rest.Handler("IngestProfile.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultIngest.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "profileID"),
+		)
+	}))
+
+	r.Get("/{profileID}/runs", rest.Handler("IngestProfile.Runs", func(r *http.Request) (interface{}, error) {
+		return DefaultIngest.With(r.Context()).Runs(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "profileID"),
+		)
+	}))
+
+	r.Post("/{profileID}/scan", rest.Handler("IngestProfile.Scan", func(r *http.Request) (interface{}, error) {
+		return DefaultIngest.With(r.Context()).Scan(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "profileID"),
+		)
+	}))
+}
@@ -0,0 +1,327 @@
+package ingest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+)
+
+type (
+	ingestService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		module   service.ModuleService
+		settings settingsGetter
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanUpdateModule(context.Context, *types.Module) bool
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	IngestService interface {
+		With(ctx context.Context) IngestService
+
+		FindByID(namespaceID, profileID uint64) (*Profile, error)
+		Find(ProfileFilter) (ProfileSet, error)
+		Create(*Profile) (*Profile, error)
+		Update(*Profile) (*Profile, error)
+		DeleteByID(namespaceID, profileID uint64) error
+
+		Runs(namespaceID, profileID uint64) (RunSet, error)
+		Scan(namespaceID, profileID uint64) (RunSet, error)
+	}
+)
+
+const (
+	// Compose setting with the ingestion root directory
+	//
+	// Profile directories are relative to it; when it points to the
+	// chroot of an SFTP/FTP server, partners can drop files there directly.
+	settingRoot = "crust.ingest.root"
+)
+
+var (
+	DefaultIngest IngestService
+
+	// Record fields that can be set from the mapping, besides module fields
+	systemFields = map[string]bool{
+		"ownedBy": true,
+	}
+)
+
+// Init initializes ingest service and starts watching profile directories
+// in the background
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &ingestService{
+		logger:   log,
+		ac:       service.DefaultAccessControl,
+		module:   service.DefaultModule,
+		settings: service.DefaultSettings,
+	}
+
+	DefaultIngest = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc ingestService) With(ctx context.Context) IngestService {
+	return &ingestService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		ac:       svc.ac,
+		settings: svc.settings,
+
+		module: svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc ingestService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc ingestService) FindByID(namespaceID, profileID uint64) (p *Profile, err error) {
+	if profileID == 0 {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	if p, err = svc.repository.FindByID(namespaceID, profileID); err != nil {
+		return
+	}
+
+	if err = svc.canManage(namespaceID, p.ModuleID); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (svc ingestService) Find(f ProfileFilter) (ProfileSet, error) {
+	if err := svc.canManage(f.NamespaceID, f.ModuleID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Find(f)
+}
+
+func (svc ingestService) Create(in *Profile) (*Profile, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	p := &Profile{
+		NamespaceID: in.NamespaceID,
+		ModuleID:    in.ModuleID,
+		Name:        in.Name,
+		Directory:   in.Directory,
+		Pattern:     in.Pattern,
+		Format:      in.Format,
+		Mapping:     in.Mapping,
+		OnError:     in.OnError,
+		Notify:      in.Notify,
+		Enabled:     in.Enabled,
+		OwnedBy:     auth.GetIdentityFromContext(svc.ctx).Identity(),
+	}
+
+	return svc.repository.Create(p)
+}
+
+func (svc ingestService) Update(upd *Profile) (*Profile, error) {
+	p, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Module can not be changed
+	upd.ModuleID = p.ModuleID
+
+	if err = svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	p.Name = upd.Name
+	p.Directory = upd.Directory
+	p.Pattern = upd.Pattern
+	p.Format = upd.Format
+	p.Mapping = upd.Mapping
+	p.OnError = upd.OnError
+	p.Notify = upd.Notify
+	p.Enabled = upd.Enabled
+
+	return svc.repository.Update(p)
+}
+
+func (svc ingestService) DeleteByID(namespaceID, profileID uint64) error {
+	p, err := svc.repository.FindByID(namespaceID, profileID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.canManage(namespaceID, p.ModuleID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, profileID)
+}
+
+// Runs returns the latest runs of the profile
+func (svc ingestService) Runs(namespaceID, profileID uint64) (RunSet, error) {
+	p, err := svc.FindByID(namespaceID, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindRuns(p.ID)
+}
+
+// Scan processes files waiting in profile's directory right away,
+// without waiting for the next watcher tick
+func (svc ingestService) Scan(namespaceID, profileID uint64) (RunSet, error) {
+	p, err := svc.FindByID(namespaceID, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.scan(svc.ctx, svc.repository, p)
+}
+
+func (svc ingestService) canManage(namespaceID, moduleID uint64) error {
+	m, err := svc.module.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+func (svc ingestService) validate(p *Profile) error {
+	if p.NamespaceID == 0 {
+		return service.ErrNamespaceRequired
+	}
+
+	if p.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	m, err := svc.module.FindByID(p.NamespaceID, p.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	// Directory must stay inside the ingestion root
+	p.Directory = filepath.Clean(p.Directory)
+	if p.Directory == "." || filepath.IsAbs(p.Directory) || strings.HasPrefix(p.Directory, "..") {
+		return ErrInvalidDirectory.withStack()
+	}
+
+	if p.Pattern != "" {
+		if _, err = filepath.Match(p.Pattern, ""); err != nil {
+			return ErrInvalidPattern.withStack()
+		}
+	}
+
+	if !p.Format.IsValid() {
+		return ErrInvalidFormat.withStack()
+	}
+
+	switch p.OnError {
+	case "":
+		p.OnError = service.IMPORT_ON_ERROR_SKIP
+	case service.IMPORT_ON_ERROR_SKIP, service.IMPORT_ON_ERROR_FAIL:
+	default:
+		return ErrInvalidOnError.withStack()
+	}
+
+	if len(p.Mapping) == 0 {
+		return ErrMappingRequired.withStack()
+	}
+
+	for _, name := range p.Mapping {
+		if !systemFields[name] && !m.Fields.HasName(name) {
+			return ErrInvalidField.withStack()
+		}
+	}
+
+	return nil
+}
+
+// root returns configured ingestion root directory
+func (svc ingestService) root(ctx context.Context) string {
+	v, err := svc.settings.Get(auth.SetSuperUserContext(ctx), settingRoot, 0)
+	if err != nil {
+		svc.logger.Error("could not load ingestion root setting", zap.Error(err))
+		return ""
+	} else if v == nil {
+		return ""
+	}
+
+	return v.String()
+}
+
+// watch periodically processes files in directories of enabled profiles
+func (svc ingestService) watch(ctx context.Context) {
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.scanAll(ctx)
+		}
+	}
+}
+
+func (svc ingestService) scanAll(ctx context.Context) {
+	if svc.root(ctx) == "" {
+		// Ingestion is not configured
+		return
+	}
+
+	repo := Repository(ctx, factory.Database.MustGet("compose").With(ctx))
+
+	set, err := repo.FindEnabled()
+	if err != nil {
+		svc.logger.Error("could not load ingest profiles", zap.Error(err))
+		return
+	}
+
+	for _, p := range set {
+		if _, err = svc.scan(ctx, repo, p); err != nil {
+			svc.logger.Error("could not scan ingest directory", zap.Uint64("profileID", p.ID), zap.Error(err))
+		}
+	}
+}
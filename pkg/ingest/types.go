@@ -0,0 +1,179 @@
+package ingest
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Profile watches a directory for CSV/JSON drops and imports them as module records
+	Profile struct {
+		ID          uint64 `json:"profileID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+
+		Name string `json:"name" db:"name"`
+
+		// Directory, relative to the ingestion root, and pattern of file names to pick up
+		Directory string `json:"directory" db:"directory"`
+		Pattern   string `json:"pattern" db:"pattern"`
+
+		// Format of the files (csv or json); detected from the file extension when empty
+		Format Format `json:"format" db:"format"`
+
+		// Maps columns (CSV) or keys (JSON) to module fields
+		Mapping Mapping `json:"mapping" db:"mapping"`
+
+		// What to do when entry can not be imported: SKIP it or FAIL the whole file
+		OnError string `json:"onError" db:"on_error"`
+
+		// Who is notified about results (user IDs or email addresses)
+		Notify Recipients `json:"notify" db:"notify"`
+
+		Enabled bool `json:"enabled" db:"enabled"`
+
+		// Records are imported in the name of the owner
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	ProfileFilter struct {
+		NamespaceID uint64 `json:"namespaceID,string"`
+		ModuleID    uint64 `json:"moduleID,string"`
+	}
+
+	ProfileSet []*Profile
+
+	// Run is a result of processing one file
+	Run struct {
+		ID        uint64    `json:"runID,string" db:"id"`
+		ProfileID uint64    `json:"profileID,string" db:"rel_profile"`
+		File      string    `json:"file" db:"file"`
+		Status    RunStatus `json:"status" db:"status"`
+
+		// Where the file was moved after processing, relative to profile's directory
+		ArchivedAs string `json:"archivedAs,omitempty" db:"archived_as"`
+
+		Completed  uint   `json:"completed" db:"completed"`
+		Failed     uint   `json:"failed" db:"failed"`
+		FailReason string `json:"failReason,omitempty" db:"fail_reason"`
+
+		StartedAt  time.Time  `json:"startedAt" db:"started_at"`
+		FinishedAt *time.Time `json:"finishedAt,omitempty" db:"finished_at"`
+	}
+
+	RunSet []*Run
+
+	RunStatus string
+
+	Format string
+
+	Mapping map[string]string
+
+	Recipients []string
+)
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+
+	RunStatusRunning RunStatus = "running"
+	RunStatusOK      RunStatus = "ok"
+	RunStatusFailed  RunStatus = "failed"
+
+	// Subdirectories of profile's directory
+	processingDir = "processing"
+	archiveDir    = "archive"
+	failedDir     = "failed"
+
+	// How often directories are scanned
+	watchInterval = time.Minute
+
+	// Files modified more recently are considered incomplete (upload in progress)
+	settlePeriod = 30 * time.Second
+
+	maxRuns = 100
+)
+
+func (f Format) IsValid() bool {
+	return f == "" || f == FormatCSV || f == FormatJSON
+}
+
+// detect returns format of the file; profile's format takes precedence over file extension
+func (f Format) detect(file string) Format {
+	if f != "" {
+		return f
+	}
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	}
+
+	return ""
+}
+
+// matches checks if file should be picked up by the profile
+func (p Profile) matches(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+
+	if p.Pattern != "" {
+		ok, _ := filepath.Match(p.Pattern, name)
+		return ok
+	}
+
+	return p.Format.detect(name) != ""
+}
+
+func (m Mapping) Value() (driver.Value, error) {
+	if m == nil {
+		m = Mapping{}
+	}
+
+	return json.Marshal(m)
+}
+
+func (m *Mapping) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*m = Mapping{}
+	case []byte:
+		if err := json.Unmarshal(b, m); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Mapping", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (rr Recipients) Value() (driver.Value, error) {
+	if rr == nil {
+		rr = Recipients{}
+	}
+
+	return json.Marshal(rr)
+}
+
+func (rr *Recipients) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*rr = Recipients{}
+	case []byte:
+		if err := json.Unmarshal(b, rr); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Recipients", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package live
+
+import (
+	"context"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+// authorize checks if the user can subscribe to the scope
+//
+// Channels and records must be readable, records of modules as well;
+// presence of any user can be subscribed to, user scope only by the user.
+// Permissions are checked only when subscribing. Module of module and
+// record scopes is returned.
+func authorize(ctx context.Context, s scope) (*composeTypes.Module, error) {
+	switch s.kind {
+	case ScopeChannel:
+		if messagingService.DefaultChannel == nil {
+			// Messaging is not bundled with the server
+			return nil, ErrInvalidScope.withStack()
+		}
+
+		_, err := messagingService.DefaultChannel.With(ctx).FindByID(s.ids[0])
+		return nil, err
+
+	case ScopeModule, ScopeRecord:
+		if composeService.DefaultModule == nil {
+			// Compose is not bundled with the server
+			return nil, ErrInvalidScope.withStack()
+		}
+
+		moduleID := s.ids[1]
+		if s.kind == ScopeRecord {
+			r, err := composeService.DefaultRecord.With(ctx).FindByID(s.ids[0], s.ids[1])
+			if err != nil {
+				return nil, err
+			}
+
+			moduleID = r.ModuleID
+		}
+
+		m, err := composeService.DefaultModule.With(ctx).FindByID(s.ids[0], moduleID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !composeService.DefaultAccessControl.CanReadRecord(ctx, m) {
+			return nil, ErrNoPermissions.withStack().WithID("moduleID", m.ID)
+		}
+
+		return m, nil
+
+	case ScopeUser:
+		if auth.GetIdentityFromContext(ctx).Identity() != s.ids[0] {
+			return nil, ErrNoPermissions.withStack().WithID("userID", s.ids[0])
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+package live
+
+import (
+	"encoding/json"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+)
+
+// publish broadcasts client's field event to other viewers of the record
+// or typing event to other members of the channel, see publishTyping
+//
+// Connection must be subscribed to the record. Viewers get events of
+// fields they can read; changes can be published only by users that
+// can update the field. Events are not stored, values are saved by
+// updating the record.
+func (c *conn) publish(ce *clientEvent) error {
+	sc, err := parseScope(ce.Scope)
+	if err != nil {
+		return err
+	}
+
+	switch sc.kind {
+	case ScopeChannel:
+		return c.publishTyping(sc, ce)
+	case ScopeRecord:
+	default:
+		return ErrInvalidEvent.withStack()
+	}
+
+	s := sc.String()
+
+	c.Lock()
+	m := c.modules[s]
+	c.Unlock()
+
+	if m == nil {
+		return ErrNotSubscribed.withStack()
+	}
+
+	var p FieldEvent
+	if err = json.Unmarshal(ce.Payload, &p); err != nil || p.Field == "" {
+		return ErrInvalidEvent.withStack()
+	}
+
+	f := m.Fields.FindByName(p.Field)
+	if f == nil {
+		return ErrFieldNotFound.withStack().WithID("moduleID", m.ID)
+	}
+
+	ac := composeService.DefaultAccessControl
+
+	switch ce.Type {
+	case EventFieldFocus, EventFieldBlur:
+		p.Value = nil
+		if !ac.CanReadRecordValue(c.ctx, f) {
+			return ErrNoPermissions.withStack()
+		}
+
+	case EventFieldChange:
+		if !ac.CanUpdateRecord(c.ctx, m) || !ac.CanUpdateRecordValue(c.ctx, f) {
+			return ErrNoPermissions.withStack()
+		}
+
+	default:
+		return ErrInvalidEvent.withStack()
+	}
+
+	p.UserID = c.userID
+
+	c.hub.broadcast(&Event{Scope: s, Type: ce.Type, Payload: &p}, func(viewer *conn) bool {
+		return viewer != c && ac.CanReadRecordValue(viewer.ctx, f)
+	})
+
+	return nil
+}
@@ -0,0 +1,355 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// conn is websocket connection of a user
+	conn struct {
+		sync.Mutex
+
+		ctx    context.Context
+		ws     *websocket.Conn
+		hub    *hub
+		userID uint64
+		logger *zap.Logger
+
+		out    chan *frame
+		closed bool
+		subs   map[string]bool
+
+		// Modules of module and record scopes, for field permissions
+		modules map[string]*composeTypes.Module
+
+		// Scopes with dropped events, client is told to reload them
+		stale map[string]bool
+
+		// Resume token of the slow consumer, closes the connection
+		kick chan string
+
+		// Presence session of the connection and its status, online or away
+		session string
+		status  string
+
+		// Typing of the user, stops when timers fire; by channel and thread
+		typing map[typingKey]*time.Timer
+	}
+)
+
+const (
+	// Clients must answer pings, connections are closed otherwise
+	pingInterval = 30 * time.Second
+	pongTimeout  = 60 * time.Second
+	writeTimeout = 10 * time.Second
+
+	// Commands are small, larger messages close the connection
+	maxCommandSize = 64 << 10
+)
+
+// run reads commands and writes events until the connection is closed
+func (c *conn) run() {
+	c.hub.connected(c)
+	defer c.hub.disconnected(c)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.writeLoop()
+	}()
+
+	c.readLoop()
+
+	// Stops the write loop
+	c.Lock()
+	c.closed = true
+	close(c.out)
+	c.Unlock()
+
+	<-done
+}
+
+func (c *conn) readLoop() {
+	c.ws.SetReadLimit(maxCommandSize)
+	_ = c.ws.SetReadDeadline(time.Now().Add(pongTimeout))
+	c.ws.SetPongHandler(func(string) error {
+		// Pongs are heartbeats of the presence session
+		c.hub.presence.beat(c)
+		return c.ws.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	for {
+		_, raw, err := c.ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var cmd command
+		if err = json.Unmarshal(raw, &cmd); err != nil {
+			c.reply(nil, ErrInvalidCommand.withStack())
+			continue
+		}
+
+		c.handle(cmd)
+	}
+}
+
+func (c *conn) writeLoop() {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	defer c.ws.Close()
+
+	for {
+		select {
+		case f, ok := <-c.out:
+			if !ok {
+				_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+				return
+			}
+
+			if err := c.write(f); err != nil {
+				return
+			}
+
+			// Queue is drained, client can catch up on scopes with dropped events
+			if len(c.out) == 0 {
+				for _, s := range c.takeStale() {
+					f, _ := encode(&Event{Scope: s, Type: EventStale})
+					if err := c.write(f); err != nil {
+						return
+					}
+				}
+			}
+		case token := <-c.kick:
+			_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(CloseSlowConsumer, token), time.Now().Add(writeTimeout))
+			return
+		case <-ping.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *conn) write(f *frame) error {
+	_ = c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if err := c.ws.WritePreparedMessage(f); err != nil {
+		return err
+	}
+
+	metricSent.Inc()
+	return nil
+}
+
+// handle subscribes & unsubscribes the connection, publishes client's
+// events and changes its status
+//
+// Scopes that can not be subscribed to are reported in the reply,
+// others are subscribed to.
+func (c *conn) handle(cmd command) {
+	var ee []*scopeError
+
+	for _, s := range cmd.Unsubscribe {
+		if sc, err := parseScope(s); err == nil {
+			c.Lock()
+			delete(c.subs, sc.String())
+			delete(c.modules, sc.String())
+			c.Unlock()
+
+			c.hub.unsubscribe(c, sc.String())
+		}
+	}
+
+	for _, s := range cmd.Subscribe {
+		if err := c.subscribe(s); err != nil {
+			ee = append(ee, &scopeError{Scope: s, Message: err.Error()})
+		}
+	}
+
+	if cmd.Publish != nil {
+		if err := c.publish(cmd.Publish); err != nil {
+			ee = append(ee, &scopeError{Scope: cmd.Publish.Scope, Message: err.Error()})
+		}
+	}
+
+	if cmd.Status != "" {
+		if err := c.hub.presence.status(c, cmd.Status); err != nil {
+			ee = append(ee, &scopeError{Message: err.Error()})
+		}
+	}
+
+	if (cmd.Publish != nil || cmd.Status != "") && len(ee) == 0 && cmd.Subscribe == nil && cmd.Unsubscribe == nil {
+		// Published events and statuses are not confirmed
+		return
+	}
+
+	c.reply(ee, nil)
+}
+
+func (c *conn) subscribe(s string) error {
+	sc, err := parseScope(s)
+	if err != nil {
+		return err
+	}
+
+	s = sc.String()
+
+	c.Lock()
+	switch {
+	case c.subs[s]:
+		c.Unlock()
+		return nil
+	case len(c.subs) >= subscriptionLimit:
+		c.Unlock()
+		return ErrTooManySubscriptions.withStack()
+	}
+	c.Unlock()
+
+	m, err := authorize(c.ctx, sc)
+	if err != nil {
+		c.logger.Debug("could not subscribe", zap.String("scope", s), zap.Error(err))
+		return err
+	}
+
+	c.Lock()
+	c.subs[s] = true
+	if m != nil {
+		c.modules[s] = m
+	}
+	c.Unlock()
+
+	c.hub.subscribe(c, s)
+
+	// Subscribers learn the current presence right away
+	if sc.kind == ScopePresence {
+		ss, err := c.hub.presence.find(sc.ids[0])
+		if status := ss[sc.ids[0]]; err == nil && status != StatusOffline {
+			f, _ := encode(&Event{Scope: s, Type: status, Payload: &PresenceEvent{UserID: sc.ids[0], Status: status}})
+			c.send(s, f)
+		}
+	}
+
+	return nil
+}
+
+// reply sends scopes of the connection and errors of the command
+func (c *conn) reply(ee []*scopeError, err error) {
+	r := &reply{Subscriptions: c.scopes(), Errors: ee}
+	if err != nil {
+		c.logger.Debug("invalid command", zap.Error(err))
+		r.Errors = append(r.Errors, &scopeError{Message: err.Error()})
+	}
+
+	f, _ := encode(r)
+	c.send("", f)
+}
+
+// resume subscribes to scopes of the resume token
+//
+// Events were missed while disconnected, all resumed scopes are stale.
+func (c *conn) resume(token string) {
+	ss, err := c.hub.resume(c.userID, token)
+	if err != nil {
+		c.reply(nil, err)
+		return
+	}
+
+	metricResumed.Inc()
+	c.handle(command{Subscribe: ss})
+
+	c.Lock()
+	for _, s := range ss {
+		if c.subs[s] {
+			c.stale[s] = true
+		}
+	}
+	c.Unlock()
+}
+
+// send queues the frame of the scope (empty for replies)
+//
+// When the queue is full, the frame is dropped and the connection
+// is handled by the slow consumer policy.
+func (c *conn) send(scope string, f *frame) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.closed {
+		return
+	}
+
+	select {
+	case c.out <- f:
+		return
+	default:
+		metricDropped.Inc()
+	}
+
+	switch slowConsumerPolicy {
+	case PolicyDisconnect:
+		ss := make([]string, 0, len(c.subs))
+		for s := range c.subs {
+			ss = append(ss, s)
+		}
+
+		// Nothing is sent after the kick
+		c.closed = true
+		c.kick <- c.hub.suspend(c.userID, ss)
+
+		metricSlowConsumers.WithLabelValues(PolicyDisconnect).Inc()
+		c.logger.Info("send queue full, slow consumer disconnected")
+
+	default:
+		if len(c.stale) == 0 {
+			metricSlowConsumers.WithLabelValues(PolicyDrop).Inc()
+			c.logger.Debug("send queue full, dropping events")
+		}
+
+		if scope != "" {
+			c.stale[scope] = true
+		}
+	}
+}
+
+// takeStale returns and forgets stale scopes the connection is still subscribed to
+func (c *conn) takeStale() []string {
+	c.Lock()
+	defer c.Unlock()
+
+	ss := make([]string, 0, len(c.stale))
+	for s := range c.stale {
+		if c.subs[s] {
+			ss = append(ss, s)
+		}
+	}
+
+	c.stale = map[string]bool{}
+
+	sort.Strings(ss)
+	return ss
+}
+
+// scopes returns sorted scopes the connection is subscribed to
+func (c *conn) scopes() []string {
+	c.Lock()
+	defer c.Unlock()
+
+	ss := make([]string, 0, len(c.subs))
+	for s := range c.subs {
+		ss = append(ss, s)
+	}
+
+	sort.Strings(ss)
+	return ss
+}
@@ -0,0 +1,52 @@
+package live
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+type (
+	// frame is an encoded message, ready to be written to any connection
+	frame = websocket.PreparedMessage
+)
+
+const (
+	// Buffers that grew larger are left to the GC
+	maxPooledBuffer = 64 << 10
+)
+
+var (
+	buffers = sync.Pool{
+		New: func() interface{} { return &bytes.Buffer{} },
+	}
+
+	// Subscribers of the scope, collected by broadcast
+	recipients = sync.Pool{
+		New: func() interface{} { s := make([]*conn, 0, 64); return &s },
+	}
+)
+
+// encode marshals the value to a websocket frame
+//
+// Value is marshaled into a pooled buffer; the frame holds its own copy
+// of the payload so the buffer is reused right away. Frame is encoded
+// once and shared by all connections it is written to.
+func encode(v interface{}) (*frame, error) {
+	buf := buffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			buffers.Put(buf)
+		}
+	}()
+
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	// Encoder terminates values with a newline
+	return websocket.NewPreparedMessage(websocket.TextMessage, bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}))
+}
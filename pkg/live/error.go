@@ -0,0 +1,40 @@
+package live
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	liveError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidScope         = liveError{"InvalidScope", fault.Invalid}
+	ErrTooManySubscriptions = liveError{"TooManySubscriptions", fault.TooMany}
+	ErrInvalidCommand       = liveError{"InvalidCommand", fault.Invalid}
+	ErrNoPermissions        = liveError{"NoPermissions", fault.Forbidden}
+	ErrInvalidResumeToken   = liveError{"InvalidResumeToken", fault.Invalid}
+	ErrInvalidEvent         = liveError{"InvalidEvent", fault.Invalid}
+	ErrNotSubscribed        = liveError{"NotSubscribed", fault.Invalid}
+	ErrFieldNotFound        = liveError{"FieldNotFound", fault.NotFound}
+	ErrInvalidStatus        = liveError{"InvalidStatus", fault.Invalid}
+)
+
+func (e liveError) Error() string {
+	return e.String()
+}
+
+func (e liveError) String() string {
+	return "crust.live." + e.name
+}
+
+func (e liveError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e liveError) withStack() *fault.Error {
+	return fault.New(e)
+}
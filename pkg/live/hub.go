@@ -0,0 +1,186 @@
+package live
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+type (
+	// hub keeps connections by scopes they are subscribed to
+	hub struct {
+		sync.RWMutex
+
+		logger *zap.Logger
+
+		scopes map[string]map[*conn]bool
+
+		presence *presence
+
+		// Subscriptions of disconnected slow consumers, by resume token
+		suspended map[string]*suspended
+	}
+)
+
+func newHub(log *zap.Logger, p *presence) *hub {
+	return &hub{
+		logger:   log,
+		scopes:   map[string]map[*conn]bool{},
+		presence: p,
+
+		suspended: map[string]*suspended{},
+	}
+}
+
+// Publish sends the event to connections subscribed to its scope
+//
+// Event is encoded once, only when there are subscribers.
+func Publish(e *Event) {
+	if defaultHub != nil {
+		defaultHub.publish(e)
+	}
+}
+
+// Subscribed checks if any connection is subscribed to the scope
+//
+// Publishers use it to skip preparing events that nobody would get.
+func Subscribed(scope string) bool {
+	if defaultHub == nil {
+		return false
+	}
+
+	defaultHub.RLock()
+	defer defaultHub.RUnlock()
+	return len(defaultHub.scopes[scope]) > 0
+}
+
+// SubscribedUsers returns IDs of users subscribed to their user scopes
+//
+// Publishers use it to send periodic events only to users that would get them.
+func SubscribedUsers() []uint64 {
+	if defaultHub == nil {
+		return nil
+	}
+
+	defaultHub.RLock()
+	defer defaultHub.RUnlock()
+
+	out := []uint64{}
+	for s, cc := range defaultHub.scopes {
+		if len(cc) == 0 {
+			continue
+		}
+
+		if sc, err := parseScope(s); err == nil && sc.kind == ScopeUser {
+			out = append(out, sc.ids[0])
+		}
+	}
+
+	return out
+}
+
+// subscribedTo checks if any connection is subscribed to a scope of the kind
+func (h *hub) subscribedTo(kind string) bool {
+	h.RLock()
+	defer h.RUnlock()
+
+	for s := range h.scopes {
+		if len(s) > len(kind) && s[:len(kind)+1] == kind+":" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *hub) publish(e *Event) {
+	h.broadcast(e, nil)
+}
+
+// broadcast sends the event to connections subscribed to its scope that pass the filter
+//
+// Event is encoded once into a frame that is written to all recipients.
+func (h *hub) broadcast(e *Event, filter func(*conn) bool) {
+	rp := recipients.Get().(*[]*conn)
+	defer func() {
+		// Connections are not kept alive by the pool
+		for i := range *rp {
+			(*rp)[i] = nil
+		}
+
+		*rp = (*rp)[:0]
+		recipients.Put(rp)
+	}()
+
+	cc := *rp
+
+	h.RLock()
+	for c := range h.scopes[e.Scope] {
+		if filter == nil || filter(c) {
+			cc = append(cc, c)
+		}
+	}
+	h.RUnlock()
+
+	// Pool keeps the grown slice
+	*rp = cc
+
+	if len(cc) == 0 {
+		return
+	}
+
+	f, err := encode(e)
+	if err != nil {
+		h.logger.Error("could not encode event", zap.String("scope", e.Scope), zap.String("type", e.Type), zap.Error(err))
+		return
+	}
+
+	metricEncoded.Inc()
+
+	for _, c := range cc {
+		c.send(e.Scope, f)
+	}
+}
+
+func (h *hub) subscribe(c *conn, scope string) {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.scopes[scope] == nil {
+		h.scopes[scope] = map[*conn]bool{}
+	}
+
+	h.scopes[scope][c] = true
+}
+
+func (h *hub) unsubscribe(c *conn, scope string) {
+	h.Lock()
+	defer h.Unlock()
+
+	if delete(h.scopes[scope], c); len(h.scopes[scope]) == 0 {
+		delete(h.scopes, scope)
+	}
+}
+
+// connected starts connection's presence session, user is online
+func (h *hub) connected(c *conn) {
+	metricConnections.Inc()
+	h.presence.connected(c)
+}
+
+// disconnected removes connection's subscriptions and ends its typing
+// and presence session, user without sessions is offline
+func (h *hub) disconnected(c *conn) {
+	metricConnections.Dec()
+
+	h.Lock()
+	for _, s := range c.scopes() {
+		if delete(h.scopes[s], c); len(h.scopes[s]) == 0 {
+			delete(h.scopes, s)
+		}
+	}
+	h.Unlock()
+
+	c.stopTyping()
+	h.presence.disconnected(c)
+}
@@ -0,0 +1,90 @@
+package live
+
+import (
+	"context"
+	"io"
+
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+)
+
+type (
+	// message wraps message service and publishes message events
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with publishing of message events
+//
+// Events are published to the channel's scope after messages are created,
+// updated or deleted.
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Create(m)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.publish(EventMessageCreated, m.ChannelID, m)
+	return m, nil
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.CreateWithAvatar(m, avatar)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.publish(EventMessageCreated, m.ChannelID, m)
+	return m, nil
+}
+
+func (svc message) Update(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Update(m)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.publish(EventMessageUpdated, m.ChannelID, m)
+	return m, nil
+}
+
+func (svc message) Delete(messageID uint64) error {
+	channelID, err := Repository(svc.ctx, nil).MessageChannel(messageID)
+	if err != nil {
+		logger.AddRequestID(svc.ctx, defaultHub.logger).
+			Error("could not load channel of the message", zap.Uint64("messageID", messageID), zap.Error(err))
+	}
+
+	if err = svc.MessageService.Delete(messageID); err != nil {
+		return err
+	}
+
+	if channelID > 0 {
+		svc.publish(EventMessageDeleted, channelID, &messagingTypes.Message{ID: messageID, ChannelID: channelID})
+	}
+
+	return nil
+}
+
+func (svc message) publish(typ string, channelID uint64, m *messagingTypes.Message) {
+	if s := ChannelScope(channelID); Subscribed(s) {
+		Publish(&Event{Scope: s, Type: typ, Payload: payload.Message(svc.ctx, m)})
+	}
+}
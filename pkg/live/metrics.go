@@ -0,0 +1,54 @@
+package live
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "crust_live_connections",
+		Help: "Number of open websocket connections.",
+	})
+
+	metricEncoded = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_live_events_encoded_total",
+		Help: "Number of broadcast events encoded, once per event regardless of the number of recipients.",
+	})
+
+	metricSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_live_messages_sent_total",
+		Help: "Number of events and replies written to connections.",
+	})
+
+	metricDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_live_messages_dropped_total",
+		Help: "Number of events and replies dropped because send queues were full.",
+	})
+
+	metricSlowConsumers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "crust_live_slow_consumers_total",
+		Help: "Number of times connections fell behind, by slow consumer policy.",
+	}, []string{"policy"})
+
+	metricResumed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_live_resumed_total",
+		Help: "Number of connections that resumed subscriptions with a resume token.",
+	})
+
+	metricRelayDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_live_relay_dropped_total",
+		Help: "Number of presence and typing events that could not be forwarded to other nodes.",
+	})
+)
+
+func registerMetrics() {
+	prometheus.MustRegister(
+		metricConnections,
+		metricEncoded,
+		metricSent,
+		metricDropped,
+		metricSlowConsumers,
+		metricResumed,
+		metricRelayDropped,
+	)
+}
@@ -0,0 +1,330 @@
+package live
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+)
+
+type (
+	// PresenceStore keeps statuses of users' sessions (websocket connections)
+	//
+	// Sessions are kept until they expire, connections renew them with
+	// every heartbeat. Stores that are shared by nodes make presence
+	// consistent across the cluster.
+	PresenceStore interface {
+		Set(userID uint64, session, status string, ttl time.Duration) error
+		Remove(userID uint64, session string) error
+
+		// Find returns statuses of the users; users without sessions are offline
+		Find(userIDs ...uint64) (map[uint64]string, error)
+	}
+
+	// presence tracks statuses of users and publishes their changes
+	presence struct {
+		sync.Mutex
+
+		logger *zap.Logger
+		store  PresenceStore
+
+		// Forwards presence and typing events to other nodes, when clustered
+		relay *relay
+
+		// Last published statuses of users that are not offline
+		known map[uint64]string
+	}
+
+	// memoryStore keeps sessions of this node only
+	memoryStore struct {
+		sync.Mutex
+		sessions map[uint64]map[string]*session
+	}
+
+	session struct {
+		status  string
+		expires time.Time
+	}
+)
+
+const (
+	PresenceMemory = "memory"
+	PresenceRedis  = "redis"
+)
+
+var (
+	// Presence stores by LIVE_PRESENCE_STORE; stores other than memory
+	// forward presence and typing events to other nodes as well
+	PresenceStores = map[string]func(*options.PubSubOpt) PresenceStore{
+		PresenceMemory: func(*options.PubSubOpt) PresenceStore { return newMemoryStore() },
+		PresenceRedis:  func(opt *options.PubSubOpt) PresenceStore { return newRedisStore(opt) },
+	}
+
+	// used by connections, service decorators and the user middleware
+	defaultPresence *presence
+
+	// Sessions expire when they miss heartbeats (pongs) for this long
+	presenceTTL = 2 * pongTimeout
+
+	// How often statuses of known users are checked for sessions that expired
+	sweepInterval = pingInterval
+)
+
+// InitPresence initializes presence tracking without the hub
+//
+// Presence of users is added to system's user payloads. Presence is
+// tracked by connections to the live endpoint of the messaging or
+// compose app; when running as a separate app, presence store must
+// be shared (LIVE_PRESENCE_STORE=redis), otherwise everyone is offline.
+func InitPresence(ctx context.Context, log *zap.Logger) error {
+	initPresence(ctx, log)
+	return nil
+}
+
+// Presence returns statuses of the users
+func Presence(userIDs ...uint64) (map[uint64]string, error) {
+	if defaultPresence == nil {
+		out := map[uint64]string{}
+		for _, ID := range userIDs {
+			out[ID] = StatusOffline
+		}
+
+		return out, nil
+	}
+
+	return defaultPresence.find(userIDs...)
+}
+
+func initPresence(ctx context.Context, log *zap.Logger) {
+	if defaultPresence != nil {
+		return
+	}
+
+	var (
+		opt  = options.PubSub("")
+		kind = options.EnvString("", "LIVE_PRESENCE_STORE", PresenceMemory)
+	)
+
+	presenceTTL = options.EnvDuration("", "LIVE_PRESENCE_TTL", presenceTTL)
+
+	mk, ok := PresenceStores[kind]
+	if !ok {
+		log.Warn("unknown presence store, keeping presence in memory", zap.String("store", kind))
+		kind, mk = PresenceMemory, PresenceStores[PresenceMemory]
+	}
+
+	p := &presence{
+		logger: log,
+		store:  mk(opt),
+		known:  map[uint64]string{},
+	}
+
+	if kind != PresenceMemory {
+		p.relay = newRelay(log, opt)
+		go p.relay.forward(ctx)
+		go p.relay.subscribe(ctx, p)
+	}
+
+	defaultPresence = p
+
+	go p.sweep(ctx)
+}
+
+// connected starts a session of the connection, online
+func (p *presence) connected(c *conn) {
+	p.set(c, StatusOnline)
+}
+
+// beat renews the session of the connection, with its current status
+func (p *presence) beat(c *conn) {
+	c.Lock()
+	status := c.status
+	c.Unlock()
+
+	p.set(c, status)
+}
+
+// status changes status of the connection's session
+func (p *presence) status(c *conn, status string) error {
+	if status != StatusOnline && status != StatusAway {
+		return ErrInvalidStatus.withStack()
+	}
+
+	c.Lock()
+	c.status = status
+	c.Unlock()
+
+	p.set(c, status)
+	return nil
+}
+
+// disconnected ends the session, user without sessions is offline
+func (p *presence) disconnected(c *conn) {
+	if err := p.store.Remove(c.userID, c.session); err != nil {
+		p.logger.Error("could not remove session", zap.Uint64("userID", c.userID), zap.Error(err))
+	}
+
+	p.update(c.userID)
+}
+
+func (p *presence) set(c *conn, status string) {
+	if err := p.store.Set(c.userID, c.session, status, presenceTTL); err != nil {
+		p.logger.Error("could not store session", zap.Uint64("userID", c.userID), zap.Error(err))
+		return
+	}
+
+	p.update(c.userID)
+}
+
+// update publishes user's status when it changed
+func (p *presence) update(userID uint64) {
+	ss, err := p.store.Find(userID)
+	if err != nil {
+		p.logger.Error("could not load presence", zap.Uint64("userID", userID), zap.Error(err))
+		return
+	}
+
+	if p.changed(userID, ss[userID]) && p.relay != nil {
+		p.relay.publish(&Event{Scope: PresenceScope(userID), Type: ss[userID], Payload: &PresenceEvent{UserID: userID, Status: ss[userID]}})
+	}
+}
+
+// changed publishes presence event to connections of this node when
+// user's status is not the one published last
+func (p *presence) changed(userID uint64, status string) bool {
+	p.Lock()
+	if p.known[userID] == status || (p.known[userID] == "" && status == StatusOffline) {
+		p.Unlock()
+		return false
+	}
+
+	if status == StatusOffline {
+		delete(p.known, userID)
+	} else {
+		p.known[userID] = status
+	}
+	p.Unlock()
+
+	Publish(&Event{Scope: PresenceScope(userID), Type: status, Payload: &PresenceEvent{UserID: userID, Status: status}})
+	return true
+}
+
+func (p *presence) find(userIDs ...uint64) (map[uint64]string, error) {
+	return p.store.Find(userIDs...)
+}
+
+// sweep periodically checks statuses of known users
+//
+// Users of nodes that went away (and of connections that missed
+// heartbeats) go offline when their sessions expire. Every node
+// sweeps for its own connections, changes are not forwarded.
+func (p *presence) sweep(ctx context.Context) {
+	defer sentry.Recover()
+
+	t := time.NewTicker(sweepInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			p.Lock()
+			ids := make([]uint64, 0, len(p.known))
+			for ID := range p.known {
+				ids = append(ids, ID)
+			}
+			p.Unlock()
+
+			if len(ids) == 0 {
+				continue
+			}
+
+			ss, err := p.store.Find(ids...)
+			if err != nil {
+				p.logger.Error("could not load presence", zap.Error(err))
+				continue
+			}
+
+			for _, ID := range ids {
+				p.changed(ID, ss[ID])
+			}
+		}
+	}
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{sessions: map[uint64]map[string]*session{}}
+}
+
+func (s *memoryStore) Set(userID uint64, sessionID, status string, ttl time.Duration) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.sessions[userID] == nil {
+		s.sessions[userID] = map[string]*session{}
+	}
+
+	s.sessions[userID][sessionID] = &session{status: status, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryStore) Remove(userID uint64, sessionID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if delete(s.sessions[userID], sessionID); len(s.sessions[userID]) == 0 {
+		delete(s.sessions, userID)
+	}
+
+	return nil
+}
+
+func (s *memoryStore) Find(userIDs ...uint64) (map[uint64]string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var (
+		now = time.Now()
+		out = map[uint64]string{}
+	)
+
+	for _, ID := range userIDs {
+		for sID, ss := range s.sessions[ID] {
+			if now.After(ss.expires) {
+				delete(s.sessions[ID], sID)
+			}
+		}
+
+		if len(s.sessions[ID]) == 0 {
+			delete(s.sessions, ID)
+		}
+
+		out[ID] = aggregate(s.sessions[ID], now)
+	}
+
+	return out, nil
+}
+
+// aggregate returns status of the user from the sessions; online in any
+// session makes the user online, away in all of them away
+func aggregate(ss map[string]*session, now time.Time) string {
+	status := StatusOffline
+
+	for _, s := range ss {
+		switch {
+		case now.After(s.expires):
+			continue
+		case s.status == StatusOnline:
+			return StatusOnline
+		case s.status == StatusAway:
+			status = StatusAway
+		}
+	}
+
+	return status
+}
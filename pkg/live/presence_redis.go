@@ -0,0 +1,120 @@
+package live
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+)
+
+type (
+	// redisStore keeps sessions of all nodes in Redis
+	//
+	// Sessions of the user are fields of a hash, "<status> <expires>";
+	// hash expires with the last session that was renewed, expired
+	// sessions of live hashes are ignored and removed when read.
+	redisStore struct {
+		pool *redis.Pool
+	}
+)
+
+func newRedisStore(opt *options.PubSubOpt) *redisStore {
+	return &redisStore{
+		pool: &redis.Pool{
+			MaxIdle:     4,
+			IdleTimeout: 5 * time.Minute,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial(
+					"tcp",
+					opt.RedisAddr,
+					redis.DialConnectTimeout(opt.RedisTimeout),
+					redis.DialReadTimeout(opt.RedisTimeout),
+					redis.DialWriteTimeout(opt.RedisTimeout),
+				)
+			},
+		},
+	}
+}
+
+func (s *redisStore) Set(userID uint64, sessionID, status string, ttl time.Duration) error {
+	c := s.pool.Get()
+	defer c.Close()
+
+	var (
+		key     = presenceKey(userID)
+		expires = time.Now().Add(ttl).Unix()
+	)
+
+	_ = c.Send("MULTI")
+	_ = c.Send("HSET", key, sessionID, status+" "+strconv.FormatInt(expires, 10))
+	_ = c.Send("EXPIRE", key, int64(ttl/time.Second))
+	_, err := c.Do("EXEC")
+	return err
+}
+
+func (s *redisStore) Remove(userID uint64, sessionID string) error {
+	c := s.pool.Get()
+	defer c.Close()
+
+	_, err := c.Do("HDEL", presenceKey(userID), sessionID)
+	return err
+}
+
+func (s *redisStore) Find(userIDs ...uint64) (map[uint64]string, error) {
+	c := s.pool.Get()
+	defer c.Close()
+
+	for _, ID := range userIDs {
+		if err := c.Send("HGETALL", presenceKey(ID)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	var (
+		now     = time.Now()
+		out     = map[uint64]string{}
+		expired = map[string][]interface{}{}
+	)
+
+	for _, ID := range userIDs {
+		fields, err := redis.StringMap(c.Receive())
+		if err != nil {
+			return nil, err
+		}
+
+		ss := map[string]*session{}
+		for sID, v := range fields {
+			parts := strings.SplitN(v, " ", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			sec, _ := strconv.ParseInt(parts[1], 10, 64)
+			ss[sID] = &session{status: parts[0], expires: time.Unix(sec, 0)}
+
+			if now.After(ss[sID].expires) {
+				key := presenceKey(ID)
+				expired[key] = append(expired[key], sID)
+			}
+		}
+
+		out[ID] = aggregate(ss, now)
+	}
+
+	for key, sIDs := range expired {
+		_, _ = c.Do("HDEL", append([]interface{}{key}, sIDs...)...)
+	}
+
+	return out, nil
+}
+
+func presenceKey(userID uint64) string {
+	return "crust:presence:" + strconv.FormatUint(userID, 10)
+}
@@ -0,0 +1,83 @@
+package live
+
+import (
+	"context"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// record wraps record service and publishes record events
+	record struct {
+		composeService.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with publishing of record events
+//
+// Events are published to the module's scope after records are created,
+// updated or deleted. Events hold only IDs, subscribers load records
+// with their own permissions.
+func Record(rs composeService.RecordService) composeService.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) composeService.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *composeTypes.Record) (*composeTypes.Record, error) {
+	r, err := svc.RecordService.Create(r)
+	if err != nil {
+		return nil, err
+	}
+
+	publishRecord(EventRecordCreated, r)
+	return r, nil
+}
+
+func (svc record) Update(r *composeTypes.Record) (*composeTypes.Record, error) {
+	r, err := svc.RecordService.Update(r)
+	if err != nil {
+		return nil, err
+	}
+
+	publishRecord(EventRecordUpdated, r)
+	return r, nil
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	var r *composeTypes.Record
+
+	// Module of the record is needed for the scope,
+	// it is loaded only when anyone listens
+	if defaultHub.subscribedTo(ScopeModule) {
+		r, _ = svc.RecordService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(namespaceID, recordID)
+	}
+
+	if err := svc.RecordService.DeleteByID(namespaceID, recordID); err != nil {
+		return err
+	}
+
+	if r != nil {
+		publishRecord(EventRecordDeleted, r)
+	}
+
+	return nil
+}
+
+func publishRecord(typ string, r *composeTypes.Record) {
+	if s := ModuleScope(r.NamespaceID, r.ModuleID); Subscribed(s) {
+		Publish(&Event{Scope: s, Type: typ, Payload: &RecordEvent{
+			NamespaceID: r.NamespaceID,
+			ModuleID:    r.ModuleID,
+			RecordID:    r.ID,
+		}})
+	}
+}
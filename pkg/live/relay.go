@@ -0,0 +1,142 @@
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/crusttech/crust-server/pkg/eventbus"
+)
+
+type (
+	// relay forwards presence and typing events to other nodes
+	//
+	// Events of records and messages are published by every node that
+	// changes them and are not forwarded.
+	relay struct {
+		logger  *zap.Logger
+		backend eventbus.Backend
+		channel string
+
+		// Identifies events published by this node
+		origin uint64
+
+		// Events waiting to be published
+		out chan []byte
+	}
+
+	// relayed event, published to other nodes
+	relayed struct {
+		Origin  uint64          `json:"origin,string"`
+		Scope   string          `json:"scope"`
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+)
+
+const (
+	// Events waiting to be published, the rest are dropped while
+	// the backend is slow or unavailable
+	relayBacklog = 1024
+)
+
+var (
+	// How long to wait before the backend is subscribed to again
+	resubscribeDelay = 5 * time.Second
+)
+
+func newRelay(log *zap.Logger, opt *options.PubSubOpt) *relay {
+	return &relay{
+		logger:  log,
+		backend: eventbus.Backends[eventbus.ModeRedis](opt),
+		channel: options.EnvString("", "LIVE_RELAY_CHANNEL", "crust.live.events"),
+		origin:  factory.Sonyflake.NextID(),
+		out:     make(chan []byte, relayBacklog),
+	}
+}
+
+// publish queues the event for other nodes
+func (r *relay) publish(e *Event) {
+	p, err := json.Marshal(e.Payload)
+	if err != nil {
+		r.logger.Error("could not encode event", zap.String("scope", e.Scope), zap.Error(err))
+		return
+	}
+
+	msg, _ := json.Marshal(&relayed{Origin: r.origin, Scope: e.Scope, Type: e.Type, Payload: p})
+
+	select {
+	case r.out <- msg:
+	default:
+		metricRelayDropped.Inc()
+	}
+}
+
+// forward sends queued events to the backend, one at a time, so that
+// other nodes get them in order
+func (r *relay) forward(ctx context.Context) {
+	defer sentry.Recover()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-r.out:
+			if err := r.backend.Publish(ctx, r.channel, msg); err != nil {
+				metricRelayDropped.Inc()
+				r.logger.Warn("could not forward event", zap.Error(err))
+			}
+		}
+	}
+}
+
+// subscribe publishes events of other nodes to connections of this node,
+// until the context is canceled
+//
+// Presence events update statuses known to this node, see presence.changed.
+func (r *relay) subscribe(ctx context.Context, p *presence) {
+	defer sentry.Recover()
+
+	for {
+		err := r.backend.Subscribe(ctx, r.channel, func(msg []byte) {
+			e := &relayed{}
+			if err := json.Unmarshal(msg, e); err != nil {
+				r.logger.Warn("could not decode event", zap.Error(err))
+				return
+			}
+
+			if e.Origin == r.origin {
+				return
+			}
+
+			sc, err := parseScope(e.Scope)
+			if err != nil {
+				return
+			}
+
+			if sc.kind == ScopePresence {
+				p.changed(sc.ids[0], e.Type)
+				return
+			}
+
+			Publish(&Event{Scope: e.Scope, Type: e.Type, Payload: e.Payload})
+		})
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		r.logger.Error("subscription to live events failed, events of other nodes are not delivered", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(resubscribeDelay):
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package live
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/titpetric/factory"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+// MessageChannel returns ID of message's channel
+func (r repository) MessageChannel(messageID uint64) (channelID uint64, err error) {
+	err = r.db().Get(&channelID, "SELECT rel_channel FROM messaging_message WHERE id = ?", messageID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return channelID, err
+}
@@ -0,0 +1,61 @@
+package live
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+	"github.com/titpetric/factory"
+
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+var (
+	upgrader = websocket.Upgrader{
+		// Connections are authenticated with JWT, not with cookies
+		CheckOrigin: func(*http.Request) bool { return true },
+	}
+)
+
+// MountRoutes mounts websocket endpoint of live events
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Clients subscribe to scopes by sending commands, see command
+	//
+	// ?resume=<token> resubscribes slow consumers to scopes they had
+	// when disconnected, token is the reason of the close frame
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// Upgrader already responded
+			return
+		}
+
+		c := &conn{
+			ctx:     r.Context(),
+			ws:      ws,
+			hub:     defaultHub,
+			userID:  auth.GetIdentityFromContext(r.Context()).Identity(),
+			logger:  logger.AddRequestID(r.Context(), defaultHub.logger),
+			out:     make(chan *frame, sendQueueSize),
+			subs:    map[string]bool{},
+			modules: map[string]*composeTypes.Module{},
+			stale:   map[string]bool{},
+			kick:    make(chan string, 1),
+			session: strconv.FormatUint(factory.Sonyflake.NextID(), 10),
+			status:  StatusOnline,
+			typing:  map[typingKey]*time.Timer{},
+		}
+
+		if token := r.URL.Query().Get("resume"); token != "" {
+			c.resume(token)
+		}
+
+		c.run()
+	})
+}
@@ -0,0 +1,53 @@
+package live
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+type (
+	// suspended subscriptions of a disconnected slow consumer
+	suspended struct {
+		userID  uint64
+		scopes  []string
+		expires time.Time
+	}
+)
+
+// suspend keeps user's scopes until resumed or expired, returns the resume token
+func (h *hub) suspend(userID uint64, scopes []string) string {
+	var (
+		b   = make([]byte, 16)
+		now = time.Now()
+	)
+
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	h.Lock()
+	defer h.Unlock()
+
+	for t, s := range h.suspended {
+		if now.After(s.expires) {
+			delete(h.suspended, t)
+		}
+	}
+
+	h.suspended[token] = &suspended{userID: userID, scopes: scopes, expires: now.Add(resumeTTL)}
+	return token
+}
+
+// resume returns scopes of the token; tokens can be used once, by the same user
+func (h *hub) resume(userID uint64, token string) ([]string, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	s := h.suspended[token]
+	if s == nil || s.userID != userID || time.Now().After(s.expires) {
+		return nil, ErrInvalidResumeToken.withStack()
+	}
+
+	delete(h.suspended, token)
+	return s.scopes, nil
+}
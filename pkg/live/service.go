@@ -0,0 +1,73 @@
+package live
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+)
+
+var (
+	// used by connections and service decorators
+	defaultHub *hub
+
+	// Number of scopes a connection can subscribe to
+	subscriptionLimit = 100
+
+	// Number of messages queued for a connection, see slowConsumerPolicy
+	sendQueueSize = 256
+
+	// What happens to connections with full send queues, PolicyDrop or PolicyDisconnect
+	slowConsumerPolicy = PolicyDrop
+
+	// How long subscriptions of disconnected slow consumers are kept
+	resumeTTL = 2 * time.Minute
+)
+
+// Init initializes the hub and decorates message service with publishing
+// of message events
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	initHub(ctx, log)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+// InitRecords initializes the hub and decorates record service with publishing
+// of record events
+//
+// Must be called after compose services are initialized. When running as a
+// monolith, clients connected to either app get both message and record events.
+func InitRecords(ctx context.Context, log *zap.Logger) error {
+	initHub(ctx, log)
+
+	composeService.DefaultRecord = Record(composeService.DefaultRecord)
+	return nil
+}
+
+func initHub(ctx context.Context, log *zap.Logger) {
+	if defaultHub != nil {
+		return
+	}
+
+	subscriptionLimit = options.EnvInt("", "LIVE_MAX_SUBSCRIPTIONS", subscriptionLimit)
+	sendQueueSize = options.EnvInt("", "LIVE_SEND_QUEUE_SIZE", sendQueueSize)
+	resumeTTL = options.EnvDuration("", "LIVE_RESUME_TTL", resumeTTL)
+
+	switch p := options.EnvString("", "LIVE_SLOW_CONSUMER_POLICY", slowConsumerPolicy); p {
+	case PolicyDrop, PolicyDisconnect:
+		slowConsumerPolicy = p
+	default:
+		log.Warn("unknown slow consumer policy, dropping events", zap.String("policy", p))
+	}
+
+	registerMetrics()
+	initPresence(ctx, log)
+	defaultHub = newHub(log, defaultPresence)
+}
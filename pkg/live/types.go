@@ -0,0 +1,261 @@
+package live
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Event is sent to connections subscribed to its scope
+	//
+	// Stale event tells the client that events of the scope were dropped
+	// (or missed while disconnected) and that it should reload its data.
+	Event struct {
+		Scope   string      `json:"scope"`
+		Type    string      `json:"type"`
+		Payload interface{} `json:"payload,omitempty"`
+	}
+
+	// command is sent by the client
+	//
+	//	{"subscribe": ["channel:123", "module:1:2", "record:1:3", "presence:456", "user:789"]}
+	//	{"unsubscribe": ["channel:123"]}
+	//	{"publish": {"scope": "record:1:3", "type": "field.focus", "payload": {"field": "title"}}}
+	//	{"publish": {"scope": "channel:123", "type": "typing.start", "payload": {"threadID": "456"}}}
+	//	{"status": "away"}
+	command struct {
+		Subscribe   []string     `json:"subscribe"`
+		Unsubscribe []string     `json:"unsubscribe"`
+		Publish     *clientEvent `json:"publish"`
+
+		// Presence of the connection, online or away; clients
+		// report away when the user is idle
+		Status string `json:"status"`
+	}
+
+	// clientEvent is published by the client to other viewers of the record
+	// or members of the channel
+	clientEvent struct {
+		Scope   string          `json:"scope"`
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+
+	// reply to the command, scopes the connection is subscribed to now
+	reply struct {
+		Subscriptions []string      `json:"subscriptions"`
+		Errors        []*scopeError `json:"errors,omitempty"`
+	}
+
+	// scopeError tells the client why it could not subscribe to the scope
+	scopeError struct {
+		Scope   string `json:"scope"`
+		Message string `json:"message"`
+	}
+
+	// scope of events, kind and IDs: "channel:<channelID>",
+	// "module:<namespaceID>:<moduleID>", "record:<namespaceID>:<recordID>",
+	// "presence:<userID>" or "user:<userID>"
+	scope struct {
+		kind string
+		ids  []uint64
+	}
+
+	// RecordEvent is the payload of record events; records are not sent,
+	// subscribers may not be allowed to read all of their values
+	RecordEvent struct {
+		NamespaceID uint64 `json:"namespaceID,string"`
+		ModuleID    uint64 `json:"moduleID,string"`
+		RecordID    uint64 `json:"recordID,string"`
+	}
+
+	// PresenceEvent is the payload of presence events, status is the same
+	// as the type of the event
+	PresenceEvent struct {
+		UserID uint64 `json:"userID,string"`
+		Status string `json:"status"`
+	}
+
+	// TypingEvent is the payload of typing events, sent by members of the channel
+	TypingEvent struct {
+		ChannelID uint64 `json:"channelID,string"`
+		ThreadID  uint64 `json:"threadID,string,omitempty"`
+		UserID    uint64 `json:"userID,string"`
+	}
+
+	// ThreadEvent is the payload of thread events
+	ThreadEvent struct {
+		ChannelID uint64 `json:"channelID,string"`
+		ThreadID  uint64 `json:"threadID,string"`
+		MessageID uint64 `json:"messageID,string"`
+
+		// Author of the reply
+		UserID uint64 `json:"userID,string"`
+
+		// Unread replies of the thread, of the participant
+		Unread uint32 `json:"unread"`
+	}
+
+	// FieldEvent is the payload of field events, sent by viewers of the record
+	//
+	// Value is the (unsaved) value of the field, only for change events.
+	FieldEvent struct {
+		UserID uint64          `json:"userID,string"`
+		Field  string          `json:"field"`
+		Value  json.RawMessage `json:"value,omitempty"`
+	}
+)
+
+const (
+	ScopeChannel  = "channel"
+	ScopeModule   = "module"
+	ScopeRecord   = "record"
+	ScopePresence = "presence"
+	ScopeUser     = "user"
+
+	EventMessageCreated = "message.created"
+	EventMessageUpdated = "message.updated"
+	EventMessageDeleted = "message.deleted"
+
+	EventRecordCreated = "record.created"
+	EventRecordUpdated = "record.updated"
+	EventRecordDeleted = "record.deleted"
+
+	// Published by record locks, payload is the lock
+	EventRecordLocked   = "record.locked"
+	EventRecordUnlocked = "record.unlocked"
+
+	// Published to presence scopes when status of the user changes
+	EventOnline  = "online"
+	EventAway    = "away"
+	EventOffline = "offline"
+
+	// Statuses of users, see PresenceEvent
+	StatusOnline  = EventOnline
+	StatusAway    = EventAway
+	StatusOffline = EventOffline
+
+	// Published by clients to other viewers of the record
+	EventFieldFocus  = "field.focus"
+	EventFieldBlur   = "field.blur"
+	EventFieldChange = "field.change"
+
+	// Published by clients to other members of the channel; typing
+	// stops by itself when it is not started again for a while
+	EventTypingStart = "typing.start"
+	EventTypingStop  = "typing.stop"
+
+	// Published to user scopes of thread participants, payload is ThreadEvent
+	EventThreadReply = "thread.reply"
+
+	// Published to user scopes by counters; deltas after messages are
+	// created, snapshots after reads and periodically
+	EventCountersDelta    = "counters.delta"
+	EventCountersSnapshot = "counters.snapshot"
+
+	// Published to the channel by counters when a member reads it,
+	// payload is the member's read marker
+	EventReadMarker = "read.marker"
+
+	// Published to user scopes of moderators by flood control, payload
+	// is the restriction of the muted user
+	EventFloodMuted = "flood.muted"
+
+	// Published to the user scope of the muted, kicked or banned user,
+	// payload is the moderation action, also when it is lifted
+	EventModerationAction = "moderation.action"
+
+	// Published to user scopes by notification routing, payload is the
+	// notification or the digest of messages held back
+	EventNotification       = "notification"
+	EventNotificationDigest = "notification.digest"
+
+	// Published to the channel by image processing, payload is the
+	// attachment's new dimensions and thumbnails
+	EventAttachmentProcessed = "attachment.processed"
+
+	// Published to the channel by connectors, payload is the message
+	// with previews of issues it references
+	EventMessageUnfurled = "message.unfurled"
+
+	// Published to the user scope by slash commands, payload is the
+	// response only the user that invoked the command sees
+	EventCommandResponse = "command.response"
+
+	EventStale = "stale"
+
+	// Events are dropped when send queue is full and connection's scopes
+	// are marked stale; client is told about them once the queue is drained
+	PolicyDrop = "drop"
+
+	// Connection is closed with CloseSlowConsumer when send queue is full;
+	// the reason is a token the client can resume subscriptions with
+	PolicyDisconnect = "disconnect"
+
+	CloseSlowConsumer = 4008
+)
+
+var (
+	// Number of IDs in scopes of the kind
+	scopeIDs = map[string]int{
+		ScopeChannel:  1,
+		ScopeModule:   2,
+		ScopeRecord:   2,
+		ScopePresence: 1,
+		ScopeUser:     1,
+	}
+)
+
+// ChannelScope returns scope of channel's events
+func ChannelScope(channelID uint64) string {
+	return scope{kind: ScopeChannel, ids: []uint64{channelID}}.String()
+}
+
+// ModuleScope returns scope of events of module's records
+func ModuleScope(namespaceID, moduleID uint64) string {
+	return scope{kind: ScopeModule, ids: []uint64{namespaceID, moduleID}}.String()
+}
+
+// RecordScope returns scope of events of record's viewers
+func RecordScope(namespaceID, recordID uint64) string {
+	return scope{kind: ScopeRecord, ids: []uint64{namespaceID, recordID}}.String()
+}
+
+// PresenceScope returns scope of user's presence events
+func PresenceScope(userID uint64) string {
+	return scope{kind: ScopePresence, ids: []uint64{userID}}.String()
+}
+
+// UserScope returns scope of events for the user only
+func UserScope(userID uint64) string {
+	return scope{kind: ScopeUser, ids: []uint64{userID}}.String()
+}
+
+func parseScope(s string) (sc scope, err error) {
+	parts := strings.Split(s, ":")
+	if n, ok := scopeIDs[parts[0]]; !ok || len(parts) != n+1 {
+		return sc, ErrInvalidScope.withStack()
+	}
+
+	sc.kind = parts[0]
+	for _, p := range parts[1:] {
+		id, err := strconv.ParseUint(p, 10, 64)
+		if err != nil || id == 0 {
+			return sc, ErrInvalidScope.withStack()
+		}
+
+		sc.ids = append(sc.ids, id)
+	}
+
+	return sc, nil
+}
+
+func (s scope) String() string {
+	out := s.kind
+	for _, id := range s.ids {
+		out += ":" + strconv.FormatUint(id, 10)
+	}
+
+	return out
+}
@@ -0,0 +1,114 @@
+package live
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	// typingKey identifies what the user is typing into, channel or its thread
+	typingKey struct {
+		channelID uint64
+		threadID  uint64
+	}
+)
+
+var (
+	// Typing stops when it is not started again for this long; clients
+	// start it again every few seconds while the user is typing
+	typingTimeout = 6 * time.Second
+)
+
+// publishTyping broadcasts typing event of the user to other members of the channel
+//
+// Connection must be subscribed to the channel. Repeated starts only
+// keep the typing going, they are not broadcast.
+func (c *conn) publishTyping(sc scope, ce *clientEvent) error {
+	s := sc.String()
+
+	c.Lock()
+	subscribed := c.subs[s]
+	c.Unlock()
+
+	if !subscribed {
+		return ErrNotSubscribed.withStack()
+	}
+
+	var p TypingEvent
+	if len(ce.Payload) > 0 && json.Unmarshal(ce.Payload, &p) != nil {
+		return ErrInvalidEvent.withStack()
+	}
+
+	k := typingKey{channelID: sc.ids[0], threadID: p.ThreadID}
+
+	switch ce.Type {
+	case EventTypingStart:
+		c.Lock()
+		t, typing := c.typing[k]
+		if typing {
+			t.Reset(typingTimeout)
+		} else {
+			c.typing[k] = time.AfterFunc(typingTimeout, func() { c.typingStopped(k) })
+		}
+		c.Unlock()
+
+		if !typing {
+			c.broadcastTyping(EventTypingStart, k)
+		}
+
+	case EventTypingStop:
+		c.typingStopped(k)
+
+	default:
+		return ErrInvalidEvent.withStack()
+	}
+
+	return nil
+}
+
+// typingStopped forgets the typing and tells others that it stopped
+func (c *conn) typingStopped(k typingKey) {
+	c.Lock()
+	t, typing := c.typing[k]
+	delete(c.typing, k)
+	c.Unlock()
+
+	if !typing {
+		return
+	}
+
+	t.Stop()
+	c.broadcastTyping(EventTypingStop, k)
+}
+
+// stopTyping stops all typing of the connection, when it is closed
+func (c *conn) stopTyping() {
+	c.Lock()
+	kk := make([]typingKey, 0, len(c.typing))
+	for k := range c.typing {
+		kk = append(kk, k)
+	}
+	c.Unlock()
+
+	for _, k := range kk {
+		c.typingStopped(k)
+	}
+}
+
+// broadcastTyping sends typing event to other connections subscribed to
+// the channel, on all nodes
+func (c *conn) broadcastTyping(typ string, k typingKey) {
+	e := &Event{
+		Scope:   ChannelScope(k.channelID),
+		Type:    typ,
+		Payload: &TypingEvent{ChannelID: k.channelID, ThreadID: k.threadID, UserID: c.userID},
+	}
+
+	c.hub.broadcast(e, func(member *conn) bool {
+		return member != c
+	})
+
+	if c.hub.presence.relay != nil {
+		c.hub.presence.relay.publish(e)
+	}
+}
@@ -0,0 +1,128 @@
+package live
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+type (
+	// recorder buffers response, so that it can be changed before it is sent
+	recorder struct {
+		header http.Header
+		status int
+		body   bytes.Buffer
+	}
+)
+
+var (
+	// Users are listed and read by system's user endpoints
+	usersPath = regexp.MustCompile(`/users/?(\d+)?$`)
+)
+
+// PresenceMiddleware adds presence of users to system's user payloads
+//
+// Users that are listed or read get "presence" with their status,
+// online, away or offline.
+func PresenceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || !usersPath.MatchString(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == http.StatusOK {
+			if b, ok := withPresence(rec.body.Bytes()); ok {
+				rec.body.Reset()
+				rec.body.Write(b)
+				rec.header.Del("Content-Length")
+			}
+		}
+
+		rec.flush(w)
+	})
+}
+
+// withPresence adds presence to the user or users of the response
+func withPresence(b []byte) ([]byte, bool) {
+	var (
+		rsp map[string]interface{}
+		dec = json.NewDecoder(bytes.NewReader(b))
+	)
+
+	// Numbers are kept as they are, IDs would lose precision as float64
+	dec.UseNumber()
+	if dec.Decode(&rsp) != nil {
+		return nil, false
+	}
+
+	var (
+		uu  []map[string]interface{}
+		ids []uint64
+	)
+
+	if m, ok := rsp["response"].(map[string]interface{}); ok {
+		if set, ok := m["set"].([]interface{}); ok {
+			for _, u := range set {
+				if u, ok := u.(map[string]interface{}); ok {
+					uu = append(uu, u)
+				}
+			}
+		} else {
+			uu = append(uu, m)
+		}
+	}
+
+	for _, u := range uu {
+		// Filter has userID too, a list
+		s, _ := u["userID"].(string)
+		if ID, err := strconv.ParseUint(s, 10, 64); err == nil {
+			ids = append(ids, ID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, false
+	}
+
+	ss, err := Presence(ids...)
+	if err != nil {
+		return nil, false
+	}
+
+	for _, u := range uu {
+		s, _ := u["userID"].(string)
+		if ID, err := strconv.ParseUint(s, 10, 64); err == nil {
+			u["presence"] = ss[ID]
+		}
+	}
+
+	out, err := json.Marshal(rsp)
+	return out, err == nil
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (rec *recorder) flush(w http.ResponseWriter) {
+	for k, vv := range rec.header {
+		w.Header()[k] = vv
+	}
+
+	w.WriteHeader(rec.status)
+	_, _ = w.Write(rec.body.Bytes())
+}
@@ -0,0 +1,64 @@
+package localized
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type (
+	// preference holds caller's language preferences
+	preference struct {
+		// Caller wants values in all locales
+		all bool
+
+		// Preferred locales, most preferred first
+		locales []string
+	}
+
+	contextKey struct{}
+)
+
+// Middleware reads caller's language preferences and stores them into context
+//
+// Locale can be set explicitly with ?locale= query param (use "all" to
+// get values in all locales), otherwise Accept-Language header is used.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			p      = preference{}
+			locale = strings.TrimSpace(r.URL.Query().Get("locale"))
+		)
+
+		switch {
+		case locale == "all" || locale == "*":
+			p.all = true
+		case locale != "":
+			if locale = normalizeLocale(locale); locale != "" {
+				p.locales = []string{locale}
+			}
+		default:
+			p.locales = parseAcceptLanguage(r.Header.Get("Accept-Language"))
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, p)))
+	})
+}
+
+func preferenceFromContext(ctx context.Context) preference {
+	if p, ok := ctx.Value(contextKey{}).(preference); ok {
+		return p
+	}
+
+	// Outside of HTTP requests (automation, imports...) raw values are used
+	return preference{all: true}
+}
+
+// locale returns locale that submitted plain text values are stored under
+func (p preference) locale() string {
+	if len(p.locales) > 0 {
+		return p.locales[0]
+	}
+
+	return DefaultLocale
+}
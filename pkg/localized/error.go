@@ -0,0 +1,32 @@
+package localized
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	localizedError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidLocale = localizedError{"InvalidLocale", fault.Invalid}
+)
+
+func (e localizedError) Error() string {
+	return e.String()
+}
+
+func (e localizedError) String() string {
+	return "crust.localized." + e.name
+}
+
+func (e localizedError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e localizedError) withStack() *fault.Error {
+	return fault.New(e)
+}
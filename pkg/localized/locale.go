@@ -0,0 +1,72 @@
+package localized
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	localeMatcher = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+)
+
+// normalizeLocale converts locale into canonical (lowercase, dash separated) form
+//
+// Returns empty string for invalid locales
+func normalizeLocale(l string) string {
+	l = strings.ToLower(strings.Replace(strings.TrimSpace(l), "_", "-", -1))
+	if !localeMatcher.MatchString(l) {
+		return ""
+	}
+
+	return l
+}
+
+// baseLocale returns language part of the locale (en for en-us)
+func baseLocale(l string) string {
+	if i := strings.Index(l, "-"); i > 0 {
+		return l[:i]
+	}
+
+	return l
+}
+
+// parseAcceptLanguage returns locales from Accept-Language header, ordered by quality
+func parseAcceptLanguage(h string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var ww []weighted
+	for _, part := range strings.Split(h, ",") {
+		var (
+			pp = strings.Split(part, ";")
+			w  = weighted{locale: normalizeLocale(pp[0]), q: 1}
+		)
+
+		if w.locale == "" {
+			continue
+		}
+
+		for _, p := range pp[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				w.q, _ = strconv.ParseFloat(p[2:], 64)
+			}
+		}
+
+		if w.q > 0 {
+			ww = append(ww, w)
+		}
+	}
+
+	sort.SliceStable(ww, func(i, j int) bool { return ww[i].q > ww[j].q })
+
+	ll := make([]string, len(ww))
+	for i := range ww {
+		ll[i] = ww[i].locale
+	}
+
+	return ll
+}
@@ -0,0 +1,205 @@
+package localized
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// record wraps record service and handles localized text fields
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with localized text field handling
+//
+// Values of localized fields are stored as JSON objects with all locales.
+// Plain text values are stored under caller's locale, keeping values in other
+// locales intact. Unless caller asks for all locales, values on returned
+// records are replaced with the best match for caller's language.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+// Init hooks localized text fields into record service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, _ *zap.Logger) error {
+	service.DefaultRecord = Record(service.DefaultRecord)
+	return nil
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) FindByID(namespaceID, recordID uint64) (*types.Record, error) {
+	r, err := svc.RecordService.FindByID(namespaceID, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, svc.resolve(r)
+}
+
+func (svc record) Find(filter types.RecordFilter) (set types.RecordSet, f types.RecordFilter, err error) {
+	if set, f, err = svc.RecordService.Find(filter); err != nil {
+		return
+	}
+
+	return set, f, svc.resolve(set...)
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	m, err := svc.module(r.NamespaceID, r.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = svc.normalize(m, r, nil); err != nil {
+		return nil, err
+	}
+
+	if r, err = svc.RecordService.Create(r); err != nil {
+		return nil, err
+	}
+
+	return r, svc.resolve(r)
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	m, err := svc.module(r.NamespaceID, r.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasLocalized(m) {
+		// Load raw values, without resolving them
+		existing, err := svc.RecordService.FindByID(r.NamespaceID, r.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = svc.normalize(m, r, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	if r, err = svc.RecordService.Update(r); err != nil {
+		return nil, err
+	}
+
+	return r, svc.resolve(r)
+}
+
+func (svc record) module(namespaceID, moduleID uint64) (*types.Module, error) {
+	return service.DefaultModule.With(svc.ctx).FindByID(namespaceID, moduleID)
+}
+
+// normalize converts submitted values of localized fields into JSON objects
+//
+// Plain text is stored under caller's locale and merged with
+// the existing value (if any)
+func (svc record) normalize(m *types.Module, r, existing *types.Record) error {
+	var (
+		locale = preferenceFromContext(svc.ctx).locale()
+		places = map[string]int{}
+	)
+
+	for _, v := range r.Values {
+		if !IsLocalized(m.Fields.FindByName(v.Name)) {
+			continue
+		}
+
+		place := places[v.Name]
+		places[v.Name]++
+
+		t, ok := ParseText(v.Value)
+		if !ok {
+			t = nil
+			if existing != nil {
+				if ev := existing.Values.FilterByName(v.Name); place < len(ev) {
+					t, _ = ParseText(ev[place].Value)
+				}
+			}
+
+			if t == nil {
+				t = Text{}
+			}
+
+			t[locale] = v.Value
+		}
+
+		t, err := t.Normalize()
+		if err != nil {
+			return err
+		}
+
+		v.Value = t.String()
+	}
+
+	return nil
+}
+
+// resolve replaces values of localized fields with the best match for caller's language
+func (svc record) resolve(rr ...*types.Record) error {
+	var (
+		p  = preferenceFromContext(svc.ctx)
+		mm = map[uint64]*types.Module{}
+	)
+
+	if p.all {
+		return nil
+	}
+
+	for _, r := range rr {
+		if r == nil {
+			continue
+		}
+
+		m, ok := mm[r.ModuleID]
+		if !ok {
+			var err error
+			if m, err = svc.module(r.NamespaceID, r.ModuleID); err != nil {
+				return err
+			}
+
+			mm[r.ModuleID] = m
+		}
+
+		if !hasLocalized(m) {
+			continue
+		}
+
+		for _, v := range r.Values {
+			if !IsLocalized(m.Fields.FindByName(v.Name)) {
+				continue
+			}
+
+			if t, ok := ParseText(v.Value); ok {
+				v.Value = t.Best(p.locales...)
+			}
+		}
+	}
+
+	return nil
+}
+
+func hasLocalized(m *types.Module) bool {
+	for _, f := range m.Fields {
+		if IsLocalized(f) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package localized
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Text holds localized values, by locale
+	//
+	// Stored as JSON object in record value: {"en": "Hello", "de": "Hallo"}
+	// All locales are in a single value, so record filters and search
+	// (name LIKE '%hallo%') match text in any locale.
+	Text map[string]string
+)
+
+const (
+	// FieldKind is kind of the module field that holds localized text
+	FieldKind = "LocalizedText"
+)
+
+var (
+	// DefaultLocale is used when caller does not state its language
+	DefaultLocale = "en"
+)
+
+// IsLocalized returns true if field holds localized text
+func IsLocalized(f *types.ModuleField) bool {
+	return f != nil && f.Kind == FieldKind
+}
+
+// ParseText parses stored or submitted value into localized text
+//
+// Returns false if value is not a JSON object with string values
+func ParseText(v string) (Text, bool) {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, "{") {
+		return nil, false
+	}
+
+	t := Text{}
+	if err := json.Unmarshal([]byte(v), &t); err != nil {
+		return nil, false
+	}
+
+	return t, true
+}
+
+// Normalize validates locales and converts them into canonical form
+func (t Text) Normalize() (Text, error) {
+	out := Text{}
+	for l, v := range t {
+		if l = normalizeLocale(l); l == "" {
+			return nil, ErrInvalidLocale.withStack()
+		}
+
+		out[l] = v
+	}
+
+	return out, nil
+}
+
+// Locales returns sorted list of all locales
+func (t Text) Locales() []string {
+	ll := make([]string, 0, len(t))
+	for l := range t {
+		ll = append(ll, l)
+	}
+
+	sort.Strings(ll)
+	return ll
+}
+
+// Best returns value in the locale that best matches the preferred ones
+//
+// Each preferred locale is matched exactly and then by its base language
+// (de-at matches de and de-de). Falls back to default locale and then
+// to the first locale.
+func (t Text) Best(preferred ...string) string {
+	if len(t) == 0 {
+		return ""
+	}
+
+	locales := t.Locales()
+
+	for _, p := range preferred {
+		if v, ok := t[p]; ok {
+			return v
+		}
+
+		for _, l := range locales {
+			if baseLocale(l) == baseLocale(p) {
+				return t[l]
+			}
+		}
+	}
+
+	if v, ok := t[DefaultLocale]; ok {
+		return v
+	}
+
+	return t[locales[0]]
+}
+
+func (t Text) String() string {
+	b, _ := json.Marshal(t)
+	return string(b)
+}
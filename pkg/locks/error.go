@@ -0,0 +1,36 @@
+package locks
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	locksError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions  = locksError{"NoPermissions", fault.Forbidden}
+	ErrRecordNotFound = locksError{"RecordNotFound", fault.NotFound}
+	ErrLockNotFound   = locksError{"LockNotFound", fault.NotFound}
+	ErrLockTaken      = locksError{"LockTaken", fault.Conflict}
+	ErrLockRequired   = locksError{"LockRequired", fault.Conflict}
+)
+
+func (e locksError) Error() string {
+	return e.String()
+}
+
+func (e locksError) String() string {
+	return "crust.locks." + e.name
+}
+
+func (e locksError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e locksError) withStack() *fault.Error {
+	return fault.New(e)
+}
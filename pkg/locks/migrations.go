@@ -0,0 +1,27 @@
+package locks
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200214000000.locks",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_record_lock (
+  rel_record       BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  rel_module       BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+
+  acquired_at      DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_record),
+  INDEX (rel_module, expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,60 @@
+package locks
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// record wraps record service and enforces required locks
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with lock checks
+//
+// Records of modules that require locks can be updated and deleted only
+// by users that hold their locks. Locks of deleted records are removed.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	if err := svc.locks().check(r.ModuleID, r.ID); err != nil {
+		return nil, err
+	}
+
+	return svc.RecordService.Update(r)
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	r, err := svc.RecordService.FindByID(namespaceID, recordID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.locks().check(r.ModuleID, r.ID); err != nil {
+		return err
+	}
+
+	if err = svc.RecordService.DeleteByID(namespaceID, recordID); err != nil {
+		return err
+	}
+
+	return svc.locks().repository.DeleteByRecord(recordID)
+}
+
+func (svc record) locks() *lockService {
+	return defaultLocks.with(svc.ctx)
+}
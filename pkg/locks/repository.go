@@ -0,0 +1,124 @@
+package locks
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_record_lock"
+}
+
+func (r repository) columns() []string {
+	return []string{"rel_record", "rel_namespace", "rel_module", "rel_user", "acquired_at", "expires_at"}
+}
+
+// FindByRecord returns record's lock, nil when record is not locked
+//
+// Returned lock can be expired.
+func (r repository) FindByRecord(recordID uint64) (*Lock, error) {
+	var (
+		l = &Lock{}
+		q = squirrel.
+			Select(r.columns()...).
+			From(r.table()).
+			Where(squirrel.Eq{"rel_record": recordID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, l); err != nil {
+		return nil, err
+	} else if l.RecordID == 0 {
+		return nil, nil
+	}
+
+	return l, nil
+}
+
+// FindByModule returns locks of module's records that did not expire
+func (r repository) FindByModule(moduleID uint64, now time.Time) (ll LockSet, err error) {
+	q := squirrel.
+		Select(r.columns()...).
+		From(r.table()).
+		Where(squirrel.Eq{"rel_module": moduleID}).
+		Where(squirrel.Gt{"expires_at": now}).
+		OrderBy("rel_record")
+
+	return ll, rh.FetchAll(r.db(), q, &ll)
+}
+
+// Acquire stores the lock unless the record is locked by another user
+//
+// Lock of the same user is extended; the existing lock is returned when
+// it is held by another user and did not expire.
+func (r repository) Acquire(l *Lock, now time.Time) (*Lock, error) {
+	var existing *Lock
+
+	return existing, r.db().Transaction(func() (err error) {
+		existing = &Lock{}
+		q := squirrel.
+			Select(r.columns()...).
+			From(r.table()).
+			Where(squirrel.Eq{"rel_record": l.RecordID}).
+			Suffix("FOR UPDATE")
+
+		if err = rh.FetchOne(r.db(), q, existing); err != nil {
+			return err
+		}
+
+		switch {
+		case existing.RecordID == 0 || existing.expired(now):
+			existing = nil
+		case existing.UserID != l.UserID:
+			return nil
+		default:
+			// Extending own lock
+			l.AcquiredAt = existing.AcquiredAt
+			existing = nil
+		}
+
+		return errors.WithStack(r.db().Replace(r.table(), l))
+	})
+}
+
+// Release removes the lock of the user, or any lock that expired
+func (r repository) Release(recordID, userID uint64, now time.Time) error {
+	return rh.Delete(r.db(), r.table(), squirrel.And{
+		squirrel.Eq{"rel_record": recordID},
+		squirrel.Or{
+			squirrel.Eq{"rel_user": userID},
+			squirrel.LtOrEq{"expires_at": now},
+		},
+	})
+}
+
+func (r repository) DeleteByRecord(recordID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_record": recordID})
+}
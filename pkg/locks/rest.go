@@ -0,0 +1,51 @@
+package locks
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts record lock endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {moduleID} params
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Locks of module's records, who edits what
+	r.Get("/", rest.Handler("RecordLock.List", func(r *http.Request) (interface{}, error) {
+		return DefaultLocks.With(r.Context()).Find(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+		)
+	}))
+
+	r.Get("/{recordID}", rest.Handler("RecordLock.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultLocks.With(r.Context()).Read(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+			rest.ParamUint64(r, "recordID"),
+		)
+	}))
+
+	// Acquires the lock; clients holding it call it again (heartbeat) before it expires
+	r.Put("/{recordID}", rest.Handler("RecordLock.Acquire", func(r *http.Request) (interface{}, error) {
+		return DefaultLocks.With(r.Context()).Acquire(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+			rest.ParamUint64(r, "recordID"),
+		)
+	}))
+
+	r.Delete("/{recordID}", rest.Handler("RecordLock.Release", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultLocks.With(r.Context()).Release(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+			rest.ParamUint64(r, "recordID"),
+		)
+	}))
+}
@@ -0,0 +1,263 @@
+package locks
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+type (
+	lockService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		settings settingsGetter
+
+		module service.ModuleService
+		record service.RecordService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanReadRecord(context.Context, *types.Module) bool
+		CanUpdateRecord(context.Context, *types.Module) bool
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	LockService interface {
+		With(ctx context.Context) LockService
+
+		Find(namespaceID, moduleID uint64) (LockSet, error)
+		Read(namespaceID, moduleID, recordID uint64) (*Lock, error)
+
+		Acquire(namespaceID, moduleID, recordID uint64) (*Lock, error)
+		Release(namespaceID, moduleID, recordID uint64) error
+	}
+)
+
+var (
+	DefaultLocks LockService
+
+	// used by record service decorator
+	defaultLocks *lockService
+
+	// Locks expire unless acquired again, clients should do it at about half of the TTL
+	lockTTL = 2 * time.Minute
+)
+
+// Init initializes lock service and enforces locks of modules that require them
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	lockTTL = options.EnvDuration("", "RECORD_LOCK_TTL", lockTTL)
+
+	svc := (&lockService{
+		logger:   log,
+		ac:       service.DefaultAccessControl,
+		settings: service.DefaultSettings,
+		module:   service.DefaultModule,
+		record:   service.DefaultRecord,
+	}).with(ctx)
+
+	DefaultLocks = svc
+	defaultLocks = svc
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	return nil
+}
+
+func (svc lockService) With(ctx context.Context) LockService {
+	return svc.with(ctx)
+}
+
+func (svc lockService) with(ctx context.Context) *lockService {
+	return &lockService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		ac:       svc.ac,
+		settings: svc.settings,
+
+		module: svc.module.With(ctx),
+		record: svc.record.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// Find returns locks of module's records that did not expire
+func (svc lockService) Find(namespaceID, moduleID uint64) (LockSet, error) {
+	m, err := svc.module.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanReadRecord(svc.ctx, m) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return svc.repository.FindByModule(moduleID, time.Now())
+}
+
+// Read returns lock of the record, to see who holds it
+func (svc lockService) Read(namespaceID, moduleID, recordID uint64) (*Lock, error) {
+	if _, err := svc.loadRecord(namespaceID, moduleID, recordID); err != nil {
+		return nil, err
+	}
+
+	l, err := svc.repository.FindByRecord(recordID)
+	if err != nil {
+		return nil, err
+	} else if l.expired(time.Now()) {
+		return nil, ErrLockNotFound.withStack().WithID("recordID", recordID)
+	}
+
+	return l, nil
+}
+
+// Acquire locks the record for the current user or extends user's lock
+//
+// Record must not be locked by another user. Users that can not update
+// records of the module can not lock them.
+func (svc lockService) Acquire(namespaceID, moduleID, recordID uint64) (*Lock, error) {
+	m, err := svc.loadRecord(namespaceID, moduleID, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanUpdateRecord(svc.ctx, m) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	var (
+		now = time.Now().Truncate(time.Second)
+		l   = &Lock{
+			RecordID:    recordID,
+			NamespaceID: namespaceID,
+			ModuleID:    moduleID,
+			UserID:      auth.GetIdentityFromContext(svc.ctx).Identity(),
+			AcquiredAt:  now,
+			ExpiresAt:   now.Add(lockTTL),
+		}
+	)
+
+	holder, err := svc.repository.Acquire(l, now)
+	if err != nil {
+		return nil, err
+	} else if holder != nil {
+		return nil, ErrLockTaken.withStack().
+			WithID("recordID", recordID).
+			WithID("userID", holder.UserID)
+	}
+
+	if l.AcquiredAt.Equal(now) {
+		svc.publish(live.EventRecordLocked, l)
+	}
+
+	return l, nil
+}
+
+// Release removes user's lock of the record
+func (svc lockService) Release(namespaceID, moduleID, recordID uint64) error {
+	if _, err := svc.loadRecord(namespaceID, moduleID, recordID); err != nil {
+		return err
+	}
+
+	var (
+		now    = time.Now()
+		userID = auth.GetIdentityFromContext(svc.ctx).Identity()
+	)
+
+	l, err := svc.repository.FindByRecord(recordID)
+	if err != nil {
+		return err
+	} else if l.expired(now) {
+		return nil
+	} else if l.UserID != userID {
+		return ErrLockTaken.withStack().
+			WithID("recordID", recordID).
+			WithID("userID", l.UserID)
+	}
+
+	if err = svc.repository.Release(recordID, userID, now); err != nil {
+		return err
+	}
+
+	svc.publish(live.EventRecordUnlocked, l)
+	return nil
+}
+
+// check makes sure that the current user can change the record
+//
+// Records of modules that require locks can be changed only by users
+// that hold their locks.
+func (svc lockService) check(moduleID, recordID uint64) error {
+	if required, err := svc.required(moduleID); err != nil || !required {
+		return err
+	}
+
+	l, err := svc.repository.FindByRecord(recordID)
+	if err != nil {
+		return err
+	}
+
+	if l.expired(time.Now()) || l.UserID != auth.GetIdentityFromContext(svc.ctx).Identity() {
+		return ErrLockRequired.withStack().WithID("recordID", recordID)
+	}
+
+	return nil
+}
+
+// required checks if the module requires locks
+func (svc lockService) required(moduleID uint64) (bool, error) {
+	v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingRequired, 0)
+	if err != nil || v == nil {
+		return false, err
+	}
+
+	var ids []string
+	if err = v.Value.Unmarshal(&ids); err != nil {
+		return false, err
+	}
+
+	for _, id := range payload.ParseUInt64s(ids) {
+		if id == moduleID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// loadRecord checks that the record is readable and of the module, returns the module
+func (svc lockService) loadRecord(namespaceID, moduleID, recordID uint64) (*types.Module, error) {
+	r, err := svc.record.FindByID(namespaceID, recordID)
+	if err != nil {
+		return nil, err
+	} else if r.ModuleID != moduleID {
+		return nil, ErrRecordNotFound.withStack().WithID("recordID", recordID)
+	}
+
+	return svc.module.FindByID(namespaceID, moduleID)
+}
+
+func (svc lockService) publish(typ string, l *Lock) {
+	if s := live.ModuleScope(l.NamespaceID, l.ModuleID); live.Subscribed(s) {
+		live.Publish(&live.Event{Scope: s, Type: typ, Payload: l})
+	}
+}
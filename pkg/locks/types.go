@@ -0,0 +1,34 @@
+package locks
+
+import (
+	"time"
+)
+
+type (
+	// Lock of a record, held by the user that edits it
+	//
+	// Locks are advisory unless required for the module (see
+	// settingRequired); clients keep them by acquiring them again
+	// before they expire.
+	Lock struct {
+		RecordID    uint64 `json:"recordID,string" db:"rel_record"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+		UserID      uint64 `json:"userID,string" db:"rel_user"`
+
+		AcquiredAt time.Time `json:"acquiredAt" db:"acquired_at"`
+		ExpiresAt  time.Time `json:"expiresAt" db:"expires_at"`
+	}
+
+	LockSet []*Lock
+)
+
+const (
+	// Module IDs (strings) of modules where records can be updated
+	// and deleted only by users that hold their locks
+	settingRequired = "crust.locks.required-modules"
+)
+
+func (l *Lock) expired(now time.Time) bool {
+	return l == nil || !now.Before(l.ExpiresAt)
+}
@@ -0,0 +1,36 @@
+package members
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	membersError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions   = membersError{"NoPermissions", fault.Forbidden}
+	ErrBatchTooLarge   = membersError{"BatchTooLarge", fault.Invalid}
+	ErrInvalidID       = membersError{"InvalidID", fault.Invalid}
+	ErrUserNotFound    = membersError{"UserNotFound", fault.NotFound}
+	ErrAddedAndRemoved = membersError{"AddedAndRemoved", fault.Invalid}
+)
+
+func (e membersError) Error() string {
+	return e.String()
+}
+
+func (e membersError) String() string {
+	return "crust.members." + e.name
+}
+
+func (e membersError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e membersError) withStack() *fault.Error {
+	return fault.New(e)
+}
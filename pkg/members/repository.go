@@ -0,0 +1,52 @@
+package members
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+// Users returns IDs of the users that exist and are not deleted
+func (r repository) Users(userIDs []uint64) (ids []uint64, err error) {
+	q := squirrel.
+		Select("id").
+		From("sys_user").
+		Where(squirrel.Eq{"id": userIDs, "deleted_at": nil})
+
+	return ids, rh.FetchAll(r.db(), q, &ids)
+}
+
+// Members returns IDs of the users that are members of the role
+func (r repository) Members(roleID uint64, userIDs []uint64) (ids []uint64, err error) {
+	q := squirrel.
+		Select("rel_user").
+		From("sys_role_member").
+		Where(squirrel.Eq{"rel_role": roleID, "rel_user": userIDs})
+
+	return ids, rh.FetchAll(r.db(), q, &ids)
+}
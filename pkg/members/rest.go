@@ -0,0 +1,36 @@
+package members
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts bulk role membership endpoint
+//
+// Expects to be mounted under a path with {roleID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Adds and removes members, with a result for every user
+	r.Post("/", rest.Handler("RoleMembers.Bulk", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Add    []string `json:"add"`
+			Remove []string `json:"remove"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultMembers.With(r.Context()).MemberChangeBulk(
+			rest.ParamUint64(r, "roleID"),
+			payload.ParseUInt64s(body.Add),
+			payload.ParseUInt64s(body.Remove),
+		)
+	}))
+}
@@ -0,0 +1,238 @@
+package members
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	membersService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		role service.RoleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageRoleMembers(context.Context, *types.Role) bool
+	}
+
+	MemberService interface {
+		With(ctx context.Context) MemberService
+
+		MemberAddBulk(roleID uint64, userIDs []uint64) (*Result, error)
+		MemberRemoveBulk(roleID uint64, userIDs []uint64) (*Result, error)
+		MemberChangeBulk(roleID uint64, add, remove []uint64) (*Result, error)
+	}
+)
+
+var (
+	DefaultMembers MemberService
+)
+
+// Init initializes bulk role membership service
+//
+// Must be called after system services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultMembers = (&membersService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc membersService) With(ctx context.Context) MemberService {
+	return &membersService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		// Role service is taken when used, with decorators of the
+		// extensions that are initialized after this one
+		role: service.DefaultRole.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// MemberAddBulk adds users to the role
+func (svc membersService) MemberAddBulk(roleID uint64, userIDs []uint64) (*Result, error) {
+	return svc.MemberChangeBulk(roleID, userIDs, nil)
+}
+
+// MemberRemoveBulk removes users from the role
+func (svc membersService) MemberRemoveBulk(roleID uint64, userIDs []uint64) (*Result, error) {
+	return svc.MemberChangeBulk(roleID, nil, userIDs)
+}
+
+// MemberChangeBulk adds and removes members of the role
+//
+// Users that can not be changed (invalid IDs, users that do not exist,
+// users that are both added and removed) are reported as failed and
+// the rest are changed. Adding members and removing users that are not
+// members leaves them unchanged.
+//
+// Members are changed one by one through the role service, so that changes
+// are audited and expirations, cached roles and such are updated.
+func (svc membersService) MemberChangeBulk(roleID uint64, add, remove []uint64) (*Result, error) {
+	role, err := svc.role.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanManageRoleMembers(svc.ctx, role) {
+		return nil, ErrNoPermissions.withStack().WithID("roleID", roleID)
+	}
+
+	add, remove = unique(add), unique(remove)
+	if len(add)+len(remove) > maxBatchSize {
+		return nil, ErrBatchTooLarge.withStack()
+	}
+
+	var (
+		out = &Result{
+			Added:     []string{},
+			Removed:   []string{},
+			Unchanged: []string{},
+			Failed:    []*Failure{},
+		}
+
+		fail = func(userID uint64, err error) {
+			out.Failed = append(out.Failed, &Failure{UserID: payload.Uint64toa(userID), Error: err.Error()})
+		}
+
+		adding, removing = set(add), set(remove)
+	)
+
+	add = filter(add, func(userID uint64) bool {
+		switch {
+		case userID == 0:
+			fail(userID, ErrInvalidID)
+		case removing[userID]:
+			fail(userID, ErrAddedAndRemoved)
+		default:
+			return true
+		}
+
+		return false
+	})
+
+	remove = filter(remove, func(userID uint64) bool {
+		if userID == 0 {
+			fail(userID, ErrInvalidID)
+			return false
+		}
+
+		return !adding[userID]
+	})
+
+	if len(add) > 0 {
+		ids, err := svc.repository.Users(add)
+		if err != nil {
+			return nil, err
+		}
+
+		existing := set(ids)
+		add = filter(add, func(userID uint64) bool {
+			if !existing[userID] {
+				fail(userID, ErrUserNotFound)
+			}
+
+			return existing[userID]
+		})
+	}
+
+	ids, err := svc.repository.Members(roleID, append(append([]uint64{}, add...), remove...))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		members = set(ids)
+
+		unchanged = func(userID uint64) {
+			out.Unchanged = append(out.Unchanged, payload.Uint64toa(userID))
+		}
+	)
+
+	add = filter(add, func(userID uint64) bool {
+		if members[userID] {
+			unchanged(userID)
+		}
+
+		return !members[userID]
+	})
+
+	remove = filter(remove, func(userID uint64) bool {
+		if !members[userID] {
+			unchanged(userID)
+		}
+
+		return members[userID]
+	})
+
+	for _, userID := range add {
+		if err = svc.role.MemberAdd(roleID, userID); err != nil {
+			fail(userID, err)
+		} else {
+			out.Added = append(out.Added, payload.Uint64toa(userID))
+		}
+	}
+
+	for _, userID := range remove {
+		if err = svc.role.MemberRemove(roleID, userID); err != nil {
+			fail(userID, err)
+		} else {
+			out.Removed = append(out.Removed, payload.Uint64toa(userID))
+		}
+	}
+
+	return out, nil
+}
+
+func unique(ids []uint64) []uint64 {
+	var (
+		out  = make([]uint64, 0, len(ids))
+		seen = map[uint64]bool{}
+	)
+
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
+
+func set(ids []uint64) map[uint64]bool {
+	out := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		out[id] = true
+	}
+
+	return out
+}
+
+func filter(ids []uint64, keep func(uint64) bool) []uint64 {
+	out := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if keep(id) {
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
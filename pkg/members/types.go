@@ -0,0 +1,24 @@
+package members
+
+type (
+	// Result of a bulk membership change
+	//
+	// Users that could not be added or removed are listed as failed,
+	// the others are changed anyway.
+	Result struct {
+		Added     []string   `json:"added"`
+		Removed   []string   `json:"removed"`
+		Unchanged []string   `json:"unchanged"`
+		Failed    []*Failure `json:"failed"`
+	}
+
+	Failure struct {
+		UserID string `json:"userID"`
+		Error  string `json:"error"`
+	}
+)
+
+const (
+	// Users per request, larger teams are synced in batches
+	maxBatchSize = 1000
+)
@@ -0,0 +1,94 @@
+package messages
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+// Stream returns iterator over messages that match the filter, ordered by ID
+//
+// Messages are read from a single DB cursor, one at a time; iteration
+// stops when the context is canceled.
+func (r repository) Stream(f StreamFilter) (MessageIterator, error) {
+	q := squirrel.
+		Select(
+			"m.id",
+			"COALESCE(m.type,'') AS type",
+			"m.message",
+			"m.rel_user",
+			"m.rel_channel",
+			"m.reply_to",
+			"m.replies",
+			"m.created_at",
+			"m.updated_at",
+		).
+		From("messaging_message AS m").
+		Where(squirrel.Eq{"m.rel_channel": f.ChannelID, "m.deleted_at": nil}).
+		OrderBy("m.id")
+
+	if f.ThreadID > 0 {
+		q = q.Where(squirrel.Eq{"m.reply_to": f.ThreadID})
+	}
+
+	if f.UserID > 0 {
+		q = q.Where(squirrel.Eq{"m.rel_user": f.UserID})
+	}
+
+	if f.AfterID > 0 {
+		q = q.Where(squirrel.Gt{"m.id": f.AfterID})
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(fn func(*messagingTypes.Message) error) error {
+		rows, err := r.db().QueryxContext(r.ctx, query, args...)
+		if err != nil {
+			return errors.Wrap(err, "can not execute message stream query")
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			m := &messagingTypes.Message{}
+			if err = rows.StructScan(m); err != nil {
+				return errors.Wrap(err, "can not scan streamed message")
+			}
+
+			if err = fn(m); err != nil {
+				return err
+			}
+		}
+
+		return rows.Err()
+	}, nil
+}
@@ -0,0 +1,41 @@
+package messages
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts message streaming endpoint
+//
+// Expects to be mounted under a path with {channelID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// All channel's messages as newline delimited JSON, ordered by ID:
+	//   ?threadID=<replies of the thread>&userID=<messages of the user>
+	// Interrupted streams can be resumed with ?afterID=<last ID>
+	r.Get("/", rest.Handler("Messages.Stream", func(r *http.Request) (interface{}, error) {
+		it, err := DefaultMessage.With(r.Context()).Stream(StreamFilter{
+			ChannelID: rest.ParamUint64(r, "channelID"),
+			ThreadID:  rest.QueryUint64(r, "threadID"),
+			UserID:    rest.QueryUint64(r, "userID"),
+			AfterID:   rest.QueryUint64(r, "afterID"),
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return rest.Stream("Messages.Stream", func(emit rest.Emitter) error {
+			return it(func(m *messagingTypes.Message) error {
+				return emit(payload.Message(r.Context(), m))
+			})
+		}), nil
+	}))
+}
@@ -0,0 +1,67 @@
+package messages
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		channels messagingService.ChannelService
+
+		repository *repository
+	}
+
+	MessageService interface {
+		With(ctx context.Context) MessageService
+
+		Stream(StreamFilter) (MessageIterator, error)
+	}
+)
+
+var (
+	DefaultMessage MessageService
+)
+
+// Init initializes message streaming service
+//
+// Must be called after messaging services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultMessage = (&service{
+		logger:   log,
+		channels: messagingService.DefaultChannel,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc service) With(ctx context.Context) MessageService {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		channels: svc.channels.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// Stream returns iterator over all messages of the channel that match the filter
+//
+// Unlike corteza's message list, messages are not paged nor loaded into
+// memory; they are ordered by ID, without attachments and reactions.
+func (svc service) Stream(f StreamFilter) (MessageIterator, error) {
+	// Verifies if current user can read the channel
+	if _, err := svc.channels.FindByID(f.ChannelID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Stream(f)
+}
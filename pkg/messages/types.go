@@ -0,0 +1,24 @@
+package messages
+
+import (
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// StreamFilter selects streamed messages of a channel
+	StreamFilter struct {
+		ChannelID uint64
+
+		// Replies of the thread only (messages from all threads by default)
+		ThreadID uint64
+
+		// Messages of the user only
+		UserID uint64
+
+		// Messages after the given one (exclusive), for resuming
+		AfterID uint64
+	}
+
+	// MessageIterator calls fn for each message, stops on the first error
+	MessageIterator func(fn func(*messagingTypes.Message) error) error
+)
@@ -0,0 +1,115 @@
+package migrations
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+)
+
+type (
+	// Migration holds one set of SQL statements
+	//
+	// Name should be prefixed with a timestamp (YYYYMMDDHHMMSS) so that
+	// migrations of one extension are executed in the expected order.
+	Migration struct {
+		Name string
+		Up   string
+	}
+
+	Set []Migration
+
+	status struct {
+		Project        string `db:"project"`
+		Filename       string `db:"filename"`
+		StatementIndex int    `db:"statement_index"`
+		Status         string `db:"status"`
+	}
+)
+
+const (
+	// All crust extensions share the same project in migrations table;
+	// field is limited to 16 chars so we can not use extension name here
+	project = "crust"
+
+	// Same as corteza's migrations.sql; we need it in case crust
+	// migrations run on a database that corteza did not touch yet
+	migrationsTable = "CREATE TABLE IF NOT EXISTS `migrations` (" +
+		" `project` varchar(16) NOT NULL COMMENT 'sam, crm, ...'," +
+		" `filename` varchar(255) NOT NULL COMMENT 'yyyymmddHHMMSS.sql'," +
+		" `statement_index` int(11) NOT NULL COMMENT 'Statement number from SQL file'," +
+		" `status` TEXT NOT NULL COMMENT 'ok or full error message'," +
+		" PRIMARY KEY (`project`,`filename`)" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8"
+)
+
+var (
+	statementSplitter = regexp.MustCompilePOSIX(";$")
+)
+
+// Migrate runs all migrations from the set that were not yet (successfully) executed
+//
+// Migrations are tracked in corteza's migrations table under "crust" project;
+// filename is composed from extension name and migration name.
+func Migrate(db *factory.DB, log *zap.Logger, ext string, mm Set) error {
+	log = log.Named("database.migrations").With(zap.String("extension", ext))
+
+	if _, err := db.Exec(migrationsTable); err != nil {
+		return errors.Wrap(err, "could not create migrations table")
+	}
+
+	for _, m := range mm {
+		if err := migrate(db, log, ext, m); err != nil {
+			return errors.Wrapf(err, "migration %s/%s failed", ext, m.Name)
+		}
+	}
+
+	return nil
+}
+
+func migrate(db *factory.DB, log *zap.Logger, ext string, m Migration) error {
+	var (
+		s = status{
+			Project:  project,
+			Filename: ext + "/" + m.Name,
+		}
+	)
+
+	if err := db.Get(&s, "SELECT * FROM migrations WHERE project = ? AND filename = ?", s.Project, s.Filename); err != nil {
+		return err
+	}
+
+	if s.Status == "ok" {
+		return nil
+	}
+
+	err := db.Transaction(func() error {
+		log.Debug("running migration", zap.String("name", m.Name))
+
+		for i, query := range statementSplitter.Split(m.Up, -1) {
+			if strings.TrimSpace(query) == "" || i < s.StatementIndex {
+				continue
+			}
+
+			s.StatementIndex = i
+			if _, err := db.Exec(query); err != nil {
+				return err
+			}
+		}
+
+		s.Status = "ok"
+		return nil
+	})
+
+	if err != nil {
+		s.Status = err.Error()
+	}
+
+	if rerr := db.Replace("migrations", s); rerr != nil {
+		return errors.Wrap(rerr, "migration update failed")
+	}
+
+	return err
+}
@@ -0,0 +1,46 @@
+package moderation
+
+import (
+	"context"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// channel wraps channel service and keeps banned users out
+	channel struct {
+		messagingService.ChannelService
+		ctx context.Context
+	}
+)
+
+// Channel decorates channel service with enforcing bans
+//
+// Banned users can not join the channel, be added or invited to it.
+func Channel(cs messagingService.ChannelService) messagingService.ChannelService {
+	return &channel{ChannelService: cs, ctx: context.Background()}
+}
+
+func (svc channel) With(ctx context.Context) messagingService.ChannelService {
+	return &channel{
+		ChannelService: svc.ChannelService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc channel) AddMember(channelID uint64, memberIDs ...uint64) (messagingTypes.ChannelMemberSet, error) {
+	if err := defaultModeration.with(svc.ctx).canJoin(channelID, memberIDs...); err != nil {
+		return nil, err
+	}
+
+	return svc.ChannelService.AddMember(channelID, memberIDs...)
+}
+
+func (svc channel) InviteUser(channelID uint64, memberIDs ...uint64) (messagingTypes.ChannelMemberSet, error) {
+	if err := defaultModeration.with(svc.ctx).canJoin(channelID, memberIDs...); err != nil {
+		return nil, err
+	}
+
+	return svc.ChannelService.InviteUser(channelID, memberIDs...)
+}
@@ -0,0 +1,42 @@
+package moderation
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	moderationError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrActionNotFound       = moderationError{"ActionNotFound", fault.NotFound}
+	ErrInvalidKind          = moderationError{"InvalidKind", fault.Invalid}
+	ErrInvalidDuration      = moderationError{"InvalidDuration", fault.Invalid}
+	ErrInvalidReason        = moderationError{"InvalidReason", fault.Invalid}
+	ErrInvalidUser          = moderationError{"InvalidUser", fault.Invalid}
+	ErrNotAppealable        = moderationError{"NotAppealable", fault.Conflict}
+	ErrAlreadyLifted        = moderationError{"AlreadyLifted", fault.Conflict}
+	ErrAlreadyAppealed      = moderationError{"AlreadyAppealed", fault.Conflict}
+	ErrNoPermissions        = moderationError{"NoPermissions", fault.Forbidden}
+	ErrPostingRestricted    = moderationError{"PostingRestricted", fault.Forbidden}
+	ErrChannelAccessRevoked = moderationError{"ChannelAccessRevoked", fault.Forbidden}
+)
+
+func (e moderationError) Error() string {
+	return e.String()
+}
+
+func (e moderationError) String() string {
+	return "crust.moderation." + e.name
+}
+
+func (e moderationError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e moderationError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,72 @@
+package moderation
+
+import (
+	"context"
+	"io"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// message wraps message service and enforces mutes and bans
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with enforcing mutes and bans
+//
+// Muted and banned users can not post, edit or react in the channel.
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	if err := defaultModeration.with(svc.ctx).canPost(m.ChannelID); err != nil {
+		return nil, err
+	}
+
+	return svc.MessageService.Create(m)
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	if err := defaultModeration.with(svc.ctx).canPost(m.ChannelID); err != nil {
+		return nil, err
+	}
+
+	return svc.MessageService.CreateWithAvatar(m, avatar)
+}
+
+func (svc message) Update(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	if m != nil {
+		if err := defaultModeration.with(svc.ctx).canPost(m.ChannelID); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.MessageService.Update(m)
+}
+
+func (svc message) React(messageID uint64, reaction string) error {
+	mod := defaultModeration.with(svc.ctx)
+
+	channelID, err := mod.repository.MessageChannel(messageID)
+	if err != nil {
+		return err
+	}
+
+	if err = mod.canPost(channelID); err != nil {
+		return err
+	}
+
+	return svc.MessageService.React(messageID, reaction)
+}
@@ -0,0 +1,33 @@
+package moderation
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200227000000.moderation",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_channel_moderation (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  kind             VARCHAR(16)     NOT NULL,
+  reason           TEXT            CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL,
+  expires_at       DATETIME            NULL,
+  created_by       BIGINT UNSIGNED NOT NULL,
+  created_at       DATETIME        NOT NULL,
+  lifted_by        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  lifted_at        DATETIME            NULL,
+  lift_reason      TEXT            CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL,
+  appeal           TEXT            CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci NOT NULL,
+  appealed_at      DATETIME            NULL,
+
+  PRIMARY KEY (id),
+  INDEX lookup_user (rel_channel, rel_user)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,169 @@
+package moderation
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_channel_moderation"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_channel",
+			"rel_user",
+			"kind",
+			"reason",
+			"expires_at",
+			"created_by",
+			"created_at",
+			"lifted_by",
+			"lifted_at",
+			"lift_reason",
+			"appeal",
+			"appealed_at",
+		).
+		From(r.table())
+}
+
+// enforced limits the query to mutes and bans that are active at the given time
+func enforced(q squirrel.SelectBuilder, now time.Time) squirrel.SelectBuilder {
+	return q.
+		Where(squirrel.Eq{"kind": []Kind{KindMute, KindBan}, "lifted_at": nil}).
+		Where(squirrel.Or{squirrel.Eq{"expires_at": nil}, squirrel.Gt{"expires_at": now}})
+}
+
+func (r repository) FindByID(channelID, ID uint64) (*Action, error) {
+	var (
+		a = &Action{}
+		q = r.query().Where(squirrel.Eq{"id": ID, "rel_channel": channelID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, a); err != nil {
+		return nil, err
+	} else if a.ID == 0 {
+		return nil, ErrActionNotFound.withStack().WithID("channelID", channelID).WithID("actionID", ID)
+	}
+
+	return a, nil
+}
+
+// Find returns actions that match the filter, latest first
+func (r repository) Find(f Filter, now time.Time) (set ActionSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_channel": f.ChannelID}).
+		OrderBy("id DESC")
+
+	if f.UserID > 0 {
+		q = q.Where(squirrel.Eq{"rel_user": f.UserID})
+	}
+
+	if f.Kind != "" {
+		q = q.Where(squirrel.Eq{"kind": f.Kind})
+	}
+
+	if f.Active {
+		q = enforced(q, now)
+	}
+
+	if f.Appealed {
+		q = q.Where(squirrel.NotEq{"appealed_at": nil})
+	}
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Enforced returns active mutes and bans of the users in the channel
+func (r repository) Enforced(channelID uint64, userIDs []uint64, now time.Time) (set ActionSet, err error) {
+	q := enforced(r.query(), now).
+		Where(squirrel.Eq{"rel_channel": channelID, "rel_user": userIDs}).
+		OrderBy("id DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// MessageChannel returns channel of the message, 0 when it does not exist
+func (r repository) MessageChannel(messageID uint64) (channelID uint64, err error) {
+	err = r.db().Get(&channelID, "SELECT COALESCE(MAX(rel_channel), 0) FROM messaging_message WHERE id = ?", messageID)
+	return channelID, errors.WithStack(err)
+}
+
+func (r repository) Create(a *Action) (*Action, error) {
+	a.ID = factory.Sonyflake.NextID()
+
+	return a, errors.WithStack(r.db().Insert(r.table(), a))
+}
+
+// Lift ends the mute or ban; returns false when it was already lifted
+func (r repository) Lift(a *Action) (bool, error) {
+	return r.update(a, squirrel.Eq{"lifted_at": nil}, rh.Set{
+		"lifted_by":   a.LiftedBy,
+		"lifted_at":   a.LiftedAt,
+		"lift_reason": a.LiftReason,
+	})
+}
+
+// Appeal stores user's appeal; returns false when it was already appealed
+func (r repository) Appeal(a *Action) (bool, error) {
+	return r.update(a, squirrel.Eq{"appealed_at": nil}, rh.Set{
+		"appeal":      a.Appeal,
+		"appealed_at": a.AppealedAt,
+	})
+}
+
+// update changes the action only when it matches the condition
+func (r repository) update(a *Action, cond squirrel.Eq, set rh.Set) (bool, error) {
+	query, args, err := squirrel.
+		Update(r.table()).
+		SetMap(set).
+		Where(squirrel.Eq{"id": a.ID}).
+		Where(cond).
+		ToSql()
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	res, err := r.db().Exec(query, args...)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
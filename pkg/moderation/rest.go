@@ -0,0 +1,71 @@
+package moderation
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts channel moderation endpoints
+//
+// Expects to be mounted under a path with {channelID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Moderation log of the channel, latest first:
+	//   ?userID=<ID>&kind=mute|kick|ban&active=true&appealed=true
+	r.Get("/", rest.Handler("Moderation.List", func(r *http.Request) (interface{}, error) {
+		return DefaultModeration.With(r.Context()).Find(Filter{
+			ChannelID: rest.ParamUint64(r, "channelID"),
+			UserID:    rest.QueryUint64(r, "userID"),
+			Kind:      Kind(r.URL.Query().Get("kind")),
+			Active:    rest.QueryBool(r, "active"),
+			Appealed:  rest.QueryBool(r, "appealed"),
+		})
+	}))
+
+	r.Post("/", rest.Handler("Moderation.Create", func(r *http.Request) (interface{}, error) {
+		a := &Action{}
+		if err := rest.Decode(r, a); err != nil {
+			return nil, err
+		}
+
+		a.ChannelID = rest.ParamUint64(r, "channelID")
+		return DefaultModeration.With(r.Context()).Create(a)
+	}))
+
+	r.Get("/{actionID}", rest.Handler("Moderation.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultModeration.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "channelID"),
+			rest.ParamUint64(r, "actionID"),
+		)
+	}))
+
+	// Unmute or unban: ?reason=<why>
+	r.Delete("/{actionID}", rest.Handler("Moderation.Lift", func(r *http.Request) (interface{}, error) {
+		return DefaultModeration.With(r.Context()).Lift(
+			rest.ParamUint64(r, "channelID"),
+			rest.ParamUint64(r, "actionID"),
+			r.URL.Query().Get("reason"),
+		)
+	}))
+
+	r.Post("/{actionID}/appeal", rest.Handler("Moderation.Appeal", func(r *http.Request) (interface{}, error) {
+		in := struct {
+			Appeal string `json:"appeal"`
+		}{}
+
+		if err := rest.Decode(r, &in); err != nil {
+			return nil, err
+		}
+
+		return DefaultModeration.With(r.Context()).Appeal(
+			rest.ParamUint64(r, "channelID"),
+			rest.ParamUint64(r, "actionID"),
+			in.Appeal,
+		)
+	}))
+}
@@ -0,0 +1,353 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+type (
+	accessController interface {
+		CanManageChannelMembers(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+
+		repository *repository
+	}
+
+	ModerationService interface {
+		With(ctx context.Context) ModerationService
+
+		Find(Filter) (ActionSet, error)
+		FindByID(channelID, ID uint64) (*Action, error)
+
+		Create(*Action) (*Action, error)
+		Lift(channelID, ID uint64, reason string) (*Action, error)
+		Appeal(channelID, ID uint64, appeal string) (*Action, error)
+	}
+)
+
+var (
+	DefaultModeration ModerationService
+
+	// used by service decorators
+	defaultModeration *service
+
+	// now is used for expiration of mutes and bans and can be overridden
+	now = time.Now
+)
+
+// Init initializes channel moderation and decorates message and channel
+// services with enforcing mutes and bans
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		channels: messagingService.DefaultChannel,
+	}
+
+	DefaultModeration = svc.With(ctx)
+	defaultModeration = svc.with(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	messagingService.DefaultChannel = Channel(messagingService.DefaultChannel)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) ModerationService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns moderation log of the channel
+//
+// Moderators see all actions, other users only actions taken against them.
+func (svc service) Find(f Filter) (ActionSet, error) {
+	ch, err := svc.channels.FindByID(f.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.isModerator(ch) {
+		f.UserID = auth.GetIdentityFromContext(svc.ctx).Identity()
+	}
+
+	t := now()
+	aa, err := svc.repository.Find(f, t)
+	if err != nil {
+		return nil, err
+	} else if aa == nil {
+		return ActionSet{}, nil
+	}
+
+	for _, a := range aa {
+		a.Active = a.active(t)
+	}
+
+	return aa, nil
+}
+
+// FindByID returns the action to moderators and to the sanctioned user
+func (svc service) FindByID(channelID, ID uint64) (*Action, error) {
+	ch, err := svc.channels.FindByID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := svc.repository.FindByID(channelID, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.UserID != auth.GetIdentityFromContext(svc.ctx).Identity() && !svc.isModerator(ch) {
+		return nil, ErrActionNotFound.withStack().WithID("channelID", channelID).WithID("actionID", ID)
+	}
+
+	a.Active = a.active(now())
+	return a, nil
+}
+
+// Create mutes, kicks or bans the user
+//
+// Kicked and banned users are removed from the channel, banned can not
+// join or be added back until the ban expires or is lifted.
+func (svc service) Create(in *Action) (*Action, error) {
+	if _, err := svc.canModerate(in.ChannelID); err != nil {
+		return nil, err
+	}
+
+	moderatorID := auth.GetIdentityFromContext(svc.ctx).Identity()
+
+	switch {
+	case !in.Kind.IsValid():
+		return nil, ErrInvalidKind.withStack()
+	case in.UserID == 0 || in.UserID == moderatorID:
+		return nil, ErrInvalidUser.withStack()
+	case in.Kind == KindKick && in.Duration > 0,
+		time.Duration(in.Duration)*time.Second > maxDuration:
+		return nil, ErrInvalidDuration.withStack()
+	}
+
+	reason, err := validReason(in.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	t := now().Truncate(time.Second)
+	a := &Action{
+		ChannelID: in.ChannelID,
+		UserID:    in.UserID,
+		Kind:      in.Kind,
+		Reason:    reason,
+		CreatedBy: moderatorID,
+		CreatedAt: t,
+	}
+
+	if in.Duration > 0 {
+		exp := t.Add(time.Duration(in.Duration) * time.Second)
+		a.ExpiresAt, a.Duration = &exp, in.Duration
+	}
+
+	if a.Kind != KindMute {
+		if err = svc.channels.DeleteMember(a.ChannelID, a.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = svc.repository.Create(a); err != nil {
+		return nil, err
+	}
+
+	a.Active = a.active(t)
+	svc.log(zap.Uint64("channelID", a.ChannelID), zap.Uint64("userID", a.UserID), zap.String("kind", string(a.Kind))).
+		Info("moderation action taken")
+
+	svc.notify(a)
+	return a, nil
+}
+
+// Lift unmutes or unbans the user before the action expires
+func (svc service) Lift(channelID, ID uint64, reason string) (*Action, error) {
+	if _, err := svc.canModerate(channelID); err != nil {
+		return nil, err
+	}
+
+	a, err := svc.repository.FindByID(channelID, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	t := now().Truncate(time.Second)
+	if a.Kind == KindKick {
+		// Kicked users can join again
+		return nil, ErrInvalidKind.withStack().WithID("actionID", ID)
+	} else if !a.active(t) {
+		return nil, ErrAlreadyLifted.withStack().WithID("actionID", ID)
+	}
+
+	if a.LiftReason, err = validReason(reason); err != nil {
+		return nil, err
+	}
+
+	a.LiftedBy, a.LiftedAt = auth.GetIdentityFromContext(svc.ctx).Identity(), &t
+
+	if ok, err := svc.repository.Lift(a); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrAlreadyLifted.withStack().WithID("actionID", ID)
+	}
+
+	svc.log(zap.Uint64("channelID", a.ChannelID), zap.Uint64("userID", a.UserID), zap.String("kind", string(a.Kind))).
+		Info("moderation action lifted")
+
+	svc.notify(a)
+	return a, nil
+}
+
+// Appeal asks moderators to lift the mute or ban, once per action
+//
+// Banned users are not members of the channel anymore and can appeal
+// only when they can still read it.
+func (svc service) Appeal(channelID, ID uint64, appeal string) (*Action, error) {
+	a, err := svc.FindByID(channelID, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.UserID != auth.GetIdentityFromContext(svc.ctx).Identity() || !a.Active {
+		return nil, ErrNotAppealable.withStack().WithID("actionID", ID)
+	}
+
+	if a.Appeal, err = validReason(appeal); err != nil {
+		return nil, err
+	} else if a.Appeal == "" {
+		return nil, ErrInvalidReason.withStack()
+	}
+
+	t := now().Truncate(time.Second)
+	a.AppealedAt = &t
+
+	if ok, err := svc.repository.Appeal(a); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrAlreadyAppealed.withStack().WithID("actionID", ID)
+	}
+
+	return a, nil
+}
+
+// canPost checks if current user is not muted or banned in the channel
+func (svc service) canPost(channelID uint64) error {
+	t := now()
+
+	aa, err := svc.repository.Enforced(channelID, []uint64{auth.GetIdentityFromContext(svc.ctx).Identity()}, t)
+	if err != nil || len(aa) == 0 {
+		return err
+	}
+
+	a := aa[0]
+	for _, b := range aa {
+		if b.Kind == KindBan {
+			a = b
+			break
+		}
+	}
+
+	var e = ErrPostingRestricted.withStack()
+	if a.Kind == KindBan {
+		e = ErrChannelAccessRevoked.withStack()
+	}
+
+	return e.WithID("channelID", channelID).WithRetryAfter(a.retryAfter(t))
+}
+
+// canJoin checks if none of the users is banned from the channel
+func (svc service) canJoin(channelID uint64, userIDs ...uint64) error {
+	t := now()
+
+	aa, err := svc.repository.Enforced(channelID, userIDs, t)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range aa {
+		if a.Kind == KindBan {
+			return ErrChannelAccessRevoked.withStack().
+				WithID("channelID", channelID).
+				WithID("userID", a.UserID).
+				WithRetryAfter(a.retryAfter(t))
+		}
+	}
+
+	return nil
+}
+
+// notify tells the sanctioned user about the action
+func (svc service) notify(a *Action) {
+	if s := live.UserScope(a.UserID); live.Subscribed(s) {
+		live.Publish(&live.Event{Scope: s, Type: live.EventModerationAction, Payload: a})
+	}
+}
+
+// isModerator checks if current user can moderate the channel
+//
+// Moderators are those with a role that can manage members of the channel.
+func (svc service) isModerator(ch *messagingTypes.Channel) bool {
+	return auth.IsSuperUser(auth.GetIdentityFromContext(svc.ctx)) || svc.ac.CanManageChannelMembers(svc.ctx, ch)
+}
+
+func (svc service) canModerate(channelID uint64) (*messagingTypes.Channel, error) {
+	ch, err := svc.channels.FindByID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.isModerator(ch) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return ch, nil
+}
+
+func validReason(reason string) (string, error) {
+	if reason = strings.TrimSpace(reason); utf8.RuneCountInString(reason) > maxReasonLength {
+		return "", ErrInvalidReason.withStack()
+	}
+
+	return reason, nil
+}
@@ -0,0 +1,84 @@
+package moderation
+
+import (
+	"time"
+)
+
+type (
+	// Action a moderator took against a member of the channel
+	//
+	// Mutes and bans are active until they expire or are lifted, kicks
+	// only remove the user from the channel. Together they are the
+	// moderation log of the channel.
+	Action struct {
+		ID        uint64 `json:"actionID,string" db:"id"`
+		ChannelID uint64 `json:"channelID,string" db:"rel_channel"`
+		UserID    uint64 `json:"userID,string" db:"rel_user"`
+		Kind      Kind   `json:"kind" db:"kind"`
+		Reason    string `json:"reason" db:"reason"`
+
+		// Seconds the mute or ban lasts, it is permanent without it
+		Duration  uint       `json:"duration,omitempty" db:"-"`
+		ExpiresAt *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
+
+		CreatedBy uint64    `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+
+		// Unmuted or unbanned before it expired
+		LiftedBy   uint64     `json:"liftedBy,string,omitempty" db:"lifted_by"`
+		LiftedAt   *time.Time `json:"liftedAt,omitempty" db:"lifted_at"`
+		LiftReason string     `json:"liftReason,omitempty" db:"lift_reason"`
+
+		// Sanctioned user's appeal to moderators
+		Appeal     string     `json:"appeal,omitempty" db:"appeal"`
+		AppealedAt *time.Time `json:"appealedAt,omitempty" db:"appealed_at"`
+
+		// Mute or ban is enforced
+		Active bool `json:"active" db:"-"`
+	}
+
+	ActionSet []*Action
+
+	Filter struct {
+		ChannelID uint64 `json:"channelID,string"`
+		UserID    uint64 `json:"userID,string"`
+		Kind      Kind   `json:"kind"`
+
+		// Mutes and bans that are enforced
+		Active bool `json:"active"`
+
+		// Actions that were appealed
+		Appealed bool `json:"appealed"`
+	}
+
+	Kind string
+)
+
+const (
+	KindMute Kind = "mute"
+	KindKick Kind = "kick"
+	KindBan  Kind = "ban"
+
+	// Longest mute or ban that is not permanent
+	maxDuration = 365 * 24 * time.Hour
+
+	maxReasonLength = 1024
+)
+
+func (k Kind) IsValid() bool {
+	return k == KindMute || k == KindKick || k == KindBan
+}
+
+// active checks if the mute or ban is enforced at the given time
+func (a *Action) active(t time.Time) bool {
+	return a.Kind != KindKick && a.LiftedAt == nil && (a.ExpiresAt == nil || a.ExpiresAt.After(t))
+}
+
+// retryAfter returns how long the mute or ban lasts, 0 when it is permanent
+func (a *Action) retryAfter(t time.Time) time.Duration {
+	if a.ExpiresAt == nil {
+		return 0
+	}
+
+	return a.ExpiresAt.Sub(t)
+}
@@ -0,0 +1,36 @@
+package notifications
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	notificationError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidTimezone = notificationError{"InvalidTimezone", fault.Invalid}
+	ErrInvalidWindow   = notificationError{"InvalidWindow", fault.Invalid}
+	ErrInvalidRule     = notificationError{"InvalidRule", fault.Invalid}
+	ErrTooManyRules    = notificationError{"TooManyRules", fault.TooMany}
+	ErrTooManyWindows  = notificationError{"TooManyWindows", fault.TooMany}
+)
+
+func (e notificationError) Error() string {
+	return e.String()
+}
+
+func (e notificationError) String() string {
+	return "crust.notifications." + e.name
+}
+
+func (e notificationError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e notificationError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,122 @@
+package notifications
+
+import (
+	"go.uber.org/zap"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+// notify routes notifications about the new message to members of its
+// channel, by their rules and do-not-disturb schedules
+func (svc service) notify(m *messagingTypes.Message) {
+	defer sentry.Recover()
+
+	log := svc.log(zap.Uint64("messageID", m.ID), zap.Uint64("channelID", m.ChannelID))
+
+	ee, err := svc.events(m)
+	if err != nil {
+		log.Error("could not notify about message", zap.Error(err))
+		return
+	} else if len(ee) == 0 {
+		return
+	}
+
+	userIDs := make([]uint64, 0, len(ee))
+	for userID := range ee {
+		userIDs = append(userIDs, userID)
+	}
+
+	ss, err := svc.settingsOf(userIDs...)
+	if err != nil {
+		log.Error("could not notify about message", zap.Error(err))
+		return
+	}
+
+	var (
+		t  = now()
+		dd = DigestSet{}
+	)
+
+	for userID, e := range ee {
+		switch ss[userID].route(e, m.ChannelID, t) {
+		case RoutePush:
+			if s := live.UserScope(userID); live.Subscribed(s) {
+				live.Publish(&live.Event{Scope: s, Type: live.EventNotification, Payload: &Notification{
+					ChannelID: m.ChannelID,
+					ThreadID:  m.ReplyTo,
+					MessageID: m.ID,
+					UserID:    m.UserID,
+					Event:     e,
+				}})
+			}
+
+		case RouteDigest:
+			d := &Digest{
+				UserID:         userID,
+				ChannelID:      m.ChannelID,
+				Messages:       1,
+				FirstMessageID: m.ID,
+				LastMessageID:  m.ID,
+				LastAt:         m.CreatedAt,
+			}
+
+			if e == EventMention {
+				d.Mentions = 1
+			}
+
+			dd = append(dd, d)
+		}
+	}
+
+	if err = svc.repository.AddToDigests(dd...); err != nil {
+		log.Error("could not add message to digests", zap.Error(err))
+	}
+}
+
+// events returns why each member of the channel is notified about the message
+func (svc service) events(m *messagingTypes.Message) (map[uint64]Event, error) {
+	userIDs, typ, err := svc.repository.Recipients(m.ChannelID, m.UserID)
+	if err != nil || len(userIDs) == 0 {
+		return nil, err
+	}
+
+	out := make(map[uint64]Event, len(userIDs))
+	for _, userID := range userIDs {
+		out[userID] = EventChannel
+		if typ == messagingTypes.ChannelTypeGroup {
+			out[userID] = EventDirect
+		}
+	}
+
+	if typ == messagingTypes.ChannelTypeGroup {
+		return out, nil
+	}
+
+	if m.ReplyTo > 0 {
+		pp, err := svc.repository.Participants(m.ReplyTo)
+		if err != nil {
+			return nil, err
+		}
+
+		mark(out, EventThread, pp)
+	}
+
+	mm, err := svc.repository.Mentioned(m.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	mark(out, EventMention, mm)
+	return out, nil
+}
+
+// mark sets the event of the members among the users
+func mark(ee map[uint64]Event, e Event, userIDs []uint64) {
+	for _, userID := range userIDs {
+		if _, member := ee[userID]; member {
+			ee[userID] = e
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package notifications
+
+import (
+	"context"
+	"io"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// message wraps message service and notifies about new messages
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with notifying channel members about new messages
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Create(m)
+	if err != nil {
+		return nil, err
+	}
+
+	go defaultNotification.notify(m)
+	return m, nil
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.CreateWithAvatar(m, avatar)
+	if err != nil {
+		return nil, err
+	}
+
+	go defaultNotification.notify(m)
+	return m, nil
+}
@@ -0,0 +1,37 @@
+package notifications
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200301000000.notifications",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_notification_settings (
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  timezone         VARCHAR(64)     NOT NULL,
+  dnd_schedule     TEXT            NOT NULL,
+  dnd_until        DATETIME            NULL,
+  rules            TEXT            NOT NULL,
+  updated_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_user)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_messaging_notification_digest (
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  messages         INT UNSIGNED    NOT NULL,
+  mentions         INT UNSIGNED    NOT NULL,
+  first_message    BIGINT UNSIGNED NOT NULL,
+  last_message     BIGINT UNSIGNED NOT NULL,
+  last_at          DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_user, rel_channel)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
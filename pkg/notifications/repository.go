@@ -0,0 +1,184 @@
+package notifications
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) settings() string {
+	return "crust_messaging_notification_settings"
+}
+
+func (r repository) digests() string {
+	return "crust_messaging_notification_digest"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select("rel_user", "timezone", "dnd_schedule", "dnd_until", "rules", "updated_at").
+		From(r.settings())
+}
+
+// FindSettings returns settings of the user, nil when they were not changed
+func (r repository) FindSettings(userID uint64) (*Settings, error) {
+	var (
+		s = &Settings{}
+		q = r.query().Where(squirrel.Eq{"rel_user": userID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.UserID == 0 {
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+// FindSettingsByUserIDs returns settings of the users that changed them
+func (r repository) FindSettingsByUserIDs(userIDs ...uint64) (set []*Settings, err error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	return set, rh.FetchAll(r.db(), r.query().Where(squirrel.Eq{"rel_user": userIDs}), &set)
+}
+
+func (r repository) SetSettings(s *Settings) error {
+	return errors.WithStack(r.db().Replace(r.settings(), s))
+}
+
+// Recipients returns members of the channel, except the author, and the
+// type of the channel
+func (r repository) Recipients(channelID, exceptUserID uint64) (userIDs []uint64, typ messagingTypes.ChannelType, err error) {
+	if err = r.db().Get(&typ, "SELECT type FROM messaging_channel WHERE id = ?", channelID); err != nil {
+		return nil, "", errors.Wrap(err, "can not load channel")
+	}
+
+	err = r.db().Select(&userIDs,
+		"SELECT rel_user FROM messaging_channel_member WHERE rel_channel = ? AND type <> 'invitee' AND rel_user <> ?",
+		channelID, exceptUserID,
+	)
+
+	return userIDs, typ, errors.Wrap(err, "can not load channel members")
+}
+
+// Mentioned returns IDs of users mentioned in the message
+func (r repository) Mentioned(messageID uint64) (userIDs []uint64, err error) {
+	err = r.db().Select(&userIDs, "SELECT rel_user FROM messaging_mention WHERE rel_message = ?", messageID)
+	return userIDs, errors.Wrap(err, "can not load mentions")
+}
+
+// Participants returns authors of the thread's root message and replies
+func (r repository) Participants(threadID uint64) (userIDs []uint64, err error) {
+	err = r.db().Select(&userIDs,
+		"SELECT DISTINCT rel_user FROM messaging_message WHERE (id = ? OR reply_to = ?) AND deleted_at IS NULL",
+		threadID, threadID,
+	)
+
+	return userIDs, errors.Wrap(err, "can not load thread participants")
+}
+
+// AddToDigests counts the message in digests of the users
+func (r repository) AddToDigests(dd ...*Digest) error {
+	if len(dd) == 0 {
+		return nil
+	}
+
+	var (
+		values = make([]string, len(dd))
+		args   = make([]interface{}, 0, len(dd)*7)
+	)
+
+	for i, d := range dd {
+		values[i] = "(?, ?, ?, ?, ?, ?, ?)"
+		args = append(args, d.UserID, d.ChannelID, d.Messages, d.Mentions, d.FirstMessageID, d.LastMessageID, d.LastAt)
+	}
+
+	_, err := r.db().Exec(`
+INSERT INTO `+r.digests()+` (rel_user, rel_channel, messages, mentions, first_message, last_message, last_at)
+VALUES `+strings.Join(values, ", ")+`
+ON DUPLICATE KEY UPDATE
+  messages     = messages + VALUES(messages),
+  mentions     = mentions + VALUES(mentions),
+  last_message = VALUES(last_message),
+  last_at      = VALUES(last_at)`, args...)
+
+	return errors.Wrap(err, "can not add to digests")
+}
+
+// FindDigest returns user's digest, latest channel first
+func (r repository) FindDigest(userID uint64) (set DigestSet, err error) {
+	q := squirrel.
+		Select("rel_user", "rel_channel", "messages", "mentions", "first_message", "last_message", "last_at").
+		From(r.digests()).
+		Where(squirrel.Eq{"rel_user": userID}).
+		OrderBy("last_at DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// DigestedUsers returns those of the users that have a digest
+func (r repository) DigestedUsers(userIDs ...uint64) (out []uint64, err error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := squirrel.
+		Select("DISTINCT rel_user").
+		From(r.digests()).
+		Where(squirrel.Eq{"rel_user": userIDs}).
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, errors.WithStack(r.db().Select(&out, query, args...))
+}
+
+// ClearDigest removes the delivered digest; messages counted after it
+// was loaded are kept
+func (r repository) ClearDigest(dd DigestSet) error {
+	for _, d := range dd {
+		_, err := r.db().Exec(
+			"DELETE FROM "+r.digests()+" WHERE rel_user = ? AND rel_channel = ? AND last_message = ?",
+			d.UserID, d.ChannelID, d.LastMessageID,
+		)
+
+		if err != nil {
+			return errors.Wrap(err, "can not clear digest")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package notifications
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts notification endpoints of the current user
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Do-not-disturb schedule and routing rules
+	r.Get("/settings", rest.Handler("Notifications.Settings", func(r *http.Request) (interface{}, error) {
+		return DefaultNotification.With(r.Context()).Settings()
+	}))
+
+	r.Put("/settings", rest.Handler("Notifications.SetSettings", func(r *http.Request) (interface{}, error) {
+		s := &Settings{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		return DefaultNotification.With(r.Context()).SetSettings(s)
+	}))
+
+	// Messages routed to the digest that were not pushed yet
+	r.Get("/digest", rest.Handler("Notifications.Digest", func(r *http.Request) (interface{}, error) {
+		return DefaultNotification.With(r.Context()).Digest()
+	}))
+
+	r.Delete("/digest", rest.Handler("Notifications.ClearDigest", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultNotification.With(r.Context()).ClearDigest()
+	}))
+}
@@ -0,0 +1,133 @@
+package notifications
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// Loaded time zones by name
+	locations sync.Map
+)
+
+// location returns the time zone, UTC when it is empty
+func location(name string) (*time.Location, error) {
+	if l, ok := locations.Load(name); ok {
+		return l.(*time.Location), nil
+	}
+
+	l, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+
+	locations.Store(name, l)
+	return l, nil
+}
+
+// minutes parses "15:04" into minutes after midnight
+func minutes(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (w *Window) validate() error {
+	if _, err := minutes(w.Start); err != nil {
+		return ErrInvalidWindow.withStack()
+	}
+
+	if _, err := minutes(w.End); err != nil {
+		return ErrInvalidWindow.withStack()
+	}
+
+	for _, d := range w.Days {
+		if d < time.Sunday || d > time.Saturday {
+			return ErrInvalidWindow.withStack()
+		}
+	}
+
+	return nil
+}
+
+// covers checks if the window covers the local time; windows that end
+// before (or when) they start end on the next day
+func (w *Window) covers(t time.Time) bool {
+	var (
+		start, _ = minutes(w.Start)
+		end, _   = minutes(w.End)
+		m        = t.Hour()*60 + t.Minute()
+		day      = t.Weekday()
+	)
+
+	if start < end {
+		return w.on(day) && m >= start && m < end
+	}
+
+	return (w.on(day) && m >= start) || (w.on((day+6)%7) && m < end)
+}
+
+func (w *Window) on(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, day := range w.Days {
+		if day == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dnd checks if the user is not disturbed at the given time
+func (s *Settings) dnd(t time.Time) bool {
+	if s.SnoozedUntil != nil && s.SnoozedUntil.After(t) {
+		return true
+	}
+
+	if len(s.Schedule) == 0 {
+		return false
+	}
+
+	loc, err := location(s.Timezone)
+	if err != nil {
+		// Validated when stored
+		loc = time.UTC
+	}
+
+	t = t.In(loc)
+	for _, w := range s.Schedule {
+		if w.covers(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// route returns how the user is notified about the event in the channel,
+// push, digest or mute
+func (s *Settings) route(e Event, channelID uint64, t time.Time) Route {
+	r := defaultRoutes[e]
+
+	for _, rule := range s.Rules {
+		if (rule.Event == "" || rule.Event == e) && (rule.ChannelID == 0 || rule.ChannelID == channelID) {
+			r = rule.Route
+			break
+		}
+	}
+
+	switch {
+	case r == RouteAlways:
+		return RoutePush
+	case r == RoutePush && s.dnd(t):
+		return RouteDigest
+	}
+
+	return r
+}
@@ -0,0 +1,270 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		repository *repository
+	}
+
+	NotificationService interface {
+		With(ctx context.Context) NotificationService
+
+		Settings() (*Settings, error)
+		SetSettings(*Settings) (*Settings, error)
+
+		Digest() (DigestSet, error)
+		ClearDigest() error
+	}
+)
+
+var (
+	DefaultNotification NotificationService
+
+	// used by service decorators
+	defaultNotification *service
+
+	// now is used for do-not-disturb schedules and can be overridden
+	now = time.Now
+)
+
+// Init initializes notification settings and decorates message service
+// with notifying channel members about new messages
+//
+// Digests are pushed to users that are not disturbed every
+// NOTIFICATIONS_DIGEST_INTERVAL (1h by default).
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger: log,
+	}
+
+	DefaultNotification = svc.With(ctx)
+	defaultNotification = svc.with(ctx)
+
+	go defaultNotification.watchDigests(ctx, options.EnvDuration("", "NOTIFICATIONS_DIGEST_INTERVAL", time.Hour))
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) NotificationService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Settings returns notification settings of the current user
+func (svc service) Settings() (*Settings, error) {
+	userID := auth.GetIdentityFromContext(svc.ctx).Identity()
+
+	s, err := svc.repository.FindSettings(userID)
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		s = defaultSettings(userID)
+	}
+
+	s.DND = s.dnd(now())
+	return s, nil
+}
+
+// SetSettings replaces notification settings of the current user
+func (svc service) SetSettings(in *Settings) (*Settings, error) {
+	t := now().Truncate(time.Second)
+	s := &Settings{
+		UserID:       auth.GetIdentityFromContext(svc.ctx).Identity(),
+		Timezone:     in.Timezone,
+		Schedule:     in.Schedule,
+		SnoozedUntil: in.SnoozedUntil,
+		Rules:        in.Rules,
+		UpdatedAt:    &t,
+	}
+
+	if err := s.validate(t); err != nil {
+		return nil, err
+	}
+
+	if err := svc.repository.SetSettings(s); err != nil {
+		return nil, err
+	}
+
+	s.DND = s.dnd(t)
+	return s, nil
+}
+
+// Digest returns messages the current user was not notified about yet
+func (svc service) Digest() (DigestSet, error) {
+	dd, err := svc.repository.FindDigest(auth.GetIdentityFromContext(svc.ctx).Identity())
+	if err != nil {
+		return nil, err
+	} else if dd == nil {
+		return DigestSet{}, nil
+	}
+
+	return dd, nil
+}
+
+// ClearDigest removes the digest of the current user, after it was read
+func (svc service) ClearDigest() error {
+	dd, err := svc.repository.FindDigest(auth.GetIdentityFromContext(svc.ctx).Identity())
+	if err != nil {
+		return err
+	}
+
+	return svc.repository.ClearDigest(dd)
+}
+
+// pushDigests sends digests to the users that are not disturbed and clears them
+func (svc service) pushDigests(userIDs ...uint64) {
+	userIDs, err := svc.repository.DigestedUsers(userIDs...)
+	if err != nil || len(userIDs) == 0 {
+		if err != nil {
+			svc.log(zap.Error(err)).Error("could not push notification digests")
+		}
+
+		return
+	}
+
+	ss, err := svc.settingsOf(userIDs...)
+	if err != nil {
+		svc.log(zap.Error(err)).Error("could not push notification digests")
+		return
+	}
+
+	t := now()
+	for _, userID := range userIDs {
+		if ss[userID].dnd(t) {
+			continue
+		}
+
+		log := svc.log(zap.Uint64("userID", userID))
+
+		dd, err := svc.repository.FindDigest(userID)
+		if err != nil {
+			log.Error("could not push notification digest", zap.Error(err))
+			continue
+		} else if len(dd) == 0 {
+			continue
+		}
+
+		live.Publish(&live.Event{Scope: live.UserScope(userID), Type: live.EventNotificationDigest, Payload: dd})
+
+		if err = svc.repository.ClearDigest(dd); err != nil {
+			log.Error("could not clear notification digest", zap.Error(err))
+		}
+	}
+}
+
+// settingsOf returns settings of the users by ID, defaults for those
+// that did not change them
+func (svc service) settingsOf(userIDs ...uint64) (map[uint64]*Settings, error) {
+	set, err := svc.repository.FindSettingsByUserIDs(userIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[uint64]*Settings, len(userIDs))
+	for _, userID := range userIDs {
+		out[userID] = defaultSettings(userID)
+	}
+
+	for _, s := range set {
+		out[s.UserID] = s
+	}
+
+	return out, nil
+}
+
+// watchDigests pushes digests to subscribed users
+func (svc service) watchDigests(ctx context.Context, interval time.Duration) {
+	defer sentry.Recover()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.with(ctx).pushDigests(live.SubscribedUsers()...)
+		}
+	}
+}
+
+func defaultSettings(userID uint64) *Settings {
+	return &Settings{UserID: userID, Timezone: "UTC", Schedule: Schedule{}, Rules: RuleSet{}}
+}
+
+func (s *Settings) validate(t time.Time) error {
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+
+	if _, err := location(s.Timezone); err != nil {
+		return ErrInvalidTimezone.withStack()
+	}
+
+	if s.SnoozedUntil != nil && !s.SnoozedUntil.After(t) {
+		s.SnoozedUntil = nil
+	}
+
+	if s.Schedule == nil {
+		s.Schedule = Schedule{}
+	} else if len(s.Schedule) > maxWindows {
+		return ErrTooManyWindows.withStack()
+	}
+
+	for _, w := range s.Schedule {
+		if w == nil {
+			return ErrInvalidWindow.withStack()
+		} else if err := w.validate(); err != nil {
+			return err
+		}
+	}
+
+	if s.Rules == nil {
+		s.Rules = RuleSet{}
+	} else if len(s.Rules) > maxRules {
+		return ErrTooManyRules.withStack()
+	}
+
+	for _, r := range s.Rules {
+		if r == nil || (r.Event != "" && !r.Event.IsValid()) || !r.Route.IsValid() {
+			return ErrInvalidRule.withStack()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package notifications
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Settings of the user's notifications
+	Settings struct {
+		UserID uint64 `json:"userID,string" db:"rel_user"`
+
+		// IANA name of the zone the schedule is in, UTC by default
+		Timezone string `json:"timezone" db:"timezone"`
+
+		// Weekly do-not-disturb windows
+		Schedule Schedule `json:"dndSchedule" db:"dnd_schedule"`
+
+		// Do not disturb until the given time, regardless of the schedule
+		SnoozedUntil *time.Time `json:"dndUntil,omitempty" db:"dnd_until"`
+
+		// Routing rules, the first matching rule routes the notification
+		Rules RuleSet `json:"rules" db:"rules"`
+
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+
+		// User is not disturbed now
+		DND bool `json:"dnd" db:"-"`
+	}
+
+	// Window of a week when the user is not disturbed
+	//
+	// Window starts on one of the days and ends on the same day or, when
+	// the end is before the start, on the next day: 22:00-07:00.
+	Window struct {
+		// 0 is Sunday, every day when empty
+		Days  []time.Weekday `json:"days,omitempty"`
+		Start string         `json:"start"`
+		End   string         `json:"end"`
+	}
+
+	Schedule []*Window
+
+	// Rule routes notifications of the event (any event when empty) in
+	// the channel (any channel when empty)
+	Rule struct {
+		Event     Event  `json:"event,omitempty"`
+		ChannelID uint64 `json:"channelID,string,omitempty"`
+		Route     Route  `json:"route"`
+	}
+
+	RuleSet []*Rule
+
+	// Event is why the user is notified about the message
+	Event string
+
+	// Route is how the user is notified
+	Route string
+
+	// Notification is published to the user scope of the notified user
+	Notification struct {
+		ChannelID uint64 `json:"channelID,string"`
+		ThreadID  uint64 `json:"threadID,string,omitempty"`
+		MessageID uint64 `json:"messageID,string"`
+
+		// Author of the message
+		UserID uint64 `json:"userID,string"`
+
+		Event Event `json:"event"`
+	}
+
+	// Digest holds messages of the channel the user was not notified about
+	Digest struct {
+		UserID         uint64    `json:"-" db:"rel_user"`
+		ChannelID      uint64    `json:"channelID,string" db:"rel_channel"`
+		Messages       uint      `json:"messages" db:"messages"`
+		Mentions       uint      `json:"mentions" db:"mentions"`
+		FirstMessageID uint64    `json:"firstMessageID,string" db:"first_message"`
+		LastMessageID  uint64    `json:"lastMessageID,string" db:"last_message"`
+		LastAt         time.Time `json:"lastAt" db:"last_at"`
+	}
+
+	DigestSet []*Digest
+)
+
+const (
+	// Messages in groups (direct messages)
+	EventDirect Event = "direct"
+
+	EventMention Event = "mention"
+
+	// Replies in threads the user took part in
+	EventThread Event = "thread"
+
+	// Any other message in the channel
+	EventChannel Event = "channel"
+
+	// Pushed now, added to digest while the user is not disturbed
+	RoutePush Route = "push"
+
+	// Pushed now, also while the user is not disturbed
+	RouteAlways Route = "always"
+
+	// Added to the digest
+	RouteDigest Route = "digest"
+
+	RouteMute Route = "mute"
+
+	maxRules   = 100
+	maxWindows = 28
+)
+
+var (
+	// Routes of events that match none of the user's rules
+	defaultRoutes = map[Event]Route{
+		EventDirect:  RoutePush,
+		EventMention: RoutePush,
+		EventThread:  RoutePush,
+		EventChannel: RouteMute,
+	}
+)
+
+func (e Event) IsValid() bool {
+	_, ok := defaultRoutes[e]
+	return ok
+}
+
+func (r Route) IsValid() bool {
+	return r == RoutePush || r == RouteAlways || r == RouteDigest || r == RouteMute
+}
+
+func (s Schedule) Value() (driver.Value, error) {
+	if s == nil {
+		s = Schedule{}
+	}
+
+	return json.Marshal(s)
+}
+
+func (s *Schedule) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*s = Schedule{}
+	case []byte:
+		if err := json.Unmarshal(b, s); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Schedule", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (rr RuleSet) Value() (driver.Value, error) {
+	if rr == nil {
+		rr = RuleSet{}
+	}
+
+	return json.Marshal(rr)
+}
+
+func (rr *RuleSet) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*rr = RuleSet{}
+	case []byte:
+		if err := json.Unmarshal(b, rr); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into RuleSet", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package offline
+
+import (
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	composeRepository "github.com/cortezaproject/corteza-server/compose/repository"
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+// Changes returns changes of subscribed modules and channels since the token
+//
+// Changes are paged per module and channel; all pages of one sync share
+// the start time, so that the next sync picks up changes made while paging.
+// Deletions are returned with the first page only.
+func (svc offlineService) Changes(req ChangesRequest) (*Changeset, error) {
+	t, err := decodeToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Page == 0 {
+		t.Until, t.Page = now().Truncate(time.Second), 1
+	}
+
+	cs := &Changeset{
+		Records:         []*Record{},
+		DeletedRecords:  []*DeletedItem{},
+		Messages:        []*Message{},
+		DeletedMessages: []*DeletedItem{},
+		Unavailable:     Subscription{Modules: []ModuleRef{}, Channels: []uint64{}},
+	}
+
+	if err = svc.recordChanges(req.Modules, t, cs); err != nil {
+		return nil, err
+	}
+
+	if err = svc.messageChanges(req.Channels, t, cs); err != nil {
+		return nil, err
+	}
+
+	if cs.More {
+		cs.Token = token{Since: t.Since, Until: t.Until, Page: t.Page + 1}.encode()
+	} else {
+		cs.Token = token{Since: t.Until}.encode()
+	}
+
+	return cs, nil
+}
+
+func (svc offlineService) recordChanges(refs []ModuleRef, t token, cs *Changeset) error {
+	var (
+		ms = composeService.DefaultModule.With(svc.ctx)
+		rs = composeService.DefaultRecord.With(svc.ctx)
+		ac = composeService.DefaultAccessControl
+
+		readable []uint64
+	)
+
+	for _, ref := range refs {
+		m, err := ms.FindByID(ref.NamespaceID, ref.ModuleID)
+		if err != nil && !isUnavailable(err) {
+			return err
+		}
+
+		if m == nil || !ac.CanReadRecord(svc.ctx, m) {
+			cs.Unavailable.Modules = append(cs.Unavailable.Modules, ref)
+			continue
+		}
+
+		f := composeTypes.RecordFilter{
+			NamespaceID: m.NamespaceID,
+			ModuleID:    m.ID,
+			Sort:        "id",
+			PageFilter:  rh.Paging(t.Page, pageSize),
+		}
+
+		if !t.Since.IsZero() {
+			s := t.Since.Local().Format("2006-01-02 15:04:05")
+			f.Filter = "createdAt >= '" + s + "' OR updatedAt >= '" + s + "'"
+		}
+
+		rr, _, err := rs.Find(f)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rr {
+			cs.Records = append(cs.Records, compactRecord(r))
+		}
+
+		cs.More = cs.More || len(rr) == pageSize
+		readable = append(readable, m.ID)
+	}
+
+	if t.Page > 1 || t.Since.IsZero() || len(readable) == 0 {
+		return nil
+	}
+
+	q := squirrel.
+		Select("id", "module_id AS parent_id").
+		From("compose_record").
+		Where(squirrel.Eq{"module_id": readable}).
+		Where(squirrel.GtOrEq{"deleted_at": t.Since})
+
+	return rh.FetchAll(factory.Database.MustGet("compose").With(svc.ctx), q, &cs.DeletedRecords)
+}
+
+func (svc offlineService) messageChanges(channelIDs []uint64, t token, cs *Changeset) error {
+	if len(channelIDs) == 0 {
+		return nil
+	}
+
+	var (
+		db       = factory.Database.MustGet("messaging").With(svc.ctx)
+		readable []uint64
+	)
+
+	cc, _, err := messagingService.DefaultChannel.With(svc.ctx).Find(messagingTypes.ChannelFilter{
+		CurrentUserID: auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, ID := range channelIDs {
+		if cc.FindByID(ID) == nil {
+			cs.Unavailable.Channels = append(cs.Unavailable.Channels, ID)
+			continue
+		}
+
+		var (
+			mm []*Message
+			q  = messageQuery().
+				Where(squirrel.Eq{"rel_channel": ID, "deleted_at": nil}).
+				OrderBy("id").
+				Limit(pageSize).
+				Offset(uint64((t.Page - 1) * pageSize))
+		)
+
+		if !t.Since.IsZero() {
+			q = q.Where(squirrel.Or{
+				squirrel.GtOrEq{"created_at": t.Since},
+				squirrel.GtOrEq{"updated_at": t.Since},
+			})
+		}
+
+		if err = rh.FetchAll(db, q, &mm); err != nil {
+			return err
+		}
+
+		for _, m := range mm {
+			m.Version = version(m.CreatedAt, m.UpdatedAt)
+		}
+
+		cs.Messages = append(cs.Messages, mm...)
+		cs.More = cs.More || len(mm) == pageSize
+		readable = append(readable, ID)
+	}
+
+	if t.Page > 1 || t.Since.IsZero() || len(readable) == 0 {
+		return nil
+	}
+
+	q := squirrel.
+		Select("id", "rel_channel AS parent_id").
+		From("messaging_message").
+		Where(squirrel.Eq{"rel_channel": readable}).
+		Where(squirrel.GtOrEq{"deleted_at": t.Since})
+
+	return rh.FetchAll(db, q, &cs.DeletedMessages)
+}
+
+// findMessage loads message directly, messaging service can not find messages by ID
+func (svc offlineService) findMessage(messageID uint64) (*Message, error) {
+	var (
+		m = &Message{}
+		q = messageQuery().Where(squirrel.Eq{"id": messageID, "deleted_at": nil})
+	)
+
+	if err := rh.FetchOne(factory.Database.MustGet("messaging").With(svc.ctx), q, m); err != nil {
+		return nil, err
+	} else if m.ID == 0 {
+		return nil, ErrTargetNotFound.withStack()
+	}
+
+	m.Version = version(m.CreatedAt, m.UpdatedAt)
+	return m, nil
+}
+
+func messageQuery() squirrel.SelectBuilder {
+	return squirrel.
+		Select("id", "rel_channel", "rel_user", "reply_to", "type", "message", "created_at", "updated_at").
+		From("messaging_message")
+}
+
+func compactRecord(r *composeTypes.Record) *Record {
+	return &Record{
+		ID:          r.ID,
+		NamespaceID: r.NamespaceID,
+		ModuleID:    r.ModuleID,
+		Values:      r.Values,
+		Version:     version(r.CreatedAt, r.UpdatedAt),
+	}
+}
+
+// isUnavailable checks if the module does not exist (anymore) or can not be read
+func isUnavailable(err error) bool {
+	return fault.IsAny(err, composeRepository.ErrModuleNotFound, composeRepository.ErrNamespaceNotFound, composeService.ErrNoReadPermissions)
+}
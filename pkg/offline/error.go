@@ -0,0 +1,39 @@
+package offline
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	offlineError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidToken        = offlineError{"InvalidToken", fault.Invalid}
+	ErrInvalidConflictRule = offlineError{"InvalidConflictRule", fault.Invalid}
+	ErrInvalidKind         = offlineError{"InvalidKind", fault.Invalid}
+	ErrInvalidOp           = offlineError{"InvalidOp", fault.Invalid}
+	ErrWriteIDRequired     = offlineError{"WriteIDRequired", fault.Invalid}
+	ErrBaseRequired        = offlineError{"BaseRequired", fault.Invalid}
+	ErrTargetNotFound      = offlineError{"TargetNotFound", fault.NotFound}
+	ErrTooManyWrites       = offlineError{"TooManyWrites", fault.TooMany}
+)
+
+func (e offlineError) Error() string {
+	return e.String()
+}
+
+func (e offlineError) String() string {
+	return "crust.offline." + e.name
+}
+
+func (e offlineError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e offlineError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,33 @@
+package offline
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts offline sync endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Post("/changes", rest.Handler("Offline.Changes", func(r *http.Request) (interface{}, error) {
+		req := ChangesRequest{}
+		if err := rest.Decode(r, &req); err != nil {
+			return nil, err
+		}
+
+		return DefaultOffline.With(r.Context()).Changes(req)
+	}))
+
+	r.Post("/writes", rest.Handler("Offline.Write", func(r *http.Request) (interface{}, error) {
+		b := WriteBatch{}
+		if err := rest.Decode(r, &b); err != nil {
+			return nil, err
+		}
+
+		return DefaultOffline.With(r.Context()).Write(b)
+	}))
+}
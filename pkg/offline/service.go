@@ -0,0 +1,129 @@
+package offline
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	offlineService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		applied *appliedWrites
+	}
+
+	OfflineService interface {
+		With(ctx context.Context) OfflineService
+
+		Changes(ChangesRequest) (*Changeset, error)
+		Write(WriteBatch) ([]*WriteResult, error)
+	}
+
+	// appliedWrites keeps results of applied writes, so that retried
+	// writes (client did not get the response) are not applied twice
+	//
+	// Results are kept in memory, retries must reach the same server
+	// to be recognized.
+	appliedWrites struct {
+		sync.Mutex
+		results map[string]*appliedWrite
+	}
+
+	appliedWrite struct {
+		result    *WriteResult
+		appliedAt time.Time
+	}
+)
+
+var (
+	DefaultOffline OfflineService
+
+	// now is used for sync tokens and can be overridden
+	now = time.Now
+)
+
+// Init initializes offline sync service
+//
+// Must be called after services of all apps are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &offlineService{
+		logger:  log,
+		applied: &appliedWrites{results: map[string]*appliedWrite{}},
+	}
+
+	DefaultOffline = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc offlineService) With(ctx context.Context) OfflineService {
+	return &offlineService{
+		ctx:     ctx,
+		logger:  svc.logger,
+		applied: svc.applied,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc offlineService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// watch removes old results of applied writes
+func (svc offlineService) watch(ctx context.Context) {
+	t := time.NewTicker(pruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.applied.prune(now().Add(-appliedRetention))
+		}
+	}
+}
+
+func (svc offlineService) appliedKey(writeID string) string {
+	return strconv.FormatUint(auth.GetIdentityFromContext(svc.ctx).Identity(), 10) + ":" + writeID
+}
+
+func (aw *appliedWrites) get(key string) *WriteResult {
+	aw.Lock()
+	defer aw.Unlock()
+
+	if a, ok := aw.results[key]; ok {
+		return a.result
+	}
+
+	return nil
+}
+
+func (aw *appliedWrites) set(key string, res *WriteResult) {
+	aw.Lock()
+	defer aw.Unlock()
+
+	aw.results[key] = &appliedWrite{result: res, appliedAt: now()}
+}
+
+func (aw *appliedWrites) prune(before time.Time) {
+	aw.Lock()
+	defer aw.Unlock()
+
+	for key, a := range aw.results {
+		if a.appliedAt.Before(before) {
+			delete(aw.results, key)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package offline
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Subscription lists modules and channels the client keeps offline
+	Subscription struct {
+		Modules  []ModuleRef `json:"modules"`
+		Channels []uint64    `json:"channels,string"`
+	}
+
+	ModuleRef struct {
+		NamespaceID uint64 `json:"namespaceID,string"`
+		ModuleID    uint64 `json:"moduleID,string"`
+	}
+
+	// ChangesRequest asks for changes since the sync token
+	//
+	// Empty token requests everything (initial sync)
+	ChangesRequest struct {
+		Subscription
+		Token string `json:"token"`
+	}
+
+	// Changeset holds changes of subscribed modules and channels
+	//
+	// When More is set, the client should request again with the returned
+	// token to get the rest; otherwise the token is kept for the next sync.
+	Changeset struct {
+		Records         []*Record      `json:"records"`
+		DeletedRecords  []*DeletedItem `json:"deletedRecords"`
+		Messages        []*Message     `json:"messages"`
+		DeletedMessages []*DeletedItem `json:"deletedMessages"`
+
+		// Subscribed modules and channels that are (no longer) accessible;
+		// the client should drop their local copies
+		Unavailable Subscription `json:"unavailable"`
+
+		Token string `json:"token"`
+		More  bool   `json:"more"`
+	}
+
+	// Record is a compact representation of a compose record
+	Record struct {
+		ID          uint64                      `json:"recordID,string"`
+		NamespaceID uint64                      `json:"namespaceID,string"`
+		ModuleID    uint64                      `json:"moduleID,string"`
+		Values      composeTypes.RecordValueSet `json:"values"`
+		Version     time.Time                   `json:"version"`
+	}
+
+	// Message is a compact representation of a messaging message
+	Message struct {
+		ID        uint64    `json:"messageID,string" db:"id"`
+		ChannelID uint64    `json:"channelID,string" db:"rel_channel"`
+		UserID    uint64    `json:"userID,string" db:"rel_user"`
+		ReplyTo   uint64    `json:"replyTo,string,omitempty" db:"reply_to"`
+		Type      string    `json:"type" db:"type"`
+		Message   string    `json:"message" db:"message"`
+		Version   time.Time `json:"version" db:"-"`
+
+		CreatedAt time.Time  `json:"-" db:"created_at"`
+		UpdatedAt *time.Time `json:"-" db:"updated_at"`
+	}
+
+	// DeletedItem identifies removed record (with module) or message (with channel)
+	DeletedItem struct {
+		ID       uint64 `json:"id,string" db:"id"`
+		ParentID uint64 `json:"parentID,string" db:"parent_id"`
+	}
+
+	// WriteBatch holds writes the client queued while offline
+	//
+	// Writes are applied in order; each one gets its own result.
+	WriteBatch struct {
+		Conflict ConflictRule `json:"conflict"`
+		Writes   []*Write     `json:"writes"`
+	}
+
+	// Write is one queued local change
+	Write struct {
+		// Client-generated ID, unique per user; writes with IDs that
+		// were already applied are not applied again
+		ID string `json:"id"`
+
+		Kind Kind `json:"kind"`
+		Op   Op   `json:"op"`
+
+		// Target of an update or delete; instead of ID, it can refer to
+		// an earlier create (by its write ID) that was not synced yet
+		TargetID uint64 `json:"targetID,string,omitempty"`
+		Ref      string `json:"ref,omitempty"`
+
+		// Version of the target the client changed, required for updates & deletes
+		Base *time.Time `json:"base,omitempty"`
+
+		// Records; on update, only the sent fields are changed
+		NamespaceID uint64                      `json:"namespaceID,string,omitempty"`
+		ModuleID    uint64                      `json:"moduleID,string,omitempty"`
+		Values      composeTypes.RecordValueSet `json:"values,omitempty"`
+
+		// Messages
+		ChannelID uint64 `json:"channelID,string,omitempty"`
+		ReplyTo   uint64 `json:"replyTo,string,omitempty"`
+		Message   string `json:"message,omitempty"`
+	}
+
+	WriteResult struct {
+		ID       string      `json:"id"`
+		Status   WriteStatus `json:"status"`
+		TargetID uint64      `json:"targetID,string,omitempty"`
+		Version  *time.Time  `json:"version,omitempty"`
+		Error    string      `json:"error,omitempty"`
+
+		// Server's version of the target when write was rejected due to conflict
+		Current interface{} `json:"current,omitempty"`
+	}
+
+	ConflictRule string
+	Kind         string
+	Op           string
+	WriteStatus  string
+
+	// token is the content of an (opaque) sync token
+	token struct {
+		// Changes made at or after this time are returned
+		Since time.Time `json:"s"`
+
+		// Time the sync started and page to continue with, set while paging
+		Until time.Time `json:"u,omitempty"`
+		Page  uint      `json:"p,omitempty"`
+	}
+)
+
+const (
+	// Write is rejected when the target changed on the server after the client's version
+	ServerWins ConflictRule = "server-wins"
+
+	// Write is applied regardless of the changes on the server
+	ClientWins ConflictRule = "client-wins"
+
+	KindRecord  Kind = "record"
+	KindMessage Kind = "message"
+
+	OpCreate Op = "create"
+	OpUpdate Op = "update"
+	OpDelete Op = "delete"
+
+	StatusApplied  WriteStatus = "applied"
+	StatusConflict WriteStatus = "conflict"
+	StatusFailed   WriteStatus = "failed"
+
+	// Max number of records & messages per module or channel in one changeset
+	pageSize = 100
+
+	maxWrites = 500
+
+	// How long results of applied writes are kept for retries
+	appliedRetention = 24 * time.Hour
+	pruneInterval    = time.Hour
+)
+
+func (r ConflictRule) IsValid() bool {
+	return r == ServerWins || r == ClientWins
+}
+
+func (k Kind) IsValid() bool {
+	return k == KindRecord || k == KindMessage
+}
+
+func (o Op) IsValid() bool {
+	return o == OpCreate || o == OpUpdate || o == OpDelete
+}
+
+// encode returns opaque, URL safe token
+func (t token) encode() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeToken(s string) (t token, err error) {
+	if s == "" {
+		return
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, ErrInvalidToken.withStack()
+	}
+
+	if err = json.Unmarshal(b, &t); err != nil {
+		return t, ErrInvalidToken.withStack()
+	}
+
+	return t, nil
+}
+
+// version returns time of the last change of a record or message
+func version(createdAt time.Time, updatedAt *time.Time) time.Time {
+	if updatedAt != nil {
+		return updatedAt.Truncate(time.Second)
+	}
+
+	return createdAt.Truncate(time.Second)
+}
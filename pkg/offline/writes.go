@@ -0,0 +1,228 @@
+package offline
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	composeRepository "github.com/cortezaproject/corteza-server/compose/repository"
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+// Write applies writes the client queued while offline
+//
+// Invalid batch is rejected as a whole; failures and conflicts of
+// individual writes are reported in their results.
+func (svc offlineService) Write(b WriteBatch) ([]*WriteResult, error) {
+	if b.Conflict == "" {
+		b.Conflict = ServerWins
+	} else if !b.Conflict.IsValid() {
+		return nil, ErrInvalidConflictRule.withStack()
+	}
+
+	if len(b.Writes) > maxWrites {
+		return nil, ErrTooManyWrites.withStack()
+	}
+
+	for _, w := range b.Writes {
+		if err := w.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		out = make([]*WriteResult, 0, len(b.Writes))
+
+		// IDs of targets created by writes, for writes that refer to them
+		created = map[string]uint64{}
+	)
+
+	for _, w := range b.Writes {
+		key := svc.appliedKey(w.ID)
+
+		res := svc.applied.get(key)
+		if res == nil {
+			if w.TargetID == 0 && w.Ref != "" {
+				if w.TargetID = created[w.Ref]; w.TargetID == 0 {
+					if ref := svc.applied.get(svc.appliedKey(w.Ref)); ref != nil {
+						w.TargetID = ref.TargetID
+					}
+				}
+			}
+
+			res = svc.apply(w, b.Conflict)
+
+			if res.Status == StatusApplied {
+				svc.applied.set(key, res)
+			}
+		}
+
+		if w.Op == OpCreate && res.Status == StatusApplied {
+			created[w.ID] = res.TargetID
+		}
+
+		out = append(out, res)
+	}
+
+	return out, nil
+}
+
+func (w Write) validate() error {
+	switch {
+	case w.ID == "":
+		return ErrWriteIDRequired.withStack()
+	case !w.Kind.IsValid():
+		return ErrInvalidKind.withStack()
+	case !w.Op.IsValid():
+		return ErrInvalidOp.withStack()
+	case w.Op != OpCreate && w.Base == nil:
+		return ErrBaseRequired.withStack()
+	}
+
+	return nil
+}
+
+func (svc offlineService) apply(w *Write, rule ConflictRule) (res *WriteResult) {
+	var err error
+
+	res = &WriteResult{ID: w.ID, TargetID: w.TargetID}
+
+	if w.Op != OpCreate && w.TargetID == 0 {
+		err = ErrTargetNotFound.withStack()
+	} else if w.Kind == KindRecord {
+		err = svc.applyRecord(w, rule, res)
+	} else {
+		err = svc.applyMessage(w, rule, res)
+	}
+
+	if err != nil {
+		svc.log(zap.String("writeID", w.ID), zap.Error(err)).Debug("could not apply offline write")
+		res.Status, res.Error = StatusFailed, err.Error()
+	}
+
+	return res
+}
+
+func (svc offlineService) applyRecord(w *Write, rule ConflictRule, res *WriteResult) error {
+	rs := composeService.DefaultRecord.With(svc.ctx)
+
+	if w.Op == OpCreate {
+		r, err := rs.Create(&composeTypes.Record{NamespaceID: w.NamespaceID, ModuleID: w.ModuleID, Values: w.Values})
+		if err != nil {
+			return err
+		}
+
+		return res.applied(r.ID, version(r.CreatedAt, r.UpdatedAt))
+	}
+
+	r, err := rs.FindByID(w.NamespaceID, w.TargetID)
+	if fault.Is(err, composeRepository.ErrRecordNotFound) {
+		if w.Op == OpDelete {
+			// Already deleted
+			res.Status = StatusApplied
+			return nil
+		}
+
+		return ErrTargetNotFound.withStack()
+	} else if err != nil {
+		return err
+	}
+
+	if rule == ServerWins && version(r.CreatedAt, r.UpdatedAt).After(*w.Base) {
+		res.Status, res.Current = StatusConflict, compactRecord(r)
+		return nil
+	}
+
+	if w.Op == OpDelete {
+		if err = rs.DeleteByID(r.NamespaceID, r.ID); err != nil {
+			return err
+		}
+
+		res.Status = StatusApplied
+		return nil
+	}
+
+	r.Values = mergeValues(r.Values, w.Values)
+	if r, err = rs.Update(r); err != nil {
+		return err
+	}
+
+	return res.applied(r.ID, version(r.CreatedAt, r.UpdatedAt))
+}
+
+func (svc offlineService) applyMessage(w *Write, rule ConflictRule, res *WriteResult) error {
+	ms := messagingService.DefaultMessage.With(svc.ctx)
+
+	if w.Op == OpCreate {
+		m, err := ms.Create(&messagingTypes.Message{ChannelID: w.ChannelID, ReplyTo: w.ReplyTo, Message: w.Message})
+		if err != nil {
+			return err
+		}
+
+		return res.applied(m.ID, version(m.CreatedAt, m.UpdatedAt))
+	}
+
+	m, err := svc.findMessage(w.TargetID)
+	if fault.Is(err, ErrTargetNotFound) {
+		if w.Op == OpDelete {
+			// Already deleted
+			res.Status = StatusApplied
+			return nil
+		}
+
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if rule == ServerWins && m.Version.After(*w.Base) {
+		res.Status, res.Current = StatusConflict, m
+		return nil
+	}
+
+	if w.Op == OpDelete {
+		if err = ms.Delete(m.ID); err != nil {
+			return err
+		}
+
+		res.Status = StatusApplied
+		return nil
+	}
+
+	updated, err := ms.Update(&messagingTypes.Message{ID: m.ID, ChannelID: m.ChannelID, Message: w.Message})
+	if err != nil {
+		return err
+	}
+
+	return res.applied(updated.ID, version(updated.CreatedAt, updated.UpdatedAt))
+}
+
+func (res *WriteResult) applied(targetID uint64, v time.Time) error {
+	res.Status, res.TargetID, res.Version = StatusApplied, targetID, &v
+	return nil
+}
+
+// mergeValues replaces values of the sent fields and keeps the rest
+func mergeValues(current, sent composeTypes.RecordValueSet) composeTypes.RecordValueSet {
+	var (
+		out   = composeTypes.RecordValueSet{}
+		names = map[string]bool{}
+	)
+
+	for _, v := range sent {
+		names[v.Name] = true
+		out = append(out, v)
+	}
+
+	for _, v := range current {
+		if !names[v.Name] {
+			out = append(out, v)
+		}
+	}
+
+	return out
+}
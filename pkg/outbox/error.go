@@ -0,0 +1,32 @@
+package outbox
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	outboxError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidTopic = outboxError{"InvalidTopic", fault.Invalid}
+)
+
+func (e outboxError) Error() string {
+	return e.String()
+}
+
+func (e outboxError) String() string {
+	return "crust.outbox." + e.name
+}
+
+func (e outboxError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e outboxError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,31 @@
+package outbox
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200213000000.outbox",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_outbox (
+  id                 BIGINT UNSIGNED NOT NULL,
+  topic              VARCHAR(64)     NOT NULL,
+  payload            MEDIUMTEXT      NOT NULL,
+
+  status             VARCHAR(16)     NOT NULL,
+  attempts           INT UNSIGNED    NOT NULL DEFAULT 0,
+  last_error         TEXT            NOT NULL,
+  next_attempt_at    DATETIME            NULL DEFAULT NULL,
+
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  dispatched_at      DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (status, next_attempt_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
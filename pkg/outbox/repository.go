@@ -0,0 +1,118 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_outbox"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"topic",
+			"payload",
+			"status",
+			"attempts",
+			"last_error",
+			"next_attempt_at",
+			"created_at",
+			"dispatched_at",
+		).
+		From(r.table())
+}
+
+// FindDue returns pending events of the given topics that should be dispatched by now
+//
+// Events are returned in the order they were written.
+func (r repository) FindDue(now time.Time, topics ...string) (set EventSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"status": StatusPending, "topic": topics}).
+		Where(squirrel.LtOrEq{"next_attempt_at": now}).
+		OrderBy("id").
+		Limit(batchSize)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(e *Event) (*Event, error) {
+	e.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	return e, errors.WithStack(r.db().Insert(r.table(), e))
+}
+
+// Claim postpones event's next attempt; returns false when event was
+// already claimed (next attempt was moved) by another instance
+func (r repository) Claim(e *Event, until time.Time) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.table()+" SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at = ?",
+		until, e.ID, StatusPending, e.NextAttemptAt,
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
+
+// UpdateState stores result of the delivery
+func (r repository) UpdateState(e *Event) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{
+			"status":          e.Status,
+			"attempts":        e.Attempts,
+			"last_error":      e.LastError,
+			"next_attempt_at": e.NextAttemptAt,
+			"dispatched_at":   e.DispatchedAt,
+		},
+		squirrel.Eq{"id": e.ID},
+	)
+}
+
+// Prune removes events dispatched before the given time
+func (r repository) Prune(before time.Time) error {
+	return rh.Delete(r.db(), r.table(), squirrel.And{
+		squirrel.Eq{"status": StatusDispatched},
+		squirrel.Lt{"dispatched_at": before},
+	})
+}
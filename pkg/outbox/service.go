@@ -0,0 +1,190 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	dispatcher struct {
+		logger *zap.Logger
+	}
+
+	// handlers holds handlers of the topics
+	handlers struct {
+		sync.RWMutex
+		m map[string]Handler
+	}
+)
+
+var (
+	// now is used for scheduling and can be overridden
+	now = time.Now
+
+	registered = &handlers{m: map[string]Handler{}}
+)
+
+// Init starts dispatching events in the background
+//
+// Events of topics without a handler stay pending until one is registered.
+func Init(ctx context.Context, log *zap.Logger) error {
+	d := &dispatcher{logger: log}
+
+	go d.watch(ctx)
+
+	return nil
+}
+
+// Handle registers handler of the topic, replacing the existing one
+func Handle(topic string, h Handler) {
+	registered.Lock()
+	defer registered.Unlock()
+
+	registered.m[topic] = h
+}
+
+// Write stores event to the outbox of the compose database
+//
+// Pass the database handle of the transaction that changes the state, so
+// that the event is stored (and later dispatched) only when the change is
+// committed; nothing is dispatched from a transaction that is rolled back.
+func Write(db *factory.DB, topic string, payload interface{}) error {
+	if topic == "" || len(topic) > maxTopicLength {
+		return ErrInvalidTopic.withStack()
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	at := now()
+	_, err = Repository(context.Background(), db).Create(&Event{
+		Topic:         topic,
+		Payload:       b,
+		Status:        StatusPending,
+		NextAttemptAt: &at,
+	})
+
+	return err
+}
+
+// watch dispatches due events and removes old dispatched ones
+func (d dispatcher) watch(ctx context.Context) {
+	var (
+		w = time.NewTicker(watchInterval)
+		p = time.NewTicker(pruneInterval)
+	)
+
+	defer w.Stop()
+	defer p.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.C:
+			topics := registered.topics()
+			if len(topics) == 0 {
+				continue
+			}
+
+			set, err := Repository(ctx, nil).FindDue(now(), topics...)
+			if err != nil {
+				d.logger.Error("could not load due events", zap.Error(err))
+				continue
+			}
+
+			for _, e := range set {
+				if ctx.Err() != nil {
+					return
+				}
+
+				d.dispatch(ctx, e)
+			}
+		case <-p.C:
+			if err := Repository(ctx, nil).Prune(now().Add(-retention)); err != nil {
+				d.logger.Error("could not remove old events", zap.Error(err))
+			}
+		}
+	}
+}
+
+// dispatch claims the event and calls topic's handler
+func (d dispatcher) dispatch(ctx context.Context, e *Event) {
+	var (
+		r   = Repository(ctx, nil)
+		log = d.logger.With(zap.Uint64("eventID", e.ID), zap.String("topic", e.Topic))
+		h   = registered.get(e.Topic)
+	)
+
+	if h == nil {
+		return
+	}
+
+	if ok, err := r.Claim(e, now().Add(claimTimeout)); err != nil {
+		log.Error("could not claim event", zap.Error(err))
+		return
+	} else if !ok {
+		return
+	}
+
+	e.Attempts++
+	e.LastError = ""
+
+	if err := d.call(ctx, h, e); err != nil {
+		e.LastError = err.Error()
+
+		if e.Attempts >= maxAttempts {
+			e.Status, e.NextAttemptAt = StatusDead, nil
+			log.Warn("event delivery failed too many times", zap.Error(err))
+		} else {
+			at := now().Add(backoff(e.Attempts))
+			e.NextAttemptAt = &at
+		}
+	} else {
+		at := now()
+		e.Status, e.NextAttemptAt, e.DispatchedAt = StatusDispatched, nil, &at
+	}
+
+	if err := r.UpdateState(e); err != nil {
+		log.Error("could not store event state", zap.Error(err))
+	}
+}
+
+// call runs the handler with super user's context
+func (d dispatcher) call(ctx context.Context, h Handler, e *Event) error {
+	ctx, cancel := context.WithTimeout(auth.SetSuperUserContext(ctx), dispatchTimeout)
+	defer cancel()
+
+	return h(ctx, e.Payload)
+}
+
+func (h *handlers) get(topic string) Handler {
+	h.RLock()
+	defer h.RUnlock()
+
+	return h.m[topic]
+}
+
+func (h *handlers) topics() []string {
+	h.RLock()
+	defer h.RUnlock()
+
+	tt := make([]string, 0, len(h.m))
+	for topic := range h.m {
+		tt = append(tt, topic)
+	}
+
+	sort.Strings(tt)
+	return tt
+}
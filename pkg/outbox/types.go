@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type (
+	// Event is written in the same transaction as the state change it
+	// describes and is dispatched to topic's handler after the commit
+	Event struct {
+		ID      uint64          `db:"id"`
+		Topic   string          `db:"topic"`
+		Payload json.RawMessage `db:"payload"`
+
+		Status        Status     `db:"status"`
+		Attempts      uint       `db:"attempts"`
+		LastError     string     `db:"last_error"`
+		NextAttemptAt *time.Time `db:"next_attempt_at"`
+
+		CreatedAt    time.Time  `db:"created_at"`
+		DispatchedAt *time.Time `db:"dispatched_at"`
+	}
+
+	EventSet []*Event
+
+	// Handler delivers event's payload
+	//
+	// Events are delivered at least once; handlers that fail are called
+	// again with backoff, so they should be idempotent.
+	Handler func(ctx context.Context, payload json.RawMessage) error
+
+	Status string
+)
+
+const (
+	StatusPending    Status = "pending"
+	StatusDispatched Status = "dispatched"
+	StatusDead       Status = "dead"
+
+	// First delivery counts as an attempt
+	maxAttempts = 8
+
+	// Delay after the first failed attempt, doubled with every next one
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+
+	// Events are claimed for the duration of the delivery, so that
+	// other instances do not pick them up
+	claimTimeout    = time.Minute
+	dispatchTimeout = 30 * time.Second
+
+	watchInterval = 2 * time.Second
+	batchSize     = 100
+
+	pruneInterval = time.Hour
+
+	// Dispatched events are kept for inspection; dead ones until removed
+	retention = 3 * 24 * time.Hour
+
+	maxTopicLength = 64
+)
+
+// backoff returns delay before the next attempt
+func backoff(attempts uint) time.Duration {
+	d := baseBackoff
+	for i := uint(1); i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return d
+}
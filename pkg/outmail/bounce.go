@@ -0,0 +1,238 @@
+package outmail
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"net"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+type (
+	// pop3 is a minimal POP3 client, enough to read and remove bounces
+	pop3 struct {
+		conn *textproto.Conn
+	}
+)
+
+const (
+	// Whole session with the mailbox must fit into it
+	pop3Timeout = 2 * time.Minute
+
+	// Messages read from the mailbox at once, the rest are read next time
+	maxPolled = 100
+)
+
+var (
+	// Tokens of our emails, found in Message-ID of the bounced email
+	tokenPattern = regexp.MustCompile(`\b[0-9a-f]{64}\b`)
+)
+
+// poll reads bounces from sender's mailbox
+//
+// Bounces of sender's emails are removed from the mailbox,
+// other messages are left there.
+func (svc outmailService) poll(s *Sender) error {
+	p, err := dialPOP3(s.BounceMailbox, s.BounceUsername, s.BouncePassword)
+	if err != nil {
+		return err
+	}
+
+	defer p.close()
+
+	n, err := p.count()
+	if err != nil {
+		return err
+	}
+
+	if n > maxPolled {
+		n = maxPolled
+	}
+
+	for i := 1; i <= n; i++ {
+		msg, err := p.retrieve(i)
+		if err != nil {
+			return err
+		}
+
+		e, reason := svc.bounceOf(s, msg)
+		if e == nil {
+			continue
+		}
+
+		if err = svc.bounced(e, reason); err != nil {
+			return err
+		}
+
+		if err = p.delete(i); err != nil {
+			return err
+		}
+	}
+
+	// Messages are removed when the session ends
+	return p.quit()
+}
+
+// bounceOf returns sender's email the message is a bounce of, with the reason
+func (svc outmailService) bounceOf(s *Sender, msg []byte) (*Email, string) {
+	tokens := tokenPattern.FindAll(msg, 10)
+
+	for _, t := range tokens {
+		e, err := svc.repository.FindEmailByToken(string(t))
+		if isNotFound(err) {
+			continue
+		} else if err != nil {
+			svc.log(zap.Error(err)).Error("could not find bounced email")
+			return nil, ""
+		}
+
+		if e.SenderID == s.ID {
+			return e, parseBounce(msg)
+		}
+	}
+
+	return nil, ""
+}
+
+// parseBounce returns reason of the bounce from delivery status notification
+//
+// Diagnostic code is preferred to the status, recipient is added when known.
+func parseBounce(msg []byte) string {
+	var (
+		recipient, status, diagnostic string
+
+		value = func(line string) string {
+			v := line[strings.Index(line, ":")+1:]
+			if i := strings.Index(v, ";"); i >= 0 {
+				v = v[i+1:]
+			}
+
+			return strings.TrimSpace(v)
+		}
+
+		s = bufio.NewScanner(bytes.NewReader(msg))
+	)
+
+	for s.Scan() {
+		line := s.Text()
+		switch l := strings.ToLower(line); {
+		case recipient == "" && strings.HasPrefix(l, "final-recipient:"):
+			recipient = value(line)
+		case status == "" && strings.HasPrefix(l, "status:"):
+			status = value(line)
+		case diagnostic == "" && strings.HasPrefix(l, "diagnostic-code:"):
+			diagnostic = value(line)
+		}
+	}
+
+	if diagnostic != "" {
+		return bounceReason(recipient, diagnostic)
+	}
+
+	return bounceReason(recipient, status)
+}
+
+// dialPOP3 connects to the mailbox over TLS and signs in
+func dialPOP3(mailbox, username, password string) (*pop3, error) {
+	host, port, err := splitHost(mailbox, defaultPOP3Port)
+	if err != nil {
+		return nil, ErrInvalidMailbox.withStack().WithMessage("invalid bounce mailbox " + mailbox)
+	}
+
+	d := &net.Dialer{Timeout: 30 * time.Second}
+	c, err := tls.DialWithDialer(d, "tcp", net.JoinHostPort(host, port), &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if err = c.SetDeadline(time.Now().Add(pop3Timeout)); err != nil {
+		c.Close()
+		return nil, errors.WithStack(err)
+	}
+
+	p := &pop3{conn: textproto.NewConn(c)}
+
+	// Greeting
+	if _, err = p.read(); err != nil {
+		p.close()
+		return nil, err
+	}
+
+	if _, err = p.cmd("USER %s", username); err == nil {
+		_, err = p.cmd("PASS %s", password)
+	}
+
+	if err != nil {
+		p.close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// count returns number of messages in the mailbox
+func (p *pop3) count() (int, error) {
+	line, err := p.cmd("STAT")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.Atoi(strings.Fields(line + " ")[0])
+	if err != nil {
+		return 0, ErrMailboxFailed.withStack().WithMessage("unexpected STAT response " + line)
+	}
+
+	return n, nil
+}
+
+func (p *pop3) retrieve(i int) ([]byte, error) {
+	if _, err := p.cmd("RETR %d", i); err != nil {
+		return nil, err
+	}
+
+	b, err := p.conn.ReadDotBytes()
+	return b, errors.WithStack(err)
+}
+
+func (p *pop3) delete(i int) error {
+	_, err := p.cmd("DELE %d", i)
+	return err
+}
+
+func (p *pop3) quit() error {
+	_, err := p.cmd("QUIT")
+	return err
+}
+
+func (p *pop3) close() {
+	_ = p.conn.Close()
+}
+
+func (p *pop3) cmd(format string, args ...interface{}) (string, error) {
+	if err := p.conn.PrintfLine(format, args...); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return p.read()
+}
+
+// read returns status line of the response without +OK
+func (p *pop3) read() (string, error) {
+	line, err := p.conn.ReadLine()
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	if !strings.HasPrefix(line, "+OK") {
+		return "", ErrMailboxFailed.withStack().WithMessage(line)
+	}
+
+	return strings.TrimSpace(strings.TrimPrefix(line, "+OK")), nil
+}
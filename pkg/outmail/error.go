@@ -0,0 +1,42 @@
+package outmail
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	outmailError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrSenderNotFound    = outmailError{"SenderNotFound", fault.NotFound}
+	ErrEmailNotFound     = outmailError{"EmailNotFound", fault.NotFound}
+	ErrInvalidAddress    = outmailError{"InvalidAddress", fault.Invalid}
+	ErrInvalidSender     = outmailError{"InvalidSender", fault.Invalid}
+	ErrInvalidMailbox    = outmailError{"InvalidMailbox", fault.Invalid}
+	ErrInvalidBounce     = outmailError{"InvalidBounce", fault.Invalid}
+	ErrNoRecipients      = outmailError{"NoRecipients", fault.Invalid}
+	ErrTooManyRecipients = outmailError{"TooManyRecipients", fault.TooMany}
+	ErrEmptyMessage      = outmailError{"EmptyMessage", fault.Invalid}
+	ErrMailboxFailed     = outmailError{"MailboxFailed", fault.Unavailable}
+	ErrNoPermissions     = outmailError{"NoPermissions", fault.Forbidden}
+)
+
+func (e outmailError) Error() string {
+	return e.String()
+}
+
+func (e outmailError) String() string {
+	return "crust.outmail." + e.name
+}
+
+func (e outmailError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e outmailError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,94 @@
+package outmail
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200312000000.outmail",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_mail_sender (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  from_name          VARCHAR(255)    NOT NULL DEFAULT '',
+  from_address       VARCHAR(255)    NOT NULL,
+  reply_to           VARCHAR(255)    NOT NULL DEFAULT '',
+
+  smtp_host          VARCHAR(255)    NOT NULL DEFAULT '',
+  smtp_username      VARCHAR(255)    NOT NULL DEFAULT '',
+  smtp_password      TEXT            NOT NULL,
+
+  track_opens        BOOLEAN         NOT NULL DEFAULT FALSE,
+  track_clicks       BOOLEAN         NOT NULL DEFAULT FALSE,
+
+  bounce_token       VARCHAR(64)     NOT NULL,
+  bounce_mailbox     VARCHAR(255)    NOT NULL DEFAULT '',
+  bounce_username    VARCHAR(255)    NOT NULL DEFAULT '',
+  bounce_password    TEXT            NOT NULL,
+  polled_at          DATETIME            NULL DEFAULT NULL,
+  last_error         TEXT            NOT NULL,
+
+  created_by         BIGINT UNSIGNED NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  UNIQUE KEY uid_bounce_token (bounce_token)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_mail_email (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_module         BIGINT UNSIGNED NOT NULL,
+  rel_record         BIGINT UNSIGNED NOT NULL,
+  rel_sender         BIGINT UNSIGNED NOT NULL,
+  recipients_to      TEXT            NOT NULL,
+  recipients_cc      TEXT            NOT NULL,
+  recipients_bcc     TEXT            NOT NULL,
+  subject            VARCHAR(512)    NOT NULL,
+  body_html          MEDIUMTEXT      NOT NULL,
+  body_text          MEDIUMTEXT      NOT NULL,
+
+  token              VARCHAR(64)     NOT NULL,
+  links              TEXT            NOT NULL,
+  tracking_url       VARCHAR(512)    NOT NULL DEFAULT '',
+
+  status             VARCHAR(16)     NOT NULL,
+  error              TEXT            NOT NULL,
+  attempts           INT UNSIGNED    NOT NULL DEFAULT 0,
+  opens              INT UNSIGNED    NOT NULL DEFAULT 0,
+  clicks             INT UNSIGNED    NOT NULL DEFAULT 0,
+  sent_at            DATETIME            NULL DEFAULT NULL,
+  opened_at          DATETIME            NULL DEFAULT NULL,
+  clicked_at         DATETIME            NULL DEFAULT NULL,
+  bounced_at         DATETIME            NULL DEFAULT NULL,
+
+  created_by         BIGINT UNSIGNED NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_record),
+  UNIQUE KEY uid_token (token)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_mail_event (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_email          BIGINT UNSIGNED NOT NULL,
+  rel_record         BIGINT UNSIGNED NOT NULL,
+  kind               VARCHAR(16)     NOT NULL,
+  detail             TEXT            NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  INDEX (rel_record, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,335 @@
+package outmail
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableSender() string {
+	return "crust_compose_mail_sender"
+}
+
+func (r repository) tableEmail() string {
+	return "crust_compose_mail_email"
+}
+
+func (r repository) tableEvent() string {
+	return "crust_compose_mail_event"
+}
+
+func (r repository) querySender() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"name",
+			"from_name",
+			"from_address",
+			"reply_to",
+			"smtp_host",
+			"smtp_username",
+			"smtp_password",
+			"track_opens",
+			"track_clicks",
+			"bounce_token",
+			"bounce_mailbox",
+			"bounce_username",
+			"bounce_password",
+			"polled_at",
+			"last_error",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableSender()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindSenderByID(namespaceID, senderID uint64) (*Sender, error) {
+	return r.findSender(squirrel.Eq{"id": senderID, "rel_namespace": namespaceID})
+}
+
+func (r repository) FindSenderByBounceToken(token string) (*Sender, error) {
+	return r.findSender(squirrel.Eq{"bounce_token": token})
+}
+
+func (r repository) findSender(cnd squirrel.Sqlizer) (*Sender, error) {
+	var (
+		s = &Sender{}
+		q = r.querySender().Where(cnd)
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.ID == 0 {
+		return nil, ErrSenderNotFound.withStack()
+	}
+
+	return s, nil
+}
+
+func (r repository) FindSenders(namespaceID uint64) (set SenderSet, err error) {
+	q := r.querySender().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindPolledSenders returns senders with bounce mailboxes
+func (r repository) FindPolledSenders() (set SenderSet, err error) {
+	q := r.querySender().
+		Where(squirrel.NotEq{"bounce_mailbox": ""}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateSender(s *Sender) (*Sender, error) {
+	s.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&s.CreatedAt)
+
+	return s, errors.WithStack(r.db().Insert(r.tableSender(), s))
+}
+
+func (r repository) UpdateSender(s *Sender) (*Sender, error) {
+	rh.SetCurrentTimeRounded(&s.UpdatedAt)
+
+	return s, errors.WithStack(r.db().Update(r.tableSender(), s, "id"))
+}
+
+// ClaimPoll marks sender's mailbox as read now, returns false when it was
+// read by another instance less than a poll interval ago
+func (r repository) ClaimPoll(senderID uint64, at time.Time) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.tableSender()+" SET polled_at = ? WHERE id = ? AND (polled_at IS NULL OR polled_at <= ?)",
+		at,
+		senderID,
+		at.Add(-pollInterval),
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, errors.WithStack(err)
+}
+
+func (r repository) UpdatePollError(senderID uint64, lastError string) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableSender(),
+		rh.Set{"last_error": lastError},
+		squirrel.Eq{"id": senderID},
+	)
+}
+
+func (r repository) DeleteSenderByID(namespaceID, senderID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableSender(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": senderID, "rel_namespace": namespaceID},
+	)
+}
+
+func (r repository) queryEmail() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_module",
+			"rel_record",
+			"rel_sender",
+			"recipients_to",
+			"recipients_cc",
+			"recipients_bcc",
+			"subject",
+			"body_html",
+			"body_text",
+			"token",
+			"links",
+			"tracking_url",
+			"status",
+			"error",
+			"attempts",
+			"opens",
+			"clicks",
+			"sent_at",
+			"opened_at",
+			"clicked_at",
+			"bounced_at",
+			"created_by",
+			"created_at",
+			"updated_at",
+		).
+		From(r.tableEmail())
+}
+
+func (r repository) FindEmailByID(namespaceID, emailID uint64) (*Email, error) {
+	return r.findEmail(squirrel.Eq{"id": emailID, "rel_namespace": namespaceID})
+}
+
+func (r repository) FindEmailByToken(token string) (*Email, error) {
+	return r.findEmail(squirrel.Eq{"token": token})
+}
+
+func (r repository) findEmail(cnd squirrel.Sqlizer) (*Email, error) {
+	var (
+		e = &Email{}
+		q = r.queryEmail().Where(cnd)
+	)
+
+	if err := rh.FetchOne(r.db(), q, e); err != nil {
+		return nil, err
+	} else if e.ID == 0 {
+		return nil, ErrEmailNotFound.withStack()
+	}
+
+	return e, nil
+}
+
+// FindEmails returns emails of the record, latest first
+func (r repository) FindEmails(namespaceID, recordID uint64) (set EmailSet, err error) {
+	q := r.queryEmail().
+		Where(squirrel.Eq{"rel_namespace": namespaceID, "rel_record": recordID}).
+		OrderBy("id DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateEmail(e *Email) (*Email, error) {
+	e.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	return e, errors.WithStack(r.db().Insert(r.tableEmail(), e))
+}
+
+// UpdateDelivery stores the outcome of sending attempt
+func (r repository) UpdateDelivery(e *Email) error {
+	rh.SetCurrentTimeRounded(&e.UpdatedAt)
+
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableEmail(),
+		rh.Set{
+			"links":      e.Links,
+			"status":     e.Status,
+			"error":      e.Error,
+			"attempts":   e.Attempts,
+			"sent_at":    e.SentAt,
+			"updated_at": e.UpdatedAt,
+		},
+		squirrel.Eq{"id": e.ID},
+	)
+}
+
+// Opened counts an open, returns true on the first one
+func (r repository) Opened(emailID uint64, at time.Time) (bool, error) {
+	return r.count(emailID, "opens", "opened_at", at)
+}
+
+// Clicked counts a click, returns true on the first one
+func (r repository) Clicked(emailID uint64, at time.Time) (bool, error) {
+	return r.count(emailID, "clicks", "clicked_at", at)
+}
+
+func (r repository) count(emailID uint64, counter, first string, at time.Time) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.tableEmail()+" SET "+counter+" = "+counter+" + 1, "+first+" = ? WHERE id = ? AND "+first+" IS NULL",
+		at,
+		emailID,
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return false, errors.WithStack(err)
+	} else if n > 0 {
+		return true, nil
+	}
+
+	_, err = r.db().Exec("UPDATE "+r.tableEmail()+" SET "+counter+" = "+counter+" + 1 WHERE id = ?", emailID)
+	return false, errors.WithStack(err)
+}
+
+// Bounced marks a sent email as bounced, returns false when it already was
+func (r repository) Bounced(emailID uint64, at time.Time, reason string) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.tableEmail()+" SET status = ?, error = ?, bounced_at = ? WHERE id = ? AND status = ?",
+		StatusBounced,
+		reason,
+		at,
+		emailID,
+		StatusSent,
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, errors.WithStack(err)
+}
+
+func (r repository) queryEvent() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_email",
+			"rel_record",
+			"kind",
+			"detail",
+			"created_at",
+		).
+		From(r.tableEvent())
+}
+
+// FindEvents returns the latest events of record's emails, latest first
+func (r repository) FindEvents(recordID uint64) (set EventSet, err error) {
+	q := r.queryEvent().
+		Where(squirrel.Eq{"rel_record": recordID}).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(maxEvents)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateEvent(e *Event) error {
+	e.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	return errors.WithStack(r.db().Insert(r.tableEvent(), e))
+}
@@ -0,0 +1,165 @@
+package outmail
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+var (
+	// Transparent 1x1 GIF
+	pixel = []byte{
+		0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+	}
+)
+
+// MountRoutes mounts outbound mail endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param.
+// Tracking and bounce webhook endpoints are called by recipients'
+// mail clients and mail providers, without signing in.
+func MountRoutes(r chi.Router) {
+	r.Get("/t/{token}/open.gif", func(w http.ResponseWriter, r *http.Request) {
+		// Image is served even when the email is not known
+		_ = DefaultOutmail.With(r.Context()).Opened(rest.ParamUint64(r, "namespaceID"), chi.URLParam(r, "token"))
+
+		w.Header().Set("Content-Type", "image/gif")
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		_, _ = w.Write(pixel)
+	})
+
+	r.Get("/t/{token}/click/{link}", rest.Handler("OutboundMail.Click", func(r *http.Request) (interface{}, error) {
+		link, _ := strconv.ParseUint(chi.URLParam(r, "link"), 10, 32)
+
+		u, err := DefaultOutmail.With(r.Context()).Clicked(rest.ParamUint64(r, "namespaceID"), chi.URLParam(r, "token"), uint(link))
+		if err != nil {
+			return nil, err
+		}
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, u, http.StatusFound)
+		}, nil
+	}))
+
+	r.Post("/bounces/{token}", rest.Handler("OutboundMail.Bounce", func(r *http.Request) (interface{}, error) {
+		b := &Bounce{}
+		if err := rest.Decode(r, b); err != nil {
+			return nil, err
+		}
+
+		return resputil.OK(), DefaultOutmail.With(r.Context()).Bounced(rest.ParamUint64(r, "namespaceID"), chi.URLParam(r, "token"), b)
+	}))
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.MiddlewareValidOnly)
+
+		r.Route("/senders", func(r chi.Router) {
+			r.Get("/", rest.Handler("MailSender.List", func(r *http.Request) (interface{}, error) {
+				return DefaultOutmail.With(r.Context()).FindSenders(rest.ParamUint64(r, "namespaceID"))
+			}))
+
+			r.Post("/", rest.Handler("MailSender.Create", func(r *http.Request) (interface{}, error) {
+				s := &Sender{}
+				if err := rest.Decode(r, s); err != nil {
+					return nil, err
+				}
+
+				s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+				return DefaultOutmail.With(r.Context()).CreateSender(s)
+			}))
+
+			r.Put("/{senderID}", rest.Handler("MailSender.Update", func(r *http.Request) (interface{}, error) {
+				s := &Sender{}
+				if err := rest.Decode(r, s); err != nil {
+					return nil, err
+				}
+
+				s.ID = rest.ParamUint64(r, "senderID")
+				s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+				return DefaultOutmail.With(r.Context()).UpdateSender(s)
+			}))
+
+			r.Delete("/{senderID}", rest.Handler("MailSender.Delete", func(r *http.Request) (interface{}, error) {
+				return resputil.OK(), DefaultOutmail.With(r.Context()).DeleteSender(
+					rest.ParamUint64(r, "namespaceID"),
+					rest.ParamUint64(r, "senderID"),
+				)
+			}))
+
+			// Issues a new bounce webhook token, the old one stops working
+			r.Post("/{senderID}/bounce-token", rest.Handler("MailSender.RegenerateBounceToken", func(r *http.Request) (interface{}, error) {
+				return DefaultOutmail.With(r.Context()).RegenerateBounceToken(
+					rest.ParamUint64(r, "namespaceID"),
+					rest.ParamUint64(r, "senderID"),
+				)
+			}))
+		})
+
+		r.Route("/records/{recordID}", func(r chi.Router) {
+			r.Get("/emails", rest.Handler("OutboundMail.List", func(r *http.Request) (interface{}, error) {
+				return DefaultOutmail.With(r.Context()).FindEmails(
+					rest.ParamUint64(r, "namespaceID"),
+					rest.ParamUint64(r, "recordID"),
+				)
+			}))
+
+			r.Post("/emails", rest.Handler("OutboundMail.Send", func(r *http.Request) (interface{}, error) {
+				e := &Email{}
+				if err := rest.Decode(r, e); err != nil {
+					return nil, err
+				}
+
+				e.NamespaceID = rest.ParamUint64(r, "namespaceID")
+				e.RecordID = rest.ParamUint64(r, "recordID")
+				e.TrackingURL = baseURL(r)
+				return DefaultOutmail.With(r.Context()).Send(e)
+			}))
+
+			r.Get("/timeline", rest.Handler("OutboundMail.Timeline", func(r *http.Request) (interface{}, error) {
+				return DefaultOutmail.With(r.Context()).Timeline(
+					rest.ParamUint64(r, "namespaceID"),
+					rest.ParamUint64(r, "recordID"),
+				)
+			}))
+		})
+
+		r.Get("/emails/{emailID}", rest.Handler("OutboundMail.Read", func(r *http.Request) (interface{}, error) {
+			return DefaultOutmail.With(r.Context()).FindEmailByID(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "emailID"),
+			)
+		}))
+	})
+}
+
+// baseURL returns URL where outbound mail endpoints are served
+//
+// OUTMAIL_BASE_URL is used when the server is behind a proxy that
+// changes the path, otherwise it is taken from the request.
+func baseURL(r *http.Request) string {
+	if u := options.EnvString("", "OUTMAIL_BASE_URL", ""); u != "" {
+		return u
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	// Path of the endpoints, without /records/{recordID}/emails
+	p := r.URL.Path
+	if i := strings.LastIndex(p, "/records/"); i >= 0 {
+		p = p[:i]
+	}
+
+	return scheme + "://" + r.Host + p
+}
@@ -0,0 +1,212 @@
+package outmail
+
+import (
+	"encoding/json"
+	"html"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	gomail "gopkg.in/mail.v2"
+
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+)
+
+var (
+	// Links in href attributes of anchors, wrapped when clicks are tracked
+	anchorHref = regexp.MustCompile(`(?i)(<a\s[^>]*?href\s*=\s*)("https?://[^"]+"|'https?://[^']+')`)
+
+	closingBody = regexp.MustCompile(`(?i)</body>`)
+)
+
+// deliver sends queued email
+//
+// Emails that were already sent or removed are skipped. Failures are
+// stored on the email and retried by the outbox until permanent, then
+// the email is marked as failed.
+func (svc outmailService) deliver(payload json.RawMessage) error {
+	d := &delivery{}
+	if err := json.Unmarshal(payload, d); err != nil {
+		return errors.WithStack(err)
+	}
+
+	e, err := svc.repository.FindEmailByID(d.NamespaceID, d.EmailID)
+	if isNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if e.Status != StatusQueued {
+		return nil
+	}
+
+	s, err := svc.repository.FindSenderByID(e.NamespaceID, e.SenderID)
+	if err == nil {
+		err = send(e, s)
+	} else if !isNotFound(err) {
+		return err
+	}
+
+	e.Attempts++
+
+	switch {
+	case err == nil:
+		sentAt := now()
+		e.Status, e.Error, e.SentAt = StatusSent, "", &sentAt
+		return svc.delivered(e, &Event{EmailID: e.ID, RecordID: e.RecordID, Kind: EventSent})
+
+	case isNotFound(err) || permanent(err) || e.Attempts >= maxAttempts:
+		e.Status, e.Error = StatusFailed, err.Error()
+		svc.log(zap.Uint64("emailID", e.ID), zap.Error(err)).Warn("could not send email")
+		return svc.delivered(e, &Event{EmailID: e.ID, RecordID: e.RecordID, Kind: EventFailed, Detail: e.Error})
+
+	default:
+		e.Error = err.Error()
+		if serr := svc.delivered(e, nil); serr != nil {
+			return serr
+		}
+
+		return err
+	}
+}
+
+// delivered stores outcome of the attempt with the event, if any
+func (svc outmailService) delivered(e *Email, ev *Event) error {
+	db := svc.repository.db()
+
+	return db.Transaction(func() error {
+		r := Repository(svc.ctx, db)
+
+		if err := r.UpdateDelivery(e); err != nil {
+			return err
+		}
+
+		if ev == nil {
+			return nil
+		}
+
+		return r.CreateEvent(ev)
+	})
+}
+
+// send relays the email through sender's or server's SMTP server
+func send(e *Email, s *Sender) error {
+	m := message(e, s)
+
+	if s.SMTPHost == "" {
+		return mail.Send(m)
+	}
+
+	host, port, err := splitHost(s.SMTPHost, defaultSMTPPort)
+	if err != nil {
+		return ErrInvalidSender.withStack().WithMessage("invalid SMTP host " + s.SMTPHost)
+	}
+
+	p, _ := strconv.Atoi(port)
+	return mail.Send(m, gomail.NewDialer(host, p, s.SMTPUsername, s.SMTPPassword))
+}
+
+// message composes the email, links are wrapped and open pixel added
+// when sender tracks them
+func message(e *Email, s *Sender) *gomail.Message {
+	m := gomail.NewMessage()
+
+	m.SetAddressHeader("From", s.FromAddress, s.FromName)
+	if s.ReplyTo != "" {
+		m.SetHeader("Reply-To", s.ReplyTo)
+	}
+
+	m.SetHeader("To", e.To...)
+	if len(e.Cc) > 0 {
+		m.SetHeader("Cc", e.Cc...)
+	}
+
+	if len(e.Bcc) > 0 {
+		m.SetHeader("Bcc", e.Bcc...)
+	}
+
+	m.SetHeader("Subject", e.Subject)
+	m.SetHeader("Message-ID", e.messageID(s))
+
+	body := e.HTML
+	if body != "" && e.TrackingURL != "" {
+		if s.TrackClicks {
+			body, e.Links = e.wrapLinks(body)
+		}
+
+		if s.TrackOpens {
+			body = e.withPixel(body)
+		}
+	}
+
+	switch {
+	case e.Text != "" && body != "":
+		m.SetBody("text/plain", e.Text)
+		m.AddAlternative("text/html", body)
+	case body != "":
+		m.SetBody("text/html", body)
+	default:
+		m.SetBody("text/plain", e.Text)
+	}
+
+	return m
+}
+
+// wrapLinks replaces links in the body with tracked ones
+//
+// Same links share the index, so every URL is stored once.
+func (e Email) wrapLinks(body string) (string, Links) {
+	var (
+		links = Links{}
+		index = map[string]int{}
+	)
+
+	body = anchorHref.ReplaceAllStringFunc(body, func(m string) string {
+		var (
+			parts = anchorHref.FindStringSubmatch(m)
+			link  = html.UnescapeString(parts[2][1 : len(parts[2])-1])
+		)
+
+		i, ok := index[link]
+		if !ok {
+			i = len(links)
+			index[link] = i
+			links = append(links, link)
+		}
+
+		return parts[1] + `"` + e.trackingURL("click/"+strconv.Itoa(i)) + `"`
+	})
+
+	return body, links
+}
+
+// withPixel adds image that reports opens to the end of the body
+func (e Email) withPixel(body string) string {
+	pixel := `<img src="` + e.trackingURL("open.gif") + `" width="1" height="1" alt="" style="border:0" />`
+
+	if loc := closingBody.FindAllStringIndex(body, -1); len(loc) > 0 {
+		i := loc[len(loc)-1][0]
+		return body[:i] + pixel + body[i:]
+	}
+
+	return body + pixel
+}
+
+func (e Email) trackingURL(path string) string {
+	return strings.TrimRight(e.TrackingURL, "/") + "/t/" + e.Token + "/" + path
+}
+
+// permanent reports whether SMTP server refused the email for good
+func permanent(err error) bool {
+	err = errors.Cause(err)
+	if se, ok := err.(*gomail.SendError); ok {
+		err = se.Cause
+	}
+
+	te, ok := err.(*textproto.Error)
+	return ok && te.Code >= 500
+}
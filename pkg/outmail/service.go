@@ -0,0 +1,566 @@
+package outmail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/outbox"
+)
+
+type (
+	outmailService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+		record    service.RecordService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+		CanUpdateRecord(context.Context, *types.Module) bool
+	}
+
+	OutmailService interface {
+		With(ctx context.Context) OutmailService
+
+		FindSenders(namespaceID uint64) (SenderSet, error)
+		CreateSender(*Sender) (*Sender, error)
+		UpdateSender(*Sender) (*Sender, error)
+		DeleteSender(namespaceID, senderID uint64) error
+		RegenerateBounceToken(namespaceID, senderID uint64) (*Sender, error)
+
+		Send(*Email) (*Email, error)
+		FindEmails(namespaceID, recordID uint64) (EmailSet, error)
+		FindEmailByID(namespaceID, emailID uint64) (*Email, error)
+		Timeline(namespaceID, recordID uint64) (EventSet, error)
+
+		Opened(namespaceID uint64, token string) error
+		Clicked(namespaceID uint64, token string, link uint) (string, error)
+		Bounced(namespaceID uint64, bounceToken string, b *Bounce) error
+	}
+
+	// delivery is the outbox payload of a queued email
+	delivery struct {
+		NamespaceID uint64 `json:"namespaceID,string"`
+		EmailID     uint64 `json:"emailID,string"`
+	}
+)
+
+var (
+	DefaultOutmail OutmailService
+
+	// now is used for timeline events and can be overridden
+	now = time.Now
+)
+
+// Init initializes outbound mail service and starts reading bounce
+// mailboxes in the background
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &outmailService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+		record:    service.DefaultRecord,
+	}
+
+	DefaultOutmail = svc.With(ctx)
+
+	outbox.Handle(topicSend, func(ctx context.Context, payload json.RawMessage) error {
+		return svc.with(ctx).deliver(payload)
+	})
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc outmailService) With(ctx context.Context) OutmailService {
+	return svc.with(ctx)
+}
+
+func (svc outmailService) with(ctx context.Context) *outmailService {
+	return &outmailService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+		record:    svc.record.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc outmailService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc outmailService) FindSenders(namespaceID uint64) (SenderSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.FindSenders(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set {
+		set[i] = set[i].withoutSecrets()
+	}
+
+	return set, nil
+}
+
+func (svc outmailService) CreateSender(in *Sender) (*Sender, error) {
+	if err := svc.canManage(in.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.CreateSender(&Sender{
+		NamespaceID:    in.NamespaceID,
+		Name:           in.Name,
+		FromName:       in.FromName,
+		FromAddress:    in.FromAddress,
+		ReplyTo:        in.ReplyTo,
+		SMTPHost:       in.SMTPHost,
+		SMTPUsername:   in.SMTPUsername,
+		SMTPPassword:   in.SMTPPassword,
+		TrackOpens:     in.TrackOpens,
+		TrackClicks:    in.TrackClicks,
+		BounceToken:    generateToken(),
+		BounceMailbox:  in.BounceMailbox,
+		BounceUsername: in.BounceUsername,
+		BouncePassword: in.BouncePassword,
+		CreatedBy:      auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withoutSecrets(), nil
+}
+
+// UpdateSender modifies sender
+//
+// Secrets are changed only when new ones are given, bounce token
+// only with RegenerateBounceToken
+func (svc outmailService) UpdateSender(upd *Sender) (*Sender, error) {
+	if err := svc.canManage(upd.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.FindSenderByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if upd.SMTPPassword == "" {
+		upd.SMTPPassword = s.SMTPPassword
+	}
+
+	if upd.BouncePassword == "" {
+		upd.BouncePassword = s.BouncePassword
+	}
+
+	if err = upd.validate(); err != nil {
+		return nil, err
+	}
+
+	s.Name = upd.Name
+	s.FromName = upd.FromName
+	s.FromAddress = upd.FromAddress
+	s.ReplyTo = upd.ReplyTo
+	s.SMTPHost = upd.SMTPHost
+	s.SMTPUsername = upd.SMTPUsername
+	s.SMTPPassword = upd.SMTPPassword
+	s.TrackOpens = upd.TrackOpens
+	s.TrackClicks = upd.TrackClicks
+	s.BounceMailbox = upd.BounceMailbox
+	s.BounceUsername = upd.BounceUsername
+	s.BouncePassword = upd.BouncePassword
+
+	if s, err = svc.repository.UpdateSender(s); err != nil {
+		return nil, err
+	}
+
+	return s.withoutSecrets(), nil
+}
+
+// DeleteSender removes sender, emails that are still queued fail
+func (svc outmailService) DeleteSender(namespaceID, senderID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindSenderByID(namespaceID, senderID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteSenderByID(namespaceID, senderID)
+}
+
+// RegenerateBounceToken issues a new bounce webhook token, the old one stops working
+func (svc outmailService) RegenerateBounceToken(namespaceID, senderID uint64) (*Sender, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.FindSenderByID(namespaceID, senderID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.BounceToken = generateToken()
+	if s, err = svc.repository.UpdateSender(s); err != nil {
+		return nil, err
+	}
+
+	return s.withoutSecrets(), nil
+}
+
+// Send queues email about the record
+//
+// Emails can be sent by users that can update the record; they are sent
+// in the background and retried when the SMTP server is not available.
+func (svc outmailService) Send(in *Email) (*Email, error) {
+	rec, err := svc.record.FindByID(in.NamespaceID, in.RecordID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := svc.module.FindByID(in.NamespaceID, rec.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanUpdateRecord(svc.ctx, m) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if _, err = svc.repository.FindSenderByID(in.NamespaceID, in.SenderID); err != nil {
+		return nil, err
+	}
+
+	if err = svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	var (
+		e = &Email{
+			NamespaceID: in.NamespaceID,
+			ModuleID:    rec.ModuleID,
+			RecordID:    rec.ID,
+			SenderID:    in.SenderID,
+			To:          in.To,
+			Cc:          in.Cc,
+			Bcc:         in.Bcc,
+			Subject:     in.Subject,
+			HTML:        in.HTML,
+			Text:        in.Text,
+			Token:       generateToken(),
+			TrackingURL: in.TrackingURL,
+			Status:      StatusQueued,
+			CreatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+		}
+
+		db = svc.repository.db()
+	)
+
+	err = db.Transaction(func() error {
+		r := Repository(svc.ctx, db)
+
+		if _, err := r.CreateEmail(e); err != nil {
+			return err
+		}
+
+		if err := r.CreateEvent(&Event{EmailID: e.ID, RecordID: e.RecordID, Kind: EventQueued}); err != nil {
+			return err
+		}
+
+		return outbox.Write(db, topicSend, &delivery{NamespaceID: e.NamespaceID, EmailID: e.ID})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("emailID", e.ID), zap.Uint64("recordID", e.RecordID)).Debug("email queued")
+
+	return e, nil
+}
+
+func (svc outmailService) validate(e *Email) error {
+	if len(e.To) == 0 {
+		return ErrNoRecipients.withStack()
+	}
+
+	if len(e.recipients()) > maxRecipients {
+		return ErrTooManyRecipients.withStack()
+	}
+
+	if err := e.recipients().validate(); err != nil {
+		return err
+	}
+
+	e.Subject = strings.TrimSpace(e.Subject)
+	if e.Subject == "" || len(e.Subject) > 512 {
+		return ErrEmptyMessage.withStack().WithMessage("subject is required and can be at most 512 characters long")
+	}
+
+	if strings.TrimSpace(e.HTML) == "" && strings.TrimSpace(e.Text) == "" {
+		return ErrEmptyMessage.withStack()
+	}
+
+	return nil
+}
+
+// FindEmails returns emails of the record, bodies are omitted
+func (svc outmailService) FindEmails(namespaceID, recordID uint64) (EmailSet, error) {
+	if _, err := svc.record.FindByID(namespaceID, recordID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.FindEmails(namespaceID, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range set {
+		e.HTML, e.Text = "", ""
+	}
+
+	return set, nil
+}
+
+func (svc outmailService) FindEmailByID(namespaceID, emailID uint64) (*Email, error) {
+	e, err := svc.repository.FindEmailByID(namespaceID, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Emails are as visible as their records
+	if _, err = svc.record.FindByID(namespaceID, e.RecordID); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Timeline returns events of record's emails, latest first
+func (svc outmailService) Timeline(namespaceID, recordID uint64) (EventSet, error) {
+	if _, err := svc.record.FindByID(namespaceID, recordID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindEvents(recordID)
+}
+
+// Opened counts an open of the email, the first one is logged
+func (svc outmailService) Opened(namespaceID uint64, token string) error {
+	e, err := svc.findTracked(namespaceID, token)
+	if err != nil {
+		return err
+	}
+
+	first, err := svc.repository.Opened(e.ID, now())
+	if err != nil || !first {
+		return err
+	}
+
+	return svc.repository.CreateEvent(&Event{EmailID: e.ID, RecordID: e.RecordID, Kind: EventOpened})
+}
+
+// Clicked logs a click of email's link and returns URL it leads to
+func (svc outmailService) Clicked(namespaceID uint64, token string, link uint) (string, error) {
+	e, err := svc.findTracked(namespaceID, token)
+	if err != nil {
+		return "", err
+	}
+
+	if link >= uint(len(e.Links)) {
+		return "", ErrEmailNotFound.withStack()
+	}
+
+	if _, err = svc.repository.Clicked(e.ID, now()); err != nil {
+		return "", err
+	}
+
+	err = svc.repository.CreateEvent(&Event{EmailID: e.ID, RecordID: e.RecordID, Kind: EventClicked, Detail: e.Links[link]})
+	if err != nil {
+		return "", err
+	}
+
+	return e.Links[link], nil
+}
+
+func (svc outmailService) findTracked(namespaceID uint64, token string) (*Email, error) {
+	e, err := svc.repository.FindEmailByToken(token)
+	if err != nil {
+		return nil, err
+	} else if e.NamespaceID != namespaceID {
+		return nil, ErrEmailNotFound.withStack()
+	}
+
+	return e, nil
+}
+
+// Bounced marks the email as bounced, as reported to sender's webhook
+func (svc outmailService) Bounced(namespaceID uint64, bounceToken string, b *Bounce) error {
+	s, err := svc.repository.FindSenderByBounceToken(bounceToken)
+	if err != nil {
+		return err
+	} else if s.NamespaceID != namespaceID {
+		return ErrSenderNotFound.withStack()
+	}
+
+	// Message ID is <token@domain>
+	token := strings.Trim(b.MessageID, "<> ")
+	if i := strings.Index(token, "@"); i > 0 {
+		token = token[:i]
+	}
+
+	if token == "" {
+		return ErrInvalidBounce.withStack()
+	}
+
+	e, err := svc.repository.FindEmailByToken(token)
+	if err != nil {
+		return err
+	} else if e.SenderID != s.ID {
+		return ErrEmailNotFound.withStack()
+	}
+
+	return svc.bounced(e, bounceReason(b.Recipient, b.Reason))
+}
+
+// bounced marks the email as bounced and logs it, once
+func (svc outmailService) bounced(e *Email, reason string) error {
+	db := svc.repository.db()
+
+	return db.Transaction(func() error {
+		r := Repository(svc.ctx, db)
+
+		ok, err := r.Bounced(e.ID, now(), reason)
+		if err != nil || !ok {
+			return err
+		}
+
+		svc.log(zap.Uint64("emailID", e.ID), zap.String("reason", reason)).Info("email bounced")
+
+		return r.CreateEvent(&Event{EmailID: e.ID, RecordID: e.RecordID, Kind: EventBounced, Detail: reason})
+	})
+}
+
+func (svc outmailService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// watch periodically reads bounces from senders' mailboxes
+//
+// Mailboxes are claimed before they are read, so that only one instance
+// reads each of them.
+func (svc outmailService) watch(ctx context.Context) {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			ctx := auth.SetSuperUserContext(ctx)
+			repo := Repository(ctx, nil)
+
+			set, err := repo.FindPolledSenders()
+			if err != nil {
+				svc.logger.Error("could not load senders with bounce mailboxes", zap.Error(err))
+				continue
+			}
+
+			for _, s := range set {
+				if ok, err := repo.ClaimPoll(s.ID, now()); err != nil {
+					svc.logger.Error("could not claim bounce mailbox", zap.Uint64("senderID", s.ID), zap.Error(err))
+					continue
+				} else if !ok {
+					continue
+				}
+
+				var lastError string
+				if err = svc.with(ctx).poll(s); err != nil {
+					svc.logger.Warn("could not read bounce mailbox", zap.Uint64("senderID", s.ID), zap.Error(err))
+					lastError = err.Error()
+				}
+
+				if err = repo.UpdatePollError(s.ID, lastError); err != nil {
+					svc.logger.Error("could not store bounce mailbox state", zap.Uint64("senderID", s.ID), zap.Error(err))
+				}
+			}
+		}
+	}
+}
+
+func bounceReason(recipient, reason string) string {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = "bounced"
+	}
+
+	if recipient = strings.TrimSpace(recipient); recipient != "" {
+		return recipient + ": " + reason
+	}
+
+	return reason
+}
+
+func generateToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// isNotFound reports whether email or its sender no longer exist
+func isNotFound(err error) bool {
+	return fault.Is(err, ErrEmailNotFound) || fault.Is(err, ErrSenderNotFound)
+}
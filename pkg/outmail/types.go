@@ -0,0 +1,276 @@
+package outmail
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+)
+
+type (
+	// Sender is an identity that namespace's emails are sent from
+	//
+	// Emails are relayed through sender's own SMTP server when one is set,
+	// through the server's one otherwise. Opens and clicks are tracked only
+	// when enabled. Bounces are reported to the webhook with the bounce token
+	// or read from the POP3 mailbox. Secrets are never sent back and are
+	// left as they are when not given.
+	Sender struct {
+		ID          uint64 `json:"senderID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Name        string `json:"name" db:"name"`
+		FromName    string `json:"fromName" db:"from_name"`
+		FromAddress string `json:"fromAddress" db:"from_address"`
+		ReplyTo     string `json:"replyTo,omitempty" db:"reply_to"`
+
+		// host:port, port 587 when omitted
+		SMTPHost     string `json:"smtpHost,omitempty" db:"smtp_host"`
+		SMTPUsername string `json:"smtpUsername,omitempty" db:"smtp_username"`
+		SMTPPassword string `json:"smtpPassword,omitempty" db:"smtp_password"`
+
+		TrackOpens  bool `json:"trackOpens" db:"track_opens"`
+		TrackClicks bool `json:"trackClicks" db:"track_clicks"`
+
+		BounceToken string `json:"bounceToken" db:"bounce_token"`
+
+		// POP3 over TLS, host:port, port 995 when omitted
+		BounceMailbox  string     `json:"bounceMailbox,omitempty" db:"bounce_mailbox"`
+		BounceUsername string     `json:"bounceUsername,omitempty" db:"bounce_username"`
+		BouncePassword string     `json:"bouncePassword,omitempty" db:"bounce_password"`
+		PolledAt       *time.Time `json:"polledAt,omitempty" db:"polled_at"`
+		LastError      string     `json:"lastError,omitempty" db:"last_error"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	SenderSet []*Sender
+
+	// Email is sent about a record, for example a quote to the customer
+	//
+	// Emails are queued and sent in the background; sending, opens, clicks
+	// and bounces are logged as events on record's timeline.
+	Email struct {
+		ID          uint64    `json:"emailID,string" db:"id"`
+		NamespaceID uint64    `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64    `json:"moduleID,string" db:"rel_module"`
+		RecordID    uint64    `json:"recordID,string" db:"rel_record"`
+		SenderID    uint64    `json:"senderID,string" db:"rel_sender"`
+		To          Addresses `json:"to" db:"recipients_to"`
+		Cc          Addresses `json:"cc,omitempty" db:"recipients_cc"`
+		Bcc         Addresses `json:"bcc,omitempty" db:"recipients_bcc"`
+		Subject     string    `json:"subject" db:"subject"`
+		HTML        string    `json:"html,omitempty" db:"body_html"`
+		Text        string    `json:"text,omitempty" db:"body_text"`
+
+		// Token identifies the email in tracking URLs and bounces
+		Token string `json:"-" db:"token"`
+
+		// Wrapped links, clicks are redirected by their index
+		Links Links `json:"-" db:"links"`
+
+		// Where tracking endpoints are served, taken from the request
+		TrackingURL string `json:"-" db:"tracking_url"`
+
+		Status    Status     `json:"status" db:"status"`
+		Error     string     `json:"error,omitempty" db:"error"`
+		Attempts  uint       `json:"attempts" db:"attempts"`
+		Opens     uint       `json:"opens" db:"opens"`
+		Clicks    uint       `json:"clicks" db:"clicks"`
+		SentAt    *time.Time `json:"sentAt,omitempty" db:"sent_at"`
+		OpenedAt  *time.Time `json:"openedAt,omitempty" db:"opened_at"`
+		ClickedAt *time.Time `json:"clickedAt,omitempty" db:"clicked_at"`
+		BouncedAt *time.Time `json:"bouncedAt,omitempty" db:"bounced_at"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	EmailSet []*Email
+
+	// Event is an entry on record's timeline
+	Event struct {
+		ID        uint64    `json:"eventID,string" db:"id"`
+		EmailID   uint64    `json:"emailID,string" db:"rel_email"`
+		RecordID  uint64    `json:"recordID,string" db:"rel_record"`
+		Kind      EventKind `json:"kind" db:"kind"`
+		Detail    string    `json:"detail,omitempty" db:"detail"`
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	EventSet []*Event
+
+	// Bounce is reported to the webhook by the mail provider
+	//
+	// Message ID is the one of the bounced email, with or without brackets.
+	Bounce struct {
+		MessageID string `json:"messageID"`
+		Recipient string `json:"recipient"`
+		Reason    string `json:"reason"`
+	}
+
+	Addresses []string
+	Links     []string
+
+	Status    string
+	EventKind string
+)
+
+const (
+	StatusQueued  Status = "queued"
+	StatusSent    Status = "sent"
+	StatusFailed  Status = "failed"
+	StatusBounced Status = "bounced"
+
+	EventQueued  EventKind = "queued"
+	EventSent    EventKind = "sent"
+	EventFailed  EventKind = "failed"
+	EventOpened  EventKind = "opened"
+	EventClicked EventKind = "clicked"
+	EventBounced EventKind = "bounced"
+
+	topicSend = "outmail.send"
+
+	// Failed sends are retried until then, permanent SMTP errors are not
+	maxAttempts = 5
+
+	maxRecipients = 50
+	maxEvents     = 500
+
+	defaultSMTPPort = "587"
+	defaultPOP3Port = "995"
+
+	// How often bounce mailboxes are read
+	pollInterval = 5 * time.Minute
+)
+
+// validate checks sender's addresses and servers
+func (s *Sender) validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	if s.Name == "" || len(s.Name) > 64 {
+		return ErrInvalidSender.withStack().WithMessage("name is required and can be at most 64 characters long")
+	}
+
+	if !mail.IsValidAddress(s.FromAddress) {
+		return ErrInvalidAddress.withStack().WithMessage("invalid sender address " + s.FromAddress)
+	}
+
+	if s.ReplyTo != "" && !mail.IsValidAddress(s.ReplyTo) {
+		return ErrInvalidAddress.withStack().WithMessage("invalid reply-to address " + s.ReplyTo)
+	}
+
+	if s.SMTPHost != "" {
+		if _, _, err := splitHost(s.SMTPHost, defaultSMTPPort); err != nil {
+			return ErrInvalidSender.withStack().WithMessage("invalid SMTP host " + s.SMTPHost)
+		}
+	}
+
+	if s.BounceMailbox != "" {
+		if _, _, err := splitHost(s.BounceMailbox, defaultPOP3Port); err != nil {
+			return ErrInvalidMailbox.withStack().WithMessage("invalid bounce mailbox " + s.BounceMailbox)
+		}
+
+		if s.BounceUsername == "" {
+			return ErrInvalidMailbox.withStack().WithMessage("bounce mailbox username is required")
+		}
+	}
+
+	return nil
+}
+
+func (s Sender) withoutSecrets() *Sender {
+	s.SMTPPassword = ""
+	s.BouncePassword = ""
+	return &s
+}
+
+// domain returns domain of sender's address, used in message IDs
+func (s Sender) domain() string {
+	return s.FromAddress[strings.LastIndex(s.FromAddress, "@")+1:]
+}
+
+// recipients returns all addresses the email is sent to
+func (e Email) recipients() Addresses {
+	return append(append(append(Addresses{}, e.To...), e.Cc...), e.Bcc...)
+}
+
+// messageID returns value of email's Message-ID header
+func (e Email) messageID(s *Sender) string {
+	return "<" + e.Token + "@" + s.domain() + ">"
+}
+
+func (aa Addresses) validate() error {
+	for _, a := range aa {
+		if !mail.IsValidAddress(a) {
+			return ErrInvalidAddress.withStack().WithMessage("invalid address " + a)
+		}
+	}
+
+	return nil
+}
+
+func (aa Addresses) Value() (driver.Value, error) {
+	if aa == nil {
+		aa = Addresses{}
+	}
+
+	return json.Marshal(aa)
+}
+
+func (aa *Addresses) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*aa = Addresses{}
+	case []byte:
+		if err := json.Unmarshal(b, aa); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Addresses", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (ll Links) Value() (driver.Value, error) {
+	if ll == nil {
+		ll = Links{}
+	}
+
+	return json.Marshal(ll)
+}
+
+func (ll *Links) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ll = Links{}
+	case []byte:
+		if err := json.Unmarshal(b, ll); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Links", string(b))
+		}
+	}
+
+	return nil
+}
+
+// splitHost splits host:port, with the default port when it is omitted
+func splitHost(hostport, port string) (string, string, error) {
+	if !strings.Contains(hostport, ":") {
+		hostport += ":" + port
+	}
+
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", "", err
+	} else if host == "" || port == "" {
+		return "", "", errors.New("host and port are required")
+	}
+
+	return host, port, nil
+}
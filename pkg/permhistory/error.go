@@ -0,0 +1,33 @@
+package permhistory
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	permhistoryError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions     = permhistoryError{"NoPermissions", fault.Forbidden}
+	ErrTimestampRequired = permhistoryError{"TimestampRequired", fault.Invalid}
+)
+
+func (e permhistoryError) Error() string {
+	return e.String()
+}
+
+func (e permhistoryError) String() string {
+	return "crust.permhistory." + e.name
+}
+
+func (e permhistoryError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e permhistoryError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,70 @@
+package permhistory
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// Corteza's endpoints that change rules of the role, with app's prefix in monolith
+	rulesPath = regexp.MustCompile(`^(/(system|compose|messaging))?/permissions/(\d+)/rules/?$`)
+)
+
+// MiddlewareCompose records changes of compose rules made through the API
+func MiddlewareCompose(next http.Handler) http.Handler {
+	return middleware("compose", next)
+}
+
+// MiddlewareSystem records changes of system rules made through the API
+func MiddlewareSystem(next http.Handler) http.Handler {
+	return middleware("system", next)
+}
+
+// MiddlewareMessaging records changes of messaging rules made through the API
+func MiddlewareMessaging(next http.Handler) http.Handler {
+	return middleware("messaging", next)
+}
+
+// middleware compares role's rules before and after the request
+//
+// Changes are taken from the database, corteza flushes rules before it responds.
+func middleware(app string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if defaultPermHistory == nil || (r.Method != http.MethodPatch && r.Method != http.MethodDelete) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m := rulesPath.FindStringSubmatch(r.URL.Path)
+		if m == nil || (m[2] != "" && m[2] != app) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var (
+			roleID, _ = strconv.ParseUint(m[3], 10, 64)
+			svc       = defaultPermHistory.with(r.Context())
+			repo      = svc.repository(app)
+		)
+
+		before, err := repo.Rules(roleID)
+		if err != nil {
+			svc.log(zap.String("app", app), zap.Error(err)).Error("could not load permission rules")
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+
+		after, err := repo.Rules(roleID)
+		if err != nil {
+			svc.log(zap.String("app", app), zap.Error(err)).Error("could not load permission rules")
+			return
+		}
+
+		svc.record(repo, diff(before, after))
+	})
+}
@@ -0,0 +1,41 @@
+package permhistory
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	ComposeMigrations   = migrationsOf("compose")
+	SystemMigrations    = migrationsOf("system")
+	MessagingMigrations = migrationsOf("messaging")
+)
+
+// migrationsOf returns migrations of app's history table
+//
+// Names differ by app, apps can share the database.
+func migrationsOf(app string) migrations.Set {
+	return migrations.Set{
+		{
+			Name: "20200221000000.permhistory-" + app,
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_` + app + `_permission_change (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_role         BIGINT UNSIGNED NOT NULL,
+  resource         VARCHAR(128)    NOT NULL,
+  operation        VARCHAR(50)     NOT NULL,
+  old_access       TINYINT         NOT NULL,
+  new_access       TINYINT         NOT NULL,
+  rel_actor        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  rollback_to      DATETIME(3)         NULL,
+
+  created_at       DATETIME(3)     NOT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_role, created_at),
+  INDEX (resource, created_at),
+  INDEX (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+}
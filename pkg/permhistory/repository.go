@@ -0,0 +1,165 @@
+package permhistory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+		app string
+	}
+)
+
+// Tables of app's permission rules
+var rulesTables = map[string]string{
+	"system":    "sys_permission_rules",
+	"compose":   "compose_permission_rules",
+	"messaging": "messaging_permission_rules",
+}
+
+func Repository(ctx context.Context, db *factory.DB, app string) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+		app: app,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet(r.app).With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_" + r.app + "_permission_change"
+}
+
+func (r repository) query(f ChangeFilter) squirrel.SelectBuilder {
+	q := squirrel.
+		Select(
+			"id",
+			"rel_role",
+			"resource",
+			"operation",
+			"old_access",
+			"new_access",
+			"rel_actor",
+			"rollback_to",
+			"created_at",
+		).
+		From(r.table())
+
+	if f.RoleID > 0 {
+		q = q.Where(squirrel.Eq{"rel_role": f.RoleID})
+	}
+
+	if f.Resource != "" {
+		q = q.Where(squirrel.Eq{"resource": f.Resource})
+	}
+
+	if f.ActorID > 0 {
+		q = q.Where(squirrel.Eq{"rel_actor": f.ActorID})
+	}
+
+	if f.From != nil {
+		q = q.Where(squirrel.GtOrEq{"created_at": f.From})
+	}
+
+	if f.To != nil {
+		q = q.Where(squirrel.Lt{"created_at": f.To})
+	}
+
+	return q
+}
+
+// Find returns a page of changes that match the filter, newest first
+func (r repository) Find(f ChangeFilter) (set ChangeSet, _ ChangeFilter, err error) {
+	q := r.query(f)
+
+	if f.Count, err = rh.Count(r.db(), q); err != nil || f.Count == 0 {
+		return nil, f, err
+	}
+
+	q = q.OrderBy("created_at DESC", "id DESC")
+
+	return set, f, rh.FetchPaged(r.db(), q, f.Page, f.PerPage, &set)
+}
+
+// Since returns changes made after the time, oldest first
+func (r repository) Since(t time.Time) (set ChangeSet, err error) {
+	q := r.query(ChangeFilter{}).
+		Where(squirrel.Gt{"created_at": t}).
+		OrderBy("created_at", "id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Record stores changes, all with the same time
+func (r repository) Record(cc ChangeSet) error {
+	now := time.Now()
+
+	return r.db().Transaction(func() error {
+		for _, c := range cc {
+			c.ID = factory.Sonyflake.NextID()
+			c.CreatedAt = now
+
+			if err := r.db().Insert(r.table(), c); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Rules returns current rules of the role, from app's rules table
+func (r repository) Rules(roleID uint64) (rr permissions.RuleSet, err error) {
+	q := squirrel.
+		Select("rel_role", "resource", "operation", "access").
+		From(rulesTables[r.app]).
+		Where(squirrel.Eq{"rel_role": roleID})
+
+	return rr, rh.FetchAll(r.db(), q, &rr)
+}
+
+// Access returns current access of the rules, inherit when there is no rule
+func (r repository) Access(kk []key) (map[key]permissions.Access, error) {
+	var (
+		out = make(map[key]permissions.Access, len(kk))
+		rr  permissions.RuleSet
+		ids = map[uint64]bool{}
+	)
+
+	for _, k := range kk {
+		out[k] = permissions.Inherit
+		ids[k.roleID] = true
+	}
+
+	for roleID := range ids {
+		var err error
+		if rr, err = r.Rules(roleID); err != nil {
+			return nil, err
+		}
+
+		for _, rule := range rr {
+			if _, ok := out[keyOf(rule)]; ok {
+				out[keyOf(rule)] = rule.Access
+			}
+		}
+	}
+
+	return out, nil
+}
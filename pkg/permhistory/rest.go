@@ -0,0 +1,54 @@
+package permhistory
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountComposeRoutes mounts history of compose permission rules
+func MountComposeRoutes(r chi.Router) {
+	mount(r, "compose")
+}
+
+// MountSystemRoutes mounts history of system permission rules
+func MountSystemRoutes(r chi.Router) {
+	mount(r, "system")
+}
+
+// MountMessagingRoutes mounts history of messaging permission rules
+func MountMessagingRoutes(r chi.Router) {
+	mount(r, "messaging")
+}
+
+func mount(r chi.Router, app string) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?roleID=&resource=compose:module:*&actorID=&from=2020-01-01T00:00:00Z&to=&page=1&perPage=50
+	r.Get("/", rest.Handler("PermissionHistory.List", func(r *http.Request) (interface{}, error) {
+		return DefaultPermHistory.With(r.Context()).Find(app, ChangeFilter{
+			RoleID:   rest.QueryUint64(r, "roleID"),
+			Resource: r.URL.Query().Get("resource"),
+			ActorID:  rest.QueryUint64(r, "actorID"),
+			From:     rest.QueryTime(r, "from"),
+			To:       rest.QueryTime(r, "to"),
+
+			PageFilter: rh.Paging(rest.QueryUint(r, "page"), rest.QueryUint(r, "perPage")),
+		})
+	}))
+
+	// ?to=2020-01-01T00:00:00Z
+	r.Post("/rollback", rest.Handler("PermissionHistory.Rollback", func(r *http.Request) (interface{}, error) {
+		var to time.Time
+		if t := rest.QueryTime(r, "to"); t != nil {
+			to = *t
+		}
+
+		return DefaultPermHistory.With(r.Context()).Rollback(app, to)
+	}))
+}
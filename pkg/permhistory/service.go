@@ -0,0 +1,219 @@
+package permhistory
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	permhistoryService struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	accessController interface {
+		CanGrant(context.Context) bool
+		Grant(context.Context, ...*permissions.Rule) error
+	}
+
+	PermHistoryService interface {
+		With(ctx context.Context) PermHistoryService
+
+		Find(app string, f ChangeFilter) (*Payload, error)
+		Rollback(app string, to time.Time) (ChangeSet, error)
+	}
+)
+
+var (
+	DefaultPermHistory PermHistoryService
+
+	// used by middlewares
+	defaultPermHistory *permhistoryService
+
+	// Apps with initialized history
+	apps = map[string]*app{}
+)
+
+// InitCompose starts recording changes of compose permission rules
+//
+// Must be called after compose services are initialized
+func InitCompose(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log, &app{name: "compose", ac: composeService.DefaultAccessControl})
+}
+
+// InitSystem starts recording changes of system permission rules
+//
+// Must be called after system services are initialized
+func InitSystem(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log, &app{name: "system", ac: systemService.DefaultAccessControl})
+}
+
+// InitMessaging starts recording changes of messaging permission rules
+//
+// Must be called after messaging services are initialized
+func InitMessaging(ctx context.Context, log *zap.Logger) error {
+	return start(ctx, log, &app{name: "messaging", ac: messagingService.DefaultAccessControl})
+}
+
+func start(ctx context.Context, log *zap.Logger, a *app) error {
+	svc := (&permhistoryService{logger: log}).with(ctx)
+
+	apps[a.name] = a
+	DefaultPermHistory = svc
+	defaultPermHistory = svc
+
+	return nil
+}
+
+func (svc permhistoryService) With(ctx context.Context) PermHistoryService {
+	return svc.with(ctx)
+}
+
+func (svc permhistoryService) with(ctx context.Context) *permhistoryService {
+	return &permhistoryService{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc permhistoryService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc permhistoryService) repository(app string) *repository {
+	return Repository(svc.ctx, factory.Database.MustGet(app).With(svc.ctx), app)
+}
+
+// Find returns a page of changes of app's rules, newest first
+//
+// History is available to those that can grant app's permissions
+func (svc permhistoryService) Find(name string, f ChangeFilter) (*Payload, error) {
+	a := apps[name]
+	if a == nil || !a.ac.CanGrant(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if f.PerPage == 0 {
+		f.PerPage = defaultPerPage
+	} else if f.PerPage > maxPerPage {
+		f.PerPage = maxPerPage
+	}
+
+	set, f, err := svc.repository(name).Find(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Payload{Filter: f, Set: set}, nil
+}
+
+// Rollback restores app's rules to how they were at the time
+//
+// Changes made after the time are reverted, the oldest change of each rule
+// tells what its access was. Rules are granted at once, in one transaction,
+// and the rollback is recorded as changes too.
+//
+// Rules changed outside of the API (provisioning, garbage collection)
+// have no history and are left as they are.
+func (svc permhistoryService) Rollback(name string, to time.Time) (ChangeSet, error) {
+	a := apps[name]
+	if a == nil || !a.ac.CanGrant(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if to.IsZero() {
+		return nil, ErrTimestampRequired.withStack()
+	}
+
+	r := svc.repository(name)
+
+	since, err := r.Since(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		kk     = []key{}
+		target = map[key]permissions.Access{}
+	)
+
+	for _, c := range since {
+		k := key{c.RoleID, c.Resource, c.Operation}
+		if _, ok := target[k]; !ok {
+			kk = append(kk, k)
+			target[k] = c.OldAccess
+		}
+	}
+
+	current, err := r.Access(kk)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		rr = permissions.RuleSet{}
+		cc = ChangeSet{}
+	)
+
+	for _, k := range kk {
+		if current[k] == target[k] {
+			continue
+		}
+
+		rr = append(rr, &permissions.Rule{
+			RoleID:    k.roleID,
+			Resource:  permissions.Resource(k.resource),
+			Operation: permissions.Operation(k.operation),
+			Access:    target[k],
+		})
+
+		c := change(k, current[k], target[k])
+		c.RollbackTo = &to
+		cc = append(cc, c)
+	}
+
+	if len(rr) == 0 {
+		return cc, nil
+	}
+
+	if err = a.ac.Grant(svc.ctx, rr...); err != nil {
+		return nil, err
+	}
+
+	svc.record(r, cc)
+
+	svc.log(zap.String("app", name), zap.Time("to", to), zap.Int("changes", len(cc))).
+		Info("permission rules rolled back")
+
+	return cc, nil
+}
+
+// record stores changes made by the current user
+//
+// Rules are already changed, errors are only logged.
+func (svc permhistoryService) record(r *repository, cc ChangeSet) {
+	if len(cc) == 0 {
+		return
+	}
+
+	actorID := auth.GetIdentityFromContext(svc.ctx).Identity()
+	for _, c := range cc {
+		c.ActorID = actorID
+	}
+
+	if err := r.Record(cc); err != nil {
+		svc.log(zap.String("app", r.app), zap.Error(err)).Error("could not record permission changes")
+	}
+}
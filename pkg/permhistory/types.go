@@ -0,0 +1,115 @@
+package permhistory
+
+import (
+	"time"
+
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	// Change of a permission rule
+	//
+	// Inherit access means there was (or is) no rule.
+	Change struct {
+		ID uint64 `json:"changeID,string" db:"id"`
+
+		RoleID    uint64             `json:"roleID,string" db:"rel_role"`
+		Resource  string             `json:"resource" db:"resource"`
+		Operation string             `json:"operation" db:"operation"`
+		OldAccess permissions.Access `json:"oldAccess" db:"old_access"`
+		NewAccess permissions.Access `json:"newAccess" db:"new_access"`
+
+		ActorID uint64 `json:"actorID,string" db:"rel_actor"`
+
+		// Set on changes made by rollback, time rules were rolled back to
+		RollbackTo *time.Time `json:"rollbackTo,omitempty" db:"rollback_to"`
+
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	ChangeSet []*Change
+
+	ChangeFilter struct {
+		RoleID   uint64 `json:"roleID,string"`
+		Resource string `json:"resource"`
+		ActorID  uint64 `json:"actorID,string"`
+
+		From *time.Time `json:"from"`
+		To   *time.Time `json:"to"`
+
+		rh.PageFilter
+	}
+
+	// Payload is a page of changes with the filter and total count
+	Payload struct {
+		Filter ChangeFilter `json:"filter"`
+		Set    ChangeSet    `json:"set"`
+	}
+
+	// app with its rules table and access control
+	app struct {
+		name  string
+		rules string
+		ac    accessController
+	}
+
+	// key of the rule
+	key struct {
+		roleID    uint64
+		resource  string
+		operation string
+	}
+)
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 500
+)
+
+func keyOf(r *permissions.Rule) key {
+	return key{r.RoleID, r.Resource.String(), r.Operation.String()}
+}
+
+// diff returns changes from old to new rules
+func diff(old, new permissions.RuleSet) ChangeSet {
+	var (
+		out    = ChangeSet{}
+		before = map[key]permissions.Access{}
+		after  = map[key]permissions.Access{}
+	)
+
+	for _, r := range old {
+		before[keyOf(r)] = r.Access
+	}
+
+	for _, r := range new {
+		after[keyOf(r)] = r.Access
+	}
+
+	for k, a := range before {
+		if b, ok := after[k]; !ok {
+			out = append(out, change(k, a, permissions.Inherit))
+		} else if a != b {
+			out = append(out, change(k, a, b))
+		}
+	}
+
+	for k, b := range after {
+		if _, ok := before[k]; !ok {
+			out = append(out, change(k, permissions.Inherit, b))
+		}
+	}
+
+	return out
+}
+
+func change(k key, old, new permissions.Access) *Change {
+	return &Change{
+		RoleID:    k.roleID,
+		Resource:  k.resource,
+		Operation: k.operation,
+		OldAccess: old,
+		NewAccess: new,
+	}
+}
@@ -0,0 +1,38 @@
+package privacy
+
+import (
+	"context"
+	"io"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// attachment wraps attachment service and enforces blocks and DM consent
+	attachment struct {
+		messagingService.AttachmentService
+		ctx context.Context
+	}
+)
+
+// Attachment decorates attachment service with enforcing blocks and DM
+// consent; uploads are posted as messages
+func Attachment(as messagingService.AttachmentService) messagingService.AttachmentService {
+	return &attachment{AttachmentService: as, ctx: context.Background()}
+}
+
+func (svc attachment) With(ctx context.Context) messagingService.AttachmentService {
+	return &attachment{
+		AttachmentService: svc.AttachmentService.With(ctx),
+		ctx:               ctx,
+	}
+}
+
+func (svc attachment) Create(name string, size int64, fh io.ReadSeeker, channelID, replyTo uint64) (*messagingTypes.Attachment, error) {
+	if err := defaultPrivacy.with(svc.ctx).canPost(channelID); err != nil {
+		return nil, err
+	}
+
+	return svc.AttachmentService.Create(name, size, fh, channelID, replyTo)
+}
@@ -0,0 +1,56 @@
+package privacy
+
+import (
+	"context"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// channel wraps channel service and enforces blocks and DM consent
+	channel struct {
+		messagingService.ChannelService
+		ctx context.Context
+	}
+)
+
+// Channel decorates channel service with enforcing blocks and DM consent
+//
+// Groups (direct messages) can not be started with users that do not
+// accept direct messages from the creator, nor can such users be added.
+func Channel(cs messagingService.ChannelService) messagingService.ChannelService {
+	return &channel{ChannelService: cs, ctx: context.Background()}
+}
+
+func (svc channel) With(ctx context.Context) messagingService.ChannelService {
+	return &channel{
+		ChannelService: svc.ChannelService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc channel) Create(ch *messagingTypes.Channel) (*messagingTypes.Channel, error) {
+	if ch != nil && ch.Type == messagingTypes.ChannelTypeGroup {
+		if err := defaultPrivacy.with(svc.ctx).canMessage(ch.Members...); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.ChannelService.Create(ch)
+}
+
+func (svc channel) AddMember(channelID uint64, memberIDs ...uint64) (messagingTypes.ChannelMemberSet, error) {
+	ch, err := svc.ChannelService.FindByID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ch.Type == messagingTypes.ChannelTypeGroup {
+		if err = defaultPrivacy.with(svc.ctx).canMessage(memberIDs...); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.ChannelService.AddMember(channelID, memberIDs...)
+}
@@ -0,0 +1,35 @@
+package privacy
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	privacyError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidConsent           = privacyError{"InvalidConsent", fault.Invalid}
+	ErrInvalidUser              = privacyError{"InvalidUser", fault.Invalid}
+	ErrTooManyBlocked           = privacyError{"TooManyBlocked", fault.TooMany}
+	ErrDirectMessagesRestricted = privacyError{"DirectMessagesRestricted", fault.Forbidden}
+)
+
+func (e privacyError) Error() string {
+	return e.String()
+}
+
+func (e privacyError) String() string {
+	return "crust.privacy." + e.name
+}
+
+func (e privacyError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e privacyError) withStack() *fault.Error {
+	return fault.New(e)
+}
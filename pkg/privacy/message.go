@@ -0,0 +1,70 @@
+package privacy
+
+import (
+	"context"
+	"io"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// message wraps message service and enforces blocks and DM consent
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with enforcing blocks and DM consent
+//
+// Messages to groups are refused when a member does not accept them,
+// mentions of users that blocked the author are not mentions anymore.
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	if err := svc.check(m); err != nil {
+		return nil, err
+	}
+
+	return svc.MessageService.Create(m)
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	if err := svc.check(m); err != nil {
+		return nil, err
+	}
+
+	return svc.MessageService.CreateWithAvatar(m, avatar)
+}
+
+func (svc message) Update(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	if m != nil {
+		var err error
+		if m.Message, err = defaultPrivacy.with(svc.ctx).unmention(m.Message); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.MessageService.Update(m)
+}
+
+func (svc message) check(m *messagingTypes.Message) (err error) {
+	p := defaultPrivacy.with(svc.ctx)
+
+	if err = p.canPost(m.ChannelID); err != nil {
+		return err
+	}
+
+	m.Message, err = p.unmention(m.Message)
+	return err
+}
@@ -0,0 +1,31 @@
+package privacy
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200229000000.privacy",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_user_block (
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  rel_blocked      BIGINT UNSIGNED NOT NULL,
+  created_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_user, rel_blocked),
+  INDEX lookup_blocked (rel_blocked)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_messaging_dm_consent (
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  consent          VARCHAR(16)     NOT NULL,
+  updated_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_user)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
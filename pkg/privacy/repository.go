@@ -0,0 +1,171 @@
+package privacy
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) blocks() string {
+	return "crust_messaging_user_block"
+}
+
+func (r repository) consents() string {
+	return "crust_messaging_dm_consent"
+}
+
+// FindBlocked returns users blocked by the user, latest first
+func (r repository) FindBlocked(userID uint64) (set BlockSet, err error) {
+	q := squirrel.
+		Select("rel_user", "rel_blocked", "created_at").
+		From(r.blocks()).
+		Where(squirrel.Eq{"rel_user": userID}).
+		OrderBy("created_at DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CountBlocked(userID uint64) (n uint, err error) {
+	err = r.db().Get(&n, "SELECT COUNT(*) FROM "+r.blocks()+" WHERE rel_user = ?", userID)
+	return n, errors.WithStack(err)
+}
+
+// Block stores the block, blocking twice keeps the first
+func (r repository) Block(b *Block) error {
+	_, err := r.db().Exec(
+		"INSERT IGNORE INTO "+r.blocks()+" (rel_user, rel_blocked, created_at) VALUES (?, ?, ?)",
+		b.UserID, b.BlockedID, b.CreatedAt,
+	)
+
+	return errors.WithStack(err)
+}
+
+func (r repository) Unblock(userID, blockedID uint64) error {
+	_, err := r.db().Exec("DELETE FROM "+r.blocks()+" WHERE rel_user = ? AND rel_blocked = ?", userID, blockedID)
+	return errors.WithStack(err)
+}
+
+// BlockedBy returns those of the users that blocked the given one
+func (r repository) BlockedBy(userIDs []uint64, blockedID uint64) (out []uint64, err error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := squirrel.
+		Select("rel_user").
+		From(r.blocks()).
+		Where(squirrel.Eq{"rel_user": userIDs, "rel_blocked": blockedID}).
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, errors.WithStack(r.db().Select(&out, query, args...))
+}
+
+// FindSettings returns settings of the user, nil when they were not changed
+func (r repository) FindSettings(userID uint64) (*Settings, error) {
+	var (
+		s = &Settings{}
+		q = squirrel.
+			Select("rel_user", "consent", "updated_at").
+			From(r.consents()).
+			Where(squirrel.Eq{"rel_user": userID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.UserID == 0 {
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+func (r repository) SetSettings(s *Settings) error {
+	return errors.WithStack(r.db().Replace(r.consents(), s))
+}
+
+// Consents returns consents of the users that changed it
+func (r repository) Consents(userIDs []uint64) (map[uint64]Consent, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := squirrel.
+		Select("rel_user", "consent").
+		From(r.consents()).
+		Where(squirrel.Eq{"rel_user": userIDs}).
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rr []*consentRow
+	if err = r.db().Select(&rr, query, args...); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	out := make(map[uint64]Consent, len(rr))
+	for _, c := range rr {
+		out[c.UserID] = c.Consent
+	}
+
+	return out, nil
+}
+
+// Shared checks if both users are members of the same channel that is not a group
+func (r repository) Shared(userID, otherID uint64) (shared bool, err error) {
+	err = r.db().Get(&shared, `
+SELECT EXISTS(
+  SELECT 1
+    FROM messaging_channel_member AS a
+         INNER JOIN messaging_channel_member AS b ON (b.rel_channel = a.rel_channel)
+         INNER JOIN messaging_channel AS c ON (c.id = a.rel_channel)
+   WHERE a.rel_user = ? AND b.rel_user = ?
+     AND a.type <> 'invitee' AND b.type <> 'invitee'
+     AND c.type <> ? AND c.deleted_at IS NULL
+)`, userID, otherID, messagingTypes.ChannelTypeGroup)
+
+	return shared, errors.WithStack(err)
+}
+
+// GroupMembers returns members of the channel when it is a group, nil otherwise
+func (r repository) GroupMembers(channelID uint64) (out []uint64, err error) {
+	err = r.db().Select(&out, `
+SELECT cm.rel_user
+  FROM messaging_channel_member AS cm
+       INNER JOIN messaging_channel AS c ON (c.id = cm.rel_channel)
+ WHERE cm.rel_channel = ? AND c.type = ?`, channelID, messagingTypes.ChannelTypeGroup)
+
+	return out, errors.WithStack(err)
+}
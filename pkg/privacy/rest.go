@@ -0,0 +1,42 @@
+package privacy
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts privacy endpoints of the current user
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Who can send direct messages to the current user
+	r.Get("/dm-consent", rest.Handler("Privacy.Settings", func(r *http.Request) (interface{}, error) {
+		return DefaultPrivacy.With(r.Context()).Settings()
+	}))
+
+	r.Put("/dm-consent", rest.Handler("Privacy.SetSettings", func(r *http.Request) (interface{}, error) {
+		s := &Settings{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		return DefaultPrivacy.With(r.Context()).SetSettings(s)
+	}))
+
+	r.Get("/blocked-users", rest.Handler("Privacy.Blocked", func(r *http.Request) (interface{}, error) {
+		return DefaultPrivacy.With(r.Context()).Blocked()
+	}))
+
+	r.Put("/blocked-users/{userID}", rest.Handler("Privacy.Block", func(r *http.Request) (interface{}, error) {
+		return DefaultPrivacy.With(r.Context()).Block(rest.ParamUint64(r, "userID"))
+	}))
+
+	r.Delete("/blocked-users/{userID}", rest.Handler("Privacy.Unblock", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultPrivacy.With(r.Context()).Unblock(rest.ParamUint64(r, "userID"))
+	}))
+}
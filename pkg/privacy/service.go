@@ -0,0 +1,240 @@
+package privacy
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		repository *repository
+	}
+
+	PrivacyService interface {
+		With(ctx context.Context) PrivacyService
+
+		Settings() (*Settings, error)
+		SetSettings(*Settings) (*Settings, error)
+
+		Blocked() (BlockSet, error)
+		Block(userID uint64) (*Block, error)
+		Unblock(userID uint64) error
+	}
+)
+
+var (
+	DefaultPrivacy PrivacyService
+
+	// used by service decorators
+	defaultPrivacy *service
+
+	// now is used for block and settings times and can be overridden
+	now = time.Now
+
+	// User mentions, <@123> or <@123 John>, same as corteza's
+	mention = regexp.MustCompile(`<@(\d+)((?:\s)([^>]+))?>`)
+)
+
+// Init initializes blocking of users and DM consent and decorates
+// message, attachment and channel services with enforcing them
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger: log,
+	}
+
+	DefaultPrivacy = svc.With(ctx)
+	defaultPrivacy = svc.with(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	messagingService.DefaultAttachment = Attachment(messagingService.DefaultAttachment)
+	messagingService.DefaultChannel = Channel(messagingService.DefaultChannel)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) PrivacyService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// Settings returns privacy settings of the current user
+func (svc service) Settings() (*Settings, error) {
+	userID := auth.GetIdentityFromContext(svc.ctx).Identity()
+
+	s, err := svc.repository.FindSettings(userID)
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		return &Settings{UserID: userID, DirectMessages: ConsentAnyone}, nil
+	}
+
+	return s, nil
+}
+
+func (svc service) SetSettings(in *Settings) (*Settings, error) {
+	if !in.DirectMessages.IsValid() {
+		return nil, ErrInvalidConsent.withStack()
+	}
+
+	t := now().Truncate(time.Second)
+	s := &Settings{
+		UserID:         auth.GetIdentityFromContext(svc.ctx).Identity(),
+		DirectMessages: in.DirectMessages,
+		UpdatedAt:      &t,
+	}
+
+	return s, svc.repository.SetSettings(s)
+}
+
+// Blocked returns users blocked by the current user
+func (svc service) Blocked() (BlockSet, error) {
+	bb, err := svc.repository.FindBlocked(auth.GetIdentityFromContext(svc.ctx).Identity())
+	if err != nil {
+		return nil, err
+	} else if bb == nil {
+		return BlockSet{}, nil
+	}
+
+	return bb, nil
+}
+
+// Block keeps the user from sending direct messages to the current user
+// and from mentioning them
+//
+// Blocked user is not told about it, their messages are refused the same
+// way as when the current user does not accept direct messages.
+func (svc service) Block(blockedID uint64) (*Block, error) {
+	userID := auth.GetIdentityFromContext(svc.ctx).Identity()
+	if blockedID == 0 || blockedID == userID {
+		return nil, ErrInvalidUser.withStack()
+	}
+
+	if n, err := svc.repository.CountBlocked(userID); err != nil {
+		return nil, err
+	} else if n >= maxBlocked {
+		return nil, ErrTooManyBlocked.withStack()
+	}
+
+	b := &Block{UserID: userID, BlockedID: blockedID, CreatedAt: now().Truncate(time.Second)}
+	if err := svc.repository.Block(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (svc service) Unblock(blockedID uint64) error {
+	return svc.repository.Unblock(auth.GetIdentityFromContext(svc.ctx).Identity(), blockedID)
+}
+
+// canPost checks if members of the group accept direct messages from
+// the current user; other channels are not restricted
+func (svc service) canPost(channelID uint64) error {
+	mm, err := svc.repository.GroupMembers(channelID)
+	if err != nil || len(mm) == 0 {
+		return err
+	}
+
+	return svc.canMessage(mm...)
+}
+
+// canMessage checks if all of the users accept direct messages from the current user
+func (svc service) canMessage(userIDs ...uint64) error {
+	var (
+		senderID   = auth.GetIdentityFromContext(svc.ctx).Identity()
+		recipients = make([]uint64, 0, len(userIDs))
+	)
+
+	for _, ID := range userIDs {
+		if ID != senderID {
+			recipients = append(recipients, ID)
+		}
+	}
+
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	if bb, err := svc.repository.BlockedBy(recipients, senderID); err != nil {
+		return err
+	} else if len(bb) > 0 {
+		return ErrDirectMessagesRestricted.withStack()
+	}
+
+	cc, err := svc.repository.Consents(recipients)
+	if err != nil {
+		return err
+	}
+
+	for _, ID := range recipients {
+		switch cc[ID] {
+		case ConsentNobody:
+			return ErrDirectMessagesRestricted.withStack()
+		case ConsentShared:
+			if shared, err := svc.repository.Shared(senderID, ID); err != nil {
+				return err
+			} else if !shared {
+				return ErrDirectMessagesRestricted.withStack()
+			}
+		}
+	}
+
+	return nil
+}
+
+// unmention turns mentions of users that blocked the current user into
+// plain text, so they are not notified
+func (svc service) unmention(text string) (string, error) {
+	mm := mention.FindAllStringSubmatch(text, -1)
+	if len(mm) == 0 {
+		return text, nil
+	}
+
+	ID := make([]uint64, 0, len(mm))
+	for _, m := range mm {
+		if v, err := strconv.ParseUint(m[1], 10, 64); err == nil {
+			ID = append(ID, v)
+		}
+	}
+
+	bb, err := svc.repository.BlockedBy(ID, auth.GetIdentityFromContext(svc.ctx).Identity())
+	if err != nil || len(bb) == 0 {
+		return text, err
+	}
+
+	blocked := make(map[string]bool, len(bb))
+	for _, b := range bb {
+		blocked[strconv.FormatUint(b, 10)] = true
+	}
+
+	return mention.ReplaceAllStringFunc(text, func(s string) string {
+		m := mention.FindStringSubmatch(s)
+		if !blocked[m[1]] {
+			return s
+		} else if m[3] != "" {
+			return "@" + m[3]
+		}
+
+		return "@" + m[1]
+	}), nil
+}
@@ -0,0 +1,48 @@
+package privacy
+
+import (
+	"time"
+)
+
+type (
+	// Settings of who can send direct messages to the user
+	Settings struct {
+		UserID         uint64     `json:"userID,string" db:"rel_user"`
+		DirectMessages Consent    `json:"directMessages" db:"consent"`
+		UpdatedAt      *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	// Block keeps the blocked user from sending direct messages to the
+	// user and from mentioning them
+	Block struct {
+		UserID    uint64    `json:"-" db:"rel_user"`
+		BlockedID uint64    `json:"userID,string" db:"rel_blocked"`
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	BlockSet []*Block
+
+	Consent string
+
+	// consentRow is the consent of a user
+	consentRow struct {
+		UserID  uint64  `db:"rel_user"`
+		Consent Consent `db:"consent"`
+	}
+)
+
+const (
+	ConsentAnyone Consent = "anyone"
+
+	// Users that are members of a channel (not a group) with the user
+	ConsentShared Consent = "shared"
+
+	ConsentNobody Consent = "nobody"
+
+	// Users the user can block
+	maxBlocked = 1000
+)
+
+func (c Consent) IsValid() bool {
+	return c == ConsentAnyone || c == ConsentShared || c == ConsentNobody
+}
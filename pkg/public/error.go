@@ -0,0 +1,39 @@
+package public
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	publicError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrChannelNotFound    = publicError{"ChannelNotFound", fault.NotFound}
+	ErrAttachmentNotFound = publicError{"AttachmentNotFound", fault.NotFound}
+	ErrInvalidSlug        = publicError{"InvalidSlug", fault.Invalid}
+	ErrInvalidChannelType = publicError{"InvalidChannelType", fault.Invalid}
+	ErrSlugTaken          = publicError{"SlugTaken", fault.Conflict}
+	ErrAlreadyExposed     = publicError{"AlreadyExposed", fault.Conflict}
+	ErrRateLimitReached   = publicError{"RateLimitReached", fault.TooMany}
+	ErrNoPermissions      = publicError{"NoPermissions", fault.Forbidden}
+)
+
+func (e publicError) Error() string {
+	return e.String()
+}
+
+func (e publicError) String() string {
+	return "crust.public." + e.name
+}
+
+func (e publicError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e publicError) withStack() *fault.Error {
+	return fault.New(e)
+}
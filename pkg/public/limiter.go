@@ -0,0 +1,63 @@
+package public
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// limiter counts requests of visitors (by IP) in fixed windows
+	//
+	// Requests are counted on the instance they were made on.
+	limiter struct {
+		sync.Mutex
+
+		// Requests allowed in the window, 0 disables the limit
+		limit  int
+		window time.Duration
+
+		visitors map[string]*visits
+		swept    time.Time
+	}
+
+	visits struct {
+		start time.Time
+		count int
+	}
+)
+
+// allow counts the request or returns error when the visitor made too many of them
+func (l *limiter) allow(ip string, now time.Time) error {
+	if l.limit <= 0 {
+		return nil
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	// Visitors that were not seen for a window are forgotten
+	if now.Sub(l.swept) > l.window {
+		for k, v := range l.visitors {
+			if now.Sub(v.start) > l.window {
+				delete(l.visitors, k)
+			}
+		}
+
+		l.swept = now
+	}
+
+	v := l.visitors[ip]
+	if v == nil || now.Sub(v.start) > l.window {
+		v = &visits{start: now}
+		l.visitors[ip] = v
+	}
+
+	if v.count >= l.limit {
+		return ErrRateLimitReached.withStack().
+			WithMessage("too many requests, try again later").
+			WithRetryAfter(v.start.Add(l.window).Sub(now))
+	}
+
+	v.count++
+	return nil
+}
@@ -0,0 +1,25 @@
+package public
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200303000000.public",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_public_channel (
+  slug             VARCHAR(64)     NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  attachments      BOOLEAN         NOT NULL DEFAULT FALSE,
+  exposed_by       BIGINT UNSIGNED NOT NULL,
+  exposed_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (slug),
+  UNIQUE KEY uid_channel (rel_channel)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,147 @@
+package public
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_public_channel"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"slug",
+			"rel_channel",
+			"attachments",
+			"exposed_by",
+			"exposed_at",
+		).
+		From(r.table())
+}
+
+// Find returns all public channels
+func (r repository) Find() (set PublicChannelSet, err error) {
+	return set, rh.FetchAll(r.db(), r.query().OrderBy("slug"), &set)
+}
+
+// FindBySlug returns public channel with the slug, nil when there is none
+func (r repository) FindBySlug(slug string) (*PublicChannel, error) {
+	return r.findOneBy(squirrel.Eq{"slug": slug})
+}
+
+// FindByChannelID returns the channel when it is public, nil when it is not
+func (r repository) FindByChannelID(channelID uint64) (*PublicChannel, error) {
+	return r.findOneBy(squirrel.Eq{"rel_channel": channelID})
+}
+
+func (r repository) findOneBy(cnd squirrel.Sqlizer) (*PublicChannel, error) {
+	var c = &PublicChannel{}
+
+	if err := rh.FetchOne(r.db(), r.query().Where(cnd), c); err != nil {
+		return nil, err
+	} else if c.ChannelID == 0 {
+		return nil, nil
+	}
+
+	return c, nil
+}
+
+func (r repository) Save(c *PublicChannel) error {
+	return errors.WithStack(r.db().Replace(r.table(), c))
+}
+
+func (r repository) Delete(slug string) error {
+	_, err := r.db().Exec("DELETE FROM "+r.table()+" WHERE slug = ?", slug)
+	return errors.WithStack(err)
+}
+
+// Attachment returns attachment of the channel's message, nil when there is none
+//
+// Attachments of deleted messages and of replies are not included.
+func (r repository) Attachment(channelID, attachmentID uint64) (*messagingTypes.Attachment, error) {
+	var (
+		a = &messagingTypes.Attachment{}
+		q = squirrel.
+			Select(
+				"a.id",
+				"a.rel_user",
+				"a.url",
+				"a.preview_url",
+				"a.name",
+				"a.meta",
+				"a.created_at",
+				"a.updated_at",
+				"a.deleted_at",
+			).
+			From("messaging_attachment AS a").
+			Join("messaging_message_attachment AS ma ON (ma.rel_attachment = a.id)").
+			Join("messaging_message AS m ON (m.id = ma.rel_message)").
+			Where(squirrel.Eq{
+				"a.id":          attachmentID,
+				"a.deleted_at":  nil,
+				"m.rel_channel": channelID,
+				"m.reply_to":    0,
+				"m.deleted_at":  nil,
+			})
+	)
+
+	if err := rh.FetchOne(r.db(), q, a); err != nil {
+		return nil, err
+	} else if a.ID == 0 {
+		return nil, nil
+	}
+
+	return a, nil
+}
+
+// Users returns names of the users from system database
+//
+// Standalone messaging server does not connect to it, names are not
+// available there.
+func (r repository) Users(userIDs []uint64) (set userSet, err error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	db, err := factory.Database.Get("system")
+	if err != nil {
+		return nil, nil
+	}
+
+	q := squirrel.
+		Select("id", "name", "handle").
+		From("sys_user").
+		Where(squirrel.Eq{"id": userIDs})
+
+	return set, rh.FetchAll(db.With(r.ctx), q, &set)
+}
@@ -0,0 +1,158 @@
+package public
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/crusttech/crust-server/pkg/rest"
+	"github.com/crusttech/crust-server/pkg/seclog"
+)
+
+// MountRoutes mounts endpoints of public channels
+//
+// Channels are read by visitors without signing in; requests of visitors
+// are rate limited by their IP and can be made from any origin, so that
+// channels can be embedded into other sites.
+func MountRoutes(r chi.Router) {
+	r.Group(func(r chi.Router) {
+		r.Use(cors)
+
+		// Channel with the latest messages: ?beforeID=<ID>&limit=<n>
+		r.Get("/{slug}", visitor("Public.Channel", func(r *http.Request) (interface{}, error) {
+			return DefaultPublic.With(r.Context()).Channel(
+				chi.URLParam(r, "slug"),
+				rest.QueryUint64(r, "beforeID"),
+				rest.QueryUint(r, "limit"),
+			)
+		}))
+
+		// Channel as JSON Feed
+		r.Get("/{slug}/feed.json", visitor("Public.Feed", func(r *http.Request) (interface{}, error) {
+			f, err := DefaultPublic.With(r.Context()).Feed(chi.URLParam(r, "slug"), baseURL(r))
+			if err != nil {
+				return nil, err
+			}
+
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/feed+json; charset=utf-8")
+				_ = json.NewEncoder(w).Encode(f)
+			}, nil
+		}))
+
+		r.Get("/{slug}/attachments/{attachmentID}", visitor("Public.Attachment", func(r *http.Request) (interface{}, error) {
+			return serveAttachment(r, false)
+		}))
+
+		r.Get("/{slug}/attachments/{attachmentID}/preview", visitor("Public.AttachmentPreview", func(r *http.Request) (interface{}, error) {
+			return serveAttachment(r, true)
+		}))
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.MiddlewareValidOnly)
+
+		r.Get("/", rest.Handler("Public.List", func(r *http.Request) (interface{}, error) {
+			return DefaultPublic.With(r.Context()).Find()
+		}))
+
+		// Exposes channel under the slug, or changes its settings
+		r.Put("/{slug}", rest.Handler("Public.Expose", func(r *http.Request) (interface{}, error) {
+			var body struct {
+				ChannelID   uint64 `json:"channelID,string"`
+				Attachments bool   `json:"attachments"`
+			}
+
+			if err := rest.Decode(r, &body); err != nil {
+				return nil, err
+			}
+
+			return DefaultPublic.With(r.Context()).Expose(chi.URLParam(r, "slug"), body.ChannelID, body.Attachments)
+		}))
+
+		r.Delete("/{slug}", rest.Handler("Public.Withdraw", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultPublic.With(r.Context()).Withdraw(chi.URLParam(r, "slug"))
+		}))
+	})
+}
+
+// visitor wraps controller of visitors' endpoint with the rate limit
+func visitor(name string, ctrl rest.Controller) http.HandlerFunc {
+	return rest.Handler(name, func(r *http.Request) (interface{}, error) {
+		if err := visitors.allow(seclog.RemoteIP(r), now()); err != nil {
+			return nil, err
+		}
+
+		return ctrl(r)
+	})
+}
+
+func serveAttachment(r *http.Request, preview bool) (interface{}, error) {
+	a, fh, err := DefaultPublic.With(r.Context()).OpenAttachment(
+		chi.URLParam(r, "slug"),
+		rest.ParamUint64(r, "attachmentID"),
+		preview,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		if c, ok := fh.(io.Closer); ok {
+			defer c.Close()
+		}
+
+		var (
+			name     = url.QueryEscape(a.Name)
+			mimetype = a.Meta.Original.Mimetype
+		)
+
+		if preview && a.Meta.Preview != nil {
+			mimetype = a.Meta.Preview.Mimetype
+		}
+
+		w.Header().Set("Content-Type", mimetype)
+		w.Header().Set("Content-Disposition", "inline; filename="+name)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeContent(w, req, name, a.CreatedAt, fh)
+	}, nil
+}
+
+// cors allows visitors' endpoints to be read from any origin
+func cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// baseURL returns URL where public channel endpoints are served
+//
+// PUBLIC_BASE_URL is used when the server is behind a proxy that
+// changes the path, otherwise it is taken from the request.
+func baseURL(r *http.Request) string {
+	if u := options.EnvString("", "PUBLIC_BASE_URL", ""); u != "" {
+		return u
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	// Path of the feed, without /{slug}/feed.json
+	p := r.URL.Path
+	for i := 0; i < 2 && strings.Contains(p, "/"); i++ {
+		p = p[:strings.LastIndex(p, "/")]
+	}
+
+	return scheme + "://" + r.Host + p
+}
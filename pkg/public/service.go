@@ -0,0 +1,464 @@
+package public
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingRepository "github.com/cortezaproject/corteza-server/messaging/repository"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	accessController interface {
+		CanUpdateChannel(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+
+		// Attachments of these types and up to the size are served to visitors
+		attachmentTypes   map[string]bool
+		attachmentMaxSize int64
+
+		repository *repository
+	}
+
+	PublicService interface {
+		With(ctx context.Context) PublicService
+
+		Find() (PublicChannelSet, error)
+		Expose(slug string, channelID uint64, attachments bool) (*PublicChannel, error)
+		Withdraw(slug string) error
+
+		Channel(slug string, beforeID uint64, limit uint) (*Channel, error)
+		Feed(slug, baseURL string) (*Feed, error)
+		OpenAttachment(slug string, attachmentID uint64, preview bool) (*messagingTypes.Attachment, io.ReadSeeker, error)
+	}
+)
+
+var (
+	DefaultPublic PublicService
+
+	// now is used for exposure times and rate limits and can be overridden
+	now = time.Now
+
+	// Requests of visitors
+	visitors *limiter
+
+	// Types of messages visitors see
+	messageTypes = []string{
+		messagingTypes.MessageTypeSimpleMessage.String(),
+		messagingTypes.MessageTypeAttachment.String(),
+		messagingTypes.MessageTypeInlineImage.String(),
+	}
+)
+
+// Init initializes read-only access of visitors to public channels
+//
+// Visitors can make PUBLIC_RATE_LIMIT requests per PUBLIC_RATE_WINDOW
+// (0 disables the limit). Attachments of PUBLIC_ATTACHMENT_TYPES up to
+// PUBLIC_ATTACHMENT_MAX_SIZE bytes are served to them from channels
+// that allow it, other attachments are not listed.
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger:            log,
+		ac:                messagingService.DefaultAccessControl,
+		channels:          messagingService.DefaultChannel,
+		attachmentTypes:   map[string]bool{},
+		attachmentMaxSize: int64(options.EnvInt("", "PUBLIC_ATTACHMENT_MAX_SIZE", 10<<20)),
+	}
+
+	for _, t := range strings.Split(options.EnvString("", "PUBLIC_ATTACHMENT_TYPES", "image/jpeg,image/png,image/gif,application/pdf"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			svc.attachmentTypes[t] = true
+		}
+	}
+
+	visitors = &limiter{
+		limit:    options.EnvInt("", "PUBLIC_RATE_LIMIT", 120),
+		window:   options.EnvDuration("", "PUBLIC_RATE_WINDOW", time.Minute),
+		visitors: map[string]*visits{},
+	}
+
+	DefaultPublic = svc.With(ctx)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) PublicService {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+
+		attachmentTypes:   svc.attachmentTypes,
+		attachmentMaxSize: svc.attachmentMaxSize,
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns public channels that the current user can read
+func (svc service) Find() (PublicChannelSet, error) {
+	pp, err := svc.repository.Find()
+	if err != nil || len(pp) == 0 {
+		return PublicChannelSet{}, err
+	}
+
+	IDs := make([]uint64, len(pp))
+	for i, p := range pp {
+		IDs[i] = p.ChannelID
+	}
+
+	cc, _, err := svc.channels.Find(messagingTypes.ChannelFilter{ChannelID: IDs})
+	if err != nil {
+		return nil, err
+	}
+
+	out := PublicChannelSet{}
+	for _, p := range pp {
+		if cc.FindByID(p.ChannelID) != nil {
+			out = append(out, p)
+		}
+	}
+
+	return out, nil
+}
+
+// Expose makes the channel readable by visitors under the slug
+//
+// Exposed channel's slug and attachment access can be changed by
+// exposing it again. Group channels (direct messages) can not be exposed.
+func (svc service) Expose(slug string, channelID uint64, attachments bool) (*PublicChannel, error) {
+	if !slugRule.MatchString(slug) {
+		return nil, ErrInvalidSlug.withStack().WithMessage("slug can contain lowercase letters, digits and dashes only")
+	}
+
+	ch, err := svc.channels.FindByID(channelID)
+	if err != nil {
+		return nil, err
+	} else if !svc.ac.CanUpdateChannel(svc.ctx, ch) {
+		return nil, ErrNoPermissions.withStack().WithID("channelID", channelID)
+	} else if ch.Type == messagingTypes.ChannelTypeGroup {
+		return nil, ErrInvalidChannelType.withStack().WithMessage("direct messages can not be public")
+	}
+
+	if c, err := svc.repository.FindBySlug(slug); err != nil {
+		return nil, err
+	} else if c != nil && c.ChannelID != channelID {
+		return nil, ErrSlugTaken.withStack().WithMessage("slug is used by another channel")
+	}
+
+	if c, err := svc.repository.FindByChannelID(channelID); err != nil {
+		return nil, err
+	} else if c != nil && c.Slug != slug {
+		return nil, ErrAlreadyExposed.withStack().WithMessage("channel is public as " + c.Slug)
+	}
+
+	c := &PublicChannel{
+		Slug:        slug,
+		ChannelID:   channelID,
+		Attachments: attachments,
+		ExposedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+		ExposedAt:   now().Truncate(time.Second),
+	}
+
+	if err = svc.repository.Save(c); err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("channelID", channelID), zap.String("slug", slug)).Info("channel exposed to visitors")
+	return c, nil
+}
+
+// Withdraw stops exposing the channel to visitors
+func (svc service) Withdraw(slug string) error {
+	c, err := svc.repository.FindBySlug(slug)
+	if err != nil {
+		return err
+	} else if c == nil {
+		return ErrChannelNotFound.withStack()
+	}
+
+	// Channel may be deleted already, so it is not looked up with the
+	// service that would refuse it
+	ch, err := messagingRepository.Channel(svc.ctx, messagingRepository.DB(svc.ctx)).FindByID(c.ChannelID)
+	if err != nil {
+		return err
+	} else if !svc.ac.CanUpdateChannel(svc.ctx, ch) {
+		return ErrNoPermissions.withStack().WithID("channelID", c.ChannelID)
+	}
+
+	if err = svc.repository.Delete(slug); err != nil {
+		return err
+	}
+
+	svc.log(zap.Uint64("channelID", c.ChannelID), zap.String("slug", slug)).Info("channel withdrawn from visitors")
+	return nil
+}
+
+// Channel returns the public channel with its latest messages, before
+// the message when it is given
+func (svc service) Channel(slug string, beforeID uint64, limit uint) (*Channel, error) {
+	c, ch, err := svc.exposed(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = defaultLimit
+	} else if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	db := messagingRepository.DB(svc.ctx)
+
+	mm, _, err := messagingRepository.Message(svc.ctx, db).Find(messagingTypes.MessageFilter{
+		ChannelID: []uint64{ch.ID},
+		Type:      messageTypes,
+		BeforeID:  beforeID,
+		Limit:     limit,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Channel{Slug: c.Slug, Name: ch.Name, Topic: ch.Topic, Messages: MessageSet{}}
+	if len(mm) == 0 {
+		return out, nil
+	}
+
+	var (
+		names = map[uint64]string{}
+		atts  = map[uint64]*messagingTypes.Attachment{}
+	)
+
+	if c.Attachments {
+		aa, err := messagingRepository.Attachment(svc.ctx, db).FindAttachmentByMessageID(mm.IDs()...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, a := range aa {
+			if svc.safe(&a.Attachment) {
+				atts[a.MessageID] = &a.Attachment
+			}
+		}
+	}
+
+	if names, err = svc.names(mm); err != nil {
+		return nil, err
+	}
+
+	for _, m := range mm {
+		pm := &Message{
+			ID:        m.ID,
+			Author:    &Author{UserID: m.UserID, Name: names[m.UserID]},
+			Text:      plain(m.Message, names),
+			Replies:   m.Replies,
+			CreatedAt: m.CreatedAt,
+			UpdatedAt: m.UpdatedAt,
+		}
+
+		// Bots post under their own names
+		if m.Meta != nil && m.Meta.Username != "" {
+			pm.Author.Name = m.Meta.Username
+		}
+
+		if a := atts[m.ID]; a != nil {
+			pm.Attachment = &Attachment{
+				ID:       a.ID,
+				Name:     a.Name,
+				Mimetype: a.Meta.Original.Mimetype,
+				Size:     a.Meta.Original.Size,
+				Preview:  a.PreviewUrl != "",
+			}
+		}
+
+		out.Messages = append(out.Messages, pm)
+	}
+
+	return out, nil
+}
+
+// Feed returns the latest messages of the public channel as JSON Feed
+//
+// URLs of the feed and attachments are relative to the base URL, where
+// public channel endpoints are served.
+func (svc service) Feed(slug, baseURL string) (*Feed, error) {
+	c, err := svc.Channel(slug, 0, defaultLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		base = strings.TrimSuffix(baseURL, "/") + "/" + c.Slug
+		f    = &Feed{
+			Version:     feedVersion,
+			Title:       c.Name,
+			Description: c.Topic,
+			FeedURL:     base + "/feed.json",
+			Items:       make([]*FeedItem, len(c.Messages)),
+		}
+	)
+
+	for i, m := range c.Messages {
+		item := &FeedItem{
+			ID:            strconv.FormatUint(m.ID, 10),
+			ContentText:   m.Text,
+			DatePublished: m.CreatedAt,
+			DateModified:  m.UpdatedAt,
+		}
+
+		if m.Author.Name != "" {
+			item.Author = &FeedAuthor{Name: m.Author.Name}
+		}
+
+		if a := m.Attachment; a != nil {
+			item.Attachments = []*FeedAttachment{{
+				URL:         base + "/attachments/" + strconv.FormatUint(a.ID, 10),
+				MimeType:    a.Mimetype,
+				Title:       a.Name,
+				SizeInBytes: a.Size,
+			}}
+		}
+
+		f.Items[i] = item
+	}
+
+	return f, nil
+}
+
+// OpenAttachment returns public-safe attachment of the public channel's
+// message, its original or preview
+//
+// Attachments are opened with attachment service, so that its decorators
+// (quarantine) can refuse them.
+func (svc service) OpenAttachment(slug string, attachmentID uint64, preview bool) (*messagingTypes.Attachment, io.ReadSeeker, error) {
+	c, ch, err := svc.exposed(slug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !c.Attachments {
+		return nil, nil, ErrAttachmentNotFound.withStack()
+	}
+
+	a, err := svc.repository.Attachment(ch.ID, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	} else if a == nil || !svc.safe(a) || (preview && a.PreviewUrl == "") {
+		return nil, nil, ErrAttachmentNotFound.withStack()
+	}
+
+	var (
+		as = messagingService.DefaultAttachment.With(svc.ctx)
+		fh io.ReadSeeker
+	)
+
+	if preview {
+		fh, err = as.OpenPreview(a)
+	} else {
+		fh, err = as.OpenOriginal(a)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return a, fh, nil
+}
+
+// exposed returns public channel with the slug, when the channel
+// is neither archived nor deleted
+func (svc service) exposed(slug string) (*PublicChannel, *messagingTypes.Channel, error) {
+	c, err := svc.repository.FindBySlug(slug)
+	if err != nil {
+		return nil, nil, err
+	} else if c == nil {
+		return nil, nil, ErrChannelNotFound.withStack()
+	}
+
+	ch, err := messagingRepository.Channel(svc.ctx, messagingRepository.DB(svc.ctx)).FindByID(c.ChannelID)
+	if err == messagingRepository.ErrChannelNotFound || (err == nil && !ch.IsValid()) {
+		return nil, nil, ErrChannelNotFound.withStack()
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return c, ch, nil
+}
+
+// safe reports if attachment can be served to visitors
+func (svc service) safe(a *messagingTypes.Attachment) bool {
+	return svc.attachmentTypes[a.Meta.Original.Mimetype] && a.Meta.Original.Size <= svc.attachmentMaxSize
+}
+
+// names returns names of authors and mentioned users
+func (svc service) names(mm messagingTypes.MessageSet) (map[uint64]string, error) {
+	var (
+		IDs   []uint64
+		names = map[uint64]string{}
+	)
+
+	for _, m := range mm {
+		IDs = append(IDs, m.UserID)
+
+		for _, s := range mention.FindAllStringSubmatch(m.Message, -1) {
+			if ID, err := strconv.ParseUint(s[1], 10, 64); err == nil {
+				IDs = append(IDs, ID)
+			}
+		}
+	}
+
+	uu, err := svc.repository.Users(IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, u := range uu {
+		names[u.ID] = u.name()
+	}
+
+	return names, nil
+}
+
+// plain turns mentions into plain text, visitors can not follow them
+func plain(text string, names map[uint64]string) string {
+	return mention.ReplaceAllStringFunc(text, func(s string) string {
+		m := mention.FindStringSubmatch(s)
+		if m[3] != "" {
+			return "@" + m[3]
+		}
+
+		if ID, err := strconv.ParseUint(m[1], 10, 64); err == nil && names[ID] != "" {
+			return "@" + names[ID]
+		}
+
+		return "@user"
+	})
+}
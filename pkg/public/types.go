@@ -0,0 +1,133 @@
+package public
+
+import (
+	"regexp"
+	"time"
+)
+
+type (
+	// PublicChannel is a channel that visitors can read without signing in
+	PublicChannel struct {
+		Slug      string `json:"slug" db:"slug"`
+		ChannelID uint64 `json:"channelID,string" db:"rel_channel"`
+
+		// Public-safe attachments are served to visitors
+		Attachments bool `json:"attachments" db:"attachments"`
+
+		ExposedBy uint64    `json:"exposedBy,string" db:"exposed_by"`
+		ExposedAt time.Time `json:"exposedAt" db:"exposed_at"`
+	}
+
+	PublicChannelSet []*PublicChannel
+
+	// Channel as visitors see it, with the latest messages
+	Channel struct {
+		Slug     string     `json:"slug"`
+		Name     string     `json:"name"`
+		Topic    string     `json:"topic"`
+		Messages MessageSet `json:"messages"`
+	}
+
+	// Message as visitors see it
+	//
+	// Mentions are turned into plain text, replies are not included.
+	Message struct {
+		ID         uint64      `json:"messageID,string"`
+		Author     *Author     `json:"author"`
+		Text       string      `json:"text"`
+		Replies    uint        `json:"replies"`
+		Attachment *Attachment `json:"attachment,omitempty"`
+		CreatedAt  time.Time   `json:"createdAt"`
+		UpdatedAt  *time.Time  `json:"updatedAt,omitempty"`
+	}
+
+	MessageSet []*Message
+
+	// Author of the message, name only
+	Author struct {
+		UserID uint64 `json:"userID,string"`
+		Name   string `json:"name"`
+	}
+
+	// Attachment of the message, only public-safe ones are listed
+	Attachment struct {
+		ID       uint64 `json:"attachmentID,string"`
+		Name     string `json:"name"`
+		Mimetype string `json:"mimetype"`
+		Size     int64  `json:"size"`
+		Preview  bool   `json:"preview"`
+	}
+
+	// Feed is the channel in JSON Feed format (https://jsonfeed.org/version/1)
+	Feed struct {
+		Version     string      `json:"version"`
+		Title       string      `json:"title"`
+		Description string      `json:"description,omitempty"`
+		FeedURL     string      `json:"feed_url"`
+		Items       []*FeedItem `json:"items"`
+	}
+
+	FeedItem struct {
+		ID            string            `json:"id"`
+		ContentText   string            `json:"content_text"`
+		DatePublished time.Time         `json:"date_published"`
+		DateModified  *time.Time        `json:"date_modified,omitempty"`
+		Author        *FeedAuthor       `json:"author,omitempty"`
+		Attachments   []*FeedAttachment `json:"attachments,omitempty"`
+	}
+
+	FeedAuthor struct {
+		Name string `json:"name"`
+	}
+
+	FeedAttachment struct {
+		URL         string `json:"url"`
+		MimeType    string `json:"mime_type"`
+		Title       string `json:"title,omitempty"`
+		SizeInBytes int64  `json:"size_in_bytes"`
+	}
+
+	// user is the author, from system database
+	user struct {
+		ID     uint64 `db:"id"`
+		Name   string `db:"name"`
+		Handle string `db:"handle"`
+	}
+
+	userSet []*user
+)
+
+const (
+	feedVersion = "https://jsonfeed.org/version/1"
+
+	// Number of messages listed by default and at most
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+var (
+	slugRule = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,63}$`)
+
+	// Mentions of users, <@ID label>
+	mention = regexp.MustCompile(`<@(\d+)((?:\s)([^>]+))?>`)
+)
+
+// FindBySlug returns public channel with the slug, nil when there is none
+func (set PublicChannelSet) FindBySlug(slug string) *PublicChannel {
+	for _, c := range set {
+		if c.Slug == slug {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// name returns the name user is shown with, handle when the name is not set
+func (u user) name() string {
+	if u.Name != "" {
+		return u.Name
+	}
+
+	return u.Handle
+}
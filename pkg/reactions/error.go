@@ -0,0 +1,35 @@
+package reactions
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	reactionError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidReaction  = reactionError{"InvalidReaction", fault.Invalid}
+	ErrTooManyReactions = reactionError{"TooManyReactions", fault.TooMany}
+	ErrMessageNotFound  = reactionError{"MessageNotFound", fault.NotFound}
+	ErrNoPermissions    = reactionError{"NoPermissions", fault.Forbidden}
+)
+
+func (e reactionError) Error() string {
+	return e.String()
+}
+
+func (e reactionError) String() string {
+	return "crust.reactions." + e.name
+}
+
+func (e reactionError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e reactionError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,52 @@
+package reactions
+
+import (
+	"context"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+)
+
+type (
+	// message wraps message service and validates reactions
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with validation of reactions
+//
+// Corteza accepts any flag as a reaction (and pins the message when it is
+// empty) and checks if the user can react only when reaction is removed.
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) React(messageID uint64, reaction string) error {
+	reaction, err := normalize(reaction)
+	if err != nil {
+		return err
+	}
+
+	if err = defaultReaction.with(svc.ctx).canReact(messageID, reaction); err != nil {
+		return err
+	}
+
+	return svc.MessageService.React(messageID, reaction)
+}
+
+func (svc message) RemoveReaction(messageID uint64, reaction string) error {
+	reaction, err := normalize(reaction)
+	if err != nil {
+		return err
+	}
+
+	return svc.MessageService.RemoveReaction(messageID, reaction)
+}
@@ -0,0 +1,21 @@
+package reactions
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	// Reactions are stored as corteza's message flags; flags were utf8,
+	// most emoji could not be stored, and were compared case-insensitively
+	Migrations = migrations.Set{
+		{
+			Name: "20200222000000.reactions",
+			Up: `
+ALTER TABLE messaging_message_flag
+  MODIFY flag VARCHAR(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '';
+
+CREATE INDEX crust_lookup_reaction ON messaging_message_flag (rel_message, rel_user, flag);
+`,
+		},
+	}
+)
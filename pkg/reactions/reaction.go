@@ -0,0 +1,61 @@
+package reactions
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+const (
+	// Same as the size of the flag column
+	maxLength = 64
+)
+
+var (
+	// Custom emoji, :thumbsup: or :party_parrot:
+	shortcode = regexp.MustCompile(`^:[a-z0-9_+\-]+:$`)
+)
+
+// normalize trims the reaction and checks that it is an emoji
+//
+// Reaction is either a shortcode or a sequence of emoji runes, with
+// modifiers, joiners and keycaps. Pins and bookmarks are flags too,
+// they can not be reactions.
+func normalize(reaction string) (string, error) {
+	reaction = strings.TrimSpace(reaction)
+
+	switch {
+	case reaction == "",
+		utf8.RuneCountInString(reaction) > maxLength,
+		reaction == messagingTypes.MessageFlagPinnedToChannel,
+		reaction == messagingTypes.MessageFlagBookmarkedMessage:
+		return "", ErrInvalidReaction.withStack()
+
+	case shortcode.MatchString(reaction):
+		return reaction, nil
+	}
+
+	var emoji bool
+	for _, r := range reaction {
+		switch {
+		case r >= utf8.RuneSelf && unicode.In(r, unicode.So, unicode.Sk, unicode.Mn, unicode.Me, unicode.Cf):
+			// Symbols, skin tones, variation selectors, keycaps, joiners and tags
+			emoji = emoji || unicode.In(r, unicode.So, unicode.Me)
+
+		case r == '#' || r == '*' || (r >= '0' && r <= '9'):
+			// Keycap bases
+
+		default:
+			return "", ErrInvalidReaction.withStack()
+		}
+	}
+
+	if !emoji {
+		return "", ErrInvalidReaction.withStack()
+	}
+
+	return reaction, nil
+}
@@ -0,0 +1,118 @@
+package reactions
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+var (
+	// Flags that are not reactions
+	notReactions = []string{
+		messagingTypes.MessageFlagPinnedToChannel,
+		messagingTypes.MessageFlagBookmarkedMessage,
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "messaging_message_flag"
+}
+
+// Messages returns messages with their channels, deleted are omitted
+func (r repository) Messages(messageIDs ...uint64) (rr messageRowSet, err error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := squirrel.
+		Select("id", "rel_channel").
+		From("messaging_message").
+		Where(squirrel.Eq{"id": messageIDs, "deleted_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.db().Select(&rr, query, args...); err != nil {
+		return nil, errors.Wrap(err, "can not load messages")
+	}
+
+	return rr, nil
+}
+
+// Has checks if the message has the reaction, of any user
+func (r repository) Has(messageID uint64, reaction string) (has bool, err error) {
+	err = r.db().Get(&has, "SELECT EXISTS(SELECT 1 FROM "+r.table()+" WHERE rel_message = ? AND flag = ?)", messageID, reaction)
+	return has, errors.Wrap(err, "can not check reaction")
+}
+
+// Distinct returns number of different reactions of the message
+func (r repository) Distinct(messageID uint64) (n uint, err error) {
+	query, args, err := squirrel.
+		Select("COUNT(DISTINCT flag)").
+		From(r.table()).
+		Where(squirrel.Eq{"rel_message": messageID}).
+		Where(squirrel.NotEq{"flag": notReactions}).
+		ToSql()
+
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.db().Get(&n, query, args...)
+	return n, errors.Wrap(err, "can not count reactions")
+}
+
+// Counts returns reaction counts of the messages, ordered by the first reaction
+func (r repository) Counts(userID uint64, messageIDs ...uint64) (rr []*countRow, err error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := squirrel.
+		Select("rel_message", "flag", "COUNT(*) AS count").
+		Column("MAX(rel_user = ?) AS reacted", userID).
+		From(r.table()).
+		Where(squirrel.Eq{"rel_message": messageIDs}).
+		Where(squirrel.NotEq{"flag": notReactions}).
+		GroupBy("rel_message", "flag").
+		OrderBy("rel_message", "MIN(id)").
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.db().Select(&rr, query, args...); err != nil {
+		return nil, errors.Wrap(err, "can not count reactions")
+	}
+
+	return rr, nil
+}
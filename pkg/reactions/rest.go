@@ -0,0 +1,22 @@
+package reactions
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts reaction count endpoint
+//
+// Reactions are added and removed with corteza's message endpoints.
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Reaction counts of the messages: ?messageID=<ID>&messageID=<ID>
+	r.Get("/", rest.Handler("Reactions.List", func(r *http.Request) (interface{}, error) {
+		return DefaultReaction.With(r.Context()).Find(rest.QueryUint64s(r, "messageID")...)
+	}))
+}
@@ -0,0 +1,167 @@
+package reactions
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+)
+
+type (
+	accessController interface {
+		CanReactMessage(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+
+		repository *repository
+	}
+
+	ReactionService interface {
+		With(ctx context.Context) ReactionService
+
+		Find(messageIDs ...uint64) (SummarySet, error)
+	}
+)
+
+var (
+	DefaultReaction ReactionService
+
+	// used by service decorators
+	defaultReaction *service
+
+	// Number of different reactions a message can have
+	maxDistinct uint = 50
+)
+
+// Init initializes reaction counts and decorates message service with
+// validation of reactions
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	maxDistinct = uint(options.EnvInt("", "REACTIONS_MAX_DISTINCT", int(maxDistinct)))
+
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		channels: messagingService.DefaultChannel,
+	}
+
+	DefaultReaction = svc.With(ctx)
+	defaultReaction = svc.with(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) ReactionService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// Find returns reaction counts of messages in channels that the current
+// user can read; messages without reactions have none
+func (svc service) Find(messageIDs ...uint64) (SummarySet, error) {
+	mm, err := svc.readable(messageIDs...)
+	if err != nil || len(mm) == 0 {
+		return SummarySet{}, err
+	}
+
+	var (
+		out   = make(SummarySet, 0, len(mm))
+		index = map[uint64]*Summary{}
+	)
+
+	for _, m := range mm {
+		index[m.ID] = &Summary{MessageID: m.ID, Reactions: []*Count{}}
+		out = append(out, index[m.ID])
+	}
+
+	rr, err := svc.repository.Counts(auth.GetIdentityFromContext(svc.ctx).Identity(), mm.IDs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rr {
+		s := index[r.MessageID]
+		s.Reactions = append(s.Reactions, &Count{Reaction: r.Reaction, Count: r.Count, Reacted: r.Reacted})
+	}
+
+	return out, nil
+}
+
+// readable returns messages in channels that the current user can read
+func (svc service) readable(messageIDs ...uint64) (messageRowSet, error) {
+	mm, err := svc.repository.Messages(messageIDs...)
+	if err != nil || len(mm) == 0 {
+		return nil, err
+	}
+
+	cc, _, err := svc.channels.Find(messagingTypes.ChannelFilter{ChannelID: mm.ChannelIDs()})
+	if err != nil {
+		return nil, err
+	}
+
+	out := messageRowSet{}
+	for _, m := range mm {
+		if cc.FindByID(m.ChannelID) != nil {
+			out = append(out, m)
+		}
+	}
+
+	return out, nil
+}
+
+// canReact checks if the current user can react to the message and if it
+// can have the reaction
+func (svc service) canReact(messageID uint64, reaction string) error {
+	mm, err := svc.repository.Messages(messageID)
+	if err != nil {
+		return err
+	} else if len(mm) == 0 {
+		return ErrMessageNotFound.withStack().WithID("messageID", messageID)
+	}
+
+	ch, err := svc.channels.FindByID(mm[0].ChannelID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanReactMessage(svc.ctx, ch) {
+		return ErrNoPermissions.withStack().WithID("channelID", ch.ID)
+	}
+
+	if has, err := svc.repository.Has(messageID, reaction); err != nil || has {
+		return err
+	}
+
+	n, err := svc.repository.Distinct(messageID)
+	if err != nil {
+		return err
+	} else if n >= maxDistinct {
+		return ErrTooManyReactions.withStack().WithID("messageID", messageID)
+	}
+
+	return nil
+}
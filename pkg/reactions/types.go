@@ -0,0 +1,63 @@
+package reactions
+
+type (
+	// Summary holds reaction counts of a message
+	Summary struct {
+		MessageID uint64   `json:"messageID,string"`
+		Reactions []*Count `json:"reactions"`
+	}
+
+	SummarySet []*Summary
+
+	// Count of users that reacted to the message with the reaction
+	Count struct {
+		Reaction string `json:"reaction"`
+		Count    uint   `json:"count"`
+
+		// Current user is one of them
+		Reacted bool `json:"reacted"`
+	}
+
+	// countRow is a reaction count of a message, first reacted first
+	countRow struct {
+		MessageID uint64 `db:"rel_message"`
+		Reaction  string `db:"flag"`
+		Count     uint   `db:"count"`
+		Reacted   bool   `db:"reacted"`
+	}
+
+	// messageRow is a message with its channel
+	messageRow struct {
+		ID        uint64 `db:"id"`
+		ChannelID uint64 `db:"rel_channel"`
+	}
+
+	messageRowSet []*messageRow
+)
+
+// IDs returns IDs of the messages
+func (set messageRowSet) IDs() []uint64 {
+	out := make([]uint64, len(set))
+	for i, m := range set {
+		out[i] = m.ID
+	}
+
+	return out
+}
+
+// ChannelIDs returns IDs of channels of the messages
+func (set messageRowSet) ChannelIDs() []uint64 {
+	var (
+		out  = []uint64{}
+		seen = map[uint64]bool{}
+	)
+
+	for _, m := range set {
+		if !seen[m.ChannelID] {
+			seen[m.ChannelID] = true
+			out = append(out, m.ChannelID)
+		}
+	}
+
+	return out
+}
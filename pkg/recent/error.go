@@ -0,0 +1,33 @@
+package recent
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	recentError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidType       = recentError{"InvalidType", fault.Invalid}
+	ErrInvalidResourceID = recentError{"InvalidResourceID", fault.Invalid}
+)
+
+func (e recentError) Error() string {
+	return e.String()
+}
+
+func (e recentError) String() string {
+	return "crust.recent." + e.name
+}
+
+func (e recentError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e recentError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,29 @@
+package recent
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200125000000.recent",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_recent_item (
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  kind             VARCHAR(16)     NOT NULL,
+  rel_resource     BIGINT UNSIGNED NOT NULL,
+  refs             JSON            NOT NULL,
+
+  views            INT UNSIGNED    NOT NULL DEFAULT 0,
+  score            DOUBLE          NOT NULL DEFAULT 0,
+  last_viewed_at   DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_user, kind, rel_resource),
+  INDEX (rel_user, last_viewed_at),
+  INDEX (last_viewed_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
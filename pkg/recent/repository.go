@@ -0,0 +1,111 @@
+package recent
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_recent_item"
+}
+
+func (r repository) query(f ItemFilter) squirrel.SelectBuilder {
+	q := squirrel.
+		Select("rel_user", "kind", "rel_resource", "refs", "views", "score", "last_viewed_at").
+		From(r.table()).
+		Where(squirrel.Eq{"rel_user": f.UserID}).
+		Limit(uint64(f.Limit))
+
+	if len(f.Types) > 0 {
+		q = q.Where(squirrel.Eq{"kind": f.Types})
+	}
+
+	return q
+}
+
+// Recent returns user's items, most recently viewed first
+func (r repository) Recent(f ItemFilter) (set ItemSet, err error) {
+	q := r.query(f).OrderBy("last_viewed_at DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Frequent returns user's items with the highest score, decayed to the given time
+func (r repository) Frequent(f ItemFilter, at time.Time) (set ItemSet, err error) {
+	q := r.query(f).OrderByClause(
+		"score * POW(0.5, TIMESTAMPDIFF(SECOND, last_viewed_at, ?) / ?) DESC",
+		at, halfLife.Seconds(),
+	)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// View stores a view of the item
+//
+// Existing item's score is decayed to the time of the view before it is increased
+func (r repository) View(i *Item) error {
+	refs, err := i.Refs.Value()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db().Exec(
+		"INSERT INTO "+r.table()+" (rel_user, kind, rel_resource, refs, views, score, last_viewed_at) "+
+			"VALUES (?, ?, ?, ?, 1, 1, ?) "+
+			"ON DUPLICATE KEY UPDATE "+
+			"score = score * POW(0.5, GREATEST(TIMESTAMPDIFF(SECOND, last_viewed_at, VALUES(last_viewed_at)), 0) / ?) + 1, "+
+			"views = views + 1, "+
+			"refs = VALUES(refs), "+
+			"last_viewed_at = VALUES(last_viewed_at)",
+		i.UserID, i.Type, i.ResourceID, refs, i.LastViewedAt, halfLife.Seconds(),
+	)
+
+	return errors.WithStack(err)
+}
+
+func (r repository) Delete(userID uint64, t ItemType, resourceID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_user": userID, "kind": t, "rel_resource": resourceID})
+}
+
+// Clear removes all user's items of the given types (or all, when no types are given)
+func (r repository) Clear(userID uint64, tt ...ItemType) error {
+	cnd := squirrel.Eq{"rel_user": userID}
+	if len(tt) > 0 {
+		cnd["kind"] = tt
+	}
+
+	return rh.Delete(r.db(), r.table(), cnd)
+}
+
+// Prune removes items of all users that were not viewed since the given time
+func (r repository) Prune(before time.Time) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Lt{"last_viewed_at": before})
+}
@@ -0,0 +1,70 @@
+package recent
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts current user's recent & frequent items endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?types=record,page&limit=10
+	r.Get("/", rest.Handler("Recent.Recent", func(r *http.Request) (interface{}, error) {
+		return DefaultRecent.With(r.Context()).Recent(filter(r))
+	}))
+
+	// ?types=channel&limit=10
+	r.Get("/frequent", rest.Handler("Recent.Frequent", func(r *http.Request) (interface{}, error) {
+		return DefaultRecent.With(r.Context()).Frequent(filter(r))
+	}))
+
+	r.Post("/", rest.Handler("Recent.View", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Type       ItemType `json:"type"`
+			ResourceID uint64   `json:"resourceID,string"`
+			Refs       Refs     `json:"refs"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return resputil.OK(), DefaultRecent.With(r.Context()).View(body.Type, body.ResourceID, body.Refs)
+	}))
+
+	// ?types=record
+	r.Delete("/", rest.Handler("Recent.Clear", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultRecent.With(r.Context()).Clear(types(r)...)
+	}))
+
+	r.Delete("/{type}/{resourceID}", rest.Handler("Recent.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultRecent.With(r.Context()).Delete(
+			ItemType(chi.URLParam(r, "type")),
+			rest.ParamUint64(r, "resourceID"),
+		)
+	}))
+}
+
+func filter(r *http.Request) ItemFilter {
+	return ItemFilter{
+		Types: types(r),
+		Limit: rest.QueryUint(r, "limit"),
+	}
+}
+
+func types(r *http.Request) (tt []ItemType) {
+	for _, t := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tt = append(tt, ItemType(t))
+		}
+	}
+
+	return
+}
@@ -0,0 +1,172 @@
+package recent
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	recentService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		repository *repository
+	}
+
+	RecentService interface {
+		With(ctx context.Context) RecentService
+
+		View(t ItemType, resourceID uint64, refs Refs) error
+
+		Recent(ItemFilter) (ItemSet, error)
+		Frequent(ItemFilter) (ItemSet, error)
+
+		Delete(t ItemType, resourceID uint64) error
+		Clear(tt ...ItemType) error
+	}
+)
+
+var (
+	DefaultRecent RecentService
+
+	// now is used for views and decay and can be overridden
+	now = time.Now
+)
+
+// Init initializes recency service and starts removing old items in the background
+//
+// Items are kept per user and are not checked against permissions;
+// resources can be deleted or become inaccessible after they were viewed,
+// so clients need to resolve them through the owning app
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &recentService{
+		logger: log,
+	}
+
+	DefaultRecent = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc recentService) With(ctx context.Context) RecentService {
+	return &recentService{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// View records current user's view of the resource
+func (svc recentService) View(t ItemType, resourceID uint64, refs Refs) error {
+	if !t.IsValid() {
+		return ErrInvalidType.withStack()
+	}
+
+	if resourceID == 0 {
+		return ErrInvalidResourceID.withStack()
+	}
+
+	return svc.repository.View(&Item{
+		UserID:       auth.GetIdentityFromContext(svc.ctx).Identity(),
+		Type:         t,
+		ResourceID:   resourceID,
+		Refs:         refs,
+		LastViewedAt: now().Truncate(time.Second),
+	})
+}
+
+// Recent returns current user's items, most recently viewed first
+func (svc recentService) Recent(f ItemFilter) (ItemSet, error) {
+	if err := svc.normalize(&f); err != nil {
+		return nil, err
+	}
+
+	return svc.decayed(svc.repository.Recent(f))
+}
+
+// Frequent returns current user's items with the highest decay-weighted score first
+//
+// Each view adds 1 to the score, which then halves every week
+func (svc recentService) Frequent(f ItemFilter) (ItemSet, error) {
+	if err := svc.normalize(&f); err != nil {
+		return nil, err
+	}
+
+	return svc.decayed(svc.repository.Frequent(f, now()))
+}
+
+func (svc recentService) Delete(t ItemType, resourceID uint64) error {
+	if !t.IsValid() {
+		return ErrInvalidType.withStack()
+	}
+
+	return svc.repository.Delete(auth.GetIdentityFromContext(svc.ctx).Identity(), t, resourceID)
+}
+
+// Clear removes current user's items of the given types (or all, when no types are given)
+func (svc recentService) Clear(tt ...ItemType) error {
+	for _, t := range tt {
+		if !t.IsValid() {
+			return ErrInvalidType.withStack()
+		}
+	}
+
+	return svc.repository.Clear(auth.GetIdentityFromContext(svc.ctx).Identity(), tt...)
+}
+
+func (svc recentService) normalize(f *ItemFilter) error {
+	for _, t := range f.Types {
+		if !t.IsValid() {
+			return ErrInvalidType.withStack()
+		}
+	}
+
+	switch {
+	case f.Limit == 0:
+		f.Limit = defaultLimit
+	case f.Limit > maxLimit:
+		f.Limit = maxLimit
+	}
+
+	f.UserID = auth.GetIdentityFromContext(svc.ctx).Identity()
+	return nil
+}
+
+// decayed replaces stored scores with scores at the current time
+func (svc recentService) decayed(set ItemSet, err error) (ItemSet, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	n := now()
+	for _, i := range set {
+		i.Score = i.Frecency(n)
+	}
+
+	return set, nil
+}
+
+func (svc recentService) watch(ctx context.Context) {
+	t := time.NewTicker(pruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			err := Repository(ctx, factory.Database.MustGet("system").With(ctx)).Prune(now().Add(-retention))
+			if err != nil {
+				svc.logger.Error("could not remove old items", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package recent
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Item is a resource that user viewed, with view count and decayed score
+	Item struct {
+		UserID     uint64   `json:"-" db:"rel_user"`
+		Type       ItemType `json:"type" db:"kind"`
+		ResourceID uint64   `json:"resourceID,string" db:"rel_resource"`
+
+		// IDs of resources the item belongs to (namespaceID, moduleID, channelID...)
+		Refs Refs `json:"refs,omitempty" db:"refs"`
+
+		Views uint `json:"views" db:"views"`
+
+		// Score at the time of the last view; services return it decayed to the time of the query
+		Score float64 `json:"score" db:"score"`
+
+		LastViewedAt time.Time `json:"lastViewedAt" db:"last_viewed_at"`
+	}
+
+	ItemSet []*Item
+
+	ItemType string
+
+	ItemFilter struct {
+		UserID uint64     `json:"-"`
+		Types  []ItemType `json:"types,omitempty"`
+		Limit  uint       `json:"limit"`
+	}
+
+	Refs map[string]string
+)
+
+const (
+	ItemChannel ItemType = "channel"
+	ItemRecord  ItemType = "record"
+	ItemPage    ItemType = "page"
+
+	defaultLimit = 20
+	maxLimit     = 100
+
+	// Score of a view halves every week
+	halfLife = 7 * 24 * time.Hour
+
+	// Items not viewed for this long are removed
+	retention = 90 * 24 * time.Hour
+
+	// How often old items are removed
+	pruneInterval = time.Hour
+)
+
+func (t ItemType) IsValid() bool {
+	switch t {
+	case ItemChannel, ItemRecord, ItemPage:
+		return true
+	}
+
+	return false
+}
+
+// Frecency returns score of the item, decayed to the given time
+func (i Item) Frecency(at time.Time) float64 {
+	return decay(i.Score, at.Sub(i.LastViewedAt))
+}
+
+// decay returns score after exponential decay over the given period
+func decay(score float64, d time.Duration) float64 {
+	if d <= 0 {
+		return score
+	}
+
+	return score * math.Pow(0.5, float64(d)/float64(halfLife))
+}
+
+func (rr Refs) Value() (driver.Value, error) {
+	if rr == nil {
+		rr = Refs{}
+	}
+
+	return json.Marshal(rr)
+}
+
+func (rr *Refs) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*rr = Refs{}
+	case []byte:
+		if err := json.Unmarshal(b, rr); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Refs", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package records
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	recordsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidAggregate = recordsError{"InvalidAggregate", fault.Invalid}
+	ErrInvalidField     = recordsError{"InvalidField", fault.Invalid}
+	ErrNoPermissions    = recordsError{"NoPermissions", fault.Forbidden}
+)
+
+func (e recordsError) Error() string {
+	return e.String()
+}
+
+func (e recordsError) String() string {
+	return "crust.records." + e.name
+}
+
+func (e recordsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e recordsError) withStack() *fault.Error {
+	return fault.New(e)
+}
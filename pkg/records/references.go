@@ -0,0 +1,155 @@
+package records
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/crusttech/crust-server/pkg/access"
+)
+
+type (
+	// Reference is a record referenced from one of the record fields
+	Reference struct {
+		*types.Record
+
+		// Value of the field set as "labelField" in referencing field's options
+		Label string `json:"label,omitempty"`
+	}
+
+	// References holds referenced records, by record ID
+	References map[string]*Reference
+)
+
+const (
+	// Max number of records loaded with one filter
+	loadChunk = 100
+
+	// Resolves references in all record fields
+	allReferences = "*"
+)
+
+// references resolves records referenced from the given fields of records
+//
+// Records are loaded with one query per referenced module (per chunk of IDs),
+// regardless of the number of records and values. Fields that are not readable
+// and modules with unreadable records are skipped.
+func (svc recordService) references(m *types.Module, rr types.RecordSet, fields []string) (References, error) {
+	var (
+		out    = References{}
+		ids    = map[uint64][]uint64{}
+		labels = map[uint64]string{}
+		seen   = map[uint64]bool{}
+	)
+
+	ff, err := referenceFields(m, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range access.ReadableFields(svc.ctx, ff) {
+		moduleID := optionUint64(f, "moduleID")
+		if tm, err := svc.module.FindByID(m.NamespaceID, moduleID); err != nil || !svc.ac.CanReadRecord(svc.ctx, tm) {
+			continue
+		}
+
+		if label, ok := f.Options["labelField"].(string); ok {
+			labels[moduleID] = label
+		}
+
+		for _, r := range rr {
+			for _, v := range r.Values.FilterByName(f.Name) {
+				id, err := strconv.ParseUint(v.Value, 10, 64)
+				if err != nil || id == 0 || seen[id] {
+					continue
+				}
+
+				seen[id] = true
+				ids[moduleID] = append(ids[moduleID], id)
+			}
+		}
+	}
+
+	for moduleID, mids := range ids {
+		for len(mids) > 0 {
+			n := len(mids)
+			if n > loadChunk {
+				n = loadChunk
+			}
+
+			loaded, err := svc.load(m.NamespaceID, moduleID, mids[:n])
+			if err != nil {
+				return nil, err
+			}
+
+			for _, r := range loaded {
+				ref := &Reference{Record: r}
+				if vv := r.Values.FilterByName(labels[moduleID]); len(vv) > 0 {
+					ref.Label = vv[0].Value
+				}
+
+				out[strconv.FormatUint(r.ID, 10)] = ref
+			}
+
+			mids = mids[n:]
+		}
+	}
+
+	return out, nil
+}
+
+// load fetches records of the module by IDs
+func (svc recordService) load(namespaceID, moduleID uint64, ids []uint64) (types.RecordSet, error) {
+	cnd := make([]string, len(ids))
+	for i, id := range ids {
+		cnd[i] = fmt.Sprintf("id = %d", id)
+	}
+
+	rr, _, err := svc.record.Find(types.RecordFilter{
+		NamespaceID: namespaceID,
+		ModuleID:    moduleID,
+		Filter:      strings.Join(cnd, " OR "),
+	})
+
+	return rr, err
+}
+
+// referenceFields returns record fields by name; all record fields for "*"
+func referenceFields(m *types.Module, names []string) (ff types.ModuleFieldSet, err error) {
+	for _, name := range names {
+		if name == allReferences {
+			for _, f := range m.Fields {
+				if f.Kind == "Record" {
+					ff = append(ff, f)
+				}
+			}
+
+			continue
+		}
+
+		f := m.Fields.FindByName(name)
+		if f == nil || f.Kind != "Record" {
+			return nil, ErrInvalidField.withStack()
+		}
+
+		ff = append(ff, f)
+	}
+
+	return
+}
+
+func optionUint64(f *types.ModuleField, name string) (id uint64) {
+	if f.Options == nil {
+		return 0
+	}
+
+	switch v := f.Options[name].(type) {
+	case string:
+		fmt.Sscan(v, &id)
+	case float64:
+		id = uint64(v)
+	}
+
+	return
+}
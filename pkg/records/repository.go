@@ -0,0 +1,362 @@
+package records
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/ql"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+
+	// streamedRow is a record joined with one of its values
+	streamedRow struct {
+		types.Record
+
+		Name  sql.NullString `db:"name"`
+		Value sql.NullString `db:"value"`
+	}
+
+	currencyTotal struct {
+		Currency string  `db:"currency"`
+		Total    float64 `db:"total"`
+		Count    uint    `db:"count"`
+		Min      float64 `db:"min"`
+		Max      float64 `db:"max"`
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+// filtered builds a query that selects IDs of all module's records matching the filter
+//
+// Filter is translated the same way as in corteza's record repository
+func (r repository) filtered(m *types.Module, filter string) (query squirrel.SelectBuilder, err error) {
+	query = squirrel.
+		Select("r.id").
+		From("compose_record AS r").
+		Where("r.deleted_at IS NULL").
+		Where("r.module_id = ?", m.ID).
+		Where("r.rel_namespace = ?", m.NamespaceID)
+
+	if strings.TrimSpace(filter) == "" {
+		return
+	}
+
+	var (
+		fp = ql.NewParser()
+		fn ql.ASTNode
+
+		joined = map[string]bool{}
+	)
+
+	fp.OnIdent = func(i ql.Ident) (ql.Ident, error) {
+		var is bool
+		if i.Value, is = recordColumn(i.Value); is {
+			return i, nil
+		}
+
+		if !m.Fields.HasName(i.Value) {
+			return i, errors.Errorf("unknown field %q", i.Value)
+		}
+
+		if !joined[i.Value] {
+			joined[i.Value] = true
+			query = query.LeftJoin(fmt.Sprintf(
+				"compose_record_value AS rv_%s ON (rv_%s.record_id = r.id AND rv_%s.name = ? AND rv_%s.deleted_at IS NULL)",
+				i.Value, i.Value, i.Value, i.Value,
+			), i.Value)
+		}
+
+		i.Value = fmt.Sprintf("rv_%s.value", i.Value)
+		return i, nil
+	}
+
+	if fn, err = fp.ParseExpression(filter); err != nil {
+		return
+	}
+
+	filterSql, filterArgs, err := fn.ToSql()
+	if err != nil {
+		return
+	}
+
+	return query.Where("("+filterSql+")", filterArgs...), nil
+}
+
+// Stream returns iterator over all module's records that match the filter,
+// ordered by ID, with values of the given fields
+//
+// Records are joined with their values and read from a single DB cursor,
+// one record at a time; iteration stops when the context is canceled.
+// Filter is checked before the iterator is returned.
+func (r repository) Stream(m *types.Module, filter string, fields []string) (RecordIterator, error) {
+	ids, err := r.filtered(m, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	idsSql, idsArgs, err := ids.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	names, namesArgs, err := squirrel.Eq{"v.name": fields}.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	query, args, err := squirrel.
+		Select(
+			"r.id AS id",
+			"r.module_id AS module_id",
+			"r.rel_namespace AS rel_namespace",
+			"r.owned_by AS owned_by",
+			"r.created_at AS created_at",
+			"r.created_by AS created_by",
+			"r.updated_at AS updated_at",
+			"r.updated_by AS updated_by",
+			"v.name AS name",
+			"v.value AS value",
+		).
+		From("compose_record AS r").
+		LeftJoin("compose_record_value AS v ON (v.record_id = r.id AND v.deleted_at IS NULL AND "+names+")", namesArgs...).
+		Where("r.id IN ("+idsSql+")", idsArgs...).
+		OrderBy("r.id", "v.name", "v.place").
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(fn func(*types.Record) error) error {
+		rows, err := r.db().QueryxContext(r.ctx, query, args...)
+		if err != nil {
+			return errors.Wrap(err, "can not execute record stream query")
+		}
+
+		defer rows.Close()
+
+		var rec *types.Record
+
+		for rows.Next() {
+			row := &streamedRow{}
+			if err = rows.StructScan(row); err != nil {
+				return errors.Wrap(err, "can not scan streamed record")
+			}
+
+			if rec == nil || rec.ID != row.ID {
+				if rec != nil {
+					if err = fn(rec); err != nil {
+						return err
+					}
+				}
+
+				rec = &row.Record
+			}
+
+			if row.Name.Valid {
+				rec.Values = append(rec.Values, &types.RecordValue{RecordID: rec.ID, Name: row.Name.String, Value: row.Value.String})
+			}
+		}
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		if rec != nil {
+			return fn(rec)
+		}
+
+		return nil
+	}, nil
+}
+
+// Aggregate computes aggregates over values of all records that match the filter
+//
+// All aggregates are computed with a single query; multi-value fields
+// contribute each of their values.
+func (r repository) Aggregate(m *types.Module, filter string, aa AggregateSet) (Aggregates, error) {
+	ids, err := r.filtered(m, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	idsSql, idsArgs, err := ids.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		names = []string{}
+		q     = squirrel.
+			Select().
+			From("compose_record_value AS v").
+			Where("v.deleted_at IS NULL")
+	)
+
+	for i, a := range aa {
+		q = q.Column(fmt.Sprintf("%s AS a%d", aggregateExpr(m.Fields.FindByName(a.Field), a.Func), i), a.Field)
+		names = append(names, a.Field)
+	}
+
+	q = q.
+		Where(squirrel.Eq{"v.name": names}).
+		Where("v.record_id IN ("+idsSql+")", idsArgs...)
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db().Query(query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "can not execute aggregate query")
+	}
+
+	defer rows.Close()
+
+	var (
+		out = Aggregates{}
+		row = map[string]interface{}{}
+	)
+
+	if rows.Next() {
+		if err = sqlx.MapScan(rows, row); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, a := range aa {
+		out.set(a, castAggregate(m.Fields.FindByName(a.Field), a.Func, row[fmt.Sprintf("a%d", i)]))
+	}
+
+	return out, rows.Err()
+}
+
+// CurrencyTotals computes sum, count, min and max of currency field
+// values, by currency, over all records that match the filter
+func (r repository) CurrencyTotals(m *types.Module, filter, field string) (tt []*currencyTotal, err error) {
+	ids, err := r.filtered(m, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	idsSql, idsArgs, err := ids.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	const amount = "CAST(SUBSTRING_INDEX(v.value, ' ', 1) AS DECIMAL(65,10))"
+
+	q := squirrel.
+		Select(
+			"SUBSTRING_INDEX(v.value, ' ', -1) AS currency",
+			"SUM("+amount+") AS total",
+			"COUNT(*) AS count",
+			"MIN("+amount+") AS min",
+			"MAX("+amount+") AS max",
+		).
+		From("compose_record_value AS v").
+		Where("v.deleted_at IS NULL").
+		Where(squirrel.Eq{"v.name": field}).
+		Where(squirrel.NotEq{"v.value": ""}).
+		Where("v.record_id IN ("+idsSql+")", idsArgs...).
+		GroupBy("currency")
+
+	return tt, rh.FetchAll(r.db(), q, &tt)
+}
+
+// aggregateExpr returns SQL expression for aggregate function over field values
+//
+// Expects field name as the only argument
+func aggregateExpr(f *types.ModuleField, fn AggregateFunc) string {
+	var value string
+	switch {
+	case f.IsRef():
+		value = "v.ref"
+	case f.IsNumeric() || fn.IsNumeric():
+		value = "CAST(v.value AS DECIMAL(65,10))"
+	case f.IsDateTime():
+		value = "CAST(v.value AS DATETIME)"
+	default:
+		value = "v.value"
+	}
+
+	value = "CASE WHEN v.name = ? THEN " + value + " END"
+
+	switch fn {
+	case AggregateCountDistinct:
+		return "COUNT(DISTINCT " + value + ")"
+	default:
+		return strings.ToUpper(string(fn)) + "(" + value + ")"
+	}
+}
+
+// castAggregate converts raw aggregate value into number when possible
+func castAggregate(f *types.ModuleField, fn AggregateFunc, v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+
+	switch {
+	case fn == AggregateCount, fn == AggregateCountDistinct, fn.IsNumeric(), f.IsNumeric():
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	}
+
+	return s
+}
+
+// recordColumn translates record's property into a column name
+func recordColumn(name string) (string, bool) {
+	switch name {
+	case "recordID", "id":
+		return "r.id", true
+	case "ownedBy", "owned_by":
+		return "r.owned_by", true
+	case "createdBy", "created_by":
+		return "r.created_by", true
+	case "createdAt", "created_at":
+		return "r.created_at", true
+	case "updatedBy", "updated_by":
+		return "r.updated_by", true
+	case "updatedAt", "updated_at":
+		return "r.updated_at", true
+	}
+
+	return name, false
+}
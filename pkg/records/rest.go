@@ -0,0 +1,77 @@
+package records
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts record list endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {moduleID} params
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Same as corteza's record list with additional params:
+	//   ?aggregate=sum(amount),avg(amount),countDistinct(owner)&currency=EUR
+	//   &include=projects (related records from relation fields)
+	//   &incRelated=account,owner (referenced records from record fields, * for all)
+	r.Get("/", rest.Handler("Records.List", func(r *http.Request) (interface{}, error) {
+		aa, err := ParseAggregates(r.URL.Query().Get("aggregate"))
+		if err != nil {
+			return nil, err
+		}
+
+		return DefaultRecord.With(r.Context()).Find(types.RecordFilter{
+			NamespaceID: rest.ParamUint64(r, "namespaceID"),
+			ModuleID:    rest.ParamUint64(r, "moduleID"),
+			Filter:      r.URL.Query().Get("filter"),
+			Sort:        r.URL.Query().Get("sort"),
+
+			PageFilter: rh.Paging(rest.QueryUint(r, "page"), rest.QueryUint(r, "perPage")),
+		}, aa, r.URL.Query().Get("currency"), options(r))
+	}))
+
+	// All matching records as newline delimited JSON, ordered by ID:
+	//   ?filter=createdAt > '2020-01-01'
+	// Interrupted streams can be resumed with "recordID > <last ID>" filter
+	r.Get("/stream", rest.Handler("Records.Stream", func(r *http.Request) (interface{}, error) {
+		it, err := DefaultRecord.With(r.Context()).Stream(types.RecordFilter{
+			NamespaceID: rest.ParamUint64(r, "namespaceID"),
+			ModuleID:    rest.ParamUint64(r, "moduleID"),
+			Filter:      r.URL.Query().Get("filter"),
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return rest.Stream("Records.Stream", func(emit rest.Emitter) error {
+			return it(func(rec *types.Record) error {
+				return emit(rec)
+			})
+		}), nil
+	}))
+
+	// Single record with related and referenced records:
+	//   ?include=projects,members&incRelated=account
+	r.Get("/{recordID}", rest.Handler("Records.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultRecord.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recordID"),
+			options(r),
+		)
+	}))
+}
+
+func options(r *http.Request) Options {
+	return Options{
+		Include:    ParseFields(r.URL.Query().Get("include")),
+		IncRelated: ParseFields(r.URL.Query().Get("incRelated")),
+	}
+}
@@ -0,0 +1,357 @@
+package records
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/crusttech/crust-server/pkg/access"
+	currencyPkg "github.com/crusttech/crust-server/pkg/currency"
+	"github.com/crusttech/crust-server/pkg/hierarchy"
+	"github.com/crusttech/crust-server/pkg/relations"
+)
+
+type (
+	recordService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		module service.ModuleService
+		record service.RecordService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanReadRecord(context.Context, *types.Module) bool
+		CanUpdateRecord(context.Context, *types.Module) bool
+		CanDeleteRecord(context.Context, *types.Module) bool
+		CanReadRecordValue(context.Context, *types.ModuleField) bool
+	}
+
+	RecordService interface {
+		With(ctx context.Context) RecordService
+
+		FindByID(namespaceID, recordID uint64, opt Options) (*recordPayload, error)
+		Find(filter types.RecordFilter, aa AggregateSet, currency string, opt Options) (*Payload, error)
+		Stream(filter types.RecordFilter) (RecordIterator, error)
+	}
+)
+
+var (
+	DefaultRecord RecordService
+)
+
+// Init initializes record list extensions
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultRecord = (&recordService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		module: service.DefaultModule,
+		record: service.DefaultRecord,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc recordService) With(ctx context.Context) RecordService {
+	return &recordService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		module: svc.module.With(ctx),
+		record: svc.record.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// FindByID returns record with its related and referenced records
+func (svc recordService) FindByID(namespaceID, recordID uint64, opt Options) (*recordPayload, error) {
+	r, err := svc.record.FindByID(namespaceID, recordID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := svc.module.FindByID(namespaceID, r.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := svc.payload(m, types.RecordSet{r}, opt.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opt.IncRelated) > 0 {
+		if set[0].Referenced, err = svc.references(m, types.RecordSet{r}, opt.IncRelated); err != nil {
+			return nil, err
+		}
+	}
+
+	return set[0], nil
+}
+
+// Find returns one page of records and aggregates computed over all records
+// that match the filter
+//
+// Aggregates over currency fields are converted into the given currency
+// (or field's default currency) using today's exchange rates
+func (svc recordService) Find(filter types.RecordFilter, aa AggregateSet, currency string, opt Options) (out *Payload, err error) {
+	var (
+		m  *types.Module
+		rr types.RecordSet
+	)
+
+	if m, err = svc.module.FindByID(filter.NamespaceID, filter.ModuleID); err != nil {
+		return
+	}
+
+	if err = svc.checkAggregates(m, aa); err != nil {
+		return
+	}
+
+	out = &Payload{}
+	if rr, out.Filter, err = svc.record.Find(filter); err != nil {
+		return nil, err
+	}
+
+	if out.Set, err = svc.payload(m, rr, opt.Include); err != nil {
+		return nil, err
+	}
+
+	if len(opt.IncRelated) > 0 {
+		if out.Referenced, err = svc.references(m, rr, opt.IncRelated); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		plain    AggregateSet
+		monetary AggregateSet
+	)
+
+	for _, a := range aa {
+		if a.Func.IsMonetary() && currencyPkg.IsCurrency(m.Fields.FindByName(a.Field)) {
+			monetary = append(monetary, a)
+		} else {
+			plain = append(plain, a)
+		}
+	}
+
+	if len(plain) > 0 {
+		if out.Aggregates, err = svc.repository.Aggregate(m, filter.Filter, plain); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(monetary) > 0 {
+		if out.Aggregates == nil {
+			out.Aggregates = Aggregates{}
+		}
+
+		if err = svc.currencyAggregates(m, filter.Filter, monetary, currency, out); err != nil {
+			return nil, err
+		}
+	}
+
+	return
+}
+
+// Stream returns iterator over all records that match the filter
+//
+// Unlike Find, records are not paged nor loaded into memory; they are
+// ordered by ID and come with values of readable fields only.
+func (svc recordService) Stream(filter types.RecordFilter) (RecordIterator, error) {
+	m, err := svc.module.FindByID(filter.NamespaceID, filter.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanReadRecord(svc.ctx, m) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	var fields []string
+	for _, f := range access.ReadableFields(svc.ctx, m.Fields) {
+		fields = append(fields, f.Name)
+	}
+
+	return svc.repository.Stream(m, filter.Filter, fields)
+}
+
+// payload wraps records with permissions, tree positions and related records
+func (svc recordService) payload(m *types.Module, rr types.RecordSet, include []string) ([]*recordPayload, error) {
+	set := make([]*recordPayload, len(rr))
+	for i := range rr {
+		set[i] = &recordPayload{
+			Record:          rr[i],
+			CanUpdateRecord: svc.ac.CanUpdateRecord(svc.ctx, m),
+			CanDeleteRecord: svc.ac.CanDeleteRecord(svc.ctx, m),
+		}
+	}
+
+	if len(rr) == 0 {
+		return set, nil
+	}
+
+	if hierarchy.ParentField(m) != nil {
+		if err := svc.attachTree(set); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(include) > 0 {
+		related, err := relations.DefaultRelation.With(svc.ctx).Include(m, rr, include...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range set {
+			p.Related = related[p.ID]
+		}
+	}
+
+	return set, nil
+}
+
+// attachTree adds depth, position and parent of each record in module's tree
+func (svc recordService) attachTree(set []*recordPayload) error {
+	ids := make([]uint64, len(set))
+	for i := range set {
+		ids[i] = set[i].ID
+	}
+
+	nn, err := hierarchy.DefaultTree.With(svc.ctx).Nodes(ids...)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range set {
+		p.Tree = nn.FindByID(p.ID)
+	}
+
+	return nil
+}
+
+// currencyAggregates computes aggregates over currency fields
+//
+// Totals are computed per currency and then converted into the target currency
+func (svc recordService) currencyAggregates(m *types.Module, filter string, aa AggregateSet, currency string, out *Payload) error {
+	var totals = map[string][]*currencyTotal{}
+
+	out.Currencies = map[string]string{}
+
+	for _, a := range aa {
+		var (
+			f      = m.Fields.FindByName(a.Field)
+			target = strings.ToUpper(currency)
+		)
+
+		if target == "" {
+			target = currencyPkg.FieldCurrency(f)
+		}
+
+		out.Currencies[a.Field] = target
+
+		tt, ok := totals[a.Field]
+		if !ok {
+			var err error
+			if tt, err = svc.repository.CurrencyTotals(m, filter, a.Field); err != nil {
+				return err
+			}
+
+			totals[a.Field] = tt
+		}
+
+		var (
+			sum   float64
+			count uint
+			value interface{}
+		)
+
+		for _, t := range tt {
+			var (
+				conv = func(v float64) (float64, error) {
+					return currencyPkg.DefaultRate.With(svc.ctx).Convert(v, t.Currency, target, time.Now())
+				}
+
+				c   float64
+				err error
+			)
+
+			switch a.Func {
+			case AggregateSum, AggregateAvg:
+				if c, err = conv(t.Total); err != nil {
+					return err
+				}
+
+				sum += c
+				count += t.Count
+			case AggregateMin:
+				if c, err = conv(t.Min); err != nil {
+					return err
+				}
+
+				if value == nil || c < value.(float64) {
+					value = c
+				}
+			case AggregateMax:
+				if c, err = conv(t.Max); err != nil {
+					return err
+				}
+
+				if value == nil || c > value.(float64) {
+					value = c
+				}
+			}
+		}
+
+		switch a.Func {
+		case AggregateSum:
+			value = currencyPkg.Round(sum, target)
+		case AggregateAvg:
+			if count > 0 {
+				value = currencyPkg.Round(sum/float64(count), target)
+			}
+		}
+
+		out.Aggregates.set(a, value)
+	}
+
+	return nil
+}
+
+// checkAggregates verifies that fields exist, are readable and of the right type
+func (svc recordService) checkAggregates(m *types.Module, aa AggregateSet) error {
+	if len(aa) > 0 && !svc.ac.CanReadRecord(svc.ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	for _, a := range aa {
+		f := m.Fields.FindByName(a.Field)
+		if f == nil {
+			return ErrInvalidField.withStack()
+		}
+
+		if !svc.ac.CanReadRecordValue(svc.ctx, f) {
+			return ErrNoPermissions.withStack()
+		}
+
+		if a.Func.IsNumeric() && !f.IsNumeric() && !currencyPkg.IsCurrency(f) {
+			return ErrInvalidAggregate.withStack()
+		}
+	}
+
+	return nil
+}
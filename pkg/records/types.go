@@ -0,0 +1,155 @@
+package records
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/crusttech/crust-server/pkg/hierarchy"
+	"github.com/crusttech/crust-server/pkg/relations"
+)
+
+type (
+	// Aggregate is an aggregate function applied on one module field
+	Aggregate struct {
+		Func  AggregateFunc
+		Field string
+	}
+
+	AggregateSet []Aggregate
+
+	AggregateFunc string
+
+	// Aggregates holds computed aggregates, by field and by function
+	//
+	//   {"amount": {"sum": 1200, "avg": 400}, "owner": {"countDistinct": 2}}
+	Aggregates map[string]map[AggregateFunc]interface{}
+
+	// recordPayload mimics payload used by corteza's record list endpoint
+	recordPayload struct {
+		*types.Record
+
+		CanUpdateRecord bool `json:"canUpdateRecord"`
+		CanDeleteRecord bool `json:"canDeleteRecord"`
+
+		// Position of the record in module's tree (for modules with a tree field)
+		Tree *hierarchy.Node `json:"tree,omitempty"`
+
+		// Related records, by relation field (see include param)
+		Related relations.Includes `json:"related,omitempty"`
+
+		// Records referenced from record fields (see incRelated param)
+		Referenced References `json:"referenced,omitempty"`
+	}
+
+	// Payload is a record list, extended with aggregates
+	Payload struct {
+		Filter     types.RecordFilter `json:"filter"`
+		Set        []*recordPayload   `json:"set"`
+		Aggregates Aggregates         `json:"aggregates,omitempty"`
+
+		// Currency of aggregates over currency fields
+		Currencies map[string]string `json:"currencies,omitempty"`
+
+		// Records referenced from record fields of all records in the set
+		Referenced References `json:"referenced,omitempty"`
+	}
+
+	// RecordIterator calls fn for each record, stops on the first error
+	RecordIterator func(fn func(*types.Record) error) error
+
+	// Options control what is loaded with records
+	Options struct {
+		// Relation fields with related records to include
+		Include []string
+
+		// Record fields with referenced records to resolve ("*" for all)
+		IncRelated []string
+	}
+)
+
+const (
+	AggregateSum           AggregateFunc = "sum"
+	AggregateAvg           AggregateFunc = "avg"
+	AggregateMin           AggregateFunc = "min"
+	AggregateMax           AggregateFunc = "max"
+	AggregateCount         AggregateFunc = "count"
+	AggregateCountDistinct AggregateFunc = "countDistinct"
+)
+
+var (
+	aggregateMatcher = regexp.MustCompile(`^\s*(\w+)\s*\(\s*(\w+)\s*\)\s*$`)
+)
+
+// ParseFields parses comma separated list of field names
+func ParseFields(s string) (ff []string) {
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			ff = append(ff, f)
+		}
+	}
+
+	return
+}
+
+// ParseAggregates parses comma separated list of aggregates
+//
+// Expected format is func(field), for example: sum(amount),countDistinct(owner)
+func ParseAggregates(s string) (aa AggregateSet, err error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	for _, part := range strings.Split(s, ",") {
+		m := aggregateMatcher.FindStringSubmatch(part)
+		if m == nil {
+			return nil, ErrInvalidAggregate.withStack()
+		}
+
+		a := Aggregate{Func: normalizeFunc(m[1]), Field: m[2]}
+		if a.Func == "" {
+			return nil, ErrInvalidAggregate.withStack()
+		}
+
+		aa = append(aa, a)
+	}
+
+	return
+}
+
+func normalizeFunc(name string) AggregateFunc {
+	switch strings.ToLower(name) {
+	case "sum":
+		return AggregateSum
+	case "avg":
+		return AggregateAvg
+	case "min":
+		return AggregateMin
+	case "max":
+		return AggregateMax
+	case "count":
+		return AggregateCount
+	case "countdistinct":
+		return AggregateCountDistinct
+	}
+
+	return ""
+}
+
+// IsNumeric returns true if function works on numeric fields only
+func (f AggregateFunc) IsNumeric() bool {
+	return f == AggregateSum || f == AggregateAvg
+}
+
+// IsMonetary returns true if function result on currency fields is an amount
+func (f AggregateFunc) IsMonetary() bool {
+	return f == AggregateSum || f == AggregateAvg || f == AggregateMin || f == AggregateMax
+}
+
+func (aa Aggregates) set(a Aggregate, v interface{}) {
+	if aa[a.Field] == nil {
+		aa[a.Field] = map[AggregateFunc]interface{}{}
+	}
+
+	aa[a.Field][a.Func] = v
+}
@@ -0,0 +1,40 @@
+package recurrence
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	recurrenceError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID          = recurrenceError{"InvalidID", fault.Invalid}
+	ErrNameRequired       = recurrenceError{"NameRequired", fault.Invalid}
+	ErrInvalidRule        = recurrenceError{"InvalidRule", fault.Invalid}
+	ErrInvalidTimezone    = recurrenceError{"InvalidTimezone", fault.Invalid}
+	ErrInvalidSkipDate    = recurrenceError{"InvalidSkipDate", fault.Invalid}
+	ErrInvalidSkipPolicy  = recurrenceError{"InvalidSkipPolicy", fault.Invalid}
+	ErrTemplateMismatch   = recurrenceError{"TemplateMismatch", fault.Invalid}
+	ErrNoPermissions      = recurrenceError{"NoPermissions", fault.Forbidden}
+	ErrRecurrenceNotFound = recurrenceError{"RecurrenceNotFound", fault.NotFound}
+)
+
+func (e recurrenceError) Error() string {
+	return e.String()
+}
+
+func (e recurrenceError) String() string {
+	return "crust.recurrence." + e.name
+}
+
+func (e recurrenceError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e recurrenceError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,45 @@
+package recurrence
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200120000000.recurrences",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_record_recurrence (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  rel_module       BIGINT UNSIGNED NOT NULL,
+  rel_template     BIGINT UNSIGNED NOT NULL,
+  name             VARCHAR(64)     NOT NULL,
+
+  rrule            VARCHAR(512)    NOT NULL,
+  starts_at        DATETIME        NOT NULL,
+  timezone         VARCHAR(64)     NOT NULL DEFAULT '',
+
+  skip_dates       JSON            NOT NULL,
+  skip_weekends    BOOLEAN         NOT NULL DEFAULT FALSE,
+  on_skip          VARCHAR(16)     NOT NULL DEFAULT 'skip',
+
+  enabled          BOOLEAN         NOT NULL DEFAULT TRUE,
+  occurrences      INT UNSIGNED    NOT NULL DEFAULT 0,
+  last_occurrence  DATETIME            NULL DEFAULT NULL,
+  next_occurrence  DATETIME            NULL DEFAULT NULL,
+  next_run_at      DATETIME            NULL DEFAULT NULL,
+
+  owned_by         BIGINT UNSIGNED NOT NULL,
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       DATETIME            NULL DEFAULT NULL,
+  deleted_at       DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace, rel_module),
+  INDEX (next_run_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
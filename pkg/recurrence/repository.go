@@ -0,0 +1,149 @@
+package recurrence
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_record_recurrence"
+}
+
+func (r repository) columns() []string {
+	return []string{
+		"id",
+		"rel_namespace",
+		"rel_module",
+		"rel_template",
+		"name",
+		"rrule",
+		"starts_at",
+		"timezone",
+		"skip_dates",
+		"skip_weekends",
+		"on_skip",
+		"enabled",
+		"occurrences",
+		"last_occurrence",
+		"next_occurrence",
+		"next_run_at",
+		"owned_by",
+		"created_at",
+		"updated_at",
+		"deleted_at",
+	}
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(r.columns()...).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(namespaceID, recurrenceID uint64) (*Recurrence, error) {
+	var (
+		rec = &Recurrence{}
+		q   = r.query().Where(squirrel.Eq{"id": recurrenceID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, rec); err != nil {
+		return nil, err
+	} else if rec.ID == 0 {
+		return nil, ErrRecurrenceNotFound.withStack().WithID("namespaceID", namespaceID).WithID("recurrenceID", recurrenceID)
+	}
+
+	return rec, nil
+}
+
+func (r repository) Find(f RecurrenceFilter) (set RecurrenceSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": f.NamespaceID, "rel_module": f.ModuleID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindDue returns all enabled recurrences that should run
+func (r repository) FindDue(now time.Time) (set RecurrenceSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"enabled": true}).
+		Where(squirrel.LtOrEq{"next_run_at": now}).
+		OrderBy("next_run_at")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(rec *Recurrence) (*Recurrence, error) {
+	rec.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&rec.CreatedAt)
+
+	return rec, errors.WithStack(r.db().Insert(r.table(), rec))
+}
+
+func (r repository) Update(rec *Recurrence) (*Recurrence, error) {
+	rh.SetCurrentTimeRounded(&rec.UpdatedAt)
+
+	return rec, errors.WithStack(r.db().Replace(r.table(), rec))
+}
+
+// Advance moves recurrence to the next occurrence
+//
+// Update is conditional on the current next_run_at so that only one of
+// the (possibly many) server instances processes each occurrence.
+// Returns false if recurrence was already advanced.
+func (r repository) Advance(rec *Recurrence, runAt time.Time) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.table()+" SET occurrences = ?, last_occurrence = ?, next_occurrence = ?, next_run_at = ? WHERE id = ? AND next_run_at = ?",
+		rec.Occurrences,
+		rec.LastOccurrence,
+		rec.NextOccurrence,
+		rec.NextRunAt,
+		rec.ID,
+		runAt,
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r repository) DeleteByID(namespaceID, recurrenceID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": recurrenceID, "rel_namespace": namespaceID},
+	)
+}
@@ -0,0 +1,70 @@
+package recurrence
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts record recurrence endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {moduleID} params
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("RecordRecurrence.List", func(r *http.Request) (interface{}, error) {
+		return DefaultRecurrence.With(r.Context()).Find(RecurrenceFilter{
+			NamespaceID: rest.ParamUint64(r, "namespaceID"),
+			ModuleID:    rest.ParamUint64(r, "moduleID"),
+		})
+	}))
+
+	r.Post("/", rest.Handler("RecordRecurrence.Create", func(r *http.Request) (interface{}, error) {
+		rec := &Recurrence{}
+		if err := rest.Decode(r, rec); err != nil {
+			return nil, err
+		}
+
+		rec.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		rec.ModuleID = rest.ParamUint64(r, "moduleID")
+		return DefaultRecurrence.With(r.Context()).Create(rec)
+	}))
+
+	r.Get("/{recurrenceID}", rest.Handler("RecordRecurrence.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultRecurrence.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recurrenceID"),
+		)
+	}))
+
+	r.Put("/{recurrenceID}", rest.Handler("RecordRecurrence.Update", func(r *http.Request) (interface{}, error) {
+		rec := &Recurrence{}
+		if err := rest.Decode(r, rec); err != nil {
+			return nil, err
+		}
+
+		rec.ID = rest.ParamUint64(r, "recurrenceID")
+		rec.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultRecurrence.With(r.Context()).Update(rec)
+	}))
+
+	r.Delete("/{recurrenceID}", rest.Handler("RecordRecurrence.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultRecurrence.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recurrenceID"),
+		)
+	}))
+
+	// ?count=10
+	r.Get("/{recurrenceID}/preview", rest.Handler("RecordRecurrence.Preview", func(r *http.Request) (interface{}, error) {
+		return DefaultRecurrence.With(r.Context()).Preview(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recurrenceID"),
+			int(rest.QueryUint(r, "count")),
+		)
+	}))
+}
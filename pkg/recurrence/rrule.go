@@ -0,0 +1,366 @@
+package recurrence
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// RRule is a subset of RFC 5545 recurrence rule
+	//
+	// Supported parts: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL,
+	// BYDAY (with ordinals for monthly & yearly rules, 1MO, -1FR), BYMONTHDAY
+	// (negative values count from the end of month), BYMONTH, COUNT and UNTIL.
+	RRule struct {
+		Freq       string
+		Interval   int
+		ByDay      []byDay
+		ByMonthDay []int
+		ByMonth    []int
+		Count      int
+		Until      *time.Time
+	}
+
+	byDay struct {
+		// Ordinal; 0 means every such weekday in the period
+		n   int
+		day time.Weekday
+	}
+)
+
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+
+	// Safety net for rules that (almost) never match, like BYMONTHDAY=31;BYMONTH=2
+	maxPeriods = 10000
+)
+
+var (
+	weekdays = map[string]time.Weekday{
+		"SU": time.Sunday,
+		"MO": time.Monday,
+		"TU": time.Tuesday,
+		"WE": time.Wednesday,
+		"TH": time.Thursday,
+		"FR": time.Friday,
+		"SA": time.Saturday,
+	}
+)
+
+// ParseRRule parses recurrence rule ("FREQ=MONTHLY;BYMONTHDAY=1;COUNT=12")
+//
+// Optional "RRULE:" prefix is ignored
+func ParseRRule(s string) (*RRule, error) {
+	var (
+		r   = &RRule{Interval: 1}
+		err error
+	)
+
+	s = strings.TrimPrefix(strings.TrimSpace(strings.ToUpper(s)), "RRULE:")
+	if s == "" {
+		return nil, errors.New("empty recurrence rule")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("invalid recurrence rule part %q", part)
+		}
+
+		switch kv[0] {
+		case "FREQ":
+			switch kv[1] {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				r.Freq = kv[1]
+			default:
+				return nil, errors.Errorf("unsupported frequency %q", kv[1])
+			}
+
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(kv[1]); err != nil || r.Interval < 1 {
+				return nil, errors.Errorf("invalid interval %q", kv[1])
+			}
+
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(kv[1]); err != nil || r.Count < 1 {
+				return nil, errors.Errorf("invalid count %q", kv[1])
+			}
+
+		case "UNTIL":
+			var u time.Time
+			for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+				if u, err = time.Parse(layout, kv[1]); err == nil {
+					break
+				}
+			}
+
+			if err != nil {
+				return nil, errors.Errorf("invalid until %q", kv[1])
+			}
+
+			r.Until = &u
+
+		case "BYDAY":
+			for _, d := range strings.Split(kv[1], ",") {
+				if len(d) < 2 {
+					return nil, errors.Errorf("invalid weekday %q", d)
+				}
+
+				bd := byDay{}
+				if wd, ok := weekdays[d[len(d)-2:]]; ok {
+					bd.day = wd
+				} else {
+					return nil, errors.Errorf("invalid weekday %q", d)
+				}
+
+				if o := d[:len(d)-2]; o != "" {
+					if bd.n, err = strconv.Atoi(o); err != nil || bd.n == 0 || bd.n > 53 || bd.n < -53 {
+						return nil, errors.Errorf("invalid weekday %q", d)
+					}
+				}
+
+				r.ByDay = append(r.ByDay, bd)
+			}
+
+		case "BYMONTHDAY":
+			if r.ByMonthDay, err = parseInts(kv[1], -31, 31); err != nil {
+				return nil, err
+			}
+
+		case "BYMONTH":
+			if r.ByMonth, err = parseInts(kv[1], 1, 12); err != nil {
+				return nil, err
+			}
+
+		default:
+			return nil, errors.Errorf("unsupported recurrence rule part %q", kv[0])
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, errors.New("recurrence rule frequency missing")
+	}
+
+	if r.Count > 0 && r.Until != nil {
+		return nil, errors.New("recurrence rule can not have both count and until")
+	}
+
+	return r, nil
+}
+
+func parseInts(s string, min, max int) (ii []int, err error) {
+	for _, p := range strings.Split(s, ",") {
+		i, err := strconv.Atoi(p)
+		if err != nil || i == 0 || i < min || i > max {
+			return nil, errors.Errorf("invalid value %q", p)
+		}
+
+		ii = append(ii, i)
+	}
+
+	return
+}
+
+// Next returns the first occurrence after the given time
+//
+// Occurrences are generated from start (DTSTART); time of day of all
+// occurrences is the same as start's. Returns false when there
+// are no more occurrences.
+func (r RRule) Next(start, after time.Time) (time.Time, bool) {
+	var found time.Time
+
+	r.iterate(start, func(t time.Time) bool {
+		if t.After(after) {
+			found = t
+			return false
+		}
+
+		return true
+	})
+
+	return found, !found.IsZero()
+}
+
+// iterate calls fn with each occurrence, in order, until fn returns false
+func (r RRule) iterate(start time.Time, fn func(time.Time) bool) {
+	var count int
+
+	for p := 0; p < maxPeriods; p++ {
+		for _, t := range r.candidates(start, p*r.Interval) {
+			if t.Before(start) {
+				continue
+			}
+
+			if r.Until != nil && t.After(*r.Until) {
+				return
+			}
+
+			count++
+			if r.Count > 0 && count > r.Count {
+				return
+			}
+
+			if !fn(t) {
+				return
+			}
+		}
+	}
+}
+
+// candidates returns sorted occurrences within the n-th period from start
+func (r RRule) candidates(start time.Time, n int) (tt []time.Time) {
+	var (
+		y, m, d = start.Date()
+		h, i, s = start.Clock()
+		loc     = start.Location()
+
+		at = func(y int, m time.Month, d int) time.Time {
+			return time.Date(y, m, d, h, i, s, 0, loc)
+		}
+	)
+
+	switch r.Freq {
+	case FreqDaily:
+		t := at(y, m, d+n)
+		if r.matchMonth(t) && r.matchMonthDay(t) && r.matchWeekday(t) {
+			tt = append(tt, t)
+		}
+
+	case FreqWeekly:
+		// Weeks start on monday
+		monday := at(y, m, d-(int(start.Weekday())+6)%7+n*7)
+
+		for i := 0; i < 7; i++ {
+			t := monday.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && t.Weekday() != start.Weekday() {
+				continue
+			}
+
+			if r.matchWeekday(t) && r.matchMonth(t) {
+				tt = append(tt, t)
+			}
+		}
+
+	case FreqMonthly:
+		first := at(y, m+time.Month(n), 1)
+		if r.matchMonth(first) {
+			tt = r.inMonth(first, d)
+		}
+
+	case FreqYearly:
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []int{int(m)}
+		}
+
+		for _, month := range months {
+			tt = append(tt, r.inMonth(at(y+n, time.Month(month), 1), d)...)
+		}
+	}
+
+	sort.Slice(tt, func(i, j int) bool { return tt[i].Before(tt[j]) })
+	return
+}
+
+// inMonth returns occurrences in the month of the given (first) day
+func (r RRule) inMonth(first time.Time, defaultDay int) (tt []time.Time) {
+	var (
+		days = daysIn(first)
+	)
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if defaultDay <= days {
+			tt = append(tt, first.AddDate(0, 0, defaultDay-1))
+		}
+
+		return
+	}
+
+	for i := 0; i < days; i++ {
+		t := first.AddDate(0, 0, i)
+		if r.matchMonthDay(t) && r.matchWeekdayInMonth(t) {
+			tt = append(tt, t)
+		}
+	}
+
+	return
+}
+
+func (r RRule) matchMonth(t time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+
+	for _, m := range r.ByMonth {
+		if time.Month(m) == t.Month() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r RRule) matchMonthDay(t time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+
+	days := daysIn(t)
+	for _, d := range r.ByMonthDay {
+		if d == t.Day() || (d < 0 && days+d+1 == t.Day()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchWeekday matches weekday, ignoring ordinals
+func (r RRule) matchWeekday(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+
+	for _, bd := range r.ByDay {
+		if bd.day == t.Weekday() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchWeekdayInMonth matches weekday with ordinal (2nd monday, last friday)
+func (r RRule) matchWeekdayInMonth(t time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+
+	var (
+		nth     = (t.Day()-1)/7 + 1
+		nthLast = -((daysIn(t)-t.Day())/7 + 1)
+	)
+
+	for _, bd := range r.ByDay {
+		if bd.day != t.Weekday() {
+			continue
+		}
+
+		if bd.n == 0 || bd.n == nth || bd.n == nthLast {
+			return true
+		}
+	}
+
+	return false
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
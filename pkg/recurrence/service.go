@@ -0,0 +1,368 @@
+package recurrence
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/runas"
+	"github.com/crusttech/crust-server/pkg/templates"
+)
+
+type (
+	recurrenceService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		module service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanUpdateModule(context.Context, *types.Module) bool
+	}
+
+	RecurrenceService interface {
+		With(ctx context.Context) RecurrenceService
+
+		FindByID(namespaceID, recurrenceID uint64) (*Recurrence, error)
+		Find(RecurrenceFilter) (RecurrenceSet, error)
+		Create(*Recurrence) (*Recurrence, error)
+		Update(*Recurrence) (*Recurrence, error)
+		DeleteByID(namespaceID, recurrenceID uint64) error
+
+		Preview(namespaceID, recurrenceID uint64, count int) ([]time.Time, error)
+	}
+)
+
+const (
+	// How often due recurrences are checked
+	watchInterval = time.Minute
+
+	maxPreview = 100
+)
+
+var (
+	DefaultRecurrence RecurrenceService
+)
+
+// Init initializes recurrence service and starts creating records
+// for due recurrences in the background
+//
+// Must be called after templates are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &recurrenceService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		module: service.DefaultModule,
+	}
+
+	DefaultRecurrence = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc recurrenceService) With(ctx context.Context) RecurrenceService {
+	return &recurrenceService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		module: svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc recurrenceService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc recurrenceService) FindByID(namespaceID, recurrenceID uint64) (rec *Recurrence, err error) {
+	if recurrenceID == 0 {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	if rec, err = svc.repository.FindByID(namespaceID, recurrenceID); err != nil {
+		return
+	}
+
+	// Verifies if current user can read the module
+	if _, err = svc.module.FindByID(namespaceID, rec.ModuleID); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (svc recurrenceService) Find(f RecurrenceFilter) (RecurrenceSet, error) {
+	if _, err := svc.module.FindByID(f.NamespaceID, f.ModuleID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Find(f)
+}
+
+func (svc recurrenceService) Create(in *Recurrence) (*Recurrence, error) {
+	rule, err := svc.validate(in)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Recurrence{
+		NamespaceID:  in.NamespaceID,
+		ModuleID:     in.ModuleID,
+		TemplateID:   in.TemplateID,
+		Name:         in.Name,
+		RRule:        in.RRule,
+		StartsAt:     in.StartsAt,
+		Timezone:     in.Timezone,
+		SkipDates:    in.SkipDates,
+		SkipWeekends: in.SkipWeekends,
+		OnSkip:       in.OnSkip,
+		Enabled:      in.Enabled,
+		OwnedBy:      auth.GetIdentityFromContext(svc.ctx).Identity(),
+	}
+
+	rec.reschedule(rule)
+
+	return svc.repository.Create(rec)
+}
+
+func (svc recurrenceService) Update(upd *Recurrence) (*Recurrence, error) {
+	rec, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Module can not be changed
+	upd.ModuleID = rec.ModuleID
+
+	rule, err := svc.validate(upd)
+	if err != nil {
+		return nil, err
+	}
+
+	rec.TemplateID = upd.TemplateID
+	rec.Name = upd.Name
+	rec.RRule = upd.RRule
+	rec.StartsAt = upd.StartsAt
+	rec.Timezone = upd.Timezone
+	rec.SkipDates = upd.SkipDates
+	rec.SkipWeekends = upd.SkipWeekends
+	rec.OnSkip = upd.OnSkip
+	rec.Enabled = upd.Enabled
+
+	rec.reschedule(rule)
+
+	return svc.repository.Update(rec)
+}
+
+func (svc recurrenceService) DeleteByID(namespaceID, recurrenceID uint64) error {
+	rec, err := svc.repository.FindByID(namespaceID, recurrenceID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.canManage(namespaceID, rec.ModuleID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, recurrenceID)
+}
+
+// Preview returns times of the upcoming runs
+func (svc recurrenceService) Preview(namespaceID, recurrenceID uint64, count int) (tt []time.Time, err error) {
+	rec, err := svc.FindByID(namespaceID, recurrenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, err := ParseRRule(rec.RRule)
+	if err != nil {
+		return nil, ErrInvalidRule.withStack()
+	}
+
+	if count <= 0 || count > maxPreview {
+		count = maxPreview
+	}
+
+	tt = []time.Time{}
+	for after := rec.after(); len(tt) < count; {
+		occurrence, runAt, ok := rec.schedule(rule, after)
+		if !ok {
+			break
+		}
+
+		tt = append(tt, runAt)
+		after = occurrence
+	}
+
+	return
+}
+
+func (svc recurrenceService) canManage(namespaceID, moduleID uint64) error {
+	m, err := svc.module.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+func (svc recurrenceService) validate(rec *Recurrence) (*RRule, error) {
+	if rec.NamespaceID == 0 {
+		return nil, service.ErrNamespaceRequired
+	}
+
+	if rec.Name == "" {
+		return nil, ErrNameRequired.withStack()
+	}
+
+	if err := svc.canManage(rec.NamespaceID, rec.ModuleID); err != nil {
+		return nil, err
+	}
+
+	rule, err := ParseRRule(rec.RRule)
+	if err != nil {
+		return nil, ErrInvalidRule.withStack()
+	}
+
+	if _, err = time.LoadLocation(rec.Timezone); err != nil {
+		return nil, ErrInvalidTimezone.withStack()
+	}
+
+	if rec.OnSkip == "" {
+		rec.OnSkip = SkipPolicyNone
+	} else if !rec.OnSkip.IsValid() {
+		return nil, ErrInvalidSkipPolicy.withStack()
+	}
+
+	if err = rec.SkipDates.validate(); err != nil {
+		return nil, err
+	}
+
+	if rec.StartsAt.IsZero() {
+		rec.StartsAt = time.Now()
+	}
+
+	t, err := templates.DefaultTemplate.With(svc.ctx).FindByID(rec.NamespaceID, rec.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.ModuleID != rec.ModuleID {
+		return nil, ErrTemplateMismatch.withStack()
+	}
+
+	return rule, nil
+}
+
+// after returns time after which next occurrence is searched for
+func (rec Recurrence) after() time.Time {
+	if rec.LastOccurrence != nil {
+		return *rec.LastOccurrence
+	}
+
+	// Start itself is the first occurrence
+	return rec.StartsAt.Add(-time.Second)
+}
+
+// reschedule computes next occurrence from the last processed one
+func (rec *Recurrence) reschedule(rule *RRule) {
+	rec.NextOccurrence, rec.NextRunAt = nil, nil
+
+	if occurrence, runAt, ok := rec.schedule(rule, rec.after()); ok {
+		rec.NextOccurrence, rec.NextRunAt = &occurrence, &runAt
+	}
+}
+
+// watch periodically creates records for due recurrences
+func (svc recurrenceService) watch(ctx context.Context) {
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.runDue(ctx)
+		}
+	}
+}
+
+func (svc recurrenceService) runDue(ctx context.Context) {
+	repo := Repository(ctx, factory.Database.MustGet("compose").With(ctx))
+
+	set, err := repo.FindDue(time.Now())
+	if err != nil {
+		svc.logger.Error("could not load due recurrences", zap.Error(err))
+		return
+	}
+
+	for _, rec := range set {
+		if err = svc.run(ctx, repo, rec); err != nil {
+			svc.logger.Error("could not create recurring record", zap.Uint64("recurrenceID", rec.ID), zap.Error(err))
+		}
+	}
+}
+
+// run advances recurrence to the next occurrence and creates the record
+//
+// Recurrence is advanced first; if record creation fails, occurrence is
+// not retried (to avoid creating duplicates on every tick).
+func (svc recurrenceService) run(ctx context.Context, repo *repository, rec *Recurrence) error {
+	rule, err := ParseRRule(rec.RRule)
+	if err != nil {
+		return err
+	}
+
+	var (
+		runAt      = *rec.NextRunAt
+		occurrence = *rec.NextOccurrence
+	)
+
+	rec.LastOccurrence = &occurrence
+	rec.Occurrences++
+	rec.reschedule(rule)
+
+	if ok, err := repo.Advance(rec, runAt); err != nil || !ok {
+		// Error or another instance already handled this occurrence
+		return err
+	}
+
+	if ctx, err = runas.Compose(ctx, rec.OwnedBy); err != nil {
+		return err
+	}
+
+	r, err := templates.DefaultTemplate.With(ctx).CreateRecord(rec.NamespaceID, rec.TemplateID, nil)
+	if err != nil {
+		return err
+	}
+
+	svc.logger.Info("recurring record created",
+		zap.Uint64("recurrenceID", rec.ID),
+		zap.Uint64("recordID", r.ID),
+		zap.Time("occurrence", occurrence),
+	)
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package recurrence
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Recurrence creates records from a template on a schedule
+	Recurrence struct {
+		ID          uint64 `json:"recurrenceID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+		TemplateID  uint64 `json:"templateID,string" db:"rel_template"`
+
+		Name string `json:"name" db:"name"`
+
+		// Recurrence rule (RFC 5545), first occurrence and timezone
+		// in which occurrences are computed
+		RRule    string    `json:"rrule" db:"rrule"`
+		StartsAt time.Time `json:"startsAt" db:"starts_at"`
+		Timezone string    `json:"timezone" db:"timezone"`
+
+		// Holiday handling; occurrences on skipped dates (and weekends, when
+		// enabled) are handled according to OnSkip policy
+		SkipDates    dates      `json:"skipDates" db:"skip_dates"`
+		SkipWeekends bool       `json:"skipWeekends" db:"skip_weekends"`
+		OnSkip       SkipPolicy `json:"onSkip" db:"on_skip"`
+
+		Enabled bool `json:"enabled" db:"enabled"`
+
+		// Number of records created so far
+		Occurrences uint `json:"occurrences" db:"occurrences"`
+
+		// Last processed occurrence (as computed by the rule, before holiday handling)
+		LastOccurrence *time.Time `json:"lastOccurrence,omitempty" db:"last_occurrence"`
+
+		// Next occurrence and when it will be created (after holiday handling);
+		// nil when recurrence ended
+		NextOccurrence *time.Time `json:"nextOccurrence,omitempty" db:"next_occurrence"`
+		NextRunAt      *time.Time `json:"nextRunAt,omitempty" db:"next_run_at"`
+
+		// Records are created in the name of the owner
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	RecurrenceFilter struct {
+		NamespaceID uint64 `json:"namespaceID,string"`
+		ModuleID    uint64 `json:"moduleID,string"`
+	}
+
+	RecurrenceSet []*Recurrence
+
+	SkipPolicy string
+
+	// dates is a list of dates (YYYY-MM-DD), stored as JSON
+	dates []string
+)
+
+const (
+	// SkipPolicyNone drops occurrences on skipped dates
+	SkipPolicyNone SkipPolicy = "skip"
+
+	// SkipPolicyNext moves occurrence to the next non-skipped date
+	SkipPolicyNext SkipPolicy = "next"
+
+	// SkipPolicyPrevious moves occurrence to the previous non-skipped date
+	SkipPolicyPrevious SkipPolicy = "previous"
+
+	dateLayout = "2006-01-02"
+)
+
+func (p SkipPolicy) IsValid() bool {
+	switch p {
+	case SkipPolicyNone, SkipPolicyNext, SkipPolicyPrevious:
+		return true
+	}
+
+	return false
+}
+
+func (dd dates) Value() (driver.Value, error) {
+	if dd == nil {
+		dd = dates{}
+	}
+
+	return json.Marshal(dd)
+}
+
+func (dd *dates) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*dd = dates{}
+	case []byte:
+		if err := json.Unmarshal(b, dd); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into dates", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (dd dates) has(t time.Time) bool {
+	d := t.Format(dateLayout)
+	for _, s := range dd {
+		if s == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (dd dates) validate() error {
+	for _, s := range dd {
+		if _, err := time.Parse(dateLayout, s); err != nil {
+			return ErrInvalidSkipDate.withStack()
+		}
+	}
+
+	return nil
+}
+
+// location returns recurrence's timezone
+func (r Recurrence) location() *time.Location {
+	if loc, err := time.LoadLocation(r.Timezone); err == nil && r.Timezone != "" {
+		return loc
+	}
+
+	return time.UTC
+}
+
+func (r Recurrence) skipped(t time.Time) bool {
+	if r.SkipWeekends && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+		return true
+	}
+
+	return r.SkipDates.has(t)
+}
+
+// schedule computes the next occurrence after the given time and when it should run
+//
+// Returns false when there are no more occurrences
+func (r Recurrence) schedule(rule *RRule, after time.Time) (occurrence, runAt time.Time, ok bool) {
+	var (
+		loc   = r.location()
+		start = r.StartsAt.In(loc)
+	)
+
+	after = after.In(loc)
+
+	for i := 0; i < maxPeriods; i++ {
+		if occurrence, ok = rule.Next(start, after); !ok {
+			return
+		}
+
+		runAt = occurrence
+		if !r.skipped(runAt) {
+			return
+		}
+
+		switch r.OnSkip {
+		case SkipPolicyNext, SkipPolicyPrevious:
+			step := 1
+			if r.OnSkip == SkipPolicyPrevious {
+				step = -1
+			}
+
+			for j := 0; j < 366 && r.skipped(runAt); j++ {
+				runAt = runAt.AddDate(0, 0, step)
+			}
+
+			return
+		}
+
+		// Skip this occurrence and try the next one
+		after = occurrence
+	}
+
+	return occurrence, runAt, false
+}
@@ -0,0 +1,108 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// recorder buffers response, so that it can be redacted before it is sent
+	recorder struct {
+		header http.Header
+		status int
+		body   bytes.Buffer
+	}
+
+	// response of corteza's handler, decoded
+	response interface {
+		// found is false when there is no value in the response (errors)
+		found() bool
+	}
+
+	// Responses of system's user endpoints, as corteza encodes them
+	userResponse struct {
+		Response *types.User `json:"response"`
+	}
+
+	userSetResponse struct {
+		Response *struct {
+			Filter json.RawMessage `json:"filter"`
+			Set    types.UserSet   `json:"set"`
+		} `json:"response"`
+	}
+)
+
+var (
+	// Users are listed and read by system's user endpoints
+	usersPath = regexp.MustCompile(`^(/system)?/users/?$`)
+	userPath  = regexp.MustCompile(`^(/system)?/users/\d+/?$`)
+)
+
+// Middleware redacts users that system's user endpoints list and read
+//
+// Crust's handlers redact the values they return (see rest.Handler); responses
+// of corteza's handlers are decoded into the types they were encoded from,
+// redacted with the same rules and encoded again.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rsp response
+		switch {
+		case r.Method != http.MethodGet:
+			next.ServeHTTP(w, r)
+			return
+		case usersPath.MatchString(r.URL.Path):
+			rsp = &userSetResponse{}
+		case userPath.MatchString(r.URL.Path):
+			rsp = &userResponse{}
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &recorder{header: http.Header{}, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if rec.status == http.StatusOK && json.Unmarshal(rec.body.Bytes(), rsp) == nil && rsp.found() {
+			if b, err := json.Marshal(Value(r.Context(), rsp)); err == nil {
+				rec.body.Reset()
+				rec.body.Write(b)
+				rec.header.Del("Content-Length")
+			}
+		}
+
+		rec.flush(w)
+	})
+}
+
+func (rsp userResponse) found() bool {
+	return rsp.Response != nil
+}
+
+func (rsp userSetResponse) found() bool {
+	return rsp.Response != nil
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (rec *recorder) flush(w http.ResponseWriter) {
+	for k, vv := range rec.header {
+		w.Header()[k] = vv
+	}
+
+	w.WriteHeader(rec.status)
+	_, _ = w.Write(rec.body.Bytes())
+}
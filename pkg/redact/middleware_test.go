@@ -0,0 +1,116 @@
+package redact
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+func TestMiddleware(t *testing.T) {
+	var (
+		jane = &types.User{ID: 1, Email: "jane@example.com"}
+		john = &types.User{ID: 2, Email: "john@example.com"}
+
+		set = struct {
+			Filter types.UserFilter `json:"filter"`
+			Set    types.UserSet    `json:"set"`
+		}{types.UserFilter{Query: "j"}, types.UserSet{jane, john}}
+	)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		identity auth.Identifiable
+		rsp      interface{}
+		want     []string
+		unwanted []string
+	}{
+		{
+			name:     "list of users",
+			method:   http.MethodGet,
+			path:     "/system/users/",
+			identity: auth.NewIdentity(1),
+			rsp:      set,
+			want:     []string{`"jane@example.com"`, `"j***@example.com"`, `"query":"j"`},
+			unwanted: []string{`"john@example.com"`},
+		},
+		{
+			name:     "list of users as admin",
+			method:   http.MethodGet,
+			path:     "/users",
+			identity: auth.NewIdentity(3, permissions.AdminsRoleID),
+			rsp:      set,
+			want:     []string{`"jane@example.com"`, `"john@example.com"`},
+		},
+		{
+			name:     "user",
+			method:   http.MethodGet,
+			path:     "/system/users/2",
+			identity: auth.NewIdentity(1),
+			rsp:      john,
+			want:     []string{`"j***@example.com"`},
+			unwanted: []string{`"john@example.com"`},
+		},
+		{
+			name:     "own user",
+			method:   http.MethodGet,
+			path:     "/users/2",
+			identity: auth.NewIdentity(2),
+			rsp:      john,
+			want:     []string{`"john@example.com"`},
+		},
+		{
+			name:     "error",
+			method:   http.MethodGet,
+			path:     "/users/2",
+			identity: auth.NewIdentity(1),
+			rsp:      errors.New("user not found"),
+			want:     []string{`{"error":{"message":"user not found"}}`},
+		},
+		{
+			name:     "created user",
+			method:   http.MethodPost,
+			path:     "/users/",
+			identity: auth.NewIdentity(1),
+			rsp:      john,
+			want:     []string{`"john@example.com"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				h = Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					resputil.JSON(w, tt.rsp)
+				}))
+
+				w = httptest.NewRecorder()
+				r = httptest.NewRequest(tt.method, tt.path, nil)
+			)
+
+			h.ServeHTTP(w, r.WithContext(auth.SetIdentityToContext(r.Context(), tt.identity)))
+
+			body := w.Body.String()
+			for _, s := range tt.want {
+				if !strings.Contains(body, s) {
+					t.Errorf("expected %s in %s", s, body)
+				}
+			}
+
+			for _, s := range tt.unwanted {
+				if strings.Contains(body, s) {
+					t.Errorf("expected no %s in %s", s, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package redact
+
+import (
+	"reflect"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	moduleKey struct {
+		namespaceID, moduleID uint64
+	}
+)
+
+func init() {
+	RegisterFunc(types.Record{}, record)
+}
+
+// record removes values of private module fields
+//
+// Private values are readable only by the owner of the record and by administrators.
+func record(s *Scope, v reflect.Value) (reflect.Value, bool) {
+	r := v.Interface().(types.Record)
+	if len(r.Values) == 0 || s.Allowed(r.OwnedBy, "admin", "self") {
+		return v, false
+	}
+
+	private, ok := s.Memo(moduleKey{r.NamespaceID, r.ModuleID}, func() interface{} {
+		m, err := service.DefaultModule.With(auth.SetSuperUserContext(s.Context())).FindByID(r.NamespaceID, r.ModuleID)
+		if err != nil {
+			// Without module there is no way to tell which fields are private
+			return nil
+		}
+
+		private := map[string]bool{}
+		for _, f := range m.Fields {
+			if f.Private {
+				private[f.Name] = true
+			}
+		}
+
+		return private
+	}).(map[string]bool)
+
+	values := types.RecordValueSet{}
+	for _, rv := range r.Values {
+		if ok && !private[rv.Name] {
+			values = append(values, rv)
+		}
+	}
+
+	if len(values) == len(r.Values) {
+		return v, false
+	}
+
+	r.Values = values
+	return reflect.ValueOf(r), true
+}
@@ -0,0 +1,483 @@
+package redact
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type (
+	// Rule decides if the caller can read a field of the value that is owned
+	// by the given user (0 when value has no owner field)
+	Rule func(ctx context.Context, owner uint64) bool
+
+	// Func redacts values of a type that can not be annotated (types from corteza)
+	//
+	// It receives a struct value and returns a redacted copy and true when anything was redacted.
+	Func func(s *Scope, v reflect.Value) (reflect.Value, bool)
+
+	// Scope holds state of a single redaction pass
+	Scope struct {
+		ctx     context.Context
+		allowed map[decision]bool
+		memo    map[interface{}]interface{}
+	}
+
+	decision struct {
+		rule  string
+		owner uint64
+	}
+
+	// plan describes how struct fields are redacted
+	plan struct {
+		// index of the owner field, -1 when there is none
+		owner int
+
+		fields []field
+	}
+
+	field struct {
+		index int
+
+		// Field is readable when any of the rules allows it
+		rules []string
+
+		// Masked fields are partially hidden instead of removed
+		mask bool
+	}
+)
+
+const (
+	// Tag on the response type's field, for example:
+	//
+	//   Email   string `json:"email" redact:"self|email,mask"`
+	//   OwnedBy uint64 `json:"ownedBy,string" redact:"owner"`
+	tagName = "redact"
+
+	// Tag value that marks the field holding ID of the value's owner
+	tagOwner = "owner"
+)
+
+var (
+	registry = struct {
+		sync.RWMutex
+
+		rules       map[string]Rule
+		funcs       map[reflect.Type]Func
+		annotations map[reflect.Type]map[string]string
+
+		plans    map[reflect.Type]*plan
+		relevant map[reflect.Type]bool
+	}{
+		rules:       map[string]Rule{},
+		funcs:       map[reflect.Type]Func{},
+		annotations: map[reflect.Type]map[string]string{},
+		plans:       map[reflect.Type]*plan{},
+		relevant:    map[reflect.Type]bool{},
+	}
+)
+
+// Register adds (or replaces) a named rule that can be used in tags
+func Register(name string, r Rule) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.rules[name] = r
+}
+
+// RegisterFunc sets redaction function for the type of v
+func RegisterFunc(v interface{}, fn Func) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.funcs[indirect(reflect.TypeOf(v))] = fn
+	registry.relevant = map[reflect.Type]bool{}
+}
+
+// Annotate sets tag on a field of a type that is not ours
+//
+// Annotations are used for fields without a redact tag.
+func Annotate(v interface{}, fieldName, tag string) {
+	t := indirect(reflect.TypeOf(v))
+	if _, ok := t.FieldByName(fieldName); !ok {
+		panic("redact: unknown field " + t.String() + "." + fieldName)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if registry.annotations[t] == nil {
+		registry.annotations[t] = map[string]string{}
+	}
+
+	registry.annotations[t][fieldName] = tag
+	registry.plans = map[reflect.Type]*plan{}
+	registry.relevant = map[reflect.Type]bool{}
+}
+
+// Value returns v with fields that the caller (identity from ctx) can not read
+// removed or masked
+//
+// Given value is never modified; parts that need redaction are copied.
+func Value(ctx context.Context, v interface{}) interface{} {
+	return NewScope(ctx).Value(v)
+}
+
+// NewScope returns scope for redaction of multiple values
+//
+// Decisions and memoized data are shared between values; used when
+// values are sent one by one (streamed responses).
+func NewScope(ctx context.Context) *Scope {
+	return &Scope{
+		ctx:     ctx,
+		allowed: map[decision]bool{},
+		memo:    map[interface{}]interface{}{},
+	}
+}
+
+// Value returns v with fields that the caller can not read removed or masked
+func (s *Scope) Value(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return v
+	}
+
+	if out, changed := s.walk(rv); changed {
+		return out.Interface()
+	}
+
+	return v
+}
+
+// Context returns context of the redaction pass
+func (s *Scope) Context() context.Context {
+	return s.ctx
+}
+
+// Allowed checks if any of the rules allows reading value of the owner
+//
+// Unknown rules do not allow anything.
+func (s *Scope) Allowed(owner uint64, rules ...string) bool {
+	for _, name := range rules {
+		d := decision{rule: name, owner: owner}
+
+		allowed, ok := s.allowed[d]
+		if !ok {
+			registry.RLock()
+			r := registry.rules[name]
+			registry.RUnlock()
+
+			allowed = r != nil && r(s.ctx, owner)
+			s.allowed[d] = allowed
+		}
+
+		if allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Memo returns value stored under the key, calls fn to get it the first time
+//
+// Helps redaction funcs to avoid loading the same data for every value.
+func (s *Scope) Memo(key interface{}, fn func() interface{}) interface{} {
+	if v, ok := s.memo[key]; ok {
+		return v
+	}
+
+	v := fn()
+	s.memo[key] = v
+	return v
+}
+
+func (s *Scope) walk(v reflect.Value) (reflect.Value, bool) {
+	if !relevant(v.Type()) {
+		return v, false
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+
+		e, changed := s.walk(v.Elem())
+		if !changed {
+			return v, false
+		}
+
+		if v.Kind() == reflect.Ptr {
+			p := reflect.New(e.Type())
+			p.Elem().Set(e)
+			return p, true
+		}
+
+		i := reflect.New(v.Type()).Elem()
+		i.Set(e)
+		return i, true
+
+	case reflect.Slice, reflect.Array:
+		var out reflect.Value
+
+		for i := 0; i < v.Len(); i++ {
+			e, changed := s.walk(v.Index(i))
+			if !changed {
+				continue
+			}
+
+			if !out.IsValid() {
+				if v.Kind() == reflect.Slice {
+					out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+					reflect.Copy(out, v)
+				} else {
+					out = reflect.New(v.Type()).Elem()
+					out.Set(v)
+				}
+			}
+
+			out.Index(i).Set(e)
+		}
+
+		return valueOr(out, v)
+
+	case reflect.Map:
+		var out reflect.Value
+
+		for _, k := range v.MapKeys() {
+			e, changed := s.walk(v.MapIndex(k))
+			if !changed {
+				continue
+			}
+
+			if !out.IsValid() {
+				out = reflect.MakeMapWithSize(v.Type(), v.Len())
+				for _, k := range v.MapKeys() {
+					out.SetMapIndex(k, v.MapIndex(k))
+				}
+			}
+
+			out.SetMapIndex(k, e)
+		}
+
+		return valueOr(out, v)
+
+	case reflect.Struct:
+		registry.RLock()
+		fn := registry.funcs[v.Type()]
+		registry.RUnlock()
+
+		if fn != nil {
+			return fn(s, v)
+		}
+
+		return s.walkStruct(v)
+	}
+
+	return v, false
+}
+
+func (s *Scope) walkStruct(v reflect.Value) (reflect.Value, bool) {
+	var (
+		p     = planFor(v.Type())
+		out   reflect.Value
+		owner uint64
+
+		set = func(i int, f reflect.Value) {
+			if !out.IsValid() {
+				out = reflect.New(v.Type()).Elem()
+				out.Set(v)
+			}
+
+			out.Field(i).Set(f)
+		}
+	)
+
+	if p.owner >= 0 {
+		owner = v.Field(p.owner).Uint()
+	}
+
+	for _, f := range p.fields {
+		fv := v.Field(f.index)
+
+		if len(f.rules) > 0 && !s.Allowed(owner, f.rules...) {
+			if f.mask {
+				set(f.index, mask(fv))
+			} else {
+				set(f.index, reflect.Zero(fv.Type()))
+			}
+
+			continue
+		}
+
+		if e, changed := s.walk(fv); changed {
+			set(f.index, e)
+		}
+	}
+
+	return valueOr(out, v)
+}
+
+// planFor returns (cached) plan for the struct type
+func planFor(t reflect.Type) *plan {
+	registry.RLock()
+	p := registry.plans[t]
+	registry.RUnlock()
+
+	if p != nil {
+		return p
+	}
+
+	p = &plan{owner: -1}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			// Unexported
+			continue
+		}
+
+		tag, ok := sf.Tag.Lookup(tagName)
+		if !ok {
+			registry.RLock()
+			tag = registry.annotations[t][sf.Name]
+			registry.RUnlock()
+		}
+
+		if tag == tagOwner {
+			p.owner = i
+			continue
+		}
+
+		f := field{index: i}
+		if tag != "" {
+			parts := strings.Split(tag, ",")
+			f.rules = strings.Split(parts[0], "|")
+			f.mask = len(parts) > 1 && parts[1] == "mask"
+		}
+
+		p.fields = append(p.fields, f)
+	}
+
+	registry.Lock()
+	registry.plans[t] = p
+	registry.Unlock()
+
+	return p
+}
+
+// relevant checks if values of the type can contain anything to redact
+func relevant(t reflect.Type) bool {
+	registry.RLock()
+	r, ok := registry.relevant[t]
+	registry.RUnlock()
+
+	if ok {
+		return r
+	}
+
+	r = reaches(t, map[reflect.Type]bool{})
+
+	registry.Lock()
+	registry.relevant[t] = r
+	registry.Unlock()
+
+	return r
+}
+
+// reaches walks through all types reachable from t and looks for annotated or registered types
+func reaches(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		// Can hold anything
+		return true
+
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return reaches(t.Elem(), seen)
+
+	case reflect.Struct:
+		registry.RLock()
+		_, hasFunc := registry.funcs[t]
+		registry.RUnlock()
+
+		if hasFunc {
+			return true
+		}
+
+		for _, f := range planFor(t).fields {
+			if len(f.rules) > 0 || reaches(t.Field(f.index).Type, seen) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// mask hides most of the string value(s), other values are zeroed
+func mask(v reflect.Value) reflect.Value {
+	switch {
+	case v.Kind() == reflect.String:
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(maskString(v.String()))
+		return out
+
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		if v.IsNil() {
+			return v
+		}
+
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).SetString(maskString(v.Index(i).String()))
+		}
+
+		return out
+	}
+
+	return reflect.Zero(v.Type())
+}
+
+// maskString keeps the first character (and domain of email addresses)
+//
+//	john.doe@example.com => j***@example.com
+func maskString(s string) string {
+	if s == "" {
+		return s
+	}
+
+	var (
+		first = []rune(s)[:1]
+		at    = strings.LastIndex(s, "@")
+	)
+
+	if at > 0 {
+		return string(first) + "***" + s[at:]
+	}
+
+	return string(first) + "***"
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+func valueOr(out, v reflect.Value) (reflect.Value, bool) {
+	if out.IsValid() {
+		return out, true
+	}
+
+	return v, false
+}
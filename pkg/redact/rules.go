@@ -0,0 +1,53 @@
+package redact
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/payload/outgoing"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+func init() {
+	Register("admin", isAdmin)
+	Register("self", isSelf)
+	Register("email", canUnmaskEmail)
+
+	// Users' emails are masked the same way as corteza masks them on user endpoints
+	Annotate(types.User{}, "ID", tagOwner)
+	Annotate(types.User{}, "Email", "self|email,mask")
+	Annotate(outgoing.User{}, "ID", tagOwner)
+	Annotate(outgoing.User{}, "Email", "self|email,mask")
+}
+
+// isAdmin allows super user and members of the administrators role
+func isAdmin(ctx context.Context, _ uint64) bool {
+	i := auth.GetIdentityFromContext(ctx)
+	if auth.IsSuperUser(i) {
+		return true
+	}
+
+	for _, roleID := range i.Roles() {
+		if roleID == permissions.AdminsRoleID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSelf allows the owner of the value
+func isSelf(ctx context.Context, owner uint64) bool {
+	return owner > 0 && auth.GetIdentityFromContext(ctx).Identity() == owner
+}
+
+// canUnmaskEmail allows users with permission to unmask owner's email
+func canUnmaskEmail(ctx context.Context, owner uint64) bool {
+	if service.DefaultAccessControl == nil {
+		return isAdmin(ctx, owner)
+	}
+
+	return service.DefaultAccessControl.CanUnmaskEmail(ctx, &types.User{ID: owner})
+}
@@ -0,0 +1,39 @@
+package relations
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	relationsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID        = relationsError{"InvalidID", fault.Invalid}
+	ErrInvalidField     = relationsError{"InvalidField", fault.Invalid}
+	ErrInvalidTarget    = relationsError{"InvalidTarget", fault.Invalid}
+	ErrInvalidAttribute = relationsError{"InvalidAttribute", fault.Invalid}
+	ErrDuplicate        = relationsError{"Duplicate", fault.Conflict}
+	ErrRestricted       = relationsError{"Restricted", fault.Forbidden}
+	ErrNoPermissions    = relationsError{"NoPermissions", fault.Forbidden}
+	ErrRelationNotFound = relationsError{"RelationNotFound", fault.NotFound}
+)
+
+func (e relationsError) Error() string {
+	return e.String()
+}
+
+func (e relationsError) String() string {
+	return "crust.relations." + e.name
+}
+
+func (e relationsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e relationsError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,33 @@
+package relations
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200122000000.relations",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_record_relation (
+  id                BIGINT UNSIGNED NOT NULL,
+  rel_namespace     BIGINT UNSIGNED NOT NULL,
+  rel_module        BIGINT UNSIGNED NOT NULL,
+  field             VARCHAR(64)     NOT NULL,
+  rel_record        BIGINT UNSIGNED NOT NULL,
+  rel_target_module BIGINT UNSIGNED NOT NULL,
+  rel_target        BIGINT UNSIGNED NOT NULL,
+  attributes        JSON            NOT NULL,
+
+  created_by        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at        DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at        DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  UNIQUE INDEX (rel_record, field, rel_target),
+  INDEX (rel_target)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
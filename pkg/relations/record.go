@@ -0,0 +1,122 @@
+package relations
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// record wraps record service and maintains links of deleted records
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with relation handling
+//
+// Links are managed through relation endpoints only, so values submitted
+// for relation fields are ignored. When record is deleted, its links are
+// handled according to the field's delete policy.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	if err := svc.strip(r); err != nil {
+		return nil, err
+	}
+
+	return svc.RecordService.Create(r)
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	if err := svc.strip(r); err != nil {
+		return nil, err
+	}
+
+	return svc.RecordService.Update(r)
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	var (
+		repo    = Repository(svc.ctx, nil)
+		cascade []uint64
+	)
+
+	outgoing, err := repo.Find(RelationFilter{NamespaceID: namespaceID, RecordIDs: []uint64{recordID}})
+	if err != nil {
+		return err
+	}
+
+	incoming, err := repo.FindByTarget(recordID)
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range append(outgoing, incoming...) {
+		switch svc.policy(rel) {
+		case DeleteRestrict:
+			return ErrRestricted.withStack()
+		case DeleteCascade:
+			if rel.RecordID == recordID {
+				cascade = append(cascade, rel.TargetID)
+			}
+		}
+	}
+
+	if err = svc.RecordService.DeleteByID(namespaceID, recordID); err != nil {
+		return err
+	}
+
+	// Links in both directions are removed before cascading,
+	// so records that link to each other are deleted only once
+	if err = repo.DeleteByRecord(recordID); err != nil {
+		return err
+	}
+
+	for _, targetID := range cascade {
+		if err = svc.DeleteByID(namespaceID, targetID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// policy returns delete policy of the link's field
+func (svc record) policy(rel *Relation) DeletePolicy {
+	m, err := service.DefaultModule.With(svc.ctx).FindByID(rel.NamespaceID, rel.ModuleID)
+	if err != nil {
+		return DeleteUnlink
+	}
+
+	return OnDelete(m.Fields.FindByName(rel.Field))
+}
+
+// strip removes values of relation fields
+func (svc record) strip(r *types.Record) error {
+	m, err := service.DefaultModule.With(svc.ctx).FindByID(r.NamespaceID, r.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	vv := types.RecordValueSet{}
+	for _, v := range r.Values {
+		if !IsRelation(m.Fields.FindByName(v.Name)) {
+			vv = append(vv, v)
+		}
+	}
+
+	r.Values = vv
+	return nil
+}
@@ -0,0 +1,130 @@
+package relations
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_record_relation"
+}
+
+func (r repository) columns() []string {
+	return []string{
+		"id",
+		"rel_namespace",
+		"rel_module",
+		"field",
+		"rel_record",
+		"rel_target_module",
+		"rel_target",
+		"attributes",
+		"created_by",
+		"created_at",
+		"updated_at",
+	}
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(r.columns()...).
+		From(r.table())
+}
+
+func (r repository) FindByID(namespaceID, relationID uint64) (*Relation, error) {
+	var (
+		rel = &Relation{}
+		q   = r.query().Where(squirrel.Eq{"id": relationID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, rel); err != nil {
+		return nil, err
+	} else if rel.ID == 0 {
+		return nil, ErrRelationNotFound.withStack().WithID("namespaceID", namespaceID).WithID("relationID", relationID)
+	}
+
+	return rel, nil
+}
+
+func (r repository) Find(f RelationFilter) (set RelationSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": f.NamespaceID, "rel_record": f.RecordIDs}).
+		OrderBy("created_at", "id")
+
+	if len(f.Fields) > 0 {
+		q = q.Where(squirrel.Eq{"field": f.Fields})
+	}
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindByTarget returns all links to the record
+func (r repository) FindByTarget(targetID uint64) (set RelationSet, err error) {
+	return set, rh.FetchAll(r.db(), r.query().Where(squirrel.Eq{"rel_target": targetID}), &set)
+}
+
+// Exists checks if record is already linked to the target through the field
+func (r repository) Exists(recordID uint64, field string, targetID uint64) (bool, error) {
+	var (
+		rel = &Relation{}
+		q   = r.query().Where(squirrel.Eq{"rel_record": recordID, "field": field, "rel_target": targetID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, rel); err != nil {
+		return false, err
+	}
+
+	return rel.ID > 0, nil
+}
+
+func (r repository) Create(rel *Relation) (*Relation, error) {
+	rel.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&rel.CreatedAt)
+
+	return rel, errors.WithStack(r.db().Insert(r.table(), rel))
+}
+
+func (r repository) Update(rel *Relation) (*Relation, error) {
+	rh.SetCurrentTimeRounded(&rel.UpdatedAt)
+
+	return rel, errors.WithStack(r.db().Replace(r.table(), rel))
+}
+
+func (r repository) DeleteByID(namespaceID, relationID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"id": relationID, "rel_namespace": namespaceID})
+}
+
+// DeleteByRecord removes all links from and to the record
+func (r repository) DeleteByRecord(recordID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Or{
+		squirrel.Eq{"rel_record": recordID},
+		squirrel.Eq{"rel_target": recordID},
+	})
+}
@@ -0,0 +1,63 @@
+package relations
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts record relation endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {recordID} params
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?field=projects
+	r.Get("/", rest.Handler("RecordRelation.List", func(r *http.Request) (interface{}, error) {
+		return DefaultRelation.With(r.Context()).Find(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "recordID"),
+			r.URL.Query().Get("field"),
+		)
+	}))
+
+	r.Post("/", rest.Handler("RecordRelation.Create", func(r *http.Request) (interface{}, error) {
+		rel := &Relation{}
+		if err := rest.Decode(r, rel); err != nil {
+			return nil, err
+		}
+
+		rel.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		rel.RecordID = rest.ParamUint64(r, "recordID")
+		return DefaultRelation.With(r.Context()).Create(rel)
+	}))
+
+	r.Get("/{relationID}", rest.Handler("RecordRelation.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultRelation.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "relationID"),
+		)
+	}))
+
+	r.Put("/{relationID}", rest.Handler("RecordRelation.Update", func(r *http.Request) (interface{}, error) {
+		rel := &Relation{}
+		if err := rest.Decode(r, rel); err != nil {
+			return nil, err
+		}
+
+		rel.ID = rest.ParamUint64(r, "relationID")
+		rel.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultRelation.With(r.Context()).Update(rel)
+	}))
+
+	r.Delete("/{relationID}", rest.Handler("RecordRelation.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultRelation.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "relationID"),
+		)
+	}))
+}
@@ -0,0 +1,363 @@
+package relations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/access"
+)
+
+type (
+	relationService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		module service.ModuleService
+		record service.RecordService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanReadRecord(context.Context, *types.Module) bool
+		CanUpdateRecord(context.Context, *types.Module) bool
+		CanReadRecordValue(context.Context, *types.ModuleField) bool
+		CanUpdateRecordValue(context.Context, *types.ModuleField) bool
+	}
+
+	RelationService interface {
+		With(ctx context.Context) RelationService
+
+		FindByID(namespaceID, relationID uint64) (*Relation, error)
+		Find(namespaceID, recordID uint64, field string) (RelationSet, error)
+		Create(*Relation) (*Relation, error)
+		Update(*Relation) (*Relation, error)
+		DeleteByID(namespaceID, relationID uint64) error
+
+		Include(m *types.Module, rr types.RecordSet, fields ...string) (map[uint64]Includes, error)
+	}
+)
+
+const (
+	// Max number of records loaded with one filter
+	loadChunk = 100
+)
+
+var (
+	DefaultRelation RelationService
+)
+
+// Init initializes relation service and decorates compose's record service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	DefaultRelation = (&relationService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		module: service.DefaultModule,
+		record: service.DefaultRecord,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc relationService) With(ctx context.Context) RelationService {
+	return &relationService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		module: svc.module.With(ctx),
+		record: svc.record.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc relationService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc relationService) FindByID(namespaceID, relationID uint64) (*Relation, error) {
+	if relationID == 0 {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	rel, err := svc.repository.FindByID(namespaceID, relationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err = svc.source(namespaceID, rel.RecordID, rel.Field, false); err != nil {
+		return nil, err
+	}
+
+	return rel, nil
+}
+
+// Find returns links of the record; all readable relation fields when field is empty
+func (svc relationService) Find(namespaceID, recordID uint64, field string) (RelationSet, error) {
+	if recordID == 0 {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	f := RelationFilter{NamespaceID: namespaceID, RecordIDs: []uint64{recordID}}
+
+	if field != "" {
+		if _, _, err := svc.source(namespaceID, recordID, field, false); err != nil {
+			return nil, err
+		}
+
+		f.Fields = []string{field}
+	} else {
+		m, _, err := svc.source(namespaceID, recordID, "", false)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, mf := range access.ReadableFields(svc.ctx, m.Fields) {
+			if IsRelation(mf) {
+				f.Fields = append(f.Fields, mf.Name)
+			}
+		}
+
+		if len(f.Fields) == 0 {
+			return RelationSet{}, nil
+		}
+	}
+
+	return svc.repository.Find(f)
+}
+
+func (svc relationService) Create(in *Relation) (*Relation, error) {
+	m, f, err := svc.source(in.NamespaceID, in.RecordID, in.Field, true)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := svc.record.FindByID(in.NamespaceID, in.TargetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.ModuleID != TargetModuleID(f) {
+		return nil, ErrInvalidTarget.withStack()
+	}
+
+	if err = checkAttributes(f, in.Attributes); err != nil {
+		return nil, err
+	}
+
+	if exists, err := svc.repository.Exists(in.RecordID, f.Name, target.ID); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, ErrDuplicate.withStack()
+	}
+
+	rel := &Relation{
+		NamespaceID:    in.NamespaceID,
+		ModuleID:       m.ID,
+		Field:          f.Name,
+		RecordID:       in.RecordID,
+		TargetModuleID: target.ModuleID,
+		TargetID:       target.ID,
+		Attributes:     in.Attributes,
+		CreatedBy:      auth.GetIdentityFromContext(svc.ctx).Identity(),
+	}
+
+	return svc.repository.Create(rel)
+}
+
+// Update updates attributes of the link
+func (svc relationService) Update(upd *Relation) (*Relation, error) {
+	rel, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, f, err := svc.source(rel.NamespaceID, rel.RecordID, rel.Field, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkAttributes(f, upd.Attributes); err != nil {
+		return nil, err
+	}
+
+	rel.Attributes = upd.Attributes
+	return svc.repository.Update(rel)
+}
+
+func (svc relationService) DeleteByID(namespaceID, relationID uint64) error {
+	rel, err := svc.repository.FindByID(namespaceID, relationID)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err = svc.source(rel.NamespaceID, rel.RecordID, rel.Field, true); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, relationID)
+}
+
+// Include loads related records of the given records, for each of the relation fields
+//
+// Fields that are not readable (or link to modules with unreadable records)
+// are silently skipped
+func (svc relationService) Include(m *types.Module, rr types.RecordSet, fields ...string) (map[uint64]Includes, error) {
+	var (
+		out     = map[uint64]Includes{}
+		targets = map[uint64][]uint64{}
+		loaded  = map[uint64]*types.Record{}
+
+		f = RelationFilter{NamespaceID: m.NamespaceID, RecordIDs: make([]uint64, len(rr))}
+	)
+
+	for i := range rr {
+		f.RecordIDs[i] = rr[i].ID
+	}
+
+	for _, name := range fields {
+		mf := m.Fields.FindByName(name)
+		if !IsRelation(mf) {
+			return nil, ErrInvalidField.withStack()
+		}
+
+		if !svc.ac.CanReadRecordValue(svc.ctx, mf) {
+			continue
+		}
+
+		if tm, err := svc.module.FindByID(m.NamespaceID, TargetModuleID(mf)); err != nil || !svc.ac.CanReadRecord(svc.ctx, tm) {
+			continue
+		}
+
+		f.Fields = append(f.Fields, name)
+	}
+
+	if len(f.Fields) == 0 || len(rr) == 0 {
+		return out, nil
+	}
+
+	set, err := svc.repository.Find(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rel := range set {
+		targets[rel.TargetModuleID] = append(targets[rel.TargetModuleID], rel.TargetID)
+	}
+
+	for moduleID, ids := range targets {
+		for len(ids) > 0 {
+			n := len(ids)
+			if n > loadChunk {
+				n = loadChunk
+			}
+
+			if err = svc.load(m.NamespaceID, moduleID, ids[:n], loaded); err != nil {
+				return nil, err
+			}
+
+			ids = ids[n:]
+		}
+	}
+
+	for _, rel := range set {
+		r, ok := loaded[rel.TargetID]
+		if !ok {
+			// Deleted or not readable
+			continue
+		}
+
+		if out[rel.RecordID] == nil {
+			out[rel.RecordID] = Includes{}
+		}
+
+		out[rel.RecordID][rel.Field] = append(out[rel.RecordID][rel.Field], &Related{Relation: rel, Record: r})
+	}
+
+	return out, nil
+}
+
+// load fetches records by IDs into the map
+func (svc relationService) load(namespaceID, moduleID uint64, ids []uint64, into map[uint64]*types.Record) error {
+	cnd := make([]string, len(ids))
+	for i, id := range ids {
+		cnd[i] = fmt.Sprintf("id = %d", id)
+	}
+
+	rr, _, err := svc.record.Find(types.RecordFilter{
+		NamespaceID: namespaceID,
+		ModuleID:    moduleID,
+		Filter:      strings.Join(cnd, " OR "),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rr {
+		into[r.ID] = r
+	}
+
+	return nil
+}
+
+// source loads module of the source record and its relation field and checks permissions
+//
+// Without field name, only access to the record is checked
+func (svc relationService) source(namespaceID, recordID uint64, field string, write bool) (*types.Module, *types.ModuleField, error) {
+	r, err := svc.record.FindByID(namespaceID, recordID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := svc.module.FindByID(namespaceID, r.ModuleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if field == "" {
+		return m, nil, nil
+	}
+
+	f := m.Fields.FindByName(field)
+	if !IsRelation(f) {
+		return nil, nil, ErrInvalidField.withStack()
+	}
+
+	if write {
+		if !svc.ac.CanUpdateRecord(svc.ctx, m) || !svc.ac.CanUpdateRecordValue(svc.ctx, f) {
+			return nil, nil, ErrNoPermissions.withStack()
+		}
+	} else if !svc.ac.CanReadRecordValue(svc.ctx, f) {
+		return nil, nil, ErrNoPermissions.withStack()
+	}
+
+	return m, f, nil
+}
+
+func checkAttributes(f *types.ModuleField, aa Attributes) error {
+	for name := range aa {
+		if !allowsAttribute(f, name) {
+			return ErrInvalidAttribute.withStack()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package relations
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Relation links record to a record of the related module
+	//
+	// Links are stored outside record values, so each link can
+	// carry its own attributes (for example, role on a project)
+	Relation struct {
+		ID          uint64 `json:"relationID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+		Field       string `json:"field" db:"field"`
+		RecordID    uint64 `json:"recordID,string" db:"rel_record"`
+
+		TargetModuleID uint64 `json:"targetModuleID,string" db:"rel_target_module"`
+		TargetID       uint64 `json:"targetID,string" db:"rel_target"`
+
+		Attributes Attributes `json:"attributes" db:"attributes"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	RelationFilter struct {
+		NamespaceID uint64   `json:"namespaceID,string"`
+		RecordIDs   []uint64 `json:"recordIDs,string"`
+		Fields      []string `json:"fields"`
+	}
+
+	RelationSet []*Relation
+
+	// Attributes are free-form values stored on the link
+	Attributes map[string]interface{}
+
+	// Related is a linked record with its link
+	Related struct {
+		Relation *Relation     `json:"relation"`
+		Record   *types.Record `json:"record"`
+	}
+
+	// Includes holds related records, by relation field
+	Includes map[string][]*Related
+
+	// DeletePolicy controls what happens with links when a linked record is deleted
+	DeletePolicy string
+)
+
+const (
+	// FieldKind is kind of the module field that holds many-to-many links
+	//
+	// Field options:
+	//   - moduleID: ID of the related module (required)
+	//   - onDelete: unlink (default), restrict or cascade
+	//   - attributes: list of allowed attribute names (any when not set)
+	FieldKind = "Relation"
+
+	// Links are removed, related records are kept
+	DeleteUnlink DeletePolicy = "unlink"
+
+	// Records with links can not be deleted
+	DeleteRestrict DeletePolicy = "restrict"
+
+	// Deleting the record deletes all records it links to
+	DeleteCascade DeletePolicy = "cascade"
+)
+
+// IsRelation returns true if field holds many-to-many links
+func IsRelation(f *types.ModuleField) bool {
+	return f != nil && f.Kind == FieldKind
+}
+
+// TargetModuleID returns ID of the module that field links to
+func TargetModuleID(f *types.ModuleField) uint64 {
+	var id uint64
+	if f == nil || f.Options == nil {
+		return 0
+	}
+
+	switch v := f.Options["moduleID"].(type) {
+	case string:
+		fmt.Sscan(v, &id)
+	case float64:
+		id = uint64(v)
+	}
+
+	return id
+}
+
+// OnDelete returns delete policy of the relation field
+func OnDelete(f *types.ModuleField) DeletePolicy {
+	if f != nil && f.Options != nil {
+		if p, ok := f.Options["onDelete"].(string); ok && DeletePolicy(p).IsValid() {
+			return DeletePolicy(p)
+		}
+	}
+
+	return DeleteUnlink
+}
+
+func (p DeletePolicy) IsValid() bool {
+	switch p {
+	case DeleteUnlink, DeleteRestrict, DeleteCascade:
+		return true
+	}
+
+	return false
+}
+
+// allowsAttribute checks attribute name against the list in field's options
+func allowsAttribute(f *types.ModuleField, name string) bool {
+	if f.Options == nil {
+		return true
+	}
+
+	aa, ok := f.Options["attributes"].([]interface{})
+	if !ok {
+		return true
+	}
+
+	for _, a := range aa {
+		if a == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (set RelationSet) FindByID(ID uint64) *Relation {
+	for i := range set {
+		if set[i].ID == ID {
+			return set[i]
+		}
+	}
+
+	return nil
+}
+
+// TargetIDs returns IDs of linked records
+func (set RelationSet) TargetIDs() (ids []uint64) {
+	ids = make([]uint64, len(set))
+	for i := range set {
+		ids[i] = set[i].TargetID
+	}
+
+	return
+}
+
+func (aa Attributes) Value() (driver.Value, error) {
+	if aa == nil {
+		aa = Attributes{}
+	}
+
+	return json.Marshal(aa)
+}
+
+func (aa *Attributes) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*aa = Attributes{}
+	case []byte:
+		if err := json.Unmarshal(b, aa); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Attributes", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package reports
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	reportsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNameRequired         = reportsError{"NameRequired", fault.Invalid}
+	ErrReportRequired       = reportsError{"ReportRequired", fault.Invalid}
+	ErrInvalidFormat        = reportsError{"InvalidFormat", fault.Invalid}
+	ErrInvalidRRule         = reportsError{"InvalidRRule", fault.Invalid}
+	ErrInvalidTimezone      = reportsError{"InvalidTimezone", fault.Invalid}
+	ErrInvalidRecipient     = reportsError{"InvalidRecipient", fault.Invalid}
+	ErrRecipientsRequired   = reportsError{"RecipientsRequired", fault.Invalid}
+	ErrNoPermissions        = reportsError{"NoPermissions", fault.Forbidden}
+	ErrSubscriptionNotFound = reportsError{"SubscriptionNotFound", fault.NotFound}
+)
+
+func (e reportsError) Error() string {
+	return e.String()
+}
+
+func (e reportsError) String() string {
+	return "crust.reports." + e.name
+}
+
+func (e reportsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e reportsError) withStack() *fault.Error {
+	return fault.New(e)
+}
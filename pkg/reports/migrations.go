@@ -0,0 +1,56 @@
+package reports
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200204000000.reports",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_report_subscription (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(64)     NOT NULL,
+  rel_chart          BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  report             TEXT                NULL,
+  format             VARCHAR(16)     NOT NULL,
+  rrule              VARCHAR(255)    NOT NULL,
+  starts_at          DATETIME        NOT NULL,
+  timezone           VARCHAR(64)     NOT NULL DEFAULT '',
+  recipients         TEXT            NOT NULL,
+  alert_recipients   TEXT            NOT NULL,
+  failures           INT UNSIGNED    NOT NULL DEFAULT 0,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+  last_run_at        DATETIME            NULL DEFAULT NULL,
+  next_run_at        DATETIME            NULL DEFAULT NULL,
+
+  owned_by           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace),
+  INDEX (next_run_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_report_run (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_subscription   BIGINT UNSIGNED NOT NULL,
+  status             VARCHAR(16)     NOT NULL,
+  row_count          INT UNSIGNED    NOT NULL DEFAULT 0,
+  delivered          INT UNSIGNED    NOT NULL DEFAULT 0,
+  error              TEXT            NOT NULL,
+  manual             BOOLEAN         NOT NULL DEFAULT FALSE,
+  started_at         DATETIME        NOT NULL,
+  finished_at        DATETIME        NOT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_subscription, started_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,130 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// A4, landscape
+	pdfWidth  = 842
+	pdfHeight = 595
+	pdfMargin = 36
+
+	pdfFontSize = 8
+	pdfLeading  = 11
+
+	// PDFLineWidth is the number of characters that fit on a line
+	// (Courier glyphs are 0.6 of the font size wide)
+	PDFLineWidth = (pdfWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6)
+)
+
+// writePDF writes tables as a PDF document
+func writePDF(w io.Writer, title string, tt []*table) error {
+	var lines = []string{title, ""}
+
+	for _, t := range tt {
+		if t.Title != "" && t.Title != title {
+			lines = append(lines, t.Title, "")
+		}
+
+		lines = append(append(lines, t.lines()...), "")
+	}
+
+	return WritePDF(w, lines)
+}
+
+// WritePDF writes lines of text as a PDF document
+//
+// Lines are typeset with the (built-in) Courier font so that fixed-width
+// columns line up; there is no need for font metrics or embedding.
+// Characters outside of Latin-1 are replaced, long lines are not wrapped.
+func WritePDF(w io.Writer, lines []string) error {
+	var (
+		perPage = (pdfHeight - 2*pdfMargin) / pdfLeading
+		pages   [][]string
+	)
+
+	for len(lines) > 0 {
+		n := perPage
+		if n > len(lines) {
+			n = len(lines)
+		}
+
+		pages, lines = append(pages, lines[:n]), lines[n:]
+	}
+
+	var (
+		buf     bytes.Buffer
+		offsets []int
+		object  = func(body string) {
+			offsets = append(offsets, buf.Len())
+			fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+		}
+
+		// Objects 1-3 are catalog, page tree and font, followed by
+		// a page and its content for every page
+		kids []string
+	)
+
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 4+i*2))
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range pages {
+		var content bytes.Buffer
+
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfHeight-pdfMargin)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) '\n", pdfString(line))
+		}
+		content.WriteString("ET")
+
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfWidth, pdfHeight, 5+i*2,
+		))
+
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", o)
+	}
+
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// pdfString encodes text for a literal string
+func pdfString(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,190 @@
+package reports
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize/v2"
+)
+
+var (
+	// Characters that are not allowed in sheet names
+	invalidSheetChars = regexp.MustCompile(`[\[\]:*?/\\]`)
+)
+
+// writeXLSX writes tables into a workbook, one sheet per table
+func writeXLSX(w io.Writer, tt []*table) error {
+	var (
+		f     = excelize.NewFile()
+		first = f.GetSheetName(1)
+	)
+
+	for i, t := range tt {
+		sheet := sheetName(t.Title, i)
+		if i == 0 {
+			f.SetSheetName(first, sheet)
+		} else {
+			f.NewSheet(sheet)
+		}
+
+		for c, col := range t.Columns {
+			if err := f.SetCellStr(sheet, axis(c, 0), col); err != nil {
+				return err
+			}
+		}
+
+		for r, row := range t.Rows {
+			for c, v := range row {
+				var value interface{} = v
+				if c >= t.Dimensions {
+					if n, err := strconv.ParseFloat(v, 64); err == nil {
+						value = n
+					}
+				}
+
+				if err := f.SetCellValue(sheet, axis(c, r+1), value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return f.Write(w)
+}
+
+// writeHTML writes tables as HTML, used as a body of the inline email
+func writeHTML(w io.Writer, title string, tt []*table) error {
+	var b strings.Builder
+
+	b.WriteString("<html><body style=\"font-family: sans-serif\">")
+	fmt.Fprintf(&b, "<h2>%s</h2>", html.EscapeString(title))
+
+	for _, t := range tt {
+		if t.Title != "" && t.Title != title {
+			fmt.Fprintf(&b, "<h3>%s</h3>", html.EscapeString(t.Title))
+		}
+
+		b.WriteString("<table cellpadding=\"4\" cellspacing=\"0\" border=\"1\" style=\"border-collapse: collapse\"><tr>")
+		for _, col := range t.Columns {
+			fmt.Fprintf(&b, "<th>%s</th>", html.EscapeString(col))
+		}
+		b.WriteString("</tr>")
+
+		for _, row := range t.Rows {
+			b.WriteString("<tr>")
+			for c, v := range row {
+				align := "left"
+				if c >= t.Dimensions {
+					align = "right"
+				}
+
+				fmt.Fprintf(&b, "<td align=\"%s\">%s</td>", align, html.EscapeString(v))
+			}
+			b.WriteString("</tr>")
+		}
+
+		b.WriteString("</table>")
+	}
+
+	b.WriteString("</body></html>")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeText writes tables as plain text, alternative to the HTML body
+func writeText(w io.Writer, title string, tt []*table) error {
+	var b strings.Builder
+
+	b.WriteString(title + "\n")
+	for _, t := range tt {
+		b.WriteString("\n")
+		if t.Title != "" && t.Title != title {
+			b.WriteString(t.Title + "\n\n")
+		}
+
+		for _, line := range t.lines() {
+			b.WriteString(line + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// lines renders the table with fixed-width columns
+func (t table) lines() []string {
+	const maxWidth = 30
+
+	var (
+		widths = make([]int, len(t.Columns))
+		format = func(row []string) string {
+			cc := make([]string, len(row))
+			for c, v := range row {
+				if r := []rune(v); len(r) > widths[c] {
+					v = string(r[:widths[c]-1]) + "~"
+				}
+
+				pad := strings.Repeat(" ", widths[c]-len([]rune(v)))
+				if c >= t.Dimensions {
+					cc[c] = pad + v
+				} else {
+					cc[c] = v + pad
+				}
+			}
+
+			return strings.TrimRight(strings.Join(cc, "  "), " ")
+		}
+	)
+
+	for c, col := range t.Columns {
+		widths[c] = len([]rune(col))
+	}
+
+	for _, row := range t.Rows {
+		for c, v := range row {
+			if l := len([]rune(v)); l > widths[c] {
+				widths[c] = l
+			}
+		}
+	}
+
+	var sep []string
+	for c := range widths {
+		if widths[c] > maxWidth {
+			widths[c] = maxWidth
+		}
+
+		sep = append(sep, strings.Repeat("-", widths[c]))
+	}
+
+	out := []string{format(t.Columns), strings.Join(sep, "  ")}
+	for _, row := range t.Rows {
+		out = append(out, format(row))
+	}
+
+	return out
+}
+
+func sheetName(title string, i int) string {
+	name := strings.TrimSpace(invalidSheetChars.ReplaceAllString(title, " "))
+	if name == "" {
+		name = "Report"
+	}
+
+	suffix := " " + strconv.Itoa(i+1)
+	if r := []rune(name); len(r)+len(suffix) > 31 {
+		name = string(r[:31-len(suffix)])
+	}
+
+	return name + suffix
+}
+
+func axis(col, row int) string {
+	a, _ := excelize.CoordinatesToCellName(col+1, row+1)
+	return a
+}
@@ -0,0 +1,194 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/ql"
+)
+
+var (
+	// Chart dimension modifiers, translated into report's group-by functions
+	dimensionModifiers = map[string]string{
+		"DATE":    "DATE_FORMAT(%s, '%%Y-%%m-%%d')",
+		"WEEK":    "DATE_FORMAT(%s, '%%x-%%v')",
+		"MONTH":   "DATE_FORMAT(%s, '%%Y-%%m')",
+		"QUARTER": "CONCAT(YEAR(%s), '-Q', QUARTER(%s))",
+		"YEAR":    "YEAR(%s)",
+	}
+)
+
+// reports returns definitions of the subscription's report
+//
+// Charts are converted into reports with the same metrics & dimensions,
+// one per chart's report.
+func (svc reportsService) reports(ctx context.Context, s *Subscription) ([]*Report, error) {
+	if s.ChartID == 0 {
+		return []*Report{s.Report}, nil
+	}
+
+	c, err := service.DefaultChart.With(ctx).FindByID(s.NamespaceID, s.ChartID)
+	if err != nil {
+		return nil, err
+	}
+
+	return chartReports(c), nil
+}
+
+func chartReports(c *types.Chart) []*Report {
+	var rr []*Report
+
+	for i, cr := range c.Config.Reports {
+		r := &Report{
+			Title:    c.Name,
+			ModuleID: cr.ModuleID,
+			Filter:   cr.Filter,
+		}
+
+		if len(c.Config.Reports) > 1 {
+			r.Title = fmt.Sprintf("%s (%d)", c.Name, i+1)
+		}
+
+		var mm, dd []string
+
+		for _, m := range cr.Metrics {
+			field, _ := m["field"].(string)
+			if field == "" || field == "count" {
+				// Count is always a part of the report
+				continue
+			}
+
+			aggregate, _ := m["aggregate"].(string)
+			if aggregate == "" {
+				aggregate = "SUM"
+			}
+
+			mm = append(mm, fmt.Sprintf("%s(%s) AS %s", strings.ToUpper(aggregate), field, field))
+		}
+
+		for _, d := range cr.Dimensions {
+			field, _ := d["field"].(string)
+			if field == "" {
+				continue
+			}
+
+			expr := field
+			if modifier, _ := d["modifier"].(string); dimensionModifiers[strings.ToUpper(modifier)] != "" {
+				format := dimensionModifiers[strings.ToUpper(modifier)]
+				expr = fmt.Sprintf(format, repeat(field, strings.Count(format, "%s"))...)
+			}
+
+			dd = append(dd, fmt.Sprintf("%s AS %s", expr, field))
+		}
+
+		r.Metrics, r.Dimensions = strings.Join(mm, ", "), strings.Join(dd, ", ")
+		rr = append(rr, r)
+	}
+
+	return rr
+}
+
+// table runs the report and converts its rows into a table
+//
+// Dimensions come first, followed by the count and metrics.
+func (r Report) table(ctx context.Context, namespaceID uint64) (*table, error) {
+	out, err := service.DefaultRecord.With(ctx).Report(namespaceID, r.ModuleID, r.Metrics, r.Dimensions, r.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := out.([]map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("unexpected report result %T", out)
+	}
+
+	dimensions, err := aliases(r.Dimensions, "dimension_%d")
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := aliases(r.Metrics, "metric_%d")
+	if err != nil {
+		return nil, err
+	}
+
+	t := &table{
+		Title:      r.Title,
+		Columns:    append(append(dimensions, "count"), metrics...),
+		Dimensions: len(dimensions),
+	}
+
+	if len(rows) > maxRows {
+		rows = rows[:maxRows]
+	}
+
+	for _, row := range rows {
+		cells := make([]string, len(t.Columns))
+		for i, col := range t.Columns {
+			cells[i] = cell(row[col])
+		}
+
+		t.Rows = append(t.Rows, cells)
+	}
+
+	return t, nil
+}
+
+// aliases returns names of the report's columns, the same as report builder assigns them
+func aliases(columns, format string) ([]string, error) {
+	if strings.TrimSpace(columns) == "" {
+		return nil, nil
+	}
+
+	cc, err := ql.NewParser().ParseColumns(columns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse columns %q", columns)
+	}
+
+	aa := make([]string, len(cc))
+	for i, c := range cc {
+		if aa[i] = c.Alias; aa[i] == "" {
+			aa[i] = fmt.Sprintf(format, i)
+		}
+	}
+
+	return aa, nil
+}
+
+func cell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func repeat(s string, n int) []interface{} {
+	out := make([]interface{}, n)
+	for i := range out {
+		out[i] = s
+	}
+
+	return out
+}
+
+// rowCount returns number of rows in all tables
+func rowCount(tt []*table) (n uint) {
+	for _, t := range tt {
+		n += uint(len(t.Rows))
+	}
+
+	return
+}
@@ -0,0 +1,157 @@
+package reports
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_report_subscription"
+}
+
+func (r repository) tableRun() string {
+	return "crust_compose_report_run"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"name",
+			"rel_chart",
+			"report",
+			"format",
+			"rrule",
+			"starts_at",
+			"timezone",
+			"recipients",
+			"alert_recipients",
+			"failures",
+			"enabled",
+			"last_run_at",
+			"next_run_at",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(namespaceID, subscriptionID uint64) (*Subscription, error) {
+	var (
+		s = &Subscription{}
+		q = r.query().Where(squirrel.Eq{"id": subscriptionID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.ID == 0 {
+		return nil, ErrSubscriptionNotFound.withStack().WithID("namespaceID", namespaceID).WithID("subscriptionID", subscriptionID)
+	}
+
+	return s, nil
+}
+
+func (r repository) Find(namespaceID uint64) (set SubscriptionSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindDue returns enabled subscriptions that are scheduled to run
+func (r repository) FindDue(now time.Time) (set SubscriptionSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"enabled": true}).
+		Where(squirrel.LtOrEq{"next_run_at": now}).
+		OrderBy("next_run_at")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(s *Subscription) (*Subscription, error) {
+	s.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&s.CreatedAt)
+
+	return s, errors.WithStack(r.db().Insert(r.table(), s))
+}
+
+func (r repository) Update(s *Subscription) (*Subscription, error) {
+	rh.SetCurrentTimeRounded(&s.UpdatedAt)
+
+	return s, errors.WithStack(r.db().Replace(r.table(), s))
+}
+
+// UpdateState stores outcome of the run without touching subscription's configuration
+func (r repository) UpdateState(s *Subscription) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{
+			"failures":    s.Failures,
+			"enabled":     s.Enabled,
+			"last_run_at": s.LastRunAt,
+			"next_run_at": s.NextRunAt,
+		},
+		squirrel.Eq{"id": s.ID},
+	)
+}
+
+func (r repository) DeleteByID(namespaceID, subscriptionID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": subscriptionID, "rel_namespace": namespaceID},
+	)
+}
+
+// FindRuns returns the latest runs of the subscription, newest first
+func (r repository) FindRuns(subscriptionID uint64) (set RunSet, err error) {
+	q := squirrel.
+		Select("id", "rel_subscription", "status", "row_count", "delivered", "error", "manual", "started_at", "finished_at").
+		From(r.tableRun()).
+		Where(squirrel.Eq{"rel_subscription": subscriptionID}).
+		OrderBy("started_at DESC", "id DESC").
+		Limit(maxRuns)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateRun(run *Run) (*Run, error) {
+	run.ID = factory.Sonyflake.NextID()
+
+	return run, errors.WithStack(r.db().Insert(r.tableRun(), run))
+}
@@ -0,0 +1,71 @@
+package reports
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts report subscription endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("ReportSubscription.List", func(r *http.Request) (interface{}, error) {
+		return DefaultReports.With(r.Context()).Find(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Post("/", rest.Handler("ReportSubscription.Create", func(r *http.Request) (interface{}, error) {
+		s := &Subscription{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultReports.With(r.Context()).Create(s)
+	}))
+
+	r.Get("/{subscriptionID}", rest.Handler("ReportSubscription.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultReports.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "subscriptionID"),
+		)
+	}))
+
+	r.Put("/{subscriptionID}", rest.Handler("ReportSubscription.Update", func(r *http.Request) (interface{}, error) {
+		s := &Subscription{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		s.ID = rest.ParamUint64(r, "subscriptionID")
+		s.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultReports.With(r.Context()).Update(s)
+	}))
+
+	r.Delete("/{subscriptionID}", rest.Handler("ReportSubscription.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultReports.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "subscriptionID"),
+		)
+	}))
+
+	r.Post("/{subscriptionID}/run", rest.Handler("ReportSubscription.Run", func(r *http.Request) (interface{}, error) {
+		return DefaultReports.With(r.Context()).Run(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "subscriptionID"),
+		)
+	}))
+
+	r.Get("/{subscriptionID}/runs", rest.Handler("ReportSubscription.Runs", func(r *http.Request) (interface{}, error) {
+		return DefaultReports.With(r.Context()).FindRuns(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "subscriptionID"),
+		)
+	}))
+}
@@ -0,0 +1,517 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+	"github.com/crusttech/crust-server/pkg/recurrence"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	reportsService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+		chart     service.ChartService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	ReportsService interface {
+		With(ctx context.Context) ReportsService
+
+		Find(namespaceID uint64) (SubscriptionSet, error)
+		FindByID(namespaceID, subscriptionID uint64) (*Subscription, error)
+		Create(*Subscription) (*Subscription, error)
+		Update(*Subscription) (*Subscription, error)
+		DeleteByID(namespaceID, subscriptionID uint64) error
+
+		Run(namespaceID, subscriptionID uint64) (*Run, error)
+		FindRuns(namespaceID, subscriptionID uint64) (RunSet, error)
+	}
+)
+
+var (
+	DefaultReports ReportsService
+
+	// now is used for scheduling and can be overridden
+	now = time.Now
+
+	// Characters that are replaced in names of attached files
+	unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// Init initializes report service and starts delivering scheduled reports in the background
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &reportsService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+		chart:     service.DefaultChart,
+	}
+
+	DefaultReports = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc reportsService) With(ctx context.Context) ReportsService {
+	return svc.with(ctx)
+}
+
+func (svc reportsService) with(ctx context.Context) *reportsService {
+	return &reportsService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+		chart:     svc.chart.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc reportsService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc reportsService) Find(namespaceID uint64) (SubscriptionSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Find(namespaceID)
+}
+
+func (svc reportsService) FindByID(namespaceID, subscriptionID uint64) (*Subscription, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindByID(namespaceID, subscriptionID)
+}
+
+// Create stores new subscription and schedules its first delivery
+func (svc reportsService) Create(in *Subscription) (*Subscription, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	s := &Subscription{
+		NamespaceID:     in.NamespaceID,
+		Name:            in.Name,
+		ChartID:         in.ChartID,
+		Report:          in.Report,
+		Format:          in.Format,
+		RRule:           in.RRule,
+		StartsAt:        in.StartsAt,
+		Timezone:        in.Timezone,
+		Recipients:      in.Recipients,
+		AlertRecipients: in.AlertRecipients,
+		Enabled:         in.Enabled,
+		OwnedBy:         auth.GetIdentityFromContext(svc.ctx).Identity(),
+	}
+
+	s.NextRunAt = s.next(now())
+
+	return svc.repository.Create(s)
+}
+
+// Update modifies the subscription and reschedules the next delivery
+//
+// Enabling a subscription resets the counter of failed deliveries.
+func (svc reportsService) Update(upd *Subscription) (*Subscription, error) {
+	s, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	if upd.Enabled && !s.Enabled {
+		s.Failures = 0
+	}
+
+	s.Name = upd.Name
+	s.ChartID = upd.ChartID
+	s.Report = upd.Report
+	s.Format = upd.Format
+	s.RRule = upd.RRule
+	s.StartsAt = upd.StartsAt
+	s.Timezone = upd.Timezone
+	s.Recipients = upd.Recipients
+	s.AlertRecipients = upd.AlertRecipients
+	s.Enabled = upd.Enabled
+	s.NextRunAt = s.next(now())
+
+	return svc.repository.Update(s)
+}
+
+func (svc reportsService) DeleteByID(namespaceID, subscriptionID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindByID(namespaceID, subscriptionID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, subscriptionID)
+}
+
+// Run delivers the report immediately
+//
+// Manual runs are recorded in the history but do not affect
+// the schedule or the counter of failed deliveries.
+func (svc reportsService) Run(namespaceID, subscriptionID uint64) (*Run, error) {
+	s, err := svc.FindByID(namespaceID, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.run(s, true), nil
+}
+
+// FindRuns returns history of subscription's deliveries
+func (svc reportsService) FindRuns(namespaceID, subscriptionID uint64) (RunSet, error) {
+	if _, err := svc.FindByID(namespaceID, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindRuns(subscriptionID)
+}
+
+// run renders and delivers the report, records the run and (for scheduled runs)
+// schedules the next one
+func (svc reportsService) run(s *Subscription, manual bool) *Run {
+	var (
+		log = svc.log(zap.Uint64("subscriptionID", s.ID))
+		run = &Run{
+			SubscriptionID: s.ID,
+			Manual:         manual,
+			StartedAt:      now().Truncate(time.Second),
+		}
+	)
+
+	err := svc.deliver(s, run)
+
+	run.FinishedAt = now().Truncate(time.Second)
+	run.Status = RunSucceeded
+	if err != nil {
+		run.Status, run.Error = RunFailed, err.Error()
+		log.Warn("report delivery failed", zap.Error(err))
+	}
+
+	if _, serr := svc.repository.CreateRun(run); serr != nil {
+		log.Error("could not store report run", zap.Error(serr))
+	}
+
+	if manual {
+		return run
+	}
+
+	s.LastRunAt, s.NextRunAt = &run.StartedAt, s.next(run.StartedAt)
+
+	if err == nil {
+		s.Failures = 0
+	} else {
+		s.Failures++
+		if s.Failures >= maxFailures {
+			s.Enabled = false
+		}
+
+		// Alert on the first failure and when delivery is given up,
+		// not on every run in between
+		if s.Failures == 1 || !s.Enabled {
+			if aerr := svc.alert(s, run); aerr != nil {
+				log.Error("could not send report delivery alert", zap.Error(aerr))
+			}
+		}
+	}
+
+	if serr := svc.repository.UpdateState(s); serr != nil {
+		log.Error("could not store subscription state", zap.Error(serr))
+	}
+
+	return run
+}
+
+// deliver renders the report in the name of the owner and sends it to every recipient
+//
+// Every recipient gets a separate message; delivery continues when sending
+// to one of them fails and the first error is returned.
+func (svc reportsService) deliver(s *Subscription, run *Run) error {
+	ctx, err := runas.Compose(svc.ctx, s.OwnedBy)
+	if err != nil {
+		return err
+	}
+
+	rr, err := svc.reports(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	var tt []*table
+	for _, r := range rr {
+		t, err := r.table(ctx, s.NamespaceID)
+		if err != nil {
+			return err
+		}
+
+		tt = append(tt, t)
+	}
+
+	run.Rows = rowCount(tt)
+
+	var (
+		title      = fmt.Sprintf("%s (%s)", s.Name, run.StartedAt.In(s.location()).Format("2006-01-02"))
+		text, html bytes.Buffer
+		attachment bytes.Buffer
+		fileName   = strings.Trim(unsafeFileChars.ReplaceAllString(s.Name, "-"), "-") + "-" + run.StartedAt.In(s.location()).Format("20060102")
+	)
+
+	if err = writeText(&text, title, tt); err != nil {
+		return err
+	}
+
+	switch s.Format {
+	case FormatXLSX:
+		err = writeXLSX(&attachment, tt)
+	case FormatPDF:
+		err = writePDF(&attachment, title, tt)
+	case FormatInline:
+		err = writeHTML(&html, title, tt)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var failed error
+	for _, rcpt := range s.Recipients {
+		m := mail.New()
+
+		err = service.DefaultNotification.AttachEmailRecipients(auth.SetSuperUserContext(ctx), m, "To", rcpt)
+		if err == nil {
+			m.SetHeader("Subject", title)
+
+			if s.Format == FormatInline {
+				m.SetBody("text/plain", text.String())
+				m.AddAlternative("text/html", html.String())
+			} else {
+				m.SetBody("text/plain", fmt.Sprintf("Report %s is attached.\n", title))
+				m.AttachReader(fileName+"."+string(s.Format), bytes.NewReader(attachment.Bytes()))
+			}
+
+			err = mail.Send(m)
+		}
+
+		if err != nil {
+			if failed == nil {
+				failed = err
+			}
+
+			continue
+		}
+
+		run.Delivered++
+	}
+
+	return failed
+}
+
+// alert notifies alert recipients (or the owner) about the failed delivery
+func (svc reportsService) alert(s *Subscription, run *Run) error {
+	var (
+		m    = mail.New()
+		rcpt = append([]string{}, s.AlertRecipients...)
+		body = fmt.Sprintf(
+			"Report %q could not be delivered.\n\nStarted at: %s\nDelivered: %d of %d\nError: %s\n",
+			s.Name, run.StartedAt.Format(time.RFC3339), run.Delivered, len(s.Recipients), run.Error,
+		)
+	)
+
+	if len(rcpt) == 0 {
+		rcpt = []string{strconv.FormatUint(s.OwnedBy, 10)}
+	}
+
+	if !s.Enabled {
+		body += fmt.Sprintf("\nDelivery failed %d times in a row, subscription was disabled.\n", s.Failures)
+	}
+
+	err := service.DefaultNotification.AttachEmailRecipients(auth.SetSuperUserContext(svc.ctx), m, "To", rcpt...)
+	if err != nil {
+		return err
+	}
+
+	m.SetHeader("Subject", fmt.Sprintf("Report delivery failed: %s", s.Name))
+	m.SetBody("text/plain", body)
+
+	return mail.Send(m)
+}
+
+// next returns time of the next scheduled delivery, nil when there is none
+func (s Subscription) next(after time.Time) *time.Time {
+	rule, err := recurrence.ParseRRule(s.RRule)
+	if err != nil {
+		return nil
+	}
+
+	loc := s.location()
+	t, ok := rule.Next(s.StartsAt.In(loc), after.In(loc))
+	if !ok {
+		return nil
+	}
+
+	return &t
+}
+
+func (svc reportsService) validate(s *Subscription) error {
+	if err := svc.canManage(s.NamespaceID); err != nil {
+		return err
+	}
+
+	if s.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	if s.ChartID > 0 {
+		if _, err := svc.chart.FindByID(s.NamespaceID, s.ChartID); err != nil {
+			return err
+		}
+
+		s.Report = nil
+	} else if s.Report == nil || s.Report.ModuleID == 0 {
+		return ErrReportRequired.withStack()
+	} else {
+		if _, err := svc.module.FindByID(s.NamespaceID, s.Report.ModuleID); err != nil {
+			return err
+		}
+
+		if _, err := aliases(s.Report.Metrics, ""); err != nil {
+			return err
+		}
+
+		if _, err := aliases(s.Report.Dimensions, ""); err != nil {
+			return err
+		}
+	}
+
+	if s.Format == "" {
+		s.Format = FormatXLSX
+	} else if !s.Format.IsValid() {
+		return ErrInvalidFormat.withStack()
+	}
+
+	if _, err := recurrence.ParseRRule(s.RRule); err != nil {
+		return ErrInvalidRRule.withStack()
+	}
+
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return ErrInvalidTimezone.withStack()
+	}
+
+	if s.StartsAt.IsZero() {
+		s.StartsAt = now().Truncate(time.Second)
+	}
+
+	if len(s.Recipients) == 0 {
+		return ErrRecipientsRequired.withStack()
+	}
+
+	for _, rr := range []Recipients{s.Recipients, s.AlertRecipients} {
+		for _, rcpt := range rr {
+			if !validRecipient(rcpt) {
+				return ErrInvalidRecipient.withStack()
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc reportsService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// watch delivers scheduled reports
+func (svc reportsService) watch(ctx context.Context) {
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			ctx := auth.SetSuperUserContext(ctx)
+
+			set, err := Repository(ctx, nil).FindDue(now())
+			if err != nil {
+				svc.logger.Error("could not load due report subscriptions", zap.Error(err))
+				continue
+			}
+
+			for _, s := range set {
+				svc.with(ctx).run(s, false)
+			}
+		}
+	}
+}
+
+// validRecipient checks if recipient is a user ID or an email address
+func validRecipient(rcpt string) bool {
+	rcpt = strings.TrimSpace(rcpt)
+	if ID, err := strconv.ParseUint(rcpt, 10, 64); err == nil {
+		return ID > 0
+	}
+
+	if i := strings.Index(rcpt, " "); i > -1 {
+		// "<email> <name>"
+		rcpt = rcpt[:i]
+	}
+
+	return mail.IsValidAddress(rcpt)
+}
@@ -0,0 +1,163 @@
+package reports
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Subscription delivers rendered report to recipients on a schedule
+	//
+	// Report is either a chart (all of its reports) or an inline definition
+	// with the same metrics, dimensions & filter syntax as chart reports use.
+	Subscription struct {
+		ID          uint64 `json:"subscriptionID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Name        string `json:"name" db:"name"`
+
+		ChartID uint64  `json:"chartID,string,omitempty" db:"rel_chart"`
+		Report  *Report `json:"report,omitempty" db:"report"`
+
+		Format Format `json:"format" db:"format"`
+
+		// Recurrence rule (RFC 5545), first delivery and timezone
+		// in which deliveries are scheduled
+		RRule    string    `json:"rrule" db:"rrule"`
+		StartsAt time.Time `json:"startsAt" db:"starts_at"`
+		Timezone string    `json:"timezone" db:"timezone"`
+
+		// Who receives the report (user IDs or email addresses)
+		Recipients Recipients `json:"recipients" db:"recipients"`
+
+		// Who is notified when delivery fails, defaults to the owner;
+		// subscription is disabled after too many consecutive failures
+		AlertRecipients Recipients `json:"alertRecipients" db:"alert_recipients"`
+		Failures        uint       `json:"failures" db:"failures"`
+
+		Enabled bool `json:"enabled" db:"enabled"`
+
+		LastRunAt *time.Time `json:"lastRunAt,omitempty" db:"last_run_at"`
+		NextRunAt *time.Time `json:"nextRunAt,omitempty" db:"next_run_at"`
+
+		// Reports are generated in the name of the owner
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	SubscriptionSet []*Subscription
+
+	// Report defines one table of the delivered report
+	Report struct {
+		Title      string `json:"title"`
+		ModuleID   uint64 `json:"moduleID,string"`
+		Metrics    string `json:"metrics"`
+		Dimensions string `json:"dimensions"`
+		Filter     string `json:"filter"`
+	}
+
+	// Run is one (attempted) delivery
+	Run struct {
+		ID             uint64    `json:"runID,string" db:"id"`
+		SubscriptionID uint64    `json:"subscriptionID,string" db:"rel_subscription"`
+		Status         RunStatus `json:"status" db:"status"`
+		Rows           uint      `json:"rows" db:"row_count"`
+		Delivered      uint      `json:"delivered" db:"delivered"`
+		Error          string    `json:"error,omitempty" db:"error"`
+		Manual         bool      `json:"manual" db:"manual"`
+		StartedAt      time.Time `json:"startedAt" db:"started_at"`
+		FinishedAt     time.Time `json:"finishedAt" db:"finished_at"`
+	}
+
+	RunSet []*Run
+
+	// table is a rendered report
+	//
+	// Columns after the dimensions (count & metrics) are numeric
+	table struct {
+		Title      string
+		Columns    []string
+		Dimensions int
+		Rows       [][]string
+	}
+
+	Format    string
+	RunStatus string
+
+	Recipients []string
+)
+
+const (
+	FormatXLSX   Format = "xlsx"
+	FormatPDF    Format = "pdf"
+	FormatInline Format = "inline"
+
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+
+	// Subscription is disabled after this many consecutive failures
+	maxFailures = 5
+
+	// Max number of rows per table, reports are meant to be aggregated
+	maxRows = 1000
+
+	maxRuns = 100
+
+	watchInterval = time.Minute
+)
+
+func (f Format) IsValid() bool {
+	return f == FormatXLSX || f == FormatPDF || f == FormatInline
+}
+
+// location returns subscription's timezone
+func (s Subscription) location() *time.Location {
+	if loc, err := time.LoadLocation(s.Timezone); err == nil && s.Timezone != "" {
+		return loc
+	}
+
+	return time.UTC
+}
+
+func (r *Report) Value() (driver.Value, error) {
+	if r == nil {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(r)
+}
+
+func (r *Report) Scan(value interface{}) error {
+	if b, ok := value.([]byte); ok {
+		if err := json.Unmarshal(b, r); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Report", string(b))
+		}
+	}
+
+	return nil
+}
+
+func (rr Recipients) Value() (driver.Value, error) {
+	if rr == nil {
+		rr = Recipients{}
+	}
+
+	return json.Marshal(rr)
+}
+
+func (rr *Recipients) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*rr = Recipients{}
+	case []byte:
+		if err := json.Unmarshal(b, rr); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Recipients", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package residency
+
+import (
+	"context"
+	"io"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// attachment wraps attachment service and stores files of
+	// namespaces with residency on the assigned backend
+	attachment struct {
+		service.AttachmentService
+		ctx       context.Context
+		residency *residencyService
+	}
+)
+
+// attachmentDecorator decorates attachment service with storage routing
+func attachmentDecorator(as service.AttachmentService, rs *residencyService) service.AttachmentService {
+	return &attachment{AttachmentService: as, ctx: context.Background(), residency: rs}
+}
+
+func (svc attachment) With(ctx context.Context) service.AttachmentService {
+	return &attachment{
+		AttachmentService: svc.AttachmentService.With(ctx),
+		ctx:               ctx,
+		residency:         svc.residency.with(ctx),
+	}
+}
+
+func (svc attachment) CreatePageAttachment(namespaceID uint64, name string, size int64, fh io.ReadSeeker, pageID uint64) (*types.Attachment, error) {
+	as, err := svc.forNamespace(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return as.CreatePageAttachment(namespaceID, name, size, fh, pageID)
+}
+
+func (svc attachment) CreateRecordAttachment(namespaceID uint64, name string, size int64, fh io.ReadSeeker, moduleID, recordID uint64, fieldName string) (*types.Attachment, error) {
+	as, err := svc.forNamespace(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return as.CreateRecordAttachment(namespaceID, name, size, fh, moduleID, recordID, fieldName)
+}
+
+func (svc attachment) OpenOriginal(att *types.Attachment) (io.ReadSeeker, error) {
+	if name := backendName(att.Url); name != "" {
+		return svc.open(name, att.Url)
+	}
+
+	return svc.AttachmentService.OpenOriginal(att)
+}
+
+func (svc attachment) OpenPreview(att *types.Attachment) (io.ReadSeeker, error) {
+	if name := backendName(att.PreviewUrl); name != "" {
+		return svc.open(name, att.PreviewUrl)
+	}
+
+	return svc.AttachmentService.OpenPreview(att)
+}
+
+// forNamespace returns attachment service that stores files on namespace's backend
+func (svc attachment) forNamespace(namespaceID uint64) (service.AttachmentService, error) {
+	s, err := svc.residency.store(namespaceID)
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		return svc.AttachmentService, nil
+	}
+
+	return service.Attachment(s).With(svc.ctx), nil
+}
+
+func (svc attachment) open(backend, filename string) (io.ReadSeeker, error) {
+	b, err := svc.residency.backend(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := stores.get(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Open(filename)
+}
@@ -0,0 +1,35 @@
+package residency
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	residencyError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions     = residencyError{"NoPermissions", fault.Forbidden}
+	ErrBackendNotFound   = residencyError{"BackendNotFound", fault.NotFound}
+	ErrInvalidBackend    = residencyError{"InvalidBackend", fault.Invalid}
+	ErrResidencyNotFound = residencyError{"ResidencyNotFound", fault.NotFound}
+)
+
+func (e residencyError) Error() string {
+	return e.String()
+}
+
+func (e residencyError) String() string {
+	return "crust.residency." + e.name
+}
+
+func (e residencyError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e residencyError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,24 @@
+package residency
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200131000000.residency",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_residency (
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  backend          VARCHAR(64)     NOT NULL,
+
+  updated_by       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  updated_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
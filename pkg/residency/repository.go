@@ -0,0 +1,66 @@
+package residency
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_residency"
+}
+
+// FindByNamespace returns residency of the namespace, nil when namespace is not assigned to any backend
+func (r repository) FindByNamespace(namespaceID uint64) (*Residency, error) {
+	var (
+		res = &Residency{}
+		q   = squirrel.
+			Select("rel_namespace", "backend", "updated_by", "updated_at").
+			From(r.table()).
+			Where(squirrel.Eq{"rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, res); err != nil {
+		return nil, err
+	} else if res.NamespaceID == 0 {
+		return nil, nil
+	}
+
+	return res, nil
+}
+
+func (r repository) Save(res *Residency) (*Residency, error) {
+	rh.SetCurrentTimeRounded(&res.UpdatedAt)
+
+	return res, errors.WithStack(r.db().Replace(r.table(), res))
+}
+
+func (r repository) DeleteByNamespace(namespaceID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_namespace": namespaceID})
+}
@@ -0,0 +1,42 @@
+package residency
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts namespace residency endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("Residency.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultResidency.With(r.Context()).Read(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Put("/", rest.Handler("Residency.Set", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Backend string `json:"backend"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultResidency.With(r.Context()).Set(rest.ParamUint64(r, "namespaceID"), body.Backend)
+	}))
+
+	r.Delete("/", rest.Handler("Residency.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultResidency.With(r.Context()).Delete(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Get("/backends", rest.Handler("Residency.Backends", func(r *http.Request) (interface{}, error) {
+		return DefaultResidency.With(r.Context()).Backends(rest.ParamUint64(r, "namespaceID"))
+	}))
+}
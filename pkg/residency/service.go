@@ -0,0 +1,218 @@
+package residency
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	"github.com/cortezaproject/corteza-server/pkg/store"
+)
+
+type (
+	residencyService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		settings settingsGetter
+
+		namespace service.NamespaceService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	ResidencyService interface {
+		With(ctx context.Context) ResidencyService
+
+		Backends(namespaceID uint64) ([]*BackendInfo, error)
+
+		Read(namespaceID uint64) (*Residency, error)
+		Set(namespaceID uint64, backend string) (*Residency, error)
+		Delete(namespaceID uint64) error
+	}
+)
+
+var (
+	DefaultResidency ResidencyService
+)
+
+// Init initializes residency service and routes attachments of namespaces with residency
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := (&residencyService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		settings:  service.DefaultSettings,
+		namespace: service.DefaultNamespace,
+	}).with(ctx)
+
+	DefaultResidency = svc
+	service.DefaultAttachment = attachmentDecorator(service.DefaultAttachment, svc)
+
+	return nil
+}
+
+func (svc residencyService) With(ctx context.Context) ResidencyService {
+	return svc.with(ctx)
+}
+
+func (svc residencyService) with(ctx context.Context) *residencyService {
+	return &residencyService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		ac:       svc.ac,
+		settings: svc.settings,
+
+		namespace: svc.namespace.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc residencyService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Backends returns names and regions of backends that namespace can be assigned to
+func (svc residencyService) Backends(namespaceID uint64) ([]*BackendInfo, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	bb, err := svc.backends()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*BackendInfo, len(bb))
+	for i, b := range bb {
+		out[i] = b.info()
+	}
+
+	return out, nil
+}
+
+func (svc residencyService) Read(namespaceID uint64) (*Residency, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	res, err := svc.repository.FindByNamespace(namespaceID)
+	if err != nil {
+		return nil, err
+	} else if res == nil {
+		return nil, ErrResidencyNotFound.withStack()
+	}
+
+	return res, nil
+}
+
+// Set assigns namespace to the backend
+//
+// Connection to the backend is checked before the assignment is stored.
+func (svc residencyService) Set(namespaceID uint64, backend string) (*Residency, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	b, err := svc.backend(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = stores.get(b); err != nil {
+		svc.log(zap.String("backend", b.Name)).Error("could not connect to backend", zap.Error(err))
+		return nil, ErrInvalidBackend.withStack()
+	}
+
+	res := &Residency{
+		NamespaceID: namespaceID,
+		Backend:     b.Name,
+		UpdatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+	}
+
+	if res, err = svc.repository.Save(res); err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("namespaceID", namespaceID), zap.String("backend", b.Name)).Info("namespace residency changed")
+	return res, nil
+}
+
+// Delete removes residency, new attachments are stored in the default store
+func (svc residencyService) Delete(namespaceID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByNamespace(namespaceID)
+}
+
+// store returns store of namespace's backend, nil when namespace has no residency
+func (svc residencyService) store(namespaceID uint64) (store.Store, error) {
+	res, err := svc.repository.FindByNamespace(namespaceID)
+	if err != nil || res == nil {
+		return nil, err
+	}
+
+	b, err := svc.backend(res.Backend)
+	if err != nil {
+		// Never fall back to the default store, that would break residency
+		return nil, err
+	}
+
+	return stores.get(b)
+}
+
+func (svc residencyService) backend(name string) (*Backend, error) {
+	bb, err := svc.backends()
+	if err != nil {
+		return nil, err
+	}
+
+	if b := bb.FindByName(name); b != nil {
+		return b, nil
+	}
+
+	return nil, ErrBackendNotFound.withStack()
+}
+
+// backends returns backends from compose settings
+func (svc residencyService) backends() (bb BackendSet, err error) {
+	v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingBackends, 0)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	return bb, v.Value.Unmarshal(&bb)
+}
+
+func (svc residencyService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
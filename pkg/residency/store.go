@@ -0,0 +1,110 @@
+package residency
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/cortezaproject/corteza-server/pkg/store"
+	"github.com/cortezaproject/corteza-server/pkg/store/minio"
+	"github.com/cortezaproject/corteza-server/pkg/store/plain"
+)
+
+type (
+	// backendStore prefixes file names with name of the backend
+	//
+	// Prefix is stored with the attachment (URL) so that files can be
+	// opened even after namespace is assigned to a different backend.
+	backendStore struct {
+		name string
+		base store.Store
+	}
+
+	// storeCache keeps connected stores and replaces them when backend's configuration changes
+	storeCache struct {
+		sync.Mutex
+		stores  map[string]store.Store
+		configs map[string]string
+	}
+)
+
+var (
+	stores = &storeCache{
+		stores:  map[string]store.Store{},
+		configs: map[string]string{},
+	}
+)
+
+func (s backendStore) Original(id uint64, ext string) string {
+	return s.name + backendSeparator + s.base.Original(id, ext)
+}
+
+func (s backendStore) Preview(id uint64, ext string) string {
+	return s.name + backendSeparator + s.base.Preview(id, ext)
+}
+
+func (s backendStore) Save(filename string, f io.Reader) error {
+	return s.base.Save(s.strip(filename), f)
+}
+
+func (s backendStore) Remove(filename string) error {
+	return s.base.Remove(s.strip(filename))
+}
+
+func (s backendStore) Open(filename string) (io.ReadSeeker, error) {
+	return s.base.Open(s.strip(filename))
+}
+
+func (s backendStore) strip(filename string) string {
+	return strings.TrimPrefix(filename, s.name+backendSeparator)
+}
+
+// get returns store for the backend
+func (c *storeCache) get(b *Backend) (store.Store, error) {
+	cfg, _ := json.Marshal(b)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if s, ok := c.stores[b.Name]; ok && c.configs[b.Name] == string(cfg) {
+		return s, nil
+	}
+
+	base, err := connect(b)
+	if err != nil {
+		return nil, err
+	}
+
+	c.stores[b.Name] = &backendStore{name: b.Name, base: base}
+	c.configs[b.Name] = string(cfg)
+
+	return c.stores[b.Name], nil
+}
+
+func connect(b *Backend) (store.Store, error) {
+	if b.Path != "" {
+		return plain.New(b.Path)
+	}
+
+	return minio.New(b.Bucket, minio.Options{
+		Endpoint:        b.Endpoint,
+		Secure:          b.Secure,
+		Strict:          true,
+		AccessKeyID:     b.AccessKeyID,
+		SecretAccessKey: b.SecretAccessKey,
+
+		ServerSideEncryptKey: []byte(b.SSECKey),
+	})
+}
+
+// backendName extracts name of the backend from the attachment URL
+//
+// Returns empty string for files in the default store.
+func backendName(url string) string {
+	if i := strings.Index(url, backendSeparator); i > 0 && !strings.Contains(url[:i], "/") {
+		return url[:i]
+	}
+
+	return ""
+}
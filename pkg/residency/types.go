@@ -0,0 +1,74 @@
+package residency
+
+import (
+	"time"
+)
+
+type (
+	// Backend is a storage where attachments of assigned namespaces are kept
+	//
+	// Backends are defined by administrators in compose settings
+	// (crust.residency.backends); they are either S3 compatible (endpoint
+	// and bucket) or local directories (path), for example a volume that
+	// is mounted from the storage in the required region.
+	//
+	// Buckets must exist; they are never created automatically
+	// since that would put them in the default region.
+	Backend struct {
+		Name   string `json:"name"`
+		Region string `json:"region"`
+
+		Endpoint        string `json:"endpoint,omitempty"`
+		Secure          bool   `json:"secure,omitempty"`
+		Bucket          string `json:"bucket,omitempty"`
+		AccessKeyID     string `json:"accessKeyID,omitempty"`
+		SecretAccessKey string `json:"secretAccessKey,omitempty"`
+		SSECKey         string `json:"sseCKey,omitempty"`
+
+		Path string `json:"path,omitempty"`
+	}
+
+	BackendSet []*Backend
+
+	// BackendInfo is a backend, as visible to namespace managers
+	BackendInfo struct {
+		Name   string `json:"name"`
+		Region string `json:"region"`
+	}
+
+	// Residency assigns namespace to a backend
+	//
+	// New attachments of the namespace are stored there. Existing
+	// attachments stay where they were stored when uploaded.
+	//
+	// Record data is not routed; it stays in the compose database,
+	// deployments that need it in the region run a separate instance.
+	Residency struct {
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Backend     string `json:"backend" db:"backend"`
+
+		UpdatedBy uint64    `json:"updatedBy,string" db:"updated_by"`
+		UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	}
+)
+
+const (
+	settingBackends = "crust.residency.backends"
+
+	// Separates backend name from the file name in attachment URLs
+	backendSeparator = ":"
+)
+
+func (set BackendSet) FindByName(name string) *Backend {
+	for _, b := range set {
+		if b.Name == name {
+			return b
+		}
+	}
+
+	return nil
+}
+
+func (b Backend) info() *BackendInfo {
+	return &BackendInfo{Name: b.Name, Region: b.Region}
+}
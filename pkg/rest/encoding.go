@@ -0,0 +1,211 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+)
+
+type (
+	// encoding of responses other than JSON
+	//
+	// Values are first encoded as JSON, so that all encodings share field
+	// names (json tags, naming of the format) and the redaction; the decoded
+	// JSON (maps, slices, json.Number, strings, bools and nils) is encoded then.
+	encoding struct {
+		contentType string
+		encode      func(buf *bytes.Buffer, v interface{})
+	}
+)
+
+const (
+	MessagePack = "application/msgpack"
+
+	// Responses are encoded as google.protobuf.Value (struct.proto)
+	Protobuf = "application/x-protobuf"
+)
+
+var (
+	encodings = map[string]encoding{
+		MessagePack: {contentType: MessagePack, encode: msgpack},
+		Protobuf:    {contentType: Protobuf + "; messageType=google.protobuf.Value", encode: protobufValue},
+	}
+
+	// Other media types clients use for the encodings
+	encodingAliases = map[string]string{
+		"application/x-msgpack":   MessagePack,
+		"application/vnd.msgpack": MessagePack,
+		"application/protobuf":    Protobuf,
+	}
+)
+
+// msgpack writes MessagePack (https://msgpack.org) encoding of the decoded JSON
+//
+// Integers are kept as integers; keys of maps are sorted.
+func msgpack(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			msgpackInt(buf, i)
+		} else if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			buf.WriteByte(0xcf)
+			_ = binary.Write(buf, binary.BigEndian, u)
+		} else {
+			f, _ := v.Float64()
+			buf.WriteByte(0xcb)
+			_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+		}
+	case string:
+		msgpackHeader(buf, len(v), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		msgpackHeader(buf, len(v), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			msgpack(buf, item)
+		}
+	case map[string]interface{}:
+		msgpackHeader(buf, len(v), 0x80, 16, 0, 0xde, 0xdf)
+		for _, k := range keys(v) {
+			msgpack(buf, k)
+			msgpack(buf, v[k])
+		}
+	}
+}
+
+func msgpackInt(buf *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i < 128:
+		buf.WriteByte(byte(i))
+	case i < 0 && i >= -32:
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		buf.WriteByte(0xd0)
+		buf.WriteByte(byte(int8(i)))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		buf.WriteByte(0xd1)
+		_ = binary.Write(buf, binary.BigEndian, int16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		buf.WriteByte(0xd2)
+		_ = binary.Write(buf, binary.BigEndian, int32(i))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, i)
+	}
+}
+
+// msgpackHeader writes type and length of string, array or map
+//
+// Short ones have the length in the type byte (fix); 8 bit lengths are
+// available for strings only.
+func msgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, b8, b16, b32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case n <= math.MaxUint8 && b8 != 0:
+		buf.WriteByte(b8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(b16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(b32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// protobufValue writes the decoded JSON as google.protobuf.Value message
+//
+// Numbers are doubles there; IDs are strings in JSON already.
+func protobufValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		// null_value = 1 (enum NullValue.NULL_VALUE)
+		protobufTag(buf, 1, 0)
+		protobufUvarint(buf, 0)
+	case bool:
+		// bool_value = 4
+		var b uint64
+		if v {
+			b = 1
+		}
+
+		protobufTag(buf, 4, 0)
+		protobufUvarint(buf, b)
+	case json.Number:
+		// number_value = 2
+		f, _ := v.Float64()
+		protobufTag(buf, 2, 1)
+		_ = binary.Write(buf, binary.LittleEndian, math.Float64bits(f))
+	case string:
+		// string_value = 3
+		protobufBytes(buf, 3, []byte(v))
+	case []interface{}:
+		// list_value = 6, ListValue { repeated Value values = 1 }
+		list := &bytes.Buffer{}
+		for _, item := range v {
+			protobufMessage(list, 1, item)
+		}
+
+		protobufBytes(buf, 6, list.Bytes())
+	case map[string]interface{}:
+		// struct_value = 5, Struct { map<string, Value> fields = 1 }
+		st := &bytes.Buffer{}
+		for _, k := range keys(v) {
+			entry := &bytes.Buffer{}
+			protobufBytes(entry, 1, []byte(k))
+			protobufMessage(entry, 2, v[k])
+			protobufBytes(st, 1, entry.Bytes())
+		}
+
+		protobufBytes(buf, 5, st.Bytes())
+	}
+}
+
+// protobufMessage writes Value message as the field
+func protobufMessage(buf *bytes.Buffer, field uint64, v interface{}) {
+	msg := &bytes.Buffer{}
+	protobufValue(msg, v)
+	protobufBytes(buf, field, msg.Bytes())
+}
+
+// protobufBytes writes length delimited field (wire type 2)
+func protobufBytes(buf *bytes.Buffer, field uint64, b []byte) {
+	protobufTag(buf, field, 2)
+	protobufUvarint(buf, uint64(len(b)))
+	buf.Write(b)
+}
+
+// protobufTag writes key of the field, number and wire type
+func protobufTag(buf *bytes.Buffer, field, wire uint64) {
+	protobufUvarint(buf, field<<3|wire)
+}
+
+func protobufUvarint(buf *bytes.Buffer, v uint64) {
+	for v >= 0x80 {
+		buf.WriteByte(byte(v) | 0x80)
+		v >>= 7
+	}
+
+	buf.WriteByte(byte(v))
+}
+
+func keys(m map[string]interface{}) []string {
+	kk := make([]string, 0, len(m))
+	for k := range m {
+		kk = append(kk, k)
+	}
+
+	sort.Strings(kk)
+	return kk
+}
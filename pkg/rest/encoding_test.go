@@ -0,0 +1,216 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+type (
+	// Messages of google.protobuf.Value (struct.proto), as generated by protoc-gen-go;
+	// protobuf's unmarshaler decodes the responses into them
+	pbValue struct {
+		Kind isPbKind `protobuf_oneof:"kind"`
+	}
+
+	pbStruct struct {
+		Fields map[string]*pbValue `protobuf:"bytes,1,rep,name=fields,proto3" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	}
+
+	pbList struct {
+		Values []*pbValue `protobuf:"bytes,1,rep,name=values,proto3"`
+	}
+
+	isPbKind interface {
+		isPbKind()
+	}
+
+	pbNullValue struct {
+		NullValue int32 `protobuf:"varint,1,opt,name=null_value,proto3,oneof"`
+	}
+
+	pbNumberValue struct {
+		NumberValue float64 `protobuf:"fixed64,2,opt,name=number_value,proto3,oneof"`
+	}
+
+	pbStringValue struct {
+		StringValue string `protobuf:"bytes,3,opt,name=string_value,proto3,oneof"`
+	}
+
+	pbBoolValue struct {
+		BoolValue bool `protobuf:"varint,4,opt,name=bool_value,proto3,oneof"`
+	}
+
+	pbStructValue struct {
+		StructValue *pbStruct `protobuf:"bytes,5,opt,name=struct_value,proto3,oneof"`
+	}
+
+	pbListValue struct {
+		ListValue *pbList `protobuf:"bytes,6,opt,name=list_value,proto3,oneof"`
+	}
+)
+
+func (m *pbValue) Reset()         { *m = pbValue{} }
+func (m *pbValue) String() string { return proto.CompactTextString(m) }
+func (*pbValue) ProtoMessage()    {}
+
+func (*pbValue) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*pbNullValue)(nil),
+		(*pbNumberValue)(nil),
+		(*pbStringValue)(nil),
+		(*pbBoolValue)(nil),
+		(*pbStructValue)(nil),
+		(*pbListValue)(nil),
+	}
+}
+
+func (m *pbStruct) Reset()         { *m = pbStruct{} }
+func (m *pbStruct) String() string { return proto.CompactTextString(m) }
+func (*pbStruct) ProtoMessage()    {}
+
+func (m *pbList) Reset()         { *m = pbList{} }
+func (m *pbList) String() string { return proto.CompactTextString(m) }
+func (*pbList) ProtoMessage()    {}
+
+func (*pbNullValue) isPbKind()   {}
+func (*pbNumberValue) isPbKind() {}
+func (*pbStringValue) isPbKind() {}
+func (*pbBoolValue) isPbKind()   {}
+func (*pbStructValue) isPbKind() {}
+func (*pbListValue) isPbKind()   {}
+
+// plain returns the decoded message as JSON would be decoded with encoding/json
+func (m *pbValue) plain() interface{} {
+	switch k := m.Kind.(type) {
+	case *pbNumberValue:
+		return k.NumberValue
+	case *pbStringValue:
+		return k.StringValue
+	case *pbBoolValue:
+		return k.BoolValue
+	case *pbStructValue:
+		obj := map[string]interface{}{}
+		for key, v := range k.StructValue.Fields {
+			obj[key] = v.plain()
+		}
+
+		return obj
+	case *pbListValue:
+		arr := []interface{}{}
+		for _, v := range k.ListValue.Values {
+			arr = append(arr, v.plain())
+		}
+
+		return arr
+	}
+
+	return nil
+}
+
+// decoded returns JSON decoded the way Marshal decodes it before it is encoded
+func decoded(t *testing.T, in string) interface{} {
+	var (
+		dec = json.NewDecoder(strings.NewReader(in))
+		aux interface{}
+	)
+
+	dec.UseNumber()
+	if err := dec.Decode(&aux); err != nil {
+		t.Fatal(err)
+	}
+
+	return aux
+}
+
+func TestMsgpack(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"nil", `null`, "c0"},
+		{"false", `false`, "c2"},
+		{"true", `true`, "c3"},
+		{"positive fixint", `127`, "7f"},
+		{"negative fixint", `-32`, "e0"},
+		{"int 8", `-33`, "d0df"},
+		{"int 16", `300`, "d1012c"},
+		{"int 32", `-70000`, "d2fffeee90"},
+		{"int 64", `1099511627776`, "d30000010000000000"},
+		{"ID", `98765432109876543`, "d3015ee2a320ff453f"},
+		{"uint 64", `18446744073709551615`, "cfffffffffffffffff"},
+		{"float", `1.5`, "cb3ff8000000000000"},
+		{"float with exponent", `-2.5e-3`, "cbbf647ae147ae147b"},
+		{"fixstr", `"crust"`, "a56372757374"},
+		{"str 8", `"` + strings.Repeat("a", 32) + `"`, "d920" + strings.Repeat("61", 32)},
+		{"str 16", `"` + strings.Repeat("a", 256) + `"`, "da0100" + strings.Repeat("61", 256)},
+		{"multibyte string", `"č"`, "a2c48d"},
+		{"fixarray", `[1,"a",null]`, "9301a161c0"},
+		{"array 16", `[` + strings.Repeat("0,", 15) + `0]`, "dc0010" + strings.Repeat("00", 16)},
+		{"fixmap with sorted keys", `{"b":1,"a":[]}`, "82a16190a16201"},
+		{"nested", `{"response":{"recordID":"98765432109876543","values":[{"name":"x","value":2.25}]}}`,
+			"81a8726573706f6e736582a87265636f72644944b13938373635343332313039383736353433a676616c7565739182a46e616d65a178a576616c7565cb4002000000000000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			msgpack(buf, decoded(t, tt.in))
+
+			if got := hex.EncodeToString(buf.Bytes()); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProtobufValue(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"null", `null`},
+		{"bool", `true`},
+		{"false", `false`},
+		{"integer", `42`},
+		{"negative", `-7`},
+		{"float", `0.1`},
+		{"float with exponent", `-2.5e-3`},
+		{"zero", `0`},
+		{"ID as string", `"98765432109876543"`},
+		{"ID as number", `9007199254740992`},
+		{"empty string", `""`},
+		{"string", `"čžš"`},
+		{"empty list", `[]`},
+		{"empty object", `{}`},
+		{"record", `{"response":{"recordID":"98765432109876543","values":[{"name":"x","value":2.25},{"name":"y","value":null}],"deleted":false}}`},
+		{"long list", `[` + strings.Repeat(`"`+strings.Repeat("a", 200)+`",`, 10) + `1]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			protobufValue(buf, decoded(t, tt.in))
+
+			msg := &pbValue{}
+			if err := proto.Unmarshal(buf.Bytes(), msg); err != nil {
+				t.Fatalf("could not decode %x: %v", buf.Bytes(), err)
+			}
+
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.in), &want); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := msg.plain(); !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %#v, got %#v", want, got)
+			}
+		})
+	}
+}
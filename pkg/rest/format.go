@@ -0,0 +1,360 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/titpetric/factory/resputil"
+)
+
+type (
+	// Format of successful responses, negotiated with the client
+	//
+	// Legacy clients get the zero value: values wrapped in {"response": ...},
+	// keys as they are encoded (camelCase).
+	Format struct {
+		// Values are sent as they are, without the {"response": ...} wrapper
+		Flat bool
+
+		// Keys of JSON objects are sent in snake_case
+		Snake bool
+
+		// Media type of the encoding (MessagePack, Protobuf), JSON when empty
+		Encoding string
+	}
+)
+
+const (
+	versionHeader  = "X-Crust-Api-Version"
+	envelopeHeader = "X-Crust-Envelope"
+	namingHeader   = "X-Crust-Naming"
+)
+
+var (
+	// Default formats of API versions; clients that do not ask for a version get v1
+	versions = map[string]Format{
+		"1": {},
+		"2": {Flat: true},
+	}
+
+	// Version can also be requested with vendor media type, application/vnd.crust.v2+json
+	acceptVersion = regexp.MustCompile(`application/vnd\.crust\.v(\d+)\+json`)
+
+	successType   = reflect.TypeOf(resputil.OK())
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// Negotiate returns response format that the client asked for
+//
+// Version (X-Crust-Api-Version header or vendor media type in Accept header)
+// selects the default format; envelope (X-Crust-Envelope: wrapped, flat) and
+// naming (X-Crust-Naming: camelCase, snake_case) headers override it.
+// Unknown versions and values are ignored.
+//
+// Encoding is the first of the media types in Accept header that is known,
+// MessagePack or Protobuf; clients get JSON otherwise. Errors are always
+// sent as JSON.
+//
+// Only responses of crust's handlers (Handler, Respond) are negotiated;
+// corteza's endpoints, mounted on the same router, ignore the headers and
+// keep sending the legacy format. Renaming keys needs Go values of the
+// responses (see Marshal), so it can not be done by a middleware that
+// rewrites encoded responses.
+func Negotiate(r *http.Request) Format {
+	var (
+		f       Format
+		version = r.Header.Get(versionHeader)
+	)
+
+	if m := acceptVersion.FindStringSubmatch(r.Header.Get("Accept")); version == "" && m != nil {
+		version = m[1]
+	}
+
+	if v, ok := versions[strings.TrimPrefix(strings.ToLower(version), "v")]; ok {
+		f = v
+	}
+
+	f.Encoding = accepted(r.Header.Get("Accept"))
+
+	switch strings.ToLower(r.Header.Get(envelopeHeader)) {
+	case "wrapped":
+		f.Flat = false
+	case "flat":
+		f.Flat = true
+	}
+
+	switch strings.ToLower(r.Header.Get(namingHeader)) {
+	case "camelcase", "camel":
+		f.Snake = false
+	case "snake_case", "snake":
+		f.Snake = true
+	}
+
+	return f
+}
+
+// Marshal encodes the value in the format, without the wrapper
+//
+// Keys that come from struct fields are renamed to snake_case if needed;
+// keys of maps (user data, such as record values or settings) and of values
+// that encode themselves (json.Marshaler) are sent as they are.
+func (f Format) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || (!f.Snake && f.Encoding == "") {
+		return b, err
+	}
+
+	var (
+		dec = json.NewDecoder(bytes.NewReader(b))
+		aux interface{}
+	)
+
+	// Numbers are kept as they are, IDs would lose precision as float64
+	dec.UseNumber()
+	if err = dec.Decode(&aux); err != nil {
+		return nil, err
+	}
+
+	if f.Snake {
+		aux = snakeKeys(reflect.ValueOf(v), aux)
+	}
+
+	if e, ok := encodings[f.Encoding]; ok {
+		buf := &bytes.Buffer{}
+		e.encode(buf, aux)
+		return buf.Bytes(), nil
+	}
+
+	return json.Marshal(aux)
+}
+
+// ContentType returns media type of the encoded responses
+func (f Format) ContentType() string {
+	if e, ok := encodings[f.Encoding]; ok {
+		return e.contentType
+	}
+
+	return "application/json"
+}
+
+// Respond sends the value in the format negotiated with the client
+//
+// For controllers that return a func to set headers or status first.
+func Respond(w http.ResponseWriter, r *http.Request, v interface{}) {
+	Negotiate(r).respond(w, v)
+}
+
+// respond sends the value to the client
+//
+// Legacy format is left to resputil, so that the responses do not change.
+func (f Format) respond(w http.ResponseWriter, v interface{}) {
+	w.Header().Add("Vary", strings.Join([]string{"Accept", versionHeader, envelopeHeader, namingHeader}, ", "))
+
+	if f == (Format{}) {
+		resputil.JSON(w, v)
+		return
+	}
+
+	// Success messages (resputil.OK) are never wrapped
+	if !f.Flat && reflect.TypeOf(v) != successType {
+		v = struct {
+			Response interface{} `json:"response"`
+		}{v}
+	}
+
+	b, err := f.Marshal(v)
+	if err != nil {
+		resputil.JSON(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", f.ContentType())
+	_, _ = w.Write(b)
+}
+
+// accepted returns the first encoding (other than JSON) of Accept header's media types
+//
+// Quality values are ignored, clients list the preferred type first.
+func accepted(accept string) string {
+	for _, mt := range strings.Split(accept, ",") {
+		mt = strings.ToLower(strings.TrimSpace(strings.SplitN(mt, ";", 2)[0]))
+		if alias, ok := encodingAliases[mt]; ok {
+			mt = alias
+		}
+
+		if _, ok := encodings[mt]; ok {
+			return mt
+		}
+
+		if mt == "application/json" || acceptVersion.MatchString(mt) {
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// snakeKeys renames keys of the decoded JSON value that come from fields of structs in v
+//
+// Value and its JSON are walked side by side, so that keys of maps are told apart from fields.
+func snakeKeys(v reflect.Value, aux interface{}) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return aux
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Type().Implements(marshalerType) || (v.CanAddr() && v.Addr().Type().Implements(marshalerType)) {
+		return aux
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		obj, ok := aux.(map[string]interface{})
+		if !ok {
+			return aux
+		}
+
+		var (
+			fields = jsonFields(v)
+			m      = make(map[string]interface{}, len(obj))
+		)
+
+		for k, val := range obj {
+			if f, ok := fields[k]; ok {
+				m[snake(k)] = snakeKeys(f, val)
+			} else {
+				m[k] = val
+			}
+		}
+
+		return m
+	case reflect.Map:
+		obj, ok := aux.(map[string]interface{})
+		if !ok {
+			return aux
+		}
+
+		for iter := v.MapRange(); iter.Next(); {
+			k := mapKey(iter.Key())
+			if val, ok := obj[k]; ok {
+				obj[k] = snakeKeys(iter.Value(), val)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := aux.([]interface{})
+		if !ok {
+			return aux
+		}
+
+		for i := range arr {
+			if i < v.Len() {
+				arr[i] = snakeKeys(v.Index(i), arr[i])
+			}
+		}
+	}
+
+	return aux
+}
+
+// mapKey returns key of the map (string or integer) the way encoding/json encodes it
+func mapKey(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+
+	return fmt.Sprint(k.Interface())
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+
+	return t
+}
+
+// jsonFields returns struct's fields by their JSON names, with fields of embedded structs
+//
+// Fields of the struct itself take precedence over embedded ones, as they do in encoding/json.
+func jsonFields(v reflect.Value) map[string]reflect.Value {
+	var (
+		fields   = map[string]reflect.Value{}
+		embedded []reflect.Value
+	)
+
+	for i := 0; i < v.NumField(); i++ {
+		var (
+			sf   = v.Type().Field(i)
+			tag  = sf.Tag.Get("json")
+			name = strings.Split(tag, ",")[0]
+		)
+
+		if tag == "-" || (sf.PkgPath != "" && !sf.Anonymous) {
+			continue
+		}
+
+		if f := reflect.Indirect(v.Field(i)); sf.Anonymous && name == "" && indirect(sf.Type).Kind() == reflect.Struct {
+			if f.IsValid() {
+				embedded = append(embedded, f)
+			}
+
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fields[name] = v.Field(i)
+	}
+
+	for _, e := range embedded {
+		for name, f := range jsonFields(e) {
+			if _, ok := fields[name]; !ok {
+				fields[name] = f
+			}
+		}
+	}
+
+	return fields
+}
+
+// snake converts camelCase key to snake_case, "ownerIDs" => "owner_ids"
+//
+// Acronyms are kept together: "recordID" => "record_id", "URLPath" => "url_path".
+func snake(s string) string {
+	var (
+		rr  = []rune(strings.Replace(s, "IDs", "Ids", -1))
+		out = make([]rune, 0, len(rr)+4)
+	)
+
+	for i, r := range rr {
+		if unicode.IsUpper(r) && i > 0 {
+			var (
+				prev = rr[i-1]
+				next = i+1 < len(rr) && unicode.IsLower(rr[i+1])
+			)
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && next) {
+				out = append(out, '_')
+			}
+		}
+
+		out = append(out, unicode.ToLower(r))
+	}
+
+	return string(out)
+}
@@ -0,0 +1,71 @@
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type (
+	snakeOwner struct {
+		OwnerID uint64 `json:"ownerID,string"`
+	}
+
+	snakeRecord struct {
+		snakeOwner
+		RecordID uint64                 `json:"recordID,string"`
+		Values   map[string]interface{} `json:"values"`
+		Meta     json.RawMessage        `json:"meta"`
+		Children []snakeRecord          `json:"childRecords,omitempty"`
+		ByName   map[string]snakeOwner  `json:"byName,omitempty"`
+	}
+)
+
+func TestFormatMarshalSnake(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{
+			name: "fields of structs",
+			v:    snakeRecord{snakeOwner: snakeOwner{OwnerID: 1}, RecordID: 2},
+			want: `{"meta":null,"owner_id":"1","record_id":"2","values":null}`,
+		},
+		{
+			name: "keys of maps are kept",
+			v:    snakeRecord{Values: map[string]interface{}{"firstName": "Jane", "homeAddress": map[string]interface{}{"zipCode": "1000"}}},
+			want: `{"meta":null,"owner_id":"0","record_id":"0","values":{"firstName":"Jane","homeAddress":{"zipCode":"1000"}}}`,
+		},
+		{
+			name: "values that encode themselves are kept",
+			v:    snakeRecord{Meta: json.RawMessage(`{"createdBy":"x"}`)},
+			want: `{"meta":{"createdBy":"x"},"owner_id":"0","record_id":"0","values":null}`,
+		},
+		{
+			name: "structs in slices and maps",
+			v: &snakeRecord{
+				Children: []snakeRecord{{RecordID: 3}},
+				ByName:   map[string]snakeOwner{"janeDoe": {OwnerID: 4}},
+			},
+			want: `{"by_name":{"janeDoe":{"owner_id":"4"}},"child_records":[{"meta":null,"owner_id":"0","record_id":"3","values":null}],"meta":null,"owner_id":"0","record_id":"0","values":null}`,
+		},
+		{
+			name: "maps of values",
+			v:    []map[string]interface{}{{"recordID": snakeOwner{OwnerID: 5}}},
+			want: `[{"recordID":{"owner_id":"5"}}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Format{Snake: true}.Marshal(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(b) != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, b)
+			}
+		})
+	}
+}
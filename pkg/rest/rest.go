@@ -0,0 +1,248 @@
+package rest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory/resputil"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/redact"
+	"github.com/crusttech/crust-server/pkg/validate"
+)
+
+type (
+	// Controller is a simplified version of corteza's generated handler funcs
+	//
+	// Returned value is encoded and sent back to the client
+	// the same way as corteza's handlers do it.
+	Controller func(r *http.Request) (interface{}, error)
+)
+
+// Handler wraps controller into http.HandlerFunc
+//
+// Name is used for logging controller calls & errors. Fields of the
+// returned value that the caller can not read are redacted (see redact pkg)
+// and the value is sent in the format the client asked for (see Negotiate).
+func Handler(name string, ctrl Controller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		value, err := ctrl(r)
+		if err != nil {
+			respondError(w, r, name, err)
+			return
+		}
+
+		logger.LogControllerCall(name, r, nil)
+
+		if fn, ok := value.(func(http.ResponseWriter, *http.Request)); ok {
+			fn(w, r)
+			return
+		}
+
+		Negotiate(r).respond(w, redact.Value(r.Context(), value))
+	}
+}
+
+// Error sends the error back to the client, with HTTP status of its kind
+//
+// Middlewares that reject requests use it the same way handlers do.
+// Errors that are not of services (see fault pkg) are sent as internal
+// ones, so that a rejected request never looks like a successful one.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	if _, ok := fault.As(err); !ok {
+		if _, ok := errors.Cause(err).(validate.Errors); !ok {
+			err = fault.New(err)
+		}
+	}
+
+	respondError(w, r, "middleware", err)
+}
+
+// respondError logs the error and sends it back to the client
+//
+// Errors of services (see fault pkg) are sent with HTTP status of their kind,
+// together with IDs and the message for the user; internal ones are logged
+// as errors. Other errors are sent the same way as corteza's handlers do it.
+func respondError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	if ee, ok := errors.Cause(err).(validate.Errors); ok {
+		logger.LogParamError(name, r, err)
+		fieldErrors(w, ee)
+		return
+	}
+
+	fe, ok := fault.As(err)
+	if !ok {
+		logger.LogControllerError(name, r, err, nil)
+		resputil.JSON(w, err)
+		return
+	}
+
+	var (
+		status = fault.Status(fe)
+		log    = logger.ContextValue(r.Context()).With(zap.String("controller", name), zap.Error(err))
+		rsp    struct {
+			Error struct {
+				Message    string            `json:"message"`
+				Details    string            `json:"details,omitempty"`
+				IDs        map[string]string `json:"ids,omitempty"`
+				RetryAfter int               `json:"retryAfter,omitempty"`
+			} `json:"error"`
+		}
+	)
+
+	if status >= http.StatusInternalServerError {
+		log.Error("error in REST controller "+name, fault.Fields(fe)...)
+	} else {
+		log.Debug("error in REST controller "+name, fault.Fields(fe)...)
+	}
+
+	rsp.Error.Message = fe.Error()
+	rsp.Error.Details = fe.Message
+	rsp.Error.IDs = fe.IDMap()
+
+	if fe.RetryAfter > 0 {
+		// Whole seconds, rounded up
+		rsp.Error.RetryAfter = int((fe.RetryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(rsp.Error.RetryAfter))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(rsp)
+}
+
+// fieldErrors responds with failed fields of the request,
+// in the same envelope as other errors
+func fieldErrors(w http.ResponseWriter, ee validate.Errors) {
+	var rsp struct {
+		Error struct {
+			Message string          `json:"message"`
+			Fields  validate.Errors `json:"fields"`
+		} `json:"error"`
+	}
+
+	rsp.Error.Message = ee.Error()
+	rsp.Error.Fields = ee
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(rsp)
+}
+
+// Validate checks the request against rules in its validate tags
+//
+// Generated handlers call it through rest package, so that
+// it does not collide with names in the handler's package.
+func Validate(req interface{}) error {
+	return validate.Struct(req)
+}
+
+// Decode decodes JSON request body into dst
+//
+// Empty body is not considered an error. Structs are checked against
+// rules in their validate tags (see Validate) after they are decoded,
+// in generated and hand-written handlers alike; embedded structs are not.
+func Decode(r *http.Request, dst interface{}) error {
+	if err := decode(r, dst); err != nil {
+		return err
+	}
+
+	if reflect.Indirect(reflect.ValueOf(dst)).Kind() != reflect.Struct {
+		return nil
+	}
+
+	return Validate(dst)
+}
+
+func decode(r *http.Request, dst interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+
+	if ct := strings.ToLower(r.Header.Get("content-type")); ct != "" && !strings.HasPrefix(ct, "application/json") {
+		return errors.Errorf("unsupported content type %q", ct)
+	}
+
+	switch err := json.NewDecoder(r.Body).Decode(dst); {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return errors.Wrap(err, "error parsing http request body")
+	default:
+		return nil
+	}
+}
+
+// ParamUint64 returns URL param as uint64 (0 if param is missing or invalid)
+func ParamUint64(r *http.Request, name string) uint64 {
+	return payload.ParseUInt64(chi.URLParam(r, name))
+}
+
+// QueryUint64 returns query string value as uint64 (0 if missing or invalid)
+func QueryUint64(r *http.Request, name string) uint64 {
+	return payload.ParseUInt64(r.URL.Query().Get(name))
+}
+
+// QueryUint64s returns all query string values of the name as uint64s,
+// (?id=1&id=2); missing and invalid are omitted
+func QueryUint64s(r *http.Request, name string) []uint64 {
+	out := []uint64{}
+	for _, v := range r.URL.Query()[name] {
+		if id := payload.ParseUInt64(v); id > 0 {
+			out = append(out, id)
+		}
+	}
+
+	return out
+}
+
+// QueryUint returns query string value as uint (0 if missing or invalid)
+func QueryUint(r *http.Request, name string) uint {
+	return uint(payload.ParseUInt64(r.URL.Query().Get(name)))
+}
+
+// QueryTime returns query string value as time (nil if missing or not in RFC 3339 format)
+func QueryTime(r *http.Request, name string) *time.Time {
+	t, err := time.Parse(time.RFC3339, r.URL.Query().Get(name))
+	if err != nil {
+		return nil
+	}
+
+	return &t
+}
+
+// QueryBool returns true when query string value is "1" or "true"
+func QueryBool(r *http.Request, name string) bool {
+	switch strings.ToLower(r.URL.Query().Get(name)) {
+	case "1", "true":
+		return true
+	}
+
+	return false
+}
+
+// Token returns JWT from the request, looked up the same way as corteza does it
+// (Authorization header, jwt cookie, jwt query string parameter)
+func Token(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return h[7:]
+	}
+
+	if c, err := r.Cookie("jwt"); err == nil {
+		return c.Value
+	}
+
+	return r.URL.Query().Get("jwt")
+}
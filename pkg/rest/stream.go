@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/redact"
+)
+
+type (
+	// Emitter sends a single item of the streamed response
+	//
+	// Returns an error when the client is gone; producer should stop then.
+	Emitter func(v interface{}) error
+
+	// Producer emits items of the streamed response one by one
+	Producer func(emit Emitter) error
+)
+
+const (
+	// Items are flushed to the client in chunks
+	streamFlushEvery = 100
+)
+
+// Stream returns a handler that writes items as newline delimited JSON
+//
+// Items are redacted and written as they are produced, nothing is kept in
+// memory. Items are never wrapped, their keys are named as negotiated with
+// the client (see Negotiate). Status and headers are sent before the first item, so errors that
+// occur later are written as the last line ({"error":{"message":"..."}}).
+// Return it from the controller after the request is validated.
+func Stream(name string, fn Producer) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var (
+			f, _   = w.(http.Flusher)
+			enc    = json.NewEncoder(w)
+			scope  = redact.NewScope(r.Context())
+			format = Negotiate(r)
+			count  = 0
+		)
+
+		// Lines are always JSON
+		format.Encoding = ""
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+
+		err := fn(func(v interface{}) error {
+			b, err := format.Marshal(scope.Value(v))
+			if err != nil {
+				return err
+			}
+
+			if _, err = w.Write(append(b, '\n')); err != nil {
+				return err
+			}
+
+			if count++; count%streamFlushEvery == 0 && f != nil {
+				f.Flush()
+			}
+
+			return r.Context().Err()
+		})
+
+		if err != nil && r.Context().Err() == nil {
+			logger.LogControllerError(name, r, err, nil)
+
+			e := struct {
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}{}
+
+			e.Error.Message = err.Error()
+			_ = enc.Encode(e)
+		}
+
+		if f != nil {
+			f.Flush()
+		}
+	}
+}
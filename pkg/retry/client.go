@@ -0,0 +1,132 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+	"github.com/cortezaproject/corteza-server/pkg/automation/corredor"
+)
+
+type (
+	// client wraps script runner client and stores failed runs
+	// of non-critical scripts as jobs to be retried
+	//
+	// Critical scripts are not retried, their failure is reported to the
+	// user and aborts the operation.
+	client struct {
+		corredor.ScriptRunnerClient
+	}
+)
+
+var (
+	// runner is used for retries, it bypasses storing of failed runs
+	runner corredor.ScriptRunnerClient
+)
+
+// Client decorates script runner client with retries of failed runs
+func Client(c corredor.ScriptRunnerClient) corredor.ScriptRunnerClient {
+	runner = c
+	return &client{ScriptRunnerClient: c}
+}
+
+func (c client) Namespace(ctx context.Context, req *corredor.RunNamespaceRequest, opts ...grpc.CallOption) (*corredor.RunNamespaceResponse, error) {
+	rsp, err := c.ScriptRunnerClient.Namespace(ctx, req, opts...)
+	if err != nil {
+		r := *req
+		r.Config, r.Script = nil, nil
+		park(ctx, KindNamespace, req.Namespace.GetNamespaceID(), req.Namespace.GetNamespaceID(), req.Script, &r, err)
+	}
+
+	return rsp, err
+}
+
+func (c client) Module(ctx context.Context, req *corredor.RunModuleRequest, opts ...grpc.CallOption) (*corredor.RunModuleResponse, error) {
+	rsp, err := c.ScriptRunnerClient.Module(ctx, req, opts...)
+	if err != nil {
+		r := *req
+		r.Config, r.Script = nil, nil
+		park(ctx, KindModule, req.Namespace.GetNamespaceID(), req.Module.GetModuleID(), req.Script, &r, err)
+	}
+
+	return rsp, err
+}
+
+func (c client) Record(ctx context.Context, req *corredor.RunRecordRequest, opts ...grpc.CallOption) (*corredor.RunRecordResponse, error) {
+	rsp, err := c.ScriptRunnerClient.Record(ctx, req, opts...)
+	if err != nil {
+		r := *req
+		r.Config, r.Script = nil, nil
+		park(ctx, KindRecord, req.Namespace.GetNamespaceID(), req.Record.GetRecordID(), req.Script, &r, err)
+	}
+
+	return rsp, err
+}
+
+// park stores failed run as a job, request must not hold credentials
+func park(ctx context.Context, kind JobKind, namespaceID, resourceID uint64, cs *corredor.Script, req interface{}, runErr error) {
+	if cs == nil {
+		return
+	}
+
+	s := findScript(namespaceID, cs.Name)
+	if s == nil || s.Critical {
+		return
+	}
+
+	var (
+		log = defaultLogger.With(zap.Uint64("scriptID", s.ID), zap.String("kind", string(kind)))
+		i   = auth.GetIdentityFromContext(ctx)
+		at  = now().Add(backoff(1))
+	)
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		log.Error("could not encode failed script run", zap.Error(err))
+		return
+	}
+
+	// Run's context can be already canceled (timeout)
+	_, err = Repository(context.Background(), nil).Create(&Job{
+		NamespaceID:   namespaceID,
+		ScriptID:      s.ID,
+		Script:        s.Name,
+		Kind:          kind,
+		ResourceID:    resourceID,
+		Request:       b,
+		RunAs:         i.Identity(),
+		Status:        StatusRetrying,
+		Attempts:      1,
+		LastError:     runErr.Error(),
+		NextAttemptAt: &at,
+	})
+
+	if err != nil {
+		log.Error("could not store failed script run", zap.Error(err))
+		return
+	}
+
+	log.Info("failed script run scheduled for retry", zap.Error(runErr))
+}
+
+// findScript finds runnable script by name, names are unique in a namespace
+//
+// Retries run the current version of the script.
+func findScript(namespaceID uint64, name string) *automation.Script {
+	if service.DefaultInternalAutomationManager == nil {
+		return nil
+	}
+
+	for _, s := range service.DefaultInternalAutomationManager.FindRunnableScripts("", "") {
+		if s.NamespaceID == namespaceID && s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
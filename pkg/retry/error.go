@@ -0,0 +1,38 @@
+package retry
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	retryError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID       = retryError{"InvalidID", fault.Invalid}
+	ErrJobNotFound     = retryError{"JobNotFound", fault.NotFound}
+	ErrJobRetrying     = retryError{"JobRetrying", fault.Conflict}
+	ErrScriptNotFound  = retryError{"ScriptNotFound", fault.NotFound}
+	ErrNotConnected    = retryError{"NotConnected", fault.Unavailable}
+	ErrNothingToReplay = retryError{"NothingToReplay", fault.Invalid}
+	ErrNoPermissions   = retryError{"NoPermissions", fault.Forbidden}
+)
+
+func (e retryError) Error() string {
+	return e.String()
+}
+
+func (e retryError) String() string {
+	return "crust.retry." + e.name
+}
+
+func (e retryError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e retryError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,45 @@
+package retry
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200208000000.retry",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_automation_job (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_script         BIGINT UNSIGNED NOT NULL,
+  script             VARCHAR(255)    NOT NULL,
+  kind               VARCHAR(16)     NOT NULL,
+  rel_resource       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  request            MEDIUMTEXT      NOT NULL,
+  run_as             BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  roles              TEXT            NOT NULL,
+
+  status             VARCHAR(16)     NOT NULL,
+  attempts           INT UNSIGNED    NOT NULL DEFAULT 0,
+  last_error         TEXT            NOT NULL,
+  next_attempt_at    DATETIME            NULL DEFAULT NULL,
+
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (status, next_attempt_at),
+  INDEX (rel_namespace, rel_script)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+		{
+			Name: "20200313000000.retry-roles",
+			Up: `
+ALTER TABLE crust_compose_automation_job
+  DROP COLUMN roles;
+`,
+		},
+	}
+)
@@ -0,0 +1,202 @@
+package retry
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_automation_job"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_script",
+			"script",
+			"kind",
+			"rel_resource",
+			"request",
+			"run_as",
+			"status",
+			"attempts",
+			"last_error",
+			"next_attempt_at",
+			"created_at",
+			"updated_at",
+		).
+		From(r.table())
+}
+
+func (r repository) FindByID(jobID uint64) (*Job, error) {
+	var (
+		j = &Job{}
+		q = r.query().Where(squirrel.Eq{"id": jobID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, j); err != nil {
+		return nil, err
+	} else if j.ID == 0 {
+		return nil, ErrJobNotFound.withStack().WithID("jobID", jobID)
+	}
+
+	return j, nil
+}
+
+func (r repository) Find(f JobFilter) (set JobSet, err error) {
+	q := r.filter(r.query(), f).
+		OrderBy("id DESC").
+		Limit(uint64(f.Limit))
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindIDs returns IDs of all jobs that match the filter
+func (r repository) FindIDs(f JobFilter) (ids []uint64, err error) {
+	var (
+		set JobSet
+		q   = r.filter(squirrel.Select("id").From(r.table()), f)
+	)
+
+	if err = rh.FetchAll(r.db(), q, &set); err != nil {
+		return nil, err
+	}
+
+	for _, j := range set {
+		ids = append(ids, j.ID)
+	}
+
+	return ids, nil
+}
+
+func (r repository) filter(q squirrel.SelectBuilder, f JobFilter) squirrel.SelectBuilder {
+	if f.NamespaceID > 0 {
+		q = q.Where(squirrel.Eq{"rel_namespace": f.NamespaceID})
+	}
+
+	if f.ScriptID > 0 {
+		q = q.Where(squirrel.Eq{"rel_script": f.ScriptID})
+	}
+
+	if f.Status != "" {
+		q = q.Where(squirrel.Eq{"status": f.Status})
+	}
+
+	return q
+}
+
+// FindDue returns jobs that should be retried by now
+func (r repository) FindDue(now time.Time) (set JobSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"status": StatusRetrying}).
+		Where(squirrel.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at").
+		Limit(batchSize)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(j *Job) (*Job, error) {
+	j.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&j.CreatedAt)
+
+	return j, errors.WithStack(r.db().Insert(r.table(), j))
+}
+
+// Claim postpones job's next attempt; returns false when job was
+// already claimed (next attempt was moved) by another instance
+func (r repository) Claim(j *Job, until time.Time) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.table()+" SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at = ?",
+		until, j.ID, StatusRetrying, j.NextAttemptAt,
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
+
+// UpdateState stores result of the attempt
+func (r repository) UpdateState(j *Job) error {
+	rh.SetCurrentTimeRounded(&j.UpdatedAt)
+
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{
+			"status":          j.Status,
+			"attempts":        j.Attempts,
+			"last_error":      j.LastError,
+			"next_attempt_at": j.NextAttemptAt,
+			"updated_at":      j.UpdatedAt,
+		},
+		squirrel.Eq{"id": j.ID},
+	)
+}
+
+// Replay schedules jobs (that are not retrying already) to be retried right away
+func (r repository) Replay(jobIDs []uint64, now time.Time) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.table(),
+		rh.Set{
+			"status":          StatusRetrying,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"updated_at":      now,
+		},
+		squirrel.And{
+			squirrel.Eq{"id": jobIDs},
+			squirrel.NotEq{"status": StatusRetrying},
+		},
+	)
+}
+
+func (r repository) DeleteByID(jobID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"id": jobID})
+}
+
+// Prune removes succeeded jobs updated before the given time
+func (r repository) Prune(before time.Time) error {
+	return rh.Delete(r.db(), r.table(), squirrel.And{
+		squirrel.Eq{"status": StatusSucceeded},
+		squirrel.Lt{"updated_at": before},
+	})
+}
@@ -0,0 +1,51 @@
+package retry
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts failed script run inspection & replay endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?status=dead&namespaceID=&scriptID=&limit=100
+	r.Get("/", rest.Handler("AutomationJob.List", func(r *http.Request) (interface{}, error) {
+		return DefaultRetry.With(r.Context()).Find(JobFilter{
+			NamespaceID: rest.QueryUint64(r, "namespaceID"),
+			ScriptID:    rest.QueryUint64(r, "scriptID"),
+			Status:      JobStatus(r.URL.Query().Get("status")),
+			Limit:       rest.QueryUint(r, "limit"),
+		})
+	}))
+
+	// Replays jobs by IDs ({"jobIDs": [...]}) or by filter ({"namespaceID": ..., "scriptID": ...})
+	r.Post("/replay", rest.Handler("AutomationJob.Replay", func(r *http.Request) (interface{}, error) {
+		rp := Replay{}
+		if err := rest.Decode(r, &rp); err != nil {
+			return nil, err
+		}
+
+		n, err := DefaultRetry.With(r.Context()).Replay(rp)
+		if err != nil {
+			return nil, err
+		}
+
+		return struct {
+			Replayed uint `json:"replayed"`
+		}{n}, nil
+	}))
+
+	r.Get("/{jobID}", rest.Handler("AutomationJob.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultRetry.With(r.Context()).FindByID(rest.ParamUint64(r, "jobID"))
+	}))
+
+	r.Delete("/{jobID}", rest.Handler("AutomationJob.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultRetry.With(r.Context()).DeleteByID(rest.ParamUint64(r, "jobID"))
+	}))
+}
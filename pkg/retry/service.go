@@ -0,0 +1,311 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+	"github.com/cortezaproject/corteza-server/pkg/automation/corredor"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	retryService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		opt *options.CorredorOpt
+
+		repository *repository
+	}
+
+	RetryService interface {
+		With(ctx context.Context) RetryService
+
+		Find(JobFilter) (JobSet, error)
+		FindByID(jobID uint64) (*Job, error)
+		Replay(Replay) (uint, error)
+		DeleteByID(jobID uint64) error
+	}
+)
+
+var (
+	DefaultRetry RetryService
+
+	// defaultLogger is used by the script runner client
+	defaultLogger = zap.NewNop()
+
+	// now is used for scheduling of retries and can be overridden
+	now = time.Now
+)
+
+// Init initializes retry service and starts retrying failed script runs
+//
+// Failed runs are stored by the script runner client (see Client)
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &retryService{
+		logger: log,
+		opt:    options.Corredor("compose"),
+	}
+
+	DefaultRetry = svc.With(ctx)
+	defaultLogger = log
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc retryService) With(ctx context.Context) RetryService {
+	return &retryService{
+		ctx:    ctx,
+		logger: svc.logger,
+		opt:    svc.opt,
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc retryService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc retryService) Find(f JobFilter) (JobSet, error) {
+	if !isAdmin(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if f.Limit == 0 {
+		f.Limit = defaultLimit
+	} else if f.Limit > maxLimit {
+		f.Limit = maxLimit
+	}
+
+	return svc.repository.Find(f)
+}
+
+func (svc retryService) FindByID(jobID uint64) (*Job, error) {
+	if !isAdmin(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return svc.repository.FindByID(jobID)
+}
+
+// Replay schedules jobs to be retried right away, with all attempts
+//
+// Jobs are selected by IDs or, when none are given, by filter (dead jobs
+// unless status is given). Returns number of selected jobs; jobs that are
+// already retrying are left as they are.
+func (svc retryService) Replay(r Replay) (uint, error) {
+	if !isAdmin(svc.ctx) {
+		return 0, ErrNoPermissions.withStack()
+	}
+
+	var ids []uint64
+
+	for _, s := range r.JobIDs {
+		ID, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || ID == 0 {
+			return 0, ErrInvalidID.withStack()
+		}
+
+		ids = append(ids, ID)
+	}
+
+	if len(r.JobIDs) == 0 {
+		f := r.JobFilter
+		if f.Status == "" {
+			f.Status = StatusDead
+		}
+
+		var err error
+		if ids, err = svc.repository.FindIDs(f); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(ids) == 0 {
+		return 0, ErrNothingToReplay.withStack()
+	}
+
+	if err := svc.repository.Replay(ids, now()); err != nil {
+		return 0, err
+	}
+
+	svc.log(zap.Int("jobs", len(ids))).Info("script runs replayed")
+
+	return uint(len(ids)), nil
+}
+
+func (svc retryService) DeleteByID(jobID uint64) error {
+	j, err := svc.FindByID(jobID)
+	if err != nil {
+		return err
+	}
+
+	if j.Status == StatusRetrying {
+		return ErrJobRetrying.withStack()
+	}
+
+	return svc.repository.DeleteByID(j.ID)
+}
+
+// retry runs the job with the current version of the script
+func (svc retryService) retry(ctx context.Context, j *Job) {
+	var (
+		r   = Repository(ctx, nil)
+		log = svc.logger.With(zap.Uint64("jobID", j.ID), zap.Uint64("scriptID", j.ScriptID))
+	)
+
+	if ok, err := r.Claim(j, now().Add(claimTimeout)); err != nil {
+		log.Error("could not claim job", zap.Error(err))
+		return
+	} else if !ok {
+		return
+	}
+
+	j.Attempts++
+	j.LastError = ""
+	j.Status = StatusSucceeded
+	j.NextAttemptAt = nil
+
+	if err := svc.run(ctx, j); err != nil {
+		j.LastError = err.Error()
+
+		if j.Attempts >= maxAttempts {
+			j.Status = StatusDead
+			log.Warn("script run failed too many times", zap.Error(err))
+		} else {
+			at := now().Add(backoff(j.Attempts))
+			j.Status = StatusRetrying
+			j.NextAttemptAt = &at
+		}
+	}
+
+	if err := r.UpdateState(j); err != nil {
+		log.Error("could not store job state", zap.Error(err))
+	}
+}
+
+func (svc retryService) run(ctx context.Context, j *Job) (err error) {
+	if runner == nil {
+		return ErrNotConnected
+	}
+
+	s := findScript(j.NamespaceID, j.Script)
+	if s == nil {
+		return ErrScriptNotFound
+	}
+
+	cfg, err := svc.config(ctx, s, j)
+	if err != nil {
+		return err
+	}
+
+	cs := corredor.FromScript(s)
+
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+
+	switch j.Kind {
+	case KindNamespace:
+		req := &corredor.RunNamespaceRequest{}
+		if err = json.Unmarshal(j.Request, req); err == nil {
+			req.Config, req.Script = cfg, cs
+			_, err = runner.Namespace(ctx, req)
+		}
+	case KindModule:
+		req := &corredor.RunModuleRequest{}
+		if err = json.Unmarshal(j.Request, req); err == nil {
+			req.Config, req.Script = cfg, cs
+			_, err = runner.Module(ctx, req)
+		}
+	default:
+		req := &corredor.RunRecordRequest{}
+		if err = json.Unmarshal(j.Request, req); err == nil {
+			req.Config, req.Script = cfg, cs
+			_, err = runner.Record(ctx, req)
+		}
+	}
+
+	return errors.Wrap(err, "script failed")
+}
+
+// config issues new credentials, the same way as the automation runner does
+func (svc retryService) config(ctx context.Context, s *automation.Script, j *Job) (map[string]string, error) {
+	jwt := s.Credentials()
+	if !s.RunAsDefined() {
+		var err error
+		if jwt, err = runas.Token(ctx, j.RunAs); err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]string{
+		"api.jwt": jwt,
+
+		"api.baseURL.system":    svc.opt.ApiBaseURLSystem,
+		"api.baseURL.compose":   svc.opt.ApiBaseURLCompose,
+		"api.baseURL.messaging": svc.opt.ApiBaseURLMessaging,
+	}, nil
+}
+
+// watch retries due jobs and removes old succeeded ones
+func (svc retryService) watch(ctx context.Context) {
+	var (
+		w = time.NewTicker(watchInterval)
+		p = time.NewTicker(pruneInterval)
+	)
+
+	defer w.Stop()
+	defer p.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.C:
+			set, err := Repository(ctx, nil).FindDue(now())
+			if err != nil {
+				svc.logger.Error("could not load due jobs", zap.Error(err))
+				continue
+			}
+
+			for _, j := range set {
+				svc.retry(ctx, j)
+			}
+		case <-p.C:
+			if err := Repository(ctx, nil).Prune(now().Add(-retention)); err != nil {
+				svc.logger.Error("could not remove old jobs", zap.Error(err))
+			}
+		}
+	}
+}
+
+func isAdmin(ctx context.Context) bool {
+	i := auth.GetIdentityFromContext(ctx)
+	if auth.IsSuperUser(i) {
+		return true
+	}
+
+	for _, roleID := range i.Roles() {
+		if roleID == permissions.AdminsRoleID {
+			return true
+		}
+	}
+
+	return false
+}
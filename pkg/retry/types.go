@@ -0,0 +1,99 @@
+package retry
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type (
+	// Job is a failed script run that is retried with backoff
+	//
+	// Job holds the request that was sent to Corredor (without credentials);
+	// runs that fail too many times are dead and wait to be replayed.
+	Job struct {
+		ID          uint64          `json:"jobID,string" db:"id"`
+		NamespaceID uint64          `json:"namespaceID,string" db:"rel_namespace"`
+		ScriptID    uint64          `json:"scriptID,string" db:"rel_script"`
+		Script      string          `json:"script" db:"script"`
+		Kind        JobKind         `json:"kind" db:"kind"`
+		ResourceID  uint64          `json:"resourceID,string" db:"rel_resource"`
+		Request     json.RawMessage `json:"request" db:"request"`
+
+		// Invoker; new credentials, with roles they currently have, are
+		// issued for every attempt
+		RunAs uint64 `json:"runAs,string" db:"run_as"`
+
+		Status        JobStatus  `json:"status" db:"status"`
+		Attempts      uint       `json:"attempts" db:"attempts"`
+		LastError     string     `json:"lastError" db:"last_error"`
+		NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty" db:"next_attempt_at"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	JobSet []*Job
+
+	JobFilter struct {
+		NamespaceID uint64    `json:"namespaceID,string"`
+		ScriptID    uint64    `json:"scriptID,string"`
+		Status      JobStatus `json:"status"`
+		Limit       uint      `json:"limit"`
+	}
+
+	// Replay selects jobs to be replayed, by IDs or by filter
+	Replay struct {
+		JobIDs []string `json:"jobIDs"`
+		JobFilter
+	}
+
+	JobKind   string
+	JobStatus string
+)
+
+const (
+	KindNamespace JobKind = "namespace"
+	KindModule    JobKind = "module"
+	KindRecord    JobKind = "record"
+
+	StatusRetrying  JobStatus = "retrying"
+	StatusDead      JobStatus = "dead"
+	StatusSucceeded JobStatus = "succeeded"
+
+	// First run counts as an attempt
+	maxAttempts = 6
+
+	// Delay after the first attempt, multiplied by 4 with every next one
+	baseBackoff = time.Minute
+	maxBackoff  = 6 * time.Hour
+
+	// Jobs are claimed for the duration of the run, so that other
+	// instances do not pick them up
+	claimTimeout = time.Minute
+	runTimeout   = 30 * time.Second
+
+	watchInterval = 10 * time.Second
+	batchSize     = 50
+
+	pruneInterval = time.Hour
+
+	// Succeeded jobs are kept for inspection; dead ones until removed
+	retention = 7 * 24 * time.Hour
+
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// backoff returns delay before the next attempt
+func backoff(attempts uint) time.Duration {
+	d := baseBackoff
+	for i := uint(1); i < attempts && d < maxBackoff; i++ {
+		d *= 4
+	}
+
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return d
+}
@@ -0,0 +1,33 @@
+package revisions
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Middleware stores comment of the change into context
+//
+// Revisions made while handling the request are stored with it.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if comment := strings.TrimSpace(r.Header.Get(commentHeader)); comment != "" {
+			r = r.WithContext(withComment(r.Context(), comment))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func withComment(ctx context.Context, comment string) context.Context {
+	if r := []rune(comment); len(r) > maxCommentLength {
+		comment = string(r[:maxCommentLength])
+	}
+
+	return context.WithValue(ctx, contextKey{}, comment)
+}
+
+func commentFromContext(ctx context.Context) string {
+	comment, _ := ctx.Value(contextKey{}).(string)
+	return comment
+}
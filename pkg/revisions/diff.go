@@ -0,0 +1,143 @@
+package revisions
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+var (
+	// Keys that change with every revision
+	ignoredKeys = map[string]bool{
+		"createdAt": true,
+		"updatedAt": true,
+		"deletedAt": true,
+	}
+)
+
+// diff compares two snapshots
+func diff(from, to []byte) ([]*Change, error) {
+	var a, b interface{}
+
+	if err := json.Unmarshal(from, &a); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(to, &b); err != nil {
+		return nil, err
+	}
+
+	cc := []*Change{}
+	compare("", a, b, &cc)
+	return cc, nil
+}
+
+func compare(path string, a, b interface{}, cc *[]*Change) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		*cc = append(*cc, &Change{Path: path, Op: OpAdded, To: b})
+		return
+	case b == nil:
+		*cc = append(*cc, &Change{Path: path, Op: OpRemoved, From: a})
+		return
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			compareObjects(path, av, bv, cc)
+			return
+		}
+
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			if an, bn := named(av), named(bv); an != nil && bn != nil {
+				compareNamed(path, an, bn, cc)
+			} else {
+				compareArrays(path, av, bv, cc)
+			}
+
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*cc = append(*cc, &Change{Path: path, Op: OpChanged, From: a, To: b})
+	}
+}
+
+func compareObjects(path string, a, b map[string]interface{}, cc *[]*Change) {
+	for _, k := range keys(a, b) {
+		if !ignoredKeys[k] {
+			compare(join(path, k), a[k], b[k], cc)
+		}
+	}
+}
+
+func compareArrays(path string, a, b []interface{}, cc *[]*Change) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var av, bv interface{}
+		if i < len(a) {
+			av = a[i]
+		}
+
+		if i < len(b) {
+			bv = b[i]
+		}
+
+		compare(path+"["+strconv.Itoa(i)+"]", av, bv, cc)
+	}
+}
+
+func compareNamed(path string, a, b map[string]interface{}, cc *[]*Change) {
+	for _, k := range keys(a, b) {
+		compare(path+"["+k+"]", a[k], b[k], cc)
+	}
+}
+
+// named returns items of the array by their names, nil when any of them has no name
+func named(items []interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		name, ok := obj["name"].(string)
+		if !ok || name == "" || out[name] != nil {
+			return nil
+		}
+
+		out[name] = obj
+	}
+
+	return out
+}
+
+func keys(a, b map[string]interface{}) []string {
+	kk := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		kk = append(kk, k)
+	}
+
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			kk = append(kk, k)
+		}
+	}
+
+	sort.Strings(kk)
+	return kk
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
@@ -0,0 +1,33 @@
+package revisions
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	revisionsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrRevisionNotFound = revisionsError{"RevisionNotFound", fault.NotFound}
+	ErrFieldsRemoved    = revisionsError{"FieldsRemoved", fault.Conflict}
+)
+
+func (e revisionsError) Error() string {
+	return e.String()
+}
+
+func (e revisionsError) String() string {
+	return "crust.revisions." + e.name
+}
+
+func (e revisionsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e revisionsError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,30 @@
+package revisions
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200216000000.revisions",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_revision (
+  id               BIGINT UNSIGNED NOT NULL,
+  resource         VARCHAR(16)     NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  rel_resource     BIGINT UNSIGNED NOT NULL,
+  version          INT UNSIGNED    NOT NULL,
+  snapshot         MEDIUMTEXT      NOT NULL,
+  comment          VARCHAR(255)    NOT NULL DEFAULT '',
+
+  created_by       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  UNIQUE (resource, rel_resource, version)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+`,
+		},
+	}
+)
@@ -0,0 +1,60 @@
+package revisions
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// module wraps module service and stores revisions of modules
+	module struct {
+		service.ModuleService
+		ctx context.Context
+	}
+)
+
+// Module decorates module service with revisions
+//
+// Snapshot of the module (with fields) is stored after it is created
+// or updated.
+func Module(ms service.ModuleService) service.ModuleService {
+	return &module{ModuleService: ms, ctx: context.Background()}
+}
+
+func (svc module) With(ctx context.Context) service.ModuleService {
+	return &module{
+		ModuleService: svc.ModuleService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc module) Create(m *types.Module) (*types.Module, error) {
+	m, err := svc.ModuleService.Create(m)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.revisions().record(ResourceModule, m.NamespaceID, m.ID, m)
+	return m, nil
+}
+
+func (svc module) Update(m *types.Module) (*types.Module, error) {
+	svc.revisions().baseline(ResourceModule, m.NamespaceID, m.ID, func() (interface{}, error) {
+		return svc.ModuleService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(m.NamespaceID, m.ID)
+	})
+
+	m, err := svc.ModuleService.Update(m)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.revisions().record(ResourceModule, m.NamespaceID, m.ID, m)
+	return m, nil
+}
+
+func (svc module) revisions() *revisionService {
+	return defaultRevisions.with(svc.ctx)
+}
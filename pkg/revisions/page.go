@@ -0,0 +1,60 @@
+package revisions
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// page wraps page service and stores revisions of pages
+	page struct {
+		service.PageService
+		ctx context.Context
+	}
+)
+
+// Page decorates page service with revisions
+//
+// Snapshot of the page (with blocks) is stored after it is created
+// or updated. Reordering pages does not make revisions.
+func Page(ps service.PageService) service.PageService {
+	return &page{PageService: ps, ctx: context.Background()}
+}
+
+func (svc page) With(ctx context.Context) service.PageService {
+	return &page{
+		PageService: svc.PageService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc page) Create(p *types.Page) (*types.Page, error) {
+	p, err := svc.PageService.Create(p)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.revisions().record(ResourcePage, p.NamespaceID, p.ID, p)
+	return p, nil
+}
+
+func (svc page) Update(p *types.Page) (*types.Page, error) {
+	svc.revisions().baseline(ResourcePage, p.NamespaceID, p.ID, func() (interface{}, error) {
+		return svc.PageService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(p.NamespaceID, p.ID)
+	})
+
+	p, err := svc.PageService.Update(p)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.revisions().record(ResourcePage, p.NamespaceID, p.ID, p)
+	return p, nil
+}
+
+func (svc page) revisions() *revisionService {
+	return defaultRevisions.with(svc.ctx)
+}
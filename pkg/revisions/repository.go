@@ -0,0 +1,100 @@
+package revisions
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_revision"
+}
+
+func (r repository) columns() []string {
+	return []string{"id", "resource", "rel_namespace", "rel_resource", "version", "comment", "created_by", "created_at"}
+}
+
+// Find returns revisions of the resource without snapshots, latest first
+func (r repository) Find(resource string, resourceID uint64) (set RevisionSet, err error) {
+	q := squirrel.
+		Select(r.columns()...).
+		From(r.table()).
+		Where(squirrel.Eq{"resource": resource, "rel_resource": resourceID}).
+		OrderBy("version DESC")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindByVersion returns revision with snapshot, nil when there is no such version
+//
+// Version 0 is the latest one.
+func (r repository) FindByVersion(resource string, resourceID uint64, version uint) (*Revision, error) {
+	var (
+		rev = &Revision{}
+		q   = squirrel.
+			Select(append(r.columns(), "snapshot")...).
+			From(r.table()).
+			Where(squirrel.Eq{"resource": resource, "rel_resource": resourceID}).
+			OrderBy("version DESC").
+			Limit(1)
+	)
+
+	if version > 0 {
+		q = q.Where(squirrel.Eq{"version": version})
+	}
+
+	if err := rh.FetchOne(r.db(), q, rev); err != nil {
+		return nil, err
+	} else if rev.ID == 0 {
+		return nil, nil
+	}
+
+	return rev, nil
+}
+
+// Create stores the revision as the next version of the resource
+func (r repository) Create(rev *Revision) (*Revision, error) {
+	rev.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&rev.CreatedAt)
+
+	return rev, r.db().Transaction(func() error {
+		err := r.db().Get(
+			&rev.Version,
+			"SELECT COALESCE(MAX(version), 0) + 1 FROM "+r.table()+" WHERE resource = ? AND rel_resource = ? FOR UPDATE",
+			rev.Resource,
+			rev.ResourceID,
+		)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return errors.WithStack(r.db().Insert(r.table(), rev))
+	})
+}
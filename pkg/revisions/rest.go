@@ -0,0 +1,67 @@
+package revisions
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountModuleRoutes mounts module revision endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {moduleID} params
+func MountModuleRoutes(r chi.Router) {
+	mount(r, ResourceModule, "moduleID")
+}
+
+// MountPageRoutes mounts page revision endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {pageID} params
+func MountPageRoutes(r chi.Router) {
+	mount(r, ResourcePage, "pageID")
+}
+
+func mount(r chi.Router, resource, param string) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("Revision.List", func(r *http.Request) (interface{}, error) {
+		return DefaultRevisions.With(r.Context()).Find(
+			resource,
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, param),
+		)
+	}))
+
+	r.Get("/{version}", rest.Handler("Revision.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultRevisions.With(r.Context()).Read(
+			resource,
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, param),
+			uint(rest.ParamUint64(r, "version")),
+		)
+	}))
+
+	// Changes from the version to the one in ?to, latest by default
+	r.Get("/{version}/diff", rest.Handler("Revision.Diff", func(r *http.Request) (interface{}, error) {
+		return DefaultRevisions.With(r.Context()).Diff(
+			resource,
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, param),
+			uint(rest.ParamUint64(r, "version")),
+			rest.QueryUint(r, "to"),
+		)
+	}))
+
+	// Restores the version; ?force=true allows removing fields added since
+	r.Post("/{version}/rollback", rest.Handler("Revision.Rollback", func(r *http.Request) (interface{}, error) {
+		return DefaultRevisions.With(r.Context()).Rollback(
+			resource,
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, param),
+			uint(rest.ParamUint64(r, "version")),
+			rest.QueryBool(r, "force"),
+		)
+	}))
+}
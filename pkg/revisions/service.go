@@ -0,0 +1,267 @@
+package revisions
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	revisionService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		module service.ModuleService
+		page   service.PageService
+
+		repository *repository
+	}
+
+	RevisionService interface {
+		With(ctx context.Context) RevisionService
+
+		Find(resource string, namespaceID, resourceID uint64) (RevisionSet, error)
+		Read(resource string, namespaceID, resourceID uint64, version uint) (*Revision, error)
+		Diff(resource string, namespaceID, resourceID uint64, from, to uint) (*Diff, error)
+
+		Rollback(resource string, namespaceID, resourceID uint64, version uint, force bool) (*Revision, error)
+	}
+)
+
+var (
+	DefaultRevisions RevisionService
+
+	// used by service decorators
+	defaultRevisions *revisionService
+)
+
+// Init initializes revision service and decorates module & page services
+// with storing of revisions
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	service.DefaultModule = Module(service.DefaultModule)
+	service.DefaultPage = Page(service.DefaultPage)
+
+	// Rollbacks go through decorated services, so they are stored as revisions too
+	svc := (&revisionService{
+		logger: log,
+		module: service.DefaultModule,
+		page:   service.DefaultPage,
+	}).with(ctx)
+
+	DefaultRevisions = svc
+	defaultRevisions = svc
+
+	return nil
+}
+
+func (svc revisionService) With(ctx context.Context) RevisionService {
+	return svc.with(ctx)
+}
+
+func (svc revisionService) with(ctx context.Context) *revisionService {
+	return &revisionService{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		module: svc.module.With(ctx),
+		page:   svc.page.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc revisionService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns revisions of the module or page, latest first
+func (svc revisionService) Find(resource string, namespaceID, resourceID uint64) (RevisionSet, error) {
+	if err := svc.readable(resource, namespaceID, resourceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Find(resource, resourceID)
+}
+
+// Read returns revision with the snapshot
+func (svc revisionService) Read(resource string, namespaceID, resourceID uint64, version uint) (*Revision, error) {
+	if err := svc.readable(resource, namespaceID, resourceID); err != nil {
+		return nil, err
+	}
+
+	return svc.revision(resource, resourceID, version)
+}
+
+// Diff compares snapshots of two versions, to the latest one when to is 0
+func (svc revisionService) Diff(resource string, namespaceID, resourceID uint64, from, to uint) (*Diff, error) {
+	if err := svc.readable(resource, namespaceID, resourceID); err != nil {
+		return nil, err
+	}
+
+	a, err := svc.revision(resource, resourceID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := svc.revision(resource, resourceID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := diff(a.Snapshot, b.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Diff{From: a.Version, To: b.Version, Changes: cc}, nil
+}
+
+// Rollback restores definition of the module or page from the revision
+//
+// Restored definition is stored as a new revision. Module fields that
+// were added after the revision would be removed (their values hidden),
+// so rollback is refused unless forced. Pages stay where they are in
+// the page tree.
+func (svc revisionService) Rollback(resource string, namespaceID, resourceID uint64, version uint, force bool) (*Revision, error) {
+	rev, err := svc.revision(resource, resourceID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := svc.ctx
+	if commentFromContext(ctx) == "" {
+		ctx = withComment(ctx, "Rollback to version "+strconv.FormatUint(uint64(rev.Version), 10))
+	}
+
+	switch resource {
+	case ResourceModule:
+		err = svc.rollbackModule(ctx, namespaceID, rev, force)
+	case ResourcePage:
+		err = svc.rollbackPage(ctx, namespaceID, rev)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindByVersion(resource, resourceID, 0)
+}
+
+func (svc revisionService) rollbackModule(ctx context.Context, namespaceID uint64, rev *Revision, force bool) error {
+	m, err := svc.module.FindByID(namespaceID, rev.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	old := &types.Module{}
+	if err = json.Unmarshal(rev.Snapshot, old); err != nil {
+		return err
+	}
+
+	var removed []string
+	for _, f := range m.Fields {
+		if old.Fields.FindByName(f.Name) == nil {
+			removed = append(removed, f.Name)
+		}
+	}
+
+	if len(removed) > 0 && !force {
+		return ErrFieldsRemoved.withStack().
+			WithID("moduleID", m.ID).
+			WithMessage("rollback would remove fields: " + strings.Join(removed, ", "))
+	}
+
+	m.Name, m.Handle, m.Meta, m.Fields = old.Name, old.Handle, old.Meta, old.Fields
+
+	_, err = svc.module.With(ctx).Update(m)
+	return err
+}
+
+func (svc revisionService) rollbackPage(ctx context.Context, namespaceID uint64, rev *Revision) error {
+	p, err := svc.page.FindByID(namespaceID, rev.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	old := &types.Page{}
+	if err = json.Unmarshal(rev.Snapshot, old); err != nil {
+		return err
+	}
+
+	p.Title, p.Handle, p.Description, p.Blocks, p.Visible = old.Title, old.Handle, old.Description, old.Blocks, old.Visible
+
+	_, err = svc.page.With(ctx).Update(p)
+	return err
+}
+
+// record stores the snapshot as the next revision
+//
+// Errors are logged, changes are made already.
+func (svc revisionService) record(resource string, namespaceID, resourceID uint64, v interface{}) {
+	snapshot, err := json.Marshal(v)
+	if err == nil {
+		_, err = svc.repository.Create(&Revision{
+			Resource:    resource,
+			NamespaceID: namespaceID,
+			ResourceID:  resourceID,
+			Comment:     commentFromContext(svc.ctx),
+			Snapshot:    snapshot,
+			CreatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+		})
+	}
+
+	if err != nil {
+		svc.log(zap.String("resource", resource), zap.Uint64("resourceID", resourceID)).
+			Error("could not store revision", zap.Error(err))
+	}
+}
+
+// baseline stores the definition before the first change made with revisions,
+// so that changes of modules & pages made before can be rolled back
+func (svc revisionService) baseline(resource string, namespaceID, resourceID uint64, load func() (interface{}, error)) {
+	if rev, err := svc.repository.FindByVersion(resource, resourceID, 0); err != nil || rev != nil {
+		return
+	}
+
+	v, err := load()
+	if err != nil {
+		return
+	}
+
+	svc.with(context.WithValue(svc.ctx, contextKey{}, "")).record(resource, namespaceID, resourceID, v)
+}
+
+func (svc revisionService) revision(resource string, resourceID uint64, version uint) (*Revision, error) {
+	rev, err := svc.repository.FindByVersion(resource, resourceID, version)
+	if err != nil {
+		return nil, err
+	} else if rev == nil {
+		return nil, ErrRevisionNotFound.withStack().WithID("resourceID", resourceID)
+	}
+
+	return rev, nil
+}
+
+func (svc revisionService) readable(resource string, namespaceID, resourceID uint64) (err error) {
+	switch resource {
+	case ResourceModule:
+		_, err = svc.module.FindByID(namespaceID, resourceID)
+	case ResourcePage:
+		_, err = svc.page.FindByID(namespaceID, resourceID)
+	}
+
+	return err
+}
@@ -0,0 +1,63 @@
+package revisions
+
+import (
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+type (
+	// Revision is a snapshot of module's or page's definition after a change
+	Revision struct {
+		ID          uint64 `json:"revisionID,string" db:"id"`
+		Resource    string `json:"resource" db:"resource"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ResourceID  uint64 `json:"resourceID,string" db:"rel_resource"`
+		Version     uint   `json:"version" db:"version"`
+		Comment     string `json:"comment" db:"comment"`
+
+		// Module (with fields) or page (with blocks), as returned by the API;
+		// omitted from lists
+		Snapshot types.JSONText `json:"snapshot,omitempty" db:"snapshot"`
+
+		CreatedBy uint64    `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	RevisionSet []*Revision
+
+	// Diff lists changes between snapshots of two versions
+	Diff struct {
+		From    uint      `json:"from"`
+		To      uint      `json:"to"`
+		Changes []*Change `json:"changes"`
+	}
+
+	// Change of a value in the snapshot
+	//
+	// Path is made of object keys and array indexes, items of arrays of
+	// named objects (module fields) are addressed by names:
+	// fields[email].options.multiDelimiter
+	Change struct {
+		Path string      `json:"path"`
+		Op   string      `json:"op"`
+		From interface{} `json:"from,omitempty"`
+		To   interface{} `json:"to,omitempty"`
+	}
+
+	contextKey struct{}
+)
+
+const (
+	ResourceModule = "module"
+	ResourcePage   = "page"
+
+	OpAdded   = "added"
+	OpRemoved = "removed"
+	OpChanged = "changed"
+
+	// Comment of the change, stored with the revision
+	commentHeader = "X-Crust-Change-Comment"
+
+	maxCommentLength = 255
+)
@@ -0,0 +1,50 @@
+package rolecache
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// authService wraps auth service and caches effective roles
+	authService struct {
+		service.AuthService
+		ctx context.Context
+	}
+)
+
+// Auth decorates auth service with the cache of effective roles
+//
+// Roles are resolved by the wrapped services (role rules, nested roles,
+// expiring memberships) when the session is created and served from the
+// cache to tokens issued after.
+func Auth(as service.AuthService) service.AuthService {
+	return &authService{AuthService: as, ctx: context.Background()}
+}
+
+func (svc authService) With(ctx context.Context) service.AuthService {
+	return &authService{
+		AuthService: svc.AuthService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc authService) LoadRoleMemberships(u *types.User) error {
+	if rr, ok := current.get(u.ID, now()); ok {
+		metricHits.Inc()
+		u.SetRoles(append([]uint64(nil), rr...))
+		return nil
+	}
+
+	metricMisses.Inc()
+	generation := current.current()
+
+	if err := svc.AuthService.LoadRoleMemberships(u); err != nil {
+		return err
+	}
+
+	current.put(u.ID, append([]uint64(nil), u.Roles()...), generation, now())
+	return nil
+}
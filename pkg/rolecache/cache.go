@@ -0,0 +1,82 @@
+package rolecache
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// cache holds effective roles of users, resolved with nested and
+	// dynamic roles and without expired memberships
+	cache struct {
+		sync.RWMutex
+
+		users map[uint64]*resolved
+
+		// Incremented by every invalidation; roles resolved before it
+		// are not stored, they might be stale already
+		generation uint64
+	}
+
+	resolved struct {
+		roleIDs []uint64
+		at      time.Time
+	}
+)
+
+func newCache() *cache {
+	return &cache{
+		users: map[uint64]*resolved{},
+	}
+}
+
+// get returns effective roles of the user, unless they were resolved more than ttl ago
+func (c *cache) get(userID uint64, at time.Time) ([]uint64, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if r, ok := c.users[userID]; ok && at.Sub(r.at) < ttl {
+		return r.roleIDs, true
+	}
+
+	return nil, false
+}
+
+// current returns the generation that roles are resolved in
+func (c *cache) current() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.generation
+}
+
+// put stores effective roles of the user, resolved in the generation
+func (c *cache) put(userID uint64, roleIDs []uint64, generation uint64, at time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	c.users[userID] = &resolved{roleIDs: roleIDs, at: at}
+}
+
+// invalidate forgets roles of the users
+func (c *cache) invalidate(userIDs ...uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	for _, userID := range userIDs {
+		delete(c.users, userID)
+	}
+}
+
+// flush forgets roles of all users
+func (c *cache) flush() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	c.users = map[uint64]*resolved{}
+}
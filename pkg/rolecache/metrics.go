@@ -0,0 +1,30 @@
+package rolecache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_rolecache_hits_total",
+		Help: "Number of effective role sets served from the cache.",
+	})
+
+	metricMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_rolecache_misses_total",
+		Help: "Number of effective role sets resolved from role memberships.",
+	})
+
+	metricInvalidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "crust_rolecache_invalidations_total",
+		Help: "Number of cache invalidations, of some users or of all.",
+	}, []string{"scope"})
+)
+
+func registerMetrics() {
+	prometheus.MustRegister(
+		metricHits,
+		metricMisses,
+		metricInvalidations,
+	)
+}
@@ -0,0 +1,58 @@
+package rolecache
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/system/service"
+)
+
+// Middleware replaces roles of the identity with cached effective roles
+//
+// Tokens hold roles that were resolved when they were issued; changes
+// of memberships apply to tokens issued before, without loading
+// memberships on every request. Roles are resolved again only after
+// they were invalidated.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := auth.GetIdentityFromContext(r.Context())
+		if defaultLogger == nil || !i.Valid() || auth.IsSuperUser(i) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rr, ok := current.get(i.Identity(), now())
+		if !ok {
+			var err error
+			if rr, err = resolve(r, i.Identity()); err != nil {
+				// Token's roles are used until roles can be resolved
+				logger.AddRequestID(r.Context(), defaultLogger).
+					Warn("could not resolve effective roles", zap.Uint64("userID", i.Identity()), zap.Error(err))
+
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.SetIdentityToContext(r.Context(), auth.NewIdentity(i.Identity(), rr...))))
+	})
+}
+
+// resolve loads the user and effective roles, they are cached by the auth service
+func resolve(r *http.Request, userID uint64) ([]uint64, error) {
+	ctx := auth.SetSuperUserContext(r.Context())
+
+	u, err := service.DefaultUser.With(ctx).FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = service.DefaultAuth.With(ctx).LoadRoleMemberships(u); err != nil {
+		return nil, err
+	}
+
+	return u.Roles(), nil
+}
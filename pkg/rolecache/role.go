@@ -0,0 +1,76 @@
+package rolecache
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	// roleService wraps role service and invalidates cached roles
+	roleService struct {
+		service.RoleService
+		ctx context.Context
+	}
+)
+
+// Role decorates role service with invalidation of cached roles
+//
+// Members are invalidated when they are added or removed, all users
+// when the role itself changes in a way that affects its members.
+func Role(rs service.RoleService) service.RoleService {
+	return &roleService{RoleService: rs, ctx: context.Background()}
+}
+
+func (svc roleService) With(ctx context.Context) service.RoleService {
+	return &roleService{
+		RoleService: svc.RoleService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc roleService) MemberAdd(roleID, userID uint64) error {
+	if err := svc.RoleService.MemberAdd(roleID, userID); err != nil {
+		return err
+	}
+
+	Invalidate(userID)
+	return nil
+}
+
+func (svc roleService) MemberRemove(roleID, userID uint64) error {
+	if err := svc.RoleService.MemberRemove(roleID, userID); err != nil {
+		return err
+	}
+
+	Invalidate(userID)
+	return nil
+}
+
+func (svc roleService) Merge(roleID, targetRoleID uint64) error {
+	return flushAfter(svc.RoleService.Merge(roleID, targetRoleID))
+}
+
+func (svc roleService) Archive(roleID uint64) error {
+	return flushAfter(svc.RoleService.Archive(roleID))
+}
+
+func (svc roleService) Unarchive(roleID uint64) error {
+	return flushAfter(svc.RoleService.Unarchive(roleID))
+}
+
+func (svc roleService) Delete(roleID uint64) error {
+	return flushAfter(svc.RoleService.Delete(roleID))
+}
+
+func (svc roleService) Undelete(roleID uint64) error {
+	return flushAfter(svc.RoleService.Undelete(roleID))
+}
+
+func flushAfter(err error) error {
+	if err == nil {
+		Flush()
+	}
+
+	return err
+}
@@ -0,0 +1,37 @@
+package rolecache
+
+import (
+	"time"
+)
+
+var (
+	current = newCache()
+
+	// Roles changed outside of this process (other instances, direct
+	// changes of the database) are picked up when cached roles expire
+	ttl = 5 * time.Minute
+
+	now = time.Now
+)
+
+// Invalidate forgets effective roles of the users
+//
+// Must be called whenever memberships of users change in a way that
+// the role service does not see.
+func Invalidate(userIDs ...uint64) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	metricInvalidations.WithLabelValues("users").Inc()
+	current.invalidate(userIDs...)
+}
+
+// Flush forgets effective roles of all users
+//
+// Must be called on changes that affect memberships of many users,
+// like changes of nested roles.
+func Flush() {
+	metricInvalidations.WithLabelValues("all").Inc()
+	current.flush()
+}
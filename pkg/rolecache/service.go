@@ -0,0 +1,32 @@
+package rolecache
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/system/service"
+)
+
+var (
+	// set by Init, middleware does nothing before
+	defaultLogger *zap.Logger
+)
+
+// Init caches effective roles of users and invalidates them on membership changes
+//
+// Must be called after system services are initialized and after role
+// rules, expiring memberships and role tree, so that their roles are
+// cached too.
+func Init(ctx context.Context, log *zap.Logger) error {
+	ttl = options.EnvDuration("", "ROLE_CACHE_TTL", ttl)
+
+	registerMetrics()
+	defaultLogger = log
+
+	service.DefaultAuth = Auth(service.DefaultAuth)
+	service.DefaultRole = Role(service.DefaultRole)
+
+	return nil
+}
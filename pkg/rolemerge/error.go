@@ -0,0 +1,36 @@
+package rolemerge
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	rolemergeError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions = rolemergeError{"NoPermissions", fault.Forbidden}
+	ErrInvalidTarget = rolemergeError{"InvalidTarget", fault.Invalid}
+	ErrRoleNotFound  = rolemergeError{"RoleNotFound", fault.NotFound}
+	ErrSameRole      = rolemergeError{"SameRole", fault.Invalid}
+	ErrReservedRole  = rolemergeError{"ReservedRole", fault.Forbidden}
+)
+
+func (e rolemergeError) Error() string {
+	return e.String()
+}
+
+func (e rolemergeError) String() string {
+	return "crust.rolemerge." + e.name
+}
+
+func (e rolemergeError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e rolemergeError) withStack() *fault.Error {
+	return fault.New(e)
+}
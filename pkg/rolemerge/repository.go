@@ -0,0 +1,65 @@
+package rolemerge
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "sys_role_member"
+}
+
+// Members returns IDs of role's members
+func (r repository) Members(roleID uint64) (ids []uint64, err error) {
+	q := squirrel.
+		Select("rel_user").
+		From(r.table()).
+		Where(squirrel.Eq{"rel_role": roleID})
+
+	return ids, rh.FetchAll(r.db(), q, &ids)
+}
+
+// Transfer moves all members of the role to the target, at once
+//
+// Users that are members of both roles stay members of the target.
+func (r repository) Transfer(roleID, targetRoleID uint64) error {
+	return r.db().Transaction(func() error {
+		_, err := r.db().Exec(
+			"INSERT IGNORE INTO "+r.table()+" (rel_role, rel_user) SELECT ?, rel_user FROM "+r.table()+" WHERE rel_role = ?",
+			targetRoleID, roleID,
+		)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		return rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_role": roleID})
+	})
+}
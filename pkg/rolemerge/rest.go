@@ -0,0 +1,25 @@
+package rolemerge
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts dry-run of role merge, merge itself is corteza's endpoint
+//
+// Expects to be mounted under a path with {roleID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// What merging into the target would change
+	r.Get("/{targetRoleID}", rest.Handler("RoleMerge.DryRun", func(r *http.Request) (interface{}, error) {
+		return DefaultRoleMerge.With(r.Context()).DryRun(
+			rest.ParamUint64(r, "roleID"),
+			rest.ParamUint64(r, "targetRoleID"),
+		)
+	}))
+}
@@ -0,0 +1,34 @@
+package rolemerge
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	// roleService wraps role service and merges roles
+	roleService struct {
+		service.RoleService
+		ctx context.Context
+	}
+)
+
+// Role decorates role service with merge of members and rules
+//
+// Corteza's role repository does not implement merge.
+func Role(rs service.RoleService) service.RoleService {
+	return &roleService{RoleService: rs, ctx: context.Background()}
+}
+
+func (svc roleService) With(ctx context.Context) service.RoleService {
+	return &roleService{
+		RoleService: svc.RoleService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc roleService) Merge(roleID, targetRoleID uint64) error {
+	_, err := defaultRoleMerge.with(svc.ctx).Merge(roleID, targetRoleID)
+	return err
+}
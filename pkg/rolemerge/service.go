@@ -0,0 +1,314 @@
+package rolemerge
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	rolemergeService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac roleAccessController
+
+		role service.RoleService
+
+		repository *repository
+	}
+
+	roleAccessController interface {
+		CanReadRole(context.Context, *types.Role) bool
+		CanUpdateRole(context.Context, *types.Role) bool
+		CanManageRoleMembers(context.Context, *types.Role) bool
+	}
+
+	RoleMergeService interface {
+		With(ctx context.Context) RoleMergeService
+
+		DryRun(roleID, targetRoleID uint64) (*Report, error)
+		Merge(roleID, targetRoleID uint64) (*Report, error)
+	}
+)
+
+var (
+	DefaultRoleMerge RoleMergeService
+
+	// used by role service decorator
+	defaultRoleMerge *rolemergeService
+)
+
+// Init initializes role merge and implements merge of role service
+//
+// Must be called after system services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := (&rolemergeService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		role:   service.DefaultRole,
+	}).with(ctx)
+
+	DefaultRoleMerge = svc
+	defaultRoleMerge = svc
+
+	service.DefaultRole = Role(service.DefaultRole)
+
+	return nil
+}
+
+func (svc rolemergeService) With(ctx context.Context) RoleMergeService {
+	return svc.with(ctx)
+}
+
+func (svc rolemergeService) with(ctx context.Context) *rolemergeService {
+	return &rolemergeService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+		role:   svc.role,
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc rolemergeService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// DryRun reports what merging the role into the target would change, without changing anything
+//
+// Report is available to those that can read both roles
+func (svc rolemergeService) DryRun(roleID, targetRoleID uint64) (*Report, error) {
+	src, dst, err := svc.load(roleID, targetRoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanReadRole(svc.ctx, src) || !svc.ac.CanReadRole(svc.ctx, dst) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	rep, _, err := svc.plan(roleID, targetRoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	rep.DryRun = true
+	return rep, nil
+}
+
+// Merge moves members and rules of the role to the target
+//
+// Members are moved at once; rules are granted to the target and removed
+// from the role at once for each app. Target keeps its rules that conflict
+// with rules of the role. The role itself is left empty, not removed.
+//
+// Merge is available to those that can update and manage members of both
+// roles and grant permissions of all apps
+func (svc rolemergeService) Merge(roleID, targetRoleID uint64) (*Report, error) {
+	src, dst, err := svc.load(roleID, targetRoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range []*types.Role{src, dst} {
+		if !svc.ac.CanUpdateRole(svc.ctx, r) || !svc.ac.CanManageRoleMembers(svc.ctx, r) {
+			return nil, ErrNoPermissions.withStack()
+		}
+	}
+
+	rep, grants, err := svc.plan(roleID, targetRoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	aa := apps()
+	for _, a := range aa {
+		if len(grants[a.name]) > 0 && !a.ac.CanGrant(svc.ctx) {
+			return nil, ErrNoPermissions.withStack()
+		}
+	}
+
+	for _, a := range aa {
+		if len(grants[a.name]) == 0 {
+			continue
+		}
+
+		if err = a.ac.Grant(svc.ctx, grants[a.name]...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = svc.repository.Transfer(roleID, targetRoleID); err != nil {
+		return nil, err
+	}
+
+	svc.log(
+		zap.Uint64("roleID", roleID),
+		zap.Uint64("targetRoleID", targetRoleID),
+		zap.Int("transferred", len(rep.Transferred)),
+		zap.Int("rules", len(rep.Rules)),
+		zap.Int("conflicts", rep.Conflicts),
+	).Info("role merged")
+
+	return rep, nil
+}
+
+// load returns both roles, reserved roles can not be merged
+func (svc rolemergeService) load(roleID, targetRoleID uint64) (src, dst *types.Role, err error) {
+	switch {
+	case targetRoleID == 0:
+		return nil, nil, ErrInvalidTarget.withStack()
+	case roleID == targetRoleID:
+		return nil, nil, ErrSameRole.withStack()
+	case roleID == permissions.EveryoneRoleID || targetRoleID == permissions.EveryoneRoleID:
+		return nil, nil, ErrReservedRole.withStack()
+	}
+
+	rs := svc.role.With(auth.SetSuperUserContext(svc.ctx))
+
+	if src, err = rs.FindByID(roleID); err != nil {
+		return nil, nil, ErrRoleNotFound.withStack()
+	}
+
+	if dst, err = rs.FindByID(targetRoleID); err != nil {
+		return nil, nil, ErrRoleNotFound.withStack()
+	}
+
+	return src, dst, nil
+}
+
+// plan compares members and rules of both roles
+//
+// Returns report and rules to grant for each app: target's new rules and
+// inherit rules that remove all rules of the role.
+func (svc rolemergeService) plan(roleID, targetRoleID uint64) (*Report, map[string]permissions.RuleSet, error) {
+	var (
+		rep = &Report{
+			RoleID:       roleID,
+			TargetRoleID: targetRoleID,
+			Transferred:  []string{},
+			Duplicates:   []string{},
+			Rules:        []*Rule{},
+		}
+
+		grants = map[string]permissions.RuleSet{}
+	)
+
+	src, err := svc.repository.Members(roleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dst, err := svc.repository.Members(targetRoleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	members := map[uint64]bool{}
+	for _, userID := range dst {
+		members[userID] = true
+	}
+
+	for _, userID := range src {
+		if members[userID] {
+			rep.Duplicates = append(rep.Duplicates, strconv.FormatUint(userID, 10))
+		} else {
+			rep.Transferred = append(rep.Transferred, strconv.FormatUint(userID, 10))
+		}
+	}
+
+	running := map[string]*app{}
+	for _, a := range apps() {
+		running[a.name] = a
+	}
+
+	for _, name := range []string{"system", "compose", "messaging"} {
+		a := running[name]
+		if a == nil {
+			rep.Skipped = append(rep.Skipped, name)
+			continue
+		}
+
+		target := map[string]permissions.Access{}
+		for _, r := range a.rules.FindRulesByRoleID(targetRoleID) {
+			target[r.Resource.String()+"|"+r.Operation.String()] = r.Access
+		}
+
+		rr := a.rules.FindRulesByRoleID(roleID)
+		sort.Slice(rr, func(i, j int) bool {
+			if rr[i].Resource != rr[j].Resource {
+				return rr[i].Resource < rr[j].Resource
+			}
+
+			return rr[i].Operation < rr[j].Operation
+		})
+
+		for _, r := range rr {
+			if r.Access == permissions.Inherit {
+				continue
+			}
+
+			rule := &Rule{
+				App:       name,
+				Resource:  r.Resource,
+				Operation: r.Operation,
+				Access:    r.Access,
+				Outcome:   OutcomeTransfer,
+			}
+
+			if t, ok := target[r.Resource.String()+"|"+r.Operation.String()]; ok {
+				rule.TargetAccess = &t
+				if t == r.Access {
+					rule.Outcome = OutcomeDuplicate
+				} else {
+					rule.Outcome = OutcomeConflict
+					rep.Conflicts++
+				}
+			}
+
+			if rule.Outcome == OutcomeTransfer {
+				grants[name] = append(grants[name], &permissions.Rule{
+					RoleID:    targetRoleID,
+					Resource:  r.Resource,
+					Operation: r.Operation,
+					Access:    r.Access,
+				})
+			}
+
+			grants[name] = append(grants[name], permissions.InheritRule(roleID, r.Resource, r.Operation))
+			rep.Rules = append(rep.Rules, rule)
+		}
+	}
+
+	return rep, grants, nil
+}
+
+// apps returns apps running in this process
+func apps() []*app {
+	aa := []*app{{name: "system", rules: service.DefaultPermissions, ac: service.DefaultAccessControl}}
+
+	if composeService.DefaultPermissions != nil {
+		aa = append(aa, &app{name: "compose", rules: composeService.DefaultPermissions, ac: composeService.DefaultAccessControl})
+	}
+
+	if messagingService.DefaultPermissions != nil {
+		aa = append(aa, &app{name: "messaging", rules: messagingService.DefaultPermissions, ac: messagingService.DefaultAccessControl})
+	}
+
+	return aa
+}
@@ -0,0 +1,70 @@
+package rolemerge
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+)
+
+type (
+	// Report of the merge, what changes (or changed) when role is merged into the target
+	Report struct {
+		RoleID       uint64 `json:"roleID,string"`
+		TargetRoleID uint64 `json:"targetRoleID,string"`
+		DryRun       bool   `json:"dryRun"`
+
+		// Members of the role that become members of the target
+		Transferred []string `json:"transferred"`
+
+		// Members of both roles, memberships of the role are removed
+		Duplicates []string `json:"duplicates"`
+
+		Rules []*Rule `json:"rules"`
+
+		// Number of rules that conflict with rules of the target
+		Conflicts int `json:"conflicts"`
+
+		// Apps not running in this process, their rules are not merged
+		Skipped []string `json:"skipped,omitempty"`
+	}
+
+	// Rule of the role and what happens to it
+	Rule struct {
+		App       string                `json:"app"`
+		Resource  permissions.Resource  `json:"resource"`
+		Operation permissions.Operation `json:"operation"`
+		Access    permissions.Access    `json:"access"`
+
+		// Access of target's rule for the same resource & operation, if any
+		TargetAccess *permissions.Access `json:"targetAccess,omitempty"`
+
+		Outcome string `json:"outcome"`
+	}
+
+	// app with rules of roles
+	app struct {
+		name  string
+		rules rulesFinder
+		ac    accessController
+	}
+
+	rulesFinder interface {
+		FindRulesByRoleID(roleID uint64) permissions.RuleSet
+	}
+
+	accessController interface {
+		CanGrant(context.Context) bool
+		Grant(context.Context, ...*permissions.Rule) error
+	}
+)
+
+const (
+	// Rule is granted to the target
+	OutcomeTransfer = "transfer"
+
+	// Target has the same rule
+	OutcomeDuplicate = "duplicate"
+
+	// Target has the opposite rule, it is kept
+	OutcomeConflict = "conflict"
+)
@@ -0,0 +1,46 @@
+package roletree
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// authService wraps auth service and adds descendant roles to memberships
+	authService struct {
+		service.AuthService
+		ctx context.Context
+	}
+)
+
+// Auth decorates auth service with nested role memberships
+//
+// Tokens are issued with memberships that include descendants of user's
+// roles, so permission checks of all apps see them without any changes.
+// Changes of the tree apply to tokens issued after them.
+func Auth(as service.AuthService) service.AuthService {
+	return &authService{AuthService: as, ctx: context.Background()}
+}
+
+func (svc authService) With(ctx context.Context) service.AuthService {
+	return &authService{
+		AuthService: svc.AuthService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc authService) LoadRoleMemberships(u *types.User) error {
+	if err := svc.AuthService.LoadRoleMemberships(u); err != nil {
+		return err
+	}
+
+	rr, err := defaultRoleTree.with(svc.ctx).expand(u.Roles())
+	if err != nil {
+		return err
+	}
+
+	u.SetRoles(rr)
+	return nil
+}
@@ -0,0 +1,33 @@
+package roletree
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	roletreeError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions = roletreeError{"NoPermissions", fault.Forbidden}
+	ErrCycle         = roletreeError{"Cycle", fault.Invalid}
+)
+
+func (e roletreeError) Error() string {
+	return e.String()
+}
+
+func (e roletreeError) String() string {
+	return "crust.roletree." + e.name
+}
+
+func (e roletreeError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e roletreeError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,25 @@
+package roletree
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200215000000.roletree",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_system_role_child (
+  rel_parent       BIGINT UNSIGNED NOT NULL,
+  rel_child        BIGINT UNSIGNED NOT NULL,
+
+  created_by       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_parent, rel_child),
+  INDEX (rel_child)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
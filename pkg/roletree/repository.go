@@ -0,0 +1,57 @@
+package roletree
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_system_role_child"
+}
+
+// Find returns all edges, trees are small enough to be walked in memory
+func (r repository) Find() (set EdgeSet, err error) {
+	q := squirrel.
+		Select("rel_parent", "rel_child", "created_by", "created_at").
+		From(r.table()).
+		OrderBy("rel_parent", "rel_child")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(e *Edge) (*Edge, error) {
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	return e, errors.WithStack(r.db().Replace(r.table(), e))
+}
+
+func (r repository) Delete(parentID, childID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_parent": parentID, "rel_child": childID})
+}
@@ -0,0 +1,42 @@
+package roletree
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts role tree endpoints
+//
+// Expects to be mounted under a path with {roleID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?recursive=true returns children, their children and so on
+	r.Get("/", rest.Handler("RoleTree.Children", func(r *http.Request) (interface{}, error) {
+		svc := DefaultRoleTree.With(r.Context())
+		if rest.QueryBool(r, "recursive") {
+			return svc.Effective(rest.ParamUint64(r, "roleID"))
+		}
+
+		return svc.Children(rest.ParamUint64(r, "roleID"))
+	}))
+
+	r.Put("/{childID}", rest.Handler("RoleTree.AddChild", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultRoleTree.With(r.Context()).AddChild(
+			rest.ParamUint64(r, "roleID"),
+			rest.ParamUint64(r, "childID"),
+		)
+	}))
+
+	r.Delete("/{childID}", rest.Handler("RoleTree.RemoveChild", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultRoleTree.With(r.Context()).RemoveChild(
+			rest.ParamUint64(r, "roleID"),
+			rest.ParamUint64(r, "childID"),
+		)
+	}))
+}
@@ -0,0 +1,224 @@
+package roletree
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/cortezaproject/corteza-server/system/types"
+	"github.com/crusttech/crust-server/pkg/rolecache"
+)
+
+type (
+	roletreeService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		role service.RoleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanReadRole(context.Context, *types.Role) bool
+		CanUpdateRole(context.Context, *types.Role) bool
+		CanManageRoleMembers(context.Context, *types.Role) bool
+	}
+
+	RoleTreeService interface {
+		With(ctx context.Context) RoleTreeService
+
+		Children(roleID uint64) (types.RoleSet, error)
+		Effective(roleID uint64) (types.RoleSet, error)
+
+		AddChild(parentID, childID uint64) error
+		RemoveChild(parentID, childID uint64) error
+	}
+)
+
+var (
+	DefaultRoleTree RoleTreeService
+
+	// used by auth service decorator
+	defaultRoleTree *roletreeService
+)
+
+// Init initializes role tree service and expands role memberships of issued tokens
+//
+// Must be called after system services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := (&roletreeService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		role:   service.DefaultRole,
+	}).with(ctx)
+
+	DefaultRoleTree = svc
+	defaultRoleTree = svc
+
+	service.DefaultAuth = Auth(service.DefaultAuth)
+
+	return nil
+}
+
+func (svc roletreeService) With(ctx context.Context) RoleTreeService {
+	return svc.with(ctx)
+}
+
+func (svc roletreeService) with(ctx context.Context) *roletreeService {
+	return &roletreeService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		role: svc.role.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// Children returns direct children of the role
+func (svc roletreeService) Children(roleID uint64) (types.RoleSet, error) {
+	if _, err := svc.readable(roleID); err != nil {
+		return nil, err
+	}
+
+	t, err := svc.tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.roles(t[roleID])
+}
+
+// Effective returns all roles that members of the role are treated as members of
+//
+// These are children of the role, their children and so on.
+func (svc roletreeService) Effective(roleID uint64) (types.RoleSet, error) {
+	if _, err := svc.readable(roleID); err != nil {
+		return nil, err
+	}
+
+	t, err := svc.tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.roles(t.descendants(roleID)[1:])
+}
+
+// AddChild makes the child role a part of the parent role
+//
+// Members of the parent get all grants of the child, so the user must
+// be able to update the parent and to manage members of the child.
+// Roles can not be their own descendants.
+func (svc roletreeService) AddChild(parentID, childID uint64) error {
+	if err := svc.canManage(parentID, childID); err != nil {
+		return err
+	}
+
+	t, err := svc.tree()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range t.descendants(childID) {
+		if id == parentID {
+			return ErrCycle.withStack().WithID("parentID", parentID).WithID("childID", childID)
+		}
+	}
+
+	_, err = svc.repository.Create(&Edge{
+		ParentID:  parentID,
+		ChildID:   childID,
+		CreatedBy: auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err == nil {
+		// Members of all ancestors get the child's roles
+		rolecache.Flush()
+	}
+
+	return err
+}
+
+// RemoveChild removes the child role from the parent role
+func (svc roletreeService) RemoveChild(parentID, childID uint64) error {
+	if err := svc.canManage(parentID, childID); err != nil {
+		return err
+	}
+
+	if err := svc.repository.Delete(parentID, childID); err != nil {
+		return err
+	}
+
+	rolecache.Flush()
+	return nil
+}
+
+// expand returns the roles with all of their descendants
+func (svc roletreeService) expand(roleIDs []uint64) ([]uint64, error) {
+	t, err := svc.tree()
+	if err != nil {
+		return nil, err
+	}
+
+	return t.descendants(roleIDs...), nil
+}
+
+func (svc roletreeService) tree() (tree, error) {
+	set, err := svc.repository.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	return set.tree(), nil
+}
+
+// roles loads the roles, ones that can not be read are skipped
+func (svc roletreeService) roles(roleIDs []uint64) (types.RoleSet, error) {
+	out := types.RoleSet{}
+	for _, roleID := range roleIDs {
+		if r, err := svc.readable(roleID); err == nil {
+			out = append(out, r)
+		}
+	}
+
+	return out, nil
+}
+
+func (svc roletreeService) readable(roleID uint64) (*types.Role, error) {
+	r, err := svc.role.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanReadRole(svc.ctx, r) {
+		return nil, ErrNoPermissions.withStack().WithID("roleID", roleID)
+	}
+
+	return r, nil
+}
+
+func (svc roletreeService) canManage(parentID, childID uint64) error {
+	parent, err := svc.role.FindByID(parentID)
+	if err != nil {
+		return err
+	}
+
+	child, err := svc.role.FindByID(childID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateRole(svc.ctx, parent) || !svc.ac.CanManageRoleMembers(svc.ctx, child) {
+		return ErrNoPermissions.withStack().WithID("parentID", parentID).WithID("childID", childID)
+	}
+
+	return nil
+}
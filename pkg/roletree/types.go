@@ -0,0 +1,56 @@
+package roletree
+
+import (
+	"time"
+)
+
+type (
+	// Edge makes the child role a part of the parent role
+	//
+	// Members of the parent are treated as members of the child
+	// (and of its children), so they get all grants of the child.
+	Edge struct {
+		ParentID uint64 `json:"parentID,string" db:"rel_parent"`
+		ChildID  uint64 `json:"childID,string" db:"rel_child"`
+
+		CreatedBy uint64    `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	EdgeSet []*Edge
+
+	// tree of roles, children by parents
+	tree map[uint64][]uint64
+)
+
+func (set EdgeSet) tree() tree {
+	t := tree{}
+	for _, e := range set {
+		t[e.ParentID] = append(t[e.ParentID], e.ChildID)
+	}
+
+	return t
+}
+
+// descendants returns the roles and all of their descendants, without duplicates
+func (t tree) descendants(roleIDs ...uint64) []uint64 {
+	var (
+		out  = make([]uint64, 0, len(roleIDs))
+		seen = map[uint64]bool{}
+	)
+
+	for len(roleIDs) > 0 {
+		roleID := roleIDs[0]
+		roleIDs = roleIDs[1:]
+
+		if seen[roleID] {
+			continue
+		}
+
+		seen[roleID] = true
+		out = append(out, roleID)
+		roleIDs = append(roleIDs, t[roleID]...)
+	}
+
+	return out
+}
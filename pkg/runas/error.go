@@ -0,0 +1,33 @@
+package runas
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	runasError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrUserInactive     = runasError{"UserInactive", fault.Forbidden}
+	ErrUsersUnavailable = runasError{"UsersUnavailable", fault.Unavailable}
+)
+
+func (e runasError) Error() string {
+	return e.String()
+}
+
+func (e runasError) String() string {
+	return "crust.runas." + e.name
+}
+
+func (e runasError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e runasError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,167 @@
+package runas
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/system/proto"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	tokenIssuer interface {
+		MakeJWT(ctx context.Context, ID uint64) (string, error)
+	}
+)
+
+const (
+	// Tokens are issued by system service; when it can not be reached
+	// in time, resolving fails instead of blocking the caller
+	issueTimeout = 10 * time.Second
+)
+
+var (
+	// system is gRPC client of system service, for apps that run without it
+	system struct {
+		sync.Once
+		users tokenIssuer
+		err   error
+	}
+)
+
+// Compose returns context with identity and JWT of the given user
+//
+// Used by background jobs that act in the name of a user. Token is issued
+// by the system service (same way as for corteza's automation scripts),
+// so identity carries user's current role memberships.
+func Compose(ctx context.Context, userID uint64) (context.Context, error) {
+	identity, jwt, err := issue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = auth.SetIdentityToContext(ctx, identity)
+	ctx = auth.SetJwtToContext(ctx, jwt)
+
+	return ctx, nil
+}
+
+// Identity returns identity of the given user with current role memberships
+//
+// Used instead of roles stored with webhooks, jobs and such, so that
+// changed memberships apply to them; users that were suspended or deleted
+// since can not act anymore.
+//
+// Apps that run without system services (standalone compose and messaging
+// servers) get the identity from the token that system service issues over
+// gRPC, the same way as Compose does; system service issues tokens without
+// checking if the user is suspended or deleted.
+func Identity(ctx context.Context, userID uint64) (auth.Identifiable, error) {
+	if systemService.DefaultUser == nil || systemService.DefaultAuth == nil {
+		i, _, err := issue(ctx, userID)
+		return i, err
+	}
+
+	u, err := systemService.DefaultUser.With(auth.SetSuperUserContext(ctx)).FindByID(userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not load user %d", userID)
+	} else if !u.Valid() {
+		return nil, ErrUserInactive.withStack()
+	}
+
+	if err = systemService.DefaultAuth.LoadRoleMemberships(u); err != nil {
+		return nil, errors.Wrapf(err, "could not load roles of user %d", userID)
+	}
+
+	return u, nil
+}
+
+// Context returns context with identity of the given user, see Identity
+func Context(ctx context.Context, userID uint64) (context.Context, error) {
+	i, err := Identity(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.SetIdentityToContext(ctx, i), nil
+}
+
+// Token returns JWT of the given user with current role memberships, see Identity
+//
+// Used where credentials are handed over, e.g. to automation scripts.
+func Token(ctx context.Context, userID uint64) (string, error) {
+	if systemService.DefaultUser == nil || systemService.DefaultAuth == nil {
+		_, jwt, err := issue(ctx, userID)
+		return jwt, err
+	}
+
+	i, err := Identity(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return auth.DefaultJwtHandler.Encode(i), nil
+}
+
+// issue has system service issue token for the user and decodes its identity
+//
+// Fails with ErrUsersUnavailable when system service can not be reached.
+func issue(ctx context.Context, userID uint64) (auth.Identifiable, string, error) {
+	users, err := issuer()
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(auth.SetSuperUserContext(ctx), issueTimeout)
+	defer cancel()
+
+	jwt, err := users.MakeJWT(ctx, userID)
+	if c := status.Code(err); c == codes.Unavailable || c == codes.DeadlineExceeded {
+		return nil, "", ErrUsersUnavailable.withStack().WithMessage(err.Error())
+	} else if err != nil {
+		return nil, "", errors.Wrapf(err, "could not issue token for user %d", userID)
+	}
+
+	identity, err := auth.DefaultJwtHandler.Decode(jwt)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "could not decode token for user %d", userID)
+	}
+
+	return identity, jwt, nil
+}
+
+// issuer returns compose's client of system service or connects its own
+//
+// Connection is made when first needed, with the same settings
+// (SYSTEM_GRPC_SERVER_ADDR...) that compose uses.
+func issuer() (tokenIssuer, error) {
+	if service.DefaultSystemUser != nil {
+		return service.DefaultSystemUser, nil
+	}
+
+	system.Do(func() {
+		var log = zap.NewNop()
+		if service.DefaultLogger != nil {
+			log = service.DefaultLogger
+		}
+
+		conn, err := service.NewSystemGRPCClient(context.Background(), *options.GRPCServer("system"), log.Named("runas"))
+		if err != nil {
+			system.err = ErrUsersUnavailable.withStack().WithMessage(err.Error())
+			return
+		}
+
+		system.users = service.SystemUser(proto.NewUsersClient(conn))
+	})
+
+	return system.users, system.err
+}
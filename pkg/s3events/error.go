@@ -0,0 +1,46 @@
+package s3events
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	s3eventsError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID          = s3eventsError{"InvalidID", fault.Invalid}
+	ErrNameRequired       = s3eventsError{"NameRequired", fault.Invalid}
+	ErrInvalidTopic       = s3eventsError{"InvalidTopic", fault.Invalid}
+	ErrInvalidBucket      = s3eventsError{"InvalidBucket", fault.Invalid}
+	ErrInvalidMode        = s3eventsError{"InvalidMode", fault.Invalid}
+	ErrInvalidField       = s3eventsError{"InvalidField", fault.Invalid}
+	ErrInvalidMessage     = s3eventsError{"InvalidMessage", fault.Invalid}
+	ErrInvalidSignature   = s3eventsError{"InvalidSignature", fault.Forbidden}
+	ErrTopicMismatch      = s3eventsError{"TopicMismatch", fault.Forbidden}
+	ErrObjectTooLarge     = s3eventsError{"ObjectTooLarge", fault.Invalid}
+	ErrInvalidObjectKey   = s3eventsError{"InvalidObjectKey", fault.Invalid}
+	ErrNoPermissions      = s3eventsError{"NoPermissions", fault.Forbidden}
+	ErrSourceNotFound     = s3eventsError{"SourceNotFound", fault.NotFound}
+	ErrSourceDisabled     = s3eventsError{"SourceDisabled", fault.Conflict}
+	ErrUnknownMessageType = s3eventsError{"UnknownMessageType", fault.Invalid}
+)
+
+func (e s3eventsError) Error() string {
+	return e.String()
+}
+
+func (e s3eventsError) String() string {
+	return "crust.s3events." + e.name
+}
+
+func (e s3eventsError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e s3eventsError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,57 @@
+package s3events
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200127000000.s3events",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_s3_source (
+  id                BIGINT UNSIGNED NOT NULL,
+  rel_namespace     BIGINT UNSIGNED NOT NULL,
+  rel_module        BIGINT UNSIGNED NOT NULL,
+  name              VARCHAR(255)    NOT NULL,
+  topic_arn         VARCHAR(255)    NOT NULL,
+  endpoint          VARCHAR(255)    NOT NULL,
+  insecure          BOOLEAN         NOT NULL DEFAULT FALSE,
+  bucket            VARCHAR(64)     NOT NULL,
+  prefix            VARCHAR(255)    NOT NULL DEFAULT '',
+  access_key_id     VARCHAR(128)    NOT NULL,
+  secret_access_key VARCHAR(255)    NOT NULL,
+  mode              VARCHAR(16)     NOT NULL,
+  file_field        VARCHAR(64)     NOT NULL,
+  key_field         VARCHAR(64)     NOT NULL DEFAULT '',
+  enabled           BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  owned_by          BIGINT UNSIGNED NOT NULL,
+  created_at        DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at        DATETIME            NULL DEFAULT NULL,
+  deleted_at        DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace, rel_module)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_s3_object (
+  id                BIGINT UNSIGNED NOT NULL,
+  rel_source        BIGINT UNSIGNED NOT NULL,
+  object_key        VARCHAR(1024)   NOT NULL,
+  etag              VARCHAR(64)     NOT NULL,
+  size              BIGINT          NOT NULL DEFAULT 0,
+  rel_attachment    BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  rel_record        BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  fail_reason       TEXT            NOT NULL,
+
+  created_at        DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  INDEX (rel_source, etag),
+  INDEX (rel_source, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
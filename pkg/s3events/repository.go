@@ -0,0 +1,161 @@
+package s3events
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableSource() string {
+	return "crust_compose_s3_source"
+}
+
+func (r repository) tableObject() string {
+	return "crust_compose_s3_object"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_namespace",
+			"rel_module",
+			"name",
+			"topic_arn",
+			"endpoint",
+			"insecure",
+			"bucket",
+			"prefix",
+			"access_key_id",
+			"secret_access_key",
+			"mode",
+			"file_field",
+			"key_field",
+			"enabled",
+			"owned_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.tableSource()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(namespaceID, sourceID uint64) (*Source, error) {
+	return r.findOne(squirrel.Eq{"id": sourceID, "rel_namespace": namespaceID})
+}
+
+// FindByIDAnyNamespace returns source for the notification endpoint that knows only source's ID
+func (r repository) FindByIDAnyNamespace(sourceID uint64) (*Source, error) {
+	return r.findOne(squirrel.Eq{"id": sourceID})
+}
+
+func (r repository) findOne(cnd squirrel.Sqlizer) (*Source, error) {
+	var (
+		src = &Source{}
+		q   = r.query().Where(cnd)
+	)
+
+	if err := rh.FetchOne(r.db(), q, src); err != nil {
+		return nil, err
+	} else if src.ID == 0 {
+		return nil, ErrSourceNotFound.withStack()
+	}
+
+	return src, nil
+}
+
+func (r repository) Find(f SourceFilter) (set SourceSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": f.NamespaceID, "rel_module": f.ModuleID}).
+		OrderBy("name")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(src *Source) (*Source, error) {
+	src.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&src.CreatedAt)
+
+	return src, errors.WithStack(r.db().Insert(r.tableSource(), src))
+}
+
+func (r repository) Update(src *Source) (*Source, error) {
+	rh.SetCurrentTimeRounded(&src.UpdatedAt)
+
+	return src, errors.WithStack(r.db().Replace(r.tableSource(), src))
+}
+
+func (r repository) DeleteByID(namespaceID, sourceID uint64) error {
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableSource(),
+		rh.Set{"deleted_at": time.Now()},
+		squirrel.Eq{"id": sourceID, "rel_namespace": namespaceID},
+	)
+}
+
+// FindObjects returns the latest objects of the source, newest first
+func (r repository) FindObjects(sourceID uint64) (set ObjectSet, err error) {
+	q := squirrel.
+		Select("id", "rel_source", "object_key", "etag", "size", "rel_attachment", "rel_record", "fail_reason", "created_at").
+		From(r.tableObject()).
+		Where(squirrel.Eq{"rel_source": sourceID}).
+		OrderBy("created_at DESC").
+		Limit(maxObjects)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Registered checks if the object (with the same content) was already registered
+//
+// S3 delivers notifications at least once; duplicates are ignored
+func (r repository) Registered(sourceID uint64, key, etag string) (bool, error) {
+	var (
+		count uint
+		q     = squirrel.
+			Select("COUNT(*)").
+			From(r.tableObject()).
+			Where(squirrel.Eq{"rel_source": sourceID, "etag": etag, "object_key": key, "fail_reason": ""})
+	)
+
+	if err := rh.FetchOne(r.db(), q, &count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (r repository) CreateObject(obj *Object) (*Object, error) {
+	obj.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&obj.CreatedAt)
+
+	return obj, errors.WithStack(r.db().Insert(r.tableObject(), obj))
+}
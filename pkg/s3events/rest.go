@@ -0,0 +1,93 @@
+package s3events
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+const (
+	// SNS messages are limited to 256KB
+	maxMessageSize = 256 << 10
+)
+
+// MountRoutes mounts S3 source endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {moduleID} params
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("S3Source.List", func(r *http.Request) (interface{}, error) {
+		return DefaultS3Events.With(r.Context()).Find(SourceFilter{
+			NamespaceID: rest.ParamUint64(r, "namespaceID"),
+			ModuleID:    rest.ParamUint64(r, "moduleID"),
+		})
+	}))
+
+	r.Post("/", rest.Handler("S3Source.Create", func(r *http.Request) (interface{}, error) {
+		src := &Source{}
+		if err := rest.Decode(r, src); err != nil {
+			return nil, err
+		}
+
+		src.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		src.ModuleID = rest.ParamUint64(r, "moduleID")
+		return DefaultS3Events.With(r.Context()).Create(src)
+	}))
+
+	r.Get("/{sourceID}", rest.Handler("S3Source.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultS3Events.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "sourceID"),
+		)
+	}))
+
+	r.Put("/{sourceID}", rest.Handler("S3Source.Update", func(r *http.Request) (interface{}, error) {
+		src := &Source{}
+		if err := rest.Decode(r, src); err != nil {
+			return nil, err
+		}
+
+		src.ID = rest.ParamUint64(r, "sourceID")
+		src.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultS3Events.With(r.Context()).Update(src)
+	}))
+
+	r.Delete("/{sourceID}", rest.Handler("S3Source.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultS3Events.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "sourceID"),
+		)
+	}))
+
+	r.Get("/{sourceID}/objects", rest.Handler("S3Source.Objects", func(r *http.Request) (interface{}, error) {
+		return DefaultS3Events.With(r.Context()).Objects(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "sourceID"),
+		)
+	}))
+}
+
+// MountNotificationRoutes mounts endpoint for SNS HTTP(S) subscriptions
+//
+// SNS can not authenticate, messages are verified by their signature
+func MountNotificationRoutes(r chi.Router) {
+	r.Post("/{sourceID}", rest.Handler("S3Source.Notify", func(r *http.Request) (interface{}, error) {
+		payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return resputil.OK(), DefaultS3Events.With(r.Context()).Notify(
+			rest.ParamUint64(r, "sourceID"),
+			payload,
+		)
+	}))
+}
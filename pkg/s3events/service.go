@@ -0,0 +1,483 @@
+package s3events
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/store/minio"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	s3eventsService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		// Objects are registered in the background, outside of notification request
+		bg context.Context
+
+		ac accessController
+
+		module service.ModuleService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanUpdateModule(context.Context, *types.Module) bool
+	}
+
+	S3EventsService interface {
+		With(ctx context.Context) S3EventsService
+
+		FindByID(namespaceID, sourceID uint64) (*Source, error)
+		Find(SourceFilter) (SourceSet, error)
+		Create(*Source) (*Source, error)
+		Update(*Source) (*Source, error)
+		DeleteByID(namespaceID, sourceID uint64) error
+
+		Objects(namespaceID, sourceID uint64) (ObjectSet, error)
+
+		Notify(sourceID uint64, payload []byte) error
+	}
+)
+
+var (
+	DefaultS3Events S3EventsService
+
+	topicArn   = regexp.MustCompile(`^arn:aws[a-z-]*:sns:[a-z0-9-]+:[0-9]{12}:[A-Za-z0-9_.-]+$`)
+	bucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+)
+
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &s3eventsService{
+		logger: log,
+		bg:     ctx,
+		ac:     service.DefaultAccessControl,
+		module: service.DefaultModule,
+	}
+
+	DefaultS3Events = svc.With(ctx)
+
+	return nil
+}
+
+func (svc s3eventsService) With(ctx context.Context) S3EventsService {
+	return &s3eventsService{
+		ctx:    ctx,
+		logger: svc.logger,
+		bg:     svc.bg,
+		ac:     svc.ac,
+
+		module: svc.module.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc s3eventsService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc s3eventsService) FindByID(namespaceID, sourceID uint64) (*Source, error) {
+	if sourceID == 0 {
+		return nil, ErrInvalidID.withStack()
+	}
+
+	src, err := svc.repository.FindByID(namespaceID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = svc.canManage(namespaceID, src.ModuleID); err != nil {
+		return nil, err
+	}
+
+	return src.withoutSecret(), nil
+}
+
+func (svc s3eventsService) Find(f SourceFilter) (SourceSet, error) {
+	if _, err := svc.canManage(f.NamespaceID, f.ModuleID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.Find(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set {
+		set[i] = set[i].withoutSecret()
+	}
+
+	return set, nil
+}
+
+func (svc s3eventsService) Create(in *Source) (*Source, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	src := &Source{
+		NamespaceID:     in.NamespaceID,
+		ModuleID:        in.ModuleID,
+		Name:            in.Name,
+		TopicArn:        in.TopicArn,
+		Endpoint:        in.Endpoint,
+		Insecure:        in.Insecure,
+		Bucket:          in.Bucket,
+		Prefix:          in.Prefix,
+		AccessKeyID:     in.AccessKeyID,
+		SecretAccessKey: in.SecretAccessKey,
+		Mode:            in.Mode,
+		FileField:       in.FileField,
+		KeyField:        in.KeyField,
+		Enabled:         in.Enabled,
+		OwnedBy:         auth.GetIdentityFromContext(svc.ctx).Identity(),
+	}
+
+	src, err := svc.repository.Create(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return src.withoutSecret(), nil
+}
+
+// Update modifies the source
+//
+// Secret access key is changed only when a new one is given
+func (svc s3eventsService) Update(upd *Source) (*Source, error) {
+	src, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Module can not be changed
+	upd.ModuleID = src.ModuleID
+
+	if err = svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	src.Name = upd.Name
+	src.TopicArn = upd.TopicArn
+	src.Endpoint = upd.Endpoint
+	src.Insecure = upd.Insecure
+	src.Bucket = upd.Bucket
+	src.Prefix = upd.Prefix
+	src.AccessKeyID = upd.AccessKeyID
+	src.Mode = upd.Mode
+	src.FileField = upd.FileField
+	src.KeyField = upd.KeyField
+	src.Enabled = upd.Enabled
+
+	if upd.SecretAccessKey != "" {
+		src.SecretAccessKey = upd.SecretAccessKey
+	}
+
+	if src, err = svc.repository.Update(src); err != nil {
+		return nil, err
+	}
+
+	return src.withoutSecret(), nil
+}
+
+func (svc s3eventsService) DeleteByID(namespaceID, sourceID uint64) error {
+	src, err := svc.repository.FindByID(namespaceID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	if _, err = svc.canManage(namespaceID, src.ModuleID); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, sourceID)
+}
+
+// Objects returns the latest objects registered by the source
+func (svc s3eventsService) Objects(namespaceID, sourceID uint64) (ObjectSet, error) {
+	src, err := svc.FindByID(namespaceID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindObjects(src.ID)
+}
+
+// Notify handles message that SNS posted to source's subscription
+//
+// Messages are accepted only from source's topic and with a valid signature.
+// Subscription is confirmed automatically; objects from bucket notifications
+// are registered in the background so that SNS does not time out and retry.
+func (svc s3eventsService) Notify(sourceID uint64, payload []byte) error {
+	var m = snsMessage{}
+
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return ErrInvalidMessage.withStack()
+	}
+
+	src, err := svc.repository.FindByIDAnyNamespace(sourceID)
+	if err != nil {
+		return err
+	}
+
+	if err = m.check(svc.ctx, src.TopicArn); err != nil {
+		return err
+	}
+
+	log := svc.log(zap.Uint64("sourceID", src.ID), zap.String("messageID", m.MessageId))
+
+	switch m.Type {
+	case snsSubscriptionConfirmation:
+		if err = m.confirm(svc.ctx); err != nil {
+			return err
+		}
+
+		log.Info("subscription confirmed", zap.String("topicArn", m.TopicArn))
+		return nil
+
+	case snsUnsubscribeConfirmation:
+		log.Info("unsubscribed", zap.String("topicArn", m.TopicArn))
+		return nil
+
+	case snsNotification:
+		if !src.Enabled {
+			log.Debug("source disabled, notification ignored")
+			return nil
+		}
+
+		n := s3Notification{}
+		if err = json.Unmarshal([]byte(m.Message), &n); err != nil {
+			// S3 test events and other non-record messages
+			log.Debug("notification without records ignored")
+			return nil
+		}
+
+		go svc.registerAll(src, n.Records)
+		return nil
+	}
+
+	return ErrUnknownMessageType.withStack()
+}
+
+func (svc s3eventsService) registerAll(src *Source, rr []*s3Record) {
+	repo := Repository(svc.bg, factory.Database.MustGet("compose").With(svc.bg))
+
+	for _, rec := range rr {
+		if err := svc.register(svc.bg, repo, src, rec); err != nil {
+			svc.logger.Error("could not register object",
+				zap.Uint64("sourceID", src.ID),
+				zap.String("key", rec.S3.Object.Key),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// register stores the object as an attachment and records the result
+func (svc s3eventsService) register(ctx context.Context, repo *repository, src *Source, rec *s3Record) error {
+	if !rec.created() {
+		return nil
+	}
+
+	key, err := rec.key()
+	if err != nil {
+		return ErrInvalidObjectKey.withStack()
+	}
+
+	if !src.matches(rec.S3.Bucket.Name, key) {
+		return nil
+	}
+
+	if ok, err := repo.Registered(src.ID, key, rec.S3.Object.ETag); err != nil || ok {
+		return err
+	}
+
+	obj := &Object{
+		SourceID: src.ID,
+		Key:      key,
+		ETag:     rec.S3.Object.ETag,
+		Size:     rec.S3.Object.Size,
+	}
+
+	if err = svc.store(ctx, src, obj); err != nil {
+		obj.FailReason = err.Error()
+	}
+
+	if _, err = repo.CreateObject(obj); err != nil {
+		return err
+	}
+
+	svc.logger.Info("object registered",
+		zap.Uint64("sourceID", src.ID),
+		zap.String("key", obj.Key),
+		zap.Uint64("attachmentID", obj.AttachmentID),
+		zap.Uint64("recordID", obj.RecordID),
+		zap.String("failReason", obj.FailReason),
+	)
+
+	return nil
+}
+
+// store copies the object into an attachment and links it with a record,
+// in the name of source's owner
+func (svc s3eventsService) store(ctx context.Context, src *Source, obj *Object) (err error) {
+	if obj.Size > maxObjectSize {
+		return ErrObjectTooLarge.withStack()
+	}
+
+	if src.Mode == ModeAttach {
+		if obj.RecordID, err = src.recordID(obj.Key); err != nil {
+			return
+		}
+	}
+
+	if ctx, err = runas.Compose(ctx, src.OwnedBy); err != nil {
+		return
+	}
+
+	m, err := svc.module.With(ctx).FindByID(src.NamespaceID, src.ModuleID)
+	if err != nil {
+		return
+	}
+
+	bucket, err := minio.New(src.Bucket, minio.Options{
+		Endpoint:        src.Endpoint,
+		Secure:          !src.Insecure,
+		Strict:          true,
+		AccessKeyID:     src.AccessKeyID,
+		SecretAccessKey: src.SecretAccessKey,
+	})
+
+	if err != nil {
+		return
+	}
+
+	fh, err := bucket.Open(obj.Key)
+	if err != nil {
+		return
+	}
+
+	att, err := service.DefaultAttachment.With(ctx).
+		CreateRecordAttachment(src.NamespaceID, fileName(obj.Key), obj.Size, fh, src.ModuleID, obj.RecordID, src.FileField)
+
+	if err != nil {
+		return
+	}
+
+	obj.AttachmentID = att.ID
+
+	var (
+		records = service.DefaultRecord.With(ctx)
+		r       = &types.Record{NamespaceID: src.NamespaceID, ModuleID: src.ModuleID}
+	)
+
+	if obj.RecordID > 0 {
+		if r, err = records.FindByID(src.NamespaceID, obj.RecordID); err != nil {
+			return
+		}
+	}
+
+	r.Values = setValue(r.Values, m.Fields.FindByName(src.FileField), strconv.FormatUint(att.ID, 10))
+	if src.KeyField != "" {
+		r.Values = setValue(r.Values, m.Fields.FindByName(src.KeyField), obj.Key)
+	}
+
+	if obj.RecordID > 0 {
+		_, err = records.Update(r)
+	} else if r, err = records.Create(r); err == nil {
+		obj.RecordID = r.ID
+	}
+
+	return
+}
+
+func (svc s3eventsService) canManage(namespaceID, moduleID uint64) (*types.Module, error) {
+	m, err := svc.module.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return m, nil
+}
+
+func (svc s3eventsService) validate(src *Source) error {
+	if src.NamespaceID == 0 {
+		return service.ErrNamespaceRequired
+	}
+
+	if src.Name == "" {
+		return ErrNameRequired.withStack()
+	}
+
+	m, err := svc.canManage(src.NamespaceID, src.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if !topicArn.MatchString(src.TopicArn) {
+		return ErrInvalidTopic.withStack()
+	}
+
+	if !bucketName.MatchString(src.Bucket) {
+		return ErrInvalidBucket.withStack()
+	}
+
+	if src.Endpoint == "" {
+		src.Endpoint = defaultEndpoint
+	}
+
+	if src.Mode == "" {
+		src.Mode = ModeCreate
+	} else if !src.Mode.IsValid() {
+		return ErrInvalidMode.withStack()
+	}
+
+	if f := m.Fields.FindByName(src.FileField); f == nil || f.Kind != "File" {
+		return ErrInvalidField.withStack()
+	}
+
+	if src.KeyField != "" {
+		if f := m.Fields.FindByName(src.KeyField); f == nil || f.Kind == "File" {
+			return ErrInvalidField.withStack()
+		}
+	}
+
+	return nil
+}
+
+// setValue sets value of the field; values of multi-value fields are appended
+func setValue(vv types.RecordValueSet, f *types.ModuleField, value string) types.RecordValueSet {
+	var (
+		place uint
+		out   = types.RecordValueSet{}
+	)
+
+	for _, v := range vv {
+		if v.Name != f.Name {
+			out = append(out, v)
+		} else if f.Multi {
+			out = append(out, v)
+			place++
+		}
+	}
+
+	return append(out, &types.RecordValue{Name: f.Name, Value: value, Place: place})
+}
@@ -0,0 +1,205 @@
+package s3events
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// certCache keeps SNS signing certificates, they are rotated rarely
+	certCache struct {
+		sync.Mutex
+		certs map[string]*x509.Certificate
+	}
+)
+
+var (
+	// SNS signing certificates and subscription URLs must come from AWS
+	snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+	snsClient = &http.Client{Timeout: 10 * time.Second}
+
+	signingCerts = &certCache{certs: map[string]*x509.Certificate{}}
+)
+
+// check verifies that the message was sent by SNS from the topic
+func (m snsMessage) check(ctx context.Context, topicArn string) error {
+	if m.TopicArn != topicArn {
+		return ErrTopicMismatch.withStack()
+	}
+
+	return m.verify(ctx)
+}
+
+// verify checks SNS message signature
+//
+// See https://docs.aws.amazon.com/sns/latest/dg/sns-verify-signature-of-message.html
+func (m snsMessage) verify(ctx context.Context) error {
+	var hash crypto.Hash
+
+	switch m.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return ErrInvalidSignature.withStack()
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return ErrInvalidSignature.withStack()
+	}
+
+	cert, err := signingCerts.get(ctx, m.SigningCertURL)
+	if err != nil {
+		return err
+	}
+
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return ErrInvalidSignature.withStack()
+	}
+
+	if err = rsa.VerifyPKCS1v15(key, hash, digest(hash, m.signed()), signature); err != nil {
+		return ErrInvalidSignature.withStack()
+	}
+
+	return nil
+}
+
+// signed returns string that SNS signs, fields depend on message type
+func (m snsMessage) signed() string {
+	var (
+		s   string
+		add = func(name, value string) {
+			s += name + "\n" + value + "\n"
+		}
+	)
+
+	add("Message", m.Message)
+	add("MessageId", m.MessageId)
+
+	if m.Type == snsNotification {
+		if m.Subject != "" {
+			add("Subject", m.Subject)
+		}
+	} else {
+		add("SubscribeURL", m.SubscribeURL)
+	}
+
+	add("Timestamp", m.Timestamp)
+
+	if m.Type != snsNotification {
+		add("Token", m.Token)
+	}
+
+	add("TopicArn", m.TopicArn)
+	add("Type", m.Type)
+
+	return s
+}
+
+// confirm confirms subscription by visiting the URL from the message
+func (m snsMessage) confirm(ctx context.Context) error {
+	if !isSnsURL(m.SubscribeURL) {
+		return ErrInvalidMessage.withStack()
+	}
+
+	rsp, err := get(ctx, m.SubscribeURL)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return errors.Errorf("could not confirm subscription, unexpected status: %s", rsp.Status)
+	}
+
+	return nil
+}
+
+// get requests the URL, aborted when the context is done
+func get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return snsClient.Do(req.WithContext(ctx))
+}
+
+func (c *certCache) get(ctx context.Context, certURL string) (*x509.Certificate, error) {
+	if !isSnsURL(certURL) {
+		return nil, ErrInvalidSignature.withStack()
+	}
+
+	c.Lock()
+	cert, ok := c.certs[certURL]
+	c.Unlock()
+
+	if ok {
+		return cert, nil
+	}
+
+	rsp, err := get(ctx, certURL)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("could not load signing certificate, unexpected status: %s", rsp.Status)
+	}
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("could not decode signing certificate")
+	}
+
+	if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	c.Lock()
+	c.certs[certURL] = cert
+	c.Unlock()
+
+	return cert, nil
+}
+
+func isSnsURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "https" && snsHost.MatchString(u.Hostname())
+}
+
+func digest(hash crypto.Hash, s string) []byte {
+	if hash == crypto.SHA1 {
+		h := sha1.Sum([]byte(s))
+		return h[:]
+	}
+
+	h := sha256.Sum256([]byte(s))
+	return h[:]
+}
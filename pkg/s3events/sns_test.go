@@ -0,0 +1,237 @@
+package s3events
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+const (
+	testTopicArn = "arn:aws:sns:us-east-1:123456789012:uploads"
+	testCertURL  = "https://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+)
+
+// testKey signs messages, its certificate is cached under testCertURL
+func testKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingCerts.Lock()
+	signingCerts.certs[testCertURL] = cert
+	signingCerts.Unlock()
+
+	return key
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, m snsMessage) snsMessage {
+	hash := crypto.SHA1
+	if m.SignatureVersion == "2" {
+		hash = crypto.SHA256
+	}
+
+	s, err := rsa.SignPKCS1v15(rand.Reader, key, hash, digest(hash, m.signed()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Signature = base64.StdEncoding.EncodeToString(s)
+	return m
+}
+
+func TestSnsMessageCheck(t *testing.T) {
+	var (
+		key = testKey(t)
+
+		other, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+		notification = snsMessage{
+			Type:             snsNotification,
+			MessageId:        "f3c8d4d6-0000-0000-0000-000000000001",
+			TopicArn:         testTopicArn,
+			Subject:          "Amazon S3 Notification",
+			Message:          `{"Records":[]}`,
+			Timestamp:        "2020-03-01T12:00:00.000Z",
+			SignatureVersion: "1",
+			SigningCertURL:   testCertURL,
+		}
+
+		confirmation = snsMessage{
+			Type:             snsSubscriptionConfirmation,
+			MessageId:        "f3c8d4d6-0000-0000-0000-000000000002",
+			Token:            "token",
+			TopicArn:         testTopicArn,
+			Message:          "You have chosen to subscribe to the topic",
+			SubscribeURL:     "https://sns.us-east-1.amazonaws.com/?Action=ConfirmSubscription",
+			Timestamp:        "2020-03-01T12:00:00.000Z",
+			SignatureVersion: "1",
+			SigningCertURL:   testCertURL,
+		}
+	)
+
+	tests := []struct {
+		name  string
+		topic string
+		msg   func() snsMessage
+		err   error
+	}{
+		{
+			name: "signed notification",
+			msg:  func() snsMessage { return sign(t, key, notification) },
+		},
+		{
+			name: "signed subscription confirmation",
+			msg:  func() snsMessage { return sign(t, key, confirmation) },
+		},
+		{
+			name: "signature version 2",
+			msg: func() snsMessage {
+				m := notification
+				m.SignatureVersion = "2"
+				return sign(t, key, m)
+			},
+		},
+		{
+			name: "notification without subject",
+			msg: func() snsMessage {
+				m := notification
+				m.Subject = ""
+				return sign(t, key, m)
+			},
+		},
+		{
+			name:  "other topic",
+			topic: "arn:aws:sns:us-east-1:123456789012:other",
+			msg:   func() snsMessage { return sign(t, key, notification) },
+			err:   ErrTopicMismatch,
+		},
+		{
+			name: "changed message",
+			msg: func() snsMessage {
+				m := sign(t, key, notification)
+				m.Message = `{"Records":[{}]}`
+				return m
+			},
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "changed subscribe URL",
+			msg: func() snsMessage {
+				m := sign(t, key, confirmation)
+				m.SubscribeURL = "https://sns.us-east-1.amazonaws.com/?Action=Other"
+				return m
+			},
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "signed with other key",
+			msg:  func() snsMessage { return sign(t, other, notification) },
+			err:  ErrInvalidSignature,
+		},
+		{
+			name: "unknown signature version",
+			msg: func() snsMessage {
+				m := sign(t, key, notification)
+				m.SignatureVersion = "3"
+				return m
+			},
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "signature not encoded",
+			msg: func() snsMessage {
+				m := notification
+				m.Signature = "not base64!"
+				return m
+			},
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "certificate from other host",
+			msg: func() snsMessage {
+				m := notification
+				m.SigningCertURL = "https://example.com/SimpleNotificationService-test.pem"
+				return sign(t, key, m)
+			},
+			err: ErrInvalidSignature,
+		},
+		{
+			name: "certificate over HTTP",
+			msg: func() snsMessage {
+				m := notification
+				m.SigningCertURL = "http://sns.us-east-1.amazonaws.com/SimpleNotificationService-test.pem"
+				return sign(t, key, m)
+			},
+			err: ErrInvalidSignature,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topic := tt.topic
+			if topic == "" {
+				topic = testTopicArn
+			}
+
+			err := tt.msg().check(context.Background(), topic)
+			if tt.err == nil && err != nil {
+				t.Fatalf("expected message to be accepted, got %v", err)
+			} else if tt.err != nil && !fault.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestIsSnsURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://sns.us-east-1.amazonaws.com/SimpleNotificationService.pem", true},
+		{"https://sns.cn-north-1.amazonaws.com.cn/SimpleNotificationService.pem", true},
+		{"https://sns.us-east-1.amazonaws.com:443/SimpleNotificationService.pem", true},
+		{"http://sns.us-east-1.amazonaws.com/SimpleNotificationService.pem", false},
+		{"https://sns.us-east-1.amazonaws.com.example.com/SimpleNotificationService.pem", false},
+		{"https://example.com/sns.us-east-1.amazonaws.com/SimpleNotificationService.pem", false},
+		{"https://fakesns.us-east-1.amazonaws.com/SimpleNotificationService.pem", false},
+		{"https://sns.amazonaws.com/SimpleNotificationService.pem", false},
+		{"https://s3.us-east-1.amazonaws.com/SimpleNotificationService.pem", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := isSnsURL(tt.url); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package s3events
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Source registers objects uploaded to an S3 bucket as record files
+	//
+	// Bucket notifications are published to an SNS topic that has an
+	// HTTP(S) subscription pointing to the source's notification endpoint.
+	Source struct {
+		ID          uint64 `json:"sourceID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+
+		Name string `json:"name" db:"name"`
+
+		// Only notifications from this topic are accepted
+		TopicArn string `json:"topicArn" db:"topic_arn"`
+
+		// Bucket and key prefix of objects to register; endpoint defaults to AWS S3
+		Endpoint string `json:"endpoint" db:"endpoint"`
+		Insecure bool   `json:"insecure" db:"insecure"`
+		Bucket   string `json:"bucket" db:"bucket"`
+		Prefix   string `json:"prefix" db:"prefix"`
+
+		// Credentials used to download objects
+		AccessKeyID     string `json:"accessKeyID" db:"access_key_id"`
+		SecretAccessKey string `json:"secretAccessKey,omitempty" db:"secret_access_key"`
+
+		// Create a new record for every object or attach it to an existing one
+		Mode Mode `json:"mode" db:"mode"`
+
+		// File field that receives the attachment and (optional) field that receives object's key
+		FileField string `json:"fileField" db:"file_field"`
+		KeyField  string `json:"keyField" db:"key_field"`
+
+		Enabled bool `json:"enabled" db:"enabled"`
+
+		// Records are created & updated in the name of the owner
+		OwnedBy   uint64     `json:"ownedBy,string" db:"owned_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	SourceFilter struct {
+		NamespaceID uint64 `json:"namespaceID,string"`
+		ModuleID    uint64 `json:"moduleID,string"`
+	}
+
+	SourceSet []*Source
+
+	// Object is a bucket object that was registered (or failed to)
+	Object struct {
+		ID       uint64 `json:"objectID,string" db:"id"`
+		SourceID uint64 `json:"sourceID,string" db:"rel_source"`
+		Key      string `json:"key" db:"object_key"`
+		ETag     string `json:"etag" db:"etag"`
+		Size     int64  `json:"size" db:"size"`
+
+		AttachmentID uint64 `json:"attachmentID,string,omitempty" db:"rel_attachment"`
+		RecordID     uint64 `json:"recordID,string,omitempty" db:"rel_record"`
+		FailReason   string `json:"failReason,omitempty" db:"fail_reason"`
+
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	ObjectSet []*Object
+
+	Mode string
+
+	// snsMessage is a message that SNS posts to HTTP(S) subscriptions
+	snsMessage struct {
+		Type             string
+		MessageId        string
+		Token            string
+		TopicArn         string
+		Subject          string
+		Message          string
+		Timestamp        string
+		SubscribeURL     string
+		Signature        string
+		SignatureVersion string
+		SigningCertURL   string
+	}
+
+	// s3Notification is a bucket notification, carried in SNS message
+	s3Notification struct {
+		Records []*s3Record
+	}
+
+	s3Record struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key  string `json:"key"`
+				Size int64  `json:"size"`
+				ETag string `json:"eTag"`
+			} `json:"object"`
+		} `json:"s3"`
+	}
+)
+
+const (
+	// New record is created for every object
+	ModeCreate Mode = "create"
+
+	// Object is attached to an existing record; key (after prefix) must start
+	// with the record ID, for example "<prefix>/12345/report.pdf"
+	ModeAttach Mode = "attach"
+
+	snsSubscriptionConfirmation = "SubscriptionConfirmation"
+	snsNotification             = "Notification"
+	snsUnsubscribeConfirmation  = "UnsubscribeConfirmation"
+
+	defaultEndpoint = "s3.amazonaws.com"
+
+	// Objects are copied into the attachment store, keep them within reason
+	maxObjectSize = 100 << 20
+
+	maxObjects = 100
+)
+
+func (m Mode) IsValid() bool {
+	return m == ModeCreate || m == ModeAttach
+}
+
+// created checks if the record is about a newly created object
+func (r s3Record) created() bool {
+	return strings.HasPrefix(r.EventName, "ObjectCreated:")
+}
+
+// key returns decoded object key
+//
+// Keys in notifications are URL-encoded, with spaces as "+"
+func (r s3Record) key() (string, error) {
+	return url.QueryUnescape(r.S3.Object.Key)
+}
+
+// matches checks if the object belongs to the source
+func (src Source) matches(bucket, key string) bool {
+	return bucket == src.Bucket && strings.HasPrefix(key, src.Prefix) && !strings.HasSuffix(key, "/")
+}
+
+// recordID extracts ID of the record from object's key (attach mode)
+func (src Source) recordID(key string) (uint64, error) {
+	var (
+		rel = strings.TrimPrefix(strings.TrimPrefix(key, src.Prefix), "/")
+		i   = strings.Index(rel, "/")
+	)
+
+	if i < 1 {
+		return 0, ErrInvalidObjectKey.withStack()
+	}
+
+	ID, err := strconv.ParseUint(rel[:i], 10, 64)
+	if err != nil || ID == 0 {
+		return 0, ErrInvalidObjectKey.withStack()
+	}
+
+	return ID, nil
+}
+
+// fileName returns name of the attachment created from the object
+func fileName(key string) string {
+	return path.Base(key)
+}
+
+func (src Source) withoutSecret() *Source {
+	src.SecretAccessKey = ""
+	return &src
+}
@@ -0,0 +1,102 @@
+package sandbox
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/pkg/automation/corredor"
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	// client wraps script runner client and accounts script executions
+	client struct {
+		corredor.ScriptRunnerClient
+	}
+)
+
+// Client decorates script runner client with budgets & kill switches
+//
+// Scripts of namespaces over budget fail with "resource exhausted" error
+// (critical scripts abort the operation), killed scripts are skipped.
+func Client(c corredor.ScriptRunnerClient) corredor.ScriptRunnerClient {
+	return &client{ScriptRunnerClient: c}
+}
+
+func (c client) Namespace(ctx context.Context, req *corredor.RunNamespaceRequest, opts ...grpc.CallOption) (rsp *corredor.RunNamespaceResponse, err error) {
+	err = c.run(req.Namespace.GetNamespaceID(), req.Script, func() error {
+		rsp, err = c.ScriptRunnerClient.Namespace(ctx, req, opts...)
+		return err
+	})
+
+	if fault.Is(err, ErrScriptKilled) {
+		return &corredor.RunNamespaceResponse{Namespace: req.Namespace}, nil
+	}
+
+	return
+}
+
+func (c client) Module(ctx context.Context, req *corredor.RunModuleRequest, opts ...grpc.CallOption) (rsp *corredor.RunModuleResponse, err error) {
+	err = c.run(req.Namespace.GetNamespaceID(), req.Script, func() error {
+		rsp, err = c.ScriptRunnerClient.Module(ctx, req, opts...)
+		return err
+	})
+
+	if fault.Is(err, ErrScriptKilled) {
+		return &corredor.RunModuleResponse{Module: req.Module}, nil
+	}
+
+	return
+}
+
+func (c client) Record(ctx context.Context, req *corredor.RunRecordRequest, opts ...grpc.CallOption) (rsp *corredor.RunRecordResponse, err error) {
+	err = c.run(req.Namespace.GetNamespaceID(), req.Script, func() error {
+		rsp, err = c.ScriptRunnerClient.Record(ctx, req, opts...)
+		return err
+	})
+
+	if fault.Is(err, ErrScriptKilled) {
+		return &corredor.RunRecordResponse{Record: req.Record}, nil
+	}
+
+	return
+}
+
+func (c client) run(namespaceID uint64, s *corredor.Script, fn func() error) error {
+	scriptID := scriptID(namespaceID, s)
+
+	if err := usage.acquire(namespaceID, scriptID); fault.Is(err, ErrScriptKilled) {
+		return err
+	} else if err != nil {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+
+	var (
+		started = now()
+		err     = fn()
+	)
+
+	usage.release(namespaceID, scriptID, now().Sub(started), err != nil)
+	return err
+}
+
+// scriptID finds ID of the runnable script; requests carry only name & source
+//
+// Script names are unique in a namespace.
+func scriptID(namespaceID uint64, s *corredor.Script) uint64 {
+	if s == nil || service.DefaultInternalAutomationManager == nil {
+		return 0
+	}
+
+	for _, rs := range service.DefaultInternalAutomationManager.FindRunnableScripts("", "") {
+		if rs.NamespaceID == namespaceID && rs.Name == s.Name && rs.Source == s.Source {
+			return rs.ID
+		}
+	}
+
+	return 0
+}
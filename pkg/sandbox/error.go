@@ -0,0 +1,39 @@
+package sandbox
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	sandboxError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvocationQuota  = sandboxError{"InvocationQuotaExceeded", fault.TooMany}
+	ErrExecTimeQuota    = sandboxError{"ExecTimeQuotaExceeded", fault.TooMany}
+	ErrConcurrencyLimit = sandboxError{"ConcurrencyLimitReached", fault.TooMany}
+	ErrScriptKilled     = sandboxError{"ScriptKilled", fault.Forbidden}
+	ErrScriptNotKilled  = sandboxError{"ScriptNotKilled", fault.Conflict}
+	ErrBudgetNotFound   = sandboxError{"BudgetNotFound", fault.NotFound}
+	ErrNoPermissions    = sandboxError{"NoPermissions", fault.Forbidden}
+	ErrInvalidTimeRange = sandboxError{"InvalidTimeRange", fault.Invalid}
+)
+
+func (e sandboxError) Error() string {
+	return e.String()
+}
+
+func (e sandboxError) String() string {
+	return "crust.sandbox." + e.name
+}
+
+func (e sandboxError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e sandboxError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,206 @@
+package sandbox
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	usageKey struct {
+		namespaceID uint64
+		scriptID    uint64
+		period      time.Time
+	}
+
+	// meter accounts script executions and enforces budgets
+	//
+	// Usage is collected in memory and periodically added to the stored
+	// usage; namespace totals of the current period (with usage of other
+	// instances) are reloaded at the same time.
+	meter struct {
+		sync.Mutex
+
+		running map[uint64]uint
+		pending map[usageKey]*Usage
+		totals  map[uint64]*Usage
+		budgets map[uint64]*Budget
+		killed  map[uint64]bool
+	}
+)
+
+var (
+	usage = &meter{
+		running: map[uint64]uint{},
+		pending: map[usageKey]*Usage{},
+		totals:  map[uint64]*Usage{},
+		budgets: map[uint64]*Budget{},
+		killed:  map[uint64]bool{},
+	}
+)
+
+// acquire checks script & namespace's budget and counts the script as running
+func (m *meter) acquire(namespaceID, scriptID uint64) error {
+	m.Lock()
+	defer m.Unlock()
+
+	err := m.check(namespaceID, scriptID)
+	if err != nil {
+		m.current(namespaceID, scriptID).Rejected++
+		return err
+	}
+
+	m.running[namespaceID]++
+	return nil
+}
+
+func (m *meter) check(namespaceID, scriptID uint64) error {
+	if m.killed[scriptID] {
+		return ErrScriptKilled
+	}
+
+	b := m.budgets[namespaceID]
+	if b == nil {
+		return nil
+	}
+
+	var (
+		p    = now().Truncate(period)
+		used = Usage{}
+	)
+
+	if t := m.totals[namespaceID]; t != nil && t.Period.Equal(p) {
+		used = *t
+	}
+
+	for k, u := range m.pending {
+		if k.namespaceID == namespaceID && k.period.Equal(p) {
+			used.Invocations += u.Invocations
+			used.ExecTime += u.ExecTime
+		}
+	}
+
+	switch {
+	case b.MaxInvocations > 0 && used.Invocations >= b.MaxInvocations:
+		return ErrInvocationQuota
+	case b.MaxExecTime > 0 && used.ExecTime >= uint64(b.MaxExecTime):
+		return ErrExecTimeQuota
+	case b.MaxConcurrent > 0 && m.running[namespaceID] >= b.MaxConcurrent:
+		return ErrConcurrencyLimit
+	}
+
+	return nil
+}
+
+// release counts finished execution of the script
+func (m *meter) release(namespaceID, scriptID uint64, d time.Duration, failed bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.running[namespaceID] > 1 {
+		m.running[namespaceID]--
+	} else {
+		delete(m.running, namespaceID)
+	}
+
+	var (
+		u  = m.current(namespaceID, scriptID)
+		ms = uint(d / time.Millisecond)
+	)
+
+	u.Invocations++
+	u.ExecTime += uint64(ms)
+	if ms > u.MaxExecTime {
+		u.MaxExecTime = ms
+	}
+
+	if failed {
+		u.Failures++
+	}
+}
+
+// current returns pending usage of the script in the current period
+func (m *meter) current(namespaceID, scriptID uint64) *Usage {
+	k := usageKey{namespaceID: namespaceID, scriptID: scriptID, period: now().Truncate(period)}
+
+	if m.pending[k] == nil {
+		m.pending[k] = &Usage{NamespaceID: namespaceID, ScriptID: scriptID, Period: k.period}
+	}
+
+	return m.pending[k]
+}
+
+// take removes and returns pending usage
+//
+// Taken usage is added to the totals so that it is not missed
+// before the totals are reloaded.
+func (m *meter) take() (out []*Usage) {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, u := range m.pending {
+		out = append(out, u)
+
+		t := m.totals[u.NamespaceID]
+		if t == nil || !t.Period.Equal(u.Period) {
+			t = &Usage{NamespaceID: u.NamespaceID, Period: u.Period}
+			m.totals[u.NamespaceID] = t
+		}
+
+		t.Invocations += u.Invocations
+		t.ExecTime += u.ExecTime
+	}
+
+	m.pending = map[usageKey]*Usage{}
+	return
+}
+
+// reset replaces totals, budgets and killed scripts with the stored ones
+func (m *meter) reset(p time.Time, totals []*NamespaceStats, budgets []*Budget, kills KillSet) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.totals = map[uint64]*Usage{}
+	for _, t := range totals {
+		m.totals[t.NamespaceID] = &Usage{NamespaceID: t.NamespaceID, Period: p, Invocations: t.Invocations, ExecTime: t.ExecTime}
+	}
+
+	m.budgets = map[uint64]*Budget{}
+	for _, b := range budgets {
+		m.budgets[b.NamespaceID] = b
+	}
+
+	m.killed = map[uint64]bool{}
+	for _, k := range kills {
+		m.killed[k.ScriptID] = true
+	}
+}
+
+func (m *meter) setBudget(namespaceID uint64, b *Budget) {
+	m.Lock()
+	defer m.Unlock()
+
+	if b == nil {
+		delete(m.budgets, namespaceID)
+	} else {
+		m.budgets[namespaceID] = b
+	}
+}
+
+func (m *meter) setKilled(scriptID uint64, killed bool) {
+	m.Lock()
+	defer m.Unlock()
+
+	if killed {
+		m.killed[scriptID] = true
+	} else {
+		delete(m.killed, scriptID)
+	}
+}
+
+// runningIn returns number of scripts running in the namespace on this instance
+func (m *meter) runningIn(namespaceID uint64) uint {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.running[namespaceID]
+}
@@ -0,0 +1,52 @@
+package sandbox
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200207000000.sandbox",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_automation_budget (
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  max_invocations    INT UNSIGNED    NOT NULL DEFAULT 0,
+  max_exec_ms        INT UNSIGNED    NOT NULL DEFAULT 0,
+  max_concurrent     INT UNSIGNED    NOT NULL DEFAULT 0,
+
+  updated_by         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_automation_kill (
+  rel_script         BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  reason             VARCHAR(255)    NOT NULL DEFAULT '',
+  killed_by          BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  killed_at          DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_script),
+  INDEX (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_automation_usage (
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  rel_script         BIGINT UNSIGNED NOT NULL,
+  period_start       DATETIME        NOT NULL,
+  invocations        INT UNSIGNED    NOT NULL DEFAULT 0,
+  failures           INT UNSIGNED    NOT NULL DEFAULT 0,
+  rejected           INT UNSIGNED    NOT NULL DEFAULT 0,
+  exec_ms            BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  max_exec_ms        INT UNSIGNED    NOT NULL DEFAULT 0,
+
+  PRIMARY KEY (rel_namespace, rel_script, period_start),
+  INDEX (period_start)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,211 @@
+package sandbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableBudget() string {
+	return "crust_compose_automation_budget"
+}
+
+func (r repository) tableKill() string {
+	return "crust_compose_automation_kill"
+}
+
+func (r repository) tableUsage() string {
+	return "crust_compose_automation_usage"
+}
+
+func (r repository) queryBudgets() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"rel_namespace",
+			"max_invocations",
+			"max_exec_ms",
+			"max_concurrent",
+			"updated_by",
+			"created_at",
+			"updated_at",
+		).
+		From(r.tableBudget())
+}
+
+func (r repository) FindBudget(namespaceID uint64) (*Budget, error) {
+	var (
+		b = &Budget{}
+		q = r.queryBudgets().Where(squirrel.Eq{"rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, b); err != nil {
+		return nil, err
+	} else if b.NamespaceID == 0 {
+		return nil, ErrBudgetNotFound.withStack().WithID("namespaceID", namespaceID)
+	}
+
+	return b, nil
+}
+
+// FindBudgets returns budgets of all namespaces
+func (r repository) FindBudgets() (set []*Budget, err error) {
+	return set, rh.FetchAll(r.db(), r.queryBudgets(), &set)
+}
+
+func (r repository) CreateBudget(b *Budget) (*Budget, error) {
+	rh.SetCurrentTimeRounded(&b.CreatedAt)
+
+	return b, errors.WithStack(r.db().Insert(r.tableBudget(), b))
+}
+
+func (r repository) UpdateBudget(b *Budget) (*Budget, error) {
+	rh.SetCurrentTimeRounded(&b.UpdatedAt)
+
+	return b, errors.WithStack(r.db().Replace(r.tableBudget(), b))
+}
+
+func (r repository) DeleteBudget(namespaceID uint64) error {
+	return rh.Delete(r.db(), r.tableBudget(), squirrel.Eq{"rel_namespace": namespaceID})
+}
+
+func (r repository) queryKills() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"rel_script",
+			"rel_namespace",
+			"reason",
+			"killed_by",
+			"killed_at",
+		).
+		From(r.tableKill())
+}
+
+func (r repository) FindKills(namespaceID uint64) (set KillSet, err error) {
+	q := r.queryKills().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("killed_at")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindAllKills returns killed scripts of all namespaces
+func (r repository) FindAllKills() (set KillSet, err error) {
+	return set, rh.FetchAll(r.db(), r.queryKills(), &set)
+}
+
+func (r repository) CreateKill(k *Kill) (*Kill, error) {
+	rh.SetCurrentTimeRounded(&k.KilledAt)
+
+	return k, errors.WithStack(r.db().Replace(r.tableKill(), k))
+}
+
+func (r repository) DeleteKill(namespaceID, scriptID uint64) error {
+	return rh.Delete(r.db(), r.tableKill(), squirrel.Eq{"rel_script": scriptID, "rel_namespace": namespaceID})
+}
+
+// AddUsage adds usage to the stored usage of the same script & period
+func (r repository) AddUsage(u *Usage) error {
+	_, err := r.db().Exec(
+		"INSERT INTO "+r.tableUsage()+" (rel_namespace, rel_script, period_start, invocations, failures, rejected, exec_ms, max_exec_ms) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE "+
+			"invocations = invocations + VALUES(invocations), "+
+			"failures = failures + VALUES(failures), "+
+			"rejected = rejected + VALUES(rejected), "+
+			"exec_ms = exec_ms + VALUES(exec_ms), "+
+			"max_exec_ms = GREATEST(max_exec_ms, VALUES(max_exec_ms))",
+		u.NamespaceID, u.ScriptID, u.Period, u.Invocations, u.Failures, u.Rejected, u.ExecTime, u.MaxExecTime,
+	)
+
+	return errors.WithStack(err)
+}
+
+func (r repository) sums() []string {
+	return []string{
+		"SUM(u.invocations) AS invocations",
+		"SUM(u.failures) AS failures",
+		"SUM(u.rejected) AS rejected",
+		"SUM(u.exec_ms) AS exec_ms",
+		"MAX(u.max_exec_ms) AS max_exec_ms",
+	}
+}
+
+func (r repository) usageFilter(q squirrel.SelectBuilder, f UsageFilter) squirrel.SelectBuilder {
+	if f.NamespaceID > 0 {
+		q = q.Where(squirrel.Eq{"u.rel_namespace": f.NamespaceID})
+	}
+
+	if f.Since != nil {
+		q = q.Where(squirrel.GtOrEq{"u.period_start": f.Since.Truncate(period)})
+	}
+
+	if f.Until != nil {
+		q = q.Where(squirrel.Lt{"u.period_start": *f.Until})
+	}
+
+	return q
+}
+
+// NamespaceUsage sums usage by namespace
+func (r repository) NamespaceUsage(f UsageFilter) (set []*NamespaceStats, err error) {
+	q := r.usageFilter(
+		squirrel.
+			Select("u.rel_namespace", "COALESCE(MAX(n.name), '') AS namespace").
+			Columns(r.sums()...).
+			From(r.tableUsage()+" AS u").
+			LeftJoin("compose_namespace AS n ON (n.id = u.rel_namespace)").
+			GroupBy("u.rel_namespace").
+			OrderBy("exec_ms DESC"),
+		f,
+	)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// ScriptUsage sums usage of namespace's scripts
+func (r repository) ScriptUsage(f UsageFilter) (set []*ScriptStats, err error) {
+	q := r.usageFilter(
+		squirrel.
+			Select("u.rel_script", "COALESCE(MAX(s.name), '') AS name").
+			Columns(r.sums()...).
+			From(r.tableUsage()+" AS u").
+			LeftJoin("compose_automation_script AS s ON (s.id = u.rel_script)").
+			GroupBy("u.rel_script").
+			OrderBy("exec_ms DESC"),
+		f,
+	)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// PruneUsage removes usage of periods before the given time
+func (r repository) PruneUsage(before time.Time) error {
+	return rh.Delete(r.db(), r.tableUsage(), squirrel.Lt{"period_start": before})
+}
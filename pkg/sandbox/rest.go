@@ -0,0 +1,86 @@
+package sandbox
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts automation usage dashboard, budget and kill switch endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?since=2020-01-01T00:00:00Z&until=...; last day by default
+	r.Get("/usage", rest.Handler("AutomationSandbox.Usage", func(r *http.Request) (interface{}, error) {
+		return DefaultSandbox.With(r.Context()).Usage(usageFilter(r))
+	}))
+
+	r.Route("/namespace/{namespaceID}", func(r chi.Router) {
+		r.Get("/usage", rest.Handler("AutomationSandbox.ScriptUsage", func(r *http.Request) (interface{}, error) {
+			f := usageFilter(r)
+			f.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultSandbox.With(r.Context()).ScriptUsage(f)
+		}))
+
+		r.Get("/budget", rest.Handler("AutomationSandbox.ReadBudget", func(r *http.Request) (interface{}, error) {
+			return DefaultSandbox.With(r.Context()).FindBudget(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Put("/budget", rest.Handler("AutomationSandbox.SaveBudget", func(r *http.Request) (interface{}, error) {
+			b := &Budget{}
+			if err := rest.Decode(r, b); err != nil {
+				return nil, err
+			}
+
+			b.NamespaceID = rest.ParamUint64(r, "namespaceID")
+			return DefaultSandbox.With(r.Context()).SaveBudget(b)
+		}))
+
+		r.Delete("/budget", rest.Handler("AutomationSandbox.DeleteBudget", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultSandbox.With(r.Context()).DeleteBudget(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Get("/killed", rest.Handler("AutomationSandbox.Killed", func(r *http.Request) (interface{}, error) {
+			return DefaultSandbox.With(r.Context()).FindKills(rest.ParamUint64(r, "namespaceID"))
+		}))
+
+		r.Put("/killed/{scriptID}", rest.Handler("AutomationSandbox.Kill", func(r *http.Request) (interface{}, error) {
+			k := &Kill{}
+			if err := rest.Decode(r, k); err != nil {
+				return nil, err
+			}
+
+			return DefaultSandbox.With(r.Context()).Kill(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "scriptID"),
+				k.Reason,
+			)
+		}))
+
+		r.Delete("/killed/{scriptID}", rest.Handler("AutomationSandbox.Revive", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultSandbox.With(r.Context()).Revive(
+				rest.ParamUint64(r, "namespaceID"),
+				rest.ParamUint64(r, "scriptID"),
+			)
+		}))
+	})
+}
+
+func usageFilter(r *http.Request) (f UsageFilter) {
+	q := r.URL.Query()
+
+	if since, err := time.Parse(time.RFC3339, q.Get("since")); err == nil {
+		f.Since = &since
+	}
+
+	if until, err := time.Parse(time.RFC3339, q.Get("until")); err == nil {
+		f.Until = &until
+	}
+
+	return
+}
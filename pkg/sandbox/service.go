@@ -0,0 +1,393 @@
+package sandbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	sandboxService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		script    scriptLoader
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageNamespace(context.Context, *types.Namespace) bool
+	}
+
+	scriptLoader interface {
+		FindByID(ctx context.Context, namespaceID, scriptID uint64) (*automation.Script, error)
+	}
+
+	SandboxService interface {
+		With(ctx context.Context) SandboxService
+
+		Usage(UsageFilter) ([]*NamespaceStats, error)
+		ScriptUsage(UsageFilter) ([]*ScriptStats, error)
+
+		FindBudget(namespaceID uint64) (*Budget, error)
+		SaveBudget(*Budget) (*Budget, error)
+		DeleteBudget(namespaceID uint64) error
+
+		FindKills(namespaceID uint64) (KillSet, error)
+		Kill(namespaceID, scriptID uint64, reason string) (*Kill, error)
+		Revive(namespaceID, scriptID uint64) error
+	}
+)
+
+var (
+	DefaultSandbox SandboxService
+
+	// now is used for execution time & periods and can be overridden
+	now = time.Now
+)
+
+// Init initializes automation budgets and starts storing usage in the background
+//
+// Script executions are accounted by the script runner client (see Client)
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &sandboxService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		script:    service.DefaultAutomationScriptManager,
+	}
+
+	DefaultSandbox = svc.With(ctx)
+
+	svc.flush(ctx)
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc sandboxService) With(ctx context.Context) SandboxService {
+	return &sandboxService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+		script: svc.script,
+
+		namespace: svc.namespace.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc sandboxService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Usage returns usage of all namespaces with their budgets
+func (svc sandboxService) Usage(f UsageFilter) ([]*NamespaceStats, error) {
+	if !isAdmin(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if err := timeRange(&f); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.NamespaceUsage(f)
+	if err != nil {
+		return nil, err
+	}
+
+	bb, err := svc.repository.FindBudgets()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range set {
+		s.average()
+		s.Running = usage.runningIn(s.NamespaceID)
+
+		for _, b := range bb {
+			if b.NamespaceID == s.NamespaceID {
+				s.Budget = b
+			}
+		}
+	}
+
+	return set, nil
+}
+
+// ScriptUsage returns usage of namespace's scripts
+func (svc sandboxService) ScriptUsage(f UsageFilter) ([]*ScriptStats, error) {
+	if err := svc.canManage(f.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	if err := timeRange(&f); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.ScriptUsage(f)
+	if err != nil {
+		return nil, err
+	}
+
+	kk, err := svc.repository.FindKills(f.NamespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range set {
+		s.average()
+
+		for _, k := range kk {
+			if k.ScriptID == s.ScriptID {
+				s.Killed = k
+			}
+		}
+	}
+
+	return set, nil
+}
+
+func (svc sandboxService) FindBudget(namespaceID uint64) (*Budget, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindBudget(namespaceID)
+}
+
+// SaveBudget sets or replaces namespace's budget; only administrators can do that
+func (svc sandboxService) SaveBudget(in *Budget) (*Budget, error) {
+	if !isAdmin(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if _, err := svc.namespace.FindByID(in.NamespaceID); err != nil {
+		return nil, err
+	}
+
+	b, err := svc.repository.FindBudget(in.NamespaceID)
+	if err != nil && !fault.Is(err, ErrBudgetNotFound) {
+		return nil, err
+	}
+
+	if b == nil {
+		b = &Budget{NamespaceID: in.NamespaceID}
+	}
+
+	b.MaxInvocations = in.MaxInvocations
+	b.MaxExecTime = in.MaxExecTime
+	b.MaxConcurrent = in.MaxConcurrent
+	b.UpdatedBy = auth.GetIdentityFromContext(svc.ctx).Identity()
+
+	if b.CreatedAt.IsZero() {
+		b, err = svc.repository.CreateBudget(b)
+	} else {
+		b, err = svc.repository.UpdateBudget(b)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	usage.setBudget(b.NamespaceID, b)
+	return b, nil
+}
+
+func (svc sandboxService) DeleteBudget(namespaceID uint64) error {
+	if !isAdmin(svc.ctx) {
+		return ErrNoPermissions.withStack()
+	}
+
+	if _, err := svc.repository.FindBudget(namespaceID); err != nil {
+		return err
+	}
+
+	if err := svc.repository.DeleteBudget(namespaceID); err != nil {
+		return err
+	}
+
+	usage.setBudget(namespaceID, nil)
+	return nil
+}
+
+func (svc sandboxService) FindKills(namespaceID uint64) (KillSet, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindKills(namespaceID)
+}
+
+// Kill stops the script from running on all instances
+func (svc sandboxService) Kill(namespaceID, scriptID uint64, reason string) (*Kill, error) {
+	if err := svc.canManage(namespaceID); err != nil {
+		return nil, err
+	}
+
+	if _, err := svc.script.FindByID(svc.ctx, namespaceID, scriptID); err != nil {
+		return nil, err
+	}
+
+	k, err := svc.repository.CreateKill(&Kill{
+		ScriptID:    scriptID,
+		NamespaceID: namespaceID,
+		Reason:      reason,
+		KilledBy:    auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("scriptID", scriptID), zap.String("reason", reason)).Info("automation script killed")
+
+	usage.setKilled(scriptID, true)
+	return k, nil
+}
+
+// Revive lets killed script run again
+func (svc sandboxService) Revive(namespaceID, scriptID uint64) error {
+	if err := svc.canManage(namespaceID); err != nil {
+		return err
+	}
+
+	kk, err := svc.repository.FindKills(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	var killed bool
+	for _, k := range kk {
+		killed = killed || k.ScriptID == scriptID
+	}
+
+	if !killed {
+		return ErrScriptNotKilled.withStack()
+	}
+
+	if err = svc.repository.DeleteKill(namespaceID, scriptID); err != nil {
+		return err
+	}
+
+	usage.setKilled(scriptID, false)
+	return nil
+}
+
+// canManage allows administrators and namespace managers
+func (svc sandboxService) canManage(namespaceID uint64) error {
+	ns, err := svc.namespace.FindByID(namespaceID)
+	if err != nil {
+		return err
+	}
+
+	if !isAdmin(svc.ctx) && !svc.ac.CanManageNamespace(svc.ctx, ns) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return nil
+}
+
+// flush stores pending usage and reloads totals, budgets and killed scripts
+func (svc sandboxService) flush(ctx context.Context) {
+	var (
+		r = Repository(ctx, nil)
+		p = now().Truncate(period)
+	)
+
+	for _, u := range usage.take() {
+		if err := r.AddUsage(u); err != nil {
+			svc.logger.Error("could not store automation usage", zap.Uint64("namespaceID", u.NamespaceID), zap.Error(err))
+		}
+	}
+
+	totals, err := r.NamespaceUsage(UsageFilter{Since: &p})
+	if err != nil {
+		svc.logger.Error("could not load automation usage", zap.Error(err))
+		return
+	}
+
+	bb, err := r.FindBudgets()
+	if err != nil {
+		svc.logger.Error("could not load automation budgets", zap.Error(err))
+		return
+	}
+
+	kk, err := r.FindAllKills()
+	if err != nil {
+		svc.logger.Error("could not load killed automation scripts", zap.Error(err))
+		return
+	}
+
+	usage.reset(p, totals, bb, kk)
+}
+
+// watch stores usage and removes usage past retention
+func (svc sandboxService) watch(ctx context.Context) {
+	var (
+		f = time.NewTicker(flushInterval)
+		p = time.NewTicker(pruneInterval)
+	)
+
+	defer f.Stop()
+	defer p.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-f.C:
+			svc.flush(ctx)
+		case <-p.C:
+			if err := Repository(ctx, nil).PruneUsage(now().Add(-retention)); err != nil {
+				svc.logger.Error("could not remove old automation usage", zap.Error(err))
+			}
+		}
+	}
+}
+
+// timeRange defaults to the last day
+func timeRange(f *UsageFilter) error {
+	if f.Since == nil {
+		since := now().Add(-defaultSpan)
+		f.Since = &since
+	}
+
+	if f.Until != nil && !f.Until.After(*f.Since) {
+		return ErrInvalidTimeRange.withStack()
+	}
+
+	return nil
+}
+
+func isAdmin(ctx context.Context) bool {
+	i := auth.GetIdentityFromContext(ctx)
+	if auth.IsSuperUser(i) {
+		return true
+	}
+
+	for _, roleID := range i.Roles() {
+		if roleID == permissions.AdminsRoleID {
+			return true
+		}
+	}
+
+	return false
+}
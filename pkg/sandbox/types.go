@@ -0,0 +1,105 @@
+package sandbox
+
+import (
+	"time"
+)
+
+type (
+	// Budget limits automation scripts of a namespace
+	//
+	// Quotas are per hour and shared by all instances; concurrency is
+	// limited per instance. Zero means unlimited.
+	Budget struct {
+		NamespaceID    uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		MaxInvocations uint   `json:"maxInvocations" db:"max_invocations"`
+
+		// Execution time (ms) of scripts, as measured by the server;
+		// includes time spent waiting for Corredor
+		MaxExecTime   uint `json:"maxExecTimeMs" db:"max_exec_ms"`
+		MaxConcurrent uint `json:"maxConcurrent" db:"max_concurrent"`
+
+		UpdatedBy uint64     `json:"updatedBy,string" db:"updated_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	// Kill stops the script from running until it is revived
+	//
+	// Killed scripts are skipped as if they were not triggered; records
+	// are saved without their changes.
+	Kill struct {
+		ScriptID    uint64    `json:"scriptID,string" db:"rel_script"`
+		NamespaceID uint64    `json:"namespaceID,string" db:"rel_namespace"`
+		Reason      string    `json:"reason" db:"reason"`
+		KilledBy    uint64    `json:"killedBy,string" db:"killed_by"`
+		KilledAt    time.Time `json:"killedAt" db:"killed_at"`
+	}
+
+	KillSet []*Kill
+
+	// Usage holds script executions in one period
+	Usage struct {
+		NamespaceID uint64    `db:"rel_namespace"`
+		ScriptID    uint64    `db:"rel_script"`
+		Period      time.Time `db:"period_start"`
+		Invocations uint      `db:"invocations"`
+		Failures    uint      `db:"failures"`
+		Rejected    uint      `db:"rejected"`
+		ExecTime    uint64    `db:"exec_ms"`
+		MaxExecTime uint      `db:"max_exec_ms"`
+	}
+
+	// NamespaceStats summarizes script executions of a namespace
+	NamespaceStats struct {
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Namespace   string `json:"namespace" db:"namespace"`
+		stats
+
+		// Scripts running on this instance right now
+		Running uint    `json:"running"`
+		Budget  *Budget `json:"budget,omitempty"`
+	}
+
+	// ScriptStats summarizes executions of a script
+	ScriptStats struct {
+		ScriptID uint64 `json:"scriptID,string" db:"rel_script"`
+		Name     string `json:"name" db:"name"`
+		stats
+
+		Killed *Kill `json:"killed,omitempty"`
+	}
+
+	stats struct {
+		Invocations uint   `json:"invocations" db:"invocations"`
+		Failures    uint   `json:"failures" db:"failures"`
+		Rejected    uint   `json:"rejected" db:"rejected"`
+		ExecTime    uint64 `json:"execTimeMs" db:"exec_ms"`
+		AvgExecTime uint64 `json:"avgExecTimeMs" db:"-"`
+		MaxExecTime uint   `json:"maxExecTimeMs" db:"max_exec_ms"`
+	}
+
+	UsageFilter struct {
+		NamespaceID uint64
+		Since       *time.Time
+		Until       *time.Time
+	}
+)
+
+const (
+	period = time.Hour
+
+	// how often is usage stored and reloaded (usage of other instances)
+	flushInterval = 10 * time.Second
+
+	pruneInterval = time.Hour
+	retention     = 90 * 24 * time.Hour
+
+	// stats cover the last day unless requested otherwise
+	defaultSpan = 24 * time.Hour
+)
+
+func (s *stats) average() {
+	if s.Invocations > 0 {
+		s.AvgExecTime = s.ExecTime / uint64(s.Invocations)
+	}
+}
@@ -0,0 +1,38 @@
+package scheduled
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	scheduledError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrMessageRequired          = scheduledError{"MessageRequired", fault.Invalid}
+	ErrSendAtInPast             = scheduledError{"SendAtInPast", fault.Invalid}
+	ErrSendAtTooFar             = scheduledError{"SendAtTooFar", fault.Invalid}
+	ErrTooManyScheduled         = scheduledError{"TooManyScheduled", fault.TooMany}
+	ErrNotPending               = scheduledError{"NotPending", fault.Conflict}
+	ErrNoPermissions            = scheduledError{"NoPermissions", fault.Forbidden}
+	ErrScheduledMessageNotFound = scheduledError{"ScheduledMessageNotFound", fault.NotFound}
+)
+
+func (e scheduledError) Error() string {
+	return e.String()
+}
+
+func (e scheduledError) String() string {
+	return "crust.scheduled." + e.name
+}
+
+func (e scheduledError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e scheduledError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,42 @@
+package scheduled
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200224000000.scheduled",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_scheduled_message (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  reply_to         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  message          TEXT            NOT NULL,
+  send_at          DATETIME        NOT NULL,
+  status           VARCHAR(16)     NOT NULL,
+  rel_message      BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  error            TEXT            NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  roles            TEXT            NOT NULL,
+
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       DATETIME            NULL DEFAULT NULL,
+  sent_at          DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_channel, rel_user),
+  INDEX (status, send_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+`,
+		},
+		{
+			Name: "20200313000000.scheduled-roles",
+			Up: `
+ALTER TABLE crust_messaging_scheduled_message
+  DROP COLUMN roles;
+`,
+		},
+	}
+)
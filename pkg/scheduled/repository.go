@@ -0,0 +1,188 @@
+package scheduled
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_scheduled_message"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_channel",
+			"reply_to",
+			"message",
+			"send_at",
+			"status",
+			"rel_message",
+			"error",
+			"rel_user",
+			"created_at",
+			"updated_at",
+			"sent_at",
+		).
+		From(r.table())
+}
+
+func (r repository) FindByID(channelID, ID uint64) (*Message, error) {
+	var (
+		m = &Message{}
+		q = r.query().Where(squirrel.Eq{"id": ID, "rel_channel": channelID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, m); err != nil {
+		return nil, err
+	} else if m.ID == 0 {
+		return nil, ErrScheduledMessageNotFound.withStack().WithID("channelID", channelID).WithID("scheduledMessageID", ID)
+	}
+
+	return m, nil
+}
+
+// Find returns messages that match the filter, in order they are sent
+func (r repository) Find(f Filter) (set MessageSet, err error) {
+	q := r.query().OrderBy("send_at", "id")
+
+	if f.ChannelID > 0 {
+		q = q.Where(squirrel.Eq{"rel_channel": f.ChannelID})
+	}
+
+	if f.UserID > 0 {
+		q = q.Where(squirrel.Eq{"rel_user": f.UserID})
+	}
+
+	if f.Status != "" {
+		q = q.Where(squirrel.Eq{"status": f.Status})
+	}
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// CountPending returns number of user's messages that are not sent yet
+func (r repository) CountPending(userID uint64) (n uint, err error) {
+	err = r.db().Get(&n,
+		"SELECT COUNT(*) FROM "+r.table()+" WHERE rel_user = ? AND status = ?",
+		userID, StatusPending,
+	)
+
+	return n, errors.WithStack(err)
+}
+
+// FindDue returns pending messages that should be sent
+func (r repository) FindDue(now time.Time) (set MessageSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"status": StatusPending}).
+		Where(squirrel.LtOrEq{"send_at": now}).
+		OrderBy("send_at", "id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(m *Message) (*Message, error) {
+	m.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&m.CreatedAt)
+
+	return m, errors.WithStack(r.db().Insert(r.table(), m))
+}
+
+// Update changes message that is still pending; returns false when it is not
+func (r repository) Update(m *Message) (bool, error) {
+	rh.SetCurrentTimeRounded(&m.UpdatedAt)
+
+	return r.transition(m, StatusPending, rh.Set{
+		"message":    m.Message,
+		"send_at":    m.SendAt,
+		"updated_at": m.UpdatedAt,
+	})
+}
+
+// Cancel marks pending message as canceled; returns false when it is not pending
+func (r repository) Cancel(m *Message) (bool, error) {
+	rh.SetCurrentTimeRounded(&m.UpdatedAt)
+
+	return r.transition(m, StatusPending, rh.Set{
+		"status":     StatusCanceled,
+		"updated_at": m.UpdatedAt,
+	})
+}
+
+// Claim marks the message as being sent; returns false when it was
+// already claimed by another instance (or canceled meanwhile)
+func (r repository) Claim(m *Message) (bool, error) {
+	return r.transition(m, StatusPending, rh.Set{"status": StatusSending})
+}
+
+// Release returns claimed message to pending, to be sent later
+func (r repository) Release(m *Message) error {
+	_, err := r.transition(m, StatusSending, rh.Set{"status": StatusPending})
+	return err
+}
+
+// UpdateState stores outcome of sending
+func (r repository) UpdateState(m *Message) error {
+	_, err := r.transition(m, StatusSending, rh.Set{
+		"status":      m.Status,
+		"rel_message": m.MessageID,
+		"error":       m.Error,
+		"sent_at":     m.SentAt,
+	})
+
+	return err
+}
+
+// transition updates the message only when it has the given status
+func (r repository) transition(m *Message, from Status, set rh.Set) (bool, error) {
+	query, args, err := squirrel.
+		Update(r.table()).
+		SetMap(set).
+		Where(squirrel.Eq{"id": m.ID, "status": from}).
+		ToSql()
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	res, err := r.db().Exec(query, args...)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
@@ -0,0 +1,61 @@
+package scheduled
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts scheduled message endpoints
+//
+// Expects to be mounted under a path with {channelID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Current user's scheduled messages in the channel: ?status=pending
+	r.Get("/", rest.Handler("ScheduledMessage.List", func(r *http.Request) (interface{}, error) {
+		return DefaultScheduled.With(r.Context()).Find(Filter{
+			ChannelID: rest.ParamUint64(r, "channelID"),
+			Status:    Status(r.URL.Query().Get("status")),
+		})
+	}))
+
+	r.Post("/", rest.Handler("ScheduledMessage.Create", func(r *http.Request) (interface{}, error) {
+		m := &Message{}
+		if err := rest.Decode(r, m); err != nil {
+			return nil, err
+		}
+
+		m.ChannelID = rest.ParamUint64(r, "channelID")
+		return DefaultScheduled.With(r.Context()).Create(m)
+	}))
+
+	r.Get("/{scheduledMessageID}", rest.Handler("ScheduledMessage.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultScheduled.With(r.Context()).FindByID(
+			rest.ParamUint64(r, "channelID"),
+			rest.ParamUint64(r, "scheduledMessageID"),
+		)
+	}))
+
+	r.Put("/{scheduledMessageID}", rest.Handler("ScheduledMessage.Update", func(r *http.Request) (interface{}, error) {
+		m := &Message{}
+		if err := rest.Decode(r, m); err != nil {
+			return nil, err
+		}
+
+		m.ID = rest.ParamUint64(r, "scheduledMessageID")
+		m.ChannelID = rest.ParamUint64(r, "channelID")
+		return DefaultScheduled.With(r.Context()).Update(m)
+	}))
+
+	// Canceled messages are kept, with their status
+	r.Delete("/{scheduledMessageID}", rest.Handler("ScheduledMessage.Cancel", func(r *http.Request) (interface{}, error) {
+		return DefaultScheduled.With(r.Context()).Cancel(
+			rest.ParamUint64(r, "channelID"),
+			rest.ParamUint64(r, "scheduledMessageID"),
+		)
+	}))
+}
@@ -0,0 +1,287 @@
+package scheduled
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	accessController interface {
+		CanSendMessage(context.Context, *messagingTypes.Channel) bool
+		CanReplyMessage(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+
+		repository *repository
+	}
+
+	ScheduledService interface {
+		With(ctx context.Context) ScheduledService
+
+		Find(Filter) (MessageSet, error)
+		FindByID(channelID, ID uint64) (*Message, error)
+		Create(*Message) (*Message, error)
+		Update(*Message) (*Message, error)
+		Cancel(channelID, ID uint64) (*Message, error)
+	}
+)
+
+var (
+	DefaultScheduled ScheduledService
+
+	// now is used for scheduling and can be overridden
+	now = time.Now
+
+	// Messages a user can have waiting to be sent
+	maxPending uint = 100
+)
+
+// Init initializes scheduled messages and starts sending them in the background
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	maxPending = uint(options.EnvInt("", "SCHEDULED_MAX_PENDING", int(maxPending)))
+
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		channels: messagingService.DefaultChannel,
+	}
+
+	DefaultScheduled = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc service) With(ctx context.Context) ScheduledService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns current user's scheduled messages in the channel
+func (svc service) Find(f Filter) (MessageSet, error) {
+	if _, err := svc.channels.FindByID(f.ChannelID); err != nil {
+		return nil, err
+	}
+
+	f.UserID = auth.GetIdentityFromContext(svc.ctx).Identity()
+	return svc.repository.Find(f)
+}
+
+// FindByID returns scheduled message of the current user
+func (svc service) FindByID(channelID, ID uint64) (*Message, error) {
+	if _, err := svc.channels.FindByID(channelID); err != nil {
+		return nil, err
+	}
+
+	m, err := svc.repository.FindByID(channelID, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.UserID != auth.GetIdentityFromContext(svc.ctx).Identity() {
+		return nil, ErrScheduledMessageNotFound.withStack().WithID("channelID", channelID).WithID("scheduledMessageID", ID)
+	}
+
+	return m, nil
+}
+
+// Create schedules the message, current user must be able to send it now
+func (svc service) Create(in *Message) (*Message, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	i := auth.GetIdentityFromContext(svc.ctx)
+
+	if n, err := svc.repository.CountPending(i.Identity()); err != nil {
+		return nil, err
+	} else if n >= maxPending {
+		return nil, ErrTooManyScheduled.withStack()
+	}
+
+	return svc.repository.Create(&Message{
+		ChannelID: in.ChannelID,
+		ReplyTo:   in.ReplyTo,
+		Message:   in.Message,
+		SendAt:    in.SendAt,
+		Status:    StatusPending,
+		UserID:    i.Identity(),
+	})
+}
+
+// Update changes text and time of the message that was not sent yet
+func (svc service) Update(upd *Message) (*Message, error) {
+	m, err := svc.FindByID(upd.ChannelID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	upd.ReplyTo = m.ReplyTo
+	if err = svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	m.Message, m.SendAt = upd.Message, upd.SendAt
+
+	if ok, err := svc.repository.Update(m); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrNotPending.withStack().WithID("scheduledMessageID", m.ID)
+	}
+
+	return m, nil
+}
+
+// Cancel prevents the message from being sent
+func (svc service) Cancel(channelID, ID uint64) (*Message, error) {
+	m, err := svc.FindByID(channelID, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := svc.repository.Cancel(m); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, ErrNotPending.withStack().WithID("scheduledMessageID", m.ID)
+	}
+
+	m.Status = StatusCanceled
+	return m, nil
+}
+
+func (svc service) validate(m *Message) error {
+	ch, err := svc.channels.FindByID(m.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	if m.ReplyTo > 0 && !svc.ac.CanReplyMessage(svc.ctx, ch) {
+		return ErrNoPermissions.withStack()
+	} else if m.ReplyTo == 0 && !svc.ac.CanSendMessage(svc.ctx, ch) {
+		return ErrNoPermissions.withStack()
+	}
+
+	if m.Message = strings.TrimSpace(m.Message); m.Message == "" {
+		return ErrMessageRequired.withStack()
+	}
+
+	t := now()
+	if m.SendAt = m.SendAt.Truncate(time.Second); !m.SendAt.After(t) {
+		return ErrSendAtInPast.withStack()
+	} else if m.SendAt.After(t.Add(maxAhead)) {
+		return ErrSendAtTooFar.withStack()
+	}
+
+	return nil
+}
+
+// send creates the message in the name of its author and stores the outcome
+//
+// Messages stay pending while the author can not be resolved because
+// system service (in another process) is unreachable.
+func (svc service) send(m *Message) {
+	log := svc.log(zap.Uint64("scheduledMessageID", m.ID), zap.Uint64("channelID", m.ChannelID))
+
+	if ok, err := svc.repository.Claim(m); err != nil {
+		log.Error("could not claim scheduled message", zap.Error(err))
+		return
+	} else if !ok {
+		return
+	}
+
+	var sent *messagingTypes.Message
+
+	ctx, err := runas.Context(svc.ctx, m.UserID)
+	if fault.Is(err, runas.ErrUsersUnavailable) {
+		log.Warn("could not resolve author of scheduled message, will retry", zap.Error(err))
+		if err = svc.repository.Release(m); err != nil {
+			log.Error("could not release scheduled message", zap.Error(err))
+		}
+
+		return
+	} else if err == nil {
+		sent, err = messagingService.DefaultMessage.With(ctx).Create(&messagingTypes.Message{
+			ChannelID: m.ChannelID,
+			ReplyTo:   m.ReplyTo,
+			Message:   m.Message,
+		})
+	}
+
+	t := now().Truncate(time.Second)
+	m.SentAt = &t
+
+	if err != nil {
+		m.Status, m.Error = StatusFailed, err.Error()
+		log.Warn("could not send scheduled message", zap.Error(err))
+	} else {
+		m.Status, m.MessageID = StatusSent, sent.ID
+	}
+
+	if err = svc.repository.UpdateState(m); err != nil {
+		log.Error("could not store state of scheduled message", zap.Error(err))
+	}
+}
+
+// watch sends due messages
+func (svc service) watch(ctx context.Context) {
+	defer sentry.Recover()
+
+	t := time.NewTicker(watchInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			set, err := Repository(ctx, nil).FindDue(now())
+			if err != nil {
+				svc.logger.Error("could not load due scheduled messages", zap.Error(err))
+				continue
+			}
+
+			for _, m := range set {
+				svc.with(ctx).send(m)
+			}
+		}
+	}
+}
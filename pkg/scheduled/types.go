@@ -0,0 +1,55 @@
+package scheduled
+
+import (
+	"time"
+)
+
+type (
+	// Message is sent to the channel at the scheduled time
+	//
+	// Message is sent in the name of its author, with roles the author
+	// has when it is sent; it fails when the author can not send messages
+	// to the channel anymore or was suspended or deleted.
+	Message struct {
+		ID        uint64 `json:"scheduledMessageID,string" db:"id"`
+		ChannelID uint64 `json:"channelID,string" db:"rel_channel"`
+		ReplyTo   uint64 `json:"replyTo,string,omitempty" db:"reply_to"`
+		Message   string `json:"message" db:"message"`
+
+		SendAt time.Time `json:"sendAt" db:"send_at"`
+		Status Status    `json:"status" db:"status"`
+
+		// Sent message or why it could not be sent
+		MessageID uint64 `json:"messageID,string,omitempty" db:"rel_message"`
+		Error     string `json:"error,omitempty" db:"error"`
+
+		UserID uint64 `json:"userID,string" db:"rel_user"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		SentAt    *time.Time `json:"sentAt,omitempty" db:"sent_at"`
+	}
+
+	MessageSet []*Message
+
+	Filter struct {
+		ChannelID uint64 `json:"channelID,string"`
+		UserID    uint64 `json:"userID,string"`
+		Status    Status `json:"status"`
+	}
+
+	Status string
+)
+
+const (
+	StatusPending  Status = "pending"
+	StatusSending  Status = "sending"
+	StatusSent     Status = "sent"
+	StatusFailed   Status = "failed"
+	StatusCanceled Status = "canceled"
+
+	// How far ahead messages can be scheduled
+	maxAhead = 365 * 24 * time.Hour
+
+	watchInterval = 10 * time.Second
+)
@@ -0,0 +1,116 @@
+package schemacache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// cache holds modules with their fields
+	cache struct {
+		sync.RWMutex
+
+		modules map[uint64]*entry
+
+		// Incremented by every invalidation; modules loaded before it
+		// are not stored, they might be stale already
+		generation uint64
+	}
+
+	entry struct {
+		module *types.Module
+		fields types.ModuleFieldSet
+		at     time.Time
+	}
+)
+
+func newCache() *cache {
+	return &cache{
+		modules: map[uint64]*entry{},
+	}
+}
+
+// get returns the module with fields, unless it was loaded more than ttl ago
+func (c *cache) get(moduleID uint64, at time.Time) (*entry, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	if e, ok := c.modules[moduleID]; ok && at.Sub(e.at) < ttl {
+		return e, true
+	}
+
+	return nil, false
+}
+
+// current returns the generation that modules are loaded in
+func (c *cache) current() uint64 {
+	c.RLock()
+	defer c.RUnlock()
+	return c.generation
+}
+
+// put stores modules with their fields, loaded in the generation
+func (c *cache) put(mm types.ModuleSet, ff types.ModuleFieldSet, generation uint64, at time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	for _, m := range mm {
+		c.modules[m.ID] = &entry{
+			module: cloneModule(m),
+			fields: cloneFields(ff.FilterByModule(m.ID)),
+			at:     at,
+		}
+	}
+}
+
+// invalidate forgets the modules
+func (c *cache) invalidate(moduleIDs ...uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	for _, moduleID := range moduleIDs {
+		delete(c.modules, moduleID)
+	}
+}
+
+// flush forgets all modules
+func (c *cache) flush() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.generation++
+	c.modules = map[uint64]*entry{}
+}
+
+// size returns number of cached modules
+func (c *cache) size() int {
+	c.RLock()
+	defer c.RUnlock()
+	return len(c.modules)
+}
+
+// cloneModule copies the module without fields, services
+// append fields to modules they get
+func cloneModule(m *types.Module) *types.Module {
+	c := *m
+	c.Fields = nil
+	return &c
+}
+
+// cloneFields copies the fields, so that cached ones are never modified
+func cloneFields(ff types.ModuleFieldSet) types.ModuleFieldSet {
+	out := make(types.ModuleFieldSet, len(ff))
+	for i, f := range ff {
+		c := *f
+		out[i] = &c
+	}
+
+	return out
+}
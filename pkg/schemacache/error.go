@@ -0,0 +1,32 @@
+package schemacache
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	schemaCacheError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoPermissions = schemaCacheError{"NoPermissions", fault.Forbidden}
+)
+
+func (e schemaCacheError) Error() string {
+	return e.String()
+}
+
+func (e schemaCacheError) String() string {
+	return "crust.schemacache." + e.name
+}
+
+func (e schemaCacheError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e schemaCacheError) withStack() *fault.Error {
+	return fault.New(e)
+}
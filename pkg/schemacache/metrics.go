@@ -0,0 +1,38 @@
+package schemacache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_schemacache_hits_total",
+		Help: "Number of modules and their fields served from the cache.",
+	})
+
+	metricMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "crust_schemacache_misses_total",
+		Help: "Number of modules and their fields loaded from the database.",
+	})
+
+	metricInvalidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "crust_schemacache_invalidations_total",
+		Help: "Number of cache invalidations, of some modules or of all.",
+	}, []string{"scope"})
+
+	metricModules = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "crust_schemacache_modules",
+		Help: "Number of cached modules.",
+	}, func() float64 {
+		return float64(current.size())
+	})
+)
+
+func registerMetrics() {
+	prometheus.MustRegister(
+		metricHits,
+		metricMisses,
+		metricInvalidations,
+		metricModules,
+	)
+}
@@ -0,0 +1,50 @@
+package schemacache
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// module wraps module service so that it loads modules from the cache
+	// and invalidates changed modules
+	module struct {
+		service.ModuleService
+	}
+)
+
+// Module decorates module service with the cached module repository
+//
+// Changed modules are invalidated by the repository and once more after
+// the change is committed, in case they were loaded in between.
+func Module(ms service.ModuleService) service.ModuleService {
+	return &module{ModuleService: ms}
+}
+
+func (svc module) With(ctx context.Context) service.ModuleService {
+	ms := svc.ModuleService.With(ctx)
+	useCache(ms, "ModuleService")
+
+	return &module{ModuleService: ms}
+}
+
+func (svc module) Update(m *types.Module) (*types.Module, error) {
+	m, err := svc.ModuleService.Update(m)
+	if err != nil {
+		return nil, err
+	}
+
+	Invalidate(m.ID)
+	return m, nil
+}
+
+func (svc module) DeleteByID(namespaceID, moduleID uint64) error {
+	if err := svc.ModuleService.DeleteByID(namespaceID, moduleID); err != nil {
+		return err
+	}
+
+	Invalidate(moduleID)
+	return nil
+}
@@ -0,0 +1,26 @@
+package schemacache
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+)
+
+type (
+	// record wraps record service so that it loads modules from the cache
+	record struct {
+		service.RecordService
+	}
+)
+
+// Record decorates record service with the cached module repository
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	rs := svc.RecordService.With(ctx)
+	useCache(rs, "RecordService")
+
+	return &record{RecordService: rs}
+}
@@ -0,0 +1,95 @@
+package schemacache
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/compose/repository"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// moduleRepository serves modules and fields by ID from the cache
+	// and invalidates modules it changes
+	moduleRepository struct {
+		repository.ModuleRepository
+	}
+)
+
+// ModuleRepository wraps corteza's module repository with the cache
+//
+// Modules are looked up by ID only, lists, names and handles are always
+// loaded from the database.
+func ModuleRepository(r repository.ModuleRepository) repository.ModuleRepository {
+	if _, ok := r.(*moduleRepository); ok {
+		return r
+	}
+
+	return &moduleRepository{ModuleRepository: r}
+}
+
+func (r moduleRepository) With(ctx context.Context, db *factory.DB) repository.ModuleRepository {
+	return &moduleRepository{ModuleRepository: r.ModuleRepository.With(ctx, db)}
+}
+
+// FindByID returns the cached module, module is loaded and cached with its
+// fields when missing
+func (r moduleRepository) FindByID(namespaceID, moduleID uint64) (*types.Module, error) {
+	if e, ok := current.get(moduleID, now()); ok && e.module.NamespaceID == namespaceID {
+		metricHits.Inc()
+		return cloneModule(e.module), nil
+	}
+
+	metricMisses.Inc()
+	generation := current.current()
+
+	m, err := r.ModuleRepository.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ff, err := r.ModuleRepository.FindFields(m.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	current.put(types.ModuleSet{m}, ff, generation, now())
+	return m, nil
+}
+
+// FindFields returns cached fields when all modules are cached
+func (r moduleRepository) FindFields(moduleIDs ...uint64) (types.ModuleFieldSet, error) {
+	var (
+		at  = now()
+		out = types.ModuleFieldSet{}
+	)
+
+	for _, moduleID := range moduleIDs {
+		e, ok := current.get(moduleID, at)
+		if !ok {
+			metricMisses.Inc()
+			return r.ModuleRepository.FindFields(moduleIDs...)
+		}
+
+		out = append(out, cloneFields(e.fields)...)
+	}
+
+	metricHits.Inc()
+	return out, nil
+}
+
+func (r moduleRepository) Update(m *types.Module) (*types.Module, error) {
+	defer Invalidate(m.ID)
+	return r.ModuleRepository.Update(m)
+}
+
+func (r moduleRepository) UpdateFields(moduleID uint64, ff types.ModuleFieldSet, hasRecords bool) error {
+	defer Invalidate(moduleID)
+	return r.ModuleRepository.UpdateFields(moduleID, ff, hasRecords)
+}
+
+func (r moduleRepository) DeleteByID(namespaceID, moduleID uint64) error {
+	defer Invalidate(moduleID)
+	return r.ModuleRepository.DeleteByID(namespaceID, moduleID)
+}
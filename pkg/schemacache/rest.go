@@ -0,0 +1,24 @@
+package schemacache
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts schema cache endpoints, for those that can manage compose settings
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("SchemaCache.Stats", func(r *http.Request) (interface{}, error) {
+		return DefaultSchemaCache.With(r.Context()).Stats()
+	}))
+
+	// Forgets all modules and loads them again, or only the one of ?moduleID=
+	r.Post("/refresh", rest.Handler("SchemaCache.Refresh", func(r *http.Request) (interface{}, error) {
+		return DefaultSchemaCache.With(r.Context()).Refresh(rest.QueryUint64(r, "moduleID"))
+	}))
+}
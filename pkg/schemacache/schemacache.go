@@ -0,0 +1,34 @@
+package schemacache
+
+import (
+	"time"
+)
+
+var (
+	current = newCache()
+
+	// Modules changed outside of this process (other instances, imports
+	// made directly in the database) are picked up when cached ones expire
+	ttl = 10 * time.Minute
+
+	now = time.Now
+)
+
+// Invalidate forgets the modules
+//
+// Must be called whenever modules or their fields change in a way that
+// the module service does not see.
+func Invalidate(moduleIDs ...uint64) {
+	if len(moduleIDs) == 0 {
+		return
+	}
+
+	metricInvalidations.WithLabelValues("modules").Inc()
+	current.invalidate(moduleIDs...)
+}
+
+// Flush forgets all modules
+func Flush() {
+	metricInvalidations.WithLabelValues("all").Inc()
+	current.flush()
+}
@@ -0,0 +1,168 @@
+package schemacache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/repository"
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	accessController interface {
+		CanManageSettings(context.Context) bool
+	}
+
+	schemaCacheService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+	}
+
+	SchemaCacheService interface {
+		With(ctx context.Context) SchemaCacheService
+
+		Stats() (*Stats, error)
+		Refresh(moduleID uint64) (*Stats, error)
+	}
+)
+
+var (
+	DefaultSchemaCache SchemaCacheService
+
+	warmedAt struct {
+		sync.RWMutex
+		at *time.Time
+	}
+)
+
+// Init caches modules with their fields for record and module services
+//
+// All modules are loaded in the background at startup, unless disabled
+// with SCHEMA_CACHE_WARMUP=false; others are cached when first used. Must
+// be called after compose services are initialized and after record service
+// is recreated by triggers.
+func Init(ctx context.Context, log *zap.Logger) error {
+	ttl = options.EnvDuration("", "SCHEMA_CACHE_TTL", ttl)
+
+	registerMetrics()
+
+	svc := &schemaCacheService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+	}
+
+	DefaultSchemaCache = svc.With(ctx)
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+	service.DefaultModule = Module(service.DefaultModule)
+
+	if options.EnvBool("", "SCHEMA_CACHE_WARMUP", true) {
+		go svc.warm(ctx)
+	}
+
+	return nil
+}
+
+func (svc schemaCacheService) With(ctx context.Context) SchemaCacheService {
+	return svc.with(ctx)
+}
+
+func (svc schemaCacheService) with(ctx context.Context) *schemaCacheService {
+	return &schemaCacheService{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac: svc.ac,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc schemaCacheService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Stats returns size of the cache of this instance
+func (svc schemaCacheService) Stats() (*Stats, error) {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return svc.stats(), nil
+}
+
+// Refresh forgets the module or all modules of this instance; all
+// modules are loaded again right away
+func (svc schemaCacheService) Refresh(moduleID uint64) (*Stats, error) {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if moduleID > 0 {
+		Invalidate(moduleID)
+		return svc.stats(), nil
+	}
+
+	Flush()
+	if err := svc.load(); err != nil {
+		return nil, err
+	}
+
+	return svc.stats(), nil
+}
+
+func (svc schemaCacheService) stats() *Stats {
+	warmedAt.RLock()
+	defer warmedAt.RUnlock()
+
+	return &Stats{
+		Modules:  current.size(),
+		WarmedAt: warmedAt.at,
+		TTL:      ttl.String(),
+	}
+}
+
+// warm loads all modules at startup
+func (svc schemaCacheService) warm(ctx context.Context) {
+	if err := svc.with(ctx).load(); err != nil {
+		svc.logger.Error("could not load modules", zap.Error(err))
+		return
+	}
+
+	svc.logger.Debug("modules loaded", zap.Int("modules", current.size()))
+}
+
+// load caches all modules with their fields
+func (svc schemaCacheService) load() error {
+	var (
+		at         = now()
+		generation = current.current()
+		r          = repository.Module(svc.ctx, repository.DB(svc.ctx))
+	)
+
+	mm, _, err := r.Find(types.ModuleFilter{})
+	if err != nil {
+		return err
+	}
+
+	ff, err := r.FindFields(mm.IDs()...)
+	if err != nil {
+		return err
+	}
+
+	current.put(mm, ff, generation, at)
+
+	warmedAt.Lock()
+	warmedAt.at = &at
+	warmedAt.Unlock()
+
+	return nil
+}
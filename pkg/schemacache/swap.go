@@ -0,0 +1,41 @@
+package schemacache
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/cortezaproject/corteza-server/compose/repository"
+)
+
+var (
+	moduleRepositoryType = reflect.TypeOf((*repository.ModuleRepository)(nil)).Elem()
+)
+
+// useCache replaces module repository of corteza's service with the cached one
+//
+// Corteza's services create their repositories in With() and have no way
+// to replace them; the unexported field is set through reflection.
+// Decorators embed the service they wrap (ModuleService, RecordService)
+// and are unwrapped until corteza's service is found.
+func useCache(svc interface{}, embedded string) bool {
+	v := reflect.Indirect(reflect.ValueOf(svc))
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+
+	if f := v.FieldByName("moduleRepo"); f.IsValid() && f.CanAddr() && f.Type() == moduleRepositoryType {
+		f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+		if r, ok := f.Interface().(repository.ModuleRepository); ok && r != nil {
+			f.Set(reflect.ValueOf(ModuleRepository(r)))
+			return true
+		}
+
+		return false
+	}
+
+	if f := v.FieldByName(embedded); f.IsValid() && f.Kind() == reflect.Interface && !f.IsNil() {
+		return useCache(f.Interface(), embedded)
+	}
+
+	return false
+}
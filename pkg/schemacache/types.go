@@ -0,0 +1,17 @@
+package schemacache
+
+import (
+	"time"
+)
+
+type (
+	// Stats of the cache, of this instance
+	Stats struct {
+		Modules int `json:"modules"`
+
+		// When all modules were last loaded, at startup or on refresh
+		WarmedAt *time.Time `json:"warmedAt,omitempty"`
+
+		TTL string `json:"ttl"`
+	}
+)
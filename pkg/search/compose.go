@@ -0,0 +1,212 @@
+package search
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/access"
+)
+
+type (
+	// readableModule is a module with readable records and list of readable fields
+	readableModule struct {
+		module *composeTypes.Module
+		fields []string
+	}
+
+	readableModules map[uint64]*readableModule
+
+	recordMatch struct {
+		RecordID    uint64    `db:"id"`
+		ModuleID    uint64    `db:"module_id"`
+		NamespaceID uint64    `db:"rel_namespace"`
+		CreatedAt   time.Time `db:"created_at"`
+		Field       string    `db:"name"`
+		Value       string    `db:"value"`
+	}
+
+	recordFileMatch struct {
+		AttachmentID uint64    `db:"id"`
+		Name         string    `db:"name"`
+		CreatedAt    time.Time `db:"created_at"`
+		RecordID     uint64    `db:"record_id"`
+		ModuleID     uint64    `db:"module_id"`
+		NamespaceID  uint64    `db:"rel_namespace"`
+	}
+)
+
+// readableModules returns modules in all namespaces with records that current user can read
+func (svc searchService) readableModules() (readableModules, error) {
+	var (
+		out = readableModules{}
+		ns  = composeService.DefaultNamespace.With(svc.ctx)
+		ms  = composeService.DefaultModule.With(svc.ctx)
+	)
+
+	nn, _, err := ns.Find(composeTypes.NamespaceFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range nn {
+		mm, _, err := ms.Find(composeTypes.ModuleFilter{NamespaceID: n.ID})
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			readable = access.CanReadModuleRecords(svc.ctx, mm)
+			ff       composeTypes.ModuleFieldSet
+		)
+
+		for _, m := range mm {
+			if readable[m.ID] {
+				ff = append(ff, m.Fields...)
+			}
+		}
+
+		values := access.CanReadRecordValues(svc.ctx, ff)
+
+		for _, m := range mm {
+			if !readable[m.ID] {
+				continue
+			}
+
+			rm := &readableModule{module: m}
+			for _, f := range m.Fields {
+				if values[f.ID] {
+					rm.fields = append(rm.fields, f.Name)
+				}
+			}
+
+			if len(rm.fields) > 0 {
+				out[m.ID] = rm
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// condition limits query to readable fields of readable modules
+func (mm readableModules) condition() squirrel.Or {
+	cnd := squirrel.Or{}
+	for ID, rm := range mm {
+		cnd = append(cnd, squirrel.Eq{"r.module_id": ID, "v.name": rm.fields})
+	}
+
+	return cnd
+}
+
+// records searches through values of all readable records
+func (svc searchService) records(q string, mm readableModules) (ResultSet, error) {
+	if len(mm) == 0 {
+		return ResultSet{}, nil
+	}
+
+	var (
+		matches = make([]*recordMatch, 0)
+		out     = ResultSet{}
+		byID    = map[uint64]*Result{}
+
+		query = squirrel.
+			Select("r.id", "r.module_id", "r.rel_namespace", "r.created_at", "v.name", "v.value").
+			From("compose_record_value AS v").
+			Join("compose_record AS r ON (r.id = v.record_id)").
+			Where(squirrel.Eq{"r.deleted_at": nil, "v.deleted_at": nil}).
+			Where(squirrel.Like{"LOWER(v.value)": likeQuery(q)}).
+			Where(mm.condition()).
+			OrderBy("r.id DESC").
+			// Each record can match with more than one value
+			Limit(maxCandidates * 4)
+	)
+
+	if err := rh.FetchAll(factory.Database.MustGet("compose").With(svc.ctx), query, &matches); err != nil {
+		return nil, err
+	}
+
+	for _, m := range matches {
+		s := score(m.Value, q)
+		if r, ok := byID[m.RecordID]; ok {
+			if s > r.Score {
+				r.Score, r.Snippet = s, snippet(m.Value, q)
+				r.Refs["field"] = m.Field
+			}
+
+			continue
+		}
+
+		if len(out) >= maxCandidates {
+			continue
+		}
+
+		r := &Result{
+			Type:      ResultRecord,
+			ID:        m.RecordID,
+			Title:     mm[m.ModuleID].module.Name,
+			Snippet:   snippet(m.Value, q),
+			Score:     s,
+			CreatedAt: m.CreatedAt,
+			Refs: map[string]string{
+				"namespaceID": strconv.FormatUint(m.NamespaceID, 10),
+				"moduleID":    strconv.FormatUint(m.ModuleID, 10),
+				"field":       m.Field,
+			},
+		}
+
+		byID[m.RecordID] = r
+		out = append(out, r)
+	}
+
+	return out, nil
+}
+
+// recordFiles searches through names of files attached to readable records
+func (svc searchService) recordFiles(q string, mm readableModules) (ResultSet, error) {
+	if len(mm) == 0 {
+		return ResultSet{}, nil
+	}
+
+	var (
+		matches = make([]*recordFileMatch, 0)
+		out     = ResultSet{}
+
+		query = squirrel.
+			Select("a.id", "a.name", "a.created_at", "r.id AS record_id", "r.module_id", "r.rel_namespace").
+			From("compose_attachment AS a").
+			Join("compose_record_value AS v ON (v.ref = a.id AND v.deleted_at IS NULL)").
+			Join("compose_record AS r ON (r.id = v.record_id AND r.deleted_at IS NULL)").
+			Where(squirrel.Eq{"a.kind": composeTypes.RecordAttachment, "a.deleted_at": nil}).
+			Where(squirrel.Like{"LOWER(a.name)": likeQuery(q)}).
+			Where(mm.condition()).
+			OrderBy("a.id DESC").
+			Limit(maxCandidates)
+	)
+
+	if err := rh.FetchAll(factory.Database.MustGet("compose").With(svc.ctx), query, &matches); err != nil {
+		return nil, err
+	}
+
+	for _, m := range matches {
+		out = append(out, &Result{
+			Type:      ResultFile,
+			ID:        m.AttachmentID,
+			Title:     m.Name,
+			Score:     score(m.Name, q),
+			CreatedAt: m.CreatedAt,
+			Refs: map[string]string{
+				"namespaceID": strconv.FormatUint(m.NamespaceID, 10),
+				"moduleID":    strconv.FormatUint(m.ModuleID, 10),
+				"recordID":    strconv.FormatUint(m.RecordID, 10),
+			},
+		})
+	}
+
+	return out, nil
+}
@@ -0,0 +1,33 @@
+package search
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	searchError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrQueryTooShort = searchError{"QueryTooShort", fault.Invalid}
+	ErrInvalidType   = searchError{"InvalidType", fault.Invalid}
+)
+
+func (e searchError) Error() string {
+	return e.String()
+}
+
+func (e searchError) String() string {
+	return "crust.search." + e.name
+}
+
+func (e searchError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e searchError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,177 @@
+package search
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	"github.com/crusttech/crust-server/pkg/edits"
+	"github.com/crusttech/crust-server/pkg/fulltext"
+)
+
+type (
+	messageFileMatch struct {
+		AttachmentID uint64    `db:"id"`
+		Name         string    `db:"name"`
+		CreatedAt    time.Time `db:"created_at"`
+		MessageID    uint64    `db:"message_id"`
+		ChannelID    uint64    `db:"rel_channel"`
+	}
+)
+
+// readableChannels returns channels that current user can read messages from
+func (svc searchService) readableChannels() (messagingTypes.ChannelSet, error) {
+	cc, _, err := messagingService.DefaultChannel.With(svc.ctx).Find(messagingTypes.ChannelFilter{
+		CurrentUserID: auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	return cc, err
+}
+
+// messages searches through messages in readable channels
+//
+// Full-text index is used when messages are indexed.
+func (svc searchService) messages(q string, cc messagingTypes.ChannelSet) (ResultSet, error) {
+	if len(cc) == 0 {
+		return ResultSet{}, nil
+	}
+
+	if fulltext.Enabled() {
+		return svc.indexedMessages(q, cc)
+	}
+
+	mm, _, err := messagingService.DefaultMessage.With(svc.ctx).Find(messagingTypes.MessageFilter{
+		Query:     q,
+		ChannelID: cc.IDs(),
+		Limit:     maxCandidates,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(ResultSet, 0, len(mm))
+	for _, m := range mm {
+		r := &Result{
+			Type:      ResultMessage,
+			ID:        m.ID,
+			Snippet:   snippet(m.Message, q),
+			Score:     score(m.Message, q),
+			CreatedAt: m.CreatedAt,
+			Refs: map[string]string{
+				"channelID": strconv.FormatUint(m.ChannelID, 10),
+			},
+		}
+
+		if ch := cc.FindByID(m.ChannelID); ch != nil {
+			r.Title = ch.Name
+		}
+
+		if m.ReplyTo > 0 {
+			r.Refs["threadID"] = strconv.FormatUint(m.ReplyTo, 10)
+		}
+
+		out = append(out, r)
+	}
+
+	revisions, err := edits.Counts(svc.ctx, mm.IDs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range out {
+		r.Revisions = revisions[r.ID]
+		r.Edited = r.Revisions > 0
+	}
+
+	return out, nil
+}
+
+// indexedMessages searches through messages in readable channels with the full-text index
+func (svc searchService) indexedMessages(q string, cc messagingTypes.ChannelSet) (ResultSet, error) {
+	hh, err := fulltext.DefaultFulltext.With(svc.ctx).Search(fulltext.Query{
+		Terms:     q,
+		ChannelID: cc.IDs(),
+		Limit:     maxCandidates,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(ResultSet, 0, len(hh))
+	for _, h := range hh {
+		r := &Result{
+			Type:      ResultMessage,
+			ID:        h.MessageID,
+			Snippet:   h.Snippet,
+			Score:     h.Score,
+			CreatedAt: h.CreatedAt,
+			Refs: map[string]string{
+				"channelID": strconv.FormatUint(h.ChannelID, 10),
+			},
+			Edited:    h.Edited,
+			Revisions: h.Revisions,
+		}
+
+		if ch := cc.FindByID(h.ChannelID); ch != nil {
+			r.Title = ch.Name
+		}
+
+		if h.ThreadID > 0 {
+			r.Refs["threadID"] = strconv.FormatUint(h.ThreadID, 10)
+		}
+
+		out = append(out, r)
+	}
+
+	return out, nil
+}
+
+// messageFiles searches through names of files attached to messages in readable channels
+func (svc searchService) messageFiles(q string, cc messagingTypes.ChannelSet) (ResultSet, error) {
+	if len(cc) == 0 {
+		return ResultSet{}, nil
+	}
+
+	var (
+		matches = make([]*messageFileMatch, 0)
+		out     = ResultSet{}
+
+		query = squirrel.
+			Select("a.id", "a.name", "a.created_at", "m.id AS message_id", "m.rel_channel").
+			From("messaging_attachment AS a").
+			Join("messaging_message_attachment AS ma ON (ma.rel_attachment = a.id)").
+			Join("messaging_message AS m ON (m.id = ma.rel_message AND m.deleted_at IS NULL)").
+			Where(squirrel.Eq{"m.rel_channel": cc.IDs(), "a.deleted_at": nil}).
+			Where(squirrel.Like{"LOWER(a.name)": likeQuery(q)}).
+			OrderBy("a.id DESC").
+			Limit(maxCandidates)
+	)
+
+	if err := rh.FetchAll(factory.Database.MustGet("messaging").With(svc.ctx), query, &matches); err != nil {
+		return nil, err
+	}
+
+	for _, m := range matches {
+		out = append(out, &Result{
+			Type:      ResultFile,
+			ID:        m.AttachmentID,
+			Title:     m.Name,
+			Score:     score(m.Name, q),
+			CreatedAt: m.CreatedAt,
+			Refs: map[string]string{
+				"channelID": strconv.FormatUint(m.ChannelID, 10),
+				"messageID": strconv.FormatUint(m.MessageID, 10),
+			},
+		})
+	}
+
+	return out, nil
+}
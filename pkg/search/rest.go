@@ -0,0 +1,37 @@
+package search
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts unified search endpoint
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?q=acme&types=records,files&perPage=10&page.records=2
+	r.Get("/", rest.Handler("Search.Search", func(r *http.Request) (interface{}, error) {
+		f := Filter{
+			Query:   r.URL.Query().Get("q"),
+			PerPage: rest.QueryUint(r, "perPage"),
+			Pages:   map[ResultType]uint{},
+		}
+
+		for _, t := range strings.Split(r.URL.Query().Get("types"), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				f.Types = append(f.Types, ResultType(t))
+			}
+		}
+
+		for _, t := range allTypes {
+			f.Pages[t] = rest.QueryUint(r, "page."+string(t))
+		}
+
+		return DefaultSearch.With(r.Context()).Search(f)
+	}))
+}
@@ -0,0 +1,191 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	searchService struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	SearchService interface {
+		With(ctx context.Context) SearchService
+
+		Search(Filter) (*Payload, error)
+	}
+)
+
+var (
+	DefaultSearch SearchService
+)
+
+// Init initializes search service
+//
+// Must be called after services of all apps are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultSearch = (&searchService{
+		logger: log,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc searchService) With(ctx context.Context) SearchService {
+	return &searchService{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc searchService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Search queries all requested result types simultaneously
+//
+// Each type is ranked and paged on its own (buckets); first page of
+// results of all types is additionally ranked together.
+func (svc searchService) Search(f Filter) (out *Payload, err error) {
+	if f, err = svc.sanitize(f); err != nil {
+		return
+	}
+
+	var (
+		q = strings.ToLower(f.Query)
+
+		mm readableModules
+		cc messagingTypes.ChannelSet
+
+		wg      sync.WaitGroup
+		mux     sync.Mutex
+		results = map[ResultType]ResultSet{}
+		errs    []error
+
+		collect = func(t ResultType, fn func() (ResultSet, error)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				set, err := fn()
+
+				mux.Lock()
+				defer mux.Unlock()
+
+				if err != nil {
+					errs = append(errs, err)
+					return
+				}
+
+				results[t] = append(results[t], set...)
+			}()
+		}
+	)
+
+	if f.has(ResultRecord) || f.has(ResultFile) {
+		if mm, err = svc.readableModules(); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.has(ResultMessage) || f.has(ResultFile) {
+		if cc, err = svc.readableChannels(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range f.Types {
+		switch t {
+		case ResultRecord:
+			collect(t, func() (ResultSet, error) { return svc.records(q, mm) })
+		case ResultMessage:
+			collect(t, func() (ResultSet, error) { return svc.messages(q, cc) })
+		case ResultUser:
+			collect(t, func() (ResultSet, error) { return svc.users(q) })
+		case ResultFile:
+			collect(t, func() (ResultSet, error) { return svc.recordFiles(q, mm) })
+			collect(t, func() (ResultSet, error) { return svc.messageFiles(q, cc) })
+		}
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		svc.log(zap.String("query", f.Query), zap.Errors("errors", errs)).Error("search failed")
+		return nil, errs[0]
+	}
+
+	out = &Payload{
+		Filter:  f,
+		Results: ResultSet{},
+		Buckets: map[ResultType]*Bucket{},
+	}
+
+	for _, t := range f.Types {
+		set := results[t]
+		if len(set) > maxCandidates {
+			set = set.Ranked()[:maxCandidates]
+		}
+
+		out.Buckets[t] = set.bucket(t, f.Pages[t], f.PerPage)
+		out.Results = append(out.Results, set.Ranked()...)
+	}
+
+	if out.Results = out.Results.Ranked(); uint(len(out.Results)) > f.PerPage {
+		out.Results = out.Results[:f.PerPage]
+	}
+
+	return out, nil
+}
+
+func (svc searchService) sanitize(f Filter) (Filter, error) {
+	if f.Query = strings.TrimSpace(f.Query); len([]rune(f.Query)) < minQueryLength {
+		return f, ErrQueryTooShort.withStack()
+	}
+
+	if len(f.Types) == 0 {
+		f.Types = allTypes
+	}
+
+	for _, t := range f.Types {
+		if !t.IsValid() {
+			return f, ErrInvalidType.withStack()
+		}
+	}
+
+	switch {
+	case f.PerPage == 0:
+		f.PerPage = defaultPerPage
+	case f.PerPage > maxPerPage:
+		f.PerPage = maxPerPage
+	}
+
+	pp := map[ResultType]uint{}
+	for _, t := range f.Types {
+		if pp[t] = f.Pages[t]; pp[t] == 0 {
+			pp[t] = 1
+		}
+	}
+
+	f.Pages = pp
+	return f, nil
+}
+
+func (f Filter) has(t ResultType) bool {
+	for _, a := range f.Types {
+		if a == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,44 @@
+package search
+
+import (
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+// users searches through users that current user can read
+//
+// Masked emails and names (privacy settings) are not searched or returned
+func (svc searchService) users(q string) (ResultSet, error) {
+	uu, _, err := systemService.DefaultUser.With(svc.ctx).Find(systemTypes.UserFilter{
+		Query:      q,
+		PageFilter: rh.Paging(1, maxCandidates),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(ResultSet, 0, len(uu))
+	for _, u := range uu {
+		r := &Result{
+			Type:      ResultUser,
+			ID:        u.ID,
+			CreatedAt: u.CreatedAt,
+		}
+
+		for _, s := range []string{u.Name, u.Handle, u.Username, u.Email} {
+			if r.Title == "" {
+				r.Title = s
+			}
+
+			if sc := score(s, q); sc > r.Score {
+				r.Score, r.Snippet = sc, s
+			}
+		}
+
+		out = append(out, r)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,195 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type (
+	ResultType string
+
+	// Result is one search hit, regardless of its type
+	Result struct {
+		Type    ResultType `json:"type"`
+		ID      uint64     `json:"id,string"`
+		Title   string     `json:"title"`
+		Snippet string     `json:"snippet,omitempty"`
+
+		// Relevance, from 0 to 1
+		Score float64 `json:"score"`
+
+		CreatedAt time.Time `json:"createdAt"`
+
+		// IDs of resources the result belongs to (namespaceID, moduleID, channelID...)
+		Refs map[string]string `json:"refs,omitempty"`
+
+		// Messages only, the message was edited and number of its previous revisions
+		Edited    bool `json:"edited,omitempty"`
+		Revisions uint `json:"revisions,omitempty"`
+	}
+
+	ResultSet []*Result
+
+	// Bucket holds one page of results of one type
+	Bucket struct {
+		Type    ResultType `json:"type"`
+		Total   uint       `json:"total"`
+		Page    uint       `json:"page"`
+		PerPage uint       `json:"perPage"`
+		Results ResultSet  `json:"results"`
+	}
+
+	Filter struct {
+		Query string       `json:"query"`
+		Types []ResultType `json:"types"`
+
+		// Page per result type, first page by default
+		Pages   map[ResultType]uint `json:"pages"`
+		PerPage uint                `json:"perPage"`
+	}
+
+	// Payload holds results of all types, ranked together, and buckets by type
+	Payload struct {
+		Filter  Filter                 `json:"filter"`
+		Results ResultSet              `json:"results"`
+		Buckets map[ResultType]*Bucket `json:"buckets"`
+	}
+)
+
+const (
+	ResultRecord  ResultType = "records"
+	ResultMessage ResultType = "messages"
+	ResultUser    ResultType = "users"
+	ResultFile    ResultType = "files"
+
+	minQueryLength = 2
+
+	defaultPerPage = 10
+	maxPerPage     = 50
+
+	// Max number of matches per type that are ranked; totals are capped to it
+	maxCandidates = 500
+
+	snippetLength = 160
+)
+
+var (
+	allTypes = []ResultType{ResultRecord, ResultMessage, ResultUser, ResultFile}
+)
+
+func (t ResultType) IsValid() bool {
+	for _, a := range allTypes {
+		if t == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+// score returns relevance of the text for the (lowercase) query; 0 when text does not match
+//
+// Exact matches rank above prefix matches, prefix matches above matches at
+// the start of a word and these above matches inside a word.
+func score(text, q string) float64 {
+	var (
+		t = strings.ToLower(text)
+		i = strings.Index(t, q)
+	)
+
+	switch {
+	case i < 0:
+		return 0
+	case t == q:
+		return 1
+	case i == 0:
+		return 0.8
+	}
+
+	if r := []rune(t[:i]); !unicode.IsLetter(r[len(r)-1]) && !unicode.IsDigit(r[len(r)-1]) {
+		return 0.6
+	}
+
+	return 0.4
+}
+
+// snippet cuts part of the text around the first match of the (lowercase) query
+func snippet(text, q string) string {
+	var (
+		rr = []rune(text)
+		i  = strings.Index(strings.ToLower(text), q)
+	)
+
+	if len(rr) <= snippetLength {
+		return text
+	}
+
+	start := 0
+	if i > 0 {
+		// Convert byte offset into rune offset and keep some context before the match
+		start = len([]rune(text[:i])) - snippetLength/4
+		if start < 0 {
+			start = 0
+		}
+	}
+
+	end := start + snippetLength
+	if end > len(rr) {
+		end, start = len(rr), len(rr)-snippetLength
+	}
+
+	out := string(rr[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+
+	if end < len(rr) {
+		out += "…"
+	}
+
+	return out
+}
+
+// likeQuery converts (lowercase) query into LIKE pattern, with wildcards escaped
+func likeQuery(q string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q) + "%"
+}
+
+// Ranked sorts results by score and then by creation time (newest first)
+func (set ResultSet) Ranked() ResultSet {
+	sort.SliceStable(set, func(i, j int) bool {
+		if set[i].Score == set[j].Score {
+			return set[i].CreatedAt.After(set[j].CreatedAt)
+		}
+
+		return set[i].Score > set[j].Score
+	})
+
+	return set
+}
+
+// bucket ranks results and cuts the requested page
+func (set ResultSet) bucket(t ResultType, page, perPage uint) *Bucket {
+	set = set.Ranked()
+
+	b := &Bucket{
+		Type:    t,
+		Total:   uint(len(set)),
+		Page:    page,
+		PerPage: perPage,
+		Results: ResultSet{},
+	}
+
+	if from := (page - 1) * perPage; from < b.Total {
+		to := from + perPage
+		if to > b.Total {
+			to = b.Total
+		}
+
+		b.Results = set[from:to]
+	}
+
+	return b
+}
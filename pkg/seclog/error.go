@@ -0,0 +1,33 @@
+package seclog
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	seclogError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrKindRequired  = seclogError{"KindRequired", fault.Invalid}
+	ErrNoPermissions = seclogError{"NoPermissions", fault.Forbidden}
+)
+
+func (e seclogError) Error() string {
+	return e.String()
+}
+
+func (e seclogError) String() string {
+	return "crust.seclog." + e.name
+}
+
+func (e seclogError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e seclogError) withStack() *fault.Error {
+	return fault.New(e)
+}
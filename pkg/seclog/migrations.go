@@ -0,0 +1,33 @@
+package seclog
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200128000000.seclog",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_security_event (
+  id               BIGINT UNSIGNED NOT NULL,
+  kind             VARCHAR(32)     NOT NULL,
+  action           VARCHAR(64)     NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  remote_ip        VARCHAR(45)     NOT NULL DEFAULT '',
+  user_agent       VARCHAR(255)    NOT NULL DEFAULT '',
+  risk             DOUBLE          NOT NULL DEFAULT 0,
+  outcome          VARCHAR(16)     NOT NULL DEFAULT '',
+  meta             JSON            NOT NULL,
+
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (id),
+  INDEX (kind, action, remote_ip, created_at),
+  INDEX (rel_user, created_at),
+  INDEX (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
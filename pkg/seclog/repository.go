@@ -0,0 +1,106 @@
+package seclog
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_security_event"
+}
+
+func (r repository) where(q squirrel.SelectBuilder, f EventFilter) squirrel.SelectBuilder {
+	if f.Kind != "" {
+		q = q.Where(squirrel.Eq{"kind": f.Kind})
+	}
+
+	if f.Action != "" {
+		q = q.Where(squirrel.Eq{"action": f.Action})
+	}
+
+	if f.UserID > 0 {
+		q = q.Where(squirrel.Eq{"rel_user": f.UserID})
+	}
+
+	if f.RemoteIP != "" {
+		q = q.Where(squirrel.Eq{"remote_ip": f.RemoteIP})
+	}
+
+	if f.Outcome != "" {
+		q = q.Where(squirrel.Eq{"outcome": f.Outcome})
+	}
+
+	if f.Since != nil {
+		q = q.Where(squirrel.GtOrEq{"created_at": f.Since})
+	}
+
+	return q
+}
+
+// Find returns events that match the filter, newest first
+func (r repository) Find(f EventFilter) (set EventSet, err error) {
+	q := r.where(squirrel.Select(
+		"id",
+		"kind",
+		"action",
+		"rel_user",
+		"remote_ip",
+		"user_agent",
+		"risk",
+		"outcome",
+		"meta",
+		"created_at",
+	), f).
+		From(r.table()).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(f.Limit))
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Count returns number of events that match the filter
+func (r repository) Count(f EventFilter) (count uint, err error) {
+	q := r.where(squirrel.Select("COUNT(*)"), f).From(r.table())
+
+	return count, rh.FetchOne(r.db(), q, &count)
+}
+
+func (r repository) Create(e *Event) (*Event, error) {
+	e.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&e.CreatedAt)
+
+	return e, errors.WithStack(r.db().Insert(r.table(), e))
+}
+
+// Prune removes events recorded before the given time
+func (r repository) Prune(before time.Time) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Lt{"created_at": before})
+}
@@ -0,0 +1,37 @@
+package seclog
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts security event log endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?kind=antifraud&action=signup&userID=&remoteIP=&outcome=blocked&since=2020-01-01T00:00:00Z&limit=50
+	r.Get("/", rest.Handler("SecurityEvent.List", func(r *http.Request) (interface{}, error) {
+		var (
+			q = r.URL.Query()
+			f = EventFilter{
+				Kind:     q.Get("kind"),
+				Action:   q.Get("action"),
+				UserID:   rest.QueryUint64(r, "userID"),
+				RemoteIP: q.Get("remoteIP"),
+				Outcome:  q.Get("outcome"),
+				Limit:    rest.QueryUint(r, "limit"),
+			}
+		)
+
+		if since, err := time.Parse(time.RFC3339, q.Get("since")); err == nil {
+			f.Since = &since
+		}
+
+		return DefaultSecLog.With(r.Context()).Find(f)
+	}))
+}
@@ -0,0 +1,149 @@
+package seclog
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/system/service"
+)
+
+type (
+	seclogService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanManageSettings(context.Context) bool
+	}
+
+	SecLogService interface {
+		With(ctx context.Context) SecLogService
+
+		Record(*Event) error
+
+		Find(EventFilter) (EventSet, error)
+		Count(EventFilter) (uint, error)
+	}
+)
+
+var (
+	DefaultSecLog SecLogService
+)
+
+// Init initializes security event log and starts removing old events in the background
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &seclogService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+	}
+
+	DefaultSecLog = svc.With(ctx)
+
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc seclogService) With(ctx context.Context) SecLogService {
+	return &seclogService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// Record stores the event
+//
+// When user is not set, current user (if any) is used
+func (svc seclogService) Record(e *Event) error {
+	if e.Kind == "" {
+		return ErrKindRequired.withStack()
+	}
+
+	if e.UserID == 0 {
+		e.UserID = auth.GetIdentityFromContext(svc.ctx).Identity()
+	}
+
+	if len(e.UserAgent) > maxUserAgentLength {
+		e.UserAgent = e.UserAgent[:maxUserAgentLength]
+	}
+
+	_, err := svc.repository.Create(e)
+	return err
+}
+
+// Find returns events that match the filter
+//
+// Security events are available to those that can manage settings
+func (svc seclogService) Find(f EventFilter) (EventSet, error) {
+	if !svc.ac.CanManageSettings(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	switch {
+	case f.Limit == 0:
+		f.Limit = defaultLimit
+	case f.Limit > maxLimit:
+		f.Limit = maxLimit
+	}
+
+	return svc.repository.Find(f)
+}
+
+// Count returns number of events that match the filter
+//
+// Used internally (rate & risk checks), without access control
+func (svc seclogService) Count(f EventFilter) (uint, error) {
+	return svc.repository.Count(f)
+}
+
+func (svc seclogService) watch(ctx context.Context) {
+	t := time.NewTicker(pruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			err := Repository(ctx, factory.Database.MustGet("system").With(ctx)).Prune(time.Now().Add(-retention))
+			if err != nil {
+				svc.logger.Error("could not remove old security events", zap.Error(err))
+			}
+		}
+	}
+}
+
+// FromRequest returns an event with client's address and user agent
+func FromRequest(r *http.Request, kind, action string) *Event {
+	return &Event{
+		Kind:      kind,
+		Action:    action,
+		RemoteIP:  RemoteIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// RemoteIP returns client's address, without the port
+//
+// Proxy headers are already resolved by the server's RealIP middleware
+func RemoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
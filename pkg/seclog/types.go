@@ -0,0 +1,84 @@
+package seclog
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Event is a security relevant occurrence, for example a blocked signup
+	// or a login from a new device
+	Event struct {
+		ID uint64 `json:"eventID,string" db:"id"`
+
+		// Kind of the event (feature that recorded it) and what was attempted
+		Kind   string `json:"kind" db:"kind"`
+		Action string `json:"action" db:"action"`
+
+		// User the event relates to, when known
+		UserID uint64 `json:"userID,string,omitempty" db:"rel_user"`
+
+		RemoteIP  string `json:"remoteIP" db:"remote_ip"`
+		UserAgent string `json:"userAgent" db:"user_agent"`
+
+		// Risk score (0-1) and outcome, for example allowed or blocked
+		Risk    float64 `json:"risk" db:"risk"`
+		Outcome string  `json:"outcome" db:"outcome"`
+
+		Meta Meta `json:"meta,omitempty" db:"meta"`
+
+		CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	}
+
+	EventSet []*Event
+
+	EventFilter struct {
+		Kind     string     `json:"kind"`
+		Action   string     `json:"action"`
+		UserID   uint64     `json:"userID,string"`
+		RemoteIP string     `json:"remoteIP"`
+		Outcome  string     `json:"outcome"`
+		Since    *time.Time `json:"since"`
+		Limit    uint       `json:"limit"`
+	}
+
+	// Meta holds additional, event specific, details
+	Meta map[string]interface{}
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+
+	maxUserAgentLength = 255
+
+	// Events older than this are removed
+	retention = 180 * 24 * time.Hour
+
+	// How often old events are removed
+	pruneInterval = time.Hour
+)
+
+func (m Meta) Value() (driver.Value, error) {
+	if m == nil {
+		m = Meta{}
+	}
+
+	return json.Marshal(m)
+}
+
+func (m *Meta) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*m = Meta{}
+	case []byte:
+		if err := json.Unmarshal(b, m); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into Meta", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli"
+)
+
+// Command generates synthetic data for load and performance tests
+//
+// Users, roles, channels, messages and records are created in databases of
+// all apps the server runs; report is written to stdout as JSON:
+//
+//	crust-server seed --users=5000 --channels=200 --messages=1000000 --distribution=zipf
+func Command(ctx context.Context, c *cli.Config) *cobra.Command {
+	var (
+		cfg     = Config{}
+		authors []string
+
+		now = time.Now().Unix()
+	)
+
+	cmd := &cobra.Command{
+		Use:          "seed",
+		Short:        "Generate synthetic data for load tests",
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, a := range authors {
+				id, err := strconv.ParseUint(a, 10, 64)
+				if err != nil {
+					return err
+				}
+
+				cfg.Authors = append(cfg.Authors, id)
+			}
+
+			c.InitServices(ctx, c)
+
+			rep, err := Run(ctx, c.Log.Named("seed"), cfg)
+			if rep != nil {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				_ = enc.Encode(rep)
+			}
+
+			return err
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&cfg.Prefix, "prefix", "seed"+strconv.FormatInt(now, 10), "Prefix of names, handles and emails")
+	f.IntVar(&cfg.Users, "users", 100, "Number of users")
+	f.IntVar(&cfg.Roles, "roles", 10, "Number of roles")
+	f.IntVar(&cfg.RolesPerUser, "roles-per-user", 2, "Average number of roles of a user")
+	f.IntVar(&cfg.Channels, "channels", 20, "Number of channels")
+	f.IntVar(&cfg.MembersPerChannel, "members-per-channel", 20, "Number of members of a channel")
+	f.IntVar(&cfg.Messages, "messages", 1000, "Number of messages")
+	f.Float64Var(&cfg.ReplyRatio, "reply-ratio", 0.1, "Share of messages that are replies")
+	f.IntVar(&cfg.Modules, "modules", 5, "Number of modules")
+	f.IntVar(&cfg.Records, "records", 1000, "Number of records")
+	f.StringVar(&cfg.Distribution, "distribution", DistributionUniform, "Spread of members, messages and records: uniform or zipf")
+	f.Float64Var(&cfg.Skew, "skew", 1.5, "Skew of zipf distribution, greater than 1")
+	f.StringSliceVar(&authors, "author", nil, "Users that author messages and records when no users are generated")
+	f.Int64Var(&cfg.Seed, "seed", now, "Seed of the random generator")
+
+	return cmd
+}
@@ -0,0 +1,33 @@
+package seed
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	seedError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNoAuthors           = seedError{"NoAuthors", fault.Invalid}
+	ErrInvalidDistribution = seedError{"InvalidDistribution", fault.Invalid}
+)
+
+func (e seedError) Error() string {
+	return e.String()
+}
+
+func (e seedError) String() string {
+	return "crust.seed." + e.name
+}
+
+func (e seedError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e seedError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,306 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	generator struct {
+		ctx    context.Context
+		logger *zap.Logger
+		cfg    Config
+
+		rand *rand.Rand
+		pick picker
+
+		// Generated (or given) users and generated roles
+		users []uint64
+		roles []uint64
+
+		report *Report
+	}
+)
+
+const (
+	// Progress is logged every so many items
+	progressEvery = 1000
+)
+
+// Run generates data of apps with initialized services
+//
+// Data is created through corteza's services, the same way as through the
+// API. Users act with admins role, so default rules let them create
+// channels, messages and records. Compose and messaging data of standalone
+// servers is authored by the given users.
+func Run(ctx context.Context, log *zap.Logger, cfg Config) (*Report, error) {
+	var (
+		start = time.Now()
+		g     = &generator{
+			ctx:    auth.SetSuperUserContext(ctx),
+			logger: log,
+			cfg:    cfg,
+			rand:   rand.New(rand.NewSource(cfg.Seed)),
+			report: &Report{Prefix: cfg.Prefix},
+		}
+		err error
+	)
+
+	if g.pick, err = distribution(g.rand, cfg.Distribution, cfg.Skew); err != nil {
+		return nil, err
+	}
+
+	if systemService.DefaultUser != nil {
+		if err = g.system(); err != nil {
+			return g.report, err
+		}
+	}
+
+	if len(g.users) == 0 {
+		g.users = cfg.Authors
+	}
+
+	if messagingService.DefaultChannel != nil && cfg.Channels > 0 {
+		if len(g.users) == 0 {
+			return g.report, ErrNoAuthors.withStack()
+		}
+
+		if err = g.messaging(); err != nil {
+			return g.report, err
+		}
+	}
+
+	if composeService.DefaultRecord != nil && cfg.Modules > 0 {
+		if len(g.users) == 0 {
+			return g.report, ErrNoAuthors.withStack()
+		}
+
+		if err = g.compose(); err != nil {
+			return g.report, err
+		}
+	}
+
+	g.report.Took = time.Since(start).String()
+	return g.report, nil
+}
+
+// as returns context of the user, with admins role
+func (g *generator) as(userID uint64) context.Context {
+	return auth.SetIdentityToContext(g.ctx, auth.NewIdentity(userID, permissions.AdminsRoleID))
+}
+
+// name returns prefixed name, valid as handle
+func (g *generator) name(kind string, i int) string {
+	return g.cfg.Prefix + "_" + kind + strconv.Itoa(i)
+}
+
+func (g *generator) progress(what string, done, total int) {
+	if done%progressEvery == 0 || done == total {
+		g.logger.Info("seeding", zap.String("what", what), zap.Int("done", done), zap.Int("total", total))
+	}
+}
+
+// system generates users, roles and memberships
+func (g *generator) system() error {
+	var (
+		users = systemService.DefaultUser.With(g.ctx)
+		roles = systemService.DefaultRole.With(g.ctx)
+	)
+
+	for i := 1; i <= g.cfg.Roles; i++ {
+		r, err := roles.Create(&systemTypes.Role{
+			Name:   fmt.Sprintf("%s %s", words[g.rand.Intn(len(words))], g.name("role", i)),
+			Handle: g.name("role", i),
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "could not create role")
+		}
+
+		g.roles = append(g.roles, r.ID)
+		g.report.Roles++
+	}
+
+	for i := 1; i <= g.cfg.Users; i++ {
+		var (
+			first = firstNames[g.rand.Intn(len(firstNames))]
+			last  = lastNames[g.rand.Intn(len(lastNames))]
+		)
+
+		u, err := users.Create(&systemTypes.User{
+			Email:  g.name("user", i) + "@example.com",
+			Name:   first + " " + last,
+			Handle: g.name("user", i),
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "could not create user")
+		}
+
+		g.users = append(g.users, u.ID)
+		g.report.Users++
+
+		for _, r := range distinct(g.pick, len(g.roles), g.rand.Intn(g.cfg.RolesPerUser*2+1)) {
+			if err = roles.MemberAdd(g.roles[r], u.ID); err != nil {
+				return errors.Wrap(err, "could not add member")
+			}
+
+			g.report.Memberships++
+		}
+
+		g.progress("users", i, g.cfg.Users)
+	}
+
+	return nil
+}
+
+// messaging generates channels with members, messages and replies
+func (g *generator) messaging() error {
+	var (
+		channels = make([]uint64, 0, g.cfg.Channels)
+		members  = map[uint64][]uint64{}
+
+		// Messages of channels, replies refer to them
+		posted = map[uint64][]uint64{}
+	)
+
+	for i := 1; i <= g.cfg.Channels; i++ {
+		var (
+			mm    []uint64
+			owner = g.users[g.pick(len(g.users))]
+			typ   = messagingTypes.ChannelTypePublic
+		)
+
+		for _, u := range distinct(g.pick, len(g.users), g.cfg.MembersPerChannel) {
+			mm = append(mm, g.users[u])
+		}
+
+		if g.rand.Intn(4) == 0 {
+			typ = messagingTypes.ChannelTypePrivate
+		}
+
+		ch, err := messagingService.DefaultChannel.With(g.as(owner)).Create(&messagingTypes.Channel{
+			Name:    g.name("channel", i),
+			Topic:   sentence(g.rand),
+			Type:    typ,
+			Members: mm,
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "could not create channel")
+		}
+
+		channels = append(channels, ch.ID)
+		members[ch.ID] = append(mm, owner)
+		g.report.Channels++
+	}
+
+	for i := 1; i <= g.cfg.Messages; i++ {
+		var (
+			channelID = channels[g.pick(len(channels))]
+			authorID  = members[channelID][g.rand.Intn(len(members[channelID]))]
+			msg       = &messagingTypes.Message{ChannelID: channelID, Message: sentence(g.rand)}
+		)
+
+		if pp := posted[channelID]; len(pp) > 0 && g.rand.Float64() < g.cfg.ReplyRatio {
+			msg.ReplyTo = pp[g.rand.Intn(len(pp))]
+		}
+
+		m, err := messagingService.DefaultMessage.With(g.as(authorID)).Create(msg)
+		if err != nil {
+			return errors.Wrap(err, "could not create message")
+		}
+
+		if msg.ReplyTo > 0 {
+			g.report.Replies++
+		} else {
+			posted[channelID] = append(posted[channelID], m.ID)
+		}
+
+		g.report.Messages++
+		g.progress("messages", i, g.cfg.Messages)
+	}
+
+	return nil
+}
+
+// compose generates a namespace with modules and their records
+func (g *generator) compose() error {
+	ns, err := composeService.DefaultNamespace.With(g.ctx).Create(&composeTypes.Namespace{
+		Name:    g.cfg.Prefix,
+		Slug:    g.cfg.Prefix,
+		Enabled: true,
+	})
+
+	if err != nil {
+		return errors.Wrap(err, "could not create namespace")
+	}
+
+	g.report.Namespaces++
+
+	modules := make([]*composeTypes.Module, 0, g.cfg.Modules)
+	for i := 1; i <= g.cfg.Modules; i++ {
+		m, err := composeService.DefaultModule.With(g.ctx).Create(&composeTypes.Module{
+			NamespaceID: ns.ID,
+			Name:        fmt.Sprintf("%s %d", words[g.rand.Intn(len(words))], i),
+			Handle:      g.name("module", i),
+			Fields: composeTypes.ModuleFieldSet{
+				{Kind: "String", Name: "title", Label: "Title"},
+				{Kind: "String", Name: "description", Label: "Description"},
+				{Kind: "Number", Name: "amount", Label: "Amount"},
+				{Kind: "DateTime", Name: "due", Label: "Due"},
+				{Kind: "Bool", Name: "done", Label: "Done"},
+			},
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "could not create module")
+		}
+
+		modules = append(modules, m)
+		g.report.Modules++
+	}
+
+	for i := 1; i <= g.cfg.Records; i++ {
+		var (
+			m   = modules[g.pick(len(modules))]
+			due = time.Now().Add(time.Duration(g.rand.Intn(24*365)-24*180) * time.Hour)
+		)
+
+		_, err := composeService.DefaultRecord.With(g.as(g.users[g.pick(len(g.users))])).Create(&composeTypes.Record{
+			NamespaceID: ns.ID,
+			ModuleID:    m.ID,
+			Values: composeTypes.RecordValueSet{
+				{Name: "title", Value: words[g.rand.Intn(len(words))] + " " + strconv.Itoa(i)},
+				{Name: "description", Value: sentence(g.rand)},
+				{Name: "amount", Value: strconv.Itoa(g.rand.Intn(100000))},
+				{Name: "due", Value: due.UTC().Format(time.RFC3339)},
+				{Name: "done", Value: strconv.Itoa(g.rand.Intn(2))},
+			},
+		})
+
+		if err != nil {
+			return errors.Wrap(err, "could not create record")
+		}
+
+		g.report.Records++
+		g.progress("records", i, g.cfg.Records)
+	}
+
+	return nil
+}
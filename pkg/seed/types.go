@@ -0,0 +1,148 @@
+package seed
+
+import (
+	"math/rand"
+	"strings"
+)
+
+type (
+	// Config of the generated data
+	Config struct {
+		// Prefix of names, handles and emails, keeps runs apart
+		Prefix string
+
+		Users        int
+		Roles        int
+		RolesPerUser int
+
+		Channels          int
+		MembersPerChannel int
+		Messages          int
+
+		// Share of messages that are replies to earlier messages of the channel
+		ReplyRatio float64
+
+		Modules int
+		Records int
+
+		// How members, messages and records are spread over users, roles,
+		// channels and modules: uniform or zipf
+		Distribution string
+
+		// Skew of zipf distribution, must be > 1
+		Skew float64
+
+		// Users that author messages & records when the run does not create users
+		Authors []uint64
+
+		// Seed of the random generator, same seed generates the same data
+		Seed int64
+	}
+
+	// Report counts what was generated
+	Report struct {
+		Prefix string `json:"prefix"`
+
+		Users       int `json:"users"`
+		Roles       int `json:"roles"`
+		Memberships int `json:"memberships"`
+		Channels    int `json:"channels"`
+		Messages    int `json:"messages"`
+		Replies     int `json:"replies"`
+		Namespaces  int `json:"namespaces"`
+		Modules     int `json:"modules"`
+		Records     int `json:"records"`
+
+		Took string `json:"took"`
+	}
+
+	// picker picks an index from [0, n)
+	picker func(n int) int
+)
+
+const (
+	DistributionUniform = "uniform"
+	DistributionZipf    = "zipf"
+)
+
+var (
+	words = strings.Fields(`
+		account action agenda approval budget call campaign client contract customer
+		deadline delivery demo design draft estimate feedback forecast invoice issue
+		launch lead meeting milestone notes offer order partner payment plan pricing
+		proposal quote release report request review risk roadmap sales schedule
+		shipment sprint status supplier support target task team ticket timeline
+		today tomorrow update vendor week`)
+
+	firstNames = strings.Fields(`
+		Ana Ben Chloe David Eva Filip Grace Hugo Ines Jan Kara Luka Maja Nik Olga
+		Peter Rosa Sara Tom Una Vid Wanda Zoe`)
+
+	lastNames = strings.Fields(`
+		Novak Horvat Smith Jones Kovac Muller Rossi Silva Dubois Berg Nowak Costa
+		Meyer Fischer Weber Garcia Martin Lopez Young King`)
+)
+
+// distribution returns picker of the configured distribution
+//
+// Zipf picks low indexes much more often, like a few busy channels and
+// many quiet ones.
+func distribution(r *rand.Rand, name string, skew float64) (picker, error) {
+	switch name {
+	case DistributionUniform, "":
+		return r.Intn, nil
+
+	case DistributionZipf:
+		if skew <= 1 {
+			return nil, ErrInvalidDistribution.withStack()
+		}
+
+		zz := map[int]*rand.Zipf{}
+		return func(n int) int {
+			if n < 2 {
+				return 0
+			}
+
+			if zz[n] == nil {
+				zz[n] = rand.NewZipf(r, skew, 1, uint64(n-1))
+			}
+
+			return int(zz[n].Uint64())
+		}, nil
+	}
+
+	return nil, ErrInvalidDistribution.withStack()
+}
+
+// sentence of 3 to 30 random words
+func sentence(r *rand.Rand) string {
+	ww := make([]string, 3+r.Intn(28))
+	for i := range ww {
+		ww[i] = words[r.Intn(len(words))]
+	}
+
+	ww[0] = strings.Title(ww[0])
+	return strings.Join(ww, " ") + "."
+}
+
+// distinct picks k distinct indexes from [0, n)
+func distinct(pick picker, n, k int) []int {
+	if k > n {
+		k = n
+	}
+
+	var (
+		out  = make([]int, 0, k)
+		seen = map[int]bool{}
+	)
+
+	// Skewed pickers repeat often, attempts are limited
+	for a := 0; len(out) < k && a < k*20; a++ {
+		if i := pick(n); !seen[i] {
+			seen[i] = true
+			out = append(out, i)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,142 @@
+package slashcommands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/crusttech/crust-server/pkg/scheduled"
+)
+
+type (
+	// builtin is a command handled by the server
+	builtin struct {
+		description string
+		enabled     func() bool
+		handle      func(svc service, inv *Invocation) (*Response, error)
+	}
+)
+
+const (
+	remindUsage = "Usage: /remind [me] in <duration> <text>, for example /remind me in 2h check the build"
+)
+
+var (
+	builtins = map[string]*builtin{
+		"remind": {
+			description: "Remind yourself of something later",
+			enabled:     func() bool { return true },
+			handle:      remind,
+		},
+		"giphy": {
+			description: "Post a random GIF on the topic",
+			enabled:     func() bool { return giphyKey != "" },
+			handle:      giphy,
+		},
+	}
+
+	giphyKey string
+
+	giphyClient = &http.Client{Timeout: invokeTimeout}
+)
+
+// remind schedules the text to be posted into the channel after
+// the duration; durations are in Go's format, with days (d) on top
+func remind(svc service, inv *Invocation) (*Response, error) {
+	ff := strings.Fields(inv.Text)
+	if len(ff) > 0 && ff[0] == "me" {
+		ff = ff[1:]
+	}
+
+	if len(ff) < 3 || ff[0] != "in" {
+		return &Response{Text: remindUsage, Ephemeral: true}, nil
+	}
+
+	d, err := parseDuration(ff[1])
+	if err != nil || d <= 0 {
+		return &Response{Text: remindUsage, Ephemeral: true}, nil
+	}
+
+	m, err := scheduled.DefaultScheduled.With(svc.ctx).Create(&scheduled.Message{
+		ChannelID: inv.ChannelID,
+		ReplyTo:   inv.ReplyTo,
+		Message:   "Reminder: " + strings.Join(ff[2:], " "),
+		SendAt:    now().Add(d).Truncate(time.Second),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Text: "I will remind you on " + m.SendAt.Format(time.RFC1123), Ephemeral: true}, nil
+}
+
+// giphy posts the first GIF found for the text
+func giphy(svc service, inv *Invocation) (*Response, error) {
+	if inv.Text == "" {
+		return &Response{Text: "Usage: /giphy <topic>", Ephemeral: true}, nil
+	}
+
+	q := url.Values{
+		"api_key": {giphyKey},
+		"q":       {inv.Text},
+		"limit":   {"1"},
+		"rating":  {"g"},
+	}
+
+	ctx, cancel := context.WithTimeout(svc.ctx, invokeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, "https://api.giphy.com/v1/gifs/search?"+q.Encode(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rsp, err := giphyClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %d", rsp.StatusCode)
+	}
+
+	var out struct {
+		Data []struct {
+			Images struct {
+				Original struct {
+					URL string `json:"url"`
+				} `json:"original"`
+			} `json:"images"`
+		} `json:"data"`
+	}
+
+	if err = json.NewDecoder(rsp.Body).Decode(&out); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if len(out.Data) == 0 {
+		return &Response{Text: "No GIFs found for " + inv.Text, Ephemeral: true}, nil
+	}
+
+	return &Response{Text: fmt.Sprintf("![%s](%s)", inv.Text, out.Data[0].Images.Original.URL)}, nil
+}
+
+// parseDuration parses Go's durations and days, like 2d
+func parseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+
+	return time.ParseDuration(s)
+}
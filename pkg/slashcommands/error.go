@@ -0,0 +1,38 @@
+package slashcommands
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	commandError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrCommandNotFound   = commandError{"CommandNotFound", fault.NotFound}
+	ErrResponderNotFound = commandError{"ResponderNotFound", fault.NotFound}
+	ErrInvalidName       = commandError{"InvalidName", fault.Invalid}
+	ErrNameTaken         = commandError{"NameTaken", fault.Conflict}
+	ErrInvalidURL        = commandError{"InvalidURL", fault.Invalid}
+	ErrEmptyResponse     = commandError{"EmptyResponse", fault.Invalid}
+	ErrNoPermissions     = commandError{"NoPermissions", fault.Forbidden}
+)
+
+func (e commandError) Error() string {
+	return e.String()
+}
+
+func (e commandError) String() string {
+	return "crust.slashcommands." + e.name
+}
+
+func (e commandError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e commandError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,95 @@
+package slashcommands
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/egress"
+)
+
+var (
+	client = egress.Client(invokeTimeout)
+)
+
+// call posts the invocation to command's URL
+//
+// Command responds with the response in the body or later with the token;
+// empty body means there is nothing to respond with right away.
+func (svc service) call(c *Command, inv *Invocation) (*Response, error) {
+	i := auth.GetIdentityFromContext(svc.ctx)
+
+	inv.ResponseToken = generateToken()
+	if svc.baseURL != "" {
+		inv.ResponseURL = svc.baseURL + "/slash-commands/responses/" + inv.ResponseToken
+	}
+
+	err := svc.repository.CreateResponder(&responder{
+		TokenHash: hash(inv.ResponseToken),
+		CommandID: c.ID,
+		ChannelID: inv.ChannelID,
+		ReplyTo:   inv.ReplyTo,
+		UserID:    i.Identity(),
+		ExpiresAt: now().Add(responderTTL),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(inv)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Command", c.Name)
+
+	if c.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(c.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Command-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	ctx, cancel := context.WithTimeout(svc.ctx, invokeTimeout)
+	defer cancel()
+
+	rsp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return nil, errors.Errorf("unexpected status %d", rsp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(rsp.Body, maxResponseSize))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if len(bytes.TrimSpace(b)) == 0 {
+		return nil, nil
+	}
+
+	out := &Response{}
+	if err = json.Unmarshal(b, out); err != nil {
+		return nil, errors.Wrap(err, "invalid response")
+	}
+
+	return out, nil
+}
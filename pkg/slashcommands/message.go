@@ -0,0 +1,50 @@
+package slashcommands
+
+import (
+	"context"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// message wraps message service and invokes commands of created messages
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with slash commands
+//
+// Messages posted by bots, webhooks and commands themselves (the ones
+// with username) do not invoke commands.
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	name, text, ok := parse(m.Message)
+	if !ok || (m.Meta != nil && m.Meta.Username != "") || !defaultCommand.with(svc.ctx).exists(name) {
+		return svc.MessageService.Create(m)
+	}
+
+	m, err := svc.MessageService.Create(m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Invoked after the request is done, as the current user
+	ctx := auth.SetIdentityToContext(context.Background(), auth.GetIdentityFromContext(svc.ctx))
+	go defaultCommand.with(ctx).invoke(name, text, m)
+
+	return m, nil
+}
@@ -0,0 +1,52 @@
+package slashcommands
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200309000000.slashcommands",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_command (
+  id                 BIGINT UNSIGNED NOT NULL,
+  name               VARCHAR(32)     NOT NULL,
+  description        VARCHAR(255)    NOT NULL DEFAULT '',
+  url                VARCHAR(512)    NOT NULL,
+  secret             VARCHAR(255)    NOT NULL DEFAULT '',
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  created_by         BIGINT UNSIGNED NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_messaging_command_responder (
+  token_hash         CHAR(64)        NOT NULL,
+  rel_command        BIGINT UNSIGNED NOT NULL,
+  rel_channel        BIGINT UNSIGNED NOT NULL,
+  reply_to           BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  rel_user           BIGINT UNSIGNED NOT NULL,
+  roles              TEXT            NOT NULL,
+  responses          INT UNSIGNED    NOT NULL DEFAULT 0,
+  expires_at         DATETIME        NOT NULL,
+
+  PRIMARY KEY (token_hash),
+  INDEX (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+		{
+			Name: "20200313000000.slashcommands-roles",
+			Up: `
+ALTER TABLE crust_messaging_command_responder
+  DROP COLUMN roles;
+`,
+		},
+	}
+)
@@ -0,0 +1,155 @@
+package slashcommands
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_command"
+}
+
+func (r repository) tableResponder() string {
+	return "crust_messaging_command_responder"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"name",
+			"description",
+			"url",
+			"secret",
+			"enabled",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) FindByID(commandID uint64) (*Command, error) {
+	return r.findOneBy(squirrel.Eq{"id": commandID})
+}
+
+func (r repository) FindByName(name string) (*Command, error) {
+	return r.findOneBy(squirrel.Eq{"name": name})
+}
+
+func (r repository) findOneBy(cnd squirrel.Sqlizer) (*Command, error) {
+	var c = &Command{}
+
+	if err := rh.FetchOne(r.db(), r.query().Where(cnd), c); err != nil {
+		return nil, err
+	} else if c.ID == 0 {
+		return nil, ErrCommandNotFound.withStack()
+	}
+
+	return c, nil
+}
+
+func (r repository) Find() (set CommandSet, err error) {
+	return set, rh.FetchAll(r.db(), r.query().OrderBy("name"), &set)
+}
+
+func (r repository) Create(c *Command) (*Command, error) {
+	c.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&c.CreatedAt)
+
+	return c, errors.WithStack(r.db().Insert(r.table(), c))
+}
+
+func (r repository) Update(c *Command) (*Command, error) {
+	rh.SetCurrentTimeRounded(&c.UpdatedAt)
+
+	return c, errors.WithStack(r.db().Update(r.table(), c, "id"))
+}
+
+// Delete removes the command; its pending responses are not accepted anymore
+func (r repository) Delete(commandID uint64) error {
+	return r.db().Transaction(func() error {
+		err := rh.UpdateColumns(r.db(), r.table(), rh.Set{"deleted_at": time.Now()}, squirrel.Eq{"id": commandID})
+		if err != nil {
+			return err
+		}
+
+		return rh.Delete(r.db(), r.tableResponder(), squirrel.Eq{"rel_command": commandID})
+	})
+}
+
+func (r repository) CreateResponder(rsp *responder) error {
+	return errors.WithStack(r.db().Insert(r.tableResponder(), rsp))
+}
+
+// UseResponder returns responder with the token and counts the response;
+// responders that expired or were used up are not returned
+func (r repository) UseResponder(hash string, now time.Time) (*responder, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.tableResponder()+" SET responses = responses + 1 WHERE token_hash = ? AND expires_at > ? AND responses < ?",
+		hash, now, maxResponses,
+	)
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return nil, errors.WithStack(err)
+	} else if n == 0 {
+		return nil, ErrResponderNotFound.withStack()
+	}
+
+	var (
+		rsp = &responder{}
+		q   = squirrel.
+			Select("token_hash", "rel_command", "rel_channel", "reply_to", "rel_user", "responses", "expires_at").
+			From(r.tableResponder()).
+			Where(squirrel.Eq{"token_hash": hash})
+	)
+
+	return rsp, rh.FetchOne(r.db(), q, rsp)
+}
+
+// ReleaseResponder uncounts the response that could not be delivered
+func (r repository) ReleaseResponder(hash string) error {
+	_, err := r.db().Exec(
+		"UPDATE "+r.tableResponder()+" SET responses = responses - 1 WHERE token_hash = ? AND responses > 0",
+		hash,
+	)
+
+	return errors.WithStack(err)
+}
+
+// PruneResponders removes expired responders
+func (r repository) PruneResponders(now time.Time) error {
+	return rh.Delete(r.db(), r.tableResponder(), squirrel.Lt{"expires_at": now})
+}
@@ -0,0 +1,64 @@
+package slashcommands
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts endpoints of slash commands
+//
+// External commands post delayed responses without signing in, with
+// the token they got with the invocation.
+func MountRoutes(r chi.Router) {
+	r.Post("/responses/{token}", rest.Handler("Command.Respond", func(r *http.Request) (interface{}, error) {
+		rsp := &Response{}
+
+		r.Body = ioutil.NopCloser(io.LimitReader(r.Body, maxResponseSize))
+		if err := rest.Decode(r, rsp); err != nil {
+			return nil, err
+		}
+
+		return resputil.OK(), DefaultCommand.With(r.Context()).Respond(chi.URLParam(r, "token"), rsp)
+	}))
+
+	r.Group(func(r chi.Router) {
+		r.Use(auth.MiddlewareValidOnly)
+
+		r.Get("/", rest.Handler("Command.Available", func(r *http.Request) (interface{}, error) {
+			return DefaultCommand.With(r.Context()).Available()
+		}))
+
+		r.Get("/external", rest.Handler("Command.List", func(r *http.Request) (interface{}, error) {
+			return DefaultCommand.With(r.Context()).Find()
+		}))
+
+		r.Post("/external", rest.Handler("Command.Create", func(r *http.Request) (interface{}, error) {
+			in := CommandInput{}
+			if err := rest.Decode(r, &in); err != nil {
+				return nil, err
+			}
+
+			return DefaultCommand.With(r.Context()).Create(in)
+		}))
+
+		r.Put("/external/{commandID}", rest.Handler("Command.Update", func(r *http.Request) (interface{}, error) {
+			in := CommandInput{}
+			if err := rest.Decode(r, &in); err != nil {
+				return nil, err
+			}
+
+			return DefaultCommand.With(r.Context()).Update(rest.ParamUint64(r, "commandID"), in)
+		}))
+
+		r.Delete("/external/{commandID}", rest.Handler("Command.Delete", func(r *http.Request) (interface{}, error) {
+			return resputil.OK(), DefaultCommand.With(r.Context()).DeleteByID(rest.ParamUint64(r, "commandID"))
+		}))
+	})
+}
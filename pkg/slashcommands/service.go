@@ -0,0 +1,388 @@
+package slashcommands
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/permissions"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/live"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		// Base URL of messaging API, for response URLs of external commands
+		baseURL string
+
+		repository *repository
+	}
+
+	CommandService interface {
+		With(ctx context.Context) CommandService
+
+		Available() (messagingTypes.CommandSet, error)
+
+		Find() (CommandSet, error)
+		Create(in CommandInput) (*Command, error)
+		Update(commandID uint64, in CommandInput) (*Command, error)
+		DeleteByID(commandID uint64) error
+
+		Respond(token string, rsp *Response) error
+	}
+)
+
+var (
+	DefaultCommand CommandService
+
+	defaultCommand *service
+
+	// now is used for reminders and expiration of responders and can be overridden
+	now = time.Now
+)
+
+// Init initializes slash commands
+//
+// Messages that start with a command are posted as they are, responses
+// are delivered in the background. GIPHY_API_KEY enables /giphy.
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	giphyKey = options.EnvString("", "GIPHY_API_KEY", "")
+
+	svc := &service{
+		logger:  log,
+		baseURL: strings.TrimSuffix(options.Corredor("messaging").ApiBaseURLMessaging, "/"),
+	}
+
+	DefaultCommand = svc.With(ctx)
+	defaultCommand = svc.with(ctx)
+
+	go defaultCommand.watch(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) CommandService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:     ctx,
+		logger:  svc.logger,
+		baseURL: svc.baseURL,
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Available returns commands that can be used, for autocompletion
+func (svc service) Available() (messagingTypes.CommandSet, error) {
+	set, err := svc.repository.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	out := messagingTypes.CommandSet{}
+	for name, b := range builtins {
+		if b.enabled() {
+			out = append(out, &messagingTypes.Command{Name: name, Description: b.description})
+		}
+	}
+
+	for _, c := range set {
+		if c.Enabled {
+			out = append(out, &messagingTypes.Command{Name: c.Name, Description: c.Description})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Find returns external commands; only admins can see them
+func (svc service) Find() (CommandSet, error) {
+	if !isAdmin(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	set, err := svc.repository.Find()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range set {
+		c.HasSecret = c.Secret != ""
+	}
+
+	return set, nil
+}
+
+func (svc service) Create(in CommandInput) (*Command, error) {
+	if !isAdmin(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	c := &Command{Enabled: true, CreatedBy: auth.GetIdentityFromContext(svc.ctx).Identity()}
+	if err := c.apply(in); err != nil {
+		return nil, err
+	}
+
+	if err := svc.unique(c); err != nil {
+		return nil, err
+	}
+
+	c, err := svc.repository.Create(c)
+	if err != nil {
+		return nil, err
+	}
+
+	c.HasSecret = c.Secret != ""
+
+	svc.log(zap.Uint64("commandID", c.ID), zap.String("name", c.Name)).Info("command created")
+	return c, nil
+}
+
+func (svc service) Update(commandID uint64, in CommandInput) (*Command, error) {
+	if !isAdmin(svc.ctx) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	c, err := svc.repository.FindByID(commandID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.apply(in); err != nil {
+		return nil, err
+	}
+
+	if err = svc.unique(c); err != nil {
+		return nil, err
+	}
+
+	if c, err = svc.repository.Update(c); err != nil {
+		return nil, err
+	}
+
+	c.HasSecret = c.Secret != ""
+
+	svc.log(zap.Uint64("commandID", c.ID), zap.String("name", c.Name)).Info("command updated")
+	return c, nil
+}
+
+func (svc service) DeleteByID(commandID uint64) error {
+	if !isAdmin(svc.ctx) {
+		return ErrNoPermissions.withStack()
+	}
+
+	if _, err := svc.repository.FindByID(commandID); err != nil {
+		return err
+	}
+
+	if err := svc.repository.Delete(commandID); err != nil {
+		return err
+	}
+
+	svc.log(zap.Uint64("commandID", commandID)).Info("command deleted")
+	return nil
+}
+
+// Respond delivers the response of an external command after the invocation
+//
+// Response is not counted when the invoker can not be resolved because
+// system service (in another process) is unreachable; command can retry it.
+func (svc service) Respond(token string, rsp *Response) error {
+	if strings.TrimSpace(rsp.Text) == "" {
+		return ErrEmptyResponse.withStack()
+	}
+
+	h := hash(token)
+
+	r, err := svc.repository.UseResponder(h, now())
+	if err != nil {
+		return err
+	}
+
+	c, err := svc.repository.FindByID(r.CommandID)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := runas.Context(svc.ctx, r.UserID)
+	if fault.Is(err, runas.ErrUsersUnavailable) {
+		if rerr := svc.repository.ReleaseResponder(h); rerr != nil {
+			svc.log(zap.Uint64("commandID", c.ID)).Error("could not release responder", zap.Error(rerr))
+		}
+
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	return svc.with(ctx).respond(c.Name, r.ChannelID, r.ReplyTo, rsp)
+}
+
+// exists checks if there is an enabled command with the name
+func (svc service) exists(name string) bool {
+	if b, ok := builtins[name]; ok {
+		return b.enabled()
+	}
+
+	c, err := svc.repository.FindByName(name)
+	return err == nil && c.Enabled
+}
+
+// invoke runs the command of the message and delivers its response;
+// user is told when it fails
+func (svc service) invoke(name, text string, m *messagingTypes.Message) {
+	var (
+		log = svc.log(zap.String("command", name), zap.Uint64("messageID", m.ID))
+		rsp *Response
+		err error
+
+		inv = &Invocation{
+			Command:   name,
+			Text:      text,
+			ChannelID: m.ChannelID,
+			ReplyTo:   m.ReplyTo,
+			MessageID: m.ID,
+			UserID:    m.UserID,
+			Timestamp: now(),
+		}
+	)
+
+	if b, ok := builtins[name]; ok {
+		rsp, err = b.handle(svc, inv)
+	} else {
+		var c *Command
+		if c, err = svc.repository.FindByName(name); err == nil {
+			rsp, err = svc.call(c, inv)
+		}
+	}
+
+	if err != nil {
+		log.Warn("command failed", zap.Error(err))
+		rsp = &Response{Text: "/" + name + " failed, try again later", Ephemeral: true}
+	}
+
+	if rsp == nil {
+		return
+	}
+
+	if err = svc.respond(name, m.ChannelID, m.ReplyTo, rsp); err != nil {
+		log.Error("could not deliver command response", zap.Error(err))
+	}
+}
+
+// respond posts the response into the channel in the name of the current
+// user or publishes it only to them
+func (svc service) respond(name string, channelID, replyTo uint64, rsp *Response) error {
+	var (
+		userID = auth.GetIdentityFromContext(svc.ctx).Identity()
+		text   = strings.TrimSpace(rsp.Text)
+	)
+
+	if rr := []rune(text); len(rr) > maxTextLength {
+		text = string(rr[:maxTextLength])
+	}
+
+	if text == "" {
+		return nil
+	}
+
+	if rsp.Ephemeral {
+		e := &ResponseEvent{Command: name, ChannelID: channelID, ReplyTo: replyTo, Text: text}
+		live.Publish(&live.Event{Scope: live.UserScope(userID), Type: live.EventCommandResponse, Payload: e})
+		return nil
+	}
+
+	_, err := messagingService.DefaultMessage.With(svc.ctx).Create(&messagingTypes.Message{
+		ChannelID: channelID,
+		ReplyTo:   replyTo,
+		UserID:    userID,
+		Message:   text,
+		Meta:      &messagingTypes.MessageMeta{Username: "/" + name},
+	})
+
+	return err
+}
+
+// watch removes expired responders in intervals
+func (svc service) watch(ctx context.Context) {
+	t := time.NewTicker(pruneInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := Repository(ctx, nil).PruneResponders(now()); err != nil {
+				svc.logger.Error("could not remove expired responders", zap.Error(err))
+			}
+		}
+	}
+}
+
+// unique checks that no other command has the name
+func (svc service) unique(c *Command) error {
+	if o, err := svc.repository.FindByName(c.Name); err == nil && o.ID != c.ID {
+		return ErrNameTaken.withStack()
+	}
+
+	return nil
+}
+
+func isAdmin(ctx context.Context) bool {
+	i := auth.GetIdentityFromContext(ctx)
+	if auth.IsSuperUser(i) {
+		return true
+	}
+
+	for _, roleID := range i.Roles() {
+		if roleID == permissions.AdminsRoleID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func generateToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
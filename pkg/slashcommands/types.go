@@ -0,0 +1,157 @@
+package slashcommands
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type (
+	// Command is an external command, registered by admins
+	//
+	// Invocations are posted to the URL, signed with the secret when one
+	// is set; secret is never sent back.
+	Command struct {
+		ID          uint64 `json:"commandID,string" db:"id"`
+		Name        string `json:"name" db:"name"`
+		Description string `json:"description" db:"description"`
+		URL         string `json:"url" db:"url"`
+		Secret      string `json:"-" db:"secret"`
+		Enabled     bool   `json:"enabled" db:"enabled"`
+
+		HasSecret bool `json:"hasSecret" db:"-"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	CommandSet []*Command
+
+	// CommandInput holds settings of the command; secret is left as
+	// it is when not given and removed when empty
+	CommandInput struct {
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		URL         string  `json:"url"`
+		Secret      *string `json:"secret"`
+		Enabled     *bool   `json:"enabled"`
+	}
+
+	// Invocation is a message that starts with a command; it is posted
+	// to URLs of external commands
+	//
+	// Responses can be posted with the token for a while after the
+	// invocation, when the command needs more time.
+	Invocation struct {
+		Command       string    `json:"command"`
+		Text          string    `json:"text"`
+		ChannelID     uint64    `json:"channelID,string"`
+		ReplyTo       uint64    `json:"replyTo,string,omitempty"`
+		MessageID     uint64    `json:"messageID,string"`
+		UserID        uint64    `json:"userID,string"`
+		ResponseToken string    `json:"responseToken,omitempty"`
+		ResponseURL   string    `json:"responseURL,omitempty"`
+		Timestamp     time.Time `json:"timestamp"`
+	}
+
+	// Response is posted into the channel or, when ephemeral, shown only
+	// to the user that invoked the command
+	Response struct {
+		Text      string `json:"text"`
+		Ephemeral bool   `json:"ephemeral"`
+	}
+
+	// ResponseEvent is published to the user for ephemeral responses
+	ResponseEvent struct {
+		Command   string `json:"command"`
+		ChannelID uint64 `json:"channelID,string"`
+		ReplyTo   uint64 `json:"replyTo,string,omitempty"`
+		Text      string `json:"text"`
+	}
+
+	// responder lets external command respond after the invocation, in the
+	// name of the user that invoked it and with roles they have at the time
+	responder struct {
+		TokenHash string    `db:"token_hash"`
+		CommandID uint64    `db:"rel_command"`
+		ChannelID uint64    `db:"rel_channel"`
+		ReplyTo   uint64    `db:"reply_to"`
+		UserID    uint64    `db:"rel_user"`
+		Responses uint      `db:"responses"`
+		ExpiresAt time.Time `db:"expires_at"`
+	}
+)
+
+const (
+	// External commands have to respond in time, later responses are
+	// posted with the token
+	invokeTimeout = 5 * time.Second
+
+	responderTTL  = 30 * time.Minute
+	maxResponses  = 5
+	maxTextLength = 4000
+
+	// Response body is read up to the size
+	maxResponseSize = 64 << 10
+
+	pruneInterval = time.Hour
+)
+
+var (
+	validName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+	// Commands handled by clients
+	reserved = map[string]bool{
+		"me":        true,
+		"shrug":     true,
+		"tableflip": true,
+		"unflip":    true,
+	}
+)
+
+// parse returns name of the command and its text when the message
+// starts with one
+func parse(message string) (name, text string, ok bool) {
+	if !strings.HasPrefix(message, "/") {
+		return "", "", false
+	}
+
+	name = message[1:]
+	if i := strings.IndexAny(name, " \t\n"); i > -1 {
+		name, text = name[:i], strings.TrimSpace(name[i:])
+	}
+
+	if !validName.MatchString(name) {
+		return "", "", false
+	}
+
+	return name, text, true
+}
+
+// apply validates and sets command's settings
+func (c *Command) apply(in CommandInput) error {
+	if !validName.MatchString(in.Name) || reserved[in.Name] || builtins[in.Name] != nil {
+		return ErrInvalidName.withStack()
+	}
+
+	if u, err := url.Parse(in.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL.withStack()
+	}
+
+	c.Name = in.Name
+	c.Description = strings.TrimSpace(in.Description)
+	c.URL = in.URL
+
+	if in.Secret != nil {
+		c.Secret = *in.Secret
+	}
+
+	if in.Enabled != nil {
+		c.Enabled = *in.Enabled
+	}
+
+	return nil
+}
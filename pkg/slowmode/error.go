@@ -0,0 +1,34 @@
+package slowmode
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	slowmodeError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrCooldownLimitReached = slowmodeError{"CooldownLimitReached", fault.TooMany}
+	ErrInvalidInterval      = slowmodeError{"InvalidInterval", fault.Invalid}
+	ErrNoPermissions        = slowmodeError{"NoPermissions", fault.Forbidden}
+)
+
+func (e slowmodeError) Error() string {
+	return e.String()
+}
+
+func (e slowmodeError) String() string {
+	return "crust.slowmode." + e.name
+}
+
+func (e slowmodeError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e slowmodeError) withStack() *fault.Error {
+	return fault.New(e)
+}
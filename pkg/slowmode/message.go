@@ -0,0 +1,45 @@
+package slowmode
+
+import (
+	"context"
+	"io"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+)
+
+type (
+	// message wraps message service and enforces slow mode of channels
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with enforcing slow mode of channels
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	if err := defaultSlowMode.with(svc.ctx).check(m); err != nil {
+		return nil, err
+	}
+
+	return svc.MessageService.Create(m)
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	if err := defaultSlowMode.with(svc.ctx).check(m); err != nil {
+		return nil, err
+	}
+
+	return svc.MessageService.CreateWithAvatar(m, avatar)
+}
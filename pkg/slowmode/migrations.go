@@ -0,0 +1,24 @@
+package slowmode
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200225000000.slowmode",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_channel_slow_mode (
+  rel_channel      BIGINT UNSIGNED NOT NULL,
+  interval_sec     INT UNSIGNED    NOT NULL,
+  exempt_roles     TEXT            NOT NULL,
+  updated_by       BIGINT UNSIGNED NOT NULL,
+  updated_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (rel_channel)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
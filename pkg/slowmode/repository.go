@@ -0,0 +1,76 @@
+package slowmode
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_channel_slow_mode"
+}
+
+// FindByChannelID returns slow mode of the channel, nil when it is off
+func (r repository) FindByChannelID(channelID uint64) (*SlowMode, error) {
+	var (
+		s = &SlowMode{}
+		q = squirrel.
+			Select("rel_channel", "interval_sec", "exempt_roles", "updated_by", "updated_at").
+			From(r.table()).
+			Where(squirrel.Eq{"rel_channel": channelID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, s); err != nil {
+		return nil, err
+	} else if s.ChannelID == 0 {
+		return nil, nil
+	}
+
+	return s, nil
+}
+
+func (r repository) Set(s *SlowMode) error {
+	return errors.WithStack(r.db().Replace(r.table(), s))
+}
+
+func (r repository) Delete(channelID uint64) error {
+	_, err := r.db().Exec("DELETE FROM "+r.table()+" WHERE rel_channel = ?", channelID)
+	return errors.WithStack(err)
+}
+
+// LastPostAt returns when the user last posted to the channel, nil when never
+func (r repository) LastPostAt(channelID, userID uint64) (at *time.Time, err error) {
+	err = r.db().Get(&at,
+		"SELECT MAX(created_at) FROM messaging_message WHERE rel_channel = ? AND rel_user = ? AND deleted_at IS NULL",
+		channelID, userID,
+	)
+
+	return at, errors.WithStack(err)
+}
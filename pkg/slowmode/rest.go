@@ -0,0 +1,37 @@
+package slowmode
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts slow mode endpoints
+//
+// Expects to be mounted under a path with {channelID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Slow mode of the channel and seconds until current user can post again
+	r.Get("/", rest.Handler("SlowMode.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultSlowMode.With(r.Context()).Read(rest.ParamUint64(r, "channelID"))
+	}))
+
+	r.Put("/", rest.Handler("SlowMode.Set", func(r *http.Request) (interface{}, error) {
+		s := &SlowMode{}
+		if err := rest.Decode(r, s); err != nil {
+			return nil, err
+		}
+
+		s.ChannelID = rest.ParamUint64(r, "channelID")
+		return DefaultSlowMode.With(r.Context()).Set(s)
+	}))
+
+	r.Delete("/", rest.Handler("SlowMode.Disable", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultSlowMode.With(r.Context()).Disable(rest.ParamUint64(r, "channelID"))
+	}))
+}
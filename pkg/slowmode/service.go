@@ -0,0 +1,222 @@
+package slowmode
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	accessController interface {
+		CanUpdateChannel(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+
+		repository *repository
+	}
+
+	SlowModeService interface {
+		With(ctx context.Context) SlowModeService
+
+		Read(channelID uint64) (*SlowMode, error)
+		Set(*SlowMode) (*SlowMode, error)
+		Disable(channelID uint64) error
+	}
+)
+
+var (
+	DefaultSlowMode SlowModeService
+
+	// used by service decorators
+	defaultSlowMode *service
+
+	// now is used for cooldowns and can be overridden
+	now = time.Now
+)
+
+// Init initializes slow mode of channels and decorates message service
+// with enforcing it
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		channels: messagingService.DefaultChannel,
+	}
+
+	DefaultSlowMode = svc.With(ctx)
+	defaultSlowMode = svc.with(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) SlowModeService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Read returns slow mode of the channel, with the cooldown of current user
+func (svc service) Read(channelID uint64) (*SlowMode, error) {
+	ch, err := svc.channels.FindByID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := svc.repository.FindByChannelID(channelID)
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		return &SlowMode{ChannelID: channelID, ExemptRoles: roleIDs{}}, nil
+	}
+
+	if s.Exempt = svc.exempt(ch, s); !s.Exempt {
+		d, err := svc.cooldown(s)
+		if err != nil {
+			return nil, err
+		}
+
+		s.RetryAfter = uint((d + time.Second - 1) / time.Second)
+	}
+
+	return s, nil
+}
+
+// Set turns slow mode of the channel on (or off, with zero interval)
+//
+// Slow mode is set by those that can update the channel.
+func (svc service) Set(in *SlowMode) (*SlowMode, error) {
+	if _, err := svc.canManage(in.ChannelID); err != nil {
+		return nil, err
+	}
+
+	if in.Interval > uint(maxInterval/time.Second) {
+		return nil, ErrInvalidInterval.withStack()
+	}
+
+	if in.Interval == 0 {
+		return &SlowMode{ChannelID: in.ChannelID, ExemptRoles: roleIDs{}}, svc.Disable(in.ChannelID)
+	}
+
+	t := now().Truncate(time.Second)
+	s := &SlowMode{
+		ChannelID:   in.ChannelID,
+		Interval:    in.Interval,
+		ExemptRoles: in.ExemptRoles,
+		UpdatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+		UpdatedAt:   &t,
+	}
+
+	if s.ExemptRoles == nil {
+		s.ExemptRoles = roleIDs{}
+	}
+
+	if err := svc.repository.Set(s); err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("channelID", s.ChannelID), zap.Uint("interval", s.Interval)).Info("slow mode set")
+	return s, nil
+}
+
+func (svc service) Disable(channelID uint64) error {
+	if _, err := svc.canManage(channelID); err != nil {
+		return err
+	}
+
+	return svc.repository.Delete(channelID)
+}
+
+// check rejects the message when current user posted to the channel too recently
+func (svc service) check(m *messagingTypes.Message) error {
+	s, err := svc.repository.FindByChannelID(m.ChannelID)
+	if err != nil || s == nil {
+		return err
+	}
+
+	ch, err := svc.channels.FindByID(m.ChannelID)
+	if err != nil {
+		// Reported by the message service
+		return nil
+	}
+
+	if svc.exempt(ch, s) {
+		return nil
+	}
+
+	d, err := svc.cooldown(s)
+	if err != nil {
+		return err
+	} else if d > 0 {
+		return ErrCooldownLimitReached.withStack().
+			WithID("channelID", m.ChannelID).
+			WithMessage("channel is in slow mode, you can post again in " + d.Round(time.Second).String()).
+			WithRetryAfter(d)
+	}
+
+	return nil
+}
+
+// cooldown returns how long current user has to wait before posting again
+func (svc service) cooldown(s *SlowMode) (time.Duration, error) {
+	at, err := svc.repository.LastPostAt(s.ChannelID, auth.GetIdentityFromContext(svc.ctx).Identity())
+	if err != nil || at == nil {
+		return 0, err
+	}
+
+	if d := at.Add(time.Duration(s.Interval) * time.Second).Sub(now()); d > 0 {
+		return d, nil
+	}
+
+	return 0, nil
+}
+
+// exempt checks if current user is not limited by slow mode of the channel
+func (svc service) exempt(ch *messagingTypes.Channel, s *SlowMode) bool {
+	i := auth.GetIdentityFromContext(svc.ctx)
+	return auth.IsSuperUser(i) || s.ExemptRoles.exempts(i.Roles()) || svc.ac.CanUpdateChannel(svc.ctx, ch)
+}
+
+func (svc service) canManage(channelID uint64) (*messagingTypes.Channel, error) {
+	ch, err := svc.channels.FindByID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanUpdateChannel(svc.ctx, ch) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return ch, nil
+}
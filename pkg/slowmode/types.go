@@ -0,0 +1,92 @@
+package slowmode
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+)
+
+type (
+	// SlowMode limits members of the channel to one message per interval
+	SlowMode struct {
+		ChannelID uint64 `json:"channelID,string" db:"rel_channel"`
+
+		// Seconds between messages of the same user, 0 when slow mode is off
+		Interval uint `json:"interval" db:"interval_sec"`
+
+		// Members of these roles are not limited, nor are those that can
+		// update the channel
+		ExemptRoles roleIDs `json:"exemptRoles" db:"exempt_roles"`
+
+		UpdatedBy uint64     `json:"updatedBy,string,omitempty" db:"updated_by"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+
+		// Seconds until current user can post again
+		RetryAfter uint `json:"retryAfter" db:"-"`
+
+		// Current user is not limited
+		Exempt bool `json:"exempt" db:"-"`
+	}
+
+	roleIDs []uint64
+)
+
+const (
+	// Longest interval that can be set
+	maxInterval = 6 * time.Hour
+)
+
+// exempts checks if any of the roles is exempt
+func (ids roleIDs) exempts(rr []uint64) bool {
+	for _, e := range ids {
+		for _, r := range rr {
+			if e == r {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (ids roleIDs) MarshalJSON() ([]byte, error) {
+	return json.Marshal(payload.Uint64stoa(ids))
+}
+
+func (ids *roleIDs) UnmarshalJSON(data []byte) error {
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return err
+	}
+
+	*ids = payload.ParseUInt64s(ss)
+	return nil
+}
+
+func (ids roleIDs) Value() (driver.Value, error) {
+	if ids == nil {
+		ids = roleIDs{}
+	}
+
+	return json.Marshal([]uint64(ids))
+}
+
+func (ids *roleIDs) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ids = roleIDs{}
+	case []byte:
+		var rr []uint64
+		if err := json.Unmarshal(b, &rr); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into roleIDs", string(b))
+		}
+
+		*ids = rr
+	}
+
+	return nil
+}
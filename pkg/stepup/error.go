@@ -0,0 +1,37 @@
+package stepup
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	stepupError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrStepUpRequired  = stepupError{"StepUpRequired", fault.Forbidden}
+	ErrInvalidPassword = stepupError{"InvalidPassword", fault.Invalid}
+	ErrInvalidCode     = stepupError{"InvalidCode", fault.Invalid}
+	ErrCodeExpired     = stepupError{"CodeExpired", fault.Invalid}
+	ErrTokenRequired   = stepupError{"TokenRequired", fault.Forbidden}
+	ErrTooManyCodes    = stepupError{"TooManyCodes", fault.TooMany}
+)
+
+func (e stepupError) Error() string {
+	return e.String()
+}
+
+func (e stepupError) String() string {
+	return "crust.stepup." + e.name
+}
+
+func (e stepupError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e stepupError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,22 @@
+package stepup
+
+import (
+	"net/http"
+
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// Middleware rejects requests to protected routes from sessions that did
+// not confirm identity recently enough
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if DefaultStepUp != nil {
+			if err := DefaultStepUp.With(r.Context()).Check(r); err != nil {
+				rest.Error(w, r, err)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
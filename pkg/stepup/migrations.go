@@ -0,0 +1,34 @@
+package stepup
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200130000000.stepup",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_stepup (
+  token_hash       CHAR(64)        NOT NULL,
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  confirmed_at     DATETIME            NULL DEFAULT NULL,
+  code_hash        CHAR(64)        NOT NULL DEFAULT '',
+  code_expires_at  DATETIME            NULL DEFAULT NULL,
+  attempts         INT UNSIGNED    NOT NULL DEFAULT 0,
+  expires_at       DATETIME        NOT NULL,
+
+  PRIMARY KEY (token_hash),
+  INDEX (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+		{
+			Name: "20200313000000.stepup-codes-sent",
+			Up: `
+ALTER TABLE crust_stepup
+  ADD COLUMN codes_sent INT UNSIGNED NOT NULL DEFAULT 0 AFTER code_expires_at;
+`,
+		},
+	}
+)
@@ -0,0 +1,64 @@
+package stepup
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_stepup"
+}
+
+// Find returns step-up state of the token (nil if there is none)
+func (r repository) Find(tokenHash string) (*Confirmation, error) {
+	var (
+		c = &Confirmation{}
+		q = squirrel.
+			Select("token_hash", "rel_user", "confirmed_at", "code_hash", "code_expires_at", "codes_sent", "attempts", "expires_at").
+			From(r.table()).
+			Where(squirrel.Eq{"token_hash": tokenHash})
+	)
+
+	if err := rh.FetchOne(r.db(), q, c); err != nil || c.TokenHash == "" {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (r repository) Save(c *Confirmation) (*Confirmation, error) {
+	return c, errors.WithStack(r.db().Replace(r.table(), c))
+}
+
+// Prune removes states of expired tokens
+func (r repository) Prune(now time.Time) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Lt{"expires_at": now})
+}
@@ -0,0 +1,48 @@
+package stepup
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts endpoints for confirming identity of the current session
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("StepUp.Status", func(r *http.Request) (interface{}, error) {
+		return DefaultStepUp.With(r.Context()).Status(r)
+	}))
+
+	r.Post("/password", rest.Handler("StepUp.ConfirmPassword", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Password string `json:"password" validate:"required"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultStepUp.With(r.Context()).ConfirmPassword(r, body.Password)
+	}))
+
+	r.Post("/code", rest.Handler("StepUp.SendCode", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultStepUp.With(r.Context()).SendCode(r)
+	}))
+
+	r.Post("/code/confirm", rest.Handler("StepUp.ConfirmCode", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Code string `json:"code" validate:"required"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultStepUp.With(r.Context()).ConfirmCode(r, body.Code)
+	}))
+}
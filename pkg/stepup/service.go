@@ -0,0 +1,339 @@
+package stepup
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/mail"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	"github.com/cortezaproject/corteza-server/system/service"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/rest"
+	"github.com/crusttech/crust-server/pkg/seclog"
+)
+
+type (
+	stepupService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		settings settingsGetter
+
+		repository *repository
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	StepUpService interface {
+		With(ctx context.Context) StepUpService
+
+		Status(r *http.Request) (*Confirmation, error)
+		ConfirmPassword(r *http.Request, password string) (*Confirmation, error)
+		SendCode(r *http.Request) error
+		ConfirmCode(r *http.Request, code string) (*Confirmation, error)
+
+		Check(r *http.Request) error
+	}
+
+	// policyCache holds policy, so that settings are not loaded on every request
+	policyCache struct {
+		sync.RWMutex
+		policy Policy
+	}
+)
+
+var (
+	DefaultStepUp StepUpService
+
+	current = &policyCache{}
+
+	// now is used for confirmations and can be overridden
+	now = time.Now
+)
+
+// Init initializes step-up service and starts reloading policy in the background
+//
+// Must be called after security event log is initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &stepupService{
+		logger:   log,
+		settings: service.DefaultSettings,
+	}
+
+	DefaultStepUp = svc.With(ctx)
+
+	svc.refresh(ctx)
+	go svc.watch(ctx)
+
+	return nil
+}
+
+func (svc stepupService) With(ctx context.Context) StepUpService {
+	return &stepupService{
+		ctx:      ctx,
+		logger:   svc.logger,
+		settings: svc.settings,
+
+		repository: Repository(ctx, factory.Database.MustGet("system").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc stepupService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Status returns step-up state of the current session
+func (svc stepupService) Status(r *http.Request) (*Confirmation, error) {
+	return svc.state(r)
+}
+
+// ConfirmPassword confirms identity of the current user with password
+func (svc stepupService) ConfirmPassword(r *http.Request, password string) (*Confirmation, error) {
+	c, err := svc.state(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := auth.SetSuperUserContext(svc.ctx)
+
+	u, err := service.DefaultUser.With(ctx).FindByID(c.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = service.DefaultAuth.With(ctx).InternalLogin(u.Email, password); err != nil {
+		svc.record(r, c, "password", "failed")
+		return nil, ErrInvalidPassword.withStack()
+	}
+
+	return svc.confirm(r, c, "password")
+}
+
+// SendCode sends confirmation code to current user's email
+//
+// For users that do not have a password (external authentication);
+// only a few codes are sent until identity is confirmed.
+func (svc stepupService) SendCode(r *http.Request) error {
+	c, err := svc.state(r)
+	if err != nil {
+		return err
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return err
+	}
+
+	if err = c.issue(code, now()); err != nil {
+		return err
+	}
+
+	if _, err = svc.repository.Save(c); err != nil {
+		return err
+	}
+
+	u, err := service.DefaultUser.With(auth.SetSuperUserContext(svc.ctx)).FindByID(c.UserID)
+	if err != nil {
+		return err
+	}
+
+	m := mail.New()
+	m.SetAddressHeader("To", u.Email, u.Name)
+	m.SetHeader("Subject", "Confirmation code")
+	m.SetBody("text/plain", fmt.Sprintf(
+		"Your confirmation code is %s\n\nIt expires in %d minutes.\n\n"+
+			"If you did not request it, change your password.\n",
+		code, int(codeTTL.Minutes()),
+	))
+
+	return mail.Send(m)
+}
+
+// ConfirmCode confirms identity of the current user with the code sent by email
+func (svc stepupService) ConfirmCode(r *http.Request, code string) (*Confirmation, error) {
+	c, err := svc.state(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.verify(code, now()); fault.Is(err, ErrInvalidCode) {
+		if _, serr := svc.repository.Save(c); serr != nil {
+			return nil, serr
+		}
+
+		svc.record(r, c, "code", "failed")
+		return nil, err
+	} else if err != nil {
+		return nil, err
+	}
+
+	return svc.confirm(r, c, "code")
+}
+
+// Check verifies that request to a protected route comes from a recently confirmed session
+//
+// Requests without token are left to the route's own authentication.
+func (svc stepupService) Check(r *http.Request) error {
+	current.RLock()
+	maxAge, ok := current.policy.match(r)
+	current.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	token := rest.Token(r)
+	if token == "" {
+		return nil
+	}
+
+	c, err := svc.repository.Find(hash(token))
+	if err != nil {
+		return err
+	}
+
+	if c.fresh(now(), maxAge) {
+		return nil
+	}
+
+	e := seclog.FromRequest(r, eventKind, "required")
+	e.Outcome = "blocked"
+	e.Meta = seclog.Meta{"method": r.Method, "path": r.URL.Path}
+
+	if identity, err := auth.DefaultJwtHandler.Decode(token); err == nil {
+		e.UserID = identity.Identity()
+	}
+
+	if err = seclog.DefaultSecLog.With(svc.ctx).Record(e); err != nil {
+		svc.log().Error("could not record security event", zap.Error(err))
+	}
+
+	return ErrStepUpRequired.withStack()
+}
+
+// state returns (new or stored) step-up state of the current session
+func (svc stepupService) state(r *http.Request) (*Confirmation, error) {
+	token := rest.Token(r)
+	if token == "" {
+		return nil, ErrTokenRequired.withStack()
+	}
+
+	c, err := svc.repository.Find(hash(token))
+	if err != nil || c != nil {
+		return c, err
+	}
+
+	return &Confirmation{
+		TokenHash: hash(token),
+		UserID:    auth.GetIdentityFromContext(svc.ctx).Identity(),
+		ExpiresAt: expiry(token),
+	}, nil
+}
+
+func (svc stepupService) confirm(r *http.Request, c *Confirmation, method string) (*Confirmation, error) {
+	n := now().Truncate(time.Second)
+	c.ConfirmedAt, c.CodesSent, c.Attempts = &n, 0, 0
+
+	c, err := svc.repository.Save(c)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.record(r, c, method, "confirmed")
+	return c, nil
+}
+
+func (svc stepupService) record(r *http.Request, c *Confirmation, method, outcome string) {
+	e := seclog.FromRequest(r, eventKind, "confirm")
+	e.UserID, e.Outcome, e.Meta = c.UserID, outcome, seclog.Meta{"method": method}
+
+	if err := seclog.DefaultSecLog.With(svc.ctx).Record(e); err != nil {
+		svc.log().Error("could not record security event", zap.Error(err))
+	}
+}
+
+// refresh reloads policy from system settings
+func (svc stepupService) refresh(ctx context.Context) {
+	var p Policy
+
+	v, err := svc.settings.Get(auth.SetSuperUserContext(ctx), settingPolicy, 0)
+	if err != nil {
+		svc.logger.Error("could not load step-up policy", zap.Error(err))
+		return
+	} else if v != nil {
+		if err = v.Value.Unmarshal(&p); err != nil {
+			svc.logger.Error("could not decode step-up policy", zap.Error(err))
+			return
+		}
+	}
+
+	current.Lock()
+	current.policy = p
+	current.Unlock()
+}
+
+func (svc stepupService) watch(ctx context.Context) {
+	t := time.NewTicker(refreshInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.refresh(ctx)
+
+			if err := Repository(ctx, factory.Database.MustGet("system").With(ctx)).Prune(now()); err != nil {
+				svc.logger.Error("could not remove expired step-up states", zap.Error(err))
+			}
+		}
+	}
+}
+
+// expiry returns expiration time of the token
+func expiry(token string) time.Time {
+	c := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, c); err == nil {
+		if exp, ok := c["exp"].(float64); ok {
+			return time.Unix(int64(exp), 0)
+		}
+	}
+
+	return now().Add(24 * time.Hour)
+}
+
+func generateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", codeLength, n), nil
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,191 @@
+package stepup
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+type (
+	// Confirmation is a step-up state of one session (token)
+	Confirmation struct {
+		TokenHash string `json:"-" db:"token_hash"`
+		UserID    uint64 `json:"userID,string" db:"rel_user"`
+
+		// When user last confirmed identity
+		ConfirmedAt *time.Time `json:"confirmedAt,omitempty" db:"confirmed_at"`
+
+		// Code sent by email, for users without a password; codes sent and
+		// failed attempts are counted until identity is confirmed
+		CodeHash      string     `json:"-" db:"code_hash"`
+		CodeExpiresAt *time.Time `json:"-" db:"code_expires_at"`
+		CodesSent     uint       `json:"-" db:"codes_sent"`
+		Attempts      uint       `json:"-" db:"attempts"`
+
+		// Expiration of the token; state is removed afterwards
+		ExpiresAt time.Time `json:"-" db:"expires_at"`
+	}
+
+	// Policy is stored in system settings, under settingPolicy
+	//
+	// Example:
+	//   {
+	//     "enabled": true,
+	//     "maxAge": 15,
+	//     "routes": [
+	//       { "method": "DELETE", "path": "/users/*" },
+	//       { "method": "GET", "path": "/namespace/*/module/*/record/export*", "maxAge": 5 }
+	//     ]
+	//   }
+	//
+	// When no routes are given, defaultRoutes are protected.
+	Policy struct {
+		Enabled bool `json:"enabled"`
+
+		// How long (in minutes) confirmation is valid
+		MaxAge uint `json:"maxAge"`
+
+		Routes []*Route `json:"routes"`
+	}
+
+	// Route is a protected API endpoint
+	//
+	// Path is matched against the end of the request path, segment by
+	// segment (see path.Match), so it works with and without app's prefix.
+	Route struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+
+		// Overrides policy's max age
+		MaxAge uint `json:"maxAge,omitempty"`
+	}
+)
+
+const (
+	settingPolicy = "crust.stepup"
+
+	defaultMaxAge = 15
+
+	// Kind of security log events
+	eventKind = "stepup"
+
+	codeLength      = 6
+	codeTTL         = 10 * time.Minute
+	maxCodesSent    = 3
+	maxCodeAttempts = 5
+
+	// How often policy is reloaded and expired states removed
+	refreshInterval = time.Minute
+)
+
+var (
+	// Permission changes, user deletion and data export
+	defaultRoutes = []*Route{
+		{Method: http.MethodPatch, Path: "/permissions/*/rules"},
+		{Method: http.MethodDelete, Path: "/permissions/*/rules"},
+		{Method: http.MethodPost, Path: "/permissions/history/rollback"},
+		{Method: http.MethodPost, Path: "/roles/*/member/*"},
+		{Method: http.MethodDelete, Path: "/roles/*/member/*"},
+		{Method: http.MethodPost, Path: "/roles/*/members/bulk"},
+		{Method: http.MethodPut, Path: "/roles/*/children/*"},
+		{Method: http.MethodDelete, Path: "/roles/*/children/*"},
+		{Method: http.MethodPut, Path: "/roles/*/expiring-members/*"},
+		{Method: http.MethodDelete, Path: "/roles/*/expiring-members/*"},
+		{Method: http.MethodDelete, Path: "/roles/*"},
+		{Method: http.MethodPost, Path: "/users/*/membership/*"},
+		{Method: http.MethodDelete, Path: "/users/*/membership/*"},
+		{Method: http.MethodPost, Path: "/users/*/password"},
+		{Method: http.MethodDelete, Path: "/users/*"},
+		{Method: http.MethodGet, Path: "/namespace/*/module/*/record/export*"},
+	}
+)
+
+// match returns max age of confirmation required by the route that matches the request
+func (p Policy) match(r *http.Request) (time.Duration, bool) {
+	if !p.Enabled {
+		return 0, false
+	}
+
+	rr := p.Routes
+	if len(rr) == 0 {
+		rr = defaultRoutes
+	}
+
+	for _, rt := range rr {
+		if !strings.EqualFold(rt.Method, r.Method) || !rt.matches(r.URL.Path) {
+			continue
+		}
+
+		maxAge := rt.MaxAge
+		if maxAge == 0 {
+			maxAge = p.MaxAge
+		}
+
+		if maxAge == 0 {
+			maxAge = defaultMaxAge
+		}
+
+		return time.Duration(maxAge) * time.Minute, true
+	}
+
+	return 0, false
+}
+
+// matches compares the last segments of the path with route's path
+func (rt Route) matches(p string) bool {
+	var (
+		pattern = strings.Split(strings.Trim(rt.Path, "/"), "/")
+		segs    = strings.Split(strings.Trim(p, "/"), "/")
+	)
+
+	if len(segs) < len(pattern) {
+		return false
+	}
+
+	segs = segs[len(segs)-len(pattern):]
+	for i := range pattern {
+		if ok, _ := path.Match(pattern[i], segs[i]); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fresh checks if identity was confirmed within max age
+func (c *Confirmation) fresh(now time.Time, maxAge time.Duration) bool {
+	return c != nil && c.ConfirmedAt != nil && c.ConfirmedAt.Add(maxAge).After(now)
+}
+
+// issue stores hash of the new code, unless too many codes were sent already
+func (c *Confirmation) issue(code string, now time.Time) error {
+	if c.CodesSent >= maxCodesSent {
+		return ErrTooManyCodes.withStack()
+	}
+
+	exp := now.Add(codeTTL).Truncate(time.Second)
+	c.CodeHash, c.CodeExpiresAt = hash(c.TokenHash+code), &exp
+	c.CodesSent++
+
+	return nil
+}
+
+// verify compares the code with the one that was sent
+//
+// Failed attempts are counted across all codes, sending a new code does
+// not give more of them.
+func (c *Confirmation) verify(code string, now time.Time) error {
+	if c.CodeHash == "" || c.CodeExpiresAt == nil || c.CodeExpiresAt.Before(now) || c.Attempts >= maxCodeAttempts {
+		return ErrCodeExpired.withStack()
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.CodeHash), []byte(hash(c.TokenHash+code))) != 1 {
+		c.Attempts++
+		return ErrInvalidCode.withStack()
+	}
+
+	c.CodeHash, c.CodeExpiresAt = "", nil
+	return nil
+}
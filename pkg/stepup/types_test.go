@@ -0,0 +1,150 @@
+package stepup
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+func TestConfirmationVerify(t *testing.T) {
+	var (
+		sent = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	tests := []struct {
+		name     string
+		code     string
+		at       time.Time
+		attempts uint
+		err      error
+		left     uint
+	}{
+		{"valid code", "123456", sent.Add(time.Minute), 0, nil, 0},
+		{"valid code after failed attempts", "123456", sent.Add(time.Minute), maxCodeAttempts - 1, nil, maxCodeAttempts - 1},
+		{"invalid code", "654321", sent.Add(time.Minute), 0, ErrInvalidCode, 1},
+		{"last attempt", "654321", sent.Add(time.Minute), maxCodeAttempts - 1, ErrInvalidCode, maxCodeAttempts},
+		{"no attempts left", "123456", sent.Add(time.Minute), maxCodeAttempts, ErrCodeExpired, maxCodeAttempts},
+		{"at expiration", "123456", sent.Add(codeTTL), 0, nil, 0},
+		{"expired", "123456", sent.Add(codeTTL + time.Second), 0, ErrCodeExpired, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Confirmation{TokenHash: hash("token")}
+			if err := c.issue("123456", sent); err != nil {
+				t.Fatalf("could not issue code: %v", err)
+			}
+
+			c.Attempts = tt.attempts
+
+			err := c.verify(tt.code, tt.at)
+			if tt.err == nil && err != nil {
+				t.Fatalf("expected code to be valid, got %v", err)
+			} else if tt.err != nil && !fault.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			if c.Attempts != tt.left {
+				t.Errorf("expected %d failed attempts, got %d", tt.left, c.Attempts)
+			}
+
+			if tt.err == nil && c.CodeHash != "" {
+				t.Errorf("expected code to be used")
+			}
+		})
+	}
+}
+
+func TestConfirmationVerifyWithoutCode(t *testing.T) {
+	c := &Confirmation{TokenHash: hash("token")}
+	if err := c.verify("", time.Now()); !fault.Is(err, ErrCodeExpired) {
+		t.Fatalf("expected %v, got %v", ErrCodeExpired, err)
+	}
+}
+
+func TestConfirmationIssue(t *testing.T) {
+	var (
+		sent = time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	tests := []struct {
+		name     string
+		sent     uint
+		attempts uint
+		err      error
+	}{
+		{"first code", 0, 0, nil},
+		{"resent code", 1, 2, nil},
+		{"last code", maxCodesSent - 1, 0, nil},
+		{"too many codes", maxCodesSent, 0, ErrTooManyCodes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Confirmation{TokenHash: hash("token"), CodesSent: tt.sent, Attempts: tt.attempts}
+
+			err := c.issue("123456", sent)
+			if tt.err != nil {
+				if !fault.Is(err, tt.err) {
+					t.Fatalf("expected %v, got %v", tt.err, err)
+				}
+
+				if c.CodesSent != tt.sent || c.CodeHash != "" {
+					t.Errorf("expected code not to be issued")
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("could not issue code: %v", err)
+			}
+
+			if c.CodesSent != tt.sent+1 {
+				t.Errorf("expected %d codes sent, got %d", tt.sent+1, c.CodesSent)
+			}
+
+			if c.Attempts != tt.attempts {
+				t.Errorf("expected failed attempts to be kept, got %d", c.Attempts)
+			}
+
+			if c.CodeExpiresAt == nil || !c.CodeExpiresAt.Equal(sent.Add(codeTTL)) {
+				t.Errorf("expected code to expire at %v, got %v", sent.Add(codeTTL), c.CodeExpiresAt)
+			}
+		})
+	}
+}
+
+func TestPolicyMatchDefaultRoutes(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{http.MethodPost, "/permissions/history/rollback", true},
+		{http.MethodPost, "/compose/permissions/history/rollback", true},
+		{http.MethodGet, "/permissions/history", false},
+		{http.MethodPost, "/roles/1/member/2", true},
+		{http.MethodPost, "/system/roles/1/member/2", true},
+		{http.MethodGet, "/roles/1/member/2", false},
+		{http.MethodPost, "/roles/1/members/bulk", true},
+		{http.MethodPost, "/system/roles/1/members/bulk/", true},
+		{http.MethodGet, "/roles/1/members", false},
+		{http.MethodPut, "/roles/1/children/2", true},
+		{http.MethodDelete, "/system/roles/1/children/2", true},
+		{http.MethodGet, "/roles/1/children/", false},
+		{http.MethodPut, "/roles/1/expiring-members/2", true},
+		{http.MethodDelete, "/system/roles/1/expiring-members/2", true},
+		{http.MethodGet, "/roles/1/expiring-members/", false},
+	}
+
+	p := Policy{Enabled: true}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if _, got := p.match(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+				t.Errorf("expected route to be protected: %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
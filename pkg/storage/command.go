@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/cli"
+)
+
+// Command moves attachment files from local filesystem to S3
+//
+// Apps that do not use S3 storage driver are skipped. Reports are written
+// to stdout as JSON, one per app. Command can be run while server is
+// running and repeated until no files are left:
+//
+//	STORAGE_DRIVER=s3 crust-server storage-migrate --remove
+func Command(ctx context.Context, c *cli.Config) *cobra.Command {
+	var (
+		remove bool
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:          "storage-migrate",
+		Short:        "Move attachment files from local filesystem to S3",
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				enc    = json.NewEncoder(cmd.OutOrStdout())
+				failed bool
+			)
+
+			enc.SetIndent("", "  ")
+
+			for _, app := range appNames {
+				// Standalone servers connect to their own database only
+				if _, err := factory.Database.Get(app); err != nil {
+					continue
+				}
+
+				if driver(app) != DriverS3 {
+					continue
+				}
+
+				rep, err := migrate(ctx, c.Log.Named("storage"), app, remove, dryRun)
+				if err != nil {
+					return err
+				}
+
+				if err = enc.Encode(rep); err != nil {
+					return err
+				}
+
+				failed = failed || rep.Failed > 0
+			}
+
+			if failed {
+				return errors.New("some files were not moved")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove local files after they are moved")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only count files that would be moved")
+
+	return cmd
+}
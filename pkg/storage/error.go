@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	storageError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrUnknownDriver     = storageError{"UnknownDriver", fault.Invalid}
+	ErrUnknownEncryption = storageError{"UnknownEncryption", fault.Invalid}
+	ErrInvalidBucket     = storageError{"InvalidBucket", fault.Invalid}
+	ErrInvalidPartSize   = storageError{"InvalidPartSize", fault.Invalid}
+	ErrInvalidName       = storageError{"InvalidName", fault.Invalid}
+	ErrBucketNotFound    = storageError{"BucketNotFound", fault.NotFound}
+	ErrNotConfigured     = storageError{"NotConfigured", fault.Unavailable}
+)
+
+func (e storageError) Error() string {
+	return e.String()
+}
+
+func (e storageError) String() string {
+	return "crust.storage." + e.name
+}
+
+func (e storageError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e storageError) withStack() *fault.Error {
+	return fault.New(e)
+}
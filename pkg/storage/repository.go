@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+		app string
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB, app string) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+		app: app,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet(r.app).With(r.ctx)
+}
+
+func (r repository) table() string {
+	return r.app + "_attachment"
+}
+
+// Local returns files of attachments, stored under the local path
+//
+// Deleted attachments are included, their files are still kept.
+func (r repository) Local(path string) (set fileSet, err error) {
+	var like = path + "/%"
+
+	q := squirrel.
+		Select("id", "url", "preview_url").
+		From(r.table()).
+		Where(squirrel.Or{
+			squirrel.Like{"url": like},
+			squirrel.Like{"preview_url": like},
+		}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// Move points attachment to the new location of its files
+func (r repository) Move(f *file) error {
+	_, err := r.db().Exec(
+		"UPDATE "+r.table()+" SET url = ?, preview_url = ? WHERE id = ?",
+		f.URL, f.PreviewURL, f.ID,
+	)
+
+	return errors.WithStack(err)
+}
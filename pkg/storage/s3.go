@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	minio "github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+	"github.com/pkg/errors"
+)
+
+type (
+	// s3Store keeps files as objects in S3 bucket
+	//
+	// Unlike corteza's minio store it supports all kinds of server-side
+	// encryption, keys with prefix and uploads of unknown size with
+	// bounded memory use.
+	s3Store struct {
+		mc *minio.Client
+
+		bucket   string
+		prefix   string
+		partSize uint64
+
+		sse encrypt.ServerSide
+	}
+)
+
+// S3 connects to the S3 service and makes sure the bucket exists
+func S3(opt S3Options) (*s3Store, error) {
+	var (
+		s = &s3Store{
+			bucket:   opt.Bucket,
+			prefix:   strings.Trim(opt.Prefix, "/"),
+			partSize: opt.PartSize,
+		}
+
+		err error
+	)
+
+	if err = s3utils.CheckValidBucketName(s.bucket); err != nil {
+		return nil, ErrInvalidBucket.withStack().WithMessage(err.Error())
+	}
+
+	if s.partSize < minPartSize {
+		return nil, ErrInvalidPartSize.withStack().WithMessage(fmt.Sprintf("part size must be at least %d bytes", minPartSize))
+	}
+
+	switch opt.Encryption {
+	case SSENone:
+	case SSES3:
+		s.sse = encrypt.NewSSE()
+	case SSEKMS:
+		if s.sse, err = encrypt.NewSSEKMS(opt.KMSKeyID, nil); err != nil {
+			return nil, ErrUnknownEncryption.withStack().WithMessage(err.Error())
+		}
+	case SSEC:
+		if s.sse, err = encrypt.NewSSEC(opt.CustomerKey); err != nil {
+			return nil, ErrUnknownEncryption.withStack().WithMessage(err.Error())
+		}
+	default:
+		return nil, ErrUnknownEncryption.withStack().WithMessage("unknown server-side encryption " + opt.Encryption)
+	}
+
+	if s.mc, err = minio.NewWithRegion(opt.Endpoint, opt.AccessKeyID, opt.SecretAccessKey, opt.Secure, opt.Region); err != nil {
+		return nil, errors.Wrap(err, "could not connect to S3")
+	}
+
+	if exists, err := s.mc.BucketExists(s.bucket); err != nil {
+		return nil, errors.Wrap(err, "could not check bucket")
+	} else if !exists {
+		if opt.Strict {
+			return nil, ErrBucketNotFound.withStack().WithMessage(fmt.Sprintf("bucket %q does not exist", s.bucket))
+		}
+
+		if err = s.mc.MakeBucket(s.bucket, opt.Region); err != nil {
+			return nil, errors.Wrap(err, "could not create bucket")
+		}
+	}
+
+	return s, nil
+}
+
+func (s s3Store) Original(id uint64, ext string) string {
+	return s.key(fmt.Sprintf("%d.%s", id, ext))
+}
+
+func (s s3Store) Preview(id uint64, ext string) string {
+	return s.key(fmt.Sprintf("%d_preview.%s", id, ext))
+}
+
+// Save streams file to the bucket
+//
+// Size of the file is not known, so it is uploaded in parts
+func (s s3Store) Save(name string, f io.Reader) error {
+	if err := s.check(name); err != nil {
+		return err
+	}
+
+	_, err := s.mc.PutObject(s.bucket, name, f, -1, minio.PutObjectOptions{
+		PartSize:             s.partSize,
+		ServerSideEncryption: s.sse,
+	})
+
+	return errors.Wrap(err, "could not save object")
+}
+
+func (s s3Store) Remove(name string) error {
+	if err := s.check(name); err != nil {
+		return err
+	}
+
+	return errors.Wrap(s.mc.RemoveObject(s.bucket, name), "could not remove object")
+}
+
+// Open returns object reader that fetches contents as they are read
+//
+// Object is checked before it is returned so that missing objects
+// are reported here and not on the first read
+func (s s3Store) Open(name string) (io.ReadSeeker, error) {
+	if err := s.check(name); err != nil {
+		return nil, err
+	}
+
+	// Only customer keys are sent with reads, S3 decrypts
+	// objects encrypted with its own keys by itself
+	obj, err := s.mc.GetObject(s.bucket, name, minio.GetObjectOptions{
+		ServerSideEncryption: encrypt.SSE(s.sse),
+	})
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open object")
+	}
+
+	if _, err = obj.Stat(); err != nil {
+		_ = obj.Close()
+		return nil, errors.Wrap(err, "could not open object")
+	}
+
+	return obj, nil
+}
+
+// key returns object key of the file, prefixed with store's prefix
+func (s s3Store) key(filename string) string {
+	if s.prefix == "" {
+		return filename
+	}
+
+	return path.Join(s.prefix, filename)
+}
+
+// check makes sure store is not asked for objects outside its prefix
+func (s s3Store) check(name string) error {
+	if name == "" || (s.prefix != "" && !strings.HasPrefix(name, s.prefix+"/")) {
+		return ErrInvalidName.withStack().WithMessage(fmt.Sprintf("invalid object name %q", name))
+	}
+
+	return nil
+}
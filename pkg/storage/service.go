@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"go.uber.org/zap"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/store"
+	"github.com/cortezaproject/corteza-server/pkg/store/plain"
+)
+
+// InitCompose switches compose attachments to the store selected with STORAGE_DRIVER
+//
+// Must be called after compose services are initialized and before
+// extensions that use compose store or decorate its attachment service.
+func InitCompose(ctx context.Context, log *zap.Logger) error {
+	return initApp(log, "compose", func(s store.Store) {
+		composeService.DefaultStore = s
+		composeService.DefaultAttachment = composeService.Attachment(s)
+	})
+}
+
+// InitMessaging switches messaging attachments to the store selected with STORAGE_DRIVER
+//
+// Must be called after messaging services are initialized and before
+// extensions that use messaging store or decorate its attachment service.
+func InitMessaging(ctx context.Context, log *zap.Logger) error {
+	return initApp(log, "messaging", func(s store.Store) {
+		messagingService.DefaultStore = s
+		messagingService.DefaultAttachment = messagingService.Attachment(ctx, s)
+	})
+}
+
+// initApp replaces app's store when driver other than local is selected
+//
+// Files that were stored on local filesystem before are still served
+// from there until they are moved with the migration command.
+func initApp(log *zap.Logger, app string, set func(store.Store)) error {
+	s, err := connect(app)
+	if err != nil || s == nil {
+		log.Debug("attachments are stored on local filesystem", zap.String("app", app), zap.Error(err))
+		return err
+	}
+
+	path := localPath(app)
+	if isLocal(path, s.key("")+"/") {
+		return ErrInvalidName.withStack().WithMessage("S3 prefix must not start with local storage path " + path)
+	}
+
+	local, err := plain.New(path)
+	if err != nil {
+		return err
+	}
+
+	set(&fallbackStore{Store: s, local: local, path: path})
+
+	log.Info("attachments are stored on S3",
+		zap.String("app", app),
+		zap.String("bucket", s.bucket),
+		zap.String("prefix", s.prefix),
+	)
+
+	return nil
+}
+
+// connect returns S3 store of the app or nil when app stores files locally
+func connect(app string) (*s3Store, error) {
+	switch d := driver(app); d {
+	case DriverLocal:
+		return nil, nil
+	case DriverS3:
+		return S3(s3Options(app))
+	default:
+		return nil, ErrUnknownDriver.withStack().WithMessage("unknown storage driver " + d)
+	}
+}
+
+// localPath returns directory where the app keeps files on local filesystem
+func localPath(app string) string {
+	return strings.TrimSuffix(options.Storage(app).Path, "/")
+}
+
+// migrate moves attachment files of the app from local filesystem to its store
+//
+// Attachment is pointed to its new location only after both files are
+// copied. Local copies are kept unless remove is set.
+func migrate(ctx context.Context, log *zap.Logger, app string, remove, dryRun bool) (*Report, error) {
+	s, err := connect(app)
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		return nil, ErrNotConfigured.withStack().WithMessage(app + " does not use S3 storage driver")
+	}
+
+	var (
+		path  = localPath(app)
+		repo  = Repository(ctx, nil, app)
+		rep   = &Report{App: app, DryRun: dryRun}
+		local store.Store
+		set   fileSet
+	)
+
+	if local, err = plain.New(path); err != nil {
+		return nil, err
+	}
+
+	if set, err = repo.Local(path); err != nil {
+		return nil, err
+	}
+
+	for _, f := range set {
+		if !isLocal(path, f.URL) && !isLocal(path, f.PreviewURL) {
+			continue
+		}
+
+		rep.Local++
+
+		if dryRun {
+			continue
+		}
+
+		moved := *f
+
+		if moved.URL, err = copyFile(local, s, path, f.URL); err == nil {
+			if moved.PreviewURL, err = copyFile(local, s, path, f.PreviewURL); err == nil {
+				err = repo.Move(&moved)
+			}
+		}
+
+		if err != nil {
+			log.Warn("could not move attachment", zap.String("app", app), zap.Uint64("attachmentID", f.ID), zap.Error(err))
+
+			rep.Failed++
+			if len(rep.FailedIDs) < maxFailedIDs {
+				rep.FailedIDs = append(rep.FailedIDs, f.ID)
+			}
+
+			continue
+		}
+
+		rep.Moved++
+
+		if !remove {
+			continue
+		}
+
+		for _, name := range []string{f.URL, f.PreviewURL} {
+			if !isLocal(path, name) {
+				continue
+			}
+
+			if err = local.Remove(name); err != nil {
+				log.Warn("could not remove local file", zap.String("app", app), zap.String("file", name), zap.Error(err))
+			}
+		}
+	}
+
+	return rep, nil
+}
+
+// copyFile streams local file to S3 and returns its new name
+//
+// Names of files that are not local are returned unchanged
+func copyFile(local store.Store, s *s3Store, path, name string) (string, error) {
+	if !isLocal(path, name) {
+		return name, nil
+	}
+
+	f, err := local.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	if c, ok := f.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	key := s.key(strings.TrimPrefix(name, path+"/"))
+	return key, s.Save(key, f)
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"io"
+	"strings"
+
+	"github.com/cortezaproject/corteza-server/pkg/store"
+)
+
+type (
+	// fallbackStore saves new files to the base store and still reads
+	// files that were stored on the local filesystem before the switch
+	//
+	// Local files are recognised by their path and can be moved to the
+	// base store with the migration command.
+	fallbackStore struct {
+		store.Store
+
+		local store.Store
+		path  string
+	}
+)
+
+func (s fallbackStore) Remove(name string) error {
+	if s.isLocal(name) {
+		return s.local.Remove(name)
+	}
+
+	return s.Store.Remove(name)
+}
+
+func (s fallbackStore) Open(name string) (io.ReadSeeker, error) {
+	if s.isLocal(name) {
+		return s.local.Open(name)
+	}
+
+	return s.Store.Open(name)
+}
+
+func (s fallbackStore) isLocal(name string) bool {
+	return isLocal(s.path, name)
+}
+
+// isLocal reports if file name is a path in the local store
+func isLocal(path, name string) bool {
+	return strings.HasPrefix(name, strings.TrimSuffix(path, "/")+"/")
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+)
+
+type (
+	// S3Options configure store on S3 or any S3-compatible service (MinIO, Ceph, ...)
+	S3Options struct {
+		Endpoint string
+		Region   string
+		Secure   bool
+
+		AccessKeyID     string
+		SecretAccessKey string
+
+		Bucket string
+
+		// Prepended to all object keys, allows sharing of a bucket
+		Prefix string
+
+		// Do not create bucket when it does not exist
+		Strict bool
+
+		// Size of parts of streamed (multipart) uploads
+		//
+		// Memory used by each upload is proportional to it
+		PartSize uint64
+
+		// Server-side encryption, one of SSE* constants
+		Encryption string
+
+		// ID of the KMS master key, used with SSEKMS
+		KMSKeyID string
+
+		// 32 byte customer key, used with SSEC
+		CustomerKey []byte
+	}
+
+	// Report of files moved from local filesystem to the app's store
+	Report struct {
+		App    string `json:"app"`
+		DryRun bool   `json:"dryRun,omitempty"`
+
+		// Number of attachments with files on the local filesystem
+		Local int `json:"local"`
+
+		Moved  int `json:"moved"`
+		Failed int `json:"failed"`
+
+		// IDs of attachments that could not be moved, first few only
+		FailedIDs []uint64 `json:"failedIDs,omitempty"`
+	}
+
+	// file of the attachment, original and its preview
+	file struct {
+		ID         uint64 `db:"id"`
+		URL        string `db:"url"`
+		PreviewURL string `db:"preview_url"`
+	}
+
+	fileSet []*file
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+
+	SSENone = ""
+	SSES3   = "s3"
+	SSEKMS  = "kms"
+	SSEC    = "c"
+
+	// Number of failed attachments listed in the report
+	maxFailedIDs = 20
+
+	// S3 does not accept parts (except the last one) smaller than that
+	minPartSize = 5 << 20
+)
+
+var (
+	// Apps that keep attachments
+	appNames = []string{"compose", "messaging"}
+)
+
+// driver returns name of the store driver app uses
+func driver(app string) string {
+	return options.EnvString(app, "STORAGE_DRIVER", DriverLocal)
+}
+
+// s3Options reads store options of the app from the environment
+//
+// Each option can be given for all apps or only for one of them
+// (STORAGE_S3_BUCKET vs. MESSAGING_STORAGE_S3_BUCKET)
+func s3Options(app string) S3Options {
+	return S3Options{
+		Endpoint:        options.EnvString(app, "STORAGE_S3_ENDPOINT", "s3.amazonaws.com"),
+		Region:          options.EnvString(app, "STORAGE_S3_REGION", ""),
+		Secure:          options.EnvBool(app, "STORAGE_S3_SECURE", true),
+		AccessKeyID:     options.EnvString(app, "STORAGE_S3_ACCESS_KEY", ""),
+		SecretAccessKey: options.EnvString(app, "STORAGE_S3_SECRET_KEY", ""),
+		Bucket:          options.EnvString(app, "STORAGE_S3_BUCKET", app),
+		Prefix:          options.EnvString(app, "STORAGE_S3_PREFIX", ""),
+		Strict:          options.EnvBool(app, "STORAGE_S3_STRICT", false),
+		PartSize:        uint64(options.EnvInt(app, "STORAGE_S3_PART_SIZE", 16<<20)),
+		Encryption:      options.EnvString(app, "STORAGE_S3_SSE", SSENone),
+		KMSKeyID:        options.EnvString(app, "STORAGE_S3_SSE_KMS_KEY", ""),
+		CustomerKey:     []byte(options.EnvString(app, "STORAGE_S3_SSE_C_KEY", "")),
+	}
+}
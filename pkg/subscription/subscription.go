@@ -184,7 +184,7 @@ func (s *subscription) CanRegister(currentTotal uint) error {
 }
 
 // Converts error template into error using subscription values
-func (s subscription) error(t string) error {
+func (s *subscription) error(t string) error {
 	t = strings.NewReplacer(
 		"[exp-date]", s.expires.Format(time.RFC1123),
 		"[sales-email]", salesEmail,
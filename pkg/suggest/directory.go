@@ -0,0 +1,131 @@
+package suggest
+
+import (
+	"strings"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+// users suggests users by name, username, email or handle (prefix)
+//
+// With handles, only users with matching handle are suggested, as "@handle".
+// With fuzzy filter, users with similar names, usernames or handles are added
+// when there are not enough prefix matches
+func (svc suggestService) users(f Filter, handles bool) (SuggestionSet, error) {
+	q := f.Query
+	if handles {
+		q = strings.TrimPrefix(q, "@")
+	}
+
+	if q == "" {
+		return SuggestionSet{}, nil
+	}
+
+	uu, _, err := systemService.DefaultUser.With(svc.ctx).Find(systemTypes.UserFilter{
+		Query:      q,
+		PageFilter: rh.Paging(1, f.Limit),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := SuggestionSet{}
+	for _, u := range uu {
+		if s := userSuggestion(u, q, handles); s.rank >= 0 {
+			out = append(out, s)
+		}
+	}
+
+	if !f.Fuzzy || uint(len(out)) >= f.Limit {
+		return out, nil
+	}
+
+	ids, err := UserRepository(svc.ctx, nil).Fuzzy(trigrams(q), f.Limit*fuzzyCandidates)
+	if err != nil || len(ids) == 0 {
+		return out, err
+	}
+
+	if uu, _, err = systemService.DefaultUser.With(svc.ctx).Find(systemTypes.UserFilter{UserID: ids}); err != nil {
+		return nil, err
+	}
+
+	for _, u := range uu {
+		if out.has(SuggestUser, u.ID) || out.has(SuggestHandle, u.ID) {
+			continue
+		}
+
+		s := userSuggestion(u, q, handles)
+		if s.Value == "" {
+			continue
+		}
+
+		values := []string{u.Handle}
+		if !handles {
+			values = append(values, u.Name, u.Username)
+		}
+
+		for _, v := range values {
+			if score := similarity(q, v); score > s.Score {
+				s.Score = score
+			}
+		}
+
+		if s.Score >= f.Threshold {
+			s.rank = rankFuzzy
+			out = append(out, s)
+		}
+	}
+
+	return out, nil
+}
+
+// userSuggestion converts user into suggestion and ranks it against the query
+func userSuggestion(u *systemTypes.User, q string, handle bool) *Suggestion {
+	if handle {
+		if u.Handle == "" {
+			return &Suggestion{rank: -1}
+		}
+
+		return &Suggestion{Type: SuggestHandle, ID: u.ID, Value: "@" + u.Handle, rank: rank(u.Handle, q)}
+	}
+
+	s := &Suggestion{Type: SuggestUser, ID: u.ID, rank: -1}
+	for _, v := range []string{u.Name, u.Username, u.Email, u.Handle} {
+		if r := rank(v, q); r >= 0 && (s.rank < 0 || r < s.rank) {
+			s.rank = r
+		}
+
+		if s.Value == "" {
+			s.Value = v
+		}
+	}
+
+	return s
+}
+
+// channels suggests channels that current user can access by name
+func (svc suggestService) channels(f Filter) (SuggestionSet, error) {
+	cc, _, err := messagingService.DefaultChannel.With(svc.ctx).Find(messagingTypes.ChannelFilter{
+		Query:         f.Query,
+		CurrentUserID: auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := SuggestionSet{}
+	for _, c := range cc {
+		if r := rank(c.Name, f.Query); r >= 0 {
+			out = append(out, &Suggestion{Type: SuggestChannel, ID: c.ID, Value: c.Name, rank: r})
+		}
+	}
+
+	return out.ranked(f.Limit), nil
+}
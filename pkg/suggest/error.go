@@ -0,0 +1,37 @@
+package suggest
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	suggestError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidField         = suggestError{"InvalidField", fault.Invalid}
+	ErrInvalidType          = suggestError{"InvalidType", fault.Invalid}
+	ErrQueryTooShort        = suggestError{"QueryTooShort", fault.Invalid}
+	ErrInvalidThreshold     = suggestError{"InvalidThreshold", fault.Invalid}
+	ErrNoPermissions        = suggestError{"NoPermissions", fault.Forbidden}
+	ErrDisplayFieldNotFound = suggestError{"DisplayFieldNotFound", fault.NotFound}
+)
+
+func (e suggestError) Error() string {
+	return e.String()
+}
+
+func (e suggestError) String() string {
+	return "crust.suggest." + e.name
+}
+
+func (e suggestError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e suggestError) withStack() *fault.Error {
+	return fault.New(e)
+}
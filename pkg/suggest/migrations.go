@@ -0,0 +1,64 @@
+package suggest
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200123000000.suggest",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_display_field (
+  rel_module       BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  field            VARCHAR(64)     NOT NULL,
+
+  updated_by       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  updated_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (rel_module)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_compose_record_display (
+  rel_record       BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  rel_module       BIGINT UNSIGNED NOT NULL,
+  value            VARCHAR(255)    NOT NULL,
+
+  PRIMARY KEY (rel_record),
+  INDEX (rel_module, value)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+		{
+			Name: "20200124000000.trigram",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_record_trigram (
+  rel_record       BIGINT UNSIGNED NOT NULL,
+  rel_module       BIGINT UNSIGNED NOT NULL,
+  trigram          CHAR(3)         NOT NULL COLLATE utf8_bin,
+
+  PRIMARY KEY (rel_module, trigram, rel_record),
+  INDEX (rel_record)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+
+	// SystemMigrations are executed on system's database
+	SystemMigrations = migrations.Set{
+		{
+			Name: "20200124000000.user-trigram",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_system_user_trigram (
+  rel_user         BIGINT UNSIGNED NOT NULL,
+  trigram          CHAR(3)         NOT NULL COLLATE utf8_bin,
+
+  PRIMARY KEY (trigram, rel_user),
+  INDEX (rel_user)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,79 @@
+package suggest
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	// record wraps record service and keeps display value index up to date
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with display value indexing
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	r, err := svc.RecordService.Create(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, svc.index(r)
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	r, err := svc.RecordService.Update(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, svc.index(r)
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	if err := svc.RecordService.DeleteByID(namespaceID, recordID); err != nil {
+		return err
+	}
+
+	return Repository(svc.ctx, nil).Unindex(recordID)
+}
+
+// index stores record's display value, when module has display field configured
+func (svc record) index(r *types.Record) error {
+	repo := Repository(svc.ctx, nil)
+
+	df, err := repo.FindDisplayField(r.ModuleID)
+	if fault.Is(err, ErrDisplayFieldNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	vv := r.Values.FilterByName(df.Field)
+	if len(vv) == 0 || vv[0].Value == "" {
+		return repo.Unindex(r.ID)
+	}
+
+	return repo.Index(&entry{
+		RecordID:    r.ID,
+		NamespaceID: r.NamespaceID,
+		ModuleID:    r.ModuleID,
+		Value:       truncate(vv[0].Value),
+	})
+}
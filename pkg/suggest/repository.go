@@ -0,0 +1,221 @@
+package suggest
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) tableDisplayField() string {
+	return "crust_compose_display_field"
+}
+
+func (r repository) tableIndex() string {
+	return "crust_compose_record_display"
+}
+
+func (r repository) tableTrigram() string {
+	return "crust_compose_record_trigram"
+}
+
+func (r repository) FindDisplayField(moduleID uint64) (*DisplayField, error) {
+	var (
+		df = &DisplayField{}
+		q  = squirrel.
+			Select("rel_module", "rel_namespace", "field", "updated_by", "updated_at").
+			From(r.tableDisplayField()).
+			Where(squirrel.Eq{"rel_module": moduleID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, df); err != nil {
+		return nil, err
+	} else if df.ModuleID == 0 {
+		return nil, ErrDisplayFieldNotFound
+	}
+
+	return df, nil
+}
+
+func (r repository) FindDisplayFields(moduleIDs ...uint64) (set DisplayFieldSet, err error) {
+	if len(moduleIDs) == 0 {
+		return DisplayFieldSet{}, nil
+	}
+
+	q := squirrel.
+		Select("rel_module", "rel_namespace", "field", "updated_by", "updated_at").
+		From(r.tableDisplayField()).
+		Where(squirrel.Eq{"rel_module": moduleIDs})
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) SaveDisplayField(df *DisplayField) (*DisplayField, error) {
+	rh.SetCurrentTimeRounded(&df.UpdatedAt)
+	return df, errors.WithStack(r.db().Replace(r.tableDisplayField(), df))
+}
+
+func (r repository) DeleteDisplayField(moduleID uint64) error {
+	return rh.Delete(r.db(), r.tableDisplayField(), squirrel.Eq{"rel_module": moduleID})
+}
+
+// Search returns index entries of the modules with display values that start with the prefix
+//
+// Index is case-insensitive (collation), so prefix LIKE uses the (rel_module, value) index
+func (r repository) Search(moduleIDs []uint64, prefix string, limit uint) (ee []*entry, err error) {
+	q := squirrel.
+		Select("rel_record", "rel_namespace", "rel_module", "value").
+		From(r.tableIndex()).
+		Where(squirrel.Eq{"rel_module": moduleIDs}).
+		Where(squirrel.Like{"value": likePrefix(prefix)}).
+		OrderBy("value").
+		Limit(uint64(limit))
+
+	return ee, rh.FetchAll(r.db(), q, &ee)
+}
+
+// Index stores display value of the record and its trigrams
+func (r repository) Index(e *entry) error {
+	return r.db().Transaction(func() (err error) {
+		if err = r.db().Replace(r.tableIndex(), e); err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err = rh.Delete(r.db(), r.tableTrigram(), squirrel.Eq{"rel_record": e.RecordID}); err != nil {
+			return
+		}
+
+		return r.insertTrigrams(e)
+	})
+}
+
+func (r repository) Unindex(recordID uint64) error {
+	return r.db().Transaction(func() (err error) {
+		if err = rh.Delete(r.db(), r.tableIndex(), squirrel.Eq{"rel_record": recordID}); err != nil {
+			return
+		}
+
+		return rh.Delete(r.db(), r.tableTrigram(), squirrel.Eq{"rel_record": recordID})
+	})
+}
+
+// Fuzzy returns index entries of the modules that share the most trigrams with the given ones
+//
+// Entries are candidates only; similarity needs to be checked by the caller
+func (r repository) Fuzzy(moduleIDs []uint64, tt []string, limit uint) (ee []*entry, err error) {
+	var (
+		ids = []uint64{}
+		q   = squirrel.
+			Select("rel_record").
+			From(r.tableTrigram()).
+			Where(squirrel.Eq{"rel_module": moduleIDs, "trigram": tt}).
+			GroupBy("rel_record").
+			OrderBy("COUNT(*) DESC").
+			Limit(uint64(limit))
+	)
+
+	if err = rh.FetchAll(r.db(), q, &ids); err != nil || len(ids) == 0 {
+		return
+	}
+
+	q = squirrel.
+		Select("rel_record", "rel_namespace", "rel_module", "value").
+		From(r.tableIndex()).
+		Where(squirrel.Eq{"rel_record": ids})
+
+	return ee, rh.FetchAll(r.db(), q, &ee)
+}
+
+// Reindex replaces all index entries of the module with display values from the field
+func (r repository) Reindex(moduleID uint64, field string) error {
+	return r.db().Transaction(func() (err error) {
+		if err = r.Clear(moduleID); err != nil {
+			return
+		}
+
+		if field == "" {
+			return
+		}
+
+		_, err = r.db().Exec(
+			"INSERT INTO "+r.tableIndex()+" (rel_record, rel_namespace, rel_module, value) "+
+				"SELECT r.id, r.rel_namespace, r.module_id, LEFT(v.value, ?) "+
+				"FROM compose_record AS r "+
+				"INNER JOIN compose_record_value AS v ON (v.record_id = r.id AND v.name = ? AND v.place = 0 AND v.deleted_at IS NULL) "+
+				"WHERE r.module_id = ? AND r.deleted_at IS NULL AND v.value <> ''",
+			maxValueLength, field, moduleID,
+		)
+
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		var (
+			ee []*entry
+			q  = squirrel.
+				Select("rel_record", "rel_namespace", "rel_module", "value").
+				From(r.tableIndex()).
+				Where(squirrel.Eq{"rel_module": moduleID})
+		)
+
+		if err = rh.FetchAll(r.db(), q, &ee); err != nil {
+			return
+		}
+
+		return r.insertTrigrams(ee...)
+	})
+}
+
+// Clear removes all index entries of the module
+func (r repository) Clear(moduleID uint64) (err error) {
+	if err = rh.Delete(r.db(), r.tableIndex(), squirrel.Eq{"rel_module": moduleID}); err != nil {
+		return
+	}
+
+	return rh.Delete(r.db(), r.tableTrigram(), squirrel.Eq{"rel_module": moduleID})
+}
+
+// insertTrigrams stores trigrams of display values, in batches
+func (r repository) insertTrigrams(ee ...*entry) error {
+	var b = newBatchInsert(r.db(), r.tableTrigram(), "rel_record", "rel_module", "trigram")
+
+	for _, e := range ee {
+		for _, t := range trigrams(e.Value) {
+			if err := b.add(e.RecordID, e.ModuleID, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	return b.flush()
+}
+
+func likePrefix(q string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q) + "%"
+}
@@ -0,0 +1,87 @@
+package suggest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts record suggestion and display field endpoints
+//
+// Expects to be mounted under a path with {namespaceID} and {moduleID} params
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?q=acm&limit=10&fuzzy=true&threshold=0.4
+	r.Get("/", rest.Handler("Suggest.Records", func(r *http.Request) (interface{}, error) {
+		return DefaultSuggest.With(r.Context()).Records(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+			filter(r),
+		)
+	}))
+
+	r.Get("/display-field", rest.Handler("Suggest.DisplayField", func(r *http.Request) (interface{}, error) {
+		return DefaultSuggest.With(r.Context()).FindDisplayField(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+		)
+	}))
+
+	r.Put("/display-field", rest.Handler("Suggest.SetDisplayField", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			Field string `json:"field"`
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultSuggest.With(r.Context()).SetDisplayField(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+			body.Field,
+		)
+	}))
+
+	r.Post("/reindex", rest.Handler("Suggest.Reindex", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultSuggest.With(r.Context()).Reindex(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "moduleID"),
+		)
+	}))
+}
+
+// MountSuggestRoutes mounts suggestion endpoint over users, handles, channels and records
+func MountSuggestRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?q=jo&types=users,channels&limit=10&fuzzy=true
+	r.Get("/", rest.Handler("Suggest.Suggest", func(r *http.Request) (interface{}, error) {
+		f := filter(r)
+		for _, t := range strings.Split(r.URL.Query().Get("types"), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				f.Types = append(f.Types, SuggestionType(t))
+			}
+		}
+
+		return DefaultSuggest.With(r.Context()).Suggest(f)
+	}))
+}
+
+func filter(r *http.Request) Filter {
+	threshold, _ := strconv.ParseFloat(r.URL.Query().Get("threshold"), 64)
+
+	return Filter{
+		Query:     r.URL.Query().Get("q"),
+		Limit:     rest.QueryUint(r, "limit"),
+		Fuzzy:     rest.QueryBool(r, "fuzzy"),
+		Threshold: threshold,
+	}
+}
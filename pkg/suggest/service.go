@@ -0,0 +1,424 @@
+package suggest
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/settings"
+	"github.com/crusttech/crust-server/pkg/access"
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	suggestService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		namespace service.NamespaceService
+		module    service.ModuleService
+		settings  settingsGetter
+
+		repository *repository
+	}
+
+	settingsGetter interface {
+		Get(context.Context, string, uint64) (*settings.Value, error)
+	}
+
+	accessController interface {
+		CanReadRecord(context.Context, *types.Module) bool
+		CanReadRecordValue(context.Context, *types.ModuleField) bool
+		CanUpdateModule(context.Context, *types.Module) bool
+	}
+
+	SuggestService interface {
+		With(ctx context.Context) SuggestService
+
+		FindDisplayField(namespaceID, moduleID uint64) (*DisplayField, error)
+		SetDisplayField(namespaceID, moduleID uint64, field string) (*DisplayField, error)
+		Reindex(namespaceID, moduleID uint64) error
+
+		Records(namespaceID, moduleID uint64, f Filter) (SuggestionSet, error)
+		Suggest(f Filter) (SuggestionSet, error)
+	}
+)
+
+var (
+	DefaultSuggest SuggestService
+)
+
+// Init initializes suggestion service and decorates compose's record service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	DefaultSuggest = (&suggestService{
+		logger:    log,
+		ac:        service.DefaultAccessControl,
+		namespace: service.DefaultNamespace,
+		module:    service.DefaultModule,
+		settings:  service.DefaultSettings,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc suggestService) With(ctx context.Context) SuggestService {
+	return &suggestService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		namespace: svc.namespace.With(ctx),
+		module:    svc.module.With(ctx),
+		settings:  svc.settings,
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc suggestService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc suggestService) FindDisplayField(namespaceID, moduleID uint64) (*DisplayField, error) {
+	if _, err := svc.module.FindByID(namespaceID, moduleID); err != nil {
+		return nil, err
+	}
+
+	df, err := svc.repository.FindDisplayField(moduleID)
+	if fault.Is(err, ErrDisplayFieldNotFound) {
+		return nil, ErrDisplayFieldNotFound.withStack()
+	}
+
+	return df, err
+}
+
+// SetDisplayField configures display field of the module and rebuilds its index
+//
+// Empty field name removes the configuration
+func (svc suggestService) SetDisplayField(namespaceID, moduleID uint64, field string) (*DisplayField, error) {
+	m, err := svc.module.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	if field == "" {
+		if err = svc.repository.DeleteDisplayField(m.ID); err != nil {
+			return nil, err
+		}
+
+		return nil, svc.repository.Clear(m.ID)
+	}
+
+	if f := m.Fields.FindByName(field); f == nil || f.Multi || f.IsRef() {
+		return nil, ErrInvalidField.withStack()
+	}
+
+	df, err := svc.repository.SaveDisplayField(&DisplayField{
+		ModuleID:    m.ID,
+		NamespaceID: m.NamespaceID,
+		Field:       field,
+		UpdatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return df, svc.repository.Reindex(m.ID, field)
+}
+
+// Reindex rebuilds display value index of the module
+func (svc suggestService) Reindex(namespaceID, moduleID uint64) error {
+	m, err := svc.module.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	df, err := svc.repository.FindDisplayField(m.ID)
+	if fault.Is(err, ErrDisplayFieldNotFound) {
+		return ErrDisplayFieldNotFound.withStack()
+	} else if err != nil {
+		return err
+	}
+
+	svc.log(zap.Uint64("moduleID", m.ID), zap.String("field", df.Field)).
+		Info("reindexing display values")
+
+	return svc.repository.Reindex(m.ID, df.Field)
+}
+
+// Records returns records of the module with display values that start with the query
+//
+// With fuzzy filter, records with similar display values are added when there are not enough prefix matches
+func (svc suggestService) Records(namespaceID, moduleID uint64, f Filter) (SuggestionSet, error) {
+	if err := svc.normalize(&f); err != nil {
+		return nil, err
+	}
+
+	m, err := svc.module.FindByID(namespaceID, moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	df, err := svc.repository.FindDisplayField(m.ID)
+	if fault.Is(err, ErrDisplayFieldNotFound) {
+		return nil, ErrDisplayFieldNotFound.withStack()
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanReadRecord(svc.ctx, m) || !svc.ac.CanReadRecordValue(svc.ctx, m.Fields.FindByName(df.Field)) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return svc.records([]uint64{m.ID}, f)
+}
+
+// Suggest returns suggestions of all requested types, best matches first
+//
+// Users, handles and channels are available only when running as a monolith
+func (svc suggestService) Suggest(f Filter) (out SuggestionSet, err error) {
+	if err = svc.normalize(&f); err != nil {
+		return nil, err
+	}
+
+	var set SuggestionSet
+
+	out = SuggestionSet{}
+	for _, t := range f.Types {
+		switch t {
+		case SuggestUser:
+			set, err = svc.users(f, false)
+		case SuggestHandle:
+			set, err = svc.users(f, true)
+		case SuggestChannel:
+			set, err = svc.channels(f)
+		case SuggestRecord:
+			var mm []uint64
+			if mm, err = svc.indexedModules(); err == nil {
+				set, err = svc.records(mm, f)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, set...)
+	}
+
+	return out.ranked(f.Limit), nil
+}
+
+// normalize validates filter, lowercases the query and sets defaults
+func (svc suggestService) normalize(f *Filter) error {
+	if f.Query = strings.ToLower(strings.TrimSpace(f.Query)); len(f.Query) < minQueryLength {
+		return ErrQueryTooShort.withStack()
+	}
+
+	if len(f.Types) == 0 {
+		f.Types = allTypes
+	}
+
+	for _, t := range f.Types {
+		if !t.IsValid() {
+			return ErrInvalidType.withStack()
+		}
+	}
+
+	switch {
+	case f.Limit == 0:
+		f.Limit = defaultLimit
+	case f.Limit > maxLimit:
+		f.Limit = maxLimit
+	}
+
+	switch {
+	case f.Threshold < 0 || f.Threshold > 1:
+		return ErrInvalidThreshold.withStack()
+	case f.Threshold == 0:
+		f.Threshold = svc.threshold()
+	}
+
+	return nil
+}
+
+// threshold returns configured relevance threshold of fuzzy matches
+func (svc suggestService) threshold() float64 {
+	v, err := svc.settings.Get(auth.SetSuperUserContext(svc.ctx), settingThreshold, 0)
+	if err != nil {
+		svc.log().Error("could not load fuzzy threshold setting", zap.Error(err))
+		return defaultThreshold
+	} else if v == nil {
+		return defaultThreshold
+	}
+
+	var t float64
+	if err = v.Value.Unmarshal(&t); err != nil {
+		// Settings are often stored as strings
+		t, _ = strconv.ParseFloat(v.String(), 64)
+	}
+
+	if t <= 0 || t > 1 {
+		return defaultThreshold
+	}
+
+	return t
+}
+
+// indexedModules returns IDs of all modules with configured and readable display fields
+func (svc suggestService) indexedModules() (out []uint64, err error) {
+	nn, _, err := svc.namespace.Find(types.NamespaceFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var modules types.ModuleSet
+	for _, n := range nn {
+		mm, _, err := svc.module.Find(types.ModuleFilter{NamespaceID: n.ID})
+		if err != nil {
+			return nil, err
+		}
+
+		modules = append(modules, mm...)
+	}
+
+	dd, err := svc.repository.FindDisplayFields(modules.IDs()...)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		readable = access.CanReadModuleRecords(svc.ctx, modules)
+		ff       types.ModuleFieldSet
+	)
+
+	for _, m := range modules {
+		if df := dd.FindByModuleID(m.ID); df != nil && readable[m.ID] {
+			if f := m.Fields.FindByName(df.Field); f != nil {
+				ff = append(ff, f)
+			}
+		}
+	}
+
+	values := access.CanReadRecordValues(svc.ctx, ff)
+
+	for _, m := range modules {
+		df := dd.FindByModuleID(m.ID)
+		if df == nil || !readable[m.ID] {
+			continue
+		}
+
+		if f := m.Fields.FindByName(df.Field); f != nil && values[f.ID] {
+			out = append(out, m.ID)
+		}
+	}
+
+	return
+}
+
+func (svc suggestService) records(moduleIDs []uint64, f Filter) (SuggestionSet, error) {
+	if len(moduleIDs) == 0 {
+		return SuggestionSet{}, nil
+	}
+
+	ee, err := svc.repository.Search(moduleIDs, f.Query, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	out := SuggestionSet{}
+	for _, e := range ee {
+		out = append(out, recordSuggestion(e, rank(e.Value, f.Query), 0))
+	}
+
+	if !f.Fuzzy || uint(len(out)) >= f.Limit {
+		return out.ranked(f.Limit), nil
+	}
+
+	if ee, err = svc.repository.Fuzzy(moduleIDs, trigrams(f.Query), f.Limit*fuzzyCandidates); err != nil {
+		return nil, err
+	}
+
+	for _, e := range ee {
+		if out.has(SuggestRecord, e.RecordID) {
+			continue
+		}
+
+		if score := similarity(f.Query, e.Value); score >= f.Threshold {
+			out = append(out, recordSuggestion(e, rankFuzzy, score))
+		}
+	}
+
+	return out.ranked(f.Limit), nil
+}
+
+func recordSuggestion(e *entry, rank int, score float64) *Suggestion {
+	return &Suggestion{
+		Type:  SuggestRecord,
+		ID:    e.RecordID,
+		Value: e.Value,
+		Refs: map[string]string{
+			"namespaceID": strconv.FormatUint(e.NamespaceID, 10),
+			"moduleID":    strconv.FormatUint(e.ModuleID, 10),
+		},
+		Score: score,
+		rank:  rank,
+	}
+}
+
+// ranked sorts suggestions by rank and score, shorter values first, and cuts the list
+func (set SuggestionSet) ranked(limit uint) SuggestionSet {
+	sort.SliceStable(set, func(i, j int) bool {
+		switch {
+		case set[i].rank != set[j].rank:
+			return set[i].rank < set[j].rank
+		case set[i].Score != set[j].Score:
+			return set[i].Score > set[j].Score
+		case len(set[i].Value) != len(set[j].Value):
+			return len(set[i].Value) < len(set[j].Value)
+		}
+
+		return set[i].Value < set[j].Value
+	})
+
+	if uint(len(set)) > limit {
+		return set[:limit]
+	}
+
+	return set
+}
+
+func (set SuggestionSet) has(t SuggestionType, ID uint64) bool {
+	for i := range set {
+		if set[i].Type == t && set[i].ID == ID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,105 @@
+package suggest
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+)
+
+// trigrams splits value into lowercase words and returns unique trigrams of all words
+//
+// Words are padded with two spaces in front and one at the end so that
+// short words and word beginnings produce trigrams too ("ab" => "  a", " ab", "ab ")
+func trigrams(value string) (tt []string) {
+	var (
+		seen  = map[string]bool{}
+		words = strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+	)
+
+	for _, w := range words {
+		rr := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(rr); i++ {
+			t := string(rr[i : i+3])
+			if !seen[t] {
+				seen[t] = true
+				tt = append(tt, t)
+			}
+		}
+	}
+
+	return
+}
+
+// similarity returns Sørensen–Dice coefficient of value trigram sets, from 0 to 1
+func similarity(a, b string) float64 {
+	var (
+		at, bt = trigrams(a), trigrams(b)
+		shared int
+	)
+
+	if len(at)+len(bt) == 0 {
+		return 0
+	}
+
+	set := map[string]bool{}
+	for _, t := range at {
+		set[t] = true
+	}
+
+	for _, t := range bt {
+		if set[t] {
+			shared++
+		}
+	}
+
+	return 2 * float64(shared) / float64(len(at)+len(bt))
+}
+
+// batchInsert collects rows and inserts them with one statement per batch
+type batchInsert struct {
+	db      *factory.DB
+	table   string
+	columns []string
+
+	q squirrel.InsertBuilder
+	n int
+}
+
+func newBatchInsert(db *factory.DB, table string, columns ...string) *batchInsert {
+	return &batchInsert{
+		db:      db,
+		table:   table,
+		columns: columns,
+		q:       squirrel.Insert(table).Columns(columns...),
+	}
+}
+
+func (b *batchInsert) add(values ...interface{}) error {
+	b.q, b.n = b.q.Values(values...), b.n+1
+	if b.n < batchSize {
+		return nil
+	}
+
+	return b.flush()
+}
+
+func (b *batchInsert) flush() error {
+	if b.n == 0 {
+		return nil
+	}
+
+	query, args, err := b.q.ToSql()
+	if err != nil {
+		return err
+	}
+
+	b.q, b.n = squirrel.Insert(b.table).Columns(b.columns...), 0
+
+	_, err = b.db.Exec(query, args...)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,140 @@
+package suggest
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+type (
+	// DisplayField configures which field holds record's display value
+	DisplayField struct {
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Field       string `json:"field" db:"field"`
+
+		UpdatedBy uint64    `json:"updatedBy,string" db:"updated_by"`
+		UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+	}
+
+	DisplayFieldSet []*DisplayField
+
+	// entry is a record display value in the prefix index
+	entry struct {
+		RecordID    uint64 `db:"rel_record"`
+		NamespaceID uint64 `db:"rel_namespace"`
+		ModuleID    uint64 `db:"rel_module"`
+		Value       string `db:"value"`
+	}
+
+	SuggestionType string
+
+	Suggestion struct {
+		Type  SuggestionType `json:"type"`
+		ID    uint64         `json:"id,string"`
+		Value string         `json:"value"`
+
+		// IDs of resources the suggestion belongs to (namespaceID, moduleID)
+		Refs map[string]string `json:"refs,omitempty"`
+
+		// Similarity of the value and the query, set on fuzzy matches only
+		Score float64 `json:"score,omitempty"`
+
+		rank int
+	}
+
+	SuggestionSet []*Suggestion
+
+	Filter struct {
+		Query string           `json:"query"`
+		Types []SuggestionType `json:"types,omitempty"`
+		Limit uint             `json:"limit"`
+
+		// Fuzzy adds matches that tolerate typos when there are not enough prefix matches
+		Fuzzy bool `json:"fuzzy"`
+
+		// Minimal similarity (0-1] of fuzzy matches; configured threshold is used when not set
+		Threshold float64 `json:"threshold,omitempty"`
+	}
+)
+
+const (
+	SuggestUser    SuggestionType = "users"
+	SuggestHandle  SuggestionType = "handles"
+	SuggestChannel SuggestionType = "channels"
+	SuggestRecord  SuggestionType = "records"
+
+	minQueryLength = 1
+	defaultLimit   = 10
+	maxLimit       = 25
+
+	// Display values are indexed up to this length (in characters)
+	maxValueLength = 255
+
+	// Fuzzy matches rank below all prefix matches
+	rankFuzzy = 3
+
+	// Number of fuzzy candidates (per requested suggestion) loaded from the trigram index
+	fuzzyCandidates = 4
+
+	defaultThreshold = 0.35
+
+	// Compose setting with relevance threshold of fuzzy matches
+	settingThreshold = "crust.suggest.fuzzy-threshold"
+
+	batchSize = 500
+)
+
+var (
+	allTypes = []SuggestionType{SuggestUser, SuggestHandle, SuggestChannel, SuggestRecord}
+)
+
+func (t SuggestionType) IsValid() bool {
+	for _, a := range allTypes {
+		if t == a {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rank returns how well value matches the (lowercase) prefix; -1 when it does not
+//
+// Prefix of the whole value ranks above prefix of one of the words
+func rank(value, prefix string) int {
+	v := strings.ToLower(value)
+	switch {
+	case v == prefix:
+		return 0
+	case strings.HasPrefix(v, prefix):
+		return 1
+	}
+
+	for _, w := range strings.FieldsFunc(v, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if strings.HasPrefix(w, prefix) {
+			return 2
+		}
+	}
+
+	return -1
+}
+
+// truncate cuts value to the max length of the indexed value
+func truncate(v string) string {
+	if rr := []rune(v); len(rr) > maxValueLength {
+		return string(rr[:maxValueLength])
+	}
+
+	return v
+}
+
+func (set DisplayFieldSet) FindByModuleID(moduleID uint64) *DisplayField {
+	for i := range set {
+		if set[i].ModuleID == moduleID {
+			return set[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package suggest
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	systemTypes "github.com/cortezaproject/corteza-server/system/types"
+)
+
+type (
+	// userRepository keeps trigrams of user names, usernames and handles in system's database
+	userRepository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+
+	// user wraps user service and keeps user trigram index up to date
+	user struct {
+		systemService.UserService
+		ctx context.Context
+	}
+)
+
+// InitUsers decorates system's user service and rebuilds user trigram index in the background
+//
+// Must be called after system services are initialized
+func InitUsers(ctx context.Context, log *zap.Logger) error {
+	systemService.DefaultUser = User(systemService.DefaultUser)
+
+	go func() {
+		if err := reindexUsers(auth.SetSuperUserContext(ctx)); err != nil {
+			log.Error("could not rebuild user trigram index", zap.Error(err))
+		}
+	}()
+
+	return nil
+}
+
+// User decorates user service with trigram indexing
+func User(us systemService.UserService) systemService.UserService {
+	return &user{UserService: us, ctx: context.Background()}
+}
+
+func (svc user) With(ctx context.Context) systemService.UserService {
+	return &user{
+		UserService: svc.UserService.With(ctx),
+		ctx:         ctx,
+	}
+}
+
+func (svc user) Create(u *systemTypes.User) (*systemTypes.User, error) {
+	return svc.index(svc.UserService.Create(u))
+}
+
+func (svc user) Update(u *systemTypes.User) (*systemTypes.User, error) {
+	return svc.index(svc.UserService.Update(u))
+}
+
+func (svc user) Delete(userID uint64) error {
+	if err := svc.UserService.Delete(userID); err != nil {
+		return err
+	}
+
+	return UserRepository(svc.ctx, nil).Unindex(userID)
+}
+
+func (svc user) Undelete(userID uint64) error {
+	if err := svc.UserService.Undelete(userID); err != nil {
+		return err
+	}
+
+	_, err := svc.index(svc.UserService.FindByID(userID))
+	return err
+}
+
+func (svc user) index(u *systemTypes.User, err error) (*systemTypes.User, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return u, UserRepository(svc.ctx, nil).Index(u)
+}
+
+// reindexUsers replaces trigrams of all users
+func reindexUsers(ctx context.Context) error {
+	uu, _, err := systemService.DefaultUser.With(ctx).Find(systemTypes.UserFilter{})
+	if err != nil {
+		return err
+	}
+
+	return UserRepository(ctx, nil).Reindex(uu)
+}
+
+func UserRepository(ctx context.Context, db *factory.DB) *userRepository {
+	return &userRepository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r userRepository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+func (r userRepository) table() string {
+	return "crust_system_user_trigram"
+}
+
+// Fuzzy returns IDs of users that share the most trigrams with the given ones
+func (r userRepository) Fuzzy(tt []string, limit uint) (ids []uint64, err error) {
+	q := squirrel.
+		Select("rel_user").
+		From(r.table()).
+		Where(squirrel.Eq{"trigram": tt}).
+		GroupBy("rel_user").
+		OrderBy("COUNT(*) DESC").
+		Limit(uint64(limit))
+
+	return ids, rh.FetchAll(r.db(), q, &ids)
+}
+
+func (r userRepository) Index(u *systemTypes.User) error {
+	return r.db().Transaction(func() (err error) {
+		if err = r.Unindex(u.ID); err != nil {
+			return
+		}
+
+		return r.insert(u)
+	})
+}
+
+func (r userRepository) Unindex(userID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"rel_user": userID})
+}
+
+// Reindex replaces trigrams of all users with trigrams of the given ones
+func (r userRepository) Reindex(uu systemTypes.UserSet) error {
+	return r.db().Transaction(func() (err error) {
+		if _, err = r.db().Exec("DELETE FROM " + r.table()); err != nil {
+			return
+		}
+
+		return r.insert(uu...)
+	})
+}
+
+// insert stores trigrams of user's name, username and handle, in batches
+func (r userRepository) insert(uu ...*systemTypes.User) error {
+	var b = newBatchInsert(r.db(), r.table(), "rel_user", "trigram")
+
+	for _, u := range uu {
+		for _, t := range userTrigrams(u) {
+			if err := b.add(u.ID, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	return b.flush()
+}
+
+func userTrigrams(u *systemTypes.User) []string {
+	return trigrams(u.Name + " " + u.Username + " " + u.Handle)
+}
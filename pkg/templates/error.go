@@ -0,0 +1,35 @@
+package templates
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	templatesError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidField     = templatesError{"InvalidField", fault.Invalid}
+	ErrInvalidValue     = templatesError{"InvalidValue", fault.Invalid}
+	ErrNoPermissions    = templatesError{"NoPermissions", fault.Forbidden}
+	ErrTemplateNotFound = templatesError{"TemplateNotFound", fault.NotFound}
+)
+
+func (e templatesError) Error() string {
+	return e.String()
+}
+
+func (e templatesError) String() string {
+	return "crust.templates." + e.name
+}
+
+func (e templatesError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e templatesError) withStack() *fault.Error {
+	return fault.New(e)
+}
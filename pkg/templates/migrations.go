@@ -0,0 +1,30 @@
+package templates
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200119000000.templates",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_record_template (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  rel_module       BIGINT UNSIGNED NOT NULL,
+  name             VARCHAR(64)     NOT NULL,
+  field_values     JSON            NOT NULL,
+
+  created_by       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       DATETIME            NULL DEFAULT NULL,
+  deleted_at       DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace, rel_module)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
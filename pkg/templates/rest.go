@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"context"
+
+	"github.com/titpetric/factory/resputil"
+)
+
+//go:generate go run ../../codegen/rest
+
+type (
+	// controller implements endpoints defined in rest.json
+	controller struct{}
+)
+
+func (controller) List(ctx context.Context, req *RecordTemplateListRequest) (interface{}, error) {
+	return DefaultTemplate.With(ctx).Find(TemplateFilter{
+		NamespaceID: req.NamespaceID,
+		ModuleID:    req.ModuleID,
+		Sort:        req.Sort,
+		Page:        req.Page,
+		PerPage:     req.PerPage,
+	})
+}
+
+func (controller) Create(ctx context.Context, req *RecordTemplateCreateRequest) (interface{}, error) {
+	return DefaultTemplate.With(ctx).Create(&Template{
+		NamespaceID: req.NamespaceID,
+		ModuleID:    req.ModuleID,
+		Name:        req.Name,
+		Values:      req.Values,
+	})
+}
+
+func (controller) Read(ctx context.Context, req *RecordTemplateReadRequest) (interface{}, error) {
+	return DefaultTemplate.With(ctx).FindByID(req.NamespaceID, req.TemplateID)
+}
+
+// Update modifies the template, module of the template can not be changed
+func (controller) Update(ctx context.Context, req *RecordTemplateUpdateRequest) (interface{}, error) {
+	return DefaultTemplate.With(ctx).Update(&Template{
+		ID:          req.TemplateID,
+		NamespaceID: req.NamespaceID,
+		Name:        req.Name,
+		Values:      req.Values,
+	})
+}
+
+func (controller) Delete(ctx context.Context, req *RecordTemplateDeleteRequest) (interface{}, error) {
+	return resputil.OK(), DefaultTemplate.With(ctx).DeleteByID(req.NamespaceID, req.TemplateID)
+}
+
+func (controller) Prefill(ctx context.Context, req *RecordTemplatePrefillRequest) (interface{}, error) {
+	return DefaultTemplate.With(ctx).Prefill(req.NamespaceID, req.TemplateID)
+}
+
+func (controller) CreateRecord(ctx context.Context, req *RecordTemplateCreateRecordRequest) (interface{}, error) {
+	return DefaultTemplate.With(ctx).CreateRecord(req.NamespaceID, req.TemplateID, req.Values)
+}
@@ -0,0 +1,347 @@
+package templates
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+type (
+	templateService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		module service.ModuleService
+		record service.RecordService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanUpdateModule(context.Context, *types.Module) bool
+		CanCreateRecord(context.Context, *types.Module) bool
+	}
+
+	TemplateService interface {
+		With(ctx context.Context) TemplateService
+
+		FindByID(namespaceID, templateID uint64) (*Template, error)
+		Find(TemplateFilter) (TemplateSet, error)
+		Create(*Template) (*Template, error)
+		Update(*Template) (*Template, error)
+		DeleteByID(namespaceID, templateID uint64) error
+
+		Prefill(namespaceID, templateID uint64) (types.RecordValueSet, error)
+		CreateRecord(namespaceID, templateID uint64, override types.RecordValueSet) (*types.Record, error)
+	}
+)
+
+var (
+	DefaultTemplate TemplateService
+)
+
+// Init initializes record template service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultTemplate = (&templateService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		module: service.DefaultModule,
+		record: service.DefaultRecord,
+	}).With(ctx)
+
+	return nil
+}
+
+func (svc templateService) With(ctx context.Context) TemplateService {
+	return &templateService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		module: svc.module.With(ctx),
+		record: svc.record.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc templateService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc templateService) FindByID(namespaceID, templateID uint64) (t *Template, err error) {
+	if t, err = svc.repository.FindByID(namespaceID, templateID); err != nil {
+		return
+	}
+
+	// Verifies if current user can read the module
+	if _, err = svc.module.FindByID(namespaceID, t.ModuleID); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (svc templateService) Find(f TemplateFilter) (TemplateSet, error) {
+	if _, err := svc.module.FindByID(f.NamespaceID, f.ModuleID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Find(f)
+}
+
+func (svc templateService) Create(in *Template) (*Template, error) {
+	if err := svc.validate(in); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Create(&Template{
+		NamespaceID: in.NamespaceID,
+		ModuleID:    in.ModuleID,
+		Name:        in.Name,
+		Values:      in.Values,
+		CreatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+	})
+}
+
+func (svc templateService) Update(upd *Template) (*Template, error) {
+	t, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Module can not be changed
+	upd.ModuleID = t.ModuleID
+
+	if err = svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	t.Name = upd.Name
+	t.Values = upd.Values
+
+	return svc.repository.Update(t)
+}
+
+func (svc templateService) DeleteByID(namespaceID, templateID uint64) error {
+	t, err := svc.repository.FindByID(namespaceID, templateID)
+	if err != nil {
+		return err
+	}
+
+	m, err := svc.module.FindByID(namespaceID, t.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	return svc.repository.DeleteByID(namespaceID, templateID)
+}
+
+// Prefill evaluates template values without creating a record
+func (svc templateService) Prefill(namespaceID, templateID uint64) (types.RecordValueSet, error) {
+	t, m, err := svc.load(namespaceID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.evaluate(m, t)
+}
+
+// CreateRecord creates new record from the template
+//
+// Given values override template values of the same field
+func (svc templateService) CreateRecord(namespaceID, templateID uint64, override types.RecordValueSet) (*types.Record, error) {
+	t, m, err := svc.load(namespaceID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	vv, err := svc.evaluate(m, t)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := svc.record.Create(&types.Record{
+		NamespaceID: namespaceID,
+		ModuleID:    m.ID,
+		Values:      merge(vv, override),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("templateID", t.ID), zap.Uint64("recordID", r.ID)).
+		Info("record created from template")
+
+	return r, nil
+}
+
+// load loads template & module and checks if user can create records
+func (svc templateService) load(namespaceID, templateID uint64) (*Template, *types.Module, error) {
+	t, err := svc.FindByID(namespaceID, templateID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := svc.module.FindByID(namespaceID, t.ModuleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !svc.ac.CanCreateRecord(svc.ctx, m) {
+		return nil, nil, ErrNoPermissions.withStack()
+	}
+
+	return t, m, nil
+}
+
+// validate checks template's name, values and permissions to manage templates of the module
+func (svc templateService) validate(t *Template) error {
+	m, err := svc.module.FindByID(t.NamespaceID, t.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	for _, v := range t.Values {
+		if !m.Fields.HasName(v.Name) {
+			return ErrInvalidField.withStack()
+		}
+
+		if v.Expression {
+			if _, err = expr.Parse(v.Value); err != nil {
+				return ErrInvalidValue.withStack()
+			}
+		}
+	}
+
+	return nil
+}
+
+// evaluate converts template values into record values
+//
+// Expressions can use now, today and user (userID & roles)
+func (svc templateService) evaluate(m *types.Module, t *Template) (types.RecordValueSet, error) {
+	var (
+		out   = types.RecordValueSet{}
+		n     = time.Now()
+		scope = expr.Scope{
+			"now":   n,
+			"today": time.Date(n.Year(), n.Month(), n.Day(), 0, 0, 0, 0, n.Location()),
+			"user":  userScope(svc.ctx),
+		}
+	)
+
+	for _, tv := range t.Values {
+		f := m.Fields.FindByName(tv.Name)
+		if f == nil {
+			// Field was removed after template was created
+			continue
+		}
+
+		if !tv.Expression {
+			out = append(out, &types.RecordValue{Name: tv.Name, Value: tv.Value})
+			continue
+		}
+
+		e, err := expr.Parse(tv.Value)
+		if err != nil {
+			return nil, ErrInvalidValue.withStack()
+		}
+
+		v, err := e.Eval(scope)
+		if err != nil {
+			return nil, err
+		}
+
+		if list, ok := v.([]interface{}); ok {
+			for _, item := range list {
+				if s, ok := formatValue(f, item); ok {
+					out = append(out, &types.RecordValue{Name: tv.Name, Value: s})
+				}
+			}
+		} else if s, ok := formatValue(f, v); ok {
+			out = append(out, &types.RecordValue{Name: tv.Name, Value: s})
+		}
+	}
+
+	return out, nil
+}
+
+func userScope(ctx context.Context) expr.Scope {
+	var (
+		i     = auth.GetIdentityFromContext(ctx)
+		roles = []string{}
+	)
+
+	for _, r := range i.Roles() {
+		roles = append(roles, strconv.FormatUint(r, 10))
+	}
+
+	return expr.Scope{
+		"userID": strconv.FormatUint(i.Identity(), 10),
+		"roles":  roles,
+	}
+}
+
+// formatValue converts evaluated expression into record value string
+func formatValue(f *types.ModuleField, v interface{}) (string, bool) {
+	switch c := v.(type) {
+	case nil:
+		return "", false
+	case string:
+		return c, true
+	case float64:
+		return strconv.FormatFloat(c, 'f', -1, 64), true
+	case bool:
+		if c {
+			return "1", true
+		}
+		return "0", true
+	case time.Time:
+		if f.IsDateTime() && f.Options != nil && f.Options["onlyDate"] == true {
+			return c.Format("2006-01-02"), true
+		}
+
+		return c.UTC().Format(time.RFC3339), true
+	}
+
+	return fmt.Sprint(v), true
+}
+
+// merge returns values with overridden values replacing ones with the same name
+func merge(vv, override types.RecordValueSet) types.RecordValueSet {
+	out := types.RecordValueSet{}
+
+	for _, v := range vv {
+		if len(override.FilterByName(v.Name)) == 0 {
+			out = append(out, v)
+		}
+	}
+
+	return append(out, override...)
+}
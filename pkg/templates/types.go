@@ -0,0 +1,63 @@
+package templates
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//go:generate go run ../../codegen/repository -type Template -table crust_compose_record_template -database compose -sort name
+
+type (
+	// Template is a named preset of field values for new records
+	Template struct {
+		ID          uint64 `json:"templateID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace" dal:"scope"`
+		ModuleID    uint64 `json:"moduleID,string" db:"rel_module" dal:"filter"`
+
+		Name   string         `json:"name" db:"name" dal:"sort"`
+		Values TemplateValues `json:"values" db:"field_values"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at" dal:"sort"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	// TemplateValue is a preset value of one field
+	//
+	// When Expression is set, value is evaluated when the template is applied,
+	// for example "today + 7d" or "user.userID".
+	TemplateValue struct {
+		Name       string `json:"name"`
+		Value      string `json:"value"`
+		Expression bool   `json:"expression,omitempty"`
+	}
+
+	TemplateValues []*TemplateValue
+
+	TemplateSet []*Template
+)
+
+func (vv TemplateValues) Value() (driver.Value, error) {
+	if vv == nil {
+		vv = TemplateValues{}
+	}
+
+	return json.Marshal(vv)
+}
+
+func (vv *TemplateValues) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*vv = TemplateValues{}
+	case []byte:
+		if err := json.Unmarshal(b, vv); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into TemplateValues", string(b))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package threads
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	threadError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrThreadNotFound = threadError{"ThreadNotFound", fault.NotFound}
+)
+
+func (e threadError) Error() string {
+	return e.String()
+}
+
+func (e threadError) String() string {
+	return "crust.threads." + e.name
+}
+
+func (e threadError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e threadError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,80 @@
+package threads
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+const (
+	// Authors of the root and replies that can still read the channel:
+	// members of private and group channels, anyone of public ones
+	sqlParticipants = `
+SELECT m.rel_user, COALESCE(MAX(u.count), 0) AS unread
+  FROM messaging_message AS m
+       INNER JOIN messaging_channel AS ch ON (ch.id = m.rel_channel AND ch.deleted_at IS NULL)
+       LEFT JOIN messaging_channel_member AS cm ON (cm.rel_channel = m.rel_channel AND cm.rel_user = m.rel_user)
+       LEFT JOIN messaging_unread AS u ON (u.rel_channel = m.rel_channel AND u.rel_reply_to = ? AND u.rel_user = m.rel_user)
+ WHERE (m.id = ? OR m.reply_to = ?)
+   AND m.deleted_at IS NULL
+   AND (ch.type = 'public' OR cm.rel_user IS NOT NULL)
+ GROUP BY m.rel_user`
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+// Participants returns users that started or replied to the thread,
+// with their unread counts of the thread
+func (r repository) Participants(threadID uint64) (pp []*participant, err error) {
+	if err = r.db().Select(&pp, sqlParticipants, threadID, threadID, threadID); err != nil {
+		return nil, errors.Wrap(err, "can not load thread participants")
+	}
+
+	return pp, nil
+}
+
+// Unread returns user's unread counts of threads, of channels the user
+// is a member of; threads without unread replies are omitted
+func (r repository) Unread(userID uint64) (set ThreadUnreadSet, err error) {
+	query, args, err := squirrel.
+		Select("u.rel_channel", "u.rel_reply_to", "u.rel_last_message", "u.count").
+		From("messaging_unread AS u").
+		Join("messaging_channel_member AS cm ON (cm.rel_channel = u.rel_channel AND cm.rel_user = u.rel_user)").
+		Join("messaging_channel AS ch ON (ch.id = u.rel_channel AND ch.deleted_at IS NULL)").
+		Where(squirrel.Eq{"u.rel_user": userID}).
+		Where(squirrel.Gt{"u.rel_reply_to": 0, "u.count": 0}).
+		OrderBy("u.rel_reply_to").
+		ToSql()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.db().Select(&set, query, args...); err != nil {
+		return nil, errors.Wrap(err, "can not load unread threads")
+	}
+
+	return set, nil
+}
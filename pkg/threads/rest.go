@@ -0,0 +1,31 @@
+package threads
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts thread endpoints
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// Unread counts of current user's threads
+	r.Get("/unread", rest.Handler("Threads.Unread", func(r *http.Request) (interface{}, error) {
+		return DefaultThread.With(r.Context()).Unread()
+	}))
+
+	// Root, participants and replies, newest first:
+	//   ?query=<replies that contain it>&beforeID=<for paging>&limit=<page size>
+	r.Get("/{threadID}", rest.Handler("Threads.Search", func(r *http.Request) (interface{}, error) {
+		return DefaultThread.With(r.Context()).Find(ThreadFilter{
+			ThreadID: rest.ParamUint64(r, "threadID"),
+			Query:    r.URL.Query().Get("query"),
+			BeforeID: rest.QueryUint64(r, "beforeID"),
+			Limit:    rest.QueryUint(r, "limit"),
+		})
+	}))
+}
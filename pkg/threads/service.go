@@ -0,0 +1,164 @@
+package threads
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/crusttech/crust-server/pkg/live"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		messages messagingService.MessageService
+
+		repository *repository
+	}
+
+	ThreadService interface {
+		With(ctx context.Context) ThreadService
+
+		Find(ThreadFilter) (*Thread, error)
+		Unread() (ThreadUnreadSet, error)
+	}
+)
+
+var (
+	DefaultThread ThreadService
+
+	// used by service decorators
+	defaultThread *service
+)
+
+// Init initializes thread service and decorates message service with
+// notifying of thread participants
+//
+// Participants get thread events to their user scope of live, after the
+// reply is created. Must be called after messaging services and live are
+// initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger:   log,
+		messages: messagingService.DefaultMessage,
+	}
+
+	DefaultThread = svc.With(ctx)
+	defaultThread = svc.with(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) ThreadService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		messages: svc.messages.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns the thread with replies that match the filter
+//
+// Thread is found by ID of its root message; replies can not be
+// threads themselves, corteza attaches replies of replies to the root.
+func (svc service) Find(f ThreadFilter) (*Thread, error) {
+	// Only roots are found without a thread filter, in readable channels
+	mm, _, err := svc.messages.Find(messagingTypes.MessageFilter{FromID: f.ThreadID, ToID: f.ThreadID})
+	if err != nil {
+		return nil, err
+	} else if len(mm) == 0 {
+		return nil, ErrThreadNotFound.withStack().WithID("threadID", f.ThreadID)
+	}
+
+	root := mm[0]
+
+	replies, _, err := svc.messages.Find(messagingTypes.MessageFilter{
+		ChannelID: []uint64{root.ChannelID},
+		ThreadID:  []uint64{root.ID},
+		Query:     f.Query,
+		BeforeID:  f.BeforeID,
+		Limit:     f.Limit,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	pp, err := svc.repository.Participants(root.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		userID = auth.GetIdentityFromContext(svc.ctx).Identity()
+		out    = &Thread{
+			Root:         payload.Message(svc.ctx, root),
+			Replies:      payload.Messages(svc.ctx, replies),
+			Participants: make([]string, len(pp)),
+		}
+	)
+
+	for i, p := range pp {
+		out.Participants[i] = payload.Uint64toa(p.UserID)
+		if p.UserID == userID {
+			out.Unread = p.Unread
+		}
+	}
+
+	return out, nil
+}
+
+// Unread returns current user's unread counts of threads
+func (svc service) Unread() (ThreadUnreadSet, error) {
+	return svc.repository.Unread(auth.GetIdentityFromContext(svc.ctx).Identity())
+}
+
+// replied notifies participants of the thread, except the author of the reply
+func (svc service) replied(m *messagingTypes.Message) {
+	defer sentry.Recover()
+
+	pp, err := svc.repository.Participants(m.ReplyTo)
+	if err != nil {
+		svc.log(zap.Uint64("threadID", m.ReplyTo), zap.Error(err)).Error("could not notify thread participants")
+		return
+	}
+
+	for _, p := range pp {
+		if p.UserID == m.UserID {
+			continue
+		}
+
+		if s := live.UserScope(p.UserID); live.Subscribed(s) {
+			live.Publish(&live.Event{Scope: s, Type: live.EventThreadReply, Payload: &live.ThreadEvent{
+				ChannelID: m.ChannelID,
+				ThreadID:  m.ReplyTo,
+				MessageID: m.ID,
+				UserID:    m.UserID,
+				Unread:    p.Unread,
+			}})
+		}
+	}
+}
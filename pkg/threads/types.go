@@ -0,0 +1,48 @@
+package threads
+
+import (
+	"github.com/cortezaproject/corteza-server/pkg/payload/outgoing"
+)
+
+type (
+	// Thread is the root message with its replies
+	Thread struct {
+		Root    *outgoing.Message    `json:"root"`
+		Replies *outgoing.MessageSet `json:"replies"`
+
+		// Users that started the thread or replied to it
+		Participants []string `json:"participants"`
+
+		// Unread replies of the current user
+		Unread uint32 `json:"unread"`
+	}
+
+	// ThreadFilter selects replies of the thread, newest first
+	ThreadFilter struct {
+		ThreadID uint64
+
+		// Replies that contain the query
+		Query string
+
+		// Replies before the given one (exclusive), for paging
+		BeforeID uint64
+
+		Limit uint
+	}
+
+	// ThreadUnread is user's unread count of a thread
+	ThreadUnread struct {
+		ChannelID     uint64 `json:"channelID,string" db:"rel_channel"`
+		ThreadID      uint64 `json:"threadID,string" db:"rel_reply_to"`
+		LastMessageID uint64 `json:"lastMessageID,string" db:"rel_last_message"`
+		Count         uint32 `json:"count" db:"count"`
+	}
+
+	ThreadUnreadSet []*ThreadUnread
+
+	// participant of a thread and their unread count
+	participant struct {
+		UserID uint64 `db:"rel_user"`
+		Unread uint32 `db:"unread"`
+	}
+)
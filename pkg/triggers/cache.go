@@ -0,0 +1,65 @@
+package triggers
+
+import (
+	"sync"
+
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+type (
+	// filterCache keeps parsed filter expressions by trigger ID
+	//
+	// Filters are evaluated on every record change of the filtered module,
+	// so they are kept in memory and reloaded periodically.
+	filterCache struct {
+		sync.RWMutex
+		exprs map[uint64]*expr.Expr
+	}
+)
+
+var (
+	filters = &filterCache{exprs: map[uint64]*expr.Expr{}}
+)
+
+func (c *filterCache) get(triggerID uint64) *expr.Expr {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.exprs[triggerID]
+}
+
+func (c *filterCache) set(triggerID uint64, e *expr.Expr) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.exprs[triggerID] = e
+}
+
+func (c *filterCache) remove(triggerID uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.exprs, triggerID)
+}
+
+// reload replaces all cached filters; unparsable expressions are skipped
+// and returned so that they can be reported
+func (c *filterCache) reload(set FilterSet) (invalid FilterSet) {
+	var exprs = make(map[uint64]*expr.Expr, len(set))
+
+	for _, f := range set {
+		e, err := expr.Parse(f.Expression)
+		if err != nil {
+			invalid = append(invalid, f)
+			continue
+		}
+
+		exprs[f.TriggerID] = e
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.exprs = exprs
+	return
+}
@@ -0,0 +1,138 @@
+package triggers
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/cortezaproject/corteza-server/compose/proto"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+	"github.com/cortezaproject/corteza-server/pkg/automation/corredor"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+type (
+	// client wraps script runner client and skips record scripts
+	// when filters of their triggers do not match the change
+	client struct {
+		corredor.ScriptRunnerClient
+
+		scripts scriptFinder
+		logger  *zap.Logger
+	}
+
+	scriptFinder interface {
+		FindRunnableScripts(resource, event string, cc ...automation.TriggerConditionChecker) automation.ScriptSet
+	}
+
+	changeKey struct{}
+
+	// change is passed through the context from the record decorator
+	// to the script runner client
+	change struct {
+		op  string
+		old *types.Record
+	}
+)
+
+func withChange(ctx context.Context, op string, old *types.Record) context.Context {
+	return context.WithValue(ctx, changeKey{}, &change{op: op, old: old})
+}
+
+// Record runs record script unless it was triggered by a record change
+// that none of its triggers' filters match
+//
+// Scripts that are run manually (without change in the context) are never filtered.
+func (c client) Record(ctx context.Context, req *corredor.RunRecordRequest, opts ...grpc.CallOption) (*corredor.RunRecordResponse, error) {
+	ch, ok := ctx.Value(changeKey{}).(*change)
+	if !ok || c.runs(ch, req) {
+		return c.ScriptRunnerClient.Record(ctx, req, opts...)
+	}
+
+	// Skipped script returns the record as it was sent
+	return &corredor.RunRecordResponse{Record: req.Record}, nil
+}
+
+// runs checks filters of script's triggers that fired on the change
+//
+// Runner does not tell which of the script's triggers fired, so filters of
+// triggers on both before & after events of the operation are checked;
+// script runs when any of them matches or has no filter.
+func (c client) runs(ch *change, req *corredor.RunRecordRequest) bool {
+	if req.Script == nil || req.Module == nil {
+		return true
+	}
+
+	var (
+		moduleID = req.Module.ModuleID
+		matched  bool
+		sc       expr.Scope
+	)
+
+	for _, s := range c.scripts.FindRunnableScripts(resourceRecord, "", automation.MakeMatcherIDCondition(moduleID)) {
+		if s.Name != req.Script.Name || s.Source != req.Script.Source {
+			continue
+		}
+
+		for _, t := range recordTriggers(s, moduleID, ch.op) {
+			matched = true
+
+			e := filters.get(t.ID)
+			if e == nil {
+				return true
+			}
+
+			if sc == nil {
+				sc = scope(ch.op, req.Module, proto.ToRecord(req.Record), ch.old)
+			}
+
+			ok, err := e.Test(sc)
+			if err != nil {
+				c.logger.Warn("could not evaluate trigger filter", zap.Uint64("triggerID", t.ID), zap.Error(err))
+				continue
+			}
+
+			if ok {
+				return true
+			}
+		}
+	}
+
+	// Script is run when we can not find its triggers (scripts were reloaded)
+	return !matched
+}
+
+// filtered checks if any of the module's record triggers has a filter
+func (c client) filtered(moduleID uint64) bool {
+	for _, s := range c.scripts.FindRunnableScripts(resourceRecord, "", automation.MakeMatcherIDCondition(moduleID)) {
+		for _, t := range recordTriggers(s, moduleID, "update") {
+			if filters.get(t.ID) != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// recordTriggers returns script's valid triggers on module's records for the operation
+func recordTriggers(s *automation.Script, moduleID uint64, op string) (out automation.TriggerSet) {
+	var condition = strconv.FormatUint(moduleID, 10)
+
+	for _, t := range s.Triggers() {
+		if !t.IsValid() || t.Resource != resourceRecord || t.Condition != condition {
+			continue
+		}
+
+		for _, e := range operations[op] {
+			if t.Event == e {
+				out = append(out, t)
+			}
+		}
+	}
+
+	return
+}
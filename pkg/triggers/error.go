@@ -0,0 +1,36 @@
+package triggers
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	triggersError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrExpressionRequired = triggersError{"ExpressionRequired", fault.Invalid}
+	ErrInvalidExpression  = triggersError{"InvalidExpression", fault.Invalid}
+	ErrNotFilterable      = triggersError{"NotFilterable", fault.Invalid}
+	ErrNoPermissions      = triggersError{"NoPermissions", fault.Forbidden}
+	ErrFilterNotFound     = triggersError{"FilterNotFound", fault.NotFound}
+)
+
+func (e triggersError) Error() string {
+	return e.String()
+}
+
+func (e triggersError) String() string {
+	return "crust.triggers." + e.name
+}
+
+func (e triggersError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e triggersError) withStack() *fault.Error {
+	return fault.New(e)
+}
@@ -0,0 +1,27 @@
+package triggers
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200206000000.triggers",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_trigger_filter (
+  rel_trigger        BIGINT UNSIGNED NOT NULL,
+  rel_namespace      BIGINT UNSIGNED NOT NULL,
+  expression         TEXT            NOT NULL,
+
+  updated_by         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (rel_trigger),
+  INDEX (rel_namespace)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
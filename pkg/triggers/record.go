@@ -0,0 +1,58 @@
+package triggers
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	// record wraps record service and passes record changes
+	// to the trigger filters
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service so that scripts of filtered triggers
+// know what operation triggered them
+//
+// Record is reloaded as superuser before the update when any of module's
+// triggers has a filter, so that filters can compare values.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	return svc.RecordService.With(withChange(svc.ctx, "create", nil)).Create(r)
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	var (
+		old *types.Record
+		err error
+	)
+
+	if defaultClient.filtered(r.ModuleID) {
+		old, err = svc.RecordService.With(auth.SetSuperUserContext(svc.ctx)).FindByID(r.NamespaceID, r.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.RecordService.With(withChange(svc.ctx, "update", old)).Update(r)
+}
+
+func (svc record) DeleteByID(namespaceID, recordID uint64) error {
+	return svc.RecordService.With(withChange(svc.ctx, "delete", nil)).DeleteByID(namespaceID, recordID)
+}
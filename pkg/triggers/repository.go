@@ -0,0 +1,98 @@
+package triggers
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_trigger_filter"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"rel_trigger",
+			"rel_namespace",
+			"expression",
+			"updated_by",
+			"created_at",
+			"updated_at",
+		).
+		From(r.table())
+}
+
+func (r repository) FindByTriggerID(namespaceID, triggerID uint64) (*Filter, error) {
+	var (
+		f = &Filter{}
+		q = r.query().Where(squirrel.Eq{"rel_trigger": triggerID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, f); err != nil {
+		return nil, err
+	} else if f.TriggerID == 0 {
+		return nil, ErrFilterNotFound.withStack().WithID("namespaceID", namespaceID).WithID("triggerID", triggerID)
+	}
+
+	return f, nil
+}
+
+func (r repository) Find(namespaceID uint64) (set FilterSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": namespaceID}).
+		OrderBy("rel_trigger")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindAll returns filters of all namespaces
+func (r repository) FindAll() (set FilterSet, err error) {
+	return set, rh.FetchAll(r.db(), r.query(), &set)
+}
+
+func (r repository) Create(f *Filter) (*Filter, error) {
+	rh.SetCurrentTimeRounded(&f.CreatedAt)
+
+	return f, errors.WithStack(r.db().Insert(r.table(), f))
+}
+
+func (r repository) Update(f *Filter) (*Filter, error) {
+	rh.SetCurrentTimeRounded(&f.UpdatedAt)
+
+	return f, errors.WithStack(r.db().Replace(r.table(), f))
+}
+
+func (r repository) DeleteByTriggerID(namespaceID, triggerID uint64) error {
+	return rh.Delete(
+		r.db(),
+		r.table(),
+		squirrel.Eq{"rel_trigger": triggerID, "rel_namespace": namespaceID},
+	)
+}
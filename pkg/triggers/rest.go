@@ -0,0 +1,47 @@
+package triggers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts trigger filter endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/", rest.Handler("TriggerFilter.List", func(r *http.Request) (interface{}, error) {
+		return DefaultTriggers.With(r.Context()).FindFilters(rest.ParamUint64(r, "namespaceID"))
+	}))
+
+	r.Get("/{triggerID}", rest.Handler("TriggerFilter.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultTriggers.With(r.Context()).FindFilter(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "triggerID"),
+		)
+	}))
+
+	r.Put("/{triggerID}", rest.Handler("TriggerFilter.Save", func(r *http.Request) (interface{}, error) {
+		f := &Filter{}
+		if err := rest.Decode(r, f); err != nil {
+			return nil, err
+		}
+
+		f.TriggerID = rest.ParamUint64(r, "triggerID")
+		f.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultTriggers.With(r.Context()).SaveFilter(f)
+	}))
+
+	r.Delete("/{triggerID}", rest.Handler("TriggerFilter.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultTriggers.With(r.Context()).DeleteFilter(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "triggerID"),
+		)
+	}))
+}
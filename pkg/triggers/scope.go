@@ -0,0 +1,107 @@
+package triggers
+
+import (
+	"github.com/cortezaproject/corteza-server/compose/proto"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+// scope prepares variables & functions for filter evaluation
+//
+// Old record is empty for created records and the same as the record
+// for deleted ones.
+func scope(op string, m *proto.Module, r, old *types.Record) expr.Scope {
+	if op == "delete" {
+		old = r
+	}
+
+	return expr.Scope{
+		"record":    recordScope(m, r),
+		"old":       recordScope(m, old),
+		"operation": op,
+		"changed": expr.Func(func(args ...interface{}) (interface{}, error) {
+			var names []string
+
+			for _, a := range args {
+				if s, ok := a.(string); ok {
+					names = append(names, s)
+				}
+			}
+
+			if len(args) == 0 {
+				for _, f := range m.GetFields() {
+					names = append(names, f.Name)
+				}
+			}
+
+			for _, name := range names {
+				if !sameValues(values(r, name), values(old, name)) {
+					return true, nil
+				}
+			}
+
+			return false, nil
+		}),
+	}
+}
+
+func recordScope(m *proto.Module, r *types.Record) expr.Scope {
+	var (
+		vv = expr.Scope{}
+		rs = expr.Scope{"values": vv}
+	)
+
+	if r == nil {
+		return rs
+	}
+
+	rs["recordID"] = payload.Uint64toa(r.ID)
+	rs["ownedBy"] = payload.Uint64toa(r.OwnedBy)
+	rs["createdBy"] = payload.Uint64toa(r.CreatedBy)
+	rs["createdAt"] = r.CreatedAt
+	rs["updatedAt"] = r.UpdatedAt
+
+	for _, f := range m.GetFields() {
+		ss := values(r, f.Name)
+
+		if f.IsMulti {
+			vv[f.Name] = ss
+		} else if len(ss) > 0 {
+			vv[f.Name] = ss[0]
+		}
+	}
+
+	return rs
+}
+
+func values(r *types.Record, name string) []string {
+	if r == nil {
+		return nil
+	}
+
+	var (
+		vv = r.Values.FilterByName(name)
+		ss = make([]string, 0, len(vv))
+	)
+
+	for _, v := range vv {
+		ss = append(ss, v.Value)
+	}
+
+	return ss
+}
+
+func sameValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
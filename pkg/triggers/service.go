@@ -0,0 +1,272 @@
+package triggers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/automation"
+	"github.com/cortezaproject/corteza-server/pkg/automation/corredor"
+	"github.com/cortezaproject/corteza-server/pkg/cli/options"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/expr"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/retry"
+	"github.com/crusttech/crust-server/pkg/sandbox"
+)
+
+type (
+	triggersService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		trigger triggerLoader
+		script  scriptLoader
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanUpdateAutomationScript(context.Context, *automation.Script) bool
+	}
+
+	triggerLoader interface {
+		FindByID(ctx context.Context, triggerID uint64) (*automation.Trigger, error)
+	}
+
+	scriptLoader interface {
+		FindByID(ctx context.Context, namespaceID, scriptID uint64) (*automation.Script, error)
+	}
+
+	TriggersService interface {
+		With(ctx context.Context) TriggersService
+
+		FindFilters(namespaceID uint64) (FilterSet, error)
+		FindFilter(namespaceID, triggerID uint64) (*Filter, error)
+		SaveFilter(*Filter) (*Filter, error)
+		DeleteFilter(namespaceID, triggerID uint64) error
+	}
+)
+
+var (
+	DefaultTriggers TriggersService
+
+	// defaultClient is used by the record decorator
+	defaultClient *client
+)
+
+// Init initializes trigger filters and replaces compose's automation runner
+// with one that checks them before record scripts are run, accounts
+// script executions and retries failed ones
+//
+// Must be called after compose services are initialized and before any
+// other extension decorates record service, since record service is
+// recreated with the new runner. Filters can be managed but are not
+// applied when Corredor is disabled.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &triggersService{
+		logger:  log,
+		ac:      service.DefaultAccessControl,
+		trigger: service.DefaultAutomationTriggerManager,
+		script:  service.DefaultAutomationScriptManager,
+	}
+
+	DefaultTriggers = svc.With(ctx)
+
+	svc.reload(ctx)
+	go svc.watch(ctx)
+
+	opt := options.Corredor("compose")
+	if !opt.Enabled {
+		return nil
+	}
+
+	conn, err := corredor.NewConnection(ctx, *opt, log)
+	if err != nil {
+		return errors.Wrap(err, "could not connect to corredor")
+	}
+
+	// Filtered scripts are skipped before they are accounted;
+	// runs rejected by the sandbox are retried
+	defaultClient = &client{
+		ScriptRunnerClient: retry.Client(sandbox.Client(corredor.NewScriptRunnerClient(conn))),
+		scripts:            service.DefaultInternalAutomationManager,
+		logger:             log,
+	}
+
+	service.DefaultAutomationRunner = service.AutomationRunner(
+		service.AutomationRunnerOpt{
+			ApiBaseURLSystem:    opt.ApiBaseURLSystem,
+			ApiBaseURLMessaging: opt.ApiBaseURLMessaging,
+			ApiBaseURLCompose:   opt.ApiBaseURLCompose,
+		},
+		service.DefaultInternalAutomationManager,
+		defaultClient,
+	)
+
+	// Record service holds the runner it was created with
+	service.DefaultRecord = Record(service.Record())
+
+	return nil
+}
+
+func (svc triggersService) With(ctx context.Context) TriggersService {
+	return &triggersService{
+		ctx:     ctx,
+		logger:  svc.logger,
+		ac:      svc.ac,
+		trigger: svc.trigger,
+		script:  svc.script,
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc triggersService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// FindFilters returns namespace's filters of triggers that user can manage
+func (svc triggersService) FindFilters(namespaceID uint64) (FilterSet, error) {
+	set, err := svc.repository.Find(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := FilterSet{}
+	for _, f := range set {
+		if _, err = svc.canUpdate(namespaceID, f.TriggerID); err == nil {
+			out = append(out, f)
+		}
+	}
+
+	return out, nil
+}
+
+func (svc triggersService) FindFilter(namespaceID, triggerID uint64) (*Filter, error) {
+	if _, err := svc.canUpdate(namespaceID, triggerID); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.FindByTriggerID(namespaceID, triggerID)
+}
+
+// SaveFilter sets or replaces trigger's filter
+func (svc triggersService) SaveFilter(in *Filter) (*Filter, error) {
+	t, err := svc.canUpdate(in.NamespaceID, in.TriggerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Resource != resourceRecord || !filterable(t.Event) {
+		return nil, ErrNotFilterable.withStack()
+	}
+
+	if in.Expression == "" {
+		return nil, ErrExpressionRequired.withStack()
+	}
+
+	e, err := expr.Parse(in.Expression)
+	if err != nil {
+		return nil, ErrInvalidExpression.withStack()
+	}
+
+	f, err := svc.repository.FindByTriggerID(in.NamespaceID, in.TriggerID)
+	if err != nil && !fault.Is(err, ErrFilterNotFound) {
+		return nil, err
+	}
+
+	if f == nil {
+		f, err = svc.repository.Create(&Filter{
+			TriggerID:   in.TriggerID,
+			NamespaceID: in.NamespaceID,
+			Expression:  in.Expression,
+			UpdatedBy:   auth.GetIdentityFromContext(svc.ctx).Identity(),
+		})
+	} else {
+		f.Expression = in.Expression
+		f.UpdatedBy = auth.GetIdentityFromContext(svc.ctx).Identity()
+		f, err = svc.repository.Update(f)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	filters.set(f.TriggerID, e)
+	return f, nil
+}
+
+// DeleteFilter removes trigger's filter; trigger's script runs on every change again
+func (svc triggersService) DeleteFilter(namespaceID, triggerID uint64) error {
+	if _, err := svc.canUpdate(namespaceID, triggerID); err != nil {
+		return err
+	}
+
+	if _, err := svc.repository.FindByTriggerID(namespaceID, triggerID); err != nil {
+		return err
+	}
+
+	if err := svc.repository.DeleteByTriggerID(namespaceID, triggerID); err != nil {
+		return err
+	}
+
+	filters.remove(triggerID)
+	return nil
+}
+
+// canUpdate loads trigger and checks if user can update its script
+func (svc triggersService) canUpdate(namespaceID, triggerID uint64) (*automation.Trigger, error) {
+	t, err := svc.trigger.FindByID(svc.ctx, triggerID)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := svc.script.FindByID(svc.ctx, namespaceID, t.ScriptID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !svc.ac.CanUpdateAutomationScript(svc.ctx, s) {
+		return nil, ErrNoPermissions.withStack()
+	}
+
+	return t, nil
+}
+
+// reload loads filters of all namespaces into the cache
+func (svc triggersService) reload(ctx context.Context) {
+	set, err := Repository(ctx, nil).FindAll()
+	if err != nil {
+		svc.logger.Error("could not load trigger filters", zap.Error(err))
+		return
+	}
+
+	for _, f := range filters.reload(set) {
+		svc.logger.Warn("skipping invalid trigger filter", zap.Uint64("triggerID", f.TriggerID))
+	}
+}
+
+// watch reloads filters to pick up changes made on other instances
+func (svc triggersService) watch(ctx context.Context) {
+	t := time.NewTicker(reloadInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			svc.reload(ctx)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package triggers
+
+import (
+	"time"
+)
+
+type (
+	// Filter is a condition that record automation trigger must meet
+	// for its script to run
+	//
+	// Expression is evaluated before the script is invoked; script is not
+	// run when it evaluates to a falsy value. Expression can access:
+	//   - record.values.<field>, record.recordID, record.ownedBy, ...
+	//   - old.values.<field> etc. with values before the update
+	//   - operation ("create", "update" or "delete")
+	//   - changed(<field>, ...) (any values when called without fields)
+	Filter struct {
+		TriggerID   uint64 `json:"triggerID,string" db:"rel_trigger"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+		Expression  string `json:"expression" db:"expression"`
+
+		UpdatedBy uint64     `json:"updatedBy,string" db:"updated_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	FilterSet []*Filter
+)
+
+const (
+	resourceRecord = "compose:record"
+
+	// how often are filters reloaded from the database
+	// (changes on other instances)
+	reloadInterval = time.Minute
+)
+
+var (
+	// Record operations and events of triggers that can be filtered
+	operations = map[string][]string{
+		"create": {"beforeCreate", "afterCreate"},
+		"update": {"beforeUpdate", "afterUpdate"},
+		"delete": {"beforeDelete", "afterDelete"},
+	}
+)
+
+// filterable checks if event is a record event that can have a filter
+func filterable(event string) bool {
+	for _, ee := range operations {
+		for _, e := range ee {
+			if e == event {
+				return true
+			}
+		}
+	}
+
+	return false
+}
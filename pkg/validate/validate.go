@@ -0,0 +1,228 @@
+// Package validate checks request structs against rules in validate tags
+//
+// Supported rules, separated with commas:
+//
+//	required     value is not empty (zero, blank string, empty slice or nil)
+//	max=N        at most N characters, items or N for numbers
+//	min=N        at least N characters, items or N for numbers
+//	oneof=a b c  value is one of the listed (enum)
+//
+// Rules other than required are not checked for empty values.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// FieldError describes rule that the field does not pass
+	FieldError struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
+	}
+
+	// Errors holds all failed fields of the struct
+	Errors []*FieldError
+
+	rule struct {
+		name string
+		arg  string
+		num  float64
+		set  map[string]bool
+	}
+
+	field struct {
+		index int
+		name  string
+		rules []*rule
+	}
+)
+
+const (
+	RuleRequired = "required"
+	RuleMax      = "max"
+	RuleMin      = "min"
+	RuleOneOf    = "oneof"
+)
+
+var (
+	// Parsed rules of struct types
+	cache = sync.Map{}
+)
+
+func (ee Errors) Error() string {
+	return "crust.validate.Invalid"
+}
+
+// Struct checks fields of the struct (or pointer to it) and returns Errors
+// with all fields that do not pass their rules
+func Struct(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return errors.Errorf("can not validate %T", v)
+	}
+
+	ff, err := fields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	var ee Errors
+	for _, f := range ff {
+		fv := rv.Field(f.index)
+		for _, r := range f.rules {
+			if msg := r.check(fv); msg != "" {
+				ee = append(ee, &FieldError{Field: f.name, Rule: r.name, Message: msg})
+				break
+			}
+		}
+	}
+
+	if len(ee) > 0 {
+		return ee
+	}
+
+	return nil
+}
+
+// fields returns fields of the type with rules
+func fields(t reflect.Type) ([]*field, error) {
+	if ff, ok := cache.Load(t); ok {
+		return ff.([]*field), nil
+	}
+
+	var ff []*field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+
+		f := &field{index: i, name: name(sf)}
+		for _, expr := range strings.Split(tag, ",") {
+			r, err := parse(expr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid rules of %s.%s", t.Name(), sf.Name)
+			}
+
+			f.rules = append(f.rules, r)
+		}
+
+		ff = append(ff, f)
+	}
+
+	cache.Store(t, ff)
+	return ff, nil
+}
+
+func parse(expr string) (*rule, error) {
+	var (
+		r   = &rule{name: strings.TrimSpace(expr)}
+		err error
+	)
+
+	if i := strings.Index(r.name, "="); i > -1 {
+		r.name, r.arg = r.name[:i], r.name[i+1:]
+	}
+
+	switch r.name {
+	case RuleRequired:
+	case RuleMax, RuleMin:
+		if r.num, err = strconv.ParseFloat(r.arg, 64); err != nil {
+			return nil, errors.Errorf("%s requires a number", r.name)
+		}
+	case RuleOneOf:
+		r.set = map[string]bool{}
+		for _, v := range strings.Fields(r.arg) {
+			r.set[v] = true
+		}
+	default:
+		return nil, errors.Errorf("unknown rule %q", r.name)
+	}
+
+	return r, nil
+}
+
+// check returns message when the value does not pass the rule
+func (r rule) check(v reflect.Value) string {
+	if isEmpty(v) {
+		if r.name == RuleRequired {
+			return "is required"
+		}
+
+		return ""
+	}
+
+	v = reflect.Indirect(v)
+
+	switch r.name {
+	case RuleMax:
+		if size, unit := measure(v); size > r.num {
+			return fmt.Sprintf("must be at most %s%s", r.arg, unit)
+		}
+	case RuleMin:
+		if size, unit := measure(v); size < r.num {
+			return fmt.Sprintf("must be at least %s%s", r.arg, unit)
+		}
+	case RuleOneOf:
+		if !r.set[fmt.Sprint(v.Interface())] {
+			return "must be one of " + strings.Join(strings.Fields(r.arg), ", ")
+		}
+	}
+
+	return ""
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	}
+
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// measure returns length of strings and collections and value of numbers
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+
+	return 0, ""
+}
+
+// name returns name of the field as the client knows it, name of the
+// JSON property or (for path and query params) Go name in lower camel case
+func name(sf reflect.StructField) string {
+	if n := strings.Split(sf.Tag.Get("json"), ",")[0]; n != "" && n != "-" {
+		return n
+	}
+
+	rr := []rune(sf.Name)
+	rr[0] = unicode.ToLower(rr[0])
+	return string(rr)
+}
@@ -0,0 +1,53 @@
+package versions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("system").With(r.ctx)
+}
+
+// Version returns version of the set, count and checksum of rows of every query
+func (r repository) Version(k *kind, s Scope) (string, error) {
+	var vv []string
+
+	for _, q := range k.queries(s) {
+		var aux struct {
+			Count    uint64 `db:"count"`
+			Checksum uint64 `db:"checksum"`
+		}
+
+		if err := rh.FetchOne(r.db(), q, &aux); err != nil {
+			return "", err
+		}
+
+		vv = append(vv, fmt.Sprintf("%x.%x", aux.Count, aux.Checksum))
+	}
+
+	return strings.Join(vv, "."), nil
+}
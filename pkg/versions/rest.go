@@ -0,0 +1,60 @@
+package versions
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountSystemRoutes mounts versions endpoint of permissions, settings, roles and memberships
+func MountSystemRoutes(r chi.Router) {
+	mount(r, "system")
+}
+
+// MountComposeRoutes mounts versions endpoint of compose permissions and namespace's modules
+//
+// Expects to be mounted under /namespace/{namespaceID}
+func MountComposeRoutes(r chi.Router) {
+	mount(r, "compose")
+}
+
+// MountMessagingRoutes mounts versions endpoint of messaging permissions
+func MountMessagingRoutes(r chi.Router) {
+	mount(r, "messaging")
+}
+
+func mount(r chi.Router, app string) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?changedSince=<version>
+	//
+	// Responds with 304 when If-None-Match holds the current version (ETag)
+	r.Get("/", rest.Handler("Versions.Changes", func(r *http.Request) (interface{}, error) {
+		c, err := DefaultVersions.With(r.Context()).Changes(
+			app,
+			rest.ParamUint64(r, "namespaceID"),
+			r.URL.Query().Get("changedSince"),
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			etag := `"` + c.Version + `"`
+
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "no-cache")
+
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			rest.Respond(w, r, c)
+		}, nil
+	}))
+}
@@ -0,0 +1,103 @@
+package versions
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+)
+
+type (
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+
+	VersionService interface {
+		With(ctx context.Context) VersionService
+
+		Changes(app string, namespaceID uint64, since string) (*Changes, error)
+	}
+)
+
+var (
+	DefaultVersions VersionService
+
+	// Sets of resources by app
+	apps = map[string][]*kind{
+		"system":    systemKinds,
+		"compose":   composeKinds,
+		"messaging": messagingKinds,
+	}
+)
+
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultVersions = (&service{logger: log}).With(ctx)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) VersionService {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+	}
+}
+
+// Changes returns versions of app's resource sets and sets that changed since the given version
+//
+// Version is a token of versions of all sets; sets changed since it
+// (or all, when the token is empty or invalid) are listed as changed.
+// Compose sets are of the namespace, it must be readable by the user.
+func (svc service) Changes(app string, namespaceID uint64, since string) (*Changes, error) {
+	var (
+		s = Scope{
+			UserID:      auth.GetIdentityFromContext(svc.ctx).Identity(),
+			NamespaceID: namespaceID,
+		}
+
+		out = &Changes{Versions: Versions{}, Changed: []string{}}
+		old = parse(since)
+	)
+
+	if app == "compose" {
+		if _, err := composeService.DefaultNamespace.With(svc.ctx).FindByID(namespaceID); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, k := range apps[app] {
+		v, err := Repository(svc.ctx, factory.Database.MustGet(k.database).With(svc.ctx)).Version(k, s)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Versions[k.name] = v
+		if old[k.name] != v {
+			out.Changed = append(out.Changed, k.name)
+		}
+	}
+
+	// Keys of maps are sorted, tokens of the same versions are equal
+	token, err := json.Marshal(out.Versions)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Version = base64.RawURLEncoding.EncodeToString(token)
+	return out, nil
+}
+
+// parse returns versions of the token, nil if it is invalid
+func parse(token string) (vv Versions) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(b, &vv) != nil {
+		return nil
+	}
+
+	return vv
+}
@@ -0,0 +1,110 @@
+package versions
+
+import (
+	"strings"
+
+	"github.com/Masterminds/squirrel"
+)
+
+type (
+	// Versions of resource sets by their kind ("permissions", "settings", ...)
+	Versions map[string]string
+
+	// Changes of resource sets since the version the client has
+	Changes struct {
+		// Token of the current versions; clients pass it back as changedSince
+		Version string `json:"version"`
+
+		// Kinds of sets that changed, all of them when client has no (valid) version
+		Changed []string `json:"changed"`
+
+		Versions Versions `json:"versions"`
+	}
+
+	// Scope of the sets, versions depend on the user (own settings, memberships)
+	// and the namespace (modules)
+	Scope struct {
+		UserID      uint64
+		NamespaceID uint64
+	}
+
+	// kind of resource set
+	//
+	// Version of the set is made of row count and checksum of rows of the
+	// queries, tables of the sets do not track deleted rows.
+	kind struct {
+		name     string
+		database string
+		queries  func(s Scope) []squirrel.SelectBuilder
+	}
+)
+
+var (
+	systemKinds = []*kind{
+		{name: "permissions", database: "system", queries: permissions("sys_permission_rules")},
+		{name: "settings", database: "system", queries: settings},
+		{name: "roles", database: "system", queries: roles},
+		{name: "memberships", database: "system", queries: memberships},
+	}
+
+	composeKinds = []*kind{
+		{name: "permissions", database: "compose", queries: permissions("compose_permission_rules")},
+		{name: "modules", database: "compose", queries: modules},
+	}
+
+	messagingKinds = []*kind{
+		{name: "permissions", database: "messaging", queries: permissions("messaging_permission_rules")},
+	}
+)
+
+// checksum selects count and checksum of rows, made of the columns
+//
+// Checksums of rows are XORed so that the order does not matter.
+func checksum(table string, columns ...string) squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"COUNT(*) AS count",
+			"COALESCE(BIT_XOR(CRC32(CONCAT_WS(',', "+strings.Join(columns, ", ")+"))), 0) AS checksum",
+		).
+		From(table)
+}
+
+func permissions(table string) func(Scope) []squirrel.SelectBuilder {
+	return func(Scope) []squirrel.SelectBuilder {
+		return []squirrel.SelectBuilder{
+			checksum(table, "rel_role", "resource", "operation", "access"),
+		}
+	}
+}
+
+// settings the user gets, global and own
+func settings(s Scope) []squirrel.SelectBuilder {
+	return []squirrel.SelectBuilder{
+		checksum("sys_settings", "rel_owner", "name", "updated_at").
+			Where(squirrel.Eq{"rel_owner": []uint64{0, s.UserID}}),
+	}
+}
+
+func roles(Scope) []squirrel.SelectBuilder {
+	return []squirrel.SelectBuilder{
+		checksum("sys_role", "id", "name", "handle", "updated_at", "archived_at", "deleted_at"),
+	}
+}
+
+// memberships of the user
+func memberships(s Scope) []squirrel.SelectBuilder {
+	return []squirrel.SelectBuilder{
+		checksum("sys_role_member", "rel_role").
+			Where(squirrel.Eq{"rel_user": s.UserID}),
+	}
+}
+
+// modules of the namespace and their fields
+func modules(s Scope) []squirrel.SelectBuilder {
+	return []squirrel.SelectBuilder{
+		checksum("compose_module", "id", "handle", "updated_at", "deleted_at").
+			Where(squirrel.Eq{"rel_namespace": s.NamespaceID}),
+		checksum("compose_module_field", "id", "name", "updated_at", "deleted_at").
+			Where("rel_module IN (SELECT id FROM compose_module WHERE rel_namespace = ?)", s.NamespaceID),
+	}
+}
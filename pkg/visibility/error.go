@@ -0,0 +1,39 @@
+package visibility
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	visibilityError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrInvalidID         = visibilityError{"InvalidID", fault.Invalid}
+	ErrInvalidTarget     = visibilityError{"InvalidTarget", fault.Invalid}
+	ErrInvalidBlock      = visibilityError{"InvalidBlock", fault.Invalid}
+	ErrInvalidField      = visibilityError{"InvalidField", fault.Invalid}
+	ErrInvalidCondition  = visibilityError{"InvalidCondition", fault.Invalid}
+	ErrNoPermissions     = visibilityError{"NoPermissions", fault.Forbidden}
+	ErrRuleNotFound      = visibilityError{"RuleNotFound", fault.NotFound}
+	ErrNamespaceMismatch = visibilityError{"NamespaceMismatch", fault.Invalid}
+)
+
+func (e visibilityError) Error() string {
+	return e.String()
+}
+
+func (e visibilityError) String() string {
+	return "crust.visibility." + e.name
+}
+
+func (e visibilityError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e visibilityError) withStack() *fault.Error {
+	return fault.New(e)
+}
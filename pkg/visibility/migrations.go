@@ -0,0 +1,31 @@
+package visibility
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200116000000.rules",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_compose_visibility_rule (
+  id               BIGINT UNSIGNED NOT NULL,
+  rel_namespace    BIGINT UNSIGNED NOT NULL,
+  rel_page         BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  block            INT             NOT NULL DEFAULT 0,
+  rel_module       BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  field            VARCHAR(64)     NOT NULL DEFAULT '',
+  expression       TEXT            NOT NULL,
+
+  created_at       DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_namespace, rel_page),
+  INDEX (rel_namespace, rel_module)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,97 @@
+package visibility
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// record wraps record service and prevents writes to hidden fields
+	record struct {
+		service.RecordService
+		ctx context.Context
+	}
+)
+
+// Record decorates record service with field visibility checks
+//
+// Values of fields that are hidden (according to visibility rules and
+// values that are being stored) are ignored: on create they are removed,
+// on update existing values are preserved.
+func Record(rs service.RecordService) service.RecordService {
+	return &record{RecordService: rs, ctx: context.Background()}
+}
+
+func (svc record) With(ctx context.Context) service.RecordService {
+	return &record{
+		RecordService: svc.RecordService.With(ctx),
+		ctx:           ctx,
+	}
+}
+
+func (svc record) Create(r *types.Record) (*types.Record, error) {
+	hidden, err := svc.hiddenFields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Values = withoutFields(r.Values, hidden)
+	return svc.RecordService.Create(r)
+}
+
+func (svc record) Update(r *types.Record) (*types.Record, error) {
+	hidden, err := svc.hiddenFields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hidden) > 0 {
+		existing, err := svc.RecordService.FindByID(r.NamespaceID, r.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		values := withoutFields(r.Values, hidden)
+		for _, v := range existing.Values {
+			if inSlice(v.Name, hidden) {
+				values = append(values, v)
+			}
+		}
+
+		r.Values = values
+	}
+
+	return svc.RecordService.Update(r)
+}
+
+func (svc record) hiddenFields(r *types.Record) ([]string, error) {
+	m, err := service.DefaultModule.With(svc.ctx).FindByID(r.NamespaceID, r.ModuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return DefaultRule.With(svc.ctx).HiddenFields(m, r)
+}
+
+func withoutFields(vv types.RecordValueSet, names []string) (out types.RecordValueSet) {
+	out = types.RecordValueSet{}
+	for _, v := range vv {
+		if !inSlice(v.Name, names) {
+			out = append(out, v)
+		}
+	}
+
+	return
+}
+
+func inSlice(s string, ss []string) bool {
+	for _, i := range ss {
+		if i == s {
+			return true
+		}
+	}
+
+	return false
+}
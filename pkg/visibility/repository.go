@@ -0,0 +1,109 @@
+package visibility
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("compose").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_compose_visibility_rule"
+}
+
+func (r repository) columns() []string {
+	return []string{
+		"id",
+		"rel_namespace",
+		"rel_page",
+		"block",
+		"rel_module",
+		"field",
+		"expression",
+		"created_at",
+		"updated_at",
+	}
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(r.columns()...).
+		From(r.table())
+}
+
+func (r repository) FindByID(namespaceID, ruleID uint64) (*Rule, error) {
+	var (
+		rule = &Rule{}
+		q    = r.query().Where(squirrel.Eq{"id": ruleID, "rel_namespace": namespaceID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, rule); err != nil {
+		return nil, err
+	} else if rule.ID == 0 {
+		return nil, ErrRuleNotFound.withStack().WithID("namespaceID", namespaceID).WithID("ruleID", ruleID)
+	}
+
+	return rule, nil
+}
+
+func (r repository) Find(f RuleFilter) (set RuleSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_namespace": f.NamespaceID}).
+		OrderBy("id")
+
+	switch {
+	case f.PageID > 0 && f.ModuleID > 0:
+		q = q.Where(squirrel.Or{
+			squirrel.Eq{"rel_page": f.PageID},
+			squirrel.Eq{"rel_module": f.ModuleID},
+		})
+	case f.PageID > 0:
+		q = q.Where(squirrel.Eq{"rel_page": f.PageID})
+	case f.ModuleID > 0:
+		q = q.Where(squirrel.Eq{"rel_module": f.ModuleID})
+	}
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(rule *Rule) (*Rule, error) {
+	rule.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&rule.CreatedAt)
+
+	return rule, errors.WithStack(r.db().Insert(r.table(), rule))
+}
+
+func (r repository) Update(rule *Rule) (*Rule, error) {
+	rh.SetCurrentTimeRounded(&rule.UpdatedAt)
+
+	return rule, errors.WithStack(r.db().Replace(r.table(), rule))
+}
+
+func (r repository) DeleteByID(namespaceID, ruleID uint64) error {
+	return rh.Delete(r.db(), r.table(), squirrel.Eq{"id": ruleID, "rel_namespace": namespaceID})
+}
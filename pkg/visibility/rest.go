@@ -0,0 +1,62 @@
+package visibility
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts visibility rule endpoints
+//
+// Expects to be mounted under a path with {namespaceID} param
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	r.Get("/rules/", rest.Handler("Visibility.List", func(r *http.Request) (interface{}, error) {
+		return DefaultRule.With(r.Context()).Find(RuleFilter{
+			NamespaceID: rest.ParamUint64(r, "namespaceID"),
+			PageID:      rest.QueryUint64(r, "pageID"),
+			ModuleID:    rest.QueryUint64(r, "moduleID"),
+		})
+	}))
+
+	r.Post("/rules/", rest.Handler("Visibility.Create", func(r *http.Request) (interface{}, error) {
+		rule := &Rule{}
+		if err := rest.Decode(r, rule); err != nil {
+			return nil, err
+		}
+
+		rule.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultRule.With(r.Context()).Create(rule)
+	}))
+
+	r.Put("/rules/{ruleID}", rest.Handler("Visibility.Update", func(r *http.Request) (interface{}, error) {
+		rule := &Rule{}
+		if err := rest.Decode(r, rule); err != nil {
+			return nil, err
+		}
+
+		rule.ID = rest.ParamUint64(r, "ruleID")
+		rule.NamespaceID = rest.ParamUint64(r, "namespaceID")
+		return DefaultRule.With(r.Context()).Update(rule)
+	}))
+
+	r.Delete("/rules/{ruleID}", rest.Handler("Visibility.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultRule.With(r.Context()).DeleteByID(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "ruleID"),
+		)
+	}))
+
+	r.Get("/page/{pageID}", rest.Handler("Visibility.Page", func(r *http.Request) (interface{}, error) {
+		return DefaultRule.With(r.Context()).Page(
+			rest.ParamUint64(r, "namespaceID"),
+			rest.ParamUint64(r, "pageID"),
+			rest.QueryUint64(r, "recordID"),
+		)
+	}))
+}
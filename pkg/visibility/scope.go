@@ -0,0 +1,106 @@
+package visibility
+
+import (
+	"context"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+// scope prepares variables & functions for condition evaluation
+//
+// Conditions can access:
+//   - record.values.<field> (string, slice of strings for multi-value fields)
+//   - record.recordID, record.ownedBy, record.createdBy, record.createdAt
+//   - user.userID, user.roles (IDs and handles of all user's roles)
+//   - hasRole(<role ID or handle>)
+//
+// When record is nil (rendering a page for a new record), all values are empty.
+func scope(ctx context.Context, m *types.Module, r *types.Record) expr.Scope {
+	var (
+		i     = auth.GetIdentityFromContext(ctx)
+		roles = roleIdentifiers(ctx, i.Roles())
+	)
+
+	return expr.Scope{
+		"record": recordScope(m, r),
+		"user": expr.Scope{
+			"userID": payload.Uint64toa(i.Identity()),
+			"roles":  roles,
+		},
+		"hasRole": expr.Func(func(args ...interface{}) (interface{}, error) {
+			for _, a := range args {
+				for _, r := range roles {
+					if expr.Equal(a, r) {
+						return true, nil
+					}
+				}
+			}
+
+			return false, nil
+		}),
+	}
+}
+
+func recordScope(m *types.Module, r *types.Record) expr.Scope {
+	var (
+		values = expr.Scope{}
+		rs     = expr.Scope{"values": values}
+	)
+
+	if r == nil {
+		return rs
+	}
+
+	rs["recordID"] = payload.Uint64toa(r.ID)
+	rs["ownedBy"] = payload.Uint64toa(r.OwnedBy)
+	rs["createdBy"] = payload.Uint64toa(r.CreatedBy)
+	rs["createdAt"] = r.CreatedAt
+
+	if m == nil {
+		return rs
+	}
+
+	for _, f := range m.Fields {
+		vv := r.Values.FilterByName(f.Name)
+
+		if f.Multi {
+			ss := make([]string, 0, len(vv))
+			for _, v := range vv {
+				ss = append(ss, v.Value)
+			}
+
+			values[f.Name] = ss
+		} else if len(vv) > 0 {
+			values[f.Name] = vv[0].Value
+		}
+	}
+
+	return rs
+}
+
+// roleIdentifiers returns IDs and handles of the given roles
+func roleIdentifiers(ctx context.Context, roleIDs []uint64) []string {
+	var out = payload.Uint64stoa(roleIDs)
+
+	if len(roleIDs) == 0 || service.DefaultSystemRole == nil {
+		return out
+	}
+
+	rr, err := service.DefaultSystemRole.Find(ctx)
+	if err != nil {
+		// Handles are not crucial; we can still check against IDs
+		return out
+	}
+
+	for _, roleID := range roleIDs {
+		if r := rr.FindByID(roleID); r != nil && r.Handle != "" {
+			out = append(out, r.Handle)
+		}
+	}
+
+	return out
+}
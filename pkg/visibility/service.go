@@ -0,0 +1,326 @@
+package visibility
+
+import (
+	"context"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/cortezaproject/corteza-server/compose/service"
+	"github.com/cortezaproject/corteza-server/compose/types"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/crusttech/crust-server/pkg/expr"
+)
+
+type (
+	ruleService struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac accessController
+
+		page   service.PageService
+		module service.ModuleService
+		record service.RecordService
+
+		repository *repository
+	}
+
+	accessController interface {
+		CanReadPage(context.Context, *types.Page) bool
+		CanUpdatePage(context.Context, *types.Page) bool
+		CanReadModule(context.Context, *types.Module) bool
+		CanUpdateModule(context.Context, *types.Module) bool
+	}
+
+	RuleService interface {
+		With(ctx context.Context) RuleService
+
+		Find(RuleFilter) (RuleSet, error)
+		Create(*Rule) (*Rule, error)
+		Update(*Rule) (*Rule, error)
+		DeleteByID(namespaceID, ruleID uint64) error
+
+		Page(namespaceID, pageID, recordID uint64) (*Page, error)
+		HiddenFields(m *types.Module, r *types.Record) ([]string, error)
+	}
+)
+
+var (
+	DefaultRule RuleService
+)
+
+// Init initializes visibility rule service and hooks
+// field visibility rules into record service
+//
+// Must be called after compose services are initialized
+func Init(ctx context.Context, log *zap.Logger) error {
+	DefaultRule = (&ruleService{
+		logger: log,
+		ac:     service.DefaultAccessControl,
+		page:   service.DefaultPage,
+		module: service.DefaultModule,
+		record: service.DefaultRecord,
+	}).With(ctx)
+
+	service.DefaultRecord = Record(service.DefaultRecord)
+
+	return nil
+}
+
+func (svc ruleService) With(ctx context.Context) RuleService {
+	return &ruleService{
+		ctx:    ctx,
+		logger: svc.logger,
+		ac:     svc.ac,
+
+		page:   svc.page.With(ctx),
+		module: svc.module.With(ctx),
+		record: svc.record.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("compose").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc ruleService) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+func (svc ruleService) Find(f RuleFilter) (RuleSet, error) {
+	if f.NamespaceID == 0 {
+		return nil, service.ErrNamespaceRequired
+	}
+
+	if f.PageID > 0 {
+		if _, err := svc.page.FindByID(f.NamespaceID, f.PageID); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.ModuleID > 0 {
+		if _, err := svc.module.FindByID(f.NamespaceID, f.ModuleID); err != nil {
+			return nil, err
+		}
+	}
+
+	return svc.repository.Find(f)
+}
+
+func (svc ruleService) Create(rule *Rule) (*Rule, error) {
+	if err := svc.validate(rule); err != nil {
+		return nil, err
+	}
+
+	return svc.repository.Create(rule)
+}
+
+func (svc ruleService) Update(upd *Rule) (*Rule, error) {
+	rule, err := svc.repository.FindByID(upd.NamespaceID, upd.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check permissions on the existing target as well
+	if err = svc.validate(rule); err != nil {
+		return nil, err
+	}
+
+	if err = svc.validate(upd); err != nil {
+		return nil, err
+	}
+
+	rule.PageID = upd.PageID
+	rule.Block = upd.Block
+	rule.ModuleID = upd.ModuleID
+	rule.Field = upd.Field
+	rule.Condition = upd.Condition
+
+	return svc.repository.Update(rule)
+}
+
+func (svc ruleService) DeleteByID(namespaceID, ruleID uint64) error {
+	rule, err := svc.repository.FindByID(namespaceID, ruleID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.validate(rule); err != nil {
+		return err
+	}
+
+	return svc.repository.DeleteByID(namespaceID, ruleID)
+}
+
+// validate checks rule's target, condition and permissions to manage it
+func (svc ruleService) validate(rule *Rule) error {
+	if rule.NamespaceID == 0 {
+		return service.ErrNamespaceRequired
+	}
+
+	if rule.IsBlockRule() == rule.IsFieldRule() {
+		// Either both or none
+		return ErrInvalidTarget.withStack()
+	}
+
+	if _, err := expr.Parse(rule.Condition); err != nil {
+		return ErrInvalidCondition.withStack()
+	}
+
+	if rule.IsBlockRule() {
+		p, err := svc.page.FindByID(rule.NamespaceID, rule.PageID)
+		if err != nil {
+			return err
+		}
+
+		if !svc.ac.CanUpdatePage(svc.ctx, p) {
+			return ErrNoPermissions.withStack()
+		}
+
+		if rule.Block < 0 || rule.Block >= len(p.Blocks) {
+			return ErrInvalidBlock.withStack()
+		}
+
+		return nil
+	}
+
+	m, err := svc.module.FindByID(rule.NamespaceID, rule.ModuleID)
+	if err != nil {
+		return err
+	}
+
+	if !svc.ac.CanUpdateModule(svc.ctx, m) {
+		return ErrNoPermissions.withStack()
+	}
+
+	if !m.Fields.HasName(rule.Field) {
+		return ErrInvalidField.withStack()
+	}
+
+	return nil
+}
+
+// Page loads page and evaluates visibility rules of its blocks
+//
+// When page is bound to a module, field rules of that module are evaluated
+// as well. Record (when given) is used as source of values for conditions.
+func (svc ruleService) Page(namespaceID, pageID, recordID uint64) (out *Page, err error) {
+	var (
+		p  *types.Page
+		m  *types.Module
+		r  *types.Record
+		rr RuleSet
+	)
+
+	if p, err = svc.page.FindByID(namespaceID, pageID); err != nil {
+		return
+	}
+
+	if p.ModuleID > 0 {
+		if m, err = svc.module.FindByID(namespaceID, p.ModuleID); err != nil {
+			return
+		}
+
+		if recordID > 0 {
+			if r, err = svc.record.FindByID(namespaceID, recordID); err != nil {
+				return
+			}
+
+			if r.ModuleID != m.ID {
+				return nil, ErrInvalidID.withStack()
+			}
+		}
+	}
+
+	if rr, err = svc.repository.Find(RuleFilter{NamespaceID: namespaceID, PageID: p.ID, ModuleID: p.ModuleID}); err != nil {
+		return
+	}
+
+	var (
+		s      = scope(svc.ctx, m, r)
+		hidden = map[int]bool{}
+		blocks = types.PageBlocks{}
+	)
+
+	out = &Page{
+		RecordID:     recordID,
+		HiddenBlocks: []int{},
+		HiddenFields: []string{},
+	}
+
+	for _, rule := range rr.BlockRules(p.ID) {
+		if !svc.test(rule, s) {
+			hidden[rule.Block] = true
+		}
+	}
+
+	for i := range p.Blocks {
+		if hidden[i] {
+			out.HiddenBlocks = append(out.HiddenBlocks, i)
+		} else {
+			blocks = append(blocks, p.Blocks[i])
+		}
+	}
+
+	if m != nil {
+		out.HiddenFields = svc.hiddenFields(rr.FieldRules(m.ID), s)
+	}
+
+	// Copy the page, we do not want to modify the original
+	pc := *p
+	pc.Blocks = blocks
+	out.Page = &pc
+
+	return out, nil
+}
+
+// HiddenFields returns names of all fields that should be hidden for the given record
+func (svc ruleService) HiddenFields(m *types.Module, r *types.Record) ([]string, error) {
+	rr, err := svc.repository.Find(RuleFilter{NamespaceID: m.NamespaceID, ModuleID: m.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rr) == 0 {
+		return nil, nil
+	}
+
+	return svc.hiddenFields(rr.FieldRules(m.ID), scope(svc.ctx, m, r)), nil
+}
+
+func (svc ruleService) hiddenFields(rr RuleSet, s expr.Scope) []string {
+	var (
+		hidden = []string{}
+		seen   = map[string]bool{}
+	)
+
+	for _, rule := range rr {
+		if seen[rule.Field] || svc.test(rule, s) {
+			continue
+		}
+
+		seen[rule.Field] = true
+		hidden = append(hidden, rule.Field)
+	}
+
+	return hidden
+}
+
+// test evaluates rule's condition
+//
+// Target of a rule with a broken condition is considered hidden
+func (svc ruleService) test(rule *Rule, s expr.Scope) bool {
+	e, err := expr.Parse(rule.Condition)
+	if err == nil {
+		var visible bool
+		if visible, err = e.Test(s); err == nil {
+			return visible
+		}
+	}
+
+	svc.log(zap.Uint64("ruleID", rule.ID), zap.Error(err)).
+		Warn("could not evaluate visibility condition")
+
+	return false
+}
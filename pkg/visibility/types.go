@@ -0,0 +1,80 @@
+package visibility
+
+import (
+	"time"
+
+	"github.com/cortezaproject/corteza-server/compose/types"
+)
+
+type (
+	// Rule controls visibility of one page block or one module field
+	//
+	// Target is visible when condition evaluates to true. Page block
+	// rules have PageID and Block (index of the block in page's blocks)
+	// set, field rules have ModuleID and Field (name of the field).
+	Rule struct {
+		ID          uint64 `json:"ruleID,string" db:"id"`
+		NamespaceID uint64 `json:"namespaceID,string" db:"rel_namespace"`
+
+		PageID uint64 `json:"pageID,string,omitempty" db:"rel_page"`
+		Block  int    `json:"block" db:"block"`
+
+		ModuleID uint64 `json:"moduleID,string,omitempty" db:"rel_module"`
+		Field    string `json:"field,omitempty" db:"field"`
+
+		Condition string `json:"condition" db:"expression"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	RuleFilter struct {
+		NamespaceID uint64 `json:"namespaceID,string"`
+		PageID      uint64 `json:"pageID,string"`
+		ModuleID    uint64 `json:"moduleID,string"`
+	}
+
+	RuleSet []*Rule
+
+	// Page is page with evaluated visibility rules
+	//
+	// Hidden blocks are removed from the page, their original
+	// indexes are listed under hiddenBlocks
+	Page struct {
+		*types.Page
+
+		RecordID     uint64   `json:"recordID,string,omitempty"`
+		HiddenBlocks []int    `json:"hiddenBlocks"`
+		HiddenFields []string `json:"hiddenFields"`
+	}
+)
+
+func (r Rule) IsBlockRule() bool {
+	return r.PageID > 0
+}
+
+func (r Rule) IsFieldRule() bool {
+	return r.ModuleID > 0 && r.Field != ""
+}
+
+// BlockRules returns all rules for page blocks of the given page
+func (set RuleSet) BlockRules(pageID uint64) (out RuleSet) {
+	for i := range set {
+		if set[i].IsBlockRule() && set[i].PageID == pageID {
+			out = append(out, set[i])
+		}
+	}
+
+	return
+}
+
+// FieldRules returns all rules for fields of the given module
+func (set RuleSet) FieldRules(moduleID uint64) (out RuleSet) {
+	for i := range set {
+		if set[i].IsFieldRule() && set[i].ModuleID == moduleID {
+			out = append(out, set[i])
+		}
+	}
+
+	return
+}
@@ -0,0 +1,38 @@
+package webdav
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	webdavError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrNotFound      = webdavError{"NotFound", fault.NotFound}
+	ErrNotDirectory  = webdavError{"NotDirectory", fault.Invalid}
+	ErrReadOnly      = webdavError{"ReadOnly", fault.Forbidden}
+	ErrInvalidName   = webdavError{"InvalidName", fault.Invalid}
+	ErrAlreadyExists = webdavError{"AlreadyExists", fault.Conflict}
+	ErrUnauthorized  = webdavError{"Unauthorized", fault.Forbidden}
+	ErrFileTooLarge  = webdavError{"FileTooLarge", fault.Invalid}
+)
+
+func (e webdavError) Error() string {
+	return e.String()
+}
+
+func (e webdavError) String() string {
+	return "crust.webdav." + e.name
+}
+
+func (e webdavError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e webdavError) withStack() *fault.Error {
+	return fault.New(e)
+}
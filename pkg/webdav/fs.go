@@ -0,0 +1,354 @@
+package webdav
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	composeRepository "github.com/cortezaproject/corteza-server/compose/repository"
+	composeService "github.com/cortezaproject/corteza-server/compose/service"
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	messagingRepository "github.com/cortezaproject/corteza-server/messaging/repository"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	"github.com/crusttech/crust-server/pkg/access"
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	// filesystem resolves paths into nodes of the virtual filesystem
+	//
+	//   /channels/<channel>/<file>
+	//   /records/<namespace>/<module>/<record>/<file>
+	//
+	// Only resources that current user can read are listed and resolved
+	filesystem struct {
+		ctx    context.Context
+		logger *zap.Logger
+	}
+)
+
+func (fs filesystem) resolve(pp []string) (*node, error) {
+	if len(pp) == 0 {
+		return &node{dir: true, list: func() ([]*node, error) {
+			return []*node{
+				{name: channelsDir, dir: true},
+				{name: recordsDir, dir: true},
+			}, nil
+		}}, nil
+	}
+
+	switch pp[0] {
+	case channelsDir:
+		return fs.channels(pp[1:])
+	case recordsDir:
+		return fs.records(pp[1:])
+	}
+
+	return nil, ErrNotFound.withStack()
+}
+
+func (fs filesystem) channels(pp []string) (*node, error) {
+	svc := messagingService.DefaultChannel.With(fs.ctx)
+
+	if len(pp) == 0 {
+		return &node{name: channelsDir, dir: true, list: func() ([]*node, error) {
+			cc, _, err := svc.Find(messagingTypes.ChannelFilter{
+				CurrentUserID: auth.GetIdentityFromContext(fs.ctx).Identity(),
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			nn := make([]*node, len(cc))
+			for i, ch := range cc {
+				nn[i] = channelNode(ch)
+			}
+
+			return nn, nil
+		}}, nil
+	}
+
+	ch, err := svc.FindByID(parseID(pp[0]))
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	repo := Repository(fs.ctx)
+
+	if len(pp) == 1 {
+		n := channelNode(ch)
+		n.list = func() ([]*node, error) {
+			aa, err := repo.ChannelFiles(ch.ID, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			nn := make([]*node, len(aa))
+			for i, a := range aa {
+				nn[i] = messagingFileNode(a)
+			}
+
+			return nn, nil
+		}
+
+		if fs.writable() && ch.ArchivedAt == nil {
+			n.put = func(name string, size int64, fh io.ReadSeeker) error {
+				_, err := messagingService.DefaultAttachment.With(fs.ctx).Create(name, size, fh, ch.ID, 0)
+				return err
+			}
+		}
+
+		return n, nil
+	}
+
+	if len(pp) > 2 {
+		return nil, ErrNotFound.withStack()
+	}
+
+	aa, err := repo.ChannelFiles(ch.ID, parseID(pp[1]))
+	if err != nil {
+		return nil, err
+	} else if len(aa) == 0 {
+		return nil, ErrNotFound.withStack()
+	}
+
+	return messagingFileNode(aa[0]), nil
+}
+
+func (fs filesystem) records(pp []string) (*node, error) {
+	if len(pp) == 0 {
+		return &node{name: recordsDir, dir: true, list: func() ([]*node, error) {
+			nss, _, err := composeService.DefaultNamespace.With(fs.ctx).Find(composeTypes.NamespaceFilter{})
+			if err != nil {
+				return nil, err
+			}
+
+			nn := make([]*node, len(nss))
+			for i, ns := range nss {
+				nn[i] = &node{
+					name:    segment(ns.ID, ns.Slug),
+					dir:     true,
+					created: ns.CreatedAt,
+					updated: timeOf(ns.UpdatedAt),
+				}
+			}
+
+			return nn, nil
+		}}, nil
+	}
+
+	ns, err := composeService.DefaultNamespace.With(fs.ctx).FindByID(parseID(pp[0]))
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	if len(pp) == 1 {
+		return &node{
+			name:    segment(ns.ID, ns.Slug),
+			dir:     true,
+			created: ns.CreatedAt,
+			updated: timeOf(ns.UpdatedAt),
+			list: func() ([]*node, error) {
+				mm, _, err := composeService.DefaultModule.With(fs.ctx).Find(composeTypes.ModuleFilter{NamespaceID: ns.ID})
+				if err != nil {
+					return nil, err
+				}
+
+				nn := make([]*node, 0, len(mm))
+				for _, m := range mm {
+					if len(fs.fileFields(m)) > 0 {
+						nn = append(nn, moduleNode(m))
+					}
+				}
+
+				return nn, nil
+			},
+		}, nil
+	}
+
+	m, err := composeService.DefaultModule.With(fs.ctx).FindByID(ns.ID, parseID(pp[1]))
+	if err != nil {
+		return nil, notFound(err)
+	}
+
+	ff := fs.fileFields(m)
+	if len(ff) == 0 {
+		return nil, ErrNotFound.withStack()
+	}
+
+	repo := Repository(fs.ctx)
+
+	switch len(pp) {
+	case 2:
+		n := moduleNode(m)
+		n.list = func() ([]*node, error) {
+			set, err := repo.RecordFiles(m.ID, ff, 0, 0)
+			if err != nil {
+				return nil, err
+			}
+
+			// One directory per record, with time of the latest file
+			var (
+				nn    = []*node{}
+				index = map[uint64]*node{}
+			)
+
+			for _, f := range set {
+				if n, ok := index[f.RecordID]; ok {
+					if f.modTime().After(n.updated) {
+						n.updated = f.modTime()
+					}
+
+					continue
+				}
+
+				index[f.RecordID] = &node{name: segment(f.RecordID, ""), dir: true, created: f.modTime(), updated: f.modTime()}
+				nn = append(nn, index[f.RecordID])
+			}
+
+			return nn, nil
+		}
+
+		return n, nil
+
+	case 3:
+		recordID := parseID(pp[2])
+		set, err := repo.RecordFiles(m.ID, ff, recordID, 0)
+		if err != nil {
+			return nil, err
+		} else if len(set) == 0 {
+			return nil, ErrNotFound.withStack()
+		}
+
+		return &node{name: segment(recordID, ""), dir: true, list: func() ([]*node, error) {
+			nn := make([]*node, len(set))
+			for i, f := range set {
+				nn[i] = composeFileNode(&f.Attachment)
+			}
+
+			return nn, nil
+		}}, nil
+
+	case 4:
+		set, err := repo.RecordFiles(m.ID, ff, parseID(pp[2]), parseID(pp[3]))
+		if err != nil {
+			return nil, err
+		} else if len(set) == 0 {
+			return nil, ErrNotFound.withStack()
+		}
+
+		return composeFileNode(&set[0].Attachment), nil
+	}
+
+	return nil, ErrNotFound.withStack()
+}
+
+// fileFields returns names of module's file fields that current user can read
+func (fs filesystem) fileFields(m *composeTypes.Module) (ff []string) {
+	ac := composeService.DefaultAccessControl
+	if !ac.CanReadRecord(fs.ctx, m) {
+		return
+	}
+
+	for _, f := range access.ReadableFields(fs.ctx, m.Fields) {
+		if f.Kind == "File" {
+			ff = append(ff, f.Name)
+		}
+	}
+
+	return
+}
+
+// writable checks system settings if uploads are enabled
+func (fs filesystem) writable() bool {
+	v, err := systemService.DefaultSettings.Get(auth.SetSuperUserContext(fs.ctx), settingWrite, 0)
+	if err != nil {
+		fs.logger.Error("could not load webdav settings", zap.Error(err))
+		return false
+	}
+
+	return v.Bool()
+}
+
+func channelNode(ch *messagingTypes.Channel) *node {
+	return &node{
+		name:    segment(ch.ID, ch.Name),
+		dir:     true,
+		created: ch.CreatedAt,
+		updated: timeOf(ch.UpdatedAt),
+	}
+}
+
+func moduleNode(m *composeTypes.Module) *node {
+	name := m.Handle
+	if name == "" {
+		name = m.Name
+	}
+
+	return &node{
+		name:    segment(m.ID, name),
+		dir:     true,
+		created: m.CreatedAt,
+		updated: timeOf(m.UpdatedAt),
+	}
+}
+
+func messagingFileNode(a *messagingTypes.Attachment) *node {
+	return &node{
+		name:    segment(a.ID, a.Name),
+		size:    a.Meta.Original.Size,
+		mime:    a.Meta.Original.Mimetype,
+		created: a.CreatedAt,
+		updated: timeOf(a.UpdatedAt),
+		open: func() (io.ReadSeeker, error) {
+			return messagingService.DefaultAttachment.OpenOriginal(a)
+		},
+	}
+}
+
+func composeFileNode(a *composeTypes.Attachment) *node {
+	return &node{
+		name:    segment(a.ID, a.Name),
+		size:    a.Meta.Original.Size,
+		mime:    a.Meta.Original.Mimetype,
+		created: a.CreatedAt,
+		updated: timeOf(a.UpdatedAt),
+		open: func() (io.ReadSeeker, error) {
+			return composeService.DefaultAttachment.OpenOriginal(a)
+		},
+	}
+}
+
+func (f recordFile) modTime() time.Time {
+	return composeFileNode(&f.Attachment).modTime()
+}
+
+// notFound hides resources that do not exist or are not accessible
+func notFound(err error) error {
+	if fault.IsAny(err,
+		messagingRepository.ErrChannelNotFound,
+		messagingService.ErrNoPermissions,
+		composeRepository.ErrNamespaceNotFound,
+		composeRepository.ErrModuleNotFound,
+		composeService.ErrNoPermissions,
+		composeService.ErrNoReadPermissions,
+	) {
+		return ErrNotFound.withStack()
+	}
+
+	return err
+}
+
+func timeOf(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return *t
+}
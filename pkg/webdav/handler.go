@@ -0,0 +1,247 @@
+package webdav
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	systemService "github.com/cortezaproject/corteza-server/system/service"
+	"github.com/crusttech/crust-server/pkg/fault"
+	"github.com/crusttech/crust-server/pkg/runas"
+)
+
+var (
+	log = zap.NewNop()
+)
+
+func init() {
+	// Methods need to be known to the router before routes are mounted
+	chi.RegisterMethod("PROPFIND")
+}
+
+// Init sets logger
+func Init(ctx context.Context, l *zap.Logger) error {
+	log = l
+	return nil
+}
+
+// MountRoutes mounts WebDAV endpoint
+//
+// Endpoint can be mounted by OS file managers; besides JWT (Bearer) auth,
+// it accepts HTTP Basic auth with email & password of internal credentials
+func MountRoutes(r chi.Router) {
+	r.Use(authenticate)
+
+	r.HandleFunc("/*", serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	var (
+		name = "WebDAV." + r.Method
+		err  = handle(w, r)
+	)
+
+	if err == nil {
+		logger.LogControllerCall(name, r, nil)
+		return
+	}
+
+	logger.LogControllerError(name, r, err, nil)
+
+	switch {
+	case fault.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case fault.Is(err, ErrNotDirectory):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case fault.IsAny(err, ErrReadOnly, ErrInvalidName, ErrAlreadyExists):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case fault.Is(err, ErrFileTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func handle(w http.ResponseWriter, r *http.Request) error {
+	var (
+		fs = filesystem{ctx: r.Context(), logger: log}
+		pp = split(chi.URLParam(r, "*"))
+	)
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("DAV", "1")
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PROPFIND, PUT")
+		w.Header().Set("MS-Author-Via", "DAV")
+		return nil
+
+	case http.MethodPut:
+		if len(pp) == 0 {
+			return ErrReadOnly.withStack()
+		}
+
+		dir, err := fs.resolve(pp[:len(pp)-1])
+		if err != nil {
+			return err
+		}
+
+		return put(w, r, dir, pp[len(pp)-1])
+	}
+
+	n, err := fs.resolve(pp)
+	if err != nil {
+		return err
+	}
+
+	switch r.Method {
+	case "PROPFIND":
+		return propfind(w, r, n)
+
+	case http.MethodGet, http.MethodHead:
+		if n.dir {
+			w.Header().Set("Allow", "OPTIONS, PROPFIND")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return nil
+		}
+
+		fh, err := n.open()
+		if err != nil {
+			return err
+		} else if fh == nil {
+			return ErrNotFound.withStack()
+		}
+
+		if c, ok := fh.(io.Closer); ok {
+			defer c.Close()
+		}
+
+		if n.mime != "" {
+			w.Header().Set("Content-Type", n.mime)
+		}
+
+		w.Header().Set("ETag", n.etag())
+		http.ServeContent(w, r, n.name, n.modTime(), fh)
+		return nil
+	}
+
+	return ErrReadOnly.withStack()
+}
+
+// put stores request body as a new file in the directory
+//
+// Files can not be overwritten; hidden files (created by some
+// file managers, like .DS_Store) are rejected
+func put(w http.ResponseWriter, r *http.Request, dir *node, name string) error {
+	if !dir.dir {
+		return ErrNotDirectory.withStack()
+	}
+
+	if dir.put == nil {
+		return ErrReadOnly.withStack()
+	}
+
+	if name == "" || strings.HasPrefix(name, ".") {
+		return ErrInvalidName.withStack()
+	}
+
+	if parseID(name) > 0 {
+		// Names that start with an ID refer to existing files
+		return ErrAlreadyExists.withStack()
+	}
+
+	if r.ContentLength > maxUploadSize {
+		return ErrFileTooLarge.withStack()
+	}
+
+	// Request body is buffered into a temporary file;
+	// attachment services expect seekable content
+	tmp, err := ioutil.TempFile("", "crust-webdav-")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, io.LimitReader(r.Body, maxUploadSize+1))
+	if err != nil {
+		return err
+	} else if size > maxUploadSize {
+		return ErrFileTooLarge.withStack()
+	}
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err = dir.put(name, size, tmp); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// authenticate accepts requests with valid identity or HTTP Basic credentials
+func authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if !auth.GetIdentityFromContext(ctx).Valid() {
+			var err error
+			if ctx, err = basic(r); err != nil {
+				logger.LogControllerError("WebDAV.Auth", r, err, nil)
+				w.Header().Set("WWW-Authenticate", `Basic realm="crust", charset="UTF-8"`)
+				http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// basic verifies HTTP Basic credentials and returns context with user's identity
+func basic(r *http.Request) (context.Context, error) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(strings.ToLower(h), "basic ") {
+		return nil, ErrUnauthorized.withStack()
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(h[6:]))
+	if err != nil {
+		return nil, ErrUnauthorized.withStack()
+	}
+
+	creds := strings.SplitN(string(raw), ":", 2)
+	if len(creds) != 2 {
+		return nil, ErrUnauthorized.withStack()
+	}
+
+	u, err := systemService.DefaultAuth.With(r.Context()).InternalLogin(creds[0], creds[1])
+	if err != nil {
+		return nil, err
+	}
+
+	return runas.Compose(r.Context(), u.ID)
+}
+
+// split splits path into segments and drops empty ones
+func split(path string) (pp []string) {
+	for _, p := range strings.Split(path, "/") {
+		if p != "" {
+			pp = append(pp, p)
+		}
+	}
+
+	return
+}
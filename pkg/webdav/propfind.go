@@ -0,0 +1,112 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	multistatus struct {
+		XMLName   xml.Name   `xml:"D:multistatus"`
+		Namespace string     `xml:"xmlns:D,attr"`
+		Responses []response `xml:"D:response"`
+	}
+
+	response struct {
+		Href     string   `xml:"D:href"`
+		Propstat propstat `xml:"D:propstat"`
+	}
+
+	propstat struct {
+		Prop   prop   `xml:"D:prop"`
+		Status string `xml:"D:status"`
+	}
+
+	prop struct {
+		DisplayName   string       `xml:"D:displayname"`
+		ResourceType  resourceType `xml:"D:resourcetype"`
+		ContentLength *int64       `xml:"D:getcontentlength,omitempty"`
+		ContentType   string       `xml:"D:getcontenttype,omitempty"`
+		CreationDate  string       `xml:"D:creationdate,omitempty"`
+		LastModified  string       `xml:"D:getlastmodified,omitempty"`
+		ETag          string       `xml:"D:getetag,omitempty"`
+	}
+
+	resourceType struct {
+		Collection *struct{} `xml:"D:collection,omitempty"`
+	}
+)
+
+// propfind writes properties of the node and (with depth 1) its entries
+//
+// All properties are always returned, regardless of the ones requested;
+// infinite depth is served as depth 1
+func propfind(w http.ResponseWriter, r *http.Request, n *node) error {
+	var (
+		base = r.URL.EscapedPath()
+		ms   = multistatus{Namespace: "DAV:"}
+	)
+
+	if n.dir && !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	ms.Responses = append(ms.Responses, n.response(base))
+
+	if n.dir && r.Header.Get("Depth") != "0" {
+		nn, err := n.list()
+		if err != nil {
+			return err
+		}
+
+		for _, c := range nn {
+			href := base + url.PathEscape(c.name)
+			if c.dir {
+				href += "/"
+			}
+
+			ms.Responses = append(ms.Responses, c.response(href))
+		}
+	}
+
+	w.Header().Set("Content-Type", `application/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusMultiStatus)
+
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(ms)
+}
+
+func (n node) response(href string) response {
+	p := prop{
+		DisplayName: n.name,
+	}
+
+	if t := n.modTime(); !t.IsZero() {
+		p.LastModified = t.UTC().Format(http.TimeFormat)
+	}
+
+	if !n.created.IsZero() {
+		p.CreationDate = n.created.UTC().Format("2006-01-02T15:04:05Z")
+	}
+
+	if n.dir {
+		p.ResourceType.Collection = &struct{}{}
+	} else {
+		p.ContentLength = &n.size
+		p.ContentType = n.mime
+		p.ETag = n.etag()
+	}
+
+	return response{
+		Href: href,
+		Propstat: propstat{
+			Prop:   p,
+			Status: "HTTP/1.1 200 OK",
+		},
+	}
+}
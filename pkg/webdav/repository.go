@@ -0,0 +1,74 @@
+package webdav
+
+import (
+	"context"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/titpetric/factory"
+
+	composeTypes "github.com/cortezaproject/corteza-server/compose/types"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+	}
+
+	// recordFile is an attachment, referenced from record's file field
+	recordFile struct {
+		RecordID uint64 `db:"record_id"`
+		composeTypes.Attachment
+	}
+
+	recordFileSet []*recordFile
+)
+
+func Repository(ctx context.Context) *repository {
+	return &repository{ctx: ctx}
+}
+
+// ChannelFiles returns files attached to channel's messages, newest first
+//
+// When attachmentID is set, only that file is returned (if it belongs to the channel)
+func (r repository) ChannelFiles(channelID, attachmentID uint64) (set []*messagingTypes.Attachment, err error) {
+	q := squirrel.
+		Select("a.id", "a.rel_user", "a.url", "a.preview_url", "a.name", "a.meta", "a.created_at", "a.updated_at").
+		From("messaging_attachment AS a").
+		Join("messaging_message_attachment AS ma ON (ma.rel_attachment = a.id)").
+		Join("messaging_message AS m ON (m.id = ma.rel_message AND m.deleted_at IS NULL)").
+		Where(squirrel.Eq{"m.rel_channel": channelID, "a.deleted_at": nil}).
+		OrderBy("a.id DESC").
+		Limit(maxEntries)
+
+	if attachmentID > 0 {
+		q = q.Where(squirrel.Eq{"a.id": attachmentID})
+	}
+
+	return set, rh.FetchAll(factory.Database.MustGet("messaging").With(r.ctx), q, &set)
+}
+
+// RecordFiles returns files referenced from the given fields of module's records
+//
+// Record and attachment filters are optional
+func (r repository) RecordFiles(moduleID uint64, fields []string, recordID, attachmentID uint64) (set recordFileSet, err error) {
+	q := squirrel.
+		Select("v.record_id", "a.id", "a.rel_namespace", "a.url", "a.preview_url", "a.name", "a.meta", "a.created_at", "a.updated_at").
+		From("compose_record_value AS v").
+		Join("compose_record AS r ON (r.id = v.record_id AND r.deleted_at IS NULL)").
+		Join("compose_attachment AS a ON (a.id = v.value AND a.deleted_at IS NULL)").
+		Where(squirrel.Eq{"r.module_id": moduleID, "v.name": fields, "v.deleted_at": nil}).
+		OrderBy("v.record_id DESC", "v.place").
+		Limit(maxEntries)
+
+	if recordID > 0 {
+		q = q.Where(squirrel.Eq{"v.record_id": recordID})
+	}
+
+	if attachmentID > 0 {
+		q = q.Where(squirrel.Eq{"a.id": attachmentID})
+	}
+
+	return set, rh.FetchAll(factory.Database.MustGet("compose").With(r.ctx), q, &set)
+}
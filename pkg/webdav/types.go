@@ -0,0 +1,79 @@
+package webdav
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// node is a directory or a file in the virtual filesystem
+	node struct {
+		name    string
+		dir     bool
+		size    int64
+		mime    string
+		created time.Time
+		updated time.Time
+
+		// Lists directory entries
+		list func() ([]*node, error)
+
+		// Opens file content
+		open func() (io.ReadSeeker, error)
+
+		// Stores new file into directory; nil for read-only directories
+		put func(name string, size int64, fh io.ReadSeeker) error
+	}
+)
+
+const (
+	// Directory listings are capped to keep PROPFIND responses reasonable
+	maxEntries = 5000
+
+	// Largest file that can be uploaded
+	maxUploadSize = 100 << 20
+
+	// System setting that enables uploads
+	settingWrite = "crust.webdav.write"
+
+	channelsDir = "channels"
+	recordsDir  = "records"
+)
+
+// segment composes directory or file name from resource ID and its name
+//
+// IDs keep names unique (channels and files can share names) and are
+// used to resolve resources from paths, names are there for humans
+func segment(ID uint64, name string) string {
+	name = strings.NewReplacer("/", "-", "\\", "-").Replace(strings.TrimSpace(name))
+	if name == "" {
+		return strconv.FormatUint(ID, 10)
+	}
+
+	return strconv.FormatUint(ID, 10) + "_" + name
+}
+
+// parseID returns resource ID from the path segment; 0 when segment does not start with an ID
+func parseID(s string) uint64 {
+	if i := strings.IndexByte(s, '_'); i > 0 {
+		s = s[:i]
+	}
+
+	ID, _ := strconv.ParseUint(s, 10, 64)
+	return ID
+}
+
+// modTime returns time of the last change of the node
+func (n node) modTime() time.Time {
+	if n.updated.After(n.created) {
+		return n.updated
+	}
+
+	return n.created
+}
+
+func (n node) etag() string {
+	return `"` + strconv.FormatInt(n.modTime().UnixNano(), 36) + "-" + strconv.FormatInt(n.size, 36) + `"`
+}
@@ -0,0 +1,194 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+
+	"github.com/cortezaproject/corteza-server/pkg/sentry"
+	"github.com/crusttech/crust-server/pkg/egress"
+)
+
+var (
+	client = egress.Client(deliveryTimeout)
+)
+
+// enqueue stores the event as a delivery for every webhook of the
+// channel that is subscribed to it
+func (svc service) enqueue(event string, channelID uint64, msg interface{}) {
+	defer sentry.Recover()
+
+	var (
+		r   = Repository(svc.ctx, nil)
+		log = svc.logger.With(zap.String("event", event), zap.Uint64("channelID", channelID))
+	)
+
+	set, err := r.FindByChannelID(channelID)
+	if err != nil {
+		log.Error("could not load webhooks", zap.Error(err))
+		return
+	}
+
+	for _, w := range set {
+		if !w.Subscribed(event) {
+			continue
+		}
+
+		at := now()
+		d := &Delivery{
+			ID:            factory.Sonyflake.NextID(),
+			WebhookID:     w.ID,
+			Event:         event,
+			Status:        StatusPending,
+			NextAttemptAt: &at,
+		}
+
+		d.Payload, err = json.Marshal(body{
+			DeliveryID: d.ID,
+			WebhookID:  w.ID,
+			ChannelID:  channelID,
+			Event:      event,
+			Message:    msg,
+			Timestamp:  at,
+		})
+
+		if err != nil {
+			log.Error("could not encode delivery", zap.Error(err))
+			return
+		}
+
+		if _, err = r.CreateDelivery(d); err != nil {
+			log.Error("could not store delivery", zap.Uint64("webhookID", w.ID), zap.Error(err))
+		}
+	}
+}
+
+// deliver posts the event and stores the result of the attempt
+func (svc service) deliver(ctx context.Context, d *Delivery) {
+	var (
+		r   = Repository(ctx, nil)
+		log = svc.logger.With(zap.Uint64("deliveryID", d.ID), zap.Uint64("webhookID", d.WebhookID))
+	)
+
+	if ok, err := r.Claim(d, now().Add(claimTimeout)); err != nil {
+		log.Error("could not claim delivery", zap.Error(err))
+		return
+	} else if !ok {
+		return
+	}
+
+	d.Attempts++
+	d.LastError = ""
+	d.ResponseStatus = 0
+	d.Status = StatusDelivered
+	d.NextAttemptAt = nil
+
+	w, err := r.FindByID(d.WebhookID)
+	if err == nil {
+		d.ResponseStatus, err = post(ctx, w, d)
+	}
+
+	if err != nil {
+		d.LastError = err.Error()
+
+		if d.Attempts >= maxAttempts {
+			d.Status = StatusDead
+			log.Warn("delivery failed too many times", zap.Error(err))
+		} else {
+			at := now().Add(backoff(d.Attempts))
+			d.Status = StatusPending
+			d.NextAttemptAt = &at
+		}
+	}
+
+	if err := r.UpdateState(d); err != nil {
+		log.Error("could not store delivery state", zap.Error(err))
+	}
+}
+
+// post sends the delivery, signed with HMAC-SHA256 of the body
+//
+// Responses with status other than 2xx are errors; only the status is
+// kept, response body and URL (that might hold secrets) are not part of it.
+func post(ctx context.Context, w *Webhook, d *Delivery) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Crust-Webhook")
+	req.Header.Set("X-Webhook-Event", d.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(d.ID, 10))
+
+	if w.Secret != "" {
+		req.Header.Set("X-Webhook-Signature", signature(w.Secret, d.Payload))
+	}
+
+	rsp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+
+		return 0, errors.Wrap(err, "request failed")
+	}
+
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		return rsp.StatusCode, errors.Errorf("webhook responded with unexpected status: %d", rsp.StatusCode)
+	}
+
+	return rsp.StatusCode, nil
+}
+
+// signature returns value of the signature header, hex encoded HMAC-SHA256 of the body
+func signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// watch posts due deliveries and removes old delivered ones
+func (svc service) watch(ctx context.Context) {
+	var (
+		w = time.NewTicker(watchInterval)
+		p = time.NewTicker(pruneInterval)
+	)
+
+	defer w.Stop()
+	defer p.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.C:
+			set, err := Repository(ctx, nil).FindDue(now())
+			if err != nil {
+				svc.logger.Error("could not load due deliveries", zap.Error(err))
+				continue
+			}
+
+			for _, d := range set {
+				svc.deliver(ctx, d)
+			}
+		case <-p.C:
+			if err := Repository(ctx, nil).Prune(now().Add(-retention)); err != nil {
+				svc.logger.Error("could not remove old deliveries", zap.Error(err))
+			}
+		}
+	}
+}
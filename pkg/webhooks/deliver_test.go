@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		body   string
+		want   string
+	}{
+		{
+			// RFC 4231, test case 2
+			name:   "RFC 4231",
+			secret: "Jefe",
+			body:   "what do ya want for nothing?",
+			want:   "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		},
+		{
+			name:   "payload",
+			secret: "webhook secret",
+			body:   `{"event":"message.created","channelID":"1"}`,
+			want:   "sha256=827e8483f5265afe16eea342ea05129b541a24b49c84095cf62a7da21b926eb8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signature(tt.secret, []byte(tt.body)); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPostSignature(t *testing.T) {
+	var (
+		payload = `{"event":"message.created","channelID":"1"}`
+		headers = make(chan http.Header, 1)
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+	}))
+	defer srv.Close()
+
+	// Deliveries to loopback addresses are refused by egress client
+	defer func(c *http.Client) { client = c }(client)
+	client = srv.Client()
+
+	tests := []struct {
+		name   string
+		secret string
+		want   string
+	}{
+		{"signed", "webhook secret", "sha256=827e8483f5265afe16eea342ea05129b541a24b49c84095cf62a7da21b926eb8"},
+		{"without secret", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w = &Webhook{URL: srv.URL, Secret: tt.secret}
+				d = &Delivery{ID: 1, Event: "message.created", Payload: json.RawMessage(payload)}
+			)
+
+			if _, err := post(context.Background(), w, d); err != nil {
+				t.Fatalf("could not post delivery: %v", err)
+			}
+
+			if got := (<-headers).Get("X-Webhook-Signature"); got != tt.want {
+				t.Errorf("expected signature %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
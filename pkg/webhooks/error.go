@@ -0,0 +1,38 @@
+package webhooks
+
+import (
+	"github.com/crusttech/crust-server/pkg/fault"
+)
+
+type (
+	webhookError struct {
+		name string
+		kind fault.Kind
+	}
+)
+
+var (
+	ErrWebhookNotFound    = webhookError{"WebhookNotFound", fault.NotFound}
+	ErrDeliveryNotFound   = webhookError{"DeliveryNotFound", fault.NotFound}
+	ErrInvalidURL         = webhookError{"InvalidURL", fault.Invalid}
+	ErrInvalidEvent       = webhookError{"InvalidEvent", fault.Invalid}
+	ErrInvalidChannelType = webhookError{"InvalidChannelType", fault.Invalid}
+	ErrDeliveryPending    = webhookError{"DeliveryPending", fault.Conflict}
+	ErrNoPermissions      = webhookError{"NoPermissions", fault.Forbidden}
+)
+
+func (e webhookError) Error() string {
+	return e.String()
+}
+
+func (e webhookError) String() string {
+	return "crust.webhooks." + e.name
+}
+
+func (e webhookError) Kind() fault.Kind {
+	return e.kind
+}
+
+func (e webhookError) withStack() *fault.Error {
+	return fault.New(e)
+}
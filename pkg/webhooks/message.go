@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+
+	"go.uber.org/zap"
+
+	messagingRepository "github.com/cortezaproject/corteza-server/messaging/repository"
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+	"github.com/cortezaproject/corteza-server/pkg/payload"
+)
+
+type (
+	// message wraps message service and posts message events to webhooks
+	message struct {
+		messagingService.MessageService
+		ctx context.Context
+	}
+)
+
+// Message decorates message service with posting of message events to
+// webhooks of the channel
+func Message(ms messagingService.MessageService) messagingService.MessageService {
+	return &message{MessageService: ms, ctx: context.Background()}
+}
+
+func (svc message) With(ctx context.Context) messagingService.MessageService {
+	return &message{
+		MessageService: svc.MessageService.With(ctx),
+		ctx:            ctx,
+	}
+}
+
+func (svc message) Create(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Create(m)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.enqueue(EventMessageCreated, m)
+	return m, nil
+}
+
+func (svc message) CreateWithAvatar(m *messagingTypes.Message, avatar io.Reader) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.CreateWithAvatar(m, avatar)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.enqueue(EventMessageCreated, m)
+	return m, nil
+}
+
+func (svc message) Update(m *messagingTypes.Message) (*messagingTypes.Message, error) {
+	m, err := svc.MessageService.Update(m)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.enqueue(EventMessageUpdated, m)
+	return m, nil
+}
+
+func (svc message) Delete(messageID uint64) error {
+	m, err := messagingRepository.Message(svc.ctx, messagingRepository.DB(svc.ctx)).FindByID(messageID)
+	if err != nil {
+		logger.AddRequestID(svc.ctx, defaultWebhook.logger).
+			Error("could not load deleted message", zap.Uint64("messageID", messageID), zap.Error(err))
+	}
+
+	if err = svc.MessageService.Delete(messageID); err != nil {
+		return err
+	}
+
+	if m != nil {
+		svc.enqueue(EventMessageDeleted, m)
+	}
+
+	return nil
+}
+
+// enqueue stores deliveries in the background; messages are encoded
+// without flags of the current user
+func (svc message) enqueue(event string, m *messagingTypes.Message) {
+	go defaultWebhook.enqueue(event, m.ChannelID, payload.Message(context.Background(), m))
+}
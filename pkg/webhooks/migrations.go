@@ -0,0 +1,51 @@
+package webhooks
+
+import (
+	"github.com/crusttech/crust-server/pkg/migrations"
+)
+
+var (
+	Migrations = migrations.Set{
+		{
+			Name: "20200304000000.webhooks",
+			Up: `
+CREATE TABLE IF NOT EXISTS crust_messaging_webhook (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_channel        BIGINT UNSIGNED NOT NULL,
+  url                TEXT            NOT NULL,
+  secret             VARCHAR(255)    NOT NULL DEFAULT '',
+  events             TEXT            NOT NULL,
+  enabled            BOOLEAN         NOT NULL DEFAULT TRUE,
+
+  created_by         BIGINT UNSIGNED NOT NULL,
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+  deleted_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (rel_channel)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE IF NOT EXISTS crust_messaging_webhook_delivery (
+  id                 BIGINT UNSIGNED NOT NULL,
+  rel_webhook        BIGINT UNSIGNED NOT NULL,
+  event              VARCHAR(64)     NOT NULL,
+  payload            MEDIUMTEXT      NOT NULL,
+
+  status             VARCHAR(16)     NOT NULL,
+  attempts           INT UNSIGNED    NOT NULL DEFAULT 0,
+  last_error         TEXT            NOT NULL,
+  response_status    INT             NOT NULL DEFAULT 0,
+  next_attempt_at    DATETIME            NULL DEFAULT NULL,
+
+  created_at         DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME            NULL DEFAULT NULL,
+
+  PRIMARY KEY (id),
+  INDEX (status, next_attempt_at),
+  INDEX (rel_webhook, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+`,
+		},
+	}
+)
@@ -0,0 +1,219 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/pkg/errors"
+	"github.com/titpetric/factory"
+
+	"github.com/cortezaproject/corteza-server/pkg/rh"
+)
+
+type (
+	repository struct {
+		ctx context.Context
+		dbh *factory.DB
+	}
+)
+
+func Repository(ctx context.Context, db *factory.DB) *repository {
+	return &repository{
+		ctx: ctx,
+		dbh: db,
+	}
+}
+
+func (r repository) db() *factory.DB {
+	if r.dbh != nil {
+		return r.dbh
+	}
+
+	return factory.Database.MustGet("messaging").With(r.ctx)
+}
+
+func (r repository) table() string {
+	return "crust_messaging_webhook"
+}
+
+func (r repository) tableDelivery() string {
+	return "crust_messaging_webhook_delivery"
+}
+
+func (r repository) query() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_channel",
+			"url",
+			"secret",
+			"events",
+			"enabled",
+			"created_by",
+			"created_at",
+			"updated_at",
+			"deleted_at",
+		).
+		From(r.table()).
+		Where(squirrel.Eq{"deleted_at": nil})
+}
+
+func (r repository) queryDelivery() squirrel.SelectBuilder {
+	return squirrel.
+		Select(
+			"id",
+			"rel_webhook",
+			"event",
+			"payload",
+			"status",
+			"attempts",
+			"last_error",
+			"response_status",
+			"next_attempt_at",
+			"created_at",
+			"updated_at",
+		).
+		From(r.tableDelivery())
+}
+
+func (r repository) FindByID(webhookID uint64) (*Webhook, error) {
+	var (
+		w = &Webhook{}
+		q = r.query().Where(squirrel.Eq{"id": webhookID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, w); err != nil {
+		return nil, err
+	} else if w.ID == 0 {
+		return nil, ErrWebhookNotFound.withStack().WithID("webhookID", webhookID)
+	}
+
+	return w, nil
+}
+
+// FindByChannelID returns webhooks of the channel
+func (r repository) FindByChannelID(channelID uint64) (set WebhookSet, err error) {
+	q := r.query().
+		Where(squirrel.Eq{"rel_channel": channelID}).
+		OrderBy("id")
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) Create(w *Webhook) (*Webhook, error) {
+	w.ID = factory.Sonyflake.NextID()
+	rh.SetCurrentTimeRounded(&w.CreatedAt)
+
+	return w, errors.WithStack(r.db().Insert(r.table(), w))
+}
+
+func (r repository) Update(w *Webhook) (*Webhook, error) {
+	rh.SetCurrentTimeRounded(&w.UpdatedAt)
+
+	return w, errors.WithStack(r.db().Update(r.table(), w, "id"))
+}
+
+// Delete removes the webhook with all its deliveries
+func (r repository) Delete(webhookID uint64) error {
+	return r.db().Transaction(func() error {
+		err := rh.UpdateColumns(r.db(), r.table(), rh.Set{"deleted_at": time.Now()}, squirrel.Eq{"id": webhookID})
+		if err != nil {
+			return err
+		}
+
+		return rh.Delete(r.db(), r.tableDelivery(), squirrel.Eq{"rel_webhook": webhookID})
+	})
+}
+
+func (r repository) FindDeliveryByID(deliveryID uint64) (*Delivery, error) {
+	var (
+		d = &Delivery{}
+		q = r.queryDelivery().Where(squirrel.Eq{"id": deliveryID})
+	)
+
+	if err := rh.FetchOne(r.db(), q, d); err != nil {
+		return nil, err
+	} else if d.ID == 0 {
+		return nil, ErrDeliveryNotFound.withStack().WithID("deliveryID", deliveryID)
+	}
+
+	return d, nil
+}
+
+func (r repository) FindDeliveries(f DeliveryFilter) (set DeliverySet, err error) {
+	q := r.queryDelivery().
+		Where(squirrel.Eq{"rel_webhook": f.WebhookID}).
+		OrderBy("id DESC").
+		Limit(uint64(f.Limit))
+
+	if f.Status != "" {
+		q = q.Where(squirrel.Eq{"status": f.Status})
+	}
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+// FindDue returns deliveries that should be attempted by now
+func (r repository) FindDue(now time.Time) (set DeliverySet, err error) {
+	q := r.queryDelivery().
+		Where(squirrel.Eq{"status": StatusPending}).
+		Where(squirrel.LtOrEq{"next_attempt_at": now}).
+		OrderBy("next_attempt_at").
+		Limit(batchSize)
+
+	return set, rh.FetchAll(r.db(), q, &set)
+}
+
+func (r repository) CreateDelivery(d *Delivery) (*Delivery, error) {
+	rh.SetCurrentTimeRounded(&d.CreatedAt)
+
+	return d, errors.WithStack(r.db().Insert(r.tableDelivery(), d))
+}
+
+// Claim postpones delivery's next attempt; returns false when delivery
+// was already claimed (next attempt was moved) by another instance
+func (r repository) Claim(d *Delivery, until time.Time) (bool, error) {
+	res, err := r.db().Exec(
+		"UPDATE "+r.tableDelivery()+" SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at = ?",
+		until, d.ID, StatusPending, d.NextAttemptAt,
+	)
+
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return n > 0, nil
+}
+
+// UpdateState stores result of the attempt
+func (r repository) UpdateState(d *Delivery) error {
+	rh.SetCurrentTimeRounded(&d.UpdatedAt)
+
+	return rh.UpdateColumns(
+		r.db(),
+		r.tableDelivery(),
+		rh.Set{
+			"status":          d.Status,
+			"attempts":        d.Attempts,
+			"last_error":      d.LastError,
+			"response_status": d.ResponseStatus,
+			"next_attempt_at": d.NextAttemptAt,
+			"updated_at":      d.UpdatedAt,
+		},
+		squirrel.Eq{"id": d.ID},
+	)
+}
+
+// Prune removes delivered events updated before the given time
+func (r repository) Prune(before time.Time) error {
+	return rh.Delete(r.db(), r.tableDelivery(), squirrel.And{
+		squirrel.Eq{"status": StatusDelivered},
+		squirrel.Lt{"updated_at": before},
+	})
+}
@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/titpetric/factory/resputil"
+
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/crusttech/crust-server/pkg/rest"
+)
+
+// MountRoutes mounts endpoints of outgoing webhooks and their deliveries
+func MountRoutes(r chi.Router) {
+	r.Use(auth.MiddlewareValidOnly)
+
+	// ?channelID=<ID>
+	r.Get("/", rest.Handler("Webhook.List", func(r *http.Request) (interface{}, error) {
+		return DefaultWebhook.With(r.Context()).Find(rest.QueryUint64(r, "channelID"))
+	}))
+
+	r.Post("/", rest.Handler("Webhook.Create", func(r *http.Request) (interface{}, error) {
+		var body struct {
+			ChannelID uint64 `json:"channelID,string"`
+			WebhookInput
+		}
+
+		if err := rest.Decode(r, &body); err != nil {
+			return nil, err
+		}
+
+		return DefaultWebhook.With(r.Context()).Create(body.ChannelID, body.WebhookInput)
+	}))
+
+	r.Get("/{webhookID}", rest.Handler("Webhook.Read", func(r *http.Request) (interface{}, error) {
+		return DefaultWebhook.With(r.Context()).FindByID(rest.ParamUint64(r, "webhookID"))
+	}))
+
+	r.Put("/{webhookID}", rest.Handler("Webhook.Update", func(r *http.Request) (interface{}, error) {
+		in := WebhookInput{}
+		if err := rest.Decode(r, &in); err != nil {
+			return nil, err
+		}
+
+		return DefaultWebhook.With(r.Context()).Update(rest.ParamUint64(r, "webhookID"), in)
+	}))
+
+	r.Delete("/{webhookID}", rest.Handler("Webhook.Delete", func(r *http.Request) (interface{}, error) {
+		return resputil.OK(), DefaultWebhook.With(r.Context()).Delete(rest.ParamUint64(r, "webhookID"))
+	}))
+
+	// ?status=dead&limit=100
+	r.Get("/{webhookID}/deliveries", rest.Handler("Webhook.Deliveries", func(r *http.Request) (interface{}, error) {
+		return DefaultWebhook.With(r.Context()).Deliveries(DeliveryFilter{
+			WebhookID: rest.ParamUint64(r, "webhookID"),
+			Status:    Status(r.URL.Query().Get("status")),
+			Limit:     rest.QueryUint(r, "limit"),
+		})
+	}))
+
+	r.Get("/deliveries/{deliveryID}", rest.Handler("Webhook.Delivery", func(r *http.Request) (interface{}, error) {
+		return DefaultWebhook.With(r.Context()).Delivery(rest.ParamUint64(r, "deliveryID"))
+	}))
+
+	// Posts the delivery again, with all attempts
+	r.Post("/deliveries/{deliveryID}/redeliver", rest.Handler("Webhook.Redeliver", func(r *http.Request) (interface{}, error) {
+		return DefaultWebhook.With(r.Context()).Redeliver(rest.ParamUint64(r, "deliveryID"))
+	}))
+}
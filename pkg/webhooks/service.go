@@ -0,0 +1,259 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/titpetric/factory"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	messagingService "github.com/cortezaproject/corteza-server/messaging/service"
+	messagingTypes "github.com/cortezaproject/corteza-server/messaging/types"
+	"github.com/cortezaproject/corteza-server/pkg/auth"
+	"github.com/cortezaproject/corteza-server/pkg/logger"
+)
+
+type (
+	accessController interface {
+		CanUpdateChannel(context.Context, *messagingTypes.Channel) bool
+	}
+
+	service struct {
+		ctx    context.Context
+		logger *zap.Logger
+
+		ac       accessController
+		channels messagingService.ChannelService
+
+		repository *repository
+	}
+
+	WebhookService interface {
+		With(ctx context.Context) WebhookService
+
+		Find(channelID uint64) (WebhookSet, error)
+		FindByID(webhookID uint64) (*Webhook, error)
+		Create(channelID uint64, in WebhookInput) (*Webhook, error)
+		Update(webhookID uint64, in WebhookInput) (*Webhook, error)
+		Delete(webhookID uint64) error
+
+		Deliveries(DeliveryFilter) (DeliverySet, error)
+		Delivery(deliveryID uint64) (*Delivery, error)
+		Redeliver(deliveryID uint64) (*Delivery, error)
+	}
+)
+
+var (
+	DefaultWebhook WebhookService
+
+	// used by service decorators
+	defaultWebhook *service
+
+	// now is used for delivery times and can be overridden
+	now = time.Now
+)
+
+// Init initializes outgoing webhooks and decorates message service
+// with posting message events to webhooks of the channel
+//
+// Events are stored as deliveries and posted in the background; failed
+// deliveries are retried with exponential backoff.
+//
+// Must be called after messaging services are initialized.
+func Init(ctx context.Context, log *zap.Logger) error {
+	svc := &service{
+		logger:   log,
+		ac:       messagingService.DefaultAccessControl,
+		channels: messagingService.DefaultChannel,
+	}
+
+	DefaultWebhook = svc.With(ctx)
+	defaultWebhook = svc.with(ctx)
+
+	go defaultWebhook.watch(ctx)
+
+	messagingService.DefaultMessage = Message(messagingService.DefaultMessage)
+	return nil
+}
+
+func (svc service) With(ctx context.Context) WebhookService {
+	return svc.with(ctx)
+}
+
+func (svc service) with(ctx context.Context) *service {
+	return &service{
+		ctx:    ctx,
+		logger: svc.logger,
+
+		ac:       svc.ac,
+		channels: svc.channels.With(ctx),
+
+		repository: Repository(ctx, factory.Database.MustGet("messaging").With(ctx)),
+	}
+}
+
+// log() returns zap's logger with requestID from current context and fields.
+func (svc service) log(fields ...zapcore.Field) *zap.Logger {
+	return logger.AddRequestID(svc.ctx, svc.logger).With(fields...)
+}
+
+// Find returns webhooks of the channel
+func (svc service) Find(channelID uint64) (WebhookSet, error) {
+	if err := svc.canManage(channelID); err != nil {
+		return nil, err
+	}
+
+	set, err := svc.repository.FindByChannelID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range set {
+		w.HasSecret = w.Secret != ""
+	}
+
+	return set, nil
+}
+
+func (svc service) FindByID(webhookID uint64) (*Webhook, error) {
+	w, err := svc.repository.FindByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = svc.canManage(w.ChannelID); err != nil {
+		return nil, err
+	}
+
+	w.HasSecret = w.Secret != ""
+	return w, nil
+}
+
+func (svc service) Create(channelID uint64, in WebhookInput) (*Webhook, error) {
+	if err := svc.canManage(channelID); err != nil {
+		return nil, err
+	}
+
+	w := &Webhook{
+		ChannelID: channelID,
+		Enabled:   true,
+		CreatedBy: auth.GetIdentityFromContext(svc.ctx).Identity(),
+	}
+
+	if err := w.apply(in); err != nil {
+		return nil, err
+	}
+
+	w, err := svc.repository.Create(w)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("webhookID", w.ID), zap.Uint64("channelID", channelID)).Info("webhook created")
+
+	w.HasSecret = w.Secret != ""
+	return w, nil
+}
+
+func (svc service) Update(webhookID uint64, in WebhookInput) (*Webhook, error) {
+	w, err := svc.FindByID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = w.apply(in); err != nil {
+		return nil, err
+	}
+
+	if w, err = svc.repository.Update(w); err != nil {
+		return nil, err
+	}
+
+	w.HasSecret = w.Secret != ""
+	return w, nil
+}
+
+// Delete removes the webhook; its pending deliveries are not posted
+func (svc service) Delete(webhookID uint64) error {
+	w, err := svc.FindByID(webhookID)
+	if err != nil {
+		return err
+	}
+
+	if err = svc.repository.Delete(w.ID); err != nil {
+		return err
+	}
+
+	svc.log(zap.Uint64("webhookID", w.ID), zap.Uint64("channelID", w.ChannelID)).Info("webhook deleted")
+	return nil
+}
+
+// Deliveries returns the latest deliveries of the webhook
+func (svc service) Deliveries(f DeliveryFilter) (DeliverySet, error) {
+	if _, err := svc.FindByID(f.WebhookID); err != nil {
+		return nil, err
+	}
+
+	if f.Limit == 0 {
+		f.Limit = defaultLimit
+	} else if f.Limit > maxLimit {
+		f.Limit = maxLimit
+	}
+
+	return svc.repository.FindDeliveries(f)
+}
+
+func (svc service) Delivery(deliveryID uint64) (*Delivery, error) {
+	d, err := svc.repository.FindDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = svc.FindByID(d.WebhookID); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Redeliver schedules the delivery to be posted again right away, with all attempts
+func (svc service) Redeliver(deliveryID uint64) (*Delivery, error) {
+	d, err := svc.Delivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Status == StatusPending {
+		return nil, ErrDeliveryPending.withStack().WithID("deliveryID", deliveryID)
+	}
+
+	at := now()
+	d.Status = StatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = &at
+
+	if err = svc.repository.UpdateState(d); err != nil {
+		return nil, err
+	}
+
+	svc.log(zap.Uint64("deliveryID", d.ID), zap.Uint64("webhookID", d.WebhookID)).Info("delivery replayed")
+	return d, nil
+}
+
+// canManage checks if the current user can manage webhooks of the channel
+//
+// Webhooks are managed by users that can update the channel; direct
+// messages can not have them.
+func (svc service) canManage(channelID uint64) error {
+	ch, err := svc.channels.FindByID(channelID)
+	if err != nil {
+		return err
+	} else if !svc.ac.CanUpdateChannel(svc.ctx, ch) {
+		return ErrNoPermissions.withStack().WithID("channelID", channelID)
+	} else if ch.Type == messagingTypes.ChannelTypeGroup {
+		return ErrInvalidChannelType.withStack().WithMessage("direct messages can not have webhooks")
+	}
+
+	return nil
+}
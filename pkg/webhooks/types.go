@@ -0,0 +1,205 @@
+package webhooks
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Webhook posts channel's message events to an external URL
+	//
+	// Every event is stored as a delivery and posted with retries;
+	// deliveries are signed with the secret when one is set.
+	Webhook struct {
+		ID        uint64   `json:"webhookID,string" db:"id"`
+		ChannelID uint64   `json:"channelID,string" db:"rel_channel"`
+		URL       string   `json:"url" db:"url"`
+		Secret    string   `json:"-" db:"secret"`
+		Events    eventSet `json:"events" db:"events"`
+		Enabled   bool     `json:"enabled" db:"enabled"`
+
+		// Secret is never sent back
+		HasSecret bool `json:"hasSecret" db:"-"`
+
+		CreatedBy uint64     `json:"createdBy,string" db:"created_by"`
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+		DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at"`
+	}
+
+	WebhookSet []*Webhook
+
+	// WebhookInput holds settings of the webhook; secret is left as
+	// it is when not given and removed when empty
+	WebhookInput struct {
+		URL     string   `json:"url"`
+		Secret  *string  `json:"secret"`
+		Events  []string `json:"events"`
+		Enabled *bool    `json:"enabled"`
+	}
+
+	// Delivery is a single event, posted to the webhook's URL
+	Delivery struct {
+		ID        uint64          `json:"deliveryID,string" db:"id"`
+		WebhookID uint64          `json:"webhookID,string" db:"rel_webhook"`
+		Event     string          `json:"event" db:"event"`
+		Payload   json.RawMessage `json:"payload" db:"payload"`
+
+		Status         Status     `json:"status" db:"status"`
+		Attempts       uint       `json:"attempts" db:"attempts"`
+		LastError      string     `json:"lastError" db:"last_error"`
+		ResponseStatus int        `json:"responseStatus" db:"response_status"`
+		NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty" db:"next_attempt_at"`
+
+		CreatedAt time.Time  `json:"createdAt,omitempty" db:"created_at"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty" db:"updated_at"`
+	}
+
+	DeliverySet []*Delivery
+
+	DeliveryFilter struct {
+		WebhookID uint64 `json:"webhookID,string"`
+		Status    Status `json:"status"`
+		Limit     uint   `json:"limit"`
+	}
+
+	// body is posted to the webhook's URL
+	body struct {
+		DeliveryID uint64      `json:"deliveryID,string"`
+		WebhookID  uint64      `json:"webhookID,string"`
+		ChannelID  uint64      `json:"channelID,string"`
+		Event      string      `json:"event"`
+		Message    interface{} `json:"message"`
+		Timestamp  time.Time   `json:"timestamp"`
+	}
+
+	Status string
+
+	eventSet []string
+)
+
+const (
+	EventMessageCreated = "message.created"
+	EventMessageUpdated = "message.updated"
+	EventMessageDeleted = "message.deleted"
+
+	StatusPending   Status = "pending"
+	StatusDelivered Status = "delivered"
+	StatusDead      Status = "dead"
+
+	// First delivery counts as an attempt
+	maxAttempts = 8
+
+	// Delay after the first failed attempt, doubled with every next one
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+
+	// Deliveries are claimed for the duration of the request, so that
+	// other instances do not pick them up
+	claimTimeout    = time.Minute
+	deliveryTimeout = 10 * time.Second
+
+	watchInterval = 2 * time.Second
+	batchSize     = 100
+
+	pruneInterval = time.Hour
+
+	// Delivered events are kept for inspection; dead ones until redelivered
+	// or the webhook is removed
+	retention = 3 * 24 * time.Hour
+
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+var (
+	events = map[string]bool{
+		EventMessageCreated: true,
+		EventMessageUpdated: true,
+		EventMessageDeleted: true,
+	}
+)
+
+// backoff returns delay before the next attempt
+func backoff(attempts uint) time.Duration {
+	d := baseBackoff
+	for i := uint(1); i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return d
+}
+
+// Subscribed checks if the webhook is enabled and posts events of the type
+func (w Webhook) Subscribed(event string) bool {
+	if !w.Enabled || w.DeletedAt != nil {
+		return false
+	}
+
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply validates and sets webhook's settings
+func (w *Webhook) apply(in WebhookInput) error {
+	if u, err := url.Parse(in.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL.withStack()
+	}
+
+	if len(in.Events) == 0 {
+		return ErrInvalidEvent.withStack().WithMessage("at least one event is required")
+	}
+
+	for _, e := range in.Events {
+		if !events[e] {
+			return ErrInvalidEvent.withStack().WithMessage("unknown event " + e)
+		}
+	}
+
+	w.URL = in.URL
+	w.Events = in.Events
+
+	if in.Secret != nil {
+		w.Secret = *in.Secret
+	}
+
+	if in.Enabled != nil {
+		w.Enabled = *in.Enabled
+	}
+
+	return nil
+}
+
+func (ee eventSet) Value() (driver.Value, error) {
+	if ee == nil {
+		ee = eventSet{}
+	}
+
+	return json.Marshal(ee)
+}
+
+func (ee *eventSet) Scan(value interface{}) error {
+	switch b := value.(type) {
+	case nil:
+		*ee = eventSet{}
+	case []byte:
+		if err := json.Unmarshal(b, ee); err != nil {
+			return errors.Wrapf(err, "can not scan '%v' into eventSet", string(b))
+		}
+	}
+
+	return nil
+}